@@ -0,0 +1,129 @@
+package address
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Address{}))
+	return db
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		country  string
+		postcode string
+		wantCode string
+		wantPost string
+		wantErr  error
+	}{
+		{"uk postcode is spaced", "United Kingdom", "sw1a1aa", "GB", "SW1A 1AA", nil},
+		{"uk alias", "uk", "M1 1AE", "GB", "M1 1AE", nil},
+		{"invalid uk postcode", "GB", "12345", "", "", ErrInvalidPostcode},
+		{"french postcode", "france", "75008", "FR", "75008", nil},
+		{"dutch postcode", "NL", "1012ab", "NL", "1012 AB", nil},
+		{"eircode", "Ireland", "d02x285", "IE", "D02 X285", nil},
+		{"us zip+4", "USA", "10001-1234", "US", "10001-1234", nil},
+		{"country without format", "Qatar", "  12 34 ", "QA", "12 34", nil},
+		{"unknown country", "Atlantis", "12345", "", "", ErrUnknownCountry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := models.Address{StreetAddress1: " 1 High Street ", City: "Town", Country: tt.country, PostalCode: tt.postcode}
+			err := Normalize(&a)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCode, a.Country)
+			assert.Equal(t, tt.wantPost, a.PostalCode)
+			assert.Equal(t, "1 High Street", a.StreetAddress1)
+		})
+	}
+
+	missing := models.Address{StreetAddress1: "1 High Street", Country: "GB", PostalCode: "M1 1AE"}
+	assert.ErrorIs(t, Normalize(&missing), ErrMissingField)
+}
+
+func TestDefaults(t *testing.T) {
+	db := setupTestDB(t)
+	userID := uint(7)
+	home := models.Address{StreetAddress1: "1 Home St", City: "Leeds", PostalCode: "LS1 1AA", Country: "GB", UserID: &userID, IsDefault: true}
+	office := models.Address{StreetAddress1: "2 Office Rd", City: "York", PostalCode: "YO1 1AA", Country: "GB", UserID: &userID}
+	require.NoError(t, db.Create(&home).Error)
+	require.NoError(t, db.Create(&office).Error)
+
+	// Without a billing default, the shipping default is billed
+	billing, err := Default(db, userID, Billing)
+	require.NoError(t, err)
+	assert.Equal(t, home.ID, billing.ID)
+	_, err = Flagged(db, userID, Billing)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, SetDefault(db, userID, office.ID, Billing))
+	require.NoError(t, SetDefault(db, userID, office.ID, Shipping))
+	shipping, err := Default(db, userID, Shipping)
+	require.NoError(t, err)
+	assert.Equal(t, office.ID, shipping.ID)
+	var reloaded models.Address
+	require.NoError(t, db.First(&reloaded, home.ID).Error)
+	assert.False(t, reloaded.IsDefault)
+
+	// Deleting the default hands its flags to the remaining address
+	var deleted models.Address
+	require.NoError(t, db.First(&deleted, office.ID).Error)
+	require.NoError(t, db.Delete(&deleted).Error)
+	require.NoError(t, ReassignDefaults(db, &deleted))
+	var remaining models.Address
+	require.NoError(t, db.First(&remaining, home.ID).Error)
+	assert.True(t, remaining.IsDefault)
+	assert.True(t, remaining.IsDefaultBilling)
+
+	_, err = Default(db, 99, Shipping)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestNominatimGeocode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "gb", r.URL.Query().Get("countrycodes"))
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		if r.URL.Query().Get("postalcode") == "ZZ1 1ZZ" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"lat":"53.7997","lon":"-1.5492"}]`))
+	}))
+	defer server.Close()
+	geocoder := NewNominatim(server.URL, "test-agent")
+
+	a := models.Address{StreetAddress1: "1 Home St", City: "Leeds", PostalCode: "LS1 1AA", Country: "GB"}
+	Geocode(context.Background(), geocoder, &a)
+	require.NotNil(t, a.Latitude)
+	assert.InDelta(t, 53.7997, *a.Latitude, 0.0001)
+	assert.InDelta(t, -1.5492, *a.Longitude, 0.0001)
+	assert.NotNil(t, a.GeocodedAt)
+
+	// An address the provider cannot place is saved without coordinates
+	a.PostalCode = "ZZ1 1ZZ"
+	Geocode(context.Background(), geocoder, &a)
+	assert.Nil(t, a.Latitude)
+	assert.Nil(t, a.GeocodedAt)
+
+	// Without a provider, addresses are not geocoded
+	Geocode(context.Background(), nil, &a)
+	assert.Nil(t, a.Latitude)
+}
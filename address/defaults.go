@@ -0,0 +1,121 @@
+package address
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// Kind is the use an address is the default for
+type Kind string
+
+const (
+	Shipping Kind = "shipping"
+	Billing  Kind = "billing"
+)
+
+// column is the flag marking an address as the user's default of a kind
+func (k Kind) column() string {
+	if k == Billing {
+		return "is_default_billing"
+	}
+	return "is_default"
+}
+
+// ParseKind parses a kind, defaulting to shipping
+func ParseKind(s string) (Kind, bool) {
+	switch Kind(s) {
+	case "", Shipping:
+		return Shipping, true
+	case Billing:
+		return Billing, true
+	}
+	return "", false
+}
+
+// SetDefault makes an address the user's default of a kind, clearing the flag
+// on their other addresses
+func SetDefault(tx *gorm.DB, userID, addressID uint, kind Kind) error {
+	column := kind.column()
+	if err := tx.Model(&models.Address{}).
+		Where("user_id = ? AND id <> ? AND "+column+" = ?", userID, addressID, true).
+		Update(column, false).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Address{}).
+		Where("id = ? AND user_id = ?", addressID, userID).
+		Update(column, true).Error
+}
+
+// ClaimDefaults clears the default flags a saved address holds from the
+// user's other addresses
+func ClaimDefaults(tx *gorm.DB, a *models.Address) error {
+	if a.UserID == nil {
+		return nil
+	}
+	if a.IsDefault {
+		if err := SetDefault(tx, *a.UserID, a.ID, Shipping); err != nil {
+			return err
+		}
+	}
+	if a.IsDefaultBilling {
+		if err := SetDefault(tx, *a.UserID, a.ID, Billing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flagged returns the address the user has marked as their default of a
+// kind, or gorm.ErrRecordNotFound when they have not marked one
+func Flagged(db *gorm.DB, userID uint, kind Kind) (*models.Address, error) {
+	var address models.Address
+	if err := db.Where("user_id = ? AND "+kind.column()+" = ?", userID, true).First(&address).Error; err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// Default returns the user's default address of a kind. Without one, the
+// default shipping address stands in for billing and the most recent address
+// for shipping. It returns gorm.ErrRecordNotFound when the user has no
+// addresses.
+func Default(db *gorm.DB, userID uint, kind Kind) (*models.Address, error) {
+	address, err := Flagged(db, userID, kind)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return address, err
+	}
+	if kind == Billing {
+		return Default(db, userID, Shipping)
+	}
+	var latest models.Address
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").First(&latest).Error; err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
+
+// ReassignDefaults gives the defaults held by a deleted address to the user's
+// oldest remaining address
+func ReassignDefaults(tx *gorm.DB, deleted *models.Address) error {
+	if deleted.UserID == nil || (!deleted.IsDefault && !deleted.IsDefaultBilling) {
+		return nil
+	}
+	var next models.Address
+	err := tx.Where("user_id = ? AND id <> ?", *deleted.UserID, deleted.ID).Order("created_at ASC, id ASC").First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{}
+	if deleted.IsDefault {
+		updates[Shipping.column()] = true
+	}
+	if deleted.IsDefaultBilling {
+		updates[Billing.column()] = true
+	}
+	return tx.Model(&next).Updates(updates).Error
+}
@@ -0,0 +1,135 @@
+package address
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/tracing"
+)
+
+// ErrNotFound is returned by a Geocoder that cannot place an address
+var ErrNotFound = errors.New("address not found")
+
+// Coordinates locate an address
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Geocoder looks up the coordinates of an address
+type Geocoder interface {
+	Geocode(ctx context.Context, a *models.Address) (*Coordinates, error)
+}
+
+// NewGeocoder returns the geocoder configured by GEOCODING_PROVIDER, or nil
+// when geocoding is off
+func NewGeocoder(config *cfg.GeocodingConfig) Geocoder {
+	if config == nil {
+		return nil
+	}
+	switch strings.ToLower(config.Provider) {
+	case "nominatim":
+		return NewNominatim(config.URL, config.UserAgent)
+	case "", "none":
+		return nil
+	default:
+		slog.Warn("unknown geocoding provider, geocoding is off", "component", "address", "provider", config.Provider)
+		return nil
+	}
+}
+
+// Geocode sets the coordinates of an address. Geocoding is best effort: a
+// failure is logged and leaves the address without coordinates, so a slow or
+// unavailable provider never stops a customer saving an address.
+func Geocode(ctx context.Context, geocoder Geocoder, a *models.Address) {
+	a.Latitude, a.Longitude, a.GeocodedAt = nil, nil, nil
+	if geocoder == nil {
+		return
+	}
+	coords, err := geocoder.Geocode(ctx, a)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			slog.WarnContext(ctx, "failed to geocode address", "component", "address", "error", err)
+		}
+		return
+	}
+	now := time.Now()
+	a.Latitude, a.Longitude, a.GeocodedAt = &coords.Latitude, &coords.Longitude, &now
+}
+
+// Nominatim geocodes addresses with the OpenStreetMap Nominatim API
+type Nominatim struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+func NewNominatim(baseURL, userAgent string) *Nominatim {
+	return &Nominatim{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		userAgent: userAgent,
+		httpClient: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: tracing.Transport(nil),
+		},
+	}
+}
+
+func (n *Nominatim) Geocode(ctx context.Context, a *models.Address) (*Coordinates, error) {
+	query := url.Values{
+		"format":       {"jsonv2"},
+		"limit":        {"1"},
+		"street":       {strings.TrimSpace(a.StreetAddress1 + " " + a.StreetAddress2)},
+		"city":         {a.City},
+		"postalcode":   {a.PostalCode},
+		"countrycodes": {strings.ToLower(a.Country)},
+	}
+	if a.State != "" {
+		query.Set("state", a.State)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", n.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nominatim returned status %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode nominatim response: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude %q: %w", results[0].Lat, err)
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude %q: %w", results[0].Lon, err)
+	}
+	return &Coordinates{Latitude: lat, Longitude: lon}, nil
+}
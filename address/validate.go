@@ -0,0 +1,209 @@
+// Package address validates users' saved addresses, keeps track of their
+// default shipping and billing addresses and geocodes them.
+package address
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+var (
+	ErrUnknownCountry  = errors.New("unknown country")
+	ErrInvalidPostcode = errors.New("invalid postcode")
+	ErrMissingField    = errors.New("missing required address field")
+)
+
+// countries maps the ISO 3166-1 alpha-2 codes of the countries the store
+// ships to onto their English names. Both are accepted as input.
+var countries = map[string]string{
+	"AE": "United Arab Emirates",
+	"AT": "Austria",
+	"AU": "Australia",
+	"BE": "Belgium",
+	"BG": "Bulgaria",
+	"CA": "Canada",
+	"CH": "Switzerland",
+	"CY": "Cyprus",
+	"CZ": "Czech Republic",
+	"DE": "Germany",
+	"DK": "Denmark",
+	"DZ": "Algeria",
+	"EE": "Estonia",
+	"EG": "Egypt",
+	"ES": "Spain",
+	"FI": "Finland",
+	"FR": "France",
+	"GB": "United Kingdom",
+	"GR": "Greece",
+	"HR": "Croatia",
+	"HU": "Hungary",
+	"IE": "Ireland",
+	"IT": "Italy",
+	"LT": "Lithuania",
+	"LU": "Luxembourg",
+	"LV": "Latvia",
+	"MA": "Morocco",
+	"MT": "Malta",
+	"NL": "Netherlands",
+	"NO": "Norway",
+	"PL": "Poland",
+	"PT": "Portugal",
+	"QA": "Qatar",
+	"RO": "Romania",
+	"SA": "Saudi Arabia",
+	"SE": "Sweden",
+	"SI": "Slovenia",
+	"SK": "Slovakia",
+	"TN": "Tunisia",
+	"TR": "Turkey",
+	"US": "United States",
+}
+
+// countryAliases are other common names for supported countries
+var countryAliases = map[string]string{
+	"UK":                       "GB",
+	"GREAT BRITAIN":            "GB",
+	"ENGLAND":                  "GB",
+	"SCOTLAND":                 "GB",
+	"WALES":                    "GB",
+	"NORTHERN IRELAND":         "GB",
+	"USA":                      "US",
+	"UNITED STATES OF AMERICA": "US",
+	"CZECHIA":                  "CZ",
+	"HOLLAND":                  "NL",
+	"TURKIYE":                  "TR",
+	"ALGERIE":                  "DZ",
+}
+
+// postcodeFormats are the postcode patterns of countries with a fixed format,
+// matched after the postcode is upper-cased and respaced. Other countries
+// accept any short alphanumeric code.
+var postcodeFormats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[A-Z] \d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"DZ": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^(GIR 0AA|[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2})$`),
+	"IE": regexp.MustCompile(`^([AC-FHKNPRTV-Y]\d{2}|D6W) [0-9AC-FHKNPRTV-Y]{4}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"MA": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} [A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} \d{2}$`),
+	"TN": regexp.MustCompile(`^\d{4}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+var anyPostcode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+
+// CountryCode returns the ISO 3166-1 alpha-2 code of a supported country,
+// given its code or English name
+func CountryCode(country string) (string, bool) {
+	key := strings.ToUpper(strings.Join(strings.Fields(country), " "))
+	if _, ok := countries[key]; ok {
+		return key, true
+	}
+	if code, ok := countryAliases[key]; ok {
+		return code, true
+	}
+	for code, name := range countries {
+		if strings.ToUpper(name) == key {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// CountryName returns the English name of a country code, or the code itself
+// when it is not supported
+func CountryName(code string) string {
+	if name, ok := countries[strings.ToUpper(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// NormalizePostcode returns a postcode in its country's canonical form,
+// upper-cased and spaced as the postal service writes it, or
+// ErrInvalidPostcode when it does not match the country's format
+func NormalizePostcode(countryCode, postcode string) (string, error) {
+	compact := strings.ToUpper(strings.Join(strings.Fields(postcode), ""))
+	normalized := compact
+	switch countryCode {
+	case "GB", "CA":
+		// The inward code is always the last three characters
+		if len(compact) > 3 {
+			normalized = compact[:len(compact)-3] + " " + compact[len(compact)-3:]
+		}
+	case "IE":
+		if len(compact) == 7 {
+			normalized = compact[:3] + " " + compact[3:]
+		}
+	case "NL":
+		if len(compact) == 6 {
+			normalized = compact[:4] + " " + compact[4:]
+		}
+	case "SE":
+		if len(compact) == 5 {
+			normalized = compact[:3] + " " + compact[3:]
+		}
+	default:
+		normalized = strings.ToUpper(strings.Join(strings.Fields(postcode), " "))
+	}
+
+	format, ok := postcodeFormats[countryCode]
+	if !ok {
+		format = anyPostcode
+	}
+	if !format.MatchString(normalized) {
+		return "", fmt.Errorf("%w for %s: %q", ErrInvalidPostcode, CountryName(countryCode), postcode)
+	}
+	return normalized, nil
+}
+
+// Normalize validates an address entered by a user and rewrites it in
+// canonical form: fields are trimmed, the country becomes its ISO code and the
+// postcode is formatted for that country
+func Normalize(a *models.Address) error {
+	a.StreetAddress1 = strings.TrimSpace(a.StreetAddress1)
+	a.StreetAddress2 = strings.TrimSpace(a.StreetAddress2)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.TrimSpace(a.State)
+	a.Label = strings.TrimSpace(a.Label)
+	a.Phone = strings.TrimSpace(a.Phone)
+	required := []struct{ field, value string }{
+		{"street_address1", a.StreetAddress1},
+		{"city", a.City},
+		{"postal_code", a.PostalCode},
+		{"country", a.Country},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			return fmt.Errorf("%w: %s", ErrMissingField, r.field)
+		}
+	}
+
+	code, ok := CountryCode(a.Country)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownCountry, a.Country)
+	}
+	postcode, err := NormalizePostcode(code, a.PostalCode)
+	if err != nil {
+		return err
+	}
+	a.Country = code
+	a.PostalCode = postcode
+	return nil
+}
@@ -0,0 +1,308 @@
+// Package analytics builds the sales reports of the admin dashboard by
+// aggregating orders and their lines in SQL. Cancelled and returned orders,
+// and cancelled or returned lines, are left out of every report.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// Interval is the length of the periods a sales report is broken down by
+type Interval string
+
+const (
+	Day   Interval = "day"
+	Week  Interval = "week" // weeks start on Monday
+	Month Interval = "month"
+)
+
+// ParseInterval parses an interval query parameter; empty means Day
+func ParseInterval(value string) (Interval, error) {
+	switch Interval(value) {
+	case "", Day:
+		return Day, nil
+	case Week, Month:
+		return Interval(value), nil
+	default:
+		return "", fmt.Errorf("unsupported interval %q, use day, week or month", value)
+	}
+}
+
+// Range is the order dates a report covers, From inclusive and To exclusive
+type Range struct {
+	From time.Time
+	To   time.Time
+}
+
+// Totals are the sales of a set of orders
+type Totals struct {
+	Revenue           float64 `json:"revenue"`
+	Orders            int64   `json:"orders"`
+	AverageOrderValue float64 `json:"average_order_value"`
+	Units             int64   `json:"units"`
+}
+
+// Period is the sales of one day, week or month, named by its first day
+// (YYYY-MM-DD)
+type Period struct {
+	Period string `json:"period"`
+	Totals
+}
+
+// SalesReport is the sales of a date range, overall and by period. Periods
+// without sales are included.
+type SalesReport struct {
+	Interval Interval `json:"interval"`
+	Totals   Totals   `json:"totals"`
+	Series   []Period `json:"series"`
+}
+
+// ProductSales is the sales of one product
+type ProductSales struct {
+	ProductID uint    `json:"product_id"`
+	Name      string  `json:"name"`
+	Revenue   float64 `json:"revenue"`
+	Units     int64   `json:"units"`
+	Orders    int64   `json:"orders"`
+}
+
+// CategorySales is the sales of the products in one category. A product in
+// several categories counts towards each of them.
+type CategorySales struct {
+	CategoryID uint    `json:"category_id"`
+	Name       string  `json:"name"`
+	Revenue    float64 `json:"revenue"`
+	Units      int64   `json:"units"`
+	Orders     int64   `json:"orders"`
+}
+
+// Cohort is the customers who placed their first order in the same month,
+// and how many of them have ordered again since
+type Cohort struct {
+	Cohort          string  `json:"cohort"` // first day of the month, YYYY-MM-DD
+	Customers       int64   `json:"customers"`
+	RepeatCustomers int64   `json:"repeat_customers"`
+	RepeatRate      float64 `json:"repeat_rate"` // percentage of the customers
+	Orders          int64   `json:"orders"`
+	Revenue         float64 `json:"revenue"`
+}
+
+// Service builds sales reports
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// WithContext returns a service whose queries run with ctx, so they are
+// cancelled with the request and can be sent to a read replica
+func (s *Service) WithContext(ctx context.Context) *Service {
+	return &Service{db: s.db.WithContext(ctx)}
+}
+
+// excludedStatuses are the orders that did not end in a sale
+var excludedStatuses = []models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusReturned}
+
+// soldOrders selects the orders that count as sales, placed in r
+func (s *Service) soldOrders(r Range) *gorm.DB {
+	return s.db.Table("orders").
+		Where("orders.deleted_at IS NULL AND orders.status NOT IN ?", excludedStatuses).
+		Where("orders.order_date >= ? AND orders.order_date < ?", r.From, r.To)
+}
+
+// soldLines selects the lines of the orders that count as sales, placed in r
+func (s *Service) soldLines(r Range) *gorm.DB {
+	return s.db.Table("order_items").
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Where("order_items.deleted_at IS NULL AND order_items.status NOT IN ?", []string{"cancelled", "returned"}).
+		Where("orders.status NOT IN ?", excludedStatuses).
+		Where("orders.order_date >= ? AND orders.order_date < ?", r.From, r.To)
+}
+
+// Sales returns the revenue, orders, average order value and units sold in
+// r, overall and for each period of the interval
+func (s *Service) Sales(r Range, interval Interval) (*SalesReport, error) {
+	period := s.periodExpr(interval, "orders.order_date")
+
+	var orderRows []struct {
+		Period  string
+		Revenue float64
+		Orders  int64
+	}
+	if err := s.soldOrders(r).
+		Select(period + " AS period, COALESCE(SUM(orders.final_amount), 0) AS revenue, COUNT(*) AS orders").
+		Group(period).
+		Scan(&orderRows).Error; err != nil {
+		return nil, err
+	}
+	var unitRows []struct {
+		Period string
+		Units  int64
+	}
+	if err := s.soldLines(r).
+		Select(period + " AS period, COALESCE(SUM(order_items.quantity), 0) AS units").
+		Group(period).
+		Scan(&unitRows).Error; err != nil {
+		return nil, err
+	}
+
+	periods := map[string]*Totals{}
+	report := &SalesReport{Interval: interval, Series: []Period{}}
+	for start := periodStart(r.From, interval); start.Before(r.To); start = nextPeriod(start, interval) {
+		report.Series = append(report.Series, Period{Period: start.Format("2006-01-02")})
+	}
+	for i := range report.Series {
+		periods[report.Series[i].Period] = &report.Series[i].Totals
+	}
+	for _, row := range orderRows {
+		if totals, ok := periods[row.Period]; ok {
+			totals.Revenue = row.Revenue
+			totals.Orders = row.Orders
+		}
+		report.Totals.Revenue += row.Revenue
+		report.Totals.Orders += row.Orders
+	}
+	for _, row := range unitRows {
+		if totals, ok := periods[row.Period]; ok {
+			totals.Units = row.Units
+		}
+		report.Totals.Units += row.Units
+	}
+	for i := range report.Series {
+		report.Series[i].Totals.round()
+	}
+	report.Totals.round()
+	return report, nil
+}
+
+// round rounds amounts to pence and works out the average order value
+func (t *Totals) round() {
+	if t.Orders > 0 {
+		t.AverageOrderValue = roundMoney(t.Revenue / float64(t.Orders))
+	}
+	t.Revenue = roundMoney(t.Revenue)
+}
+
+// TopProducts returns the best selling products in r by revenue, at most
+// limit of them
+func (s *Service) TopProducts(r Range, limit int) ([]ProductSales, error) {
+	products := []ProductSales{}
+	if err := s.soldLines(r).
+		Select(`products.id AS product_id, products.name AS name, COALESCE(SUM(order_items.total_amount), 0) AS revenue,
+			COALESCE(SUM(order_items.quantity), 0) AS units, COUNT(DISTINCT orders.id) AS orders`).
+		Joins("JOIN product_variants ON product_variants.id = order_items.product_variant_id").
+		Joins("JOIN products ON products.id = product_variants.product_id").
+		Group("products.id, products.name").
+		Order("revenue DESC, products.id").
+		Limit(limit).
+		Scan(&products).Error; err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Revenue = roundMoney(products[i].Revenue)
+	}
+	return products, nil
+}
+
+// TopCategories returns the best selling categories in r by revenue, at most
+// limit of them
+func (s *Service) TopCategories(r Range, limit int) ([]CategorySales, error) {
+	categories := []CategorySales{}
+	if err := s.soldLines(r).
+		Select(`categories.id AS category_id, categories.name AS name, COALESCE(SUM(order_items.total_amount), 0) AS revenue,
+			COALESCE(SUM(order_items.quantity), 0) AS units, COUNT(DISTINCT orders.id) AS orders`).
+		Joins("JOIN product_variants ON product_variants.id = order_items.product_variant_id").
+		Joins("JOIN product_categories ON product_categories.product_id = product_variants.product_id").
+		Joins("JOIN categories ON categories.id = product_categories.category_id AND categories.deleted_at IS NULL").
+		Group("categories.id, categories.name").
+		Order("revenue DESC, categories.id").
+		Limit(limit).
+		Scan(&categories).Error; err != nil {
+		return nil, err
+	}
+	for i := range categories {
+		categories[i].Revenue = roundMoney(categories[i].Revenue)
+	}
+	return categories, nil
+}
+
+// Cohorts groups the customers whose first order was placed in r by the
+// month of that order. A customer is a repeat customer once they have placed
+// a second order, whenever it was; Orders and Revenue cover all of the
+// cohort's orders to date.
+func (s *Service) Cohorts(r Range) ([]Cohort, error) {
+	cohort := s.periodExpr(Month, "MIN(orders.order_date)")
+	customers := s.db.Table("orders").
+		Select(cohort+" AS cohort, COUNT(*) AS orders, COALESCE(SUM(orders.final_amount), 0) AS revenue").
+		Where("orders.deleted_at IS NULL AND orders.status NOT IN ?", excludedStatuses).
+		Group("orders.user_id").
+		Having("MIN(orders.order_date) >= ? AND MIN(orders.order_date) < ?", r.From, r.To)
+
+	cohorts := []Cohort{}
+	if err := s.db.Table("(?) AS customers", customers).
+		Select(`cohort, COUNT(*) AS customers, SUM(CASE WHEN orders > 1 THEN 1 ELSE 0 END) AS repeat_customers,
+			SUM(orders) AS orders, SUM(revenue) AS revenue`).
+		Group("cohort").
+		Order("cohort").
+		Scan(&cohorts).Error; err != nil {
+		return nil, err
+	}
+	for i := range cohorts {
+		cohorts[i].Revenue = roundMoney(cohorts[i].Revenue)
+		if cohorts[i].Customers > 0 {
+			cohorts[i].RepeatRate = math.Round(float64(cohorts[i].RepeatCustomers)/float64(cohorts[i].Customers)*1000) / 10
+		}
+	}
+	return cohorts, nil
+}
+
+// periodExpr is the SQL naming the period of column by its first day
+// (YYYY-MM-DD)
+func (s *Service) periodExpr(interval Interval, column string) string {
+	if s.db.Dialector.Name() == "postgres" {
+		return fmt.Sprintf("to_char(date_trunc('%s', %s), 'YYYY-MM-DD')", interval, column)
+	}
+	switch interval {
+	case Week:
+		return fmt.Sprintf("date(%s, 'weekday 0', '-6 days')", column)
+	case Month:
+		return fmt.Sprintf("strftime('%%Y-%%m-01', %s)", column)
+	}
+	return fmt.Sprintf("date(%s)", column)
+}
+
+// periodStart returns the first day of the period t is in
+func periodStart(t time.Time, interval Interval) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case Week:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// nextPeriod returns the first day of the period after the one starting on start
+func nextPeriod(start time.Time, interval Interval) time.Time {
+	switch interval {
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
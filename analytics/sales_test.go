@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func day(value string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04", value)
+	return t
+}
+
+func TestSalesReports(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Address{}, &models.Category{}, &models.Product{}, &models.ProductVariant{},
+		&models.Order{}, &models.OrderItem{}))
+
+	spices := models.Category{Name: "Spices", Slug: "spices"}
+	sweets := models.Category{Name: "Sweets", Slug: "sweets"}
+	require.NoError(t, db.Create(&[]*models.Category{&spices, &sweets}).Error)
+	harissa := models.Product{Name: "Harissa", Categories: []*models.Category{&spices}}
+	dates := models.Product{Name: "Deglet Nour dates", Categories: []*models.Category{&sweets}}
+	require.NoError(t, db.Create(&[]*models.Product{&harissa, &dates}).Error)
+	harissaJar := models.ProductVariant{ProductID: harissa.ID, Name: "250g", SKU: "HAR-250"}
+	datesBox := models.ProductVariant{ProductID: dates.ID, Name: "1kg", SKU: "DAT-1000"}
+	require.NoError(t, db.Create(&[]*models.ProductVariant{&harissaJar, &datesBox}).Error)
+
+	amina := models.User{Email: "amina@example.com", Password: "x", UserType: models.Customer}
+	karim := models.User{Email: "karim@example.com", Password: "x", UserType: models.Customer}
+	require.NoError(t, db.Create(&[]*models.User{&amina, &karim}).Error)
+
+	order := func(number string, user models.User, status models.OrderStatus, at string, lines ...models.OrderItem) {
+		o := models.Order{OrderNumber: number, UserID: user.ID, Status: status, OrderDate: day(at)}
+		for _, line := range lines {
+			o.FinalAmount += line.TotalAmount
+		}
+		o.Items = lines
+		require.NoError(t, db.Create(&o).Error)
+	}
+	line := func(variant models.ProductVariant, quantity int, total float64) models.OrderItem {
+		return models.OrderItem{ProductVariantID: variant.ID, Quantity: quantity, UnitPrice: total / float64(quantity), TotalAmount: total}
+	}
+	// Monday 5 and Wednesday 7 October, then the next Monday and November
+	order("ORD-1", amina, models.OrderStatusDelivered, "2026-10-05 10:00", line(harissaJar, 2, 10), line(datesBox, 1, 15))
+	order("ORD-2", karim, models.OrderStatusPending, "2026-10-07 18:30", line(harissaJar, 1, 5))
+	order("ORD-3", amina, models.OrderStatusShipped, "2026-10-12 09:00", line(datesBox, 2, 30))
+	order("ORD-4", karim, models.OrderStatusCancelled, "2026-10-12 12:00", line(datesBox, 10, 150))
+	order("ORD-5", karim, models.OrderStatusPending, "2026-11-02 08:00", line(harissaJar, 1, 5))
+
+	sales := NewService(db)
+	october := Range{From: day("2026-10-05 00:00"), To: day("2026-10-13 00:00")}
+
+	daily, err := sales.Sales(october, Day)
+	require.NoError(t, err)
+	assert.Equal(t, Totals{Revenue: 60, Orders: 3, AverageOrderValue: 20, Units: 6}, daily.Totals, "cancelled orders are left out")
+	require.Len(t, daily.Series, 8, "days without sales are included")
+	assert.Equal(t, "2026-10-05", daily.Series[0].Period)
+	assert.Equal(t, Totals{Revenue: 25, Orders: 1, AverageOrderValue: 25, Units: 3}, daily.Series[0].Totals)
+	assert.Zero(t, daily.Series[1].Orders)
+	assert.EqualValues(t, 1, daily.Series[2].Orders)
+
+	weekly, err := sales.Sales(october, Week)
+	require.NoError(t, err)
+	require.Len(t, weekly.Series, 2)
+	assert.Equal(t, "2026-10-05", weekly.Series[0].Period)
+	assert.Equal(t, Totals{Revenue: 30, Orders: 2, AverageOrderValue: 15, Units: 4}, weekly.Series[0].Totals)
+	assert.Equal(t, "2026-10-12", weekly.Series[1].Period)
+	assert.EqualValues(t, 30, weekly.Series[1].Revenue)
+
+	monthly, err := sales.Sales(Range{From: day("2026-10-01 00:00"), To: day("2026-12-01 00:00")}, Month)
+	require.NoError(t, err)
+	require.Len(t, monthly.Series, 2)
+	assert.Equal(t, "2026-11-01", monthly.Series[1].Period)
+	assert.EqualValues(t, 5, monthly.Series[1].Revenue)
+
+	products, err := sales.TopProducts(october, 10)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, ProductSales{ProductID: dates.ID, Name: "Deglet Nour dates", Revenue: 45, Units: 3, Orders: 2}, products[0])
+	assert.Equal(t, ProductSales{ProductID: harissa.ID, Name: "Harissa", Revenue: 15, Units: 3, Orders: 2}, products[1])
+	top, err := sales.TopProducts(october, 1)
+	require.NoError(t, err)
+	assert.Len(t, top, 1)
+
+	categories, err := sales.TopCategories(october, 10)
+	require.NoError(t, err)
+	require.Len(t, categories, 2)
+	assert.Equal(t, "Sweets", categories[0].Name)
+	assert.EqualValues(t, 45, categories[0].Revenue)
+
+	cohorts, err := sales.Cohorts(Range{From: day("2026-10-01 00:00"), To: day("2026-11-01 00:00")})
+	require.NoError(t, err)
+	require.Len(t, cohorts, 1)
+	assert.Equal(t, Cohort{Cohort: "2026-10-01", Customers: 2, RepeatCustomers: 2, RepeatRate: 100, Orders: 4, Revenue: 65}, cohorts[0])
+	cohorts, err = sales.Cohorts(Range{From: day("2026-11-01 00:00"), To: day("2026-12-01 00:00")})
+	require.NoError(t, err)
+	assert.Empty(t, cohorts, "customers belong to the month of their first order")
+}
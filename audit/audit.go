@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// ignoredFields are bookkeeping columns left out of change sets
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+	"created_at": true,
+	"UpdatedAt":  true,
+	"CreatedAt":  true,
+}
+
+// ToJSON converts a value to a JSON object map. Non-object values return nil.
+func ToJSON(v interface{}) models.JSON {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return models.JSON(m)
+}
+
+// Snapshot loads an entity by primary key and returns its JSON form, or nil
+// when it does not exist
+func Snapshot(db *gorm.DB, model interface{}, id string) models.JSON {
+	if model == nil || id == "" {
+		return nil
+	}
+	if err := db.First(model, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ToJSON(model)
+}
+
+// Diff returns the top-level fields that differ between two snapshots
+func Diff(before, after models.JSON) models.JSON {
+	changes := models.JSON{}
+	for key, from := range before {
+		if ignoredFields[key] {
+			continue
+		}
+		to, ok := after[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = map[string]interface{}{"from": from, "to": to}
+		}
+	}
+	for key, to := range after {
+		if ignoredFields[key] {
+			continue
+		}
+		if _, ok := before[key]; !ok {
+			changes[key] = map[string]interface{}{"from": nil, "to": to}
+		}
+	}
+	return changes
+}
+
+// Record writes an audit entry, computing the change set from Before and After
+func Record(db *gorm.DB, entry *models.AuditLog) error {
+	if entry.Changes == nil && (entry.Before != nil || entry.After != nil) {
+		entry.Changes = Diff(entry.Before, entry.After)
+	}
+	return db.Create(entry).Error
+}
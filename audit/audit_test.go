@@ -0,0 +1,22 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := models.JSON{"name": "Olive Oil", "price": 10.0, "updated_at": "a", "removed": true}
+	after := models.JSON{"name": "Olive Oil", "price": 12.0, "updated_at": "b", "added": "x"}
+
+	changes := Diff(before, after)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, map[string]interface{}{"from": 10.0, "to": 12.0}, changes["price"])
+	assert.Equal(t, map[string]interface{}{"from": true, "to": nil}, changes["removed"])
+	assert.Equal(t, map[string]interface{}{"from": nil, "to": "x"}, changes["added"])
+
+	// A created entity lists every field as added
+	assert.Len(t, Diff(nil, models.JSON{"name": "New"}), 1)
+}
@@ -24,17 +24,23 @@ func NewAppwriteService(client client.Client) *AppwriteService {
 }
 
 func (s *AppwriteService) UploadFile(fileHeader *multipart.FileHeader) (string, error) {
-	cfg, err := cfg.LoadConfig()
-	if err != nil {
-		return "", err
-	}
-	bucketId := cfg.AppwriteBucketId
 	// Open the uploaded file
 	src, err := fileHeader.Open()
 	if err != nil {
 		return "", err
 	}
 	defer src.Close()
+	return s.Upload(fileHeader.Filename, src)
+}
+
+// Upload stores content as a publicly readable file named fileName and
+// returns its file ID
+func (s *AppwriteService) Upload(fileName string, content io.Reader) (string, error) {
+	cfg, err := cfg.LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	bucketId := cfg.AppwriteBucketId
 
 	// Create a temporary file
 	tmpFile, err := os.CreateTemp("", "upload-*")
@@ -46,13 +52,13 @@ func (s *AppwriteService) UploadFile(fileHeader *multipart.FileHeader) (string,
 		os.Remove(tmpFile.Name())
 	}()
 
-	// Copy the uploaded file to the temp file
-	if _, err := io.Copy(tmpFile, src); err != nil {
+	// Copy the content to the temp file
+	if _, err := io.Copy(tmpFile, content); err != nil {
 		return "", err
 	}
 
 	// Create InputFile for Appwrite
-	inputFile := file.NewInputFile(tmpFile.Name(), fileHeader.Filename)
+	inputFile := file.NewInputFile(tmpFile.Name(), fileName)
 
 	// Create file in Appwrite storage
 	result, err := s.Storage.CreateFile(
@@ -69,6 +75,16 @@ func (s *AppwriteService) UploadFile(fileHeader *multipart.FileHeader) (string,
 	return result.Id, nil
 }
 
+// DeleteFile removes a file from Appwrite storage.
+func (s *AppwriteService) DeleteFile(fileId string) error {
+	cfg, err := cfg.LoadConfig()
+	if err != nil {
+		return err
+	}
+	_, err = s.Storage.DeleteFile(cfg.AppwriteBucketId, fileId)
+	return err
+}
+
 // GetFileURL constructs the public view URL for a file in Appwrite storage.
 func (s *AppwriteService) GetFileURL(fileId string) string {
 	return "/file/preview/" + fileId
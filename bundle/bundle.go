@@ -0,0 +1,251 @@
+// Package bundle manages bundles and kits: variants sold as a set of other
+// variants. A bundle has no stock of its own. It is available as many times
+// as its components' stock makes it up, and an order for it is allocated, and
+// shipped, from its components' stock (see the stock and fulfillment
+// packages). Its prices are the sum of its components' unless overridden.
+package bundle
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotFound          = errors.New("product variant not found")
+	ErrNoComponents      = errors.New("a bundle needs at least one component")
+	ErrInvalidQuantity   = errors.New("component quantities must be at least 1")
+	ErrInvalidPrice      = errors.New("bundle price must not be negative")
+	ErrComponentNotFound = errors.New("component variant not found or not active")
+	ErrContainsItself    = errors.New("a bundle cannot be a component of itself")
+	ErrNestedBundle      = errors.New("a bundle cannot be a component of another bundle")
+	ErrNotBundle         = errors.New("product variant is not a bundle")
+)
+
+// ComponentInput is a variant, and how many of it, one bundle is made of
+type ComponentInput struct {
+	VariantID uint
+	Quantity  int
+}
+
+// Input sets a bundle's components. A nil Price prices the bundle from its
+// components.
+type Input struct {
+	Components []ComponentInput
+	Price      *float64
+}
+
+// Prices are the prices of a bundle made up from its components'
+type Prices struct {
+	Base float64 `json:"base_price"`
+	B2B  float64 `json:"b2b_price"`
+	Cost float64 `json:"cost_price"`
+}
+
+// Service manages bundles
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a bundle service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Get returns a bundle with its components
+func (s *Service) Get(ctx context.Context, variantID uint) (*models.ProductVariant, error) {
+	variant, err := load(s.db.WithContext(ctx), variantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(variant.BundleComponents) == 0 {
+		return nil, ErrNotBundle
+	}
+	return variant, nil
+}
+
+// Set makes a variant a bundle of the given components, replacing those it
+// had, and updates its prices and stock total
+func (s *Service) Set(ctx context.Context, variantID uint, in Input) (*models.ProductVariant, error) {
+	if len(in.Components) == 0 {
+		return nil, ErrNoComponents
+	}
+	if in.Price != nil && *in.Price < 0 {
+		return nil, ErrInvalidPrice
+	}
+	// Repeated components are merged
+	quantities := make(map[uint]int, len(in.Components))
+	var componentIDs []uint
+	for _, component := range in.Components {
+		if component.Quantity < 1 {
+			return nil, ErrInvalidQuantity
+		}
+		if component.VariantID == variantID {
+			return nil, ErrContainsItself
+		}
+		if _, ok := quantities[component.VariantID]; !ok {
+			componentIDs = append(componentIDs, component.VariantID)
+		}
+		quantities[component.VariantID] += component.Quantity
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var variant models.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&variant, variantID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var isComponent int64
+		if err := tx.Model(&models.BundleComponent{}).Where("component_variant_id = ?", variantID).Count(&isComponent).Error; err != nil {
+			return err
+		}
+		if isComponent > 0 {
+			return ErrNestedBundle
+		}
+
+		var found int64
+		if err := tx.Model(&models.ProductVariant{}).Where("id IN ? AND is_active = ?", componentIDs, true).Count(&found).Error; err != nil {
+			return err
+		}
+		if int(found) != len(componentIDs) {
+			return ErrComponentNotFound
+		}
+		var bundled int64
+		if err := tx.Model(&models.BundleComponent{}).Where("bundle_variant_id IN ?", componentIDs).Count(&bundled).Error; err != nil {
+			return err
+		}
+		if bundled > 0 {
+			return ErrNestedBundle
+		}
+
+		if err := tx.Unscoped().Where("bundle_variant_id = ?", variantID).Delete(&models.BundleComponent{}).Error; err != nil {
+			return err
+		}
+		components := make([]models.BundleComponent, 0, len(componentIDs))
+		for _, id := range componentIDs {
+			components = append(components, models.BundleComponent{BundleVariantID: variantID, ComponentVariantID: id, Quantity: quantities[id]})
+		}
+		if err := tx.Omit("ComponentVariant").Create(&components).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&variant).Update("bundle_price", in.Price).Error; err != nil {
+			return err
+		}
+		if err := SyncPrices(tx, variantID); err != nil {
+			return err
+		}
+		return stock.SyncBundleStock(tx, variantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return load(s.db.WithContext(ctx), variantID)
+}
+
+// Remove turns a bundle back into a plain variant. It keeps its last prices,
+// and its stock total is that of its own batches again.
+func (s *Service) Remove(ctx context.Context, variantID uint) (*models.ProductVariant, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var variant models.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&variant, variantID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		deleted := tx.Unscoped().Where("bundle_variant_id = ?", variantID).Delete(&models.BundleComponent{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		if deleted.RowsAffected == 0 {
+			return ErrNotBundle
+		}
+		if err := tx.Model(&variant).Update("bundle_price", nil).Error; err != nil {
+			return err
+		}
+		return stock.SyncVariantStock(tx, variantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return load(s.db.WithContext(ctx), variantID)
+}
+
+// Price adds up the prices of components. The B2B price of a component
+// without one is its base price.
+func Price(components []models.BundleComponent) Prices {
+	var prices Prices
+	for _, component := range components {
+		variant := component.ComponentVariant
+		quantity := float64(component.Quantity)
+		b2b := variant.B2BPrice
+		if b2b <= 0 {
+			b2b = variant.BasePrice
+		}
+		prices.Base += variant.BasePrice * quantity
+		prices.B2B += b2b * quantity
+		prices.Cost += variant.CostPrice * quantity
+	}
+	prices.Base, prices.B2B, prices.Cost = round(prices.Base), round(prices.B2B), round(prices.Cost)
+	return prices
+}
+
+// SyncPrices sets the prices of the bundles among variantIDs, and of the
+// bundles any of them is a component of, from their components' prices. A
+// bundle with a price override is sold at it, to B2B customers too; its cost
+// is always its components'.
+func SyncPrices(tx *gorm.DB, variantIDs ...uint) error {
+	if len(variantIDs) == 0 {
+		return nil
+	}
+	var bundleIDs []uint
+	if err := tx.Model(&models.BundleComponent{}).
+		Where("component_variant_id IN ? OR bundle_variant_id IN ?", variantIDs, variantIDs).
+		Distinct().Pluck("bundle_variant_id", &bundleIDs).Error; err != nil {
+		return err
+	}
+	if len(bundleIDs) == 0 {
+		return nil
+	}
+	var bundles []models.ProductVariant
+	if err := tx.Select("id", "bundle_price").Preload("BundleComponents.ComponentVariant").
+		Where("id IN ?", bundleIDs).Find(&bundles).Error; err != nil {
+		return err
+	}
+	for _, bundle := range bundles {
+		prices := Price(bundle.BundleComponents)
+		if bundle.BundlePrice != nil {
+			prices.Base, prices.B2B = *bundle.BundlePrice, 0
+		}
+		if err := tx.Model(&models.ProductVariant{Model: gorm.Model{ID: bundle.ID}}).Updates(map[string]interface{}{
+			"base_price": prices.Base,
+			"b2_b_price": prices.B2B,
+			"cost_price": prices.Cost,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func load(db *gorm.DB, variantID uint) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	if err := db.Preload("BundleComponents.ComponentVariant").First(&variant, variantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
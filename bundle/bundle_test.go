@@ -0,0 +1,126 @@
+package bundle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	db    *gorm.DB
+	s     *Service
+	oil   models.ProductVariant
+	salt  models.ProductVariant
+	kit   models.ProductVariant
+	other models.ProductVariant
+}
+
+// setup creates a 10.00 oil with 8.00 B2B price, a 2.50 salt without one and
+// two variants to make kits of, with 9 oils and 5 salts in stock
+func setup(t *testing.T) *fixture {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Address{}, &models.Warehouse{}, &models.Product{}, &models.ProductVariant{},
+		&models.BundleComponent{}, &models.InventoryItem{}))
+
+	f := &fixture{db: db, s: NewService(db)}
+	product := models.Product{Name: "Pantry", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	variants := []*models.ProductVariant{&f.oil, &f.salt, &f.kit, &f.other}
+	for i, variant := range []models.ProductVariant{
+		{Name: "Oil", SKU: "OIL", BasePrice: 10, B2BPrice: 8, CostPrice: 6},
+		{Name: "Salt", SKU: "SALT", BasePrice: 2.5, CostPrice: 1},
+		{Name: "Kit", SKU: "KIT", BasePrice: 99},
+		{Name: "Other Kit", SKU: "KIT-2"},
+	} {
+		variant.ProductID, variant.IsActive = product.ID, true
+		require.NoError(t, db.Omit("Product").Create(&variant).Error)
+		*variants[i] = variant
+	}
+	warehouse := models.Warehouse{Name: "Leeds Warehouse", Code: "WH-LDS", IsActive: true}
+	require.NoError(t, db.Create(&warehouse).Error)
+	for _, stock := range []models.InventoryItem{
+		{ProductVariantID: f.oil.ID, WarehouseID: warehouse.ID, Quantity: 9, Status: "active"},
+		{ProductVariantID: f.salt.ID, WarehouseID: warehouse.ID, Quantity: 5, Status: "active"},
+	} {
+		require.NoError(t, db.Omit("ProductVariant", "Warehouse").Create(&stock).Error)
+	}
+	require.NoError(t, db.Model(&f.oil).Update("quantity_in_stock", 9).Error)
+	require.NoError(t, db.Model(&f.salt).Update("quantity_in_stock", 5).Error)
+	return f
+}
+
+func (f *fixture) variant(t *testing.T, id uint) models.ProductVariant {
+	var variant models.ProductVariant
+	require.NoError(t, f.db.First(&variant, id).Error)
+	return variant
+}
+
+func TestSetPricesBundleFromComponents(t *testing.T) {
+	f := setup(t)
+	ctx := context.Background()
+
+	// Repeated components are merged
+	kit, err := f.s.Set(ctx, f.kit.ID, Input{Components: []ComponentInput{
+		{VariantID: f.oil.ID, Quantity: 1}, {VariantID: f.salt.ID, Quantity: 2}, {VariantID: f.oil.ID, Quantity: 1},
+	}})
+	require.NoError(t, err)
+	require.Len(t, kit.BundleComponents, 2)
+	assert.Equal(t, 2, kit.BundleComponents[0].Quantity)
+	assert.Equal(t, 25.0, kit.BasePrice)
+	assert.Equal(t, 21.0, kit.B2BPrice, "salt has no B2B price so its base price is used")
+	assert.Equal(t, 14.0, kit.CostPrice)
+	assert.Equal(t, 2, kit.QuantityInStock, "5 salts make 2 kits")
+
+	// Component price changes reprice the kit
+	require.NoError(t, f.db.Model(&f.salt).Update("base_price", 3).Error)
+	require.NoError(t, SyncPrices(f.db, f.salt.ID))
+	assert.Equal(t, 26.0, f.variant(t, f.kit.ID).BasePrice)
+
+	price := 22.0
+	kit, err = f.s.Set(ctx, f.kit.ID, Input{Components: []ComponentInput{{VariantID: f.oil.ID, Quantity: 2}, {VariantID: f.salt.ID, Quantity: 2}}, Price: &price})
+	require.NoError(t, err)
+	assert.Equal(t, 22.0, kit.BasePrice)
+	assert.Zero(t, kit.B2BPrice, "B2B customers pay the override too")
+	assert.Equal(t, Prices{Base: 26, B2B: 22, Cost: 14}, Price(kit.BundleComponents))
+
+	removed, err := f.s.Remove(ctx, f.kit.ID)
+	require.NoError(t, err)
+	assert.Empty(t, removed.BundleComponents)
+	assert.Nil(t, removed.BundlePrice)
+	assert.Zero(t, removed.QuantityInStock, "the variant has no stock of its own")
+	_, err = f.s.Remove(ctx, f.kit.ID)
+	assert.ErrorIs(t, err, ErrNotBundle)
+}
+
+func TestSetValidatesComponents(t *testing.T) {
+	f := setup(t)
+	ctx := context.Background()
+	_, err := f.s.Set(ctx, f.kit.ID, Input{Components: []ComponentInput{{VariantID: f.oil.ID, Quantity: 1}}})
+	require.NoError(t, err)
+
+	cases := []struct {
+		variantID uint
+		in        Input
+		err       error
+	}{
+		{f.other.ID, Input{}, ErrNoComponents},
+		{f.other.ID, Input{Components: []ComponentInput{{VariantID: f.oil.ID}}}, ErrInvalidQuantity},
+		{f.other.ID, Input{Components: []ComponentInput{{VariantID: f.other.ID, Quantity: 1}}}, ErrContainsItself},
+		{f.other.ID, Input{Components: []ComponentInput{{VariantID: f.oil.ID + 100, Quantity: 1}}}, ErrComponentNotFound},
+		{f.other.ID, Input{Components: []ComponentInput{{VariantID: f.kit.ID, Quantity: 1}}}, ErrNestedBundle},
+		{f.oil.ID, Input{Components: []ComponentInput{{VariantID: f.salt.ID, Quantity: 1}}}, ErrNestedBundle},
+		{f.other.ID + 100, Input{Components: []ComponentInput{{VariantID: f.salt.ID, Quantity: 1}}}, ErrNotFound},
+	}
+	for _, c := range cases {
+		_, err := f.s.Set(ctx, c.variantID, c.in)
+		assert.ErrorIs(t, err, c.err)
+	}
+	_, err = f.s.Get(ctx, f.other.ID)
+	assert.ErrorIs(t, err, ErrNotBundle)
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+)
+
+// Analytics caches sales reports. They aggregate every order in their range,
+// so they are expensive to build, and a few minutes of staleness is fine for
+// a dashboard. Entries are not invalidated on writes and expire with their
+// TTL. A nil *Analytics is valid and disabled.
+type Analytics struct {
+	store Store
+	ttl   time.Duration
+}
+
+// NewAnalytics creates the sales report cache. It returns nil, a disabled
+// cache, when store is nil or the TTL is not positive.
+func NewAnalytics(store Store, config *cfg.CacheConfig) *Analytics {
+	if store == nil || config.AnalyticsTTLSeconds <= 0 {
+		return nil
+	}
+	return &Analytics{store: store, ttl: time.Duration(config.AnalyticsTTLSeconds) * time.Second}
+}
+
+// Load fills dest from the cache entry for key, or on a miss calls load to
+// fill dest and caches the result
+func (a *Analytics) Load(ctx context.Context, key string, dest interface{}, load func() error) error {
+	if a == nil {
+		return load()
+	}
+	return loadEntry(ctx, a.store, a.ttl, "analytics", "analytics:"+key, dest, load)
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+)
+
+// Availability caches stock availability reads for a few seconds. Stock
+// changes with every order, so entries are not invalidated on writes, which
+// would invalidate the catalog as often, and are instead only ever as stale as
+// their TTL. A nil *Availability is valid and disabled.
+type Availability struct {
+	store Store
+	ttl   time.Duration
+}
+
+// NewAvailability creates the availability cache. It returns nil, a disabled
+// cache, when store is nil or the TTL is not positive.
+func NewAvailability(store Store, config *cfg.CacheConfig) *Availability {
+	if store == nil || config.AvailabilityTTLSeconds <= 0 {
+		return nil
+	}
+	return &Availability{store: store, ttl: time.Duration(config.AvailabilityTTLSeconds) * time.Second}
+}
+
+// Load fills dest from the cache entry for key, or on a miss calls load to
+// fill dest and caches the result
+func (a *Availability) Load(ctx context.Context, key string, dest interface{}, load func() error) error {
+	if a == nil {
+		return load()
+	}
+	return loadEntry(ctx, a.store, a.ttl, "availability", "availability:"+key, dest, load)
+}
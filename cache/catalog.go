@@ -0,0 +1,147 @@
+// Package cache keeps read-heavy query results in Redis so they are shared
+// across instances. Entries are keyed under a version number that is bumped
+// on every write to the underlying tables, which invalidates them all at once.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is the key-value storage used by the cache
+type Store interface {
+	// Get returns the value of key, with ok false when the key is missing
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// RedisStore keeps entries in Redis
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a Redis backed store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisStore) Incr(ctx context.Context, key string) (int64, error) {
+	return s.client.Incr(ctx, key).Result()
+}
+
+const catalogVersionKey = "catalog:version"
+
+// Catalog caches product catalog reads. A nil *Catalog is valid and disabled:
+// every Load calls through to its loader.
+type Catalog struct {
+	store Store
+	ttl   time.Duration
+}
+
+// NewCatalog creates the catalog cache. It returns nil, a disabled cache, when
+// store is nil or the cache is turned off in config.
+func NewCatalog(store Store, config *cfg.CacheConfig) *Catalog {
+	if store == nil || !config.CatalogEnabled {
+		return nil
+	}
+	ttl := time.Duration(config.CatalogTTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &Catalog{store: store, ttl: ttl}
+}
+
+// Load fills dest from the cache entry for kind and key, or on a miss calls
+// load to fill dest from the database and caches the result. dest must
+// survive a JSON round trip. Loader errors are returned and not cached; cache
+// errors are logged and fall back to the loader.
+func (c *Catalog) Load(ctx context.Context, kind, key string, dest interface{}, load func() error) error {
+	if c == nil {
+		return load()
+	}
+
+	version, err := c.version(ctx)
+	if err != nil {
+		c.fail(ctx, kind, "read cache version", err)
+		return load()
+	}
+	entryKey := fmt.Sprintf("catalog:v%d:%s:%s", version, kind, key)
+	return loadEntry(ctx, c.store, c.ttl, "catalog_"+kind, entryKey, dest, load)
+}
+
+// loadEntry fills dest from the entry at key, or on a miss calls load and
+// caches what it filled dest with for ttl. Metrics are labelled with kind.
+func loadEntry(ctx context.Context, store Store, ttl time.Duration, kind, key string, dest interface{}, load func() error) error {
+	value, ok, err := store.Get(ctx, key)
+	if err != nil {
+		metrics.CacheRequests.WithLabelValues(kind, "error").Inc()
+		slog.WarnContext(ctx, "failed to read cache entry", "component", "cache", "kind", kind, "error", err)
+		return load()
+	}
+	if ok {
+		if err := json.Unmarshal(value, dest); err == nil {
+			metrics.CacheRequests.WithLabelValues(kind, "hit").Inc()
+			return nil
+		}
+	}
+
+	metrics.CacheRequests.WithLabelValues(kind, "miss").Inc()
+	if err := load(); err != nil {
+		return err
+	}
+	value, err = json.Marshal(dest)
+	if err != nil {
+		return nil
+	}
+	if err := store.Set(ctx, key, value, ttl); err != nil {
+		slog.WarnContext(ctx, "failed to write cache entry", "component", "cache", "kind", kind, "error", err)
+	}
+	return nil
+}
+
+// Invalidate makes every cached catalog entry stale by bumping the version
+// their keys are built from. Stale entries expire with their TTL.
+func (c *Catalog) Invalidate(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	_, err := c.store.Incr(ctx, catalogVersionKey)
+	return err
+}
+
+func (c *Catalog) version(ctx context.Context) (int64, error) {
+	value, ok, err := c.store.Get(ctx, catalogVersionKey)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+func (c *Catalog) fail(ctx context.Context, kind, action string, err error) {
+	metrics.CacheRequests.WithLabelValues("catalog_"+kind, "error").Inc()
+	slog.WarnContext(ctx, "failed to "+action, "component", "cache", "kind", kind, "error", err)
+}
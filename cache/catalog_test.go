@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type memoryStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memoryStore) Incr(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, _ := strconv.ParseInt(string(s.values[key]), 10, 64)
+	n++
+	s.values[key] = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+type item struct {
+	Name string `json:"name"`
+}
+
+func TestCatalogLoad(t *testing.T) {
+	ctx := context.Background()
+	catalog := NewCatalog(newMemoryStore(), &cfg.CacheConfig{CatalogEnabled: true})
+	loads := 0
+	load := func(dest *item) func() error {
+		return func() error {
+			loads++
+			dest.Name = "widget " + strconv.Itoa(loads)
+			return nil
+		}
+	}
+
+	var first, second item
+	require.NoError(t, catalog.Load(ctx, "product", "1", &first, load(&first)))
+	require.NoError(t, catalog.Load(ctx, "product", "1", &second, load(&second)))
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, "widget 1", second.Name)
+
+	require.NoError(t, catalog.Invalidate(ctx))
+	var third item
+	require.NoError(t, catalog.Load(ctx, "product", "1", &third, load(&third)))
+	assert.Equal(t, "widget 2", third.Name)
+
+	// Loader errors are returned and not cached
+	failed := errors.New("not found")
+	var missing item
+	assert.ErrorIs(t, catalog.Load(ctx, "product", "2", &missing, func() error { return failed }), failed)
+	require.NoError(t, catalog.Load(ctx, "product", "2", &missing, load(&missing)))
+	assert.Equal(t, 3, loads)
+}
+
+func TestDisabledCatalogCallsLoader(t *testing.T) {
+	catalog := NewCatalog(newMemoryStore(), &cfg.CacheConfig{CatalogEnabled: false})
+	require.Nil(t, catalog)
+
+	loads := 0
+	var dest item
+	for i := 0; i < 2; i++ {
+		require.NoError(t, catalog.Load(context.Background(), "product", "1", &dest, func() error {
+			loads++
+			return nil
+		}))
+	}
+	assert.Equal(t, 2, loads)
+	assert.NoError(t, catalog.Invalidate(context.Background()))
+}
+
+type Product struct {
+	ID   uint
+	Name string
+}
+
+type Note struct {
+	ID   uint
+	Body string
+}
+
+func TestInvalidationPlugin(t *testing.T) {
+	store := newMemoryStore()
+	catalog := NewCatalog(store, &cfg.CacheConfig{CatalogEnabled: true})
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewInvalidationPlugin(catalog)))
+	require.NoError(t, db.AutoMigrate(&Product{}, &Note{}))
+
+	version := func() int64 {
+		v, err := catalog.version(context.Background())
+		require.NoError(t, err)
+		return v
+	}
+
+	require.NoError(t, db.Create(&Note{Body: "unrelated"}).Error)
+	assert.Equal(t, int64(0), version())
+
+	product := Product{Name: "a"}
+	require.NoError(t, db.Create(&product).Error)
+	assert.Equal(t, int64(1), version())
+
+	require.NoError(t, db.Model(&product).Update("name", "b").Error)
+	assert.Equal(t, int64(2), version())
+
+	// Writes that change nothing leave the cache alone
+	require.NoError(t, db.Where("id = ?", 999).Delete(&Product{}).Error)
+	assert.Equal(t, int64(2), version())
+
+	require.NoError(t, db.Delete(&product).Error)
+	assert.Equal(t, int64(3), version())
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// catalogTables are the tables product listing, product detail and home page
+// reads are built from
+var catalogTables = map[string]bool{
+	"products":                    true,
+	"product_variants":            true,
+	"product_variant_price_tiers": true,
+	"bundle_components":           true,
+	"product_options":             true,
+	"product_option_values":       true,
+	"variant_option_values":       true,
+	"product_images":              true,
+	"product_specifications":      true,
+	"product_categories":          true,
+	"product_tags":                true,
+	"categories":                  true,
+	"tags":                        true,
+	"brands":                      true,
+	"inventory_items":             true,
+	"warehouses":                  true,
+	"banners":                     true,
+	"collections":                 true,
+	"collection_products":         true,
+}
+
+// reinvalidateAfter is how long after a write made inside a transaction the
+// catalog is invalidated a second time, since a read between the first
+// invalidation and the commit can cache the old rows again
+const reinvalidateAfter = 2 * time.Second
+
+// InvalidationPlugin invalidates the catalog cache after every create, update
+// or delete on a catalog table, so handlers do not have to remember to
+type InvalidationPlugin struct {
+	catalog *Catalog
+}
+
+// NewInvalidationPlugin creates the plugin; register it with db.Use
+func NewInvalidationPlugin(catalog *Catalog) *InvalidationPlugin {
+	return &InvalidationPlugin{catalog: catalog}
+}
+
+func (p *InvalidationPlugin) Name() string {
+	return "cache:catalog"
+}
+
+func (p *InvalidationPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().After("gorm:create").Register("cache:invalidate_create", p.invalidate),
+		cb.Update().After("gorm:update").Register("cache:invalidate_update", p.invalidate),
+		cb.Delete().After("gorm:delete").Register("cache:invalidate_delete", p.invalidate),
+	)
+}
+
+func (p *InvalidationPlugin) invalidate(db *gorm.DB) {
+	if p.catalog == nil || db.Error != nil || db.Statement.RowsAffected == 0 || !catalogTables[db.Statement.Table] {
+		return
+	}
+	ctx := context.WithoutCancel(db.Statement.Context)
+	if err := p.catalog.Invalidate(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to invalidate catalog cache", "component", "cache", "table", db.Statement.Table, "error", err)
+	}
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		time.AfterFunc(reinvalidateAfter, func() {
+			p.catalog.Invalidate(ctx)
+		})
+	}
+}
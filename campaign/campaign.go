@@ -0,0 +1,373 @@
+// Package campaign sends marketing emails to customer segments in throttled
+// batches and tracks their opens, clicks, unsubscribes and conversions.
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotEditable    = errors.New("only draft and scheduled campaigns can be changed")
+	ErrNotSchedulable = errors.New("only draft campaigns can be scheduled")
+	ErrNotCancelable  = errors.New("campaign has already finished")
+)
+
+// Queuer queues rendered emails for sending
+type Queuer interface {
+	QueueEmail(email *models.Email) error
+}
+
+// Service schedules and sends campaigns
+type Service struct {
+	db       *gorm.DB
+	engine   email.TemplateEngine
+	queue    Queuer
+	config   cfg.CampaignConfig
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewService(db *gorm.DB, engine email.TemplateEngine, queue Queuer, config *cfg.CampaignConfig) *Service {
+	s := &Service{db: db, engine: engine, queue: queue, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	if s.config.BatchSize <= 0 {
+		s.config.BatchSize = 200
+	}
+	if s.config.AttributionDays <= 0 {
+		s.config.AttributionDays = 7
+	}
+	s.interval = time.Duration(s.config.BatchInterval) * time.Second
+	if s.interval <= 0 {
+		s.interval = time.Minute
+	}
+	s.config.TrackingURL = strings.TrimRight(s.config.TrackingURL, "/")
+	return s
+}
+
+// HasTemplate reports whether campaigns can be sent with an email template
+func (s *Service) HasTemplate(name string) bool {
+	return slices.Contains(s.engine.GetTemplateList(), name)
+}
+
+// Schedule queues a draft campaign to start sending at a time, or now when
+// the time is nil
+func (s *Service) Schedule(campaignID uint, at *time.Time) (*models.Campaign, error) {
+	sendAt := s.now()
+	if at != nil && at.After(sendAt) {
+		sendAt = *at
+	}
+	result := s.db.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", campaignID, models.CampaignStatusDraft).
+		Updates(map[string]interface{}{"status": models.CampaignStatusScheduled, "scheduled_at": sendAt})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	var campaign models.Campaign
+	if err := s.db.First(&campaign, campaignID).Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotSchedulable
+	}
+	return &campaign, nil
+}
+
+// Unschedule returns a scheduled campaign that has not started to draft
+func (s *Service) Unschedule(campaignID uint) (*models.Campaign, error) {
+	result := s.db.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", campaignID, models.CampaignStatusScheduled).
+		Updates(map[string]interface{}{"status": models.CampaignStatusDraft, "scheduled_at": nil})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	var campaign models.Campaign
+	if err := s.db.First(&campaign, campaignID).Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotEditable
+	}
+	return &campaign, nil
+}
+
+// Cancel stops a campaign. Emails already queued are still sent; recipients
+// not yet reached are skipped.
+func (s *Service) Cancel(campaignID uint) (*models.Campaign, error) {
+	var campaign models.Campaign
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Campaign{}).
+			Where("id = ? AND status IN ?", campaignID, []models.CampaignStatus{
+				models.CampaignStatusDraft, models.CampaignStatusScheduled, models.CampaignStatusSending,
+			}).
+			Updates(map[string]interface{}{"status": models.CampaignStatusCancelled, "completed_at": s.now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.First(&campaign, campaignID).Error; err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotCancelable
+		}
+		return tx.Model(&models.CampaignRecipient{}).
+			Where("campaign_id = ? AND status = ?", campaignID, models.CampaignRecipientPending).
+			Update("status", models.CampaignRecipientSkipped).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// StartDispatcher sends the next batch of every due campaign each batch
+// interval until ctx is canceled
+func (s *Service) StartDispatcher(ctx context.Context) {
+	for {
+		if count, err := s.Dispatch(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to dispatch campaigns", "component", "campaign", "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "queued campaign emails", "component", "campaign", "count", count)
+		}
+		if !worker.Sleep(ctx, s.interval) {
+			return
+		}
+	}
+}
+
+// Dispatch starts the scheduled campaigns that are due and queues the next
+// batch of emails of each sending campaign, returning the number queued
+func (s *Service) Dispatch(ctx context.Context) (int, error) {
+	var campaigns []models.Campaign
+	if err := s.db.WithContext(ctx).
+		Where("(status = ? AND scheduled_at <= ?) OR status = ?",
+			models.CampaignStatusScheduled, s.now(), models.CampaignStatusSending).
+		Order("scheduled_at, id").Find(&campaigns).Error; err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	var errs []error
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		if campaign.Status == models.CampaignStatusScheduled {
+			started, err := s.start(ctx, campaign)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("campaign %d: %w", campaign.ID, err))
+				continue
+			}
+			if !started {
+				continue // another instance started it
+			}
+		}
+		n, err := s.sendBatch(ctx, campaign)
+		queued += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("campaign %d: %w", campaign.ID, err))
+		}
+	}
+	return queued, errors.Join(errs...)
+}
+
+// start claims a due campaign and records its recipients from the segment as
+// it is now, so customers joining the segment later are not added
+func (s *Service) start(ctx context.Context, campaign *models.Campaign) (bool, error) {
+	started := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := s.now()
+		result := tx.Model(&models.Campaign{}).
+			Where("id = ? AND status = ?", campaign.ID, models.CampaignStatusScheduled).
+			Updates(map[string]interface{}{"status": models.CampaignStatusSending, "started_at": now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		started = true
+
+		var segment models.Segment
+		if err := tx.First(&segment, campaign.SegmentID).Error; err != nil {
+			return err
+		}
+		var users []models.User
+		if err := Audience(tx, &segment.Filter, now).
+			Select("users.id, users.email, users.first_name, users.last_name").
+			Order("users.id").Find(&users).Error; err != nil {
+			return err
+		}
+		recipients := make([]models.CampaignRecipient, 0, len(users))
+		for _, user := range users {
+			recipients = append(recipients, models.CampaignRecipient{
+				CampaignID: campaign.ID,
+				UserID:     user.ID,
+				Email:      user.Email,
+				Name:       strings.TrimSpace(user.FirstName + " " + user.LastName),
+				Status:     models.CampaignRecipientPending,
+			})
+		}
+		if len(recipients) > 0 {
+			if err := tx.CreateInBatches(&recipients, 500).Error; err != nil {
+				return err
+			}
+		}
+		campaign.Status, campaign.StartedAt, campaign.RecipientCount = models.CampaignStatusSending, &now, len(recipients)
+		return tx.Model(campaign).Update("recipient_count", len(recipients)).Error
+	})
+	return started, err
+}
+
+// sendBatch queues the emails of up to a batch of pending recipients, and
+// marks the campaign sent once none are left
+func (s *Service) sendBatch(ctx context.Context, campaign *models.Campaign) (int, error) {
+	var recipients []models.CampaignRecipient
+	if err := s.db.WithContext(ctx).
+		Where("campaign_id = ? AND status = ?", campaign.ID, models.CampaignRecipientPending).
+		Order("id").Limit(s.config.BatchSize).Find(&recipients).Error; err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for i := range recipients {
+		recipient := &recipients[i]
+		// Claim the recipient, so a second instance dispatching at the same
+		// time does not send them the campaign twice
+		claim := s.db.WithContext(ctx).Model(&models.CampaignRecipient{}).
+			Where("id = ? AND status = ?", recipient.ID, models.CampaignRecipientPending).
+			Updates(map[string]interface{}{"status": models.CampaignRecipientQueued, "sent_at": s.now()})
+		if claim.Error != nil {
+			return queued, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		msg, err := s.render(campaign, recipient)
+		if err == nil {
+			err = s.queue.QueueEmail(msg)
+		}
+		updates := map[string]interface{}{}
+		switch {
+		case err == nil:
+			updates["email_id"] = msg.ID
+			queued++
+		case errors.Is(err, email.ErrRecipientSuppressed):
+			updates["status"] = models.CampaignRecipientSuppressed
+			updates["sent_at"] = nil
+			if msg.ID != 0 {
+				updates["email_id"] = msg.ID
+			}
+		default:
+			slog.ErrorContext(ctx, "failed to queue campaign email", "component", "campaign",
+				"campaign_id", campaign.ID, "recipient_id", recipient.ID, "error", err)
+			updates["status"] = models.CampaignRecipientFailed
+			updates["sent_at"] = nil
+			updates["error"] = err.Error()
+		}
+		if err := s.db.WithContext(ctx).Model(recipient).Updates(updates).Error; err != nil {
+			return queued, err
+		}
+	}
+
+	var pending int64
+	if err := s.db.WithContext(ctx).Model(&models.CampaignRecipient{}).
+		Where("campaign_id = ? AND status = ?", campaign.ID, models.CampaignRecipientPending).
+		Count(&pending).Error; err != nil {
+		return queued, err
+	}
+	if pending == 0 {
+		if err := s.db.WithContext(ctx).Model(&models.Campaign{}).
+			Where("id = ? AND status = ?", campaign.ID, models.CampaignStatusSending).
+			Updates(map[string]interface{}{"status": models.CampaignStatusSent, "completed_at": s.now()}).Error; err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
+// hrefPattern finds the web links of a rendered email
+var hrefPattern = regexp.MustCompile(`href="(https?://[^"]+)"`)
+
+// render personalises a campaign email for a recipient: their name and
+// unsubscribe link in the template data, links rewritten to be tracked as
+// clicks and a pixel to track opens
+func (s *Service) render(campaign *models.Campaign, recipient *models.CampaignRecipient) (*models.Email, error) {
+	token := s.token(recipient.ID)
+	data := map[string]interface{}{}
+	for k, v := range campaign.Data {
+		data[k] = v
+	}
+	business := settings.Current()
+	for k, v := range map[string]interface{}{
+		"UserName":        recipient.Name,
+		"UserEmail":       recipient.Email,
+		"CompanyName":     business.CompanyName,
+		"SiteURL":         business.SiteURL,
+		"SupportEmail":    business.SupportEmail,
+		"CampaignName":    campaign.Name,
+		"Subject":         campaign.Subject,
+		"Content":         campaign.Content,
+		"ShopURL":         campaign.ShopURL,
+		"UnsubscribeLink": fmt.Sprintf("%s/campaigns/track/%s/unsubscribe", s.config.TrackingURL, token),
+		"subject":         campaign.Subject,
+	} {
+		data[k] = v
+	}
+	if campaign.ShopURL == "" {
+		data["ShopURL"] = business.SiteURL
+	}
+
+	htmlContent, textContent, err := s.engine.RenderTemplate(campaign.Template, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render campaign template: %w", err)
+	}
+
+	unsubscribe := data["UnsubscribeLink"].(string)
+	htmlContent = hrefPattern.ReplaceAllStringFunc(htmlContent, func(match string) string {
+		link := html.UnescapeString(hrefPattern.FindStringSubmatch(match)[1])
+		if link == unsubscribe {
+			return match
+		}
+		return fmt.Sprintf(`href="%s"`, html.EscapeString(s.clickURL(token, link)))
+	})
+	pixel := fmt.Sprintf(`<img src="%s/campaigns/track/%s/open" width="1" height="1" alt="" style="display:none">`, s.config.TrackingURL, token)
+	if i := strings.LastIndex(strings.ToLower(htmlContent), "</body>"); i >= 0 {
+		htmlContent = htmlContent[:i] + pixel + htmlContent[i:]
+	} else {
+		htmlContent += pixel
+	}
+
+	metadata := fmt.Sprintf(`{"campaign_id": %d, "campaign_recipient_id": %d}`, campaign.ID, recipient.ID)
+	userID := recipient.UserID
+	return &models.Email{
+		Type:        models.EmailTypePromotional,
+		Template:    campaign.Template,
+		Recipients:  []models.EmailRecipient{{Email: recipient.Email, Name: recipient.Name, UserID: &userID}},
+		Subject:     campaign.Subject,
+		HTMLContent: htmlContent,
+		TextContent: textContent,
+		Status:      models.EmailStatusPending,
+		Priority:    models.EmailPriorityBulk,
+		Metadata:    models.EmailJSON(metadata),
+	}, nil
+}
+
+// clickURL returns the tracked link of a campaign link
+func (s *Service) clickURL(token, link string) string {
+	return fmt.Sprintf("%s/campaigns/track/%s/click?url=%s&sig=%s",
+		s.config.TrackingURL, token, url.QueryEscape(link), s.sign("click:"+token+":"+link))
+}
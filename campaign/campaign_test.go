@@ -0,0 +1,303 @@
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeEngine renders the campaign content with a link to the shop
+type fakeEngine struct{}
+
+func (fakeEngine) RenderTemplate(name string, data map[string]interface{}) (string, string, error) {
+	return fmt.Sprintf(`<html><body><p>%v</p><a href="%v">Shop</a><a href="%v">Unsubscribe</a></body></html>`,
+		data["Content"], data["ShopURL"], data["UnsubscribeLink"]), "text", nil
+}
+
+func (fakeEngine) GetTemplateList() []string { return []string{"promotional"} }
+
+func (fakeEngine) ReloadTemplates() error { return nil }
+
+// fakeQueue records queued emails, refusing the suppressed addresses
+type fakeQueue struct {
+	db         *gorm.DB
+	emails     []*models.Email
+	suppressed map[string]bool
+}
+
+func (q *fakeQueue) QueueEmail(msg *models.Email) error {
+	if err := q.db.Create(msg).Error; err != nil {
+		return err
+	}
+	if q.suppressed[msg.Recipients[0].Email] {
+		return email.ErrRecipientSuppressed
+	}
+	q.emails = append(q.emails, msg)
+	return nil
+}
+
+var testNow = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{}, &models.Order{}, &models.Wishlist{}, &models.WishlistItem{}, &models.ProductVariant{},
+		&models.Email{}, &models.Segment{}, &models.Campaign{}, &models.CampaignRecipient{},
+	))
+	return db
+}
+
+func setupService(db *gorm.DB, batchSize int) (*Service, *fakeQueue) {
+	queue := &fakeQueue{db: db, suppressed: map[string]bool{}}
+	s := NewService(db, fakeEngine{}, queue, &cfg.CampaignConfig{
+		TrackingURL:    "https://api.example.com/api/v1/",
+		TrackingSecret: "secret",
+		BatchSize:      batchSize,
+	})
+	s.now = func() time.Time { return testNow }
+	return s, queue
+}
+
+func createUser(t *testing.T, db *gorm.DB, name string, userType models.UserType) *models.User {
+	user := models.User{Email: name + "@example.com", Password: "x", FirstName: name, UserType: userType, IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	return &user
+}
+
+func createOrder(t *testing.T, db *gorm.DB, user *models.User, amount float64, at time.Time, status models.OrderStatus) {
+	order := models.Order{
+		OrderNumber: fmt.Sprintf("ORD-%d-%d", user.ID, at.Unix()),
+		UserID:      user.ID,
+		Status:      status,
+		FinalAmount: amount,
+		OrderDate:   at,
+	}
+	require.NoError(t, db.Create(&order).Error)
+}
+
+func audienceIDs(t *testing.T, db *gorm.DB, f models.SegmentFilter) []uint {
+	var ids []uint
+	require.NoError(t, Audience(db, &f, testNow).Order("users.id").Pluck("users.id", &ids).Error)
+	return ids
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestAudience(t *testing.T) {
+	db := setupTestDB(t)
+	loyal := createUser(t, db, "loyal", models.Customer)
+	lapsed := createUser(t, db, "lapsed", models.Customer)
+	never := createUser(t, db, "never", models.Customer)
+	trade := createUser(t, db, "trade", models.Wholesaler)
+	createUser(t, db, "admin", models.Admin)
+	optedOut := createUser(t, db, "optedout", models.Customer)
+	require.NoError(t, db.Model(optedOut).Update("marketing_opt_out", true).Error)
+
+	createOrder(t, db, loyal, 60, testNow.AddDate(0, 0, -3), models.OrderStatusDelivered)
+	createOrder(t, db, loyal, 50, testNow.AddDate(0, 0, -10), models.OrderStatusDelivered)
+	createOrder(t, db, loyal, 500, testNow.AddDate(0, 0, -1), models.OrderStatusCancelled)
+	createOrder(t, db, lapsed, 300, testNow.AddDate(0, 0, -120), models.OrderStatusDelivered)
+	createOrder(t, db, trade, 80, testNow.AddDate(0, 0, -5), models.OrderStatusDelivered)
+	createOrder(t, db, optedOut, 80, testNow.AddDate(0, 0, -5), models.OrderStatusDelivered)
+
+	minSpend := 100.0
+	tests := []struct {
+		name   string
+		filter models.SegmentFilter
+		want   []uint
+	}{
+		{"everyone opted in", models.SegmentFilter{}, []uint{loyal.ID, lapsed.ID, never.ID, trade.ID}},
+		{"repeat customers, ignoring cancelled orders", models.SegmentFilter{MinOrders: intPtr(2)}, []uint{loyal.ID}},
+		{"never ordered", models.SegmentFilter{MaxOrders: intPtr(0)}, []uint{never.ID}},
+		{"spend excludes cancelled orders", models.SegmentFilter{MinSpend: &minSpend}, []uint{lapsed.ID, loyal.ID}},
+		{"lapsed customers", models.SegmentFilter{NotPurchasedForDays: intPtr(90), MinOrders: intPtr(1)}, []uint{lapsed.ID}},
+		{"recent customers", models.SegmentFilter{PurchasedWithinDays: intPtr(7)}, []uint{loyal.ID, trade.ID}},
+		{"user type", models.SegmentFilter{UserTypes: []models.UserType{models.Wholesaler}}, []uint{trade.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, audienceIDs(t, db, tt.filter))
+		})
+	}
+}
+
+func TestAudienceWishlist(t *testing.T) {
+	db := setupTestDB(t)
+	wants := createUser(t, db, "wants", models.Customer)
+	other := createUser(t, db, "other", models.Customer)
+	for _, user := range []*models.User{wants, other} {
+		require.NoError(t, db.Create(&models.Wishlist{UserID: &user.ID}).Error)
+	}
+	variant := models.ProductVariant{ProductID: 7, Name: "1kg", SKU: "SKU-7"}
+	require.NoError(t, db.Create(&variant).Error)
+	require.NoError(t, db.Create(&models.WishlistItem{WishlistID: 1, ProductVariantID: variant.ID}).Error)
+	require.NoError(t, db.Create(&models.WishlistItem{WishlistID: 2, ProductVariantID: 99}).Error)
+
+	assert.Equal(t, []uint{wants.ID}, audienceIDs(t, db, models.SegmentFilter{WishlistProductIDs: []uint{7}}))
+}
+
+func TestValidateFilter(t *testing.T) {
+	assert.NoError(t, ValidateFilter(&models.SegmentFilter{MinOrders: intPtr(1), MaxOrders: intPtr(3)}))
+	assert.ErrorIs(t, ValidateFilter(&models.SegmentFilter{MinOrders: intPtr(3), MaxOrders: intPtr(1)}), ErrInvalidFilter)
+	assert.ErrorIs(t, ValidateFilter(&models.SegmentFilter{PurchasedWithinDays: intPtr(-1)}), ErrInvalidFilter)
+}
+
+// createCampaign creates a campaign scheduled now for a segment of every
+// customer
+func createCampaign(t *testing.T, s *Service) *models.Campaign {
+	segment := models.Segment{Name: "Everyone"}
+	require.NoError(t, s.db.Create(&segment).Error)
+	campaign := models.Campaign{
+		Name:      "Spring sale",
+		SegmentID: segment.ID,
+		Subject:   "Spring sale",
+		Content:   "20% off",
+		ShopURL:   "https://shop.example.com/sale",
+		Status:    models.CampaignStatusDraft,
+	}
+	require.NoError(t, s.db.Create(&campaign).Error)
+	_, err := s.Schedule(campaign.ID, nil)
+	require.NoError(t, err)
+	return &campaign
+}
+
+func TestDispatchSendsInBatches(t *testing.T) {
+	db := setupTestDB(t)
+	s, queue := setupService(db, 2)
+	for i := range 3 {
+		createUser(t, db, fmt.Sprintf("customer%d", i), models.Customer)
+	}
+	suppressed := createUser(t, db, "bounced", models.Customer)
+	queue.suppressed[suppressed.Email] = true
+	campaign := createCampaign(t, s)
+
+	queued, err := s.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued, "only one batch is sent per dispatch")
+
+	// Customers joining the segment after the campaign starts are not added
+	createUser(t, db, "latecomer", models.Customer)
+
+	queued, err = s.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+
+	require.NoError(t, db.First(campaign, campaign.ID).Error)
+	assert.Equal(t, models.CampaignStatusSent, campaign.Status)
+	assert.Equal(t, 4, campaign.RecipientCount)
+	require.Len(t, queue.emails, 3)
+
+	msg := queue.emails[0]
+	assert.Equal(t, models.EmailPriorityBulk, msg.Priority)
+	assert.Contains(t, msg.HTMLContent, "https://api.example.com/api/v1/campaigns/track/")
+	assert.NotContains(t, msg.HTMLContent, `href="https://shop.example.com/sale"`, "links are tracked")
+	assert.Contains(t, msg.HTMLContent, "/open")
+
+	stats, err := s.Stats(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Sent)
+	assert.Equal(t, 1, stats.Suppressed)
+	assert.Equal(t, 0, stats.Pending)
+
+	queued, err = s.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, queued, "a sent campaign is not sent again")
+}
+
+func TestCancelSkipsPendingRecipients(t *testing.T) {
+	db := setupTestDB(t)
+	s, _ := setupService(db, 1)
+	createUser(t, db, "first", models.Customer)
+	createUser(t, db, "second", models.Customer)
+	campaign := createCampaign(t, s)
+
+	_, err := s.Dispatch(context.Background())
+	require.NoError(t, err)
+	_, err = s.Cancel(campaign.ID)
+	require.NoError(t, err)
+	_, err = s.Cancel(campaign.ID)
+	assert.ErrorIs(t, err, ErrNotCancelable)
+
+	stats, err := s.Stats(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Sent)
+	assert.Equal(t, 1, stats.Skipped)
+
+	_, err = s.Schedule(campaign.ID, nil)
+	assert.ErrorIs(t, err, ErrNotSchedulable)
+}
+
+// trackedLink returns the path and query of the tracked shop link of an email
+func trackedLink(t *testing.T, msg *models.Email) (token, link, sig string) {
+	start := strings.Index(msg.HTMLContent, `href="https://api.example.com/api/v1/campaigns/track/`)
+	require.GreaterOrEqual(t, start, 0)
+	href := msg.HTMLContent[start+len(`href="`):]
+	href = strings.ReplaceAll(href[:strings.Index(href, `"`)], "&amp;", "&")
+	u, err := url.Parse(href)
+	require.NoError(t, err)
+	token = strings.Split(strings.TrimPrefix(u.Path, "/api/v1/campaigns/track/"), "/")[0]
+	return token, u.Query().Get("url"), u.Query().Get("sig")
+}
+
+func TestTracking(t *testing.T) {
+	db := setupTestDB(t)
+	s, queue := setupService(db, 10)
+	buyer := createUser(t, db, "buyer", models.Customer)
+	createUser(t, db, "browser", models.Customer)
+	campaign := createCampaign(t, s)
+	_, err := s.Dispatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, queue.emails, 2)
+
+	token, link, sig := trackedLink(t, queue.emails[0])
+	assert.Equal(t, "https://shop.example.com/sale", link)
+
+	_, err = s.RecordClick(token, "https://evil.example.com", sig)
+	assert.ErrorIs(t, err, ErrInvalidToken, "a signature only covers its own link")
+	assert.ErrorIs(t, s.RecordOpen(token+"x"), ErrInvalidToken)
+
+	got, err := s.RecordClick(token, link, sig)
+	require.NoError(t, err)
+	assert.Equal(t, link, got)
+	_, err = s.RecordClick(token, link, sig)
+	require.NoError(t, err)
+	require.NoError(t, s.RecordOpen(token))
+
+	var sent models.Email
+	require.NoError(t, db.First(&sent, queue.emails[0].ID).Error)
+	assert.Equal(t, models.EmailStatusClicked, sent.Status)
+
+	// The buyer orders within the attribution window, and again after it
+	createOrder(t, db, buyer, 40, testNow.AddDate(0, 0, 2), models.OrderStatusDelivered)
+	createOrder(t, db, buyer, 70, testNow.AddDate(0, 0, 30), models.OrderStatusDelivered)
+	createOrder(t, db, buyer, 90, testNow.AddDate(0, 0, 3), models.OrderStatusCancelled)
+
+	browserToken, _, _ := trackedLink(t, queue.emails[1])
+	require.NoError(t, s.Unsubscribe(browserToken))
+
+	stats, err := s.Stats(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Sent)
+	assert.Equal(t, 1, stats.Opens, "a click counts as an open")
+	assert.Equal(t, 1, stats.Clicks)
+	assert.Equal(t, 2, stats.TotalClicks)
+	assert.Equal(t, 1, stats.Unsubscribes)
+	assert.Equal(t, 1, stats.Conversions)
+	assert.Equal(t, 1, stats.Orders)
+	assert.InDelta(t, 40.0, stats.Revenue, 0.001)
+	assert.InDelta(t, 50.0, stats.ConversionRate, 0.001)
+
+	assert.Empty(t, audienceIDs(t, db, models.SegmentFilter{MaxOrders: intPtr(0)}), "unsubscribed customers leave every segment")
+}
@@ -0,0 +1,88 @@
+package campaign
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidFilter = errors.New("invalid segment filter")
+
+// defaultUserTypes are the accounts a segment covers when its filter does not
+// choose: shoppers rather than staff and vendors
+var defaultUserTypes = []models.UserType{models.Customer, models.Wholesaler}
+
+// ValidateFilter checks that the ranges of a segment filter make sense
+func ValidateFilter(f *models.SegmentFilter) error {
+	for _, n := range []*int{f.MinOrders, f.MaxOrders, f.PurchasedWithinDays, f.NotPurchasedForDays} {
+		if n != nil && *n < 0 {
+			return fmt.Errorf("%w: counts and days cannot be negative", ErrInvalidFilter)
+		}
+	}
+	if f.MinOrders != nil && f.MaxOrders != nil && *f.MinOrders > *f.MaxOrders {
+		return fmt.Errorf("%w: min_orders is above max_orders", ErrInvalidFilter)
+	}
+	if f.MinSpend != nil && f.MaxSpend != nil && *f.MinSpend > *f.MaxSpend {
+		return fmt.Errorf("%w: min_spend is above max_spend", ErrInvalidFilter)
+	}
+	if f.LastPurchaseAfter != nil && f.LastPurchaseBefore != nil && f.LastPurchaseAfter.After(*f.LastPurchaseBefore) {
+		return fmt.Errorf("%w: last_purchase_after is after last_purchase_before", ErrInvalidFilter)
+	}
+	return nil
+}
+
+// Audience returns a query over the active users matched by a segment filter
+// who have not unsubscribed from campaigns. Orders count towards the order
+// count, spend and last purchase unless they were cancelled or returned.
+func Audience(db *gorm.DB, f *models.SegmentFilter, now time.Time) *gorm.DB {
+	stats := db.Model(&models.Order{}).
+		Select("user_id, COUNT(*) AS order_count, SUM(final_amount) AS spend, MAX(order_date) AS last_order").
+		Where("status NOT IN ?", []models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusReturned}).
+		Group("user_id")
+
+	userTypes := f.UserTypes
+	if len(userTypes) == 0 {
+		userTypes = defaultUserTypes
+	}
+	q := db.Model(&models.User{}).
+		Joins("LEFT JOIN (?) AS order_stats ON order_stats.user_id = users.id", stats).
+		Where("users.is_active = ? AND users.marketing_opt_out = ?", true, false).
+		Where("users.user_type IN ?", userTypes)
+
+	if f.MinOrders != nil {
+		q = q.Where("COALESCE(order_stats.order_count, 0) >= ?", *f.MinOrders)
+	}
+	if f.MaxOrders != nil {
+		q = q.Where("COALESCE(order_stats.order_count, 0) <= ?", *f.MaxOrders)
+	}
+	if f.MinSpend != nil {
+		q = q.Where("COALESCE(order_stats.spend, 0) >= ?", *f.MinSpend)
+	}
+	if f.MaxSpend != nil {
+		q = q.Where("COALESCE(order_stats.spend, 0) <= ?", *f.MaxSpend)
+	}
+	if f.LastPurchaseAfter != nil {
+		q = q.Where("order_stats.last_order >= ?", *f.LastPurchaseAfter)
+	}
+	if f.LastPurchaseBefore != nil {
+		q = q.Where("order_stats.last_order < ?", *f.LastPurchaseBefore)
+	}
+	if f.PurchasedWithinDays != nil {
+		q = q.Where("order_stats.last_order >= ?", now.AddDate(0, 0, -*f.PurchasedWithinDays))
+	}
+	if f.NotPurchasedForDays != nil {
+		q = q.Where("(order_stats.last_order IS NULL OR order_stats.last_order < ?)", now.AddDate(0, 0, -*f.NotPurchasedForDays))
+	}
+	if len(f.WishlistProductIDs) > 0 {
+		q = q.Where(`EXISTS (SELECT 1 FROM wishlist_items
+			JOIN wishlists ON wishlists.id = wishlist_items.wishlist_id AND wishlists.deleted_at IS NULL
+			LEFT JOIN product_variants ON product_variants.id = wishlist_items.product_variant_id
+			WHERE wishlists.user_id = users.id AND wishlist_items.deleted_at IS NULL
+			AND (product_variants.product_id IN ? OR wishlist_items.product_id IN ?))`,
+			f.WishlistProductIDs, f.WishlistProductIDs)
+	}
+	return q
+}
@@ -0,0 +1,114 @@
+package campaign
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// Stats is the reach and engagement of a campaign. Rates are percentages of
+// the emails sent.
+type Stats struct {
+	CampaignID      uint    `json:"campaign_id"`
+	Recipients      int     `json:"recipients"`
+	Sent            int     `json:"sent"`
+	Pending         int     `json:"pending"`
+	Suppressed      int     `json:"suppressed"`
+	Failed          int     `json:"failed"`
+	Skipped         int     `json:"skipped"`
+	Opens           int     `json:"opens"`  // recipients who opened
+	Clicks          int     `json:"clicks"` // recipients who clicked
+	TotalClicks     int     `json:"total_clicks"`
+	Unsubscribes    int     `json:"unsubscribes"`
+	Conversions     int     `json:"conversions"` // recipients who ordered within the attribution window
+	Orders          int     `json:"orders"`
+	Revenue         float64 `json:"revenue"`
+	OpenRate        float64 `json:"open_rate"`
+	ClickRate       float64 `json:"click_rate"`
+	ConversionRate  float64 `json:"conversion_rate"`
+	AttributionDays int     `json:"attribution_days"`
+}
+
+// Stats returns the analytics of a campaign. An order is a conversion when a
+// recipient places it within the attribution window after being sent the
+// campaign; cancelled and returned orders do not count.
+func (s *Service) Stats(campaignID uint) (*Stats, error) {
+	stats := &Stats{CampaignID: campaignID, AttributionDays: s.config.AttributionDays}
+
+	var byStatus []struct {
+		Status models.CampaignRecipientStatus
+		Count  int
+	}
+	if err := s.db.Model(&models.CampaignRecipient{}).
+		Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaignID).
+		Group("status").Scan(&byStatus).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range byStatus {
+		stats.Recipients += row.Count
+		switch row.Status {
+		case models.CampaignRecipientQueued:
+			stats.Sent = row.Count
+		case models.CampaignRecipientPending:
+			stats.Pending = row.Count
+		case models.CampaignRecipientSuppressed:
+			stats.Suppressed = row.Count
+		case models.CampaignRecipientFailed:
+			stats.Failed = row.Count
+		case models.CampaignRecipientSkipped:
+			stats.Skipped = row.Count
+		}
+	}
+
+	var engagement struct {
+		Opens        int
+		Clicks       int
+		TotalClicks  int
+		Unsubscribes int
+	}
+	if err := s.db.Model(&models.CampaignRecipient{}).
+		Select("COUNT(opened_at) AS opens, COUNT(clicked_at) AS clicks, COALESCE(SUM(click_count), 0) AS total_clicks, COUNT(unsubscribed_at) AS unsubscribes").
+		Where("campaign_id = ?", campaignID).
+		Scan(&engagement).Error; err != nil {
+		return nil, err
+	}
+	stats.Opens, stats.Clicks, stats.TotalClicks, stats.Unsubscribes =
+		engagement.Opens, engagement.Clicks, engagement.TotalClicks, engagement.Unsubscribes
+
+	var orders []struct {
+		UserID      uint
+		SentAt      time.Time
+		OrderDate   time.Time
+		FinalAmount float64
+	}
+	if err := s.db.Model(&models.CampaignRecipient{}).
+		Select("campaign_recipients.user_id, campaign_recipients.sent_at, orders.order_date, orders.final_amount").
+		Joins("JOIN orders ON orders.user_id = campaign_recipients.user_id AND orders.order_date >= campaign_recipients.sent_at AND orders.deleted_at IS NULL").
+		Where("campaign_recipients.campaign_id = ? AND campaign_recipients.status = ?", campaignID, models.CampaignRecipientQueued).
+		Where("orders.status NOT IN ?", []models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusReturned}).
+		Scan(&orders).Error; err != nil {
+		return nil, err
+	}
+	converted := map[uint]bool{}
+	for _, order := range orders {
+		if order.OrderDate.After(order.SentAt.AddDate(0, 0, s.config.AttributionDays)) {
+			continue
+		}
+		converted[order.UserID] = true
+		stats.Orders++
+		stats.Revenue += order.FinalAmount
+	}
+	stats.Conversions = len(converted)
+
+	if stats.Sent > 0 {
+		stats.OpenRate = percent(stats.Opens, stats.Sent)
+		stats.ClickRate = percent(stats.Clicks, stats.Sent)
+		stats.ConversionRate = percent(stats.Conversions, stats.Sent)
+	}
+	return stats, nil
+}
+
+func percent(n, of int) float64 {
+	return float64(int(float64(n)/float64(of)*10000+0.5)) / 100
+}
@@ -0,0 +1,127 @@
+package campaign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidToken is returned for tracking links that were not issued by the
+// service or have been tampered with
+var ErrInvalidToken = errors.New("invalid tracking link")
+
+// token identifies a recipient in tracking links: their ID and a signature,
+// so recipients cannot be enumerated
+func (s *Service) token(recipientID uint) string {
+	id := strconv.FormatUint(uint64(recipientID), 10)
+	return id + "." + s.sign("recipient:"+id)
+}
+
+func (s *Service) sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.TrackingSecret))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// recipient returns the recipient of a tracking token
+func (s *Service) recipient(tx *gorm.DB, token string) (*models.CampaignRecipient, error) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign("recipient:"+id))) {
+		return nil, ErrInvalidToken
+	}
+	var recipient models.CampaignRecipient
+	if err := tx.First(&recipient, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &recipient, nil
+}
+
+// RecordOpen records the first time a recipient opens a campaign email
+func (s *Service) RecordOpen(token string) error {
+	recipient, err := s.recipient(s.db, token)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if err := s.db.Model(&models.CampaignRecipient{}).
+		Where("id = ? AND opened_at IS NULL", recipient.ID).
+		Update("opened_at", now).Error; err != nil {
+		return err
+	}
+	if recipient.EmailID != nil {
+		return s.db.Model(&models.Email{}).
+			Where("id = ? AND opened_at IS NULL", *recipient.EmailID).
+			Updates(map[string]interface{}{"opened_at": now, "status": models.EmailStatusOpened}).Error
+	}
+	return nil
+}
+
+// RecordClick records a click on a campaign link and returns the link to
+// redirect to. A click also counts as an open, since images are often blocked.
+func (s *Service) RecordClick(token, link, sig string) (string, error) {
+	if !hmac.Equal([]byte(sig), []byte(s.sign("click:"+token+":"+link))) {
+		return "", ErrInvalidToken
+	}
+	recipient, err := s.recipient(s.db, token)
+	if err != nil {
+		return "", err
+	}
+	now := s.now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CampaignRecipient{}).Where("id = ?", recipient.ID).
+			Update("click_count", gorm.Expr("click_count + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.CampaignRecipient{}).
+			Where("id = ? AND clicked_at IS NULL", recipient.ID).
+			Update("clicked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.CampaignRecipient{}).
+			Where("id = ? AND opened_at IS NULL", recipient.ID).
+			Update("opened_at", now).Error; err != nil {
+			return err
+		}
+		if recipient.EmailID == nil {
+			return nil
+		}
+		return tx.Model(&models.Email{}).
+			Where("id = ? AND clicked_at IS NULL", *recipient.EmailID).
+			Updates(map[string]interface{}{
+				"clicked_at": now,
+				"opened_at":  gorm.Expr("COALESCE(opened_at, ?)", now),
+				"status":     models.EmailStatusClicked,
+			}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return link, nil
+}
+
+// Unsubscribe stops every future campaign to the recipient's account
+func (s *Service) Unsubscribe(token string) error {
+	recipient, err := s.recipient(s.db, token)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", recipient.UserID).
+			Update("marketing_opt_out", true).Error; err != nil {
+			return fmt.Errorf("failed to unsubscribe user: %w", err)
+		}
+		return tx.Model(&models.CampaignRecipient{}).
+			Where("id = ? AND unsubscribed_at IS NULL", recipient.ID).
+			Update("unsubscribed_at", s.now()).Error
+	})
+}
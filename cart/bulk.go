@@ -0,0 +1,251 @@
+package cart
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"gorm.io/gorm"
+)
+
+// MaxBulkLines is the most lines a bulk order may have
+const MaxBulkLines = 500
+
+// Bulk order line problems, besides the cart warnings
+const (
+	WarningInvalidQuantity WarningCode = "INVALID_QUANTITY"
+	WarningDuplicateSKU    WarningCode = "DUPLICATE_SKU"
+)
+
+var (
+	// ErrNotB2B is returned when a customer who is neither a wholesaler nor a
+	// company buyer imports a bulk order
+	ErrNotB2B = errors.New("bulk orders are only available to business customers")
+	// ErrInvalidBulkOrder is returned when a bulk order cannot be read or has
+	// no lines
+	ErrInvalidBulkOrder = errors.New("invalid bulk order")
+	// ErrBulkOrderHasErrors is returned when confirming a bulk order with
+	// lines in error. The draft still reports them.
+	ErrBulkOrderHasErrors = errors.New("fix the lines in error before confirming the order")
+)
+
+// BulkLine is a SKU and quantity of a bulk order
+type BulkLine struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// BulkDraftLine is a line of a bulk order resolved to its variant and price,
+// or the reason it cannot be ordered
+type BulkDraftLine struct {
+	Line             int         `json:"line"` // position in the import, from 1
+	SKU              string      `json:"sku"`
+	Quantity         int         `json:"quantity"`
+	ProductVariantID uint        `json:"product_variant_id,omitempty"`
+	ProductName      string      `json:"product_name,omitempty"`
+	VariantName      string      `json:"variant_name,omitempty"`
+	UnitPrice        float64     `json:"unit_price,omitempty"`
+	TotalPrice       float64     `json:"total_price,omitempty"`
+	AvailableStock   *int        `json:"available_stock,omitempty"`
+	Error            WarningCode `json:"error,omitempty"`
+	Message          string      `json:"message,omitempty"`
+}
+
+// BulkDraft is a bulk order priced for the buyer before it is confirmed
+type BulkDraft struct {
+	Valid      bool            `json:"valid"` // false when at least one line is in error
+	Lines      []BulkDraftLine `json:"lines"`
+	ErrorLines int             `json:"error_lines"`
+	Subtotal   float64         `json:"subtotal"` // of the lines without errors, before tax
+	Confirmed  bool            `json:"confirmed"`
+	Cart       *models.Cart    `json:"cart,omitempty"` // once confirmed
+}
+
+// BulkOrder resolves a B2B buyer's SKU list into a draft order: each line is
+// matched to an active variant by SKU and priced with the buyer's price list,
+// price tiers and B2B price, and checked against the minimum quantity and the
+// stock available beyond what is already in the cart. With confirm the lines
+// are added to the buyer's cart for checkout, but only when none is in error.
+func (s *CartService) BulkOrder(userID uint, lines []BulkLine, confirm bool) (*BulkDraft, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: no lines", ErrInvalidBulkOrder)
+	}
+	if len(lines) > MaxBulkLines {
+		return nil, fmt.Errorf("%w: more than %d lines", ErrInvalidBulkOrder, MaxBulkLines)
+	}
+	var user models.User
+	if err := s.db.Select("id", "user_type", "company_id").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.UserType != models.Wholesaler && user.CompanyID == nil {
+		return nil, ErrNotB2B
+	}
+	priceList, err := s.pricing.ActivePriceList(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Bulk orders are always priced as B2B lines
+	const priceType = "b2b"
+
+	draft := &BulkDraft{Lines: make([]BulkDraftLine, 0, len(lines))}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var cart models.Cart
+		if err := tx.Where("user_id = ?", userID).FirstOrCreate(&cart, models.Cart{UserID: &userID}).Error; err != nil {
+			return err
+		}
+
+		seen := make(map[string]int, len(lines))
+		variants := make(map[int]*models.ProductVariant, len(lines))
+		inCart := make(map[int]int, len(lines))
+		for i, l := range lines {
+			line := BulkDraftLine{Line: i + 1, SKU: strings.TrimSpace(l.SKU), Quantity: l.Quantity}
+			variant, quantityInCart, err := s.resolveBulkLine(tx, cart.ID, priceType, priceList, &line, seen)
+			if err != nil {
+				return err
+			}
+			if line.Error != "" {
+				draft.ErrorLines++
+			} else {
+				variants[i], inCart[i] = variant, quantityInCart
+				draft.Subtotal += line.TotalPrice
+			}
+			draft.Lines = append(draft.Lines, line)
+		}
+		draft.Valid = draft.ErrorLines == 0
+
+		if !confirm {
+			return nil
+		}
+		if !draft.Valid {
+			return ErrBulkOrderHasErrors
+		}
+		for i, line := range draft.Lines {
+			if err := addToCart(tx, cart.ID, variants[i].ID, priceType, inCart[i]+line.Quantity, line.UnitPrice); err != nil {
+				return err
+			}
+		}
+		if err := tx.Preload("Items.ProductVariant.Product").Preload("Items.ProductVariant.Images").First(&cart, cart.ID).Error; err != nil {
+			return err
+		}
+		draft.Confirmed, draft.Cart = true, &cart
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrBulkOrderHasErrors) {
+			return draft, err
+		}
+		return nil, err
+	}
+	return draft, nil
+}
+
+// resolveBulkLine fills in a line's variant and price, or the error that
+// stops it being ordered, and returns the variant with the quantity of it
+// already in the cart. seen maps the SKUs of earlier lines to their line.
+func (s *CartService) resolveBulkLine(tx *gorm.DB, cartID uint, priceType string, priceList *models.PriceList, line *BulkDraftLine, seen map[string]int) (*models.ProductVariant, int, error) {
+	fail := func(code WarningCode, message string) (*models.ProductVariant, int, error) {
+		line.Error, line.Message = code, message
+		return nil, 0, nil
+	}
+	if line.Quantity <= 0 {
+		return fail(WarningInvalidQuantity, "Quantity must be at least 1")
+	}
+	key := strings.ToUpper(line.SKU)
+	if first, ok := seen[key]; ok {
+		return fail(WarningDuplicateSKU, fmt.Sprintf("SKU %s is already on line %d", line.SKU, first))
+	}
+	seen[key] = line.Line
+
+	var variant models.ProductVariant
+	if err := tx.Preload("Product").Preload("PriceTiers").Where("UPPER(sku) = ?", key).First(&variant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fail(WarningVariantNotFound, fmt.Sprintf("No product has SKU %s", line.SKU))
+		}
+		return nil, 0, err
+	}
+	line.ProductVariantID = variant.ID
+	line.ProductName, line.VariantName = variant.Product.Name, variant.Name
+	if !variant.Product.IsActive || !variant.Product.IsPublished(time.Now()) {
+		return fail(WarningProductInactive, fmt.Sprintf("Product '%s' is not available", variant.Product.Name))
+	}
+	if !variant.IsActive {
+		return fail(WarningVariantDisabled, fmt.Sprintf("Variant '%s' has been disabled", variant.Name))
+	}
+
+	inCart, err := cartQuantity(tx, cartID, variant.ID, priceType)
+	if err != nil {
+		return nil, 0, err
+	}
+	line.UnitPrice = pricing.UnitPriceWithList(priceList, &variant, inCart+line.Quantity, priceType)
+	line.TotalPrice = float64(line.Quantity) * line.UnitPrice
+	if inCart+line.Quantity < variant.MinQuantity {
+		return fail(WarningBelowMinQuantity, fmt.Sprintf("Minimum quantity for variant '%s' is %d", variant.Name, variant.MinQuantity))
+	}
+
+	available, err := availableStock(tx, &variant)
+	if err != nil {
+		return nil, 0, err
+	}
+	available = max(available-inCart, 0)
+	if available <= 0 {
+		line.AvailableStock = &available
+		return fail(WarningOutOfStock, fmt.Sprintf("Variant '%s' is out of stock", variant.Name))
+	}
+	if line.Quantity > available {
+		line.AvailableStock = &available
+		return fail(WarningInsufficientStock, fmt.Sprintf("Only %d units of '%s' are available", available, variant.Name))
+	}
+	return &variant, inCart, nil
+}
+
+// ParseBulkCSV reads bulk order lines from CSV with a header row with sku and
+// quantity columns. Rows with a quantity that is not a number are kept with a
+// quantity of 0 so they are reported on their line.
+func ParseBulkCSV(r io.Reader) ([]BulkLine, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidBulkOrder)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	skuColumn, hasSKU := columns["sku"]
+	quantityColumn, hasQuantity := columns["quantity"]
+	if !hasSKU || !hasQuantity {
+		return nil, fmt.Errorf("%w: missing sku or quantity column", ErrInvalidBulkOrder)
+	}
+
+	var lines []BulkLine
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidBulkOrder, row, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		var line BulkLine
+		if skuColumn < len(record) {
+			line.SKU = strings.TrimSpace(record[skuColumn])
+		}
+		if quantityColumn < len(record) {
+			line.Quantity, _ = strconv.Atoi(strings.TrimSpace(record[quantityColumn]))
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
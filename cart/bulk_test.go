@@ -0,0 +1,103 @@
+package cart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkOrder(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewCartService(db, &cfg.CartConfig{})
+
+	buyer := models.User{Email: "wholesale@example.com", UserType: models.Wholesaler}
+	require.NoError(t, db.Create(&buyer).Error)
+
+	b2b := createVariant(t, db, "B2B-1", 10, 50)
+	tiered := createVariant(t, db, "TIER-1", 6, 100)
+	require.NoError(t, db.Create(&models.ProductVariantPriceTier{ProductVariantID: tiered.ID, MinQuantity: 10, Price: 4}).Error)
+	scarce := createVariant(t, db, "SCARCE-1", 5, 3)
+
+	// Stock already in the cart is not available to the import
+	cart := models.Cart{UserID: &buyer.ID}
+	require.NoError(t, db.Create(&cart).Error)
+	require.NoError(t, db.Create(&models.CartItem{CartID: cart.ID, ProductVariantID: scarce.ID, Quantity: 1, PriceType: "b2b", UnitPrice: 4, TotalPrice: 4}).Error)
+
+	lines := []BulkLine{
+		{SKU: "b2b-1", Quantity: 5},
+		{SKU: "TIER-1", Quantity: 12},
+		{SKU: "SCARCE-1", Quantity: 5},
+		{SKU: "UNKNOWN", Quantity: 1},
+		{SKU: "B2B-1", Quantity: 1},
+		{SKU: "TIER-1 ", Quantity: 0},
+	}
+	draft, err := service.BulkOrder(buyer.ID, lines, false)
+	require.NoError(t, err)
+	assert.False(t, draft.Valid)
+	assert.Equal(t, 4, draft.ErrorLines)
+	assert.Equal(t, 5*8.0+12*4.0, draft.Subtotal)
+
+	require.Len(t, draft.Lines, 6)
+	assert.Equal(t, b2b.ID, draft.Lines[0].ProductVariantID)
+	assert.Equal(t, 8.0, draft.Lines[0].UnitPrice)
+	assert.Equal(t, 4.0, draft.Lines[1].UnitPrice)
+	assert.Equal(t, WarningInsufficientStock, draft.Lines[2].Error)
+	require.NotNil(t, draft.Lines[2].AvailableStock)
+	assert.Equal(t, 2, *draft.Lines[2].AvailableStock)
+	assert.Equal(t, WarningVariantNotFound, draft.Lines[3].Error)
+	assert.Equal(t, WarningDuplicateSKU, draft.Lines[4].Error)
+	assert.Equal(t, WarningInvalidQuantity, draft.Lines[5].Error)
+
+	// Nothing is added while a line is in error
+	draft, err = service.BulkOrder(buyer.ID, lines, true)
+	assert.ErrorIs(t, err, ErrBulkOrderHasErrors)
+	require.NotNil(t, draft)
+	assert.False(t, draft.Confirmed)
+	var items int64
+	require.NoError(t, db.Model(&models.CartItem{}).Where("cart_id = ?", cart.ID).Count(&items).Error)
+	assert.Equal(t, int64(1), items)
+
+	draft, err = service.BulkOrder(buyer.ID, []BulkLine{{SKU: "B2B-1", Quantity: 5}, {SKU: "SCARCE-1", Quantity: 2}}, true)
+	require.NoError(t, err)
+	assert.True(t, draft.Valid)
+	assert.True(t, draft.Confirmed)
+	quantities := map[uint]int{}
+	for _, item := range draft.Cart.Items {
+		quantities[item.ProductVariantID] = item.Quantity
+	}
+	assert.Equal(t, map[uint]int{b2b.ID: 5, scarce.ID: 3}, quantities)
+}
+
+func TestBulkOrderRequiresBusinessCustomer(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewCartService(db, &cfg.CartConfig{})
+
+	customer := models.User{Email: "customer@example.com", UserType: models.Customer}
+	require.NoError(t, db.Create(&customer).Error)
+	createVariant(t, db, "SKU-1", 10, 10)
+
+	_, err := service.BulkOrder(customer.ID, []BulkLine{{SKU: "SKU-1", Quantity: 1}}, false)
+	assert.ErrorIs(t, err, ErrNotB2B)
+
+	companyID := uint(3)
+	require.NoError(t, db.Model(&customer).Update("company_id", companyID).Error)
+	draft, err := service.BulkOrder(customer.ID, []BulkLine{{SKU: "SKU-1", Quantity: 1}}, false)
+	require.NoError(t, err)
+	assert.True(t, draft.Valid)
+
+	_, err = service.BulkOrder(customer.ID, nil, false)
+	assert.ErrorIs(t, err, ErrInvalidBulkOrder)
+}
+
+func TestParseBulkCSV(t *testing.T) {
+	lines, err := ParseBulkCSV(strings.NewReader("\ufeffName,SKU,Quantity\nHalwa,HAL-250,12\n\n ,HAL-1KG , x\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []BulkLine{{SKU: "HAL-250", Quantity: 12}, {SKU: "HAL-1KG", Quantity: 0}}, lines)
+
+	_, err = ParseBulkCSV(strings.NewReader("sku,qty\nHAL-250,1\n"))
+	assert.ErrorIs(t, err, ErrInvalidBulkOrder)
+}
@@ -0,0 +1,253 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"gorm.io/gorm"
+)
+
+// ReorderStatus is what happened to a line of a previous order when it was
+// added back to the cart
+type ReorderStatus string
+
+const (
+	ReorderAdded       ReorderStatus = "ADDED"
+	ReorderReduced     ReorderStatus = "QUANTITY_REDUCED" // added, but less than ordered was in stock
+	ReorderSubstituted ReorderStatus = "SUBSTITUTED"      // another variant of the product was added
+	ReorderUnavailable ReorderStatus = "UNAVAILABLE"
+)
+
+// ErrNothingToReorder is returned when none of an order's items can be added
+// to the cart. The result still reports why.
+var ErrNothingToReorder = errors.New("none of the order's items are available")
+
+// ReorderLine reports how a line of a previous order was added to the cart
+type ReorderLine struct {
+	OrderItemID       uint          `json:"order_item_id"`
+	ProductVariantID  uint          `json:"product_variant_id"`        // the variant ordered
+	CartVariantID     uint          `json:"cart_variant_id,omitempty"` // the variant added, a substitute when substituted
+	OrderedQuantity   int           `json:"ordered_quantity"`
+	AddedQuantity     int           `json:"added_quantity"`
+	PreviousUnitPrice float64       `json:"previous_unit_price"`
+	UnitPrice         float64       `json:"unit_price,omitempty"`
+	PriceChanged      bool          `json:"price_changed"`
+	Status            ReorderStatus `json:"status"`
+	Message           string        `json:"message,omitempty"`
+}
+
+// ReorderResult is the outcome of rebuilding a cart from a previous order
+type ReorderResult struct {
+	OrderID     uint          `json:"order_id"`
+	OrderNumber string        `json:"order_number"`
+	Lines       []ReorderLine `json:"lines"`
+	Added       int           `json:"added"`
+	Substituted int           `json:"substituted"`
+	Unavailable int           `json:"unavailable"`
+	Cart        *models.Cart  `json:"cart,omitempty"`
+}
+
+// Reorder adds the items of one of the user's previous orders to their cart
+// at today's prices. Each line is checked against current stock and active
+// state: a line is reduced to the stock available, replaced by another active
+// variant of the same product when its variant can no longer be bought, or
+// reported unavailable. Items already in the cart are kept.
+func (s *CartService) Reorder(userID, orderID uint) (*ReorderResult, error) {
+	var order models.Order
+	if err := s.db.Preload("Items", "status = ?", "active").
+		Where("id = ? AND user_id = ?", orderID, userID).
+		First(&order).Error; err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := s.db.Select("id", "user_type").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	priceType := "customer"
+	if user.UserType == models.Wholesaler {
+		priceType = "b2b"
+	}
+	priceList, err := s.pricing.ActivePriceList(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReorderResult{OrderID: order.ID, OrderNumber: order.OrderNumber, Lines: []ReorderLine{}}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var cart models.Cart
+		if err := tx.Where("user_id = ?", userID).FirstOrCreate(&cart, models.Cart{UserID: &userID}).Error; err != nil {
+			return err
+		}
+
+		for _, item := range order.Items {
+			line := ReorderLine{
+				OrderItemID:       item.ID,
+				ProductVariantID:  item.ProductVariantID,
+				OrderedQuantity:   item.Quantity,
+				PreviousUnitPrice: item.UnitPrice,
+			}
+			variant, status, message, err := reorderVariant(tx, &item)
+			if err != nil {
+				return err
+			}
+			if variant == nil {
+				line.Status, line.Message = ReorderUnavailable, message
+				result.Unavailable++
+				result.Lines = append(result.Lines, line)
+				continue
+			}
+
+			inCart, err := cartQuantity(tx, cart.ID, variant.ID, priceType)
+			if err != nil {
+				return err
+			}
+			available, err := availableStock(tx, variant)
+			if err != nil {
+				return err
+			}
+			quantity := min(max(item.Quantity, variant.MinQuantity), available-inCart)
+			if quantity <= 0 || inCart+quantity < variant.MinQuantity {
+				line.Status = ReorderUnavailable
+				line.Message = fmt.Sprintf("Variant '%s' is out of stock", variant.Name)
+				result.Unavailable++
+				result.Lines = append(result.Lines, line)
+				continue
+			}
+
+			line.CartVariantID = variant.ID
+			line.AddedQuantity = quantity
+			line.Status, line.Message = status, message
+			if status == ReorderAdded && quantity < item.Quantity {
+				line.Status = ReorderReduced
+				line.Message = fmt.Sprintf("Only %d units of '%s' are available", quantity, variant.Name)
+			}
+			line.UnitPrice = pricing.UnitPriceWithList(priceList, variant, inCart+quantity, priceType)
+			line.PriceChanged = math.Abs(line.UnitPrice-item.UnitPrice) > 0.0001
+			if err := addToCart(tx, cart.ID, variant.ID, priceType, inCart+quantity, line.UnitPrice); err != nil {
+				return err
+			}
+
+			if line.Status == ReorderSubstituted {
+				result.Substituted++
+			} else {
+				result.Added++
+			}
+			result.Lines = append(result.Lines, line)
+		}
+
+		if result.Added+result.Substituted == 0 {
+			return ErrNothingToReorder
+		}
+		if err := tx.Preload("Items.ProductVariant.Product").Preload("Items.ProductVariant.Images").First(&cart, cart.ID).Error; err != nil {
+			return err
+		}
+		result.Cart = &cart
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNothingToReorder) {
+			return result, err
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// reorderVariant returns the variant to add for an order line: the variant
+// ordered when it can still be bought, otherwise the in-stock variant of the
+// same product closest in price. It returns nil with a reason when neither
+// can be added.
+func reorderVariant(tx *gorm.DB, item *models.OrderItem) (*models.ProductVariant, ReorderStatus, string, error) {
+	var ordered models.ProductVariant
+	if err := tx.Unscoped().Preload("Product").Preload("PriceTiers").First(&ordered, item.ProductVariantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ReorderUnavailable, "Product variant no longer exists", nil
+		}
+		return nil, "", "", err
+	}
+	if ordered.Product.ID == 0 || !ordered.Product.IsActive || ordered.Product.DeletedAt.Valid || !ordered.Product.IsPublished(time.Now()) {
+		return nil, ReorderUnavailable, fmt.Sprintf("Product '%s' is no longer available", ordered.Product.Name), nil
+	}
+
+	reason := ""
+	switch {
+	case ordered.DeletedAt.Valid:
+		reason = fmt.Sprintf("Variant '%s' no longer exists", ordered.Name)
+	case !ordered.IsActive:
+		reason = fmt.Sprintf("Variant '%s' has been disabled", ordered.Name)
+	default:
+		available, err := availableStock(tx, &ordered)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if available > 0 {
+			return &ordered, ReorderAdded, "", nil
+		}
+		reason = fmt.Sprintf("Variant '%s' is out of stock", ordered.Name)
+	}
+
+	var candidates []models.ProductVariant
+	if err := tx.Preload("Product").Preload("PriceTiers").
+		Where("product_id = ? AND id <> ? AND is_active = ?", ordered.ProductID, ordered.ID, true).
+		Find(&candidates).Error; err != nil {
+		return nil, "", "", err
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		di := math.Abs(candidates[i].BasePrice - ordered.BasePrice)
+		dj := math.Abs(candidates[j].BasePrice - ordered.BasePrice)
+		if di != dj {
+			return di < dj
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	for i := range candidates {
+		available, err := availableStock(tx, &candidates[i])
+		if err != nil {
+			return nil, "", "", err
+		}
+		if available > 0 {
+			return &candidates[i], ReorderSubstituted, fmt.Sprintf("%s; replaced with '%s'", reason, candidates[i].Name), nil
+		}
+	}
+	return nil, ReorderUnavailable, reason, nil
+}
+
+// cartQuantity returns the quantity of a variant already in a cart
+func cartQuantity(tx *gorm.DB, cartID, variantID uint, priceType string) (int, error) {
+	var item models.CartItem
+	err := tx.Where("cart_id = ? AND product_variant_id = ? AND price_type = ?", cartID, variantID, priceType).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return item.Quantity, err
+}
+
+// addToCart sets the quantity and price of a variant in a cart, adding it
+// when it is not there yet
+func addToCart(tx *gorm.DB, cartID, variantID uint, priceType string, quantity int, unitPrice float64) error {
+	var item models.CartItem
+	err := tx.Where("cart_id = ? AND product_variant_id = ? AND price_type = ?", cartID, variantID, priceType).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.CartItem{
+			CartID:           cartID,
+			ProductVariantID: variantID,
+			Quantity:         quantity,
+			PriceType:        priceType,
+			UnitPrice:        unitPrice,
+			TotalPrice:       float64(quantity) * unitPrice,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&item).Updates(map[string]interface{}{
+		"quantity":    quantity,
+		"unit_price":  unitPrice,
+		"total_price": float64(quantity) * unitPrice,
+	}).Error
+}
@@ -0,0 +1,106 @@
+package cart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createOrder(t *testing.T, db *gorm.DB, userID uint, number string, items ...models.OrderItem) models.Order {
+	order := models.Order{
+		OrderNumber:   number,
+		UserID:        userID,
+		Status:        models.OrderStatusDelivered,
+		PaymentStatus: models.PaymentStatusPaid,
+		OrderDate:     time.Now(),
+		Items:         items,
+	}
+	require.NoError(t, db.Create(&order).Error)
+	return order
+}
+
+func TestReorder(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewCartService(db, &cfg.CartConfig{})
+
+	user := models.User{Email: "buyer@example.com", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+
+	repriced := createVariant(t, db, "REPRICED", 12, 10)
+	scarce := createVariant(t, db, "SCARCE", 5, 1)
+	disabled := createVariant(t, db, "DISABLED-RED", 8, 10)
+	require.NoError(t, db.Model(&disabled).Update("is_active", false).Error)
+	substitute := models.ProductVariant{ProductID: disabled.ProductID, Name: "BLUE", SKU: "DISABLED-BLUE", BasePrice: 9, IsActive: true, MinQuantity: 1, QuantityInStock: 5}
+	require.NoError(t, db.Create(&substitute).Error)
+	withdrawn := createVariant(t, db, "WITHDRAWN", 4, 10)
+	require.NoError(t, db.Model(&models.Product{}).Where("id = ?", withdrawn.ProductID).Update("is_active", false).Error)
+
+	order := createOrder(t, db, user.ID, "ORD-REORDER",
+		models.OrderItem{ProductVariantID: repriced.ID, Quantity: 2, UnitPrice: 10, TotalAmount: 20},
+		models.OrderItem{ProductVariantID: scarce.ID, Quantity: 3, UnitPrice: 5, TotalAmount: 15},
+		models.OrderItem{ProductVariantID: disabled.ID, Quantity: 2, UnitPrice: 8, TotalAmount: 16},
+		models.OrderItem{ProductVariantID: withdrawn.ID, Quantity: 1, UnitPrice: 4, TotalAmount: 4},
+	)
+
+	// Items already in the cart are kept and merged with
+	cart := models.Cart{UserID: &user.ID}
+	require.NoError(t, db.Create(&cart).Error)
+	require.NoError(t, db.Create(&models.CartItem{CartID: cart.ID, ProductVariantID: repriced.ID, Quantity: 1, PriceType: "customer", UnitPrice: 12, TotalPrice: 12}).Error)
+
+	result, err := service.Reorder(user.ID, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Added)
+	assert.Equal(t, 1, result.Substituted)
+	assert.Equal(t, 1, result.Unavailable)
+
+	lines := map[uint]ReorderLine{}
+	for _, line := range result.Lines {
+		lines[line.ProductVariantID] = line
+	}
+	assert.Equal(t, ReorderAdded, lines[repriced.ID].Status)
+	assert.True(t, lines[repriced.ID].PriceChanged)
+	assert.Equal(t, 12.0, lines[repriced.ID].UnitPrice)
+	assert.Equal(t, ReorderReduced, lines[scarce.ID].Status)
+	assert.Equal(t, 1, lines[scarce.ID].AddedQuantity)
+	assert.Equal(t, ReorderSubstituted, lines[disabled.ID].Status)
+	assert.Equal(t, substitute.ID, lines[disabled.ID].CartVariantID)
+	assert.Equal(t, ReorderUnavailable, lines[withdrawn.ID].Status)
+
+	quantities := map[uint]int{}
+	for _, item := range result.Cart.Items {
+		quantities[item.ProductVariantID] = item.Quantity
+	}
+	assert.Equal(t, map[uint]int{repriced.ID: 3, scarce.ID: 1, substitute.ID: 2}, quantities)
+}
+
+func TestReorderRejectsOtherUsersAndEmptyOrders(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewCartService(db, &cfg.CartConfig{})
+
+	owner := models.User{Email: "owner@example.com", UserType: models.Customer}
+	other := models.User{Email: "other@example.com", UserType: models.Customer}
+	require.NoError(t, db.Create(&owner).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	soldOut := createVariant(t, db, "SOLD-OUT", 5, 0)
+	order := createOrder(t, db, owner.ID, "ORD-EMPTY",
+		models.OrderItem{ProductVariantID: soldOut.ID, Quantity: 1, UnitPrice: 5, TotalAmount: 5},
+	)
+
+	_, err := service.Reorder(other.ID, order.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	result, err := service.Reorder(owner.ID, order.ID)
+	assert.ErrorIs(t, err, ErrNothingToReorder)
+	require.NotNil(t, result)
+	assert.Equal(t, ReorderUnavailable, result.Lines[0].Status)
+
+	var count int64
+	require.NoError(t, db.Model(&models.CartItem{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
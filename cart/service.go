@@ -0,0 +1,276 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+// WarningCode identifies the kind of problem found on a cart line
+type WarningCode string
+
+const (
+	WarningPriceChanged      WarningCode = "PRICE_CHANGED"
+	WarningOutOfStock        WarningCode = "OUT_OF_STOCK"
+	WarningInsufficientStock WarningCode = "INSUFFICIENT_STOCK"
+	WarningVariantDisabled   WarningCode = "VARIANT_DISABLED"
+	WarningProductInactive   WarningCode = "PRODUCT_INACTIVE"
+	WarningVariantNotFound   WarningCode = "VARIANT_NOT_FOUND"
+	WarningBelowMinQuantity  WarningCode = "BELOW_MIN_QUANTITY"
+)
+
+// LineWarning describes a single problem on a cart item
+type LineWarning struct {
+	CartItemID       uint        `json:"cart_item_id"`
+	ProductVariantID uint        `json:"product_variant_id"`
+	Code             WarningCode `json:"code"`
+	Message          string      `json:"message"`
+	OldUnitPrice     float64     `json:"old_unit_price,omitempty"`
+	NewUnitPrice     float64     `json:"new_unit_price,omitempty"`
+	AvailableStock   *int        `json:"available_stock,omitempty"`
+}
+
+// RevalidationResult is the outcome of revalidating a cart
+type RevalidationResult struct {
+	CartID   uint          `json:"cart_id"`
+	Valid    bool          `json:"valid"` // false when at least one line blocks checkout
+	Subtotal float64       `json:"subtotal"`
+	Warnings []LineWarning `json:"warnings"`
+	Cart     *models.Cart  `json:"cart"`
+}
+
+// CartService holds cart business logic shared by handlers and workers
+type CartService struct {
+	db         *gorm.DB
+	pricing    *pricing.Resolver
+	itemExpiry time.Duration
+}
+
+// NewCartService creates a new cart service
+func NewCartService(db *gorm.DB, config *cfg.CartConfig) *CartService {
+	var expiry time.Duration
+	if config != nil && config.ItemExpiryHours > 0 {
+		expiry = time.Duration(config.ItemExpiryHours) * time.Hour
+	}
+	return &CartService{
+		db:         db,
+		pricing:    pricing.NewResolver(db),
+		itemExpiry: expiry,
+	}
+}
+
+// AvailableStock returns the sellable quantity for a variant. Active inventory
+// items are used when present, otherwise the variant's QuantityInStock. A
+// bundle has what its components' sellable quantities make up.
+func (s *CartService) AvailableStock(variant *models.ProductVariant) (int, error) {
+	return availableStock(s.db, variant)
+}
+
+func availableStock(db *gorm.DB, variant *models.ProductVariant) (int, error) {
+	bundles, err := stock.BundleComponents(db, []uint{variant.ID})
+	if err != nil {
+		return 0, err
+	}
+	if components, ok := bundles[variant.ID]; ok {
+		available := make(map[uint]int, len(components))
+		for _, component := range components {
+			var componentVariant models.ProductVariant
+			if err := db.Select("id", "quantity_in_stock").First(&componentVariant, component.ComponentVariantID).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					continue
+				}
+				return 0, fmt.Errorf("failed to load bundle component: %w", err)
+			}
+			if available[component.ComponentVariantID], err = availableStock(db, &componentVariant); err != nil {
+				return 0, err
+			}
+		}
+		return stock.BundleAvailable(components, available), nil
+	}
+
+	var count int64
+	if err := db.Model(&models.InventoryItem{}).
+		Where("product_variant_id = ?", variant.ID).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count inventory items: %w", err)
+	}
+	if count == 0 {
+		return variant.QuantityInStock, nil
+	}
+
+	var available int
+	if err := db.Model(&models.InventoryItem{}).
+		Where("product_variant_id = ? AND status = ?", variant.ID, "active").
+		Select("COALESCE(SUM(quantity - reserved), 0)").
+		Row().Scan(&available); err != nil {
+		return 0, fmt.Errorf("failed to calculate available stock: %w", err)
+	}
+	if available < 0 {
+		available = 0
+	}
+	return available, nil
+}
+
+// Revalidate checks every item of the user's cart against current prices
+// (price list, price tiers, B2B and base price), stock and active state. Line
+// prices are refreshed in place so the returned cart reflects what checkout
+// will charge.
+func (s *CartService) Revalidate(userID uint) (*RevalidationResult, error) {
+	var cart models.Cart
+	if err := s.db.Preload("Items.ProductVariant.Product").
+		Preload("Items.ProductVariant.PriceTiers").
+		Where("user_id = ?", userID).
+		First(&cart).Error; err != nil {
+		return nil, err
+	}
+
+	priceList, err := s.pricing.ActivePriceList(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RevalidationResult{
+		CartID:   cart.ID,
+		Valid:    true,
+		Warnings: []LineWarning{},
+	}
+
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		variant := item.ProductVariant
+		if variant == nil || variant.ID == 0 {
+			result.Valid = false
+			result.Warnings = append(result.Warnings, LineWarning{
+				CartItemID:       item.ID,
+				ProductVariantID: item.ProductVariantID,
+				Code:             WarningVariantNotFound,
+				Message:          "Product variant no longer exists",
+			})
+			continue
+		}
+
+		if !variant.Product.IsActive || !variant.Product.IsPublished(time.Now()) {
+			result.Valid = false
+			result.Warnings = append(result.Warnings, LineWarning{
+				CartItemID:       item.ID,
+				ProductVariantID: variant.ID,
+				Code:             WarningProductInactive,
+				Message:          fmt.Sprintf("Product '%s' is no longer available", variant.Product.Name),
+			})
+			continue
+		}
+
+		if !variant.IsActive {
+			result.Valid = false
+			result.Warnings = append(result.Warnings, LineWarning{
+				CartItemID:       item.ID,
+				ProductVariantID: variant.ID,
+				Code:             WarningVariantDisabled,
+				Message:          fmt.Sprintf("Variant '%s' has been disabled", variant.Name),
+			})
+			continue
+		}
+
+		if item.Quantity < variant.MinQuantity {
+			result.Valid = false
+			result.Warnings = append(result.Warnings, LineWarning{
+				CartItemID:       item.ID,
+				ProductVariantID: variant.ID,
+				Code:             WarningBelowMinQuantity,
+				Message:          fmt.Sprintf("Minimum quantity for variant '%s' is %d", variant.Name, variant.MinQuantity),
+			})
+		}
+
+		available, err := s.AvailableStock(variant)
+		if err != nil {
+			return nil, err
+		}
+		if available <= 0 {
+			result.Valid = false
+			result.Warnings = append(result.Warnings, LineWarning{
+				CartItemID:       item.ID,
+				ProductVariantID: variant.ID,
+				Code:             WarningOutOfStock,
+				Message:          fmt.Sprintf("Variant '%s' is out of stock", variant.Name),
+				AvailableStock:   &available,
+			})
+		} else if item.Quantity > available {
+			result.Valid = false
+			result.Warnings = append(result.Warnings, LineWarning{
+				CartItemID:       item.ID,
+				ProductVariantID: variant.ID,
+				Code:             WarningInsufficientStock,
+				Message:          fmt.Sprintf("Only %d units of '%s' are available", available, variant.Name),
+				AvailableStock:   &available,
+			})
+		}
+
+		currentPrice := pricing.UnitPriceWithList(priceList, variant, item.Quantity, item.PriceType)
+		if math.Abs(currentPrice-item.UnitPrice) > 0.0001 {
+			result.Warnings = append(result.Warnings, LineWarning{
+				CartItemID:       item.ID,
+				ProductVariantID: variant.ID,
+				Code:             WarningPriceChanged,
+				Message:          fmt.Sprintf("Price of '%s' changed from %.2f to %.2f", variant.Name, item.UnitPrice, currentPrice),
+				OldUnitPrice:     item.UnitPrice,
+				NewUnitPrice:     currentPrice,
+			})
+			item.UnitPrice = currentPrice
+			item.TotalPrice = float64(item.Quantity) * currentPrice
+			if err := s.db.Model(&models.CartItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"unit_price":  item.UnitPrice,
+				"total_price": item.TotalPrice,
+			}).Error; err != nil {
+				return nil, fmt.Errorf("failed to refresh cart item price: %w", err)
+			}
+		}
+
+		result.Subtotal += item.TotalPrice
+	}
+
+	result.Cart = &cart
+	return result, nil
+}
+
+// ExpireStaleItems removes cart items that have not been touched within the
+// configured expiry period. It is a no-op when expiry is disabled.
+func (s *CartService) ExpireStaleItems() (int64, error) {
+	if s.itemExpiry <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-s.itemExpiry)
+	res := s.db.Where("updated_at < ?", cutoff).Delete(&models.CartItem{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to expire stale cart items: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("🧹 CART: Expired %d stale cart items older than %s", res.RowsAffected, s.itemExpiry)
+	}
+	return res.RowsAffected, nil
+}
+
+// StartExpiryWorker periodically expires stale cart items until ctx is canceled
+func (s *CartService) StartExpiryWorker(ctx context.Context, interval time.Duration) {
+	if s.itemExpiry <= 0 {
+		log.Printf("CART: Stale item expiry disabled")
+		return
+	}
+	log.Printf("🔄 CART: Starting stale item expiry worker (expiry: %s)", s.itemExpiry)
+	for {
+		if _, err := s.ExpireStaleItems(); err != nil {
+			log.Printf("❌ CART: %v", err)
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package cart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.BundleComponent{},
+		&models.ProductVariantPriceTier{},
+		&models.ProductImage{},
+		&models.InventoryItem{},
+		&models.Cart{},
+		&models.CartItem{},
+		&models.PriceList{},
+		&models.PriceListItem{},
+		&models.Order{},
+		&models.OrderItem{},
+	)
+	require.NoError(t, err)
+
+	return db
+}
+
+func createVariant(t *testing.T, db *gorm.DB, sku string, price float64, stock int) models.ProductVariant {
+	product := models.Product{Name: "Product " + sku, IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{
+		ProductID:       product.ID,
+		Name:            sku,
+		SKU:             sku,
+		BasePrice:       price,
+		B2BPrice:        price * 0.8,
+		IsActive:        true,
+		MinQuantity:     1,
+		QuantityInStock: stock,
+	}
+	require.NoError(t, db.Create(&variant).Error)
+	return variant
+}
+
+func TestRevalidate(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewCartService(db, &cfg.CartConfig{ItemExpiryHours: 24})
+
+	userID := uint(1)
+	cart := models.Cart{UserID: &userID}
+	require.NoError(t, db.Create(&cart).Error)
+
+	repriced := createVariant(t, db, "REPRICED", 12, 10)
+	outOfStock := createVariant(t, db, "EMPTY", 5, 0)
+	disabled := createVariant(t, db, "DISABLED", 5, 10)
+	require.NoError(t, db.Model(&disabled).Update("is_active", false).Error)
+
+	items := []models.CartItem{
+		{CartID: cart.ID, ProductVariantID: repriced.ID, Quantity: 2, PriceType: "customer", UnitPrice: 10, TotalPrice: 20},
+		{CartID: cart.ID, ProductVariantID: outOfStock.ID, Quantity: 1, PriceType: "customer", UnitPrice: 5, TotalPrice: 5},
+		{CartID: cart.ID, ProductVariantID: disabled.ID, Quantity: 1, PriceType: "customer", UnitPrice: 5, TotalPrice: 5},
+	}
+	require.NoError(t, db.Create(&items).Error)
+
+	result, err := service.Revalidate(userID)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+
+	codes := map[uint]WarningCode{}
+	for _, w := range result.Warnings {
+		codes[w.ProductVariantID] = w.Code
+	}
+	assert.Equal(t, WarningPriceChanged, codes[repriced.ID])
+	assert.Equal(t, WarningOutOfStock, codes[outOfStock.ID])
+	assert.Equal(t, WarningVariantDisabled, codes[disabled.ID])
+
+	var refreshed models.CartItem
+	require.NoError(t, db.First(&refreshed, items[0].ID).Error)
+	assert.Equal(t, 12.0, refreshed.UnitPrice)
+	assert.Equal(t, 24.0, refreshed.TotalPrice)
+}
+
+func TestExpireStaleItems(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewCartService(db, &cfg.CartConfig{ItemExpiryHours: 24})
+
+	userID := uint(1)
+	cart := models.Cart{UserID: &userID}
+	require.NoError(t, db.Create(&cart).Error)
+	variant := createVariant(t, db, "SKU-1", 10, 10)
+
+	fresh := models.CartItem{CartID: cart.ID, ProductVariantID: variant.ID, Quantity: 1, UnitPrice: 10, TotalPrice: 10}
+	stale := models.CartItem{CartID: cart.ID, ProductVariantID: variant.ID, Quantity: 1, UnitPrice: 10, TotalPrice: 10}
+	require.NoError(t, db.Create(&fresh).Error)
+	require.NoError(t, db.Create(&stale).Error)
+	require.NoError(t, db.Model(&stale).UpdateColumn("updated_at", time.Now().Add(-48*time.Hour)).Error)
+
+	expired, err := service.ExpireStaleItems()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+
+	var count int64
+	db.Model(&models.CartItem{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	disabledService := NewCartService(db, &cfg.CartConfig{ItemExpiryHours: 0})
+	expired, err = disabledService.ExpireStaleItems()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), expired)
+}
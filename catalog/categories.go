@@ -0,0 +1,343 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// Categories form a tree through parent_id. Each category also stores its
+// materialized path, the IDs from the top level down to itself like
+// "/1/4/9/", so a subtree is every category whose path starts with the
+// subtree root's path.
+
+var (
+	ErrCategoryCycle      = errors.New("a category cannot be moved under itself or its descendants")
+	ErrCategoryNotSibling = errors.New("categories to reorder must share the parent")
+)
+
+// categoryPath is the path of a category with id under parent
+func categoryPath(parent *models.Category, id uint) string {
+	prefix := "/"
+	if parent != nil {
+		prefix = parent.Path
+	}
+	return fmt.Sprintf("%s%d/", prefix, id)
+}
+
+// findParent loads the parent with parentID, or returns nil for top level
+func findParent(tx *gorm.DB, parentID *uint) (*models.Category, error) {
+	if parentID == nil {
+		return nil, nil
+	}
+	var parent models.Category
+	if err := tx.First(&parent, *parentID).Error; err != nil {
+		return nil, err
+	}
+	return &parent, nil
+}
+
+// siblings scopes a category query to the children of parentID
+func siblings(parentID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if parentID == nil {
+			return db.Where("parent_id IS NULL")
+		}
+		return db.Where("parent_id = ?", *parentID)
+	}
+}
+
+// PlaceCategory sets the path, depth and position of a newly created
+// category, last among its siblings. It returns gorm.ErrRecordNotFound when
+// the parent does not exist.
+func PlaceCategory(tx *gorm.DB, category *models.Category) error {
+	parent, err := findParent(tx, category.ParentID)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := tx.Model(&models.Category{}).Scopes(siblings(category.ParentID)).Where("id <> ?", category.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	category.Path = categoryPath(parent, category.ID)
+	category.Depth = 0
+	if parent != nil {
+		category.Depth = parent.Depth + 1
+	}
+	category.Position = int(count)
+	return tx.Model(category).Updates(map[string]interface{}{
+		"path":     category.Path,
+		"depth":    category.Depth,
+		"position": category.Position,
+	}).Error
+}
+
+// MoveCategory moves a category and its subtree under parentID, or to the top
+// level when parentID is nil, at position among its new siblings. A nil
+// position puts it last.
+func MoveCategory(tx *gorm.DB, id uint, parentID *uint, position *int) (*models.Category, error) {
+	var category models.Category
+	if err := tx.First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	parent, err := findParent(tx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent != nil && strings.HasPrefix(parent.Path, category.Path) {
+		return nil, ErrCategoryCycle
+	}
+
+	oldPath := category.Path
+	newPath := categoryPath(parent, category.ID)
+	newDepth := 0
+	if parent != nil {
+		newDepth = parent.Depth + 1
+	}
+	if newPath != oldPath {
+		if err := tx.Model(&category).Update("parent_id", parentID).Error; err != nil {
+			return nil, err
+		}
+		// Rewrite the path prefix of the whole subtree, soft deleted rows included
+		if err := tx.Model(&models.Category{}).Unscoped().
+			Where("path LIKE ?", oldPath+"%").
+			Updates(map[string]interface{}{
+				"path":  gorm.Expr("? || SUBSTR(path, ?)", newPath, len(oldPath)+1),
+				"depth": gorm.Expr("depth + ?", newDepth-category.Depth),
+			}).Error; err != nil {
+			return nil, err
+		}
+		oldParentID := category.ParentID
+		category.ParentID, category.Path, category.Depth = parentID, newPath, newDepth
+		if err := renumber(tx, oldParentID, nil, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without a position a category keeps its place, or goes last under a new parent
+	fallback := -1
+	if newPath == oldPath {
+		fallback = category.Position
+	}
+	if err := renumber(tx, parentID, &category, positionOr(position, fallback)); err != nil {
+		return nil, err
+	}
+	if err := tx.First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func positionOr(position *int, fallback int) int {
+	if position == nil {
+		return fallback
+	}
+	return *position
+}
+
+// renumber numbers the children of parentID from 0 in their current order.
+// When moved is given it is placed at position, or last when position is out
+// of range.
+func renumber(tx *gorm.DB, parentID *uint, moved *models.Category, position int) error {
+	query := tx.Model(&models.Category{}).Scopes(siblings(parentID))
+	if moved != nil {
+		query = query.Where("id <> ?", moved.ID)
+	}
+	var ids []uint
+	if err := query.Order("position ASC, name ASC, id ASC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if moved != nil {
+		if position < 0 || position > len(ids) {
+			position = len(ids)
+		}
+		ids = append(ids[:position], append([]uint{moved.ID}, ids[position:]...)...)
+	}
+	return setPositions(tx, ids)
+}
+
+func setPositions(tx *gorm.DB, ids []uint) error {
+	for i, id := range ids {
+		if err := tx.Model(&models.Category{}).Where("id = ? AND position <> ?", id, i).Update("position", i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReorderCategories sets the order of the children of parentID. ids must all
+// be children of parentID; children not listed keep their order after them.
+func ReorderCategories(tx *gorm.DB, parentID *uint, ids []uint) error {
+	var count int64
+	if err := tx.Model(&models.Category{}).Scopes(siblings(parentID)).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(ids) {
+		return ErrCategoryNotSibling
+	}
+	var rest []uint
+	if err := tx.Model(&models.Category{}).Scopes(siblings(parentID)).Where("id NOT IN ?", ids).
+		Order("position ASC, name ASC, id ASC").Pluck("id", &rest).Error; err != nil {
+		return err
+	}
+	return setPositions(tx, append(append([]uint{}, ids...), rest...))
+}
+
+// RemoveCategory moves the children of a category about to be deleted up to
+// its parent, after its other children
+func RemoveCategory(tx *gorm.DB, category *models.Category) error {
+	var children []models.Category
+	if err := tx.Scopes(siblings(&category.ID)).Order("position ASC, name ASC, id ASC").Find(&children).Error; err != nil {
+		return err
+	}
+	for _, child := range children {
+		if _, err := MoveCategory(tx, child.ID, category.ParentID, nil); err != nil {
+			return err
+		}
+	}
+	// Close the gap the category leaves among its siblings
+	var ids []uint
+	if err := tx.Model(&models.Category{}).Scopes(siblings(category.ParentID)).Where("id <> ?", category.ID).
+		Order("position ASC, name ASC, id ASC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	return setPositions(tx, ids)
+}
+
+// CategoryAndDescendants is a subquery selecting the ID of the category with
+// categoryID and of every category below it
+func CategoryAndDescendants(db *gorm.DB, categoryID interface{}) *gorm.DB {
+	return db.Table("categories AS descendants").
+		Select("descendants.id").
+		Joins("JOIN categories AS roots ON descendants.path LIKE roots.path || '%'").
+		Where("roots.id = ? AND roots.path <> '' AND descendants.deleted_at IS NULL", categoryID)
+}
+
+// Breadcrumbs returns the trail from the top level down to the deepest of
+// categories, or nil when there are none
+func Breadcrumbs(db *gorm.DB, categories []*models.Category) ([]models.Breadcrumb, error) {
+	var deepest *models.Category
+	for _, category := range categories {
+		if category == nil || category.Path == "" {
+			continue
+		}
+		if deepest == nil || category.Depth > deepest.Depth || (category.Depth == deepest.Depth && category.ID < deepest.ID) {
+			deepest = category
+		}
+	}
+	if deepest == nil {
+		return nil, nil
+	}
+
+	ids := pathIDs(deepest.Path)
+	var trail []models.Category
+	if err := db.Unscoped().Where("id IN ?", ids).Find(&trail).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Category, len(trail))
+	for _, category := range trail {
+		byID[category.ID] = category
+	}
+	breadcrumbs := make([]models.Breadcrumb, 0, len(ids))
+	for _, id := range ids {
+		if category, ok := byID[id]; ok {
+			breadcrumbs = append(breadcrumbs, models.Breadcrumb{ID: category.ID, Name: category.Name, Slug: category.Slug})
+		}
+	}
+	return breadcrumbs, nil
+}
+
+// pathIDs parses the category IDs of a path, top level first
+func pathIDs(path string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(part, 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// CategoryNode is a category with its children, for the category tree
+type CategoryNode struct {
+	models.Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// CategoryTree returns the top level categories with their descendants,
+// siblings in position order
+func CategoryTree(db *gorm.DB) ([]*CategoryNode, error) {
+	var categories []models.Category
+	if err := db.Order("depth ASC, position ASC, name ASC, id ASC").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	nodes := make(map[uint]*CategoryNode, len(categories))
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := &CategoryNode{Category: category, Children: []*CategoryNode{}}
+		nodes[category.ID] = node
+		if category.ParentID == nil {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return roots, nil
+}
+
+// RebuildCategoryPaths recomputes the path and depth of every category from
+// parent_id. Categories whose parent is missing are treated as top level.
+func RebuildCategoryPaths(db *gorm.DB) error {
+	var categories []models.Category
+	if err := db.Unscoped().Find(&categories).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]*models.Category, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+
+	paths := make(map[uint]string, len(categories))
+	var resolve func(category *models.Category, seen map[uint]bool) string
+	resolve = func(category *models.Category, seen map[uint]bool) string {
+		if path, ok := paths[category.ID]; ok {
+			return path
+		}
+		prefix := "/"
+		if category.ParentID != nil && !seen[category.ID] {
+			if parent, ok := byID[*category.ParentID]; ok {
+				seen[category.ID] = true
+				prefix = resolve(parent, seen)
+			}
+		}
+		if path, ok := paths[category.ID]; ok {
+			return path
+		}
+		paths[category.ID] = fmt.Sprintf("%s%d/", prefix, category.ID)
+		return paths[category.ID]
+	}
+
+	ids := make([]uint, 0, len(categories))
+	for _, category := range categories {
+		ids = append(ids, category.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		category := byID[id]
+		path := resolve(category, map[uint]bool{})
+		depth := strings.Count(path, "/") - 2
+		if category.Path == path && category.Depth == depth {
+			continue
+		}
+		if err := db.Model(&models.Category{}).Unscoped().Where("id = ?", id).
+			Updates(map[string]interface{}{"path": path, "depth": depth}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
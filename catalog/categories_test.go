@@ -0,0 +1,127 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupCategoryDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Category{}, &models.Product{}))
+	return db
+}
+
+func createCategory(t *testing.T, db *gorm.DB, name string, parent *models.Category) *models.Category {
+	category := models.Category{Name: name, Slug: name}
+	if parent != nil {
+		category.ParentID = &parent.ID
+	}
+	require.NoError(t, db.Create(&category).Error)
+	require.NoError(t, PlaceCategory(db, &category))
+	return &category
+}
+
+func reload(t *testing.T, db *gorm.DB, category *models.Category) models.Category {
+	var fresh models.Category
+	require.NoError(t, db.First(&fresh, category.ID).Error)
+	return fresh
+}
+
+func TestMoveCategoryRewritesSubtree(t *testing.T) {
+	db := setupCategoryDB(t)
+	food := createCategory(t, db, "food", nil)
+	drinks := createCategory(t, db, "drinks", nil)
+	dairy := createCategory(t, db, "dairy", food)
+	cheese := createCategory(t, db, "cheese", dairy)
+	assert.Equal(t, "/1/3/4/", cheese.Path)
+	assert.Equal(t, 2, cheese.Depth)
+	assert.Equal(t, 1, drinks.Position)
+
+	moved, err := MoveCategory(db, dairy.ID, &drinks.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/2/3/", moved.Path)
+	assert.Equal(t, 1, moved.Depth)
+	fresh := reload(t, db, cheese)
+	assert.Equal(t, "/2/3/4/", fresh.Path, "descendants move with the category")
+	assert.Equal(t, 2, fresh.Depth)
+
+	_, err = MoveCategory(db, drinks.ID, &cheese.ID, nil)
+	assert.ErrorIs(t, err, ErrCategoryCycle)
+
+	top := 0
+	moved, err = MoveCategory(db, dairy.ID, nil, &top)
+	require.NoError(t, err)
+	assert.Equal(t, "/3/", moved.Path)
+	assert.Equal(t, 0, moved.Depth)
+	assert.Equal(t, 0, moved.Position)
+	assert.Equal(t, 1, reload(t, db, food).Position)
+	assert.Equal(t, 2, reload(t, db, drinks).Position)
+	assert.Equal(t, 1, reload(t, db, cheese).Depth)
+}
+
+func TestReorderAndRemoveCategories(t *testing.T) {
+	db := setupCategoryDB(t)
+	food := createCategory(t, db, "food", nil)
+	dairy := createCategory(t, db, "dairy", food)
+	bakery := createCategory(t, db, "bakery", food)
+	milk := createCategory(t, db, "milk", dairy)
+
+	assert.ErrorIs(t, ReorderCategories(db, &food.ID, []uint{milk.ID}), ErrCategoryNotSibling)
+	require.NoError(t, ReorderCategories(db, &food.ID, []uint{bakery.ID}))
+	assert.Equal(t, 0, reload(t, db, bakery).Position)
+	assert.Equal(t, 1, reload(t, db, dairy).Position, "unlisted siblings follow")
+
+	require.NoError(t, RemoveCategory(db, dairy))
+	require.NoError(t, db.Delete(dairy).Error)
+	fresh := reload(t, db, milk)
+	assert.Equal(t, food.ID, *fresh.ParentID, "children move up to the parent")
+	assert.Equal(t, "/1/4/", fresh.Path)
+	assert.Equal(t, 1, fresh.Position)
+
+	tree, err := CategoryTree(db)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	require.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "bakery", tree[0].Children[0].Name)
+	assert.Equal(t, "milk", tree[0].Children[1].Name)
+}
+
+func TestDescendantsAndBreadcrumbs(t *testing.T) {
+	db := setupCategoryDB(t)
+	food := createCategory(t, db, "food", nil)
+	dairy := createCategory(t, db, "dairy", food)
+	cheese := createCategory(t, db, "cheese", dairy)
+	createCategory(t, db, "drinks", nil)
+
+	var ids []uint
+	require.NoError(t, db.Raw("SELECT id FROM categories WHERE id IN (?) ORDER BY id", CategoryAndDescendants(db, food.ID)).Scan(&ids).Error)
+	assert.Equal(t, []uint{food.ID, dairy.ID, cheese.ID}, ids)
+
+	breadcrumbs, err := Breadcrumbs(db, []*models.Category{food, cheese, dairy})
+	require.NoError(t, err)
+	require.Len(t, breadcrumbs, 3, "the deepest category is used")
+	assert.Equal(t, "food", breadcrumbs[0].Name)
+	assert.Equal(t, "cheese", breadcrumbs[2].Slug)
+}
+
+func TestRebuildCategoryPaths(t *testing.T) {
+	db := setupCategoryDB(t)
+	food := models.Category{Name: "food", Slug: "food"}
+	require.NoError(t, db.Create(&food).Error)
+	dairy := models.Category{Name: "dairy", Slug: "dairy", ParentID: &food.ID}
+	require.NoError(t, db.Create(&dairy).Error)
+	cheese := models.Category{Name: "cheese", Slug: "cheese", ParentID: &dairy.ID}
+	require.NoError(t, db.Create(&cheese).Error)
+
+	require.NoError(t, RebuildCategoryPaths(db))
+	fresh := reload(t, db, &cheese)
+	assert.Equal(t, "/1/2/3/", fresh.Path)
+	assert.Equal(t, 2, fresh.Depth)
+}
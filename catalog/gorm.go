@@ -0,0 +1,131 @@
+package catalog
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// sourceColumns names, per table, the columns a written row is traced back to
+// its product by, in order of preference
+type sourceColumns struct {
+	product []string // columns holding a product ID
+	variant []string // columns holding a variant ID, resolved to its product
+}
+
+var summarySources = map[string]sourceColumns{
+	"products":         {product: []string{"id"}},
+	"product_variants": {product: []string{"product_id"}, variant: []string{"id"}},
+	"product_images":   {product: []string{"product_id"}, variant: []string{"product_variant_id"}},
+	"product_ratings":  {variant: []string{"product_variant_id"}},
+	"inventory_items":  {variant: []string{"product_variant_id"}},
+}
+
+// SummaryPlugin refreshes product summaries after writes to the tables they
+// are built from, inside the write's transaction
+type SummaryPlugin struct {
+	summaries *Summaries
+}
+
+// NewSummaryPlugin creates the plugin; register it with db.Use
+func NewSummaryPlugin(summaries *Summaries) *SummaryPlugin {
+	return &SummaryPlugin{summaries: summaries}
+}
+
+func (p *SummaryPlugin) Name() string {
+	return "catalog:summaries"
+}
+
+func (p *SummaryPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().After("gorm:create").Register("catalog:refresh_create", p.refresh),
+		cb.Update().After("gorm:update").Register("catalog:refresh_update", p.refresh),
+		cb.Delete().After("gorm:delete").Register("catalog:refresh_delete", p.refresh),
+	)
+}
+
+func (p *SummaryPlugin) refresh(db *gorm.DB) {
+	sources, ok := summarySources[db.Statement.Table]
+	if !ok || db.Error != nil || db.Statement.RowsAffected == 0 {
+		return
+	}
+
+	// A new session on the same connection, so the refresh joins the write's
+	// transaction and sees its uncommitted rows
+	tx := db.Session(&gorm.Session{NewDB: true})
+	productIDs, err := affectedProducts(tx, db, sources)
+	if err == nil && len(productIDs) == 0 {
+		// Written by condition rather than by model, such as a bulk update
+		p.summaries.MarkStale()
+		return
+	}
+	if err == nil {
+		err = p.summaries.Refresh(tx, productIDs)
+	}
+	if err != nil {
+		p.summaries.MarkStale()
+		slog.WarnContext(db.Statement.Context, "failed to refresh product summaries", "component", "catalog", "table", db.Statement.Table, "error", err)
+	}
+}
+
+// affectedProducts reads the product IDs of the rows written by db from its
+// model, looking up the product of variant IDs
+func affectedProducts(tx, db *gorm.DB, sources sourceColumns) ([]uint, error) {
+	if db.Statement.Schema == nil {
+		return nil, nil
+	}
+	if productIDs := columnValues(db, sources.product); len(productIDs) > 0 {
+		return productIDs, nil
+	}
+	variantIDs := columnValues(db, sources.variant)
+	if len(variantIDs) == 0 {
+		return nil, nil
+	}
+	var productIDs []uint
+	err := tx.Unscoped().Model(&models.ProductVariant{}).Where("id IN ?", variantIDs).Distinct().Pluck("product_id", &productIDs).Error
+	return productIDs, err
+}
+
+// columnValues returns the non-zero IDs held by the first of columns that has
+// any across the written rows
+func columnValues(db *gorm.DB, columns []string) []uint {
+	for _, column := range columns {
+		field := db.Statement.Schema.LookUpField(column)
+		if field == nil {
+			continue
+		}
+		var ids []uint
+		collect := func(row reflect.Value) {
+			value, zero := field.ValueOf(db.Statement.Context, row)
+			if zero {
+				return
+			}
+			switch id := value.(type) {
+			case uint:
+				ids = append(ids, id)
+			case *uint:
+				ids = append(ids, *id)
+			}
+		}
+
+		rows := reflect.Indirect(db.Statement.ReflectValue)
+		switch rows.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rows.Len(); i++ {
+				if row := reflect.Indirect(rows.Index(i)); row.Kind() == reflect.Struct {
+					collect(row)
+				}
+			}
+		case reflect.Struct:
+			collect(rows)
+		}
+		if len(ids) > 0 {
+			return ids
+		}
+	}
+	return nil
+}
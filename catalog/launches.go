@@ -0,0 +1,66 @@
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cache"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+// Published limits a query to rows of table, products or product_summaries,
+// whose launch window contains now
+func Published(table string, now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("("+table+".publish_at IS NULL OR "+table+".publish_at <= ?)", now).
+			Where("("+table+".unpublish_at IS NULL OR "+table+".unpublish_at > ?)", now)
+	}
+}
+
+// Launches invalidates the catalog cache when a product's launch window
+// opens or closes, since cached listings were built before the change
+type Launches struct {
+	db      *gorm.DB
+	catalog *cache.Catalog
+	checked time.Time
+	now     func() time.Time
+}
+
+// NewLaunches creates the launch watcher
+func NewLaunches(db *gorm.DB, catalog *cache.Catalog) *Launches {
+	return &Launches{db: db, catalog: catalog, checked: time.Now(), now: time.Now}
+}
+
+// Check invalidates the catalog cache when a launch window opened or closed
+// since the last check
+func (l *Launches) Check(ctx context.Context) error {
+	now := l.now()
+	var count int64
+	err := l.db.WithContext(ctx).Model(&models.Product{}).
+		Where("(publish_at > ? AND publish_at <= ?) OR (unpublish_at > ? AND unpublish_at <= ?)", l.checked, now, l.checked, now).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		if err := l.catalog.Invalidate(ctx); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "product launch windows changed", "component", "catalog", "products", count)
+	}
+	l.checked = now
+	return nil
+}
+
+// StartWatcher checks for opened and closed launch windows each interval
+// until ctx is canceled
+func (l *Launches) StartWatcher(ctx context.Context, interval time.Duration) {
+	for worker.Sleep(ctx, interval) {
+		if err := l.Check(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to check product launches", "component", "catalog", "error", err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package catalog
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishedScope(t *testing.T) {
+	db := setupCategoryDB(t)
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+	products := []models.Product{
+		{Name: "always"},
+		{Name: "launched", PublishAt: &earlier},
+		{Name: "upcoming", PublishAt: &later},
+		{Name: "withdrawn", UnpublishAt: &earlier},
+		{Name: "ending", PublishAt: &earlier, UnpublishAt: &later},
+	}
+	require.NoError(t, db.Create(&products).Error)
+
+	var names []string
+	require.NoError(t, db.Model(&models.Product{}).Scopes(Published("products", now)).Order("id").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"always", "launched", "ending"}, names)
+
+	for _, product := range products {
+		assert.Equal(t, slices.Contains(names, product.Name), product.IsPublished(now), product.Name)
+	}
+}
+
+func TestLaunchesCheckAdvances(t *testing.T) {
+	db := setupCategoryDB(t)
+	start := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	launch := start.Add(30 * time.Second)
+	require.NoError(t, db.Create(&models.Product{Name: "upcoming", PublishAt: &launch}).Error)
+
+	now := start.Add(time.Minute)
+	launches := &Launches{db: db, checked: start, now: func() time.Time { return now }}
+	require.NoError(t, launches.Check(context.Background()))
+	assert.Equal(t, now, launches.checked, "the next check starts where this one ended")
+}
@@ -0,0 +1,187 @@
+// Package catalog maintains the product summary projection behind the
+// storefront listing. Summaries are refreshed in the same transaction as the
+// writes that change them; writes that cannot be traced to a product mark the
+// projection stale and a background worker rebuilds it.
+package catalog
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const refreshBatchSize = 500
+
+// Summaries refreshes rows of the product_summaries table
+type Summaries struct {
+	db    *gorm.DB
+	stale atomic.Bool
+}
+
+// NewSummaries creates the summary refresher
+func NewSummaries(db *gorm.DB) *Summaries {
+	return &Summaries{db: db}
+}
+
+// MarkStale schedules a full rebuild on the worker's next tick
+func (s *Summaries) MarkStale() {
+	s.stale.Store(true)
+}
+
+// Refresh recomputes the summaries of productIDs using db, which may be a
+// transaction. Summaries of deleted products are removed.
+func (s *Summaries) Refresh(db *gorm.DB, productIDs []uint) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	var products []models.Product
+	if err := db.Unscoped().Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return err
+	}
+	var variants []models.ProductVariant
+	if err := db.Where("product_id IN ? AND is_active = ?", productIDs, true).Find(&variants).Error; err != nil {
+		return err
+	}
+	var images []models.ProductImage
+	if err := db.Where("product_id IN ?", productIDs).Order("is_primary DESC, id ASC").Find(&images).Error; err != nil {
+		return err
+	}
+	var ratings []struct {
+		ProductID    uint
+		WeightedSum  float64
+		TotalReviews int
+	}
+	err := db.Table("product_ratings").
+		Select("product_variants.product_id, SUM(product_ratings.average_rating * product_ratings.total_reviews) AS weighted_sum, SUM(product_ratings.total_reviews) AS total_reviews").
+		Joins("JOIN product_variants ON product_variants.id = product_ratings.product_variant_id").
+		Where("product_variants.product_id IN ? AND product_variants.deleted_at IS NULL AND product_ratings.deleted_at IS NULL", productIDs).
+		Group("product_variants.product_id").
+		Scan(&ratings).Error
+	if err != nil {
+		return err
+	}
+
+	summaries := make(map[uint]*models.ProductSummary, len(products))
+	var removed []uint
+	for _, product := range products {
+		if product.DeletedAt.Valid {
+			removed = append(removed, product.ID)
+			continue
+		}
+		summaries[product.ID] = &models.ProductSummary{
+			ProductID:   product.ID,
+			Name:        product.Name,
+			BrandID:     product.BrandID,
+			IsActive:    product.IsActive,
+			PublishAt:   product.PublishAt,
+			UnpublishAt: product.UnpublishAt,
+			IsFeatured:  product.IsFeatured,
+			IsVAT:       product.IsVAT,
+			UpdatedAt:   time.Now(),
+		}
+	}
+	for _, id := range productIDs {
+		if _, ok := summaries[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+
+	for _, variant := range variants {
+		summary, ok := summaries[variant.ProductID]
+		if !ok {
+			continue
+		}
+		if summary.VariantCount == 0 || variant.BasePrice < summary.MinPrice {
+			summary.MinPrice = variant.BasePrice
+		}
+		if variant.BasePrice > summary.MaxPrice {
+			summary.MaxPrice = variant.BasePrice
+		}
+		if summary.VariantCount == 0 || variant.B2BPrice < summary.MinB2BPrice {
+			summary.MinB2BPrice = variant.B2BPrice
+		}
+		if variant.B2BPrice > summary.MaxB2BPrice {
+			summary.MaxB2BPrice = variant.B2BPrice
+		}
+		summary.TotalStock += variant.QuantityInStock
+		summary.InStock = summary.TotalStock > 0
+		summary.VariantCount++
+	}
+	for _, image := range images {
+		if summary, ok := summaries[*image.ProductID]; ok && summary.PrimaryImageURL == "" {
+			summary.PrimaryImageURL = image.URL
+		}
+	}
+	for _, rating := range ratings {
+		if summary, ok := summaries[rating.ProductID]; ok && rating.TotalReviews > 0 {
+			summary.AverageRating = rating.WeightedSum / float64(rating.TotalReviews)
+			summary.TotalReviews = rating.TotalReviews
+		}
+	}
+
+	if len(removed) > 0 {
+		if err := db.Where("product_id IN ?", removed).Delete(&models.ProductSummary{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(summaries) == 0 {
+		return nil
+	}
+	rows := make([]models.ProductSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		rows = append(rows, *summary)
+	}
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rows).Error
+}
+
+// RefreshAll rebuilds every summary in batches and drops the summaries of
+// products that no longer exist
+func (s *Summaries) RefreshAll(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+	var lastID uint
+	for {
+		var ids []uint
+		if err := db.Unscoped().Model(&models.Product{}).Where("id > ?", lastID).Order("id").Limit(refreshBatchSize).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return s.Refresh(tx, ids)
+		}); err != nil {
+			return err
+		}
+		lastID = ids[len(ids)-1]
+	}
+	return db.Where("product_id NOT IN (?)", db.Unscoped().Model(&models.Product{}).Select("id")).Delete(&models.ProductSummary{}).Error
+}
+
+// StartRefresher rebuilds every summary at startup, which also backfills the
+// table after it is created, then again each interval the projection is stale.
+// It returns when ctx is canceled.
+func (s *Summaries) StartRefresher(ctx context.Context, interval time.Duration) {
+	s.rebuild(ctx)
+	for worker.Sleep(ctx, interval) {
+		if s.stale.Swap(false) {
+			s.rebuild(ctx)
+		}
+	}
+}
+
+func (s *Summaries) rebuild(ctx context.Context) {
+	start := time.Now()
+	if err := s.RefreshAll(ctx); err != nil {
+		s.MarkStale()
+		slog.Error("failed to rebuild product summaries", "component", "catalog", "error", err)
+		return
+	}
+	slog.Info("rebuilt product summaries", "component", "catalog", "duration_ms", time.Since(start).Milliseconds())
+}
@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupDB(t *testing.T) (*gorm.DB, *Summaries) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Product{}, &models.ProductVariant{}, &models.ProductImage{},
+		&models.ProductRating{}, &models.ProductSummary{},
+	))
+	summaries := NewSummaries(db)
+	require.NoError(t, db.Use(NewSummaryPlugin(summaries)))
+	return db, summaries
+}
+
+func summaryOf(t *testing.T, db *gorm.DB, productID uint) models.ProductSummary {
+	var summary models.ProductSummary
+	require.NoError(t, db.First(&summary, "product_id = ?", productID).Error)
+	return summary
+}
+
+func TestSummaryRefreshedOnWrite(t *testing.T) {
+	db, summaries := setupDB(t)
+
+	product := models.Product{Name: "Coffee", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	assert.Equal(t, "Coffee", summaryOf(t, db, product.ID).Name)
+
+	small := models.ProductVariant{ProductID: product.ID, Name: "250g", SKU: "C-250", BasePrice: 4, B2BPrice: 3, IsActive: true}
+	large := models.ProductVariant{ProductID: product.ID, Name: "1kg", SKU: "C-1000", BasePrice: 12, B2BPrice: 10, IsActive: true, QuantityInStock: 5}
+	require.NoError(t, db.Create(&[]*models.ProductVariant{&small, &large}).Error)
+	require.NoError(t, db.Create(&models.ProductImage{ProductID: &product.ID, URL: "side"}).Error)
+	require.NoError(t, db.Create(&models.ProductImage{ProductID: &product.ID, URL: "front", IsPrimary: true}).Error)
+	require.NoError(t, db.Create(&models.ProductRating{ProductVariantID: small.ID, AverageRating: 4, TotalReviews: 1}).Error)
+	require.NoError(t, db.Create(&models.ProductRating{ProductVariantID: large.ID, AverageRating: 5, TotalReviews: 3}).Error)
+
+	summary := summaryOf(t, db, product.ID)
+	assert.Equal(t, 4.0, summary.MinPrice)
+	assert.Equal(t, 12.0, summary.MaxPrice)
+	assert.Equal(t, 3.0, summary.MinB2BPrice)
+	assert.Equal(t, "front", summary.PrimaryImageURL)
+	assert.InDelta(t, 4.75, summary.AverageRating, 0.001)
+	assert.Equal(t, 4, summary.TotalReviews)
+	assert.Equal(t, 5, summary.TotalStock)
+	assert.True(t, summary.InStock)
+	assert.Equal(t, 2, summary.VariantCount)
+
+	// Stock synced through a model carrying only the variant ID
+	require.NoError(t, db.Model(&models.ProductVariant{Model: gorm.Model{ID: large.ID}}).Update("quantity_in_stock", 0).Error)
+	assert.False(t, summaryOf(t, db, product.ID).InStock)
+
+	// Writes made inside a transaction are summarized before it commits
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&small).Update("base_price", 2).Error
+	}))
+	assert.Equal(t, 2.0, summaryOf(t, db, product.ID).MinPrice)
+
+	// Bulk writes by condition leave the refresh to the worker
+	require.NoError(t, db.Model(&models.ProductVariant{}).Where("product_id = ?", product.ID).Update("is_active", false).Error)
+	assert.Equal(t, 2, summaryOf(t, db, product.ID).VariantCount)
+	assert.True(t, summaries.stale.Load())
+	require.NoError(t, summaries.RefreshAll(context.Background()))
+	assert.Equal(t, 0, summaryOf(t, db, product.ID).VariantCount)
+
+	require.NoError(t, db.Delete(&product).Error)
+	var count int64
+	db.Model(&models.ProductSummary{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestRefreshAllBackfills(t *testing.T) {
+	db, summaries := setupDB(t)
+	products := []models.Product{{Name: "A", IsActive: true}, {Name: "B", IsActive: true}}
+	require.NoError(t, db.Session(&gorm.Session{SkipHooks: true}).Create(&products).Error)
+	require.NoError(t, db.Exec("DELETE FROM product_summaries").Error)
+	require.NoError(t, db.Create(&models.ProductSummary{ProductID: 99, Name: "gone"}).Error)
+
+	require.NoError(t, summaries.RefreshAll(context.Background()))
+
+	var rows []models.ProductSummary
+	require.NoError(t, db.Order("product_id").Find(&rows).Error)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "A", rows[0].Name)
+	assert.Equal(t, "B", rows[1].Name)
+	assert.True(t, rows[1].IsActive)
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv" // Optional: for loading .env files
 )
@@ -15,6 +16,15 @@ type RevolutConfig struct {
 	WebhookSecret string
 	BaseURL       string // Different for sandbox and production
 	IsSandbox     bool
+
+	PreviousWebhookSecret string // REVOLUT_WEBHOOK_SECRET_PREVIOUS, also accepted while the webhook secret is rotated
+
+	B2BManualCapture       bool // REVOLUT_B2B_MANUAL_CAPTURE, only authorize payments of company and wholesaler orders, to capture once the order is confirmed
+	AuthorizationVoidHours int  // REVOLUT_AUTHORIZATION_VOID_HOURS, how long a manual capture authorization may stay uncaptured before it is voided; 0 never voids
+	MaxPaymentAttempts     int  // REVOLUT_MAX_PAYMENT_ATTEMPTS, payments an order may be given, the first included, before retries are refused
+
+	ReconciliationHour          int // REVOLUT_RECONCILIATION_HOUR, UTC hour the nightly reconciliation with Revolut runs at; -1 disables it
+	ReconciliationLookbackHours int // REVOLUT_RECONCILIATION_LOOKBACK_HOURS, how far back reconciliation checks Revolut orders
 }
 
 // EmailConfig holds email service configuration
@@ -22,6 +32,28 @@ type EmailConfig struct {
 	Provider    string // "outlook"
 	SenderEmail string // enquirees@algeriamarket.co.uk
 	SenderName  string // Algeria Market
+	// WebhookSecret signs bounce and complaint webhooks (EMAIL_WEBHOOK_SECRET)
+	WebhookSecret string
+	// PreviousWebhookSecret is also accepted while WebhookSecret is rotated
+	// (EMAIL_WEBHOOK_SECRET_PREVIOUS)
+	PreviousWebhookSecret string
+	// InboundSecret signs inbound support email webhooks (EMAIL_INBOUND_SECRET)
+	InboundSecret string
+	// PreviousInboundSecret is also accepted while InboundSecret is rotated
+	// (EMAIL_INBOUND_SECRET_PREVIOUS)
+	PreviousInboundSecret string
+	// DefaultLocale is the locale of the emails to recipients without a
+	// locale, and of the templates a recipient's locale has no translation
+	// of (EMAIL_DEFAULT_LOCALE: en, fr or ar)
+	DefaultLocale string
+}
+
+// EmailWorkerConfig holds email queue processing configuration
+type EmailWorkerConfig struct {
+	Concurrency   int // EMAIL_WORKER_CONCURRENCY, parallel sends
+	BatchSize     int // EMAIL_BATCH_SIZE, emails taken from the queue per dequeue
+	RatePerMinute int // EMAIL_RATE_PER_MINUTE, 0 uses the provider's limit
+	DailyLimit    int // EMAIL_DAILY_LIMIT, 0 uses the provider's limit
 }
 
 // OutlookConfig holds Microsoft Graph API configuration for Outlook Business
@@ -33,6 +65,17 @@ type OutlookConfig struct {
 	SenderName   string // Algeria Market
 }
 
+// SMTPConfig holds the SMTP server used as a fallback email provider
+type SMTPConfig struct {
+	Host        string // SMTP_HOST, empty disables the SMTP provider
+	Port        int    // SMTP_PORT
+	Username    string // SMTP_USERNAME
+	Password    string // SMTP_PASSWORD
+	TLSMode     string // SMTP_TLS: "starttls", "tls" (implicit, usually port 465) or "none"
+	SenderEmail string // SMTP_SENDER_EMAIL
+	SenderName  string // SMTP_SENDER_NAME
+}
+
 // RedisConfig holds Upstash Redis configuration
 type RedisConfig struct {
 	UpstashURL   string // UPSTASH_REDIS_REST_URL
@@ -40,16 +83,298 @@ type RedisConfig struct {
 	PoolSize     int    // Default: 10
 }
 
+// CartConfig holds cart behaviour configuration
+type CartConfig struct {
+	ItemExpiryHours int // CART_ITEM_EXPIRY_HOURS, 0 disables expiry
+}
+
+// TaxConfig holds VAT calculation configuration
+type TaxConfig struct {
+	DefaultCountry   string  // TAX_DEFAULT_COUNTRY, ISO country code of the store, e.g. "GB"
+	DefaultRate      float64 // TAX_DEFAULT_RATE, VAT percentage used when the store country has no configured rate
+	PricesIncludeVAT bool    // TAX_PRICES_INCLUDE_VAT, catalogue prices are gross rather than net
+}
+
+// FulfillmentConfig holds warehouse allocation configuration
+type FulfillmentConfig struct {
+	Strategy       string // FULFILLMENT_STRATEGY, nearest, most_stock, fifo or fefo
+	SplitShipments bool   // FULFILLMENT_SPLIT_SHIPMENTS, allow an order to ship from several warehouses
+}
+
+// DeliveryConfig holds scheduled delivery slot configuration
+type DeliveryConfig struct {
+	Timezone    string // DELIVERY_TIMEZONE, IANA zone delivery windows are in, e.g. "Europe/London"
+	CutoffHours int    // DELIVERY_SLOT_CUTOFF_HOURS, how long before a slot starts it stops taking orders
+	BookingDays int    // DELIVERY_BOOKING_DAYS, how many days ahead slots can be booked
+}
+
+// CampaignConfig holds marketing email campaign configuration
+type CampaignConfig struct {
+	TrackingURL     string // CAMPAIGN_TRACKING_URL, public API base URL used in open, click and unsubscribe links
+	TrackingSecret  string // CAMPAIGN_TRACKING_SECRET, signs tracking links; defaults to JWT_SECRET
+	BatchSize       int    // CAMPAIGN_BATCH_SIZE, emails queued per campaign per batch
+	BatchInterval   int    // CAMPAIGN_BATCH_INTERVAL_SECONDS, pause between batches
+	AttributionDays int    // CAMPAIGN_ATTRIBUTION_DAYS, how long after sending an order counts as a conversion
+}
+
+// LoyaltyConfig holds loyalty points configuration
+type LoyaltyConfig struct {
+	PointsPerPound   float64 // LOYALTY_POINTS_PER_POUND, points earned per pound spent on goods; 0 stops earning
+	PointValue       float64 // LOYALTY_POINT_VALUE, discount in pounds a point is worth at checkout
+	MaxRedeemPercent float64 // LOYALTY_MAX_REDEEM_PERCENT, largest share of an order's goods that points can pay for
+	ExpiryDays       int     // LOYALTY_EXPIRY_DAYS, how long points last after they are earned
+}
+
+// ReferralConfig holds referral program configuration
+type ReferralConfig struct {
+	RewardType       string  // REFERRAL_REWARD_TYPE, "points" or "coupon"
+	ReferrerReward   float64 // REFERRAL_REFERRER_REWARD, points or coupon pounds for the referrer; 0 for none
+	RefereeReward    float64 // REFERRAL_REFEREE_REWARD, points or coupon pounds for the new customer; 0 for none
+	MinOrderAmount   float64 // REFERRAL_MIN_ORDER_AMOUNT, goods a delivered order must be worth to qualify
+	CouponExpiryDays int     // REFERRAL_COUPON_EXPIRY_DAYS, how long reward coupons can be used
+	ShareURL         string  // REFERRAL_SHARE_URL, storefront sign-up page the code is appended to
+}
+
+// GiftCardConfig holds gift card configuration
+type GiftCardConfig struct {
+	ExpiryDays int     // GIFT_CARD_EXPIRY_DAYS, how long a card can be used after it is activated; 0 for no expiry
+	MaxAmount  float64 // GIFT_CARD_MAX_AMOUNT, largest value an admin can issue a card for
+	RedeemURL  string  // GIFT_CARD_REDEEM_URL, storefront page linked from gift card emails
+}
+
+// CompanyConfig holds company account configuration
+type CompanyConfig struct {
+	InvitationExpiryHours int    // COMPANY_INVITATION_EXPIRY_HOURS, how long a buyer invitation can be accepted
+	InvitationURL         string // COMPANY_INVITATION_URL, storefront page invitation tokens are appended to
+	ApprovalsURL          string // COMPANY_APPROVALS_URL, storefront page listing orders waiting for approval
+}
+
+// FeedConfig holds sitemap and product feed configuration
+type FeedConfig struct {
+	SiteURL         string // FEED_SITE_URL, storefront base URL product, category and brand links point to
+	APIURL          string // FEED_API_URL, public API base URL image links point to
+	Currency        string // FEED_CURRENCY, ISO currency code of feed prices
+	IntervalMinutes int    // FEED_INTERVAL_MINUTES, how often the feeds are regenerated
+	UploadToGCS     bool   // FEED_UPLOAD_TO_GCS, also publish each generated feed to the GCS bucket under feeds/
+}
+
+// StorageConfig holds product image storage configuration
+type StorageConfig struct {
+	Backend        string // STORAGE_BACKEND, appwrite or gcs
+	SignedURLs     bool   // STORAGE_SIGNED_URLS, serve image URLs that expire
+	URLTTLMinutes  int    // STORAGE_URL_TTL_MINUTES, how long a signed URL works for at least
+	SigningKey     string // STORAGE_SIGNING_KEY, signs Appwrite preview URLs; defaults to JWT_SECRET
+	MaxImageSizeMB int    // STORAGE_MAX_IMAGE_SIZE_MB, largest image accepted for upload
+}
+
+// FileCleanupConfig holds orphaned file cleanup configuration
+type FileCleanupConfig struct {
+	IntervalHours int // FILE_CLEANUP_INTERVAL_HOURS, how often unreferenced files are deleted; 0 disables the cleanup
+	GraceHours    int // FILE_CLEANUP_GRACE_HOURS, how long a new upload is kept before something must reference it
+	RetentionDays int // FILE_CLEANUP_RETENTION_DAYS, how long the files of soft-deleted rows are kept, so the rows can be restored
+}
+
+// TrashConfig holds how long soft-deleted products and support records can
+// be restored before they are purged
+type TrashConfig struct {
+	RetentionDays      int // TRASH_RETENTION_DAYS, how long deleted rows are kept
+	PurgeIntervalHours int // TRASH_PURGE_INTERVAL_HOURS, how often rows past retention are purged; 0 disables the purge
+}
+
+// FraudConfig holds checkout fraud scoring configuration. A payment is scored
+// when it is created; each rule that trips adds its score.
+type FraudConfig struct {
+	Enabled              bool // FRAUD_CHECKS_ENABLED, score payments at creation
+	ReviewScore          int  // FRAUD_REVIEW_SCORE, score from which a payment is held for manual review instead of captured
+	BlockScore           int  // FRAUD_BLOCK_SCORE, score from which a payment is refused; 0 never refuses
+	MaxOrdersPerEmail    int  // FRAUD_MAX_ORDERS_PER_EMAIL, payments an email may start in an hour before its velocity rule trips
+	MaxOrdersPerIP       int  // FRAUD_MAX_ORDERS_PER_IP, payments an IP may start in an hour before its velocity rule trips
+	MaxOrdersPerCard     int  // FRAUD_MAX_ORDERS_PER_CARD, payments a card may start in an hour before its velocity rule trips
+	VelocityScore        int  // FRAUD_VELOCITY_SCORE, score added by each velocity rule that trips
+	CountryMismatchScore int  // FRAUD_COUNTRY_MISMATCH_SCORE, score added when the billing and shipping countries differ
+}
+
+// RatingsConfig holds when product ratings are reconciled with their reviews
+type RatingsConfig struct {
+	ReconciliationHour int // RATINGS_RECONCILIATION_HOUR, UTC hour the nightly rebuild of ratings that drifted from their reviews runs at; -1 disables it
+}
+
+// ModerationConfig holds user strike configuration. When a user's active
+// strikes reach a threshold the matching capability is restricted
+// automatically; a threshold of 0 never restricts.
+type ModerationConfig struct {
+	StrikeExpiryDays int // MODERATION_STRIKE_EXPIRY_DAYS, how long a strike counts; 0 for ever
+	ReviewStrikes    int // MODERATION_REVIEW_STRIKES, strikes from which a user can no longer write reviews
+	MessageStrikes   int // MODERATION_MESSAGE_STRIKES, strikes from which a user can no longer reply to reviews and responses
+	BanStrikes       int // MODERATION_BAN_STRIKES, strikes from which a user can no longer log in
+	RestrictionDays  int // MODERATION_RESTRICTION_DAYS, how long automatic review and message restrictions last; 0 until lifted
+	BanDays          int // MODERATION_BAN_DAYS, how long automatic bans last; 0 until lifted
+}
+
+// SubscriptionConfig holds recurring order configuration
+type SubscriptionConfig struct {
+	ReminderDays  int // SUBSCRIPTION_REMINDER_DAYS, how many days before an order the customer is reminded of it; 0 sends no reminders
+	MaxFailedRuns int // SUBSCRIPTION_MAX_FAILED_RUNS, runs in a row that place no order before the subscription is paused; 0 never pauses
+}
+
+// GeocodingConfig holds address geocoding configuration
+type GeocodingConfig struct {
+	Provider  string // GEOCODING_PROVIDER, nominatim, or empty to turn geocoding off
+	URL       string // GEOCODING_URL, base URL of the provider's API
+	UserAgent string // GEOCODING_USER_AGENT, identifies the store to the provider
+}
+
+// LockoutConfig holds login brute-force protection configuration
+type LockoutConfig struct {
+	MaxAccountAttempts int // LOCKOUT_MAX_ATTEMPTS, failed logins before an account is locked
+	MaxIPAttempts      int // LOCKOUT_MAX_IP_ATTEMPTS, failed logins before an IP is throttled
+	WindowMinutes      int // LOCKOUT_WINDOW_MINUTES, period failed attempts are counted over
+	LockMinutes        int // LOCKOUT_DURATION_MINUTES, how long a lock lasts
+}
+
+// VerificationConfig holds email verification configuration
+type VerificationConfig struct {
+	Secret      string // EMAIL_VERIFICATION_SECRET, signs activation links; defaults to JWT_SECRET
+	ExpiryHours int    // EMAIL_VERIFICATION_EXPIRY_HOURS, how long an activation link works for
+	Required    bool   // EMAIL_VERIFICATION_REQUIRED, only verified accounts can place orders and write reviews
+}
+
+// SMSConfig holds text message and phone verification configuration
+type SMSConfig struct {
+	Provider    string // SMS_PROVIDER, twilio or mock; mock only logs messages
+	TemplateDir string // SMS_TEMPLATE_DIR, directory of the message templates, with a subdirectory per locale
+
+	TwilioAccountSID string // TWILIO_ACCOUNT_SID
+	TwilioAuthToken  string // TWILIO_AUTH_TOKEN
+	TwilioFrom       string // TWILIO_FROM, sender number, or messaging service SID starting with MG
+
+	TwilioWhatsAppFrom string // TWILIO_WHATSAPP_FROM, WhatsApp sender number; WhatsApp is off when empty
+	StatusCallbackURL  string // SMS_STATUS_CALLBACK_URL, public URL of the delivery report webhook, as registered with Twilio
+
+	OTPExpiryMinutes int // PHONE_OTP_EXPIRY_MINUTES, how long a verification code works for
+	OTPMaxAttempts   int // PHONE_OTP_MAX_ATTEMPTS, wrong guesses before a code stops working
+	OTPResendSeconds int // PHONE_OTP_RESEND_SECONDS, wait before another code can be sent to a number
+	OTPPerHour       int // PHONE_OTP_PER_HOUR, codes sent to a number or user an hour
+
+	RequiredAtCheckout bool // PHONE_VERIFICATION_REQUIRED, only accounts with a verified phone number can place orders
+}
+
+// OAuthProviderConfig holds the client of a social sign-in provider. The
+// provider is off when ClientID is empty.
+type OAuthProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	// Apple client secrets are JWTs signed with a private key of the team
+	TeamID     string
+	KeyID      string
+	PrivateKey string // PEM encoded
+}
+
+// OAuthConfig holds social sign-in configuration
+type OAuthConfig struct {
+	CallbackURL string // OAUTH_CALLBACK_URL, base URL providers redirect back to; /{provider}/callback is appended
+	RedirectURL string // OAUTH_REDIRECT_URL, storefront page signed-in users are sent to, with their tokens in the URL fragment
+	StateSecret string // OAUTH_STATE_SECRET, signs the state of sign-ins in progress; defaults to JWT_SECRET
+
+	Google OAuthProviderConfig // GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET
+	Apple  OAuthProviderConfig // APPLE_CLIENT_ID (the services ID), APPLE_TEAM_ID, APPLE_KEY_ID, APPLE_PRIVATE_KEY
+}
+
+// MetricsConfig holds Prometheus endpoint configuration
+type MetricsConfig struct {
+	Token string // METRICS_TOKEN, bearer token required to scrape /metrics; open when empty
+}
+
+// DatabaseConfig holds database routing configuration. The primary
+// connection is configured by the PG* or DB_* variables read in ConnectDB.
+type DatabaseConfig struct {
+	ReplicaDSNs []string // DB_REPLICA_DSNS, comma-separated postgres:// URLs of read replicas
+}
+
+// CacheConfig holds Redis read cache configuration
+type CacheConfig struct {
+	CatalogEnabled    bool // CATALOG_CACHE_ENABLED, caches product listing and detail reads
+	CatalogTTLSeconds int  // CATALOG_CACHE_TTL_SECONDS
+
+	AvailabilityTTLSeconds int // AVAILABILITY_CACHE_TTL_SECONDS, how long product availability is cached; 0 disables it
+
+	AnalyticsTTLSeconds int // ANALYTICS_CACHE_TTL_SECONDS, how long sales reports are cached; 0 disables it
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Endpoint    string  // OTEL_EXPORTER_OTLP_ENDPOINT, OTLP/HTTP collector URL; tracing is off when empty
+	ServiceName string  // OTEL_SERVICE_NAME
+	SampleRatio float64 // OTEL_TRACES_SAMPLE_RATIO, fraction of new traces recorded, 0 to 1
+}
+
+// LogConfig holds structured logging configuration
+type LogConfig struct {
+	Level  string // LOG_LEVEL, debug, info, warn or error
+	Format string // LOG_FORMAT, json or text
+}
+
+// GRPCConfig holds the internal gRPC API used by the warehouse management
+// system and ERP. Clients must present a certificate signed by the client CA.
+type GRPCConfig struct {
+	Port         string // GRPC_PORT, the gRPC API is off when empty
+	CertFile     string // GRPC_TLS_CERT_FILE, the server certificate
+	KeyFile      string // GRPC_TLS_KEY_FILE, the server certificate's private key
+	ClientCAFile string // GRPC_TLS_CLIENT_CA_FILE, the CA that signs client certificates
+}
+
+// MaintenanceConfig holds the maintenance mode forced at startup. Admins can
+// also switch it on, or schedule it, in the settings.
+type MaintenanceConfig struct {
+	Enabled           bool // MAINTENANCE_MODE, reject writes until the app restarts without it
+	RetryAfterSeconds int  // MAINTENANCE_RETRY_AFTER_SECONDS, Retry-After sent when no end time is scheduled
+}
+
+// APIConfig holds how API versions are served
+type APIConfig struct {
+	V1Sunset string // API_V1_SUNSET, RFC 3339 time after which /api/v1 may be removed; when set, v1 responses carry Deprecation and Sunset headers
+}
+
+// CORSConfig holds which browser origins may call the API. The defaults
+// depend on GIN_MODE: the storefront in production, the local dev servers
+// otherwise.
+type CORSConfig struct {
+	AllowedOrigins   []string // CORS_ALLOWED_ORIGINS, comma-separated origins such as https://algeriamarket.co.uk; * allows any origin, without credentials
+	OriginPatterns   []string // CORS_ORIGIN_PATTERNS, comma-separated regular expressions matched against the whole origin, e.g. for preview deployments
+	AllowedMethods   []string // CORS_ALLOWED_METHODS
+	AllowedHeaders   []string // CORS_ALLOWED_HEADERS, allowed on top of the request ID, tracing, idempotency and refresh token headers the API reads
+	AllowCredentials bool     // CORS_ALLOW_CREDENTIALS, let browsers send credentials such as cookies
+	MaxAgeHours      int      // CORS_MAX_AGE_HOURS, how long browsers may cache preflight responses
+}
+
+// RateLimitConfig holds request rate limits, in requests per minute. Each
+// limit is also the burst a client can send at once.
+type RateLimitConfig struct {
+	Enabled          bool // RATE_LIMIT_ENABLED, set to false to turn rate limiting off
+	IPPerMinute      int  // RATE_LIMIT_IP_PER_MINUTE, anonymous requests per IP address
+	UserPerMinute    int  // RATE_LIMIT_USER_PER_MINUTE, authenticated requests per user
+	AuthPerMinute    int  // RATE_LIMIT_AUTH_PER_MINUTE, login, registration and password reset requests
+	ReviewPerMinute  int  // RATE_LIMIT_REVIEW_PER_MINUTE, reviews created
+	ContactPerMinute int  // RATE_LIMIT_CONTACT_PER_MINUTE, contact form submissions
+}
+
 // AppConfig holds all application configurations
 type AppConfig struct {
 	Port string
+	// ShutdownTimeoutSeconds bounds how long in-flight requests and background
+	// workers are given to finish on SIGTERM (SHUTDOWN_TIMEOUT_SECONDS)
+	ShutdownTimeoutSeconds int
 	// Google Cloud Storage
 	GCSCredentialsFile string
 	GCSProjectID       string
 	GCSBucketName      string
 	DatabaseDSN        string
 	// Gin
-	GinMode string
+	GinMode string // GIN_MODE, "release" in production
+	// Auth
+	JWTSecret         string // JWT_SECRET, signs access tokens
+	PreviousJWTSecret string // JWT_SECRET_PREVIOUS, also accepted while JWT_SECRET is rotated
+	EncryptionKey     string // ENCRYPTION_KEY, encrypts TOTP secrets; JWT_SECRET is used when empty
 	// Database
 	DBHost     string
 	DBUser     string
@@ -64,9 +389,43 @@ type AppConfig struct {
 	// Revolut configuration
 	Revolut RevolutConfig
 	// Email configuration
-	Email   EmailConfig
-	Outlook OutlookConfig
-	Redis   RedisConfig
+	Email        EmailConfig
+	EmailWorker  EmailWorkerConfig
+	Outlook      OutlookConfig
+	SMTP         SMTPConfig
+	Redis        RedisConfig
+	Cart         CartConfig
+	Tax          TaxConfig
+	Fulfillment  FulfillmentConfig
+	Geocoding    GeocodingConfig
+	Delivery     DeliveryConfig
+	Campaign     CampaignConfig
+	Loyalty      LoyaltyConfig
+	Referral     ReferralConfig
+	GiftCard     GiftCardConfig
+	Company      CompanyConfig
+	Feed         FeedConfig
+	Storage      StorageConfig
+	FileCleanup  FileCleanupConfig
+	Trash        TrashConfig
+	Fraud        FraudConfig
+	Moderation   ModerationConfig
+	Ratings      RatingsConfig
+	Subscription SubscriptionConfig
+	Lockout      LockoutConfig
+	Verification VerificationConfig
+	OAuth        OAuthConfig
+	SMS          SMSConfig
+	RateLimit    RateLimitConfig
+	CORS         CORSConfig
+	API          APIConfig
+	Maintenance  MaintenanceConfig
+	GRPC         GRPCConfig
+	Log          LogConfig
+	Metrics      MetricsConfig
+	Tracing      TracingConfig
+	Cache        CacheConfig
+	Database     DatabaseConfig
 }
 
 // LoadConfig loads configuration from environment variables
@@ -86,6 +445,13 @@ func LoadConfig() (*AppConfig, error) {
 		baseURL = "https://merchant.revolut.com"
 	}
 
+	// Browsers may call the API from the storefront in production and from the
+	// local dev servers otherwise
+	corsOrigins := []string{"http://localhost:5173", "http://127.0.0.1:5173"}
+	if getEnv("GIN_MODE", "debug") == "release" {
+		corsOrigins = []string{"https://algeriamarket.co.uk", "https://www.algeriamarket.co.uk"}
+	}
+
 	cfg := &AppConfig{
 		Port:               getEnv("PORT", "8080"),
 		GCSCredentialsFile: getEnv("GCS_CREDENTIALS_FILE", ""), // Empty means use ADC
@@ -93,6 +459,9 @@ func LoadConfig() (*AppConfig, error) {
 		GCSBucketName:      getEnv("GCS_BUCKET_NAME", ""),
 		DatabaseDSN:        getEnv("DATABASE_DSN", "files.db"), // Default to SQLite
 		GinMode:            getEnv("GIN_MODE", "debug"),        // "release" for production
+		JWTSecret:          getEnv("JWT_SECRET", ""),
+		PreviousJWTSecret:  getEnv("JWT_SECRET_PREVIOUS", ""),
+		EncryptionKey:      getEnv("ENCRYPTION_KEY", ""),
 		DBHost:             getEnv("DB_HOST", "localhost"),
 		DBUser:             getEnv("DB_USER", "admin"),
 		DBPassword:         getEnv("DB_PASSWORD", "securepass"),
@@ -108,11 +477,32 @@ func LoadConfig() (*AppConfig, error) {
 			WebhookSecret: getEnv("REVOLUT_WEBHOOK_SECRET", ""),
 			BaseURL:       baseURL,
 			IsSandbox:     isSandbox,
+
+			PreviousWebhookSecret: getEnv("REVOLUT_WEBHOOK_SECRET_PREVIOUS", ""),
+
+			B2BManualCapture:       getEnv("REVOLUT_B2B_MANUAL_CAPTURE", "true") == "true",
+			AuthorizationVoidHours: getEnvAsInt("REVOLUT_AUTHORIZATION_VOID_HOURS", 144),
+			MaxPaymentAttempts:     getEnvAsInt("REVOLUT_MAX_PAYMENT_ATTEMPTS", 5),
+
+			ReconciliationHour:          getEnvAsInt("REVOLUT_RECONCILIATION_HOUR", 2),
+			ReconciliationLookbackHours: getEnvAsInt("REVOLUT_RECONCILIATION_LOOKBACK_HOURS", 48),
 		},
 		Email: EmailConfig{
-			Provider:    getEnv("EMAIL_PROVIDER", "outlook"),
-			SenderEmail: getEnv("EMAIL_SENDER_EMAIL", "enquirees@algeriamarket.co.uk"),
-			SenderName:  getEnv("EMAIL_SENDER_NAME", "Algeria Market"),
+			Provider:      getEnv("EMAIL_PROVIDER", "outlook"),
+			SenderEmail:   getEnv("EMAIL_SENDER_EMAIL", "enquirees@algeriamarket.co.uk"),
+			SenderName:    getEnv("EMAIL_SENDER_NAME", "Algeria Market"),
+			WebhookSecret: getEnv("EMAIL_WEBHOOK_SECRET", ""),
+			InboundSecret: getEnv("EMAIL_INBOUND_SECRET", ""),
+			DefaultLocale: getEnv("EMAIL_DEFAULT_LOCALE", "en"),
+
+			PreviousWebhookSecret: getEnv("EMAIL_WEBHOOK_SECRET_PREVIOUS", ""),
+			PreviousInboundSecret: getEnv("EMAIL_INBOUND_SECRET_PREVIOUS", ""),
+		},
+		EmailWorker: EmailWorkerConfig{
+			Concurrency:   getEnvAsInt("EMAIL_WORKER_CONCURRENCY", 4),
+			BatchSize:     getEnvAsInt("EMAIL_BATCH_SIZE", 10),
+			RatePerMinute: getEnvAsInt("EMAIL_RATE_PER_MINUTE", 0),
+			DailyLimit:    getEnvAsInt("EMAIL_DAILY_LIMIT", 0),
 		},
 		Outlook: OutlookConfig{
 			TenantID:     getEnv("OUTLOOK_TENANT_ID", ""),
@@ -121,15 +511,217 @@ func LoadConfig() (*AppConfig, error) {
 			SenderEmail:  getEnv("OUTLOOK_SENDER_EMAIL", "enquirees@algeriamarket.co.uk"),
 			SenderName:   getEnv("OUTLOOK_SENDER_NAME", "Algeria Market"),
 		},
+		SMTP: SMTPConfig{
+			Host:        getEnv("SMTP_HOST", ""),
+			Port:        getEnvAsInt("SMTP_PORT", 587),
+			Username:    getEnv("SMTP_USERNAME", ""),
+			Password:    getEnv("SMTP_PASSWORD", ""),
+			TLSMode:     getEnv("SMTP_TLS", "starttls"),
+			SenderEmail: getEnv("SMTP_SENDER_EMAIL", getEnv("EMAIL_SENDER_EMAIL", "enquirees@algeriamarket.co.uk")),
+			SenderName:  getEnv("SMTP_SENDER_NAME", getEnv("EMAIL_SENDER_NAME", "Algeria Market")),
+		},
 		Redis: RedisConfig{
 			UpstashURL:   getEnv("UPSTASH_REDIS_REST_URL", ""),
 			UpstashToken: getEnv("UPSTASH_REDIS_REST_TOKEN", ""),
 			PoolSize:     getEnvAsInt("REDIS_POOL_SIZE", 10),
 		},
-	}
+		Cart: CartConfig{
+			ItemExpiryHours: getEnvAsInt("CART_ITEM_EXPIRY_HOURS", 168),
+		},
+		Tax: TaxConfig{
+			DefaultCountry:   getEnv("TAX_DEFAULT_COUNTRY", "GB"),
+			DefaultRate:      getEnvAsFloat("TAX_DEFAULT_RATE", 20),
+			PricesIncludeVAT: getEnv("TAX_PRICES_INCLUDE_VAT", "false") == "true",
+		},
+		Fulfillment: FulfillmentConfig{
+			Strategy:       getEnv("FULFILLMENT_STRATEGY", "most_stock"),
+			SplitShipments: getEnv("FULFILLMENT_SPLIT_SHIPMENTS", "true") == "true",
+		},
+		Geocoding: GeocodingConfig{
+			Provider:  getEnv("GEOCODING_PROVIDER", ""),
+			URL:       getEnv("GEOCODING_URL", "https://nominatim.openstreetmap.org"),
+			UserAgent: getEnv("GEOCODING_USER_AGENT", "MarketProGo"),
+		},
+		Delivery: DeliveryConfig{
+			Timezone:    getEnv("DELIVERY_TIMEZONE", "Europe/London"),
+			CutoffHours: getEnvAsInt("DELIVERY_SLOT_CUTOFF_HOURS", 12),
+			BookingDays: getEnvAsInt("DELIVERY_BOOKING_DAYS", 14),
+		},
+		Campaign: CampaignConfig{
+			TrackingURL:     getEnv("CAMPAIGN_TRACKING_URL", "https://api.algeriamarket.co.uk/api/v1"),
+			TrackingSecret:  getEnv("CAMPAIGN_TRACKING_SECRET", getEnv("JWT_SECRET", "")),
+			BatchSize:       getEnvAsInt("CAMPAIGN_BATCH_SIZE", 200),
+			BatchInterval:   getEnvAsInt("CAMPAIGN_BATCH_INTERVAL_SECONDS", 60),
+			AttributionDays: getEnvAsInt("CAMPAIGN_ATTRIBUTION_DAYS", 7),
+		},
+		Loyalty: LoyaltyConfig{
+			PointsPerPound:   getEnvAsFloat("LOYALTY_POINTS_PER_POUND", 1),
+			PointValue:       getEnvAsFloat("LOYALTY_POINT_VALUE", 0.01),
+			MaxRedeemPercent: getEnvAsFloat("LOYALTY_MAX_REDEEM_PERCENT", 20),
+			ExpiryDays:       getEnvAsInt("LOYALTY_EXPIRY_DAYS", 365),
+		},
+		Referral: ReferralConfig{
+			RewardType:       getEnv("REFERRAL_REWARD_TYPE", "points"),
+			ReferrerReward:   getEnvAsFloat("REFERRAL_REFERRER_REWARD", 500),
+			RefereeReward:    getEnvAsFloat("REFERRAL_REFEREE_REWARD", 250),
+			MinOrderAmount:   getEnvAsFloat("REFERRAL_MIN_ORDER_AMOUNT", 20),
+			CouponExpiryDays: getEnvAsInt("REFERRAL_COUPON_EXPIRY_DAYS", 90),
+			ShareURL:         getEnv("REFERRAL_SHARE_URL", "https://algeriamarket.co.uk/register?ref="),
+		},
+		GiftCard: GiftCardConfig{
+			ExpiryDays: getEnvAsInt("GIFT_CARD_EXPIRY_DAYS", 730),
+			MaxAmount:  getEnvAsFloat("GIFT_CARD_MAX_AMOUNT", 500),
+			RedeemURL:  getEnv("GIFT_CARD_REDEEM_URL", "https://algeriamarket.co.uk/gift-cards"),
+		},
+		Company: CompanyConfig{
+			InvitationExpiryHours: getEnvAsInt("COMPANY_INVITATION_EXPIRY_HOURS", 168),
+			InvitationURL:         getEnv("COMPANY_INVITATION_URL", "https://algeriamarket.co.uk/account/company/join?token="),
+			ApprovalsURL:          getEnv("COMPANY_APPROVALS_URL", "https://algeriamarket.co.uk/account/company/approvals"),
+		},
+		Feed: FeedConfig{
+			SiteURL:         getEnv("FEED_SITE_URL", "https://algeriamarket.co.uk"),
+			APIURL:          getEnv("FEED_API_URL", "https://api.algeriamarket.co.uk/api/v1"),
+			Currency:        getEnv("FEED_CURRENCY", "GBP"),
+			IntervalMinutes: getEnvAsInt("FEED_INTERVAL_MINUTES", 60),
+			UploadToGCS:     getEnv("FEED_UPLOAD_TO_GCS", "false") == "true",
+		},
+		Storage: StorageConfig{
+			Backend:        getEnv("STORAGE_BACKEND", "appwrite"),
+			SignedURLs:     getEnv("STORAGE_SIGNED_URLS", "false") == "true",
+			URLTTLMinutes:  getEnvAsInt("STORAGE_URL_TTL_MINUTES", 60),
+			SigningKey:     getEnv("STORAGE_SIGNING_KEY", getEnv("JWT_SECRET", "")),
+			MaxImageSizeMB: getEnvAsInt("STORAGE_MAX_IMAGE_SIZE_MB", 10),
+		},
+		FileCleanup: FileCleanupConfig{
+			IntervalHours: getEnvAsInt("FILE_CLEANUP_INTERVAL_HOURS", 6),
+			GraceHours:    getEnvAsInt("FILE_CLEANUP_GRACE_HOURS", 24),
+			RetentionDays: getEnvAsInt("FILE_CLEANUP_RETENTION_DAYS", 30),
+		},
+		Trash: TrashConfig{
+			RetentionDays:      getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+			PurgeIntervalHours: getEnvAsInt("TRASH_PURGE_INTERVAL_HOURS", 24),
+		},
+		Fraud: FraudConfig{
+			Enabled:              getEnv("FRAUD_CHECKS_ENABLED", "true") == "true",
+			ReviewScore:          getEnvAsInt("FRAUD_REVIEW_SCORE", 50),
+			BlockScore:           getEnvAsInt("FRAUD_BLOCK_SCORE", 100),
+			MaxOrdersPerEmail:    getEnvAsInt("FRAUD_MAX_ORDERS_PER_EMAIL", 3),
+			MaxOrdersPerIP:       getEnvAsInt("FRAUD_MAX_ORDERS_PER_IP", 5),
+			MaxOrdersPerCard:     getEnvAsInt("FRAUD_MAX_ORDERS_PER_CARD", 3),
+			VelocityScore:        getEnvAsInt("FRAUD_VELOCITY_SCORE", 40),
+			CountryMismatchScore: getEnvAsInt("FRAUD_COUNTRY_MISMATCH_SCORE", 30),
+		},
+		Moderation: ModerationConfig{
+			StrikeExpiryDays: getEnvAsInt("MODERATION_STRIKE_EXPIRY_DAYS", 180),
+			ReviewStrikes:    getEnvAsInt("MODERATION_REVIEW_STRIKES", 2),
+			MessageStrikes:   getEnvAsInt("MODERATION_MESSAGE_STRIKES", 3),
+			BanStrikes:       getEnvAsInt("MODERATION_BAN_STRIKES", 5),
+			RestrictionDays:  getEnvAsInt("MODERATION_RESTRICTION_DAYS", 30),
+			BanDays:          getEnvAsInt("MODERATION_BAN_DAYS", 0),
+		},
+		Ratings: RatingsConfig{
+			ReconciliationHour: getEnvAsInt("RATINGS_RECONCILIATION_HOUR", 3),
+		},
+		Subscription: SubscriptionConfig{
+			ReminderDays:  getEnvAsInt("SUBSCRIPTION_REMINDER_DAYS", 3),
+			MaxFailedRuns: getEnvAsInt("SUBSCRIPTION_MAX_FAILED_RUNS", 3),
+		},
+		Lockout: LockoutConfig{
+			MaxAccountAttempts: getEnvAsInt("LOCKOUT_MAX_ATTEMPTS", 5),
+			MaxIPAttempts:      getEnvAsInt("LOCKOUT_MAX_IP_ATTEMPTS", 20),
+			WindowMinutes:      getEnvAsInt("LOCKOUT_WINDOW_MINUTES", 15),
+			LockMinutes:        getEnvAsInt("LOCKOUT_DURATION_MINUTES", 30),
+		},
+		Verification: VerificationConfig{
+			Secret:      getEnv("EMAIL_VERIFICATION_SECRET", getEnv("JWT_SECRET", "")),
+			ExpiryHours: getEnvAsInt("EMAIL_VERIFICATION_EXPIRY_HOURS", 72),
+			Required:    getEnv("EMAIL_VERIFICATION_REQUIRED", "false") == "true",
+		},
+		OAuth: OAuthConfig{
+			CallbackURL: getEnv("OAUTH_CALLBACK_URL", "https://api.algeriamarket.co.uk/api/v1/auth/oauth"),
+			RedirectURL: getEnv("OAUTH_REDIRECT_URL", "https://algeriamarket.co.uk/oauth/callback"),
+			StateSecret: getEnv("OAUTH_STATE_SECRET", getEnv("JWT_SECRET", "")),
+			Google: OAuthProviderConfig{
+				ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
+				ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+			},
+			Apple: OAuthProviderConfig{
+				ClientID:   getEnv("APPLE_CLIENT_ID", ""),
+				TeamID:     getEnv("APPLE_TEAM_ID", ""),
+				KeyID:      getEnv("APPLE_KEY_ID", ""),
+				PrivateKey: getEnv("APPLE_PRIVATE_KEY", ""),
+			},
+		},
+		SMS: SMSConfig{
+			Provider:         getEnv("SMS_PROVIDER", "mock"),
+			TemplateDir:      getEnv("SMS_TEMPLATE_DIR", "templates/sms"),
+			TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+			TwilioAuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
+			TwilioFrom:       getEnv("TWILIO_FROM", ""),
+
+			TwilioWhatsAppFrom: getEnv("TWILIO_WHATSAPP_FROM", ""),
+			StatusCallbackURL:  getEnv("SMS_STATUS_CALLBACK_URL", ""),
+
+			OTPExpiryMinutes: getEnvAsInt("PHONE_OTP_EXPIRY_MINUTES", 10),
+			OTPMaxAttempts:   getEnvAsInt("PHONE_OTP_MAX_ATTEMPTS", 5),
+			OTPResendSeconds: getEnvAsInt("PHONE_OTP_RESEND_SECONDS", 60),
+			OTPPerHour:       getEnvAsInt("PHONE_OTP_PER_HOUR", 5),
 
-	if cfg.GCSBucketName == "" {
-		log.Fatal("FATAL: GCS_BUCKET_NAME environment variable not set.")
+			RequiredAtCheckout: getEnv("PHONE_VERIFICATION_REQUIRED", "false") == "true",
+		},
+		Metrics: MetricsConfig{
+			Token: getEnv("METRICS_TOKEN", ""),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "marketpro-api"),
+			SampleRatio: getEnvAsFloat("OTEL_TRACES_SAMPLE_RATIO", 1),
+		},
+		Database: DatabaseConfig{
+			ReplicaDSNs: getEnvAsList("DB_REPLICA_DSNS"),
+		},
+		Cache: CacheConfig{
+			CatalogEnabled:    getEnv("CATALOG_CACHE_ENABLED", "true") == "true",
+			CatalogTTLSeconds: getEnvAsInt("CATALOG_CACHE_TTL_SECONDS", 300),
+
+			AvailabilityTTLSeconds: getEnvAsInt("AVAILABILITY_CACHE_TTL_SECONDS", 15),
+
+			AnalyticsTTLSeconds: getEnvAsInt("ANALYTICS_CACHE_TTL_SECONDS", 300),
+		},
+		Log: LogConfig{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:          getEnv("RATE_LIMIT_ENABLED", "true") == "true",
+			IPPerMinute:      getEnvAsInt("RATE_LIMIT_IP_PER_MINUTE", 120),
+			UserPerMinute:    getEnvAsInt("RATE_LIMIT_USER_PER_MINUTE", 300),
+			AuthPerMinute:    getEnvAsInt("RATE_LIMIT_AUTH_PER_MINUTE", 10),
+			ReviewPerMinute:  getEnvAsInt("RATE_LIMIT_REVIEW_PER_MINUTE", 5),
+			ContactPerMinute: getEnvAsInt("RATE_LIMIT_CONTACT_PER_MINUTE", 3),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvAsListOr("CORS_ALLOWED_ORIGINS", corsOrigins),
+			OriginPatterns:   getEnvAsList("CORS_ORIGIN_PATTERNS"),
+			AllowedMethods:   getEnvAsListOr("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   getEnvAsListOr("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Authorization", "Accept-Language"}),
+			AllowCredentials: getEnv("CORS_ALLOW_CREDENTIALS", "true") == "true",
+			MaxAgeHours:      getEnvAsInt("CORS_MAX_AGE_HOURS", 12),
+		},
+		API: APIConfig{
+			V1Sunset: getEnv("API_V1_SUNSET", ""),
+		},
+		Maintenance: MaintenanceConfig{
+			Enabled:           getEnv("MAINTENANCE_MODE", "false") == "true",
+			RetryAfterSeconds: getEnvAsInt("MAINTENANCE_RETRY_AFTER_SECONDS", 300),
+		},
+		GRPC: GRPCConfig{
+			Port:         getEnv("GRPC_PORT", ""),
+			CertFile:     getEnv("GRPC_TLS_CERT_FILE", ""),
+			KeyFile:      getEnv("GRPC_TLS_KEY_FILE", ""),
+			ClientCAFile: getEnv("GRPC_TLS_CLIENT_CA_FILE", ""),
+		},
+		ShutdownTimeoutSeconds: getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
 	}
 
 	log.Println("Configuration loaded successfully.")
@@ -153,3 +745,34 @@ func getEnvAsInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+// Helper function to get an environment variable as float or return a default value
+func getEnvAsFloat(key string, fallback float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return fallback
+}
+
+// Helper function to get a comma-separated environment variable as a list,
+// skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Helper function to get a comma-separated environment variable as a list,
+// or return a default list when it is not set
+func getEnvAsListOr(key string, fallback []string) []string {
+	if _, exists := os.LookupEnv(key); !exists {
+		return fallback
+	}
+	return getEnvAsList(key)
+}
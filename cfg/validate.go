@@ -0,0 +1,349 @@
+package cfg
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minSecretLength is the shortest secret accepted in production
+const minSecretLength = 32
+
+// Problem is a missing or invalid configuration variable
+type Problem struct {
+	Var     string `json:"var"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every configuration problem found, so they can all
+// be fixed at once
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = problem.Var + ": " + problem.Message
+	}
+	return fmt.Sprintf("%d configuration problem(s):\n  %s", len(e.Problems), strings.Join(lines, "\n  "))
+}
+
+// Production reports whether the app runs in production, which GIN_MODE=release
+// selects
+func (c *AppConfig) Production() bool {
+	return c.GinMode == "release"
+}
+
+// Mode is "production" or "development"
+func (c *AppConfig) Mode() string {
+	if c.Production() {
+		return "production"
+	}
+	return "development"
+}
+
+// validator collects problems
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(name, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Var: name, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(name, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(name, "is required")
+	}
+}
+
+// url checks value is an absolute http or https URL, if set
+func (v *validator) url(name, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(name, "must be an http or https URL")
+	}
+}
+
+// secret checks a set secret is long enough to be used in production
+func (v *validator) secret(production bool, name, value string) {
+	if production && value != "" && len(value) < minSecretLength {
+		v.add(name, "must be at least %d characters in production", minSecretLength)
+	}
+}
+
+func (v *validator) oneOf(name, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(name, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+func (v *validator) atLeast(name string, value, min int) {
+	if value < min {
+		v.add(name, "must be at least %d", min)
+	}
+}
+
+// Validate checks the configuration for the mode the app runs in, returning
+// a *ValidationError listing every problem. Production needs the database,
+// Redis, a real email provider and strong secrets; development only needs
+// what the app cannot start without.
+func (c *AppConfig) Validate() error {
+	v := &validator{}
+	production := c.Production()
+
+	// Application
+	v.oneOf("GIN_MODE", c.GinMode, "debug", "release", "test")
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		v.add("PORT", "must be a port number")
+	}
+	v.atLeast("SHUTDOWN_TIMEOUT_SECONDS", c.ShutdownTimeoutSeconds, 1)
+	v.oneOf("LOG_LEVEL", strings.ToLower(c.Log.Level), "debug", "info", "warn", "error")
+	v.oneOf("LOG_FORMAT", strings.ToLower(c.Log.Format), "json", "text")
+	v.url("OTEL_EXPORTER_OTLP_ENDPOINT", c.Tracing.Endpoint)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.add("OTEL_TRACES_SAMPLE_RATIO", "must be between 0 and 1")
+	}
+
+	// Auth
+	if production {
+		v.required("JWT_SECRET", c.JWTSecret)
+	}
+	v.secret(production, "JWT_SECRET", c.JWTSecret)
+	v.secret(production, "JWT_SECRET_PREVIOUS", c.PreviousJWTSecret)
+	v.secret(production, "ENCRYPTION_KEY", c.EncryptionKey)
+	v.secret(production, "EMAIL_VERIFICATION_SECRET", c.Verification.Secret)
+	v.secret(production, "OAUTH_STATE_SECRET", c.OAuth.StateSecret)
+	v.secret(production, "CAMPAIGN_TRACKING_SECRET", c.Campaign.TrackingSecret)
+	v.secret(production, "STORAGE_SIGNING_KEY", c.Storage.SigningKey)
+	v.secret(production, "EMAIL_WEBHOOK_SECRET", c.Email.WebhookSecret)
+	v.secret(production, "EMAIL_WEBHOOK_SECRET_PREVIOUS", c.Email.PreviousWebhookSecret)
+	v.secret(production, "EMAIL_INBOUND_SECRET", c.Email.InboundSecret)
+	v.secret(production, "EMAIL_INBOUND_SECRET_PREVIOUS", c.Email.PreviousInboundSecret)
+	v.atLeast("EMAIL_VERIFICATION_EXPIRY_HOURS", c.Verification.ExpiryHours, 1)
+
+	// Database: ConnectDB reads PG* in production and DB_* otherwise
+	if production {
+		for _, name := range []string{"PGHOST", "PGUSER", "PGPASSWORD", "PGDATABASE", "PGPORT"} {
+			v.required(name, os.Getenv(name))
+		}
+	} else {
+		v.required("DB_HOST", c.DBHost)
+		v.required("DB_NAME", c.DBName)
+	}
+	for i, dsn := range c.Database.ReplicaDSNs {
+		if u, err := url.Parse(dsn); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.Host == "" {
+			v.add("DB_REPLICA_DSNS", "entry %d must be a postgres:// URL", i+1)
+		}
+	}
+
+	// Storage
+	v.required("GCS_BUCKET_NAME", c.GCSBucketName)
+	v.url("APPWRITE_ENDPOINT", c.AppwriteEndpoint)
+	v.oneOf("STORAGE_BACKEND", c.Storage.Backend, "appwrite", "gcs")
+	if production {
+		v.required("APPWRITE_PROJECT", c.AppwriteProject)
+		v.required("APPWRITE_KEY", c.AppwriteKey)
+		v.required("APPWRITE_BUCKET_ID", c.AppwriteBucketId)
+	}
+	v.atLeast("STORAGE_MAX_IMAGE_SIZE_MB", c.Storage.MaxImageSizeMB, 1)
+	v.atLeast("TRASH_RETENTION_DAYS", c.Trash.RetentionDays, 1)
+	// The files of a deleted row must outlive it, or restoring it would bring
+	// back images whose files are gone
+	if c.FileCleanup.IntervalHours > 0 && c.FileCleanup.RetentionDays < c.Trash.RetentionDays {
+		v.add("FILE_CLEANUP_RETENTION_DAYS", "must be at least TRASH_RETENTION_DAYS (%d)", c.Trash.RetentionDays)
+	}
+
+	// Payments
+	v.required("REVOLUT_API_KEY", c.Revolut.APIKey)
+	if production {
+		v.required("REVOLUT_WEBHOOK_SECRET", c.Revolut.WebhookSecret)
+	}
+	if c.Revolut.PreviousWebhookSecret != "" && c.Revolut.WebhookSecret == "" {
+		v.add("REVOLUT_WEBHOOK_SECRET_PREVIOUS", "needs REVOLUT_WEBHOOK_SECRET, the secret it is rotated to")
+	}
+	v.atLeast("REVOLUT_MAX_PAYMENT_ATTEMPTS", c.Revolut.MaxPaymentAttempts, 1)
+	if c.Revolut.ReconciliationHour < -1 || c.Revolut.ReconciliationHour > 23 {
+		v.add("REVOLUT_RECONCILIATION_HOUR", "must be an hour from 0 to 23, or -1")
+	}
+	if c.Ratings.ReconciliationHour < -1 || c.Ratings.ReconciliationHour > 23 {
+		v.add("RATINGS_RECONCILIATION_HOUR", "must be an hour from 0 to 23, or -1")
+	}
+
+	// Redis backs the email queue, login lockouts, rate limits and caches,
+	// which fall back to a single instance without it
+	v.url("UPSTASH_REDIS_REST_URL", c.Redis.UpstashURL)
+	if production {
+		v.required("UPSTASH_REDIS_REST_URL", c.Redis.UpstashURL)
+		v.required("UPSTASH_REDIS_REST_TOKEN", c.Redis.UpstashToken)
+	}
+
+	// Email: without Outlook or SMTP emails only go to the mock provider
+	outlook := c.Outlook.TenantID != "" || c.Outlook.ClientID != "" || c.Outlook.ClientSecret != ""
+	if outlook {
+		v.required("OUTLOOK_TENANT_ID", c.Outlook.TenantID)
+		v.required("OUTLOOK_CLIENT_ID", c.Outlook.ClientID)
+		v.required("OUTLOOK_CLIENT_SECRET", c.Outlook.ClientSecret)
+	}
+	if production && !outlook && c.SMTP.Host == "" {
+		v.add("OUTLOOK_CLIENT_ID", "an email provider is required in production: set the OUTLOOK_* or SMTP_* variables")
+	}
+	if c.SMTP.Host != "" {
+		v.oneOf("SMTP_TLS", c.SMTP.TLSMode, "starttls", "tls", "none")
+		if c.SMTP.Port < 1 || c.SMTP.Port > 65535 {
+			v.add("SMTP_PORT", "must be a port number")
+		}
+	}
+	v.required("EMAIL_SENDER_EMAIL", c.Email.SenderEmail)
+	v.oneOf("EMAIL_DEFAULT_LOCALE", c.Email.DefaultLocale, "en", "fr", "ar")
+	v.atLeast("EMAIL_WORKER_CONCURRENCY", c.EmailWorker.Concurrency, 1)
+	v.atLeast("EMAIL_BATCH_SIZE", c.EmailWorker.BatchSize, 1)
+
+	// Text messages
+	if c.SMS.Provider != "" {
+		v.oneOf("SMS_PROVIDER", c.SMS.Provider, "twilio", "mock")
+	}
+	if c.SMS.Provider == "twilio" {
+		v.required("TWILIO_ACCOUNT_SID", c.SMS.TwilioAccountSID)
+		v.required("TWILIO_AUTH_TOKEN", c.SMS.TwilioAuthToken)
+		v.required("TWILIO_FROM", c.SMS.TwilioFrom)
+	}
+	v.url("SMS_STATUS_CALLBACK_URL", c.SMS.StatusCallbackURL)
+
+	// Social sign-in: each provider is off until its client ID is set
+	v.url("OAUTH_CALLBACK_URL", c.OAuth.CallbackURL)
+	v.url("OAUTH_REDIRECT_URL", c.OAuth.RedirectURL)
+	if c.OAuth.Google.ClientID != "" {
+		v.required("GOOGLE_CLIENT_SECRET", c.OAuth.Google.ClientSecret)
+	}
+	if c.OAuth.Apple.ClientID != "" {
+		v.required("APPLE_TEAM_ID", c.OAuth.Apple.TeamID)
+		v.required("APPLE_KEY_ID", c.OAuth.Apple.KeyID)
+		v.required("APPLE_PRIVATE_KEY", c.OAuth.Apple.PrivateKey)
+	}
+
+	// Storefront and API links
+	v.url("CAMPAIGN_TRACKING_URL", c.Campaign.TrackingURL)
+	v.url("FEED_SITE_URL", c.Feed.SiteURL)
+	v.url("FEED_API_URL", c.Feed.APIURL)
+	v.url("REFERRAL_SHARE_URL", c.Referral.ShareURL)
+	v.url("GIFT_CARD_REDEEM_URL", c.GiftCard.RedeemURL)
+	v.url("COMPANY_INVITATION_URL", c.Company.InvitationURL)
+	v.url("COMPANY_APPROVALS_URL", c.Company.ApprovalsURL)
+	v.url("GEOCODING_URL", c.Geocoding.URL)
+
+	// Business rules
+	v.oneOf("FULFILLMENT_STRATEGY", c.Fulfillment.Strategy, "nearest", "most_stock", "fifo", "fefo")
+	v.oneOf("REFERRAL_REWARD_TYPE", c.Referral.RewardType, "points", "coupon")
+	if c.Geocoding.Provider != "" {
+		v.oneOf("GEOCODING_PROVIDER", c.Geocoding.Provider, "nominatim")
+	}
+	if _, err := time.LoadLocation(c.Delivery.Timezone); err != nil {
+		v.add("DELIVERY_TIMEZONE", "must be an IANA time zone, such as Europe/London")
+	}
+	if c.Tax.DefaultRate < 0 || c.Tax.DefaultRate > 100 {
+		v.add("TAX_DEFAULT_RATE", "must be a percentage from 0 to 100")
+	}
+	if c.Loyalty.MaxRedeemPercent < 0 || c.Loyalty.MaxRedeemPercent > 100 {
+		v.add("LOYALTY_MAX_REDEEM_PERCENT", "must be a percentage from 0 to 100")
+	}
+	if c.RateLimit.Enabled {
+		v.atLeast("RATE_LIMIT_IP_PER_MINUTE", c.RateLimit.IPPerMinute, 1)
+		v.atLeast("RATE_LIMIT_USER_PER_MINUTE", c.RateLimit.UserPerMinute, 1)
+		v.atLeast("RATE_LIMIT_AUTH_PER_MINUTE", c.RateLimit.AuthPerMinute, 1)
+	}
+
+	// CORS
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				v.add("CORS_ALLOWED_ORIGINS", "* cannot be used with CORS_ALLOW_CREDENTIALS=true")
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			v.add("CORS_ALLOWED_ORIGINS", "%q must be a scheme and host, such as https://algeriamarket.co.uk", origin)
+		}
+	}
+	for _, pattern := range c.CORS.OriginPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.add("CORS_ORIGIN_PATTERNS", "%q is not a valid regular expression", pattern)
+		}
+	}
+	if len(c.CORS.AllowedMethods) == 0 {
+		v.add("CORS_ALLOWED_METHODS", "is required")
+	}
+
+	// Internal gRPC API, always over mutual TLS
+	if c.GRPC.Port != "" {
+		if c.GRPC.Port == c.Port {
+			v.add("GRPC_PORT", "must differ from PORT")
+		}
+		v.required("GRPC_TLS_CERT_FILE", c.GRPC.CertFile)
+		v.required("GRPC_TLS_KEY_FILE", c.GRPC.KeyFile)
+		v.required("GRPC_TLS_CLIENT_CA_FILE", c.GRPC.ClientCAFile)
+	}
+
+	// API versions
+	if c.API.V1Sunset != "" {
+		if _, err := time.Parse(time.RFC3339, c.API.V1Sunset); err != nil {
+			v.add("API_V1_SUNSET", "%q must be an RFC 3339 time, such as 2027-06-30T00:00:00Z", c.API.V1Sunset)
+		}
+	}
+
+	v.atLeast("MAINTENANCE_RETRY_AFTER_SECONDS", c.Maintenance.RetryAfterSeconds, 1)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// sensitive reports whether a config field holds a secret, by its name
+func sensitive(name string) bool {
+	for _, suffix := range []string{"Secret", "Token", "Password", "Key", "DSN", "DSNs", "CredentialsFile"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the configuration with every secret replaced by whether
+// it is set, for diagnostics
+func (c *AppConfig) Redacted() map[string]interface{} {
+	return redact(reflect.ValueOf(*c))
+}
+
+func redact(value reflect.Value) map[string]interface{} {
+	out := make(map[string]interface{}, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field, v := value.Type().Field(i), value.Field(i)
+		switch {
+		case !field.IsExported():
+		case v.Kind() == reflect.Struct:
+			out[field.Name] = redact(v)
+		case sensitive(field.Name):
+			if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+				out[field.Name] = ""
+			} else {
+				out[field.Name] = "[redacted]"
+			}
+		default:
+			out[field.Name] = v.Interface()
+		}
+	}
+	return out
+}
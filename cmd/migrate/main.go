@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/YasserCherfaoui/MarketProGo/database"
 	"github.com/gin-gonic/gin"
@@ -14,8 +15,10 @@ import (
 func main() {
 	// Parse command line flags
 	var (
-		action        = flag.String("action", "up", "Migration action: up, status, rollback")
+		action        = flag.String("action", "up", "Migration action: up, status, rollback, down-to")
 		migrationName = flag.String("migration", "", "Migration name for rollback")
+		target        = flag.String("to", "", "Migration name or version to roll back to for down-to, 0 for all")
+		dryRun        = flag.Bool("dry-run", false, "Print the SQL that would run without running it")
 		envFile       = flag.String("env", ".env", "Environment file path")
 	)
 	flag.Parse()
@@ -37,6 +40,17 @@ func main() {
 	// Execute action
 	switch *action {
 	case "up":
+		if *dryRun {
+			if err := database.VerifyChecksums(db); err != nil {
+				log.Fatalf("Checksum verification failed: %v", err)
+			}
+			steps, err := database.PendingMigrations(db)
+			if err != nil {
+				log.Fatalf("Failed to list pending migrations: %v", err)
+			}
+			printSteps("Pending migrations", steps)
+			return
+		}
 		fmt.Println("Running migrations...")
 		if err := database.RunMigrations(db); err != nil {
 			log.Fatalf("Failed to run migrations: %v", err)
@@ -60,29 +74,86 @@ func main() {
 			}
 		}
 
+		pending, err := database.PendingMigrations(db)
+		if err != nil {
+			log.Fatalf("Failed to list pending migrations: %v", err)
+		}
+		fmt.Printf("\n%d pending migration(s)\n", len(pending))
+		for _, step := range pending {
+			fmt.Printf("  %s\n", step.Name)
+		}
+
+		if err := database.VerifyChecksums(db); err != nil {
+			fmt.Printf("\nWarning: %v\n", err)
+		}
+
 	case "rollback":
 		if *migrationName == "" {
 			log.Fatal("Migration name is required for rollback. Use -migration flag.")
 		}
+		if *dryRun {
+			step, err := database.PlanRollback(db, *migrationName)
+			if err != nil {
+				log.Fatalf("Failed to plan rollback: %v", err)
+			}
+			printSteps("Rollback", []database.MigrationStep{step})
+			return
+		}
 		fmt.Printf("Rolling back migration: %s\n", *migrationName)
 		if err := database.RollbackMigration(db, *migrationName); err != nil {
 			log.Fatalf("Failed to rollback migration: %v", err)
 		}
 		fmt.Printf("Migration %s rolled back successfully!\n", *migrationName)
 
+	case "down-to":
+		if *target == "" {
+			log.Fatal("Target migration is required for down-to. Use -to flag.")
+		}
+		if *dryRun {
+			steps, err := database.PlanRollbackTo(db, *target)
+			if err != nil {
+				log.Fatalf("Failed to plan rollback: %v", err)
+			}
+			printSteps("Migrations to roll back", steps)
+			return
+		}
+		fmt.Printf("Rolling back to migration: %s\n", *target)
+		if err := database.RollbackTo(db, *target); err != nil {
+			log.Fatalf("Failed to roll back: %v", err)
+		}
+		fmt.Printf("Rolled back to %s successfully!\n", *target)
+
 	default:
 		fmt.Println("Usage: migrate [options]")
 		fmt.Println("Options:")
 		fmt.Println("  -action string")
-		fmt.Println("        Migration action: up, status, rollback (default 'up')")
+		fmt.Println("        Migration action: up, status, rollback, down-to (default 'up')")
 		fmt.Println("  -migration string")
 		fmt.Println("        Migration name for rollback")
+		fmt.Println("  -to string")
+		fmt.Println("        Migration name or version to roll back to for down-to, 0 for all")
+		fmt.Println("  -dry-run")
+		fmt.Println("        Print the SQL that would run without running it")
 		fmt.Println("  -env string")
 		fmt.Println("        Environment file path (default '.env')")
 		fmt.Println("\nExamples:")
 		fmt.Println("  migrate -action up")
 		fmt.Println("  migrate -action status")
 		fmt.Println("  migrate -action rollback -migration 001_create_review_tables")
+		fmt.Println("  migrate -action up -dry-run")
+		fmt.Println("  migrate -action down-to -to 035_create_product_summaries_table")
 		os.Exit(1)
 	}
 }
+
+// printSteps prints migrations and the SQL they would run
+func printSteps(title string, steps []database.MigrationStep) {
+	if len(steps) == 0 {
+		fmt.Printf("%s: none\n", title)
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	for _, step := range steps {
+		fmt.Printf("\n-- %s\n%s\n", step.Name, strings.TrimSpace(step.SQL))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/YasserCherfaoui/MarketProGo/database"
+	"github.com/YasserCherfaoui/MarketProGo/seed"
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Parse command line flags
+	var (
+		seedValue = flag.Int64("seed", 1, "Random seed, the same seed produces the same data")
+		wipe      = flag.Bool("wipe", false, "Empty the seeded tables before seeding")
+		force     = flag.Bool("force", false, "Allow -wipe when GIN_MODE is release")
+		customers = flag.Int("customers", 12, "Number of customers")
+		products  = flag.Int("products", 40, "Number of products")
+		orders    = flag.Int("orders", 80, "Number of orders")
+		tickets   = flag.Int("tickets", 15, "Number of support tickets")
+		password  = flag.String("password", seed.DefaultPassword, "Password of every seeded user")
+		envFile   = flag.String("env", ".env", "Environment file path")
+	)
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(*envFile); err != nil {
+		log.Printf("Warning: Could not load .env file: %v", err)
+	}
+
+	if *wipe && gin.Mode() == gin.ReleaseMode && !*force {
+		log.Fatal("Refusing to wipe a release mode database. Add -force if this really is a staging database.")
+	}
+
+	db, err := database.ConnectDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := database.RunMigrations(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	summary, err := seed.Run(context.Background(), db, seed.Options{
+		Seed:      *seedValue,
+		Wipe:      *wipe,
+		Customers: *customers,
+		Products:  *products,
+		Orders:    *orders,
+		Tickets:   *tickets,
+		Password:  *password,
+	})
+	if err != nil {
+		log.Fatalf("Failed to seed database: %v", err)
+	}
+
+	fmt.Println("Seeded:")
+	fmt.Printf("  %-16s %d\n", "users", summary.Users)
+	fmt.Printf("  %-16s %d\n", "companies", summary.Companies)
+	fmt.Printf("  %-16s %d\n", "categories", summary.Categories)
+	fmt.Printf("  %-16s %d\n", "brands", summary.Brands)
+	fmt.Printf("  %-16s %d\n", "products", summary.Products)
+	fmt.Printf("  %-16s %d\n", "variants", summary.Variants)
+	fmt.Printf("  %-16s %d\n", "warehouses", summary.Warehouses)
+	fmt.Printf("  %-16s %d\n", "inventory items", summary.InventoryItems)
+	fmt.Printf("  %-16s %d\n", "orders", summary.Orders)
+	fmt.Printf("  %-16s %d\n", "reviews", summary.Reviews)
+	fmt.Printf("  %-16s %d\n", "support tickets", summary.Tickets)
+	fmt.Printf("\nEvery user's password is %q, e.g. admin@%s\n", *password, seed.EmailDomain)
+}
@@ -0,0 +1,176 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/orderhistory"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"gorm.io/gorm"
+)
+
+// CheckOrder decides whether a buyer may place an order of amount for their
+// company, and whether it waits for an owner's approval. Owners, and users
+// outside a company, are not limited.
+func (s *Service) CheckOrder(tx *gorm.DB, buyer *models.User, amount float64) (needsApproval bool, err error) {
+	if buyer.CompanyID == nil || buyer.Role == models.CompanyRoleOwner {
+		return false, nil
+	}
+	if buyer.SpendLimit > 0 {
+		spent, err := s.MonthlySpend(tx, buyer)
+		if err != nil {
+			return false, err
+		}
+		if spent+amount > buyer.SpendLimit+0.005 {
+			return false, fmt.Errorf("%w: %.2f of %.2f left this month", ErrSpendLimitExceeded, max(buyer.SpendLimit-spent, 0), buyer.SpendLimit)
+		}
+	}
+	var company models.Company
+	if err := tx.Select("id", "approval_threshold").First(&company, *buyer.CompanyID).Error; err != nil {
+		return false, err
+	}
+	return company.ApprovalThreshold > 0 && amount > company.ApprovalThreshold, nil
+}
+
+// MonthlySpend totals the orders a buyer placed for their company this
+// calendar month that were not cancelled or rejected
+func (s *Service) MonthlySpend(tx *gorm.DB, buyer *models.User) (float64, error) {
+	now := s.now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var spent float64
+	if err := tx.Model(&models.Order{}).
+		Where("user_id = ? AND company_id = ? AND status <> ? AND order_date >= ?", buyer.ID, *buyer.CompanyID, models.OrderStatusCancelled, monthStart).
+		Select("COALESCE(SUM(final_amount), 0)").
+		Row().Scan(&spent); err != nil {
+		return 0, fmt.Errorf("failed to total monthly spend: %w", err)
+	}
+	return spent, nil
+}
+
+// RequestApproval emails the company's owners about a buyer's order waiting
+// for their approval, in tx so they are sent when the order is committed
+func (s *Service) RequestApproval(tx *gorm.DB, order *models.Order, buyer *models.User) error {
+	if s.emails == nil || order.CompanyID == nil {
+		return nil
+	}
+	var company models.Company
+	if err := tx.Select("id", "name").First(&company, *order.CompanyID).Error; err != nil {
+		return err
+	}
+	var owners []models.User
+	if err := tx.Where("company_id = ? AND role = ? AND is_active = ?", company.ID, models.CompanyRoleOwner, true).Find(&owners).Error; err != nil {
+		return err
+	}
+	if len(owners) == 0 {
+		slog.WarnContext(tx.Statement.Context, "order awaits approval but its company has no owner", "component", "company", "order_id", order.ID, "company_id", company.ID)
+	}
+	emails := s.emails.InTx(tx)
+	for i := range owners {
+		data := map[string]interface{}{
+			"subject":       fmt.Sprintf("Order %s is waiting for your approval", order.OrderNumber),
+			"ApproverName":  fullName(&owners[i]),
+			"BuyerName":     fullName(buyer),
+			"AccountName":   company.Name,
+			"OrderNumber":   order.OrderNumber,
+			"TotalAmount":   order.FinalAmount,
+			"Currency":      settings.Current().Currency,
+			"ItemCount":     len(order.Items),
+			"CustomerNotes": order.CustomerNotes,
+			"ApprovalsURL":  s.config.ApprovalsURL,
+		}
+		if err := emails.TriggerOrderApprovalRequest(owners[i].Email, fullName(&owners[i]), data); err != nil {
+			return fmt.Errorf("failed to send approval request email: %w", err)
+		}
+	}
+	return nil
+}
+
+// PendingApprovals lists the orders of an owner's company waiting for
+// approval, oldest first
+func (s *Service) PendingApprovals(ctx context.Context, ownerID uint) ([]models.Order, error) {
+	db := s.db.WithContext(ctx)
+	owner, err := s.owner(db, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	orders := []models.Order{}
+	if err := db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "email", "first_name", "last_name")
+	}).Preload("Items.ProductVariant").
+		Where("company_id = ? AND status = ?", *owner.CompanyID, models.OrderStatusPendingApproval).
+		Order("order_date ASC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Decide records an owner's decision on an order of their company waiting
+// for approval. An approved order can be paid; a rejected one is cancelled,
+// and releasing what it holds is up to the caller in the same transaction.
+// The buyer is emailed the decision.
+func (s *Service) Decide(tx *gorm.DB, ownerID, orderID uint, approve bool, note string) (*models.Order, error) {
+	owner, err := s.owner(tx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var order models.Order
+	if err := tx.Where("id = ? AND company_id = ?", orderID, *owner.CompanyID).First(&order).Error; err != nil {
+		return nil, err
+	}
+
+	status := models.OrderStatusCancelled
+	if approve {
+		status = models.OrderStatusPending
+	}
+	now := s.now()
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", order.ID, models.OrderStatusPendingApproval).
+		Updates(map[string]interface{}{
+			"status":              status,
+			"approver_id":         owner.ID,
+			"approval_decided_at": now,
+			"approval_note":       note,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotAwaitingApproval
+	}
+	if err := orderhistory.Record(tx, order.ID, order.Status, status, orderhistory.Change{ActorID: &owner.ID, Reason: note}); err != nil {
+		return nil, err
+	}
+	order.Status, order.ApproverID, order.ApprovalDecidedAt, order.ApprovalNote = status, &owner.ID, &now, note
+
+	if s.emails != nil {
+		var buyer models.User
+		if err := tx.First(&buyer, order.UserID).Error; err != nil {
+			return nil, err
+		}
+		var company models.Company
+		if err := tx.Select("id", "name").First(&company, *owner.CompanyID).Error; err != nil {
+			return nil, err
+		}
+		verb := "rejected"
+		if approve {
+			verb = "approved"
+		}
+		data := map[string]interface{}{
+			"subject":      fmt.Sprintf("Your order %s has been %s", order.OrderNumber, verb),
+			"UserName":     fullName(&buyer),
+			"ApproverName": fullName(owner),
+			"AccountName":  company.Name,
+			"OrderNumber":  order.OrderNumber,
+			"Approved":     approve,
+			"Note":         note,
+			"OrderURL":     settings.Current().URL("/orders/%d", order.ID),
+		}
+		if err := s.emails.InTx(tx).TriggerOrderApprovalDecision(buyer.Email, fullName(&buyer), data); err != nil {
+			slog.ErrorContext(tx.Statement.Context, "failed to queue order approval email", "component", "company", "order_id", order.ID, "error", err)
+		}
+	}
+	return &order, nil
+}
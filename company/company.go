@@ -0,0 +1,375 @@
+// Package company runs company accounts: the buyers an owner invites to
+// order for the company, their monthly spend limits, and the owners'
+// approval of buyers' orders above the company's approval threshold.
+package company
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoCompany           = errors.New("you do not belong to a company")
+	ErrNotOwner            = errors.New("only a company owner can do this")
+	ErrInvalidInvitation   = errors.New("invalid or expired invitation")
+	ErrInvitationEmail     = errors.New("the invitation was sent to another email address")
+	ErrAlreadyInCompany    = errors.New("the user already belongs to a company")
+	ErrNotEligible         = errors.New("only customer and wholesaler accounts can join a company")
+	ErrLastOwner           = errors.New("a company must keep at least one owner")
+	ErrSpendLimitExceeded  = errors.New("the order would exceed your monthly spend limit")
+	ErrNotAwaitingApproval = errors.New("order is not awaiting approval")
+)
+
+// Service runs company accounts
+type Service struct {
+	db     *gorm.DB
+	config cfg.CompanyConfig
+	emails *email.EmailTriggerService
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, config *cfg.CompanyConfig, emails *email.EmailTriggerService) *Service {
+	s := &Service{db: db, emails: emails, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	return s
+}
+
+// InviteRequest invites a buyer to the owner's company
+type InviteRequest struct {
+	Email      string  `json:"email" binding:"required,email"`
+	SpendLimit float64 `json:"spend_limit" binding:"gte=0"` // monthly; 0 for no limit
+}
+
+// MemberUpdate changes a member's spend limit or role; fields left out are
+// kept
+type MemberUpdate struct {
+	SpendLimit *float64 `json:"spend_limit" binding:"omitempty,gte=0"`
+	Role       string   `json:"role" binding:"omitempty,oneof=owner buyer"`
+}
+
+// SettingsUpdate changes a company's ordering rules
+type SettingsUpdate struct {
+	ApprovalThreshold *float64 `json:"approval_threshold" binding:"required,gte=0"` // 0 for no approvals
+}
+
+// Company returns the company of a member with its members
+func (s *Service) Company(ctx context.Context, userID uint) (*models.Company, error) {
+	return s.company(s.db.WithContext(ctx), userID)
+}
+
+func (s *Service) company(tx *gorm.DB, userID uint) (*models.Company, error) {
+	var user models.User
+	if err := tx.Select("id", "company_id").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.CompanyID == nil {
+		return nil, ErrNoCompany
+	}
+	var company models.Company
+	if err := tx.Preload("Users", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "email", "first_name", "last_name", "user_type", "company_id", "role", "spend_limit", "is_active", "created_at").Order("id")
+	}).First(&company, *user.CompanyID).Error; err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// owner returns a company owner, or ErrNotOwner
+func (s *Service) owner(tx *gorm.DB, userID uint) (*models.User, error) {
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.CompanyID == nil {
+		return nil, ErrNoCompany
+	}
+	if user.Role != models.CompanyRoleOwner {
+		return nil, ErrNotOwner
+	}
+	return &user, nil
+}
+
+// UpdateSettings changes the ordering rules of an owner's company
+func (s *Service) UpdateSettings(ctx context.Context, ownerID uint, req SettingsUpdate) (*models.Company, error) {
+	db := s.db.WithContext(ctx)
+	owner, err := s.owner(db, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Company{}).Where("id = ?", *owner.CompanyID).
+		Update("approval_threshold", *req.ApprovalThreshold).Error; err != nil {
+		return nil, err
+	}
+	return s.company(db, ownerID)
+}
+
+// Invite emails someone an invitation to order for the owner's company. The
+// invitation is recorded with its email, so it is sent exactly when saved.
+func (s *Service) Invite(ctx context.Context, ownerID uint, req InviteRequest) (*models.CompanyInvitation, error) {
+	db := s.db.WithContext(ctx)
+	owner, err := s.owner(db, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	address := strings.ToLower(strings.TrimSpace(req.Email))
+	var members int64
+	if err := db.Model(&models.User{}).Where("LOWER(email) = ? AND company_id IS NOT NULL", address).Count(&members).Error; err != nil {
+		return nil, err
+	}
+	if members > 0 {
+		return nil, ErrAlreadyInCompany
+	}
+	var company models.Company
+	if err := db.First(&company, *owner.CompanyID).Error; err != nil {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := models.CompanyInvitation{
+		CompanyID:   company.ID,
+		Email:       address,
+		SpendLimit:  req.SpendLimit,
+		TokenHash:   hashToken(token),
+		InvitedByID: owner.ID,
+		ExpiresAt:   s.now().Add(time.Duration(s.config.InvitationExpiryHours) * time.Hour),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&invitation).Error; err != nil {
+			return err
+		}
+		if s.emails == nil {
+			slog.WarnContext(ctx, "company invitation created without email service", "component", "company", "invitation_id", invitation.ID)
+			return nil
+		}
+		data := map[string]interface{}{
+			"subject":           fmt.Sprintf("You are invited to order for %s on %s", company.Name, settings.Current().CompanyName),
+			"AccountName":       company.Name,
+			"InviterName":       fullName(owner),
+			"SpendLimit":        invitation.SpendLimit,
+			"ApprovalThreshold": company.ApprovalThreshold,
+			"Currency":          settings.Current().Currency,
+			"InvitationURL":     s.config.InvitationURL + token,
+			"ExpiresAt":         invitation.ExpiresAt.Format("2 January 2006"),
+		}
+		// The token is only ever sent in this email, so failing to queue it
+		// undoes the invitation
+		if err := s.emails.InTx(tx).TriggerCompanyInvitation(invitation.Email, data); err != nil {
+			return fmt.Errorf("failed to send invitation email: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Invitations lists the invitations of an owner's company that can still be
+// accepted, newest first
+func (s *Service) Invitations(ctx context.Context, ownerID uint) ([]models.CompanyInvitation, error) {
+	db := s.db.WithContext(ctx)
+	owner, err := s.owner(db, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	invitations := []models.CompanyInvitation{}
+	if err := db.Where("company_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", *owner.CompanyID, s.now()).
+		Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation stops an invitation of an owner's company from being
+// accepted
+func (s *Service) RevokeInvitation(ctx context.Context, ownerID, invitationID uint) error {
+	db := s.db.WithContext(ctx)
+	owner, err := s.owner(db, ownerID)
+	if err != nil {
+		return err
+	}
+	result := db.Model(&models.CompanyInvitation{}).
+		Where("id = ? AND company_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationID, *owner.CompanyID).
+		Update("revoked_at", s.now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation makes the user a buyer of the company that invited them.
+// The invitation must have been sent to the user's email address.
+func (s *Service) AcceptInvitation(ctx context.Context, userID uint, token string) (*models.Company, error) {
+	db := s.db.WithContext(ctx)
+	var invitation models.CompanyInvitation
+	if err := db.Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", hashToken(token), s.now()).
+		First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	switch {
+	case !strings.EqualFold(strings.TrimSpace(user.Email), invitation.Email):
+		return nil, ErrInvitationEmail
+	case user.CompanyID != nil:
+		return nil, ErrAlreadyInCompany
+	case user.UserType != models.Customer && user.UserType != models.Wholesaler:
+		return nil, ErrNotEligible
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.CompanyInvitation{}).Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": s.now(), "accepted_by": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidInvitation
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"company_id":  invitation.CompanyID,
+			"role":        models.CompanyRoleBuyer,
+			"spend_limit": invitation.SpendLimit,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.company(db, userID)
+}
+
+// UpdateMember changes the spend limit or role of a member of an owner's
+// company
+func (s *Service) UpdateMember(ctx context.Context, ownerID, memberID uint, req MemberUpdate) (*models.User, error) {
+	db := s.db.WithContext(ctx)
+	owner, err := s.owner(db, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var member models.User
+	if err := db.Where("id = ? AND company_id = ?", memberID, *owner.CompanyID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{}
+	if req.SpendLimit != nil {
+		updates["spend_limit"] = *req.SpendLimit
+	}
+	if req.Role != "" && req.Role != member.Role {
+		if member.Role == models.CompanyRoleOwner {
+			if err := s.keepOwner(db, *owner.CompanyID, member.ID); err != nil {
+				return nil, err
+			}
+		}
+		updates["role"] = req.Role
+	}
+	if len(updates) > 0 {
+		if err := db.Model(&member).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &member, nil
+}
+
+// RemoveMember takes a member out of an owner's company. Orders they placed
+// stay with the company.
+func (s *Service) RemoveMember(ctx context.Context, ownerID, memberID uint) error {
+	db := s.db.WithContext(ctx)
+	owner, err := s.owner(db, ownerID)
+	if err != nil {
+		return err
+	}
+	var member models.User
+	if err := db.Where("id = ? AND company_id = ?", memberID, *owner.CompanyID).First(&member).Error; err != nil {
+		return err
+	}
+	if member.Role == models.CompanyRoleOwner {
+		if err := s.keepOwner(db, *owner.CompanyID, member.ID); err != nil {
+			return err
+		}
+	}
+	return db.Model(&member).Updates(map[string]interface{}{"company_id": nil, "role": "", "spend_limit": 0}).Error
+}
+
+// keepOwner returns ErrLastOwner unless the company has an owner besides
+// userID
+func (s *Service) keepOwner(tx *gorm.DB, companyID, userID uint) error {
+	var owners int64
+	if err := tx.Model(&models.User{}).
+		Where("company_id = ? AND role = ? AND id <> ?", companyID, models.CompanyRoleOwner, userID).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// SetOwner makes a user an owner of a company, adding them to it when they
+// belong to none. It is how admins set up a company's first owner.
+func (s *Service) SetOwner(ctx context.Context, companyID, userID uint) (*models.Company, error) {
+	db := s.db.WithContext(ctx)
+	if err := db.First(&models.Company{}, companyID).Error; err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.CompanyID != nil && *user.CompanyID != companyID {
+		return nil, ErrAlreadyInCompany
+	}
+	if user.CompanyID == nil && user.UserType != models.Customer && user.UserType != models.Wholesaler {
+		return nil, ErrNotEligible
+	}
+	if err := db.Model(&user).Updates(map[string]interface{}{"company_id": companyID, "role": models.CompanyRoleOwner}).Error; err != nil {
+		return nil, err
+	}
+	return s.company(db, userID)
+}
+
+func fullName(user *models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Email
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
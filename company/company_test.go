@@ -0,0 +1,178 @@
+package company
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Company{}, &models.User{}, &models.CompanyInvitation{}, &models.Order{}, &models.OrderItem{}, &models.ProductVariant{}, &models.OrderStatusChange{}))
+	return db
+}
+
+func setupService(db *gorm.DB) *Service {
+	s := NewService(db, &cfg.CompanyConfig{InvitationExpiryHours: 168}, nil)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// createCompany creates a company approving orders above threshold, with an
+// owner
+func createCompany(t *testing.T, db *gorm.DB, threshold float64) (*models.Company, *models.User) {
+	company := models.Company{Name: "Acme Ltd", ApprovalThreshold: threshold}
+	require.NoError(t, db.Create(&company).Error)
+	owner := models.User{Email: "owner@acme.test", Password: "x", UserType: models.Wholesaler, IsActive: true,
+		CompanyID: &company.ID, Role: models.CompanyRoleOwner}
+	require.NoError(t, db.Create(&owner).Error)
+	return &company, &owner
+}
+
+func createBuyer(t *testing.T, db *gorm.DB, company *models.Company, limit float64) *models.User {
+	buyer := models.User{Email: "buyer@acme.test", Password: "x", UserType: models.Customer, IsActive: true,
+		CompanyID: &company.ID, Role: models.CompanyRoleBuyer, SpendLimit: limit}
+	require.NoError(t, db.Create(&buyer).Error)
+	return &buyer
+}
+
+func createInvitation(t *testing.T, db *gorm.DB, company *models.Company, email, token string) {
+	invitation := models.CompanyInvitation{CompanyID: company.ID, Email: email, SpendLimit: 250, TokenHash: hashToken(token),
+		ExpiresAt: testNow.Add(time.Hour)}
+	require.NoError(t, db.Create(&invitation).Error)
+}
+
+func TestInviteRequiresOwner(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	company, owner := createCompany(t, db, 0)
+	buyer := createBuyer(t, db, company, 0)
+
+	_, err := s.Invite(context.Background(), buyer.ID, InviteRequest{Email: "new@acme.test"})
+	assert.ErrorIs(t, err, ErrNotOwner)
+
+	invitation, err := s.Invite(context.Background(), owner.ID, InviteRequest{Email: " New@Acme.test ", SpendLimit: 100})
+	require.NoError(t, err)
+	assert.Equal(t, "new@acme.test", invitation.Email)
+	assert.Equal(t, testNow.Add(168*time.Hour), invitation.ExpiresAt)
+
+	_, err = s.Invite(context.Background(), owner.ID, InviteRequest{Email: buyer.Email})
+	assert.ErrorIs(t, err, ErrAlreadyInCompany)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	company, _ := createCompany(t, db, 0)
+	createInvitation(t, db, company, "new@acme.test", "secret")
+	other := models.User{Email: "other@example.com", Password: "x", UserType: models.Customer, IsActive: true}
+	user := models.User{Email: "NEW@acme.test", Password: "x", UserType: models.Customer, IsActive: true}
+	require.NoError(t, db.Create(&other).Error)
+	require.NoError(t, db.Create(&user).Error)
+
+	_, err := s.AcceptInvitation(context.Background(), user.ID, "wrong")
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+	_, err = s.AcceptInvitation(context.Background(), other.ID, "secret")
+	assert.ErrorIs(t, err, ErrInvitationEmail)
+
+	joined, err := s.AcceptInvitation(context.Background(), user.ID, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, company.ID, joined.ID)
+	require.NoError(t, db.First(&user, user.ID).Error)
+	assert.Equal(t, models.CompanyRoleBuyer, user.Role)
+	assert.Equal(t, 250.0, user.SpendLimit)
+	assert.Equal(t, models.Customer, user.UserType)
+
+	_, err = s.AcceptInvitation(context.Background(), user.ID, "secret")
+	assert.ErrorIs(t, err, ErrInvalidInvitation, "an invitation is accepted once")
+}
+
+func TestRemoveLastOwner(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	company, owner := createCompany(t, db, 0)
+	buyer := createBuyer(t, db, company, 0)
+
+	assert.ErrorIs(t, s.RemoveMember(context.Background(), owner.ID, owner.ID), ErrLastOwner)
+	_, err := s.UpdateMember(context.Background(), owner.ID, owner.ID, MemberUpdate{Role: models.CompanyRoleBuyer})
+	assert.ErrorIs(t, err, ErrLastOwner)
+
+	require.NoError(t, s.RemoveMember(context.Background(), owner.ID, buyer.ID))
+	require.NoError(t, db.First(buyer, buyer.ID).Error)
+	assert.Nil(t, buyer.CompanyID)
+}
+
+func TestCheckOrderSpendLimit(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	company, owner := createCompany(t, db, 0)
+	buyer := createBuyer(t, db, company, 500)
+	orders := []models.Order{
+		{OrderNumber: "ORD-1", UserID: buyer.ID, CompanyID: &company.ID, Status: models.OrderStatusDelivered, FinalAmount: 300, OrderDate: testNow.AddDate(0, 0, -2)},
+		{OrderNumber: "ORD-2", UserID: buyer.ID, CompanyID: &company.ID, Status: models.OrderStatusCancelled, FinalAmount: 400, OrderDate: testNow.AddDate(0, 0, -1)},
+		{OrderNumber: "ORD-3", UserID: buyer.ID, CompanyID: &company.ID, Status: models.OrderStatusDelivered, FinalAmount: 400, OrderDate: testNow.AddDate(0, -1, 0)},
+	}
+	require.NoError(t, db.Create(&orders).Error)
+
+	_, err := s.CheckOrder(db, buyer, 200)
+	assert.NoError(t, err, "cancelled and last month's orders do not count")
+	_, err = s.CheckOrder(db, buyer, 200.01)
+	assert.ErrorIs(t, err, ErrSpendLimitExceeded)
+
+	_, err = s.CheckOrder(db, owner, 10000)
+	assert.NoError(t, err, "owners are not limited")
+}
+
+func TestCheckOrderThreshold(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	company, owner := createCompany(t, db, 1000)
+	buyer := createBuyer(t, db, company, 0)
+
+	needsApproval, err := s.CheckOrder(db, buyer, 1000)
+	require.NoError(t, err)
+	assert.False(t, needsApproval)
+	needsApproval, err = s.CheckOrder(db, buyer, 1000.01)
+	require.NoError(t, err)
+	assert.True(t, needsApproval)
+	needsApproval, err = s.CheckOrder(db, owner, 5000)
+	require.NoError(t, err)
+	assert.False(t, needsApproval, "owners do not need approval")
+}
+
+func TestDecide(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	company, owner := createCompany(t, db, 1000)
+	buyer := createBuyer(t, db, company, 0)
+	order := models.Order{OrderNumber: "ORD-1", UserID: buyer.ID, CompanyID: &company.ID, Status: models.OrderStatusPendingApproval, FinalAmount: 1500}
+	require.NoError(t, db.Create(&order).Error)
+
+	_, err := s.Decide(db, buyer.ID, order.ID, true, "")
+	assert.ErrorIs(t, err, ErrNotOwner)
+
+	pending, err := s.PendingApprovals(context.Background(), owner.ID)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	decided, err := s.Decide(db, owner.ID, order.ID, true, "ok")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPending, decided.Status)
+	require.NoError(t, db.First(&order, order.ID).Error)
+	assert.Equal(t, models.OrderStatusPending, order.Status)
+	assert.Equal(t, owner.ID, *order.ApproverID)
+	assert.Equal(t, "ok", order.ApprovalNote)
+
+	_, err = s.Decide(db, owner.ID, order.ID, false, "")
+	assert.ErrorIs(t, err, ErrNotAwaitingApproval, "a decision is made once")
+}
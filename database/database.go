@@ -3,8 +3,12 @@ package database
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/logging"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -39,10 +43,17 @@ func ConnectDB() (*gorm.DB, error) {
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		PrepareStmt: false,
+		Logger:      logging.NewGormLogger(200 * time.Millisecond),
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Use(metrics.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, err
+	}
 	// Always run migrations for email models
 
 	if gin.Mode() == gin.ReleaseMode {
@@ -65,6 +76,8 @@ func ConnectDB() (*gorm.DB, error) {
 			&models.Invoice{},
 			&models.PurchaseOrder{},
 			&models.POItem{},
+			&models.GoodsReceipt{},
+			&models.GoodsReceiptItem{},
 			&models.Supplier{},
 			&models.SupplierContact{},
 			&models.Document{},
@@ -81,6 +94,68 @@ func ConnectDB() (*gorm.DB, error) {
 			&models.ProductVariantPriceTier{},
 			&models.Wishlist{},
 			&models.WishlistItem{},
+			&models.Quote{},
+			&models.QuoteItem{},
+			&models.PriceList{},
+			&models.PriceListItem{},
+			&models.TaxRate{},
+			&models.RefreshToken{},
+			&models.TwoFactorAuth{},
+			&models.TwoFactorBackupCode{},
+			&models.TwoFactorChallenge{},
+			&models.OAuthIdentity{},
+			&models.SMSMessage{},
+			&models.PhoneVerification{},
+			&models.RoleScope{},
+			&models.ImpersonationLog{},
+			&models.AuditLog{},
+			&models.EmailSuppression{},
+			&models.Notification{},
+			&models.SLAPolicy{},
+			&models.CannedResponse{},
+			&models.TicketMerge{},
+			&models.SupportUpload{},
+			&models.ProductSummary{},
+			&models.OutboxMessage{},
+			&models.WebhookSubscription{},
+			&models.WebhookDelivery{},
+			&models.PickList{},
+			&models.Shipment{},
+			&models.StockAllocation{},
+			&models.StockCount{},
+			&models.StockCountLine{},
+			&models.DeliveryZone{},
+			&models.DeliveryWindow{},
+			&models.DeliverySlot{},
+			&models.Segment{},
+			&models.Campaign{},
+			&models.CampaignRecipient{},
+			&models.LoyaltyTransaction{},
+			&models.Referral{},
+			&models.Coupon{},
+			&models.GiftCard{},
+			&models.GiftCardTransaction{},
+			&models.Banner{},
+			&models.Collection{},
+			&models.CollectionProduct{},
+			&models.OrderMessage{},
+			&models.OrderMessageAttachment{},
+			&models.FraudCheck{},
+			&models.CompanyInvitation{},
+			&models.Setting{},
+			&models.OrderStatusChange{},
+			&models.OrderNote{},
+			&models.ReviewReply{},
+			&models.EnforcementAction{},
+			&models.UserStrike{},
+			&models.UserRestriction{},
+			&models.Checkout{},
+			&models.Subscription{},
+			&models.SubscriptionItem{},
+			&models.BundleComponent{},
+			&models.StoredFile{},
+			&models.ProductTranslation{},
+			&models.CategoryTranslation{},
 
 			&models.Email{},
 			&models.EmailTemplate{},
@@ -5,7 +5,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +15,7 @@ import (
 type Migration struct {
 	ID        uint   `gorm:"primaryKey"`
 	Name      string `gorm:"uniqueIndex"`
+	Checksum  string `gorm:"size:64"` // SHA-256 of the up script of SQL migrations, empty for Go migrations
 	CreatedAt time.Time
 }
 
@@ -21,37 +24,123 @@ func (Migration) TableName() string {
 	return "migrations"
 }
 
-// RunMigrations runs all database migrations
+// goMigration is a migration written as a Go function
+type goMigration struct {
+	name string
+	fn   func(*gorm.DB) error
+}
+
+// goMigrations lists the Go migrations in order. SQL migrations in the sql
+// directory are merged with them by version when migrations are run.
+var goMigrations = []goMigration{
+	{"001_create_review_tables", createReviewTables},
+	{"002_create_review_indexes", createReviewIndexes},
+	{"003_create_review_constraints", createReviewConstraints},
+	{"004_add_review_moderation_log", addReviewModerationLog},
+	{"005_optimize_review_queries", optimizeReviewQueries},
+	{"006_add_user_avatar", addUserAvatar},
+	{"007_create_payment_tables", createPaymentTables},
+	{"008_add_revolut_order_fields", addRevolutOrderFields},
+	{"009_create_email_tables", createEmailTables},
+	{"010_create_email_indexes", createEmailIndexes},
+	{"011_create_wishlist_tables", createWishlistTables},
+	{"012_create_support_tables", createSupportTables},
+	{"013_create_password_reset_table", createPasswordResetTable},
+	{"014_add_product_variant_quantity_in_stock", addProductVariantQuantityInStock},
+	{"015_create_quote_tables", createQuoteTables},
+	{"016_create_price_list_tables", createPriceListTables},
+	{"017_create_tax_tables", createTaxTables},
+	{"018_create_refresh_tokens_table", createRefreshTokensTable},
+	{"019_create_two_factor_tables", createTwoFactorTables},
+	{"020_create_role_scopes_table", createRoleScopesTable},
+	{"021_create_impersonation_logs_table", createImpersonationLogsTable},
+	{"022_create_audit_logs_table", createAuditLogsTable},
+	{"023_add_email_scheduling", addEmailScheduling},
+	{"024_add_email_priority", addEmailPriority},
+	{"025_add_email_template_versions", addEmailTemplateVersions},
+	{"026_add_email_dead_letters", addEmailDeadLetters},
+	{"027_create_email_suppressions_table", createEmailSuppressionsTable},
+	{"028_create_notifications_table", createNotificationsTable},
+	{"029_add_support_sla", addSupportSLA},
+	{"030_create_canned_responses_table", createCannedResponsesTable},
+	{"031_add_support_response_email_message_id", addSupportResponseEmailMessageID},
+	{"032_add_ticket_merges_and_links", addTicketMergesAndLinks},
+	{"033_create_support_uploads_table", createSupportUploadsTable},
+	{"034_add_email_request_id", addEmailRequestID},
+	{"035_create_product_summaries_table", createProductSummariesTable},
+	{"036_create_outbox_messages_table", createOutboxMessagesTable},
+	{"037_create_webhook_tables", createWebhookTables},
+	{"039_create_fulfillment_tables", createFulfillmentTables},
+	{"040_create_stock_count_tables", createStockCountTables},
+	{"041_create_purchasing_tables", createPurchasingTables},
+	{"042_create_pick_list_tables", createPickListTables},
+	{"043_add_address_book_fields", addAddressBookFields},
+	{"044_create_delivery_slot_tables", createDeliverySlotTables},
+	{"045_create_campaign_tables", createCampaignTables},
+	{"046_create_loyalty_tables", createLoyaltyTables},
+	{"047_create_referral_tables", createReferralTables},
+	{"048_create_gift_card_tables", createGiftCardTables},
+	{"049_create_cms_tables", createCMSTables},
+	{"050_add_category_paths", addCategoryPaths},
+	{"051_add_brand_page_fields", addBrandPageFields},
+	{"052_add_product_launch_windows", addProductLaunchWindows},
+	{"053_create_order_messages", createOrderMessages},
+	{"054_create_fraud_checks", createFraudChecks},
+	{"055_add_payment_capture_mode", addPaymentCaptureMode},
+	{"056_add_delivery_zone_warehouses", addDeliveryZoneWarehouses},
+	{"057_add_company_buyers", addCompanyBuyers},
+	{"058_create_settings", createSettings},
+	{"059_create_order_history", createOrderHistory},
+	{"060_create_review_replies", createReviewReplies},
+	{"061_add_review_assignment", addReviewAssignment},
+	{"062_create_enforcement_actions", createEnforcementActions},
+	{"063_create_user_strikes", createUserStrikes},
+	{"064_create_checkouts", createCheckouts},
+	{"065_create_subscriptions", createSubscriptions},
+	{"066_create_bundle_components", createBundleComponents},
+	{"067_add_product_image_sizes", addProductImageSizes},
+	{"068_create_stored_files", createStoredFiles},
+	{"069_create_translations", createTranslations},
+	{"070_add_email_verification", addEmailVerification},
+	{"071_create_oauth_identities", createOAuthIdentities},
+	{"072_create_sms_tables", createSMSTables},
+	{"073_add_text_order_updates", addTextOrderUpdates},
+	{"074_add_email_recipients", addEmailRecipients},
+	{"075_add_record_versions", addRecordVersions},
+	{"077_create_product_overall_ratings", createProductOverallRatings},
+	{"078_add_order_payment_retry_claims", addOrderPaymentRetryClaims},
+}
+
+// goRollbacks are the rollback functions of Go migrations. A Go migration can
+// also be rolled back by a down-only SQL file with its name.
+var goRollbacks = map[string]func(*gorm.DB) error{
+	"001_create_review_tables":      rollbackReviewTables,
+	"002_create_review_indexes":     rollbackReviewIndexes,
+	"003_create_review_constraints": rollbackReviewConstraints,
+	"004_add_review_moderation_log": rollbackReviewModerationLog,
+	"005_optimize_review_queries":   rollbackReviewQueries,
+	"006_add_user_avatar":           rollbackUserAvatar,
+}
+
+// RunMigrations runs all pending Go and SQL migrations in version order,
+// after checking that applied SQL migrations have not been edited
 func RunMigrations(db *gorm.DB) error {
 	// Create migrations table if it doesn't exist
 	if err := db.AutoMigrate(&Migration{}); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	// Define all migrations in order
-	migrations := []struct {
-		name string
-		fn   func(*gorm.DB) error
-	}{
-		{"001_create_review_tables", createReviewTables},
-		{"002_create_review_indexes", createReviewIndexes},
-		{"003_create_review_constraints", createReviewConstraints},
-		{"004_add_review_moderation_log", addReviewModerationLog},
-		{"005_optimize_review_queries", optimizeReviewQueries},
-		{"006_add_user_avatar", addUserAvatar},
-		{"007_create_payment_tables", createPaymentTables},
-		{"008_add_revolut_order_fields", addRevolutOrderFields},
-		{"009_create_email_tables", createEmailTables},
-		{"010_create_email_indexes", createEmailIndexes},
-		{"011_create_wishlist_tables", createWishlistTables},
-		{"012_create_support_tables", createSupportTables},
-		{"013_create_password_reset_table", createPasswordResetTable},
-		{"014_add_product_variant_quantity_in_stock", addProductVariantQuantityInStock},
+	plan, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksums(db, plan); err != nil {
+		return err
 	}
 
 	// Run each migration
-	for _, migration := range migrations {
-		if err := runMigration(db, migration.name, migration.fn); err != nil {
+	for _, migration := range plan {
+		if err := migration.run(db); err != nil {
 			return fmt.Errorf("failed to run migration %s: %w", migration.name, err)
 		}
 	}
@@ -407,7 +496,8 @@ func optimizeReviewQueries(db *gorm.DB) error {
 	return nil
 }
 
-// RollbackMigration rolls back a specific migration
+// RollbackMigration rolls back a specific migration, using its down SQL file
+// or its Go rollback function
 func RollbackMigration(db *gorm.DB, migrationName string) error {
 	// Check if migration exists
 	var migration Migration
@@ -415,28 +505,11 @@ func RollbackMigration(db *gorm.DB, migrationName string) error {
 		return fmt.Errorf("migration %s not found: %w", migrationName, err)
 	}
 
-	// Define rollback functions
-	rollbackFunctions := map[string]func(*gorm.DB) error{
-		"001_create_review_tables":      rollbackReviewTables,
-		"002_create_review_indexes":     rollbackReviewIndexes,
-		"003_create_review_constraints": rollbackReviewConstraints,
-		"004_add_review_moderation_log": rollbackReviewModerationLog,
-		"005_optimize_review_queries":   rollbackReviewQueries,
-		"006_add_user_avatar":           rollbackUserAvatar,
-	}
-
-	rollbackFn, exists := rollbackFunctions[migrationName]
-	if !exists {
-		return fmt.Errorf("no rollback function found for migration %s", migrationName)
-	}
-
-	// Execute rollback
-	if err := rollbackFn(db); err != nil {
-		return fmt.Errorf("failed to rollback migration %s: %w", migrationName, err)
+	plan, err := loadMigrations()
+	if err != nil {
+		return err
 	}
-
-	// Remove migration record
-	return db.Delete(&migration).Error
+	return rollbackMigration(db, &migration, findMigration(plan, migrationName))
 }
 
 // rollbackReviewTables drops the review tables
@@ -852,3 +925,732 @@ func addProductVariantQuantityInStock(db *gorm.DB) error {
 	fmt.Println("Successfully added quantity_in_stock field to product_variants table")
 	return nil
 }
+
+// createQuoteTables creates the B2B quote request tables
+func createQuoteTables(db *gorm.DB) error {
+	// Create Quote table
+	if err := db.AutoMigrate(&models.Quote{}); err != nil {
+		return fmt.Errorf("failed to create quotes table: %w", err)
+	}
+
+	// Create QuoteItem table
+	if err := db.AutoMigrate(&models.QuoteItem{}); err != nil {
+		return fmt.Errorf("failed to create quote_items table: %w", err)
+	}
+
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_quotes_user_id ON quotes(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_quotes_status ON quotes(status)",
+		"CREATE INDEX IF NOT EXISTS idx_quotes_valid_until ON quotes(valid_until)",
+		"CREATE INDEX IF NOT EXISTS idx_quote_items_quote_id ON quote_items(quote_id)",
+	}
+
+	for _, index := range indexes {
+		if err := db.Exec(index).Error; err != nil {
+			return fmt.Errorf("failed to create quote index: %w", err)
+		}
+	}
+
+	fmt.Println("Successfully created quote tables and indexes")
+	return nil
+}
+
+// createPriceListTables creates the customer-specific price list tables
+func createPriceListTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.PriceList{}); err != nil {
+		return fmt.Errorf("failed to create price_lists table: %w", err)
+	}
+
+	if err := db.AutoMigrate(&models.PriceListItem{}); err != nil {
+		return fmt.Errorf("failed to create price_list_items table: %w", err)
+	}
+
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_price_lists_company_id ON price_lists(company_id)",
+		"CREATE INDEX IF NOT EXISTS idx_price_lists_user_id ON price_lists(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_price_lists_active_dates ON price_lists(is_active, starts_at, ends_at)",
+		"CREATE INDEX IF NOT EXISTS idx_price_list_items_price_list_id ON price_list_items(price_list_id)",
+		"CREATE INDEX IF NOT EXISTS idx_price_list_items_variant_id ON price_list_items(product_variant_id)",
+	}
+
+	for _, index := range indexes {
+		if err := db.Exec(index).Error; err != nil {
+			return fmt.Errorf("failed to create price list index: %w", err)
+		}
+	}
+
+	fmt.Println("Successfully created price list tables and indexes")
+	return nil
+}
+
+// createTaxTables creates the VAT rate table and adds tax breakdown columns to orders and invoices
+func createTaxTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.TaxRate{}); err != nil {
+		return fmt.Errorf("failed to create tax_rates table: %w", err)
+	}
+
+	if err := db.AutoMigrate(&models.Order{}, &models.OrderItem{}, &models.Invoice{}); err != nil {
+		return fmt.Errorf("failed to add tax columns: %w", err)
+	}
+
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_tax_rates_is_active ON tax_rates(is_active)",
+		"CREATE INDEX IF NOT EXISTS idx_orders_tax_country ON orders(tax_country)",
+	}
+
+	for _, index := range indexes {
+		if err := db.Exec(index).Error; err != nil {
+			return fmt.Errorf("failed to create tax index: %w", err)
+		}
+	}
+
+	fmt.Println("Successfully created tax tables and indexes")
+	return nil
+}
+
+// createRefreshTokensTable creates the refresh token (session) table
+func createRefreshTokensTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.RefreshToken{}); err != nil {
+		return fmt.Errorf("failed to create refresh_tokens table: %w", err)
+	}
+
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_active ON refresh_tokens(user_id, revoked_at, expires_at)",
+	}
+
+	for _, index := range indexes {
+		if err := db.Exec(index).Error; err != nil {
+			return fmt.Errorf("failed to create refresh token index: %w", err)
+		}
+	}
+
+	fmt.Println("Successfully created refresh tokens table and indexes")
+	return nil
+}
+
+// createTwoFactorTables creates the TOTP enrollment, backup code and login challenge tables
+func createTwoFactorTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.TwoFactorAuth{}, &models.TwoFactorBackupCode{}, &models.TwoFactorChallenge{}); err != nil {
+		return fmt.Errorf("failed to create two factor tables: %w", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}); err != nil {
+		return fmt.Errorf("failed to add two factor columns: %w", err)
+	}
+
+	fmt.Println("Successfully created two factor tables")
+	return nil
+}
+
+// createRoleScopesTable creates the role to permission scope mapping table
+func createRoleScopesTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.RoleScope{}); err != nil {
+		return fmt.Errorf("failed to create role_scopes table: %w", err)
+	}
+
+	fmt.Println("Successfully created role scopes table")
+	return nil
+}
+
+// createImpersonationLogsTable creates the impersonation audit table
+func createImpersonationLogsTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ImpersonationLog{}); err != nil {
+		return fmt.Errorf("failed to create impersonation_logs table: %w", err)
+	}
+
+	fmt.Println("Successfully created impersonation logs table")
+	return nil
+}
+
+// createAuditLogsTable creates the audit log table for staff mutations
+func createAuditLogsTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.AuditLog{}); err != nil {
+		return fmt.Errorf("failed to create audit_logs table: %w", err)
+	}
+
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs(entity_type, entity_id)").Error; err != nil {
+		return fmt.Errorf("failed to create audit log entity index: %w", err)
+	}
+
+	fmt.Println("Successfully created audit logs table")
+	return nil
+}
+
+// addEmailScheduling adds the send_at column for delayed emails and the
+// pending abandoned-cart email reference on carts
+func addEmailScheduling(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Email{}); err != nil {
+		return fmt.Errorf("failed to add send_at to emails table: %w", err)
+	}
+
+	if err := db.AutoMigrate(&models.Cart{}); err != nil {
+		return fmt.Errorf("failed to add recovery_email_id to carts table: %w", err)
+	}
+
+	fmt.Println("Successfully added email scheduling columns")
+	return nil
+}
+
+// addEmailPriority adds the queue lane column to emails
+func addEmailPriority(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Email{}); err != nil {
+		return fmt.Errorf("failed to add priority to emails table: %w", err)
+	}
+
+	fmt.Println("Successfully added email priority column")
+	return nil
+}
+
+// addEmailTemplateVersions makes each (name, version) pair of an email template unique
+func addEmailTemplateVersions(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.EmailTemplate{}); err != nil {
+		return fmt.Errorf("failed to migrate email_templates table: %w", err)
+	}
+
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_email_templates_name_version ON email_templates(name, version)").Error; err != nil {
+		return fmt.Errorf("failed to create email template version index: %w", err)
+	}
+
+	fmt.Println("Successfully added email template versions")
+	return nil
+}
+
+// addEmailDeadLetters adds the columns recording why and when an email was dead-lettered
+func addEmailDeadLetters(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Email{}); err != nil {
+		return fmt.Errorf("failed to add dead letter columns to emails table: %w", err)
+	}
+
+	fmt.Println("Successfully added email dead letter columns")
+	return nil
+}
+
+// createEmailSuppressionsTable creates the list of addresses that no email is sent to
+func createEmailSuppressionsTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.EmailSuppression{}); err != nil {
+		return fmt.Errorf("failed to create email_suppressions table: %w", err)
+	}
+
+	fmt.Println("Successfully created email suppressions table")
+	return nil
+}
+
+// createNotificationsTable creates the in-app notification table
+func createNotificationsTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Notification{}); err != nil {
+		return fmt.Errorf("failed to create notifications table: %w", err)
+	}
+
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id, read_at)").Error; err != nil {
+		return fmt.Errorf("failed to create notification unread index: %w", err)
+	}
+
+	fmt.Println("Successfully created notifications table")
+	return nil
+}
+
+// addSupportSLA creates the SLA policy table and the SLA deadline columns on tickets and disputes
+func addSupportSLA(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.SLAPolicy{}, &models.SupportTicket{}, &models.Dispute{}); err != nil {
+		return fmt.Errorf("failed to add support SLA tables: %w", err)
+	}
+
+	fmt.Println("Successfully added support SLA tracking")
+	return nil
+}
+
+// createCannedResponsesTable creates the support agents' canned response library
+func createCannedResponsesTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.CannedResponse{}); err != nil {
+		return fmt.Errorf("failed to create canned_responses table: %w", err)
+	}
+
+	fmt.Println("Successfully created canned responses table")
+	return nil
+}
+
+// addSupportResponseEmailMessageID records which inbound email a ticket or dispute response came from
+func addSupportResponseEmailMessageID(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.TicketResponse{}, &models.DisputeResponse{}); err != nil {
+		return fmt.Errorf("failed to add email message id to support responses: %w", err)
+	}
+
+	fmt.Println("Successfully added email message id to support responses")
+	return nil
+}
+
+// addTicketMergesAndLinks adds ticket merge records and the dispute and merge links on tickets
+func addTicketMergesAndLinks(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.SupportTicket{}, &models.TicketMerge{}); err != nil {
+		return fmt.Errorf("failed to add ticket merges: %w", err)
+	}
+
+	fmt.Println("Successfully added ticket merges and links")
+	return nil
+}
+
+// createSupportUploadsTable creates the table tracking uploaded support attachments
+func createSupportUploadsTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.SupportUpload{}); err != nil {
+		return fmt.Errorf("failed to create support_uploads table: %w", err)
+	}
+
+	fmt.Println("Successfully created support uploads table")
+	return nil
+}
+
+// addEmailRequestID records the HTTP request an email was triggered by
+func addEmailRequestID(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Email{}); err != nil {
+		return fmt.Errorf("failed to add request_id to emails table: %w", err)
+	}
+
+	fmt.Println("Successfully added email request id column")
+	return nil
+}
+
+// createProductSummariesTable creates the denormalized product listing table.
+// Rows are filled by the catalog summary refresher on startup.
+func createProductSummariesTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ProductSummary{}); err != nil {
+		return fmt.Errorf("failed to create product_summaries table: %w", err)
+	}
+
+	fmt.Println("Successfully created product summaries table")
+	return nil
+}
+
+// createOutboxMessagesTable creates the transactional outbox for emails and webhooks
+func createOutboxMessagesTable(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.OutboxMessage{}); err != nil {
+		return fmt.Errorf("failed to create outbox_messages table: %w", err)
+	}
+
+	fmt.Println("Successfully created outbox messages table")
+	return nil
+}
+
+// createWebhookTables creates the outbound webhook subscription and delivery log tables
+func createWebhookTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}); err != nil {
+		return fmt.Errorf("failed to create webhook tables: %w", err)
+	}
+
+	fmt.Println("Successfully created webhook tables")
+	return nil
+}
+
+// createFulfillmentTables creates the shipment and stock allocation tables
+func createFulfillmentTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Shipment{}, &models.StockAllocation{}); err != nil {
+		return fmt.Errorf("failed to create fulfillment tables: %w", err)
+	}
+
+	fmt.Println("Successfully created fulfillment tables")
+	return nil
+}
+
+// createStockCountTables creates the stocktake tables
+func createStockCountTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.StockCount{}, &models.StockCountLine{}); err != nil {
+		return fmt.Errorf("failed to create stock count tables: %w", err)
+	}
+
+	fmt.Println("Successfully created stock count tables")
+	return nil
+}
+
+// createPurchasingTables creates the supplier and purchase order tables, or
+// adds the goods receipt tables and the columns they need to existing ones
+func createPurchasingTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(
+		&models.Supplier{},
+		&models.SupplierContact{},
+		&models.PurchaseOrder{},
+		&models.POItem{},
+		&models.GoodsReceipt{},
+		&models.GoodsReceiptItem{},
+	); err != nil {
+		return fmt.Errorf("failed to create purchasing tables: %w", err)
+	}
+
+	fmt.Println("Successfully created purchasing tables")
+	return nil
+}
+
+// createPickListTables creates the pick list table and adds the picking and
+// packing columns to shipments, allocations and inventory items
+func createPickListTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.PickList{}, &models.Shipment{}, &models.StockAllocation{}, &models.InventoryItem{}); err != nil {
+		return fmt.Errorf("failed to create pick list tables: %w", err)
+	}
+
+	fmt.Println("Successfully created pick list tables")
+	return nil
+}
+
+// addAddressBookFields adds default billing, label, phone and geocoding fields
+// to addresses and a billing address to orders. A user's existing default
+// address becomes their default billing address too.
+func addAddressBookFields(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Address{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to add address book fields: %w", err)
+	}
+	if err := db.Exec("UPDATE addresses SET is_default_billing = TRUE WHERE is_default = TRUE AND user_id IS NOT NULL").Error; err != nil {
+		return fmt.Errorf("failed to backfill default billing addresses: %w", err)
+	}
+
+	fmt.Println("Successfully added address book fields")
+	return nil
+}
+
+// createDeliverySlotTables creates the delivery zone, window and slot tables
+// and adds the booked delivery slot to orders
+func createDeliverySlotTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.DeliveryZone{}, &models.DeliveryWindow{}, &models.DeliverySlot{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to create delivery slot tables: %w", err)
+	}
+
+	fmt.Println("Successfully created delivery slot tables")
+	return nil
+}
+
+// createCampaignTables creates the segment, campaign and campaign recipient
+// tables and adds the marketing opt-out to users
+func createCampaignTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Segment{}, &models.Campaign{}, &models.CampaignRecipient{}, &models.User{}); err != nil {
+		return fmt.Errorf("failed to create campaign tables: %w", err)
+	}
+
+	fmt.Println("Successfully created campaign tables")
+	return nil
+}
+
+// createLoyaltyTables creates the loyalty points ledger and adds the points
+// redeemed to orders
+func createLoyaltyTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.LoyaltyTransaction{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to create loyalty tables: %w", err)
+	}
+
+	fmt.Println("Successfully created loyalty tables")
+	return nil
+}
+
+func createReferralTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Referral{}, &models.Coupon{}, &models.User{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to create referral tables: %w", err)
+	}
+
+	fmt.Println("Successfully created referral tables")
+	return nil
+}
+
+func createGiftCardTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.GiftCard{}, &models.GiftCardTransaction{}, &models.Product{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to create gift card tables: %w", err)
+	}
+
+	fmt.Println("Successfully created gift card tables")
+	return nil
+}
+
+func createCMSTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Banner{}, &models.Collection{}, &models.CollectionProduct{}); err != nil {
+		return fmt.Errorf("failed to create CMS tables: %w", err)
+	}
+
+	fmt.Println("Successfully created CMS tables")
+	return nil
+}
+
+// addCategoryPaths adds the materialized path, depth and sibling position of
+// categories and fills in the paths of existing categories from parent_id
+func addCategoryPaths(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Category{}); err != nil {
+		return fmt.Errorf("failed to add category path columns: %w", err)
+	}
+	if err := catalog.RebuildCategoryPaths(db); err != nil {
+		return fmt.Errorf("failed to build category paths: %w", err)
+	}
+
+	fmt.Println("Successfully added category paths")
+	return nil
+}
+
+func addBrandPageFields(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Brand{}); err != nil {
+		return fmt.Errorf("failed to add brand page fields: %w", err)
+	}
+
+	fmt.Println("Successfully added brand page fields")
+	return nil
+}
+
+func addProductLaunchWindows(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Product{}, &models.ProductSummary{}); err != nil {
+		return fmt.Errorf("failed to add product launch windows: %w", err)
+	}
+
+	fmt.Println("Successfully added product launch windows")
+	return nil
+}
+
+func createOrderMessages(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.OrderMessage{}, &models.OrderMessageAttachment{}); err != nil {
+		return fmt.Errorf("failed to create order message tables: %w", err)
+	}
+
+	fmt.Println("Successfully created order message tables")
+	return nil
+}
+
+func createFraudChecks(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.FraudCheck{}); err != nil {
+		return fmt.Errorf("failed to create fraud check table: %w", err)
+	}
+
+	fmt.Println("Successfully created fraud check table")
+	return nil
+}
+
+func addPaymentCaptureMode(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Payment{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to add payment capture mode: %w", err)
+	}
+
+	fmt.Println("Successfully added payment capture mode")
+	return nil
+}
+
+func addDeliveryZoneWarehouses(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.DeliveryZone{}); err != nil {
+		return fmt.Errorf("failed to add delivery zone warehouses: %w", err)
+	}
+
+	fmt.Println("Successfully added delivery zone warehouses")
+	return nil
+}
+
+func addCompanyBuyers(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Company{}, &models.User{}, &models.CompanyInvitation{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to add company buyers: %w", err)
+	}
+
+	fmt.Println("Successfully added company buyers")
+	return nil
+}
+
+func createSettings(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Setting{}, &models.Invoice{}); err != nil {
+		return fmt.Errorf("failed to create settings table: %w", err)
+	}
+
+	fmt.Println("Successfully created settings table")
+	return nil
+}
+
+// createOrderHistory adds the status history and internal notes of orders,
+// and links emails to the order they are about for the admin order timeline
+func createOrderHistory(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.OrderStatusChange{}, &models.OrderNote{}, &models.Email{}); err != nil {
+		return fmt.Errorf("failed to create order history tables: %w", err)
+	}
+
+	fmt.Println("Successfully created order history tables")
+	return nil
+}
+
+func createReviewReplies(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ReviewReply{}); err != nil {
+		return fmt.Errorf("failed to create review replies table: %w", err)
+	}
+
+	fmt.Println("Successfully created review replies table")
+	return nil
+}
+
+func addReviewAssignment(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ProductReview{}); err != nil {
+		return fmt.Errorf("failed to add review assignment: %w", err)
+	}
+
+	fmt.Println("Successfully added review assignment")
+	return nil
+}
+
+func createEnforcementActions(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.EnforcementAction{}, &models.User{}); err != nil {
+		return fmt.Errorf("failed to create enforcement actions table: %w", err)
+	}
+
+	fmt.Println("Successfully created enforcement actions table")
+	return nil
+}
+
+func createUserStrikes(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.UserStrike{}, &models.UserRestriction{}); err != nil {
+		return fmt.Errorf("failed to create user strike tables: %w", err)
+	}
+
+	fmt.Println("Successfully created user strike tables")
+	return nil
+}
+
+func createCheckouts(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Checkout{}); err != nil {
+		return fmt.Errorf("failed to create checkouts table: %w", err)
+	}
+
+	fmt.Println("Successfully created checkouts table")
+	return nil
+}
+
+func createSubscriptions(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Subscription{}, &models.SubscriptionItem{}, &models.Order{}); err != nil {
+		return fmt.Errorf("failed to create subscription tables: %w", err)
+	}
+
+	fmt.Println("Successfully created subscription tables")
+	return nil
+}
+
+func createBundleComponents(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.BundleComponent{}, &models.ProductVariant{}); err != nil {
+		return fmt.Errorf("failed to create bundle components table: %w", err)
+	}
+
+	fmt.Println("Successfully created bundle components table")
+	return nil
+}
+
+func addProductImageSizes(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ProductImage{}); err != nil {
+		return fmt.Errorf("failed to add product image sizes: %w", err)
+	}
+
+	fmt.Println("Successfully added product image sizes")
+	return nil
+}
+
+func createStoredFiles(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.StoredFile{}); err != nil {
+		return fmt.Errorf("failed to create stored files table: %w", err)
+	}
+
+	fmt.Println("Successfully created stored files table")
+	return nil
+}
+
+func createTranslations(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ProductTranslation{}, &models.CategoryTranslation{}, &models.User{}); err != nil {
+		return fmt.Errorf("failed to create translation tables: %w", err)
+	}
+
+	fmt.Println("Successfully created translation tables")
+	return nil
+}
+
+// addEmailVerification adds when users verified their email address. Existing
+// users signed up before verification and count as verified.
+func addEmailVerification(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		return fmt.Errorf("failed to add email verification: %w", err)
+	}
+	if err := db.Exec("UPDATE users SET verified_at = created_at WHERE verified_at IS NULL").Error; err != nil {
+		return fmt.Errorf("failed to backfill verified users: %w", err)
+	}
+
+	fmt.Println("Successfully added email verification")
+	return nil
+}
+
+// createOAuthIdentities creates the table linking users to their social
+// sign-in accounts
+func createOAuthIdentities(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.OAuthIdentity{}); err != nil {
+		return fmt.Errorf("failed to create oauth identities table: %w", err)
+	}
+
+	fmt.Println("Successfully created oauth identities table")
+	return nil
+}
+
+// createSMSTables creates the text message delivery log and phone verification
+// codes, and adds when users verified their phone number
+func createSMSTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.SMSMessage{}, &models.PhoneVerification{}, &models.User{}); err != nil {
+		return fmt.Errorf("failed to create sms tables: %w", err)
+	}
+
+	fmt.Println("Successfully created sms tables")
+	return nil
+}
+
+// addTextOrderUpdates adds the channel users get order updates on, when
+// orders went out for delivery, and the channel and delivery status updates
+// of text messages
+func addTextOrderUpdates(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.User{}, &models.Order{}, &models.SMSMessage{}); err != nil {
+		return fmt.Errorf("failed to add text order updates: %w", err)
+	}
+
+	fmt.Println("Successfully added text order updates")
+	return nil
+}
+
+// addEmailRecipients stores the recipient and template data of emails, so
+// admins can search them by recipient and send them again
+func addEmailRecipients(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Email{}); err != nil {
+		return fmt.Errorf("failed to add email recipients: %w", err)
+	}
+
+	fmt.Println("Successfully added email recipients")
+	return nil
+}
+
+// addRecordVersions adds the version products, variants and inventory items
+// are edited at, to refuse edits made on an outdated copy
+func addRecordVersions(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Product{}, &models.ProductVariant{}, &models.InventoryItem{}); err != nil {
+		return fmt.Errorf("failed to add record versions: %w", err)
+	}
+
+	fmt.Println("Successfully added record versions")
+	return nil
+}
+
+// createProductOverallRatings creates the overall rating of products across
+// their variants and rolls it up for the products whose variants are rated
+func createProductOverallRatings(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.ProductOverallRating{}); err != nil {
+		return fmt.Errorf("failed to create product_overall_ratings table: %w", err)
+	}
+	var productIDs []uint
+	err := db.Model(&models.ProductRating{}).
+		Joins("JOIN product_variants ON product_variants.id = product_ratings.product_variant_id AND product_variants.deleted_at IS NULL").
+		Distinct().Pluck("product_variants.product_id", &productIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to find rated products: %w", err)
+	}
+	for _, productID := range productIDs {
+		if err := ratings.RollUp(db, productID); err != nil {
+			return fmt.Errorf("failed to roll up rating of product %d: %w", productID, err)
+		}
+	}
+
+	fmt.Println("Successfully created product overall ratings")
+	return nil
+}
+
+// addOrderPaymentRetryClaims adds when a payment retry claimed an order, so
+// two retries of the same order cannot each open a checkout
+func addOrderPaymentRetryClaims(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Order{}); err != nil {
+		return fmt.Errorf("failed to add order payment retry claims: %w", err)
+	}
+
+	fmt.Println("Successfully added order payment retry claims")
+	return nil
+}
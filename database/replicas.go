@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type readRouteKey struct{}
+
+type readRoute int
+
+const (
+	readFromReplica readRoute = iota + 1
+	readFromPrimary
+)
+
+// WithReplicaReads marks ctx so queries run with db.WithContext(ctx) may read
+// from a replica. Use it for read-heavy requests that tolerate replication lag.
+func WithReplicaReads(ctx context.Context) context.Context {
+	if ForcedPrimary(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, readRouteKey{}, readFromReplica)
+}
+
+// WithPrimary forces queries run with ctx to the primary, even when ctx was
+// marked for replica reads. Use it for flows that read their own writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readRouteKey{}, readFromPrimary)
+}
+
+// ForcedPrimary reports whether ctx was marked with WithPrimary
+func ForcedPrimary(ctx context.Context) bool {
+	route, _ := ctx.Value(readRouteKey{}).(readRoute)
+	return route == readFromPrimary
+}
+
+func replicaReads(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	route, _ := ctx.Value(readRouteKey{}).(readRoute)
+	return route == readFromReplica
+}
+
+// UseReplicas registers read replicas by DSN. Writes, transactions and reads
+// go to the primary unless the statement's context was marked with
+// WithReplicaReads, so only opted-in requests see replication lag.
+func UseReplicas(db *gorm.DB, dsns []string) error {
+	replicas := make([]gorm.Dialector, 0, len(dsns))
+	for _, dsn := range dsns {
+		replicas = append(replicas, postgres.Open(dsn))
+	}
+	return useReplicas(db, replicas)
+}
+
+func useReplicas(db *gorm.DB, replicas []gorm.Dialector) error {
+	if len(replicas) == 0 {
+		return nil
+	}
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	})); err != nil {
+		return err
+	}
+
+	cb := db.Callback()
+	return errors.Join(
+		cb.Query().Before("gorm:query").Register("database:route_reads", routeReads),
+		cb.Row().Before("gorm:row").Register("database:route_reads", routeReads),
+		cb.Raw().Before("gorm:raw").Register("database:route_reads", routeReads),
+	)
+}
+
+// routeReads sends the statement to the primary unless its request opted in
+// to replica reads
+func routeReads(db *gorm.DB) {
+	if !replicaReads(db.Statement.Context) {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
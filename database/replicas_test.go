@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type replicaItem struct {
+	ID   uint
+	Name string
+}
+
+func TestReplicaRouting(t *testing.T) {
+	dir := t.TempDir()
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	replica, err := gorm.Open(sqlite.Open(filepath.Join(dir, "replica.db")), config)
+	require.NoError(t, err)
+	require.NoError(t, replica.AutoMigrate(&replicaItem{}))
+	require.NoError(t, replica.Create(&replicaItem{ID: 1, Name: "replica"}).Error)
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "primary.db")), config)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&replicaItem{}))
+	require.NoError(t, useReplicas(db, []gorm.Dialector{sqlite.Open(filepath.Join(dir, "replica.db"))}))
+	require.NoError(t, db.Create(&replicaItem{ID: 1, Name: "primary"}).Error)
+
+	read := func(ctx context.Context) string {
+		var item replicaItem
+		require.NoError(t, db.WithContext(ctx).First(&item, 1).Error)
+		return item.Name
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, "primary", read(ctx))
+	assert.Equal(t, "replica", read(WithReplicaReads(ctx)))
+	assert.Equal(t, "primary", read(WithPrimary(WithReplicaReads(ctx))))
+	assert.Equal(t, "primary", read(WithReplicaReads(WithPrimary(ctx))))
+
+	var count int64
+	require.NoError(t, db.WithContext(WithReplicaReads(ctx)).Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&replicaItem{}).Where("name = ?", "primary").Count(&count).Error
+	}))
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,2 @@
+-- Serves the delivery history of a subscription, newest first
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_created ON webhook_deliveries (subscription_id, created_at DESC);
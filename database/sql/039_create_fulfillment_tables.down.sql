@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_allocations;
+DROP TABLE IF EXISTS shipments;
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS goods_receipt_items;
+DROP TABLE IF EXISTS goods_receipts;
+ALTER TABLE po_items DROP COLUMN IF EXISTS product_variant_id;
+ALTER TABLE purchase_orders
+    DROP COLUMN IF EXISTS warehouse_id,
+    DROP COLUMN IF EXISTS created_by_id,
+    DROP COLUMN IF EXISTS sent_date,
+    DROP COLUMN IF EXISTS cancelled_date;
+ALTER TABLE suppliers
+    DROP COLUMN IF EXISTS payment_terms,
+    DROP COLUMN IF EXISTS lead_time_days,
+    DROP COLUMN IF EXISTS notes;
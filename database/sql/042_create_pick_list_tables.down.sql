@@ -0,0 +1,11 @@
+ALTER TABLE shipments
+    DROP COLUMN IF EXISTS pick_list_id,
+    DROP COLUMN IF EXISTS picked_at,
+    DROP COLUMN IF EXISTS packed_at;
+ALTER TABLE stock_allocations
+    DROP COLUMN IF EXISTS picked_at,
+    DROP COLUMN IF EXISTS picked_by_id,
+    DROP COLUMN IF EXISTS packed_at,
+    DROP COLUMN IF EXISTS packed_by_id;
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS bin_location;
+DROP TABLE IF EXISTS pick_lists;
@@ -0,0 +1,8 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS billing_address_id;
+ALTER TABLE addresses
+    DROP COLUMN IF EXISTS label,
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS is_default_billing,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS geocoded_at;
@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS delivery_slot_id;
+DROP TABLE IF EXISTS delivery_slots;
+DROP TABLE IF EXISTS delivery_windows;
+DROP TABLE IF EXISTS delivery_zones;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS marketing_opt_out;
+DROP TABLE IF EXISTS campaign_recipients;
+DROP TABLE IF EXISTS campaigns;
+DROP TABLE IF EXISTS segments;
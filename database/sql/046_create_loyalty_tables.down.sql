@@ -0,0 +1,4 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS points_redeemed,
+    DROP COLUMN IF EXISTS points_discount;
+DROP TABLE IF EXISTS loyalty_transactions;
@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS coupon_id,
+    DROP COLUMN IF EXISTS coupon_discount;
+ALTER TABLE users DROP COLUMN IF EXISTS referral_code;
+DROP TABLE IF EXISTS coupons;
+DROP TABLE IF EXISTS referrals;
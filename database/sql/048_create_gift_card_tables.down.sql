@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS gift_card_id,
+    DROP COLUMN IF EXISTS gift_card_amount;
+ALTER TABLE products DROP COLUMN IF EXISTS is_gift_card;
+DROP TABLE IF EXISTS gift_card_transactions;
+DROP TABLE IF EXISTS gift_cards;
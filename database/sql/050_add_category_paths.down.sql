@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_categories_path;
+ALTER TABLE categories
+    DROP COLUMN IF EXISTS path,
+    DROP COLUMN IF EXISTS depth,
+    DROP COLUMN IF EXISTS position;
@@ -0,0 +1,3 @@
+ALTER TABLE brands
+    DROP COLUMN IF EXISTS logo,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_payments_authorized_at;
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS capture_mode,
+    DROP COLUMN IF EXISTS authorized_at,
+    DROP COLUMN IF EXISTS captured_amount;
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS capture_mode;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS delivery_zone_warehouses;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS company_invitations;
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS approver_id,
+    DROP COLUMN IF EXISTS approval_decided_at,
+    DROP COLUMN IF EXISTS approval_note;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS spend_limit;
+ALTER TABLE companies
+    DROP COLUMN IF EXISTS approval_threshold;
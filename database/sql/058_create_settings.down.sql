@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS settings;
+ALTER TABLE invoices
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS issuer_name,
+    DROP COLUMN IF EXISTS issuer_email,
+    DROP COLUMN IF EXISTS issuer_url;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS order_notes;
+DROP TABLE IF EXISTS order_status_changes;
+DROP INDEX IF EXISTS idx_emails_order_id;
+ALTER TABLE emails DROP COLUMN IF EXISTS order_id;
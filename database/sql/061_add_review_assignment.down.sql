@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_product_reviews_assigned_to;
+ALTER TABLE product_reviews
+    DROP COLUMN IF EXISTS assigned_to,
+    DROP COLUMN IF EXISTS assigned_at;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS enforcement_actions;
+ALTER TABLE users DROP COLUMN IF EXISTS strike_count;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_restrictions;
+DROP TABLE IF EXISTS user_strikes;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_orders_subscription_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS subscription_id;
+DROP TABLE IF EXISTS subscription_items;
+DROP TABLE IF EXISTS subscriptions;
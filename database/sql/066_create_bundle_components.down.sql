@@ -0,0 +1,2 @@
+ALTER TABLE product_variants DROP COLUMN IF EXISTS bundle_price;
+DROP TABLE IF EXISTS bundle_components;
@@ -0,0 +1,3 @@
+ALTER TABLE product_images DROP COLUMN IF EXISTS thumbnail_key;
+ALTER TABLE product_images DROP COLUMN IF EXISTS medium_key;
+ALTER TABLE product_images DROP COLUMN IF EXISTS large_key;
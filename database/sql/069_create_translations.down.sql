@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS category_translations;
+DROP TABLE IF EXISTS product_translations;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
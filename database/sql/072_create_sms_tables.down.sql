@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS phone_verifications;
+DROP TABLE IF EXISTS sms_messages;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at;
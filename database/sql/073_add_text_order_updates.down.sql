@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sms_messages_provider_id;
+ALTER TABLE sms_messages DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE sms_messages DROP COLUMN IF EXISTS channel;
+ALTER TABLE orders DROP COLUMN IF EXISTS out_for_delivery_at;
+ALTER TABLE users DROP COLUMN IF EXISTS order_updates_channel;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_emails_recipient_email;
+DROP INDEX IF EXISTS idx_emails_resent_from_id;
+ALTER TABLE emails DROP COLUMN IF EXISTS resent_from_id;
+ALTER TABLE emails DROP COLUMN IF EXISTS template_data;
+ALTER TABLE emails DROP COLUMN IF EXISTS recipient_name;
+ALTER TABLE emails DROP COLUMN IF EXISTS recipient_email;
@@ -0,0 +1,3 @@
+ALTER TABLE products DROP COLUMN IF EXISTS version;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS version;
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_product_reviews_variant_status_verified;
+DROP INDEX IF EXISTS idx_review_images_live_review;
+DROP INDEX IF EXISTS idx_seller_responses_live_review;
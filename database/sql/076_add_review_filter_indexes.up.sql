@@ -0,0 +1,8 @@
+-- Serve the approved reviews of a variant filtered by verified purchase,
+-- newest first. Keyword searches scan the rows this narrows down to.
+CREATE INDEX IF NOT EXISTS idx_product_reviews_variant_status_verified ON product_reviews (product_variant_id, status, is_verified_purchase, created_at DESC) WHERE deleted_at IS NULL;
+
+-- Serve the with_images and with_response filters, which only look at rows
+-- that are not deleted
+CREATE INDEX IF NOT EXISTS idx_review_images_live_review ON review_images (product_review_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_seller_responses_live_review ON seller_responses (product_review_id) WHERE deleted_at IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_gift_card_transactions_order_refund;
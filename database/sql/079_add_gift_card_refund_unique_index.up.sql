@@ -0,0 +1,2 @@
+-- An order's gift card payment is given back at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_gift_card_transactions_order_refund ON gift_card_transactions (order_id, gift_card_id, type) WHERE type = 'refund';
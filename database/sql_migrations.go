@@ -0,0 +1,399 @@
+package database
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sqlFiles holds the SQL migrations. An up file, NNN_name.up.sql, adds a
+// migration and its optional NNN_name.down.sql rolls it back. A down file on
+// its own gives a Go migration of the same name a rollback.
+//
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+// goMigrationSQL stands in for the SQL of Go migrations in dry runs, since it
+// is only generated when they run
+const goMigrationSQL = "-- Go migration, its SQL is generated when it runs"
+
+// MigrationStep is a migration that would be applied or rolled back, with the
+// SQL that would run
+type MigrationStep struct {
+	Name string
+	SQL  string
+}
+
+// plannedMigration is a Go or SQL migration with its rollback
+type plannedMigration struct {
+	name     string
+	version  int
+	fn       func(*gorm.DB) error // Go migrations
+	up       string               // SQL migrations
+	down     string
+	rollback func(*gorm.DB) error // Go rollback, used when there is no down SQL
+}
+
+func (m *plannedMigration) checksum() string {
+	sum := sha256.Sum256([]byte(m.up))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *plannedMigration) canRollback() bool {
+	return m.down != "" || m.rollback != nil
+}
+
+func (m *plannedMigration) upSQL() string {
+	if m.fn != nil {
+		return goMigrationSQL
+	}
+	return m.up
+}
+
+func (m *plannedMigration) downSQL() string {
+	if m.down == "" {
+		return "-- Go rollback function"
+	}
+	return m.down
+}
+
+// run applies the migration unless it has been applied. SQL migrations run in
+// a transaction together with their record.
+func (m *plannedMigration) run(db *gorm.DB) error {
+	if m.fn != nil {
+		return runMigration(db, m.name, m.fn)
+	}
+
+	var count int64
+	db.Model(&Migration{}).Where("name = ?", m.name).Count(&count)
+	if count > 0 {
+		fmt.Printf("Migration %s already applied, skipping\n", m.name)
+		return nil
+	}
+
+	fmt.Printf("Running migration: %s\n", m.name)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(m.up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Migration{Name: m.name, Checksum: m.checksum(), CreatedAt: time.Now()}).Error
+	})
+}
+
+// loadMigrations merges the Go migrations with the embedded SQL files
+func loadMigrations() ([]*plannedMigration, error) {
+	files, err := fs.Sub(sqlFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return planMigrations(goMigrations, files)
+}
+
+// planMigrations merges Go migrations with the SQL files at the root of files
+// and orders them by version
+func planMigrations(goList []goMigration, files fs.FS) ([]*plannedMigration, error) {
+	var plan []*plannedMigration
+	byName := make(map[string]*plannedMigration)
+	add := func(name string) (*plannedMigration, error) {
+		version, err := migrationVersion(name)
+		if err != nil {
+			return nil, err
+		}
+		migration := &plannedMigration{name: name, version: version}
+		byName[name] = migration
+		plan = append(plan, migration)
+		return migration, nil
+	}
+
+	for _, goMigration := range goList {
+		migration, err := add(goMigration.name)
+		if err != nil {
+			return nil, err
+		}
+		migration.fn = goMigration.fn
+		migration.rollback = goRollbacks[goMigration.name]
+	}
+
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SQL migrations: %w", err)
+	}
+	for _, entry := range entries {
+		name, direction, ok := parseSQLFileName(entry.Name())
+		if !ok {
+			return nil, fmt.Errorf("invalid SQL migration file name %s, expected NNN_name.up.sql or NNN_name.down.sql", entry.Name())
+		}
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL migration %s: %w", entry.Name(), err)
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			return nil, fmt.Errorf("SQL migration %s is empty", entry.Name())
+		}
+
+		migration := byName[name]
+		if migration == nil {
+			if migration, err = add(name); err != nil {
+				return nil, err
+			}
+		}
+		if direction == "up" {
+			if migration.fn != nil {
+				return nil, fmt.Errorf("migration %s is defined both in Go and in SQL", name)
+			}
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	versions := make(map[int]string)
+	for _, migration := range plan {
+		if migration.fn == nil && migration.up == "" {
+			return nil, fmt.Errorf("down SQL file for unknown migration %s", migration.name)
+		}
+		if other, exists := versions[migration.version]; exists {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, migration.name)
+		}
+		versions[migration.version] = migration.name
+	}
+
+	sort.Slice(plan, func(i, j int) bool { return plan[i].version < plan[j].version })
+	return plan, nil
+}
+
+// parseSQLFileName splits NNN_name.up.sql into the migration name and "up"
+func parseSQLFileName(file string) (name, direction string, ok bool) {
+	for _, direction := range []string{"up", "down"} {
+		if name, found := strings.CutSuffix(file, "."+direction+".sql"); found {
+			_, err := migrationVersion(name)
+			return name, direction, err == nil
+		}
+	}
+	return "", "", false
+}
+
+// migrationVersion returns the number a migration name starts with
+func migrationVersion(name string) (int, error) {
+	prefix, _, found := strings.Cut(name, "_")
+	version, err := strconv.Atoi(prefix)
+	if !found || err != nil {
+		return 0, fmt.Errorf("migration name %s does not start with a version, e.g. 001_", name)
+	}
+	return version, nil
+}
+
+func findMigration(plan []*plannedMigration, name string) *plannedMigration {
+	for _, migration := range plan {
+		if migration.name == name {
+			return migration
+		}
+	}
+	return nil
+}
+
+// appliedMigrations returns the recorded migrations, newest version first
+func appliedMigrations(db *gorm.DB) ([]Migration, error) {
+	if !db.Migrator().HasTable(&Migration{}) {
+		return nil, nil
+	}
+	var applied []Migration
+	if err := db.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(applied, func(i, j int) bool {
+		vi, _ := migrationVersion(applied[i].Name)
+		vj, _ := migrationVersion(applied[j].Name)
+		return vi > vj
+	})
+	return applied, nil
+}
+
+// VerifyChecksums checks that the SQL of applied SQL migrations has not been
+// changed since they ran
+func VerifyChecksums(db *gorm.DB) error {
+	plan, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	return verifyChecksums(db, plan)
+}
+
+func verifyChecksums(db *gorm.DB, plan []*plannedMigration) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	var modified []string
+	for _, record := range applied {
+		migration := findMigration(plan, record.Name)
+		if record.Checksum == "" || migration == nil || migration.fn != nil {
+			continue
+		}
+		if migration.checksum() != record.Checksum {
+			modified = append(modified, record.Name)
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("applied SQL migrations were modified after they ran: %s", strings.Join(modified, ", "))
+	}
+	return nil
+}
+
+// PendingMigrations returns the migrations RunMigrations would apply, in order
+func PendingMigrations(db *gorm.DB) ([]MigrationStep, error) {
+	plan, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return pendingSteps(db, plan)
+}
+
+func pendingSteps(db *gorm.DB, plan []*plannedMigration) ([]MigrationStep, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(applied))
+	for _, record := range applied {
+		done[record.Name] = true
+	}
+
+	var steps []MigrationStep
+	for _, migration := range plan {
+		if !done[migration.name] {
+			steps = append(steps, MigrationStep{Name: migration.name, SQL: migration.upSQL()})
+		}
+	}
+	return steps, nil
+}
+
+// PlanRollback returns what RollbackMigration would run for the applied
+// migration name
+func PlanRollback(db *gorm.DB, name string) (MigrationStep, error) {
+	var record Migration
+	if err := db.Where("name = ?", name).First(&record).Error; err != nil {
+		return MigrationStep{}, fmt.Errorf("migration %s not found: %w", name, err)
+	}
+	plan, err := loadMigrations()
+	if err != nil {
+		return MigrationStep{}, err
+	}
+	migration := findMigration(plan, name)
+	if migration == nil || !migration.canRollback() {
+		return MigrationStep{}, fmt.Errorf("no rollback function found for migration %s", name)
+	}
+	return MigrationStep{Name: name, SQL: migration.downSQL()}, nil
+}
+
+// PlanRollbackTo returns the migrations RollbackTo would roll back, newest
+// first, with their down SQL
+func PlanRollbackTo(db *gorm.DB, target string) ([]MigrationStep, error) {
+	plan, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	records, migrations, err := rollbackPlan(db, plan, target)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]MigrationStep, len(records))
+	for i := range records {
+		steps[i] = MigrationStep{Name: records[i].Name, SQL: migrations[i].downSQL()}
+	}
+	return steps, nil
+}
+
+// RollbackTo rolls back every applied migration with a later version than
+// target, newest first. target is a migration name or a version number, and
+// 0 rolls back everything. Nothing is rolled back if any of the migrations
+// has no rollback.
+func RollbackTo(db *gorm.DB, target string) error {
+	plan, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	return rollbackTo(db, plan, target)
+}
+
+func rollbackTo(db *gorm.DB, plan []*plannedMigration, target string) error {
+	records, migrations, err := rollbackPlan(db, plan, target)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		fmt.Printf("Rolling back migration: %s\n", records[i].Name)
+		if err := rollbackMigration(db, &records[i], migrations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackPlan returns the applied migrations after target, newest first
+func rollbackPlan(db *gorm.DB, plan []*plannedMigration, target string) ([]Migration, []*plannedMigration, error) {
+	targetVersion, err := strconv.Atoi(target)
+	if err != nil {
+		migration := findMigration(plan, target)
+		if migration == nil {
+			return nil, nil, fmt.Errorf("unknown migration %s", target)
+		}
+		targetVersion = migration.version
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []Migration
+	var migrations []*plannedMigration
+	var missing []string
+	for _, record := range applied {
+		version, err := migrationVersion(record.Name)
+		if err != nil || version <= targetVersion {
+			continue
+		}
+		migration := findMigration(plan, record.Name)
+		if migration == nil || !migration.canRollback() {
+			missing = append(missing, record.Name)
+			continue
+		}
+		records = append(records, record)
+		migrations = append(migrations, migration)
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("cannot roll back to %s, no rollback for: %s", target, strings.Join(missing, ", "))
+	}
+	return records, migrations, nil
+}
+
+// rollbackMigration runs the down SQL or Go rollback of migration and removes
+// its record
+func rollbackMigration(db *gorm.DB, record *Migration, migration *plannedMigration) error {
+	switch {
+	case migration != nil && migration.down != "":
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.down).Error; err != nil {
+				return fmt.Errorf("failed to rollback migration %s: %w", record.Name, err)
+			}
+			return tx.Delete(record).Error
+		})
+	case migration != nil && migration.rollback != nil:
+		if err := migration.rollback(db); err != nil {
+			return fmt.Errorf("failed to rollback migration %s: %w", record.Name, err)
+		}
+		return db.Delete(record).Error
+	default:
+		return fmt.Errorf("no rollback function found for migration %s", record.Name)
+	}
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSQLMigrationDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Migration{}))
+	return db
+}
+
+func testSQLFiles() fstest.MapFS {
+	return fstest.MapFS{
+		"002_create_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT);\nCREATE INDEX idx_widgets_name ON widgets (name);\n")},
+		"002_create_widgets.down.sql": {Data: []byte("DROP TABLE widgets;\n")},
+		"001_create_gadgets.down.sql": {Data: []byte("DROP TABLE gadgets;\n")},
+	}
+}
+
+func testGoMigrations() []goMigration {
+	return []goMigration{
+		{"001_create_gadgets", func(db *gorm.DB) error {
+			return db.Exec("CREATE TABLE gadgets (id INTEGER PRIMARY KEY)").Error
+		}},
+		{"003_add_widget_color", func(db *gorm.DB) error {
+			return db.Exec("ALTER TABLE widgets ADD COLUMN color TEXT").Error
+		}},
+	}
+}
+
+func runPlan(t *testing.T, db *gorm.DB, plan []*plannedMigration) {
+	for _, migration := range plan {
+		require.NoError(t, migration.run(db))
+	}
+}
+
+func TestEmbeddedSQLMigrationsLoad(t *testing.T) {
+	plan, err := loadMigrations()
+	require.NoError(t, err)
+	assert.Equal(t, "001_create_review_tables", plan[0].name)
+	for i := 1; i < len(plan); i++ {
+		assert.Less(t, plan[i-1].version, plan[i].version)
+	}
+}
+
+func TestPlanMigrationsMergesGoAndSQL(t *testing.T) {
+	plan, err := planMigrations(testGoMigrations(), testSQLFiles())
+	require.NoError(t, err)
+
+	require.Len(t, plan, 3)
+	assert.Equal(t, "001_create_gadgets", plan[0].name)
+	assert.Equal(t, "DROP TABLE gadgets;\n", plan[0].down)
+	assert.Equal(t, "002_create_widgets", plan[1].name)
+	assert.Nil(t, plan[1].fn)
+	assert.Equal(t, "003_add_widget_color", plan[2].name)
+	assert.False(t, plan[2].canRollback())
+}
+
+func TestPlanMigrationsRejectsInvalidFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"defined twice":     {"001_create_gadgets.up.sql": {Data: []byte("SELECT 1;")}},
+		"orphan down":       {"009_drop_nothing.down.sql": {Data: []byte("SELECT 1;")}},
+		"same version":      {"003_other.up.sql": {Data: []byte("SELECT 1;")}},
+		"bad name":          {"create_things.sql": {Data: []byte("SELECT 1;")}},
+		"empty":             {"004_empty.up.sql": {Data: []byte("  \n")}},
+		"version not first": {"x_004.up.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for name, files := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := planMigrations(testGoMigrations(), files)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSQLMigrationRecordsChecksum(t *testing.T) {
+	db := setupSQLMigrationDB(t)
+	plan, err := planMigrations(testGoMigrations(), testSQLFiles())
+	require.NoError(t, err)
+
+	pending, err := pendingSteps(db, plan)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	assert.Equal(t, goMigrationSQL, pending[0].SQL)
+	assert.Contains(t, pending[1].SQL, "CREATE TABLE widgets")
+
+	runPlan(t, db, plan)
+	require.NoError(t, db.Exec("INSERT INTO widgets (name, color) VALUES ('bolt', 'red')").Error)
+
+	var record Migration
+	require.NoError(t, db.Where("name = ?", "002_create_widgets").First(&record).Error)
+	assert.Equal(t, plan[1].checksum(), record.Checksum)
+	var goRecord Migration
+	require.NoError(t, db.Where("name = ?", "001_create_gadgets").First(&goRecord).Error)
+	assert.Empty(t, goRecord.Checksum)
+
+	pending, err = pendingSteps(db, plan)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.NoError(t, verifyChecksums(db, plan))
+
+	// Editing an applied SQL migration is caught
+	edited := testSQLFiles()
+	edited["002_create_widgets.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY);\n")}
+	editedPlan, err := planMigrations(testGoMigrations(), edited)
+	require.NoError(t, err)
+	err = verifyChecksums(db, editedPlan)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "002_create_widgets")
+}
+
+func TestRollbackTo(t *testing.T) {
+	db := setupSQLMigrationDB(t)
+	plan, err := planMigrations(testGoMigrations(), testSQLFiles())
+	require.NoError(t, err)
+	runPlan(t, db, plan)
+
+	// 003 has no rollback, so nothing is rolled back
+	err = rollbackTo(db, plan, "0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "003_add_widget_color")
+	var count int64
+	db.Model(&Migration{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	// Pretend 003 was never applied and roll back the rest
+	require.NoError(t, db.Where("name = ?", "003_add_widget_color").Delete(&Migration{}).Error)
+	require.NoError(t, rollbackTo(db, plan, "001_create_gadgets"))
+	assert.False(t, db.Migrator().HasTable("widgets"))
+	assert.True(t, db.Migrator().HasTable("gadgets"))
+
+	require.NoError(t, rollbackTo(db, plan, "0"))
+	assert.False(t, db.Migrator().HasTable("gadgets"))
+	db.Model(&Migration{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestRollbackToUnknownTarget(t *testing.T) {
+	db := setupSQLMigrationDB(t)
+	plan, err := planMigrations(testGoMigrations(), testSQLFiles())
+	require.NoError(t, err)
+
+	err = rollbackTo(db, plan, "999_missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown migration")
+}
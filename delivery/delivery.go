@@ -0,0 +1,361 @@
+// Package delivery schedules orders into delivery slots: dated windows of a
+// delivery zone with a limited number of orders each.
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoZone         = errors.New("no delivery zone covers this address")
+	ErrSlotRequired   = errors.New("a delivery slot must be chosen for this address")
+	ErrSlotFull       = errors.New("delivery slot is full")
+	ErrSlotClosed     = errors.New("delivery slot is closed")
+	ErrSlotCutoff     = errors.New("delivery slot is no longer taking orders")
+	ErrWrongZone      = errors.New("delivery slot does not cover this address")
+	ErrInvalidWindow  = errors.New("invalid delivery window")
+	ErrNotEnoughSpace = errors.New("target delivery slot does not have room for the orders")
+)
+
+// Service books orders into delivery slots
+type Service struct {
+	loc    *time.Location
+	cutoff time.Duration
+	days   int
+	now    func() time.Time
+}
+
+func NewService(config *cfg.DeliveryConfig) *Service {
+	s := &Service{loc: time.UTC, cutoff: 12 * time.Hour, days: 14, now: time.Now}
+	if config != nil {
+		if config.Timezone != "" {
+			if loc, err := time.LoadLocation(config.Timezone); err == nil {
+				s.loc = loc
+			} else {
+				slog.Warn("unknown delivery timezone, using UTC", "component", "delivery", "timezone", config.Timezone)
+			}
+		}
+		if config.CutoffHours >= 0 {
+			s.cutoff = time.Duration(config.CutoffHours) * time.Hour
+		}
+		if config.BookingDays > 0 {
+			s.days = config.BookingDays
+		}
+	}
+	return s
+}
+
+// ZoneFor returns the active zone that delivers to a postcode: the zone of
+// the country with the longest matching postcode prefix, a zone without
+// prefixes covering the rest of its country
+func ZoneFor(db *gorm.DB, country, postcode string) (*models.DeliveryZone, error) {
+	var zones []models.DeliveryZone
+	if err := db.Where("is_active = ? AND UPPER(country) = ?", true, strings.ToUpper(country)).
+		Order("id").Find(&zones).Error; err != nil {
+		return nil, err
+	}
+	var best *models.DeliveryZone
+	bestLen := -1
+	for i := range zones {
+		prefixes := splitPrefixes(zones[i].PostcodePrefixes)
+		if len(prefixes) == 0 && bestLen < 0 {
+			best, bestLen = &zones[i], 0
+		}
+		for _, prefix := range prefixes {
+			if len(prefix) > bestLen && matchesPrefix(country, postcode, prefix) {
+				best, bestLen = &zones[i], len(prefix)
+			}
+		}
+	}
+	if best == nil {
+		return nil, ErrNoZone
+	}
+	return best, nil
+}
+
+// splitPrefixes returns the compact, upper-case prefixes of a zone
+func splitPrefixes(prefixes string) []string {
+	var out []string
+	for _, p := range strings.Split(prefixes, ",") {
+		if p = compact(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func compact(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// matchesPrefix reports whether a postcode is covered by a zone prefix. UK
+// prefixes are matched against whole outward codes, so area "LS" covers
+// "LS10 1AA" but district "LS1" does not.
+func matchesPrefix(country, postcode, prefix string) bool {
+	p := compact(postcode)
+	if strings.EqualFold(country, "GB") && len(p) > 3 {
+		outward := p[:len(p)-3]
+		if len(prefix) > len(outward) {
+			return strings.HasPrefix(p, prefix) // a sector such as "LS11"
+		}
+		if !strings.HasPrefix(outward, prefix) {
+			return false
+		}
+		return len(prefix) == len(outward) || isLetter(prefix[len(prefix)-1]) && !isLetter(outward[len(prefix)])
+	}
+	return strings.HasPrefix(p, prefix)
+}
+
+func isLetter(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
+
+// parseClock parses a window time such as "09:30" into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time %q must be HH:MM", ErrInvalidWindow, value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ValidateWindow checks a window's weekday, times and capacity
+func ValidateWindow(w *models.DeliveryWindow) error {
+	if w.Weekday < time.Sunday || w.Weekday > time.Saturday {
+		return fmt.Errorf("%w: weekday must be 0 (Sunday) to 6", ErrInvalidWindow)
+	}
+	start, err := parseClock(w.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.EndTime)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("%w: end time must be after start time", ErrInvalidWindow)
+	}
+	if w.Capacity < 0 {
+		return fmt.Errorf("%w: capacity cannot be negative", ErrInvalidWindow)
+	}
+	return nil
+}
+
+// Slots returns the slots of a zone from one date to another inclusive,
+// creating the slots of its active windows that do not exist yet
+func (s *Service) Slots(db *gorm.DB, zoneID uint, from, to time.Time) ([]models.DeliverySlot, error) {
+	var windows []models.DeliveryWindow
+	if err := db.Where("zone_id = ? AND is_active = ?", zoneID, true).Find(&windows).Error; err != nil {
+		return nil, err
+	}
+	first, last := s.date(from), s.date(to)
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		for _, w := range windows {
+			if w.Weekday != day.Weekday() {
+				continue
+			}
+			if err := s.createSlot(db, &w, day); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var slots []models.DeliverySlot
+	if err := db.Where("zone_id = ? AND date >= ? AND date <= ?", zoneID, first, last).
+		Order("starts_at, id").Find(&slots).Error; err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// AvailableSlots returns the slots of a zone customers can book now: open,
+// with room, and starting after the booking cutoff within the booking period
+func (s *Service) AvailableSlots(db *gorm.DB, zoneID uint) ([]models.DeliverySlot, error) {
+	now := s.now()
+	slots, err := s.Slots(db, zoneID, now, now.AddDate(0, 0, s.days))
+	if err != nil {
+		return nil, err
+	}
+	available := slots[:0]
+	for _, slot := range slots {
+		if slot.Remaining() > 0 && slot.StartsAt.After(now.Add(s.cutoff)) {
+			available = append(available, slot)
+		}
+	}
+	return available, nil
+}
+
+// createSlot creates the slot of a window on a date unless it exists
+func (s *Service) createSlot(db *gorm.DB, w *models.DeliveryWindow, day time.Time) error {
+	start, err := parseClock(w.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.EndTime)
+	if err != nil {
+		return err
+	}
+	at := func(offset time.Duration) time.Time {
+		// Built from the wall clock so windows keep their times across DST changes
+		minutes := int(offset.Minutes())
+		return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, s.loc).UTC()
+	}
+	slot := models.DeliverySlot{
+		ZoneID:   w.ZoneID,
+		WindowID: &w.ID,
+		Date:     day,
+		StartsAt: at(start),
+		EndsAt:   at(end),
+		Capacity: w.Capacity,
+		Fee:      w.Fee,
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&slot).Error
+}
+
+// date returns the calendar date of t in the delivery timezone, as midnight UTC
+func (s *Service) date(t time.Time) time.Time {
+	year, month, day := t.In(s.loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Book reserves a place in a slot for an order. The slot must cover the
+// order's shipping address, be open, have room and start after the cutoff.
+func (s *Service) Book(tx *gorm.DB, order *models.Order, address *models.Address, slotID uint) error {
+	var slot models.DeliverySlot
+	if err := tx.First(&slot, slotID).Error; err != nil {
+		return err
+	}
+	zone, err := ZoneFor(tx, address.Country, address.PostalCode)
+	if err != nil {
+		return err
+	}
+	if zone.ID != slot.ZoneID {
+		return ErrWrongZone
+	}
+	if !slot.StartsAt.After(s.now().Add(s.cutoff)) {
+		return ErrSlotCutoff
+	}
+	if err := reserve(tx, &slot, 1); err != nil {
+		return err
+	}
+	order.DeliverySlotID = &slot.ID
+	return tx.Model(order).Update("delivery_slot_id", slot.ID).Error
+}
+
+// RequireSlot returns ErrSlotRequired when the zone delivering to an address
+// only delivers in booked slots
+func RequireSlot(db *gorm.DB, address *models.Address) error {
+	zone, err := ZoneFor(db, address.Country, address.PostalCode)
+	if errors.Is(err, ErrNoZone) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if zone.RequireSlot {
+		return ErrSlotRequired
+	}
+	return nil
+}
+
+// reserve takes n places in a slot, failing when it is closed or lacks room
+func reserve(tx *gorm.DB, slot *models.DeliverySlot, n int) error {
+	result := tx.Model(&models.DeliverySlot{}).
+		Where("id = ? AND is_closed = ? AND booked + ? <= capacity", slot.ID, false, n).
+		Update("booked", gorm.Expr("booked + ?", n))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := tx.First(slot, slot.ID).Error; err != nil {
+			return err
+		}
+		if slot.IsClosed {
+			return ErrSlotClosed
+		}
+		if n > 1 {
+			return ErrNotEnoughSpace
+		}
+		return ErrSlotFull
+	}
+	slot.Booked += n
+	return nil
+}
+
+// Release gives an order's place in its slot back, when it has one
+func Release(tx *gorm.DB, order *models.Order) error {
+	if order.DeliverySlotID == nil {
+		return nil
+	}
+	if err := tx.Model(&models.DeliverySlot{}).
+		Where("id = ? AND booked > 0", *order.DeliverySlotID).
+		Update("booked", gorm.Expr("booked - 1")).Error; err != nil {
+		return err
+	}
+	order.DeliverySlotID = nil
+	return tx.Model(order).Update("delivery_slot_id", nil).Error
+}
+
+// MoveOrders moves open orders from one slot to another slot of the same
+// zone: the given orders, or every open order in the slot when none are
+// given. Closed and full target slots are refused, since the move is usually
+// made to clear a slot that is closing.
+func MoveOrders(tx *gorm.DB, fromID, toID uint, orderIDs []uint) ([]models.Order, error) {
+	var from, to models.DeliverySlot
+	if err := tx.First(&from, fromID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.First(&to, toID).Error; err != nil {
+		return nil, err
+	}
+	if from.ZoneID != to.ZoneID {
+		return nil, ErrWrongZone
+	}
+
+	query := tx.Where("delivery_slot_id = ? AND status IN ?", from.ID, openStatuses)
+	if len(orderIDs) > 0 {
+		query = query.Where("id IN ?", orderIDs)
+	}
+	var orders []models.Order
+	if err := query.Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 || from.ID == to.ID {
+		return []models.Order{}, nil
+	}
+
+	if err := reserve(tx, &to, len(orders)); err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.DeliverySlot{}).Where("id = ?", from.ID).
+		Update("booked", gorm.Expr("CASE WHEN booked > ? THEN booked - ? ELSE 0 END", len(orders), len(orders))).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+		orders[i].DeliverySlotID = &to.ID
+	}
+	if err := tx.Model(&models.Order{}).Where("id IN ?", ids).Update("delivery_slot_id", to.ID).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// openStatuses are the statuses of orders still to be delivered
+var openStatuses = []models.OrderStatus{models.OrderStatusPending, models.OrderStatusProcessing}
+
+// Describe formats a slot for customers, e.g. "Monday 3 March, 09:00-12:00"
+func (s *Service) Describe(slot *models.DeliverySlot) string {
+	start, end := slot.StartsAt.In(s.loc), slot.EndsAt.In(s.loc)
+	return fmt.Sprintf("%s, %s-%s", start.Format("Monday 2 January"), start.Format("15:04"), end.Format("15:04"))
+}
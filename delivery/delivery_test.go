@@ -0,0 +1,175 @@
+package delivery
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DeliveryZone{}, &models.DeliveryWindow{}, &models.DeliverySlot{}, &models.Order{}))
+	return db
+}
+
+// setupService returns a service whose clock reads Monday 5 January 2026,
+// 08:00 in London
+func setupService() *Service {
+	s := NewService(&cfg.DeliveryConfig{Timezone: "Europe/London", CutoffHours: 12, BookingDays: 14})
+	s.now = func() time.Time { return time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func createZone(t *testing.T, db *gorm.DB, prefixes string, windows ...models.DeliveryWindow) *models.DeliveryZone {
+	zone := models.DeliveryZone{Name: "Zone " + prefixes, Country: "GB", PostcodePrefixes: prefixes, IsActive: true, Windows: windows}
+	require.NoError(t, db.Create(&zone).Error)
+	return &zone
+}
+
+func createOrders(t *testing.T, db *gorm.DB, n int) []models.Order {
+	orders := make([]models.Order, n)
+	for i := range orders {
+		orders[i] = models.Order{OrderNumber: fmt.Sprintf("ORD-%d", i+1), UserID: 1, Status: models.OrderStatusPending}
+		require.NoError(t, db.Create(&orders[i]).Error)
+	}
+	return orders
+}
+
+func TestZoneFor(t *testing.T) {
+	db := setupTestDB(t)
+	country := createZone(t, db, "")
+	leeds := createZone(t, db, "LS")
+	centre := createZone(t, db, "LS1, BD1")
+
+	tests := []struct {
+		name     string
+		country  string
+		postcode string
+		want     uint
+		wantErr  error
+	}{
+		{"district prefix", "GB", "LS1 1AA", centre.ID, nil},
+		{"district outside the prefix falls back to the area", "GB", "LS10 1AA", leeds.ID, nil},
+		{"area prefix", "GB", "ls6 2aa", leeds.ID, nil},
+		{"different area", "GB", "L1 8JQ", country.ID, nil},
+		{"rest of the country", "GB", "M1 1AE", country.ID, nil},
+		{"other country", "FR", "75008", 0, ErrNoZone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, err := ZoneFor(db, tt.country, tt.postcode)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, zone.ID)
+		})
+	}
+}
+
+func TestBookingEnforcesCapacity(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService()
+	zone := createZone(t, db, "LS", models.DeliveryWindow{
+		Weekday: time.Tuesday, StartTime: "09:00", EndTime: "12:00", Capacity: 2, Fee: 3.5, IsActive: true,
+	})
+
+	slots, err := s.AvailableSlots(db, zone.ID)
+	require.NoError(t, err)
+	require.Len(t, slots, 2, "two Tuesdays in the booking period")
+	slot := slots[0]
+	assert.Equal(t, time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC), slot.StartsAt.UTC())
+	assert.Equal(t, "Tuesday 6 January, 09:00-12:00", s.Describe(&slot))
+
+	// Listing again does not create the slots twice
+	_, err = s.Slots(db, zone.ID, s.now(), s.now().AddDate(0, 0, 14))
+	require.NoError(t, err)
+	var count int64
+	db.Model(&models.DeliverySlot{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	address := &models.Address{Country: "GB", PostalCode: "LS6 2AA"}
+	orders := createOrders(t, db, 3)
+	require.NoError(t, s.Book(db, &orders[0], address, slot.ID))
+	require.NoError(t, s.Book(db, &orders[1], address, slot.ID))
+	assert.ErrorIs(t, s.Book(db, &orders[2], address, slot.ID), ErrSlotFull)
+
+	require.NoError(t, db.First(&slot, slot.ID).Error)
+	assert.Equal(t, 2, slot.Booked)
+	slots, err = s.AvailableSlots(db, zone.ID)
+	require.NoError(t, err)
+	assert.Len(t, slots, 1, "a full slot is not offered")
+
+	// Cancelling an order gives its place back
+	require.NoError(t, Release(db, &orders[0]))
+	require.NoError(t, s.Book(db, &orders[2], address, slot.ID))
+
+	// Addresses outside the zone cannot book it
+	assert.ErrorIs(t, s.Book(db, &orders[0], &models.Address{Country: "GB", PostalCode: "M1 1AE"}, slot.ID), ErrNoZone)
+}
+
+func TestBookingCutoff(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService()
+	zone := createZone(t, db, "", models.DeliveryWindow{
+		Weekday: time.Monday, StartTime: "18:00", EndTime: "21:00", Capacity: 5, IsActive: true,
+	})
+
+	slots, err := s.Slots(db, zone.ID, s.now(), s.now())
+	require.NoError(t, err)
+	require.Len(t, slots, 1)
+
+	orders := createOrders(t, db, 1)
+	err = s.Book(db, &orders[0], &models.Address{Country: "GB", PostalCode: "M1 1AE"}, slots[0].ID)
+	assert.ErrorIs(t, err, ErrSlotCutoff, "the slot starts within the 12 hour cutoff")
+}
+
+func TestMoveOrders(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService()
+	zone := createZone(t, db, "",
+		models.DeliveryWindow{Weekday: time.Tuesday, StartTime: "09:00", EndTime: "12:00", Capacity: 3, IsActive: true},
+		models.DeliveryWindow{Weekday: time.Tuesday, StartTime: "13:00", EndTime: "16:00", Capacity: 2, IsActive: true},
+	)
+	tuesday := time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC)
+	slots, err := s.Slots(db, zone.ID, tuesday, tuesday)
+	require.NoError(t, err)
+	require.Len(t, slots, 2)
+	morning, afternoon := slots[0], slots[1]
+
+	address := &models.Address{Country: "GB", PostalCode: "M1 1AE"}
+	orders := createOrders(t, db, 3)
+	for i := range orders {
+		require.NoError(t, s.Book(db, &orders[i], address, morning.ID))
+	}
+
+	// Three orders do not fit the afternoon slot
+	_, err = MoveOrders(db, morning.ID, afternoon.ID, nil)
+	assert.ErrorIs(t, err, ErrNotEnoughSpace)
+
+	moved, err := MoveOrders(db, morning.ID, afternoon.ID, []uint{orders[0].ID, orders[1].ID})
+	require.NoError(t, err)
+	assert.Len(t, moved, 2)
+
+	require.NoError(t, db.First(&morning, morning.ID).Error)
+	require.NoError(t, db.First(&afternoon, afternoon.ID).Error)
+	assert.Equal(t, 1, morning.Booked)
+	assert.Equal(t, 2, afternoon.Booked)
+	var order models.Order
+	require.NoError(t, db.First(&order, orders[0].ID).Error)
+	assert.Equal(t, afternoon.ID, *order.DeliverySlotID)
+
+	// Closed slots take no more orders
+	require.NoError(t, db.Model(&morning).Update("is_closed", true).Error)
+	_, err = MoveOrders(db, afternoon.ID, morning.ID, []uint{orders[0].ID})
+	assert.ErrorIs(t, err, ErrSlotClosed)
+}
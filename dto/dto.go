@@ -0,0 +1,72 @@
+// Package dto builds the JSON representations handlers return for orders,
+// payments and support entities. Each constructor takes the Audience of the
+// request: customers get the fields about their own records, staff also get
+// internal notes, assignments, SLA data and provider references.
+//
+// The JSON keys match what the GORM models serialized to before, so existing
+// clients keep working; fields are removed, never renamed.
+package dto
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+)
+
+// Audience is who a representation is built for
+type Audience int
+
+const (
+	Customer Audience = iota
+	Staff
+)
+
+// AudienceFor returns Staff when the caller's role has scope, Customer otherwise
+func AudienceFor(c *gin.Context, scope permissions.Scope) Audience {
+	if permissions.ContextHas(c, scope) {
+		return Staff
+	}
+	return Customer
+}
+
+// User is a person shown alongside another record. Contact details are only
+// shown to staff.
+type User struct {
+	ID        uint   `json:"ID"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Avatar    string `json:"avatar"`
+
+	*UserStaff
+}
+
+// UserStaff holds the user fields only staff can see
+type UserStaff struct {
+	Email    string          `json:"email"`
+	Phone    string          `json:"phone"`
+	UserType models.UserType `json:"user_type"`
+}
+
+// NewUser returns the representation of u, or nil when u is nil or was not loaded
+func NewUser(u *models.User, audience Audience) *User {
+	if u == nil || u.ID == 0 {
+		return nil
+	}
+	user := &User{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName, Avatar: u.Avatar}
+	if audience == Staff {
+		user.UserStaff = &UserStaff{Email: u.Email, Phone: u.Phone, UserType: u.UserType}
+	}
+	return user
+}
+
+// Attachment is a file attached to a ticket, dispute or abuse report
+type Attachment struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	FileName  string    `json:"file_name"`
+	FileURL   string    `json:"file_url"`
+	FileSize  int64     `json:"file_size"`
+	FileType  string    `json:"file_type"`
+}
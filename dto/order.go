@@ -0,0 +1,151 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// Order is an order summary, as shown on payments and support cases
+type Order struct {
+	ID             uint                 `json:"ID"`
+	CreatedAt      time.Time            `json:"CreatedAt"`
+	OrderNumber    string               `json:"order_number"`
+	Status         models.OrderStatus   `json:"status"`
+	PaymentStatus  models.PaymentStatus `json:"payment_status"`
+	TotalAmount    float64              `json:"total_amount"`
+	TaxAmount      float64              `json:"tax_amount"`
+	ShippingAmount float64              `json:"shipping_amount"`
+	DiscountAmount float64              `json:"discount_amount"`
+	FinalAmount    float64              `json:"final_amount"`
+	ShippingMethod string               `json:"shipping_method"`
+	TrackingNumber string               `json:"tracking_number"`
+	PaymentMethod  string               `json:"payment_method"`
+	PaymentDate    *time.Time           `json:"payment_date"`
+	CustomerNotes  string               `json:"customer_notes"`
+	OrderDate      time.Time            `json:"order_date"`
+	ShippedDate    *time.Time           `json:"shipped_date"`
+	DeliveredDate  *time.Time           `json:"delivered_date"`
+
+	*OrderStaff
+}
+
+// OrderStaff holds the order fields only staff can see
+type OrderStaff struct {
+	UserID           uint   `json:"user_id"`
+	CompanyID        *uint  `json:"company_id,omitempty"`
+	PaymentReference string `json:"payment_reference"`
+	PaymentProvider  string `json:"payment_provider"`
+	RevolutOrderID   string `json:"revolut_order_id"`
+	RevolutPaymentID string `json:"revolut_payment_id"`
+	AdminNotes       string `json:"admin_notes"`
+}
+
+// NewOrder returns the representation of o, or nil when o is nil or was not loaded
+func NewOrder(o *models.Order, audience Audience) *Order {
+	if o == nil || o.ID == 0 {
+		return nil
+	}
+	order := &Order{
+		ID:             o.ID,
+		CreatedAt:      o.CreatedAt,
+		OrderNumber:    o.OrderNumber,
+		Status:         o.Status,
+		PaymentStatus:  o.PaymentStatus,
+		TotalAmount:    o.TotalAmount,
+		TaxAmount:      o.TaxAmount,
+		ShippingAmount: o.ShippingAmount,
+		DiscountAmount: o.DiscountAmount,
+		FinalAmount:    o.FinalAmount,
+		ShippingMethod: o.ShippingMethod,
+		TrackingNumber: o.TrackingNumber,
+		PaymentMethod:  o.PaymentMethod,
+		PaymentDate:    o.PaymentDate,
+		CustomerNotes:  o.CustomerNotes,
+		OrderDate:      o.OrderDate,
+		ShippedDate:    o.ShippedDate,
+		DeliveredDate:  o.DeliveredDate,
+	}
+	if audience == Staff {
+		order.OrderStaff = &OrderStaff{
+			UserID:           o.UserID,
+			CompanyID:        o.CompanyID,
+			PaymentReference: o.PaymentReference,
+			PaymentProvider:  o.PaymentProvider,
+			RevolutOrderID:   o.RevolutOrderID,
+			RevolutPaymentID: o.RevolutPaymentID,
+			AdminNotes:       o.AdminNotes,
+		}
+	}
+	return order
+}
+
+// Payment is a payment made for an order
+type Payment struct {
+	ID             uint                        `json:"ID"`
+	CreatedAt      time.Time                   `json:"CreatedAt"`
+	UpdatedAt      time.Time                   `json:"UpdatedAt"`
+	OrderID        uint                        `json:"order_id"`
+	Order          *Order                      `json:"order,omitempty"`
+	Amount         float64                     `json:"amount"`
+	Currency       string                      `json:"currency"`
+	Status         models.RevolutPaymentStatus `json:"status"`
+	PaymentMethod  string                      `json:"payment_method"`
+	CheckoutURL    string                      `json:"checkout_url"`
+	CompletedAt    *time.Time                  `json:"completed_at"`
+	FailureReason  string                      `json:"failure_reason"`
+	RefundStatus   string                      `json:"refund_status"`
+	RefundedAmount float64                     `json:"refunded_amount"`
+
+	*PaymentStaff
+}
+
+// PaymentStaff holds the payment fields only staff can see
+type PaymentStaff struct {
+	RevolutOrderID   string      `json:"revolut_order_id"`
+	RevolutPaymentID string      `json:"revolut_payment_id"`
+	CustomerID       string      `json:"customer_id"`
+	Metadata         models.JSON `json:"metadata"`
+	CreatedBy        uint        `json:"created_by"`
+	UpdatedBy        uint        `json:"updated_by"`
+}
+
+// NewPayment returns the representation of p
+func NewPayment(p *models.Payment, audience Audience) *Payment {
+	payment := &Payment{
+		ID:             p.ID,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+		OrderID:        p.OrderID,
+		Order:          NewOrder(&p.Order, audience),
+		Amount:         p.Amount,
+		Currency:       p.Currency,
+		Status:         p.Status,
+		PaymentMethod:  p.PaymentMethod,
+		CheckoutURL:    p.CheckoutURL,
+		CompletedAt:    p.CompletedAt,
+		FailureReason:  p.FailureReason,
+		RefundStatus:   p.RefundStatus,
+		RefundedAmount: p.RefundedAmount,
+	}
+	if audience == Staff {
+		payment.PaymentStaff = &PaymentStaff{
+			RevolutOrderID:   p.RevolutOrderID,
+			RevolutPaymentID: p.RevolutPaymentID,
+			CustomerID:       p.CustomerID,
+			Metadata:         p.Metadata,
+			CreatedBy:        p.CreatedBy,
+			UpdatedBy:        p.UpdatedBy,
+		}
+	}
+	return payment
+}
+
+// NewPayments returns the representations of payments
+func NewPayments(payments []models.Payment, audience Audience) []*Payment {
+	result := make([]*Payment, 0, len(payments))
+	for i := range payments {
+		result = append(result, NewPayment(&payments[i], audience))
+	}
+	return result
+}
@@ -0,0 +1,418 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// CaseStaff holds the ticket and dispute fields only staff can see
+type CaseStaff struct {
+	AssignedTo            *uint      `json:"assigned_to,omitempty"`
+	AssignedUser          *User      `json:"assigned_user,omitempty"`
+	ResolvedBy            *uint      `json:"resolved_by,omitempty"`
+	InternalNotes         string     `json:"internal_notes"`
+	IsEscalated           bool       `json:"is_escalated"`
+	EscalatedAt           *time.Time `json:"escalated_at"`
+	EscalatedBy           *uint      `json:"escalated_by,omitempty"`
+	FirstResponseDueAt    *time.Time `json:"first_response_due_at"`
+	ResolutionDueAt       *time.Time `json:"resolution_due_at"`
+	FirstRespondedAt      *time.Time `json:"first_responded_at"`
+	FirstResponseBreached bool       `json:"first_response_breached"`
+	ResolutionBreached    bool       `json:"resolution_breached"`
+}
+
+// Response is a message on a ticket or dispute. Internal notes are never
+// shown to customers.
+type Response struct {
+	ID          uint      `json:"ID"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	TicketID    uint      `json:"ticket_id,omitempty"`
+	DisputeID   uint      `json:"dispute_id,omitempty"`
+	UserID      uint      `json:"user_id"`
+	User        *User     `json:"user,omitempty"`
+	Message     string    `json:"message"`
+	IsFromAdmin bool      `json:"is_from_admin"`
+
+	*ResponseStaff
+}
+
+// ResponseStaff holds the response fields only staff can see
+type ResponseStaff struct {
+	IsInternal     bool   `json:"is_internal"`
+	EmailMessageID string `json:"email_message_id,omitempty"`
+}
+
+// Ticket is a support ticket
+type Ticket struct {
+	ID           uint                  `json:"ID"`
+	CreatedAt    time.Time             `json:"CreatedAt"`
+	UpdatedAt    time.Time             `json:"UpdatedAt"`
+	UserID       uint                  `json:"user_id"`
+	User         *User                 `json:"user,omitempty"`
+	OrderID      *uint                 `json:"order_id,omitempty"`
+	Order        *Order                `json:"order,omitempty"`
+	DisputeID    *uint                 `json:"dispute_id,omitempty"`
+	Dispute      *Dispute              `json:"dispute,omitempty"`
+	MergedIntoID *uint                 `json:"merged_into_id,omitempty"`
+	Title        string                `json:"title"`
+	Description  string                `json:"description"`
+	Category     models.TicketCategory `json:"category"`
+	Priority     models.TicketPriority `json:"priority"`
+	Status       models.TicketStatus   `json:"status"`
+	Resolution   string                `json:"resolution"`
+	ResolvedAt   *time.Time            `json:"resolved_at"`
+	Attachments  []Attachment          `json:"attachments"`
+	Responses    []Response            `json:"responses"`
+
+	*CaseStaff
+}
+
+// NewTicket returns the representation of t. Customers only see the
+// resolution once the ticket is resolved or closed.
+func NewTicket(t *models.SupportTicket, audience Audience) *Ticket {
+	ticket := &Ticket{
+		ID:           t.ID,
+		CreatedAt:    t.CreatedAt,
+		UpdatedAt:    t.UpdatedAt,
+		UserID:       t.UserID,
+		User:         NewUser(t.User, audience),
+		OrderID:      t.OrderID,
+		Order:        NewOrder(t.Order, audience),
+		DisputeID:    t.DisputeID,
+		MergedIntoID: t.MergedIntoID,
+		Title:        t.Title,
+		Description:  t.Description,
+		Category:     t.Category,
+		Priority:     t.Priority,
+		Status:       t.Status,
+		ResolvedAt:   t.ResolvedAt,
+		Attachments:  make([]Attachment, 0, len(t.Attachments)),
+		Responses:    make([]Response, 0, len(t.Responses)),
+	}
+	if t.Dispute != nil && t.Dispute.ID != 0 {
+		ticket.Dispute = NewDispute(t.Dispute, audience)
+	}
+	if audience == Staff || t.Status == models.TicketStatusResolved || t.Status == models.TicketStatusClosed {
+		ticket.Resolution = t.Resolution
+	}
+	for _, a := range t.Attachments {
+		ticket.Attachments = append(ticket.Attachments, Attachment{ID: a.ID, CreatedAt: a.CreatedAt, FileName: a.FileName, FileURL: a.FileURL, FileSize: a.FileSize, FileType: a.FileType})
+	}
+	for i := range t.Responses {
+		if r := NewTicketResponse(&t.Responses[i], audience); r != nil {
+			ticket.Responses = append(ticket.Responses, *r)
+		}
+	}
+	if audience == Staff {
+		ticket.CaseStaff = &CaseStaff{
+			AssignedTo:            t.AssignedTo,
+			AssignedUser:          NewUser(t.AssignedUser, audience),
+			ResolvedBy:            t.ResolvedBy,
+			InternalNotes:         t.InternalNotes,
+			IsEscalated:           t.IsEscalated,
+			EscalatedAt:           t.EscalatedAt,
+			EscalatedBy:           t.EscalatedBy,
+			FirstResponseDueAt:    t.FirstResponseDueAt,
+			ResolutionDueAt:       t.ResolutionDueAt,
+			FirstRespondedAt:      t.FirstRespondedAt,
+			FirstResponseBreached: t.FirstResponseBreached,
+			ResolutionBreached:    t.ResolutionBreached,
+		}
+	}
+	return ticket
+}
+
+// NewTicketResponse returns the representation of r, or nil when a customer
+// may not see it
+func NewTicketResponse(r *models.TicketResponse, audience Audience) *Response {
+	if r.IsInternal && audience != Staff {
+		return nil
+	}
+	response := &Response{
+		ID:          r.ID,
+		CreatedAt:   r.CreatedAt,
+		TicketID:    r.TicketID,
+		UserID:      r.UserID,
+		User:        NewUser(r.User, audience),
+		Message:     r.Message,
+		IsFromAdmin: r.IsFromAdmin,
+	}
+	if audience == Staff {
+		response.ResponseStaff = &ResponseStaff{IsInternal: r.IsInternal, EmailMessageID: r.EmailMessageID}
+	}
+	return response
+}
+
+// Dispute is a customer dispute about an order or payment
+type Dispute struct {
+	ID          uint                   `json:"ID"`
+	CreatedAt   time.Time              `json:"CreatedAt"`
+	UpdatedAt   time.Time              `json:"UpdatedAt"`
+	UserID      uint                   `json:"user_id"`
+	User        *User                  `json:"user,omitempty"`
+	OrderID     *uint                  `json:"order_id,omitempty"`
+	Order       *Order                 `json:"order,omitempty"`
+	PaymentID   *uint                  `json:"payment_id,omitempty"`
+	Payment     *Payment               `json:"payment,omitempty"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Category    models.DisputeCategory `json:"category"`
+	Priority    models.DisputePriority `json:"priority"`
+	Status      models.DisputeStatus   `json:"status"`
+	Amount      *float64               `json:"amount,omitempty"`
+	Currency    string                 `json:"currency"`
+	Resolution  string                 `json:"resolution"`
+	ResolvedAt  *time.Time             `json:"resolved_at"`
+	Attachments []Attachment           `json:"attachments"`
+	Responses   []Response             `json:"responses"`
+
+	*CaseStaff
+}
+
+// NewDispute returns the representation of d. Customers only see the
+// resolution once the dispute is resolved or closed.
+func NewDispute(d *models.Dispute, audience Audience) *Dispute {
+	dispute := &Dispute{
+		ID:          d.ID,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+		UserID:      d.UserID,
+		User:        NewUser(d.User, audience),
+		OrderID:     d.OrderID,
+		Order:       NewOrder(d.Order, audience),
+		PaymentID:   d.PaymentID,
+		Title:       d.Title,
+		Description: d.Description,
+		Category:    d.Category,
+		Priority:    d.Priority,
+		Status:      d.Status,
+		Amount:      d.Amount,
+		Currency:    d.Currency,
+		ResolvedAt:  d.ResolvedAt,
+		Attachments: make([]Attachment, 0, len(d.Attachments)),
+		Responses:   make([]Response, 0, len(d.Responses)),
+	}
+	if d.Payment != nil && d.Payment.ID != 0 {
+		dispute.Payment = NewPayment(d.Payment, audience)
+	}
+	if audience == Staff || d.Status == models.DisputeStatusResolved || d.Status == models.DisputeStatusClosed {
+		dispute.Resolution = d.Resolution
+	}
+	for _, a := range d.Attachments {
+		dispute.Attachments = append(dispute.Attachments, Attachment{ID: a.ID, CreatedAt: a.CreatedAt, FileName: a.FileName, FileURL: a.FileURL, FileSize: a.FileSize, FileType: a.FileType})
+	}
+	for i := range d.Responses {
+		if r := NewDisputeResponse(&d.Responses[i], audience); r != nil {
+			dispute.Responses = append(dispute.Responses, *r)
+		}
+	}
+	if audience == Staff {
+		dispute.CaseStaff = &CaseStaff{
+			AssignedTo:            d.AssignedTo,
+			AssignedUser:          NewUser(d.AssignedUser, audience),
+			ResolvedBy:            d.ResolvedBy,
+			InternalNotes:         d.InternalNotes,
+			IsEscalated:           d.IsEscalated,
+			EscalatedAt:           d.EscalatedAt,
+			EscalatedBy:           d.EscalatedBy,
+			FirstResponseDueAt:    d.FirstResponseDueAt,
+			ResolutionDueAt:       d.ResolutionDueAt,
+			FirstRespondedAt:      d.FirstRespondedAt,
+			FirstResponseBreached: d.FirstResponseBreached,
+			ResolutionBreached:    d.ResolutionBreached,
+		}
+	}
+	return dispute
+}
+
+// NewDisputeResponse returns the representation of r, or nil when a customer
+// may not see it
+func NewDisputeResponse(r *models.DisputeResponse, audience Audience) *Response {
+	if r.IsInternal && audience != Staff {
+		return nil
+	}
+	response := &Response{
+		ID:          r.ID,
+		CreatedAt:   r.CreatedAt,
+		DisputeID:   r.DisputeID,
+		UserID:      r.UserID,
+		User:        NewUser(r.User, audience),
+		Message:     r.Message,
+		IsFromAdmin: r.IsFromAdmin,
+	}
+	if audience == Staff {
+		response.ResponseStaff = &ResponseStaff{IsInternal: r.IsInternal, EmailMessageID: r.EmailMessageID}
+	}
+	return response
+}
+
+// ContactInquiry is a contact form submission
+type ContactInquiry struct {
+	ID          uint                   `json:"ID"`
+	CreatedAt   time.Time              `json:"CreatedAt"`
+	UpdatedAt   time.Time              `json:"UpdatedAt"`
+	UserID      *uint                  `json:"user_id,omitempty"`
+	Name        string                 `json:"name"`
+	Email       string                 `json:"email"`
+	Phone       string                 `json:"phone"`
+	Subject     string                 `json:"subject"`
+	Message     string                 `json:"message"`
+	Category    models.ContactCategory `json:"category"`
+	Status      models.ContactStatus   `json:"status"`
+	Response    string                 `json:"response"`
+	RespondedAt *time.Time             `json:"responded_at"`
+
+	*ContactInquiryStaff
+}
+
+// ContactInquiryStaff holds the contact inquiry fields only staff can see
+type ContactInquiryStaff struct {
+	User          *User                  `json:"user,omitempty"`
+	Priority      models.ContactPriority `json:"priority"`
+	AssignedTo    *uint                  `json:"assigned_to,omitempty"`
+	AssignedUser  *User                  `json:"assigned_user,omitempty"`
+	RespondedBy   *uint                  `json:"responded_by,omitempty"`
+	InternalNotes string                 `json:"internal_notes"`
+}
+
+// NewContactInquiry returns the representation of i
+func NewContactInquiry(i *models.ContactInquiry, audience Audience) *ContactInquiry {
+	inquiry := &ContactInquiry{
+		ID:          i.ID,
+		CreatedAt:   i.CreatedAt,
+		UpdatedAt:   i.UpdatedAt,
+		UserID:      i.UserID,
+		Name:        i.Name,
+		Email:       i.Email,
+		Phone:       i.Phone,
+		Subject:     i.Subject,
+		Message:     i.Message,
+		Category:    i.Category,
+		Status:      i.Status,
+		Response:    i.Response,
+		RespondedAt: i.RespondedAt,
+	}
+	if audience == Staff {
+		inquiry.ContactInquiryStaff = &ContactInquiryStaff{
+			User:          NewUser(i.User, audience),
+			Priority:      i.Priority,
+			AssignedTo:    i.AssignedTo,
+			AssignedUser:  NewUser(i.AssignedUser, audience),
+			RespondedBy:   i.RespondedBy,
+			InternalNotes: i.InternalNotes,
+		}
+	}
+	return inquiry
+}
+
+// AbuseReport is a report of abusive content or behaviour
+type AbuseReport struct {
+	ID             uint                     `json:"ID"`
+	CreatedAt      time.Time                `json:"CreatedAt"`
+	UpdatedAt      time.Time                `json:"UpdatedAt"`
+	ReporterID     uint                     `json:"reporter_id"`
+	ReportedUserID *uint                    `json:"reported_user_id,omitempty"`
+	ProductID      *uint                    `json:"product_id,omitempty"`
+	Product        *models.Product          `json:"product,omitempty"`
+	ReviewID       *uint                    `json:"review_id,omitempty"`
+	Review         *models.ProductReview    `json:"review,omitempty"`
+	OrderID        *uint                    `json:"order_id,omitempty"`
+	Order          *Order                   `json:"order,omitempty"`
+	Category       models.AbuseCategory     `json:"category"`
+	Description    string                   `json:"description"`
+	Status         models.AbuseReportStatus `json:"status"`
+	Resolution     string                   `json:"resolution"`
+	ResolvedAt     *time.Time               `json:"resolved_at"`
+	Attachments    []Attachment             `json:"attachments"`
+
+	*AbuseReportStaff
+}
+
+// AbuseReportStaff holds the abuse report fields only staff can see
+type AbuseReportStaff struct {
+	Reporter      *User                `json:"reporter,omitempty"`
+	ReportedUser  *User                `json:"reported_user,omitempty"`
+	Severity      models.AbuseSeverity `json:"severity"`
+	AssignedTo    *uint                `json:"assigned_to,omitempty"`
+	AssignedUser  *User                `json:"assigned_user,omitempty"`
+	ResolvedBy    *uint                `json:"resolved_by,omitempty"`
+	InternalNotes string               `json:"internal_notes"`
+}
+
+// NewAbuseReport returns the representation of r. Reporters only see the
+// resolution once the report is resolved or dismissed.
+func NewAbuseReport(r *models.AbuseReport, audience Audience) *AbuseReport {
+	report := &AbuseReport{
+		ID:             r.ID,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+		ReporterID:     r.ReporterID,
+		ReportedUserID: r.ReportedUserID,
+		ProductID:      r.ProductID,
+		Product:        r.Product,
+		ReviewID:       r.ReviewID,
+		Review:         r.Review,
+		OrderID:        r.OrderID,
+		Order:          NewOrder(r.Order, audience),
+		Category:       r.Category,
+		Description:    r.Description,
+		Status:         r.Status,
+		ResolvedAt:     r.ResolvedAt,
+		Attachments:    make([]Attachment, 0, len(r.Attachments)),
+	}
+	if audience == Staff || r.Status == models.AbuseReportStatusResolved || r.Status == models.AbuseReportStatusDismissed {
+		report.Resolution = r.Resolution
+	}
+	for _, a := range r.Attachments {
+		report.Attachments = append(report.Attachments, Attachment{ID: a.ID, CreatedAt: a.CreatedAt, FileName: a.FileName, FileURL: a.FileURL, FileSize: a.FileSize, FileType: a.FileType})
+	}
+	if audience == Staff {
+		report.AbuseReportStaff = &AbuseReportStaff{
+			Reporter:      NewUser(r.Reporter, audience),
+			ReportedUser:  NewUser(r.ReportedUser, audience),
+			Severity:      r.Severity,
+			AssignedTo:    r.AssignedTo,
+			AssignedUser:  NewUser(r.AssignedUser, audience),
+			ResolvedBy:    r.ResolvedBy,
+			InternalNotes: r.InternalNotes,
+		}
+	}
+	return report
+}
+
+// NewTickets returns the representations of tickets
+func NewTickets(tickets []models.SupportTicket, audience Audience) []*Ticket {
+	result := make([]*Ticket, 0, len(tickets))
+	for i := range tickets {
+		result = append(result, NewTicket(&tickets[i], audience))
+	}
+	return result
+}
+
+// NewDisputes returns the representations of disputes
+func NewDisputes(disputes []models.Dispute, audience Audience) []*Dispute {
+	result := make([]*Dispute, 0, len(disputes))
+	for i := range disputes {
+		result = append(result, NewDispute(&disputes[i], audience))
+	}
+	return result
+}
+
+// NewContactInquiries returns the representations of inquiries
+func NewContactInquiries(inquiries []models.ContactInquiry, audience Audience) []*ContactInquiry {
+	result := make([]*ContactInquiry, 0, len(inquiries))
+	for i := range inquiries {
+		result = append(result, NewContactInquiry(&inquiries[i], audience))
+	}
+	return result
+}
+
+// NewAbuseReports returns the representations of reports
+func NewAbuseReports(reports []models.AbuseReport, audience Audience) []*AbuseReport {
+	result := make([]*AbuseReport, 0, len(reports))
+	for i := range reports {
+		result = append(result, NewAbuseReport(&reports[i], audience))
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ticketJSON(t *testing.T, ticket *models.SupportTicket, audience Audience) map[string]interface{} {
+	data, err := json.Marshal(NewTicket(ticket, audience))
+	require.NoError(t, err)
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
+
+func TestTicketAudience(t *testing.T) {
+	assignee := uint(9)
+	ticket := &models.SupportTicket{
+		UserID:        1,
+		User:          &models.User{Email: "jane@example.com", FirstName: "Jane"},
+		Title:         "Missing parcel",
+		Status:        models.TicketStatusInProgress,
+		Resolution:    "Draft: refund once the courier confirms",
+		InternalNotes: "Customer has claimed twice this year",
+		AssignedTo:    &assignee,
+		Responses: []models.TicketResponse{
+			{Message: "We are looking into it", IsFromAdmin: true},
+			{Message: "Courier says delivered", IsInternal: true, IsFromAdmin: true},
+		},
+	}
+	ticket.ID = 5
+	ticket.User.ID = 1
+
+	customer := ticketJSON(t, ticket, Customer)
+	assert.NotContains(t, customer, "internal_notes")
+	assert.NotContains(t, customer, "assigned_to")
+	assert.NotContains(t, customer, "first_response_due_at")
+	assert.Equal(t, "", customer["resolution"])
+	assert.Len(t, customer["responses"], 1)
+	assert.NotContains(t, customer["user"], "email")
+	assert.Equal(t, float64(5), customer["ID"])
+
+	staff := ticketJSON(t, ticket, Staff)
+	assert.Equal(t, "Customer has claimed twice this year", staff["internal_notes"])
+	assert.Equal(t, float64(9), staff["assigned_to"])
+	assert.Equal(t, "Draft: refund once the courier confirms", staff["resolution"])
+	assert.Len(t, staff["responses"], 2)
+	assert.Equal(t, "jane@example.com", staff["user"].(map[string]interface{})["email"])
+
+	// The resolution is shown to the customer once the ticket is resolved
+	ticket.Status = models.TicketStatusResolved
+	assert.Equal(t, "Draft: refund once the courier confirms", ticketJSON(t, ticket, Customer)["resolution"])
+}
+
+func TestPaymentAudience(t *testing.T) {
+	payment := &models.Payment{OrderID: 3, RevolutPaymentID: "rev-pay-1", Amount: 12.5, Currency: "GBP", CheckoutURL: "https://pay.example/1"}
+	data, err := json.Marshal(NewPayment(payment, Customer))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "rev-pay-1")
+	assert.Contains(t, string(data), "https://pay.example/1")
+	assert.NotContains(t, string(data), `"order":`)
+
+	data, err = json.Marshal(NewPayment(payment, Staff))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"revolut_payment_id":"rev-pay-1"`)
+}
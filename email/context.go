@@ -0,0 +1,76 @@
+package email
+
+import (
+	"context"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/logging"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// requestIDKey is the template data key carrying the ID of the request an
+// email was triggered by. It is stored on the email, not rendered.
+const requestIDKey = "request_id"
+
+func requestIDFrom(data map[string]interface{}) string {
+	requestID, _ := data[requestIDKey].(string)
+	return requestID
+}
+
+// orderIDKey is the template data key carrying the ID of the order an email
+// is about, so it shows on the order's timeline. It is stored on the email,
+// not rendered.
+const orderIDKey = "order_id"
+
+func orderIDFrom(data map[string]interface{}) *uint {
+	if orderID, ok := data[orderIDKey].(uint); ok {
+		return &orderID
+	}
+	return nil
+}
+
+// WithContext returns a trigger service that records the request ID carried by
+// ctx on every email it sends, so the worker's logs can be traced back to the
+// request. The context's deadline and cancellation are not used, so it is safe
+// to call the returned service after the request has finished.
+func (t *EmailTriggerService) WithContext(ctx context.Context) *EmailTriggerService {
+	requestID := logging.RequestID(ctx)
+	if t == nil || requestID == "" {
+		return t
+	}
+	return &EmailTriggerService{
+		emailService: requestEmailService{EmailService: t.emailService, requestID: requestID},
+		db:           t.db,
+	}
+}
+
+// requestEmailService adds a request ID to the data of every email sent through it
+type requestEmailService struct {
+	EmailService
+	requestID string
+}
+
+func (s requestEmailService) withRequestID(data map[string]interface{}) map[string]interface{} {
+	copy := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		copy[key] = value
+	}
+	copy[requestIDKey] = s.requestID
+	return copy
+}
+
+func (s requestEmailService) SendEmail(template string, data map[string]interface{}, recipient models.EmailRecipient) error {
+	return s.EmailService.SendEmail(template, s.withRequestID(data), recipient)
+}
+
+func (s requestEmailService) SendBulkEmail(template string, data map[string]interface{}, recipients []models.EmailRecipient) error {
+	return s.EmailService.SendBulkEmail(template, s.withRequestID(data), recipients)
+}
+
+func (s requestEmailService) SendTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient) error {
+	return s.EmailService.SendTransactionalEmail(emailType, s.withRequestID(data), recipient)
+}
+
+func (s requestEmailService) ScheduleTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient, sendAt time.Time) (*models.Email, error) {
+	return s.EmailService.ScheduleTransactionalEmail(emailType, s.withRequestID(data), recipient, sendAt)
+}
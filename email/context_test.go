@@ -0,0 +1,35 @@
+package email
+
+import (
+	"context"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/logging"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingEmailService records the data of transactional emails
+type recordingEmailService struct {
+	EmailService
+	data map[string]interface{}
+}
+
+func (s *recordingEmailService) SendTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient) error {
+	s.data = data
+	return nil
+}
+
+func TestTriggerWithContextAddsRequestID(t *testing.T) {
+	recorder := &recordingEmailService{}
+	triggers := NewEmailTriggerService(recorder, nil)
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	assert.NoError(t, triggers.WithContext(ctx).TriggerWelcomeEmail("jane@example.com", "Jane", ""))
+	assert.Equal(t, "req-1", requestIDFrom(recorder.data))
+
+	// Without a request ID the service is returned unchanged
+	assert.Same(t, triggers, triggers.WithContext(context.Background()))
+	assert.NoError(t, triggers.TriggerWelcomeEmail("jane@example.com", "Jane", ""))
+	assert.Equal(t, "", requestIDFrom(recorder.data))
+}
@@ -0,0 +1,74 @@
+package email
+
+import (
+	"errors"
+	"log"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// FallbackEmailProvider sends through a primary provider and retries through a
+// fallback when the primary reports quota or permission errors. Status and
+// bounce queries go to the primary.
+type FallbackEmailProvider struct {
+	primary  EmailProvider
+	fallback EmailProvider
+}
+
+// NewFallbackEmailProvider chains a primary provider with a fallback
+func NewFallbackEmailProvider(primary, fallback EmailProvider) *FallbackEmailProvider {
+	return &FallbackEmailProvider{primary: primary, fallback: fallback}
+}
+
+// ShouldFallback reports whether a provider error means another provider should be tried
+func ShouldFallback(err error) bool {
+	return errors.Is(err, ErrProviderQuota) || errors.Is(err, ErrProviderPermission)
+}
+
+// SendEmail sends via the primary provider, falling back when it cannot send
+func (p *FallbackEmailProvider) SendEmail(email *models.Email) error {
+	err := p.primary.SendEmail(email)
+	if err == nil || !ShouldFallback(err) {
+		return err
+	}
+
+	log.Printf("⚠️ EMAIL: Primary provider unavailable (%v), retrying email %d via fallback", err, email.ID)
+	if fallbackErr := p.fallback.SendEmail(email); fallbackErr != nil {
+		return errors.Join(err, fallbackErr)
+	}
+	return nil
+}
+
+// SendBulkEmail sends each email with fallback so a mid-batch quota error only
+// moves the remaining emails to the fallback provider
+func (p *FallbackEmailProvider) SendBulkEmail(emails []*models.Email) error {
+	for _, email := range emails {
+		if err := p.SendEmail(email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDeliveryStatus retrieves the delivery status from the primary provider
+func (p *FallbackEmailProvider) GetDeliveryStatus(emailID string) (DeliveryStatus, error) {
+	return p.primary.GetDeliveryStatus(emailID)
+}
+
+// GetBounceList retrieves bounces from the primary provider
+func (p *FallbackEmailProvider) GetBounceList() ([]string, error) {
+	return p.primary.GetBounceList()
+}
+
+// GetComplaintList retrieves complaints from the primary provider
+func (p *FallbackEmailProvider) GetComplaintList() ([]string, error) {
+	return p.primary.GetComplaintList()
+}
+
+// RateLimits returns the primary provider's limits
+func (p *FallbackEmailProvider) RateLimits() RateLimits {
+	if limited, ok := p.primary.(RateLimitedProvider); ok {
+		return limited.RateLimits()
+	}
+	return RateLimits{}
+}
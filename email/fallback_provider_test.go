@@ -0,0 +1,89 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider records sends and fails with a fixed error
+type stubProvider struct {
+	MockEmailProvider
+	err  error
+	sent int
+}
+
+func (s *stubProvider) SendEmail(email *models.Email) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent++
+	return nil
+}
+
+func TestFallbackEmailProvider(t *testing.T) {
+	email := &models.Email{Recipients: []models.EmailRecipient{{Email: "buyer@example.com"}}}
+
+	cases := []struct {
+		name          string
+		primaryErr    error
+		wantErr       bool
+		wantFallbacks int
+	}{
+		{"primary succeeds", nil, false, 0},
+		{"quota error falls back", graphStatusError(http.StatusTooManyRequests, errors.New("throttled")), false, 1},
+		{"permission error falls back", fmt.Errorf("access denied (%w)", ErrProviderPermission), false, 1},
+		{"other errors do not fall back", errors.New("invalid recipient"), true, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			primary := &stubProvider{err: tc.primaryErr}
+			fallback := &stubProvider{}
+			provider := NewFallbackEmailProvider(primary, fallback)
+
+			err := provider.SendEmail(email)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantFallbacks, fallback.sent)
+		})
+	}
+}
+
+func TestSMTPEmailProvider(t *testing.T) {
+	_, err := NewSMTPEmailProvider(&cfg.SMTPConfig{})
+	assert.Error(t, err)
+	_, err = NewSMTPEmailProvider(&cfg.SMTPConfig{Host: "smtp.example.com", Port: 587, TLSMode: "ssl", SenderEmail: "shop@example.com"})
+	assert.Error(t, err)
+
+	provider, err := NewSMTPEmailProvider(&cfg.SMTPConfig{
+		Host:        "smtp.example.com",
+		Port:        587,
+		TLSMode:     "starttls",
+		SenderEmail: "shop@example.com",
+		SenderName:  "Shop",
+	})
+	require.NoError(t, err)
+
+	message, err := provider.buildMessage(&models.Email{
+		Subject:     "Votre commande est prête",
+		HTMLContent: "<p>Hello</p>",
+		TextContent: "Hello",
+		Recipients:  []models.EmailRecipient{{Email: "buyer@example.com", Name: "Buyer"}},
+	})
+	require.NoError(t, err)
+
+	raw := string(message)
+	assert.Contains(t, raw, `From: "Shop" <shop@example.com>`)
+	assert.Contains(t, raw, `To: "Buyer" <buyer@example.com>`)
+	assert.Contains(t, raw, "Subject: =?utf-8?q?")
+	assert.Contains(t, raw, "multipart/alternative")
+	assert.Contains(t, raw, "text/plain; charset=utf-8")
+	assert.Contains(t, raw, "text/html; charset=utf-8")
+	assert.Regexp(t, `Message-ID: <\d+\.[0-9a-f]+@example\.com>\r\n`, raw)
+}
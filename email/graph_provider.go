@@ -14,6 +14,9 @@ import (
 	"github.com/AzureAD/microsoft-authentication-library-for-go/apps/confidential"
 	"github.com/YasserCherfaoui/MarketProGo/cfg"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // GraphEmailProvider implements EmailProvider using Microsoft Graph API
@@ -71,7 +74,7 @@ func NewGraphEmailProvider(config *cfg.OutlookConfig) (*GraphEmailProvider, erro
 		senderEmail: config.SenderEmail,
 		senderName:  config.SenderName,
 		authClient:  &authClient,
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		httpClient:  &http.Client{Timeout: 30 * time.Second, Transport: tracing.Transport(nil)},
 	}
 
 	// Test the connection
@@ -175,6 +178,17 @@ func (p *GraphEmailProvider) testConnection() error {
 
 // SendEmail sends a single email via Microsoft Graph API
 func (p *GraphEmailProvider) SendEmail(email *models.Email) error {
+	ctx, span := tracing.Start(context.Background(), "graph.send_mail", trace.WithAttributes(
+		attribute.Int64("email.id", int64(email.ID)),
+		attribute.String("request.id", email.RequestID),
+		attribute.Int("email.recipients", len(email.Recipients)),
+	))
+	err := p.sendEmail(ctx, email)
+	tracing.End(span, err)
+	return err
+}
+
+func (p *GraphEmailProvider) sendEmail(ctx context.Context, email *models.Email) error {
 	if len(email.Recipients) == 0 {
 		return fmt.Errorf("❌ GRAPH PROVIDER: No recipients specified")
 	}
@@ -182,7 +196,6 @@ func (p *GraphEmailProvider) SendEmail(email *models.Email) error {
 	log.Printf("📧 GRAPH PROVIDER: Sending email to %d recipients", len(email.Recipients))
 
 	// Get access token
-	ctx := context.Background()
 	scopes := []string{"https://graph.microsoft.com/.default"}
 	result, err := p.authClient.AcquireTokenSilent(ctx, scopes)
 	if err != nil {
@@ -227,7 +240,7 @@ func (p *GraphEmailProvider) SendEmail(email *models.Email) error {
 
 	// Create HTTP request
 	url := fmt.Sprintf("https://graph.microsoft.com/v1.0/users/%s/sendMail", p.senderEmail)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("❌ GRAPH PROVIDER: Failed to create request: %w", err)
 	}
@@ -268,7 +281,7 @@ func (p *GraphEmailProvider) SendEmail(email *models.Email) error {
 						"2. Admin consent for the application\n"+
 						"3. User has mailbox access\n"+
 						"4. Application has proper API permissions\n"+
-						"Error: %s", message)
+						"Error: %s (%w)", message, ErrProviderPermission)
 				case "ErrorInvalidRequest":
 					return fmt.Errorf("❌ GRAPH PROVIDER: Invalid request. Please check:\n"+
 						"1. Email format is valid\n"+
@@ -281,7 +294,7 @@ func (p *GraphEmailProvider) SendEmail(email *models.Email) error {
 						"1. Mailbox storage limits\n"+
 						"2. API rate limits\n"+
 						"3. Daily sending limits\n"+
-						"Error: %s", message)
+						"Error: %s (%w)", message, ErrProviderQuota)
 				case "ErrorMailboxNotFound":
 					return fmt.Errorf("❌ GRAPH PROVIDER: Mailbox not found. Please check:\n"+
 						"1. Sender email exists in tenant\n"+
@@ -293,20 +306,37 @@ func (p *GraphEmailProvider) SendEmail(email *models.Email) error {
 						"1. App has Mail.Send permission\n"+
 						"2. Admin consent granted\n"+
 						"3. User has mailbox access\n"+
-						"Error: %s", message)
+						"Error: %s (%w)", message, ErrProviderPermission)
 				default:
-					return fmt.Errorf("❌ GRAPH PROVIDER: Graph API error (Code: %s): %s", code, message)
+					return graphStatusError(resp.StatusCode, fmt.Errorf("❌ GRAPH PROVIDER: Graph API error (Code: %s): %s", code, message))
 				}
 			}
 		}
 
-		return fmt.Errorf("❌ GRAPH PROVIDER: Graph API returned status %d", resp.StatusCode)
+		return graphStatusError(resp.StatusCode, fmt.Errorf("❌ GRAPH PROVIDER: Graph API returned status %d", resp.StatusCode))
 	}
 
 	log.Printf("✅ GRAPH PROVIDER: Email sent successfully to %d recipients", len(email.Recipients))
 	return nil
 }
 
+// RateLimits returns Exchange Online sending limits for a single mailbox
+func (p *GraphEmailProvider) RateLimits() RateLimits {
+	return RateLimits{PerMinute: 30, PerDay: 10000}
+}
+
+// graphStatusError marks throttling and authorization failures so a fallback
+// provider can take over
+func graphStatusError(statusCode int, err error) error {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w (%w)", err, ErrProviderQuota)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w (%w)", err, ErrProviderPermission)
+	}
+	return err
+}
+
 // SendBulkEmail sends multiple emails in batch via Microsoft Graph API
 func (p *GraphEmailProvider) SendBulkEmail(emails []*models.Email) error {
 	log.Printf("📧 GRAPH PROVIDER: Sending %d emails in bulk", len(emails))
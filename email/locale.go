@@ -0,0 +1,76 @@
+package email
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// localeKey is the template data key carrying the locale an email is
+// rendered in. The trigger service sets it to the locale of the user an email
+// is sent to; without it the template engine's default locale is used.
+const localeKey = "locale"
+
+func localeFrom(data map[string]interface{}) string {
+	locale, _ := data[localeKey].(string)
+	return locale
+}
+
+// localizer is implemented by template engines holding templates in several
+// locales
+type localizer interface {
+	Localized(templateName, locale string) string
+}
+
+// localizedTemplate returns the name of the template to render templateName
+// with for the locale of the email
+func (s *EmailServiceImplementation) localizedTemplate(templateName string, data map[string]interface{}) string {
+	engine, ok := s.templateEngine.(localizer)
+	if !ok {
+		return templateName
+	}
+	return engine.Localized(templateName, localeFrom(data))
+}
+
+// userLocaleService sets the locale of the emails sent through it to the
+// locale of the user with the recipient's address, unless their data sets one
+type userLocaleService struct {
+	EmailService
+	db *gorm.DB
+}
+
+func (s userLocaleService) withLocale(data map[string]interface{}, recipient models.EmailRecipient) map[string]interface{} {
+	if localeFrom(data) != "" || recipient.Email == "" {
+		return data
+	}
+	var user models.User
+	if err := s.db.Select("locale").Where("email = ?", recipient.Email).Take(&user).Error; err != nil || user.Locale == "" {
+		return data
+	}
+	copy := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		copy[key] = value
+	}
+	copy[localeKey] = user.Locale
+	return copy
+}
+
+func (s userLocaleService) SendEmail(template string, data map[string]interface{}, recipient models.EmailRecipient) error {
+	return s.EmailService.SendEmail(template, s.withLocale(data, recipient), recipient)
+}
+
+func (s userLocaleService) SendTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient) error {
+	return s.EmailService.SendTransactionalEmail(emailType, s.withLocale(data, recipient), recipient)
+}
+
+func (s userLocaleService) ScheduleTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient, sendAt time.Time) (*models.Email, error) {
+	return s.EmailService.ScheduleTransactionalEmail(emailType, s.withLocale(data, recipient), recipient, sendAt)
+}
+
+func (s userLocaleService) InTx(tx *gorm.DB) EmailService {
+	if service, ok := s.EmailService.(transactionalEmailService); ok {
+		return userLocaleService{EmailService: service.InTx(tx), db: tx}
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalizedTemplate(t *testing.T) {
+	engine, db := setupTemplateTest(t)
+	require.NoError(t, engine.ReloadTemplates())
+
+	assert.Equal(t, "fr/welcome", engine.Localized("welcome", "fr"))
+	assert.Equal(t, "welcome", engine.Localized("welcome", "en"))
+	assert.Equal(t, "gift_card", engine.Localized("gift_card", "fr"), "locales without the template fall back to the default")
+
+	// Translations can be stored in the database under the localized name
+	require.NoError(t, db.Create(&models.EmailTemplate{
+		Name:        "ar/gift_card",
+		Subject:     "بطاقة هدية",
+		HTMLContent: "<p>{{.UserName}}</p>",
+		Version:     1,
+		IsActive:    true,
+	}).Error)
+	require.NoError(t, engine.ReloadTemplates())
+	assert.Equal(t, "ar/gift_card", engine.Localized("gift_card", "ar"))
+}
+
+func TestLocalizedTemplateFallsBackToDefaultLocale(t *testing.T) {
+	engine, _ := setupTemplateTest(t)
+	engine.WithDefaultLocale("fr")
+	require.NoError(t, engine.ReloadTemplates())
+
+	assert.Equal(t, "ar/welcome", engine.Localized("welcome", "ar"))
+	assert.Equal(t, "fr/welcome", engine.Localized("welcome", ""), "recipients without a locale get the default locale")
+	assert.Equal(t, "welcome", engine.Localized("welcome", "en"))
+	assert.Equal(t, "gift_card", engine.Localized("gift_card", "ar"), "then the English template")
+}
+
+func TestRenderArabicTemplate(t *testing.T) {
+	engine, db := setupTemplateTest(t)
+	data := map[string]interface{}{"UserName": "Yasmine", "ResetLink": "https://example.com/reset", "ExpiryTime": 24}
+
+	html, _, err := engine.RenderTemplate("ar/password_reset", data)
+	require.NoError(t, err)
+	assert.Contains(t, html, `<html lang="ar" dir="rtl">`)
+	assert.Contains(t, html, "مرحبًا Yasmine")
+
+	// Templates can lay themselves out by the locale they are rendered in
+	require.NoError(t, db.Create(&models.EmailTemplate{
+		Name:        "ar/gift_card",
+		HTMLContent: `<div lang="{{.Locale}}" dir="{{.TextDirection}}">{{.UserName}}</div>`,
+		Version:     1,
+		IsActive:    true,
+	}).Error)
+	require.NoError(t, engine.ReloadTemplates())
+	html, _, err = engine.RenderTemplate("ar/gift_card", data)
+	require.NoError(t, err)
+	assert.Equal(t, `<div lang="ar" dir="rtl">Yasmine</div>`, html)
+}
+
+func TestTriggersSendEmailsInUserLocale(t *testing.T) {
+	service, queue, db := setupSchedulingTest(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	require.NoError(t, db.Create(&models.User{Email: "amelie@example.com", Password: "x", UserType: models.Customer, Locale: "fr"}).Error)
+	require.NoError(t, db.Create(&models.User{Email: "yasmine@example.com", Password: "x", UserType: models.Customer, Locale: "ar"}).Error)
+	triggers := NewEmailTriggerService(service, db)
+
+	require.NoError(t, triggers.TriggerWelcomeEmail("amelie@example.com", "Amélie", ""))
+	french, _ := queue.Dequeue()
+	require.NotNil(t, french)
+	assert.Contains(t, french.HTMLContent, "Bienvenue, Amélie")
+	assert.Equal(t, "welcome", french.Template)
+
+	require.NoError(t, triggers.TriggerWelcomeEmail("yasmine@example.com", "Yasmine", ""))
+	arabic, _ := queue.Dequeue()
+	require.NotNil(t, arabic)
+	assert.Contains(t, arabic.HTMLContent, `dir="rtl"`)
+
+	require.NoError(t, triggers.TriggerWelcomeEmail("guest@example.com", "Guest", ""))
+	english, _ := queue.Dequeue()
+	require.NotNil(t, english)
+	assert.Contains(t, english.HTMLContent, "Welcome, Guest")
+
+	// A locale in the data overrides the user's
+	data := map[string]interface{}{"UserName": "Amélie", localeKey: "en"}
+	require.NoError(t, triggers.SendTemplateDirect("welcome", data, models.EmailRecipient{Email: "amelie@example.com"}, models.EmailTypeWelcome))
+	overridden, _ := queue.Dequeue()
+	require.NotNil(t, overridden)
+	assert.Contains(t, overridden.HTMLContent, "Welcome, Amélie")
+}
@@ -0,0 +1,75 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/outbox"
+	"gorm.io/gorm"
+)
+
+// KindEmail is the outbox kind of emails waiting to be put on the send queue
+const KindEmail = "email"
+
+// transactionalEmailService is implemented by email services that can send
+// through the outbox of a transaction
+type transactionalEmailService interface {
+	InTx(tx *gorm.DB) EmailService
+}
+
+// InTx returns a service that renders and saves emails in tx and records them
+// in the outbox instead of queuing them, so they are only sent if tx commits.
+// The outbox dispatcher puts them on the send queue. Scheduled emails are not
+// affected and are still queued straight away.
+func (s *EmailServiceImplementation) InTx(tx *gorm.DB) EmailService {
+	deferred := *s
+	deferred.db = tx
+	deferred.queue = outboxQueue{EmailQueue: s.queue, tx: tx}
+	deferred.analytics = NewEmailAnalytics(tx)
+	deferred.suppressions = NewSuppressionList(tx)
+	return &deferred
+}
+
+func (s requestEmailService) InTx(tx *gorm.DB) EmailService {
+	if service, ok := s.EmailService.(transactionalEmailService); ok {
+		return requestEmailService{EmailService: service.InTx(tx), requestID: s.requestID}
+	}
+	return s
+}
+
+// InTx returns a trigger service whose emails are written to the outbox in tx
+// and sent once it commits. See EmailServiceImplementation.InTx.
+func (t *EmailTriggerService) InTx(tx *gorm.DB) *EmailTriggerService {
+	if t == nil {
+		return nil
+	}
+	service, ok := t.emailService.(transactionalEmailService)
+	if !ok {
+		return t
+	}
+	deferred := &EmailTriggerService{emailService: service.InTx(tx), db: tx}
+	return deferred.WithContext(tx.Statement.Context)
+}
+
+// outboxQueue records enqueued emails in the outbox of a transaction
+type outboxQueue struct {
+	EmailQueue
+	tx *gorm.DB
+}
+
+func (q outboxQueue) Enqueue(email *models.Email) error {
+	return outbox.Enqueue(q.tx, KindEmail, string(email.Type), email)
+}
+
+// OutboxHandler puts emails recorded in the outbox on queue
+func OutboxHandler(queue EmailQueue) outbox.Handler {
+	return func(ctx context.Context, msg *models.OutboxMessage) error {
+		var email models.Email
+		if err := json.Unmarshal(msg.Payload, &email); err != nil {
+			return fmt.Errorf("invalid email outbox payload: %w", err)
+		}
+		return queue.Enqueue(&email)
+	}
+}
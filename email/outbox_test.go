@@ -0,0 +1,57 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/logging"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/outbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestTriggerInTxSendsOnlyOnCommit(t *testing.T) {
+	service, queue, db := setupSchedulingTest(t)
+	require.NoError(t, db.AutoMigrate(&models.OutboxMessage{}))
+	triggers := NewEmailTriggerService(service, db)
+	data := map[string]interface{}{"subject": "Ticket updated", "TicketID": 7}
+
+	// A rolled back transaction leaves neither the email nor its outbox message
+	rollback := errors.New("rollback")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, triggers.InTx(tx).TriggerTicketStatusUpdated("buyer@example.com", "Buyer", data))
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+	var emails, messages int64
+	db.Model(&models.Email{}).Count(&emails)
+	db.Model(&models.OutboxMessage{}).Count(&messages)
+	assert.Zero(t, emails)
+	assert.Zero(t, messages)
+
+	// A committed one is queued by the dispatcher, not by the trigger
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	require.NoError(t, db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return triggers.InTx(tx).TriggerTicketStatusUpdated("buyer@example.com", "Buyer", data)
+	}))
+	size, _ := queue.GetQueueSize()
+	assert.Equal(t, int64(0), size)
+
+	dispatcher := outbox.NewDispatcher(db)
+	dispatcher.Handle(KindEmail, OutboxHandler(queue))
+	attempted, err := dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	queued, _ := queue.Dequeue()
+	require.NotNil(t, queued)
+	assert.Equal(t, "buyer@example.com", queued.Recipients[0].Email)
+	assert.Equal(t, "req-1", queued.RequestID)
+
+	var stored models.Email
+	require.NoError(t, db.First(&stored, queued.ID).Error)
+	assert.Equal(t, models.EmailTypeTicketStatusUpdated, stored.Type)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -23,6 +24,13 @@ type EmailProvider interface {
 	GetComplaintList() ([]string, error)
 }
 
+// Provider errors that signal the provider cannot currently send on our behalf.
+// Providers wrap them so callers can fall back to another provider.
+var (
+	ErrProviderQuota      = errors.New("email provider quota exceeded")
+	ErrProviderPermission = errors.New("email provider permission denied")
+)
+
 // DeliveryStatus represents the delivery status of an email
 type DeliveryStatus string
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,42 +16,91 @@ import (
 type EmailQueue interface {
 	Enqueue(email *models.Email) error
 	Dequeue() (*models.Email, error)
+	DequeueBatch(max int) ([]*models.Email, error)
 	MarkAsProcessed(emailID string) error
 	MarkAsFailed(emailID string, error string) error
 	GetFailedEmails() ([]*models.Email, error)
 	GetQueueSize() (int64, error)
 	ClearQueue() error
+
+	// Delayed delivery
+	Schedule(email *models.Email, sendAt time.Time) error
+	CancelScheduled(emailID string) (bool, error)
+	PromoteDue(now time.Time) ([]*models.Email, error)
+
+	// Dead-letter queue for emails that exhausted their retries
+	DeadLetter(email *models.Email, errorMsg string) error
+	GetDeadLetters() ([]*DeadLetter, error)
+	TakeDeadLetter(emailID string) (*DeadLetter, error)
+	DeadLetterSize() (int64, error)
+}
+
+// MaxEmailRetries is the number of failed sends after which an email is dead-lettered
+const MaxEmailRetries = 3
+
+// DeadLetter is an email that failed MaxEmailRetries times, with the last error
+type DeadLetter struct {
+	Email    *models.Email `json:"email"`
+	Error    string        `json:"error"`
+	FailedAt time.Time     `json:"failed_at"`
 }
 
 // MockEmailQueue implements EmailQueue for testing and development
 type MockEmailQueue struct {
-	emails []*models.Email
+	emails    []*models.Email
+	bulk      []*models.Email
+	scheduled map[string]*models.Email
+	dead      map[string]*DeadLetter
 }
 
 // NewMockEmailQueue creates a new mock email queue
 func NewMockEmailQueue() *MockEmailQueue {
 	return &MockEmailQueue{
-		emails: make([]*models.Email, 0),
+		emails:    make([]*models.Email, 0),
+		scheduled: make(map[string]*models.Email),
+		dead:      make(map[string]*DeadLetter),
 	}
 }
 
 // Enqueue adds an email to the mock queue
 func (m *MockEmailQueue) Enqueue(email *models.Email) error {
-	m.emails = append(m.emails, email)
+	if email.Priority == models.EmailPriorityBulk {
+		m.bulk = append(m.bulk, email)
+	} else {
+		m.emails = append(m.emails, email)
+	}
 	fmt.Printf("MOCK QUEUE: Enqueued email to %s with subject: %s\n",
 		email.Recipients[0].Email, email.Subject)
 	return nil
 }
 
-// Dequeue removes and returns the next email from the mock queue
+// Dequeue removes and returns the next email from the mock queue,
+// transactional emails first
 func (m *MockEmailQueue) Dequeue() (*models.Email, error) {
-	if len(m.emails) == 0 {
-		return nil, nil
+	if len(m.emails) > 0 {
+		email := m.emails[0]
+		m.emails = m.emails[1:]
+		return email, nil
+	}
+	if len(m.bulk) > 0 {
+		email := m.bulk[0]
+		m.bulk = m.bulk[1:]
+		return email, nil
 	}
+	return nil, nil
+}
 
-	email := m.emails[0]
-	m.emails = m.emails[1:]
-	return email, nil
+// DequeueBatch removes up to max emails, transactional emails first
+func (m *MockEmailQueue) DequeueBatch(max int) ([]*models.Email, error) {
+	var batch []*models.Email
+	for len(batch) < max {
+		email, _ := m.Dequeue()
+		if email == nil {
+			break
+		}
+		batch = append(batch, email)
+	}
+	return batch, nil
 }
 
 // MarkAsProcessed marks an email as successfully processed
@@ -72,15 +122,79 @@ func (m *MockEmailQueue) GetFailedEmails() ([]*models.Email, error) {
 
 // GetQueueSize returns the size of the mock queue
 func (m *MockEmailQueue) GetQueueSize() (int64, error) {
-	return int64(len(m.emails)), nil
+	return int64(len(m.emails) + len(m.bulk)), nil
 }
 
 // ClearQueue clears the mock queue
 func (m *MockEmailQueue) ClearQueue() error {
 	m.emails = make([]*models.Email, 0)
+	m.bulk = nil
+	m.scheduled = make(map[string]*models.Email)
+	m.dead = make(map[string]*DeadLetter)
+	return nil
+}
+
+// Schedule holds an email until sendAt
+func (m *MockEmailQueue) Schedule(email *models.Email, sendAt time.Time) error {
+	email.SendAt = &sendAt
+	m.scheduled[fmt.Sprintf("%d", email.ID)] = email
 	return nil
 }
 
+// CancelScheduled removes a scheduled email, reporting whether it was still pending
+func (m *MockEmailQueue) CancelScheduled(emailID string) (bool, error) {
+	if _, ok := m.scheduled[emailID]; !ok {
+		return false, nil
+	}
+	delete(m.scheduled, emailID)
+	return true, nil
+}
+
+// PromoteDue moves scheduled emails that are due onto the queue
+func (m *MockEmailQueue) PromoteDue(now time.Time) ([]*models.Email, error) {
+	var promoted []*models.Email
+	for id, email := range m.scheduled {
+		if email.SendAt != nil && email.SendAt.After(now) {
+			continue
+		}
+		delete(m.scheduled, id)
+		m.Enqueue(email)
+		promoted = append(promoted, email)
+	}
+	return promoted, nil
+}
+
+// DeadLetter moves an email to the dead-letter queue
+func (m *MockEmailQueue) DeadLetter(email *models.Email, errorMsg string) error {
+	m.dead[fmt.Sprintf("%d", email.ID)] = &DeadLetter{Email: email, Error: errorMsg, FailedAt: time.Now()}
+	return nil
+}
+
+// GetDeadLetters returns dead-lettered emails, oldest first
+func (m *MockEmailQueue) GetDeadLetters() ([]*DeadLetter, error) {
+	letters := make([]*DeadLetter, 0, len(m.dead))
+	for _, letter := range m.dead {
+		letters = append(letters, letter)
+	}
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+// TakeDeadLetter removes and returns a dead-lettered email, or nil if it is not there
+func (m *MockEmailQueue) TakeDeadLetter(emailID string) (*DeadLetter, error) {
+	letter, ok := m.dead[emailID]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.dead, emailID)
+	return letter, nil
+}
+
+// DeadLetterSize returns the number of dead-lettered emails
+func (m *MockEmailQueue) DeadLetterSize() (int64, error) {
+	return int64(len(m.dead)), nil
+}
+
 // RedisEmailQueue implements EmailQueue using Redis
 type RedisEmailQueue struct {
 	client *redis.Client
@@ -105,7 +219,7 @@ func (r *RedisEmailQueue) Enqueue(email *models.Email) error {
 
 	// Add to Redis list (left push for FIFO)
 	ctx := context.Background()
-	err = r.client.LPush(ctx, r.queue, emailData).Err()
+	err = r.client.LPush(ctx, r.laneKey(email), emailData).Err()
 	if err != nil {
 		return fmt.Errorf("failed to enqueue email: %w", err)
 	}
@@ -117,8 +231,8 @@ func (r *RedisEmailQueue) Enqueue(email *models.Email) error {
 func (r *RedisEmailQueue) Dequeue() (*models.Email, error) {
 	ctx := context.Background()
 
-	// Pop from right side of list (FIFO)
-	result, err := r.client.BRPop(ctx, 5*time.Second, r.queue).Result()
+	// Pop from right side of list (FIFO), checking the transactional lane first
+	result, err := r.client.BRPop(ctx, 5*time.Second, r.queue, r.bulkKey()).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil // Queue is empty
@@ -140,6 +254,36 @@ func (r *RedisEmailQueue) Dequeue() (*models.Email, error) {
 	return &email, nil
 }
 
+// DequeueBatch blocks for the first email, then takes up to max-1 more without
+// waiting. Transactional emails are always drained before bulk ones.
+func (r *RedisEmailQueue) DequeueBatch(max int) ([]*models.Email, error) {
+	first, err := r.Dequeue()
+	if err != nil || first == nil {
+		return nil, err
+	}
+	batch := []*models.Email{first}
+
+	ctx := context.Background()
+	for _, lane := range []string{r.queue, r.bulkKey()} {
+		for len(batch) < max {
+			data, err := r.client.RPop(ctx, lane).Result()
+			if err == redis.Nil {
+				break
+			}
+			if err != nil {
+				return batch, fmt.Errorf("failed to dequeue email: %w", err)
+			}
+
+			var email models.Email
+			if err := json.Unmarshal([]byte(data), &email); err != nil {
+				return batch, fmt.Errorf("failed to unmarshal email: %w", err)
+			}
+			batch = append(batch, &email)
+		}
+	}
+	return batch, nil
+}
+
 // MarkAsProcessed marks an email as successfully processed
 func (r *RedisEmailQueue) MarkAsProcessed(emailID string) error {
 	ctx := context.Background()
@@ -219,15 +363,199 @@ func (r *RedisEmailQueue) GetQueueSize() (int64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to get queue size: %w", err)
 	}
-	return size, nil
+	bulkSize, err := r.client.LLen(ctx, r.bulkKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queue size: %w", err)
+	}
+	return size + bulkSize, nil
 }
 
 // ClearQueue clears the Redis queue
 func (r *RedisEmailQueue) ClearQueue() error {
 	ctx := context.Background()
-	err := r.client.Del(ctx, r.queue).Err()
+	err := r.client.Del(ctx, r.queue, r.bulkKey(), r.scheduledKey(), r.scheduledDataKey(), r.deadLetterKey()).Err()
 	if err != nil {
 		return fmt.Errorf("failed to clear queue: %w", err)
 	}
 	return nil
 }
+
+// bulkKey is the list holding bulk emails, sent after transactional ones
+func (r *RedisEmailQueue) bulkKey() string {
+	return r.queue + ":bulk"
+}
+
+// laneKey returns the list an email is queued on
+func (r *RedisEmailQueue) laneKey(email *models.Email) string {
+	if email.Priority == models.EmailPriorityBulk {
+		return r.bulkKey()
+	}
+	return r.queue
+}
+
+// scheduledKey is the sorted set of scheduled email IDs scored by send time
+func (r *RedisEmailQueue) scheduledKey() string {
+	return r.queue + ":scheduled"
+}
+
+// scheduledDataKey is the hash holding scheduled email payloads by ID
+func (r *RedisEmailQueue) scheduledDataKey() string {
+	return r.queue + ":scheduled:data"
+}
+
+// Schedule stores an email in the delayed queue until sendAt
+func (r *RedisEmailQueue) Schedule(email *models.Email, sendAt time.Time) error {
+	email.SendAt = &sendAt
+	emailData, err := json.Marshal(email)
+	if err != nil {
+		return fmt.Errorf("failed to marshal email: %w", err)
+	}
+
+	ctx := context.Background()
+	id := fmt.Sprintf("%d", email.ID)
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, r.scheduledDataKey(), id, emailData)
+	pipe.ZAdd(ctx, r.scheduledKey(), redis.Z{Score: float64(sendAt.Unix()), Member: id})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to schedule email: %w", err)
+	}
+	return nil
+}
+
+// CancelScheduled removes a scheduled email. It returns false when the email
+// is no longer in the delayed queue, e.g. because it has been dispatched.
+func (r *RedisEmailQueue) CancelScheduled(emailID string) (bool, error) {
+	ctx := context.Background()
+	removed, err := r.client.ZRem(ctx, r.scheduledKey(), emailID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel scheduled email: %w", err)
+	}
+	if err := r.client.HDel(ctx, r.scheduledDataKey(), emailID).Err(); err != nil {
+		return false, fmt.Errorf("failed to remove scheduled email data: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// PromoteDue moves scheduled emails whose send time has passed onto the
+// immediate queue. Each ID is claimed with ZREM so concurrent workers never
+// enqueue the same email twice.
+func (r *RedisEmailQueue) PromoteDue(now time.Time) ([]*models.Email, error) {
+	ctx := context.Background()
+	ids, err := r.client.ZRangeByScore(ctx, r.scheduledKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("%d", now.Unix()),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduled emails: %w", err)
+	}
+
+	var promoted []*models.Email
+	for _, id := range ids {
+		claimed, err := r.client.ZRem(ctx, r.scheduledKey(), id).Result()
+		if err != nil {
+			return promoted, fmt.Errorf("failed to claim scheduled email: %w", err)
+		}
+		if claimed == 0 {
+			continue // taken by another worker or canceled
+		}
+
+		emailData, err := r.client.HGet(ctx, r.scheduledDataKey(), id).Result()
+		if err != nil {
+			return promoted, fmt.Errorf("failed to load scheduled email %s: %w", id, err)
+		}
+		r.client.HDel(ctx, r.scheduledDataKey(), id)
+
+		var email models.Email
+		if err := json.Unmarshal([]byte(emailData), &email); err != nil {
+			return promoted, fmt.Errorf("failed to unmarshal email: %w", err)
+		}
+
+		if err := r.client.LPush(ctx, r.laneKey(&email), emailData).Err(); err != nil {
+			return promoted, fmt.Errorf("failed to enqueue scheduled email: %w", err)
+		}
+		promoted = append(promoted, &email)
+	}
+	return promoted, nil
+}
+
+// deadLetterKey is the hash holding dead-lettered emails by ID
+func (r *RedisEmailQueue) deadLetterKey() string {
+	return r.queue + ":dead"
+}
+
+// DeadLetter moves an email to the dead-letter queue
+func (r *RedisEmailQueue) DeadLetter(email *models.Email, errorMsg string) error {
+	data, err := json.Marshal(&DeadLetter{Email: email, Error: errorMsg, FailedAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	ctx := context.Background()
+	if err := r.client.HSet(ctx, r.deadLetterKey(), fmt.Sprintf("%d", email.ID), data).Err(); err != nil {
+		return fmt.Errorf("failed to dead-letter email: %w", err)
+	}
+	return nil
+}
+
+// GetDeadLetters returns dead-lettered emails, oldest first
+func (r *RedisEmailQueue) GetDeadLetters() ([]*DeadLetter, error) {
+	ctx := context.Background()
+	entries, err := r.client.HGetAll(ctx, r.deadLetterKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letters: %w", err)
+	}
+
+	letters := make([]*DeadLetter, 0, len(entries))
+	for id, data := range entries {
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(data), &letter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter %s: %w", id, err)
+		}
+		letters = append(letters, &letter)
+	}
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+// TakeDeadLetter removes and returns a dead-lettered email, or nil if it is not
+// there. The entry is claimed with HDEL so concurrent requeues cannot both take it.
+func (r *RedisEmailQueue) TakeDeadLetter(emailID string) (*DeadLetter, error) {
+	ctx := context.Background()
+	data, err := r.client.HGet(ctx, r.deadLetterKey(), emailID).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	claimed, err := r.client.HDel(ctx, r.deadLetterKey(), emailID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+	if claimed == 0 {
+		return nil, nil
+	}
+
+	var letter DeadLetter
+	if err := json.Unmarshal([]byte(data), &letter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	return &letter, nil
+}
+
+// DeadLetterSize returns the number of dead-lettered emails
+func (r *RedisEmailQueue) DeadLetterSize() (int64, error) {
+	size, err := r.client.HLen(context.Background(), r.deadLetterKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get dead letter size: %w", err)
+	}
+	return size, nil
+}
+
+// sortDeadLetters orders dead letters by failure time, oldest first
+func sortDeadLetters(letters []*DeadLetter) {
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].FailedAt.Before(letters[j].FailedAt)
+	})
+}
@@ -0,0 +1,74 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+var (
+	ErrNoRecipient    = errors.New("email has no stored recipient")
+	ErrNoTemplateData = errors.New("email was stored without its template data and cannot be rendered again")
+)
+
+// templateData returns data encoded to be stored with an email, or nil when
+// it cannot be encoded
+func templateData(data map[string]interface{}) models.EmailJSON {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// ResendEmail queues a copy of a stored email to its recipient. With
+// rerender the copy is rendered again from the email's template data with the
+// current templates; otherwise the stored content is sent as it was. Like
+// any email, the copy is not sent to a suppressed recipient.
+func (s *EmailServiceImplementation) ResendEmail(emailID uint, rerender bool) (*models.Email, error) {
+	var original models.Email
+	if err := s.db.First(&original, emailID).Error; err != nil {
+		return nil, err
+	}
+	if original.RecipientEmail == "" {
+		return nil, ErrNoRecipient
+	}
+
+	resent := &models.Email{
+		Type:         original.Type,
+		Template:     original.Template,
+		Recipients:   []models.EmailRecipient{{Email: original.RecipientEmail, Name: original.RecipientName}},
+		Subject:      original.Subject,
+		HTMLContent:  original.HTMLContent,
+		TextContent:  original.TextContent,
+		Status:       models.EmailStatusPending,
+		Priority:     original.Priority,
+		OrderID:      original.OrderID,
+		TemplateData: original.TemplateData,
+		ResentFromID: &original.ID,
+	}
+	if rerender {
+		if original.TemplateData.IsNull() {
+			return nil, ErrNoTemplateData
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal(original.TemplateData, &data); err != nil {
+			return nil, fmt.Errorf("failed to read template data: %w", err)
+		}
+		localized := s.localizedTemplate(original.Template, data)
+		htmlContent, textContent, err := s.templateEngine.RenderTemplate(localized, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render email template: %w", err)
+		}
+		resent.Subject = s.subjectFor(localized, data)
+		resent.HTMLContent = htmlContent
+		resent.TextContent = textContent
+	}
+
+	if err := s.QueueEmail(resent); err != nil {
+		return resent, err
+	}
+	return resent, nil
+}
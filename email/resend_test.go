@@ -0,0 +1,55 @@
+package email
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResendEmail(t *testing.T) {
+	service, queue, db := setupSchedulingTest(t)
+	recipient := models.EmailRecipient{Email: "buyer@example.com", Name: "Buyer"}
+	require.NoError(t, service.SendTransactionalEmail(models.EmailTypeWelcome, map[string]interface{}{"UserName": "Buyer"}, recipient))
+	queue.Dequeue()
+
+	var original models.Email
+	require.NoError(t, db.First(&original).Error)
+	assert.Equal(t, "buyer@example.com", original.RecipientEmail)
+	assert.Equal(t, []models.EmailRecipient{recipient}, original.Recipients)
+
+	// The email as it was stored, say before a template fix
+	require.NoError(t, db.Model(&original).Update("html_content", "<p>stale</p>").Error)
+
+	// A plain resend sends the stored content
+	copied, err := service.ResendEmail(original.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>stale</p>", copied.HTMLContent)
+	require.NotNil(t, copied.ResentFromID)
+	assert.Equal(t, original.ID, *copied.ResentFromID)
+	assert.Equal(t, "buyer@example.com", copied.RecipientEmail)
+	queued, _ := queue.Dequeue()
+	require.NotNil(t, queued)
+	assert.Equal(t, copied.ID, queued.ID)
+
+	// Re-rendering uses the current template and the stored data
+	rendered, err := service.ResendEmail(original.ID, true)
+	require.NoError(t, err)
+	assert.NotContains(t, rendered.HTMLContent, "stale")
+	assert.Contains(t, rendered.HTMLContent, "Buyer")
+	assert.Equal(t, original.Subject, rendered.Subject)
+
+	// Emails stored without their data can only be resent as they are
+	bare := models.Email{Type: models.EmailTypeWelcome, Template: "welcome", Subject: "Hi", Recipients: []models.EmailRecipient{recipient}}
+	require.NoError(t, db.Create(&bare).Error)
+	_, err = service.ResendEmail(bare.ID, true)
+	assert.True(t, errors.Is(err, ErrNoTemplateData))
+
+	// Suppressed recipients are not sent the copy
+	_, err = service.SuppressAddress("buyer@example.com", "")
+	require.NoError(t, err)
+	_, err = service.ResendEmail(original.ID, false)
+	assert.True(t, errors.Is(err, ErrRecipientSuppressed))
+}
@@ -0,0 +1,70 @@
+package email
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSchedulingTest(t *testing.T) (*EmailServiceImplementation, *MockEmailQueue, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Email{}, &models.EmailSuppression{}))
+
+	templates := NewHTMLTemplateEngine("../templates/emails")
+	require.NoError(t, templates.ReloadTemplates())
+
+	queue := NewMockEmailQueue()
+	service := NewEmailService(
+		NewMockEmailProvider("test@example.com", "Test Sender"),
+		templates,
+		queue,
+		NewEmailAnalytics(db),
+		&cfg.EmailConfig{SenderEmail: "test@example.com", SenderName: "Test Sender"},
+		db,
+	)
+	return service, queue, db
+}
+
+func TestScheduleTransactionalEmail(t *testing.T) {
+	service, queue, db := setupSchedulingTest(t)
+	recipient := models.EmailRecipient{Email: "buyer@example.com", Name: "Buyer"}
+	data := map[string]interface{}{"UserName": "Buyer", "OrderNumber": "ORD-1"}
+
+	due, err := service.ScheduleTransactionalEmail(models.EmailTypeReviewRequest, data, recipient, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	later, err := service.ScheduleTransactionalEmail(models.EmailTypeReviewRequest, data, recipient, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, models.EmailStatusScheduled, later.Status)
+
+	// Scheduled emails are held back from the send queue until due
+	size, _ := queue.GetQueueSize()
+	assert.Equal(t, int64(0), size)
+
+	count, err := service.DispatchDueEmails()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	next, _ := queue.Dequeue()
+	require.NotNil(t, next)
+	assert.Equal(t, due.ID, next.ID)
+	assert.Equal(t, "buyer@example.com", next.Recipients[0].Email)
+
+	var stored models.Email
+	require.NoError(t, db.First(&stored, due.ID).Error)
+	assert.NotEqual(t, models.EmailStatusScheduled, stored.Status)
+
+	// Pending emails can be canceled, dispatched ones cannot
+	require.NoError(t, service.CancelScheduledEmail(later.ID))
+	var canceled models.Email
+	require.NoError(t, db.First(&canceled, later.ID).Error)
+	assert.Equal(t, models.EmailStatusCanceled, canceled.Status)
+	assert.Error(t, service.CancelScheduledEmail(later.ID))
+	assert.Error(t, service.CancelScheduledEmail(due.ID))
+}
@@ -1,12 +1,17 @@
 package email
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/cfg"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +25,15 @@ type EmailService interface {
 	RetryFailedEmails() error
 	GetEmailMetrics(timeRange TimeRange) (*EmailMetrics, error)
 	GetQueueSize() (int64, error)
+	ScheduleTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient, sendAt time.Time) (*models.Email, error)
+	CancelScheduledEmail(emailID uint) error
+	GetDeadLetters() ([]*DeadLetter, error)
+	GetDeadLetterSize() (int64, error)
+	RequeueDeadLetters(emailIDs []string) (int, error)
+	DeleteDeadLetters(emailIDs []string) (int, error)
+	HandleDeliveryEvent(event DeliveryEvent, source string) error
+	SuppressAddress(address, detail string) (*models.EmailSuppression, error)
+	ResendEmail(emailID uint, rerender bool) (*models.Email, error)
 }
 
 // TimeRange represents a time range for metrics
@@ -30,14 +44,20 @@ type TimeRange struct {
 
 // EmailMetrics represents email performance metrics
 type EmailMetrics struct {
-	SentCount      int     `json:"sent_count"`
-	DeliveredCount int     `json:"delivered_count"`
-	OpenedCount    int     `json:"opened_count"`
-	ClickedCount   int     `json:"clicked_count"`
-	BouncedCount   int     `json:"bounced_count"`
-	DeliveryRate   float64 `json:"delivery_rate"`
-	OpenRate       float64 `json:"open_rate"`
-	ClickRate      float64 `json:"click_rate"`
+	SentCount      int `json:"sent_count"`
+	DeliveredCount int `json:"delivered_count"`
+	OpenedCount    int `json:"opened_count"`
+	ClickedCount   int `json:"clicked_count"`
+	BouncedCount   int `json:"bounced_count"`
+	// Suppression: complaints and blocked sends in the range, and the
+	// current number of suppressed addresses
+	ComplainedCount     int     `json:"complained_count"`
+	SuppressedCount     int     `json:"suppressed_count"`
+	SuppressionListSize int64   `json:"suppression_list_size"`
+	DeadLetterSize      int64   `json:"dead_letter_size"`
+	DeliveryRate        float64 `json:"delivery_rate"`
+	OpenRate            float64 `json:"open_rate"`
+	ClickRate           float64 `json:"click_rate"`
 }
 
 // EmailServiceImplementation implements EmailService
@@ -48,6 +68,7 @@ type EmailServiceImplementation struct {
 	analytics      EmailAnalytics
 	config         *cfg.EmailConfig
 	db             *gorm.DB
+	suppressions   *SuppressionList
 }
 
 // NewEmailService creates a new email service instance
@@ -66,13 +87,15 @@ func NewEmailService(
 		analytics:      analytics,
 		config:         config,
 		db:             db,
+		suppressions:   NewSuppressionList(db),
 	}
 }
 
 // SendEmail sends a single email
 func (s *EmailServiceImplementation) SendEmail(template string, data map[string]interface{}, recipient models.EmailRecipient) error {
-	// Render email content
-	htmlContent, textContent, err := s.templateEngine.RenderTemplate(template, data)
+	// Render email content in the recipient's locale
+	localized := s.localizedTemplate(template, data)
+	htmlContent, textContent, err := s.templateEngine.RenderTemplate(localized, data)
 	if err != nil {
 		return fmt.Errorf("failed to render email template: %w", err)
 	}
@@ -84,11 +107,30 @@ func (s *EmailServiceImplementation) SendEmail(template string, data map[string]
 		Recipients:  []models.EmailRecipient{recipient},
 		SenderEmail: s.config.SenderEmail,
 		SenderName:  s.config.SenderName,
-		Subject:     s.getSubjectFromData(data),
+		Subject:     s.subjectFor(localized, data),
 		HTMLContent: htmlContent,
 		TextContent: textContent,
 		Status:      models.EmailStatusPending,
 		RetryCount:  0,
+		RequestID:   requestIDFrom(data),
+		OrderID:     orderIDFrom(data),
+
+		TemplateData: templateData(data),
+	}
+
+	return s.QueueEmail(email)
+}
+
+// QueueEmail saves and queues an email that has already been rendered, such
+// as a campaign email personalised for its recipient. It returns
+// ErrRecipientSuppressed, after recording the email, when the recipient is
+// suppressed.
+func (s *EmailServiceImplementation) QueueEmail(email *models.Email) error {
+	if email.SenderEmail == "" {
+		email.SenderEmail, email.SenderName = s.config.SenderEmail, s.config.SenderName
+	}
+	if err := s.holdIfSuppressed(email); err != nil {
+		return err
 	}
 
 	// Save email to database
@@ -127,11 +169,23 @@ func (s *EmailServiceImplementation) SendBulkEmail(template string, data map[str
 			Recipients:  []models.EmailRecipient{recipient},
 			SenderEmail: s.config.SenderEmail,
 			SenderName:  s.config.SenderName,
-			Subject:     s.getSubjectFromData(data),
+			Subject:     s.subjectFor(template, data),
 			HTMLContent: htmlContent,
 			TextContent: textContent,
 			Status:      models.EmailStatusPending,
 			RetryCount:  0,
+			Priority:    models.EmailPriorityBulk,
+			RequestID:   requestIDFrom(data),
+
+			TemplateData: templateData(data),
+		}
+
+		// Suppressed recipients are recorded but skipped
+		if err := s.holdIfSuppressed(email); err != nil {
+			if errors.Is(err, ErrRecipientSuppressed) {
+				continue
+			}
+			return err
 		}
 
 		// Save email to database
@@ -159,30 +213,13 @@ func (s *EmailServiceImplementation) SendBulkEmail(template string, data map[str
 
 // SendTransactionalEmail sends a transactional email
 func (s *EmailServiceImplementation) SendTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient) error {
-	// Get template name based on email type
-	templateName := s.getTemplateNameForType(emailType)
-	if templateName == "" {
-		return fmt.Errorf("no template found for email type: %s", emailType)
-	}
-
-	// Render email content
-	htmlContent, textContent, err := s.templateEngine.RenderTemplate(templateName, data)
+	email, err := s.buildTransactionalEmail(emailType, data, recipient)
 	if err != nil {
-		return fmt.Errorf("failed to render email template: %w", err)
+		return err
 	}
 
-	// Create email record
-	email := &models.Email{
-		Type:        emailType,
-		Template:    templateName,
-		Recipients:  []models.EmailRecipient{recipient},
-		SenderEmail: s.config.SenderEmail,
-		SenderName:  s.config.SenderName,
-		Subject:     s.getSubjectFromData(data),
-		HTMLContent: htmlContent,
-		TextContent: textContent,
-		Status:      models.EmailStatusPending,
-		RetryCount:  0,
+	if err := s.holdIfSuppressed(email); err != nil {
+		return err
 	}
 
 	// Save email to database
@@ -203,6 +240,91 @@ func (s *EmailServiceImplementation) SendTransactionalEmail(emailType models.Ema
 	return nil
 }
 
+// ScheduleTransactionalEmail renders a transactional email now and holds it in
+// the delayed queue until sendAt. The returned email ID can be used to cancel it.
+func (s *EmailServiceImplementation) ScheduleTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient, sendAt time.Time) (*models.Email, error) {
+	email, err := s.buildTransactionalEmail(emailType, data, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.holdIfSuppressed(email); err != nil {
+		return nil, err
+	}
+	email.Status = models.EmailStatusScheduled
+	email.SendAt = &sendAt
+
+	if err := s.db.Create(email).Error; err != nil {
+		return nil, fmt.Errorf("failed to save email to database: %w", err)
+	}
+
+	if err := s.queue.Schedule(email, sendAt); err != nil {
+		return nil, fmt.Errorf("failed to schedule email: %w", err)
+	}
+
+	return email, nil
+}
+
+// CancelScheduledEmail cancels a scheduled email that has not been dispatched yet
+func (s *EmailServiceImplementation) CancelScheduledEmail(emailID uint) error {
+	var email models.Email
+	if err := s.db.First(&email, emailID).Error; err != nil {
+		return fmt.Errorf("failed to get email: %w", err)
+	}
+	if email.Status != models.EmailStatusScheduled {
+		return fmt.Errorf("email is not scheduled")
+	}
+
+	removed, err := s.queue.CancelScheduled(fmt.Sprintf("%d", email.ID))
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("email has already been dispatched")
+	}
+
+	if err := s.db.Model(&email).Update("status", models.EmailStatusCanceled).Error; err != nil {
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+	return nil
+}
+
+// DispatchDueEmails moves scheduled emails that are due onto the send queue
+func (s *EmailServiceImplementation) DispatchDueEmails() (int, error) {
+	promoted, err := s.queue.PromoteDue(time.Now())
+	for _, email := range promoted {
+		if err := s.db.Model(&models.Email{}).Where("id = ?", email.ID).Update("status", models.EmailStatusPending).Error; err != nil {
+			fmt.Printf("Failed to update scheduled email %d: %v\n", email.ID, err)
+		}
+		if err := s.analytics.TrackEmailSent(email); err != nil {
+			fmt.Printf("Failed to track email sent: %v\n", err)
+		}
+	}
+	return len(promoted), err
+}
+
+// StartScheduler periodically dispatches due scheduled emails until ctx is canceled
+func (s *EmailServiceImplementation) StartScheduler(ctx context.Context, interval time.Duration) {
+	for {
+		if count, err := s.DispatchDueEmails(); err != nil {
+			fmt.Printf("Failed to dispatch scheduled emails: %v\n", err)
+		} else if count > 0 {
+			fmt.Printf("Dispatched %d scheduled emails\n", count)
+		}
+		if !sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// StartRetryWorker periodically requeues failed emails until ctx is canceled
+func (s *EmailServiceImplementation) StartRetryWorker(ctx context.Context, interval time.Duration) {
+	for sleep(ctx, interval) {
+		if err := s.RetryFailedEmails(); err != nil {
+			slog.Error("failed to retry emails", "component", "email", "error", err)
+		}
+	}
+}
+
 // GetEmailStatus retrieves the status of an email
 func (s *EmailServiceImplementation) GetEmailStatus(emailID string) (models.EmailStatus, error) {
 	var email models.Email
@@ -225,8 +347,8 @@ func (s *EmailServiceImplementation) RetryFailedEmail(emailID string) error {
 		return fmt.Errorf("email is not in failed status")
 	}
 
-	// Check retry limit (max 3 retries)
-	if email.RetryCount >= 3 {
+	// Check retry limit
+	if email.RetryCount >= MaxEmailRetries {
 		return fmt.Errorf("email has exceeded maximum retry attempts")
 	}
 
@@ -250,7 +372,7 @@ func (s *EmailServiceImplementation) RetryFailedEmail(emailID string) error {
 // RetryFailedEmails retries all failed emails with exponential backoff
 func (s *EmailServiceImplementation) RetryFailedEmails() error {
 	var failedEmails []models.Email
-	if err := s.db.Where("status = ? AND retry_count < ?", models.EmailStatusFailed, 3).Find(&failedEmails).Error; err != nil {
+	if err := s.db.Where("status = ? AND retry_count < ?", models.EmailStatusFailed, MaxEmailRetries).Find(&failedEmails).Error; err != nil {
 		return fmt.Errorf("failed to get failed emails: %w", err)
 	}
 
@@ -313,15 +435,34 @@ func (s *EmailServiceImplementation) GetEmailMetrics(timeRange TimeRange) (*Emai
 		clickRate = float64(clickedCount) / float64(sentCount) * 100
 	}
 
+	var complainedCount, suppressedCount int64
+	s.db.Model(&models.Email{}).Where("status = ? AND created_at BETWEEN ? AND ?", models.EmailStatusComplained, timeRange.Start, timeRange.End).Count(&complainedCount)
+	s.db.Model(&models.Email{}).Where("status = ? AND created_at BETWEEN ? AND ?", models.EmailStatusSuppressed, timeRange.Start, timeRange.End).Count(&suppressedCount)
+
+	suppressionListSize, err := s.suppressions.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	// The dead-letter queue size is a current value, not bound to the time range
+	deadLetterSize, err := s.queue.DeadLetterSize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter size: %w", err)
+	}
+
 	return &EmailMetrics{
-		SentCount:      int(sentCount),
-		DeliveredCount: int(deliveredCount),
-		OpenedCount:    int(openedCount),
-		ClickedCount:   int(clickedCount),
-		BouncedCount:   int(bouncedCount),
-		DeliveryRate:   deliveryRate,
-		OpenRate:       openRate,
-		ClickRate:      clickRate,
+		SentCount:           int(sentCount),
+		DeliveredCount:      int(deliveredCount),
+		OpenedCount:         int(openedCount),
+		ClickedCount:        int(clickedCount),
+		BouncedCount:        int(bouncedCount),
+		ComplainedCount:     int(complainedCount),
+		SuppressedCount:     int(suppressedCount),
+		SuppressionListSize: suppressionListSize,
+		DeadLetterSize:      deadLetterSize,
+		DeliveryRate:        deliveryRate,
+		OpenRate:            openRate,
+		ClickRate:           clickRate,
 	}, nil
 }
 
@@ -330,12 +471,228 @@ func (s *EmailServiceImplementation) GetQueueSize() (int64, error) {
 	return s.queue.GetQueueSize()
 }
 
+// GetDeadLetterSize retrieves the number of dead-lettered emails
+func (s *EmailServiceImplementation) GetDeadLetterSize() (int64, error) {
+	return s.queue.DeadLetterSize()
+}
+
+// GetDeadLetters lists emails that exhausted their retries, oldest first
+func (s *EmailServiceImplementation) GetDeadLetters() ([]*DeadLetter, error) {
+	return s.queue.GetDeadLetters()
+}
+
+// RequeueDeadLetters puts dead-lettered emails back on the queue with a fresh
+// retry budget. An empty emailIDs requeues the whole dead-letter queue.
+// It returns the number of emails requeued.
+func (s *EmailServiceImplementation) RequeueDeadLetters(emailIDs []string) (int, error) {
+	letters, err := s.takeDeadLetters(emailIDs)
+	requeued := 0
+	for _, letter := range letters {
+		email := letter.Email
+		email.Status = models.EmailStatusPending
+		email.RetryCount = 0
+		email.DeadLetteredAt = nil
+
+		if err := s.db.Model(&models.Email{}).Where("id = ?", email.ID).Updates(map[string]interface{}{
+			"status":           models.EmailStatusPending,
+			"retry_count":      0,
+			"dead_lettered_at": nil,
+		}).Error; err != nil {
+			return requeued, fmt.Errorf("failed to update email %d: %w", email.ID, err)
+		}
+		if err := s.queue.Enqueue(email); err != nil {
+			return requeued, fmt.Errorf("failed to re-queue email %d: %w", email.ID, err)
+		}
+		requeued++
+	}
+	return requeued, err
+}
+
+// DeleteDeadLetters discards dead-lettered emails and marks them failed.
+// An empty emailIDs empties the whole dead-letter queue. It returns the number
+// of emails discarded.
+func (s *EmailServiceImplementation) DeleteDeadLetters(emailIDs []string) (int, error) {
+	letters, err := s.takeDeadLetters(emailIDs)
+	deleted := 0
+	for _, letter := range letters {
+		if err := s.db.Model(&models.Email{}).Where("id = ?", letter.Email.ID).
+			Update("status", models.EmailStatusFailed).Error; err != nil {
+			return deleted, fmt.Errorf("failed to update email %d: %w", letter.Email.ID, err)
+		}
+		deleted++
+	}
+	return deleted, err
+}
+
+// takeDeadLetters removes the given emails, or all of them, from the dead-letter queue
+func (s *EmailServiceImplementation) takeDeadLetters(emailIDs []string) ([]*DeadLetter, error) {
+	if len(emailIDs) == 0 {
+		all, err := s.queue.GetDeadLetters()
+		if err != nil {
+			return nil, err
+		}
+		for _, letter := range all {
+			emailIDs = append(emailIDs, fmt.Sprintf("%d", letter.Email.ID))
+		}
+	}
+
+	var letters []*DeadLetter
+	for _, id := range emailIDs {
+		letter, err := s.queue.TakeDeadLetter(id)
+		if err != nil {
+			return letters, err
+		}
+		if letter != nil {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, nil
+}
+
+// holdIfSuppressed records email as suppressed instead of sending it when a
+// recipient is on the suppression list, returning ErrRecipientSuppressed
+func (s *EmailServiceImplementation) holdIfSuppressed(email *models.Email) error {
+	for _, recipient := range email.Recipients {
+		suppressed, err := s.suppressions.IsSuppressed(recipient.Email)
+		if err != nil {
+			return err
+		}
+		if !suppressed {
+			continue
+		}
+
+		email.Status = models.EmailStatusSuppressed
+		if err := s.db.Create(email).Error; err != nil {
+			return fmt.Errorf("failed to save email to database: %w", err)
+		}
+		return fmt.Errorf("%w: %s", ErrRecipientSuppressed, recipient.Email)
+	}
+	return nil
+}
+
+// HandleDeliveryEvent records a bounce or complaint reported by a provider.
+// Hard bounces and complaints suppress the address; soft bounces are only
+// recorded on the email.
+func (s *EmailServiceImplementation) HandleDeliveryEvent(event DeliveryEvent, source string) error {
+	if normalizeAddress(event.Email) == "" {
+		return fmt.Errorf("event email address is required")
+	}
+
+	var emailID *uint
+	if event.EmailID != 0 {
+		emailID = &event.EmailID
+	}
+
+	switch event.Type {
+	case DeliveryEventBounce:
+		if emailID != nil {
+			if err := s.analytics.TrackEmailBounced(fmt.Sprintf("%d", event.EmailID), event.Reason); err != nil {
+				fmt.Printf("Failed to track email bounce: %v\n", err)
+			}
+		}
+		if strings.EqualFold(event.BounceType, "soft") {
+			return nil
+		}
+		_, err := s.suppressions.Suppress(event.Email, models.SuppressionReasonBounce, source, event.Reason, emailID)
+		return err
+	case DeliveryEventComplaint:
+		if emailID != nil {
+			if err := s.db.Model(&models.Email{}).Where("id = ?", event.EmailID).
+				Update("status", models.EmailStatusComplained).Error; err != nil {
+				fmt.Printf("Failed to track email complaint: %v\n", err)
+			}
+		}
+		_, err := s.suppressions.Suppress(event.Email, models.SuppressionReasonComplaint, source, event.Reason, emailID)
+		return err
+	default:
+		return fmt.Errorf("unknown delivery event type: %s", event.Type)
+	}
+}
+
+// SuppressAddress manually adds an address to the suppression list
+func (s *EmailServiceImplementation) SuppressAddress(address, detail string) (*models.EmailSuppression, error) {
+	return s.suppressions.Suppress(address, models.SuppressionReasonManual, models.SuppressionSourceAdmin, detail, nil)
+}
+
+// PollSuppressions records the provider's bounce and complaint lists, as a
+// fallback for providers without webhooks
+func (s *EmailServiceImplementation) PollSuppressions() (int, error) {
+	return s.suppressions.Poll(s.provider)
+}
+
+// StartSuppressionPoller periodically polls the provider for bounces and
+// complaints until ctx is canceled
+func (s *EmailServiceImplementation) StartSuppressionPoller(ctx context.Context, interval time.Duration) {
+	for {
+		if count, err := s.PollSuppressions(); err != nil {
+			fmt.Printf("Failed to poll email suppressions: %v\n", err)
+		} else if count > 0 {
+			fmt.Printf("Recorded %d bounced or complained addresses\n", count)
+		}
+		if !sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// buildTransactionalEmail renders the template for emailType into a pending email
+func (s *EmailServiceImplementation) buildTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient) (*models.Email, error) {
+	// Get template name based on email type
+	templateName := s.getTemplateNameForType(emailType)
+	if templateName == "" {
+		return nil, fmt.Errorf("no template found for email type: %s", emailType)
+	}
+
+	// Render email content in the recipient's locale
+	localized := s.localizedTemplate(templateName, data)
+	htmlContent, textContent, err := s.templateEngine.RenderTemplate(localized, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render email template: %w", err)
+	}
+
+	return &models.Email{
+		Type:        emailType,
+		Template:    templateName,
+		Recipients:  []models.EmailRecipient{recipient},
+		SenderEmail: s.config.SenderEmail,
+		SenderName:  s.config.SenderName,
+		Subject:     s.subjectFor(localized, data),
+		HTMLContent: htmlContent,
+		TextContent: textContent,
+		Status:      models.EmailStatusPending,
+		RetryCount:  0,
+		RequestID:   requestIDFrom(data),
+		OrderID:     orderIDFrom(data),
+
+		TemplateData: templateData(data),
+	}, nil
+}
+
+// subjectRenderer is implemented by template engines that store subjects
+// alongside template versions
+type subjectRenderer interface {
+	RenderSubject(templateName string, data map[string]interface{}) (string, bool)
+}
+
+// subjectFor prefers an explicit subject in data, then the subject stored
+// with the active template version, then the default subject
+func (s *EmailServiceImplementation) subjectFor(templateName string, data map[string]interface{}) string {
+	if _, ok := data["subject"].(string); !ok {
+		if renderer, ok := s.templateEngine.(subjectRenderer); ok {
+			if subject, ok := renderer.RenderSubject(templateName, data); ok && subject != "" {
+				return subject
+			}
+		}
+	}
+	return s.getSubjectFromData(data)
+}
+
 // getSubjectFromData extracts subject from template data
 func (s *EmailServiceImplementation) getSubjectFromData(data map[string]interface{}) string {
 	if subject, ok := data["subject"].(string); ok {
 		return subject
 	}
-	return "Algeria Market - Important Information"
+	return settings.Current().CompanyName + " - Important Information"
 }
 
 // getTemplateNameForType returns the template name for a given email type
@@ -375,6 +732,32 @@ func (s *EmailServiceImplementation) getTemplateNameForType(emailType models.Ema
 		return "dispute_status_updated"
 	case models.EmailTypeAbuseStatusUpdated:
 		return "abuse_status_updated"
+	case models.EmailTypeQuoteResponded:
+		return "quote_responded"
+	case models.EmailTypeReviewRequest:
+		return "review_request"
+	case models.EmailTypeGiftCard:
+		return "gift_card"
+	case models.EmailTypeOrderMessage:
+		return "order_message"
+	case models.EmailTypeCompanyInvitation:
+		return "company_invitation"
+	case models.EmailTypeOrderApprovalRequest:
+		return "order_approval_request"
+	case models.EmailTypeOrderApprovalDecision:
+		return "order_approval_decision"
+	case models.EmailTypeReviewReply:
+		return "review_reply"
+	case models.EmailTypeContentRemoved:
+		return "content_removed"
+	case models.EmailTypeAccountEnforcement:
+		return "account_enforcement"
+	case models.EmailTypeSubscriptionReminder:
+		return "subscription_reminder"
+	case models.EmailTypeSubscriptionOrder:
+		return "subscription_order"
+	case models.EmailTypeEmailVerification:
+		return "email_verification"
 	default:
 		return ""
 	}
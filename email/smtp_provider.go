@@ -0,0 +1,236 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// SMTPEmailProvider implements EmailProvider over a plain SMTP server
+type SMTPEmailProvider struct {
+	config  *cfg.SMTPConfig
+	timeout time.Duration
+}
+
+// NewSMTPEmailProvider creates a new SMTP email provider
+func NewSMTPEmailProvider(config *cfg.SMTPConfig) (*SMTPEmailProvider, error) {
+	if config == nil || strings.TrimSpace(config.Host) == "" {
+		return nil, fmt.Errorf("SMTP host is not configured")
+	}
+	if config.Port <= 0 {
+		return nil, fmt.Errorf("invalid SMTP port: %d", config.Port)
+	}
+	switch config.TLSMode {
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("invalid SMTP TLS mode %q, expected starttls, tls or none", config.TLSMode)
+	}
+	if _, err := mail.ParseAddress(config.SenderEmail); err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender email: %w", err)
+	}
+
+	return &SMTPEmailProvider{config: config, timeout: 30 * time.Second}, nil
+}
+
+// SendEmail sends an email through the SMTP server
+func (p *SMTPEmailProvider) SendEmail(email *models.Email) error {
+	if len(email.Recipients) == 0 {
+		return fmt.Errorf("❌ SMTP PROVIDER: No recipients specified")
+	}
+
+	message, err := p.buildMessage(email)
+	if err != nil {
+		return fmt.Errorf("❌ SMTP PROVIDER: Failed to build message: %w", err)
+	}
+
+	client, err := p.dial()
+	if err != nil {
+		return fmt.Errorf("❌ SMTP PROVIDER: %w", err)
+	}
+	defer client.Close()
+
+	if p.config.Username != "" {
+		auth := smtp.PlainAuth("", p.config.Username, p.config.Password, p.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("❌ SMTP PROVIDER: Authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(p.config.SenderEmail); err != nil {
+		return fmt.Errorf("❌ SMTP PROVIDER: MAIL FROM rejected: %w", err)
+	}
+	for _, recipient := range email.Recipients {
+		if err := client.Rcpt(recipient.Email); err != nil {
+			return fmt.Errorf("❌ SMTP PROVIDER: Recipient %s rejected: %w", recipient.Email, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("❌ SMTP PROVIDER: DATA rejected: %w", err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		writer.Close()
+		return fmt.Errorf("❌ SMTP PROVIDER: Failed to write message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("❌ SMTP PROVIDER: Message rejected: %w", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		log.Printf("⚠️ SMTP PROVIDER: QUIT failed after sending: %v", err)
+	}
+
+	log.Printf("✅ SMTP PROVIDER: Email sent successfully to %d recipients", len(email.Recipients))
+	return nil
+}
+
+// SendBulkEmail sends multiple emails one connection at a time
+func (p *SMTPEmailProvider) SendBulkEmail(emails []*models.Email) error {
+	for i, email := range emails {
+		if err := p.SendEmail(email); err != nil {
+			return fmt.Errorf("❌ SMTP PROVIDER: Failed to send bulk email %d/%d: %w", i+1, len(emails), err)
+		}
+	}
+	return nil
+}
+
+// GetDeliveryStatus reports accepted emails as sent; SMTP has no delivery tracking
+func (p *SMTPEmailProvider) GetDeliveryStatus(emailID string) (DeliveryStatus, error) {
+	return DeliveryStatusSent, nil
+}
+
+// GetBounceList returns no bounces; SMTP bounces arrive asynchronously by email
+func (p *SMTPEmailProvider) GetBounceList() ([]string, error) {
+	return []string{}, nil
+}
+
+// GetComplaintList returns no complaints; SMTP has no complaint feed
+func (p *SMTPEmailProvider) GetComplaintList() ([]string, error) {
+	return []string{}, nil
+}
+
+// dial connects to the server using the configured TLS mode
+func (p *SMTPEmailProvider) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(p.config.Host, strconv.Itoa(p.config.Port))
+	tlsConfig := &tls.Config{ServerName: p.config.Host}
+	dialer := &net.Dialer{Timeout: p.timeout}
+
+	var conn net.Conn
+	var err error
+	if p.config.TLSMode == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(p.timeout))
+
+	client, err := smtp.NewClient(conn, p.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+
+	if p.config.TLSMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// buildMessage renders the email as a MIME message with text and HTML parts
+func (p *SMTPEmailProvider) buildMessage(email *models.Email) ([]byte, error) {
+	var buf bytes.Buffer
+
+	senderName := email.SenderName
+	if senderName == "" {
+		senderName = p.config.SenderName
+	}
+	from := mail.Address{Name: senderName, Address: p.config.SenderEmail}
+
+	to := make([]string, 0, len(email.Recipients))
+	for _, recipient := range email.Recipients {
+		to = append(to, (&mail.Address{Name: recipient.Name, Address: recipient.Email}).String())
+	}
+
+	writer := multipart.NewWriter(&buf)
+	headers := []struct{ key, value string }{
+		{"From", from.String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", email.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", p.messageID()},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", writer.Boundary())},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.TextContent},
+		{"text/html; charset=utf-8", email.HTMLContent},
+	}
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(partWriter)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// messageID generates a unique Message-ID in the sender's domain
+func (p *SMTPEmailProvider) messageID() string {
+	random := make([]byte, 12)
+	rand.Read(random)
+	domain := p.config.Host
+	if at := strings.LastIndex(p.config.SenderEmail, "@"); at >= 0 {
+		domain = p.config.SenderEmail[at+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
@@ -0,0 +1,124 @@
+package email
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// ErrRecipientSuppressed is returned when an email is addressed to a suppressed address
+var ErrRecipientSuppressed = errors.New("recipient is on the suppression list")
+
+// DeliveryEventType is the kind of delivery feedback reported by a provider
+type DeliveryEventType string
+
+const (
+	DeliveryEventBounce    DeliveryEventType = "bounce"
+	DeliveryEventComplaint DeliveryEventType = "complaint"
+)
+
+// DeliveryEvent is a bounce or complaint reported by a provider webhook
+type DeliveryEvent struct {
+	Type       DeliveryEventType `json:"type"`
+	Email      string            `json:"email"`
+	EmailID    uint              `json:"email_id"`    // optional, the email that bounced
+	BounceType string            `json:"bounce_type"` // "hard" or "soft"; empty is treated as hard
+	Reason     string            `json:"reason"`
+}
+
+// SuppressionList stores addresses that must not receive email
+type SuppressionList struct {
+	db *gorm.DB
+}
+
+// NewSuppressionList creates a suppression list backed by the database
+func NewSuppressionList(db *gorm.DB) *SuppressionList {
+	return &SuppressionList{db: db}
+}
+
+// IsSuppressed reports whether address is on the suppression list
+func (l *SuppressionList) IsSuppressed(address string) (bool, error) {
+	var count int64
+	if err := l.db.Model(&models.EmailSuppression{}).
+		Where("email = ?", normalizeAddress(address)).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check suppression list: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Suppress adds address to the suppression list. Adding an address that is
+// already suppressed keeps the original entry.
+func (l *SuppressionList) Suppress(address string, reason models.SuppressionReason, source, detail string, emailID *uint) (*models.EmailSuppression, error) {
+	address = normalizeAddress(address)
+	if address == "" {
+		return nil, fmt.Errorf("email address is required")
+	}
+
+	suppression := models.EmailSuppression{
+		Email:   address,
+		Reason:  reason,
+		Source:  source,
+		Detail:  detail,
+		EmailID: emailID,
+	}
+	if err := l.db.Where("email = ?", address).FirstOrCreate(&suppression).Error; err != nil {
+		return nil, fmt.Errorf("failed to suppress address: %w", err)
+	}
+	return &suppression, nil
+}
+
+// Size returns the number of suppressed addresses
+func (l *SuppressionList) Size() (int64, error) {
+	var count int64
+	if err := l.db.Model(&models.EmailSuppression{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count suppressions: %w", err)
+	}
+	return count, nil
+}
+
+// Poll records the bounces and complaints a provider reports, for providers
+// that cannot deliver webhooks. It returns the number of addresses processed.
+func (l *SuppressionList) Poll(provider EmailProvider) (int, error) {
+	bounces, err := provider.GetBounceList()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get bounce list: %w", err)
+	}
+	complaints, err := provider.GetComplaintList()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get complaint list: %w", err)
+	}
+
+	for _, address := range bounces {
+		if _, err := l.Suppress(address, models.SuppressionReasonBounce, models.SuppressionSourcePoll, "reported by provider bounce list", nil); err != nil {
+			return 0, err
+		}
+	}
+	for _, address := range complaints {
+		if _, err := l.Suppress(address, models.SuppressionReasonComplaint, models.SuppressionSourcePoll, "reported by provider complaint list", nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(bounces) + len(complaints), nil
+}
+
+// VerifyWebhookSignature checks a hex HMAC-SHA256 signature of a webhook body
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.TrimPrefix(signature, "sha256=")))
+}
+
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
@@ -0,0 +1,86 @@
+package email
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// feedbackProvider reports fixed bounce and complaint lists
+type feedbackProvider struct {
+	MockEmailProvider
+	bounces    []string
+	complaints []string
+}
+
+func (p *feedbackProvider) GetBounceList() ([]string, error)    { return p.bounces, nil }
+func (p *feedbackProvider) GetComplaintList() ([]string, error) { return p.complaints, nil }
+
+func TestDeliveryEventsSuppressRecipients(t *testing.T) {
+	service, queue, db := setupSchedulingTest(t)
+	data := map[string]interface{}{"UserName": "Buyer"}
+
+	// A soft bounce is recorded but does not suppress the address
+	require.NoError(t, service.HandleDeliveryEvent(DeliveryEvent{Type: DeliveryEventBounce, Email: "soft@example.com", BounceType: "soft"}, models.SuppressionSourceWebhook))
+	require.NoError(t, service.SendTransactionalEmail(models.EmailTypeWelcome, data, models.EmailRecipient{Email: "soft@example.com"}))
+
+	// Hard bounces and complaints suppress future sends, whatever the address case
+	require.NoError(t, service.HandleDeliveryEvent(DeliveryEvent{Type: DeliveryEventBounce, Email: "Gone@Example.com", Reason: "550 mailbox not found"}, models.SuppressionSourceWebhook))
+	require.NoError(t, service.HandleDeliveryEvent(DeliveryEvent{Type: DeliveryEventComplaint, Email: "angry@example.com"}, models.SuppressionSourceWebhook))
+	assert.Error(t, service.HandleDeliveryEvent(DeliveryEvent{Type: "opened", Email: "x@example.com"}, models.SuppressionSourceWebhook))
+
+	err := service.SendTransactionalEmail(models.EmailTypeWelcome, data, models.EmailRecipient{Email: "gone@example.com"})
+	assert.True(t, errors.Is(err, ErrRecipientSuppressed))
+
+	require.NoError(t, service.SendBulkEmail("promotional", data, []models.EmailRecipient{
+		{Email: "angry@example.com"},
+		{Email: "fan@example.com"},
+	}))
+
+	size, _ := queue.GetQueueSize()
+	assert.Equal(t, int64(2), size, "only soft@ and fan@ are queued")
+
+	var suppressedSends int64
+	db.Model(&models.Email{}).Where("status = ?", models.EmailStatusSuppressed).Count(&suppressedSends)
+	assert.Equal(t, int64(2), suppressedSends)
+
+	var suppression models.EmailSuppression
+	require.NoError(t, db.Where("email = ?", "gone@example.com").First(&suppression).Error)
+	assert.Equal(t, models.SuppressionReasonBounce, suppression.Reason)
+	assert.Equal(t, "550 mailbox not found", suppression.Detail)
+}
+
+func TestSuppressionListPoll(t *testing.T) {
+	_, _, db := setupSchedulingTest(t)
+	list := NewSuppressionList(db)
+
+	count, err := list.Poll(&feedbackProvider{bounces: []string{"a@example.com"}, complaints: []string{"b@example.com", "a@example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// An address already suppressed keeps its first reason
+	size, _ := list.Size()
+	assert.Equal(t, int64(2), size)
+	var suppression models.EmailSuppression
+	require.NoError(t, db.Where("email = ?", "a@example.com").First(&suppression).Error)
+	assert.Equal(t, models.SuppressionReasonBounce, suppression.Reason)
+	assert.Equal(t, models.SuppressionSourcePoll, suppression.Source)
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"events":[]}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, VerifyWebhookSignature("secret", body, signature))
+	assert.True(t, VerifyWebhookSignature("secret", body, "sha256="+signature))
+	assert.False(t, VerifyWebhookSignature("other", body, signature))
+	assert.False(t, VerifyWebhookSignature("", body, signature), "webhooks are rejected when no secret is configured")
+}
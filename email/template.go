@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"gorm.io/gorm"
 )
 
 // TemplateEngine interface defines the contract for template engines
@@ -17,10 +24,18 @@ type TemplateEngine interface {
 	ReloadTemplates() error
 }
 
-// HTMLTemplateEngine implements TemplateEngine using Go's html/template
+// HTMLTemplateEngine implements TemplateEngine using Go's html/template.
+// Templates are read from basePath; when a database is attached, the active
+// EmailTemplate version of a name overrides the file of the same name.
+// Templates at the top of basePath are in English, and their translations
+// are in a directory per locale, such as ar/welcome.html.
 type HTMLTemplateEngine struct {
-	templates *template.Template
-	basePath  string
+	mu            sync.RWMutex
+	templates     *template.Template
+	subjects      map[string]*template.Template
+	basePath      string
+	db            *gorm.DB
+	defaultLocale string
 }
 
 // NewHTMLTemplateEngine creates a new HTML template engine
@@ -30,7 +45,21 @@ func NewHTMLTemplateEngine(basePath string) *HTMLTemplateEngine {
 	}
 }
 
-// ReloadTemplates reloads all templates from the base path
+// WithDatabase makes the engine load active template versions from the database
+func (e *HTMLTemplateEngine) WithDatabase(db *gorm.DB) *HTMLTemplateEngine {
+	e.db = db
+	return e
+}
+
+// WithDefaultLocale sets the locale of the templates used for recipients
+// without a locale, and when a recipient's locale has no translation of a
+// template
+func (e *HTMLTemplateEngine) WithDefaultLocale(locale string) *HTMLTemplateEngine {
+	e.defaultLocale = locale
+	return e
+}
+
+// ReloadTemplates reloads all templates from the base path and the database
 func (e *HTMLTemplateEngine) ReloadTemplates() error {
 	// Create a new template set
 	tmpl := template.New("email_templates")
@@ -73,27 +102,180 @@ func (e *HTMLTemplateEngine) ReloadTemplates() error {
 		return fmt.Errorf("failed to reload templates: %w", err)
 	}
 
+	subjects, err := e.loadDatabaseTemplates(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to reload templates: %w", err)
+	}
+
+	e.mu.Lock()
 	e.templates = tmpl
+	e.subjects = subjects
+	e.mu.Unlock()
 	return nil
 }
 
-// RenderTemplate renders an email template with the given data
-func (e *HTMLTemplateEngine) RenderTemplate(templateName string, data map[string]interface{}) (string, string, error) {
-	if e.templates == nil {
-		if err := e.ReloadTemplates(); err != nil {
-			return "", "", fmt.Errorf("failed to load templates: %w", err)
+// loadDatabaseTemplates overrides file templates with active database versions.
+// A version that fails to parse is skipped so the file template stays in use.
+func (e *HTMLTemplateEngine) loadDatabaseTemplates(tmpl *template.Template) (map[string]*template.Template, error) {
+	subjects := map[string]*template.Template{}
+	if e.db == nil {
+		return subjects, nil
+	}
+
+	var active []models.EmailTemplate
+	if err := e.db.Where("is_active = ?", true).Find(&active).Error; err != nil {
+		return nil, fmt.Errorf("failed to load database templates: %w", err)
+	}
+
+	for _, version := range active {
+		if _, err := template.New(version.Name).Parse(version.HTMLContent); err != nil {
+			log.Printf("⚠️ EMAIL: Skipping template %s v%d: %v", version.Name, version.Version, err)
+			continue
+		}
+		if _, err := tmpl.New(version.Name).Parse(version.HTMLContent); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", version.Name, err)
+		}
+		if version.Subject != "" {
+			if subject, err := template.New(version.Name).Parse(version.Subject); err == nil {
+				subjects[version.Name] = subject
+			}
 		}
 	}
+	return subjects, nil
+}
+
+// RenderTemplate renders an email template with the given data. The
+// business settings fill the keys data does not set, such as CompanyName, and
+// Locale and TextDirection ("ltr" or "rtl") are set from the locale of the
+// template.
+func (e *HTMLTemplateEngine) RenderTemplate(templateName string, data map[string]interface{}) (string, string, error) {
+	templates, err := e.loaded()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load templates: %w", err)
+	}
+
+	data = settings.WithTemplateData(data)
+	locale := templateLocale(templateName)
+	if _, ok := data["Locale"]; !ok {
+		data["Locale"] = locale
+	}
+	if _, ok := data["TextDirection"]; !ok {
+		data["TextDirection"] = i18n.Direction(locale)
+	}
 
 	// Execute HTML template
 	var htmlBuffer bytes.Buffer
-	if err := e.templates.ExecuteTemplate(&htmlBuffer, templateName, data); err != nil {
+	if err := templates.ExecuteTemplate(&htmlBuffer, templateName, data); err != nil {
 		return "", "", fmt.Errorf("failed to render HTML template %s: %w", templateName, err)
 	}
 
+	return htmlBuffer.String(), htmlToText(htmlBuffer.String()), nil
+}
+
+// RenderSubject renders the subject of the active database version of a
+// template. It returns false when the template has no stored subject.
+func (e *HTMLTemplateEngine) RenderSubject(templateName string, data map[string]interface{}) (string, bool) {
+	e.mu.RLock()
+	subject, ok := e.subjects[templateName]
+	e.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	if err := subject.Execute(&buf, settings.WithTemplateData(data)); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// RenderPreview renders template source that has not been activated yet
+func (e *HTMLTemplateEngine) RenderPreview(subject, htmlContent string, data map[string]interface{}) (string, string, string, error) {
+	tmpl, err := template.New("preview").Parse(htmlContent)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	data = settings.WithTemplateData(data)
+	var htmlBuffer bytes.Buffer
+	if err := tmpl.Execute(&htmlBuffer, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	var subjectBuffer bytes.Buffer
+	if subject != "" {
+		subjectTmpl, err := template.New("preview_subject").Parse(subject)
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to parse subject: %w", err)
+		}
+		if err := subjectTmpl.Execute(&subjectBuffer, data); err != nil {
+			return "", "", "", fmt.Errorf("failed to render subject: %w", err)
+		}
+	}
+
+	return subjectBuffer.String(), htmlBuffer.String(), htmlToText(htmlBuffer.String()), nil
+}
+
+// Localized returns the name of the template of templateName in locale: the
+// file of the same name in the locale's directory under the base path, such
+// as fr/welcome, or an active database version stored under that name. When
+// locale is empty or has no translation of the template, the default
+// locale's is used, and then the English template, templateName.
+func (e *HTMLTemplateEngine) Localized(templateName, locale string) string {
+	if locale == i18n.Default {
+		return templateName
+	}
+	templates, err := e.loaded()
+	if err != nil {
+		return templateName
+	}
+	for _, candidate := range []string{locale, e.defaultLocale} {
+		if candidate == "" || candidate == i18n.Default {
+			continue
+		}
+		if name := candidate + "/" + templateName; templates.Lookup(name) != nil {
+			return name
+		}
+	}
+	return templateName
+}
+
+// templateLocale returns the locale of a template from the directory its
+// name starts with
+func templateLocale(templateName string) string {
+	if locale, _, ok := strings.Cut(templateName, "/"); ok && i18n.IsSupported(locale) {
+		return locale
+	}
+	return i18n.Default
+}
+
+// ValidateTemplate checks that template source parses
+func ValidateTemplate(content string) error {
+	_, err := template.New("validate").Parse(content)
+	return err
+}
+
+// loaded returns the parsed template set, loading it on first use
+func (e *HTMLTemplateEngine) loaded() (*template.Template, error) {
+	e.mu.RLock()
+	templates := e.templates
+	e.mu.RUnlock()
+	if templates != nil {
+		return templates, nil
+	}
+
+	if err := e.ReloadTemplates(); err != nil {
+		return nil, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.templates, nil
+}
+
+// htmlToText produces a plain text version of rendered HTML
+func htmlToText(html string) string {
 	// For now, we'll use the same content for text version
 	// In a production system, you might want to create separate text templates
-	textContent := htmlBuffer.String()
+	textContent := html
 	// Simple HTML to text conversion (basic implementation)
 	textContent = strings.ReplaceAll(textContent, "<br>", "\n")
 	textContent = strings.ReplaceAll(textContent, "<br/>", "\n")
@@ -132,19 +314,18 @@ func (e *HTMLTemplateEngine) RenderTemplate(templateName string, data map[string
 	textContent = strings.ReplaceAll(textContent, "\n\n\n", "\n\n")
 	textContent = strings.TrimSpace(textContent)
 
-	return htmlBuffer.String(), textContent, nil
+	return textContent
 }
 
 // GetTemplateList returns a list of available templates
 func (e *HTMLTemplateEngine) GetTemplateList() []string {
-	if e.templates == nil {
-		if err := e.ReloadTemplates(); err != nil {
-			return []string{}
-		}
+	loaded, err := e.loaded()
+	if err != nil {
+		return []string{}
 	}
 
 	var templates []string
-	for _, tmpl := range e.templates.Templates() {
+	for _, tmpl := range loaded.Templates() {
 		if tmpl.Name() != "email_templates" {
 			templates = append(templates, tmpl.Name())
 		}
@@ -227,6 +408,7 @@ type PromotionalData struct {
 	Content         string        `json:"content"`
 	Offers          []interface{} `json:"offers"`
 	ExpiryDate      *time.Time    `json:"expiry_date"`
+	ShopURL         string        `json:"shop_url"`
 	UnsubscribeLink string        `json:"unsubscribe_link"`
 }
 
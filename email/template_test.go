@@ -0,0 +1,83 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTemplateTest(t *testing.T) (*HTMLTemplateEngine, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.EmailTemplate{}))
+
+	return NewHTMLTemplateEngine("../templates/emails").WithDatabase(db), db
+}
+
+func TestDatabaseTemplateOverridesFile(t *testing.T) {
+	engine, db := setupTemplateTest(t)
+	data := map[string]interface{}{"UserName": "Amina"}
+
+	require.NoError(t, engine.ReloadTemplates())
+	fileHTML, _, err := engine.RenderTemplate("welcome", data)
+	require.NoError(t, err)
+	_, ok := engine.RenderSubject("welcome", data)
+	assert.False(t, ok)
+
+	require.NoError(t, db.Create(&models.EmailTemplate{
+		Name:        "welcome",
+		Subject:     "Welcome aboard, {{.UserName}}",
+		HTMLContent: "<p>Hello {{.UserName}} from the database</p>",
+		Version:     1,
+		IsActive:    true,
+	}).Error)
+	require.NoError(t, engine.ReloadTemplates())
+
+	html, text, err := engine.RenderTemplate("welcome", data)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>Hello Amina from the database</p>", html)
+	assert.Equal(t, "Hello Amina from the database", text)
+	subject, ok := engine.RenderSubject("welcome", data)
+	assert.True(t, ok)
+	assert.Equal(t, "Welcome aboard, Amina", subject)
+
+	// Deactivating the version falls back to the filesystem template
+	require.NoError(t, db.Model(&models.EmailTemplate{}).Where("name = ?", "welcome").Update("is_active", false).Error)
+	require.NoError(t, engine.ReloadTemplates())
+	html, _, err = engine.RenderTemplate("welcome", data)
+	require.NoError(t, err)
+	assert.Equal(t, fileHTML, html)
+}
+
+func TestInvalidDatabaseTemplateKeepsFile(t *testing.T) {
+	engine, db := setupTemplateTest(t)
+	require.NoError(t, db.Create(&models.EmailTemplate{
+		Name:        "welcome",
+		HTMLContent: "<p>{{.UserName</p>",
+		Version:     1,
+		IsActive:    true,
+	}).Error)
+
+	require.NoError(t, engine.ReloadTemplates())
+	html, _, err := engine.RenderTemplate("welcome", map[string]interface{}{"UserName": "Amina"})
+	require.NoError(t, err)
+	assert.Contains(t, html, "Amina")
+	assert.NotContains(t, html, "{{")
+}
+
+func TestRenderPreview(t *testing.T) {
+	engine := NewHTMLTemplateEngine("../templates/emails")
+
+	subject, html, text, err := engine.RenderPreview("Order {{.OrderNumber}}", "<h1>Order {{.OrderNumber}}</h1>", map[string]interface{}{"OrderNumber": "ORD-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "Order ORD-1", subject)
+	assert.Equal(t, "<h1>Order ORD-1</h1>", html)
+	assert.Equal(t, "Order ORD-1", text)
+
+	_, _, _, err = engine.RenderPreview("", "{{if}}", nil)
+	assert.Error(t, err)
+}
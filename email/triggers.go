@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
 	"gorm.io/gorm"
 )
 
@@ -14,24 +15,28 @@ type EmailTriggerService struct {
 	db           *gorm.DB
 }
 
-// NewEmailTriggerService creates a new email trigger service
+// NewEmailTriggerService creates a new email trigger service. With a
+// database, emails are sent in the locale of the user they are sent to.
 func NewEmailTriggerService(emailService EmailService, db *gorm.DB) *EmailTriggerService {
+	if db != nil {
+		emailService = userLocaleService{EmailService: emailService, db: db}
+	}
 	return &EmailTriggerService{
 		emailService: emailService,
 		db:           db,
 	}
 }
 
+// PasswordResetExpiryHours is how long a password reset link works for
+const PasswordResetExpiryHours = 24
+
 // TriggerPasswordReset sends a password reset email
 func (t *EmailTriggerService) TriggerPasswordReset(userEmail, userName, resetToken string) error {
 	data := map[string]interface{}{
-		"UserName":     userName,
-		"ResetLink":    fmt.Sprintf("%s/reset-password?token=%s", "https://algeriamarket.co.uk", resetToken),
-		"ExpiryTime":   24, // 24 hours
-		"UserEmail":    userEmail,
-		"CompanyName":  "Algeria Market",
-		"SiteURL":      "https://algeriamarket.co.uk",
-		"SupportEmail": "enquirees@algeriamarket.co.uk",
+		"UserName":   userName,
+		"ResetLink":  settings.Current().URL("/reset-password?token=%s", resetToken),
+		"ExpiryTime": PasswordResetExpiryHours,
+		"UserEmail":  userEmail,
 	}
 
 	recipient := models.EmailRecipient{
@@ -42,15 +47,32 @@ func (t *EmailTriggerService) TriggerPasswordReset(userEmail, userName, resetTok
 	return t.emailService.SendTransactionalEmail(models.EmailTypePasswordReset, data, recipient)
 }
 
-// TriggerWelcomeEmail sends a welcome email to new users
-func (t *EmailTriggerService) TriggerWelcomeEmail(userEmail, userName string) error {
+// TriggerWelcomeEmail sends a welcome email to new users, with a link to
+// verify their email address when activationToken is set
+func (t *EmailTriggerService) TriggerWelcomeEmail(userEmail, userName, activationToken string) error {
+	data := map[string]interface{}{
+		"UserName":  userName,
+		"UserEmail": userEmail,
+	}
+	if activationToken != "" {
+		data["ActivationLink"] = settings.Current().URL("/activate?token=%s", activationToken)
+	}
+
+	recipient := models.EmailRecipient{
+		Email: userEmail,
+		Name:  userName,
+	}
+
+	return t.emailService.SendTransactionalEmail(models.EmailTypeWelcome, data, recipient)
+}
+
+// TriggerEmailVerification sends a new link to verify a user's email address
+func (t *EmailTriggerService) TriggerEmailVerification(userEmail, userName, activationToken string, expiryHours int) error {
 	data := map[string]interface{}{
 		"UserName":       userName,
 		"UserEmail":      userEmail,
-		"CompanyName":    "Algeria Market",
-		"SiteURL":        "https://algeriamarket.co.uk",
-		"SupportEmail":   "enquirees@algeriamarket.co.uk",
-		"ActivationLink": fmt.Sprintf("%s/activate?email=%s", "https://algeriamarket.co.uk", userEmail),
+		"ActivationLink": settings.Current().URL("/activate?token=%s", activationToken),
+		"ExpiryTime":     expiryHours,
 	}
 
 	recipient := models.EmailRecipient{
@@ -58,24 +80,25 @@ func (t *EmailTriggerService) TriggerWelcomeEmail(userEmail, userName string) er
 		Name:  userName,
 	}
 
-	return t.emailService.SendTransactionalEmail(models.EmailTypeWelcome, data, recipient)
+	return t.emailService.SendTransactionalEmail(models.EmailTypeEmailVerification, data, recipient)
 }
 
 // TriggerOrderConfirmation sends an order confirmation email
 func (t *EmailTriggerService) TriggerOrderConfirmation(orderID uint, userEmail, userName string, orderData map[string]interface{}) error {
 	data := map[string]interface{}{
+		orderIDKey:        orderID,
 		"UserName":        userName,
 		"UserEmail":       userEmail,
-		"CompanyName":     "Algeria Market",
-		"SiteURL":         "https://algeriamarket.co.uk",
-		"SupportEmail":    "enquirees@algeriamarket.co.uk",
 		"OrderNumber":     orderData["order_number"],
 		"OrderDate":       orderData["order_date"],
 		"TotalAmount":     orderData["total_amount"],
+		"TaxAmount":       orderData["tax_amount"],
+		"TaxBreakdown":    orderData["tax_breakdown"],
 		"Currency":        orderData["currency"],
 		"Items":           orderData["items"],
 		"ShippingAddress": orderData["shipping_address"],
-		"OrderStatusURL":  fmt.Sprintf("%s/orders/%d", "https://algeriamarket.co.uk", orderID),
+		"DeliverySlot":    orderData["delivery_slot"],
+		"OrderStatusURL":  settings.Current().URL("/orders/%d", orderID),
 	}
 
 	recipient := models.EmailRecipient{
@@ -89,17 +112,15 @@ func (t *EmailTriggerService) TriggerOrderConfirmation(orderID uint, userEmail,
 // TriggerPaymentSuccess sends a payment success email
 func (t *EmailTriggerService) TriggerPaymentSuccess(orderID uint, userEmail, userName string, paymentData map[string]interface{}) error {
 	data := map[string]interface{}{
+		orderIDKey:       orderID,
 		"UserName":       userName,
 		"UserEmail":      userEmail,
-		"CompanyName":    "Algeria Market",
-		"SiteURL":        "https://algeriamarket.co.uk",
-		"SupportEmail":   "enquirees@algeriamarket.co.uk",
 		"OrderNumber":    paymentData["order_number"],
 		"OrderDate":      paymentData["order_date"],
 		"TotalAmount":    paymentData["total_amount"],
 		"Currency":       paymentData["currency"],
 		"PaymentMethod":  paymentData["payment_method"],
-		"OrderStatusURL": fmt.Sprintf("%s/orders/%d", "https://algeriamarket.co.uk", orderID),
+		"OrderStatusURL": settings.Current().URL("/orders/%d", orderID),
 	}
 
 	recipient := models.EmailRecipient{
@@ -112,21 +133,20 @@ func (t *EmailTriggerService) TriggerPaymentSuccess(orderID uint, userEmail, use
 
 // TriggerPaymentFailed sends a payment failed email
 func (t *EmailTriggerService) TriggerPaymentFailed(orderID uint, userEmail, userName string, paymentData map[string]interface{}) error {
+	site := settings.Current()
 	data := map[string]interface{}{
+		orderIDKey:          orderID,
 		"UserName":          userName,
 		"UserEmail":         userEmail,
-		"CompanyName":       "Algeria Market",
-		"SiteURL":           "https://algeriamarket.co.uk",
-		"SupportEmail":      "enquirees@algeriamarket.co.uk",
 		"OrderNumber":       paymentData["order_number"],
 		"OrderDate":         paymentData["order_date"],
 		"TotalAmount":       paymentData["total_amount"],
 		"Currency":          paymentData["currency"],
 		"PaymentMethod":     paymentData["payment_method"],
 		"ErrorMessage":      paymentData["error_message"],
-		"RetryPaymentURL":   fmt.Sprintf("%s/orders/%d/payment/retry", "https://algeriamarket.co.uk", orderID),
-		"UpdatePaymentURL":  fmt.Sprintf("%s/orders/%d/payment/update", "https://algeriamarket.co.uk", orderID),
-		"ContactSupportURL": "https://algeriamarket.co.uk/support",
+		"RetryPaymentURL":   site.URL("/orders/%d/payment/retry", orderID),
+		"UpdatePaymentURL":  site.URL("/orders/%d/payment/update", orderID),
+		"ContactSupportURL": site.URL("/support"),
 	}
 
 	recipient := models.EmailRecipient{
@@ -140,11 +160,9 @@ func (t *EmailTriggerService) TriggerPaymentFailed(orderID uint, userEmail, user
 // TriggerOrderStatusUpdate sends an order status update email
 func (t *EmailTriggerService) TriggerOrderStatusUpdate(orderID uint, userEmail, userName string, statusData map[string]interface{}) error {
 	data := map[string]interface{}{
+		orderIDKey:          orderID,
 		"UserName":          userName,
 		"UserEmail":         userEmail,
-		"CompanyName":       "Algeria Market",
-		"SiteURL":           "https://algeriamarket.co.uk",
-		"SupportEmail":      "enquirees@algeriamarket.co.uk",
 		"OrderNumber":       statusData["order_number"],
 		"OrderDate":         statusData["order_date"],
 		"Status":            statusData["status"],
@@ -156,7 +174,7 @@ func (t *EmailTriggerService) TriggerOrderStatusUpdate(orderID uint, userEmail,
 		"TrackingURL":       statusData["tracking_url"],
 		"EstimatedDelivery": statusData["estimated_delivery"],
 		"Timeline":          statusData["timeline"],
-		"OrderStatusURL":    fmt.Sprintf("%s/orders/%d", "https://algeriamarket.co.uk", orderID),
+		"OrderStatusURL":    settings.Current().URL("/orders/%d", orderID),
 	}
 
 	recipient := models.EmailRecipient{
@@ -169,22 +187,23 @@ func (t *EmailTriggerService) TriggerOrderStatusUpdate(orderID uint, userEmail,
 
 // TriggerSecurityAlert sends a security alert email
 func (t *EmailTriggerService) TriggerSecurityAlert(userEmail, userName string, securityData map[string]interface{}) error {
+	site := settings.Current()
 	data := map[string]interface{}{
 		"UserName":          userName,
 		"UserEmail":         userEmail,
-		"CompanyName":       "Algeria Market",
-		"SiteURL":           "https://algeriamarket.co.uk",
-		"SupportEmail":      "enquirees@algeriamarket.co.uk",
 		"EventType":         securityData["event_type"],
 		"EventDateTime":     securityData["event_datetime"],
 		"Location":          securityData["location"],
 		"Device":            securityData["device"],
 		"IPAddress":         securityData["ip_address"],
-		"SecureAccountURL":  "https://algeriamarket.co.uk/account/security",
-		"ViewActivityURL":   "https://algeriamarket.co.uk/account/activity",
-		"ResetPasswordURL":  "https://algeriamarket.co.uk/account/reset-password",
-		"UnlockAccountURL":  "https://algeriamarket.co.uk/account/unlock",
-		"ContactSupportURL": "https://algeriamarket.co.uk/support",
+		"SecureAccountURL":  site.URL("/account/security"),
+		"ViewActivityURL":   site.URL("/account/activity"),
+		"ResetPasswordURL":  site.URL("/account/reset-password"),
+		"UnlockAccountURL":  site.URL("/account/unlock"),
+		"ContactSupportURL": site.URL("/support"),
+	}
+	if unlockURL, ok := securityData["unlock_url"].(string); ok && unlockURL != "" {
+		data["UnlockAccountURL"] = unlockURL
 	}
 
 	recipient := models.EmailRecipient{
@@ -197,33 +216,38 @@ func (t *EmailTriggerService) TriggerSecurityAlert(userEmail, userName string, s
 
 // TriggerAdminNotification sends an admin notification email
 func (t *EmailTriggerService) TriggerAdminNotification(adminEmail, adminName string, notificationData map[string]interface{}) error {
+	site := settings.Current()
 	data := map[string]interface{}{
-		"AdminName":              adminName,
-		"AdminEmail":             adminEmail,
-		"CompanyName":            "Algeria Market",
-		"SiteURL":                "https://algeriamarket.co.uk",
-		"SupportEmail":           "enquirees@algeriamarket.co.uk",
-		"NotificationType":       notificationData["notification_type"],
-		"Priority":               notificationData["priority"],
-		"DateTime":               notificationData["datetime"],
-		"System":                 notificationData["system"],
-		"ReferenceID":            notificationData["reference_id"],
-		"OrderNumber":            notificationData["order_number"],
-		"CustomerName":           notificationData["customer_name"],
-		"TotalAmount":            notificationData["total_amount"],
-		"Currency":               notificationData["currency"],
-		"ItemCount":              notificationData["item_count"],
-		"Amount":                 notificationData["amount"],
-		"ErrorMessage":           notificationData["error_message"],
-		"LowStockItems":          notificationData["low_stock_items"],
-		"ErrorCode":              notificationData["error_code"],
-		"Component":              notificationData["component"],
-		"OrderManagementURL":     "https://algeriamarket.co.uk/admin/orders",
-		"AdminDashboardURL":      "https://algeriamarket.co.uk/admin",
-		"PaymentManagementURL":   "https://algeriamarket.co.uk/admin/payments",
-		"CustomerSupportURL":     "https://algeriamarket.co.uk/admin/support",
-		"InventoryManagementURL": "https://algeriamarket.co.uk/admin/inventory",
-		"SystemLogsURL":          "https://algeriamarket.co.uk/admin/logs",
+		"AdminName":                adminName,
+		"AdminEmail":               adminEmail,
+		"NotificationType":         notificationData["notification_type"],
+		"Priority":                 notificationData["priority"],
+		"DateTime":                 notificationData["datetime"],
+		"System":                   notificationData["system"],
+		"ReferenceID":              notificationData["reference_id"],
+		"OrderNumber":              notificationData["order_number"],
+		"CustomerName":             notificationData["customer_name"],
+		"TotalAmount":              notificationData["total_amount"],
+		"Currency":                 notificationData["currency"],
+		"ItemCount":                notificationData["item_count"],
+		"Amount":                   notificationData["amount"],
+		"ErrorMessage":             notificationData["error_message"],
+		"LowStockItems":            notificationData["low_stock_items"],
+		"ErrorCode":                notificationData["error_code"],
+		"Component":                notificationData["component"],
+		"QuoteNumber":              notificationData["quote_number"],
+		"Discrepancies":            notificationData["discrepancies"],
+		"ReconciliationPeriod":     notificationData["reconciliation_period"],
+		"ReconciliationChecked":    notificationData["reconciliation_checked"],
+		"ReconciliationFixed":      notificationData["reconciliation_fixed"],
+		"ReconciliationUnresolved": notificationData["reconciliation_unresolved"],
+		"OrderManagementURL":       site.URL("/admin/orders"),
+		"AdminDashboardURL":        site.URL("/admin"),
+		"PaymentManagementURL":     site.URL("/admin/payments"),
+		"CustomerSupportURL":       site.URL("/admin/support"),
+		"InventoryManagementURL":   site.URL("/admin/inventory"),
+		"SystemLogsURL":            site.URL("/admin/logs"),
+		"QuoteManagementURL":       site.URL("/admin/quotes"),
 	}
 
 	recipient := models.EmailRecipient{
@@ -298,8 +322,143 @@ func (t *EmailTriggerService) TriggerPaymentFailedAdminNotification(orderID uint
 	return nil
 }
 
+// TriggerQuoteAdminNotification notifies admins about quote activity (quote_request, quote_accepted)
+func (t *EmailTriggerService) TriggerQuoteAdminNotification(notificationType string, quoteID uint, quoteData map[string]interface{}) error {
+	var adminUsers []models.User
+	if err := t.db.Where("user_type = ?", models.Admin).Find(&adminUsers).Error; err != nil {
+		return fmt.Errorf("failed to get admin users: %w", err)
+	}
+
+	for _, admin := range adminUsers {
+		notificationData := map[string]interface{}{
+			"notification_type": notificationType,
+			"priority":          "medium",
+			"datetime":          time.Now().Format("2006-01-02 15:04:05"),
+			"system":            "quote_management",
+			"reference_id":      fmt.Sprintf("QUOTE_%d", quoteID),
+			"quote_number":      quoteData["quote_number"],
+			"order_number":      quoteData["order_number"],
+			"customer_name":     quoteData["customer_name"],
+			"total_amount":      quoteData["total_amount"],
+			"currency":          quoteData["currency"],
+			"item_count":        quoteData["item_count"],
+		}
+
+		adminName := fmt.Sprintf("%s %s", admin.FirstName, admin.LastName)
+		if err := t.TriggerAdminNotification(admin.Email, adminName, notificationData); err != nil {
+			// Log error but continue with other admins
+			fmt.Printf("Failed to send admin notification to %s: %v\n", admin.Email, err)
+		}
+	}
+
+	return nil
+}
+
+// TriggerPaymentReconciliationAdminNotification sends admins the report of a
+// reconciliation with Revolut that found discrepancies
+func (t *EmailTriggerService) TriggerPaymentReconciliationAdminNotification(reportData map[string]interface{}) error {
+	var adminUsers []models.User
+	if err := t.db.Where("user_type = ?", models.Admin).Find(&adminUsers).Error; err != nil {
+		return fmt.Errorf("failed to get admin users: %w", err)
+	}
+
+	for _, admin := range adminUsers {
+		notificationData := map[string]interface{}{
+			"notification_type":         "payment_reconciliation",
+			"priority":                  reportData["priority"],
+			"datetime":                  time.Now().Format("2006-01-02 15:04:05"),
+			"system":                    "payment_processing",
+			"reconciliation_period":     reportData["period"],
+			"reconciliation_checked":    reportData["checked"],
+			"reconciliation_fixed":      reportData["fixed"],
+			"reconciliation_unresolved": reportData["unresolved"],
+			"discrepancies":             reportData["discrepancies"],
+		}
+
+		adminName := fmt.Sprintf("%s %s", admin.FirstName, admin.LastName)
+		if err := t.TriggerAdminNotification(admin.Email, adminName, notificationData); err != nil {
+			// Log error but continue with other admins
+			fmt.Printf("Failed to send admin notification to %s: %v\n", admin.Email, err)
+		}
+	}
+
+	return nil
+}
+
+// TriggerQuoteResponded notifies a customer that their quote has been priced
+func (t *EmailTriggerService) TriggerQuoteResponded(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeQuoteResponded, data, recipient)
+}
+
+// TriggerGiftCard sends a gift card's code to its recipient
+func (t *EmailTriggerService) TriggerGiftCard(recipientEmail, recipientName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: recipientEmail, Name: recipientName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeGiftCard, data, recipient)
+}
+
+// TriggerOrderMessage tells a customer the store has written about their order
+func (t *EmailTriggerService) TriggerOrderMessage(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeOrderMessage, data, recipient)
+}
+
+// TriggerCompanyInvitation invites someone to order for a company as a buyer
+func (t *EmailTriggerService) TriggerCompanyInvitation(inviteeEmail string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: inviteeEmail}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeCompanyInvitation, data, recipient)
+}
+
+// TriggerOrderApprovalRequest asks a company owner to approve a buyer's order
+func (t *EmailTriggerService) TriggerOrderApprovalRequest(approverEmail, approverName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: approverEmail, Name: approverName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeOrderApprovalRequest, data, recipient)
+}
+
+// TriggerOrderApprovalDecision tells a company buyer their order was approved
+// or rejected
+func (t *EmailTriggerService) TriggerOrderApprovalDecision(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeOrderApprovalDecision, data, recipient)
+}
+
 // Support notification helpers
 
+// TriggerReviewReply tells a review author or a seller that the other side
+// has replied in the thread below a seller response
+func (t *EmailTriggerService) TriggerReviewReply(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeReviewReply, data, recipient)
+}
+
+// TriggerContentRemoved tells a user their review or product was taken down
+// after an abuse report
+func (t *EmailTriggerService) TriggerContentRemoved(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeContentRemoved, data, recipient)
+}
+
+// TriggerAccountEnforcement tells a user they were given a strike or that
+// their account was restricted or banned
+func (t *EmailTriggerService) TriggerAccountEnforcement(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeAccountEnforcement, data, recipient)
+}
+
+// TriggerSubscriptionReminder reminds a customer of their subscription's
+// next order, in time to skip or pause it
+func (t *EmailTriggerService) TriggerSubscriptionReminder(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeSubscriptionReminder, data, recipient)
+}
+
+// TriggerSubscriptionOrder tells a customer their subscription placed an
+// order, with a link to pay for it unless it was paid with their saved card
+func (t *EmailTriggerService) TriggerSubscriptionOrder(userEmail, userName string, data map[string]interface{}) error {
+	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
+	return t.emailService.SendTransactionalEmail(models.EmailTypeSubscriptionOrder, data, recipient)
+}
+
 // TriggerTicketResponse notifies user about a new response on their ticket
 func (t *EmailTriggerService) TriggerTicketResponse(userEmail, userName string, data map[string]interface{}) error {
 	recipient := models.EmailRecipient{Email: userEmail, Name: userName}
@@ -343,3 +502,55 @@ func (t *EmailTriggerService) SendTemplateDirect(templateName string, data map[s
 	// Bypass type-to-template mapping by directly calling SendEmail with templateName
 	return t.emailService.SendEmail(templateName, data, recipient)
 }
+
+// Scheduled emails
+
+const (
+	// ReviewRequestDelay is how long after delivery customers are asked for a review
+	ReviewRequestDelay = 7 * 24 * time.Hour
+	// CartRecoveryDelay is how long a cart must sit idle before the recovery email goes out
+	CartRecoveryDelay = 2 * time.Hour
+)
+
+// ScheduleReviewRequest asks a customer to review a delivered order after ReviewRequestDelay
+func (t *EmailTriggerService) ScheduleReviewRequest(orderID uint, userEmail, userName string, orderData map[string]interface{}) (*models.Email, error) {
+	data := map[string]interface{}{
+		orderIDKey:    orderID,
+		"subject":     "How was your order?",
+		"UserName":    userName,
+		"UserEmail":   userEmail,
+		"OrderNumber": orderData["order_number"],
+		"Items":       orderData["items"],
+		"ReviewURL":   settings.Current().URL("/orders/%d/review", orderID),
+	}
+
+	recipient := models.EmailRecipient{
+		Email: userEmail,
+		Name:  userName,
+	}
+
+	return t.emailService.ScheduleTransactionalEmail(models.EmailTypeReviewRequest, data, recipient, time.Now().Add(ReviewRequestDelay))
+}
+
+// ScheduleCartRecovery sends an abandoned-cart reminder after CartRecoveryDelay
+func (t *EmailTriggerService) ScheduleCartRecovery(userEmail, userName string, cartData map[string]interface{}) (*models.Email, error) {
+	data := map[string]interface{}{
+		"subject":   "You left something in your cart",
+		"UserName":  userName,
+		"UserEmail": userEmail,
+		"CartItems": cartData["items"],
+		"CartURL":   settings.Current().URL("/cart"),
+	}
+
+	recipient := models.EmailRecipient{
+		Email: userEmail,
+		Name:  userName,
+	}
+
+	return t.emailService.ScheduleTransactionalEmail(models.EmailTypeCartRecovery, data, recipient, time.Now().Add(CartRecoveryDelay))
+}
+
+// CancelScheduledEmail cancels a scheduled email before it is dispatched
+func (t *EmailTriggerService) CancelScheduledEmail(emailID uint) error {
+	return t.emailService.CancelScheduledEmail(emailID)
+}
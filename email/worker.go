@@ -0,0 +1,338 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// RateLimits are the sending limits of a provider; zero means unlimited
+type RateLimits struct {
+	PerMinute int
+	PerDay    int
+}
+
+// RateLimitedProvider is implemented by providers with known sending limits
+type RateLimitedProvider interface {
+	RateLimits() RateLimits
+}
+
+const (
+	minQuotaBackoff = time.Second
+	maxQuotaBackoff = 5 * time.Minute
+)
+
+// QueueWorker sends queued emails with bounded concurrency, keeping within the
+// provider's rate limits and backing off while the provider reports throttling
+type QueueWorker struct {
+	queue       EmailQueue
+	provider    EmailProvider
+	concurrency int
+	batchSize   int
+	limiter     *rateLimiter
+	db          *gorm.DB
+
+	mu          sync.Mutex
+	backoff     time.Duration
+	pausedUntil time.Time
+}
+
+// NewQueueWorker creates a queue worker. Limits not set in config are taken
+// from the provider when it implements RateLimitedProvider.
+func NewQueueWorker(queue EmailQueue, provider EmailProvider, config *cfg.EmailWorkerConfig) *QueueWorker {
+	if config == nil {
+		config = &cfg.EmailWorkerConfig{}
+	}
+
+	limits := RateLimits{PerMinute: config.RatePerMinute, PerDay: config.DailyLimit}
+	if p, ok := provider.(RateLimitedProvider); ok {
+		providerLimits := p.RateLimits()
+		if limits.PerMinute == 0 {
+			limits.PerMinute = providerLimits.PerMinute
+		}
+		if limits.PerDay == 0 {
+			limits.PerDay = providerLimits.PerDay
+		}
+	}
+
+	w := &QueueWorker{
+		queue:       queue,
+		provider:    provider,
+		concurrency: config.Concurrency,
+		batchSize:   config.BatchSize,
+		limiter:     newRateLimiter(limits),
+	}
+	if w.concurrency < 1 {
+		w.concurrency = 1
+	}
+	if w.batchSize < 1 {
+		w.batchSize = 1
+	}
+	return w
+}
+
+// WithDatabase makes the worker record failures and dead-lettering on the
+// email records
+func (w *QueueWorker) WithDatabase(db *gorm.DB) *QueueWorker {
+	w.db = db
+	return w
+}
+
+// Start processes the queue until ctx is canceled
+func (w *QueueWorker) Start(ctx context.Context) {
+	slog.Info("starting email queue worker", "component", "email",
+		"concurrency", w.concurrency, "batch", w.batchSize,
+		"per_minute", w.limiter.limits.PerMinute, "per_day", w.limiter.limits.PerDay)
+
+	jobs := make(chan *models.Email, w.batchSize)
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range jobs {
+				w.process(ctx, email)
+			}
+		}()
+	}
+
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if !w.waitForBackoff(ctx) {
+			return
+		}
+
+		batch, err := w.queue.DequeueBatch(w.batchSize)
+		if err != nil {
+			slog.Error("email queue dequeue failed", "component", "email", "error", err)
+			sleep(ctx, time.Second)
+			continue
+		}
+		if len(batch) == 0 {
+			// Queue is empty, wait a bit before checking again
+			sleep(ctx, 2*time.Second)
+			continue
+		}
+
+		for _, email := range batch {
+			select {
+			case jobs <- email:
+			case <-ctx.Done():
+				// Put unsent emails back so they survive the restart
+				if err := w.queue.Enqueue(email); err != nil {
+					emailLogger(email).Error("failed to requeue email on shutdown", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// process sends a single email and records the outcome on the queue
+func (w *QueueWorker) process(ctx context.Context, email *models.Email) {
+	logger := emailLogger(email)
+	if err := w.limiter.Wait(ctx); err != nil || !w.waitForBackoff(ctx) {
+		if err := w.queue.Enqueue(email); err != nil {
+			logger.Error("failed to requeue email", "error", err)
+		}
+		return
+	}
+
+	emailID := fmt.Sprintf("%d", email.ID)
+	err := w.provider.SendEmail(email)
+	switch {
+	case err == nil:
+		w.resetBackoff()
+		metrics.EmailsSent.Inc()
+		logger.Info("email sent", "template", email.Template)
+		if err := w.queue.MarkAsProcessed(emailID); err != nil {
+			logger.Error("failed to mark email as processed", "error", err)
+		}
+	case errors.Is(err, ErrProviderQuota):
+		// Throttled: pause all sending and retry this email later
+		metrics.EmailSendFailures.WithLabelValues("throttled").Inc()
+		delay := w.throttle()
+		logger.Warn("email provider throttled, backing off", "delay", delay.String())
+		if err := w.queue.Enqueue(email); err != nil {
+			logger.Error("failed to requeue throttled email", "error", err)
+		}
+	default:
+		metrics.EmailSendFailures.WithLabelValues("error").Inc()
+		logger.Error("failed to send email", "error", err)
+		if err := w.queue.MarkAsFailed(emailID, err.Error()); err != nil {
+			logger.Error("failed to mark email as failed", "error", err)
+		}
+		w.retryOrDeadLetter(email, err)
+	}
+}
+
+// retryOrDeadLetter requeues a failed email until it has failed MaxEmailRetries
+// times, then moves it to the dead-letter queue
+func (w *QueueWorker) retryOrDeadLetter(email *models.Email, sendErr error) {
+	email.RetryCount++
+	email.LastError = sendErr.Error()
+
+	if email.RetryCount < MaxEmailRetries {
+		w.updateEmail(email.ID, map[string]interface{}{
+			"retry_count": email.RetryCount,
+			"last_error":  email.LastError,
+		})
+		if err := w.queue.Enqueue(email); err != nil {
+			emailLogger(email).Error("failed to requeue email for retry", "error", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	email.Status = models.EmailStatusDeadLettered
+	email.DeadLetteredAt = &now
+	metrics.EmailSendFailures.WithLabelValues("dead_lettered").Inc()
+	emailLogger(email).Warn("email failed too many times, moving to dead-letter queue", "retries", email.RetryCount)
+	if err := w.queue.DeadLetter(email, email.LastError); err != nil {
+		emailLogger(email).Error("failed to dead-letter email", "error", err)
+	}
+	w.updateEmail(email.ID, map[string]interface{}{
+		"status":           models.EmailStatusDeadLettered,
+		"retry_count":      email.RetryCount,
+		"last_error":       email.LastError,
+		"dead_lettered_at": now,
+	})
+}
+
+// updateEmail applies updates to the stored email when a database is attached
+func (w *QueueWorker) updateEmail(id uint, updates map[string]interface{}) {
+	if w.db == nil {
+		return
+	}
+	if err := w.db.Model(&models.Email{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		slog.Error("failed to update email", "component", "email", "email_id", id, "error", err)
+	}
+}
+
+// emailLogger returns a logger tagged with the email and the request that triggered it
+func emailLogger(email *models.Email) *slog.Logger {
+	logger := slog.With("component", "email", "email_id", email.ID)
+	if email.RequestID != "" {
+		logger = logger.With("request_id", email.RequestID)
+	}
+	return logger
+}
+
+// throttle doubles the backoff and pauses sending for it
+func (w *QueueWorker) throttle() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.backoff == 0 {
+		w.backoff = minQuotaBackoff
+	} else {
+		w.backoff *= 2
+		if w.backoff > maxQuotaBackoff {
+			w.backoff = maxQuotaBackoff
+		}
+	}
+	w.pausedUntil = time.Now().Add(w.backoff)
+	return w.backoff
+}
+
+func (w *QueueWorker) resetBackoff() {
+	w.mu.Lock()
+	w.backoff = 0
+	w.mu.Unlock()
+}
+
+// waitForBackoff blocks while sending is paused. It returns false if ctx ends first.
+func (w *QueueWorker) waitForBackoff(ctx context.Context) bool {
+	w.mu.Lock()
+	until := w.pausedUntil
+	w.mu.Unlock()
+
+	if wait := time.Until(until); wait > 0 {
+		return sleep(ctx, wait)
+	}
+	return ctx.Err() == nil
+}
+
+// rateLimiter spaces sends evenly to stay within a per-minute rate and stops
+// sending once the daily limit is reached until the next UTC day
+type rateLimiter struct {
+	limits RateLimits
+
+	mu        sync.Mutex
+	next      time.Time
+	day       string
+	sentToday int
+}
+
+func newRateLimiter(limits RateLimits) *rateLimiter {
+	return &rateLimiter{limits: limits}
+}
+
+// reserve books a send slot and returns when it may happen
+func (l *rateLimiter) reserve(now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	at := now
+	if at.Before(l.next) {
+		at = l.next
+	}
+
+	if l.limits.PerDay > 0 {
+		day := at.UTC().Format("2006-01-02")
+		if day != l.day {
+			l.day = day
+			l.sentToday = 0
+		}
+		if l.sentToday >= l.limits.PerDay {
+			// Daily quota used up, wait for the next day
+			year, month, d := at.UTC().Date()
+			at = time.Date(year, month, d+1, 0, 0, 0, 0, time.UTC)
+			l.day = at.Format("2006-01-02")
+			l.sentToday = 0
+		}
+		l.sentToday++
+	}
+
+	if l.limits.PerMinute > 0 {
+		l.next = at.Add(time.Minute / time.Duration(l.limits.PerMinute))
+	}
+	return at
+}
+
+// Wait blocks until the next send slot
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.limits.PerMinute <= 0 && l.limits.PerDay <= 0 {
+		return nil
+	}
+	if wait := time.Until(l.reserve(time.Now())); wait > 0 && !sleep(ctx, wait) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// sleep waits for d or until ctx ends, reporting whether the full wait completed
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,126 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := newRateLimiter(RateLimits{PerMinute: 30, PerDay: 3})
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+
+	// Sends are spaced two seconds apart at 30 per minute
+	assert.Equal(t, now, limiter.reserve(now))
+	assert.Equal(t, now.Add(2*time.Second), limiter.reserve(now))
+	assert.Equal(t, now.Add(4*time.Second), limiter.reserve(now))
+
+	// The daily limit pushes the next send to the following UTC day
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), limiter.reserve(now))
+}
+
+func TestMockEmailQueuePriority(t *testing.T) {
+	queue := NewMockEmailQueue()
+	recipients := []models.EmailRecipient{{Email: "buyer@example.com"}}
+	queue.Enqueue(&models.Email{Model: gorm.Model{ID: 1}, Priority: models.EmailPriorityBulk, Recipients: recipients})
+	queue.Enqueue(&models.Email{Model: gorm.Model{ID: 2}, Priority: models.EmailPriorityBulk, Recipients: recipients})
+	queue.Enqueue(&models.Email{Model: gorm.Model{ID: 3}, Recipients: recipients})
+
+	batch, err := queue.DequeueBatch(2)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, uint(3), batch[0].ID, "transactional emails are sent before campaigns")
+	assert.Equal(t, uint(1), batch[1].ID)
+}
+
+func TestQueueWorkerBacksOffOnQuota(t *testing.T) {
+	queue := NewMockEmailQueue()
+	provider := &stubProvider{err: fmt.Errorf("throttled (%w)", ErrProviderQuota)}
+	worker := NewQueueWorker(queue, provider, &cfg.EmailWorkerConfig{Concurrency: 1, BatchSize: 5})
+
+	email := &models.Email{Model: gorm.Model{ID: 7}, Recipients: []models.EmailRecipient{{Email: "buyer@example.com"}}}
+	worker.process(context.Background(), email)
+
+	// The email is put back on the queue and sending is paused
+	size, _ := queue.GetQueueSize()
+	assert.Equal(t, int64(1), size)
+	assert.Equal(t, minQuotaBackoff, worker.backoff)
+	assert.True(t, worker.pausedUntil.After(time.Now()))
+
+	// Repeated throttling doubles the backoff up to the cap
+	assert.Equal(t, 2*minQuotaBackoff, worker.throttle())
+	for i := 0; i < 20; i++ {
+		worker.throttle()
+	}
+	assert.Equal(t, maxQuotaBackoff, worker.backoff)
+
+	// A successful send clears the backoff
+	provider.err = nil
+	worker.pausedUntil = time.Time{}
+	worker.process(context.Background(), email)
+	assert.Equal(t, 1, provider.sent)
+	assert.Zero(t, worker.backoff)
+}
+
+func TestQueueWorkerUsesProviderLimits(t *testing.T) {
+	graph := &GraphEmailProvider{}
+	worker := NewQueueWorker(NewMockEmailQueue(), graph, &cfg.EmailWorkerConfig{DailyLimit: 500})
+	assert.Equal(t, RateLimits{PerMinute: 30, PerDay: 500}, worker.limiter.limits)
+}
+
+func TestQueueWorkerDeadLettersAfterMaxRetries(t *testing.T) {
+	service, queue, db := setupSchedulingTest(t)
+	provider := &stubProvider{err: fmt.Errorf("mailbox unavailable")}
+	worker := NewQueueWorker(queue, provider, &cfg.EmailWorkerConfig{Concurrency: 1, BatchSize: 5}).WithDatabase(db)
+
+	email := &models.Email{Recipients: []models.EmailRecipient{{Email: "buyer@example.com"}}, Status: models.EmailStatusPending}
+	require.NoError(t, db.Create(email).Error)
+
+	// Failures below the retry limit put the email back on the queue
+	for i := 1; i < MaxEmailRetries; i++ {
+		worker.process(context.Background(), email)
+		next, _ := queue.Dequeue()
+		require.NotNil(t, next)
+		assert.Equal(t, i, next.RetryCount)
+		email = next
+	}
+	size, _ := queue.DeadLetterSize()
+	assert.Equal(t, int64(0), size)
+
+	// The final failure moves it to the dead-letter queue
+	worker.process(context.Background(), email)
+	queueSize, _ := queue.GetQueueSize()
+	assert.Equal(t, int64(0), queueSize)
+	letters, err := service.GetDeadLetters()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, "mailbox unavailable", letters[0].Error)
+
+	var stored models.Email
+	require.NoError(t, db.First(&stored, email.ID).Error)
+	assert.Equal(t, models.EmailStatusDeadLettered, stored.Status)
+	assert.Equal(t, MaxEmailRetries, stored.RetryCount)
+	assert.NotNil(t, stored.DeadLetteredAt)
+
+	// Requeueing resets the retry budget
+	requeued, err := service.RequeueDeadLetters(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requeued)
+	size, _ = queue.DeadLetterSize()
+	assert.Equal(t, int64(0), size)
+	next, _ := queue.Dequeue()
+	require.NotNil(t, next)
+	assert.Equal(t, 0, next.RetryCount)
+	assert.Equal(t, "buyer@example.com", next.Recipients[0].Email)
+
+	var requeuedEmail models.Email
+	require.NoError(t, db.First(&requeuedEmail, email.ID).Error)
+	assert.Equal(t, models.EmailStatusPending, requeuedEmail.Status)
+}
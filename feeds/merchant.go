@@ -0,0 +1,171 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// Google Merchant Center attribute limits
+const (
+	maxTitleLength       = 150
+	maxDescriptionLength = 5000
+	maxAdditionalImages  = 10
+)
+
+// item is a product variant in the merchant feed, named after the Merchant
+// Center attributes
+type item struct {
+	ID                   string   `xml:"g:id"`
+	ItemGroupID          string   `xml:"g:item_group_id"`
+	Title                string   `xml:"g:title"`
+	Description          string   `xml:"g:description"`
+	Link                 string   `xml:"g:link"`
+	ImageLink            string   `xml:"g:image_link,omitempty"`
+	AdditionalImageLinks []string `xml:"g:additional_image_link,omitempty"`
+	Availability         string   `xml:"g:availability"`
+	Price                string   `xml:"g:price"`
+	Brand                string   `xml:"g:brand,omitempty"`
+	GTIN                 string   `xml:"g:gtin,omitempty"`
+	IdentifierExists     string   `xml:"g:identifier_exists,omitempty"`
+	Condition            string   `xml:"g:condition"`
+	ShippingWeight       string   `xml:"g:shipping_weight,omitempty"`
+}
+
+// newItem builds the feed item of a variant sold at price with stock
+// available
+func (s *Service) newItem(product *models.Product, variant *models.ProductVariant, price float64, stock int) item {
+	site := strings.TrimRight(s.config.SiteURL, "/")
+	entry := item{
+		ID:           variant.SKU,
+		ItemGroupID:  fmt.Sprintf("%d", product.ID),
+		Title:        truncate(product.Name, maxTitleLength),
+		Description:  truncate(strings.TrimSpace(product.Description), maxDescriptionLength),
+		Link:         fmt.Sprintf("%s/products/%d?variant=%d", site, product.ID, variant.ID),
+		Availability: "out_of_stock",
+		Price:        fmt.Sprintf("%.2f %s", price, s.config.Currency),
+		GTIN:         variant.Barcode,
+		Condition:    "new",
+	}
+	if variant.Name != "" && variant.Name != product.Name {
+		entry.Title = truncate(product.Name+" - "+variant.Name, maxTitleLength)
+	}
+	if entry.Description == "" {
+		entry.Description = entry.Title
+	}
+	if stock > 0 {
+		entry.Availability = "in_stock"
+	}
+	if product.Brand != nil {
+		entry.Brand = product.Brand.Name
+	}
+	if entry.GTIN == "" {
+		entry.IdentifierExists = "no"
+	}
+	if variant.Weight > 0 && variant.WeightUnit != "" {
+		entry.ShippingWeight = fmt.Sprintf("%g %s", variant.Weight, variant.WeightUnit)
+	}
+
+	// Variant images first, then the product's, the primary image of each first
+	images := append(append([]models.ProductImage{}, variant.Images...), product.Images...)
+	for _, image := range images {
+		link := s.imageURL(image.URL)
+		switch {
+		case entry.ImageLink == "":
+			entry.ImageLink = link
+		case link != entry.ImageLink && len(entry.AdditionalImageLinks) < maxAdditionalImages:
+			entry.AdditionalImageLinks = append(entry.AdditionalImageLinks, link)
+		}
+	}
+	return entry
+}
+
+// imageURL returns the public URL of a stored image. Images served through
+// the API's file preview proxy are linked on the API's public URL.
+func (s *Service) imageURL(key string) string {
+	if strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://") {
+		return key
+	}
+	link := "/file/preview/" + key
+	if s.images != nil {
+		link = s.images.URL(key)
+	}
+	if !strings.HasPrefix(link, "/") {
+		return link
+	}
+	return strings.TrimRight(s.config.APIURL, "/") + link
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+type merchantRSS struct {
+	XMLName xml.Name        `xml:"rss"`
+	Version string          `xml:"version,attr"`
+	XmlnsG  string          `xml:"xmlns:g,attr"`
+	Channel merchantChannel `xml:"channel"`
+}
+
+type merchantChannel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Items       []item `xml:"item"`
+}
+
+// buildMerchantXML renders items as a Merchant Center RSS 2.0 feed
+func buildMerchantXML(siteURL string, items []item) ([]byte, error) {
+	feed := merchantRSS{
+		Version: "2.0",
+		XmlnsG:  "http://base.google.com/ns/1.0",
+		Channel: merchantChannel{
+			Title:       "Products",
+			Link:        strings.TrimRight(siteURL, "/") + "/",
+			Description: "Product feed",
+			Items:       items,
+		},
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var merchantColumns = []string{
+	"id", "item_group_id", "title", "description", "link", "image_link", "additional_image_link",
+	"availability", "price", "brand", "gtin", "identifier_exists", "condition", "shipping_weight",
+}
+
+// buildMerchantCSV renders items as a Merchant Center delimited feed
+func buildMerchantCSV(items []item) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(merchantColumns); err != nil {
+		return nil, err
+	}
+	for _, entry := range items {
+		if err := writer.Write([]string{
+			entry.ID, entry.ItemGroupID, entry.Title, entry.Description, entry.Link, entry.ImageLink,
+			strings.Join(entry.AdditionalImageLinks, ","), entry.Availability, entry.Price, entry.Brand,
+			entry.GTIN, entry.IdentifierExists, entry.Condition, entry.ShippingWeight,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
@@ -0,0 +1,275 @@
+// Package feeds generates the storefront sitemap and the Google Merchant
+// Center product feed. Feeds are regenerated on a schedule and kept in memory
+// to be served, and can also be published to GCS for Merchant Center to fetch.
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/gcs"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+// Names of the generated feeds
+const (
+	Sitemap     = "sitemap.xml"
+	MerchantXML = "google-merchant.xml"
+	MerchantCSV = "google-merchant.csv"
+)
+
+const batchSize = 200
+
+// ErrUnknownFeed is returned for a feed name that is not generated
+var ErrUnknownFeed = errors.New("unknown feed")
+
+// File is a generated feed
+type File struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Data        []byte    `json:"-"`
+}
+
+// Publisher stores generated feeds outside the API, where crawlers and
+// Merchant Center can fetch them
+type Publisher interface {
+	Publish(ctx context.Context, file *File) error
+}
+
+// GCSPublisher uploads feeds to the GCS bucket under feeds/
+type GCSPublisher struct {
+	service *gcs.GCService
+}
+
+// NewGCSPublisher creates a publisher backed by GCS
+func NewGCSPublisher(service *gcs.GCService) *GCSPublisher {
+	return &GCSPublisher{service: service}
+}
+
+// Publish implements Publisher
+func (p *GCSPublisher) Publish(ctx context.Context, file *File) error {
+	_, err := p.service.UploadFile(ctx, bytes.NewReader(file.Data), "feeds/"+file.Name, file.ContentType)
+	return err
+}
+
+// Service generates and holds the feeds
+type Service struct {
+	db        *gorm.DB
+	config    *cfg.FeedConfig
+	taxes     *tax.TaxService
+	publisher Publisher       // nil when feeds are only served by the API
+	images    *storage.Images // nil links images to the Appwrite file preview proxy
+
+	mu    sync.RWMutex
+	files map[string]*File
+}
+
+// NewService creates the feed service. publisher may be nil.
+func NewService(db *gorm.DB, config *cfg.FeedConfig, taxes *tax.TaxService, publisher Publisher) *Service {
+	return &Service{db: db, config: config, taxes: taxes, publisher: publisher, files: map[string]*File{}}
+}
+
+// WithImages sets the storage product image links are made from
+func (s *Service) WithImages(images *storage.Images) *Service {
+	s.images = images
+	return s
+}
+
+// Get returns a feed, generating the feeds first when they have not been yet
+func (s *Service) Get(ctx context.Context, name string) (*File, error) {
+	s.mu.RLock()
+	file, ok := s.files[name]
+	generated := len(s.files) > 0
+	s.mu.RUnlock()
+	if ok {
+		return file, nil
+	}
+	if generated {
+		return nil, ErrUnknownFeed
+	}
+	if err := s.Generate(ctx); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, name)
+}
+
+// Files lists the generated feeds, for the admin status page
+func (s *Service) Files() []File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	files := make([]File, 0, len(s.files))
+	for _, name := range []string{Sitemap, MerchantXML, MerchantCSV} {
+		if file, ok := s.files[name]; ok {
+			files = append(files, *file)
+		}
+	}
+	return files
+}
+
+// Generate rebuilds every feed from the catalog and publishes them when a
+// publisher is configured. The previous feeds are served until it succeeds.
+func (s *Service) Generate(ctx context.Context) error {
+	now := time.Now()
+	items, pages, err := s.load(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	sitemap, err := buildSitemap(pages)
+	if err != nil {
+		return fmt.Errorf("failed to build sitemap: %w", err)
+	}
+	merchantXML, err := buildMerchantXML(s.config.SiteURL, items)
+	if err != nil {
+		return fmt.Errorf("failed to build merchant feed: %w", err)
+	}
+	merchantCSV, err := buildMerchantCSV(items)
+	if err != nil {
+		return fmt.Errorf("failed to build merchant feed: %w", err)
+	}
+
+	files := map[string]*File{
+		Sitemap:     {Name: Sitemap, ContentType: "application/xml; charset=utf-8", Data: sitemap},
+		MerchantXML: {Name: MerchantXML, ContentType: "application/xml; charset=utf-8", Data: merchantXML},
+		MerchantCSV: {Name: MerchantCSV, ContentType: "text/csv; charset=utf-8", Data: merchantCSV},
+	}
+	for _, file := range files {
+		file.Size = len(file.Data)
+		file.GeneratedAt = now
+	}
+	s.mu.Lock()
+	s.files = files
+	s.mu.Unlock()
+
+	if s.publisher != nil {
+		for _, file := range files {
+			if err := s.publisher.Publish(ctx, file); err != nil {
+				return fmt.Errorf("failed to publish %s: %w", file.Name, err)
+			}
+		}
+	}
+	slog.InfoContext(ctx, "generated feeds", "component", "feeds", "items", len(items), "urls", len(pages), "duration_ms", time.Since(now).Milliseconds())
+	return nil
+}
+
+// StartGenerator generates the feeds at startup and then each interval until
+// ctx is canceled
+func (s *Service) StartGenerator(ctx context.Context, interval time.Duration) {
+	for {
+		if err := s.Generate(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to generate feeds", "component", "feeds", "error", err)
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// load reads the storefront pages and the merchant items of every variant
+// of the active products inside their launch window
+func (s *Service) load(ctx context.Context, now time.Time) ([]item, []page, error) {
+	db := s.db.WithContext(ctx)
+	site := strings.TrimRight(s.config.SiteURL, "/")
+
+	vatRate, _, err := s.taxes.RateForCountry("")
+	if err != nil {
+		return nil, nil, err
+	}
+	addVAT := !s.taxes.PricesIncludeVAT()
+
+	pages := []page{{Loc: site + "/"}}
+	var categories []models.Category
+	if err := db.Select("slug", "updated_at").Order("path ASC").Find(&categories).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	for _, category := range categories {
+		pages = append(pages, page{Loc: site + "/categories/" + category.Slug, LastMod: category.UpdatedAt})
+	}
+	var brands []models.Brand
+	if err := db.Select("slug", "updated_at").Order("name ASC").Find(&brands).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load brands: %w", err)
+	}
+	for _, brand := range brands {
+		pages = append(pages, page{Loc: site + "/brands/" + brand.Slug, LastMod: brand.UpdatedAt})
+	}
+
+	var items []item
+	var batch []models.Product
+	result := db.Preload("Brand").
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("is_primary DESC, id ASC") }).
+		Preload("Variants", "is_active = ?", true).
+		Preload("Variants.Images", func(db *gorm.DB) *gorm.DB { return db.Order("is_primary DESC, id ASC") }).
+		Where("is_active = ?", true).
+		Scopes(catalog.Published("products", now)).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			stock, err := availableStock(db, batch)
+			if err != nil {
+				return err
+			}
+			for _, product := range batch {
+				pages = append(pages, page{Loc: fmt.Sprintf("%s/products/%d", site, product.ID), LastMod: product.UpdatedAt})
+				if product.IsGiftCard {
+					continue
+				}
+				for _, variant := range product.Variants {
+					price := variant.BasePrice
+					if addVAT && product.IsVAT {
+						price = math.Round(price*(1+vatRate/100)*100) / 100
+					}
+					items = append(items, s.newItem(&product, &variant, price, stock[variant.ID]))
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return nil, nil, fmt.Errorf("failed to load products: %w", result.Error)
+	}
+	return items, pages, nil
+}
+
+// availableStock returns the stock that can be sold of each variant of
+// products: the active inventory batches less reservations, or the variant's
+// own quantity when it is not tracked by batch
+func availableStock(db *gorm.DB, products []models.Product) (map[uint]int, error) {
+	stock := map[uint]int{}
+	var variantIDs []uint
+	for _, product := range products {
+		for _, variant := range product.Variants {
+			stock[variant.ID] = variant.QuantityInStock
+			variantIDs = append(variantIDs, variant.ID)
+		}
+	}
+	if len(variantIDs) == 0 {
+		return stock, nil
+	}
+	var rows []struct {
+		ProductVariantID uint
+		Available        int
+	}
+	if err := db.Model(&models.InventoryItem{}).
+		Select("product_variant_id, COALESCE(SUM(CASE WHEN status = 'active' THEN quantity - reserved ELSE 0 END), 0) AS available").
+		Where("product_variant_id IN ?", variantIDs).
+		Group("product_variant_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load inventory: %w", err)
+	}
+	for _, row := range rows {
+		stock[row.ProductVariantID] = max(row.Available, 0)
+	}
+	return stock, nil
+}
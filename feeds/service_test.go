@@ -0,0 +1,99 @@
+package feeds
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Category{}, &models.Brand{}, &models.Product{}, &models.ProductVariant{},
+		&models.ProductImage{}, &models.Warehouse{}, &models.InventoryItem{}, &models.TaxRate{}))
+	return db
+}
+
+type recordingPublisher struct {
+	names []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, file *File) error {
+	p.names = append(p.names, file.Name)
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&models.Category{Name: "Spices", Slug: "spices", Path: "/1/"}).Error)
+	brand := models.Brand{Name: "Atlas", Slug: "atlas"}
+	require.NoError(t, db.Create(&brand).Error)
+
+	product := models.Product{Name: "Harissa", Description: "Hot chilli paste", IsActive: true, IsVAT: true, BrandID: &brand.ID}
+	require.NoError(t, db.Create(&product).Error)
+	jar := models.ProductVariant{ProductID: product.ID, Name: "200g", SKU: "HAR-200", Barcode: "5012345678900", BasePrice: 2.5, IsActive: true, QuantityInStock: 4}
+	tin := models.ProductVariant{ProductID: product.ID, Name: "1kg", SKU: "HAR-1000", BasePrice: 10, IsActive: true, QuantityInStock: 9}
+	require.NoError(t, db.Create(&[]*models.ProductVariant{&jar, &tin}).Error)
+	require.NoError(t, db.Create(&models.ProductImage{ProductID: &product.ID, URL: "file-1", IsPrimary: true}).Error)
+	// The tin is tracked by batch and its only batch is reserved
+	require.NoError(t, db.Create(&models.InventoryItem{ProductVariantID: tin.ID, WarehouseID: 1, Quantity: 3, Reserved: 3, Status: "active"}).Error)
+
+	later := time.Now().Add(time.Hour)
+	upcoming := models.Product{Name: "Upcoming", IsActive: true, PublishAt: &later}
+	require.NoError(t, db.Create(&upcoming).Error)
+	require.NoError(t, db.Create(&models.ProductVariant{ProductID: upcoming.ID, Name: "1", SKU: "UP-1", BasePrice: 1, IsActive: true}).Error)
+	giftCard := models.Product{Name: "Gift card", IsActive: true, IsGiftCard: true}
+	require.NoError(t, db.Create(&giftCard).Error)
+	require.NoError(t, db.Create(&models.ProductVariant{ProductID: giftCard.ID, Name: "£25", SKU: "GC-25", BasePrice: 25, IsActive: true}).Error)
+
+	publisher := &recordingPublisher{}
+	service := NewService(db, &cfg.FeedConfig{SiteURL: "https://shop.test/", APIURL: "https://api.shop.test/api/v1", Currency: "GBP"},
+		tax.NewTaxService(db, &cfg.TaxConfig{DefaultCountry: "GB", DefaultRate: 20}), publisher)
+	require.NoError(t, service.Generate(context.Background()))
+	assert.ElementsMatch(t, []string{Sitemap, MerchantXML, MerchantCSV}, publisher.names)
+
+	sitemap, err := service.Get(context.Background(), Sitemap)
+	require.NoError(t, err)
+	body := string(sitemap.Data)
+	assert.Contains(t, body, "<loc>https://shop.test/</loc>")
+	assert.Contains(t, body, "<loc>https://shop.test/categories/spices</loc>")
+	assert.Contains(t, body, "<loc>https://shop.test/brands/atlas</loc>")
+	assert.Contains(t, body, "<loc>https://shop.test/products/1</loc>")
+	assert.Contains(t, body, "<loc>https://shop.test/products/3</loc>", "gift cards have product pages")
+	assert.NotContains(t, body, "/products/2<", "products before their launch are left out")
+
+	merchant, err := service.Get(context.Background(), MerchantXML)
+	require.NoError(t, err)
+	body = string(merchant.Data)
+	assert.Contains(t, body, `xmlns:g="http://base.google.com/ns/1.0"`)
+	assert.Contains(t, body, "<g:id>HAR-200</g:id>")
+	assert.Contains(t, body, "<g:title>Harissa - 200g</g:title>")
+	assert.Contains(t, body, "<g:price>3.00 GBP</g:price>", "VAT is added to net prices")
+	assert.Contains(t, body, "<g:image_link>https://api.shop.test/api/v1/file/preview/file-1</g:image_link>")
+	assert.Contains(t, body, "<g:brand>Atlas</g:brand>")
+	assert.Contains(t, body, "<g:gtin>5012345678900</g:gtin>")
+	assert.NotContains(t, body, "UP-1")
+	assert.NotContains(t, body, "GC-25", "gift cards are not listed in the merchant feed")
+
+	csv, err := service.Get(context.Background(), MerchantCSV)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(csv.Data)), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "id,item_group_id,title"))
+	assert.Contains(t, lines[2], "HAR-1000")
+	assert.Contains(t, lines[2], "out_of_stock", "reserved batches are not available")
+	assert.Contains(t, lines[2], "12.00 GBP")
+
+	_, err = service.Get(context.Background(), "missing.xml")
+	assert.ErrorIs(t, err, ErrUnknownFeed)
+}
@@ -0,0 +1,54 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log/slog"
+	"time"
+)
+
+// maxSitemapURLs is the most URLs a sitemap file may list
+const maxSitemapURLs = 50000
+
+// page is a storefront URL listed in the sitemap
+type page struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// buildSitemap renders pages as a sitemap. Pages past the sitemap limit are
+// left out.
+func buildSitemap(pages []page) ([]byte, error) {
+	if len(pages) > maxSitemapURLs {
+		slog.Warn("sitemap truncated", "component", "feeds", "urls", len(pages), "limit", maxSitemapURLs)
+		pages = pages[:maxSitemapURLs]
+	}
+	set := urlSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: make([]sitemapURL, 0, len(pages))}
+	for _, p := range pages {
+		entry := sitemapURL{Loc: p.Loc}
+		if !p.LastMod.IsZero() {
+			entry.LastMod = p.LastMod.UTC().Format("2006-01-02")
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(set); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,219 @@
+// Package files tracks the files uploaded to storage backends and deletes
+// those nothing references any more. Deleting an image or attachment only
+// deletes its row; the cleanup job then marks every tracked file a product
+// image, review image, attachment or document still references, and deletes
+// the unmarked ones from storage. Files of soft-deleted rows are kept for a
+// retention period so the rows can be restored.
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// What files are uploaded for
+const (
+	SourceProductImage     = "product_image"
+	SourceReviewImage      = "review_image"
+	SourceTicketAttachment = "ticket_attachment"
+)
+
+// reference is a column files are referenced by, by key or by URL
+type reference struct {
+	table  string
+	column string
+	byURL  bool
+}
+
+// references are the columns that keep a file from being deleted
+var references = []reference{
+	{"product_images", "url", false},
+	{"product_images", "thumbnail_key", false},
+	{"product_images", "medium_key", false},
+	{"product_images", "large_key", false},
+	{"review_images", "url", true},
+	{"ticket_attachments", "file_url", true},
+	{"dispute_attachments", "file_url", true},
+	{"abuse_report_attachments", "file_url", true},
+	{"order_message_attachments", "file_url", true},
+	{"in_documents", "s3_key", false},
+	{"in_documents", "url", true},
+}
+
+// Record tracks a file stored in a backend. Recording a file twice is a no-op.
+func Record(db *gorm.DB, backend, key, url, source string) error {
+	file := models.StoredFile{Backend: backend, Key: key, URL: url, Source: source}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&file).Error; err != nil {
+		return fmt.Errorf("failed to record stored file: %w", err)
+	}
+	return nil
+}
+
+// tracked records the files stored through a backend
+type tracked struct {
+	storage.Storage
+	db     *gorm.DB
+	source string
+}
+
+// Track returns store, recording the files put in it as uploaded for source
+func Track(db *gorm.DB, store storage.Storage, source string) storage.Storage {
+	return &tracked{Storage: store, db: db, source: source}
+}
+
+// Put implements storage.Storage. A file that cannot be recorded is deleted
+// again, so that no file is left untracked.
+func (t *tracked) Put(ctx context.Context, name, contentType string, content io.Reader) (string, error) {
+	key, err := t.Storage.Put(ctx, name, contentType, content)
+	if err != nil {
+		return "", err
+	}
+	if err := Record(t.db.WithContext(ctx), t.Name(), key, t.URL(key), t.source); err != nil {
+		return "", errors.Join(err, t.Storage.Delete(ctx, key))
+	}
+	return key, nil
+}
+
+// Delete implements storage.Storage
+func (t *tracked) Delete(ctx context.Context, key string) error {
+	if err := t.Storage.Delete(ctx, key); err != nil {
+		return err
+	}
+	return t.db.WithContext(ctx).Where("backend = ? AND key = ?", t.Name(), key).Delete(&models.StoredFile{}).Error
+}
+
+// Result counts what a cleanup did
+type Result struct {
+	Marked  int64 `json:"marked"`  // references found
+	Deleted int   `json:"deleted"` // unreferenced files deleted
+	Failed  int   `json:"failed"`  // unreferenced files that could not be deleted, retried next time
+}
+
+// Service deletes unreferenced files
+type Service struct {
+	db        *gorm.DB
+	stores    map[string]storage.Storage
+	grace     time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewService creates the file cleanup service deleting files from stores.
+// Files of other backends are left alone.
+func NewService(db *gorm.DB, stores []storage.Storage, config *cfg.FileCleanupConfig) *Service {
+	byName := make(map[string]storage.Storage, len(stores))
+	for _, store := range stores {
+		byName[store.Name()] = store
+	}
+	return &Service{
+		db:        db,
+		stores:    byName,
+		grace:     time.Duration(config.GraceHours) * time.Hour,
+		retention: time.Duration(config.RetentionDays) * 24 * time.Hour,
+		now:       time.Now,
+	}
+}
+
+// Cleanup marks the files still referenced, then deletes from storage the
+// files older than the grace period that were not marked
+func (s *Service) Cleanup(ctx context.Context) (Result, error) {
+	db := s.db.WithContext(ctx)
+	// Marks are compared with the time of the run, so it must survive the
+	// database's timestamp precision
+	now := s.now().Truncate(time.Second)
+	var result Result
+	for _, ref := range references {
+		marked := db.Model(&models.StoredFile{}).
+			Where(referencedBy(ref), now.Add(-s.retention)).
+			Update("marked_at", now)
+		if marked.Error != nil {
+			return result, fmt.Errorf("failed to mark files referenced by %s.%s: %w", ref.table, ref.column, marked.Error)
+		}
+		result.Marked += marked.RowsAffected
+	}
+
+	var orphans []models.StoredFile
+	err := db.Where("created_at < ? AND (marked_at IS NULL OR marked_at < ?)", now.Add(-s.grace), now).
+		FindInBatches(&orphans, 200, func(_ *gorm.DB, _ int) error {
+			for _, file := range orphans {
+				deleted, err := s.delete(ctx, file, now)
+				if err != nil {
+					return err
+				}
+				if deleted {
+					result.Deleted++
+				} else {
+					result.Failed++
+				}
+			}
+			return nil
+		}).Error
+	return result, err
+}
+
+// delete deletes an unreferenced file from storage, then its row. Files that
+// were referenced since they were marked, or whose backend is not configured,
+// are kept.
+func (s *Service) delete(ctx context.Context, file models.StoredFile, now time.Time) (bool, error) {
+	db := s.db.WithContext(ctx)
+	for _, ref := range references {
+		var referenced int64
+		if err := db.Model(&models.StoredFile{}).Where("id = ?", file.ID).
+			Where(referencedBy(ref), now.Add(-s.retention)).Count(&referenced).Error; err != nil {
+			return false, fmt.Errorf("failed to check references to file %d: %w", file.ID, err)
+		}
+		if referenced > 0 {
+			return false, nil
+		}
+	}
+	store, ok := s.stores[file.Backend]
+	if !ok {
+		slog.WarnContext(ctx, "no storage backend to delete file from", "component", "files", "backend", file.Backend, "key", file.Key)
+		return false, nil
+	}
+	if err := store.Delete(ctx, file.Key); err != nil {
+		slog.WarnContext(ctx, "failed to delete file", "component", "files", "backend", file.Backend, "key", file.Key, "error", err)
+		return false, nil
+	}
+	if err := db.Delete(&file).Error; err != nil {
+		return false, fmt.Errorf("failed to delete file %d: %w", file.ID, err)
+	}
+	return true, nil
+}
+
+// referencedBy is a condition on stored_files matching the files a row of
+// ref's table references. It takes the time before which soft-deleted rows
+// no longer count.
+func referencedBy(ref reference) string {
+	field := "stored_files.key"
+	if ref.byURL {
+		field = "stored_files.url"
+	}
+	return fmt.Sprintf("%[3]s <> '' AND EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.%[2]s = %[3]s AND (%[1]s.deleted_at IS NULL OR %[1]s.deleted_at > ?))",
+		ref.table, ref.column, field)
+}
+
+// StartCleanup deletes unreferenced files each interval until ctx is canceled
+func (s *Service) StartCleanup(ctx context.Context, interval time.Duration) {
+	for worker.Sleep(ctx, interval) {
+		result, err := s.Cleanup(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to clean up files", "component", "files", "error", err)
+			continue
+		}
+		if result.Deleted > 0 || result.Failed > 0 {
+			slog.InfoContext(ctx, "cleaned up unreferenced files", "component", "files", "deleted", result.Deleted, "failed", result.Failed)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeStorage records the files deleted from it
+type fakeStorage struct {
+	deleted []string
+	fail    map[string]bool
+}
+
+func (s *fakeStorage) Name() string { return "appwrite" }
+
+func (s *fakeStorage) Put(_ context.Context, name, _ string, _ io.Reader) (string, error) {
+	return "id-" + name, nil
+}
+
+func (s *fakeStorage) Delete(_ context.Context, key string) error {
+	if s.fail[key] {
+		return errors.New("storage unavailable")
+	}
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func (s *fakeStorage) URL(key string) string { return "/file/preview/" + key }
+
+func (s *fakeStorage) SignedURL(key string, _ time.Duration) (string, error) { return s.URL(key), nil }
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.StoredFile{}, &models.ProductImage{}, &models.ReviewImage{},
+		&models.TicketAttachment{}, &models.DisputeAttachment{}, &models.AbuseReportAttachment{},
+		&models.OrderMessageAttachment{}, &models.InDocument{}))
+	return db
+}
+
+func TestCleanupDeletesUnreferencedFiles(t *testing.T) {
+	db := setup(t)
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-48 * time.Hour)
+	for _, file := range []models.StoredFile{
+		{Backend: "appwrite", Key: "live", URL: "/file/preview/live", CreatedAt: old},
+		{Backend: "appwrite", Key: "recently-deleted-thumb", URL: "/file/preview/recently-deleted-thumb", CreatedAt: old},
+		{Backend: "appwrite", Key: "long-deleted", URL: "/file/preview/long-deleted", CreatedAt: old},
+		{Backend: "appwrite", Key: "attached", URL: "/file/preview/attached", CreatedAt: old},
+		{Backend: "appwrite", Key: "unused", URL: "/file/preview/unused", CreatedAt: old},
+		{Backend: "appwrite", Key: "unreachable", URL: "/file/preview/unreachable", CreatedAt: old},
+		{Backend: "appwrite", Key: "just-uploaded", URL: "/file/preview/just-uploaded", CreatedAt: now.Add(-time.Hour)},
+		{Backend: "gcs", Key: "images/unused.png", URL: "https://storage.googleapis.com/b/images/unused.png", CreatedAt: old},
+	} {
+		require.NoError(t, db.Create(&file).Error)
+	}
+
+	require.NoError(t, db.Create(&models.ProductImage{URL: "live"}).Error)
+	recent := models.ProductImage{URL: "https://example.com/a.png", ImageKeys: models.ImageKeys{ThumbnailKey: "recently-deleted-thumb"}}
+	require.NoError(t, db.Create(&recent).Error)
+	require.NoError(t, db.Model(&recent).Update("deleted_at", now.Add(-2*24*time.Hour)).Error)
+	review := models.ReviewImage{URL: "/file/preview/long-deleted"}
+	require.NoError(t, db.Create(&review).Error)
+	require.NoError(t, db.Model(&review).Update("deleted_at", now.Add(-40*24*time.Hour)).Error)
+	require.NoError(t, db.Create(&models.TicketAttachment{FileName: "receipt.pdf", FileURL: "/file/preview/attached"}).Error)
+
+	store := &fakeStorage{fail: map[string]bool{"unreachable": true}}
+	s := NewService(db, []storage.Storage{store}, &cfg.FileCleanupConfig{GraceHours: 24, RetentionDays: 30})
+	s.now = func() time.Time { return now }
+
+	result, err := s.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"long-deleted", "unused"}, store.deleted)
+	assert.Equal(t, 2, result.Deleted)
+	assert.Equal(t, 2, result.Failed, "the unreachable file and the file of a backend not configured are retried")
+	assert.Equal(t, int64(3), result.Marked)
+
+	var kept []string
+	require.NoError(t, db.Model(&models.StoredFile{}).Order("id").Pluck("key", &kept).Error)
+	assert.Equal(t, []string{"live", "recently-deleted-thumb", "attached", "unreachable", "just-uploaded", "images/unused.png"}, kept)
+	var live models.StoredFile
+	require.NoError(t, db.First(&live, "key = ?", "live").Error)
+	require.NotNil(t, live.MarkedAt)
+	assert.True(t, live.MarkedAt.Equal(now))
+}
+
+func TestTrackRecordsStoredFiles(t *testing.T) {
+	db := setup(t)
+	store := Track(db, &fakeStorage{}, SourceProductImage)
+	ctx := context.Background()
+
+	key, err := store.Put(ctx, "crate.png", "image/png", nil)
+	require.NoError(t, err)
+	var file models.StoredFile
+	require.NoError(t, db.First(&file, "key = ?", key).Error)
+	assert.Equal(t, models.StoredFile{ID: file.ID, CreatedAt: file.CreatedAt, Backend: "appwrite", Key: "id-crate.png",
+		URL: "/file/preview/id-crate.png", Source: SourceProductImage}, file)
+	require.NoError(t, Record(db, "appwrite", key, "/file/preview/id-crate.png", SourceReviewImage), "recording twice is a no-op")
+
+	require.NoError(t, store.Delete(ctx, key))
+	var count int64
+	require.NoError(t, db.Model(&models.StoredFile{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
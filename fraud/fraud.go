@@ -0,0 +1,215 @@
+// Package fraud scores payments for fraud when they are created. Velocity
+// rules count the orders an email, IP or card started payments for in the
+// last hour and a mismatch rule compares the billing and shipping countries.
+// Payments scoring past the review threshold are authorized but held for an
+// admin to approve or reject; those past the block threshold are refused.
+package fraud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"gorm.io/gorm"
+)
+
+var ErrAlreadyReviewed = errors.New("fraud check is not awaiting review")
+
+// velocityWindow is the period velocity rules count orders over
+const velocityWindow = time.Hour
+
+// Input is what a payment is scored on
+type Input struct {
+	OrderID         uint
+	UserID          uint
+	Email           string
+	IPAddress       string
+	CardFingerprint string // empty when the card is not known yet
+	BillingCountry  string
+	ShippingCountry string
+	Amount          float64
+}
+
+// Service scores payments and runs the manual review queue
+type Service struct {
+	db            *gorm.DB
+	config        cfg.FraudConfig
+	notifications *notification.Service
+	now           func() time.Time
+}
+
+func NewService(db *gorm.DB, config *cfg.FraudConfig) *Service {
+	s := &Service{db: db, notifications: notification.NewService(db), now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	return s
+}
+
+// InTx returns a service that records checks and notifies admins in tx
+func (s *Service) InTx(tx *gorm.DB) *Service {
+	deferred := *s
+	deferred.db = tx
+	deferred.notifications = notification.NewService(tx)
+	return &deferred
+}
+
+// Check scores a payment and records the check. When checks are disabled the
+// payment is allowed without being recorded. Admins are notified of payments
+// held for review.
+func (s *Service) Check(ctx context.Context, in Input) (*models.FraudCheck, error) {
+	if !s.config.Enabled {
+		return &models.FraudCheck{OrderID: in.OrderID, Decision: models.FraudDecisionAllow}, nil
+	}
+
+	check := &models.FraudCheck{
+		OrderID:         in.OrderID,
+		UserID:          in.UserID,
+		Email:           strings.ToLower(strings.TrimSpace(in.Email)),
+		IPAddress:       in.IPAddress,
+		CardFingerprint: in.CardFingerprint,
+		BillingCountry:  strings.ToUpper(strings.TrimSpace(in.BillingCountry)),
+		ShippingCountry: strings.ToUpper(strings.TrimSpace(in.ShippingCountry)),
+		Amount:          in.Amount,
+		Reasons:         []string{},
+	}
+
+	db := s.db.WithContext(ctx)
+	since := s.now().Add(-velocityWindow)
+	velocity := []struct {
+		column, value, name string
+		limit               int
+	}{
+		{"email", check.Email, "email", s.config.MaxOrdersPerEmail},
+		{"ip_address", check.IPAddress, "IP", s.config.MaxOrdersPerIP},
+		{"card_fingerprint", check.CardFingerprint, "card", s.config.MaxOrdersPerCard},
+	}
+	for _, rule := range velocity {
+		if rule.value == "" || rule.limit <= 0 {
+			continue
+		}
+		var orders int64
+		if err := db.Model(&models.FraudCheck{}).
+			Where(rule.column+" = ? AND order_id <> ? AND created_at >= ?", rule.value, in.OrderID, since).
+			Distinct("order_id").
+			Count(&orders).Error; err != nil {
+			return nil, fmt.Errorf("failed to count %s velocity: %w", rule.name, err)
+		}
+		if int(orders) >= rule.limit {
+			check.Score += s.config.VelocityScore
+			check.Reasons = append(check.Reasons, fmt.Sprintf("%d other orders from this %s in the last hour", orders, rule.name))
+		}
+	}
+	if check.BillingCountry != "" && check.ShippingCountry != "" && check.BillingCountry != check.ShippingCountry {
+		check.Score += s.config.CountryMismatchScore
+		check.Reasons = append(check.Reasons, fmt.Sprintf("billing country %s differs from shipping country %s", check.BillingCountry, check.ShippingCountry))
+	}
+
+	switch {
+	case s.config.BlockScore > 0 && check.Score >= s.config.BlockScore:
+		check.Decision = models.FraudDecisionBlock
+	case check.Score >= s.config.ReviewScore:
+		check.Decision = models.FraudDecisionReview
+		check.ReviewStatus = models.FraudReviewPending
+	default:
+		check.Decision = models.FraudDecisionAllow
+	}
+	if err := db.Create(check).Error; err != nil {
+		return nil, fmt.Errorf("failed to record fraud check: %w", err)
+	}
+
+	if check.Decision != models.FraudDecisionAllow {
+		slog.WarnContext(ctx, "payment flagged by fraud checks", "component", "fraud", "order_id", in.OrderID,
+			"score", check.Score, "decision", check.Decision, "reasons", check.Reasons)
+	}
+	if check.Decision == models.FraudDecisionReview {
+		if _, err := s.notifications.NotifyAdmins(notification.Message{
+			Type:  models.NotificationTypeFraudReview,
+			Title: fmt.Sprintf("Order #%d held for fraud review", in.OrderID),
+			Body:  strings.Join(check.Reasons, "; "),
+			Link:  "/admin/fraud/reviews",
+			Data:  models.JSON{"fraud_check_id": check.ID, "order_id": in.OrderID, "score": check.Score},
+		}); err != nil {
+			slog.WarnContext(ctx, "failed to notify admins of fraud review", "component", "fraud", "order_id", in.OrderID, "error", err)
+		}
+	}
+	return check, nil
+}
+
+// AttachPayment links a check to the payment created after it
+func (s *Service) AttachPayment(ctx context.Context, checkID, paymentID uint) error {
+	return s.db.WithContext(ctx).Model(&models.FraudCheck{}).Where("id = ?", checkID).Update("payment_id", paymentID).Error
+}
+
+// Reviews lists the payments held for review, oldest first, optionally only
+// those in status
+func (s *Service) Reviews(ctx context.Context, status models.FraudReviewStatus, page, limit int) ([]models.FraudCheck, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.FraudCheck{}).Where("decision = ?", models.FraudDecisionReview)
+	if status != "" {
+		query = query.Where("review_status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var checks []models.FraudCheck
+	if err := query.Preload("Order").Preload("Payment").
+		Order("created_at ASC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&checks).Error; err != nil {
+		return nil, 0, err
+	}
+	return checks, total, nil
+}
+
+// Get returns a fraud check with its order and payment
+func (s *Service) Get(ctx context.Context, id uint) (*models.FraudCheck, error) {
+	var check models.FraudCheck
+	if err := s.db.WithContext(ctx).Preload("Order").Preload("Payment").First(&check, id).Error; err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// Resolve records an admin's decision on a held payment. It only records the
+// decision: capturing or voiding the payment is up to the caller.
+func (s *Service) Resolve(ctx context.Context, id, adminID uint, approve bool, note string) (*models.FraudCheck, error) {
+	status := models.FraudReviewRejected
+	if approve {
+		status = models.FraudReviewApproved
+	}
+	now := s.now()
+	result := s.db.WithContext(ctx).Model(&models.FraudCheck{}).
+		Where("id = ? AND review_status = ?", id, models.FraudReviewPending).
+		Updates(map[string]interface{}{
+			"review_status":  status,
+			"reviewed_by_id": adminID,
+			"reviewed_at":    now,
+			"review_note":    note,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAlreadyReviewed
+	}
+	return s.Get(ctx, id)
+}
+
+// CloseForPayment rejects the review still waiting on a payment that was
+// voided without a decision, e.g. because its authorization expired
+func (s *Service) CloseForPayment(ctx context.Context, paymentID uint, note string) error {
+	return s.db.WithContext(ctx).Model(&models.FraudCheck{}).
+		Where("payment_id = ? AND review_status = ?", paymentID, models.FraudReviewPending).
+		Updates(map[string]interface{}{
+			"review_status": models.FraudReviewRejected,
+			"reviewed_at":   s.now(),
+			"review_note":   note,
+		}).Error
+}
@@ -0,0 +1,141 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Address{}, &models.Order{}, &models.Payment{},
+		&models.FraudCheck{}, &models.Notification{}))
+	return db
+}
+
+// setupService holds payments scoring 50 and refuses those scoring 100;
+// velocity rules trip on the third order in an hour
+func setupService(db *gorm.DB) *Service {
+	s := NewService(db, &cfg.FraudConfig{
+		Enabled:              true,
+		ReviewScore:          50,
+		BlockScore:           100,
+		MaxOrdersPerEmail:    2,
+		MaxOrdersPerIP:       2,
+		MaxOrdersPerCard:     2,
+		VelocityScore:        40,
+		CountryMismatchScore: 30,
+	})
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestCheckScoresAndDecides(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	ctx := context.Background()
+	require.NoError(t, db.Create(&models.User{Email: "admin@example.com", UserType: models.Admin, IsActive: true}).Error)
+
+	input := func(orderID uint) Input {
+		return Input{OrderID: orderID, UserID: 1, Email: "Buyer@Example.com", IPAddress: "10.0.0.1",
+			BillingCountry: "gb", ShippingCountry: "GB", Amount: 40}
+	}
+
+	check, err := s.Check(ctx, input(1))
+	require.NoError(t, err)
+	assert.Equal(t, models.FraudDecisionAllow, check.Decision)
+	assert.Equal(t, 0, check.Score)
+	assert.Equal(t, "buyer@example.com", check.Email)
+
+	// A second payment for the same order is not another order
+	_, err = s.Check(ctx, input(1))
+	require.NoError(t, err)
+	check, err = s.Check(ctx, input(2))
+	require.NoError(t, err)
+	assert.Equal(t, models.FraudDecisionAllow, check.Decision)
+
+	// The third order from the same email and IP trips both velocity rules
+	check, err = s.Check(ctx, input(3))
+	require.NoError(t, err)
+	assert.Equal(t, 80, check.Score)
+	assert.Len(t, check.Reasons, 2)
+	assert.Equal(t, models.FraudDecisionReview, check.Decision)
+	assert.Equal(t, models.FraudReviewPending, check.ReviewStatus)
+
+	var notified int64
+	require.NoError(t, db.Model(&models.Notification{}).Where("type = ?", models.NotificationTypeFraudReview).Count(&notified).Error)
+	assert.EqualValues(t, 1, notified)
+
+	// Adding a country mismatch takes it past the block score
+	mismatched := input(4)
+	mismatched.ShippingCountry = "FR"
+	check, err = s.Check(ctx, mismatched)
+	require.NoError(t, err)
+	assert.Equal(t, 110, check.Score)
+	assert.Equal(t, models.FraudDecisionBlock, check.Decision)
+
+	// Checks older than an hour are not counted
+	require.NoError(t, db.Model(&models.FraudCheck{}).Where("1 = 1").Update("created_at", testNow.Add(-2*time.Hour)).Error)
+	check, err = s.Check(ctx, input(5))
+	require.NoError(t, err)
+	assert.Equal(t, models.FraudDecisionAllow, check.Decision)
+}
+
+func TestCheckDisabled(t *testing.T) {
+	db := setupTestDB(t)
+	s := NewService(db, &cfg.FraudConfig{Enabled: false, ReviewScore: 0})
+
+	check, err := s.Check(context.Background(), Input{OrderID: 1, BillingCountry: "GB", ShippingCountry: "FR"})
+	require.NoError(t, err)
+	assert.Equal(t, models.FraudDecisionAllow, check.Decision)
+
+	var count int64
+	require.NoError(t, db.Model(&models.FraudCheck{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestResolve(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	ctx := context.Background()
+
+	check, err := s.Check(ctx, Input{OrderID: 1, Email: "a@example.com", BillingCountry: "GB", ShippingCountry: "DZ"})
+	require.NoError(t, err)
+	assert.Equal(t, models.FraudDecisionAllow, check.Decision)
+	_, err = s.Resolve(ctx, check.ID, 9, true, "")
+	assert.ErrorIs(t, err, ErrAlreadyReviewed)
+
+	s.config.ReviewScore = 30
+	check, err = s.Check(ctx, Input{OrderID: 2, Email: "b@example.com", BillingCountry: "GB", ShippingCountry: "DZ"})
+	require.NoError(t, err)
+	require.Equal(t, models.FraudDecisionReview, check.Decision)
+
+	pending, total, err := s.Reviews(ctx, models.FraudReviewPending, 1, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, pending, 1)
+	assert.Equal(t, check.ID, pending[0].ID)
+
+	resolved, err := s.Resolve(ctx, check.ID, 9, false, "stolen card")
+	require.NoError(t, err)
+	assert.Equal(t, models.FraudReviewRejected, resolved.ReviewStatus)
+	assert.Equal(t, "stolen card", resolved.ReviewNote)
+	require.NotNil(t, resolved.ReviewedByID)
+	assert.EqualValues(t, 9, *resolved.ReviewedByID)
+
+	_, err = s.Resolve(ctx, check.ID, 9, true, "")
+	assert.ErrorIs(t, err, ErrAlreadyReviewed)
+	_, total, err = s.Reviews(ctx, models.FraudReviewPending, 1, 20)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
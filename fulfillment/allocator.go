@@ -0,0 +1,495 @@
+// Package fulfillment decides which warehouses fulfil an order. When an order
+// is confirmed each line is allocated from warehouse stock by the configured
+// strategy, reserving it, and the order is split into one shipment per
+// warehouse. Shipping a shipment consumes its reserved stock and cancelling
+// the order gives it back. Every step is recorded as a stock movement.
+//
+// Bundles have no stock of their own: a bundle line is allocated from the
+// stock of each of its components, so its shipment, and the stock movements,
+// are per component.
+package fulfillment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Strategy chooses the stock an order line is allocated from
+type Strategy string
+
+const (
+	// StrategyNearest prefers warehouses closest to the shipping address,
+	// judged by postcode district, postcode area, city and country
+	StrategyNearest Strategy = "nearest"
+	// StrategyMostStock prefers the warehouses holding the most of the variant
+	StrategyMostStock Strategy = "most_stock"
+	// StrategyFIFO takes the oldest batches first, wherever they are
+	StrategyFIFO Strategy = "fifo"
+	// StrategyFEFO takes the batches that expire first, wherever they are,
+	// so perishable stock is sold before it expires
+	StrategyFEFO Strategy = "fefo"
+)
+
+// Stock movement types recorded by the allocator
+const (
+	MovementReserved = "reserved"
+	MovementReleased = "released"
+	MovementSold     = "sold"
+)
+
+// ErrSplitRequired is returned when split shipments are disabled and no single
+// warehouse can fulfil the whole order
+var ErrSplitRequired = errors.New("no single warehouse can fulfil the whole order")
+
+// InsufficientStockError is returned when there is not enough available stock
+// for an order line
+type InsufficientStockError struct {
+	ProductVariantID uint
+	BundleVariantID  uint // the bundle ProductVariantID is a component of, if any
+	Requested        int
+	Available        int
+}
+
+func (e *InsufficientStockError) Error() string {
+	if e.BundleVariantID != 0 {
+		return fmt.Sprintf("insufficient stock for product variant %d of bundle %d: %d requested, %d available", e.ProductVariantID, e.BundleVariantID, e.Requested, e.Available)
+	}
+	return fmt.Sprintf("insufficient stock for product variant %d: %d requested, %d available", e.ProductVariantID, e.Requested, e.Available)
+}
+
+// Allocator allocates orders to warehouse stock
+type Allocator struct {
+	strategy       Strategy
+	splitShipments bool
+	now            func() time.Time
+}
+
+// NewAllocator creates an allocator from config. An unknown strategy falls
+// back to most_stock.
+func NewAllocator(config *cfg.FulfillmentConfig) *Allocator {
+	a := &Allocator{strategy: StrategyMostStock, splitShipments: true, now: time.Now}
+	if config != nil {
+		switch strategy := Strategy(strings.ToLower(config.Strategy)); strategy {
+		case StrategyNearest, StrategyMostStock, StrategyFIFO, StrategyFEFO:
+			a.strategy = strategy
+		}
+		a.splitShipments = config.SplitShipments
+	}
+	return a
+}
+
+// Strategy returns the strategy the allocator uses
+func (a *Allocator) Strategy() Strategy {
+	return a.strategy
+}
+
+// need is the stock of one variant an order line takes: its own variant's,
+// or that of each component of a bundle
+type need struct {
+	line      *models.OrderItem
+	variantID uint
+	quantity  int
+}
+
+// bundled reports whether the need is for a component of the line's bundle
+func (n *need) bundled() bool {
+	return n.variantID != n.line.ProductVariantID
+}
+
+// insufficient describes the stock missing for the need
+func (n *need) insufficient(requested, available int) *InsufficientStockError {
+	err := &InsufficientStockError{ProductVariantID: n.variantID, Requested: requested, Available: available}
+	if n.bundled() {
+		err.BundleVariantID = n.line.ProductVariantID
+	}
+	return err
+}
+
+// candidate is a batch stock can be allocated from
+type candidate struct {
+	item      *models.InventoryItem
+	warehouse *models.Warehouse
+	available int
+}
+
+// Allocate reserves stock for every active line of order in tx and creates
+// its shipments. Orders that already have shipments are left as they are.
+// userID is recorded on the stock movements and may be nil.
+func (a *Allocator) Allocate(tx *gorm.DB, order *models.Order, userID *uint) ([]models.Shipment, error) {
+	var existing []models.Shipment
+	if err := tx.Where("order_id = ?", order.ID).Preload("Allocations").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return existing, nil
+	}
+
+	var lines []models.OrderItem
+	if err := tx.Where("order_id = ? AND status = ?", order.ID, "active").Order("id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	var address models.Address
+	if err := tx.First(&address, order.ShippingAddressID).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	needs, err := lineNeeds(tx, lines)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := a.loadCandidates(tx, needs)
+	if err != nil {
+		return nil, err
+	}
+
+	byVariant := make(map[uint][]*candidate)
+	for _, c := range candidates {
+		byVariant[c.item.ProductVariantID] = append(byVariant[c.item.ProductVariantID], c)
+	}
+	if err := checkAvailable(needs, byVariant); err != nil {
+		return nil, err
+	}
+	if !a.splitShipments {
+		if byVariant, err = a.singleWarehouse(needs, candidates, &address); err != nil {
+			return nil, err
+		}
+	}
+
+	shipments := make(map[uint]*models.Shipment)
+	var shipmentOrder []uint
+	for _, n := range needs {
+		options := byVariant[n.variantID]
+		a.sortCandidates(options, &address, warehouseTotals(options))
+
+		remaining := n.quantity
+		for _, c := range options {
+			if remaining == 0 {
+				break
+			}
+			if c.available == 0 {
+				continue
+			}
+			take := min(c.available, remaining)
+			shipment := shipments[c.warehouse.ID]
+			if shipment == nil {
+				shipment = &models.Shipment{OrderID: order.ID, WarehouseID: c.warehouse.ID, Status: models.ShipmentStatusPending}
+				if err := tx.Create(shipment).Error; err != nil {
+					return nil, err
+				}
+				shipments[c.warehouse.ID] = shipment
+				shipmentOrder = append(shipmentOrder, c.warehouse.ID)
+			}
+			allocation, err := a.reserve(tx, order, n, shipment, c, take, userID)
+			if err != nil {
+				return nil, err
+			}
+			shipment.Allocations = append(shipment.Allocations, *allocation)
+			remaining -= take
+		}
+		if remaining > 0 {
+			return nil, n.insufficient(n.quantity, n.quantity-remaining)
+		}
+	}
+
+	result := make([]models.Shipment, 0, len(shipmentOrder))
+	for _, warehouseID := range shipmentOrder {
+		shipment := shipments[warehouseID]
+		shipment.Warehouse = nil
+		result = append(result, *shipment)
+	}
+	return result, nil
+}
+
+// lineNeeds returns the stock each line takes, in line order, with the
+// components of a bundle line in their order
+func lineNeeds(tx *gorm.DB, lines []models.OrderItem) ([]*need, error) {
+	variantIDs := make([]uint, 0, len(lines))
+	for _, line := range lines {
+		variantIDs = append(variantIDs, line.ProductVariantID)
+	}
+	bundles, err := stock.BundleComponents(tx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	needs := make([]*need, 0, len(lines))
+	for i := range lines {
+		line := &lines[i]
+		components, ok := bundles[line.ProductVariantID]
+		if !ok {
+			needs = append(needs, &need{line: line, variantID: line.ProductVariantID, quantity: line.Quantity})
+			continue
+		}
+		for _, component := range components {
+			needs = append(needs, &need{line: line, variantID: component.ComponentVariantID, quantity: line.Quantity * component.Quantity})
+		}
+	}
+	return needs, nil
+}
+
+// loadCandidates locks the stock of the variants needed and returns the
+// batches that can be allocated from: active, unexpired, in an active
+// warehouse and not fully reserved
+func (a *Allocator) loadCandidates(tx *gorm.DB, needs []*need) ([]*candidate, error) {
+	variantIDs := make([]uint, 0, len(needs))
+	for _, n := range needs {
+		variantIDs = append(variantIDs, n.variantID)
+	}
+
+	var items []models.InventoryItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_variant_id IN ? AND status = ? AND quantity > reserved", variantIDs, stock.StatusActive).
+		Order("id").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	warehouseIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		warehouseIDs = append(warehouseIDs, item.WarehouseID)
+	}
+	var warehouses []models.Warehouse
+	if err := tx.Preload("Address").Where("id IN ? AND is_active = ?", warehouseIDs, true).Find(&warehouses).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Warehouse, len(warehouses))
+	for i := range warehouses {
+		byID[warehouses[i].ID] = &warehouses[i]
+	}
+
+	now := a.now()
+	var candidates []*candidate
+	for i := range items {
+		item := &items[i]
+		warehouse := byID[item.WarehouseID]
+		if warehouse == nil || (item.ExpiryDate != nil && !item.ExpiryDate.After(now)) {
+			continue
+		}
+		candidates = append(candidates, &candidate{item: item, warehouse: warehouse, available: item.Quantity - item.Reserved})
+	}
+	return candidates, nil
+}
+
+// checkAvailable fails with an InsufficientStockError when the stock of a
+// variant across all warehouses is less than the order needs
+func checkAvailable(needs []*need, byVariant map[uint][]*candidate) error {
+	needed := totalNeeded(needs)
+	for _, n := range needs {
+		available := 0
+		for _, c := range byVariant[n.variantID] {
+			available += c.available
+		}
+		if available < needed[n.variantID] {
+			return n.insufficient(needed[n.variantID], available)
+		}
+	}
+	return nil
+}
+
+// totalNeeded sums the needs of each variant
+func totalNeeded(needs []*need) map[uint]int {
+	needed := make(map[uint]int)
+	for _, n := range needs {
+		needed[n.variantID] += n.quantity
+	}
+	return needed
+}
+
+// singleWarehouse keeps only the candidates of the best warehouse that can
+// fulfil every line on its own
+func (a *Allocator) singleWarehouse(needs []*need, candidates []*candidate, to *models.Address) (map[uint][]*candidate, error) {
+	needed := totalNeeded(needs)
+	held := make(map[uint]map[uint]int) // warehouse, variant, available
+	for _, c := range candidates {
+		if held[c.warehouse.ID] == nil {
+			held[c.warehouse.ID] = make(map[uint]int)
+		}
+		held[c.warehouse.ID][c.item.ProductVariantID] += c.available
+	}
+
+	var eligible []*candidate
+	totals := make(map[uint]int)
+	for _, c := range candidates {
+		fits := true
+		for variantID, quantity := range needed {
+			if held[c.warehouse.ID][variantID] < quantity {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			eligible = append(eligible, c)
+			totals[c.warehouse.ID] += c.available
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, ErrSplitRequired
+	}
+
+	a.sortCandidates(eligible, to, totals)
+	chosen := eligible[0].warehouse.ID
+	byVariant := make(map[uint][]*candidate)
+	for _, c := range eligible {
+		if c.warehouse.ID == chosen {
+			byVariant[c.item.ProductVariantID] = append(byVariant[c.item.ProductVariantID], c)
+		}
+	}
+	return byVariant, nil
+}
+
+// sortCandidates orders candidates by the strategy. Ties, and batches within
+// a warehouse, go to the warehouse with the most stock and then to the batch
+// that expires first, or was received first.
+func (a *Allocator) sortCandidates(candidates []*candidate, to *models.Address, totals map[uint]int) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		x, y := candidates[i], candidates[j]
+		switch a.strategy {
+		case StrategyFEFO:
+			if x.warehouse.ID != y.warehouse.ID {
+				return batchBefore(x.item, y.item)
+			}
+		case StrategyFIFO:
+			if !x.item.CreatedAt.Equal(y.item.CreatedAt) {
+				return x.item.CreatedAt.Before(y.item.CreatedAt)
+			}
+		case StrategyNearest:
+			if dx, dy := Distance(&x.warehouse.Address, to), Distance(&y.warehouse.Address, to); dx != dy {
+				return dx < dy
+			}
+		}
+		if x.warehouse.ID != y.warehouse.ID {
+			if totals[x.warehouse.ID] != totals[y.warehouse.ID] {
+				return totals[x.warehouse.ID] > totals[y.warehouse.ID]
+			}
+			return x.warehouse.ID < y.warehouse.ID
+		}
+		return batchBefore(x.item, y.item)
+	})
+}
+
+// batchBefore orders batches of a warehouse: earliest expiry first, batches
+// without one last, then oldest first
+func batchBefore(x, y *models.InventoryItem) bool {
+	switch {
+	case x.ExpiryDate != nil && y.ExpiryDate == nil:
+		return true
+	case x.ExpiryDate == nil && y.ExpiryDate != nil:
+		return false
+	case x.ExpiryDate != nil && !x.ExpiryDate.Equal(*y.ExpiryDate):
+		return x.ExpiryDate.Before(*y.ExpiryDate)
+	}
+	if !x.CreatedAt.Equal(y.CreatedAt) {
+		return x.CreatedAt.Before(y.CreatedAt)
+	}
+	return x.ID < y.ID
+}
+
+func warehouseTotals(candidates []*candidate) map[uint]int {
+	totals := make(map[uint]int)
+	for _, c := range candidates {
+		totals[c.warehouse.ID] += c.available
+	}
+	return totals
+}
+
+// Distance ranks how far a warehouse address is from a shipping address
+// without geocoding: 0 in the same postcode district, 1 in the same postcode
+// area, 2 in the same city, 3 in the same country and 4 otherwise
+func Distance(from, to *models.Address) int {
+	if from == nil || to == nil {
+		return 4
+	}
+	if !strings.EqualFold(strings.TrimSpace(from.Country), strings.TrimSpace(to.Country)) {
+		return 4
+	}
+	fromDistrict, toDistrict := postcodeDistrict(from.PostalCode), postcodeDistrict(to.PostalCode)
+	switch {
+	case fromDistrict != "" && fromDistrict == toDistrict:
+		return 0
+	case fromDistrict != "" && postcodeArea(fromDistrict) == postcodeArea(toDistrict):
+		return 1
+	case strings.EqualFold(strings.TrimSpace(from.City), strings.TrimSpace(to.City)):
+		return 2
+	}
+	return 3
+}
+
+// postcodeDistrict returns the outward part of a postcode, e.g. "E1" of "E1 6AN"
+func postcodeDistrict(postcode string) string {
+	fields := strings.Fields(strings.ToUpper(postcode))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// postcodeArea returns the leading letters of a postcode district, e.g. "LS" of "LS1"
+func postcodeArea(district string) string {
+	for i, r := range district {
+		if r >= '0' && r <= '9' {
+			return district[:i]
+		}
+	}
+	return district
+}
+
+// reserve allocates quantity of c to the line of n and records the movement
+func (a *Allocator) reserve(tx *gorm.DB, order *models.Order, n *need, shipment *models.Shipment, c *candidate, quantity int, userID *uint) (*models.StockAllocation, error) {
+	line := n.line
+	if err := stock.Reserve(tx, c.item.ID, quantity); err != nil {
+		return nil, err
+	}
+	c.available -= quantity
+	c.item.Reserved += quantity
+
+	allocation := &models.StockAllocation{
+		OrderID:         order.ID,
+		OrderItemID:     line.ID,
+		ShipmentID:      shipment.ID,
+		InventoryItemID: c.item.ID,
+		WarehouseID:     c.warehouse.ID,
+		Quantity:        quantity,
+		Status:          models.AllocationStatusAllocated,
+	}
+	if err := tx.Create(allocation).Error; err != nil {
+		return nil, err
+	}
+	// A bundle line is not one batch, so it is not linked to any
+	if line.InventoryItemID == nil && !n.bundled() {
+		line.InventoryItemID = &c.item.ID
+		if err := tx.Model(&models.OrderItem{}).Where("id = ?", line.ID).Update("inventory_item_id", c.item.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	reason := "Allocated to order " + order.OrderNumber
+	if n.bundled() {
+		reason += fmt.Sprintf(" for bundle variant %d", line.ProductVariantID)
+	}
+	return allocation, recordMovement(tx, c.item.ID, MovementReserved, quantity, reason, order.OrderNumber, userID)
+}
+
+func recordMovement(tx *gorm.DB, inventoryItemID uint, movementType string, quantity int, reason, reference string, userID *uint) error {
+	return tx.Create(&models.StockMovement{
+		InventoryItemID: inventoryItemID,
+		MovementType:    movementType,
+		Quantity:        quantity,
+		Reason:          reason,
+		Reference:       reference,
+		UserID:          userID,
+	}).Error
+}
@@ -0,0 +1,357 @@
+package fulfillment
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Address{},
+		&models.User{},
+		&models.Warehouse{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.BundleComponent{},
+		&models.InventoryItem{},
+		&models.StockMovement{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.PickList{},
+		&models.Shipment{},
+		&models.StockAllocation{},
+		&models.OrderStatusChange{},
+	))
+	return db
+}
+
+func createWarehouse(t *testing.T, db *gorm.DB, city, postcode string) models.Warehouse {
+	warehouse := models.Warehouse{
+		Name:    city + " Warehouse",
+		Code:    "WH-" + city,
+		Address: models.Address{StreetAddress1: "1 Dock Road", City: city, PostalCode: postcode, Country: "GB"},
+	}
+	require.NoError(t, db.Create(&warehouse).Error)
+	return warehouse
+}
+
+func createStock(t *testing.T, db *gorm.DB, variantID uint, warehouse models.Warehouse, quantity int, expiry *time.Time) models.InventoryItem {
+	item := models.InventoryItem{
+		ProductVariantID: variantID,
+		WarehouseID:      warehouse.ID,
+		Quantity:         quantity,
+		ExpiryDate:       expiry,
+		Status:           "active",
+	}
+	require.NoError(t, db.Omit("ProductVariant", "Warehouse").Create(&item).Error)
+	return item
+}
+
+// createOrder creates an order shipping to postcode with a line per variant
+// and quantity pair
+func createOrder(t *testing.T, db *gorm.DB, city, postcode string, lines ...int) models.Order {
+	address := models.Address{StreetAddress1: "2 Home Street", City: city, PostalCode: postcode, Country: "GB"}
+	require.NoError(t, db.Create(&address).Error)
+
+	var count int64
+	db.Model(&models.Order{}).Count(&count)
+	order := models.Order{
+		OrderNumber:       fmt.Sprintf("ORD-%d", count+1),
+		UserID:            1,
+		ShippingAddressID: address.ID,
+		Status:            models.OrderStatusPending,
+	}
+	require.NoError(t, db.Create(&order).Error)
+	for i := 0; i+1 < len(lines); i += 2 {
+		item := models.OrderItem{OrderID: order.ID, ProductVariantID: uint(lines[i]), Quantity: lines[i+1], Status: "active"}
+		require.NoError(t, db.Omit("ProductVariant", "Product", "InventoryItem").Create(&item).Error)
+	}
+	return order
+}
+
+func reserved(t *testing.T, db *gorm.DB, itemID uint) (int, int) {
+	var item models.InventoryItem
+	require.NoError(t, db.First(&item, itemID).Error)
+	return item.Quantity, item.Reserved
+}
+
+func TestAllocateMostStock(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	leeds := createWarehouse(t, db, "Leeds", "LS1 1AA")
+	small := createStock(t, db, 1, london, 5, nil)
+	large := createStock(t, db, 1, leeds, 50, nil)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "most_stock", SplitShipments: true})
+	order := createOrder(t, db, "London", "E1 6AN", 1, 8)
+	shipments, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+
+	require.Len(t, shipments, 1)
+	assert.Equal(t, leeds.ID, shipments[0].WarehouseID)
+	require.Len(t, shipments[0].Allocations, 1)
+	assert.Equal(t, 8, shipments[0].Allocations[0].Quantity)
+
+	_, r := reserved(t, db, large.ID)
+	assert.Equal(t, 8, r)
+	_, r = reserved(t, db, small.ID)
+	assert.Equal(t, 0, r)
+
+	var movement models.StockMovement
+	require.NoError(t, db.Where("inventory_item_id = ?", large.ID).First(&movement).Error)
+	assert.Equal(t, MovementReserved, movement.MovementType)
+	assert.Equal(t, order.OrderNumber, movement.Reference)
+
+	var item models.OrderItem
+	require.NoError(t, db.Where("order_id = ?", order.ID).First(&item).Error)
+	require.NotNil(t, item.InventoryItemID)
+	assert.Equal(t, large.ID, *item.InventoryItemID)
+
+	// Allocating again leaves the order as it is
+	again, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	_, r = reserved(t, db, large.ID)
+	assert.Equal(t, 8, r)
+}
+
+func TestAllocateNearestSplitsShipments(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	leeds := createWarehouse(t, db, "Leeds", "LS1 1AA")
+	createStock(t, db, 1, london, 3, nil)
+	createStock(t, db, 1, leeds, 50, nil)
+	createStock(t, db, 2, leeds, 10, nil)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "nearest", SplitShipments: true})
+	order := createOrder(t, db, "London", "E1 6AN", 1, 5, 2, 1)
+	shipments, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+
+	// The nearest warehouse is used first and the rest comes from Leeds
+	require.Len(t, shipments, 2)
+	assert.Equal(t, london.ID, shipments[0].WarehouseID)
+	require.Len(t, shipments[0].Allocations, 1)
+	assert.Equal(t, 3, shipments[0].Allocations[0].Quantity)
+	assert.Equal(t, leeds.ID, shipments[1].WarehouseID)
+	require.Len(t, shipments[1].Allocations, 2)
+	assert.Equal(t, 2, shipments[1].Allocations[0].Quantity)
+	assert.Equal(t, 1, shipments[1].Allocations[1].Quantity)
+}
+
+func TestAllocateWithoutSplitShipments(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	leeds := createWarehouse(t, db, "Leeds", "LS1 1AA")
+	createStock(t, db, 1, london, 10, nil)
+	createStock(t, db, 1, leeds, 10, nil)
+	createStock(t, db, 2, leeds, 10, nil)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "nearest", SplitShipments: false})
+
+	// London is nearer but only Leeds holds both variants
+	order := createOrder(t, db, "London", "E1 6AN", 1, 5, 2, 5)
+	shipments, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	assert.Equal(t, leeds.ID, shipments[0].WarehouseID)
+
+	// Neither warehouse holds 15 of the first variant
+	order = createOrder(t, db, "London", "E1 6AN", 1, 15)
+	_, err = allocator.Allocate(db, &order, nil)
+	assert.ErrorIs(t, err, ErrSplitRequired)
+}
+
+func TestAllocateFIFOHonoursExpiry(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	now := time.Now()
+	expired := now.AddDate(0, 0, -1)
+	later := now.AddDate(0, 1, 0)
+	sooner := now.AddDate(0, 0, 7)
+
+	stale := createStock(t, db, 1, london, 10, &expired)
+	laterBatch := createStock(t, db, 1, london, 10, &later)
+	soonerBatch := createStock(t, db, 1, london, 10, &sooner)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "fifo", SplitShipments: true})
+	// Same CreatedAt for the batches so expiry decides
+	require.NoError(t, db.Model(&models.InventoryItem{}).Where("1 = 1").Update("created_at", now.Add(-time.Hour)).Error)
+
+	order := createOrder(t, db, "London", "E1 6AN", 1, 12)
+	_, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+
+	_, r := reserved(t, db, soonerBatch.ID)
+	assert.Equal(t, 10, r)
+	_, r = reserved(t, db, laterBatch.ID)
+	assert.Equal(t, 2, r)
+	_, r = reserved(t, db, stale.ID)
+	assert.Equal(t, 0, r, "expired stock is never allocated")
+}
+
+func TestAllocateFEFOAcrossWarehouses(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	leeds := createWarehouse(t, db, "Leeds", "LS1 1AA")
+	sooner := time.Now().AddDate(0, 0, 5)
+	later := time.Now().AddDate(0, 2, 0)
+	createStock(t, db, 1, london, 50, &later)
+	soonerBatch := createStock(t, db, 1, leeds, 4, &sooner)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "fefo", SplitShipments: true})
+	order := createOrder(t, db, "London", "E1 6AN", 1, 6)
+	shipments, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+
+	// The batch expiring first is used up before the nearer, larger one
+	require.Len(t, shipments, 2)
+	assert.Equal(t, leeds.ID, shipments[0].WarehouseID)
+	_, r := reserved(t, db, soonerBatch.ID)
+	assert.Equal(t, 4, r)
+	assert.Equal(t, 2, shipments[1].Allocations[0].Quantity)
+}
+
+func TestAllocateInsufficientStock(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	createStock(t, db, 1, london, 4, nil)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{})
+	order := createOrder(t, db, "London", "E1 6AN", 1, 6)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := allocator.Allocate(tx, &order, nil)
+		return err
+	})
+
+	var insufficient *InsufficientStockError
+	require.ErrorAs(t, err, &insufficient)
+	assert.Equal(t, 6, insufficient.Requested)
+	assert.Equal(t, 4, insufficient.Available)
+
+	var count int64
+	db.Model(&models.StockAllocation{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestShipAndRelease(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	leeds := createWarehouse(t, db, "Leeds", "LS1 1AA")
+	londonStock := createStock(t, db, 1, london, 4, nil)
+	leedsStock := createStock(t, db, 1, leeds, 2, nil)
+	variant := models.ProductVariant{Model: gorm.Model{ID: 1}, ProductID: 1, Name: "1kg", SKU: "SKU-1"}
+	require.NoError(t, db.Omit("Product").Create(&variant).Error)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "most_stock", SplitShipments: true})
+	order := createOrder(t, db, "London", "E1 6AN", 1, 5)
+	shipments, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+	require.Len(t, shipments, 2)
+
+	require.NoError(t, allocator.ShipShipment(db, &shipments[0], "TRACK-1", nil))
+	assert.Equal(t, models.ShipmentStatusShipped, shipments[0].Status)
+	assert.ErrorIs(t, allocator.ShipShipment(db, &shipments[0], "", nil), ErrShipmentNotPending)
+
+	quantity, r := reserved(t, db, londonStock.ID)
+	assert.Equal(t, 0, quantity)
+	assert.Equal(t, 0, r)
+
+	shipped, err := AllShipped(db, order.ID)
+	require.NoError(t, err)
+	assert.False(t, shipped)
+
+	require.NoError(t, db.First(&variant, 1).Error)
+	assert.Equal(t, 2, variant.QuantityInStock)
+
+	// Cancelling gives back what has not shipped
+	require.NoError(t, allocator.Release(db, &order, nil))
+	quantity, r = reserved(t, db, leedsStock.ID)
+	assert.Equal(t, 2, quantity)
+	assert.Equal(t, 0, r)
+
+	var cancelled models.Shipment
+	require.NoError(t, db.First(&cancelled, shipments[1].ID).Error)
+	assert.Equal(t, models.ShipmentStatusCancelled, cancelled.Status)
+
+	var movements int64
+	db.Model(&models.StockMovement{}).Where("movement_type = ?", MovementReleased).Count(&movements)
+	assert.Equal(t, int64(1), movements)
+}
+
+func TestAllocateAndShipBundle(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	oilStock := createStock(t, db, 1, london, 10, nil)
+	saltStock := createStock(t, db, 2, london, 3, nil)
+	for id := uint(1); id <= 3; id++ {
+		variant := models.ProductVariant{Model: gorm.Model{ID: id}, ProductID: 1, Name: fmt.Sprint(id), SKU: fmt.Sprint("SKU-", id)}
+		require.NoError(t, db.Omit("Product").Create(&variant).Error)
+	}
+	// Variant 3 is a kit of 2 of variant 1 and 1 of variant 2
+	require.NoError(t, db.Create(&[]models.BundleComponent{
+		{BundleVariantID: 3, ComponentVariantID: 1, Quantity: 2},
+		{BundleVariantID: 3, ComponentVariantID: 2, Quantity: 1},
+	}).Error)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "most_stock", SplitShipments: true})
+	tooMany := createOrder(t, db, "London", "E1 6AN", 3, 4)
+	_, err := allocator.Allocate(db, &tooMany, nil)
+	var insufficient *InsufficientStockError
+	require.ErrorAs(t, err, &insufficient)
+	assert.Equal(t, uint(2), insufficient.ProductVariantID)
+	assert.Equal(t, uint(3), insufficient.BundleVariantID)
+
+	// The kit and a loose unit of its component are allocated from the same stock
+	order := createOrder(t, db, "London", "E1 6AN", 3, 2, 1, 1)
+	shipments, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	require.Len(t, shipments[0].Allocations, 3)
+	_, r := reserved(t, db, oilStock.ID)
+	assert.Equal(t, 5, r)
+	_, r = reserved(t, db, saltStock.ID)
+	assert.Equal(t, 2, r)
+
+	var kit models.OrderItem
+	require.NoError(t, db.Where("order_id = ? AND product_variant_id = ?", order.ID, 3).First(&kit).Error)
+	assert.Nil(t, kit.InventoryItemID, "a bundle line is not linked to one batch")
+	var movement models.StockMovement
+	require.NoError(t, db.Where("inventory_item_id = ? AND movement_type = ?", saltStock.ID, MovementReserved).First(&movement).Error)
+	assert.Equal(t, 2, movement.Quantity)
+	assert.Contains(t, movement.Reason, "bundle variant 3")
+
+	require.NoError(t, allocator.ShipShipment(db, &shipments[0], "", nil))
+	quantity, r := reserved(t, db, oilStock.ID)
+	assert.Equal(t, 5, quantity)
+	assert.Equal(t, 0, r)
+	quantity, _ = reserved(t, db, saltStock.ID)
+	assert.Equal(t, 1, quantity)
+	var sold int64
+	db.Model(&models.StockMovement{}).Where("movement_type = ?", MovementSold).Count(&sold)
+	assert.Equal(t, int64(3), sold, "a movement per component allocation")
+
+	// The kit's stock is what is left of its components
+	var bundle models.ProductVariant
+	require.NoError(t, db.First(&bundle, 3).Error)
+	assert.Equal(t, 1, bundle.QuantityInStock)
+}
+
+func TestDistance(t *testing.T) {
+	to := &models.Address{City: "London", PostalCode: "E1 6AN", Country: "GB"}
+	assert.Equal(t, 0, Distance(&models.Address{City: "London", PostalCode: "e1 1aa", Country: "GB"}, to))
+	assert.Equal(t, 1, Distance(&models.Address{City: "London", PostalCode: "E14 2BB", Country: "GB"}, to))
+	assert.Equal(t, 2, Distance(&models.Address{City: "london", PostalCode: "N1 2BB", Country: "GB"}, to))
+	assert.Equal(t, 3, Distance(&models.Address{City: "Leeds", PostalCode: "LS1 1AA", Country: "GB"}, to))
+	assert.Equal(t, 4, Distance(&models.Address{City: "Paris", PostalCode: "75001", Country: "FR"}, to))
+}
@@ -0,0 +1,508 @@
+package fulfillment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/orderhistory"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNothingToPick is returned when no shipment is waiting to be picked
+	ErrNothingToPick = errors.New("no shipments waiting to be picked")
+	// ErrUnknownLine is returned when marking a line that is not on the shipment
+	ErrUnknownLine = errors.New("line is not on this shipment")
+	// ErrNotPicked is returned when packing a line that has not been picked
+	ErrNotPicked = errors.New("line has not been picked")
+)
+
+// defaultPickListOrders is the number of orders a pick list covers when no
+// orders are given
+const defaultPickListOrders = 50
+
+// PickListRequest selects the orders a pick list is generated for
+type PickListRequest struct {
+	OrderIDs    []uint `json:"order_ids"`    // empty for the oldest paid orders awaiting picking
+	WarehouseID *uint  `json:"warehouse_id"` // only pick the shipments of one warehouse
+	Limit       int    `json:"limit"`        // orders to cover when no orders are given, 50 by default
+}
+
+// SkippedOrder is an order left off a pick list
+type SkippedOrder struct {
+	OrderID     uint   `json:"order_id"`
+	OrderNumber string `json:"order_number,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// PickListResult is a new pick list, the orders it confirmed and the orders
+// left off it
+type PickListResult struct {
+	PickList  *models.PickList
+	Confirmed []models.Order
+	Skipped   []SkippedOrder
+}
+
+// CreatePickList puts the unpicked shipments of paid orders on a new pick
+// list. Paid orders that are still pending are confirmed first: their stock
+// is allocated and they move to processing. Orders that cannot be allocated
+// from the stock on hand are skipped.
+func (a *Allocator) CreatePickList(tx *gorm.DB, req PickListRequest, userID *uint) (*PickListResult, error) {
+	query := tx.Where("payment_status = ? AND status IN ?", models.PaymentStatusPaid,
+		[]models.OrderStatus{models.OrderStatusPending, models.OrderStatusProcessing})
+	if len(req.OrderIDs) > 0 {
+		query = query.Where("id IN ?", req.OrderIDs)
+	} else {
+		limit := req.Limit
+		if limit <= 0 {
+			limit = defaultPickListOrders
+		}
+		query = query.Order("order_date, id").Limit(limit)
+	}
+	var orders []models.Order
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	result := &PickListResult{}
+	found := make(map[uint]bool, len(orders))
+	orderIDs := make([]uint, 0, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		found[order.ID] = true
+		if order.Status == models.OrderStatusPending {
+			if _, err := a.Allocate(tx, order, userID); err != nil {
+				var insufficient *InsufficientStockError
+				if errors.As(err, &insufficient) || errors.Is(err, ErrSplitRequired) {
+					result.Skipped = append(result.Skipped, SkippedOrder{OrderID: order.ID, OrderNumber: order.OrderNumber, Reason: err.Error()})
+					continue
+				}
+				return nil, err
+			}
+			order.Status = models.OrderStatusProcessing
+			if err := tx.Model(order).Update("status", order.Status).Error; err != nil {
+				return nil, err
+			}
+			if err := orderhistory.Record(tx, order.ID, models.OrderStatusPending, order.Status, orderhistory.Change{ActorID: userID, Reason: "Confirmed for picking"}); err != nil {
+				return nil, err
+			}
+			result.Confirmed = append(result.Confirmed, *order)
+		}
+		orderIDs = append(orderIDs, order.ID)
+	}
+	for _, id := range req.OrderIDs {
+		if !found[id] {
+			result.Skipped = append(result.Skipped, SkippedOrder{OrderID: id, Reason: "not a paid order awaiting fulfilment"})
+		}
+	}
+
+	shipments := tx.Model(&models.Shipment{}).
+		Where("order_id IN ? AND status = ? AND pick_list_id IS NULL", orderIDs, models.ShipmentStatusPending)
+	if req.WarehouseID != nil {
+		shipments = shipments.Where("warehouse_id = ?", *req.WarehouseID)
+	}
+	var shipmentIDs []uint
+	if len(orderIDs) > 0 {
+		if err := shipments.Pluck("id", &shipmentIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(shipmentIDs) == 0 {
+		return nil, ErrNothingToPick
+	}
+
+	list := &models.PickList{WarehouseID: req.WarehouseID, CreatedByID: userID}
+	if err := tx.Create(list).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Shipment{}).Where("id IN ?", shipmentIDs).Updates(map[string]interface{}{
+		"pick_list_id": list.ID,
+		"status":       models.ShipmentStatusPicking,
+	}).Error; err != nil {
+		return nil, err
+	}
+	result.PickList = list
+	return result, nil
+}
+
+// PickShipment marks lines of an open shipment as picked, or every line when
+// allocationIDs is empty. The shipment is picked once all of its lines are.
+func (a *Allocator) PickShipment(tx *gorm.DB, shipment *models.Shipment, allocationIDs []uint, userID *uint) error {
+	if !IsOpen(shipment) {
+		return ErrShipmentNotPending
+	}
+	allocations, err := shipmentLines(tx, shipment, allocationIDs)
+	if err != nil {
+		return err
+	}
+
+	now := a.now()
+	for i := range allocations {
+		if allocations[i].PickedAt != nil {
+			continue
+		}
+		if err := tx.Model(&allocations[i]).Updates(map[string]interface{}{"picked_at": &now, "picked_by_id": userID}).Error; err != nil {
+			return err
+		}
+	}
+
+	var unpicked int64
+	if err := tx.Model(&models.StockAllocation{}).
+		Where("shipment_id = ? AND status = ? AND picked_at IS NULL", shipment.ID, models.AllocationStatusAllocated).
+		Count(&unpicked).Error; err != nil {
+		return err
+	}
+	updates := map[string]interface{}{}
+	switch {
+	case unpicked == 0 && shipment.PickedAt == nil:
+		shipment.Status = models.ShipmentStatusPicked
+		shipment.PickedAt = &now
+		updates["status"], updates["picked_at"] = shipment.Status, shipment.PickedAt
+	case unpicked > 0 && shipment.Status == models.ShipmentStatusPending:
+		shipment.Status = models.ShipmentStatusPicking
+		updates["status"] = shipment.Status
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Model(shipment).Updates(updates).Error
+}
+
+// PackShipment marks picked lines of an open shipment as packed, or every
+// line when allocationIDs is empty. The shipment is packed, and ready to
+// ship, once all of its lines are.
+func (a *Allocator) PackShipment(tx *gorm.DB, shipment *models.Shipment, allocationIDs []uint, userID *uint) error {
+	if !IsOpen(shipment) {
+		return ErrShipmentNotPending
+	}
+	allocations, err := shipmentLines(tx, shipment, allocationIDs)
+	if err != nil {
+		return err
+	}
+	for _, allocation := range allocations {
+		if allocation.PickedAt == nil {
+			return fmt.Errorf("%w: line %d", ErrNotPicked, allocation.ID)
+		}
+	}
+
+	now := a.now()
+	for i := range allocations {
+		if allocations[i].PackedAt != nil {
+			continue
+		}
+		if err := tx.Model(&allocations[i]).Updates(map[string]interface{}{"packed_at": &now, "packed_by_id": userID}).Error; err != nil {
+			return err
+		}
+	}
+
+	var unpacked int64
+	if err := tx.Model(&models.StockAllocation{}).
+		Where("shipment_id = ? AND status = ? AND packed_at IS NULL", shipment.ID, models.AllocationStatusAllocated).
+		Count(&unpacked).Error; err != nil {
+		return err
+	}
+	if unpacked > 0 || shipment.PackedAt != nil {
+		return nil
+	}
+	shipment.Status = models.ShipmentStatusPacked
+	shipment.PackedAt = &now
+	return tx.Model(shipment).Updates(map[string]interface{}{"status": shipment.Status, "packed_at": shipment.PackedAt}).Error
+}
+
+// shipmentLines loads the allocated lines of a shipment, or the given ones
+func shipmentLines(tx *gorm.DB, shipment *models.Shipment, allocationIDs []uint) ([]models.StockAllocation, error) {
+	var allocations []models.StockAllocation
+	if err := tx.Where("shipment_id = ? AND status = ?", shipment.ID, models.AllocationStatusAllocated).
+		Order("id").Find(&allocations).Error; err != nil {
+		return nil, err
+	}
+	if len(allocationIDs) == 0 {
+		return allocations, nil
+	}
+
+	byID := make(map[uint]models.StockAllocation, len(allocations))
+	for _, allocation := range allocations {
+		byID[allocation.ID] = allocation
+	}
+	selected := make([]models.StockAllocation, 0, len(allocationIDs))
+	for _, id := range allocationIDs {
+		allocation, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownLine, id)
+		}
+		selected = append(selected, allocation)
+	}
+	return selected, nil
+}
+
+// PickListView is a pick list laid out for the warehouse floor: its lines
+// are grouped by warehouse and bin, with a line per batch
+type PickListView struct {
+	ID         uint               `json:"id"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Warehouses []PickWarehouse    `json:"warehouses"`
+	Shipments  []PickListShipment `json:"shipments"`
+	Quantity   int                `json:"quantity"`
+	Picked     int                `json:"picked"`
+}
+
+// PickListShipment is a shipment on a pick list
+type PickListShipment struct {
+	ShipmentID  uint                  `json:"shipment_id"`
+	OrderID     uint                  `json:"order_id"`
+	OrderNumber string                `json:"order_number"`
+	WarehouseID uint                  `json:"warehouse_id"`
+	Status      models.ShipmentStatus `json:"status"`
+}
+
+// PickWarehouse is the part of a pick list picked in one warehouse
+type PickWarehouse struct {
+	WarehouseID uint      `json:"warehouse_id"`
+	Name        string    `json:"name"`
+	Code        string    `json:"code"`
+	Bins        []PickBin `json:"bins"`
+}
+
+// PickBin is the part of a pick list picked from one bin
+type PickBin struct {
+	BinLocation string     `json:"bin_location"`
+	Lines       []PickLine `json:"lines"`
+}
+
+// PickLine is the quantity to pick from a batch, and the orders it is for
+type PickLine struct {
+	InventoryItemID uint          `json:"inventory_item_id"`
+	SKU             string        `json:"sku"`
+	ProductName     string        `json:"product_name"`
+	VariantName     string        `json:"variant_name"`
+	BatchNumber     string        `json:"batch_number"`
+	ExpiryDate      *time.Time    `json:"expiry_date"`
+	Quantity        int           `json:"quantity"`
+	Picked          int           `json:"picked"`
+	Orders          []PickLineFor `json:"orders"`
+}
+
+// PickLineFor is the quantity of a pick line for one shipment
+type PickLineFor struct {
+	AllocationID uint   `json:"allocation_id"`
+	ShipmentID   uint   `json:"shipment_id"`
+	OrderNumber  string `json:"order_number"`
+	Quantity     int    `json:"quantity"`
+	Picked       bool   `json:"picked"`
+}
+
+// BuildPickList lays out a pick list by warehouse, bin and batch
+func BuildPickList(tx *gorm.DB, id uint) (*PickListView, error) {
+	var list models.PickList
+	if err := tx.First(&list, id).Error; err != nil {
+		return nil, err
+	}
+	var shipments []models.Shipment
+	if err := tx.Preload("Warehouse").
+		Preload("Allocations", "status <> ?", models.AllocationStatusReleased).
+		Preload("Allocations.InventoryItem.ProductVariant.Product").
+		Where("pick_list_id = ?", list.ID).
+		Order("id").
+		Find(&shipments).Error; err != nil {
+		return nil, err
+	}
+	orderNumbers, err := orderNumbers(tx, shipments)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &PickListView{ID: list.ID, CreatedAt: list.CreatedAt}
+	warehouses := make(map[uint]*PickWarehouse)
+	bins := make(map[uint]map[string]*PickBin)
+	lines := make(map[uint]*PickLine)
+	lineBins := make(map[uint]*PickBin)
+	var itemIDs []uint
+	for _, shipment := range shipments {
+		view.Shipments = append(view.Shipments, PickListShipment{
+			ShipmentID:  shipment.ID,
+			OrderID:     shipment.OrderID,
+			OrderNumber: orderNumbers[shipment.OrderID],
+			WarehouseID: shipment.WarehouseID,
+			Status:      shipment.Status,
+		})
+		if warehouses[shipment.WarehouseID] == nil {
+			warehouse := &PickWarehouse{WarehouseID: shipment.WarehouseID}
+			if shipment.Warehouse != nil {
+				warehouse.Name, warehouse.Code = shipment.Warehouse.Name, shipment.Warehouse.Code
+			}
+			warehouses[shipment.WarehouseID] = warehouse
+			bins[shipment.WarehouseID] = make(map[string]*PickBin)
+		}
+
+		for _, allocation := range shipment.Allocations {
+			line := lines[allocation.InventoryItemID]
+			if line == nil {
+				line = &PickLine{InventoryItemID: allocation.InventoryItemID}
+				location := ""
+				if item := allocation.InventoryItem; item != nil {
+					line.SKU = item.ProductVariant.SKU
+					line.ProductName = item.ProductVariant.Product.Name
+					line.VariantName = item.ProductVariant.Name
+					line.BatchNumber = item.BatchNumber
+					line.ExpiryDate = item.ExpiryDate
+					location = item.BinLocation
+				}
+				lines[allocation.InventoryItemID] = line
+				itemIDs = append(itemIDs, allocation.InventoryItemID)
+				if bins[shipment.WarehouseID][location] == nil {
+					bins[shipment.WarehouseID][location] = &PickBin{BinLocation: location}
+				}
+				lineBins[allocation.InventoryItemID] = bins[shipment.WarehouseID][location]
+			}
+			picked := allocation.PickedAt != nil || allocation.Status == models.AllocationStatusFulfilled
+			line.Quantity += allocation.Quantity
+			view.Quantity += allocation.Quantity
+			if picked {
+				line.Picked += allocation.Quantity
+				view.Picked += allocation.Quantity
+			}
+			line.Orders = append(line.Orders, PickLineFor{
+				AllocationID: allocation.ID,
+				ShipmentID:   shipment.ID,
+				OrderNumber:  orderNumbers[shipment.OrderID],
+				Quantity:     allocation.Quantity,
+				Picked:       picked,
+			})
+		}
+	}
+
+	for _, id := range itemIDs {
+		lineBins[id].Lines = append(lineBins[id].Lines, *lines[id])
+	}
+
+	for warehouseID, warehouse := range warehouses {
+		for _, bin := range bins[warehouseID] {
+			sort.Slice(bin.Lines, func(i, j int) bool {
+				if bin.Lines[i].SKU != bin.Lines[j].SKU {
+					return bin.Lines[i].SKU < bin.Lines[j].SKU
+				}
+				return bin.Lines[i].InventoryItemID < bin.Lines[j].InventoryItemID
+			})
+			warehouse.Bins = append(warehouse.Bins, *bin)
+		}
+		// Bins are walked in order, with unassigned stock last
+		sort.Slice(warehouse.Bins, func(i, j int) bool {
+			x, y := warehouse.Bins[i].BinLocation, warehouse.Bins[j].BinLocation
+			if (x == "") != (y == "") {
+				return y == ""
+			}
+			return x < y
+		})
+		view.Warehouses = append(view.Warehouses, *warehouse)
+	}
+	sort.Slice(view.Warehouses, func(i, j int) bool {
+		if view.Warehouses[i].Name != view.Warehouses[j].Name {
+			return view.Warehouses[i].Name < view.Warehouses[j].Name
+		}
+		return view.Warehouses[i].WarehouseID < view.Warehouses[j].WarehouseID
+	})
+	return view, nil
+}
+
+// PackingSlip is the printable contents of a shipment
+type PackingSlip struct {
+	ShipmentID      uint                  `json:"shipment_id"`
+	Status          models.ShipmentStatus `json:"status"`
+	OrderID         uint                  `json:"order_id"`
+	OrderNumber     string                `json:"order_number"`
+	OrderDate       time.Time             `json:"order_date"`
+	ShippingMethod  string                `json:"shipping_method"`
+	CustomerName    string                `json:"customer_name"`
+	ShippingAddress models.Address        `json:"shipping_address"`
+	WarehouseName   string                `json:"warehouse_name"`
+	TrackingNumber  string                `json:"tracking_number"`
+	Lines           []PackingSlipLine     `json:"lines"`
+	TotalQuantity   int                   `json:"total_quantity"`
+	CustomerNotes   string                `json:"customer_notes"`
+}
+
+// PackingSlipLine is a batch of a product in a shipment
+type PackingSlipLine struct {
+	AllocationID uint       `json:"allocation_id"`
+	SKU          string     `json:"sku"`
+	ProductName  string     `json:"product_name"`
+	VariantName  string     `json:"variant_name"`
+	BatchNumber  string     `json:"batch_number"`
+	ExpiryDate   *time.Time `json:"expiry_date"`
+	Quantity     int        `json:"quantity"`
+	Picked       bool       `json:"picked"`
+	Packed       bool       `json:"packed"`
+}
+
+// BuildPackingSlip lists what a shipment contains and where it goes
+func BuildPackingSlip(tx *gorm.DB, shipment *models.Shipment) (*PackingSlip, error) {
+	var order models.Order
+	if err := tx.Preload("User").Preload("ShippingAddress").First(&order, shipment.OrderID).Error; err != nil {
+		return nil, err
+	}
+	var warehouse models.Warehouse
+	if err := tx.Select("id", "name").First(&warehouse, shipment.WarehouseID).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	var allocations []models.StockAllocation
+	if err := tx.Preload("InventoryItem.ProductVariant.Product").
+		Where("shipment_id = ? AND status <> ?", shipment.ID, models.AllocationStatusReleased).
+		Order("id").
+		Find(&allocations).Error; err != nil {
+		return nil, err
+	}
+
+	slip := &PackingSlip{
+		ShipmentID:      shipment.ID,
+		Status:          shipment.Status,
+		OrderID:         order.ID,
+		OrderNumber:     order.OrderNumber,
+		OrderDate:       order.OrderDate,
+		ShippingMethod:  order.ShippingMethod,
+		CustomerName:    order.User.FirstName + " " + order.User.LastName,
+		ShippingAddress: order.ShippingAddress,
+		WarehouseName:   warehouse.Name,
+		TrackingNumber:  shipment.TrackingNumber,
+		CustomerNotes:   order.CustomerNotes,
+	}
+	for _, allocation := range allocations {
+		line := PackingSlipLine{
+			AllocationID: allocation.ID,
+			Quantity:     allocation.Quantity,
+			Picked:       allocation.PickedAt != nil,
+			Packed:       allocation.PackedAt != nil,
+		}
+		if item := allocation.InventoryItem; item != nil {
+			line.SKU = item.ProductVariant.SKU
+			line.ProductName = item.ProductVariant.Product.Name
+			line.VariantName = item.ProductVariant.Name
+			line.BatchNumber = item.BatchNumber
+			line.ExpiryDate = item.ExpiryDate
+		}
+		slip.Lines = append(slip.Lines, line)
+		slip.TotalQuantity += allocation.Quantity
+	}
+	return slip, nil
+}
+
+func orderNumbers(tx *gorm.DB, shipments []models.Shipment) (map[uint]string, error) {
+	ids := make([]uint, 0, len(shipments))
+	for _, shipment := range shipments {
+		ids = append(ids, shipment.OrderID)
+	}
+	numbers := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return numbers, nil
+	}
+	var orders []models.Order
+	if err := tx.Select("id", "order_number").Where("id IN ?", ids).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		numbers[order.ID] = order.OrderNumber
+	}
+	return numbers, nil
+}
@@ -0,0 +1,128 @@
+package fulfillment
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCreatePickList(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	leeds := createWarehouse(t, db, "Leeds", "LS1 1AA")
+	product := models.Product{Name: "Halloumi"}
+	require.NoError(t, db.Create(&product).Error)
+	for i, sku := range []string{"HAL-250", "HAL-1000"} {
+		variant := models.ProductVariant{Model: gorm.Model{ID: uint(i + 1)}, ProductID: product.ID, Name: sku, SKU: sku}
+		require.NoError(t, db.Omit("Product").Create(&variant).Error)
+	}
+	shelved := createStock(t, db, 1, london, 10, nil)
+	require.NoError(t, db.Model(&shelved).Update("bin_location", "A-01").Error)
+	createStock(t, db, 2, london, 10, nil)
+	createStock(t, db, 2, leeds, 1, nil)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "most_stock", SplitShipments: true})
+	paid := createOrder(t, db, "London", "E1 6AN", 1, 2, 2, 3)
+	confirmed := createOrder(t, db, "London", "E1 6AN", 1, 1)
+	unpaid := createOrder(t, db, "London", "E1 6AN", 1, 1)
+	tooBig := createOrder(t, db, "London", "E1 6AN", 1, 50)
+	for _, order := range []models.Order{paid, confirmed, tooBig} {
+		require.NoError(t, db.Model(&order).Update("payment_status", models.PaymentStatusPaid).Error)
+	}
+	require.NoError(t, db.Model(&confirmed).Update("status", models.OrderStatusProcessing).Error)
+	_, err := allocator.Allocate(db, &confirmed, nil)
+	require.NoError(t, err)
+
+	result, err := allocator.CreatePickList(db, PickListRequest{OrderIDs: []uint{paid.ID, confirmed.ID, unpaid.ID, tooBig.ID}}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Confirmed, 1)
+	assert.Equal(t, paid.ID, result.Confirmed[0].ID)
+	require.Len(t, result.Skipped, 2)
+	assert.Equal(t, tooBig.ID, result.Skipped[0].OrderID)
+	assert.Equal(t, unpaid.ID, result.Skipped[1].OrderID)
+
+	require.NoError(t, db.First(&paid, paid.ID).Error)
+	assert.Equal(t, models.OrderStatusProcessing, paid.Status)
+	require.NoError(t, db.First(&tooBig, tooBig.ID).Error)
+	assert.Equal(t, models.OrderStatusPending, tooBig.Status)
+
+	view, err := BuildPickList(db, result.PickList.ID)
+	require.NoError(t, err)
+	assert.Len(t, view.Shipments, 2, "one shipment per order")
+	assert.Equal(t, 6, view.Quantity)
+	require.Len(t, view.Warehouses, 1, "most_stock picks London for both lines of the paid order")
+	assert.Equal(t, "London Warehouse", view.Warehouses[0].Name)
+	require.Len(t, view.Warehouses[0].Bins, 2)
+	assert.Equal(t, "A-01", view.Warehouses[0].Bins[0].BinLocation)
+	line := view.Warehouses[0].Bins[0].Lines[0]
+	assert.Equal(t, "HAL-250", line.SKU)
+	assert.Equal(t, 3, line.Quantity)
+	assert.Len(t, line.Orders, 2)
+	assert.Equal(t, "", view.Warehouses[0].Bins[1].BinLocation, "unassigned stock comes last")
+
+	// Shipments already on a list are not picked again
+	_, err = allocator.CreatePickList(db, PickListRequest{}, nil)
+	assert.ErrorIs(t, err, ErrNothingToPick)
+
+	var page bytes.Buffer
+	require.NoError(t, RenderPickList(&page, view))
+	assert.Contains(t, page.String(), "A-01")
+}
+
+func TestPickAndPackShipment(t *testing.T) {
+	db := setupTestDB(t)
+	london := createWarehouse(t, db, "London", "E1 1AA")
+	product := models.Product{Name: "Halloumi"}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{Model: gorm.Model{ID: 1}, ProductID: product.ID, Name: "250g", SKU: "HAL-250"}
+	require.NoError(t, db.Omit("Product").Create(&variant).Error)
+	createStock(t, db, 1, london, 2, nil)
+	createStock(t, db, 1, london, 5, nil)
+
+	allocator := NewAllocator(&cfg.FulfillmentConfig{Strategy: "most_stock", SplitShipments: true})
+	order := createOrder(t, db, "London", "E1 6AN", 1, 6)
+	shipments, err := allocator.Allocate(db, &order, nil)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	shipment := shipments[0]
+	require.Len(t, shipment.Allocations, 2, "allocated from both batches")
+	first, second := shipment.Allocations[0].ID, shipment.Allocations[1].ID
+
+	assert.ErrorIs(t, allocator.PickShipment(db, &shipment, []uint{999}, nil), ErrUnknownLine)
+	assert.ErrorIs(t, allocator.PackShipment(db, &shipment, nil, nil), ErrNotPicked)
+
+	require.NoError(t, allocator.PickShipment(db, &shipment, []uint{first}, nil))
+	assert.Equal(t, models.ShipmentStatusPicking, shipment.Status)
+	require.NoError(t, allocator.PickShipment(db, &shipment, []uint{second}, nil))
+	assert.Equal(t, models.ShipmentStatusPicked, shipment.Status)
+	assert.NotNil(t, shipment.PickedAt)
+
+	require.NoError(t, allocator.PackShipment(db, &shipment, []uint{first}, nil))
+	assert.Equal(t, models.ShipmentStatusPicked, shipment.Status)
+	require.NoError(t, allocator.PackShipment(db, &shipment, nil, nil))
+	assert.Equal(t, models.ShipmentStatusPacked, shipment.Status)
+
+	slip, err := BuildPackingSlip(db, &shipment)
+	require.NoError(t, err)
+	assert.Equal(t, order.OrderNumber, slip.OrderNumber)
+	assert.Equal(t, 6, slip.TotalQuantity)
+	require.Len(t, slip.Lines, 2)
+	assert.True(t, slip.Lines[0].Packed)
+	assert.Equal(t, "HAL-250", slip.Lines[0].SKU)
+
+	// Packed shipments ship as before
+	require.NoError(t, allocator.ShipShipment(db, &shipment, "TRACK-1", nil))
+	shipped, err := AllShipped(db, order.ID)
+	require.NoError(t, err)
+	assert.True(t, shipped)
+	assert.ErrorIs(t, allocator.PickShipment(db, &shipment, nil, nil), ErrShipmentNotPending)
+
+	var page bytes.Buffer
+	require.NoError(t, RenderPackingSlip(&page, slip))
+	assert.Contains(t, page.String(), order.OrderNumber)
+}
@@ -0,0 +1,30 @@
+package fulfillment
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed templates/*.html
+var printTemplates embed.FS
+
+var printable = template.Must(template.New("").Funcs(template.FuncMap{
+	"date": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("02 Jan 2006")
+	},
+}).ParseFS(printTemplates, "templates/*.html"))
+
+// RenderPickList writes a pick list as a printable HTML page
+func RenderPickList(w io.Writer, view *PickListView) error {
+	return printable.ExecuteTemplate(w, "pick_list.html", view)
+}
+
+// RenderPackingSlip writes a packing slip as a printable HTML page
+func RenderPackingSlip(w io.Writer, slip *PackingSlip) error {
+	return printable.ExecuteTemplate(w, "packing_slip.html", slip)
+}
@@ -0,0 +1,119 @@
+package fulfillment
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"gorm.io/gorm"
+)
+
+// ErrShipmentNotPending is returned when shipping, picking or packing a
+// shipment that has already been shipped or cancelled
+var ErrShipmentNotPending = errors.New("shipment is not pending")
+
+// Release gives back the stock reserved for an order's open shipments and
+// cancels them. Shipped stock is not returned to stock.
+func (a *Allocator) Release(tx *gorm.DB, order *models.Order, userID *uint) error {
+	var allocations []models.StockAllocation
+	if err := tx.Where("order_id = ? AND status = ?", order.ID, models.AllocationStatusAllocated).Find(&allocations).Error; err != nil {
+		return err
+	}
+	for _, allocation := range allocations {
+		if err := stock.Unreserve(tx, allocation.InventoryItemID, allocation.Quantity); err != nil {
+			return err
+		}
+		if err := tx.Model(&allocation).Update("status", models.AllocationStatusReleased).Error; err != nil {
+			return err
+		}
+		if err := recordMovement(tx, allocation.InventoryItemID, MovementReleased, allocation.Quantity, "Released from order "+order.OrderNumber, order.OrderNumber, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.Shipment{}).
+		Where("order_id = ? AND status IN ?", order.ID, models.OpenShipmentStatuses).
+		Update("status", models.ShipmentStatusCancelled).Error
+}
+
+// ShipShipment ships an open shipment, whether or not it has been picked and
+// packed: its reserved stock leaves the warehouse and is recorded as sold.
+// The variants' stock totals are updated. It fails with
+// stock.ErrNotEnoughStock when a batch holds less than was reserved from it.
+func (a *Allocator) ShipShipment(tx *gorm.DB, shipment *models.Shipment, trackingNumber string, userID *uint) error {
+	if !IsOpen(shipment) {
+		return ErrShipmentNotPending
+	}
+	var order models.Order
+	if err := tx.Select("id", "order_number").First(&order, shipment.OrderID).Error; err != nil {
+		return err
+	}
+	var allocations []models.StockAllocation
+	if err := tx.Preload("InventoryItem").Where("shipment_id = ? AND status = ?", shipment.ID, models.AllocationStatusAllocated).Find(&allocations).Error; err != nil {
+		return err
+	}
+
+	variantIDs := make([]uint, 0, len(allocations))
+	for _, allocation := range allocations {
+		if err := stock.Ship(tx, allocation.InventoryItemID, allocation.Quantity); err != nil {
+			return err
+		}
+		if err := tx.Model(&allocation).Omit("InventoryItem").Update("status", models.AllocationStatusFulfilled).Error; err != nil {
+			return err
+		}
+		if err := recordMovement(tx, allocation.InventoryItemID, MovementSold, allocation.Quantity, "Shipped for order "+order.OrderNumber, order.OrderNumber, userID); err != nil {
+			return err
+		}
+		variantIDs = append(variantIDs, allocation.InventoryItem.ProductVariantID)
+	}
+
+	now := a.now()
+	shipment.Status = models.ShipmentStatusShipped
+	shipment.ShippedAt = &now
+	if trackingNumber != "" {
+		shipment.TrackingNumber = trackingNumber
+	}
+	if err := tx.Model(shipment).Updates(map[string]interface{}{
+		"status":          shipment.Status,
+		"shipped_at":      shipment.ShippedAt,
+		"tracking_number": shipment.TrackingNumber,
+	}).Error; err != nil {
+		return err
+	}
+	return stock.SyncVariantStock(tx, variantIDs...)
+}
+
+// Fulfill ships every open shipment of an order
+func (a *Allocator) Fulfill(tx *gorm.DB, order *models.Order, trackingNumber string, userID *uint) error {
+	var shipments []models.Shipment
+	if err := tx.Where("order_id = ? AND status IN ?", order.ID, models.OpenShipmentStatuses).Order("id").Find(&shipments).Error; err != nil {
+		return err
+	}
+	for i := range shipments {
+		if err := a.ShipShipment(tx, &shipments[i], trackingNumber, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllShipped reports whether an order has shipments and none is still open
+func AllShipped(tx *gorm.DB, orderID uint) (bool, error) {
+	var total, pending int64
+	if err := tx.Model(&models.Shipment{}).Where("order_id = ? AND status <> ?", orderID, models.ShipmentStatusCancelled).Count(&total).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.Shipment{}).Where("order_id = ? AND status IN ?", orderID, models.OpenShipmentStatuses).Count(&pending).Error; err != nil {
+		return false, err
+	}
+	return total > 0 && pending == 0, nil
+}
+
+// IsOpen reports whether a shipment has not shipped or been cancelled
+func IsOpen(shipment *models.Shipment) bool {
+	for _, status := range models.OpenShipmentStatuses {
+		if shipment.Status == status {
+			return true
+		}
+	}
+	return false
+}
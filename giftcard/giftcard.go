@@ -0,0 +1,404 @@
+// Package giftcard runs gift cards: cards bought as products or issued by
+// admins, their balance ledger, and spending them at checkout.
+package giftcard
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidCode   = errors.New("invalid or expired gift card")
+	ErrInvalidAmount = errors.New("gift card amount must be positive and within the maximum")
+	ErrVoided        = errors.New("gift card is already voided")
+)
+
+// codeAlphabet leaves out letters and digits that are easily confused
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Service runs gift cards
+type Service struct {
+	db     *gorm.DB
+	config cfg.GiftCardConfig
+	emails *email.EmailTriggerService
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, config *cfg.GiftCardConfig, emails *email.EmailTriggerService) *Service {
+	s := &Service{db: db, emails: emails, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	return s
+}
+
+// Recipient is who the gift cards bought as a product variant are sent to
+type Recipient struct {
+	ProductVariantID uint   `json:"product_variant_id" binding:"required"`
+	Email            string `json:"email" binding:"required,email"`
+	Name             string `json:"name"`
+	Message          string `json:"message"`
+}
+
+// IssueRequest is a gift card issued by an admin
+type IssueRequest struct {
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	RecipientEmail string  `json:"recipient_email" binding:"required,email"`
+	RecipientName  string  `json:"recipient_name"`
+	Message        string  `json:"message"`
+}
+
+// BalanceView is what anyone holding a card's code may see of it
+type BalanceView struct {
+	Last4     string                `json:"last4"`
+	Balance   float64               `json:"balance"`
+	Status    models.GiftCardStatus `json:"status"`
+	ExpiresAt *time.Time            `json:"expires_at,omitempty"`
+	Usable    bool                  `json:"usable"`
+}
+
+// Purchase creates a pending card for each unit of the gift card items of an
+// order. Cards go to the recipient given for their variant, or to the buyer.
+// They are activated and emailed once the order is paid.
+func (s *Service) Purchase(tx *gorm.DB, order *models.Order, buyer *models.User, recipients []Recipient) error {
+	var items []models.OrderItem
+	if err := tx.Model(&models.OrderItem{}).
+		Joins("JOIN product_variants ON product_variants.id = order_items.product_variant_id").
+		Joins("JOIN products ON products.id = product_variants.product_id").
+		Where("order_items.order_id = ? AND products.is_gift_card = ?", order.ID, true).
+		Find(&items).Error; err != nil {
+		return err
+	}
+	byVariant := make(map[uint]Recipient, len(recipients))
+	for _, r := range recipients {
+		byVariant[r.ProductVariantID] = r
+	}
+
+	var cards []models.GiftCard
+	for _, item := range items {
+		recipient, ok := byVariant[item.ProductVariantID]
+		if !ok {
+			recipient = Recipient{Email: buyer.Email, Name: strings.TrimSpace(buyer.FirstName + " " + buyer.LastName)}
+		}
+		for range item.Quantity {
+			cards = append(cards, models.GiftCard{
+				InitialAmount:    round(item.UnitPrice),
+				Status:           models.GiftCardPending,
+				OrderID:          &order.ID,
+				ProductVariantID: &item.ProductVariantID,
+				PurchaserID:      &buyer.ID,
+				RecipientEmail:   recipient.Email,
+				RecipientName:    recipient.Name,
+				Message:          recipient.Message,
+			})
+		}
+	}
+	if len(cards) == 0 {
+		return nil
+	}
+	return tx.Create(&cards).Error
+}
+
+// Activate activates and emails the pending cards bought on a paid order
+func (s *Service) Activate(tx *gorm.DB, order *models.Order) error {
+	var cards []models.GiftCard
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", order.ID, models.GiftCardPending).
+		Find(&cards).Error; err != nil {
+		return err
+	}
+	if len(cards) == 0 {
+		return nil
+	}
+	var buyer models.User
+	if err := tx.Select("id", "first_name", "last_name").First(&buyer, order.UserID).Error; err != nil {
+		return err
+	}
+	senderName := strings.TrimSpace(buyer.FirstName + " " + buyer.LastName)
+	for i := range cards {
+		if err := s.activate(tx, &cards[i], senderName, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Issue creates an active card for a recipient and emails it
+func (s *Service) Issue(req IssueRequest, adminID *uint) (*models.GiftCard, error) {
+	amount := round(req.Amount)
+	if amount <= 0 || (s.config.MaxAmount > 0 && amount > s.config.MaxAmount) {
+		return nil, ErrInvalidAmount
+	}
+	card := models.GiftCard{
+		InitialAmount:  amount,
+		IssuedByID:     adminID,
+		RecipientEmail: req.RecipientEmail,
+		RecipientName:  req.RecipientName,
+		Message:        req.Message,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.activate(tx, &card, "", adminID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// Lookup returns the balance of the card with a code
+func (s *Service) Lookup(code string) (*BalanceView, error) {
+	var card models.GiftCard
+	if err := s.db.Where("code_hash = ?", hashCode(code)).First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCode
+		}
+		return nil, err
+	}
+	return &BalanceView{
+		Last4:     card.Last4,
+		Balance:   card.Balance,
+		Status:    card.Status,
+		ExpiresAt: card.ExpiresAt,
+		Usable:    s.usable(&card),
+	}, nil
+}
+
+// Quote locks the card with a code for checkout and returns how much of
+// amount, the order's total, it pays for
+func (s *Service) Quote(tx *gorm.DB, code string, amount float64) (*models.GiftCard, float64, error) {
+	var card models.GiftCard
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code_hash = ?", hashCode(code)).
+		First(&card).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if !s.usable(&card) {
+		return nil, 0, ErrInvalidCode
+	}
+	return &card, round(math.Max(0, math.Min(card.Balance, amount))), nil
+}
+
+// Redeem spends the order's gift card amount from the card
+func (s *Service) Redeem(tx *gorm.DB, card *models.GiftCard, order *models.Order) error {
+	if order.GiftCardAmount <= 0 {
+		return nil
+	}
+	card.Balance = round(card.Balance - order.GiftCardAmount)
+	if card.Balance < 0 {
+		return ErrInvalidCode
+	}
+	if err := tx.Model(card).Update("balance", card.Balance).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.GiftCardTransaction{
+		GiftCardID:  card.ID,
+		OrderID:     &order.ID,
+		Type:        models.GiftCardRedeem,
+		Amount:      -order.GiftCardAmount,
+		Balance:     card.Balance,
+		Description: "Order " + order.OrderNumber,
+	}).Error
+}
+
+// Reverse gives back the amount a cancelled or refunded order paid by gift
+// card, once, and voids the cards bought on the order. The card is locked
+// before looking for an earlier refund, so two concurrent cancellations or
+// refunds of the order cannot both credit it.
+func (s *Service) Reverse(tx *gorm.DB, order *models.Order) error {
+	if order.GiftCardID != nil && order.GiftCardAmount > 0 {
+		var card models.GiftCard
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, *order.GiftCardID).Error; err != nil {
+			return err
+		}
+		var refunded int64
+		if err := tx.Model(&models.GiftCardTransaction{}).
+			Where("order_id = ? AND gift_card_id = ? AND type = ?", order.ID, card.ID, models.GiftCardRefund).
+			Count(&refunded).Error; err != nil {
+			return err
+		}
+		if refunded == 0 {
+			card.Balance = round(card.Balance + order.GiftCardAmount)
+			if err := tx.Model(&card).Update("balance", card.Balance).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.GiftCardTransaction{
+				GiftCardID:  card.ID,
+				OrderID:     &order.ID,
+				Type:        models.GiftCardRefund,
+				Amount:      order.GiftCardAmount,
+				Balance:     card.Balance,
+				Description: "Order " + order.OrderNumber + " cancelled or refunded",
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var bought []models.GiftCard
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status <> ?", order.ID, models.GiftCardVoided).
+		Find(&bought).Error; err != nil {
+		return err
+	}
+	for i := range bought {
+		if err := s.void(tx, &bought[i], "Order "+order.OrderNumber+" cancelled or refunded", nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Void cancels a card and its remaining balance
+func (s *Service) Void(cardID uint, reason string, adminID *uint) (*models.GiftCard, error) {
+	var card models.GiftCard
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, cardID).Error; err != nil {
+			return err
+		}
+		if card.Status == models.GiftCardVoided {
+			return ErrVoided
+		}
+		return s.void(tx, &card, reason, adminID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// activate gives a card its code and balance, records the issue in its
+// ledger and emails the code to the recipient. The card is created when it
+// is new.
+func (s *Service) activate(tx *gorm.DB, card *models.GiftCard, senderName string, adminID *uint) error {
+	code := generateCode()
+	hash := hashCode(code)
+	now := s.now()
+	card.CodeHash = &hash
+	card.Last4 = code[len(code)-4:]
+	card.Status = models.GiftCardActive
+	card.Balance = card.InitialAmount
+	card.ActivatedAt = &now
+	if s.config.ExpiryDays > 0 {
+		expiresAt := now.AddDate(0, 0, s.config.ExpiryDays)
+		card.ExpiresAt = &expiresAt
+	}
+	if err := tx.Save(card).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&models.GiftCardTransaction{
+		GiftCardID:  card.ID,
+		OrderID:     card.OrderID,
+		Type:        models.GiftCardIssue,
+		Amount:      card.InitialAmount,
+		Balance:     card.Balance,
+		CreatedByID: adminID,
+	}).Error; err != nil {
+		return err
+	}
+
+	// The code is only ever sent in this email, so failing to queue it
+	// undoes the activation
+	if s.emails == nil {
+		slog.WarnContext(tx.Statement.Context, "gift card activated without email service", "component", "giftcard", "gift_card_id", card.ID)
+		return nil
+	}
+	data := map[string]interface{}{
+		"subject":       fmt.Sprintf("You have received a %s gift card", settings.Current().CompanyName),
+		"RecipientName": card.RecipientName,
+		"SenderName":    senderName,
+		"Amount":        card.InitialAmount,
+		"Currency":      settings.Current().Currency,
+		"Code":          code,
+		"Message":       card.Message,
+		"RedeemURL":     s.config.RedeemURL,
+	}
+	if card.ExpiresAt != nil {
+		data["ExpiresAt"] = card.ExpiresAt.Format("2 January 2006")
+	}
+	if err := s.emails.InTx(tx).TriggerGiftCard(card.RecipientEmail, card.RecipientName, data); err != nil {
+		return fmt.Errorf("failed to send gift card email: %w", err)
+	}
+	return nil
+}
+
+// void zeroes a card's balance, recording it in the ledger
+func (s *Service) void(tx *gorm.DB, card *models.GiftCard, reason string, adminID *uint) error {
+	now := s.now()
+	entry := models.GiftCardTransaction{
+		GiftCardID:  card.ID,
+		Type:        models.GiftCardVoid,
+		Amount:      -card.Balance,
+		Description: reason,
+		CreatedByID: adminID,
+	}
+	card.Status, card.Balance, card.VoidedAt, card.VoidReason = models.GiftCardVoided, 0, &now, reason
+	if err := tx.Model(card).Updates(map[string]interface{}{
+		"status":      card.Status,
+		"balance":     0,
+		"voided_at":   now,
+		"void_reason": reason,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&entry).Error
+}
+
+// usable reports whether a card can be spent
+func (s *Service) usable(card *models.GiftCard) bool {
+	return card.Status == models.GiftCardActive && card.Balance > 0 &&
+		(card.ExpiresAt == nil || card.ExpiresAt.After(s.now()))
+}
+
+// hashCode hashes a code, ignoring case, spaces and dashes. Codes are random
+// enough that an unsalted hash cannot be reversed by guessing.
+func hashCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return -1
+	}, code)
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateCode returns a random code of four groups of four characters
+func generateCode() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	var code strings.Builder
+	for i := range b {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(codeAlphabet[int(b[i])%len(codeAlphabet)])
+	}
+	return code.String()
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
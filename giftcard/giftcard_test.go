@@ -0,0 +1,185 @@
+package giftcard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+// recordingEmailService records the gift card emails sent
+type recordingEmailService struct {
+	email.EmailService
+	sent []map[string]interface{}
+}
+
+func (s *recordingEmailService) SendTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient) error {
+	data["to"] = recipient.Email
+	s.sent = append(s.sent, data)
+	return nil
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Product{}, &models.ProductVariant{}, &models.Order{},
+		&models.OrderItem{}, &models.GiftCard{}, &models.GiftCardTransaction{}))
+	return db
+}
+
+// setupService issues cards lasting a year, worth up to £200
+func setupService(db *gorm.DB) (*Service, *recordingEmailService) {
+	recorder := &recordingEmailService{}
+	s := NewService(db, &cfg.GiftCardConfig{ExpiryDays: 365, MaxAmount: 200}, email.NewEmailTriggerService(recorder, db))
+	s.now = func() time.Time { return testNow }
+	return s, recorder
+}
+
+func createUser(t *testing.T, db *gorm.DB) *models.User {
+	user := models.User{Email: "buyer@example.com", FirstName: "Amina", LastName: "Haddad", Password: "x", UserType: models.Customer, IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	return &user
+}
+
+// createOrder creates an order for quantity gift cards of a denomination and
+// a jar of honey
+func createOrder(t *testing.T, db *gorm.DB, user *models.User, denomination float64, quantity int) *models.Order {
+	card := models.Product{Name: "Gift card", IsGiftCard: true}
+	honey := models.Product{Name: "Honey"}
+	require.NoError(t, db.Create(&card).Error)
+	require.NoError(t, db.Create(&honey).Error)
+	cardVariant := models.ProductVariant{ProductID: card.ID, Name: "£25", SKU: "GIFT-25", BasePrice: denomination}
+	honeyVariant := models.ProductVariant{ProductID: honey.ID, Name: "500g", SKU: "HONEY-500", BasePrice: 8}
+	require.NoError(t, db.Create(&cardVariant).Error)
+	require.NoError(t, db.Create(&honeyVariant).Error)
+
+	order := models.Order{OrderNumber: "ORD-1", UserID: user.ID, Status: models.OrderStatusPending, FinalAmount: denomination*float64(quantity) + 8}
+	require.NoError(t, db.Create(&order).Error)
+	require.NoError(t, db.Create(&[]models.OrderItem{
+		{OrderID: order.ID, ProductVariantID: cardVariant.ID, Quantity: quantity, UnitPrice: denomination, TotalAmount: denomination * float64(quantity)},
+		{OrderID: order.ID, ProductVariantID: honeyVariant.ID, Quantity: 1, UnitPrice: 8, TotalAmount: 8},
+	}).Error)
+	return &order
+}
+
+func TestPurchaseActivatesOnPayment(t *testing.T) {
+	db := setupTestDB(t)
+	s, recorder := setupService(db)
+	buyer := createUser(t, db)
+	order := createOrder(t, db, buyer, 25, 2)
+
+	var variant models.ProductVariant
+	require.NoError(t, db.Where("sku = ?", "GIFT-25").First(&variant).Error)
+	recipients := []Recipient{{ProductVariantID: variant.ID, Email: "friend@example.com", Name: "Sam", Message: "Happy birthday"}}
+	require.NoError(t, s.Purchase(db, order, buyer, recipients))
+
+	var cards []models.GiftCard
+	require.NoError(t, db.Where("order_id = ?", order.ID).Find(&cards).Error)
+	require.Len(t, cards, 2, "one card per unit")
+	for _, card := range cards {
+		assert.Equal(t, models.GiftCardPending, card.Status)
+		assert.Nil(t, card.CodeHash)
+		assert.Equal(t, "friend@example.com", card.RecipientEmail)
+	}
+	assert.Empty(t, recorder.sent, "codes are only sent once the order is paid")
+
+	require.NoError(t, s.Activate(db, order))
+	require.NoError(t, s.Activate(db, order))
+	require.Len(t, recorder.sent, 2, "each card is activated once")
+	assert.Equal(t, "friend@example.com", recorder.sent[0]["to"])
+	assert.Equal(t, "Amina Haddad", recorder.sent[0]["SenderName"])
+
+	view, err := s.Lookup(recorder.sent[0]["Code"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, view.Balance)
+	assert.True(t, view.Usable)
+	assert.Equal(t, testNow.AddDate(1, 0, 0), view.ExpiresAt.UTC())
+}
+
+func TestRedeemAndReverse(t *testing.T) {
+	db := setupTestDB(t)
+	s, recorder := setupService(db)
+	buyer := createUser(t, db)
+	_, err := s.Issue(IssueRequest{Amount: 50, RecipientEmail: "buyer@example.com"}, nil)
+	require.NoError(t, err)
+	code := recorder.sent[0]["Code"].(string)
+
+	card, applied, err := s.Quote(db, code, 30)
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, applied)
+	order := models.Order{OrderNumber: "ORD-1", UserID: buyer.ID, FinalAmount: 30, GiftCardID: &card.ID, GiftCardAmount: applied}
+	require.NoError(t, db.Create(&order).Error)
+	require.NoError(t, s.Redeem(db, card, &order))
+
+	// Codes are accepted without dashes and in lower case
+	_, applied, err = s.Quote(db, "  "+strings.ToLower(strings.ReplaceAll(code, "-", "")), 100)
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, applied, "the card only pays its remaining balance")
+
+	require.NoError(t, s.Reverse(db, &order))
+	require.NoError(t, s.Reverse(db, &order))
+	view, err := s.Lookup(code)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, view.Balance, "a cancelled order gives the card's payment back once")
+
+	var ledger float64
+	require.NoError(t, db.Model(&models.GiftCardTransaction{}).Select("SUM(amount)").Where("gift_card_id = ?", card.ID).Scan(&ledger).Error)
+	assert.Equal(t, 50.0, ledger, "the ledger matches the balance")
+}
+
+func TestReverseVoidsPurchasedCards(t *testing.T) {
+	db := setupTestDB(t)
+	s, _ := setupService(db)
+	buyer := createUser(t, db)
+	order := createOrder(t, db, buyer, 25, 1)
+	require.NoError(t, s.Purchase(db, order, buyer, nil))
+	require.NoError(t, s.Activate(db, order))
+
+	require.NoError(t, s.Reverse(db, order))
+	var card models.GiftCard
+	require.NoError(t, db.Where("order_id = ?", order.ID).First(&card).Error)
+	assert.Equal(t, models.GiftCardVoided, card.Status)
+	assert.Zero(t, card.Balance)
+	assert.Equal(t, "buyer@example.com", card.RecipientEmail, "cards without a recipient go to the buyer")
+}
+
+func TestUnusableCards(t *testing.T) {
+	db := setupTestDB(t)
+	s, recorder := setupService(db)
+
+	_, err := s.Issue(IssueRequest{Amount: 500, RecipientEmail: "friend@example.com"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	card, err := s.Issue(IssueRequest{Amount: 40, RecipientEmail: "friend@example.com"}, nil)
+	require.NoError(t, err)
+	code := recorder.sent[0]["Code"].(string)
+
+	_, _, err = s.Quote(db, "AAAA-BBBB-CCCC-DDDD", 10)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	s.now = func() time.Time { return testNow.AddDate(1, 0, 1) }
+	_, _, err = s.Quote(db, code, 10)
+	assert.ErrorIs(t, err, ErrInvalidCode, "expired cards cannot be spent")
+	s.now = func() time.Time { return testNow }
+
+	_, err = s.Void(card.ID, "Issued by mistake", nil)
+	require.NoError(t, err)
+	_, err = s.Void(card.ID, "Again", nil)
+	assert.ErrorIs(t, err, ErrVoided)
+	_, _, err = s.Quote(db, code, 10)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	view, err := s.Lookup(code)
+	require.NoError(t, err)
+	assert.False(t, view.Usable)
+	assert.Equal(t, models.GiftCardVoided, view.Status)
+}
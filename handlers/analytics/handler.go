@@ -0,0 +1,16 @@
+package analytics
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/analytics"
+	"github.com/YasserCherfaoui/MarketProGo/cache"
+	"gorm.io/gorm"
+)
+
+type AnalyticsHandler struct {
+	sales *analytics.Service
+	cache *cache.Analytics
+}
+
+func NewAnalyticsHandler(db *gorm.DB, reports *cache.Analytics) *AnalyticsHandler {
+	return &AnalyticsHandler{sales: analytics.NewService(db), cache: reports}
+}
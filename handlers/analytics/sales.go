@@ -0,0 +1,258 @@
+package analytics
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/analytics"
+	"github.com/YasserCherfaoui/MarketProGo/utils/export"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRangeDays is how many days a report covers when no range is given
+const defaultRangeDays = 30
+
+// parseRange reads the from and to (YYYY-MM-DD) query parameters, both
+// included, defaulting to the last 30 days. It responds with an error itself
+// when they are invalid.
+func parseRange(c *gin.Context, code string) (analytics.Range, bool) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	r := analytics.Range{From: today.AddDate(0, 0, 1-defaultRangeDays), To: today.AddDate(0, 0, 1)}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, code, "Invalid from date, expected YYYY-MM-DD")
+			return r, false
+		}
+		r.From = from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, code, "Invalid to date, expected YYYY-MM-DD")
+			return r, false
+		}
+		// Include the whole of the last day
+		r.To = to.AddDate(0, 0, 1)
+	}
+	if !r.From.Before(r.To) {
+		response.GenerateBadRequestResponse(c, code, "from must not be after to")
+		return r, false
+	}
+	return r, true
+}
+
+// parseLimit reads the limit query parameter of the top sellers reports
+func parseLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		return 10
+	}
+	return limit
+}
+
+// parseExport reads the format query parameter. Reports are returned as JSON
+// unless format asks for a CSV or XLSX download.
+func parseExport(c *gin.Context, code string) (format export.Format, download bool, ok bool) {
+	value := c.Query("format")
+	if value == "" || value == "json" {
+		return "", false, true
+	}
+	format, err := export.ParseFormat(value)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, err.Error())
+		return "", false, false
+	}
+	return format, true, true
+}
+
+// rangeKey names a date range in cache keys and file names
+func rangeKey(r analytics.Range) string {
+	return r.From.Format("20060102") + "-" + r.To.AddDate(0, 0, -1).Format("20060102")
+}
+
+// writeReport sends a report as a CSV or XLSX download
+func writeReport(c *gin.Context, format export.Format, name string, header []string, rows [][]string) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", format.FileName(name)))
+	c.Status(200)
+
+	// Errors after the first byte is sent can only be logged
+	writer, err := export.NewWriter(format, c.Writer, "report")
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to start report export", "component", "analytics", "report", name, "error", err)
+		return
+	}
+	for _, row := range append([][]string{header}, rows...) {
+		if err := writer.WriteRow(row); err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to write report export", "component", "analytics", "report", name, "error", err)
+			return
+		}
+	}
+	if err := writer.Close(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to finish report export", "component", "analytics", "report", name, "error", err)
+	}
+}
+
+// money formats an amount for an export cell
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func count(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// GetSales - Admin endpoint for revenue, orders, average order value and
+// units sold over a date range, by day, week or month
+func (h *AnalyticsHandler) GetSales(c *gin.Context) {
+	r, ok := parseRange(c, "analytics/sales")
+	if !ok {
+		return
+	}
+	interval, err := analytics.ParseInterval(c.Query("interval"))
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "analytics/sales", err.Error())
+		return
+	}
+	format, download, ok := parseExport(c, "analytics/sales")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var report analytics.SalesReport
+	if err := h.cache.Load(ctx, fmt.Sprintf("sales:%s:%s", interval, rangeKey(r)), &report, func() error {
+		built, err := h.sales.WithContext(ctx).Sales(r, interval)
+		if err == nil {
+			report = *built
+		}
+		return err
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to build sales report", "component", "analytics", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "analytics/sales", "Failed to build sales report")
+		return
+	}
+
+	if download {
+		rows := make([][]string, 0, len(report.Series))
+		for _, period := range report.Series {
+			rows = append(rows, []string{period.Period, money(period.Revenue), count(period.Orders), money(period.AverageOrderValue), count(period.Units)})
+		}
+		writeReport(c, format, "sales-"+rangeKey(r), []string{"Period", "Revenue", "Orders", "Average Order Value", "Units"}, rows)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Sales report fetched successfully", report)
+}
+
+// GetTopProducts - Admin endpoint for the best selling products over a date
+// range, by revenue
+func (h *AnalyticsHandler) GetTopProducts(c *gin.Context) {
+	r, ok := parseRange(c, "analytics/top_products")
+	if !ok {
+		return
+	}
+	format, download, ok := parseExport(c, "analytics/top_products")
+	if !ok {
+		return
+	}
+	limit := parseLimit(c)
+
+	ctx := c.Request.Context()
+	var products []analytics.ProductSales
+	if err := h.cache.Load(ctx, fmt.Sprintf("products:%d:%s", limit, rangeKey(r)), &products, func() error {
+		var err error
+		products, err = h.sales.WithContext(ctx).TopProducts(r, limit)
+		return err
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to build top products report", "component", "analytics", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "analytics/top_products", "Failed to build top products report")
+		return
+	}
+
+	if download {
+		rows := make([][]string, 0, len(products))
+		for _, product := range products {
+			rows = append(rows, []string{strconv.FormatUint(uint64(product.ProductID), 10), product.Name, money(product.Revenue), count(product.Units), count(product.Orders)})
+		}
+		writeReport(c, format, "top-products-"+rangeKey(r), []string{"Product ID", "Product", "Revenue", "Units", "Orders"}, rows)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Top products fetched successfully", products)
+}
+
+// GetTopCategories - Admin endpoint for the best selling categories over a
+// date range, by revenue
+func (h *AnalyticsHandler) GetTopCategories(c *gin.Context) {
+	r, ok := parseRange(c, "analytics/top_categories")
+	if !ok {
+		return
+	}
+	format, download, ok := parseExport(c, "analytics/top_categories")
+	if !ok {
+		return
+	}
+	limit := parseLimit(c)
+
+	ctx := c.Request.Context()
+	var categories []analytics.CategorySales
+	if err := h.cache.Load(ctx, fmt.Sprintf("categories:%d:%s", limit, rangeKey(r)), &categories, func() error {
+		var err error
+		categories, err = h.sales.WithContext(ctx).TopCategories(r, limit)
+		return err
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to build top categories report", "component", "analytics", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "analytics/top_categories", "Failed to build top categories report")
+		return
+	}
+
+	if download {
+		rows := make([][]string, 0, len(categories))
+		for _, category := range categories {
+			rows = append(rows, []string{strconv.FormatUint(uint64(category.CategoryID), 10), category.Name, money(category.Revenue), count(category.Units), count(category.Orders)})
+		}
+		writeReport(c, format, "top-categories-"+rangeKey(r), []string{"Category ID", "Category", "Revenue", "Units", "Orders"}, rows)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Top categories fetched successfully", categories)
+}
+
+// GetCohorts - Admin endpoint for the repeat purchase rate of the customers
+// who placed their first order in a date range, by month of that order
+func (h *AnalyticsHandler) GetCohorts(c *gin.Context) {
+	r, ok := parseRange(c, "analytics/cohorts")
+	if !ok {
+		return
+	}
+	format, download, ok := parseExport(c, "analytics/cohorts")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var cohorts []analytics.Cohort
+	if err := h.cache.Load(ctx, "cohorts:"+rangeKey(r), &cohorts, func() error {
+		var err error
+		cohorts, err = h.sales.WithContext(ctx).Cohorts(r)
+		return err
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to build cohort report", "component", "analytics", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "analytics/cohorts", "Failed to build cohort report")
+		return
+	}
+
+	if download {
+		rows := make([][]string, 0, len(cohorts))
+		for _, cohort := range cohorts {
+			rows = append(rows, []string{cohort.Cohort, count(cohort.Customers), count(cohort.RepeatCustomers),
+				strconv.FormatFloat(cohort.RepeatRate, 'f', 1, 64), count(cohort.Orders), money(cohort.Revenue)})
+		}
+		writeReport(c, format, "cohorts-"+rangeKey(r), []string{"Cohort", "Customers", "Repeat Customers", "Repeat Rate %", "Orders", "Revenue"}, rows)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Cohort report fetched successfully", cohorts)
+}
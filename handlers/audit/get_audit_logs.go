@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs - Admin endpoint to list audit entries, filterable by actor,
+// entity, action and date range (RFC3339 or YYYY-MM-DD)
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.Model(&models.AuditLog{})
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseDate(from)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "audit/logs", "Invalid from date")
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseDate(to)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "audit/logs", "Invalid to date")
+			return
+		}
+		if len(to) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1) // include the whole day
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "audit/logs", "Failed to count logs")
+		return
+	}
+
+	var logs []models.AuditLog
+	if err := query.Preload("Actor").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&logs).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "audit/logs", "Failed to fetch logs")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Audit logs retrieved successfully", gin.H{
+		"logs":        logs,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"total_pages": (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
+
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
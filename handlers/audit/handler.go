@@ -0,0 +1,11 @@
+package audit
+
+import "gorm.io/gorm"
+
+type AuditHandler struct {
+	db *gorm.DB
+}
+
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
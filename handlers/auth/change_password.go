@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/password"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+// POST /auth/change-password
+// Changing the password signs the user out of every session.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/change-password", err.Error())
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/change-password", "User not found")
+		return
+	}
+
+	if !password.Validate(req.CurrentPassword, user.Password) {
+		response.GenerateUnauthorizedResponse(c, "auth/change-password", "Invalid current password")
+		return
+	}
+
+	hashed, err := password.Hash(req.NewPassword)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/change-password", "Failed to hash password")
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", hashed).Error; err != nil {
+			return err
+		}
+		return h.revokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/change-password", "Failed to update password")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Password changed successfully", nil)
+}
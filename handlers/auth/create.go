@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/referral"
 	"github.com/YasserCherfaoui/MarketProGo/utils/password"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type RegisterRequest struct {
@@ -16,6 +20,12 @@ type RegisterRequest struct {
 	LastName  string          `json:"last_name" binding:"required"`
 	Phone     string          `json:"phone"`
 	UserType  models.UserType `json:"user_type" binding:"required"`
+
+	ReferralCode string `json:"referral_code"` // code of the user who referred them
+
+	// Language of the emails sent to the user, negotiated from the
+	// Accept-Language header when empty
+	Locale string `json:"locale" binding:"omitempty,oneof=en fr ar"`
 }
 
 func (h *AuthHandler) CreateUser(c *gin.Context) {
@@ -38,17 +48,47 @@ func (h *AuthHandler) CreateUser(c *gin.Context) {
 		LastName:  request.LastName,
 		Phone:     request.Phone,
 		UserType:  request.UserType,
+		Locale:    request.Locale,
+	}
+	if user.Locale == "" {
+		user.Locale = i18n.Locale(c)
 	}
 
-	if err := h.db.Create(&user).Error; err != nil {
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var referrer *models.User
+		if request.ReferralCode != "" {
+			var err error
+			if referrer, err = h.referrals.Referrer(tx, request.ReferralCode); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if referrer != nil {
+			_, err := h.referrals.Attribute(tx, referrer, &user, c.ClientIP(), c.Request.UserAgent())
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, referral.ErrInvalidCode) {
+		response.GenerateBadRequestResponse(c, "auth/create-user", err.Error())
+		return
+	}
+	if err != nil {
 		response.GenerateInternalServerErrorResponse(c, "auth/create-user", err.Error())
 		return
 	}
 
+	var activationToken string
+	if h.verifications != nil {
+		activationToken = h.verifications.Token(&user)
+	}
+
 	// Send welcome email asynchronously
 	go func() {
 		userName := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
-		if err := h.emailTriggerSvc.TriggerWelcomeEmail(user.Email, userName); err != nil {
+		if err := h.emailTriggerSvc.TriggerWelcomeEmail(user.Email, userName, activationToken); err != nil {
 			// Log error but don't fail the user creation
 			fmt.Printf("Failed to send welcome email to %s: %v\n", user.Email, err)
 		}
@@ -2,17 +2,36 @@ package auth
 
 import (
 	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/lockout"
+	"github.com/YasserCherfaoui/MarketProGo/oauth"
+	"github.com/YasserCherfaoui/MarketProGo/referral"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/verification"
 	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
 	db              *gorm.DB
 	emailTriggerSvc *email.EmailTriggerService
+	loginGuard      *lockout.Guard
+	referrals       *referral.Service
+	verifications   *verification.Service
+	oauth           *oauth.Service
+	sms             *sms.Service
 }
 
-func NewAuthHandler(db *gorm.DB, emailTriggerSvc *email.EmailTriggerService) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, emailTriggerSvc *email.EmailTriggerService, loginGuard *lockout.Guard, referrals *referral.Service) *AuthHandler {
 	return &AuthHandler{
 		db:              db,
 		emailTriggerSvc: emailTriggerSvc,
+		loginGuard:      loginGuard,
+		referrals:       referrals,
 	}
 }
+
+// WithVerification sets the service that issues the activation links sent to
+// new users
+func (h *AuthHandler) WithVerification(verifications *verification.Service) *AuthHandler {
+	h.verifications = verifications
+	return h
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLockout(t *testing.T) {
+	router, _ := setupAuthTest(t)
+
+	bad := map[string]string{"email": "user@example.com", "password": "wrong-password"}
+	for i := 0; i < 5; i++ {
+		w, _ := postJSON(router, "/auth/login", bad)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// The correct password is refused while the account is locked
+	w, _ := postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	assert.Equal(t, http.StatusLocked, w.Code)
+}
@@ -1,7 +1,12 @@
 package auth
 
 import (
+	"log"
+	"os"
+	"time"
+
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
 	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
 	"github.com/YasserCherfaoui/MarketProGo/utils/password"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
@@ -14,8 +19,15 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	User         models.User `json:"user"`
+
+	// Set when 2FA is enabled; complete the login with POST /auth/2fa/verify
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+	// Set for admins who must enroll in 2FA before using admin endpoints
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -25,25 +37,90 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if h.rejectThrottledLogin(c, request.Email) {
+		return
+	}
+
 	user := models.User{}
 	if err := h.db.Where("email = ?", request.Email).First(&user).Error; err != nil {
+		h.registerLoginFailure(c, request.Email, nil)
 		response.GenerateNotFoundResponse(c, "auth/login", "User not found")
 		return
 	}
 
 	if !password.Validate(request.Password, user.Password) {
+		h.registerLoginFailure(c, request.Email, &user)
 		response.GenerateUnauthorizedResponse(c, "auth/login", "Invalid password")
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.UserType, user.CompanyID)
+	if h.rejectBannedUser(c, user.ID, "auth/login") {
+		return
+	}
+
+	if h.loginGuard != nil {
+		if err := h.loginGuard.Reset(request.Email); err != nil {
+			log.Printf("Failed to reset login attempts for %s: %v", request.Email, err)
+		}
+	}
+
+	if user.TwoFactorEnabled {
+		challenge, err := h.createTwoFactorChallenge(c, user.ID)
+		if err != nil {
+			response.GenerateInternalServerErrorResponse(c, "auth/login", err.Error())
+			return
+		}
+		response.GenerateSuccessResponse(c, "Two-factor authentication required", LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		})
+		return
+	}
+
+	loginResponse, err := h.completeLogin(c, user, false)
 	if err != nil {
 		response.GenerateInternalServerErrorResponse(c, "auth/login", err.Error())
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Login successful", LoginResponse{
-		Token: token,
-		User:  user,
-	})
+	response.GenerateSuccessResponse(c, "Login successful", loginResponse)
+}
+
+// rejectBannedUser responds with forbidden when the user is banned from
+// logging in
+func (h *AuthHandler) rejectBannedUser(c *gin.Context, userID uint, code string) bool {
+	ban, err := moderation.ActiveRestriction(h.db, userID, models.CapabilityLogin)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to check account restrictions")
+		return true
+	}
+	if ban == nil {
+		return false
+	}
+	message := "Your account has been banned"
+	if ban.ExpiresAt != nil {
+		message += " until " + ban.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	response.GenerateForbiddenResponse(c, code, message)
+	return true
+}
+
+// completeLogin issues the access and refresh tokens for an authenticated user
+func (h *AuthHandler) completeLogin(c *gin.Context, user models.User, mfaVerified bool) (*LoginResponse, error) {
+	token, err := auth.GenerateSessionToken(user.ID, user.UserType, user.CompanyID, mfaVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, _, err := h.issueRefreshToken(h.db, c, user.ID, "", mfaVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:                  token,
+		RefreshToken:           refreshToken,
+		User:                   user,
+		TwoFactorSetupRequired: user.UserType == models.Admin && !user.TwoFactorEnabled && os.Getenv("ADMIN_REQUIRE_2FA") == "true",
+	}, nil
 }
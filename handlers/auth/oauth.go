@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/oauth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthStateCookie binds a sign-in to the browser that started it, so that
+// nobody can sign a victim in to the attacker's account with their callback
+const oauthStateCookie = "oauth_state"
+
+// oauthStateMaxAge is how long the state cookie lasts, matching the state
+const oauthStateMaxAge = 10 * time.Minute
+
+// WithOAuth sets the service social sign-ins are run with
+func (h *AuthHandler) WithOAuth(oauthSvc *oauth.Service) *AuthHandler {
+	h.oauth = oauthSvc
+	return h
+}
+
+// GET /auth/oauth
+func (h *AuthHandler) GetOAuthProviders(c *gin.Context) {
+	providers := []string{}
+	if h.oauth != nil {
+		providers = append(providers, h.oauth.Enabled()...)
+	}
+	response.GenerateSuccessResponse(c, "Sign-in providers retrieved", gin.H{"providers": providers})
+}
+
+// GET /auth/oauth/:provider
+func (h *AuthHandler) StartOAuth(c *gin.Context) {
+	if h.oauth == nil {
+		response.GenerateNotFoundResponse(c, "auth/oauth", oauth.ErrUnknownProvider.Error())
+		return
+	}
+	target, state, err := h.oauth.AuthURL(c.Param("provider"))
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		response.GenerateNotFoundResponse(c, "auth/oauth", err.Error())
+		return
+	}
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/oauth", "Failed to start sign-in")
+		return
+	}
+
+	// Apple posts its callback from its own site, so the cookie must be
+	// sent with cross-site requests
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(oauthStateCookie, state, int(oauthStateMaxAge/time.Second), "/", "", true, true)
+	c.Redirect(http.StatusFound, target)
+}
+
+// GET or POST /auth/oauth/:provider/callback
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	if h.oauth == nil {
+		response.GenerateNotFoundResponse(c, "auth/oauth-callback", oauth.ErrUnknownProvider.Error())
+		return
+	}
+	provider := c.Param("provider")
+	if c.Request.FormValue("error") != "" {
+		h.failOAuth(c, http.StatusUnauthorized, "Sign-in was canceled")
+		return
+	}
+
+	state := c.Request.FormValue("state")
+	cookie, _ := c.Cookie(oauthStateCookie)
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(oauthStateCookie, "", -1, "/", "", true, true)
+	if state == "" || cookie != state {
+		h.failOAuth(c, http.StatusBadRequest, oauth.ErrInvalidState.Error())
+		return
+	}
+	if err := h.oauth.CheckState(provider, state); err != nil {
+		h.failOAuth(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	identity, err := h.oauth.Exchange(c.Request.Context(), provider, c.Request.FormValue("code"))
+	switch {
+	case errors.Is(err, oauth.ErrUnknownProvider):
+		h.failOAuth(c, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		log.Printf("OAuth sign-in with %s failed: %v", provider, err)
+		h.failOAuth(c, http.StatusUnauthorized, oauth.ErrInvalidIDToken.Error())
+		return
+	}
+	identity.WithAppleUser(c.Request.PostFormValue("user"))
+
+	user, created, err := h.oauth.SignIn(identity, i18n.Locale(c))
+	switch {
+	case errors.Is(err, oauth.ErrEmailNotVerified), errors.Is(err, oauth.ErrAccountClosed):
+		h.failOAuth(c, http.StatusForbidden, err.Error())
+		return
+	case err != nil:
+		log.Printf("Failed to sign in %s identity: %v", provider, err)
+		h.failOAuth(c, http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	ban, err := moderation.ActiveRestriction(h.db, user.ID, models.CapabilityLogin)
+	if err != nil {
+		h.failOAuth(c, http.StatusInternalServerError, "Failed to check account restrictions")
+		return
+	}
+	if ban != nil {
+		h.failOAuth(c, http.StatusForbidden, "Your account has been banned")
+		return
+	}
+
+	if created && h.emailTriggerSvc != nil {
+		go func() {
+			name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+			if err := h.emailTriggerSvc.TriggerWelcomeEmail(user.Email, name, ""); err != nil {
+				log.Printf("Failed to send welcome email to user %d: %v", user.ID, err)
+			}
+		}()
+	}
+
+	var loginResponse *LoginResponse
+	if user.TwoFactorEnabled {
+		challenge, err := h.createTwoFactorChallenge(c, user.ID)
+		if err != nil {
+			h.failOAuth(c, http.StatusInternalServerError, "Failed to sign in")
+			return
+		}
+		loginResponse = &LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}
+	} else if loginResponse, err = h.completeLogin(c, *user, false); err != nil {
+		h.failOAuth(c, http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	redirect := h.oauth.RedirectURL()
+	if redirect == "" {
+		response.GenerateSuccessResponse(c, "Login successful", loginResponse)
+		return
+	}
+	// The fragment is never sent to servers, keeping tokens out of their logs
+	fragment := url.Values{}
+	if loginResponse.TwoFactorRequired {
+		fragment.Set("two_factor_required", "true")
+		fragment.Set("challenge_token", loginResponse.ChallengeToken)
+	} else {
+		fragment.Set("token", loginResponse.Token)
+		fragment.Set("refresh_token", loginResponse.RefreshToken)
+	}
+	c.Redirect(http.StatusSeeOther, redirect+"#"+fragment.Encode())
+}
+
+// failOAuth sends the user back to the storefront with an error, or responds
+// with it when no storefront page is configured
+func (h *AuthHandler) failOAuth(c *gin.Context, status int, message string) {
+	redirect := h.oauth.RedirectURL()
+	if redirect == "" {
+		response.GenerateErrorResponse(c, status, "auth/oauth-callback", message)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, redirect+"#"+url.Values{"error": {message}}.Encode())
+}
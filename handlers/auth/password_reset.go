@@ -3,15 +3,30 @@ package auth
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/email"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/password"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+const (
+	// passwordResetTTL is how long a reset token can be used for
+	passwordResetTTL = email.PasswordResetExpiryHours * time.Hour
+	// maxPasswordResetsPerHour is how many reset emails an account is sent
+	// an hour, however many addresses ask for them
+	maxPasswordResetsPerHour = 3
+)
+
+// errInvalidResetToken is returned for unknown, used and expired reset tokens
+var errInvalidResetToken = errors.New("invalid or expired token")
+
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
@@ -41,20 +56,41 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	// generate token (random)
-	raw := fmt.Sprintf("%d:%d", user.ID, time.Now().UnixNano())
-	hash := hashToken(raw)
-	expires := time.Now().Add(24 * time.Hour)
-	record := models.PasswordResetToken{UserID: user.ID, TokenHash: hash, ExpiresAt: expires}
-	if err := h.db.Create(&record).Error; err != nil {
+	// Throttle per account too, as the route limit is per IP
+	var recent int64
+	if err := h.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-time.Hour)).
+		Count(&recent).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "auth/forgot-password", "Failed to create reset token")
 		return
 	}
+	if recent >= maxPasswordResetsPerHour {
+		log.Printf("Password reset requests for user %d throttled", user.ID)
+		response.GenerateSuccessResponse(c, "If that email is registered, you will receive a reset email shortly", nil)
+		return
+	}
 
-	// send email
-	if h.emailTriggerSvc != nil {
-		name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
-		_ = h.emailTriggerSvc.TriggerPasswordReset(user.Email, name, raw)
+	raw, err := generateRandomToken()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/forgot-password", "Failed to create reset token")
+		return
+	}
+	record := models.PasswordResetToken{UserID: user.ID, TokenHash: hashToken(raw), ExpiresAt: time.Now().Add(passwordResetTTL)}
+	// the email is recorded with the token, so it is sent exactly when the token is saved
+	if err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		if h.emailTriggerSvc != nil {
+			name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+			if err := h.emailTriggerSvc.InTx(tx).TriggerPasswordReset(user.Email, name, raw); err != nil {
+				log.Printf("Failed to queue password reset email for user %d: %v", user.ID, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/forgot-password", "Failed to create reset token")
+		return
 	}
 
 	response.GenerateSuccessResponse(c, "If that email is registered, you will receive a reset email shortly", nil)
@@ -83,26 +119,76 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		response.GenerateBadRequestResponse(c, "auth/reset-password", err.Error())
 		return
 	}
-	hash := hashToken(req.Token)
-	var rec models.PasswordResetToken
-	if err := h.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hash, time.Now()).First(&rec).Error; err != nil {
-		response.GenerateUnauthorizedResponse(c, "auth/reset-password", "Invalid or expired token")
-		return
-	}
-
-	// update password
 	hashed, err := password.Hash(req.NewPassword)
 	if err != nil {
 		response.GenerateInternalServerErrorResponse(c, "auth/reset-password", "Failed to hash password")
 		return
 	}
-	if err := h.db.Model(&models.User{}).Where("id = ?", rec.UserID).Update("password", hashed).Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "auth/reset-password", "Failed to update password")
+
+	var user models.User
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var rec models.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(req.Token), time.Now()).First(&rec).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errInvalidResetToken
+			}
+			return err
+		}
+
+		// Use up the token, and any other the user was sent, so that a
+		// token works once even when requests race
+		now := time.Now()
+		used := tx.Model(&models.PasswordResetToken{}).Where("id = ? AND used_at IS NULL", rec.ID).Update("used_at", now)
+		if used.Error != nil {
+			return used.Error
+		}
+		if used.RowsAffected == 0 {
+			return errInvalidResetToken
+		}
+		if err := tx.Model(&models.PasswordResetToken{}).Where("user_id = ? AND used_at IS NULL", rec.UserID).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, rec.UserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("password", hashed).Error; err != nil {
+			return err
+		}
+		// sign out every existing session
+		if err := h.revokeUserSessions(tx, user.ID); err != nil {
+			return err
+		}
+
+		if h.emailTriggerSvc != nil {
+			name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+			data := map[string]interface{}{
+				"event_type":     "Password reset",
+				"event_datetime": now.Format("January 2, 2006 at 3:04 PM"),
+				"location":       "Unknown",
+				"device":         c.Request.UserAgent(),
+				"ip_address":     c.ClientIP(),
+			}
+			if err := h.emailTriggerSvc.InTx(tx).TriggerSecurityAlert(user.Email, name, data); err != nil {
+				log.Printf("Failed to queue password reset alert for user %d: %v", user.ID, err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errInvalidResetToken) {
+		response.GenerateUnauthorizedResponse(c, "auth/reset-password", "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/reset-password", "Failed to reset password")
 		return
 	}
-	// mark token used
-	now := time.Now()
-	_ = h.db.Model(&rec).Update("used_at", &now).Error
+	// Failed logins before the reset no longer count towards a lockout
+	if h.loginGuard != nil {
+		if err := h.loginGuard.Reset(user.Email); err != nil {
+			log.Printf("Failed to clear login failures of user %d: %v", user.ID, err)
+		}
+	}
 
 	response.GenerateSuccessResponse(c, "Password reset successful", nil)
 }
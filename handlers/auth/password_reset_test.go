@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetPasswordTokenIsSingleUse(t *testing.T) {
+	router, db := setupAuthTest(t)
+	require.NoError(t, db.Create(&models.PasswordResetToken{UserID: 1, TokenHash: hashToken("token-1"), ExpiresAt: time.Now().Add(time.Hour)}).Error)
+	require.NoError(t, db.Create(&models.PasswordResetToken{UserID: 1, TokenHash: hashToken("token-2"), ExpiresAt: time.Now().Add(time.Hour)}).Error)
+	require.NoError(t, db.Create(&models.PasswordResetToken{UserID: 1, TokenHash: hashToken("expired"), ExpiresAt: time.Now().Add(-time.Minute)}).Error)
+
+	w, _ := postJSON(router, "/auth/reset-password", map[string]string{"token": "expired", "new_password": "new-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w, _ = postJSON(router, "/auth/reset-password", map[string]string{"token": "token-1", "new_password": "new-password"})
+	require.Equal(t, http.StatusOK, w.Code)
+	w, _ = postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "new-password"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The token cannot be used again, nor the other token the user was sent
+	w, _ = postJSON(router, "/auth/reset-password", map[string]string{"token": "token-1", "new_password": "another-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = postJSON(router, "/auth/reset-password", map[string]string{"token": "token-2", "new_password": "another-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestForgotPasswordThrottledPerAccount(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	for i := 0; i < maxPasswordResetsPerHour+2; i++ {
+		w, _ := postJSON(router, "/auth/forgot-password", map[string]string{"email": "user@example.com"})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	w, _ := postJSON(router, "/auth/forgot-password", map[string]string{"email": "nobody@example.com"})
+	assert.Equal(t, http.StatusOK, w.Code, "unknown addresses get the same answer")
+
+	var tokens []models.PasswordResetToken
+	require.NoError(t, db.Find(&tokens).Error)
+	assert.Len(t, tokens, maxPasswordResetsPerHour)
+	assert.NotEqual(t, tokens[0].TokenHash, tokens[1].TokenHash)
+	assert.WithinDuration(t, time.Now().Add(passwordResetTTL), tokens[0].ExpiresAt, time.Minute)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// WithSMS sets the service phone verification codes are texted with
+func (h *AuthHandler) WithSMS(smsSvc *sms.Service) *AuthHandler {
+	h.sms = smsSvc
+	return h
+}
+
+type SendPhoneCodeRequest struct {
+	Phone   string `json:"phone" binding:"required"`
+	Purpose string `json:"purpose" binding:"omitempty,oneof=signup checkout"`
+}
+
+// POST /auth/phone/send-code
+func (h *AuthHandler) SendPhoneCode(c *gin.Context) {
+	var req SendPhoneCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/phone/send-code", err.Error())
+		return
+	}
+	if req.Purpose == "" {
+		req.Purpose = models.PhoneVerificationSignup
+	}
+	if h.sms == nil {
+		response.GenerateErrorResponse(c, http.StatusServiceUnavailable, "auth/phone/send-code", "Phone verification is not available")
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/phone/send-code", "User not found")
+		return
+	}
+
+	verification, err := h.sms.SendCode(c.Request.Context(), &user, req.Phone, req.Purpose)
+	switch {
+	case errors.Is(err, sms.ErrInvalidPhone), errors.Is(err, sms.ErrAlreadyConfirmed):
+		response.GenerateBadRequestResponse(c, "auth/phone/send-code", err.Error())
+		return
+	case errors.Is(err, sms.ErrResendTooSoon), errors.Is(err, sms.ErrTooManyCodes):
+		response.GenerateErrorResponse(c, http.StatusTooManyRequests, "auth/phone/send-code", err.Error())
+		return
+	case err != nil:
+		log.Printf("Failed to send phone verification code to user %d: %v", user.ID, err)
+		response.GenerateInternalServerErrorResponse(c, "auth/phone/send-code", "Failed to send verification code")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Verification code sent", gin.H{
+		"phone":      verification.Phone,
+		"expires_at": verification.ExpiresAt,
+	})
+}
+
+type VerifyPhoneRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// POST /auth/phone/verify
+func (h *AuthHandler) VerifyPhone(c *gin.Context) {
+	var req VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/phone/verify", err.Error())
+		return
+	}
+	if h.sms == nil {
+		response.GenerateErrorResponse(c, http.StatusServiceUnavailable, "auth/phone/verify", "Phone verification is not available")
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/phone/verify", "User not found")
+		return
+	}
+
+	err := h.sms.VerifyCode(c.Request.Context(), &user, req.Phone, req.Code)
+	switch {
+	case errors.Is(err, sms.ErrInvalidPhone), errors.Is(err, sms.ErrInvalidCode):
+		response.GenerateBadRequestResponse(c, "auth/phone/verify", err.Error())
+		return
+	case errors.Is(err, sms.ErrTooManyAttempts):
+		response.GenerateErrorResponse(c, http.StatusTooManyRequests, "auth/phone/verify", err.Error())
+		return
+	case err != nil:
+		response.GenerateInternalServerErrorResponse(c, "auth/phone/verify", "Failed to verify phone number")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Phone number verified", gin.H{
+		"phone":             user.Phone,
+		"phone_verified_at": user.PhoneVerifiedAt,
+	})
+}
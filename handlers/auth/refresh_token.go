@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenHeader carries the caller's refresh token when listing
+// sessions. It is not read from the query string, which ends up in access
+// logs.
+const RefreshTokenHeader = "X-Refresh-Token"
+
+// refreshTokenTTL is how long a session stays valid without being refreshed
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	errInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	errRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SessionResponse struct {
+	ID         uint       `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+func generateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// issueRefreshToken creates a new refresh token for the request's device. An
+// empty familyID starts a new session.
+func (h *AuthHandler) issueRefreshToken(db *gorm.DB, c *gin.Context, userID uint, familyID string, mfaVerified bool) (string, *models.RefreshToken, error) {
+	raw, err := generateRandomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	if familyID == "" {
+		if familyID, err = generateRandomToken(); err != nil {
+			return "", nil, fmt.Errorf("failed to generate session id: %w", err)
+		}
+	}
+
+	record := models.RefreshToken{
+		UserID:      userID,
+		TokenHash:   hashToken(raw),
+		FamilyID:    familyID,
+		ExpiresAt:   time.Now().Add(refreshTokenTTL),
+		UserAgent:   c.Request.UserAgent(),
+		IPAddress:   c.ClientIP(),
+		MFAVerified: mfaVerified,
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return raw, &record, nil
+}
+
+// rotateRefreshToken exchanges a refresh token for a new one in the same session.
+// Presenting an already rotated token revokes the whole session, since it means
+// the token was copied.
+func (h *AuthHandler) rotateRefreshToken(c *gin.Context, raw string) (string, *models.RefreshToken, error) {
+	var newRaw, reusedFamilyID string
+	var newRecord *models.RefreshToken
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Where("token_hash = ?", hashToken(raw)).First(&current).Error; err != nil {
+			return errInvalidRefreshToken
+		}
+
+		now := time.Now()
+		if current.RevokedAt != nil {
+			if current.ReplacedByID != nil {
+				reusedFamilyID = current.FamilyID
+				return errRefreshTokenReused
+			}
+			return errInvalidRefreshToken
+		}
+		if !current.IsActive(now) {
+			return errInvalidRefreshToken
+		}
+
+		var err error
+		newRaw, newRecord, err = h.issueRefreshToken(tx, c, current.UserID, current.FamilyID, current.MFAVerified)
+		if err != nil {
+			return err
+		}
+
+		// Only rotate a token that is still unrevoked to guard against concurrent refreshes
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":     now,
+				"replaced_by_id": newRecord.ID,
+				"last_used_at":   now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidRefreshToken
+		}
+		return nil
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		// Revoke outside the rolled back transaction so the revocation sticks
+		if revokeErr := h.revokeSession(h.db, reusedFamilyID); revokeErr != nil {
+			return "", nil, revokeErr
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return newRaw, newRecord, nil
+}
+
+// revokeSession revokes every token of a session
+func (h *AuthHandler) revokeSession(db *gorm.DB, familyID string) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeUserSessions revokes every active refresh token of a user
+func (h *AuthHandler) revokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// POST /auth/refresh
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/refresh", err.Error())
+		return
+	}
+
+	refreshToken, record, err := h.rotateRefreshToken(c, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, errInvalidRefreshToken) || errors.Is(err, errRefreshTokenReused) {
+			response.GenerateUnauthorizedResponse(c, "auth/refresh", err.Error())
+			return
+		}
+		response.GenerateInternalServerErrorResponse(c, "auth/refresh", "Failed to refresh token")
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, record.UserID).Error; err != nil {
+		response.GenerateUnauthorizedResponse(c, "auth/refresh", "User not found")
+		return
+	}
+	if h.rejectBannedUser(c, user.ID, "auth/refresh") {
+		return
+	}
+
+	token, err := auth.GenerateSessionToken(user.ID, user.UserType, user.CompanyID, record.MFAVerified)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/refresh", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Token refreshed successfully", TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	})
+}
+
+// POST /auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/logout", err.Error())
+		return
+	}
+
+	var record models.RefreshToken
+	if err := h.db.Where("token_hash = ? AND user_id = ?", hashToken(req.RefreshToken), userID).First(&record).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/logout", "Session not found")
+		return
+	}
+
+	if err := h.revokeSession(h.db, record.FamilyID); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/logout", "Failed to revoke session")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Logged out successfully", nil)
+}
+
+// GET /auth/sessions
+// The optional X-Refresh-Token header marks the caller's own session as current.
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var tokens []models.RefreshToken
+	if err := h.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/sessions", "Failed to fetch sessions")
+		return
+	}
+
+	currentHash := ""
+	if raw := c.GetHeader(RefreshTokenHeader); raw != "" {
+		currentHash = hashToken(raw)
+	}
+
+	sessions := make([]SessionResponse, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, SessionResponse{
+			ID:         t.ID,
+			UserAgent:  t.UserAgent,
+			IPAddress:  t.IPAddress,
+			CreatedAt:  t.CreatedAt,
+			LastUsedAt: t.LastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+			Current:    currentHash != "" && t.TokenHash == currentHash,
+		})
+	}
+
+	response.GenerateSuccessResponse(c, "Sessions retrieved successfully", sessions)
+}
+
+// DELETE /auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var record models.RefreshToken
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&record).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/sessions", "Session not found")
+		return
+	}
+
+	if err := h.revokeSession(h.db, record.FamilyID); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/sessions", "Failed to revoke session")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Session revoked successfully", nil)
+}
+
+// DELETE /auth/sessions
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	if err := h.revokeUserSessions(h.db, userID); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/sessions", "Failed to revoke sessions")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "All sessions revoked successfully", nil)
+}
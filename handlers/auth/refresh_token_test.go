@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/lockout"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/password"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAuthTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("JWT_SECRET", "test-secret-key")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.RefreshToken{},
+		&models.TwoFactorAuth{},
+		&models.TwoFactorBackupCode{},
+		&models.TwoFactorChallenge{},
+		&models.UserRestriction{},
+		&models.PasswordResetToken{},
+	))
+
+	hashed, err := password.Hash("password123")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.User{Email: "user@example.com", Password: hashed, UserType: models.Customer}).Error)
+
+	h := NewAuthHandler(db, nil, lockout.NewGuard(lockout.NewMemoryStore(), nil), nil)
+	router := gin.New()
+	router.POST("/auth/login", h.Login)
+	router.POST("/auth/refresh", h.RefreshToken)
+	router.POST("/auth/2fa/verify", h.VerifyTwoFactor)
+	router.POST("/auth/forgot-password", h.ForgotPassword)
+	router.POST("/auth/reset-password", h.ResetPassword)
+
+	// Protected routes authenticate as the seeded user
+	protected := router.Group("/auth", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	protected.POST("/2fa/setup", h.SetupTwoFactor)
+	protected.POST("/2fa/enable", h.EnableTwoFactor)
+	protected.GET("/sessions", h.GetSessions)
+	return router, db
+}
+
+func postJSON(router *gin.Engine, path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func refreshTokenFrom(resp map[string]interface{}) string {
+	data, _ := resp["data"].(map[string]interface{})
+	token, _ := data["refresh_token"].(string)
+	return token
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	w, resp := postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	first := refreshTokenFrom(resp)
+	require.NotEmpty(t, first)
+
+	w, resp = postJSON(router, "/auth/refresh", map[string]string{"refresh_token": first})
+	require.Equal(t, http.StatusOK, w.Code)
+	second := refreshTokenFrom(resp)
+	assert.NotEmpty(t, second)
+	assert.NotEqual(t, first, second)
+
+	// Reusing a rotated token revokes the whole session
+	w, _ = postJSON(router, "/auth/refresh", map[string]string{"refresh_token": first})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w, _ = postJSON(router, "/auth/refresh", map[string]string{"refresh_token": second})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var active int64
+	db.Model(&models.RefreshToken{}).Where("revoked_at IS NULL").Count(&active)
+	assert.Equal(t, int64(0), active)
+}
+
+func TestGetSessionsMarksCurrent(t *testing.T) {
+	router, _ := setupAuthTest(t)
+
+	w, resp := postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	token := refreshTokenFrom(resp)
+	w, _ = postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	current := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []SessionResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 2)
+		count := 0
+		for _, session := range resp.Data {
+			if session.Current {
+				count++
+			}
+		}
+		return count
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/sessions", nil)
+	req.Header.Set(RefreshTokenHeader, token)
+	assert.Equal(t, 1, current(req))
+
+	// The token is not taken from the query string
+	assert.Zero(t, current(httptest.NewRequest(http.MethodGet, "/auth/sessions?refresh_token="+token, nil)))
+}
+
+func TestLoginRejectsBannedUser(t *testing.T) {
+	router, db := setupAuthTest(t)
+
+	w, resp := postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	token := refreshTokenFrom(resp)
+
+	require.NoError(t, db.Create(&models.UserRestriction{UserID: 1, Capability: models.CapabilityLogin, Reason: "Spam"}).Error)
+
+	w, _ = postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = postJSON(router, "/auth/refresh", map[string]string{"refresh_token": token})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Other restrictions and lifted bans do not stop logins
+	now := time.Now()
+	require.NoError(t, db.Model(&models.UserRestriction{}).Where("user_id = ?", 1).Update("lifted_at", now).Error)
+	require.NoError(t, db.Create(&models.UserRestriction{UserID: 1, Capability: models.CapabilityReview, Reason: "Spam"}).Error)
+	w, _ = postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
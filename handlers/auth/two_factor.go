@@ -0,0 +1,431 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/encryption"
+	"github.com/YasserCherfaoui/MarketProGo/utils/password"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/utils/totp"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorChallengeTTL    = 5 * time.Minute
+	maxTwoFactorAttempts     = 5
+	twoFactorBackupCodeCount = 10
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP or backup code
+}
+
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled              bool       `json:"enabled"`
+	EnabledAt            *time.Time `json:"enabled_at"`
+	BackupCodesRemaining int64      `json:"backup_codes_remaining"`
+}
+
+// createTwoFactorChallenge stores a short-lived login challenge and returns its raw token
+func (h *AuthHandler) createTwoFactorChallenge(c *gin.Context, userID uint) (string, error) {
+	raw, err := generateRandomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	challenge := models.TwoFactorChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(twoFactorChallengeTTL),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+	if err := h.db.Create(&challenge).Error; err != nil {
+		return "", fmt.Errorf("failed to store challenge: %w", err)
+	}
+	return raw, nil
+}
+
+// verifySecondFactor checks a TOTP code, falling back to an unused backup code
+// which is consumed on success
+func (h *AuthHandler) verifySecondFactor(db *gorm.DB, enrollment *models.TwoFactorAuth, code string) (bool, error) {
+	secret, err := encryption.Decrypt(enrollment.SecretEncrypted)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt 2fa secret: %w", err)
+	}
+
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		if step <= enrollment.LastUsedStep {
+			return false, nil
+		}
+		enrollment.LastUsedStep = step
+		return true, db.Model(enrollment).Update("last_used_step", step).Error
+	}
+
+	normalized := normalizeBackupCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+	result := db.Model(&models.TwoFactorBackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", enrollment.UserID, hashToken(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func normalizeBackupCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// generateBackupCodes replaces the user's backup codes and returns the new plain codes
+func generateBackupCodes(db *gorm.DB, userID uint) ([]string, error) {
+	if err := db.Unscoped().Where("user_id = ?", userID).Delete(&models.TwoFactorBackupCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, twoFactorBackupCodeCount)
+	records := make([]models.TwoFactorBackupCode, 0, twoFactorBackupCodeCount)
+	for i := 0; i < twoFactorBackupCodeCount; i++ {
+		raw, err := generateRandomToken()
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		records = append(records, models.TwoFactorBackupCode{UserID: userID, CodeHash: hashToken(code)})
+	}
+	if err := db.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// sendTwoFactorAlert notifies the user that their 2FA settings changed
+func (h *AuthHandler) sendTwoFactorAlert(c *gin.Context, user models.User, eventType string) {
+	if h.emailTriggerSvc == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"event_type":     eventType,
+		"event_datetime": time.Now().Format("January 2, 2006 at 3:04 PM"),
+		"location":       "Unknown",
+		"device":         c.Request.UserAgent(),
+		"ip_address":     c.ClientIP(),
+	}
+	go func() {
+		name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+		if err := h.emailTriggerSvc.TriggerSecurityAlert(user.Email, name, data); err != nil {
+			fmt.Printf("Failed to send security alert to %s: %v\n", user.Email, err)
+		}
+	}()
+}
+
+// GET /auth/2fa
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	status := TwoFactorStatusResponse{}
+	var enrollment models.TwoFactorAuth
+	if err := h.db.Where("user_id = ? AND enabled = ?", userID, true).First(&enrollment).Error; err == nil {
+		status.Enabled = true
+		status.EnabledAt = enrollment.EnabledAt
+		h.db.Model(&models.TwoFactorBackupCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&status.BackupCodesRemaining)
+	}
+
+	response.GenerateSuccessResponse(c, "Two-factor status retrieved successfully", status)
+}
+
+// POST /auth/2fa/setup
+// Starts enrollment by generating a new secret. 2FA is not active until the
+// first code is confirmed with POST /auth/2fa/enable.
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/2fa/setup", "User not found")
+		return
+	}
+	if user.TwoFactorEnabled {
+		response.GenerateBadRequestResponse(c, "auth/2fa/setup", "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/setup", "Failed to generate secret")
+		return
+	}
+	encrypted, err := encryption.Encrypt(secret)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/setup", "Failed to encrypt secret")
+		return
+	}
+
+	// Replace any unfinished enrollment
+	if err := h.db.Unscoped().Where("user_id = ?", user.ID).Delete(&models.TwoFactorAuth{}).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/setup", "Failed to reset enrollment")
+		return
+	}
+	enrollment := models.TwoFactorAuth{UserID: user.ID, SecretEncrypted: encrypted}
+	if err := h.db.Create(&enrollment).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/setup", "Failed to store enrollment")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Scan the QR code with your authenticator app", TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(settings.Current().CompanyName, user.Email, secret),
+	})
+}
+
+// POST /auth/2fa/enable
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/2fa/enable", err.Error())
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/2fa/enable", "User not found")
+		return
+	}
+	if user.TwoFactorEnabled {
+		response.GenerateBadRequestResponse(c, "auth/2fa/enable", "Two-factor authentication is already enabled")
+		return
+	}
+
+	var enrollment models.TwoFactorAuth
+	if err := h.db.Where("user_id = ?", user.ID).First(&enrollment).Error; err != nil {
+		response.GenerateBadRequestResponse(c, "auth/2fa/enable", "Start setup before enabling two-factor authentication")
+		return
+	}
+
+	secret, err := encryption.Decrypt(enrollment.SecretEncrypted)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/enable", "Failed to read secret")
+		return
+	}
+	step, ok := totp.Validate(secret, req.Code, time.Now())
+	if !ok {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/enable", "Invalid verification code")
+		return
+	}
+
+	var codes []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&enrollment).Updates(map[string]interface{}{
+			"enabled":        true,
+			"enabled_at":     now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+		codes, err = generateBackupCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/enable", "Failed to enable two-factor authentication")
+		return
+	}
+
+	h.sendTwoFactorAlert(c, user, "Two-factor authentication enabled")
+
+	response.GenerateSuccessResponse(c, "Two-factor authentication enabled", gin.H{
+		"backup_codes": codes,
+	})
+}
+
+// POST /auth/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/2fa/disable", err.Error())
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/2fa/disable", "User not found")
+		return
+	}
+	if !password.Validate(req.Password, user.Password) {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/disable", "Invalid password")
+		return
+	}
+
+	var enrollment models.TwoFactorAuth
+	if err := h.db.Where("user_id = ? AND enabled = ?", user.ID, true).First(&enrollment).Error; err != nil {
+		response.GenerateBadRequestResponse(c, "auth/2fa/disable", "Two-factor authentication is not enabled")
+		return
+	}
+
+	ok, err := h.verifySecondFactor(h.db, &enrollment, req.Code)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/disable", "Failed to verify code")
+		return
+	}
+	if !ok {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/disable", "Invalid verification code")
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.TwoFactorBackupCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&enrollment).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("two_factor_enabled", false).Error
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/disable", "Failed to disable two-factor authentication")
+		return
+	}
+
+	h.sendTwoFactorAlert(c, user, "Two-factor authentication disabled")
+
+	response.GenerateSuccessResponse(c, "Two-factor authentication disabled", nil)
+}
+
+// POST /auth/2fa/backup-codes
+// Regenerates backup codes, invalidating the previous set.
+func (h *AuthHandler) RegenerateBackupCodes(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/2fa/backup-codes", err.Error())
+		return
+	}
+
+	var enrollment models.TwoFactorAuth
+	if err := h.db.Where("user_id = ? AND enabled = ?", userID, true).First(&enrollment).Error; err != nil {
+		response.GenerateBadRequestResponse(c, "auth/2fa/backup-codes", "Two-factor authentication is not enabled")
+		return
+	}
+
+	secret, err := encryption.Decrypt(enrollment.SecretEncrypted)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/backup-codes", "Failed to read secret")
+		return
+	}
+	step, ok := totp.Validate(secret, req.Code, time.Now())
+	if !ok || step <= enrollment.LastUsedStep {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/backup-codes", "Invalid verification code")
+		return
+	}
+
+	var codes []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&enrollment).Update("last_used_step", step).Error; err != nil {
+			return err
+		}
+		codes, err = generateBackupCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/backup-codes", "Failed to generate backup codes")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Backup codes regenerated successfully", gin.H{
+		"backup_codes": codes,
+	})
+}
+
+// POST /auth/2fa/verify
+// Completes a login that was answered with a two-factor challenge.
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req VerifyTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "auth/2fa/verify", err.Error())
+		return
+	}
+
+	var challenge models.TwoFactorChallenge
+	if err := h.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(req.ChallengeToken), time.Now()).
+		First(&challenge).Error; err != nil {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/verify", "Invalid or expired challenge")
+		return
+	}
+	if challenge.Attempts >= maxTwoFactorAttempts {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/verify", "Too many attempts, please log in again")
+		return
+	}
+
+	var enrollment models.TwoFactorAuth
+	if err := h.db.Where("user_id = ? AND enabled = ?", challenge.UserID, true).First(&enrollment).Error; err != nil {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/verify", "Two-factor authentication is not enabled")
+		return
+	}
+
+	ok, err := h.verifySecondFactor(h.db, &enrollment, req.Code)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/verify", "Failed to verify code")
+		return
+	}
+	if !ok {
+		h.db.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1"))
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/verify", "Invalid verification code")
+		return
+	}
+
+	// Consume the challenge; a concurrent request using it loses the race
+	result := h.db.Model(&models.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		response.GenerateUnauthorizedResponse(c, "auth/2fa/verify", "Invalid or expired challenge")
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, challenge.UserID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/2fa/verify", "User not found")
+		return
+	}
+	if h.rejectBannedUser(c, user.ID, "auth/2fa/verify") {
+		return
+	}
+
+	loginResponse, err := h.completeLogin(c, user, true)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "auth/2fa/verify", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Login successful", loginResponse)
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactorLoginChallenge(t *testing.T) {
+	router, _ := setupAuthTest(t)
+
+	w, resp := postJSON(router, "/auth/2fa/setup", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	secret := resp["data"].(map[string]interface{})["secret"].(string)
+
+	code, err := totp.CodeAt(secret, time.Now())
+	require.NoError(t, err)
+	w, resp = postJSON(router, "/auth/2fa/enable", map[string]string{"code": code})
+	require.Equal(t, http.StatusOK, w.Code)
+	backupCodes := resp["data"].(map[string]interface{})["backup_codes"].([]interface{})
+	require.Len(t, backupCodes, twoFactorBackupCodeCount)
+
+	// Password alone now yields a challenge instead of tokens
+	w, resp = postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	data := resp["data"].(map[string]interface{})
+	assert.Equal(t, true, data["two_factor_required"])
+	assert.Empty(t, data["token"])
+	challenge := data["challenge_token"].(string)
+
+	w, _ = postJSON(router, "/auth/2fa/verify", map[string]string{"challenge_token": challenge, "code": "000000"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w, resp = postJSON(router, "/auth/2fa/verify", map[string]string{"challenge_token": challenge, "code": backupCodes[0].(string)})
+	require.Equal(t, http.StatusOK, w.Code)
+	token := resp["data"].(map[string]interface{})["token"].(string)
+	claims, err := auth.ValidateToken(token)
+	require.NoError(t, err)
+	assert.True(t, claims.MFA)
+
+	// Challenges and backup codes are single use
+	w, _ = postJSON(router, "/auth/2fa/verify", map[string]string{"challenge_token": challenge, "code": backupCodes[1].(string)})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w, resp = postJSON(router, "/auth/login", map[string]string{"email": "user@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	challenge = resp["data"].(map[string]interface{})["challenge_token"].(string)
+	w, _ = postJSON(router, "/auth/2fa/verify", map[string]string{"challenge_token": challenge, "code": backupCodes[0].(string)})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/verification"
+	"github.com/gin-gonic/gin"
+)
+
+// GET /auth/activate?token=...
+func (h *AuthHandler) Activate(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		response.GenerateBadRequestResponse(c, "auth/activate", "Missing token")
+		return
+	}
+	if h.verifications == nil {
+		response.GenerateErrorResponse(c, http.StatusServiceUnavailable, "auth/activate", "Email verification is not available")
+		return
+	}
+
+	user, err := h.verifications.Activate(token)
+	switch {
+	case errors.Is(err, verification.ErrInvalidToken), errors.Is(err, verification.ErrTokenExpired):
+		response.GenerateBadRequestResponse(c, "auth/activate", err.Error())
+		return
+	case err != nil:
+		response.GenerateInternalServerErrorResponse(c, "auth/activate", "Failed to verify email address")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Email address verified", gin.H{
+		"email":       user.Email,
+		"verified_at": user.VerifiedAt,
+	})
+}
+
+// POST /auth/resend-verification
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	if h.verifications == nil || h.emailTriggerSvc == nil {
+		response.GenerateErrorResponse(c, http.StatusServiceUnavailable, "auth/resend-verification", "Email verification is not available")
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "auth/resend-verification", "User not found")
+		return
+	}
+	if user.VerifiedAt != nil {
+		response.GenerateBadRequestResponse(c, "auth/resend-verification", "Email address is already verified")
+		return
+	}
+
+	name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+	if err := h.emailTriggerSvc.WithContext(c.Request.Context()).
+		TriggerEmailVerification(user.Email, name, h.verifications.Token(&user), h.verifications.ExpiryHours()); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		response.GenerateInternalServerErrorResponse(c, "auth/resend-verification", "Failed to send verification email")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Verification email sent", nil)
+}
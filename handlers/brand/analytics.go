@@ -0,0 +1,131 @@
+package brand
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BrandAnalytics is the sales and rating report of a brand. Sub-brands have
+// their own rows.
+type BrandAnalytics struct {
+	BrandID       uint    `json:"brand_id"`
+	Name          string  `json:"name"`
+	Slug          string  `json:"slug"`
+	Revenue       float64 `json:"revenue"`
+	UnitsSold     int64   `json:"units_sold"`
+	OrderCount    int64   `json:"order_count"`
+	AverageRating float64 `json:"average_rating"`
+	TotalReviews  int64   `json:"total_reviews"`
+}
+
+// brandAnalytics aggregates the order lines and approved reviews of each
+// brand's products. Cancelled and returned orders and lines are left out;
+// from and to bound the order date when set.
+func brandAnalytics(db *gorm.DB, from, to *time.Time) ([]BrandAnalytics, error) {
+	var brands []models.Brand
+	if err := db.Order("name ASC").Find(&brands).Error; err != nil {
+		return nil, err
+	}
+	reports := make(map[uint]*BrandAnalytics, len(brands))
+	for _, brand := range brands {
+		reports[brand.ID] = &BrandAnalytics{BrandID: brand.ID, Name: brand.Name, Slug: brand.Slug}
+	}
+
+	sales := db.Table("order_items").
+		Select("products.brand_id AS brand_id, COALESCE(SUM(order_items.total_amount), 0) AS revenue, COALESCE(SUM(order_items.quantity), 0) AS units_sold, COUNT(DISTINCT orders.id) AS order_count").
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Joins("JOIN product_variants ON product_variants.id = order_items.product_variant_id").
+		Joins("JOIN products ON products.id = product_variants.product_id").
+		Where("order_items.deleted_at IS NULL AND products.brand_id IS NOT NULL").
+		Where("orders.status NOT IN ?", []models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusReturned}).
+		Where("order_items.status NOT IN ?", []string{"cancelled", "returned"})
+	if from != nil {
+		sales = sales.Where("orders.order_date >= ?", *from)
+	}
+	if to != nil {
+		sales = sales.Where("orders.order_date < ?", *to)
+	}
+	var salesRows []struct {
+		BrandID    uint
+		Revenue    float64
+		UnitsSold  int64
+		OrderCount int64
+	}
+	if err := sales.Group("products.brand_id").Scan(&salesRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range salesRows {
+		if report, ok := reports[row.BrandID]; ok {
+			report.Revenue = math.Round(row.Revenue*100) / 100
+			report.UnitsSold = row.UnitsSold
+			report.OrderCount = row.OrderCount
+		}
+	}
+
+	var ratingRows []struct {
+		BrandID       uint
+		AverageRating float64
+		TotalReviews  int64
+	}
+	if err := db.Table("product_reviews").
+		Select("products.brand_id AS brand_id, AVG(product_reviews.rating) AS average_rating, COUNT(*) AS total_reviews").
+		Joins("JOIN product_variants ON product_variants.id = product_reviews.product_variant_id").
+		Joins("JOIN products ON products.id = product_variants.product_id").
+		Where("product_reviews.deleted_at IS NULL AND products.brand_id IS NOT NULL").
+		Where("product_reviews.status = ?", models.ReviewStatusApproved).
+		Group("products.brand_id").
+		Scan(&ratingRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range ratingRows {
+		if report, ok := reports[row.BrandID]; ok {
+			report.AverageRating = math.Round(row.AverageRating*10) / 10
+			report.TotalReviews = row.TotalReviews
+		}
+	}
+
+	result := make([]BrandAnalytics, 0, len(brands))
+	for _, brand := range brands {
+		result = append(result, *reports[brand.ID])
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Revenue > result[j].Revenue })
+	return result, nil
+}
+
+// GetBrandAnalytics - Admin endpoint for revenue, units sold and average
+// rating per brand, best selling first. from and to (YYYY-MM-DD) bound the
+// order date; reviews are counted over all time.
+func (h *BrandHandler) GetBrandAnalytics(c *gin.Context) {
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "brand/analytics", "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+		from = &t
+	}
+	if value := c.Query("to"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "brand/analytics", "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+		// Include the whole of the last day
+		t = t.AddDate(0, 0, 1)
+		to = &t
+	}
+
+	reports, err := brandAnalytics(h.db.WithContext(c.Request.Context()), from, to)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "brand/analytics", "Failed to build brand analytics")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Brand analytics fetched successfully", reports)
+}
@@ -0,0 +1,77 @@
+package brand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Brand{}, &models.Product{}, &models.ProductVariant{},
+		&models.Order{}, &models.OrderItem{}, &models.ProductReview{}))
+	return db
+}
+
+// createBrandVariant creates a brand with one product and variant
+func createBrandVariant(t *testing.T, db *gorm.DB, name string) *models.ProductVariant {
+	brand := models.Brand{Name: name, Slug: name, Image: "logo"}
+	require.NoError(t, db.Create(&brand).Error)
+	product := models.Product{Name: name + " product", BrandID: &brand.ID}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{ProductID: product.ID, Name: "1kg", SKU: name + "-1", BasePrice: 5}
+	require.NoError(t, db.Create(&variant).Error)
+	return &variant
+}
+
+func createOrder(t *testing.T, db *gorm.DB, number string, status models.OrderStatus, date time.Time, lines map[*models.ProductVariant]int) {
+	order := models.Order{OrderNumber: number, UserID: 1, Status: status, PaymentStatus: models.PaymentStatusPaid, OrderDate: date}
+	require.NoError(t, db.Create(&order).Error)
+	for variant, quantity := range lines {
+		item := models.OrderItem{OrderID: order.ID, ProductVariantID: variant.ID, Quantity: quantity, UnitPrice: variant.BasePrice, TotalAmount: variant.BasePrice * float64(quantity)}
+		require.NoError(t, db.Create(&item).Error)
+	}
+}
+
+func TestBrandAnalytics(t *testing.T) {
+	db := setupTestDB(t)
+	atlas := createBrandVariant(t, db, "atlas")
+	sahara := createBrandVariant(t, db, "sahara")
+	createBrandVariant(t, db, "kasbah")
+
+	march := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	createOrder(t, db, "ORD-1", models.OrderStatusDelivered, march, map[*models.ProductVariant]int{atlas: 2, sahara: 1})
+	createOrder(t, db, "ORD-2", models.OrderStatusProcessing, march.AddDate(0, 1, 0), map[*models.ProductVariant]int{atlas: 3})
+	createOrder(t, db, "ORD-3", models.OrderStatusCancelled, march, map[*models.ProductVariant]int{sahara: 10})
+
+	require.NoError(t, db.Create(&[]models.ProductReview{
+		{ProductVariantID: sahara.ID, UserID: 1, Rating: 5, Status: models.ReviewStatusApproved},
+		{ProductVariantID: sahara.ID, UserID: 2, Rating: 4, Status: models.ReviewStatusApproved},
+		{ProductVariantID: sahara.ID, UserID: 3, Rating: 1, Status: models.ReviewStatusRejected},
+	}).Error)
+
+	reports, err := brandAnalytics(db, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, reports, 3, "brands without sales are listed")
+	assert.Equal(t, "atlas", reports[0].Name, "best selling first")
+	assert.Equal(t, 25.0, reports[0].Revenue)
+	assert.EqualValues(t, 5, reports[0].UnitsSold)
+	assert.EqualValues(t, 2, reports[0].OrderCount)
+	assert.Equal(t, "sahara", reports[1].Name)
+	assert.EqualValues(t, 1, reports[1].UnitsSold, "cancelled orders are left out")
+	assert.Equal(t, 4.5, reports[1].AverageRating, "only approved reviews count")
+	assert.EqualValues(t, 2, reports[1].TotalReviews)
+	assert.Zero(t, reports[2].Revenue)
+
+	from := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	reports, err = brandAnalytics(db, &from, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 15.0, reports[0].Revenue)
+	assert.Zero(t, reports[1].UnitsSold)
+}
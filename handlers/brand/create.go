@@ -24,6 +24,8 @@ func (h *BrandHandler) CreateBrand(c *gin.Context) {
 		}
 	}
 
+	description := c.PostForm("description")
+
 	// Handle image file
 	imageURL := ""
 	fileHeader, err := c.FormFile("image")
@@ -36,6 +38,18 @@ func (h *BrandHandler) CreateBrand(c *gin.Context) {
 		imageURL = fileId
 	}
 
+	// Handle logo file
+	logo := ""
+	logoHeader, err := c.FormFile("logo")
+	if err == nil && logoHeader != nil {
+		fileId, err := h.appwriteService.UploadFile(logoHeader)
+		if err != nil {
+			response.GenerateInternalServerErrorResponse(c, "brand/create", "Failed to upload logo to Appwrite: "+err.Error())
+			return
+		}
+		logo = fileId
+	}
+
 	slug := strings.ToLower(strings.ReplaceAll(name, " ", "_"))
 	tx := h.db.Begin()
 	defer func() {
@@ -56,6 +70,8 @@ func (h *BrandHandler) CreateBrand(c *gin.Context) {
 	brand := models.Brand{
 		Name:        name,
 		Image:       imageURL,
+		Logo:        logo,
+		Description: description,
 		Slug:        slug,
 		IsDisplayed: isDisplayed,
 		ParentID:    parentID,
@@ -1,17 +1,65 @@
 package brand
 
 import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 )
 
+// BrandDetail is a brand page: the brand with the size and rating of its
+// catalogue, sub-brands included
+type BrandDetail struct {
+	models.Brand
+	ProductCount  int64   `json:"product_count"`
+	AverageRating float64 `json:"average_rating"`
+	TotalReviews  int64   `json:"total_reviews"`
+}
+
+// GetBrand - Public endpoint for a brand page by ID or slug
 func (h *BrandHandler) GetBrand(c *gin.Context) {
 	id := c.Param("id")
+	query := h.db.Preload("Parent").Preload("Children")
+	if _, err := strconv.ParseUint(id, 10, 32); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("slug = ?", id)
+	}
 	var brand models.Brand
-	if err := h.db.Preload("Parent").Preload("Children").Where("id = ?", id).First(&brand).Error; err != nil {
+	if err := query.First(&brand).Error; err != nil {
 		response.GenerateNotFoundResponse(c, "brand/get", "Brand not found")
 		return
 	}
-	response.GenerateSuccessResponse(c, "Brand fetched successfully", brand)
+
+	brandIDs := []uint{brand.ID}
+	for _, child := range brand.Children {
+		brandIDs = append(brandIDs, child.ID)
+	}
+	var stats struct {
+		ProductCount int64
+		RatingSum    float64
+		TotalReviews int64
+	}
+	if err := h.db.Model(&models.ProductSummary{}).
+		Select("COUNT(*) AS product_count, COALESCE(SUM(average_rating * total_reviews), 0) AS rating_sum, COALESCE(SUM(total_reviews), 0) AS total_reviews").
+		Where("brand_id IN ? AND is_active = ?", brandIDs, true).
+		Scopes(catalog.Published("product_summaries", time.Now())).
+		Scan(&stats).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "brand/get", "Failed to get brand statistics")
+		return
+	}
+
+	detail := BrandDetail{Brand: brand, ProductCount: stats.ProductCount, TotalReviews: stats.TotalReviews}
+	if stats.TotalReviews > 0 {
+		detail.AverageRating = math.Round(stats.RatingSum/float64(stats.TotalReviews)*10) / 10
+	}
+	detail.Image = h.appwriteService.GetFileURL(detail.Image)
+	if detail.Logo != "" {
+		detail.Logo = h.appwriteService.GetFileURL(detail.Logo)
+	}
+	response.GenerateSuccessResponse(c, "Brand fetched successfully", detail)
 }
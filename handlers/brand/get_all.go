@@ -15,6 +15,9 @@ func (h *BrandHandler) GetAllBrands(c *gin.Context) {
 	// Add Appwrite URLs to brand images
 	for i := range brands {
 		brands[i].Image = h.appwriteService.GetFileURL(brands[i].Image)
+		if brands[i].Logo != "" {
+			brands[i].Logo = h.appwriteService.GetFileURL(brands[i].Logo)
+		}
 	}
 	response.GenerateSuccessResponse(c, "Brands fetched successfully", brands)
 }
@@ -46,6 +46,21 @@ func (h *BrandHandler) UpdateBrand(c *gin.Context) {
 		brand.Image = fileId
 	}
 
+	// Handle logo replacement
+	logoHeader, err := c.FormFile("logo")
+	if err == nil && logoHeader != nil {
+		fileId, err := h.appwriteService.UploadFile(logoHeader)
+		if err != nil {
+			response.GenerateInternalServerErrorResponse(c, "brand/update", "Failed to upload logo to Appwrite: "+err.Error())
+			return
+		}
+		brand.Logo = fileId
+	}
+
+	if description, ok := c.GetPostForm("description"); ok {
+		brand.Description = description
+	}
+
 	brand.Name = name
 	brand.IsDisplayed = isDisplayed
 	brand.Slug = strings.ToLower(strings.ReplaceAll(name, " ", "_"))
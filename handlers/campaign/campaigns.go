@@ -0,0 +1,298 @@
+package campaign
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/campaign"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CampaignRequest struct {
+	Name      string      `json:"name" binding:"required"`
+	SegmentID uint        `json:"segment_id" binding:"required"`
+	Template  string      `json:"template"` // defaults to "promotional"
+	Subject   string      `json:"subject" binding:"required"`
+	Content   string      `json:"content"`
+	ShopURL   string      `json:"shop_url"`
+	Data      models.JSON `json:"data"`
+}
+
+type ScheduleRequest struct {
+	SendAt *time.Time `json:"send_at"` // empty sends now
+}
+
+// campaignFromRequest validates a campaign request into a campaign
+func (h *CampaignHandler) campaignFromRequest(req *CampaignRequest, c *models.Campaign) error {
+	if req.Template == "" {
+		req.Template = "promotional"
+	}
+	if !h.campaigns.HasTemplate(req.Template) {
+		return errors.New("unknown email template " + req.Template)
+	}
+	if req.ShopURL != "" && !strings.HasPrefix(req.ShopURL, "https://") && !strings.HasPrefix(req.ShopURL, "http://") {
+		return errors.New("shop_url must be an http or https URL")
+	}
+	var segment models.Segment
+	if err := h.db.First(&segment, req.SegmentID).Error; err != nil {
+		return errors.New("segment not found")
+	}
+	c.Name = strings.TrimSpace(req.Name)
+	c.SegmentID = segment.ID
+	c.Template = req.Template
+	c.Subject = req.Subject
+	c.Content = req.Content
+	c.ShopURL = req.ShopURL
+	c.Data = req.Data
+	return nil
+}
+
+// CreateCampaign - Admin endpoint to create a draft campaign
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	var req CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/create", err.Error())
+		return
+	}
+	campaign := models.Campaign{Status: models.CampaignStatusDraft, CreatedByID: getUserIDFromContext(c)}
+	if err := h.campaignFromRequest(&req, &campaign); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/create", err.Error())
+		return
+	}
+	if err := h.db.Create(&campaign).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/create", "Failed to create campaign")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Campaign created successfully", campaign)
+}
+
+// GetCampaigns - Admin endpoint to list campaigns
+func (h *CampaignHandler) GetCampaigns(c *gin.Context) {
+	page, pageSize := pagination(c)
+	query := h.db.Model(&models.Campaign{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if segmentID := c.Query("segment_id"); segmentID != "" {
+		query = query.Where("segment_id = ?", segmentID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/list", "Failed to count campaigns")
+		return
+	}
+	var campaigns []models.Campaign
+	if err := query.Preload("Segment").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&campaigns).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/list", "Failed to get campaigns")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaigns retrieved successfully", map[string]interface{}{
+		"data":      campaigns,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetCampaign - Admin endpoint to get a campaign
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	id, ok := parseID(c, "campaign/get", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	var campaign models.Campaign
+	if err := h.db.Preload("Segment").First(&campaign, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "campaign/get", "Campaign not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign retrieved successfully", campaign)
+}
+
+// UpdateCampaign - Admin endpoint to update a campaign that has not started
+// sending
+func (h *CampaignHandler) UpdateCampaign(c *gin.Context) {
+	id, ok := parseID(c, "campaign/update", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	var req CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/update", err.Error())
+		return
+	}
+	var existing models.Campaign
+	if err := h.db.First(&existing, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "campaign/update", "Campaign not found")
+		return
+	}
+	if err := h.campaignFromRequest(&req, &existing); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/update", err.Error())
+		return
+	}
+	// Only update while the campaign is still editable, so a dispatcher
+	// starting it at the same moment sends either the old or new version
+	result := h.db.Model(&models.Campaign{}).
+		Where("id = ? AND status IN ?", id, []models.CampaignStatus{models.CampaignStatusDraft, models.CampaignStatusScheduled}).
+		Select("name", "segment_id", "template", "subject", "content", "shop_url", "data").
+		Updates(&existing)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/update", "Failed to update campaign")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateResponse(c, http.StatusConflict, campaign.ErrNotEditable.Error(), nil,
+			response.NewAPIError("campaign/update", campaign.ErrNotEditable.Error()))
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign updated successfully", existing)
+}
+
+// DeleteCampaign - Admin endpoint to delete a draft campaign
+func (h *CampaignHandler) DeleteCampaign(c *gin.Context) {
+	id, ok := parseID(c, "campaign/delete", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	var existing models.Campaign
+	if err := h.db.First(&existing, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "campaign/delete", "Campaign not found")
+		return
+	}
+	result := h.db.Where("status = ?", models.CampaignStatusDraft).Delete(&models.Campaign{}, id)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/delete", "Failed to delete campaign")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateResponse(c, http.StatusConflict, "Only draft campaigns can be deleted", nil,
+			response.NewAPIError("campaign/delete", "Only draft campaigns can be deleted; cancel it instead"))
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign deleted successfully", nil)
+}
+
+// ScheduleCampaign - Admin endpoint to send a draft campaign now or at a time
+func (h *CampaignHandler) ScheduleCampaign(c *gin.Context) {
+	id, ok := parseID(c, "campaign/schedule", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	var req ScheduleRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.GenerateBadRequestResponse(c, "campaign/schedule", err.Error())
+			return
+		}
+	}
+	result, err := h.campaigns.Schedule(id, req.SendAt)
+	if err != nil {
+		h.statusError(c, "campaign/schedule", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign scheduled successfully", result)
+}
+
+// UnscheduleCampaign - Admin endpoint to return a scheduled campaign to draft
+func (h *CampaignHandler) UnscheduleCampaign(c *gin.Context) {
+	id, ok := parseID(c, "campaign/unschedule", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	result, err := h.campaigns.Unschedule(id)
+	if err != nil {
+		h.statusError(c, "campaign/unschedule", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign unscheduled successfully", result)
+}
+
+// CancelCampaign - Admin endpoint to stop a campaign. Emails already queued
+// are still sent.
+func (h *CampaignHandler) CancelCampaign(c *gin.Context) {
+	id, ok := parseID(c, "campaign/cancel", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	result, err := h.campaigns.Cancel(id)
+	if err != nil {
+		h.statusError(c, "campaign/cancel", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign cancelled successfully", result)
+}
+
+// statusError responds to a failed campaign status change
+func (h *CampaignHandler) statusError(c *gin.Context, code string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, "Campaign not found")
+	case errors.Is(err, campaign.ErrNotSchedulable), errors.Is(err, campaign.ErrNotEditable), errors.Is(err, campaign.ErrNotCancelable):
+		response.GenerateResponse(c, http.StatusConflict, err.Error(), nil, response.NewAPIError(code, err.Error()))
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to update campaign")
+	}
+}
+
+// GetCampaignStats - Admin endpoint for a campaign's sends, opens, clicks,
+// unsubscribes and conversions
+func (h *CampaignHandler) GetCampaignStats(c *gin.Context) {
+	id, ok := parseID(c, "campaign/stats", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	var existing models.Campaign
+	if err := h.db.First(&existing, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "campaign/stats", "Campaign not found")
+		return
+	}
+	stats, err := h.campaigns.Stats(existing.ID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/stats", "Failed to get campaign stats")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign stats retrieved successfully", stats)
+}
+
+// GetCampaignRecipients - Admin endpoint to list the recipients of a campaign
+// with their engagement
+func (h *CampaignHandler) GetCampaignRecipients(c *gin.Context) {
+	id, ok := parseID(c, "campaign/recipients", "Invalid campaign ID")
+	if !ok {
+		return
+	}
+	page, pageSize := pagination(c)
+	query := h.db.Model(&models.CampaignRecipient{}).Where("campaign_id = ?", id)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/recipients", "Failed to count recipients")
+		return
+	}
+	var recipients []models.CampaignRecipient
+	if err := query.Order("id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&recipients).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/recipients", "Failed to get recipients")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Campaign recipients retrieved successfully", map[string]interface{}{
+		"data":      recipients,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
@@ -0,0 +1,166 @@
+package campaign
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/campaign"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type SegmentRequest struct {
+	Name        string               `json:"name" binding:"required"`
+	Description string               `json:"description"`
+	Filter      models.SegmentFilter `json:"filter"`
+}
+
+// previewSize is the number of matching customers a segment preview lists
+const previewSize = 20
+
+// CreateSegment - Admin endpoint to create a customer segment
+func (h *CampaignHandler) CreateSegment(c *gin.Context) {
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/create_segment", err.Error())
+		return
+	}
+	if err := campaign.ValidateFilter(&req.Filter); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/create_segment", err.Error())
+		return
+	}
+	segment := models.Segment{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Filter:      req.Filter,
+	}
+	if err := h.db.Create(&segment).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/create_segment", "Failed to create segment")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Segment created successfully", segment)
+}
+
+// GetSegments - Admin endpoint to list customer segments
+func (h *CampaignHandler) GetSegments(c *gin.Context) {
+	var segments []models.Segment
+	if err := h.db.Order("name").Find(&segments).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/segments", "Failed to get segments")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Segments retrieved successfully", segments)
+}
+
+// GetSegment - Admin endpoint to get a customer segment
+func (h *CampaignHandler) GetSegment(c *gin.Context) {
+	id, ok := parseID(c, "campaign/segment", "Invalid segment ID")
+	if !ok {
+		return
+	}
+	var segment models.Segment
+	if err := h.db.First(&segment, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "campaign/segment", "Segment not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Segment retrieved successfully", segment)
+}
+
+// UpdateSegment - Admin endpoint to update a customer segment. Campaigns
+// already sending keep the customers they started with.
+func (h *CampaignHandler) UpdateSegment(c *gin.Context) {
+	id, ok := parseID(c, "campaign/update_segment", "Invalid segment ID")
+	if !ok {
+		return
+	}
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/update_segment", err.Error())
+		return
+	}
+	if err := campaign.ValidateFilter(&req.Filter); err != nil {
+		response.GenerateBadRequestResponse(c, "campaign/update_segment", err.Error())
+		return
+	}
+	var segment models.Segment
+	if err := h.db.First(&segment, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "campaign/update_segment", "Segment not found")
+		return
+	}
+	segment.Name = strings.TrimSpace(req.Name)
+	segment.Description = req.Description
+	segment.Filter = req.Filter
+	if err := h.db.Save(&segment).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/update_segment", "Failed to update segment")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Segment updated successfully", segment)
+}
+
+// DeleteSegment - Admin endpoint to delete a segment no unfinished campaign uses
+func (h *CampaignHandler) DeleteSegment(c *gin.Context) {
+	id, ok := parseID(c, "campaign/delete_segment", "Invalid segment ID")
+	if !ok {
+		return
+	}
+	var inUse int64
+	if err := h.db.Model(&models.Campaign{}).
+		Where("segment_id = ? AND status IN ?", id, []models.CampaignStatus{
+			models.CampaignStatusDraft, models.CampaignStatusScheduled, models.CampaignStatusSending,
+		}).Count(&inUse).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/delete_segment", "Failed to delete segment")
+		return
+	}
+	if inUse > 0 {
+		response.GenerateResponse(c, http.StatusConflict, "Segment is used by unfinished campaigns", nil,
+			response.NewAPIError("campaign/delete_segment", "Segment is used by unfinished campaigns"))
+		return
+	}
+	result := h.db.Delete(&models.Segment{}, id)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/delete_segment", "Failed to delete segment")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateNotFoundResponse(c, "campaign/delete_segment", "Segment not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Segment deleted successfully", nil)
+}
+
+// PreviewSegment - Admin endpoint returning how many customers a segment
+// matches now, with a sample of them
+func (h *CampaignHandler) PreviewSegment(c *gin.Context) {
+	id, ok := parseID(c, "campaign/preview_segment", "Invalid segment ID")
+	if !ok {
+		return
+	}
+	var segment models.Segment
+	if err := h.db.First(&segment, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "campaign/preview_segment", "Segment not found")
+		return
+	}
+
+	now := time.Now()
+	var count int64
+	if err := campaign.Audience(h.db, &segment.Filter, now).Count(&count).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/preview_segment", "Failed to preview segment")
+		return
+	}
+	var users []models.User
+	if err := campaign.Audience(h.db, &segment.Filter, now).
+		Select("users.id, users.email, users.first_name, users.last_name, users.user_type").
+		Order("users.id").Limit(previewSize).Find(&users).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "campaign/preview_segment", "Failed to preview segment")
+		return
+	}
+	sample := make([]gin.H, len(users))
+	for i, u := range users {
+		sample[i] = gin.H{"id": u.ID, "email": u.Email, "first_name": u.FirstName, "last_name": u.LastName, "user_type": u.UserType}
+	}
+	response.GenerateSuccessResponse(c, "Segment preview retrieved successfully", gin.H{
+		"count":  count,
+		"sample": sample,
+	})
+}
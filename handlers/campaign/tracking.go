@@ -0,0 +1,63 @@
+package campaign
+
+import (
+	"errors"
+	"html"
+	"log/slog"
+	"net/http"
+
+	"github.com/YasserCherfaoui/MarketProGo/campaign"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/gin-gonic/gin"
+)
+
+// pixel is a transparent 1x1 GIF
+var pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackOpen - Public endpoint serving the open-tracking pixel of a campaign
+// email. The pixel is served whatever happens, so a mail client never shows a
+// broken image.
+func (h *CampaignHandler) TrackOpen(c *gin.Context) {
+	if err := h.campaigns.RecordOpen(c.Param("token")); err != nil && !errors.Is(err, campaign.ErrInvalidToken) {
+		slog.ErrorContext(c.Request.Context(), "failed to record campaign open", "component", "campaign", "error", err)
+	}
+	c.Header("Cache-Control", "no-store, max-age=0")
+	c.Data(http.StatusOK, "image/gif", pixel)
+}
+
+// TrackClick - Public endpoint recording a click on a campaign link and
+// redirecting to it. Only signed links are followed, so it is not an open
+// redirect.
+func (h *CampaignHandler) TrackClick(c *gin.Context) {
+	link, err := h.campaigns.RecordClick(c.Param("token"), c.Query("url"), c.Query("sig"))
+	if errors.Is(err, campaign.ErrInvalidToken) {
+		c.String(http.StatusBadRequest, "Invalid link")
+		return
+	}
+	if err != nil {
+		// Still take the customer where they were going
+		slog.ErrorContext(c.Request.Context(), "failed to record campaign click", "component", "campaign", "error", err)
+		link = c.Query("url")
+	}
+	c.Redirect(http.StatusFound, link)
+}
+
+// Unsubscribe - Public endpoint unsubscribing a customer from campaigns from
+// the link in a campaign email. POST supports one-click unsubscribe.
+func (h *CampaignHandler) Unsubscribe(c *gin.Context) {
+	err := h.campaigns.Unsubscribe(c.Param("token"))
+	switch {
+	case err == nil:
+		c.Data(http.StatusOK, "text/html; charset=utf-8",
+			[]byte("<!DOCTYPE html><html><body><p>You have been unsubscribed from "+html.EscapeString(settings.Current().CompanyName)+" marketing emails.</p></body></html>"))
+	case errors.Is(err, campaign.ErrInvalidToken):
+		c.String(http.StatusBadRequest, "Invalid link")
+	default:
+		slog.ErrorContext(c.Request.Context(), "failed to unsubscribe", "component", "campaign", "error", err)
+		c.String(http.StatusInternalServerError, "Failed to unsubscribe, please try again later")
+	}
+}
@@ -2,6 +2,7 @@ package cart
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
@@ -41,13 +42,13 @@ func (h *CartHandler) AddItem(c *gin.Context) {
 
 	// Get the product variant to validate and get pricing
 	var variant models.ProductVariant
-	if err := h.db.First(&variant, req.ProductVariantID).Error; err != nil {
+	if err := h.db.Preload("Product").First(&variant, req.ProductVariantID).Error; err != nil {
 		response.GenerateBadRequestResponse(c, "cart/add_item", "Product variant not found")
 		return
 	}
 
-	// Check if variant is active
-	if !variant.IsActive {
+	// Check if variant is active and its product launched
+	if !variant.IsActive || !variant.Product.IsPublished(time.Now()) {
 		response.GenerateBadRequestResponse(c, "cart/add_item", "Product variant is not available")
 		return
 	}
@@ -60,30 +61,10 @@ func (h *CartHandler) AddItem(c *gin.Context) {
 
 	// Dynamic pricing: fetch price tiers
 	h.db.Model(&variant).Preload("PriceTiers").First(&variant)
-	var unitPrice float64
-	if len(variant.PriceTiers) > 0 {
-		// Sort tiers by MinQuantity descending
-		tiers := variant.PriceTiers
-		for i := range tiers {
-			for j := i + 1; j < len(tiers); j++ {
-				if tiers[j].MinQuantity > tiers[i].MinQuantity {
-					tiers[i], tiers[j] = tiers[j], tiers[i]
-				}
-			}
-		}
-		unitPrice = variant.BasePrice
-		for _, tier := range tiers {
-			if req.Quantity >= tier.MinQuantity {
-				unitPrice = tier.Price
-				break
-			}
-		}
-	} else {
-		if req.PriceType == "b2b" {
-			unitPrice = variant.B2BPrice
-		} else {
-			unitPrice = variant.BasePrice
-		}
+	unitPrice, err := h.priceResolver.UnitPrice(uid, &variant, req.Quantity, req.PriceType)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cart/add_item", err.Error())
+		return
 	}
 
 	// Get or create cart
@@ -92,10 +73,13 @@ func (h *CartHandler) AddItem(c *gin.Context) {
 
 	// Check if item already exists in cart
 	var item models.CartItem
-	err := h.db.Where("cart_id = ? AND product_variant_id = ? AND price_type = ?", cart.ID, req.ProductVariantID, req.PriceType).First(&item).Error
+	err = h.db.Where("cart_id = ? AND product_variant_id = ? AND price_type = ?", cart.ID, req.ProductVariantID, req.PriceType).First(&item).Error
 	if err == nil {
-		// Update existing item
+		// Update existing item, re-pricing for the combined quantity
 		item.Quantity += req.Quantity
+		if price, err := h.priceResolver.UnitPrice(uid, &variant, item.Quantity, req.PriceType); err == nil {
+			item.UnitPrice = price
+		}
 		item.TotalPrice = float64(item.Quantity) * item.UnitPrice
 		h.db.Save(&item)
 	} else {
@@ -115,5 +99,7 @@ func (h *CartHandler) AddItem(c *gin.Context) {
 	// Preload variant and product data for response
 	h.db.Preload("ProductVariant.Product").Preload("ProductVariant.Images").First(&item, item.ID)
 
+	h.rescheduleRecoveryEmail(cart.ID)
+
 	response.GenerateSuccessResponse(c, "cart/add_item", item)
 }
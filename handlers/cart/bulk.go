@@ -0,0 +1,100 @@
+package cart
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	cartService "github.com/YasserCherfaoui/MarketProGo/cart"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkUploadSize limits bulk order CSV uploads
+const maxBulkUploadSize = 2 << 20
+
+type BulkOrderRequest struct {
+	Items []cartService.BulkLine `json:"items" binding:"required,min=1"`
+}
+
+// BulkOrder prices a B2B buyer's list of SKUs and quantities, sent as JSON,
+// a CSV body or an uploaded CSV file, and returns a draft order with the
+// errors of each line. With ?confirm=true the lines are added to the cart
+// for checkout when none is in error.
+func (h *CartHandler) BulkOrder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "cart/bulk_order", "Unauthorized")
+		return
+	}
+	uid := userID.(uint)
+
+	lines, err := bulkOrderLines(c)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cart/bulk_order", err.Error())
+		return
+	}
+
+	draft, err := h.cartService.BulkOrder(uid, lines, c.Query("confirm") == "true")
+	switch {
+	case err == nil:
+	case errors.Is(err, cartService.ErrNotB2B):
+		response.GenerateForbiddenResponse(c, "cart/bulk_order", err.Error())
+		return
+	case errors.Is(err, cartService.ErrInvalidBulkOrder):
+		response.GenerateBadRequestResponse(c, "cart/bulk_order", err.Error())
+		return
+	case errors.Is(err, cartService.ErrBulkOrderHasErrors):
+		// The draft reports the lines in error
+		response.GenerateResponse(c, http.StatusBadRequest, err.Error(), draft, response.NewAPIError("cart/bulk_order", err.Error()))
+		return
+	default:
+		response.GenerateInternalServerErrorResponse(c, "cart/bulk_order", err.Error())
+		return
+	}
+
+	if draft.Confirmed {
+		h.rescheduleRecoveryEmail(draft.Cart.ID)
+		response.GenerateSuccessResponse(c, "Bulk order added to cart", draft)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Bulk order priced", draft)
+}
+
+// bulkOrderLines reads the lines of a bulk order from the request body
+func bulkOrderLines(c *gin.Context) ([]cartService.BulkLine, error) {
+	contentType := c.ContentType()
+	switch {
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("a CSV file is required")
+		}
+		if fileHeader.Size > maxBulkUploadSize {
+			return nil, errors.New("file is too large")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, errors.New("failed to read file")
+		}
+		defer file.Close()
+		return cartService.ParseBulkCSV(file)
+	case contentType == "text/csv":
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkUploadSize))
+		if err != nil {
+			return nil, errors.New("file is too large")
+		}
+		return cartService.ParseBulkCSV(bytes.NewReader(body))
+	default:
+		var req BulkOrderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("no items to order")
+			}
+			return nil, err
+		}
+		return req.Items, nil
+	}
+}
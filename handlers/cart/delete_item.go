@@ -40,5 +40,7 @@ func (h *CartHandler) DeleteItem(c *gin.Context) {
 	}
 
 	h.db.Delete(&item)
+	h.rescheduleRecoveryEmail(cart.ID)
+
 	response.GenerateSuccessResponse(c, "cart/delete_item", "Item removed from cart")
 }
@@ -1,13 +1,24 @@
 package cart
 
 import (
+	cartService "github.com/YasserCherfaoui/MarketProGo/cart"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
 	"gorm.io/gorm"
 )
 
 type CartHandler struct {
-	db *gorm.DB
+	db              *gorm.DB
+	cartService     *cartService.CartService
+	priceResolver   *pricing.Resolver
+	emailTriggerSvc *email.EmailTriggerService
 }
 
-func NewCartHandler(db *gorm.DB) *CartHandler {
-	return &CartHandler{db: db}
+func NewCartHandler(db *gorm.DB, cartService *cartService.CartService, emailTriggerSvc *email.EmailTriggerService) *CartHandler {
+	return &CartHandler{
+		db:              db,
+		cartService:     cartService,
+		priceResolver:   pricing.NewResolver(db),
+		emailTriggerSvc: emailTriggerSvc,
+	}
 }
@@ -0,0 +1,58 @@
+package cart
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// rescheduleRecoveryEmail restarts the abandoned-cart timer after cart activity:
+// any pending reminder is canceled and, if the cart still has items, a new one
+// is scheduled.
+func (h *CartHandler) rescheduleRecoveryEmail(cartID uint) {
+	if h.emailTriggerSvc == nil {
+		return
+	}
+
+	var cart models.Cart
+	if err := h.db.Preload("User").Preload("Items.ProductVariant.Product").First(&cart, cartID).Error; err != nil {
+		log.Printf("CART: Failed to load cart %d for recovery email: %v", cartID, err)
+		return
+	}
+
+	if cart.RecoveryEmailID != nil {
+		if err := h.emailTriggerSvc.CancelScheduledEmail(*cart.RecoveryEmailID); err != nil {
+			log.Printf("CART: Failed to cancel recovery email %d: %v", *cart.RecoveryEmailID, err)
+		}
+	}
+
+	var recoveryEmailID *uint
+	if len(cart.Items) > 0 && cart.User != nil && cart.User.Email != "" {
+		items := make([]map[string]interface{}, 0, len(cart.Items))
+		for _, item := range cart.Items {
+			if item.ProductVariant == nil {
+				continue
+			}
+			items = append(items, map[string]interface{}{
+				"Name":  fmt.Sprintf("%s - %s", item.ProductVariant.Product.Name, item.ProductVariant.Name),
+				"Price": fmt.Sprintf("£%.2f", item.TotalPrice),
+			})
+		}
+
+		scheduled, err := h.emailTriggerSvc.ScheduleCartRecovery(
+			cart.User.Email,
+			fmt.Sprintf("%s %s", cart.User.FirstName, cart.User.LastName),
+			map[string]interface{}{"items": items},
+		)
+		if err != nil {
+			log.Printf("CART: Failed to schedule recovery email for cart %d: %v", cart.ID, err)
+		} else {
+			recoveryEmailID = &scheduled.ID
+		}
+	}
+
+	if err := h.db.Model(&cart).Update("recovery_email_id", recoveryEmailID).Error; err != nil {
+		log.Printf("CART: Failed to save recovery email for cart %d: %v", cart.ID, err)
+	}
+}
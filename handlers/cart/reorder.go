@@ -0,0 +1,48 @@
+package cart
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	cartService "github.com/YasserCherfaoui/MarketProGo/cart"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Reorder adds the items of one of the current user's previous orders to
+// their cart at current prices, reporting items that were reduced to the
+// stock available, substituted or could not be added
+func (h *CartHandler) Reorder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "cart/reorder", "Unauthorized")
+		return
+	}
+	uid := userID.(uint)
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cart/reorder", "Invalid order ID")
+		return
+	}
+
+	result, err := h.cartService.Reorder(uid, uint(orderID))
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "cart/reorder", "Order not found")
+		return
+	case errors.Is(err, cartService.ErrNothingToReorder):
+		// The result explains why each item could not be added
+		response.GenerateResponse(c, http.StatusBadRequest, err.Error(), result, response.NewAPIError("cart/reorder", err.Error()))
+		return
+	default:
+		response.GenerateInternalServerErrorResponse(c, "cart/reorder", err.Error())
+		return
+	}
+
+	h.rescheduleRecoveryEmail(result.Cart.ID)
+	response.GenerateSuccessResponse(c, "Order items added to cart", result)
+}
@@ -53,22 +53,10 @@ func (h *CartHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 	// Dynamic pricing: select price tier
-	unitPrice := variant.BasePrice
-	if len(variant.PriceTiers) > 0 {
-		tiers := variant.PriceTiers
-		for i := range tiers {
-			for j := i + 1; j < len(tiers); j++ {
-				if tiers[j].MinQuantity > tiers[i].MinQuantity {
-					tiers[i], tiers[j] = tiers[j], tiers[i]
-				}
-			}
-		}
-		for _, tier := range tiers {
-			if req.Quantity >= tier.MinQuantity {
-				unitPrice = tier.Price
-				break
-			}
-		}
+	unitPrice, err := h.priceResolver.UnitPrice(uid, &variant, req.Quantity, item.PriceType)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cart/update_item", err.Error())
+		return
 	}
 	item.Quantity = req.Quantity
 	item.UnitPrice = unitPrice
@@ -79,5 +67,7 @@ func (h *CartHandler) UpdateItem(c *gin.Context) {
 	// Preload variant and product data for response
 	h.db.Preload("ProductVariant.Product").Preload("ProductVariant.Images").First(&item, item.ID)
 
+	h.rescheduleRecoveryEmail(cart.ID)
+
 	response.GenerateSuccessResponse(c, "cart/update_item", item)
 }
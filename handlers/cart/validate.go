@@ -0,0 +1,30 @@
+package cart
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ValidateCart revalidates the current user's cart against current prices,
+// price tiers, stock and availability and returns line-level warnings
+func (h *CartHandler) ValidateCart(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "cart/validate", "Unauthorized")
+		return
+	}
+	uid := userID.(uint)
+
+	result, err := h.cartService.Revalidate(uid)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateNotFoundResponse(c, "cart/validate", "Cart not found")
+			return
+		}
+		response.GenerateInternalServerErrorResponse(c, "cart/validate", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "cart/validate", result)
+}
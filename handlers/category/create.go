@@ -1,13 +1,16 @@
 package category
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func (h *CategoryHandler) CreateCategory(c *gin.Context) {
@@ -88,6 +91,17 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 		return
 	}
 
+	// Place the category in the tree, last among its siblings
+	if err := catalog.PlaceCategory(tx, &category); err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateBadRequestResponse(c, "category/create", "Parent category not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "category/create", err.Error())
+		}
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "category/commit", err.Error())
 		return
@@ -1,16 +1,35 @@
 package category
 
 import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 	categoryID := c.Param("id")
 
-	if err := h.db.Delete(&models.Category{}, categoryID).Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "category/delete", err.Error())
+	// Children move up to the deleted category's parent
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.First(&category, categoryID).Error; err != nil {
+			return err
+		}
+		if err := catalog.RemoveCategory(tx, &category); err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "category/delete", "Category not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "category/delete", err.Error())
+		}
 		return
 	}
 
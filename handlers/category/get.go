@@ -1,6 +1,9 @@
 package category
 
 import (
+	"log/slog"
+
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
@@ -13,5 +16,8 @@ func (h *CategoryHandler) GetCategory(c *gin.Context) {
 		response.GenerateNotFoundResponse(c, "category/get", "Category not found")
 		return
 	}
+	if err := i18n.TranslateCategories(h.db, i18n.Locale(c), []*models.Category{&category}); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to translate category", "component", "category", "category_id", id, "error", err)
+	}
 	response.GenerateSuccessResponse(c, "Category fetched successfully", category)
 }
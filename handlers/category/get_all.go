@@ -1,6 +1,9 @@
 package category
 
 import (
+	"log/slog"
+
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
@@ -8,7 +11,7 @@ import (
 
 func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
 	var categories []models.Category
-	if err := h.db.Preload("Parent").Preload("Children").Preload("Products").Order("name ASC").Find(&categories).Error; err != nil {
+	if err := h.db.Preload("Parent").Preload("Children").Preload("Products").Order("depth ASC, position ASC, name ASC").Find(&categories).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "category/get_all", "Failed to get all categories")
 		return
 	}
@@ -17,5 +20,17 @@ func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
 		imageURL := h.appwriteService.GetFileURL(categories[i].Image)
 		categories[i].Image = imageURL
 	}
+	// Translate each category with its parent and children
+	var translated []*models.Category
+	for i := range categories {
+		translated = append(translated, &categories[i])
+		if categories[i].Parent != nil {
+			translated = append(translated, categories[i].Parent)
+		}
+		translated = append(translated, categories[i].Children...)
+	}
+	if err := i18n.TranslateCategories(h.db, i18n.Locale(c), translated); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to translate categories", "component", "category", "error", err)
+	}
 	response.GenerateSuccessResponse(c, "Categories fetched successfully", categories)
 }
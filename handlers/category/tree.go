@@ -0,0 +1,114 @@
+package category
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MoveCategoryRequest places a category under parent_id, or at the top level
+// when it is null, at position among its new siblings
+type MoveCategoryRequest struct {
+	ParentID *uint `json:"parent_id"`
+	Position *int  `json:"position" binding:"omitempty,min=0"`
+}
+
+// ReorderCategoriesRequest lists children of parent_id, or top level
+// categories when it is null, in their new order
+type ReorderCategoriesRequest struct {
+	ParentID *uint  `json:"parent_id"`
+	IDs      []uint `json:"ids" binding:"required,min=1"`
+}
+
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func respondMoveError(c *gin.Context, code string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, "Category not found")
+	case errors.Is(err, catalog.ErrCategoryCycle), errors.Is(err, catalog.ErrCategoryNotSibling):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to move category")
+	}
+}
+
+// GetCategoryTree - Public endpoint for the nested category tree, siblings in
+// display order
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := catalog.CategoryTree(h.db)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "category/tree", "Failed to get category tree")
+		return
+	}
+	var categories []*models.Category
+	var resolve func(nodes []*catalog.CategoryNode)
+	resolve = func(nodes []*catalog.CategoryNode) {
+		for _, node := range nodes {
+			node.Image = h.appwriteService.GetFileURL(node.Image)
+			categories = append(categories, &node.Category)
+			resolve(node.Children)
+		}
+	}
+	resolve(tree)
+	if err := i18n.TranslateCategories(h.db, i18n.Locale(c), categories); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to translate category tree", "component", "category", "error", err)
+	}
+	response.GenerateSuccessResponse(c, "Category tree fetched successfully", tree)
+}
+
+// MoveCategory - Admin endpoint to move a category and its subtree under
+// another parent and/or to another position among its siblings
+func (h *CategoryHandler) MoveCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "category/move", "Invalid category ID")
+		return
+	}
+	var req MoveCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "category/move", err.Error())
+		return
+	}
+
+	var category *models.Category
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		category, err = catalog.MoveCategory(tx, uint(id), req.ParentID, req.Position)
+		return err
+	})
+	if err != nil {
+		respondMoveError(c, "category/move", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Category moved successfully", category)
+}
+
+// ReorderCategories - Admin endpoint to set the order of a category's children
+func (h *CategoryHandler) ReorderCategories(c *gin.Context) {
+	var req ReorderCategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "category/reorder", err.Error())
+		return
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return catalog.ReorderCategories(tx, req.ParentID, req.IDs)
+	})
+	if err != nil {
+		respondMoveError(c, "category/reorder", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Categories reordered successfully", nil)
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
@@ -64,7 +65,6 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 
 	category.Name = name
 	category.Description = description
-	category.ParentID = parentID
 
 	// Handle new image file (replace old image if provided)
 	fileHeader, err := c.FormFile("image")
@@ -94,6 +94,17 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		return
 	}
 
+	// A new parent moves the category and its subtree
+	if !sameParent(category.ParentID, parentID) {
+		moved, err := catalog.MoveCategory(tx, category.ID, parentID, nil)
+		if err != nil {
+			tx.Rollback()
+			respondMoveError(c, "category/update", err)
+			return
+		}
+		category.ParentID, category.Path, category.Depth, category.Position = moved.ParentID, moved.Path, moved.Depth, moved.Position
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "category/commit", err.Error())
 		return
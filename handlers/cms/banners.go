@@ -0,0 +1,190 @@
+package cms
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListBanners - Admin endpoint to list every banner, scheduled or not
+func (h *CMSHandler) ListBanners(c *gin.Context) {
+	var banners []models.Banner
+	if err := h.db.Order("position ASC, id ASC").Find(&banners).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/banners", "Failed to get banners")
+		return
+	}
+	for i := range banners {
+		banners[i].Image = h.appwriteService.GetFileURL(banners[i].Image)
+	}
+	response.GenerateSuccessResponse(c, "Banners retrieved successfully", banners)
+}
+
+// CreateBanner - Admin endpoint to create a banner from a multipart form
+// with its image
+func (h *CMSHandler) CreateBanner(c *gin.Context) {
+	banner := models.Banner{
+		Title:      c.PostForm("title"),
+		Subtitle:   c.PostForm("subtitle"),
+		Link:       c.PostForm("link"),
+		ButtonText: c.PostForm("button_text"),
+		IsActive:   true,
+	}
+	if banner.Title == "" {
+		response.GenerateBadRequestResponse(c, "cms/banner_create", "Title is required")
+		return
+	}
+	position, _, err := formInt(c, "position")
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cms/banner_create", err.Error())
+		return
+	}
+	banner.Position = position
+	if isActive, set := formBool(c, "is_active"); set {
+		banner.IsActive = isActive
+	}
+	window := schedule{}
+	if err := readSchedule(c, &window); err != nil {
+		response.GenerateBadRequestResponse(c, "cms/banner_create", err.Error())
+		return
+	}
+	banner.StartsAt, banner.EndsAt = window.StartsAt, window.EndsAt
+
+	image, err := h.uploadImage(c)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/banner_create", "Failed to upload image to Appwrite: "+err.Error())
+		return
+	}
+	if image == "" {
+		response.GenerateBadRequestResponse(c, "cms/banner_create", "Image is required")
+		return
+	}
+	banner.Image = image
+
+	if err := h.db.Create(&banner).Error; err != nil {
+		h.appwriteService.DeleteFile(image)
+		response.GenerateInternalServerErrorResponse(c, "cms/banner_create", "Failed to create banner")
+		return
+	}
+	if !banner.IsActive {
+		h.db.Model(&banner).Update("is_active", false)
+	}
+	banner.Image = h.appwriteService.GetFileURL(banner.Image)
+	response.GenerateCreatedResponse(c, "Banner created successfully", banner)
+}
+
+// UpdateBanner - Admin endpoint to update the fields sent in a multipart
+// form. A new image replaces the old one in Appwrite.
+func (h *CMSHandler) UpdateBanner(c *gin.Context) {
+	id, ok := parseID(c, "cms/banner_update", "Invalid banner ID")
+	if !ok {
+		return
+	}
+	var banner models.Banner
+	if err := h.db.First(&banner, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "cms/banner_update", "Banner not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/banner_update", "Failed to get banner")
+		}
+		return
+	}
+
+	updates := map[string]interface{}{}
+	for field, column := range map[string]string{"title": "title", "subtitle": "subtitle", "link": "link", "button_text": "button_text"} {
+		if value, set := c.GetPostForm(field); set {
+			updates[column] = value
+		}
+	}
+	if title, set := updates["title"]; set && title == "" {
+		response.GenerateBadRequestResponse(c, "cms/banner_update", "Title cannot be empty")
+		return
+	}
+	position, set, err := formInt(c, "position")
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cms/banner_update", err.Error())
+		return
+	}
+	if set {
+		updates["position"] = position
+	}
+	if isActive, set := formBool(c, "is_active"); set {
+		updates["is_active"] = isActive
+	}
+	window := schedule{StartsAt: banner.StartsAt, EndsAt: banner.EndsAt}
+	if err := readSchedule(c, &window); err != nil {
+		response.GenerateBadRequestResponse(c, "cms/banner_update", err.Error())
+		return
+	}
+	updates["starts_at"], updates["ends_at"] = window.StartsAt, window.EndsAt
+
+	image, err := h.uploadImage(c)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/banner_update", "Failed to upload image to Appwrite: "+err.Error())
+		return
+	}
+	oldImage := banner.Image
+	if image != "" {
+		updates["image"] = image
+	}
+
+	if err := h.db.Model(&banner).Updates(updates).Error; err != nil {
+		if image != "" {
+			h.appwriteService.DeleteFile(image)
+		}
+		response.GenerateInternalServerErrorResponse(c, "cms/banner_update", "Failed to update banner")
+		return
+	}
+	if image != "" && oldImage != "" {
+		h.appwriteService.DeleteFile(oldImage)
+	}
+	h.db.First(&banner, id)
+	banner.Image = h.appwriteService.GetFileURL(banner.Image)
+	response.GenerateSuccessResponse(c, "Banner updated successfully", banner)
+}
+
+// DeleteBanner - Admin endpoint to delete a banner. The banner is soft
+// deleted, so its image is kept.
+func (h *CMSHandler) DeleteBanner(c *gin.Context) {
+	id, ok := parseID(c, "cms/banner_delete", "Invalid banner ID")
+	if !ok {
+		return
+	}
+	var banner models.Banner
+	if err := h.db.First(&banner, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "cms/banner_delete", "Banner not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/banner_delete", "Failed to get banner")
+		}
+		return
+	}
+	if err := h.db.Delete(&banner).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/banner_delete", "Failed to delete banner")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Banner deleted successfully", nil)
+}
+
+// ReorderBanners - Admin endpoint to set the display order of banners
+func (h *CMSHandler) ReorderBanners(c *gin.Context) {
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "cms/banner_reorder", err.Error())
+		return
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return reorder(tx, &models.Banner{}, req.IDs)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "cms/banner_reorder", "Banner not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/banner_reorder", "Failed to reorder banners")
+		}
+		return
+	}
+	response.GenerateSuccessResponse(c, "Banners reordered successfully", nil)
+}
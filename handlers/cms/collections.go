@@ -0,0 +1,350 @@
+package cms
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var errUnknownProduct = errors.New("product_ids contains an unknown product")
+
+// formProductIDs reads the product_ids form field, given once per product in
+// display order
+func formProductIDs(c *gin.Context) (ids []uint, set bool, err error) {
+	raw, set := c.GetPostFormArray("product_ids")
+	if !set {
+		return nil, false, nil
+	}
+	seen := map[uint]bool{}
+	for _, value := range raw {
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, true, errors.New("invalid product_ids")
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, true, nil
+}
+
+// replaceItems replaces the products of a collection with ids, in order
+func replaceItems(tx *gorm.DB, collectionID uint, ids []uint) error {
+	if len(ids) > 0 {
+		var count int64
+		if err := tx.Model(&models.Product{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return errUnknownProduct
+		}
+	}
+	if err := tx.Where("collection_id = ?", collectionID).Delete(&models.CollectionProduct{}).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	items := make([]models.CollectionProduct, len(ids))
+	for i, id := range ids {
+		items[i] = models.CollectionProduct{CollectionID: collectionID, ProductID: id, Position: i}
+	}
+	return tx.Create(&items).Error
+}
+
+// loadCollection loads a collection with its products in display order
+func (h *CMSHandler) loadCollection(id uint) (*models.Collection, error) {
+	var collection models.Collection
+	err := h.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Items.Product.Images").First(&collection, id).Error
+	if err != nil {
+		return nil, err
+	}
+	h.resolveCollectionImages(&collection)
+	return &collection, nil
+}
+
+// resolveCollectionImages turns the Appwrite file IDs of a collection into
+// URLs
+func (h *CMSHandler) resolveCollectionImages(collection *models.Collection) {
+	if collection.Image != "" {
+		collection.Image = h.appwriteService.GetFileURL(collection.Image)
+	}
+	for i := range collection.Items {
+		if product := collection.Items[i].Product; product != nil {
+			h.images.ResolveAll(product.Images)
+		}
+	}
+}
+
+// ListCollections - Admin endpoint to list every collection, scheduled or not
+func (h *CMSHandler) ListCollections(c *gin.Context) {
+	var collections []models.Collection
+	if err := h.db.Order("position ASC, id ASC").Find(&collections).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/collections", "Failed to get collections")
+		return
+	}
+	for i := range collections {
+		h.resolveCollectionImages(&collections[i])
+	}
+	response.GenerateSuccessResponse(c, "Collections retrieved successfully", collections)
+}
+
+// GetCollection - Admin endpoint for a collection and its products
+func (h *CMSHandler) GetCollection(c *gin.Context) {
+	id, ok := parseID(c, "cms/collection_get", "Invalid collection ID")
+	if !ok {
+		return
+	}
+	collection, err := h.loadCollection(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "cms/collection_get", "Collection not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/collection_get", "Failed to get collection")
+		}
+		return
+	}
+	response.GenerateSuccessResponse(c, "Collection retrieved successfully", collection)
+}
+
+// CreateCollection - Admin endpoint to create a collection from a multipart
+// form, with an optional image and its products as repeated product_ids
+func (h *CMSHandler) CreateCollection(c *gin.Context) {
+	collection := models.Collection{
+		Title:       c.PostForm("title"),
+		Slug:        c.PostForm("slug"),
+		Description: c.PostForm("description"),
+		IsActive:    true,
+	}
+	if collection.Title == "" {
+		response.GenerateBadRequestResponse(c, "cms/collection_create", "Title is required")
+		return
+	}
+	if !slugPattern.MatchString(collection.Slug) {
+		response.GenerateBadRequestResponse(c, "cms/collection_create", "Slug must be lower case letters, digits and dashes")
+		return
+	}
+	position, _, err := formInt(c, "position")
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cms/collection_create", err.Error())
+		return
+	}
+	collection.Position = position
+	if isActive, set := formBool(c, "is_active"); set {
+		collection.IsActive = isActive
+	}
+	window := schedule{}
+	if err := readSchedule(c, &window); err != nil {
+		response.GenerateBadRequestResponse(c, "cms/collection_create", err.Error())
+		return
+	}
+	collection.StartsAt, collection.EndsAt = window.StartsAt, window.EndsAt
+	productIDs, _, err := formProductIDs(c)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cms/collection_create", err.Error())
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.Collection{}).Unscoped().Where("slug = ?", collection.Slug).Count(&count)
+	if count > 0 {
+		response.GenerateBadRequestResponse(c, "cms/collection_create", "Slug is already in use")
+		return
+	}
+
+	image, err := h.uploadImage(c)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/collection_create", "Failed to upload image to Appwrite: "+err.Error())
+		return
+	}
+	collection.Image = image
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&collection).Error; err != nil {
+			return err
+		}
+		if !collection.IsActive {
+			if err := tx.Model(&collection).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return replaceItems(tx, collection.ID, productIDs)
+	})
+	if err != nil {
+		if image != "" {
+			h.appwriteService.DeleteFile(image)
+		}
+		if errors.Is(err, errUnknownProduct) {
+			response.GenerateBadRequestResponse(c, "cms/collection_create", err.Error())
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/collection_create", "Failed to create collection")
+		}
+		return
+	}
+
+	created, err := h.loadCollection(collection.ID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/collection_create", "Failed to get collection")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Collection created successfully", created)
+}
+
+// UpdateCollection - Admin endpoint to update the fields sent in a multipart
+// form. Sending product_ids replaces the collection's products.
+func (h *CMSHandler) UpdateCollection(c *gin.Context) {
+	id, ok := parseID(c, "cms/collection_update", "Invalid collection ID")
+	if !ok {
+		return
+	}
+	var collection models.Collection
+	if err := h.db.First(&collection, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "cms/collection_update", "Collection not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/collection_update", "Failed to get collection")
+		}
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if title, set := c.GetPostForm("title"); set {
+		if title == "" {
+			response.GenerateBadRequestResponse(c, "cms/collection_update", "Title cannot be empty")
+			return
+		}
+		updates["title"] = title
+	}
+	if slug, set := c.GetPostForm("slug"); set && slug != collection.Slug {
+		if !slugPattern.MatchString(slug) {
+			response.GenerateBadRequestResponse(c, "cms/collection_update", "Slug must be lower case letters, digits and dashes")
+			return
+		}
+		var count int64
+		h.db.Model(&models.Collection{}).Unscoped().Where("slug = ? AND id <> ?", slug, id).Count(&count)
+		if count > 0 {
+			response.GenerateBadRequestResponse(c, "cms/collection_update", "Slug is already in use")
+			return
+		}
+		updates["slug"] = slug
+	}
+	if description, set := c.GetPostForm("description"); set {
+		updates["description"] = description
+	}
+	position, set, err := formInt(c, "position")
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cms/collection_update", err.Error())
+		return
+	}
+	if set {
+		updates["position"] = position
+	}
+	if isActive, set := formBool(c, "is_active"); set {
+		updates["is_active"] = isActive
+	}
+	window := schedule{StartsAt: collection.StartsAt, EndsAt: collection.EndsAt}
+	if err := readSchedule(c, &window); err != nil {
+		response.GenerateBadRequestResponse(c, "cms/collection_update", err.Error())
+		return
+	}
+	updates["starts_at"], updates["ends_at"] = window.StartsAt, window.EndsAt
+	productIDs, replaceProducts, err := formProductIDs(c)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "cms/collection_update", err.Error())
+		return
+	}
+
+	image, err := h.uploadImage(c)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/collection_update", "Failed to upload image to Appwrite: "+err.Error())
+		return
+	}
+	oldImage := collection.Image
+	if image != "" {
+		updates["image"] = image
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&collection).Updates(updates).Error; err != nil {
+			return err
+		}
+		if replaceProducts {
+			return replaceItems(tx, collection.ID, productIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		if image != "" {
+			h.appwriteService.DeleteFile(image)
+		}
+		if errors.Is(err, errUnknownProduct) {
+			response.GenerateBadRequestResponse(c, "cms/collection_update", err.Error())
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/collection_update", "Failed to update collection")
+		}
+		return
+	}
+	if image != "" && oldImage != "" {
+		h.appwriteService.DeleteFile(oldImage)
+	}
+
+	updated, err := h.loadCollection(id)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/collection_update", "Failed to get collection")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Collection updated successfully", updated)
+}
+
+// DeleteCollection - Admin endpoint to delete a collection. The collection is
+// soft deleted, so its image and products are kept.
+func (h *CMSHandler) DeleteCollection(c *gin.Context) {
+	id, ok := parseID(c, "cms/collection_delete", "Invalid collection ID")
+	if !ok {
+		return
+	}
+	result := h.db.Delete(&models.Collection{}, id)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/collection_delete", "Failed to delete collection")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateNotFoundResponse(c, "cms/collection_delete", "Collection not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Collection deleted successfully", nil)
+}
+
+// ReorderCollections - Admin endpoint to set the display order of collections
+func (h *CMSHandler) ReorderCollections(c *gin.Context) {
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "cms/collection_reorder", err.Error())
+		return
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return reorder(tx, &models.Collection{}, req.IDs)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "cms/collection_reorder", "Collection not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "cms/collection_reorder", "Failed to reorder collections")
+		}
+		return
+	}
+	response.GenerateSuccessResponse(c, "Collections reordered successfully", nil)
+}
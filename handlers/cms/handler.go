@@ -0,0 +1,140 @@
+package cms
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/cache"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CMSHandler struct {
+	db              *gorm.DB
+	appwriteService *aw.AppwriteService
+	images          *storage.Images
+	catalog         *cache.Catalog
+}
+
+func NewCMSHandler(db *gorm.DB, appwriteService *aw.AppwriteService, images *storage.Images, catalog *cache.Catalog) *CMSHandler {
+	return &CMSHandler{
+		db:              db,
+		appwriteService: appwriteService,
+		images:          images,
+		catalog:         catalog,
+	}
+}
+
+// ReorderRequest lists IDs in their new display order
+type ReorderRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1"`
+}
+
+// schedule is the scheduling window shared by banners and collections
+type schedule struct {
+	StartsAt *time.Time
+	EndsAt   *time.Time
+}
+
+var errInvalidWindow = errors.New("ends_at must be after starts_at")
+
+// parseID parses the id path parameter, responding with a bad request when
+// it is not a number
+func parseID(c *gin.Context, code, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, message)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// formTime reads an optional RFC3339 form field. set is false when the field
+// is absent; an empty value clears the time.
+func formTime(c *gin.Context, field string) (value *time.Time, set bool, err error) {
+	raw, ok := c.GetPostForm(field)
+	if !ok {
+		return nil, false, nil
+	}
+	if raw == "" {
+		return nil, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid %s format. Use RFC3339", field)
+	}
+	return &t, true, nil
+}
+
+// formInt reads an optional integer form field
+func formInt(c *gin.Context, field string) (value int, set bool, err error) {
+	raw, ok := c.GetPostForm(field)
+	if !ok || raw == "" {
+		return 0, false, nil
+	}
+	value, err = strconv.Atoi(raw)
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid %s", field)
+	}
+	return value, true, nil
+}
+
+// formBool reads an optional boolean form field
+func formBool(c *gin.Context, field string) (value, set bool) {
+	raw, ok := c.GetPostForm(field)
+	if !ok || raw == "" {
+		return false, false
+	}
+	return raw == "true" || raw == "1", true
+}
+
+// readSchedule applies the starts_at and ends_at form fields to a window
+func readSchedule(c *gin.Context, window *schedule) error {
+	startsAt, set, err := formTime(c, "starts_at")
+	if err != nil {
+		return err
+	}
+	if set {
+		window.StartsAt = startsAt
+	}
+	endsAt, set, err := formTime(c, "ends_at")
+	if err != nil {
+		return err
+	}
+	if set {
+		window.EndsAt = endsAt
+	}
+	if window.StartsAt != nil && window.EndsAt != nil && !window.EndsAt.After(*window.StartsAt) {
+		return errInvalidWindow
+	}
+	return nil
+}
+
+// uploadImage uploads the image form file to Appwrite, returning its file ID
+// or an empty string when no file was sent
+func (h *CMSHandler) uploadImage(c *gin.Context) (string, error) {
+	fileHeader, err := c.FormFile("image")
+	if err != nil || fileHeader == nil {
+		return "", nil
+	}
+	return h.appwriteService.UploadFile(fileHeader)
+}
+
+// reorder sets the position of the rows of model to their place in ids
+func reorder(tx *gorm.DB, model interface{}, ids []uint) error {
+	for i, id := range ids {
+		result := tx.Model(model).Where("id = ?", id).Update("position", i)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// featuredLimit is how many featured products the home page shows
+const featuredLimit = 12
+
+// Home is the content of the storefront home page
+type Home struct {
+	Banners          []models.Banner     `json:"banners"`
+	FeaturedProducts []models.Product    `json:"featured_products"`
+	Collections      []models.Collection `json:"collections"`
+}
+
+// GetHome - Public endpoint for the home page: the banners and collections
+// scheduled for now, and the featured products
+func (h *CMSHandler) GetHome(c *gin.Context) {
+	// Cache per minute, so scheduled content appears and disappears on time
+	now := time.Now().UTC().Truncate(time.Minute)
+	var home Home
+	err := h.catalog.Load(c.Request.Context(), "home", now.Format(time.RFC3339), &home, func() error {
+		loaded, err := loadHome(h.db, now)
+		if err != nil {
+			return err
+		}
+		home = *loaded
+		return nil
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "cms/home", "Failed to get home page")
+		return
+	}
+
+	for i := range home.Banners {
+		home.Banners[i].Image = h.appwriteService.GetFileURL(home.Banners[i].Image)
+	}
+	for i := range home.FeaturedProducts {
+		h.images.ResolveAll(home.FeaturedProducts[i].Images)
+	}
+	for i := range home.Collections {
+		h.resolveCollectionImages(&home.Collections[i])
+	}
+	response.GenerateSuccessResponse(c, "Home page retrieved successfully", home)
+}
+
+// scheduledAt limits a banner or collection query to active rows whose
+// scheduling window contains now
+func scheduledAt(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("is_active = ?", true).
+			Where("starts_at IS NULL OR starts_at <= ?", now).
+			Where("ends_at IS NULL OR ends_at > ?", now)
+	}
+}
+
+// loadHome loads the home page content shown at now. Collections only list
+// their active products, and collections left empty are skipped.
+func loadHome(db *gorm.DB, now time.Time) (*Home, error) {
+	home := Home{
+		Banners:          []models.Banner{},
+		FeaturedProducts: []models.Product{},
+		Collections:      []models.Collection{},
+	}
+	if err := db.Scopes(scheduledAt(now)).Order("position ASC, id ASC").Find(&home.Banners).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("is_active = ? AND is_featured = ?", true, true).
+		Scopes(catalog.Published("products", now)).
+		Preload("Images").
+		Preload("Variants", "is_active = ?", true).
+		Order("updated_at DESC, id DESC").
+		Limit(featuredLimit).
+		Find(&home.FeaturedProducts).Error; err != nil {
+		return nil, err
+	}
+
+	var collections []models.Collection
+	if err := db.Scopes(scheduledAt(now)).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Joins("JOIN products ON products.id = collection_products.product_id").
+				Where("products.is_active = ? AND products.deleted_at IS NULL", true).
+				Scopes(catalog.Published("products", now)).
+				Order("collection_products.position ASC")
+		}).
+		Preload("Items.Product.Images").
+		Preload("Items.Product.Variants", "is_active = ?", true).
+		Order("position ASC, id ASC").
+		Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	for _, collection := range collections {
+		if len(collection.Items) > 0 {
+			home.Collections = append(home.Collections, collection)
+		}
+	}
+	return &home, nil
+}
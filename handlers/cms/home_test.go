@@ -0,0 +1,83 @@
+package cms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Product{}, &models.ProductVariant{}, &models.ProductImage{},
+		&models.Banner{}, &models.Collection{}, &models.CollectionProduct{}))
+	return db
+}
+
+func at(offset time.Duration) *time.Time {
+	t := testNow.Add(offset)
+	return &t
+}
+
+func TestLoadHomeBannerSchedule(t *testing.T) {
+	db := setupTestDB(t)
+	banners := []models.Banner{
+		{Title: "Second", Image: "b2", Position: 2, IsActive: true},
+		{Title: "First", Image: "b1", Position: 1, IsActive: true, StartsAt: at(-time.Hour), EndsAt: at(time.Hour)},
+		{Title: "Upcoming", Image: "b3", Position: 0, IsActive: true, StartsAt: at(time.Minute)},
+		{Title: "Ended", Image: "b4", Position: 0, IsActive: true, EndsAt: at(0)},
+		{Title: "Hidden", Image: "b5", Position: 0, IsActive: true},
+	}
+	require.NoError(t, db.Create(&banners).Error)
+	require.NoError(t, db.Model(&banners[4]).Update("is_active", false).Error)
+
+	home, err := loadHome(db, testNow)
+	require.NoError(t, err)
+	require.Len(t, home.Banners, 2)
+	assert.Equal(t, "First", home.Banners[0].Title)
+	assert.Equal(t, "Second", home.Banners[1].Title)
+}
+
+func TestLoadHomeProductsAndCollections(t *testing.T) {
+	db := setupTestDB(t)
+	products := []models.Product{
+		{Name: "Honey", IsActive: true, IsFeatured: true},
+		{Name: "Dates", IsActive: true},
+		{Name: "Olive oil", IsActive: true, IsFeatured: true},
+	}
+	require.NoError(t, db.Create(&products).Error)
+	require.NoError(t, db.Model(&products[2]).Update("is_active", false).Error)
+
+	collections := []models.Collection{
+		{Title: "Ramadan", Slug: "ramadan", IsActive: true, Position: 1},
+		{Title: "Summer", Slug: "summer", IsActive: true, StartsAt: at(24 * time.Hour)},
+		{Title: "Pantry", Slug: "pantry", IsActive: true, Position: 2},
+	}
+	require.NoError(t, db.Create(&collections).Error)
+	require.NoError(t, db.Create(&[]models.CollectionProduct{
+		{CollectionID: collections[0].ID, ProductID: products[1].ID, Position: 0},
+		{CollectionID: collections[0].ID, ProductID: products[0].ID, Position: 1},
+		{CollectionID: collections[0].ID, ProductID: products[2].ID, Position: 2},
+		{CollectionID: collections[1].ID, ProductID: products[0].ID, Position: 0},
+		{CollectionID: collections[2].ID, ProductID: products[2].ID, Position: 0},
+	}).Error)
+
+	home, err := loadHome(db, testNow)
+	require.NoError(t, err)
+
+	require.Len(t, home.FeaturedProducts, 1, "inactive products are not featured")
+	assert.Equal(t, "Honey", home.FeaturedProducts[0].Name)
+
+	require.Len(t, home.Collections, 1, "scheduled and empty collections are hidden")
+	items := home.Collections[0].Items
+	require.Len(t, items, 2)
+	assert.Equal(t, "Dates", items[0].Product.Name)
+	assert.Equal(t, "Honey", items[1].Product.Name)
+}
@@ -0,0 +1,51 @@
+package company
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/company"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CompanyHandler struct {
+	db        *gorm.DB
+	companies *company.Service
+}
+
+func NewCompanyHandler(db *gorm.DB, companies *company.Service) *CompanyHandler {
+	return &CompanyHandler{
+		db:        db,
+		companies: companies,
+	}
+}
+
+// parseID parses the id path parameter, responding with a bad request when
+// it is not a number
+func parseID(c *gin.Context, code, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, message)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// companyError responds to a company request that failed, notFound naming
+// what was looked up
+func companyError(c *gin.Context, code, notFound, failed string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, notFound)
+	case errors.Is(err, company.ErrNoCompany), errors.Is(err, company.ErrNotOwner):
+		response.GenerateForbiddenResponse(c, code, err.Error())
+	case errors.Is(err, company.ErrInvalidInvitation), errors.Is(err, company.ErrInvitationEmail),
+		errors.Is(err, company.ErrAlreadyInCompany), errors.Is(err, company.ErrNotEligible),
+		errors.Is(err, company.ErrLastOwner):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, failed)
+	}
+}
@@ -0,0 +1,145 @@
+package company
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/company"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type SetOwnerRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// GetMyCompany - Member endpoint for their company and its members
+func (h *CompanyHandler) GetMyCompany(c *gin.Context) {
+	result, err := h.companies.Company(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		companyError(c, "company/get", "User not found", "Failed to get company", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Company retrieved successfully", result)
+}
+
+// UpdateSettings - Owner endpoint to change the company's approval threshold
+func (h *CompanyHandler) UpdateSettings(c *gin.Context) {
+	var req company.SettingsUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "company/settings", err.Error())
+		return
+	}
+	result, err := h.companies.UpdateSettings(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		companyError(c, "company/settings", "Company not found", "Failed to update company settings", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Company settings updated successfully", result)
+}
+
+// InviteBuyer - Owner endpoint to email someone an invitation to order for
+// the company
+func (h *CompanyHandler) InviteBuyer(c *gin.Context) {
+	var req company.InviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "company/invite", err.Error())
+		return
+	}
+	invitation, err := h.companies.Invite(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		companyError(c, "company/invite", "User not found", "Failed to send invitation", err)
+		return
+	}
+	response.GenerateCreatedResponse(c, "Invitation sent successfully", invitation)
+}
+
+// GetInvitations - Owner endpoint for the company's open invitations
+func (h *CompanyHandler) GetInvitations(c *gin.Context) {
+	invitations, err := h.companies.Invitations(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		companyError(c, "company/invitations", "User not found", "Failed to get invitations", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Invitations retrieved successfully", invitations)
+}
+
+// RevokeInvitation - Owner endpoint to withdraw an open invitation
+func (h *CompanyHandler) RevokeInvitation(c *gin.Context) {
+	id, ok := parseID(c, "company/revoke_invitation", "Invalid invitation ID")
+	if !ok {
+		return
+	}
+	if err := h.companies.RevokeInvitation(c.Request.Context(), c.GetUint("user_id"), id); err != nil {
+		companyError(c, "company/revoke_invitation", "Invitation not found", "Failed to revoke invitation", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Invitation revoked successfully", nil)
+}
+
+// AcceptInvitation - Customer endpoint to join the company that invited them
+func (h *CompanyHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "company/accept_invitation", err.Error())
+		return
+	}
+	result, err := h.companies.AcceptInvitation(c.Request.Context(), c.GetUint("user_id"), req.Token)
+	if err != nil {
+		companyError(c, "company/accept_invitation", "User not found", "Failed to accept invitation", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Invitation accepted successfully", result)
+}
+
+// UpdateMember - Owner endpoint to change a member's spend limit or role
+func (h *CompanyHandler) UpdateMember(c *gin.Context) {
+	id, ok := parseID(c, "company/update_member", "Invalid member ID")
+	if !ok {
+		return
+	}
+	var req company.MemberUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "company/update_member", err.Error())
+		return
+	}
+	member, err := h.companies.UpdateMember(c.Request.Context(), c.GetUint("user_id"), id, req)
+	if err != nil {
+		companyError(c, "company/update_member", "Member not found", "Failed to update member", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Member updated successfully", member)
+}
+
+// RemoveMember - Owner endpoint to take a member out of the company
+func (h *CompanyHandler) RemoveMember(c *gin.Context) {
+	id, ok := parseID(c, "company/remove_member", "Invalid member ID")
+	if !ok {
+		return
+	}
+	if err := h.companies.RemoveMember(c.Request.Context(), c.GetUint("user_id"), id); err != nil {
+		companyError(c, "company/remove_member", "Member not found", "Failed to remove member", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Member removed successfully", nil)
+}
+
+// SetCompanyOwner - Admin endpoint to make a user an owner of a company
+func (h *CompanyHandler) SetCompanyOwner(c *gin.Context) {
+	id, ok := parseID(c, "company/set_owner", "Invalid company ID")
+	if !ok {
+		return
+	}
+	var req SetOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "company/set_owner", err.Error())
+		return
+	}
+	result, err := h.companies.SetOwner(c.Request.Context(), id, req.UserID)
+	if err != nil {
+		companyError(c, "company/set_owner", "Company or user not found", "Failed to set company owner", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Company owner set successfully", result)
+}
@@ -0,0 +1,36 @@
+package delivery
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DeliveryHandler struct {
+	db       *gorm.DB
+	slots    *delivery.Service
+	notifier *notification.Service
+}
+
+func NewDeliveryHandler(db *gorm.DB, slots *delivery.Service) *DeliveryHandler {
+	return &DeliveryHandler{
+		db:       db,
+		slots:    slots,
+		notifier: notification.NewService(db),
+	}
+}
+
+// parseID parses the id path parameter, responding with a bad request when
+// it is not a number
+func parseID(c *gin.Context, code, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, message)
+		return 0, false
+	}
+	return uint(id), true
+}
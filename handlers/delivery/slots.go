@@ -0,0 +1,258 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/address"
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSlotRange is the longest period of slots the admin listing returns
+const maxSlotRange = 62 * 24 * time.Hour
+
+type UpdateSlotRequest struct {
+	Capacity int `json:"capacity" binding:"min=0"`
+}
+
+type CloseSlotRequest struct {
+	Reason string `json:"reason"`
+}
+
+type MoveOrdersRequest struct {
+	ToSlotID uint   `json:"to_slot_id" binding:"required"`
+	OrderIDs []uint `json:"order_ids"` // empty moves every open order in the slot
+}
+
+// GetAvailableSlots - Public endpoint listing the slots a customer can book
+// for a delivery address
+func (h *DeliveryHandler) GetAvailableSlots(c *gin.Context) {
+	country, ok := address.CountryCode(c.DefaultQuery("country", "GB"))
+	if !ok {
+		response.GenerateBadRequestResponse(c, "delivery/available_slots", address.ErrUnknownCountry.Error())
+		return
+	}
+	postcode := c.Query("postcode")
+	if postcode == "" {
+		response.GenerateBadRequestResponse(c, "delivery/available_slots", "postcode is required")
+		return
+	}
+
+	zone, err := delivery.ZoneFor(h.db, country, postcode)
+	if errors.Is(err, delivery.ErrNoZone) {
+		response.GenerateNotFoundResponse(c, "delivery/available_slots", err.Error())
+		return
+	}
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/available_slots", "Failed to find delivery zone")
+		return
+	}
+	slots, err := h.slots.AvailableSlots(h.db, zone.ID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/available_slots", "Failed to get delivery slots")
+		return
+	}
+
+	type availableSlot struct {
+		models.DeliverySlot
+		Remaining   int    `json:"remaining"`
+		Description string `json:"description"`
+	}
+	out := make([]availableSlot, len(slots))
+	for i := range slots {
+		out[i] = availableSlot{DeliverySlot: slots[i], Remaining: slots[i].Remaining(), Description: h.slots.Describe(&slots[i])}
+	}
+	response.GenerateSuccessResponse(c, "Delivery slots retrieved successfully", gin.H{
+		"zone":         zone,
+		"require_slot": zone.RequireSlot,
+		"slots":        out,
+	})
+}
+
+// GetSlots - Admin endpoint listing the slots of a zone between two dates,
+// by default the next two weeks
+func (h *DeliveryHandler) GetSlots(c *gin.Context) {
+	zoneID, err := strconv.ParseUint(c.Query("zone_id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/slots", "zone_id is required")
+		return
+	}
+	from, to := time.Now(), time.Now().AddDate(0, 0, 14)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse("2006-01-02", value); err != nil {
+			response.GenerateBadRequestResponse(c, "delivery/slots", "from must be a YYYY-MM-DD date")
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse("2006-01-02", value); err != nil {
+			response.GenerateBadRequestResponse(c, "delivery/slots", "to must be a YYYY-MM-DD date")
+			return
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxSlotRange {
+		response.GenerateBadRequestResponse(c, "delivery/slots", "to must be after from and at most 62 days later")
+		return
+	}
+
+	slots, err := h.slots.Slots(h.db, uint(zoneID), from, to)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/slots", "Failed to get delivery slots")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Delivery slots retrieved successfully", slots)
+}
+
+// UpdateSlot - Admin endpoint to change the capacity of a single slot. The
+// capacity cannot go below the orders already booked.
+func (h *DeliveryHandler) UpdateSlot(c *gin.Context) {
+	id, ok := parseID(c, "delivery/update_slot", "Invalid slot ID")
+	if !ok {
+		return
+	}
+	var req UpdateSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/update_slot", err.Error())
+		return
+	}
+	result := h.db.Model(&models.DeliverySlot{}).
+		Where("id = ? AND booked <= ?", id, req.Capacity).
+		Update("capacity", req.Capacity)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/update_slot", "Failed to update delivery slot")
+		return
+	}
+	var slot models.DeliverySlot
+	if err := h.db.First(&slot, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "delivery/update_slot", "Delivery slot not found")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateBadRequestResponse(c, "delivery/update_slot",
+			fmt.Sprintf("slot already has %d orders booked; move orders out before lowering its capacity", slot.Booked))
+		return
+	}
+	response.GenerateSuccessResponse(c, "Delivery slot updated successfully", slot)
+}
+
+// CloseSlot - Admin endpoint to stop a slot taking orders. Orders already
+// booked keep their place until moved.
+func (h *DeliveryHandler) CloseSlot(c *gin.Context) {
+	h.setClosed(c, true)
+}
+
+// ReopenSlot - Admin endpoint to let a closed slot take orders again
+func (h *DeliveryHandler) ReopenSlot(c *gin.Context) {
+	h.setClosed(c, false)
+}
+
+func (h *DeliveryHandler) setClosed(c *gin.Context, closed bool) {
+	id, ok := parseID(c, "delivery/close_slot", "Invalid slot ID")
+	if !ok {
+		return
+	}
+	var req CloseSlotRequest
+	if closed && c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.GenerateBadRequestResponse(c, "delivery/close_slot", err.Error())
+			return
+		}
+	}
+	var slot models.DeliverySlot
+	if err := h.db.First(&slot, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "delivery/close_slot", "Delivery slot not found")
+		return
+	}
+	if !closed {
+		req.Reason = ""
+	}
+	if err := h.db.Model(&slot).Updates(map[string]interface{}{
+		"is_closed":     closed,
+		"closed_reason": req.Reason,
+	}).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/close_slot", "Failed to update delivery slot")
+		return
+	}
+	message := "Delivery slot reopened successfully"
+	if closed {
+		message = "Delivery slot closed successfully"
+	}
+	response.GenerateSuccessResponse(c, message, slot)
+}
+
+// GetSlotOrders - Admin endpoint listing the orders booked into a slot
+func (h *DeliveryHandler) GetSlotOrders(c *gin.Context) {
+	id, ok := parseID(c, "delivery/slot_orders", "Invalid slot ID")
+	if !ok {
+		return
+	}
+	var orders []models.Order
+	if err := h.db.Preload("User").Preload("ShippingAddress").
+		Where("delivery_slot_id = ?", id).Order("id").Find(&orders).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/slot_orders", "Failed to get slot orders")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Slot orders retrieved successfully", orders)
+}
+
+// MoveOrders - Admin endpoint to move open orders from a slot to another slot
+// of the same zone, letting each customer know their new delivery time
+func (h *DeliveryHandler) MoveOrders(c *gin.Context) {
+	id, ok := parseID(c, "delivery/move_orders", "Invalid slot ID")
+	if !ok {
+		return
+	}
+	var req MoveOrdersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/move_orders", err.Error())
+		return
+	}
+
+	var moved []models.Order
+	var target models.DeliverySlot
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if moved, err = delivery.MoveOrders(tx, id, req.ToSlotID, req.OrderIDs); err != nil {
+			return err
+		}
+		return tx.First(&target, req.ToSlotID).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "delivery/move_orders", "Delivery slot not found")
+		return
+	case errors.Is(err, delivery.ErrWrongZone), errors.Is(err, delivery.ErrSlotClosed), errors.Is(err, delivery.ErrNotEnoughSpace), errors.Is(err, delivery.ErrSlotFull):
+		response.GenerateResponse(c, http.StatusConflict, err.Error(), nil, response.NewAPIError("delivery/move_orders", err.Error()))
+		return
+	default:
+		response.GenerateInternalServerErrorResponse(c, "delivery/move_orders", "Failed to move orders")
+		return
+	}
+
+	when := h.slots.Describe(&target)
+	for _, order := range moved {
+		if _, err := h.notifier.Notify(order.UserID, notification.Message{
+			Type:  models.NotificationTypeOrderUpdate,
+			Title: fmt.Sprintf("New delivery time for order %s", order.OrderNumber),
+			Body:  fmt.Sprintf("Your order %s will now be delivered %s.", order.OrderNumber, when),
+			Link:  fmt.Sprintf("/orders/%d", order.ID),
+			Data:  models.JSON{"order_id": order.ID, "delivery_slot_id": target.ID},
+		}); err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to create delivery slot notification", "component", "delivery", "error", err)
+		}
+	}
+	response.GenerateSuccessResponse(c, "Orders moved successfully", gin.H{
+		"moved":   len(moved),
+		"to_slot": target,
+	})
+}
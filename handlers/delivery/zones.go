@@ -0,0 +1,302 @@
+package delivery
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/address"
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ZoneRequest struct {
+	Name             string   `json:"name" binding:"required"`
+	Country          string   `json:"country"`           // defaults to GB
+	PostcodePrefixes []string `json:"postcode_prefixes"` // empty covers the whole country
+	RequireSlot      bool     `json:"require_slot"`
+	IsActive         *bool    `json:"is_active"`
+	// WarehouseIDs are the warehouses whose stock is shown as available in the
+	// zone; empty means all. Left out on update, they are unchanged.
+	WarehouseIDs []uint `json:"warehouse_ids"`
+}
+
+type WindowRequest struct {
+	Weekday   *time.Weekday `json:"weekday" binding:"required"`
+	StartTime string        `json:"start_time" binding:"required"`
+	EndTime   string        `json:"end_time" binding:"required"`
+	Capacity  int           `json:"capacity" binding:"min=0"`
+	Fee       float64       `json:"fee" binding:"min=0"`
+	IsActive  *bool         `json:"is_active"`
+}
+
+// zoneFromRequest validates a zone request into a zone
+func zoneFromRequest(req *ZoneRequest, zone *models.DeliveryZone) error {
+	country := req.Country
+	if country == "" {
+		country = "GB"
+	}
+	code, ok := address.CountryCode(country)
+	if !ok {
+		return address.ErrUnknownCountry
+	}
+	prefixes := make([]string, 0, len(req.PostcodePrefixes))
+	for _, prefix := range req.PostcodePrefixes {
+		if prefix = strings.ToUpper(strings.Join(strings.Fields(prefix), "")); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	zone.Name = strings.TrimSpace(req.Name)
+	zone.Country = code
+	zone.PostcodePrefixes = strings.Join(prefixes, ",")
+	zone.RequireSlot = req.RequireSlot
+	zone.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+// setZoneWarehouses replaces the warehouses of a zone with those requested,
+// when the request names them
+func setZoneWarehouses(db *gorm.DB, zone *models.DeliveryZone, ids []uint) error {
+	if ids == nil {
+		return nil
+	}
+	unique := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	var warehouses []models.Warehouse
+	if len(ids) > 0 {
+		if err := db.Where("id IN ?", ids).Find(&warehouses).Error; err != nil {
+			return err
+		}
+		if len(warehouses) != len(unique) {
+			return errUnknownWarehouse
+		}
+	}
+	zone.Warehouses = warehouses
+	return db.Model(zone).Association("Warehouses").Replace(warehouses)
+}
+
+var errUnknownWarehouse = errors.New("warehouse_ids contains an unknown warehouse")
+
+// CreateZone - Admin endpoint to add a delivery zone
+func (h *DeliveryHandler) CreateZone(c *gin.Context) {
+	var req ZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/create_zone", err.Error())
+		return
+	}
+	var zone models.DeliveryZone
+	if err := zoneFromRequest(&req, &zone); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/create_zone", err.Error())
+		return
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&zone).Error; err != nil {
+			return err
+		}
+		// GORM skips zero values for fields with a default, so persist them explicitly
+		if !zone.IsActive {
+			if err := tx.Model(&zone).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return setZoneWarehouses(tx, &zone, req.WarehouseIDs)
+	})
+	switch {
+	case err == nil:
+		response.GenerateCreatedResponse(c, "Delivery zone created successfully", zone)
+	case errors.Is(err, errUnknownWarehouse):
+		response.GenerateBadRequestResponse(c, "delivery/create_zone", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "delivery/create_zone", "Failed to create delivery zone")
+	}
+}
+
+// GetZones - Admin endpoint to list delivery zones with their windows and
+// warehouses
+func (h *DeliveryHandler) GetZones(c *gin.Context) {
+	var zones []models.DeliveryZone
+	if err := h.db.Preload("Windows", func(db *gorm.DB) *gorm.DB {
+		return db.Order("weekday, start_time")
+	}).Preload("Warehouses").Order("country, name").Find(&zones).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/zones", "Failed to get delivery zones")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Delivery zones retrieved successfully", zones)
+}
+
+// UpdateZone - Admin endpoint to update a delivery zone
+func (h *DeliveryHandler) UpdateZone(c *gin.Context) {
+	id, ok := parseID(c, "delivery/update_zone", "Invalid zone ID")
+	if !ok {
+		return
+	}
+	var req ZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/update_zone", err.Error())
+		return
+	}
+	var zone models.DeliveryZone
+	if err := h.db.First(&zone, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "delivery/update_zone", "Delivery zone not found")
+		return
+	}
+	if err := zoneFromRequest(&req, &zone); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/update_zone", err.Error())
+		return
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&zone).Select("name", "country", "postcode_prefixes", "require_slot", "is_active").Updates(&zone).Error; err != nil {
+			return err
+		}
+		return setZoneWarehouses(tx, &zone, req.WarehouseIDs)
+	})
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "Delivery zone updated successfully", zone)
+	case errors.Is(err, errUnknownWarehouse):
+		response.GenerateBadRequestResponse(c, "delivery/update_zone", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "delivery/update_zone", "Failed to update delivery zone")
+	}
+}
+
+// DeleteZone - Admin endpoint to delete a delivery zone without upcoming
+// bookings
+func (h *DeliveryHandler) DeleteZone(c *gin.Context) {
+	id, ok := parseID(c, "delivery/delete_zone", "Invalid zone ID")
+	if !ok {
+		return
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var zone models.DeliveryZone
+		if err := tx.First(&zone, id).Error; err != nil {
+			return err
+		}
+		var booked int64
+		if err := tx.Model(&models.DeliverySlot{}).
+			Where("zone_id = ? AND starts_at > ? AND booked > 0", zone.ID, time.Now()).
+			Count(&booked).Error; err != nil {
+			return err
+		}
+		if booked > 0 {
+			return errZoneBooked
+		}
+		if err := tx.Where("zone_id = ?", zone.ID).Delete(&models.DeliveryWindow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&zone).Association("Warehouses").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&zone).Error
+	})
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "Delivery zone deleted successfully", nil)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "delivery/delete_zone", "Delivery zone not found")
+	case errors.Is(err, errZoneBooked):
+		response.GenerateBadRequestResponse(c, "delivery/delete_zone", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "delivery/delete_zone", "Failed to delete delivery zone")
+	}
+}
+
+var errZoneBooked = errors.New("zone has upcoming bookings; move them or deactivate the zone instead")
+
+// CreateWindow - Admin endpoint to add a weekly delivery window to a zone
+func (h *DeliveryHandler) CreateWindow(c *gin.Context) {
+	id, ok := parseID(c, "delivery/create_window", "Invalid zone ID")
+	if !ok {
+		return
+	}
+	var req WindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/create_window", err.Error())
+		return
+	}
+	var zone models.DeliveryZone
+	if err := h.db.First(&zone, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "delivery/create_window", "Delivery zone not found")
+		return
+	}
+
+	window := models.DeliveryWindow{
+		ZoneID:    zone.ID,
+		Weekday:   *req.Weekday,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Capacity:  req.Capacity,
+		Fee:       req.Fee,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+	}
+	if err := delivery.ValidateWindow(&window); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/create_window", err.Error())
+		return
+	}
+	if err := h.db.Create(&window).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/create_window", "Failed to create delivery window")
+		return
+	}
+	if !window.IsActive {
+		h.db.Model(&window).Update("is_active", false)
+	}
+	response.GenerateCreatedResponse(c, "Delivery window created successfully", window)
+}
+
+// UpdateWindow - Admin endpoint to update a delivery window. Slots already
+// created keep their capacity; change those on the slot itself.
+func (h *DeliveryHandler) UpdateWindow(c *gin.Context) {
+	id, ok := parseID(c, "delivery/update_window", "Invalid window ID")
+	if !ok {
+		return
+	}
+	var req WindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/update_window", err.Error())
+		return
+	}
+	var window models.DeliveryWindow
+	if err := h.db.First(&window, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "delivery/update_window", "Delivery window not found")
+		return
+	}
+	window.Weekday = *req.Weekday
+	window.StartTime = req.StartTime
+	window.EndTime = req.EndTime
+	window.Capacity = req.Capacity
+	window.Fee = req.Fee
+	window.IsActive = req.IsActive == nil || *req.IsActive
+	if err := delivery.ValidateWindow(&window); err != nil {
+		response.GenerateBadRequestResponse(c, "delivery/update_window", err.Error())
+		return
+	}
+	if err := h.db.Model(&window).Select("weekday", "start_time", "end_time", "capacity", "fee", "is_active").Updates(&window).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/update_window", "Failed to update delivery window")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Delivery window updated successfully", window)
+}
+
+// DeleteWindow - Admin endpoint to stop a delivery window. Slots already
+// created from it are kept.
+func (h *DeliveryHandler) DeleteWindow(c *gin.Context) {
+	id, ok := parseID(c, "delivery/delete_window", "Invalid window ID")
+	if !ok {
+		return
+	}
+	result := h.db.Delete(&models.DeliveryWindow{}, id)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "delivery/delete_window", "Failed to delete delivery window")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateNotFoundResponse(c, "delivery/delete_window", "Delivery window not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Delivery window deleted successfully", nil)
+}
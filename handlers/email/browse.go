@@ -0,0 +1,205 @@
+package email
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ResendEmailRequest represents the request body for resending an email.
+// With Rerender the email is rendered again with the current templates.
+type ResendEmailRequest struct {
+	Rerender bool `json:"rerender"`
+}
+
+// ResendEmailsRequest represents the request body for resending several emails
+type ResendEmailsRequest struct {
+	EmailIDs []uint `json:"email_ids" binding:"required,min=1,max=100"`
+	Rerender bool   `json:"rerender"`
+}
+
+// SearchEmails lists stored emails, filterable by recipient, type, status,
+// order and date range (RFC3339 or YYYY-MM-DD). Content is left out; fetch a
+// single email to see it.
+func (h *EmailHandler) SearchEmails(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.Model(&models.Email{})
+	if recipient := strings.ToLower(strings.TrimSpace(c.Query("recipient"))); recipient != "" {
+		query = query.Where("LOWER(recipient_email) LIKE ?", "%"+recipient+"%")
+	}
+	if emailType := c.Query("type"); emailType != "" {
+		query = query.Where("type = ?", emailType)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if orderID := c.Query("order_id"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseDate(from)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "INVALID_DATE", "Invalid from date")
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseDate(to)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "INVALID_DATE", "Invalid to date")
+			return
+		}
+		if len(to) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1) // include the whole day
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "EMAIL_SEARCH_FAILED", "Failed to search emails")
+		return
+	}
+
+	var emails []models.Email
+	if err := query.Omit("html_content", "text_content", "template_data").
+		Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&emails).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "EMAIL_SEARCH_FAILED", "Failed to search emails")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Emails retrieved successfully", gin.H{
+		"emails":      emails,
+		"page":        page,
+		"limit":       limit,
+		"total_count": total,
+		"total_pages": (int(total) + limit - 1) / limit,
+	})
+}
+
+// GetEmail returns a stored email with its content
+func (h *EmailHandler) GetEmail(c *gin.Context) {
+	stored, ok := h.findEmail(c)
+	if !ok {
+		return
+	}
+	response.GenerateSuccessResponse(c, "Email retrieved successfully", stored)
+}
+
+// GetEmailHTML serves the HTML of a stored email as it was sent, sandboxed so
+// its links and scripts do not run in the admin's session
+func (h *EmailHandler) GetEmailHTML(c *gin.Context) {
+	stored, ok := h.findEmail(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Security-Policy", "sandbox")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(stored.HTMLContent))
+}
+
+// ResendEmail queues a copy of a stored email to its recipient
+func (h *EmailHandler) ResendEmail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_EMAIL_ID", "Invalid email ID")
+		return
+	}
+	var req ResendEmailRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+			return
+		}
+	}
+
+	resent, err := h.emailService.ResendEmail(uint(id), req.Rerender)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "EMAIL_NOT_FOUND", "Email not found")
+	case errors.Is(err, email.ErrRecipientSuppressed):
+		response.GenerateErrorResponse(c, http.StatusConflict, "RECIPIENT_SUPPRESSED", err.Error())
+	case errors.Is(err, email.ErrNoRecipient), errors.Is(err, email.ErrNoTemplateData):
+		response.GenerateBadRequestResponse(c, "EMAIL_NOT_RESENDABLE", err.Error())
+	case err != nil:
+		response.GenerateInternalServerErrorResponse(c, "EMAIL_RESEND_FAILED", "Failed to resend email")
+	default:
+		response.GenerateSuccessResponse(c, "Email queued for resending", resent)
+	}
+}
+
+// ResendEmails queues copies of several stored emails, reporting the outcome
+// for each
+func (h *EmailHandler) ResendEmails(c *gin.Context) {
+	var req ResendEmailsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	results := make([]gin.H, 0, len(req.EmailIDs))
+	resent := 0
+	for _, id := range req.EmailIDs {
+		copied, err := h.emailService.ResendEmail(id, req.Rerender)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				err = errors.New("email not found")
+			}
+			results = append(results, gin.H{"email_id": id, "error": err.Error()})
+			continue
+		}
+		resent++
+		results = append(results, gin.H{"email_id": id, "resent_id": copied.ID})
+	}
+
+	response.GenerateSuccessResponse(c, "Emails queued for resending", gin.H{
+		"resent":  resent,
+		"results": results,
+	})
+}
+
+// findEmail loads the email named by the id parameter, writing an error
+// response when it cannot
+func (h *EmailHandler) findEmail(c *gin.Context) (*models.Email, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_EMAIL_ID", "Invalid email ID")
+		return nil, false
+	}
+	var stored models.Email
+	if err := h.db.First(&stored, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "EMAIL_NOT_FOUND", "Email not found")
+			return nil, false
+		}
+		response.GenerateInternalServerErrorResponse(c, "EMAIL_FETCH_FAILED", "Failed to get email")
+		return nil, false
+	}
+	return &stored, true
+}
+
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,109 @@
+package email
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterActionRequest selects dead-lettered emails for a bulk action.
+// Either list the email IDs or set All to act on the whole queue.
+type DeadLetterActionRequest struct {
+	EmailIDs []uint `json:"email_ids"`
+	All      bool   `json:"all"`
+}
+
+// GetDeadLetters lists dead-lettered emails with their last error
+func (h *EmailHandler) GetDeadLetters(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	letters, err := h.emailService.GetDeadLetters()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "DEAD_LETTER_LIST_FAILED", "Failed to get dead-lettered emails")
+		return
+	}
+
+	total := len(letters)
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+
+	response.GenerateSuccessResponse(c, "Dead-lettered emails retrieved successfully", gin.H{
+		"dead_letters": letters[start:end],
+		"page":         page,
+		"limit":        limit,
+		"total_count":  total,
+		"total_pages":  (total + limit - 1) / limit,
+	})
+}
+
+// RequeueDeadLetters puts dead-lettered emails back on the send queue
+func (h *EmailHandler) RequeueDeadLetters(c *gin.Context) {
+	ids, ok := bindDeadLetterAction(c)
+	if !ok {
+		return
+	}
+
+	requeued, err := h.emailService.RequeueDeadLetters(ids)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "DEAD_LETTER_REQUEUE_FAILED", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Dead-lettered emails requeued successfully", gin.H{
+		"requeued": requeued,
+	})
+}
+
+// DeleteDeadLetters discards dead-lettered emails
+func (h *EmailHandler) DeleteDeadLetters(c *gin.Context) {
+	ids, ok := bindDeadLetterAction(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.emailService.DeleteDeadLetters(ids)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "DEAD_LETTER_DELETE_FAILED", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Dead-lettered emails deleted successfully", gin.H{
+		"deleted": deleted,
+	})
+}
+
+// bindDeadLetterAction reads the selected email IDs; an empty result means all
+func bindDeadLetterAction(c *gin.Context) ([]string, bool) {
+	var req DeadLetterActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+		return nil, false
+	}
+	if !req.All && len(req.EmailIDs) == 0 {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Provide email_ids or set all to true")
+		return nil, false
+	}
+	if req.All {
+		return nil, true
+	}
+
+	ids := make([]string, len(req.EmailIDs))
+	for i, id := range req.EmailIDs {
+		ids[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return ids, true
+}
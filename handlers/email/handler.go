@@ -23,22 +23,51 @@ type EmailService interface {
 	RetryFailedEmails() error
 	GetEmailMetrics(timeRange email.TimeRange) (*email.EmailMetrics, error)
 	GetQueueSize() (int64, error)
+	ScheduleTransactionalEmail(emailType models.EmailType, data map[string]interface{}, recipient models.EmailRecipient, sendAt time.Time) (*models.Email, error)
+	CancelScheduledEmail(emailID uint) error
+	GetDeadLetters() ([]*email.DeadLetter, error)
+	GetDeadLetterSize() (int64, error)
+	RequeueDeadLetters(emailIDs []string) (int, error)
+	DeleteDeadLetters(emailIDs []string) (int, error)
+	HandleDeliveryEvent(event email.DeliveryEvent, source string) error
+	SuppressAddress(address, detail string) (*models.EmailSuppression, error)
+	ResendEmail(emailID uint, rerender bool) (*models.Email, error)
+}
+
+// TemplateEditor renders and reloads templates for the admin template editor
+type TemplateEditor interface {
+	ReloadTemplates() error
+	RenderPreview(subject, htmlContent string, data map[string]interface{}) (string, string, string, error)
 }
 
 // EmailHandler handles email-related HTTP requests
 type EmailHandler struct {
 	emailService EmailService
+	templates    TemplateEditor
 	db           *gorm.DB
+
+	webhookSecret string
+	// previousWebhookSecret is also accepted while the secret is rotated
+	previousWebhookSecret string
 }
 
 // NewEmailHandler creates a new email handler
-func NewEmailHandler(emailService EmailService, db *gorm.DB) *EmailHandler {
+func NewEmailHandler(emailService EmailService, templates TemplateEditor, db *gorm.DB) *EmailHandler {
 	return &EmailHandler{
 		emailService: emailService,
+		templates:    templates,
 		db:           db,
 	}
 }
 
+// WithWebhookSecret sets the key bounce and complaint webhooks are signed
+// with, and the previous key also accepted while it is rotated
+func (h *EmailHandler) WithWebhookSecret(secret, previous string) *EmailHandler {
+	h.webhookSecret = secret
+	h.previousWebhookSecret = previous
+	return h
+}
+
 // SendEmailRequest represents the request body for sending an email
 type SendEmailRequest struct {
 	Template  string                 `json:"template" binding:"required"`
@@ -58,6 +87,7 @@ type SendTransactionalEmailRequest struct {
 	EmailType models.EmailType       `json:"email_type" binding:"required"`
 	Data      map[string]interface{} `json:"data" binding:"required"`
 	Recipient models.EmailRecipient  `json:"recipient" binding:"required"`
+	SendAt    *time.Time             `json:"send_at"` // optional, schedules delivery for later
 }
 
 // EmailMetricsRequest represents the request body for getting email metrics
@@ -141,6 +171,23 @@ func (h *EmailHandler) SendTransactionalEmail(c *gin.Context) {
 		return
 	}
 
+	// Schedule for later delivery when send_at is in the future
+	if req.SendAt != nil && req.SendAt.After(time.Now()) {
+		scheduled, err := h.emailService.ScheduleTransactionalEmail(req.EmailType, req.Data, req.Recipient, *req.SendAt)
+		if err != nil {
+			response.GenerateInternalServerErrorResponse(c, "TRANSACTIONAL_EMAIL_FAILED", "Failed to schedule transactional email")
+			return
+		}
+
+		response.GenerateSuccessResponse(c, "Transactional email scheduled successfully", gin.H{
+			"message":  "Transactional email has been scheduled for delivery",
+			"type":     req.EmailType,
+			"email_id": scheduled.ID,
+			"send_at":  scheduled.SendAt,
+		})
+		return
+	}
+
 	// Send transactional email
 	err := h.emailService.SendTransactionalEmail(req.EmailType, req.Data, req.Recipient)
 	if err != nil {
@@ -194,6 +241,24 @@ func (h *EmailHandler) RetryFailedEmail(c *gin.Context) {
 	})
 }
 
+// CancelScheduledEmail cancels a scheduled email before it is dispatched
+func (h *EmailHandler) CancelScheduledEmail(c *gin.Context) {
+	emailID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_EMAIL_ID", "Invalid email ID")
+		return
+	}
+
+	if err := h.emailService.CancelScheduledEmail(uint(emailID)); err != nil {
+		response.GenerateBadRequestResponse(c, "EMAIL_CANCEL_FAILED", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Scheduled email canceled successfully", gin.H{
+		"email_id": emailID,
+	})
+}
+
 // RetryAllFailedEmails retries all failed emails
 func (h *EmailHandler) RetryAllFailedEmails(c *gin.Context) {
 	err := h.emailService.RetryFailedEmails()
@@ -305,6 +370,19 @@ func (h *EmailHandler) GetEmailTemplates(c *gin.Context) {
 		"cart_recovery",
 		"security_alert",
 		"admin_notification",
+		"quote_responded",
+		"review_request",
+		"gift_card",
+		"order_message",
+		"company_invitation",
+		"order_approval_request",
+		"order_approval_decision",
+		"review_reply",
+		"content_removed",
+		"account_enforcement",
+		"subscription_reminder",
+		"subscription_order",
+		"email_verification",
 	}
 
 	response.GenerateSuccessResponse(c, "Email templates retrieved successfully", gin.H{
@@ -321,9 +399,16 @@ func (h *EmailHandler) GetQueueStatus(c *gin.Context) {
 		return
 	}
 
+	deadLetterSize, err := h.emailService.GetDeadLetterSize()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "QUEUE_STATUS_FAILED", "Failed to get queue status")
+		return
+	}
+
 	response.GenerateSuccessResponse(c, "Queue status retrieved successfully", gin.H{
-		"queue_size": queueSize,
-		"status":     "active",
+		"queue_size":       queueSize,
+		"dead_letter_size": deadLetterSize,
+		"status":           "active",
 	})
 }
 
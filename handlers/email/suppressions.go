@@ -0,0 +1,147 @@
+package email
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// DeliveryWebhookRequest is the body of a bounce and complaint webhook
+type DeliveryWebhookRequest struct {
+	Events []email.DeliveryEvent `json:"events"`
+}
+
+// CreateSuppressionRequest represents the request body for suppressing an address
+type CreateSuppressionRequest struct {
+	Email  string `json:"email" binding:"required,email"`
+	Detail string `json:"detail"`
+}
+
+// HandleDeliveryWebhook records bounces and complaints pushed by the email provider.
+// The body must be signed with EMAIL_WEBHOOK_SECRET, or EMAIL_WEBHOOK_SECRET_PREVIOUS
+// while it is rotated, in the X-Email-Signature header.
+func (h *EmailHandler) HandleDeliveryWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Failed to read request body")
+		return
+	}
+	signature := c.GetHeader("X-Email-Signature")
+	switch {
+	case email.VerifyWebhookSignature(h.webhookSecret, body, signature):
+		metrics.SecretMatched("email_webhook", false)
+	case email.VerifyWebhookSignature(h.previousWebhookSecret, body, signature):
+		metrics.SecretMatched("email_webhook", true)
+	default:
+		response.GenerateUnauthorizedResponse(c, "INVALID_SIGNATURE", "Invalid webhook signature")
+		return
+	}
+
+	var req DeliveryWebhookRequest
+	if err := json.Unmarshal(body, &req); err != nil || len(req.Events) == 0 {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	processed := 0
+	var failures []string
+	for _, event := range req.Events {
+		if err := h.emailService.HandleDeliveryEvent(event, models.SuppressionSourceWebhook); err != nil {
+			failures = append(failures, event.Email+": "+err.Error())
+			continue
+		}
+		processed++
+	}
+
+	response.GenerateSuccessResponse(c, "Delivery events processed", gin.H{
+		"processed": processed,
+		"failed":    failures,
+	})
+}
+
+// GetSuppressions lists suppressed addresses
+func (h *EmailHandler) GetSuppressions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.Model(&models.EmailSuppression{})
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	if search := strings.ToLower(strings.TrimSpace(c.Query("email"))); search != "" {
+		query = query.Where("email LIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "SUPPRESSION_LIST_FAILED", "Failed to get suppression list")
+		return
+	}
+
+	var suppressions []models.EmailSuppression
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&suppressions).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "SUPPRESSION_LIST_FAILED", "Failed to get suppression list")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Suppression list retrieved successfully", gin.H{
+		"suppressions": suppressions,
+		"page":         page,
+		"limit":        limit,
+		"total_count":  total,
+		"total_pages":  (int(total) + limit - 1) / limit,
+	})
+}
+
+// CreateSuppression manually suppresses an address
+func (h *EmailHandler) CreateSuppression(c *gin.Context) {
+	var req CreateSuppressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	suppression, err := h.emailService.SuppressAddress(req.Email, req.Detail)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "SUPPRESSION_CREATE_FAILED", "Failed to suppress address")
+		return
+	}
+
+	response.GenerateCreatedResponse(c, "Address suppressed successfully", suppression)
+}
+
+// DeleteSuppression removes an address from the suppression list so it can receive email again
+func (h *EmailHandler) DeleteSuppression(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_SUPPRESSION_ID", "Invalid suppression ID")
+		return
+	}
+
+	result := h.db.Delete(&models.EmailSuppression{}, id)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "SUPPRESSION_DELETE_FAILED", "Failed to remove suppression")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateNotFoundResponse(c, "SUPPRESSION_NOT_FOUND", "Suppression not found")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Suppression removed successfully", gin.H{
+		"id": id,
+	})
+}
@@ -0,0 +1,317 @@
+package email
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateTemplateVersionRequest represents the request body for a new template version
+type CreateTemplateVersionRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	Type        models.EmailType `json:"type"`
+	Subject     string           `json:"subject"`
+	HTMLContent string           `json:"html_content" binding:"required"`
+	TextContent string           `json:"text_content"`
+}
+
+// UpdateTemplateVersionRequest represents the request body for editing a draft version
+type UpdateTemplateVersionRequest struct {
+	Subject     *string `json:"subject"`
+	HTMLContent *string `json:"html_content"`
+	TextContent *string `json:"text_content"`
+}
+
+// PreviewTemplateRequest represents the request body for previewing unsaved template content
+type PreviewTemplateRequest struct {
+	Subject     string                 `json:"subject"`
+	HTMLContent string                 `json:"html_content" binding:"required"`
+	Data        map[string]interface{} `json:"data"`
+}
+
+// PreviewTemplateVersionRequest represents the request body for previewing a stored version
+type PreviewTemplateVersionRequest struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// samplePreviewData fills the fields most templates use so a preview renders
+// without the caller supplying data. The business settings fill the rest.
+func samplePreviewData() map[string]interface{} {
+	business := settings.Current()
+	return map[string]interface{}{
+		"UserName":     "Jane Doe",
+		"CustomerName": "Jane Doe",
+		"Email":        "jane.doe@example.com",
+		"OrderNumber":  "ORD-20240101-1234",
+		"OrderDate":    "01/01/2024",
+		"TotalAmount":  business.CurrencySymbol() + "99.99",
+		"ResetURL":     business.URL("/reset-password?token=sample"),
+		"CartURL":      business.URL("/cart"),
+		"ReviewURL":    business.URL("/account/orders"),
+		"CartItems": []map[string]interface{}{
+			{"Name": "Sample Product", "Price": business.CurrencySymbol() + "49.99"},
+		},
+	}
+}
+
+// ListTemplateVersions lists stored template versions, optionally for one template name
+func (h *EmailHandler) ListTemplateVersions(c *gin.Context) {
+	query := h.db.Model(&models.EmailTemplate{})
+	if name := c.Query("name"); name != "" {
+		query = query.Where("name = ?", name)
+	}
+
+	var versions []models.EmailTemplate
+	if err := query.Order("name ASC, version DESC").Find(&versions).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "TEMPLATE_LIST_FAILED", "Failed to list template versions")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Template versions retrieved successfully", gin.H{
+		"templates": versions,
+	})
+}
+
+// GetTemplateVersion retrieves a single template version
+func (h *EmailHandler) GetTemplateVersion(c *gin.Context) {
+	version, ok := h.findTemplateVersion(c)
+	if !ok {
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Template version retrieved successfully", version)
+}
+
+// CreateTemplateVersion stores a new inactive version of a template
+func (h *EmailHandler) CreateTemplateVersion(c *gin.Context) {
+	var req CreateTemplateVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	if err := validateTemplateContent(req.Subject, req.HTMLContent); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_TEMPLATE", err.Error())
+		return
+	}
+
+	version := models.EmailTemplate{
+		Name:        req.Name,
+		Type:        req.Type,
+		Subject:     req.Subject,
+		HTMLContent: req.HTMLContent,
+		TextContent: req.TextContent,
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.EmailTemplate{}).
+			Where("name = ?", req.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		version.Version = latest + 1
+
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+		// IsActive defaults to true in the database, so new versions are
+		// explicitly stored as drafts until activated
+		version.IsActive = false
+		return tx.Model(&version).Update("is_active", false).Error
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "TEMPLATE_CREATE_FAILED", "Failed to create template version")
+		return
+	}
+
+	response.GenerateCreatedResponse(c, "Template version created successfully", version)
+}
+
+// UpdateTemplateVersion edits a draft version. Active versions are immutable;
+// create a new version to change them.
+func (h *EmailHandler) UpdateTemplateVersion(c *gin.Context) {
+	version, ok := h.findTemplateVersion(c)
+	if !ok {
+		return
+	}
+	if version.IsActive {
+		response.GenerateBadRequestResponse(c, "TEMPLATE_ACTIVE", "Active template versions cannot be edited, create a new version instead")
+		return
+	}
+
+	var req UpdateTemplateVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Subject != nil {
+		version.Subject = *req.Subject
+		updates["subject"] = *req.Subject
+	}
+	if req.HTMLContent != nil {
+		version.HTMLContent = *req.HTMLContent
+		updates["html_content"] = *req.HTMLContent
+	}
+	if req.TextContent != nil {
+		version.TextContent = *req.TextContent
+		updates["text_content"] = *req.TextContent
+	}
+	if len(updates) == 0 {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "No fields to update")
+		return
+	}
+	if err := validateTemplateContent(version.Subject, version.HTMLContent); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_TEMPLATE", err.Error())
+		return
+	}
+
+	if err := h.db.Model(version).Updates(updates).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "TEMPLATE_UPDATE_FAILED", "Failed to update template version")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Template version updated successfully", version)
+}
+
+// PreviewTemplate renders unsaved template content with sample data
+func (h *EmailHandler) PreviewTemplate(c *gin.Context) {
+	var req PreviewTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	h.renderPreview(c, req.Subject, req.HTMLContent, req.Data)
+}
+
+// PreviewTemplateVersion renders a stored version with sample data before activation
+func (h *EmailHandler) PreviewTemplateVersion(c *gin.Context) {
+	version, ok := h.findTemplateVersion(c)
+	if !ok {
+		return
+	}
+
+	var req PreviewTemplateVersionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.GenerateBadRequestResponse(c, "INVALID_REQUEST", "Invalid request body")
+			return
+		}
+	}
+
+	h.renderPreview(c, version.Subject, version.HTMLContent, req.Data)
+}
+
+// ActivateTemplateVersion makes a version the one used for sending, replacing
+// any other active version of the same template
+func (h *EmailHandler) ActivateTemplateVersion(c *gin.Context) {
+	version, ok := h.findTemplateVersion(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmailTemplate{}).
+			Where("name = ? AND id <> ?", version.Name, version.ID).
+			Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(version).Update("is_active", true).Error
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "TEMPLATE_ACTIVATE_FAILED", "Failed to activate template version")
+		return
+	}
+	version.IsActive = true
+
+	if err := h.templates.ReloadTemplates(); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "TEMPLATE_RELOAD_FAILED", "Template activated but failed to reload templates")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Template version activated successfully", version)
+}
+
+// DeactivateTemplateVersion stops using a version, so the template falls back
+// to the filesystem copy
+func (h *EmailHandler) DeactivateTemplateVersion(c *gin.Context) {
+	version, ok := h.findTemplateVersion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Model(version).Update("is_active", false).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "TEMPLATE_DEACTIVATE_FAILED", "Failed to deactivate template version")
+		return
+	}
+	version.IsActive = false
+
+	if err := h.templates.ReloadTemplates(); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "TEMPLATE_RELOAD_FAILED", "Template deactivated but failed to reload templates")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Template version deactivated successfully", version)
+}
+
+// findTemplateVersion loads the template version named by the :id parameter,
+// writing an error response when it cannot
+func (h *EmailHandler) findTemplateVersion(c *gin.Context) (*models.EmailTemplate, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "INVALID_TEMPLATE_ID", "Invalid template ID")
+		return nil, false
+	}
+
+	var version models.EmailTemplate
+	if err := h.db.First(&version, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateNotFoundResponse(c, "TEMPLATE_NOT_FOUND", "Template version not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "TEMPLATE_FETCH_FAILED", "Failed to get template version")
+		}
+		return nil, false
+	}
+	return &version, true
+}
+
+// renderPreview renders template content over the sample data, with data
+// from the request taking precedence
+func (h *EmailHandler) renderPreview(c *gin.Context, subject, htmlContent string, data map[string]interface{}) {
+	sample := samplePreviewData()
+	previewData := make(map[string]interface{}, len(sample)+len(data))
+	for key, value := range sample {
+		previewData[key] = value
+	}
+	for key, value := range data {
+		previewData[key] = value
+	}
+
+	renderedSubject, html, text, err := h.templates.RenderPreview(subject, htmlContent, previewData)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "TEMPLATE_RENDER_FAILED", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Template preview rendered successfully", gin.H{
+		"subject":      renderedSubject,
+		"html_content": html,
+		"text_content": text,
+	})
+}
+
+// validateTemplateContent checks that the subject and body parse as templates
+func validateTemplateContent(subject, htmlContent string) error {
+	if err := email.ValidateTemplate(subject); err != nil {
+		return err
+	}
+	return email.ValidateTemplate(htmlContent)
+}
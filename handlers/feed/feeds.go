@@ -0,0 +1,58 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/YasserCherfaoui/MarketProGo/feeds"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// serve writes a generated feed, letting clients and proxies cache it until
+// the next regeneration is due
+func (h *FeedHandler) serve(c *gin.Context, name string) {
+	file, err := h.feeds.Get(c.Request.Context(), name)
+	if err != nil {
+		if errors.Is(err, feeds.ErrUnknownFeed) {
+			response.GenerateNotFoundResponse(c, "feed/get", "Feed not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "feed/get", "Failed to generate feed")
+		}
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=900")
+	c.Header("Last-Modified", file.GeneratedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+// GetSitemap - Public endpoint for the storefront sitemap
+func (h *FeedHandler) GetSitemap(c *gin.Context) {
+	h.serve(c, feeds.Sitemap)
+}
+
+// GetMerchantFeedXML - Public endpoint for the Google Merchant Center feed as RSS
+func (h *FeedHandler) GetMerchantFeedXML(c *gin.Context) {
+	h.serve(c, feeds.MerchantXML)
+}
+
+// GetMerchantFeedCSV - Public endpoint for the Google Merchant Center feed as CSV
+func (h *FeedHandler) GetMerchantFeedCSV(c *gin.Context) {
+	h.serve(c, feeds.MerchantCSV)
+}
+
+// ListFeeds - Admin endpoint for when each feed was last generated and its size
+func (h *FeedHandler) ListFeeds(c *gin.Context) {
+	response.GenerateSuccessResponse(c, "Feeds fetched successfully", h.feeds.Files())
+}
+
+// RegenerateFeeds - Admin endpoint to rebuild the feeds now, after catalog
+// changes that should not wait for the next scheduled run
+func (h *FeedHandler) RegenerateFeeds(c *gin.Context) {
+	if err := h.feeds.Generate(c.Request.Context()); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "feed/regenerate", fmt.Sprintf("Failed to regenerate feeds: %v", err))
+		return
+	}
+	response.GenerateSuccessResponse(c, "Feeds regenerated successfully", h.feeds.Files())
+}
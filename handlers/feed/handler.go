@@ -0,0 +1,15 @@
+package feed
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/feeds"
+)
+
+type FeedHandler struct {
+	feeds *feeds.Service
+}
+
+func NewFeedHandler(feeds *feeds.Service) *FeedHandler {
+	return &FeedHandler{
+		feeds: feeds,
+	}
+}
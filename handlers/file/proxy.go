@@ -3,8 +3,10 @@ package file
 import (
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,6 +22,13 @@ func ProxyFilePreview(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load config"})
 		return
 	}
+	// A signed URL only works with its signature and until it expires
+	if expires, signature := c.Query("expires"), c.Query("signature"); expires != "" || signature != "" {
+		if err := storage.VerifyPreview(conf.Storage.SigningKey, fileId, expires, signature, time.Now()); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+	}
 	// Build the Appwrite /view URL for the file
 	viewURL := conf.AppwriteEndpoint + "/storage/buckets/" + conf.AppwriteBucketId + "/files/" + fileId + "/view?project=" + conf.AppwriteProject
 
@@ -0,0 +1,141 @@
+package fraud
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/fraud"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReviewRequest is an admin's decision on a held payment
+type ReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// ListReviews - Admin endpoint for the payments held by fraud checks, oldest
+// first. Pass status=PENDING for the ones still waiting for a decision.
+func (h *FraudHandler) ListReviews(c *gin.Context) {
+	page, pageSize := pagination(c)
+	checks, total, err := h.fraud.Reviews(c.Request.Context(), models.FraudReviewStatus(c.Query("status")), page, pageSize)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "fraud/list_reviews", "Failed to get fraud reviews")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Fraud reviews retrieved successfully", map[string]interface{}{
+		"data":      checks,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetReview - Admin endpoint for a fraud check with its score and reasons
+func (h *FraudHandler) GetReview(c *gin.Context) {
+	id, ok := parseID(c, "fraud/get_review", "Invalid fraud check ID")
+	if !ok {
+		return
+	}
+	check, err := h.fraud.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "fraud/get_review", "Fraud check not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "fraud/get_review", "Failed to get fraud check")
+		}
+		return
+	}
+	response.GenerateSuccessResponse(c, "Fraud check retrieved successfully", check)
+}
+
+// pendingReview loads a check awaiting review, responding with an error when
+// there is none
+func (h *FraudHandler) pendingReview(c *gin.Context, code string) (*models.FraudCheck, bool) {
+	id, ok := parseID(c, code, "Invalid fraud check ID")
+	if !ok {
+		return nil, false
+	}
+	check, err := h.fraud.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, code, "Fraud check not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to get fraud check")
+		}
+		return nil, false
+	}
+	if check.ReviewStatus != models.FraudReviewPending {
+		response.GenerateBadRequestResponse(c, code, fraud.ErrAlreadyReviewed.Error())
+		return nil, false
+	}
+	return check, true
+}
+
+// ApproveReview - Admin endpoint to release a held payment: the authorized
+// payment is captured and the order continues as paid
+func (h *FraudHandler) ApproveReview(c *gin.Context) {
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateBadRequestResponse(c, "fraud/approve", err.Error())
+		return
+	}
+	check, ok := h.pendingReview(c, "fraud/approve")
+	if !ok {
+		return
+	}
+	if check.Payment == nil || check.Payment.Status != models.RevolutPaymentStatusAuthorized {
+		response.GenerateBadRequestResponse(c, "fraud/approve", "The payment has not been authorized by the customer yet")
+		return
+	}
+
+	if err := h.payments.CapturePayment(c.Request.Context(), strconv.FormatUint(uint64(check.Payment.ID), 10), 0); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "fraud/approve", "Failed to capture payment: "+err.Error())
+		return
+	}
+	h.resolve(c, "fraud/approve", check.ID, true, req.Note)
+}
+
+// RejectReview - Admin endpoint to turn down a held payment: the payment is
+// voided, releasing the authorization, and the order is cancelled
+func (h *FraudHandler) RejectReview(c *gin.Context) {
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateBadRequestResponse(c, "fraud/reject", err.Error())
+		return
+	}
+	check, ok := h.pendingReview(c, "fraud/reject")
+	if !ok {
+		return
+	}
+
+	if check.Payment != nil {
+		status := check.Payment.Status
+		if status == models.RevolutPaymentStatusPending || status == models.RevolutPaymentStatusAuthorized {
+			if err := h.payments.VoidPayment(c.Request.Context(), strconv.FormatUint(uint64(check.Payment.ID), 10)); err != nil {
+				response.GenerateInternalServerErrorResponse(c, "fraud/reject", "Failed to void payment: "+err.Error())
+				return
+			}
+		}
+	}
+	h.resolve(c, "fraud/reject", check.ID, false, req.Note)
+}
+
+// resolve records the review decision and responds with the updated check
+func (h *FraudHandler) resolve(c *gin.Context, code string, id uint, approve bool, note string) {
+	check, err := h.fraud.Resolve(c.Request.Context(), id, getUserIDFromContext(c), approve, note)
+	switch {
+	case err == nil:
+		message := "Payment rejected successfully"
+		if approve {
+			message = "Payment approved successfully"
+		}
+		response.GenerateSuccessResponse(c, message, check)
+	case errors.Is(err, fraud.ErrAlreadyReviewed):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to record review")
+	}
+}
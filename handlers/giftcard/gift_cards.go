@@ -0,0 +1,159 @@
+package giftcard
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CheckBalanceRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type VoidGiftCardRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// CheckBalance - Public endpoint for the balance of the card with a code
+func (h *GiftCardHandler) CheckBalance(c *gin.Context) {
+	var req CheckBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "gift_card/balance", err.Error())
+		return
+	}
+	view, err := h.giftCards.Lookup(req.Code)
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "Gift card balance retrieved successfully", view)
+	case errors.Is(err, giftcard.ErrInvalidCode):
+		response.GenerateNotFoundResponse(c, "gift_card/balance", "Gift card not found")
+	default:
+		response.GenerateInternalServerErrorResponse(c, "gift_card/balance", "Failed to get gift card balance")
+	}
+}
+
+// GetMyGiftCards - Customer endpoint for the gift cards they bought
+func (h *GiftCardHandler) GetMyGiftCards(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == nil {
+		response.GenerateUnauthorizedResponse(c, "gift_card/mine", "User not authenticated")
+		return
+	}
+	var cards []models.GiftCard
+	if err := h.db.Where("purchaser_id = ?", *userID).Order("created_at DESC").Find(&cards).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "gift_card/mine", "Failed to get gift cards")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Gift cards retrieved successfully", cards)
+}
+
+// ListGiftCards - Admin endpoint to list gift cards
+func (h *GiftCardHandler) ListGiftCards(c *gin.Context) {
+	page, pageSize := pagination(c)
+	query := h.db.Model(&models.GiftCard{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if recipient := c.Query("recipient_email"); recipient != "" {
+		query = query.Where("LOWER(recipient_email) = LOWER(?)", recipient)
+	}
+	if last4 := c.Query("last4"); last4 != "" {
+		query = query.Where("last4 = UPPER(?)", last4)
+	}
+	if orderID := c.Query("order_id"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "gift_card/list", "Failed to count gift cards")
+		return
+	}
+	var cards []models.GiftCard
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&cards).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "gift_card/list", "Failed to get gift cards")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Gift cards retrieved successfully", map[string]interface{}{
+		"data":      cards,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetGiftCard - Admin endpoint for a gift card and its ledger
+func (h *GiftCardHandler) GetGiftCard(c *gin.Context) {
+	id, ok := parseID(c, "gift_card/get", "Invalid gift card ID")
+	if !ok {
+		return
+	}
+	var card models.GiftCard
+	if err := h.db.First(&card, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "gift_card/get", "Gift card not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "gift_card/get", "Failed to get gift card")
+		}
+		return
+	}
+	var ledger []models.GiftCardTransaction
+	if err := h.db.Where("gift_card_id = ?", id).Order("created_at DESC, id DESC").Find(&ledger).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "gift_card/get", "Failed to get gift card history")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Gift card retrieved successfully", map[string]interface{}{
+		"gift_card":    card,
+		"transactions": ledger,
+	})
+}
+
+// IssueGiftCard - Admin endpoint to issue a gift card, e.g. as a goodwill
+// gesture. The code is emailed to the recipient.
+func (h *GiftCardHandler) IssueGiftCard(c *gin.Context) {
+	var req giftcard.IssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "gift_card/issue", err.Error())
+		return
+	}
+	card, err := h.giftCards.Issue(req, getUserIDFromContext(c))
+	switch {
+	case err == nil:
+		response.GenerateCreatedResponse(c, "Gift card issued successfully", card)
+	case errors.Is(err, giftcard.ErrInvalidAmount):
+		response.GenerateBadRequestResponse(c, "gift_card/issue", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "gift_card/issue", "Failed to issue gift card")
+	}
+}
+
+// VoidGiftCard - Admin endpoint to cancel a gift card and its balance
+func (h *GiftCardHandler) VoidGiftCard(c *gin.Context) {
+	id, ok := parseID(c, "gift_card/void", "Invalid gift card ID")
+	if !ok {
+		return
+	}
+	var req VoidGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "gift_card/void", err.Error())
+		return
+	}
+	card, err := h.giftCards.Void(id, req.Reason, getUserIDFromContext(c))
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "Gift card voided successfully", card)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "gift_card/void", "Gift card not found")
+	case errors.Is(err, giftcard.ErrVoided):
+		response.GenerateBadRequestResponse(c, "gift_card/void", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "gift_card/void", "Failed to void gift card")
+	}
+}
@@ -0,0 +1,18 @@
+package impersonation
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sessionTTL is how long an impersonation token stays valid
+const sessionTTL = 15 * time.Minute
+
+type ImpersonationHandler struct {
+	db *gorm.DB
+}
+
+func NewImpersonationHandler(db *gorm.DB) *ImpersonationHandler {
+	return &ImpersonationHandler{db: db}
+}
@@ -0,0 +1,129 @@
+package impersonation
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ImpersonateResponse struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	User      models.User `json:"user"`
+}
+
+// Impersonate - Staff endpoint issuing a short-lived read-only token acting as a customer
+func (h *ImpersonationHandler) Impersonate(c *gin.Context) {
+	staffID := c.GetUint("user_id")
+	if _, impersonating := c.Get("impersonator_id"); impersonating {
+		response.GenerateForbiddenResponse(c, "impersonation/start", "Cannot impersonate while impersonating")
+		return
+	}
+
+	targetID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "impersonation/start", "Invalid user ID")
+		return
+	}
+
+	var req ImpersonateRequest
+	// The reason is optional, so an empty body is accepted
+	_ = c.ShouldBindJSON(&req)
+
+	var user models.User
+	if err := h.db.First(&user, targetID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "impersonation/start", "User not found")
+		return
+	}
+	if user.ID == staffID {
+		response.GenerateBadRequestResponse(c, "impersonation/start", "Cannot impersonate yourself")
+		return
+	}
+	if user.UserType == models.Admin || user.UserType == models.SupportAgent {
+		response.GenerateForbiddenResponse(c, "impersonation/start", "Staff accounts cannot be impersonated")
+		return
+	}
+
+	token, expiresAt, err := auth.GenerateImpersonationToken(user, staffID, sessionTTL)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "impersonation/start", "Failed to issue token")
+		return
+	}
+
+	entry := models.ImpersonationLog{
+		ImpersonatorID: staffID,
+		UserID:         user.ID,
+		Action:         models.ImpersonationActionStart,
+		Method:         c.Request.Method,
+		Path:           c.Request.URL.Path,
+		StatusCode:     200,
+		Reason:         req.Reason,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	if err := h.db.Create(&entry).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "impersonation/start", "Failed to write audit log")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Impersonation session started", ImpersonateResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      user,
+	})
+}
+
+// GetImpersonationLogs - Admin endpoint to list impersonation audit entries
+func (h *ImpersonationHandler) GetImpersonationLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.Model(&models.ImpersonationLog{})
+	if impersonatorID := c.Query("impersonator_id"); impersonatorID != "" {
+		query = query.Where("impersonator_id = ?", impersonatorID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "impersonation/logs", "Failed to count logs")
+		return
+	}
+
+	var logs []models.ImpersonationLog
+	if err := query.Preload("Impersonator").Preload("User").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&logs).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "impersonation/logs", "Failed to fetch logs")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Impersonation logs retrieved successfully", gin.H{
+		"logs":        logs,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"total_pages": (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
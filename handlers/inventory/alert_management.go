@@ -1,8 +1,11 @@
 package inventory
 
 import (
+	"fmt"
 	"strconv"
 
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 )
@@ -91,3 +94,43 @@ func (h *InventoryHandler) GetStockAlerts(c *gin.Context) {
 
 	response.GenerateSuccessResponse(c, "Stock alerts retrieved successfully", resp)
 }
+
+// lowStockThreshold is the quantity at or below which a warehouse stock line is low
+const lowStockThreshold = 10
+
+// becameLowStock reports whether a stock line went from above the low-stock
+// threshold to at or below it
+func becameLowStock(previousQuantity, quantity int) bool {
+	return previousQuantity > lowStockThreshold && quantity <= lowStockThreshold
+}
+
+// notifyLowStock alerts admins in their notification center when an adjustment
+// takes a stock line from above the low-stock threshold to at or below it
+func (h *InventoryHandler) notifyLowStock(item *models.InventoryItem, previousQuantity int) {
+	if !becameLowStock(previousQuantity, item.Quantity) {
+		return
+	}
+
+	productName := item.ProductVariant.Name
+	if item.ProductVariant.Product.Name != "" {
+		productName = item.ProductVariant.Product.Name + " - " + item.ProductVariant.Name
+	}
+	title := fmt.Sprintf("Low stock: %s", productName)
+	if item.Quantity == 0 {
+		title = fmt.Sprintf("Out of stock: %s", productName)
+	}
+
+	if _, err := h.notifier.NotifyAdmins(notification.Message{
+		Type:  models.NotificationTypeStockAlert,
+		Title: title,
+		Body:  fmt.Sprintf("%d left in %s (SKU %s)", item.Quantity, item.Warehouse.Name, item.ProductVariant.SKU),
+		Link:  fmt.Sprintf("/admin/inventory/stock/by-product/%d", item.ProductVariantID),
+		Data: models.JSON{
+			"product_variant_id": item.ProductVariantID,
+			"warehouse_id":       item.WarehouseID,
+			"quantity":           item.Quantity,
+		},
+	}); err != nil {
+		fmt.Printf("Warning: Failed to create low stock notification: %v\n", err)
+	}
+}
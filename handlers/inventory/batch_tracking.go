@@ -1,7 +1,10 @@
 package inventory
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
@@ -14,12 +17,18 @@ type BatchTrackingItem struct {
 	ProductVariant  BatchProductVariantInfo `json:"product_variant"`
 	Warehouse       BatchWarehouseInfo      `json:"warehouse"`
 	BatchNumber     string                  `json:"batch_number"`
+	BinLocation     string                  `json:"bin_location"`
 	Quantity        int                     `json:"quantity"`
 	ExpiryDate      *time.Time              `json:"expiry_date"`
 	DaysToExpiry    int                     `json:"days_to_expiry"`
 	Status          string                  `json:"status"`
 }
 
+type SetBinLocationRequest struct {
+	BinLocation string `json:"bin_location" binding:"max=50"`
+	Version     *int   `json:"version"` // version of the item the edit was made on, refused with 409 when it was edited since
+}
+
 type BatchProductVariantInfo struct {
 	ID      uint             `json:"id"`
 	Name    string           `json:"name"`
@@ -128,6 +137,7 @@ func (h *InventoryHandler) GetInventoryBatches(c *gin.Context) {
 				Code: item.Warehouse.Code,
 			},
 			BatchNumber: item.BatchNumber,
+			BinLocation: item.BinLocation,
 			Quantity:    item.Quantity,
 			ExpiryDate:  item.ExpiryDate,
 			Status:      item.Status,
@@ -152,3 +162,44 @@ func (h *InventoryHandler) GetInventoryBatches(c *gin.Context) {
 
 	response.GenerateSuccessResponse(c, "Inventory batches retrieved successfully", resp)
 }
+
+// SetBinLocation - Admin endpoint to set the bin a batch is stored in, used
+// to group pick lists
+func (h *InventoryHandler) SetBinLocation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "inventory/set_bin_location", "Invalid inventory item ID")
+		return
+	}
+	var req SetBinLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "inventory/set_bin_location", err)
+		return
+	}
+
+	var item models.InventoryItem
+	if err := h.db.First(&item, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "inventory/set_bin_location", "Inventory item not found")
+		return
+	}
+	err = models.ErrVersionConflict
+	if req.Version == nil || *req.Version == item.Version {
+		item.BinLocation = strings.TrimSpace(req.BinLocation)
+		err = models.SaveVersioned(h.db, &item, &item.Version, "bin_location")
+	}
+	if errors.Is(err, models.ErrVersionConflict) {
+		var current models.InventoryItem
+		if err := h.db.First(&current, id).Error; err != nil {
+			response.GenerateNotFoundResponse(c, "inventory/set_bin_location", "Inventory item not found")
+			return
+		}
+		response.GenerateResponse(c, http.StatusConflict, err.Error(), current,
+			response.NewAPIError("inventory/version_conflict", "The inventory item was edited since it was loaded, reload it and apply the changes again"))
+		return
+	}
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "inventory/set_bin_location", "Failed to set bin location")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Bin location updated successfully", item)
+}
@@ -0,0 +1,95 @@
+package inventory
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WriteOffRequest struct {
+	Quantity int    `json:"quantity" binding:"min=0"` // 0 writes off all unreserved stock
+	Reason   string `json:"reason"`
+}
+
+type WriteOffExpiredRequest struct {
+	WarehouseID uint   `json:"warehouse_id"` // 0 for all warehouses
+	Reason      string `json:"reason"`
+}
+
+// GetExpiringStock - Admin endpoint to report batches expiring within a number of days
+func (h *InventoryHandler) GetExpiringStock(c *gin.Context) {
+	filter := stock.ExpiringFilter{IncludeExpired: c.DefaultQuery("include_expired", "true") == "true"}
+	if days := c.Query("days"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed <= 0 {
+			response.GenerateBadRequestResponse(c, "inventory/expiring_stock", "days must be a positive number")
+			return
+		}
+		filter.Days = parsed
+	}
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		parsed, err := strconv.ParseUint(warehouseID, 10, 32)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "inventory/expiring_stock", "Invalid warehouse ID")
+			return
+		}
+		filter.WarehouseID = uint(parsed)
+	}
+
+	report, err := h.stock.Expiring(c.Request.Context(), filter)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "inventory/expiring_stock", "Failed to get expiring stock")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Expiring stock retrieved successfully", report)
+}
+
+// WriteOffStock - Admin endpoint to write off stock of a batch, e.g. expired or damaged goods
+func (h *InventoryHandler) WriteOffStock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "inventory/write_off", "Invalid inventory item ID")
+		return
+	}
+	var req WriteOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "inventory/write_off", err)
+		return
+	}
+
+	item, err := h.stock.WriteOff(c.Request.Context(), uint(id), req.Quantity, req.Reason, h.getUserIDFromContext(c))
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "inventory/write_off", "Inventory item not found")
+		return
+	case errors.Is(err, stock.ErrInvalidQuantity), errors.Is(err, stock.ErrInsufficientStock), errors.Is(err, stock.ErrNothingToWriteOff):
+		response.GenerateBadRequestResponse(c, "inventory/write_off", err.Error())
+		return
+	default:
+		response.GenerateInternalServerErrorResponse(c, "inventory/write_off", "Failed to write off stock")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Stock written off successfully", item)
+}
+
+// WriteOffExpiredStock - Admin endpoint to write off all expired stock, optionally in one warehouse
+func (h *InventoryHandler) WriteOffExpiredStock(c *gin.Context) {
+	var req WriteOffExpiredRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateValidationErrorResponse(c, "inventory/write_off_expired", err)
+		return
+	}
+
+	summary, err := h.stock.WriteOffExpired(c.Request.Context(), req.WarehouseID, req.Reason, h.getUserIDFromContext(c))
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "inventory/write_off_expired", "Failed to write off expired stock")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Expired stock written off successfully", summary)
+}
@@ -3,6 +3,8 @@ package inventory
 import (
 	"github.com/YasserCherfaoui/MarketProGo/aw"
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
 	"gorm.io/gorm"
 )
 
@@ -10,6 +12,8 @@ type InventoryHandler struct {
 	db              *gorm.DB
 	gcsService      *gcs.GCService
 	appwriteService *aw.AppwriteService
+	notifier        *notification.Service
+	stock           *stock.Service
 }
 
 func NewInventoryHandler(db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService) *InventoryHandler {
@@ -17,5 +21,7 @@ func NewInventoryHandler(db *gorm.DB, gcsService *gcs.GCService, appwriteService
 		db:              db,
 		gcsService:      gcsService,
 		appwriteService: appwriteService,
+		notifier:        notification.NewService(db),
+		stock:           stock.NewService(db),
 	}
 }
@@ -244,17 +244,17 @@ func (h *InventoryHandler) getLowStockItems(limit int) []LowStockItem {
 			i.quantity as current_quantity,
 			i.reserved as reserved_quantity,
 			(i.quantity - i.reserved) as available_quantity,
-			10 as reorder_level
+			? as reorder_level
 		FROM inventory_items i
 		JOIN product_variants pv ON pv.id = i.product_variant_id
 		JOIN products p ON p.id = pv.product_id
 		JOIN warehouses w ON w.id = i.warehouse_id
-		WHERE i.quantity <= 10
+		WHERE i.quantity <= ?
 		ORDER BY i.quantity ASC
 		LIMIT ?
 	`
 
-	h.db.Raw(query, limit).Scan(&items)
+	h.db.Raw(query, lowStockThreshold, lowStockThreshold, limit).Scan(&items)
 	return items
 }
 
@@ -357,7 +357,7 @@ func (h *InventoryHandler) generateStockAlerts() []StockAlert {
 			SKU:               item.SKU,
 			WarehouseName:     item.WarehouseName,
 			CurrentQuantity:   item.CurrentQuantity,
-			ThresholdQuantity: 10,
+			ThresholdQuantity: lowStockThreshold,
 		}
 
 		if item.CurrentQuantity == 0 {
@@ -0,0 +1,214 @@
+package inventory
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OpenStockCountRequest struct {
+	WarehouseID uint   `json:"warehouse_id" binding:"required"`
+	Notes       string `json:"notes"`
+}
+
+type RecordStockCountsRequest struct {
+	Items []stock.CountEntry `json:"items" binding:"required,min=1"`
+}
+
+type StockCountResponse struct {
+	models.StockCount
+	Summary stock.CountSummary `json:"summary"`
+}
+
+// maxCountUploadSize limits stocktake CSV uploads
+const maxCountUploadSize = 5 << 20
+
+func newStockCountResponse(count *models.StockCount) StockCountResponse {
+	return StockCountResponse{StockCount: *count, Summary: stock.Summarize(count)}
+}
+
+// OpenStockCount - Admin endpoint to open a stocktake of a warehouse
+func (h *InventoryHandler) OpenStockCount(c *gin.Context) {
+	var req OpenStockCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "inventory/open_stock_count", err)
+		return
+	}
+
+	count, err := h.stock.OpenCount(c.Request.Context(), req.WarehouseID, req.Notes, h.getUserIDFromContext(c))
+	if err != nil {
+		h.stockCountError(c, "inventory/open_stock_count", err)
+		return
+	}
+	count, err = h.stock.GetCount(c.Request.Context(), count.ID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "inventory/open_stock_count", "Stock count opened but failed to load it")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Stock count opened successfully", newStockCountResponse(count))
+}
+
+// GetStockCounts - Admin endpoint to list stocktakes
+func (h *InventoryHandler) GetStockCounts(c *gin.Context) {
+	page := 1
+	pageSize := 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+		pageSize = min(ps, 100)
+	}
+
+	query := h.db.Model(&models.StockCount{})
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		query = query.Where("warehouse_id = ?", warehouseID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "inventory/stock_counts", "Failed to count stock counts")
+		return
+	}
+	var counts []models.StockCount
+	if err := query.Preload("Warehouse").Preload("OpenedBy").Preload("ApprovedBy").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&counts).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "inventory/stock_counts", "Failed to get stock counts")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Stock counts retrieved successfully", map[string]interface{}{
+		"data":      counts,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetStockCount - Admin endpoint to get a stocktake with its lines and variances
+func (h *InventoryHandler) GetStockCount(c *gin.Context) {
+	id, ok := h.stockCountID(c, "inventory/stock_count")
+	if !ok {
+		return
+	}
+	count, err := h.stock.GetCount(c.Request.Context(), id)
+	if err != nil {
+		h.stockCountError(c, "inventory/stock_count", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Stock count retrieved successfully", newStockCountResponse(count))
+}
+
+// RecordStockCounts - Admin endpoint to record counted quantities
+func (h *InventoryHandler) RecordStockCounts(c *gin.Context) {
+	id, ok := h.stockCountID(c, "inventory/record_stock_counts")
+	if !ok {
+		return
+	}
+	var req RecordStockCountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "inventory/record_stock_counts", err)
+		return
+	}
+
+	count, err := h.stock.RecordCounts(c.Request.Context(), id, req.Items, h.getUserIDFromContext(c))
+	if err != nil {
+		h.stockCountError(c, "inventory/record_stock_counts", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Counts recorded successfully", newStockCountResponse(count))
+}
+
+// UploadStockCounts - Admin endpoint to record counted quantities from a CSV
+// file with sku, batch_number and counted_quantity columns
+func (h *InventoryHandler) UploadStockCounts(c *gin.Context) {
+	id, ok := h.stockCountID(c, "inventory/upload_stock_counts")
+	if !ok {
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "inventory/upload_stock_counts", "A CSV file is required")
+		return
+	}
+	if fileHeader.Size > maxCountUploadSize {
+		response.GenerateBadRequestResponse(c, "inventory/upload_stock_counts", "File is too large")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "inventory/upload_stock_counts", "Failed to read file")
+		return
+	}
+	defer file.Close()
+
+	entries, err := stock.ParseCountCSV(file)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "inventory/upload_stock_counts", err.Error())
+		return
+	}
+	count, err := h.stock.RecordCounts(c.Request.Context(), id, entries, h.getUserIDFromContext(c))
+	if err != nil {
+		h.stockCountError(c, "inventory/upload_stock_counts", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Counts recorded successfully", newStockCountResponse(count))
+}
+
+// ApproveStockCount - Admin endpoint to approve a stocktake and post its variances as stock adjustments
+func (h *InventoryHandler) ApproveStockCount(c *gin.Context) {
+	id, ok := h.stockCountID(c, "inventory/approve_stock_count")
+	if !ok {
+		return
+	}
+	count, err := h.stock.ApproveCount(c.Request.Context(), id, h.getUserIDFromContext(c))
+	if err != nil {
+		h.stockCountError(c, "inventory/approve_stock_count", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Stock count approved successfully", newStockCountResponse(count))
+}
+
+// CancelStockCount - Admin endpoint to cancel a stocktake without changing stock
+func (h *InventoryHandler) CancelStockCount(c *gin.Context) {
+	id, ok := h.stockCountID(c, "inventory/cancel_stock_count")
+	if !ok {
+		return
+	}
+	count, err := h.stock.CancelCount(c.Request.Context(), id)
+	if err != nil {
+		h.stockCountError(c, "inventory/cancel_stock_count", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Stock count cancelled successfully", newStockCountResponse(count))
+}
+
+func (h *InventoryHandler) stockCountID(c *gin.Context, code string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, "Invalid stock count ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *InventoryHandler) stockCountError(c *gin.Context, code string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, "Stock count or warehouse not found")
+	case errors.Is(err, stock.ErrCountNotOpen), errors.Is(err, stock.ErrCountInProgress), errors.Is(err, stock.ErrInvalidCountEntry):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to update stock count")
+	}
+}
@@ -1,13 +1,17 @@
 package inventory
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type StockAdjustmentRequest struct {
@@ -118,6 +122,10 @@ func (h *InventoryHandler) GetStockLevels(c *gin.Context) {
 	var stockLevels []StockLevelResponse
 	for _, item := range inventoryItems {
 		availableQuantity := item.Quantity - item.Reserved
+		if item.Status == stock.StatusExpired {
+			// Expired batches are kept until written off but cannot be sold
+			availableQuantity = 0
+		}
 		stockStatus := h.getStockStatus(availableQuantity)
 
 		stockLevel := StockLevelResponse{
@@ -143,7 +151,7 @@ func (h *InventoryHandler) GetStockLevels(c *gin.Context) {
 func (h *InventoryHandler) AdjustStock(c *gin.Context) {
 	var req StockAdjustmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateBadRequestResponse(c, "inventory/adjust_stock", err.Error())
+		response.GenerateValidationErrorResponse(c, "inventory/adjust_stock", err)
 		return
 	}
 
@@ -176,75 +184,35 @@ func (h *InventoryHandler) AdjustStock(c *gin.Context) {
 		expiryDate = &parsed
 	}
 
-	// Start transaction
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Get or create inventory item
-	var inventoryItem models.InventoryItem
-	err := tx.Where("product_variant_id = ? AND warehouse_id = ? AND batch_number = ?",
-		req.ProductVariantID, req.WarehouseID, req.BatchNumber).First(&inventoryItem).Error
-
-	if err == gorm.ErrRecordNotFound {
-		// Create new inventory item
-		if req.Quantity < 0 {
-			tx.Rollback()
-			response.GenerateBadRequestResponse(c, "inventory/adjust_stock", "Cannot create new inventory item with negative quantity")
-			return
-		}
-
-		inventoryItem = models.InventoryItem{
-			ProductVariantID: req.ProductVariantID,
-			WarehouseID:      req.WarehouseID,
-			Quantity:         req.Quantity,
-			BatchNumber:      req.BatchNumber,
-			ExpiryDate:       expiryDate,
-			Status:           "active",
-		}
-
-		if err := tx.Create(&inventoryItem).Error; err != nil {
-			tx.Rollback()
-			response.GenerateInternalServerErrorResponse(c, "inventory/adjust_stock", "Failed to create inventory item")
-			return
-		}
-	} else if err != nil {
-		tx.Rollback()
-		response.GenerateInternalServerErrorResponse(c, "inventory/adjust_stock", "Failed to get inventory item")
+	inventoryItem, previousQuantity, err := h.stock.Adjust(c.Request.Context(), stock.Adjustment{
+		VariantID:   req.ProductVariantID,
+		WarehouseID: req.WarehouseID,
+		Quantity:    req.Quantity,
+		BatchNumber: req.BatchNumber,
+		ExpiryDate:  expiryDate,
+		Reason:      req.Reason,
+		Notes:       req.Notes,
+		UserID:      h.getUserIDFromContext(c),
+	})
+	var insufficient *stock.InsufficientStockError
+	switch {
+	case errors.Is(err, stock.ErrNegativeNewBatch):
+		response.GenerateBadRequestResponse(c, "inventory/adjust_stock", "Cannot create new inventory item with negative quantity")
 		return
-	} else {
-		// Update existing inventory item
-		newQuantity := inventoryItem.Quantity + req.Quantity
-		if newQuantity < 0 {
-			tx.Rollback()
-			response.GenerateBadRequestResponse(c, "inventory/adjust_stock", fmt.Sprintf("Insufficient stock. Available: %d", inventoryItem.Quantity))
-			return
-		}
-
-		inventoryItem.Quantity = newQuantity
-		if err := tx.Save(&inventoryItem).Error; err != nil {
-			tx.Rollback()
-			response.GenerateInternalServerErrorResponse(c, "inventory/adjust_stock", "Failed to update inventory item")
-			return
-		}
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "inventory/adjust_stock", "Failed to commit transaction")
+	case errors.As(err, &insufficient):
+		response.GenerateBadRequestResponse(c, "inventory/adjust_stock", insufficient.Error())
+		return
+	case err != nil:
+		response.GenerateInternalServerErrorResponse(c, "inventory/adjust_stock", "Failed to adjust stock")
 		return
 	}
 
+	metrics.InventoryAdjusted("adjust", req.Quantity)
+
 	// Load complete inventory item for response
-	h.db.Preload("ProductVariant.Product").Preload("Warehouse").First(&inventoryItem, inventoryItem.ID)
+	h.db.Preload("ProductVariant.Product").Preload("Warehouse").First(inventoryItem, inventoryItem.ID)
 
-	// Sync the QuantityInStock field with actual inventory
-	if err := h.syncProductVariantStock(req.ProductVariantID); err != nil {
-		// Log the error but don't fail the request
-		fmt.Printf("Warning: Failed to sync product variant stock: %v\n", err)
-	}
+	h.notifyLowStock(inventoryItem, previousQuantity)
 
 	response.GenerateSuccessResponse(c, "Stock adjusted successfully", inventoryItem)
 }
@@ -253,7 +221,7 @@ func (h *InventoryHandler) AdjustStock(c *gin.Context) {
 func (h *InventoryHandler) BulkAdjustStock(c *gin.Context) {
 	var req BulkStockAdjustmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateBadRequestResponse(c, "inventory/bulk_adjust_stock", err.Error())
+		response.GenerateValidationErrorResponse(c, "inventory/bulk_adjust_stock", err)
 		return
 	}
 
@@ -320,6 +288,11 @@ func (h *InventoryHandler) BulkAdjustStock(c *gin.Context) {
 		response.GenerateInternalServerErrorResponse(c, "inventory/bulk_adjust_stock", "Failed to commit transaction")
 		return
 	}
+	for i, result := range results {
+		if result["status"] == "success" {
+			metrics.InventoryAdjusted("bulk", req.Items[i].Quantity)
+		}
+	}
 
 	// Sync QuantityInStock for all affected variants
 	for _, item := range req.Items {
@@ -343,7 +316,7 @@ func (h *InventoryHandler) BulkAdjustStock(c *gin.Context) {
 func (h *InventoryHandler) TransferStock(c *gin.Context) {
 	var req StockTransferRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateBadRequestResponse(c, "inventory/transfer_stock", err.Error())
+		response.GenerateValidationErrorResponse(c, "inventory/transfer_stock", err)
 		return
 	}
 
@@ -379,6 +352,19 @@ func (h *InventoryHandler) TransferStock(c *gin.Context) {
 		}
 	}()
 
+	// Lock the variant's stock in both warehouses so that concurrent
+	// adjustments and transfers queue up behind this one. Rows are locked in
+	// id order so that a transfer the other way round waits instead of
+	// deadlocking.
+	var locked []models.InventoryItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_variant_id = ? AND warehouse_id IN ?", req.ProductVariantID, []uint{req.FromWarehouseID, req.ToWarehouseID}).
+		Order("id").Find(&locked).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "inventory/transfer_stock", "Failed to lock inventory")
+		return
+	}
+
 	// Check available stock in source warehouse
 	var sourceItem models.InventoryItem
 	if err := tx.Where("product_variant_id = ? AND warehouse_id = ?", req.ProductVariantID, req.FromWarehouseID).First(&sourceItem).Error; err != nil {
@@ -395,8 +381,11 @@ func (h *InventoryHandler) TransferStock(c *gin.Context) {
 	}
 
 	// Reduce stock from source
-	sourceItem.Quantity -= req.Quantity
-	if err := tx.Save(&sourceItem).Error; err != nil {
+	if err := stock.TakeUnreserved(tx, sourceItem.ID, req.Quantity); errors.Is(err, stock.ErrNotEnoughStock) {
+		tx.Rollback()
+		response.GenerateBadRequestResponse(c, "inventory/transfer_stock", "Insufficient stock in source warehouse")
+		return
+	} else if err != nil {
 		tx.Rollback()
 		response.GenerateInternalServerErrorResponse(c, "inventory/transfer_stock", "Failed to update source inventory")
 		return
@@ -425,8 +414,7 @@ func (h *InventoryHandler) TransferStock(c *gin.Context) {
 		return
 	} else {
 		// Update existing inventory item
-		destItem.Quantity += req.Quantity
-		if err := tx.Save(&destItem).Error; err != nil {
+		if err := stock.AddQuantity(tx, destItem.ID, req.Quantity); err != nil {
 			tx.Rollback()
 			response.GenerateInternalServerErrorResponse(c, "inventory/transfer_stock", "Failed to update destination inventory")
 			return
@@ -474,6 +462,7 @@ func (h *InventoryHandler) TransferStock(c *gin.Context) {
 		response.GenerateInternalServerErrorResponse(c, "inventory/transfer_stock", "Failed to commit transaction")
 		return
 	}
+	metrics.InventoryTransferred(req.Quantity)
 
 	// Sync the QuantityInStock field with actual inventory
 	if err := h.syncProductVariantStock(req.ProductVariantID); err != nil {
@@ -536,9 +525,9 @@ func (h *InventoryHandler) processSingleStockAdjustment(tx *gorm.DB, req StockAd
 		expiryDate = &parsed
 	}
 
-	// Get or create inventory item
+	// Get, locked until the adjustments are committed, or create inventory item
 	var inventoryItem models.InventoryItem
-	err := tx.Where("product_variant_id = ? AND warehouse_id = ? AND batch_number = ?",
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("product_variant_id = ? AND warehouse_id = ? AND batch_number = ?",
 		req.ProductVariantID, req.WarehouseID, req.BatchNumber).First(&inventoryItem).Error
 
 	if err == gorm.ErrRecordNotFound {
@@ -559,13 +548,11 @@ func (h *InventoryHandler) processSingleStockAdjustment(tx *gorm.DB, req StockAd
 	} else if err != nil {
 		return err
 	} else {
-		newQuantity := inventoryItem.Quantity + req.Quantity
-		if newQuantity < 0 {
+		err := stock.AddQuantity(tx, inventoryItem.ID, req.Quantity)
+		if errors.Is(err, stock.ErrNotEnoughStock) {
 			return fmt.Errorf("insufficient stock")
 		}
-
-		inventoryItem.Quantity = newQuantity
-		return tx.Save(&inventoryItem).Error
+		return err
 	}
 }
 
@@ -577,26 +564,7 @@ func abs(x int) int {
 }
 
 // syncProductVariantStock updates the QuantityInStock field in ProductVariant
-// to reflect the total quantity across all warehouses
+// to the unexpired stock across all warehouses
 func (h *InventoryHandler) syncProductVariantStock(productVariantID uint) error {
-	var totalStock int
-	err := h.db.Model(&models.InventoryItem{}).
-		Where("product_variant_id = ?", productVariantID).
-		Select("COALESCE(SUM(quantity), 0)").
-		Row().Scan(&totalStock)
-
-	if err != nil {
-		return fmt.Errorf("failed to calculate total stock: %w", err)
-	}
-
-	// Update the ProductVariant's QuantityInStock field
-	err = h.db.Model(&models.ProductVariant{}).
-		Where("id = ?", productVariantID).
-		Update("quantity_in_stock", totalStock).Error
-
-	if err != nil {
-		return fmt.Errorf("failed to update product variant stock: %w", err)
-	}
-
-	return nil
+	return stock.SyncVariantStock(h.db, productVariantID)
 }
@@ -41,7 +41,7 @@ type StockSummary struct {
 func (h *InventoryHandler) CreateWarehouse(c *gin.Context) {
 	var req CreateWarehouseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateBadRequestResponse(c, "inventory/create_warehouse", err.Error())
+		response.GenerateValidationErrorResponse(c, "inventory/create_warehouse", err)
 		return
 	}
 
@@ -207,7 +207,7 @@ func (h *InventoryHandler) UpdateWarehouse(c *gin.Context) {
 
 	var req UpdateWarehouseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateBadRequestResponse(c, "inventory/update_warehouse", err.Error())
+		response.GenerateValidationErrorResponse(c, "inventory/update_warehouse", err)
 		return
 	}
 
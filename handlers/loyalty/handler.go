@@ -0,0 +1,54 @@
+package loyalty
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LoyaltyHandler struct {
+	db     *gorm.DB
+	points *loyalty.Service
+}
+
+func NewLoyaltyHandler(db *gorm.DB, points *loyalty.Service) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		db:     db,
+		points: points,
+	}
+}
+
+// parseID parses the id path parameter, responding with a bad request when
+// it is not a number
+func parseID(c *gin.Context, code, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, message)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// pagination reads the page and page_size query parameters
+func pagination(c *gin.Context) (page, pageSize int) {
+	page, pageSize = 1, 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+		pageSize = min(ps, 100)
+	}
+	return page, pageSize
+}
+
+func getUserIDFromContext(c *gin.Context) *uint {
+	if userID, exists := c.Get("user_id"); exists {
+		if uid, ok := userID.(uint); ok {
+			return &uid
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package loyalty
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdjustPointsRequest struct {
+	Points      int    `json:"points" binding:"required"` // negative to debit
+	Description string `json:"description" binding:"required"`
+}
+
+// GetMyPoints - Customer endpoint for their points balance
+func (h *LoyaltyHandler) GetMyPoints(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == nil {
+		response.GenerateUnauthorizedResponse(c, "loyalty/balance", "User not authenticated")
+		return
+	}
+	summary, err := h.points.Summary(*userID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "loyalty/balance", "Failed to get loyalty points")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Loyalty points retrieved successfully", summary)
+}
+
+// GetMyHistory - Customer endpoint for their points history
+func (h *LoyaltyHandler) GetMyHistory(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == nil {
+		response.GenerateUnauthorizedResponse(c, "loyalty/history", "User not authenticated")
+		return
+	}
+	h.history(c, *userID, "loyalty/history")
+}
+
+// GetUserPoints - Admin endpoint for a user's points balance
+func (h *LoyaltyHandler) GetUserPoints(c *gin.Context) {
+	id, ok := parseID(c, "loyalty/user_balance", "Invalid user ID")
+	if !ok {
+		return
+	}
+	if err := h.db.Select("id").First(&models.User{}, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "loyalty/user_balance", "User not found")
+		return
+	}
+	summary, err := h.points.Summary(id)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "loyalty/user_balance", "Failed to get loyalty points")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Loyalty points retrieved successfully", summary)
+}
+
+// GetUserHistory - Admin endpoint for a user's points history
+func (h *LoyaltyHandler) GetUserHistory(c *gin.Context) {
+	id, ok := parseID(c, "loyalty/user_history", "Invalid user ID")
+	if !ok {
+		return
+	}
+	h.history(c, id, "loyalty/user_history")
+}
+
+// AdjustUserPoints - Admin endpoint to credit or debit a user's points, e.g.
+// as a goodwill gesture
+func (h *LoyaltyHandler) AdjustUserPoints(c *gin.Context) {
+	id, ok := parseID(c, "loyalty/adjust", "Invalid user ID")
+	if !ok {
+		return
+	}
+	var req AdjustPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "loyalty/adjust", err.Error())
+		return
+	}
+	entry, err := h.points.Adjust(id, req.Points, req.Description, getUserIDFromContext(c))
+	switch {
+	case err == nil:
+		response.GenerateCreatedResponse(c, "Loyalty points adjusted successfully", entry)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "loyalty/adjust", "User not found")
+	case errors.Is(err, loyalty.ErrInsufficientPoints), errors.Is(err, loyalty.ErrInvalidPoints):
+		response.GenerateBadRequestResponse(c, "loyalty/adjust", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "loyalty/adjust", "Failed to adjust loyalty points")
+	}
+}
+
+// history responds with a page of a user's points ledger, newest first
+func (h *LoyaltyHandler) history(c *gin.Context, userID uint, code string) {
+	page, pageSize := pagination(c)
+	query := h.db.Model(&models.LoyaltyTransaction{}).Where("user_id = ?", userID)
+	if kind := c.Query("type"); kind != "" {
+		query = query.Where("type = ?", kind)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to count loyalty transactions")
+		return
+	}
+	var entries []models.LoyaltyTransaction
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&entries).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to get loyalty history")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Loyalty history retrieved successfully", map[string]interface{}{
+		"data":      entries,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
@@ -0,0 +1,33 @@
+package moderation
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ModerationHandler struct {
+	db          *gorm.DB
+	moderations *moderation.Service
+}
+
+func NewModerationHandler(db *gorm.DB, moderations *moderation.Service) *ModerationHandler {
+	return &ModerationHandler{
+		db:          db,
+		moderations: moderations,
+	}
+}
+
+// parseID parses a path parameter, responding with a bad request when it is
+// not a number
+func parseID(c *gin.Context, param, code, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, message)
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,192 @@
+package moderation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type IssueStrikeRequest struct {
+	Reason    string     `json:"reason" binding:"required,max=500"`
+	ExpiresAt *time.Time `json:"expires_at"` // defaults to the configured strike expiry
+}
+
+type RestrictUserRequest struct {
+	Capability models.UserCapability `json:"capability" binding:"required"` // review, message or login
+	Reason     string                `json:"reason" binding:"required,max=500"`
+	ExpiresAt  *time.Time            `json:"expires_at"` // null until lifted
+}
+
+// GetUserStanding - Admin endpoint for a user's strikes and restrictions
+func (h *ModerationHandler) GetUserStanding(c *gin.Context) {
+	id, ok := parseID(c, "id", "moderation/standing", "Invalid user ID")
+	if !ok {
+		return
+	}
+	standing, err := h.moderations.Standing(c.Request.Context(), id)
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "User standing retrieved successfully", standing)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "moderation/standing", "User not found")
+	default:
+		response.GenerateInternalServerErrorResponse(c, "moderation/standing", "Failed to get user standing")
+	}
+}
+
+// IssueStrike - Admin endpoint to give a user a strike. Strikes that reach a
+// threshold restrict the user automatically.
+func (h *ModerationHandler) IssueStrike(c *gin.Context) {
+	id, ok := parseID(c, "id", "moderation/issue_strike", "Invalid user ID")
+	if !ok {
+		return
+	}
+	var req IssueStrikeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "moderation/issue_strike", err.Error())
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.GenerateBadRequestResponse(c, "moderation/issue_strike", "expires_at must be in the future")
+		return
+	}
+	if !h.userExists(c, id, "moderation/issue_strike") {
+		return
+	}
+
+	var strike *models.UserStrike
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		strike, err = h.moderations.IssueStrike(tx, moderation.StrikeInput{
+			UserID:    id,
+			Source:    models.StrikeSourceManual,
+			Reason:    req.Reason,
+			IssuedBy:  c.GetUint("user_id"),
+			ExpiresAt: req.ExpiresAt,
+		})
+		return err
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "moderation/issue_strike", "Failed to issue strike")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Strike issued successfully", strike)
+}
+
+// RevokeStrike - Admin endpoint to revoke a strike, e.g. after an appeal
+func (h *ModerationHandler) RevokeStrike(c *gin.Context) {
+	id, ok := parseID(c, "id", "moderation/revoke_strike", "Invalid user ID")
+	if !ok {
+		return
+	}
+	strikeID, ok := parseID(c, "strikeId", "moderation/revoke_strike", "Invalid strike ID")
+	if !ok {
+		return
+	}
+
+	var strike *models.UserStrike
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		strike, err = h.moderations.RevokeStrike(tx, id, strikeID, c.GetUint("user_id"))
+		return err
+	})
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "Strike revoked successfully", strike)
+	case errors.Is(err, moderation.ErrStrikeNotFound):
+		response.GenerateNotFoundResponse(c, "moderation/revoke_strike", "Strike not found")
+	case errors.Is(err, moderation.ErrStrikeRevoked):
+		response.GenerateBadRequestResponse(c, "moderation/revoke_strike", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "moderation/revoke_strike", "Failed to revoke strike")
+	}
+}
+
+// RestrictUser - Admin endpoint to stop a user from reviewing, messaging or,
+// with the login capability, logging in
+func (h *ModerationHandler) RestrictUser(c *gin.Context) {
+	id, ok := parseID(c, "id", "moderation/restrict", "Invalid user ID")
+	if !ok {
+		return
+	}
+	var req RestrictUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "moderation/restrict", err.Error())
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.GenerateBadRequestResponse(c, "moderation/restrict", "expires_at must be in the future")
+		return
+	}
+	if !h.userExists(c, id, "moderation/restrict") {
+		return
+	}
+
+	var restriction *models.UserRestriction
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		restriction, err = h.moderations.Restrict(tx, moderation.RestrictionInput{
+			UserID:     id,
+			Capability: req.Capability,
+			Reason:     req.Reason,
+			IssuedBy:   c.GetUint("user_id"),
+			ExpiresAt:  req.ExpiresAt,
+		})
+		return err
+	})
+	switch {
+	case err == nil:
+		response.GenerateCreatedResponse(c, "User restricted successfully", restriction)
+	case errors.Is(err, moderation.ErrUnknownCapability), errors.Is(err, moderation.ErrAlreadyRestricted):
+		response.GenerateBadRequestResponse(c, "moderation/restrict", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "moderation/restrict", "Failed to restrict user")
+	}
+}
+
+// LiftRestriction - Admin endpoint to lift a restriction before it expires
+func (h *ModerationHandler) LiftRestriction(c *gin.Context) {
+	id, ok := parseID(c, "id", "moderation/lift_restriction", "Invalid user ID")
+	if !ok {
+		return
+	}
+	restrictionID, ok := parseID(c, "restrictionId", "moderation/lift_restriction", "Invalid restriction ID")
+	if !ok {
+		return
+	}
+
+	var restriction *models.UserRestriction
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		restriction, err = h.moderations.LiftRestriction(tx, id, restrictionID, c.GetUint("user_id"))
+		return err
+	})
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "Restriction lifted successfully", restriction)
+	case errors.Is(err, moderation.ErrRestrictionNotFound):
+		response.GenerateNotFoundResponse(c, "moderation/lift_restriction", "Restriction not found")
+	case errors.Is(err, moderation.ErrRestrictionNotActive):
+		response.GenerateBadRequestResponse(c, "moderation/lift_restriction", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "moderation/lift_restriction", "Failed to lift restriction")
+	}
+}
+
+// userExists responds with not found unless the user exists
+func (h *ModerationHandler) userExists(c *gin.Context, id uint, code string) bool {
+	if err := h.db.WithContext(c.Request.Context()).Select("id").First(&models.User{}, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, code, "User not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to get user")
+		}
+		return false
+	}
+	return true
+}
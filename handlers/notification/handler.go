@@ -0,0 +1,14 @@
+package notification
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	service *notification.Service
+}
+
+func NewNotificationHandler(db *gorm.DB) *NotificationHandler {
+	return &NotificationHandler{service: notification.NewService(db)}
+}
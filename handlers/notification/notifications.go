@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications lists the current user's notifications, newest first.
+// Pass unread=true to list only unread ones.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c, "notification/list")
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	notifications, totalCount, err := h.service.List(userID, c.Query("unread") == "true", page, limit)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "notification/list", "Failed to get notifications")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Notifications retrieved successfully", gin.H{
+		"notifications": notifications,
+		"page":          page,
+		"limit":         limit,
+		"total_count":   totalCount,
+		"total_pages":   (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetUnreadCount returns the number of unread notifications for the badge
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID, ok := currentUserID(c, "notification/unread_count")
+	if !ok {
+		return
+	}
+
+	count, err := h.service.UnreadCount(userID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "notification/unread_count", "Failed to count notifications")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Unread count retrieved successfully", gin.H{
+		"unread_count": count,
+	})
+}
+
+// MarkRead marks one notification as read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, ok := currentUserID(c, "notification/mark_read")
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "notification/mark_read", "Invalid notification ID")
+		return
+	}
+
+	notif, err := h.service.MarkRead(userID, uint(id))
+	if err != nil {
+		if errors.Is(err, notification.ErrNotFound) {
+			response.GenerateNotFoundResponse(c, "notification/mark_read", "Notification not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "notification/mark_read", "Failed to mark notification as read")
+		}
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Notification marked as read", notif)
+}
+
+// MarkAllRead marks all of the current user's notifications as read
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, ok := currentUserID(c, "notification/mark_all_read")
+	if !ok {
+		return
+	}
+
+	updated, err := h.service.MarkAllRead(userID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "notification/mark_all_read", "Failed to mark notifications as read")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "All notifications marked as read", gin.H{
+		"updated": updated,
+	})
+}
+
+// currentUserID returns the authenticated user's ID, writing an unauthorized
+// response when there is none
+func currentUserID(c *gin.Context, errorCode string) (uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, errorCode, "User not authenticated")
+		return 0, false
+	}
+	return userID.(uint), true
+}
@@ -0,0 +1,111 @@
+package order
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/company"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ApprovalDecisionRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// GetPendingApprovals - Company owner endpoint to list the buyers' orders
+// waiting for approval
+func (h *OrderHandler) GetPendingApprovals(c *gin.Context) {
+	uid := c.GetUint("user_id")
+	orders, err := h.companies.PendingApprovals(c.Request.Context(), uid)
+	if err != nil {
+		approvalError(c, "order/pending_approvals", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Orders waiting for approval retrieved successfully", orders)
+}
+
+// ApproveOrder - Company owner endpoint to approve a buyer's order so it can
+// be paid
+func (h *OrderHandler) ApproveOrder(c *gin.Context) {
+	h.decideOrder(c, "order/approve", true)
+}
+
+// RejectOrder - Company owner endpoint to reject a buyer's order, cancelling
+// it
+func (h *OrderHandler) RejectOrder(c *gin.Context) {
+	h.decideOrder(c, "order/reject", false)
+}
+
+func (h *OrderHandler) decideOrder(c *gin.Context, code string, approve bool) {
+	uid := c.GetUint("user_id")
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, "Invalid order ID")
+		return
+	}
+	var req ApprovalDecisionRequest
+	// The note is optional, so is the body
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.GenerateBadRequestResponse(c, code, err.Error())
+		return
+	}
+
+	var order *models.Order
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = h.companies.Decide(tx, uid, uint(orderID), approve, req.Note)
+		if err != nil {
+			return err
+		}
+		if !approve {
+			return h.releaseOrder(tx, order)
+		}
+		// An order paid in full by gift card was held back from being
+		// treated as paid until it was approved
+		if order.PaymentStatus == models.PaymentStatusPaid {
+			if err := h.giftCards.Activate(tx, order); err != nil {
+				return err
+			}
+			return webhook.Publish(tx, webhook.EventOrderPaid, webhook.OrderData(order))
+		}
+		return nil
+	})
+	if err != nil {
+		if !isApprovalError(err) {
+			slog.ErrorContext(c.Request.Context(), "failed to decide order approval", "component", "order", "order_id", orderID, "error", err)
+		}
+		approvalError(c, code, err)
+		return
+	}
+
+	if approve {
+		response.GenerateSuccessResponse(c, "Order approved successfully", order)
+	} else {
+		response.GenerateSuccessResponse(c, "Order rejected successfully", order)
+	}
+}
+
+func isApprovalError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, company.ErrNoCompany) ||
+		errors.Is(err, company.ErrNotOwner) || errors.Is(err, company.ErrNotAwaitingApproval)
+}
+
+// approvalError responds to an order approval that failed
+func approvalError(c *gin.Context, code string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, "Order not found")
+	case errors.Is(err, company.ErrNoCompany), errors.Is(err, company.ErrNotOwner):
+		response.GenerateForbiddenResponse(c, code, err.Error())
+	case errors.Is(err, company.ErrNotAwaitingApproval):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to process order approval")
+	}
+}
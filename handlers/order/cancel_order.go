@@ -1,7 +1,12 @@
 package order
 
 import (
+	"fmt"
+	"log/slog"
+
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/orderhistory"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -42,26 +47,30 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	}
 
 	// Check if order can be cancelled
-	if order.Status != models.OrderStatusPending {
+	if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusPendingApproval {
 		tx.Rollback()
 		response.GenerateBadRequestResponse(c, "order/cancel_order", "Order cannot be cancelled. Only pending orders can be cancelled")
 		return
 	}
 
 	// Update order status
+	previousStatus := order.Status
 	order.Status = models.OrderStatusCancelled
 	if err := tx.Save(&order).Error; err != nil {
 		tx.Rollback()
 		response.GenerateInternalServerErrorResponse(c, "order/cancel_order", "Failed to cancel order")
 		return
 	}
+	if err := orderhistory.Record(tx, order.ID, previousStatus, order.Status, orderhistory.Change{ActorID: &uid, Reason: "Cancelled by the customer"}); err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "order/cancel_order", "Failed to cancel order")
+		return
+	}
 
-	// Update order items status
-	if err := tx.Model(&models.OrderItem{}).
-		Where("order_id = ?", order.ID).
-		Update("status", "cancelled").Error; err != nil {
+	if err := h.releaseOrder(tx, &order); err != nil {
 		tx.Rollback()
-		response.GenerateInternalServerErrorResponse(c, "order/cancel_order", "Failed to update order items")
+		slog.ErrorContext(c.Request.Context(), "failed to release cancelled order", "component", "order", "order_id", order.ID, "error", err)
+		response.GenerateInternalServerErrorResponse(c, "order/cancel_order", "Failed to cancel order")
 		return
 	}
 
@@ -87,3 +96,42 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 
 	response.GenerateSuccessResponse(c, "Order cancelled successfully", completeOrder)
 }
+
+// releaseOrder cancels the items of a cancelled order and gives back what it
+// held: its reserved stock, delivery slot, coupon, loyalty points and gift
+// card balance
+func (h *OrderHandler) releaseOrder(tx *gorm.DB, order *models.Order) error {
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ?", order.ID).
+		Update("status", "cancelled").Error; err != nil {
+		return fmt.Errorf("failed to update order items: %w", err)
+	}
+
+	// Give back the stock reserved for the order at checkout
+	if h.allocator != nil {
+		if err := h.allocator.Release(tx, order, nil); err != nil {
+			return fmt.Errorf("failed to release stock: %w", err)
+		}
+	}
+
+	// Give the order's delivery slot place to another customer
+	if err := delivery.Release(tx, order); err != nil {
+		return fmt.Errorf("failed to release delivery slot: %w", err)
+	}
+
+	// Make the order's coupon usable again
+	if err := h.referrals.ReleaseCoupon(tx, order); err != nil {
+		return fmt.Errorf("failed to release coupon: %w", err)
+	}
+
+	// Give back the loyalty points spent on the order
+	if err := h.loyalty.Reverse(tx, order); err != nil {
+		return fmt.Errorf("failed to reverse loyalty points: %w", err)
+	}
+
+	// Give back what the order paid by gift card and void the cards bought on it
+	if err := h.giftCards.Reverse(tx, order); err != nil {
+		return fmt.Errorf("failed to reverse gift cards: %w", err)
+	}
+	return nil
+}
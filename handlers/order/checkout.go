@@ -0,0 +1,309 @@
+package order
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyHeader carries the client's key for a checkout; retries of a
+// checkout send the same key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed for a key already checked out
+const ReplayedHeader = "Idempotent-Replayed"
+
+// checkoutStaleAfter is how long a checkout may stay processing before a
+// retry takes it over, for checkouts whose instance stopped halfway
+const checkoutStaleAfter = 5 * time.Minute
+
+var (
+	errCheckoutKeyReused  = errors.New("idempotency key was already used for a different checkout")
+	errCheckoutInProgress = errors.New("a checkout with this idempotency key is in progress")
+)
+
+type CheckoutRequest struct {
+	PlaceOrderRequest
+
+	// Where Revolut sends the customer after paying or giving up
+	ReturnURL string `json:"return_url"`
+	CancelURL string `json:"cancel_url"`
+
+	// Fingerprint of the saved card being paid with, when the storefront
+	// knows it, for the per-card fraud velocity rule
+	CardFingerprint string `json:"card_fingerprint"`
+}
+
+// CheckoutResponse is the order a checkout placed and the payment started for
+// it. Payment is null when a gift card paid for the whole order and when the
+// order is waiting for approval.
+type CheckoutResponse struct {
+	Order   models.Order             `json:"order"`
+	Payment *payment.PaymentResponse `json:"payment"`
+}
+
+// Checkout - Places an order from the user's cart, reserves its stock and
+// starts its Revolut payment in one transaction, so a failed payment leaves no
+// order behind. Retries with the same Idempotency-Key replay the response of
+// the first checkout instead of placing another order.
+func (h *OrderHandler) Checkout(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "order/checkout", "User not authenticated")
+		return
+	}
+	uid := userID.(uint)
+
+	key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+	if key == "" {
+		response.GenerateBadRequestResponse(c, "order/checkout", IdempotencyKeyHeader+" header is required")
+		return
+	}
+	if len(key) > 255 {
+		response.GenerateBadRequestResponse(c, "order/checkout", IdempotencyKeyHeader+" must be at most 255 characters")
+		return
+	}
+
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "order/checkout", err.Error())
+		return
+	}
+
+	checkout, err := h.startCheckout(c, uid, key, checkoutHash(&req))
+	switch {
+	case errors.Is(err, errCheckoutKeyReused):
+		response.GenerateErrorResponse(c, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", err.Error())
+		return
+	case errors.Is(err, errCheckoutInProgress):
+		response.GenerateErrorResponse(c, http.StatusConflict, "CHECKOUT_IN_PROGRESS", err.Error())
+		return
+	case err != nil:
+		slog.ErrorContext(ctx, "failed to start checkout", "component", "order", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "order/checkout", "Failed to start checkout")
+		return
+	}
+	if checkout.Status == models.CheckoutStatusCompleted {
+		c.Header(ReplayedHeader, "true")
+		response.GenerateCreatedResponse(c, "Checkout completed successfully", json.RawMessage(checkout.Response))
+		return
+	}
+
+	claimed := checkout.UpdatedAt
+	result, ok := h.completeCheckout(c, checkout, uid, &req)
+	if !ok {
+		// Nothing was placed, so the key can be used again
+		if err := h.releaseCheckout(ctx, checkout.ID, claimed); err != nil {
+			slog.ErrorContext(ctx, "failed to delete failed checkout", "component", "order", "checkout_id", checkout.ID, "error", err)
+		}
+		return
+	}
+	response.GenerateCreatedResponse(c, "Checkout completed successfully", result)
+}
+
+// startCheckout records a new checkout for the user's key, or returns the
+// checkout already recorded for it. A completed checkout is returned to be
+// replayed; one still processing is refused unless it is stale. The
+// returned checkout's UpdatedAt is the time this request claimed it at.
+func (h *OrderHandler) startCheckout(c *gin.Context, uid uint, key, hash string) (*models.Checkout, error) {
+	db := h.db.WithContext(c.Request.Context())
+	// Postgres keeps microseconds, so the claim time is truncated for it to
+	// compare equal once stored
+	now := time.Now().Truncate(time.Microsecond)
+	checkout := &models.Checkout{UserID: uid, IdempotencyKey: key, RequestHash: hash, Status: models.CheckoutStatusProcessing}
+	checkout.CreatedAt, checkout.UpdatedAt = now, now
+	created := db.Clauses(clause.OnConflict{DoNothing: true}).Create(checkout)
+	if created.Error != nil {
+		return nil, fmt.Errorf("failed to record checkout: %w", created.Error)
+	}
+	if created.RowsAffected == 1 {
+		return checkout, nil
+	}
+
+	var existing models.Checkout
+	if err := db.Where("user_id = ? AND idempotency_key = ?", uid, key).First(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load checkout: %w", err)
+	}
+	if existing.RequestHash != hash {
+		return nil, errCheckoutKeyReused
+	}
+	if existing.Status == models.CheckoutStatusCompleted {
+		return &existing, nil
+	}
+
+	taken := db.Model(&existing).
+		Where("status = ? AND updated_at < ?", models.CheckoutStatusProcessing, now.Add(-checkoutStaleAfter)).
+		Update("updated_at", now)
+	if taken.Error != nil {
+		return nil, fmt.Errorf("failed to take over checkout: %w", taken.Error)
+	}
+	if taken.RowsAffected == 0 {
+		return nil, errCheckoutInProgress
+	}
+	existing.UpdatedAt = now
+	return &existing, nil
+}
+
+// releaseCheckout deletes a failed checkout while it is still processing
+// under the claim this request made at claimed. A retry that took it over as
+// stale may have completed it since, and it is then left alone.
+func (h *OrderHandler) releaseCheckout(ctx context.Context, id uint, claimed time.Time) error {
+	return h.db.WithContext(ctx).Unscoped().
+		Where("status = ? AND updated_at = ?", models.CheckoutStatusProcessing, claimed).
+		Delete(&models.Checkout{}, id).Error
+}
+
+// completeCheckout places the order, reserves its stock, creates its payment
+// and completes the checkout in one transaction. On failure it responds and
+// nothing is committed; a Revolut order created before a failed commit is
+// cancelled.
+func (h *OrderHandler) completeCheckout(c *gin.Context, checkout *models.Checkout, uid uint, req *CheckoutRequest) (*CheckoutResponse, bool) {
+	ctx := c.Request.Context()
+	tx := h.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	placed, ok := h.placeOrder(c, tx, uid, req.PlaceOrderRequest, "order/checkout")
+	if !ok {
+		tx.Rollback()
+		return nil, false
+	}
+	order := &placed.order
+
+	// Reserve the stock now so it is not sold to someone else while the
+	// customer pays
+	if h.allocator != nil {
+		if _, err := h.allocator.Allocate(tx, order, &uid); err != nil {
+			tx.Rollback()
+			if isStockError(err) {
+				response.GenerateBadRequestResponse(c, "order/checkout", err.Error())
+			} else {
+				slog.ErrorContext(ctx, "failed to reserve checkout stock", "component", "order", "error", err)
+				response.GenerateInternalServerErrorResponse(c, "order/checkout", "Failed to reserve stock")
+			}
+			return nil, false
+		}
+	}
+
+	// Orders waiting for approval are paid once approved, and those a gift
+	// card paid for in full need no payment
+	var paid *payment.PaymentResponse
+	if !placed.needsApproval && order.PaymentStatus != models.PaymentStatusPaid {
+		if h.payments == nil {
+			tx.Rollback()
+			response.GenerateInternalServerErrorResponse(c, "order/checkout", "Payments are not configured")
+			return nil, false
+		}
+		var err error
+		paid, err = h.payments.InTx(tx).CreatePayment(ctx, &payment.PaymentRequest{
+			OrderID:         order.ID,
+			Amount:          math.Round((order.FinalAmount-order.GiftCardAmount)*100) / 100,
+			Currency:        settings.Current().Currency,
+			Description:     "Order " + order.OrderNumber,
+			CustomerInfo:    checkoutCustomer(order),
+			ReturnURL:       req.ReturnURL,
+			CancelURL:       req.CancelURL,
+			CaptureMode:     req.CaptureMode,
+			ClientIP:        c.ClientIP(),
+			CardFingerprint: req.CardFingerprint,
+		})
+		if err != nil {
+			tx.Rollback()
+			if errors.Is(err, payment.ErrPaymentBlocked) {
+				response.GenerateErrorResponse(c, http.StatusUnprocessableEntity, "PAYMENT_DECLINED", "Payment could not be accepted for this order. Please contact support.")
+			} else {
+				slog.ErrorContext(ctx, "failed to create checkout payment", "component", "order", "error", err)
+				response.GenerateErrorResponse(c, http.StatusInternalServerError, "PAYMENT_CREATION_FAILED", "Failed to create payment")
+			}
+			return nil, false
+		}
+		order.RevolutOrderID, order.CheckoutURL, order.PaymentProvider = paid.OrderID, paid.CheckoutURL, "revolut"
+	}
+
+	result := &CheckoutResponse{Order: *order, Payment: paid}
+	data, err := json.Marshal(result)
+	if err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "order/checkout", "Failed to complete checkout")
+		return nil, false
+	}
+	updates := map[string]interface{}{
+		"status":       models.CheckoutStatusCompleted,
+		"order_id":     order.ID,
+		"response":     string(data),
+		"completed_at": time.Now(),
+	}
+	if paid != nil {
+		if paymentID, err := strconv.ParseUint(paid.PaymentID, 10, 32); err == nil {
+			updates["payment_id"] = uint(paymentID)
+		}
+	}
+	if err := tx.Model(checkout).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "order/checkout", "Failed to complete checkout")
+		return nil, false
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		if paid != nil {
+			slog.WarnContext(ctx, "checkout rolled back after creating its Revolut order", "component", "order", "revolut_order_id", paid.OrderID, "error", err)
+			if err := h.payments.CancelProviderOrder(ctx, paid.OrderID); err != nil {
+				slog.ErrorContext(ctx, "failed to cancel Revolut order of rolled back checkout", "component", "order", "revolut_order_id", paid.OrderID, "error", err)
+			}
+		}
+		response.GenerateInternalServerErrorResponse(c, "order/checkout", "Failed to commit transaction")
+		return nil, false
+	}
+	h.orderPlaced(ctx, placed)
+	return result, true
+}
+
+// checkoutCustomer builds the payment customer details of an order's buyer,
+// prefilling their address from the order's billing address
+func checkoutCustomer(order *models.Order) *payment.CustomerInfo {
+	user := order.User
+	customer := &payment.CustomerInfo{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  user.FirstName + " " + user.LastName,
+		Phone: user.Phone,
+	}
+	if billing := order.BillingAddress; billing != nil {
+		customer.Address = strings.TrimSpace(billing.StreetAddress1 + " " + billing.StreetAddress2)
+		customer.City = billing.City
+		customer.Country = billing.Country
+		customer.PostCode = billing.PostalCode
+		if customer.Phone == "" {
+			customer.Phone = billing.Phone
+		}
+	}
+	return customer
+}
+
+// checkoutHash identifies a checkout request, to tell a retry from a key
+// reused for a different checkout
+func checkoutHash(req *CheckoutRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,194 @@
+package order
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
+	"github.com/YasserCherfaoui/MarketProGo/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/referral"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakePayments records payments in the checkout's transaction instead of
+// creating Revolut orders
+type fakePayments struct {
+	payment.PaymentService
+	tx      *gorm.DB
+	decline bool
+}
+
+func (f *fakePayments) InTx(tx *gorm.DB) payment.PaymentService {
+	inTx := *f
+	inTx.tx = tx
+	return &inTx
+}
+
+func (f *fakePayments) CreatePayment(ctx context.Context, req *payment.PaymentRequest) (*payment.PaymentResponse, error) {
+	if f.decline {
+		return nil, payment.ErrPaymentBlocked
+	}
+	revolutID := fmt.Sprintf("rev-%d", req.OrderID)
+	record := models.Payment{OrderID: req.OrderID, RevolutOrderID: revolutID, RevolutPaymentID: revolutID, Amount: req.Amount,
+		Currency: req.Currency, Status: models.RevolutPaymentStatusPending, CheckoutURL: "https://checkout.example.com/" + revolutID}
+	if err := f.tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &payment.PaymentResponse{PaymentID: strconv.FormatUint(uint64(record.ID), 10), OrderID: revolutID, Amount: req.Amount,
+		Currency: req.Currency, Status: string(record.Status), CheckoutURL: record.CheckoutURL}, nil
+}
+
+func TestCheckout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Pricing reads outside the checkout's transaction, so the connections
+	// need to share a database
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "checkout.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Company{}, &models.Address{}, &models.Cart{}, &models.CartItem{},
+		&models.Product{}, &models.ProductVariant{}, &models.BundleComponent{}, &models.ProductVariantPriceTier{}, &models.ProductImage{}, &models.ProductOptionValue{},
+		&models.PriceList{}, &models.PriceListItem{}, &models.TaxRate{},
+		&models.DeliveryZone{}, &models.DeliveryWindow{}, &models.DeliverySlot{}, &models.Warehouse{}, &models.InventoryItem{}, &models.StockMovement{},
+		&models.Order{}, &models.OrderItem{}, &models.OrderStatusChange{}, &models.Shipment{}, &models.StockAllocation{}, &models.Payment{},
+		&models.Referral{}, &models.Coupon{}, &models.LoyaltyTransaction{}, &models.GiftCard{}, &models.GiftCardTransaction{},
+		&models.WebhookSubscription{}, &models.OutboxMessage{}, &models.Checkout{}))
+
+	customer := models.User{Email: "customer@example.com", Password: "x", FirstName: "Amina", UserType: models.Customer, IsActive: true}
+	require.NoError(t, db.Create(&customer).Error)
+	require.NoError(t, db.Create(&models.Address{UserID: &customer.ID, StreetAddress1: "2 Home Street", City: "Leeds", PostalCode: "LS1 1AA", Country: "GB"}).Error)
+	product := models.Product{Name: "Olive Oil"}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{ProductID: product.ID, Name: "1L", SKU: "OIL-1L", BasePrice: 10, IsActive: true, MinQuantity: 1}
+	require.NoError(t, db.Omit("Product").Create(&variant).Error)
+	warehouse := models.Warehouse{Name: "Leeds Warehouse", Code: "WH-LDS", IsActive: true,
+		Address: models.Address{StreetAddress1: "1 Dock Road", City: "Leeds", PostalCode: "LS2 2BB", Country: "GB"}}
+	require.NoError(t, db.Create(&warehouse).Error)
+	stock := models.InventoryItem{ProductVariantID: variant.ID, WarehouseID: warehouse.ID, Quantity: 5, Status: "active"}
+	require.NoError(t, db.Omit("ProductVariant", "Warehouse").Create(&stock).Error)
+	cart := models.Cart{UserID: &customer.ID}
+	require.NoError(t, db.Create(&cart).Error)
+	require.NoError(t, db.Omit("ProductVariant", "Product").Create(&models.CartItem{CartID: cart.ID, ProductVariantID: variant.ID, Quantity: 2}).Error)
+
+	points := loyalty.NewService(db, &cfg.LoyaltyConfig{})
+	payments := &fakePayments{decline: true}
+	handler := NewOrderHandler(db, nil, tax.NewTaxService(db, &cfg.TaxConfig{}), fulfillment.NewAllocator(nil), delivery.NewService(&cfg.DeliveryConfig{}),
+		points, referral.NewService(db, &cfg.ReferralConfig{}, points), giftcard.NewService(db, &cfg.GiftCardConfig{}, nil), nil).
+		WithPayments(payments)
+	router := gin.New()
+	customerRoutes := router.Group("", func(c *gin.Context) { c.Set("user_id", customer.ID) })
+	customerRoutes.POST("/checkout", handler.Checkout)
+	customerRoutes.PUT("/orders/:id/cancel", handler.CancelOrder)
+
+	checkout := func(key string, body interface{}) *httptest.ResponseRecorder {
+		var payload bytes.Buffer
+		require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/checkout", &payload)
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) CheckoutResponse {
+		var envelope struct {
+			Data CheckoutResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope), w.Body.String())
+		return envelope.Data
+	}
+	reserved := func() int {
+		var item models.InventoryItem
+		require.NoError(t, db.First(&item, stock.ID).Error)
+		return item.Reserved
+	}
+	body := CheckoutRequest{PlaceOrderRequest: PlaceOrderRequest{PaymentMethod: "card"}, ReturnURL: "https://shop.example.com/return"}
+
+	assert.Equal(t, http.StatusBadRequest, checkout("", body).Code, "the idempotency key is required")
+
+	// A declined payment leaves no order behind and the key can be retried
+	w := checkout("key-1", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	var orders, items, checkouts int64
+	db.Model(&models.Order{}).Count(&orders)
+	db.Model(&models.CartItem{}).Count(&items)
+	db.Unscoped().Model(&models.Checkout{}).Count(&checkouts)
+	assert.Zero(t, orders)
+	assert.Equal(t, int64(1), items, "the cart is kept")
+	assert.Zero(t, checkouts)
+	assert.Zero(t, reserved())
+
+	payments.decline = false
+	w = checkout("key-1", body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	first := decode(w)
+	assert.Equal(t, models.OrderStatusPending, first.Order.Status)
+	assert.Equal(t, 20.0, first.Order.FinalAmount)
+	require.NotNil(t, first.Payment)
+	assert.Equal(t, 20.0, first.Payment.Amount)
+	assert.NotEmpty(t, first.Payment.CheckoutURL)
+	assert.Equal(t, 2, reserved())
+	db.Model(&models.CartItem{}).Count(&items)
+	assert.Zero(t, items, "the cart is checked out")
+
+	// A retry replays the first response instead of placing another order
+	w = checkout("key-1", body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(ReplayedHeader))
+	replayed := decode(w)
+	assert.Equal(t, first.Order.ID, replayed.Order.ID)
+	assert.Equal(t, first.Payment.PaymentID, replayed.Payment.PaymentID)
+	db.Model(&models.Order{}).Count(&orders)
+	assert.Equal(t, int64(1), orders)
+
+	other := body
+	other.CustomerNotes = "Leave at the door"
+	assert.Equal(t, http.StatusUnprocessableEntity, checkout("key-1", other).Code, "a key cannot be reused for another checkout")
+
+	// Cancelling the order gives back its reserved stock
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/orders/%d/cancel", first.Order.ID), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Zero(t, reserved())
+
+	// A request whose stale checkout a retry took over and completed does not
+	// delete it when it fails
+	claimed := time.Now().Add(-2 * checkoutStaleAfter).Truncate(time.Microsecond)
+	stale := models.Checkout{UserID: customer.ID, IdempotencyKey: "key-2", RequestHash: checkoutHash(&body), Status: models.CheckoutStatusProcessing}
+	stale.CreatedAt, stale.UpdatedAt = claimed, claimed
+	require.NoError(t, db.Create(&stale).Error)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/checkout", nil)
+	retry, err := handler.startCheckout(c, customer.ID, "key-2", checkoutHash(&body))
+	require.NoError(t, err)
+	assert.True(t, retry.UpdatedAt.After(claimed))
+	require.NoError(t, db.Model(&models.Checkout{}).Where("id = ?", stale.ID).Update("status", models.CheckoutStatusCompleted).Error)
+	require.NoError(t, handler.releaseCheckout(c, stale.ID, claimed))
+	require.NoError(t, db.First(&models.Checkout{}, stale.ID).Error, "the completed checkout is kept")
+
+	// The retry's own claim still releases it while it is processing
+	require.NoError(t, db.Model(&models.Checkout{}).Where("id = ?", stale.ID).Updates(map[string]interface{}{
+		"status": models.CheckoutStatusProcessing, "updated_at": retry.UpdatedAt}).Error)
+	require.NoError(t, handler.releaseCheckout(c, stale.ID, retry.UpdatedAt))
+	db.Unscoped().Model(&models.Checkout{}).Where("id = ?", stale.ID).Count(&checkouts)
+	assert.Zero(t, checkouts)
+}
@@ -0,0 +1,112 @@
+package order
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/utils/export"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// orderHistoryRow is one line of one order in a customer's order history export
+type orderHistoryRow struct {
+	OrderNumber    string
+	OrderDate      time.Time
+	Status         string
+	PaymentStatus  string
+	PaymentMethod  string
+	TrackingNumber string
+	ShippingAmount float64
+	OrderDiscount  float64
+	OrderTotal     float64
+	ProductName    string
+	VariantName    string
+	SKU            string
+	Quantity       int
+	UnitPrice      float64
+	TaxAmount      float64
+	DiscountAmount float64
+	LineTotal      float64
+	LineStatus     string
+}
+
+// ExportOrderHistory streams the current user's full order history as CSV,
+// or XLSX with format=xlsx, one row per order line, newest order first
+func (h *OrderHandler) ExportOrderHistory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "order/export_history", "Unauthorized")
+		return
+	}
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "order/export_history", err.Error())
+		return
+	}
+
+	db := h.db.WithContext(c.Request.Context())
+	rows, err := db.Table("order_items").
+		Select(`orders.order_number, orders.order_date, orders.status, orders.payment_status, orders.payment_method,
+			orders.tracking_number, orders.shipping_amount, orders.discount_amount AS order_discount,
+			orders.final_amount AS order_total, products.name AS product_name, product_variants.name AS variant_name,
+			product_variants.sku, order_items.quantity, order_items.unit_price, order_items.tax_amount,
+			order_items.discount_amount, order_items.total_amount AS line_total, order_items.status AS line_status`).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Joins("LEFT JOIN product_variants ON product_variants.id = order_items.product_variant_id").
+		Joins("LEFT JOIN products ON products.id = product_variants.product_id").
+		Where("orders.user_id = ? AND order_items.deleted_at IS NULL", userID).
+		Order("orders.order_date DESC, orders.id DESC, order_items.id").
+		Rows()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/export_history", "Failed to export orders")
+		return
+	}
+	defer rows.Close()
+
+	fileName := format.FileName(fmt.Sprintf("orders-%s", time.Now().Format("20060102-150405")))
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Status(200)
+
+	// Errors after the first byte is sent can only be logged
+	writer, err := export.NewWriter(format, c.Writer, "orders")
+	if err != nil {
+		log.Printf("Failed to start order history export: %v", err)
+		return
+	}
+	if err := writer.WriteRow([]string{"Order Number", "Order Date", "Status", "Payment Status", "Payment Method", "Tracking Number",
+		"Shipping", "Order Discount", "Order Total", "Product", "Variant", "SKU", "Quantity", "Unit Price", "Tax", "Discount",
+		"Line Total", "Line Status"}); err != nil {
+		log.Printf("Failed to write order history export: %v", err)
+		return
+	}
+	for rows.Next() {
+		var r orderHistoryRow
+		if err := db.ScanRows(rows, &r); err != nil {
+			log.Printf("Failed to read order history export row: %v", err)
+			return
+		}
+		if err := writer.WriteRow([]string{
+			r.OrderNumber, r.OrderDate.UTC().Format(time.RFC3339), r.Status, r.PaymentStatus, r.PaymentMethod, r.TrackingNumber,
+			money(r.ShippingAmount), money(r.OrderDiscount), money(r.OrderTotal), r.ProductName, r.VariantName, r.SKU,
+			strconv.Itoa(r.Quantity), money(r.UnitPrice), money(r.TaxAmount), money(r.DiscountAmount), money(r.LineTotal), r.LineStatus,
+		}); err != nil {
+			log.Printf("Failed to write order history export: %v", err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Failed to read order history export rows: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Failed to finish order history export: %v", err)
+	}
+}
+
+// money formats an amount for an export cell
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
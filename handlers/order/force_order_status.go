@@ -0,0 +1,126 @@
+package order
+
+import (
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/orderhistory"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ForceOrderStatusRequest struct {
+	Status         models.OrderStatus `json:"status" binding:"required"`
+	Reason         string             `json:"reason" binding:"required,max=1000"`
+	TrackingNumber string             `json:"tracking_number"`
+}
+
+// ForceOrderStatus - Admin endpoint to move an order straight to a status it
+// cannot reach in one step, such as delivering an order that is still
+// pending or taking an order out of approval. The order goes through each
+// status on the way, so stock, payments and rewards follow it, and every step
+// is recorded as forced with the reason given.
+func (h *OrderHandler) ForceOrderStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("id")
+
+	var req ForceOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "order/force_status", err.Error())
+		return
+	}
+	if !isKnownStatus(req.Status) {
+		response.GenerateBadRequestResponse(c, "order/force_status", "Invalid order status")
+		return
+	}
+
+	tx := h.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var order models.Order
+	if err := tx.First(&order, "id = ?", orderID).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateNotFoundResponse(c, "order/force_status", "Order not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "order/force_status", "Failed to get order")
+		}
+		return
+	}
+
+	path := forcedPath(order.Status, req.Status)
+	if path == nil {
+		tx.Rollback()
+		response.GenerateBadRequestResponse(c, "order/force_status",
+			fmt.Sprintf("Order cannot be forced from %s to %s", order.Status, req.Status))
+		return
+	}
+
+	previousStatus := order.Status
+	change := orderhistory.Change{ActorID: currentUserID(c), Reason: req.Reason, Forced: true}
+	delivered := false
+	for _, status := range path {
+		newlyDelivered, err := h.changeStatus(tx, &order, status, req.TrackingNumber, change)
+		if err != nil {
+			tx.Rollback()
+			statusChangeError(c, "order/force_status", err)
+			return
+		}
+		delivered = delivered || newlyDelivered
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/force_status", "Failed to commit transaction")
+		return
+	}
+
+	completeOrder, ok := h.afterStatusChange(c, "order/force_status", order.ID, previousStatus, delivered)
+	if !ok {
+		return
+	}
+	response.GenerateSuccessResponse(c, "Order status forced successfully", completeOrder)
+}
+
+// forcedTransitions are the moves only a forced status change may make, on
+// top of the state machine's own
+var forcedTransitions = map[models.OrderStatus][]models.OrderStatus{
+	// Approve an order on the company owner's behalf
+	models.OrderStatusPendingApproval: {
+		models.OrderStatusPending,
+	},
+}
+
+// forcedPath returns the statuses an order goes through to get from one
+// status to another, shortest first, or nil when it cannot get there. Orders
+// never move backwards, and cancelled or returned orders stay that way.
+func forcedPath(from, to models.OrderStatus) []models.OrderStatus {
+	if from == to {
+		return nil
+	}
+	previous := map[models.OrderStatus]models.OrderStatus{from: from}
+	queue := []models.OrderStatus{from}
+	for len(queue) > 0 {
+		status := queue[0]
+		queue = queue[1:]
+		if status == to {
+			var path []models.OrderStatus
+			for ; status != from; status = previous[status] {
+				path = append([]models.OrderStatus{status}, path...)
+			}
+			return path
+		}
+		next := append(append([]models.OrderStatus{}, statusTransitions[status]...), forcedTransitions[status]...)
+		for _, candidate := range next {
+			if _, seen := previous[candidate]; !seen {
+				previous[candidate] = status
+				queue = append(queue, candidate)
+			}
+		}
+	}
+	return nil
+}
@@ -16,6 +16,8 @@ func (h *OrderHandler) GetAllOrders(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	status := c.Query("status")
 	paymentStatus := c.Query("payment_status")
+	paymentProvider := c.Query("payment_provider")
+	customerID := c.Query("customer_id")
 	search := c.Query("search") // Search by order number, customer name, or email
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
@@ -41,6 +43,12 @@ func (h *OrderHandler) GetAllOrders(c *gin.Context) {
 	if paymentStatus != "" {
 		query = query.Where("payment_status = ?", paymentStatus)
 	}
+	if paymentProvider != "" {
+		query = query.Where("orders.payment_provider = ?", paymentProvider)
+	}
+	if customerID != "" {
+		query = query.Where("orders.user_id = ?", customerID)
+	}
 	if startDate != "" {
 		query = query.Where("order_date >= ?", startDate)
 	}
@@ -16,7 +16,7 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 	}
 
 	var order models.Order
-	if err := h.db.
+	if err := preloadMessages(h.db).
 		Preload("User").
 		Preload("Company").
 		Preload("ShippingAddress").
@@ -25,6 +25,8 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 		Preload("Items.ProductVariant.OptionValues").
 		Preload("Items.Product"). // Legacy support
 		Preload("Items.InventoryItem").
+		Preload("Shipments.Warehouse").
+		Preload("Shipments.Allocations").
 		First(&order, orderID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.GenerateNotFoundResponse(c, "order/get_order_by_id", "Order not found")
@@ -33,6 +35,12 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 		}
 		return
 	}
+	unread, err := countUnread(h.db, order.ID, true)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_order_by_id", "Failed to count unread messages")
+		return
+	}
+	order.UnreadMessages = unread
 
 	response.GenerateSuccessResponse(c, "Order retrieved successfully", order)
 }
@@ -42,6 +42,7 @@ type OrderStats struct {
 
 // GetOrderStats - Admin endpoint to get order statistics for dashboard
 func (h *OrderHandler) GetOrderStats(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
 	var stats OrderStats
 
 	// Calculate date ranges
@@ -51,16 +52,16 @@ func (h *OrderHandler) GetOrderStats(c *gin.Context) {
 	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 
 	// Overall statistics
-	h.db.Model(&models.Order{}).Count(&stats.TotalOrders)
+	db.Model(&models.Order{}).Count(&stats.TotalOrders)
 
-	h.db.Model(&models.Order{}).
+	db.Model(&models.Order{}).
 		Select("COALESCE(SUM(final_amount), 0)").
 		Where("status != ?", models.OrderStatusCancelled).
 		Scan(&stats.TotalRevenue)
 
 	if stats.TotalOrders > 0 {
 		var completedOrders int64
-		h.db.Model(&models.Order{}).
+		db.Model(&models.Order{}).
 			Where("status != ?", models.OrderStatusCancelled).
 			Count(&completedOrders)
 		if completedOrders > 0 {
@@ -69,51 +70,51 @@ func (h *OrderHandler) GetOrderStats(c *gin.Context) {
 	}
 
 	// Order status breakdown
-	h.db.Model(&models.Order{}).Where("status = ?", models.OrderStatusPending).Count(&stats.PendingOrders)
-	h.db.Model(&models.Order{}).Where("status = ?", models.OrderStatusProcessing).Count(&stats.ProcessingOrders)
-	h.db.Model(&models.Order{}).Where("status = ?", models.OrderStatusShipped).Count(&stats.ShippedOrders)
-	h.db.Model(&models.Order{}).Where("status = ?", models.OrderStatusDelivered).Count(&stats.DeliveredOrders)
-	h.db.Model(&models.Order{}).Where("status = ?", models.OrderStatusCancelled).Count(&stats.CancelledOrders)
-	h.db.Model(&models.Order{}).Where("status = ?", models.OrderStatusReturned).Count(&stats.ReturnedOrders)
+	db.Model(&models.Order{}).Where("status = ?", models.OrderStatusPending).Count(&stats.PendingOrders)
+	db.Model(&models.Order{}).Where("status = ?", models.OrderStatusProcessing).Count(&stats.ProcessingOrders)
+	db.Model(&models.Order{}).Where("status = ?", models.OrderStatusShipped).Count(&stats.ShippedOrders)
+	db.Model(&models.Order{}).Where("status = ?", models.OrderStatusDelivered).Count(&stats.DeliveredOrders)
+	db.Model(&models.Order{}).Where("status = ?", models.OrderStatusCancelled).Count(&stats.CancelledOrders)
+	db.Model(&models.Order{}).Where("status = ?", models.OrderStatusReturned).Count(&stats.ReturnedOrders)
 
 	// Payment status breakdown
-	h.db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusPending).Count(&stats.PendingPayments)
-	h.db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusPaid).Count(&stats.PaidOrders)
-	h.db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusFailed).Count(&stats.FailedPayments)
-	h.db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusRefunded).Count(&stats.RefundedOrders)
+	db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusPending).Count(&stats.PendingPayments)
+	db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusPaid).Count(&stats.PaidOrders)
+	db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusFailed).Count(&stats.FailedPayments)
+	db.Model(&models.Order{}).Where("payment_status = ?", models.PaymentStatusRefunded).Count(&stats.RefundedOrders)
 
 	// Today's statistics
-	h.db.Model(&models.Order{}).
+	db.Model(&models.Order{}).
 		Where("order_date >= ?", startOfDay).
 		Count(&stats.TodayOrders)
 
-	h.db.Model(&models.Order{}).
+	db.Model(&models.Order{}).
 		Select("COALESCE(SUM(final_amount), 0)").
 		Where("order_date >= ? AND status != ?", startOfDay, models.OrderStatusCancelled).
 		Scan(&stats.TodayRevenue)
 
 	// This week's statistics
-	h.db.Model(&models.Order{}).
+	db.Model(&models.Order{}).
 		Where("order_date >= ?", startOfWeek).
 		Count(&stats.WeekOrders)
 
-	h.db.Model(&models.Order{}).
+	db.Model(&models.Order{}).
 		Select("COALESCE(SUM(final_amount), 0)").
 		Where("order_date >= ? AND status != ?", startOfWeek, models.OrderStatusCancelled).
 		Scan(&stats.WeekRevenue)
 
 	// This month's statistics
-	h.db.Model(&models.Order{}).
+	db.Model(&models.Order{}).
 		Where("order_date >= ?", startOfMonth).
 		Count(&stats.MonthOrders)
 
-	h.db.Model(&models.Order{}).
+	db.Model(&models.Order{}).
 		Select("COALESCE(SUM(final_amount), 0)").
 		Where("order_date >= ? AND status != ?", startOfMonth, models.OrderStatusCancelled).
 		Scan(&stats.MonthRevenue)
 
 	// Recent orders (last 10)
-	h.db.Preload("User").
+	db.Preload("User").
 		Preload("ShippingAddress").
 		Order("created_at DESC").
 		Limit(10).
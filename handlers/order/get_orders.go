@@ -89,7 +89,7 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 	}
 
 	var order models.Order
-	if err := h.db.
+	if err := preloadMessages(h.db).
 		Preload("User").
 		Preload("ShippingAddress").
 		Preload("Items.ProductVariant.Product").
@@ -105,6 +105,12 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		}
 		return
 	}
+	unread, err := countUnread(h.db, order.ID, false)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_order", "Failed to count unread messages")
+		return
+	}
+	order.UnreadMessages = unread
 
 	response.GenerateSuccessResponse(c, "Order retrieved successfully", order)
 }
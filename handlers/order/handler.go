@@ -1,18 +1,55 @@
 package order
 
 import (
+	"github.com/YasserCherfaoui/MarketProGo/company"
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
 	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
+	"github.com/YasserCherfaoui/MarketProGo/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/referral"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
 	"gorm.io/gorm"
 )
 
 type OrderHandler struct {
 	db              *gorm.DB
 	emailTriggerSvc *email.EmailTriggerService
+	priceResolver   *pricing.Resolver
+	taxService      *tax.TaxService
+	notifier        *notification.Service
+	allocator       *fulfillment.Allocator
+	slots           *delivery.Service
+	loyalty         *loyalty.Service
+	referrals       *referral.Service
+	giftCards       *giftcard.Service
+	companies       *company.Service
+	payments        payment.PaymentService // nil refuses checkouts
+	texts           *sms.Service           // nil sends order updates by email only
 }
 
-func NewOrderHandler(db *gorm.DB, emailTriggerSvc *email.EmailTriggerService) *OrderHandler {
+func NewOrderHandler(db *gorm.DB, emailTriggerSvc *email.EmailTriggerService, taxService *tax.TaxService, allocator *fulfillment.Allocator, slots *delivery.Service, points *loyalty.Service, referrals *referral.Service, giftCards *giftcard.Service, companies *company.Service) *OrderHandler {
 	return &OrderHandler{
 		db:              db,
 		emailTriggerSvc: emailTriggerSvc,
+		priceResolver:   pricing.NewResolver(db),
+		taxService:      taxService,
+		notifier:        notification.NewService(db),
+		allocator:       allocator,
+		slots:           slots,
+		loyalty:         points,
+		referrals:       referrals,
+		giftCards:       giftCards,
+		companies:       companies,
 	}
 }
+
+// WithPayments sets the service checkouts start their payments with
+func (h *OrderHandler) WithPayments(payments payment.PaymentService) *OrderHandler {
+	h.payments = payments
+	return h
+}
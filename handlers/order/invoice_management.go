@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -80,6 +81,7 @@ func (h *OrderHandler) CreateInvoice(c *gin.Context) {
 	invoiceNumber := generateInvoiceNumber()
 
 	// Create invoice
+	business := settings.Current()
 	invoice := models.Invoice{
 		OrderID:          req.OrderID,
 		InvoiceNumber:    invoiceNumber,
@@ -87,10 +89,15 @@ func (h *OrderHandler) CreateInvoice(c *gin.Context) {
 		DueDate:          dueDate,
 		Amount:           order.FinalAmount,
 		TaxAmount:        order.TaxAmount,
+		TaxBreakdown:     order.TaxBreakdown,
+		Currency:         business.Currency,
 		Status:           "pending",
 		PaymentMethod:    req.PaymentMethod,
 		PaymentReference: req.PaymentReference,
 		Notes:            req.Notes,
+		IssuerName:       business.CompanyName,
+		IssuerEmail:      business.SupportEmail,
+		IssuerURL:        business.SiteURL,
 	}
 
 	if err := tx.Create(&invoice).Error; err != nil {
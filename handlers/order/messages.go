@@ -0,0 +1,309 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxMessageAttachments is the most files a single order message can carry
+const maxMessageAttachments = 5
+
+var errUnknownAttachment = errors.New("attachment was not uploaded by the sender")
+
+// OrderMessageRequest is a new message in an order's conversation.
+// Attachments are the file_url values returned by the support attachment
+// upload.
+type OrderMessageRequest struct {
+	Body        string   `json:"body" binding:"required,max=5000"`
+	Attachments []string `json:"attachments"`
+}
+
+// UnreadConversation is an order with messages the viewer has not read
+type UnreadConversation struct {
+	OrderID       uint   `json:"order_id"`
+	OrderNumber   string `json:"order_number"`
+	Unread        int64  `json:"unread"`
+	LastMessageID uint   `json:"last_message_id"`
+}
+
+// UnreadMessages is the viewer's unread order messages
+type UnreadMessages struct {
+	Total         int64                `json:"total"`
+	Conversations []UnreadConversation `json:"conversations"`
+}
+
+// preloadMessages loads an order's conversation, oldest message first, with
+// only the names of the senders
+func preloadMessages(db *gorm.DB) *gorm.DB {
+	return db.Preload("Messages", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, id ASC") }).
+		Preload("Messages.Attachments").
+		Preload("Messages.Sender", func(db *gorm.DB) *gorm.DB { return db.Select("id", "first_name", "last_name") })
+}
+
+// countUnread returns how many messages of an order the customer, or the
+// store when forAdmin is set, has not read
+func countUnread(db *gorm.DB, orderID uint, forAdmin bool) (int64, error) {
+	var count int64
+	err := db.Model(&models.OrderMessage{}).
+		Where("order_id = ? AND is_from_admin = ? AND read_at IS NULL", orderID, !forAdmin).
+		Count(&count).Error
+	return count, err
+}
+
+// markRead marks the messages of an order sent by the other side as read
+func markRead(db *gorm.DB, orderID uint, forAdmin bool) error {
+	return db.Model(&models.OrderMessage{}).
+		Where("order_id = ? AND is_from_admin = ? AND read_at IS NULL", orderID, !forAdmin).
+		Update("read_at", time.Now()).Error
+}
+
+// unreadConversations lists the orders with unread messages, most recent
+// message first. userID limits them to a customer's orders when set.
+func unreadConversations(db *gorm.DB, forAdmin bool, userID *uint) (*UnreadMessages, error) {
+	query := db.Table("order_messages").
+		Select("orders.id AS order_id, orders.order_number, COUNT(*) AS unread, MAX(order_messages.id) AS last_message_id").
+		Joins("JOIN orders ON orders.id = order_messages.order_id AND orders.deleted_at IS NULL").
+		Where("order_messages.deleted_at IS NULL AND order_messages.read_at IS NULL AND order_messages.is_from_admin = ?", !forAdmin)
+	if userID != nil {
+		query = query.Where("orders.user_id = ?", *userID)
+	}
+	unread := &UnreadMessages{Conversations: []UnreadConversation{}}
+	if err := query.Group("orders.id, orders.order_number").Order("last_message_id DESC").Scan(&unread.Conversations).Error; err != nil {
+		return nil, err
+	}
+	for _, conversation := range unread.Conversations {
+		unread.Total += conversation.Unread
+	}
+	return unread, nil
+}
+
+// createMessage saves a message and its attachments in tx. Attachments must
+// have been uploaded by the sender.
+func createMessage(tx *gorm.DB, message *models.OrderMessage, fileURLs []string) error {
+	for _, fileURL := range fileURLs {
+		var upload models.SupportUpload
+		if err := tx.Where("user_id = ? AND file_url = ?", message.SenderID, fileURL).First(&upload).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errUnknownAttachment
+			}
+			return err
+		}
+		message.Attachments = append(message.Attachments, models.OrderMessageAttachment{
+			FileName: upload.FileName,
+			FileURL:  upload.FileURL,
+			FileSize: upload.FileSize,
+			FileType: upload.FileType,
+		})
+	}
+	return tx.Create(message).Error
+}
+
+// sendMessage adds a message from the customer, or the store when fromAdmin
+// is set, to an order's conversation and tells the other side about it
+func (h *OrderHandler) sendMessage(c *gin.Context, code string, order *models.Order, fromAdmin bool) {
+	senderID := c.GetUint("user_id")
+	var req OrderMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, code, err.Error())
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		response.GenerateBadRequestResponse(c, code, "Message body is required")
+		return
+	}
+	if len(req.Attachments) > maxMessageAttachments {
+		response.GenerateBadRequestResponse(c, code, fmt.Sprintf("A message can have at most %d attachments", maxMessageAttachments))
+		return
+	}
+
+	message := models.OrderMessage{OrderID: order.ID, SenderID: senderID, IsFromAdmin: fromAdmin, Body: req.Body}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := createMessage(tx, &message, req.Attachments); err != nil {
+			return err
+		}
+		// Replying reads the conversation
+		if err := markRead(tx, order.ID, fromAdmin); err != nil {
+			return err
+		}
+		if fromAdmin {
+			h.queueOrderMessageEmail(tx, order, &message)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errUnknownAttachment) {
+			response.GenerateBadRequestResponse(c, code, "Attachments must be uploaded with /support/attachments first")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to send message")
+		}
+		return
+	}
+
+	msg := notification.Message{
+		Type: models.NotificationTypeOrderMessage,
+		Body: message.Body,
+		Data: models.JSON{"order_id": order.ID, "message_id": message.ID},
+	}
+	if fromAdmin {
+		msg.Title = fmt.Sprintf("New message about order %s", order.OrderNumber)
+		msg.Link = fmt.Sprintf("/orders/%d", order.ID)
+		_, err = h.notifier.Notify(order.UserID, msg)
+	} else {
+		msg.Title = fmt.Sprintf("Customer message on order %s", order.OrderNumber)
+		msg.Link = fmt.Sprintf("/admin/orders/%d", order.ID)
+		_, err = h.notifier.NotifyAdmins(msg)
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to create order message notification", "component", "order", "error", err)
+	}
+
+	if err := h.db.Preload("Attachments").
+		Preload("Sender", func(db *gorm.DB) *gorm.DB { return db.Select("id", "first_name", "last_name") }).
+		First(&message, message.ID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Message sent but failed to load it")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Message sent successfully", message)
+}
+
+// queueOrderMessageEmail records the email telling the customer about a
+// message from the store in the outbox of tx
+func (h *OrderHandler) queueOrderMessageEmail(tx *gorm.DB, order *models.Order, message *models.OrderMessage) {
+	if h.emailTriggerSvc == nil {
+		return
+	}
+	var customer models.User
+	if err := tx.First(&customer, order.UserID).Error; err != nil || customer.Email == "" {
+		return
+	}
+	senderName := settings.Current().CompanyName
+	var sender models.User
+	if err := tx.Select("first_name", "last_name").First(&sender, message.SenderID).Error; err == nil {
+		if name := strings.TrimSpace(sender.FirstName + " " + sender.LastName); name != "" {
+			senderName = name
+		}
+	}
+	userName := strings.TrimSpace(customer.FirstName + " " + customer.LastName)
+	data := map[string]interface{}{
+		"UserName":        userName,
+		"OrderNumber":     order.OrderNumber,
+		"SenderName":      senderName,
+		"SentAt":          time.Now().Format("2006-01-02 15:04:05"),
+		"Message":         message.Body,
+		"AttachmentCount": len(message.Attachments),
+		"OrderURL":        settings.Current().URL("/orders/%d", order.ID),
+		"subject":         fmt.Sprintf("New message about your order %s", order.OrderNumber),
+	}
+	if err := h.emailTriggerSvc.InTx(tx).TriggerOrderMessage(customer.Email, userName, data); err != nil {
+		slog.Error("failed to queue order message email", "component", "order", "order_id", order.ID, "error", err)
+	}
+}
+
+// customerOrder loads an order of the authenticated customer, responding
+// with not found when it is not theirs
+func (h *OrderHandler) customerOrder(c *gin.Context, code string) (*models.Order, bool) {
+	var order models.Order
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, code, "Order not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to get order")
+		}
+		return nil, false
+	}
+	return &order, true
+}
+
+// adminOrder loads any order, responding with not found when there is none
+func (h *OrderHandler) adminOrder(c *gin.Context, code string) (*models.Order, bool) {
+	var order models.Order
+	if err := h.db.First(&order, "id = ?", c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, code, "Order not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to get order")
+		}
+		return nil, false
+	}
+	return &order, true
+}
+
+// conversation responds with an order's messages and marks the ones from the
+// other side read
+func (h *OrderHandler) conversation(c *gin.Context, code string, order *models.Order, forAdmin bool) {
+	if err := markRead(h.db, order.ID, forAdmin); err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to mark messages read")
+		return
+	}
+	if err := preloadMessages(h.db).First(order, order.ID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to get messages")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Messages retrieved successfully", order.Messages)
+}
+
+// GetOrderMessages - Customer endpoint for the conversation about one of
+// their orders; reading it marks the store's messages read
+func (h *OrderHandler) GetOrderMessages(c *gin.Context) {
+	if order, ok := h.customerOrder(c, "order/get_messages"); ok {
+		h.conversation(c, "order/get_messages", order, false)
+	}
+}
+
+// SendOrderMessage - Customer endpoint to write to the store about one of
+// their orders
+func (h *OrderHandler) SendOrderMessage(c *gin.Context) {
+	if order, ok := h.customerOrder(c, "order/send_message"); ok {
+		h.sendMessage(c, "order/send_message", order, false)
+	}
+}
+
+// GetUnreadOrderMessages - Customer endpoint for their orders with unread
+// messages from the store
+func (h *OrderHandler) GetUnreadOrderMessages(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	unread, err := unreadConversations(h.db, false, &userID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/unread_messages", "Failed to count unread messages")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Unread messages retrieved successfully", unread)
+}
+
+// GetOrderMessagesAdmin - Admin endpoint for the conversation about an
+// order; reading it marks the customer's messages read
+func (h *OrderHandler) GetOrderMessagesAdmin(c *gin.Context) {
+	if order, ok := h.adminOrder(c, "order/admin_get_messages"); ok {
+		h.conversation(c, "order/admin_get_messages", order, true)
+	}
+}
+
+// SendOrderMessageAdmin - Admin endpoint to write to the customer about
+// their order. The customer is emailed.
+func (h *OrderHandler) SendOrderMessageAdmin(c *gin.Context) {
+	if order, ok := h.adminOrder(c, "order/admin_send_message"); ok {
+		h.sendMessage(c, "order/admin_send_message", order, true)
+	}
+}
+
+// GetUnreadOrderMessagesAdmin - Admin endpoint for the orders with unread
+// customer messages
+func (h *OrderHandler) GetUnreadOrderMessagesAdmin(c *gin.Context) {
+	unread, err := unreadConversations(h.db, true, nil)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/admin_unread_messages", "Failed to count unread messages")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Unread messages retrieved successfully", unread)
+}
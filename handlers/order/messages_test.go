@@ -0,0 +1,113 @@
+package order
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestOrderConversation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Company{}, &models.Address{}, &models.Order{}, &models.OrderItem{}, &models.Product{},
+		&models.ProductVariant{}, &models.ProductImage{}, &models.ProductOptionValue{}, &models.InventoryItem{}, &models.Shipment{}, &models.StockAllocation{},
+		&models.OrderMessage{}, &models.OrderMessageAttachment{},
+		&models.SupportUpload{}, &models.Notification{}))
+
+	customer := models.User{Email: "customer@example.com", Password: "x", FirstName: "Amina", UserType: models.Customer, IsActive: true}
+	admin := models.User{Email: "admin@example.com", Password: "x", FirstName: "Karim", UserType: models.Admin, IsActive: true}
+	require.NoError(t, db.Create(&[]*models.User{&customer, &admin}).Error)
+	order := models.Order{OrderNumber: "ORD-1", UserID: customer.ID}
+	other := models.Order{OrderNumber: "ORD-2", UserID: admin.ID}
+	require.NoError(t, db.Create(&[]*models.Order{&order, &other}).Error)
+	require.NoError(t, db.Create(&models.SupportUpload{UserID: customer.ID, FileID: "f1", FileName: "damaged.png", FileURL: "/file/preview/f1", FileType: "image/png"}).Error)
+
+	handler := NewOrderHandler(db, nil, nil, nil, nil, nil, nil, nil, nil)
+	router := gin.New()
+	as := func(userID uint) gin.HandlerFunc { return func(c *gin.Context) { c.Set("user_id", userID) } }
+	customerRoutes := router.Group("/orders", as(customer.ID))
+	customerRoutes.GET("/messages/unread", handler.GetUnreadOrderMessages)
+	customerRoutes.GET("/:id", handler.GetOrder)
+	customerRoutes.GET("/:id/messages", handler.GetOrderMessages)
+	customerRoutes.POST("/:id/messages", handler.SendOrderMessage)
+	adminRoutes := router.Group("/admin/orders", as(admin.ID))
+	adminRoutes.GET("/messages/unread", handler.GetUnreadOrderMessagesAdmin)
+	adminRoutes.GET("/:id", handler.GetOrderByID)
+	adminRoutes.GET("/:id/messages", handler.GetOrderMessagesAdmin)
+	adminRoutes.POST("/:id/messages", handler.SendOrderMessageAdmin)
+
+	call := func(method, path string, body interface{}, dest interface{}) int {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if dest != nil {
+			var envelope struct {
+				Data json.RawMessage `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope), w.Body.String())
+			require.NoError(t, json.Unmarshal(envelope.Data, dest), w.Body.String())
+		}
+		return w.Code
+	}
+
+	// Customers can only write about their own orders, with their own uploads
+	assert.Equal(t, http.StatusNotFound, call(http.MethodPost, "/orders/2/messages", OrderMessageRequest{Body: "Hello"}, nil))
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/orders/1/messages", OrderMessageRequest{Body: "Hello", Attachments: []string{"/file/preview/other"}}, nil))
+
+	var sent models.OrderMessage
+	require.Equal(t, http.StatusCreated, call(http.MethodPost, "/orders/1/messages", OrderMessageRequest{Body: "The jar arrived broken", Attachments: []string{"/file/preview/f1"}}, &sent))
+	assert.False(t, sent.IsFromAdmin)
+	require.Len(t, sent.Attachments, 1)
+	assert.Equal(t, "damaged.png", sent.Attachments[0].FileName)
+
+	var unread UnreadMessages
+	call(http.MethodGet, "/admin/orders/messages/unread", nil, &unread)
+	assert.EqualValues(t, 1, unread.Total)
+	require.Len(t, unread.Conversations, 1)
+	assert.Equal(t, "ORD-1", unread.Conversations[0].OrderNumber)
+
+	var adminView models.Order
+	call(http.MethodGet, "/admin/orders/1", nil, &adminView)
+	assert.EqualValues(t, 1, adminView.UnreadMessages)
+	require.Len(t, adminView.Messages, 1)
+	assert.Equal(t, "Amina", adminView.Messages[0].Sender.FirstName)
+
+	require.Equal(t, http.StatusCreated, call(http.MethodPost, "/admin/orders/1/messages", OrderMessageRequest{Body: "Sorry, a replacement is on its way"}, nil))
+	call(http.MethodGet, "/admin/orders/messages/unread", nil, &unread)
+	assert.Zero(t, unread.Total, "replying reads the conversation")
+
+	var customerView models.Order
+	call(http.MethodGet, "/orders/1", nil, &customerView)
+	assert.EqualValues(t, 1, customerView.UnreadMessages)
+	assert.Len(t, customerView.Messages, 2)
+
+	var thread []models.OrderMessage
+	call(http.MethodGet, "/orders/1/messages", nil, &thread)
+	require.Len(t, thread, 2)
+	assert.True(t, thread[1].IsFromAdmin)
+	call(http.MethodGet, "/orders/messages/unread", nil, &unread)
+	assert.Zero(t, unread.Total, "opening the conversation reads it")
+
+	var notifications []models.Notification
+	require.NoError(t, db.Order("id").Find(&notifications).Error)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, admin.ID, notifications[0].UserID)
+	assert.Equal(t, customer.ID, notifications[1].UserID)
+	assert.Equal(t, models.NotificationTypeOrderMessage, notifications[1].Type)
+}
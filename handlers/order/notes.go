@@ -0,0 +1,67 @@
+package order
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OrderNoteRequest is a new internal note on an order
+type OrderNoteRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
+
+// preloadNoteAuthor loads only the names of a note's author
+func preloadNoteAuthor(db *gorm.DB) *gorm.DB {
+	return db.Preload("Author", func(db *gorm.DB) *gorm.DB { return db.Select("id", "first_name", "last_name") })
+}
+
+// GetOrderNotes - Admin endpoint to list the internal notes of an order,
+// oldest first
+func (h *OrderHandler) GetOrderNotes(c *gin.Context) {
+	order, ok := h.adminOrder(c, "order/get_notes")
+	if !ok {
+		return
+	}
+
+	notes := []models.OrderNote{}
+	if err := preloadNoteAuthor(h.db).
+		Where("order_id = ?", order.ID).
+		Order("created_at ASC, id ASC").
+		Find(&notes).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_notes", "Failed to get order notes")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Order notes retrieved successfully", notes)
+}
+
+// AddOrderNote - Admin endpoint to keep an internal note on an order. The
+// customer never sees it.
+func (h *OrderHandler) AddOrderNote(c *gin.Context) {
+	order, ok := h.adminOrder(c, "order/add_note")
+	if !ok {
+		return
+	}
+
+	var req OrderNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "order/add_note", err.Error())
+		return
+	}
+
+	note := models.OrderNote{
+		OrderID:  order.ID,
+		AuthorID: c.GetUint("user_id"),
+		Body:     req.Body,
+	}
+	if err := h.db.Create(&note).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/add_note", "Failed to add order note")
+		return
+	}
+	if err := preloadNoteAuthor(h.db).First(&note, note.ID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/add_note", "Note added but failed to load details")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Order note added successfully", note)
+}
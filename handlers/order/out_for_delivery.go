@@ -0,0 +1,65 @@
+package order
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MarkOutForDelivery - Admin endpoint to record that a shipped order left
+// with its driver, letting the customer know it arrives today
+func (h *OrderHandler) MarkOutForDelivery(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var order models.Order
+	if err := h.db.WithContext(ctx).First(&order, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateNotFoundResponse(c, "order/out_for_delivery", "Order not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "order/out_for_delivery", "Failed to get order")
+		}
+		return
+	}
+	if order.Status != models.OrderStatusShipped {
+		response.GenerateBadRequestResponse(c, "order/out_for_delivery", "Only shipped orders can go out for delivery")
+		return
+	}
+
+	// Customers are told once even when the request is repeated
+	now := time.Now()
+	marked := h.db.WithContext(ctx).Model(&models.Order{}).
+		Where("id = ? AND out_for_delivery_at IS NULL", order.ID).
+		Update("out_for_delivery_at", now)
+	if marked.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/out_for_delivery", "Failed to update order")
+		return
+	}
+	if marked.RowsAffected == 0 {
+		response.GenerateBadRequestResponse(c, "order/out_for_delivery", "Order is already out for delivery")
+		return
+	}
+
+	if _, err := h.notifier.Notify(order.UserID, notification.Message{
+		Type:  models.NotificationTypeOrderUpdate,
+		Title: fmt.Sprintf("Order %s is out for delivery", order.OrderNumber),
+		Body:  fmt.Sprintf("Your order %s is on its way and arrives today.", order.OrderNumber),
+		Link:  fmt.Sprintf("/orders/%d", order.ID),
+		Data:  models.JSON{"order_id": order.ID, "status": order.Status, "out_for_delivery": true},
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to create order update notification", "component", "order", "error", err)
+	}
+	h.textOrderUpdate(ctx, order.ID, sms.OrderOutForDelivery)
+
+	response.GenerateSuccessResponse(c, "Order marked out for delivery", gin.H{
+		"order_id":            order.ID,
+		"order_number":        order.OrderNumber,
+		"out_for_delivery_at": now,
+	})
+}
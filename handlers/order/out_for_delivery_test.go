@@ -0,0 +1,68 @@
+package order
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMarkOutForDelivery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1) // the order update is texted in the background
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Order{}, &models.Notification{}, &models.SMSMessage{}))
+
+	verifiedAt := time.Now()
+	customer := models.User{Email: "customer@example.com", Password: "x", FirstName: "Amina", UserType: models.Customer,
+		Phone: "+447700900123", PhoneVerifiedAt: &verifiedAt, OrderUpdatesChannel: models.ChannelSMS}
+	require.NoError(t, db.Create(&customer).Error)
+	pending := models.Order{OrderNumber: "ORD-1", UserID: customer.ID, Status: models.OrderStatusPending, OrderDate: time.Now()}
+	shipped := models.Order{OrderNumber: "ORD-2", UserID: customer.ID, Status: models.OrderStatusShipped, OrderDate: time.Now()}
+	require.NoError(t, db.Create(&[]*models.Order{&pending, &shipped}).Error)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order_out_for_delivery.txt"), []byte("{{.OrderNumber}} arrives today"), 0o644))
+	provider := sms.NewMockSMSProvider()
+	handler := NewOrderHandler(db, nil, nil, nil, nil, nil, nil, nil, nil).
+		WithTexts(sms.NewService(db, provider, &cfg.SMSConfig{TemplateDir: dir}))
+	router := gin.New()
+	router.POST("/admin/orders/:id/out-for-delivery", handler.MarkOutForDelivery)
+
+	call := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, call("/admin/orders/9/out-for-delivery"))
+	assert.Equal(t, http.StatusBadRequest, call("/admin/orders/1/out-for-delivery"), "only shipped orders go out for delivery")
+	assert.Equal(t, http.StatusOK, call("/admin/orders/2/out-for-delivery"))
+	assert.Equal(t, http.StatusBadRequest, call("/admin/orders/2/out-for-delivery"), "customers are told once")
+
+	require.Eventually(t, func() bool { return len(provider.Sent()) > 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []sms.MockSentSMS{{Channel: models.ChannelSMS, To: "+447700900123", Body: "ORD-2 arrives today"}}, provider.Sent())
+
+	var reloaded models.Order
+	require.NoError(t, db.First(&reloaded, shipped.ID).Error)
+	assert.NotNil(t, reloaded.OutForDeliveryAt)
+
+	var notifications int64
+	require.NoError(t, db.Model(&models.Notification{}).Where("user_id = ?", customer.ID).Count(&notifications).Error)
+	assert.Equal(t, int64(1), notifications)
+}
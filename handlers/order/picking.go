@@ -0,0 +1,236 @@
+package order
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MarkShipmentLinesRequest struct {
+	// AllocationIDs are the lines to mark; empty marks every line
+	AllocationIDs []uint `json:"allocation_ids"`
+}
+
+type CreatePickListResponse struct {
+	PickList *fulfillment.PickListView  `json:"pick_list"`
+	Skipped  []fulfillment.SkippedOrder `json:"skipped"`
+}
+
+// CreatePickList - Admin endpoint to put the shipments of a batch of paid
+// orders on a pick list. Paid orders that are still pending are confirmed.
+func (h *OrderHandler) CreatePickList(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req fulfillment.PickListRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateBadRequestResponse(c, "order/create_pick_list", err.Error())
+		return
+	}
+	if h.allocator == nil {
+		response.GenerateInternalServerErrorResponse(c, "order/create_pick_list", "Fulfillment is not configured")
+		return
+	}
+
+	var result *fulfillment.PickListResult
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = h.allocator.CreatePickList(tx, req, currentUserID(c))
+		return err
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, fulfillment.ErrNothingToPick):
+		response.GenerateBadRequestResponse(c, "order/create_pick_list", err.Error())
+		return
+	default:
+		slog.ErrorContext(ctx, "failed to create pick list", "component", "order", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "order/create_pick_list", "Failed to create pick list")
+		return
+	}
+
+	// Let customers whose orders were confirmed know in their notification center
+	for _, order := range result.Confirmed {
+		if _, err := h.notifier.Notify(order.UserID, notification.Message{
+			Type:  models.NotificationTypeOrderUpdate,
+			Title: fmt.Sprintf("Order %s is %s", order.OrderNumber, order.Status),
+			Body:  fmt.Sprintf("Your order %s has been updated to %s.", order.OrderNumber, order.Status),
+			Link:  fmt.Sprintf("/orders/%d", order.ID),
+			Data:  models.JSON{"order_id": order.ID, "status": order.Status},
+		}); err != nil {
+			slog.ErrorContext(ctx, "failed to create order update notification", "component", "order", "error", err)
+		}
+	}
+
+	view, err := fulfillment.BuildPickList(h.db.WithContext(ctx), result.PickList.ID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/create_pick_list", "Pick list created but failed to load it")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Pick list created successfully", CreatePickListResponse{
+		PickList: view,
+		Skipped:  result.Skipped,
+	})
+}
+
+// GetPickLists - Admin endpoint to list pick lists, newest first
+func (h *OrderHandler) GetPickLists(c *gin.Context) {
+	page := 1
+	pageSize := 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+		pageSize = min(ps, 100)
+	}
+
+	query := h.db.WithContext(c.Request.Context()).Model(&models.PickList{})
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		query = query.Where("warehouse_id = ?", warehouseID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/pick_lists", "Failed to count pick lists")
+		return
+	}
+	var lists []models.PickList
+	if err := query.Preload("Shipments").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&lists).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/pick_lists", "Failed to get pick lists")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Pick lists retrieved successfully", map[string]interface{}{
+		"data":      lists,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetPickList - Admin endpoint to get a pick list grouped by warehouse and
+// bin, as JSON or, with format=html, as a printable page
+func (h *OrderHandler) GetPickList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "order/pick_list", "Invalid pick list ID")
+		return
+	}
+	view, err := fulfillment.BuildPickList(h.db.WithContext(c.Request.Context()), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "order/pick_list", "Pick list not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "order/pick_list", "Failed to get pick list")
+		}
+		return
+	}
+
+	if c.Query("format") == "html" {
+		var page bytes.Buffer
+		if err := fulfillment.RenderPickList(&page, view); err != nil {
+			response.GenerateInternalServerErrorResponse(c, "order/pick_list", "Failed to render pick list")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+		return
+	}
+	response.GenerateSuccessResponse(c, "Pick list retrieved successfully", view)
+}
+
+// GetPackingSlip - Admin endpoint to get the packing slip of a shipment, as
+// JSON or, with format=html, as a printable page
+func (h *OrderHandler) GetPackingSlip(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
+	var shipment models.Shipment
+	if err := db.Where("id = ? AND order_id = ?", c.Param("shipmentId"), c.Param("id")).First(&shipment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "order/packing_slip", "Order or shipment not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "order/packing_slip", "Failed to get shipment")
+		}
+		return
+	}
+	slip, err := fulfillment.BuildPackingSlip(db, &shipment)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/packing_slip", "Failed to build packing slip")
+		return
+	}
+
+	if c.Query("format") == "html" {
+		var page bytes.Buffer
+		if err := fulfillment.RenderPackingSlip(&page, slip); err != nil {
+			response.GenerateInternalServerErrorResponse(c, "order/packing_slip", "Failed to render packing slip")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+		return
+	}
+	response.GenerateSuccessResponse(c, "Packing slip retrieved successfully", slip)
+}
+
+// PickShipment - Admin endpoint to mark lines of a shipment as picked
+func (h *OrderHandler) PickShipment(c *gin.Context) {
+	h.markShipmentLines(c, "order/pick_shipment", "Shipment lines picked successfully", h.allocator.PickShipment)
+}
+
+// PackShipment - Admin endpoint to mark picked lines of a shipment as packed.
+// A packed shipment is ready to ship.
+func (h *OrderHandler) PackShipment(c *gin.Context) {
+	h.markShipmentLines(c, "order/pack_shipment", "Shipment lines packed successfully", h.allocator.PackShipment)
+}
+
+func (h *OrderHandler) markShipmentLines(c *gin.Context, code, message string, mark func(*gorm.DB, *models.Shipment, []uint, *uint) error) {
+	ctx := c.Request.Context()
+	var req MarkShipmentLinesRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateBadRequestResponse(c, code, err.Error())
+		return
+	}
+
+	var shipment models.Shipment
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Select("id", "status").First(&order, c.Param("id")).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusProcessing {
+			return errOrderNotProcessing
+		}
+		if err := tx.Where("id = ? AND order_id = ?", c.Param("shipmentId"), order.ID).First(&shipment).Error; err != nil {
+			return err
+		}
+		return mark(tx, &shipment, req.AllocationIDs, currentUserID(c))
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, "Order or shipment not found")
+		return
+	case errors.Is(err, errOrderNotProcessing), errors.Is(err, fulfillment.ErrShipmentNotPending),
+		errors.Is(err, fulfillment.ErrUnknownLine), errors.Is(err, fulfillment.ErrNotPicked):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+		return
+	default:
+		slog.ErrorContext(ctx, "failed to update shipment lines", "component", "order", "error", err)
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to update shipment")
+		return
+	}
+
+	if err := h.db.WithContext(ctx).Preload("Warehouse").Preload("Allocations").First(&shipment, shipment.ID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Shipment updated but failed to load it")
+		return
+	}
+	response.GenerateSuccessResponse(c, message, shipment)
+}
@@ -1,27 +1,54 @@
 package order
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"time"
 
+	addressService "github.com/YasserCherfaoui/MarketProGo/address"
+	"github.com/YasserCherfaoui/MarketProGo/company"
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/referral"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type PlaceOrderRequest struct {
-	ShippingAddressID uint    `json:"shipping_address_id" binding:"required"`
+	ShippingAddressID uint    `json:"shipping_address_id"` // defaults to the user's default shipping address
+	BillingAddressID  uint    `json:"billing_address_id"`  // defaults to the user's default billing address
 	PaymentMethod     string  `json:"payment_method" binding:"required"`
 	CustomerNotes     string  `json:"customer_notes"`
 	ShippingMethod    string  `json:"shipping_method"`
-	TaxAmount         float64 `json:"tax_amount"`
 	ShippingAmount    float64 `json:"shipping_amount"`
 	DiscountAmount    float64 `json:"discount_amount"`
+	DeliverySlotID    *uint   `json:"delivery_slot_id"` // required when the address's delivery zone only delivers in slots
+	RedeemPoints      int     `json:"redeem_points"`    // loyalty points to spend, capped at the share of the order points may pay for
+	CouponCode        string  `json:"coupon_code"`
+	GiftCardCode      string  `json:"gift_card_code"` // pays for the order, or part of it with the rest paid by payment_method
+
+	// How the card payment is captured, automatic or manual; B2B orders
+	// default to manual when configured
+	CaptureMode string `json:"capture_mode" binding:"omitempty,oneof=automatic manual"`
+
+	// Who the gift cards bought on the order are sent to, by variant; the
+	// buyer when not given
+	GiftCardRecipients []giftcard.Recipient `json:"gift_card_recipients" binding:"dive"`
 }
 
 func (h *OrderHandler) PlaceOrder(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, exists := c.Get("user_id")
 	if !exists {
 		response.GenerateUnauthorizedResponse(c, "order/place_order", "User not authenticated")
@@ -36,13 +63,48 @@ func (h *OrderHandler) PlaceOrder(c *gin.Context) {
 	}
 
 	// Start transaction
-	tx := h.db.Begin()
+	tx := h.db.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
 		}
 	}()
 
+	placed, ok := h.placeOrder(c, tx, uid, req, "order/place_order")
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/place_order", "Failed to commit transaction")
+		return
+	}
+	h.orderPlaced(ctx, placed)
+
+	if placed.needsApproval {
+		response.GenerateCreatedResponse(c, "Order placed and sent for approval", placed.order)
+		return
+	}
+	response.GenerateCreatedResponse(c, "Order placed successfully", placed.order)
+}
+
+// placedOrder is an order placed from a cart whose transaction has not
+// committed yet
+type placedOrder struct {
+	order           models.Order // with its user, addresses and items
+	needsApproval   bool
+	recoveryEmailID *uint // the cart's abandoned-cart reminder
+}
+
+// placeOrder places an order from the user's cart in tx: it prices the cart,
+// applies discounts, points and gift cards, creates the order and its items,
+// clears the cart and records the order's events and emails. On failure it
+// responds with code and the caller rolls tx back.
+func (h *OrderHandler) placeOrder(c *gin.Context, tx *gorm.DB, uid uint, req PlaceOrderRequest, code string) (*placedOrder, bool) {
+	ctx := c.Request.Context()
+
 	// Get user's cart with items
 	var cart models.Cart
 	if err := tx.Preload("Items.ProductVariant.Product").
@@ -51,71 +113,164 @@ func (h *OrderHandler) PlaceOrder(c *gin.Context) {
 		Preload("Items.Product"). // Legacy support
 		Where("user_id = ?", uid).
 		First(&cart).Error; err != nil {
-		tx.Rollback()
 		if err == gorm.ErrRecordNotFound {
-			response.GenerateNotFoundResponse(c, "order/place_order", "Cart not found")
+			response.GenerateNotFoundResponse(c, code, "Cart not found")
 		} else {
-			response.GenerateInternalServerErrorResponse(c, "order/place_order", "Failed to get cart")
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to get cart")
 		}
-		return
+		return nil, false
 	}
 
 	// Check if cart has items
 	if len(cart.Items) == 0 {
-		tx.Rollback()
-		response.GenerateBadRequestResponse(c, "order/place_order", "Cart is empty")
-		return
+		response.GenerateBadRequestResponse(c, code, "Cart is empty")
+		return nil, false
 	}
 
-	// Verify shipping address belongs to user
-	var address models.Address
-	if err := tx.Where("id = ? AND user_id = ?", req.ShippingAddressID, uid).
-		First(&address).Error; err != nil {
-		tx.Rollback()
-		if err == gorm.ErrRecordNotFound {
-			response.GenerateNotFoundResponse(c, "order/place_order", "Shipping address not found")
+	// Verify the addresses belong to the user, prefilling the user's defaults
+	address, err := orderAddress(tx, uid, req.ShippingAddressID, addressService.Shipping)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, code, "Shipping address not found")
 		} else {
-			response.GenerateInternalServerErrorResponse(c, "order/place_order", "Failed to verify shipping address")
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to verify shipping address")
 		}
-		return
+		return nil, false
+	}
+	// Bill the shipping address unless the user chose or saved a billing address
+	billingAddress := address
+	if req.BillingAddressID != 0 {
+		billingAddress, err = orderAddress(tx, uid, req.BillingAddressID, addressService.Billing)
+	} else if billing, flagErr := addressService.Flagged(tx, uid, addressService.Billing); flagErr == nil {
+		billingAddress = billing
+	} else if !errors.Is(flagErr, gorm.ErrRecordNotFound) {
+		err = flagErr
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, code, "Billing address not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to verify billing address")
+		}
+		return nil, false
 	}
 
-	// Calculate total amount
+	// Load the customer's price list once for the whole cart
+	priceList, err := h.priceResolver.ActivePriceList(uid)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to resolve prices")
+		return nil, false
+	}
+
+	// Calculate total amount, refreshing cart item prices so order items use current prices
 	var totalAmount float64
-	for _, item := range cart.Items {
+	taxLines := make([]tax.Line, 0, len(cart.Items))
+	for i := range cart.Items {
+		item := &cart.Items[i]
 		// Fetch latest variant with price tiers
 		var variant models.ProductVariant
-		h.db.Model(&models.ProductVariant{}).Preload("PriceTiers").First(&variant, item.ProductVariantID)
+		h.db.WithContext(ctx).Model(&models.ProductVariant{}).Preload("Product").Preload("PriceTiers").First(&variant, item.ProductVariantID)
 		if item.Quantity < variant.MinQuantity {
-			tx.Rollback()
-			response.GenerateBadRequestResponse(c, "order/place_order", "Minimum quantity for variant '"+variant.Name+"' is "+strconv.Itoa(variant.MinQuantity))
-			return
-		}
-		// Dynamic pricing: select price tier
-		unitPrice := variant.BasePrice
-		if len(variant.PriceTiers) > 0 {
-			tiers := variant.PriceTiers
-			for i := range tiers {
-				for j := i + 1; j < len(tiers); j++ {
-					if tiers[j].MinQuantity > tiers[i].MinQuantity {
-						tiers[i], tiers[j] = tiers[j], tiers[i]
-					}
-				}
-			}
-			for _, tier := range tiers {
-				if item.Quantity >= tier.MinQuantity {
-					unitPrice = tier.Price
-					break
-				}
-			}
+			response.GenerateBadRequestResponse(c, code, "Minimum quantity for variant '"+variant.Name+"' is "+strconv.Itoa(variant.MinQuantity))
+			return nil, false
 		}
+		// Dynamic pricing: price list, then price tiers, B2B and base price
+		unitPrice := pricing.UnitPriceWithList(priceList, &variant, item.Quantity, item.PriceType)
 		item.UnitPrice = unitPrice
 		item.TotalPrice = float64(item.Quantity) * item.UnitPrice
 		totalAmount += item.TotalPrice
+		taxLines = append(taxLines, tax.Line{
+			ProductVariantID: item.ProductVariantID,
+			IsVAT:            variant.Product.IsVAT,
+			Amount:           item.TotalPrice,
+		})
+	}
+
+	// Calculate VAT per line for the shipping country
+	taxBreakdown, err := h.taxService.Calculate(taxLines, address.Country)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to calculate tax")
+		return nil, false
+	}
+
+	// Charge the delivery slot's fee with shipping, or make sure the address
+	// can be delivered to without one
+	shippingAmount := req.ShippingAmount
+	if req.DeliverySlotID != nil {
+		var slot models.DeliverySlot
+		if err := tx.First(&slot, *req.DeliverySlotID).Error; err != nil {
+			deliverySlotError(c, code, err)
+			return nil, false
+		}
+		shippingAmount += slot.Fee
+	} else if err := delivery.RequireSlot(tx, address); err != nil {
+		deliverySlotError(c, code, err)
+		return nil, false
+	}
+
+	// Take the coupon, then the loyalty points the customer chose to spend
+	// off the goods
+	var coupon *models.Coupon
+	var couponDiscount float64
+	if req.CouponCode != "" {
+		var err error
+		coupon, couponDiscount, err = h.referrals.QuoteCoupon(tx, uid, req.CouponCode, taxBreakdown.GrossAmount-req.DiscountAmount)
+		if err != nil {
+			if errors.Is(err, referral.ErrInvalidCoupon) || errors.Is(err, referral.ErrCouponMinimum) {
+				response.GenerateBadRequestResponse(c, code, err.Error())
+			} else {
+				response.GenerateInternalServerErrorResponse(c, code, "Failed to apply coupon")
+			}
+			return nil, false
+		}
+	}
+	pointsRedeemed, pointsDiscount, err := h.loyalty.Quote(tx, uid, req.RedeemPoints, taxBreakdown.GrossAmount-req.DiscountAmount-couponDiscount)
+	if err != nil {
+		if errors.Is(err, loyalty.ErrInsufficientPoints) {
+			response.GenerateBadRequestResponse(c, code, err.Error())
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to redeem loyalty points")
+		}
+		return nil, false
 	}
 
 	// Calculate final amount
-	finalAmount := totalAmount + req.TaxAmount + req.ShippingAmount - req.DiscountAmount
+	finalAmount := taxBreakdown.GrossAmount + shippingAmount - req.DiscountAmount - couponDiscount - pointsDiscount
+
+	// Pay what a gift card covers of the final amount
+	var giftCard *models.GiftCard
+	var giftCardAmount float64
+	if req.GiftCardCode != "" {
+		giftCard, giftCardAmount, err = h.giftCards.Quote(tx, req.GiftCardCode, finalAmount)
+		if err != nil {
+			if errors.Is(err, giftcard.ErrInvalidCode) {
+				response.GenerateBadRequestResponse(c, code, err.Error())
+			} else {
+				response.GenerateInternalServerErrorResponse(c, code, "Failed to apply gift card")
+			}
+			return nil, false
+		}
+	}
+
+	// Hold company buyers to their spend limit, and send their orders above
+	// the company's threshold to an owner for approval
+	var buyer models.User
+	if err := tx.First(&buyer, uid).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to get user")
+		return nil, false
+	}
+	var needsApproval bool
+	if h.companies != nil {
+		needsApproval, err = h.companies.CheckOrder(tx, &buyer, finalAmount)
+		if err != nil {
+			if errors.Is(err, company.ErrSpendLimitExceeded) {
+				response.GenerateBadRequestResponse(c, code, err.Error())
+			} else {
+				response.GenerateInternalServerErrorResponse(c, code, "Failed to check company spend limit")
+			}
+			return nil, false
+		}
+	}
 
 	// Generate order number
 	orderNumber := generateOrderNumber()
@@ -124,35 +279,85 @@ func (h *OrderHandler) PlaceOrder(c *gin.Context) {
 	order := models.Order{
 		OrderNumber:       orderNumber,
 		UserID:            uid,
+		CompanyID:         buyer.CompanyID,
 		Status:            models.OrderStatusPending,
 		PaymentStatus:     models.PaymentStatusPending,
 		TotalAmount:       totalAmount,
-		TaxAmount:         req.TaxAmount,
-		ShippingAmount:    req.ShippingAmount,
-		DiscountAmount:    req.DiscountAmount,
+		TaxAmount:         taxBreakdown.TaxAmount,
+		TaxCountry:        taxBreakdown.Country,
+		TaxBreakdown:      taxBreakdown.ToJSON(),
+		ShippingAmount:    shippingAmount,
+		DiscountAmount:    req.DiscountAmount + couponDiscount + pointsDiscount,
 		FinalAmount:       finalAmount,
-		ShippingAddressID: req.ShippingAddressID,
+		CouponDiscount:    couponDiscount,
+		PointsRedeemed:    pointsRedeemed,
+		PointsDiscount:    pointsDiscount,
+		ShippingAddressID: address.ID,
+		BillingAddressID:  &billingAddress.ID,
 		ShippingMethod:    req.ShippingMethod,
 		PaymentMethod:     req.PaymentMethod,
+		CaptureMode:       req.CaptureMode,
 		CustomerNotes:     req.CustomerNotes,
 		OrderDate:         time.Now(),
 	}
+	if needsApproval {
+		order.Status = models.OrderStatusPendingApproval
+	}
+	if coupon != nil {
+		order.CouponID = &coupon.ID
+	}
+	if giftCard != nil {
+		order.GiftCardID, order.GiftCardAmount = &giftCard.ID, giftCardAmount
+	}
+	// An order the gift card pays for in full needs no other payment
+	paidByGiftCard := giftCard != nil && finalAmount-giftCardAmount < 0.005
+	if paidByGiftCard {
+		order.PaymentStatus, order.PaymentDate = models.PaymentStatusPaid, &order.OrderDate
+	}
 
 	if err := tx.Create(&order).Error; err != nil {
-		tx.Rollback()
-		response.GenerateInternalServerErrorResponse(c, "order/place_order", "Failed to create order")
-		return
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to create order")
+		return nil, false
+	}
+	if req.DeliverySlotID != nil {
+		if err := h.slots.Book(tx, &order, address, *req.DeliverySlotID); err != nil {
+			deliverySlotError(c, code, err)
+			return nil, false
+		}
+	}
+	if coupon != nil {
+		if err := h.referrals.UseCoupon(tx, coupon, &order); err != nil {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to apply coupon")
+			return nil, false
+		}
+	}
+	if err := h.loyalty.Redeem(tx, &order); err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to redeem loyalty points")
+		return nil, false
+	}
+	if err := h.referrals.RecordOrder(tx, &order); err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to record referral")
+		return nil, false
+	}
+	if giftCard != nil {
+		if err := h.giftCards.Redeem(tx, giftCard, &order); err != nil {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to apply gift card")
+			return nil, false
+		}
 	}
 
 	// Create order items from cart items
 	var orderItems []models.OrderItem
-	for _, cartItem := range cart.Items {
+	for i, cartItem := range cart.Items {
+		lineTax := taxBreakdown.Lines[i]
 		orderItem := models.OrderItem{
 			OrderID:          order.ID,
 			ProductVariantID: cartItem.ProductVariantID,
 			ProductID:        cartItem.ProductID, // Legacy support
 			Quantity:         cartItem.Quantity,
 			UnitPrice:        cartItem.UnitPrice,
+			TaxRate:          lineTax.Rate,
+			TaxAmount:        lineTax.TaxAmount,
 			TotalAmount:      cartItem.TotalPrice,
 			Status:           "active",
 		}
@@ -160,66 +365,114 @@ func (h *OrderHandler) PlaceOrder(c *gin.Context) {
 	}
 
 	if err := tx.Create(&orderItems).Error; err != nil {
-		tx.Rollback()
-		response.GenerateInternalServerErrorResponse(c, "order/place_order", "Failed to create order items")
-		return
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to create order items")
+		return nil, false
 	}
 
 	// Clear cart items
 	if err := tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
-		tx.Rollback()
-		response.GenerateInternalServerErrorResponse(c, "order/place_order", "Failed to clear cart")
-		return
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to clear cart")
+		return nil, false
 	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "order/place_order", "Failed to commit transaction")
-		return
+	if cart.RecoveryEmailID != nil {
+		if err := tx.Model(&cart).Update("recovery_email_id", nil).Error; err != nil {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to clear cart")
+			return nil, false
+		}
 	}
 
 	// Load the complete order with relationships for response
 	var completeOrder models.Order
-	if err := h.db.Preload("User").
+	if err := tx.Preload("User").
 		Preload("ShippingAddress").
+		Preload("BillingAddress").
+		Preload("DeliverySlot").
 		Preload("Items.ProductVariant.Product").
 		Preload("Items.ProductVariant.Product.Images").
 		Preload("Items.ProductVariant.OptionValues").
 		Preload("Items.Product"). // Legacy support
 		First(&completeOrder, order.ID).Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "order/place_order", "Order created but failed to load details")
-		return
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to load order details")
+		return nil, false
+	}
+
+	// Create the gift cards bought on the order, activated once it is paid
+	if err := h.giftCards.Purchase(tx, &completeOrder, &completeOrder.User, req.GiftCardRecipients); err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to create gift cards")
+		return nil, false
+	}
+
+	if err := webhook.Publish(tx, webhook.EventOrderCreated, webhook.OrderData(&completeOrder)); err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to record order events")
+		return nil, false
+	}
+	// Gift cards bought on an order waiting for approval are activated once
+	// it is approved
+	if paidByGiftCard && !needsApproval {
+		if err := h.giftCards.Activate(tx, &completeOrder); err != nil {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to activate gift cards")
+			return nil, false
+		}
+		if err := webhook.Publish(tx, webhook.EventOrderPaid, webhook.OrderData(&completeOrder)); err != nil {
+			response.GenerateInternalServerErrorResponse(c, code, "Failed to record order events")
+			return nil, false
+		}
 	}
 
-	// Send order confirmation email asynchronously
-	go func() {
+	// Record the confirmation emails in the same transaction, so they are sent
+	// exactly when the order is committed
+	if h.emailTriggerSvc != nil {
 		// Prepare order data for email
 		orderData := map[string]interface{}{
 			"order_number":     completeOrder.OrderNumber,
 			"order_date":       completeOrder.OrderDate,
 			"total_amount":     completeOrder.FinalAmount,
-			"currency":         "GBP",
+			"tax_amount":       completeOrder.TaxAmount,
+			"tax_breakdown":    completeOrder.TaxBreakdown,
+			"currency":         settings.Current().Currency,
 			"items":            completeOrder.Items,
 			"shipping_address": completeOrder.ShippingAddress,
 		}
+		if completeOrder.DeliverySlot != nil {
+			orderData["delivery_slot"] = h.slots.Describe(completeOrder.DeliverySlot)
+		}
 
 		// Send order confirmation to customer
-		if err := h.emailTriggerSvc.TriggerOrderConfirmation(
+		emails := h.emailTriggerSvc.InTx(tx)
+		if err := emails.TriggerOrderConfirmation(
 			completeOrder.ID,
 			completeOrder.User.Email,
 			fmt.Sprintf("%s %s", completeOrder.User.FirstName, completeOrder.User.LastName),
 			orderData,
 		); err != nil {
-			fmt.Printf("Failed to send order confirmation email: %v\n", err)
+			slog.ErrorContext(ctx, "failed to send order confirmation email", "component", "order", "error", err)
 		}
 
 		// Send admin notification
-		if err := h.emailTriggerSvc.TriggerNewOrderAdminNotification(completeOrder.ID, orderData); err != nil {
-			fmt.Printf("Failed to send admin notification: %v\n", err)
+		if err := emails.TriggerNewOrderAdminNotification(completeOrder.ID, orderData); err != nil {
+			slog.ErrorContext(ctx, "failed to send admin notification", "component", "order", "error", err)
 		}
-	}()
+	}
+
+	if needsApproval {
+		if err := h.companies.RequestApproval(tx, &completeOrder, &buyer); err != nil {
+			slog.ErrorContext(ctx, "failed to request order approval", "component", "order", "order_id", completeOrder.ID, "error", err)
+		}
+	}
 
-	response.GenerateCreatedResponse(c, "Order placed successfully", completeOrder)
+	return &placedOrder{order: completeOrder, needsApproval: needsApproval, recoveryEmailID: cart.RecoveryEmailID}, true
+}
+
+// orderPlaced runs once a placed order is committed
+func (h *OrderHandler) orderPlaced(ctx context.Context, placed *placedOrder) {
+	h.textOrderUpdate(ctx, placed.order.ID, sms.OrderConfirmed)
+
+	// The cart has been checked out, so its abandoned-cart reminder is no longer needed
+	if placed.recoveryEmailID != nil && h.emailTriggerSvc != nil {
+		if err := h.emailTriggerSvc.CancelScheduledEmail(*placed.recoveryEmailID); err != nil {
+			slog.ErrorContext(ctx, "failed to cancel cart recovery email", "component", "order", "error", err)
+		}
+	}
 }
 
 func generateOrderNumber() string {
@@ -229,3 +482,30 @@ func generateOrderNumber() string {
 		now.Year(), now.Month(), now.Day(),
 		now.Unix()%10000) // Last 4 digits of timestamp for uniqueness
 }
+
+// deliverySlotError responds to a delivery slot that cannot be booked
+func deliverySlotError(c *gin.Context, code string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, "Delivery slot not found")
+	case errors.Is(err, delivery.ErrSlotRequired), errors.Is(err, delivery.ErrSlotFull),
+		errors.Is(err, delivery.ErrSlotClosed), errors.Is(err, delivery.ErrSlotCutoff),
+		errors.Is(err, delivery.ErrWrongZone), errors.Is(err, delivery.ErrNoZone):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to book delivery slot")
+	}
+}
+
+// orderAddress returns the user's address with the given ID, or their default
+// address of the kind when no ID is given
+func orderAddress(tx *gorm.DB, userID, addressID uint, kind addressService.Kind) (*models.Address, error) {
+	if addressID == 0 {
+		return addressService.Default(tx, userID, kind)
+	}
+	var address models.Address
+	if err := tx.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
@@ -0,0 +1,152 @@
+package order
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ShipShipmentRequest struct {
+	TrackingNumber string `json:"tracking_number"`
+}
+
+// GetOrderShipments - Admin endpoint to list the shipments of an order and
+// the stock allocated to them
+func (h *OrderHandler) GetOrderShipments(c *gin.Context) {
+	var order models.Order
+	if err := h.db.WithContext(c.Request.Context()).Select("id").First(&order, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateNotFoundResponse(c, "order/get_shipments", "Order not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "order/get_shipments", "Failed to get order")
+		}
+		return
+	}
+
+	var shipments []models.Shipment
+	if err := h.db.WithContext(c.Request.Context()).
+		Preload("Warehouse").
+		Preload("Allocations.InventoryItem").
+		Where("order_id = ?", order.ID).
+		Order("id").
+		Find(&shipments).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_shipments", "Failed to get shipments")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Shipments retrieved successfully", shipments)
+}
+
+// ShipShipment - Admin endpoint to ship one shipment of a split order. The
+// order is marked shipped once all of its shipments have shipped.
+func (h *OrderHandler) ShipShipment(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req ShipShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateBadRequestResponse(c, "order/ship_shipment", err.Error())
+		return
+	}
+
+	var order models.Order
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&order, c.Param("id")).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusProcessing {
+			return errOrderNotProcessing
+		}
+
+		var shipment models.Shipment
+		if err := tx.Where("id = ? AND order_id = ?", c.Param("shipmentId"), order.ID).First(&shipment).Error; err != nil {
+			return err
+		}
+		if err := h.allocator.ShipShipment(tx, &shipment, req.TrackingNumber, currentUserID(c)); err != nil {
+			return err
+		}
+
+		shipped, err := fulfillment.AllShipped(tx, order.ID)
+		if err != nil || !shipped {
+			return err
+		}
+		now := time.Now()
+		order.Status = models.OrderStatusShipped
+		order.ShippedDate = &now
+		if order.TrackingNumber == "" {
+			order.TrackingNumber = shipment.TrackingNumber
+		}
+		return tx.Save(&order).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "order/ship_shipment", "Order or shipment not found")
+		return
+	case errors.Is(err, errOrderNotProcessing), errors.Is(err, fulfillment.ErrShipmentNotPending), errors.Is(err, stock.ErrNotEnoughStock):
+		response.GenerateBadRequestResponse(c, "order/ship_shipment", err.Error())
+		return
+	default:
+		slog.ErrorContext(ctx, "failed to ship shipment", "component", "order", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "order/ship_shipment", "Failed to ship shipment")
+		return
+	}
+	if order.Status == models.OrderStatusShipped {
+		h.textOrderUpdate(ctx, order.ID, sms.OrderShipped)
+	}
+
+	var shipments []models.Shipment
+	if err := h.db.WithContext(ctx).Preload("Warehouse").Preload("Allocations").
+		Where("order_id = ?", order.ID).Order("id").Find(&shipments).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/ship_shipment", "Shipment shipped but failed to load shipments")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Shipment shipped successfully", gin.H{
+		"order_status": order.Status,
+		"shipments":    shipments,
+	})
+}
+
+var errOrderNotProcessing = errors.New("only processing orders can be picked, packed or shipped")
+
+// updateFulfillment moves an order's stock with its new status: stock is
+// allocated when the order is confirmed, shipped with the order and released
+// when the order is cancelled
+func (h *OrderHandler) updateFulfillment(tx *gorm.DB, order *models.Order, trackingNumber string, userID *uint) error {
+	if h.allocator == nil {
+		return nil
+	}
+	switch order.Status {
+	case models.OrderStatusProcessing:
+		_, err := h.allocator.Allocate(tx, order, userID)
+		return err
+	case models.OrderStatusShipped:
+		return h.allocator.Fulfill(tx, order, trackingNumber, userID)
+	case models.OrderStatusCancelled:
+		return h.allocator.Release(tx, order, userID)
+	}
+	return nil
+}
+
+// isStockError reports whether err means the order cannot be allocated or
+// shipped from the stock on hand
+func isStockError(err error) bool {
+	var insufficient *fulfillment.InsufficientStockError
+	return errors.As(err, &insufficient) || errors.Is(err, fulfillment.ErrSplitRequired) || errors.Is(err, stock.ErrNotEnoughStock)
+}
+
+// currentUserID returns the ID of the authenticated user, if any
+func currentUserID(c *gin.Context) *uint {
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uint); ok {
+			return &id
+		}
+	}
+	return nil
+}
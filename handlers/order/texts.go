@@ -0,0 +1,35 @@
+package order
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+)
+
+// WithTexts sets the service order updates are texted with, to customers who
+// get them by SMS or WhatsApp
+func (h *OrderHandler) WithTexts(texts *sms.Service) *OrderHandler {
+	h.texts = texts
+	return h
+}
+
+// textOrderUpdate texts the customer of an order about event in the
+// background, when they get order updates by text message
+func (h *OrderHandler) textOrderUpdate(ctx context.Context, orderID uint, event string) {
+	if h.texts == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		var order models.Order
+		if err := h.db.WithContext(ctx).Preload("User").First(&order, orderID).Error; err != nil {
+			slog.ErrorContext(ctx, "failed to load order to text", "component", "order", "order_id", orderID, "error", err)
+			return
+		}
+		if _, err := h.texts.NotifyOrder(ctx, &order, event); err != nil {
+			slog.ErrorContext(ctx, "failed to text order update", "component", "order", "order_id", orderID, "event", event, "error", err)
+		}
+	}()
+}
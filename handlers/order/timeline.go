@@ -0,0 +1,243 @@
+package order
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/orderhistory"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of order timeline events
+const (
+	TimelinePlaced       = "placed"
+	TimelineStatusChange = "status_change"
+	TimelinePayment      = "payment"
+	TimelineEmail        = "email"
+	TimelineShipment     = "shipment"
+	TimelineNote         = "note"
+)
+
+// TimelineEvent is something that happened to an order
+type TimelineEvent struct {
+	Type    string      `json:"type"`
+	At      time.Time   `json:"at"`
+	Summary string      `json:"summary"`
+	ActorID *uint       `json:"actor_id,omitempty"`
+	Data    models.JSON `json:"data,omitempty"`
+}
+
+// OrderTimeline is everything that happened to an order, oldest first
+type OrderTimeline struct {
+	OrderID     uint               `json:"order_id"`
+	OrderNumber string             `json:"order_number"`
+	Status      models.OrderStatus `json:"status"`
+	Events      []TimelineEvent    `json:"events"`
+}
+
+// GetOrderTimeline - Admin endpoint to see everything that happened to an
+// order: its status changes, payments, emails, shipments and internal notes
+func (h *OrderHandler) GetOrderTimeline(c *gin.Context) {
+	order, ok := h.adminOrder(c, "order/get_timeline")
+	if !ok {
+		return
+	}
+
+	events := []TimelineEvent{{
+		Type:    TimelinePlaced,
+		At:      order.OrderDate,
+		Summary: fmt.Sprintf("Order %s placed", order.OrderNumber),
+		ActorID: &order.UserID,
+		Data:    models.JSON{"total_amount": order.FinalAmount},
+	}}
+
+	changes, err := orderhistory.History(h.db, order.ID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_timeline", "Failed to get order status history")
+		return
+	}
+	for _, change := range changes {
+		summary := fmt.Sprintf("Status changed from %s to %s", change.FromStatus, change.ToStatus)
+		if change.Forced {
+			summary = fmt.Sprintf("Status forced from %s to %s", change.FromStatus, change.ToStatus)
+		}
+		events = append(events, TimelineEvent{
+			Type:    TimelineStatusChange,
+			At:      change.CreatedAt,
+			Summary: summary,
+			ActorID: change.ActorID,
+			Data: models.JSON{
+				"from_status": change.FromStatus,
+				"to_status":   change.ToStatus,
+				"reason":      change.Reason,
+				"forced":      change.Forced,
+			},
+		})
+	}
+
+	var payments []models.Payment
+	if err := h.db.Where("order_id = ?", order.ID).Find(&payments).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_timeline", "Failed to get order payments")
+		return
+	}
+	for _, payment := range payments {
+		events = append(events, paymentEvents(payment)...)
+	}
+
+	var emails []models.Email
+	if err := h.db.Select("id", "type", "template", "subject", "status", "created_at", "sent_at", "last_error").
+		Where("order_id = ?", order.ID).
+		Find(&emails).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_timeline", "Failed to get order emails")
+		return
+	}
+	for _, email := range emails {
+		at := email.CreatedAt
+		if email.SentAt != nil {
+			at = *email.SentAt
+		}
+		events = append(events, TimelineEvent{
+			Type:    TimelineEmail,
+			At:      at,
+			Summary: fmt.Sprintf("Email %q %s", email.Subject, email.Status),
+			Data: models.JSON{
+				"email_id":   email.ID,
+				"type":       email.Type,
+				"template":   email.Template,
+				"status":     email.Status,
+				"last_error": email.LastError,
+			},
+		})
+	}
+
+	var shipments []models.Shipment
+	if err := h.db.Preload("Warehouse").Where("order_id = ?", order.ID).Find(&shipments).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_timeline", "Failed to get order shipments")
+		return
+	}
+	for _, shipment := range shipments {
+		events = append(events, shipmentEvents(shipment)...)
+	}
+
+	var notes []models.OrderNote
+	if err := h.db.Where("order_id = ?", order.ID).Find(&notes).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/get_timeline", "Failed to get order notes")
+		return
+	}
+	for _, note := range notes {
+		authorID := note.AuthorID
+		events = append(events, TimelineEvent{
+			Type:    TimelineNote,
+			At:      note.CreatedAt,
+			Summary: note.Body,
+			ActorID: &authorID,
+			Data:    models.JSON{"note_id": note.ID},
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	response.GenerateSuccessResponse(c, "Order timeline retrieved successfully", OrderTimeline{
+		OrderID:     order.ID,
+		OrderNumber: order.OrderNumber,
+		Status:      order.Status,
+		Events:      events,
+	})
+}
+
+// paymentEvents lists the steps a payment went through
+func paymentEvents(payment models.Payment) []TimelineEvent {
+	data := func() models.JSON {
+		return models.JSON{
+			"payment_id": payment.ID,
+			"amount":     payment.Amount,
+			"currency":   payment.Currency,
+			"status":     payment.Status,
+		}
+	}
+	events := []TimelineEvent{{
+		Type:    TimelinePayment,
+		At:      payment.CreatedAt,
+		Summary: fmt.Sprintf("Payment of %.2f %s started", payment.Amount, payment.Currency),
+		Data:    data(),
+	}}
+	if payment.AuthorizedAt != nil {
+		events = append(events, TimelineEvent{
+			Type:    TimelinePayment,
+			At:      *payment.AuthorizedAt,
+			Summary: "Payment authorized",
+			Data:    data(),
+		})
+	}
+	if payment.CompletedAt != nil {
+		events = append(events, TimelineEvent{
+			Type:    TimelinePayment,
+			At:      *payment.CompletedAt,
+			Summary: "Payment completed",
+			Data:    data(),
+		})
+	}
+	switch {
+	case payment.Status == models.RevolutPaymentStatusFailed:
+		failed := data()
+		failed["failure_reason"] = payment.FailureReason
+		events = append(events, TimelineEvent{
+			Type:    TimelinePayment,
+			At:      payment.UpdatedAt,
+			Summary: "Payment failed",
+			Data:    failed,
+		})
+	case payment.Status == models.RevolutPaymentStatusCancelled:
+		events = append(events, TimelineEvent{
+			Type:    TimelinePayment,
+			At:      payment.UpdatedAt,
+			Summary: "Payment cancelled",
+			Data:    data(),
+		})
+	case payment.RefundedAmount > 0:
+		refunded := data()
+		refunded["refunded_amount"] = payment.RefundedAmount
+		events = append(events, TimelineEvent{
+			Type:    TimelinePayment,
+			At:      payment.UpdatedAt,
+			Summary: fmt.Sprintf("%.2f %s refunded", payment.RefundedAmount, payment.Currency),
+			Data:    refunded,
+		})
+	}
+	return events
+}
+
+// shipmentEvents lists the fulfillment steps a shipment went through
+func shipmentEvents(shipment models.Shipment) []TimelineEvent {
+	warehouse := fmt.Sprintf("warehouse %d", shipment.WarehouseID)
+	if shipment.Warehouse != nil {
+		warehouse = shipment.Warehouse.Name
+	}
+	steps := []struct {
+		at   *time.Time
+		verb string
+	}{
+		{shipment.PickedAt, "picked"},
+		{shipment.PackedAt, "packed"},
+		{shipment.ShippedAt, "shipped"},
+	}
+	var events []TimelineEvent
+	for _, step := range steps {
+		if step.at == nil {
+			continue
+		}
+		data := models.JSON{"shipment_id": shipment.ID, "warehouse_id": shipment.WarehouseID}
+		if step.verb == "shipped" && shipment.TrackingNumber != "" {
+			data["tracking_number"] = shipment.TrackingNumber
+		}
+		events = append(events, TimelineEvent{
+			Type:    TimelineShipment,
+			At:      *step.at,
+			Summary: fmt.Sprintf("Shipment from %s %s", warehouse, step.verb),
+			Data:    data,
+		})
+	}
+	return events
+}
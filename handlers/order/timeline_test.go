@@ -0,0 +1,126 @@
+package order
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/referral"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestForcedStatusNotesAndTimeline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Company{}, &models.Address{}, &models.Order{}, &models.OrderItem{}, &models.Product{},
+		&models.ProductVariant{}, &models.ProductImage{}, &models.ProductOptionValue{}, &models.InventoryItem{}, &models.Shipment{}, &models.StockAllocation{},
+		&models.OrderStatusChange{}, &models.OrderNote{}, &models.Payment{}, &models.Email{}, &models.Referral{},
+		&models.LoyaltyTransaction{}, &models.Notification{}))
+
+	customer := models.User{Email: "customer@example.com", Password: "x", FirstName: "Amina", UserType: models.Customer, IsActive: true}
+	admin := models.User{Email: "admin@example.com", Password: "x", FirstName: "Karim", UserType: models.Admin, IsActive: true}
+	require.NoError(t, db.Create(&[]*models.User{&customer, &admin}).Error)
+	placed := time.Now().Add(-time.Hour)
+	order := models.Order{OrderNumber: "ORD-1", UserID: customer.ID, Status: models.OrderStatusPending,
+		PaymentStatus: models.PaymentStatusPaid, OrderDate: placed}
+	require.NoError(t, db.Create(&order).Error)
+
+	points := loyalty.NewService(db, &cfg.LoyaltyConfig{})
+	handler := NewOrderHandler(db, nil, nil, nil, nil, points, referral.NewService(db, &cfg.ReferralConfig{}, points), nil, nil)
+	router := gin.New()
+	adminRoutes := router.Group("/admin/orders", func(c *gin.Context) { c.Set("user_id", admin.ID) })
+	adminRoutes.PUT("/:id/status", handler.UpdateOrderStatus)
+	adminRoutes.PUT("/:id/status/force", handler.ForceOrderStatus)
+	adminRoutes.GET("/:id/notes", handler.GetOrderNotes)
+	adminRoutes.POST("/:id/notes", handler.AddOrderNote)
+	adminRoutes.GET("/:id/timeline", handler.GetOrderTimeline)
+
+	call := func(method, path string, body interface{}, dest interface{}) int {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if dest != nil {
+			var envelope struct {
+				Data json.RawMessage `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope), w.Body.String())
+			require.NoError(t, json.Unmarshal(envelope.Data, dest), w.Body.String())
+		}
+		return w.Code
+	}
+
+	// The state machine only moves one step at a time; forcing needs a reason
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/admin/orders/1/status", UpdateOrderStatusRequest{Status: models.OrderStatusDelivered}, nil))
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/admin/orders/1/status/force", map[string]string{"status": "DELIVERED"}, nil))
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/admin/orders/1/status/force", ForceOrderStatusRequest{Status: models.OrderStatusPending, Reason: "No-op"}, nil))
+	assert.Equal(t, http.StatusNotFound, call(http.MethodPut, "/admin/orders/9/status/force", ForceOrderStatusRequest{Status: models.OrderStatusDelivered, Reason: "Lost scan"}, nil))
+
+	var forced models.Order
+	require.Equal(t, http.StatusOK, call(http.MethodPut, "/admin/orders/1/status/force",
+		ForceOrderStatusRequest{Status: models.OrderStatusDelivered, Reason: "Courier lost the scans", TrackingNumber: "TRK-1"}, &forced))
+	assert.Equal(t, models.OrderStatusDelivered, forced.Status)
+	assert.Equal(t, "TRK-1", forced.TrackingNumber)
+	assert.NotNil(t, forced.ShippedDate)
+	assert.NotNil(t, forced.DeliveredDate)
+
+	var changes []models.OrderStatusChange
+	require.NoError(t, db.Order("id").Find(&changes).Error)
+	require.Len(t, changes, 3, "the order goes through every status on the way")
+	assert.Equal(t, models.OrderStatusProcessing, changes[0].ToStatus)
+	assert.Equal(t, models.OrderStatusShipped, changes[1].ToStatus)
+	assert.Equal(t, models.OrderStatusDelivered, changes[2].ToStatus)
+	for _, change := range changes {
+		assert.True(t, change.Forced)
+		assert.Equal(t, "Courier lost the scans", change.Reason)
+		require.NotNil(t, change.ActorID)
+		assert.Equal(t, admin.ID, *change.ActorID)
+	}
+
+	// Orders never move backwards
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/admin/orders/1/status/force", ForceOrderStatusRequest{Status: models.OrderStatusPending, Reason: "Undo"}, nil))
+
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/admin/orders/1/notes", OrderNoteRequest{}, nil))
+	var note models.OrderNote
+	require.Equal(t, http.StatusCreated, call(http.MethodPost, "/admin/orders/1/notes", OrderNoteRequest{Body: "Customer confirmed delivery by phone"}, &note))
+	require.NotNil(t, note.Author)
+	assert.Equal(t, "Karim", note.Author.FirstName)
+	var notes []models.OrderNote
+	call(http.MethodGet, "/admin/orders/1/notes", nil, &notes)
+	assert.Len(t, notes, 1)
+
+	completed := placed.Add(time.Minute)
+	require.NoError(t, db.Create(&models.Payment{OrderID: order.ID, RevolutOrderID: "rev-1", RevolutPaymentID: "pay-1", Amount: 20, Currency: "GBP",
+		Status: models.RevolutPaymentStatusCompleted, CompletedAt: &completed}).Error)
+	require.NoError(t, db.Create(&models.Email{Type: models.EmailTypeOrderConfirmation, Subject: "Your order", Status: models.EmailStatusSent, OrderID: &order.ID}).Error)
+	require.NoError(t, db.Create(&models.Email{Type: models.EmailTypeWelcome, Subject: "Welcome", Status: models.EmailStatusSent}).Error)
+
+	var timeline OrderTimeline
+	require.Equal(t, http.StatusOK, call(http.MethodGet, "/admin/orders/1/timeline", nil, &timeline))
+	assert.Equal(t, models.OrderStatusDelivered, timeline.Status)
+	counts := map[string]int{}
+	for i, event := range timeline.Events {
+		counts[event.Type]++
+		if i > 0 {
+			assert.False(t, event.At.Before(timeline.Events[i-1].At), "events are oldest first")
+		}
+	}
+	assert.Equal(t, TimelinePlaced, timeline.Events[0].Type)
+	assert.Equal(t, map[string]int{TimelinePlaced: 1, TimelineStatusChange: 3, TimelinePayment: 2, TimelineEmail: 1, TimelineNote: 1}, counts)
+}
@@ -1,10 +1,18 @@
 package order
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/orderhistory"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -17,6 +25,7 @@ type UpdateOrderStatusRequest struct {
 
 // UpdateOrderStatus - Admin endpoint to update order status
 func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	ctx := c.Request.Context()
 	orderID := c.Param("id")
 	if orderID == "" {
 		response.GenerateBadRequestResponse(c, "order/update_status", "Order ID is required")
@@ -29,31 +38,13 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	// Validate status
-	validStatuses := []models.OrderStatus{
-		models.OrderStatusPending,
-		models.OrderStatusProcessing,
-		models.OrderStatusShipped,
-		models.OrderStatusDelivered,
-		models.OrderStatusCancelled,
-		models.OrderStatusReturned,
-	}
-
-	isValidStatus := false
-	for _, status := range validStatuses {
-		if status == req.Status {
-			isValidStatus = true
-			break
-		}
-	}
-
-	if !isValidStatus {
+	if !isKnownStatus(req.Status) {
 		response.GenerateBadRequestResponse(c, "order/update_status", "Invalid order status")
 		return
 	}
 
 	// Start transaction
-	tx := h.db.Begin()
+	tx := h.db.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -79,19 +70,52 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
+	previousStatus := order.Status
+	order.AdminNotes = req.AdminNotes
+	newlyDelivered, err := h.changeStatus(tx, &order, req.Status, req.TrackingNumber, orderhistory.Change{ActorID: currentUserID(c), Reason: req.AdminNotes})
+	if err != nil {
+		tx.Rollback()
+		statusChangeError(c, "order/update_status", err)
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "order/update_status", "Failed to commit transaction")
+		return
+	}
+
+	completeOrder, ok := h.afterStatusChange(c, "order/update_status", order.ID, previousStatus, newlyDelivered)
+	if !ok {
+		return
+	}
+	response.GenerateSuccessResponse(c, "Order status updated successfully", completeOrder)
+}
+
+// changeStatus moves an order to status in tx, running what the move
+// entails: allocating, shipping or releasing its stock, releasing its delivery
+// slot and coupon, and crediting or reversing loyalty points, gift cards and
+// referrals. The move is recorded in the order's status history. It reports
+// whether the order was delivered for the first time.
+func (h *OrderHandler) changeStatus(tx *gorm.DB, order *models.Order, status models.OrderStatus, trackingNumber string, change orderhistory.Change) (bool, error) {
 	// Update order
 	now := time.Now()
-	order.Status = req.Status
-	order.AdminNotes = req.AdminNotes
+	newlyDelivered := status == models.OrderStatusDelivered && order.DeliveredDate == nil
+	previousStatus := order.Status
+	// An order paid in full by gift card while it waited for approval is
+	// only treated as paid once it is approved
+	newlyPaid := previousStatus == models.OrderStatusPendingApproval && status == models.OrderStatusPending &&
+		order.PaymentStatus == models.PaymentStatusPaid
+	order.Status = status
 
 	// Set specific date fields based on status
-	switch req.Status {
+	switch status {
 	case models.OrderStatusShipped:
 		if order.ShippedDate == nil {
 			order.ShippedDate = &now
 		}
-		if req.TrackingNumber != "" {
-			order.TrackingNumber = req.TrackingNumber
+		if trackingNumber != "" {
+			order.TrackingNumber = trackingNumber
 		}
 	case models.OrderStatusDelivered:
 		if order.DeliveredDate == nil {
@@ -101,78 +125,221 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		if order.PaymentStatus == models.PaymentStatusPending {
 			order.PaymentStatus = models.PaymentStatusPaid
 			order.PaymentDate = &now
+			newlyPaid = true
 		}
 	}
 
-	if err := tx.Save(&order).Error; err != nil {
-		tx.Rollback()
-		response.GenerateInternalServerErrorResponse(c, "order/update_status", "Failed to update order status")
-		return
+	if err := tx.Save(order).Error; err != nil {
+		return false, &statusError{"Failed to update order status", err}
+	}
+	if err := orderhistory.Record(tx, order.ID, previousStatus, status, change); err != nil {
+		return false, &statusError{"Failed to update order status", err}
+	}
+
+	// Allocate stock when the order is confirmed, ship it with the order and
+	// give it back when the order is cancelled
+	if status != previousStatus {
+		if err := h.updateFulfillment(tx, order, trackingNumber, change.ActorID); err != nil {
+			if isStockError(err) {
+				return false, err
+			}
+			return false, &statusError{"Failed to update order fulfillment", err}
+		}
 	}
 
 	// Update order items status if order is cancelled or returned
-	if req.Status == models.OrderStatusCancelled || req.Status == models.OrderStatusReturned {
+	if status == models.OrderStatusCancelled || status == models.OrderStatusReturned {
 		itemStatus := "cancelled"
-		if req.Status == models.OrderStatusReturned {
+		if status == models.OrderStatusReturned {
 			itemStatus = "returned"
 		}
 
 		if err := tx.Model(&models.OrderItem{}).
 			Where("order_id = ?", order.ID).
 			Update("status", itemStatus).Error; err != nil {
-			tx.Rollback()
-			response.GenerateInternalServerErrorResponse(c, "order/update_status", "Failed to update order items")
-			return
+			return false, &statusError{"Failed to update order items", err}
+		}
+	}
+	if status == models.OrderStatusCancelled {
+		if err := delivery.Release(tx, order); err != nil {
+			return false, &statusError{"Failed to release delivery slot", err}
+		}
+		if err := h.referrals.ReleaseCoupon(tx, order); err != nil {
+			return false, &statusError{"Failed to release coupon", err}
 		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "order/update_status", "Failed to commit transaction")
-		return
+	// Credit loyalty points once the order is delivered, and undo them when
+	// it is cancelled or returned
+	var pointsErr error
+	switch {
+	case newlyDelivered:
+		pointsErr = h.loyalty.Earn(tx, order)
+	case status == models.OrderStatusCancelled || status == models.OrderStatusReturned:
+		pointsErr = h.loyalty.Reverse(tx, order)
+	}
+	if pointsErr != nil {
+		return false, &statusError{"Failed to update loyalty points", pointsErr}
+	}
+	if status == models.OrderStatusCancelled || status == models.OrderStatusReturned {
+		if err := h.giftCards.Reverse(tx, order); err != nil {
+			return false, &statusError{"Failed to reverse gift cards", err}
+		}
 	}
 
+	// A referred customer's delivered order may qualify their referral
+	if newlyDelivered {
+		if err := h.referrals.Qualify(tx, order); err != nil {
+			return false, &statusError{"Failed to reward referral", err}
+		}
+	}
+
+	if newlyPaid {
+		if err := h.giftCards.Activate(tx, order); err != nil {
+			return false, &statusError{"Failed to activate gift cards", err}
+		}
+		if err := webhook.Publish(tx, webhook.EventOrderPaid, webhook.OrderData(order)); err != nil {
+			return false, &statusError{"Failed to record payment events", err}
+		}
+	}
+	return newlyDelivered, nil
+}
+
+// statusError is a failed step of a status change, with the message to
+// respond with
+type statusError struct {
+	message string
+	err     error
+}
+
+func (e *statusError) Error() string { return e.message + ": " + e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+// statusChangeError responds to a status change that failed
+func statusChangeError(c *gin.Context, code string, err error) {
+	var failed *statusError
+	switch {
+	case isStockError(err):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	case errors.As(err, &failed):
+		slog.ErrorContext(c.Request.Context(), "failed to change order status", "component", "order", "error", err)
+		response.GenerateInternalServerErrorResponse(c, code, failed.message)
+	default:
+		slog.ErrorContext(c.Request.Context(), "failed to change order status", "component", "order", "error", err)
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to update order status")
+	}
+}
+
+// afterStatusChange loads an order whose status change was committed, lets
+// the customer know and, once it is delivered, schedules the review request.
+// It responds with an error itself when the order cannot be loaded.
+func (h *OrderHandler) afterStatusChange(c *gin.Context, code string, orderID uint, previousStatus models.OrderStatus, newlyDelivered bool) (*models.Order, bool) {
+	ctx := c.Request.Context()
+
 	// Load the complete order with relationships for response
 	var completeOrder models.Order
-	if err := h.db.
+	if err := h.db.WithContext(ctx).
 		Preload("User").
 		Preload("ShippingAddress").
 		Preload("Items.ProductVariant.Product").
 		Preload("Items.ProductVariant.Product.Images").
 		Preload("Items.ProductVariant.OptionValues").
 		Preload("Items.Product"). // Legacy support
-		First(&completeOrder, order.ID).Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "order/update_status", "Order updated but failed to load details")
-		return
+		Preload("Shipments.Allocations").
+		First(&completeOrder, orderID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Order updated but failed to load details")
+		return nil, false
 	}
 
-	response.GenerateSuccessResponse(c, "Order status updated successfully", completeOrder)
+	// Let the customer know in their notification center
+	if completeOrder.Status != previousStatus {
+		if _, err := h.notifier.Notify(completeOrder.UserID, notification.Message{
+			Type:  models.NotificationTypeOrderUpdate,
+			Title: fmt.Sprintf("Order %s is %s", completeOrder.OrderNumber, completeOrder.Status),
+			Body:  fmt.Sprintf("Your order %s has been updated to %s.", completeOrder.OrderNumber, completeOrder.Status),
+			Link:  fmt.Sprintf("/orders/%d", completeOrder.ID),
+			Data:  models.JSON{"order_id": completeOrder.ID, "status": completeOrder.Status},
+		}); err != nil {
+			slog.ErrorContext(ctx, "failed to create order update notification", "component", "order", "error", err)
+		}
+		if completeOrder.Status == models.OrderStatusShipped {
+			h.textOrderUpdate(ctx, completeOrder.ID, sms.OrderShipped)
+		}
+	}
+
+	// Ask the customer for a review once the order has had time to arrive and be used
+	if newlyDelivered && h.emailTriggerSvc != nil && completeOrder.User.Email != "" {
+		emails := h.emailTriggerSvc.WithContext(ctx)
+		go func() {
+			items := make([]string, 0, len(completeOrder.Items))
+			for _, item := range completeOrder.Items {
+				if item.ProductVariant.Product.Name != "" {
+					items = append(items, item.ProductVariant.Product.Name)
+				}
+			}
+			orderData := map[string]interface{}{
+				"order_number": completeOrder.OrderNumber,
+				"items":        items,
+			}
+			if _, err := emails.ScheduleReviewRequest(
+				completeOrder.ID,
+				completeOrder.User.Email,
+				fmt.Sprintf("%s %s", completeOrder.User.FirstName, completeOrder.User.LastName),
+				orderData,
+			); err != nil {
+				slog.ErrorContext(ctx, "failed to schedule review request email", "component", "order", "error", err)
+			}
+		}()
+	}
+	return &completeOrder, true
+}
+
+// isKnownStatus reports whether admins can move orders to status
+func isKnownStatus(status models.OrderStatus) bool {
+	for _, known := range []models.OrderStatus{
+		models.OrderStatusPending,
+		models.OrderStatusProcessing,
+		models.OrderStatusShipped,
+		models.OrderStatusDelivered,
+		models.OrderStatusCancelled,
+		models.OrderStatusReturned,
+	} {
+		if known == status {
+			return true
+		}
+	}
+	return false
+}
+
+// statusTransitions are the moves between statuses the order state machine
+// allows
+var statusTransitions = map[models.OrderStatus][]models.OrderStatus{
+	// Only the company's owner can approve an order; admins can cancel it
+	models.OrderStatusPendingApproval: {
+		models.OrderStatusCancelled,
+	},
+	models.OrderStatusPending: {
+		models.OrderStatusProcessing,
+		models.OrderStatusCancelled,
+	},
+	models.OrderStatusProcessing: {
+		models.OrderStatusShipped,
+		models.OrderStatusCancelled,
+	},
+	models.OrderStatusShipped: {
+		models.OrderStatusDelivered,
+		models.OrderStatusReturned,
+	},
+	models.OrderStatusDelivered: {
+		models.OrderStatusReturned,
+	},
+	models.OrderStatusCancelled: {}, // No transitions allowed from cancelled
+	models.OrderStatusReturned:  {}, // No transitions allowed from returned
 }
 
 // isValidStatusTransition validates if the status transition is allowed
 func isValidStatusTransition(currentStatus, newStatus models.OrderStatus) bool {
-	// Define valid transitions
-	validTransitions := map[models.OrderStatus][]models.OrderStatus{
-		models.OrderStatusPending: {
-			models.OrderStatusProcessing,
-			models.OrderStatusCancelled,
-		},
-		models.OrderStatusProcessing: {
-			models.OrderStatusShipped,
-			models.OrderStatusCancelled,
-		},
-		models.OrderStatusShipped: {
-			models.OrderStatusDelivered,
-			models.OrderStatusReturned,
-		},
-		models.OrderStatusDelivered: {
-			models.OrderStatusReturned,
-		},
-		models.OrderStatusCancelled: {}, // No transitions allowed from cancelled
-		models.OrderStatusReturned:  {}, // No transitions allowed from returned
-	}
-
-	allowedTransitions, exists := validTransitions[currentStatus]
+	allowedTransitions, exists := statusTransitions[currentStatus]
 	if !exists {
 		return false
 	}
@@ -2,10 +2,13 @@ package order
 
 import (
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -18,6 +21,7 @@ type UpdatePaymentStatusRequest struct {
 
 // UpdatePaymentStatus - Admin endpoint to update payment status
 func (h *OrderHandler) UpdatePaymentStatus(c *gin.Context) {
+	ctx := c.Request.Context()
 	orderID := c.Param("id")
 	if orderID == "" {
 		response.GenerateBadRequestResponse(c, "order/update_payment", "Order ID is required")
@@ -52,7 +56,7 @@ func (h *OrderHandler) UpdatePaymentStatus(c *gin.Context) {
 	}
 
 	// Start transaction
-	tx := h.db.Begin()
+	tx := h.db.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -73,6 +77,7 @@ func (h *OrderHandler) UpdatePaymentStatus(c *gin.Context) {
 
 	// Update payment information
 	now := time.Now()
+	newlyPaid := req.PaymentStatus == models.PaymentStatusPaid && order.PaymentStatus != models.PaymentStatusPaid
 	order.PaymentStatus = req.PaymentStatus
 	order.AdminNotes = req.AdminNotes
 
@@ -109,64 +114,95 @@ func (h *OrderHandler) UpdatePaymentStatus(c *gin.Context) {
 		return
 	}
 
+	// A refund gives back the loyalty points spent on the order and takes
+	// back those earned on it
+	if req.PaymentStatus == models.PaymentStatusRefunded {
+		if err := h.loyalty.Reverse(tx, &order); err != nil {
+			tx.Rollback()
+			response.GenerateInternalServerErrorResponse(c, "order/update_payment", "Failed to reverse loyalty points")
+			return
+		}
+		if err := h.giftCards.Reverse(tx, &order); err != nil {
+			tx.Rollback()
+			response.GenerateInternalServerErrorResponse(c, "order/update_payment", "Failed to reverse gift cards")
+			return
+		}
+	}
+
+	if newlyPaid {
+		if err := h.giftCards.Activate(tx, &order); err != nil {
+			tx.Rollback()
+			response.GenerateInternalServerErrorResponse(c, "order/update_payment", "Failed to activate gift cards")
+			return
+		}
+		if err := webhook.Publish(tx, webhook.EventOrderPaid, webhook.OrderData(&order)); err != nil {
+			tx.Rollback()
+			response.GenerateInternalServerErrorResponse(c, "order/update_payment", "Failed to record payment events")
+			return
+		}
+	}
+
+	// Record payment status emails in the same transaction, so they are sent
+	// exactly when the update is committed
+	if h.emailTriggerSvc != nil {
+		h.queuePaymentEmails(tx, &order, req)
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "order/update_payment", "Failed to commit transaction")
 		return
 	}
 
-	// Send payment status emails asynchronously
-	go func() {
-		// Load order with user data for email
-		var orderWithUser models.Order
-		if err := h.db.Preload("User").First(&orderWithUser, order.ID).Error; err != nil {
-			fmt.Printf("Failed to load order with user data: %v\n", err)
-			return
-		}
+	response.GenerateSuccessResponse(c, "Payment status updated successfully", order)
+}
 
-		// Prepare payment data for email
-		paymentData := map[string]interface{}{
-			"order_number":   orderWithUser.OrderNumber,
-			"order_date":     orderWithUser.OrderDate,
-			"total_amount":   orderWithUser.FinalAmount,
-			"currency":       "GBP",
-			"payment_method": orderWithUser.PaymentMethod,
-			"customer_name":  fmt.Sprintf("%s %s", orderWithUser.User.FirstName, orderWithUser.User.LastName),
-			"amount":         orderWithUser.FinalAmount,
+// queuePaymentEmails writes the emails for a payment status change to the
+// outbox of tx. Failures are logged and do not undo the update.
+func (h *OrderHandler) queuePaymentEmails(tx *gorm.DB, order *models.Order, req UpdatePaymentStatusRequest) {
+	ctx := tx.Statement.Context
+	if req.PaymentStatus != models.PaymentStatusPaid && req.PaymentStatus != models.PaymentStatusFailed {
+		return
+	}
+
+	var user models.User
+	if err := tx.First(&user, order.UserID).Error; err != nil {
+		slog.ErrorContext(ctx, "failed to load order user for payment email", "component", "order", "error", err)
+		return
+	}
+	userName := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+
+	// Prepare payment data for email
+	paymentData := map[string]interface{}{
+		"order_number":   order.OrderNumber,
+		"order_date":     order.OrderDate,
+		"total_amount":   order.FinalAmount,
+		"currency":       settings.Current().Currency,
+		"payment_method": order.PaymentMethod,
+		"customer_name":  userName,
+		"amount":         order.FinalAmount,
+	}
+
+	emails := h.emailTriggerSvc.InTx(tx)
+	switch req.PaymentStatus {
+	case models.PaymentStatusPaid:
+		// Send payment success email
+		if err := emails.TriggerPaymentSuccess(order.ID, user.Email, userName, paymentData); err != nil {
+			slog.ErrorContext(ctx, "failed to send payment success email", "component", "order", "error", err)
 		}
 
-		switch req.PaymentStatus {
-		case models.PaymentStatusPaid:
-			// Send payment success email
-			if err := h.emailTriggerSvc.TriggerPaymentSuccess(
-				orderWithUser.ID,
-				orderWithUser.User.Email,
-				fmt.Sprintf("%s %s", orderWithUser.User.FirstName, orderWithUser.User.LastName),
-				paymentData,
-			); err != nil {
-				fmt.Printf("Failed to send payment success email: %v\n", err)
-			}
-
-		case models.PaymentStatusFailed:
-			// Add error message to payment data
-			paymentData["error_message"] = req.AdminNotes
-
-			// Send payment failed email to customer
-			if err := h.emailTriggerSvc.TriggerPaymentFailed(
-				orderWithUser.ID,
-				orderWithUser.User.Email,
-				fmt.Sprintf("%s %s", orderWithUser.User.FirstName, orderWithUser.User.LastName),
-				paymentData,
-			); err != nil {
-				fmt.Printf("Failed to send payment failed email: %v\n", err)
-			}
-
-			// Send admin notification for failed payment
-			if err := h.emailTriggerSvc.TriggerPaymentFailedAdminNotification(orderWithUser.ID, paymentData); err != nil {
-				fmt.Printf("Failed to send admin notification for failed payment: %v\n", err)
-			}
+	case models.PaymentStatusFailed:
+		// Add error message to payment data
+		paymentData["error_message"] = req.AdminNotes
+
+		// Send payment failed email to customer
+		if err := emails.TriggerPaymentFailed(order.ID, user.Email, userName, paymentData); err != nil {
+			slog.ErrorContext(ctx, "failed to send payment failed email", "component", "order", "error", err)
 		}
-	}()
 
-	response.GenerateSuccessResponse(c, "Payment status updated successfully", order)
+		// Send admin notification for failed payment
+		if err := emails.TriggerPaymentFailedAdminNotification(order.ID, paymentData); err != nil {
+			slog.ErrorContext(ctx, "failed to send admin notification for failed payment", "component", "order", "error", err)
+		}
+	}
 }
@@ -1,15 +1,21 @@
 package payment
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/address"
+	"github.com/YasserCherfaoui/MarketProGo/dto"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -38,6 +44,25 @@ type CreatePaymentRequest struct {
 	ReturnURL   string            `json:"return_url"`
 	CancelURL   string            `json:"cancel_url"`
 	Metadata    map[string]string `json:"metadata"`
+	CaptureMode string            `json:"capture_mode" binding:"omitempty,oneof=automatic manual"`
+
+	// Fingerprint of the saved card being paid with, when the storefront
+	// knows it, for the per-card fraud velocity rule
+	CardFingerprint string `json:"card_fingerprint"`
+}
+
+// RetryPaymentRequest represents the optional request body for retrying an
+// order's payment
+type RetryPaymentRequest struct {
+	ReturnURL       string `json:"return_url"`
+	CancelURL       string `json:"cancel_url"`
+	CardFingerprint string `json:"card_fingerprint"`
+}
+
+// CapturePaymentRequest represents the request body for capturing an
+// authorized payment; amount 0 or left out captures the full authorized amount
+type CapturePaymentRequest struct {
+	Amount float64 `json:"amount" binding:"gte=0"`
 }
 
 // RefundPaymentRequest represents the request body for refunding a payment
@@ -47,11 +72,43 @@ type RefundPaymentRequest struct {
 	Metadata map[string]string `json:"metadata"`
 }
 
+// customerInfo builds the payment customer details of a user, prefilling
+// their address from the order's billing address, or their default billing
+// address for orders placed without one
+func (h *PaymentHandler) customerInfo(user *models.User, order *models.Order) *payment.CustomerInfo {
+	customerInfo := &payment.CustomerInfo{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  user.FirstName + " " + user.LastName,
+		Phone: user.Phone,
+	}
+
+	var billing *models.Address
+	if order.BillingAddressID != nil {
+		var orderBilling models.Address
+		if err := h.db.First(&orderBilling, *order.BillingAddressID).Error; err == nil {
+			billing = &orderBilling
+		}
+	} else if defaultBilling, err := address.Default(h.db, user.ID, address.Billing); err == nil {
+		billing = defaultBilling
+	}
+	if billing != nil {
+		customerInfo.Address = strings.TrimSpace(billing.StreetAddress1 + " " + billing.StreetAddress2)
+		customerInfo.City = billing.City
+		customerInfo.Country = billing.Country
+		customerInfo.PostCode = billing.PostalCode
+		if customerInfo.Phone == "" {
+			customerInfo.Phone = billing.Phone
+		}
+	}
+	return customerInfo
+}
+
 // InitiatePayment handles POST /api/v1/payments
 func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 	var req CreatePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		response.GenerateValidationErrorResponse(c, "INVALID_REQUEST", err)
 		return
 	}
 
@@ -80,13 +137,7 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 		return
 	}
 
-	// Create customer info
-	customerInfo := &payment.CustomerInfo{
-		ID:    user.ID,
-		Email: user.Email,
-		Name:  user.FirstName + " " + user.LastName,
-		Phone: user.Phone,
-	}
+	customerInfo := h.customerInfo(&user, &order)
 
 	// Create payment request
 	paymentReq := &payment.PaymentRequest{
@@ -98,10 +149,22 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 		ReturnURL:    req.ReturnURL,
 		CancelURL:    req.CancelURL,
 		Metadata:     req.Metadata,
+		CaptureMode:  req.CaptureMode,
+
+		ClientIP:        c.ClientIP(),
+		CardFingerprint: req.CardFingerprint,
 	}
 
 	// Create payment
 	paymentResp, err := h.paymentService.CreatePayment(c.Request.Context(), paymentReq)
+	if errors.Is(err, payment.ErrOrderNotPayable) {
+		response.GenerateErrorResponse(c, http.StatusConflict, "ORDER_NOT_PAYABLE", "This order is waiting for approval and cannot be paid yet")
+		return
+	}
+	if errors.Is(err, payment.ErrPaymentBlocked) {
+		response.GenerateErrorResponse(c, http.StatusUnprocessableEntity, "PAYMENT_DECLINED", "Payment could not be accepted for this order. Please contact support.")
+		return
+	}
 	if err != nil {
 		response.GenerateErrorResponse(c, http.StatusInternalServerError, "PAYMENT_CREATION_FAILED", err.Error())
 		return
@@ -188,7 +251,7 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    payment,
+		"data":    dto.NewPayment(&payment, dto.AudienceFor(c, permissions.OrdersRead)),
 	})
 }
 
@@ -256,7 +319,7 @@ func (h *PaymentHandler) ListPayments(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"payments": payments,
+			"payments": dto.NewPayments(payments, dto.AudienceFor(c, permissions.OrdersRead)),
 			"pagination": gin.H{
 				"page":        page,
 				"limit":       limit,
@@ -277,16 +340,15 @@ func (h *PaymentHandler) RefundPayment(c *gin.Context) {
 		return
 	}
 
-	// Check if user is admin
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
-		response.GenerateErrorResponse(c, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+	// Check the refund permission
+	if !permissions.ContextHas(c, permissions.PaymentsRefund) {
+		response.GenerateErrorResponse(c, http.StatusForbidden, "FORBIDDEN", "Refund permission required")
 		return
 	}
 
 	var req RefundPaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		response.GenerateValidationErrorResponse(c, "INVALID_REQUEST", err)
 		return
 	}
 
@@ -311,6 +373,115 @@ func (h *PaymentHandler) RefundPayment(c *gin.Context) {
 	})
 }
 
+// RetryPayment handles POST /api/v1/orders/:id/payment/retry, linked from the
+// payment failed email. It starts a new checkout for the outstanding amount
+// of one of the user's unpaid orders.
+func (h *PaymentHandler) RetryPayment(c *gin.Context) {
+	var req RetryPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateValidationErrorResponse(c, "INVALID_REQUEST", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		response.GenerateErrorResponse(c, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+		return
+	}
+
+	var order models.Order
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateErrorResponse(c, http.StatusNotFound, "ORDER_NOT_FOUND", "Order not found or does not belong to user")
+			return
+		}
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to verify order")
+		return
+	}
+
+	paymentReq := &payment.PaymentRequest{
+		OrderID:      order.ID,
+		Amount:       math.Round((order.FinalAmount-order.GiftCardAmount)*100) / 100,
+		Description:  fmt.Sprintf("Order %s", order.OrderNumber),
+		CustomerInfo: h.customerInfo(&user, &order),
+		ReturnURL:    req.ReturnURL,
+		CancelURL:    req.CancelURL,
+
+		ClientIP:        c.ClientIP(),
+		CardFingerprint: req.CardFingerprint,
+	}
+
+	paymentResp, err := h.paymentService.RetryPayment(c.Request.Context(), paymentReq)
+	switch {
+	case errors.Is(err, payment.ErrOrderNotPayable):
+		response.GenerateErrorResponse(c, http.StatusConflict, "ORDER_NOT_PAYABLE", "This order is not awaiting payment")
+		return
+	case errors.Is(err, payment.ErrRetryInProgress):
+		response.GenerateErrorResponse(c, http.StatusConflict, "RETRY_IN_PROGRESS", "A payment retry is already in progress for this order")
+		return
+	case errors.Is(err, payment.ErrCheckoutStillOpen):
+		response.GenerateErrorResponse(c, http.StatusConflict, "CHECKOUT_STILL_OPEN", "The previous checkout of this order could not be cancelled. Please try again shortly.")
+		return
+	case errors.Is(err, payment.ErrRetryLimitReached):
+		response.GenerateErrorResponse(c, http.StatusTooManyRequests, "RETRY_LIMIT_REACHED", "This order has reached the maximum number of payment attempts. Please contact support.")
+		return
+	case errors.Is(err, payment.ErrPaymentBlocked):
+		response.GenerateErrorResponse(c, http.StatusUnprocessableEntity, "PAYMENT_DECLINED", "Payment could not be accepted for this order. Please contact support.")
+		return
+	case err != nil:
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "PAYMENT_CREATION_FAILED", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    paymentResp,
+	})
+}
+
+// CapturePayment handles POST /api/v1/admin/payments/:id/capture for manual
+// capture payments, capturing all or part of the authorized amount
+func (h *PaymentHandler) CapturePayment(c *gin.Context) {
+	paymentID := c.Param("id")
+	if paymentID == "" {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_PAYMENT_ID", "Payment ID is required")
+		return
+	}
+
+	var req CapturePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.GenerateValidationErrorResponse(c, "INVALID_REQUEST", err)
+		return
+	}
+
+	err := h.paymentService.CapturePayment(c.Request.Context(), paymentID, req.Amount)
+	if errors.Is(err, payment.ErrNotCapturable) || errors.Is(err, payment.ErrInvalidCaptureAmount) {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_CAPTURE", err.Error())
+		return
+	}
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "CAPTURE_FAILED", err.Error())
+		return
+	}
+
+	captured, err := h.paymentService.GetPayment(c.Request.Context(), paymentID)
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to get payment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    captured,
+	})
+}
+
 // CancelPayment handles POST /api/v1/payments/:id/cancel
 func (h *PaymentHandler) CancelPayment(c *gin.Context) {
 	paymentID := c.Param("id")
@@ -353,48 +524,49 @@ func (h *PaymentHandler) CancelPayment(c *gin.Context) {
 
 // HandleWebhook handles POST /api/v1/payments/webhook
 func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger := slog.With("component", "payment")
+
 	// Read request body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		log.Printf("[DEBUG] Failed to read webhook request body: %v", err)
+		logger.WarnContext(ctx, "failed to read webhook request body", "error", err)
 		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Failed to read request body")
 		return
 	}
-	log.Printf("[DEBUG] Webhook request body read successfully: %d bytes", len(body))
+	logger.DebugContext(ctx, "webhook request body read", "bytes", len(body))
 
 	// Get webhook signature from header
 	signature := c.GetHeader("Revolut-Signature")
 	if signature == "" {
-		log.Printf("[DEBUG] Missing Revolut-Signature header in webhook request")
+		logger.WarnContext(ctx, "missing Revolut-Signature header in webhook request")
 		response.GenerateErrorResponse(c, http.StatusBadRequest, "MISSING_SIGNATURE", "Webhook signature is required")
 		return
 	}
-	log.Printf("[DEBUG] Revolut-Signature header received: %s", signature)
 
 	// Get webhook timestamp from header for additional security
 	timestamp := c.GetHeader("Revolut-Request-Timestamp")
 	if timestamp == "" {
-		log.Printf("[DEBUG] Missing Revolut-Request-Timestamp header in webhook request")
+		logger.WarnContext(ctx, "missing Revolut-Request-Timestamp header in webhook request")
 		response.GenerateErrorResponse(c, http.StatusBadRequest, "MISSING_TIMESTAMP", "Webhook timestamp is required")
 		return
 	}
-	log.Printf("[DEBUG] Revolut-Request-Timestamp header received: %s", timestamp)
 
 	// Validate timestamp (optional but recommended for security)
 	if err := h.validateWebhookTimestamp(timestamp); err != nil {
-		log.Printf("[DEBUG] Webhook timestamp validation failed: %v", err)
+		logger.WarnContext(ctx, "webhook timestamp validation failed", "timestamp", timestamp, "error", err)
 		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_TIMESTAMP", err.Error())
 		return
 	}
 
 	// Process webhook
-	if err := h.paymentService.HandleWebhook(c.Request.Context(), body, signature, timestamp); err != nil {
-		log.Printf("[DEBUG] Error processing webhook: %v", err)
+	if err := h.paymentService.HandleWebhook(ctx, body, signature, timestamp); err != nil {
+		logger.ErrorContext(ctx, "failed to process webhook", "error", err)
 		response.GenerateErrorResponse(c, http.StatusBadRequest, "WEBHOOK_PROCESSING_FAILED", err.Error())
 		return
 	}
 
-	log.Printf("[DEBUG] Webhook processed successfully")
+	logger.InfoContext(ctx, "webhook processed")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Webhook processed successfully",
@@ -0,0 +1,95 @@
+package pricelist
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePriceList - Admin endpoint to create a price list for a company or B2B user
+func (h *PriceListHandler) CreatePriceList(c *gin.Context) {
+	var req CreatePriceListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "price_list/create", err.Error())
+		return
+	}
+
+	if req.CompanyID == nil && req.UserID == nil {
+		response.GenerateBadRequestResponse(c, "price_list/create", "Either company_id or user_id is required")
+		return
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		response.GenerateBadRequestResponse(c, "price_list/create", "ends_at must be after starts_at")
+		return
+	}
+	if msg := h.validateItems(req.Items); msg != "" {
+		response.GenerateBadRequestResponse(c, "price_list/create", msg)
+		return
+	}
+
+	priceList := models.PriceList{
+		Name:        req.Name,
+		Description: req.Description,
+		CompanyID:   req.CompanyID,
+		UserID:      req.UserID,
+		Currency:    req.Currency,
+		Priority:    req.Priority,
+		IsActive:    true,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Items:       toItems(req.Items),
+	}
+	if priceList.Currency == "" {
+		priceList.Currency = settings.Current().Currency
+	}
+
+	if err := h.db.Create(&priceList).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "price_list/create", "Failed to create price list")
+		return
+	}
+	// gorm skips zero values that have a default, so apply an explicit inactive flag afterwards
+	if req.IsActive != nil && !*req.IsActive {
+		h.db.Model(&priceList).Update("is_active", false)
+	}
+
+	h.db.Preload("Items.ProductVariant").First(&priceList, priceList.ID)
+	response.GenerateCreatedResponse(c, "Price list created successfully", priceList)
+}
+
+func toItems(data []PriceListItemData) []models.PriceListItem {
+	items := make([]models.PriceListItem, 0, len(data))
+	for _, d := range data {
+		minQuantity := d.MinQuantity
+		if minQuantity < 1 {
+			minQuantity = 1
+		}
+		items = append(items, models.PriceListItem{
+			ProductVariantID: d.ProductVariantID,
+			MinQuantity:      minQuantity,
+			Price:            d.Price,
+		})
+	}
+	return items
+}
+
+// validateItems checks that every referenced variant exists
+func (h *PriceListHandler) validateItems(data []PriceListItemData) string {
+	if len(data) == 0 {
+		return ""
+	}
+	ids := make([]uint, 0, len(data))
+	seen := map[uint]bool{}
+	for _, d := range data {
+		if !seen[d.ProductVariantID] {
+			seen[d.ProductVariantID] = true
+			ids = append(ids, d.ProductVariantID)
+		}
+	}
+	var count int64
+	h.db.Model(&models.ProductVariant{}).Where("id IN ?", ids).Count(&count)
+	if int(count) != len(ids) {
+		return "One or more product variants not found"
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package pricelist
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// DeletePriceList - Admin endpoint to delete a price list and its items
+func (h *PriceListHandler) DeletePriceList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "price_list/delete", "Invalid price list ID")
+		return
+	}
+
+	var priceList models.PriceList
+	if err := h.db.First(&priceList, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "price_list/delete", "Price list not found")
+		return
+	}
+
+	tx := h.db.Begin()
+	if err := tx.Where("price_list_id = ?", priceList.ID).Delete(&models.PriceListItem{}).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "price_list/delete", "Failed to delete price list items")
+		return
+	}
+	if err := tx.Delete(&priceList).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "price_list/delete", "Failed to delete price list")
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "price_list/delete", "Failed to commit transaction")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Price list deleted successfully", nil)
+}
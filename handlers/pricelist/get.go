@@ -0,0 +1,74 @@
+package pricelist
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetPriceLists - Admin endpoint to list price lists
+func (h *PriceListHandler) GetPriceLists(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := h.db.Model(&models.PriceList{})
+	if companyID := c.Query("company_id"); companyID != "" {
+		query = query.Where("company_id = ?", companyID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if isActive := c.Query("is_active"); isActive != "" {
+		query = query.Where("is_active = ?", isActive == "true")
+	}
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var priceLists []models.PriceList
+	if err := query.Preload("Company").
+		Preload("User").
+		Order("priority DESC, created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&priceLists).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "price_list/get_all", "Failed to get price lists")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Price lists retrieved successfully", map[string]interface{}{
+		"price_lists": priceLists,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"total_pages": (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetPriceList - Admin endpoint to get a price list with its items
+func (h *PriceListHandler) GetPriceList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "price_list/get", "Invalid price list ID")
+		return
+	}
+
+	var priceList models.PriceList
+	if err := h.db.Preload("Company").
+		Preload("User").
+		Preload("Items.ProductVariant").
+		First(&priceList, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "price_list/get", "Price list not found")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Price list retrieved successfully", priceList)
+}
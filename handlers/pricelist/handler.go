@@ -0,0 +1,13 @@
+package pricelist
+
+import (
+	"gorm.io/gorm"
+)
+
+type PriceListHandler struct {
+	db *gorm.DB
+}
+
+func NewPriceListHandler(db *gorm.DB) *PriceListHandler {
+	return &PriceListHandler{db: db}
+}
@@ -0,0 +1,42 @@
+package pricelist
+
+import "time"
+
+// This file contains shared request structures for the price list handlers.
+
+type PriceListItemData struct {
+	ProductVariantID uint    `json:"product_variant_id" binding:"required"`
+	MinQuantity      int     `json:"min_quantity"`
+	Price            float64 `json:"price" binding:"gte=0"`
+}
+
+type CreatePriceListRequest struct {
+	Name        string              `json:"name" binding:"required"`
+	Description string              `json:"description"`
+	CompanyID   *uint               `json:"company_id"`
+	UserID      *uint               `json:"user_id"`
+	Currency    string              `json:"currency"`
+	Priority    int                 `json:"priority"`
+	IsActive    *bool               `json:"is_active"`
+	StartsAt    *time.Time          `json:"starts_at"`
+	EndsAt      *time.Time          `json:"ends_at"`
+	Items       []PriceListItemData `json:"items"`
+}
+
+type UpdatePriceListRequest struct {
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	CompanyID   *uint      `json:"company_id"`
+	UserID      *uint      `json:"user_id"`
+	Currency    *string    `json:"currency"`
+	Priority    *int       `json:"priority"`
+	IsActive    *bool      `json:"is_active"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	ClearStart  bool       `json:"clear_starts_at"` // remove the start date
+	ClearEnd    bool       `json:"clear_ends_at"`   // remove the end date
+}
+
+type SetPriceListItemsRequest struct {
+	Items []PriceListItemData `json:"items" binding:"required,dive"`
+}
@@ -0,0 +1,132 @@
+package pricelist
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdatePriceList - Admin endpoint to update price list settings
+func (h *PriceListHandler) UpdatePriceList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "price_list/update", "Invalid price list ID")
+		return
+	}
+
+	var req UpdatePriceListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "price_list/update", err.Error())
+		return
+	}
+
+	var priceList models.PriceList
+	if err := h.db.First(&priceList, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "price_list/update", "Price list not found")
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.CompanyID != nil {
+		updates["company_id"] = *req.CompanyID
+	}
+	if req.UserID != nil {
+		updates["user_id"] = *req.UserID
+	}
+	if req.Currency != nil {
+		updates["currency"] = *req.Currency
+	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	startsAt, endsAt := priceList.StartsAt, priceList.EndsAt
+	if req.StartsAt != nil {
+		updates["starts_at"] = *req.StartsAt
+		startsAt = req.StartsAt
+	} else if req.ClearStart {
+		updates["starts_at"] = nil
+		startsAt = nil
+	}
+	if req.EndsAt != nil {
+		updates["ends_at"] = *req.EndsAt
+		endsAt = req.EndsAt
+	} else if req.ClearEnd {
+		updates["ends_at"] = nil
+		endsAt = nil
+	}
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		response.GenerateBadRequestResponse(c, "price_list/update", "ends_at must be after starts_at")
+		return
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(&priceList).Updates(updates).Error; err != nil {
+			response.GenerateInternalServerErrorResponse(c, "price_list/update", "Failed to update price list")
+			return
+		}
+	}
+
+	h.db.Preload("Company").Preload("User").Preload("Items.ProductVariant").First(&priceList, priceList.ID)
+	response.GenerateSuccessResponse(c, "Price list updated successfully", priceList)
+}
+
+// SetPriceListItems - Admin endpoint to replace all items of a price list
+func (h *PriceListHandler) SetPriceListItems(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "price_list/set_items", "Invalid price list ID")
+		return
+	}
+
+	var req SetPriceListItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "price_list/set_items", err.Error())
+		return
+	}
+
+	var priceList models.PriceList
+	if err := h.db.First(&priceList, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "price_list/set_items", "Price list not found")
+		return
+	}
+	if msg := h.validateItems(req.Items); msg != "" {
+		response.GenerateBadRequestResponse(c, "price_list/set_items", msg)
+		return
+	}
+
+	items := toItems(req.Items)
+	tx := h.db.Begin()
+	if err := tx.Unscoped().Where("price_list_id = ?", priceList.ID).Delete(&models.PriceListItem{}).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "price_list/set_items", "Failed to clear price list items")
+		return
+	}
+	for i := range items {
+		items[i].PriceListID = priceList.ID
+	}
+	if len(items) > 0 {
+		if err := tx.Create(&items).Error; err != nil {
+			tx.Rollback()
+			response.GenerateInternalServerErrorResponse(c, "price_list/set_items", "Failed to save price list items")
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "price_list/set_items", "Failed to commit transaction")
+		return
+	}
+
+	h.db.Preload("Items.ProductVariant").First(&priceList, priceList.ID)
+	response.GenerateSuccessResponse(c, "Price list items updated successfully", priceList)
+}
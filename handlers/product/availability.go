@@ -0,0 +1,113 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProductAvailability is the stock of a product's variants, optionally only
+// that of the warehouses serving a delivery zone
+type ProductAvailability struct {
+	ProductID uint                        `json:"product_id"`
+	ZoneID    uint                        `json:"zone_id,omitempty"`
+	Variants  []stock.VariantAvailability `json:"variants"`
+}
+
+// GetProductAvailability - Storefront endpoint for the available stock of each
+// variant of a product, summed across warehouses. zone_id, or a postcode and
+// country, limit it to the warehouses serving that delivery zone.
+func (h *ProductHandler) GetProductAvailability(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "product/availability", "Invalid product ID")
+		return
+	}
+
+	var product models.Product
+	if err := h.db.WithContext(ctx).Select("id").Where("is_active = ?", true).
+		Scopes(catalog.Published("products", time.Now())).
+		First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "product/availability", "Product not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "product/availability", "Failed to get product")
+		}
+		return
+	}
+
+	zone, ok := h.requestZone(c, "product/availability")
+	if !ok {
+		return
+	}
+
+	result := ProductAvailability{ProductID: product.ID}
+	if zone != nil {
+		result.ZoneID = zone.ID
+	}
+	err = h.availability.Load(ctx, fmt.Sprintf("%d:%d", result.ProductID, result.ZoneID), &result, func() error {
+		warehouseIDs, err := h.zoneWarehouses(ctx, zone)
+		if err != nil {
+			return err
+		}
+		variants, err := h.stock.Availability(ctx, result.ProductID, warehouseIDs)
+		if err != nil {
+			return err
+		}
+		result.Variants = variants
+		return nil
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/availability", "Failed to get product availability")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Product availability retrieved successfully", result)
+}
+
+// requestZone returns the delivery zone given by the zone_id, or postcode and
+// country, query parameters, nil when there are none. It responds 404 and
+// returns false when there is no such zone.
+func (h *ProductHandler) requestZone(c *gin.Context, code string) (*models.DeliveryZone, bool) {
+	db := h.db.WithContext(c.Request.Context())
+	if zoneID := c.Query("zone_id"); zoneID != "" {
+		zone := &models.DeliveryZone{}
+		if err := db.Where("is_active = ?", true).First(zone, "id = ?", zoneID).Error; err != nil {
+			response.GenerateNotFoundResponse(c, code, "Delivery zone not found")
+			return nil, false
+		}
+		return zone, true
+	}
+	if postcode := c.Query("postcode"); postcode != "" {
+		zone, err := delivery.ZoneFor(db, c.DefaultQuery("country", "GB"), postcode)
+		if err != nil {
+			response.GenerateNotFoundResponse(c, code, "No delivery zone covers this postcode")
+			return nil, false
+		}
+		return zone, true
+	}
+	return nil, true
+}
+
+// zoneWarehouses returns the IDs of the warehouses serving a delivery zone,
+// nil for no zone
+func (h *ProductHandler) zoneWarehouses(ctx context.Context, zone *models.DeliveryZone) ([]uint, error) {
+	if zone == nil {
+		return nil, nil
+	}
+	var warehouseIDs []uint
+	err := h.db.WithContext(ctx).Table("delivery_zone_warehouses").
+		Where("delivery_zone_id = ?", zone.ID).
+		Pluck("warehouse_id", &warehouseIDs).Error
+	return warehouseIDs, err
+}
@@ -0,0 +1,202 @@
+package product
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// BatchProductsRequest lists the products to load, up to 100, about two
+// category pages
+type BatchProductsRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// BatchProductsResponse holds the products found, in the order asked for,
+// and the IDs of those that are not found or not on sale
+type BatchProductsResponse struct {
+	Products   []models.Product `json:"products"`
+	MissingIDs []uint           `json:"missing_ids"`
+}
+
+// BatchAvailabilityRequest lists the variants to get the availability of
+type BatchAvailabilityRequest struct {
+	VariantIDs []uint `json:"variant_ids" binding:"required,min=1,max=100"`
+}
+
+// BatchAvailabilityResponse is the availability of the variants found, in
+// order of ID, optionally only in the warehouses serving a delivery zone
+type BatchAvailabilityResponse struct {
+	ZoneID     uint                        `json:"zone_id,omitempty"`
+	Variants   []stock.VariantAvailability `json:"variants"`
+	MissingIDs []uint                      `json:"missing_ids"`
+}
+
+// BatchReviewSummaryRequest lists the products to get the rating summary of
+type BatchReviewSummaryRequest struct {
+	ProductIDs []uint `json:"product_ids" binding:"required,min=1,max=100"`
+}
+
+// GetProductsBatch - POST /products/batch loads up to 100 active, published
+// products by ID in one request, with their overall rating summary, so a
+// storefront page can hydrate all of its products at once
+func (h *ProductHandler) GetProductsBatch(c *gin.Context) {
+	var req BatchProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "product/batch", err)
+		return
+	}
+	ids := uniqueIDs(req.IDs)
+
+	var found []models.Product
+	if err := h.db.WithContext(c.Request.Context()).
+		Preload("Brand").
+		Preload("Categories").
+		Preload("Tags").
+		Preload("Images").
+		Preload("Options.Values").
+		Preload("Variants.Images").
+		Preload("Variants.OptionValues").
+		Preload("Variants.PriceTiers").
+		Preload("Specifications").
+		Where("id IN ? AND is_active = ?", ids, true).
+		Scopes(catalog.Published("products", time.Now())).
+		Find(&found).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/batch", "Failed to get products")
+		return
+	}
+
+	// Keep the order asked for
+	byID := make(map[uint]models.Product, len(found))
+	for _, product := range found {
+		byID[product.ID] = product
+	}
+	result := BatchProductsResponse{Products: make([]models.Product, 0, len(found)), MissingIDs: []uint{}}
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			result.Products = append(result.Products, product)
+		} else {
+			result.MissingIDs = append(result.MissingIDs, id)
+		}
+	}
+	products := result.Products
+
+	for i := range products {
+		if products[i].Brand != nil {
+			products[i].Brand.Image = h.appwriteService.GetFileURL(products[i].Brand.Image)
+		}
+		h.images.ResolveAll(products[i].Images)
+		for j := range products[i].Variants {
+			h.images.ResolveAll(products[i].Variants[j].Images)
+		}
+	}
+
+	productIDs := make([]uint, len(products))
+	for i := range products {
+		productIDs[i] = products[i].ID
+	}
+	if summaries, err := h.reviewService.GetProductRatingSummaries(productIDs); err != nil {
+		// The products are still worth showing without their ratings
+		slog.WarnContext(c.Request.Context(), "failed to load product ratings", "component", "product", "error", err)
+	} else {
+		for i := range products {
+			products[i].RatingSummary = summaries[products[i].ID]
+		}
+	}
+
+	h.applyCustomerPrices(c, products)
+	if err := i18n.TranslateProducts(h.db, i18n.Locale(c), products); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to translate products", "component", "product", "error", err)
+	}
+
+	response.GenerateSuccessResponse(c, "Products fetched successfully", result)
+}
+
+// GetVariantsAvailabilityBatch - POST /variants/availability/batch returns
+// the available stock of up to 100 variants of active, published products.
+// Like GetProductAvailability, zone_id, or a postcode and country, limit it
+// to the warehouses serving that delivery zone.
+func (h *ProductHandler) GetVariantsAvailabilityBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req BatchAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "product/availability-batch", err)
+		return
+	}
+	zone, ok := h.requestZone(c, "product/availability-batch")
+	if !ok {
+		return
+	}
+	ids := uniqueIDs(req.VariantIDs)
+
+	var onSale []uint
+	if err := h.db.WithContext(ctx).Model(&models.ProductVariant{}).
+		Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL").
+		Where("product_variants.id IN ? AND products.is_active = ?", ids, true).
+		Scopes(catalog.Published("products", time.Now())).
+		Pluck("product_variants.id", &onSale).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/availability-batch", "Failed to get variants")
+		return
+	}
+	warehouseIDs, err := h.zoneWarehouses(ctx, zone)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/availability-batch", "Failed to get delivery zone warehouses")
+		return
+	}
+	variants, err := h.stock.VariantsAvailability(ctx, onSale, warehouseIDs)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/availability-batch", "Failed to get variant availability")
+		return
+	}
+
+	result := BatchAvailabilityResponse{Variants: variants, MissingIDs: []uint{}}
+	if zone != nil {
+		result.ZoneID = zone.ID
+	}
+	available := make(map[uint]bool, len(variants))
+	for _, v := range variants {
+		available[v.VariantID] = true
+	}
+	for _, id := range ids {
+		if !available[id] {
+			result.MissingIDs = append(result.MissingIDs, id)
+		}
+	}
+	response.GenerateSuccessResponse(c, "Variant availability retrieved successfully", result)
+}
+
+// GetReviewSummariesBatch - POST /reviews/summary/batch returns the overall
+// rating summary of up to 100 products, keyed by product ID. Products
+// without reviews, or that do not exist, get an empty summary.
+func (h *ProductHandler) GetReviewSummariesBatch(c *gin.Context) {
+	var req BatchReviewSummaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "product/review-summary-batch", err)
+		return
+	}
+	summaries, err := h.reviewService.GetProductRatingSummaries(uniqueIDs(req.ProductIDs))
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/review-summary-batch", "Failed to get rating summaries")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Rating summaries retrieved successfully", summaries)
+}
+
+// uniqueIDs returns ids without duplicates or zeros, in their first order
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
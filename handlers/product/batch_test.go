@@ -0,0 +1,104 @@
+package product
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewIntegrationService_GetProductRatingSummaries(t *testing.T) {
+	db := setupReviewIntegrationTestDB(t)
+	ris := NewReviewIntegrationService(db)
+
+	reviewed, variants := createTestProductWithVariants(t, db, "1")
+	unreviewed, _ := createTestProductWithVariants(t, db, "2")
+	user := createTestUser(t, db)
+	createTestReviews(t, db, variants[0].ID, user.ID)
+	require.NoError(t, db.Create(&models.ProductRating{
+		ProductVariantID: variants[1].ID,
+		AverageRating:    1.0,
+		TotalReviews:     1,
+		RatingBreakdown:  `{"1":1,"2":0,"3":0,"4":0,"5":0}`,
+	}).Error)
+
+	summaries, err := ris.GetProductRatingSummaries([]uint{reviewed.ID, unreviewed.ID, 9999})
+	require.NoError(t, err)
+	require.Len(t, summaries, 3)
+
+	// Variant ratings are weighted by their number of reviews
+	summary := summaries[reviewed.ID]
+	assert.Equal(t, 4, summary.TotalReviews)
+	assert.InDelta(t, 3.25, summary.AverageRating, 0.001)
+	assert.True(t, summary.HasReviews)
+	assert.Equal(t, map[string]int{"1": 1, "2": 0, "3": 1, "4": 1, "5": 1}, summary.RatingBreakdown)
+	assert.Empty(t, summary.VariantRatings)
+
+	assert.False(t, summaries[unreviewed.ID].HasReviews)
+	assert.Equal(t, 0, summaries[9999].TotalReviews)
+}
+
+func TestGetProductsBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupReviewIntegrationTestDB(t)
+	require.NoError(t, db.AutoMigrate(
+		&models.Tag{},
+		&models.ProductImage{},
+		&models.ProductOption{},
+		&models.ProductOptionValue{},
+		&models.ProductVariantPriceTier{},
+		&models.ProductSpecification{},
+	))
+	handler := NewProductHandler(db, nil, nil, nil, nil, nil)
+
+	first, variants := createTestProductWithVariants(t, db, "1")
+	second, _ := createTestProductWithVariants(t, db, "2")
+	inactive, _ := createTestProductWithVariants(t, db, "3")
+	require.NoError(t, db.Model(inactive).Update("is_active", false).Error)
+	user := createTestUser(t, db)
+	createTestReviews(t, db, variants[0].ID, user.ID)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/products/batch", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.GetProductsBatch(c)
+		return w
+	}
+
+	// Products come back in the order asked for, once each
+	body, _ := json.Marshal(gin.H{"ids": []uint{second.ID, first.ID, inactive.ID, second.ID, 9999}})
+	w := send(string(body))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data struct {
+			Products []struct {
+				ID            uint                 `json:"id"`
+				RatingSummary ProductRatingSummary `json:"rating_summary"`
+			} `json:"products"`
+			MissingIDs []uint `json:"missing_ids"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Products, 2)
+	assert.Equal(t, second.ID, resp.Data.Products[0].ID)
+	assert.Equal(t, first.ID, resp.Data.Products[1].ID)
+	assert.Equal(t, 3, resp.Data.Products[1].RatingSummary.TotalReviews)
+	assert.Equal(t, []uint{inactive.ID, 9999}, resp.Data.MissingIDs)
+
+	// At most 100 IDs
+	ids := make([]uint, 101)
+	for i := range ids {
+		ids[i] = uint(i + 1)
+	}
+	body, _ = json.Marshal(gin.H{"ids": ids})
+	assert.Equal(t, http.StatusBadRequest, send(string(body)).Code)
+	assert.Equal(t, http.StatusBadRequest, send(`{"ids":[]}`).Code)
+}
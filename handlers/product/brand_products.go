@@ -0,0 +1,28 @@
+package product
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetBrandProducts - Public endpoint listing the active products of a brand,
+// by ID or slug, and of its sub-brands. It returns product summaries and takes
+// the filters, sorts and pagination of GET /products?view=summary.
+func (h *ProductHandler) GetBrandProducts(c *gin.Context) {
+	id := c.Param("id")
+	query := h.db.Preload("Children")
+	if _, err := strconv.ParseUint(id, 10, 32); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("slug = ?", id)
+	}
+	var brand models.Brand
+	if err := query.First(&brand).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "product/brand_products", "Brand not found")
+		return
+	}
+	h.listProductSummaries(c, brandAndChildren(&brand))
+}
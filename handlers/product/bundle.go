@@ -0,0 +1,106 @@
+package product
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/bundle"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// SetBundleRequest makes a variant a bundle of other variants. Without a
+// price the bundle costs what its components do.
+type SetBundleRequest struct {
+	Components []struct {
+		VariantID uint `json:"variant_id" binding:"required"`
+		Quantity  int  `json:"quantity" binding:"required,min=1"`
+	} `json:"components" binding:"required,min=1,dive"`
+	Price *float64 `json:"price" binding:"omitempty,min=0"`
+}
+
+// BundleDetails is a bundle with what its components cost on their own
+type BundleDetails struct {
+	Bundle          *models.ProductVariant `json:"bundle"`
+	ComponentPrices bundle.Prices          `json:"component_prices"`
+}
+
+func bundleError(c *gin.Context, code string, err error, message string) {
+	switch {
+	case errors.Is(err, bundle.ErrNotFound), errors.Is(err, bundle.ErrNotBundle):
+		response.GenerateNotFoundResponse(c, code, err.Error())
+	case errors.Is(err, bundle.ErrNoComponents), errors.Is(err, bundle.ErrInvalidQuantity),
+		errors.Is(err, bundle.ErrInvalidPrice), errors.Is(err, bundle.ErrComponentNotFound),
+		errors.Is(err, bundle.ErrContainsItself), errors.Is(err, bundle.ErrNestedBundle):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, message)
+	}
+}
+
+func parseVariantID(c *gin.Context, code string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, "Invalid variant ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func bundleDetails(variant *models.ProductVariant) BundleDetails {
+	return BundleDetails{Bundle: variant, ComponentPrices: bundle.Price(variant.BundleComponents)}
+}
+
+// GetBundle - Admin endpoint for a bundle's components and prices
+func (h *ProductHandler) GetBundle(c *gin.Context) {
+	id, ok := parseVariantID(c, "product/bundle")
+	if !ok {
+		return
+	}
+	variant, err := h.bundles.Get(c.Request.Context(), id)
+	if err != nil {
+		bundleError(c, "product/bundle", err, "Failed to get bundle")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Bundle retrieved successfully", bundleDetails(variant))
+}
+
+// SetBundle - Admin endpoint making a variant a bundle of other variants, or
+// changing its components or price
+func (h *ProductHandler) SetBundle(c *gin.Context) {
+	id, ok := parseVariantID(c, "product/bundle")
+	if !ok {
+		return
+	}
+	var req SetBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "product/bundle", err)
+		return
+	}
+	in := bundle.Input{Price: req.Price}
+	for _, component := range req.Components {
+		in.Components = append(in.Components, bundle.ComponentInput{VariantID: component.VariantID, Quantity: component.Quantity})
+	}
+	variant, err := h.bundles.Set(c.Request.Context(), id, in)
+	if err != nil {
+		bundleError(c, "product/bundle", err, "Failed to set bundle")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Bundle saved successfully", bundleDetails(variant))
+}
+
+// DeleteBundle - Admin endpoint turning a bundle back into a variant with
+// stock of its own
+func (h *ProductHandler) DeleteBundle(c *gin.Context) {
+	id, ok := parseVariantID(c, "product/bundle")
+	if !ok {
+		return
+	}
+	variant, err := h.bundles.Remove(c.Request.Context(), id)
+	if err != nil {
+		bundleError(c, "product/bundle", err, "Failed to remove bundle")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Bundle removed successfully", variant)
+}
@@ -16,6 +16,7 @@ type ProductData struct {
 	IsActive       bool                   `json:"is_active"`
 	IsFeatured     bool                   `json:"is_featured"`
 	IsVAT          bool                   `json:"is_vat"`
+	IsGiftCard     bool                   `json:"is_gift_card"`
 	BrandID        *uint                  `json:"brand_id"`
 	CategoryIDs    []uint                 `json:"category_ids"`
 	Tags           []string               `json:"tags"`
@@ -46,15 +47,9 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	}
 
 	// Step 3: Upload all files and map them by filename
-	files := form.File["files"]
-	uploadedFileIDs := make(map[string]string)
-	for _, fileHeader := range files {
-		fileID, err := h.appwriteService.UploadFile(fileHeader)
-		if err != nil {
-			response.GenerateInternalServerErrorResponse(c, "product/create", "Failed to upload image '"+fileHeader.Filename+"': "+err.Error())
-			return
-		}
-		uploadedFileIDs[fileHeader.Filename] = fileID
+	uploadedImages, ok := h.uploadImages(c, "product/create", form.File["files"])
+	if !ok {
+		return
 	}
 
 	// Step 4: Create product and associations in a transaction
@@ -76,6 +71,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		IsActive:    data.IsActive,
 		IsFeatured:  data.IsFeatured,
 		IsVAT:       data.IsVAT,
+		IsGiftCard:  data.IsGiftCard,
 		BrandID:     data.BrandID,
 	}
 	if err := tx.Create(&product).Error; err != nil {
@@ -86,13 +82,13 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 
 	// Associate Images with base product
 	for _, imgData := range data.Images {
-		fileID, ok := uploadedFileIDs[imgData.FileName]
+		uploaded, ok := uploadedImages[imgData.FileName]
 		if !ok {
 			tx.Rollback()
 			response.GenerateBadRequestResponse(c, "product/create", "Image file '"+imgData.FileName+"' not found in upload")
 			return
 		}
-		image := models.ProductImage{ProductID: &product.ID, URL: fileID, IsPrimary: imgData.IsPrimary, AltText: imgData.AltText}
+		image := models.ProductImage{ProductID: &product.ID, URL: uploaded.Key, ImageKeys: uploaded.ImageKeys, IsPrimary: imgData.IsPrimary, AltText: imgData.AltText}
 		if err := tx.Create(&image).Error; err != nil {
 			tx.Rollback()
 			response.GenerateInternalServerErrorResponse(c, "product/create", "Failed to create product image")
@@ -193,13 +189,13 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 
 		// Associate Images with variant
 		for _, imgData := range varData.Images {
-			fileID, ok := uploadedFileIDs[imgData.FileName]
+			uploaded, ok := uploadedImages[imgData.FileName]
 			if !ok {
 				tx.Rollback()
 				response.GenerateBadRequestResponse(c, "product/create", "Image file '"+imgData.FileName+"' for variant '"+variant.Name+"' not found in upload")
 				return
 			}
-			image := models.ProductImage{ProductVariantID: &variant.ID, URL: fileID, IsPrimary: imgData.IsPrimary, AltText: imgData.AltText}
+			image := models.ProductImage{ProductVariantID: &variant.ID, URL: uploaded.Key, ImageKeys: uploaded.ImageKeys, IsPrimary: imgData.IsPrimary, AltText: imgData.AltText}
 			if err := tx.Create(&image).Error; err != nil {
 				tx.Rollback()
 				response.GenerateInternalServerErrorResponse(c, "product/create", "Failed to create variant image")
@@ -0,0 +1,155 @@
+package product
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// VariantPricing is what a customer pays for a quantity of a variant, and
+// the quantity discounts they get
+type VariantPricing struct {
+	VariantID   uint   `json:"variant_id"`
+	SKU         string `json:"sku"`
+	Quantity    int    `json:"quantity"`
+	MinQuantity int    `json:"min_quantity"`
+	PriceType   string `json:"price_type"` // customer or b2b
+	pricing.Resolution
+	Total         float64           `json:"total"`
+	BasePrice     float64           `json:"base_price"`
+	Savings       float64           `json:"savings"` // off the base price, for the whole quantity
+	UnitPrice     *models.UnitPrice `json:"unit_price,omitempty"`
+	PriceListID   *uint             `json:"price_list_id,omitempty"`
+	PriceListName string            `json:"price_list_name,omitempty"`
+	Tiers         []PricingTier     `json:"tiers"`
+}
+
+// PricingTier is a quantity range bought at the same price
+type PricingTier struct {
+	pricing.Tier
+	UnitPrice *models.UnitPrice `json:"unit_price,omitempty"`
+}
+
+// customerPriceType returns the price type products are priced at for the request:
+// b2b for wholesalers and when asked for business prices, else customer
+func customerPriceType(c *gin.Context) string {
+	if userType, _ := c.Get("user_type"); c.Query("price_type") == "business" || userType == models.Wholesaler {
+		return "b2b"
+	}
+	return "customer"
+}
+
+// applyCustomerPrices sets UnitPrice on every variant, and CustomerPrice and
+// CustomerUnitPrice for authenticated users, following the price list →
+// price tiers → B2B price → base price resolution order
+func (h *ProductHandler) applyCustomerPrices(c *gin.Context, products []models.Product) {
+	for i := range products {
+		for j := range products[i].Variants {
+			variant := &products[i].Variants[j]
+			variant.UnitPrice = pricing.PerMeasure(variant.BasePrice, variant)
+		}
+	}
+
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		return
+	}
+
+	priceList, err := h.priceResolver.ActivePriceList(userID)
+	if err != nil {
+		return
+	}
+
+	priceType := customerPriceType(c)
+	for i := range products {
+		for j := range products[i].Variants {
+			variant := &products[i].Variants[j]
+			quantity := variant.MinQuantity
+			if quantity < 1 {
+				quantity = 1
+			}
+			price := pricing.UnitPriceWithList(priceList, variant, quantity, priceType)
+			variant.CustomerPrice = &price
+			variant.CustomerUnitPrice = pricing.PerMeasure(price, variant)
+		}
+	}
+}
+
+// GetVariantPricing - Storefront endpoint resolving what the customer pays
+// for ?quantity= of a variant, by price list, price tier, B2B price or base
+// price, with the table of quantity discounts. quantity defaults to the
+// variant's minimum.
+func (h *ProductHandler) GetVariantPricing(c *gin.Context) {
+	id, ok := parseVariantID(c, "product/pricing")
+	if !ok {
+		return
+	}
+	var variant models.ProductVariant
+	if err := h.db.WithContext(c.Request.Context()).Preload("PriceTiers").
+		Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL AND products.is_active = ?", true).
+		Scopes(catalog.Published("products", time.Now())).
+		Where("product_variants.is_active = ?", true).
+		First(&variant, "product_variants.id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "product/pricing", "Variant not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "product/pricing", "Failed to get variant")
+		}
+		return
+	}
+
+	minQuantity := max(variant.MinQuantity, 1)
+	quantity := minQuantity
+	if q := c.Query("quantity"); q != "" {
+		parsed, err := strconv.Atoi(q)
+		if err != nil || parsed < 1 {
+			response.GenerateBadRequestResponse(c, "product/pricing", "quantity must be a positive number")
+			return
+		}
+		if parsed < minQuantity {
+			response.GenerateBadRequestResponse(c, "product/pricing", "quantity is below the minimum of "+strconv.Itoa(minQuantity))
+			return
+		}
+		quantity = parsed
+	}
+
+	priceList, err := h.priceResolver.ActivePriceList(c.GetUint("user_id"))
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/pricing", "Failed to get price list")
+		return
+	}
+	priceType := customerPriceType(c)
+	resolution := pricing.ResolveWithList(priceList, &variant, quantity, priceType)
+	result := VariantPricing{
+		VariantID:   variant.ID,
+		SKU:         variant.SKU,
+		Quantity:    quantity,
+		MinQuantity: minQuantity,
+		PriceType:   priceType,
+		Resolution:  resolution,
+		Total:       roundMoney(resolution.Price * float64(quantity)),
+		BasePrice:   variant.BasePrice,
+		Savings:     roundMoney(max(variant.BasePrice-resolution.Price, 0) * float64(quantity)),
+		UnitPrice:   pricing.PerMeasure(resolution.Price, &variant),
+		Tiers:       []PricingTier{},
+	}
+	if priceList != nil && resolution.Source == pricing.SourcePriceList {
+		result.PriceListID, result.PriceListName = &priceList.ID, priceList.Name
+	}
+	for _, tier := range pricing.Tiers(priceList, &variant, priceType) {
+		result.Tiers = append(result.Tiers, PricingTier{Tier: tier, UnitPrice: pricing.PerMeasure(tier.Price, &variant)})
+	}
+	response.GenerateSuccessResponse(c, "Variant pricing retrieved successfully", result)
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -1,6 +1,12 @@
 package product
 
 import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
@@ -11,7 +17,7 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	includeInactive := c.Query("include_inactive") == "true"
 
 	var product models.Product
-	query := h.db.
+	query := h.db.WithContext(c.Request.Context()).
 		Preload("Brand").
 		Preload("Categories").
 		Preload("Tags").
@@ -22,39 +28,56 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		Preload("Variants.InventoryItems").
 		Preload("Variants.InventoryItems.Warehouse").
 		Preload("Variants.PriceTiers").
+		Preload("Variants.BundleComponents.ComponentVariant").
 		Preload("Specifications")
 
-	// Only fetch active products by default
+	// Only fetch active, published products by default
 	if !includeInactive {
-		query = query.Where("is_active = ?", true)
+		query = query.Where("is_active = ?", true).Scopes(catalog.Published("products", time.Now()))
 	}
 
-	err := query.First(&product, "id = ?", productID).Error
+	err := h.catalog.Load(c.Request.Context(), "product", fmt.Sprintf("%s:%t", productID, includeInactive), &product, func() error {
+		if err := query.First(&product, "id = ?", productID).Error; err != nil {
+			return err
+		}
+		breadcrumbs, err := catalog.Breadcrumbs(h.db, product.Categories)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "failed to load product breadcrumbs", "component", "product", "product_id", productID, "error", err)
+		}
+		product.Breadcrumbs = breadcrumbs
+		return nil
+	})
 
 	if err != nil {
 		response.GenerateNotFoundResponse(c, "product/get", "Product not found")
 		return
 	}
 
-	// Add Appwrite URLs to product and brand images
+	// Add URLs to product and brand images
 	if product.Brand != nil {
 		product.Brand.Image = h.appwriteService.GetFileURL(product.Brand.Image)
 	}
-	for i := range product.Images {
-		product.Images[i].URL = h.appwriteService.GetFileURL(product.Images[i].URL)
-	}
+	h.images.ResolveAll(product.Images)
 	for i := range product.Variants {
-		for j := range product.Variants[i].Images {
-			product.Variants[i].Images[j].URL = h.appwriteService.GetFileURL(product.Variants[i].Images[j].URL)
+		h.images.ResolveAll(product.Variants[i].Images)
+	}
+
+	// Embed the rating summary and most helpful reviews unless the client
+	// loads reviews itself
+	if c.Query("include_reviews") != "false" {
+		if err := h.reviewService.AddReviewSummaryToProduct(&product); err != nil {
+			// The product is still worth showing without its reviews
+			slog.WarnContext(c.Request.Context(), "failed to load product reviews", "component", "product", "product_id", productID, "error", err)
 		}
 	}
 
-	// Add review data to product
-	err = h.reviewService.AddReviewDataToProduct(&product)
-	if err != nil {
-		// Log error but don't fail the request
-		// TODO: Add proper logging
+	// Resolve customer-specific prices for authenticated users
+	products := []models.Product{product}
+	h.applyCustomerPrices(c, products)
+	if err := i18n.TranslateProducts(h.db, i18n.Locale(c), products); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to translate product", "component", "product", "product_id", productID, "error", err)
 	}
+	product = products[0]
 
 	response.GenerateSuccessResponse(c, "product/get", product)
 }
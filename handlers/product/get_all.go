@@ -2,7 +2,11 @@ package product
 
 import (
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
@@ -17,6 +21,12 @@ type PaginatedResponse struct {
 	PageSize int         `json:"page_size"`
 }
 
+// catalogPage is the cached result of a product listing query
+type catalogPage struct {
+	Products []models.Product `json:"products"`
+	Total    int64            `json:"total"`
+}
+
 // ProductWithStock extends the Product model with stock information
 type ProductWithStock struct {
 	models.Product
@@ -24,7 +34,8 @@ type ProductWithStock struct {
 	MaxStock   int `json:"max_stock"`
 }
 
-func (h *ProductHandler) GetAllProducts(c *gin.Context) {
+// findProducts runs the filtered, sorted and paginated product query
+func (h *ProductHandler) findProducts(c *gin.Context, page, pageSize int) ([]models.Product, int64, error) {
 	// Query params
 	name := c.Query("name")
 	sku := c.Query("sku")
@@ -44,7 +55,7 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 	var products []models.Product
 
 	// Base query with all preloads
-	db := h.db.Model(&models.Product{}).
+	db := h.db.WithContext(c.Request.Context()).Model(&models.Product{}).
 		Preload("Brand").
 		Preload("Categories").
 		Preload("Tags").
@@ -75,8 +86,7 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 		// Find the brand by slug
 		var brand models.Brand
 		if err := h.db.Preload("Children").Where("slug = ?", brandSlug).First(&brand).Error; err != nil {
-			response.GenerateInternalServerErrorResponse(c, "product/get_all", err.Error())
-			return
+			return nil, 0, err
 		}
 		brandIDs := []uint{brand.ID}
 		// If brand has children, include their IDs
@@ -98,6 +108,9 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 	} else {
 		subQuery = subQuery.Where("products.is_active = ?", isActive == "true")
 	}
+	if !showUnpublished(c) {
+		subQuery = subQuery.Scopes(catalog.Published("products", time.Now()))
+	}
 	if isFeatured != "" {
 		subQuery = subQuery.Where("products.is_featured = ?", isFeatured == "true")
 	}
@@ -105,7 +118,8 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 		subQuery = subQuery.Where("products.is_vat = ?", isVAT == "true")
 	}
 	if categoryID != "" {
-		subQuery = subQuery.Where("product_categories.category_id = ?", categoryID)
+		// A category lists the products of its subcategories too
+		subQuery = subQuery.Where("product_categories.category_id IN (?)", catalog.CategoryAndDescendants(h.db, categoryID))
 	}
 	if tag != "" {
 		subQuery = subQuery.Where("tags.name ILIKE ?", "%"+tag+"%")
@@ -135,24 +149,6 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 		db = db.Order("id ASC") // Default order
 	}
 
-	// Pagination logic
-	page := 1
-	pageSize := 20
-	if p := c.Query("page"); p != "" {
-		fmt.Sscanf(p, "%d", &page)
-	}
-	if ps := c.Query("page_size"); ps != "" {
-		fmt.Sscanf(ps, "%d", &pageSize)
-	}
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 {
-		pageSize = 20
-	} else if pageSize > 100 {
-		pageSize = 100
-	}
-
 	// Get total count based on the filtered subquery
 	var total int64
 	h.db.Table("(?) as sub", subQuery).Count(&total)
@@ -172,24 +168,60 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 	}
 
 	// Apply pagination
-	var err error
-	if err = db.Offset((page - 1) * pageSize).Limit(pageSize).Find(&products).Error; err != nil {
+	if err := db.Offset((page - 1) * pageSize).Limit(pageSize).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+func (h *ProductHandler) GetAllProducts(c *gin.Context) {
+	if c.Query("view") == "summary" {
+		h.getProductSummaries(c)
+		return
+	}
+
+	// Pagination logic
+	page := 1
+	pageSize := 20
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if ps := c.Query("page_size"); ps != "" {
+		fmt.Sscanf(ps, "%d", &pageSize)
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	// The query string holds every filter, sort and page parameter, so with
+	// whether unpublished products are shown it identifies the result; prices
+	// for the caller are applied after the cache
+	var result catalogPage
+	key := fmt.Sprintf("%s:%t", c.Request.URL.Query().Encode(), showUnpublished(c))
+	err := h.catalog.Load(c.Request.Context(), "products", key, &result, func() error {
+		var err error
+		result.Products, result.Total, err = h.findProducts(c, page, pageSize)
+		return err
+	})
+	if err != nil {
 		response.GenerateInternalServerErrorResponse(c, "product/get_all", err.Error())
 		return
 	}
+	products, total := result.Products, result.Total
 
-	// Add Appwrite URLs to product and brand images
+	// Add URLs to product and brand images
 	for i := range products {
 		if products[i].Brand != nil {
 			products[i].Brand.Image = h.appwriteService.GetFileURL(products[i].Brand.Image)
 		}
-		for j := range products[i].Images {
-			products[i].Images[j].URL = h.appwriteService.GetFileURL(products[i].Images[j].URL)
-		}
+		h.images.ResolveAll(products[i].Images)
 		for j := range products[i].Variants {
-			for k := range products[i].Variants[j].Images {
-				products[i].Variants[j].Images[k].URL = h.appwriteService.GetFileURL(products[i].Variants[j].Images[k].URL)
-			}
+			h.images.ResolveAll(products[i].Variants[j].Images)
 		}
 	}
 
@@ -200,6 +232,12 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 		// TODO: Add proper logging
 	}
 
+	// Resolve customer-specific prices for authenticated users
+	h.applyCustomerPrices(c, products)
+	if err := i18n.TranslateProducts(h.db, i18n.Locale(c), products); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to translate products", "component", "product", "error", err)
+	}
+
 	// Transform products to include stock information
 	var productsWithStock []ProductWithStock
 	for _, product := range products {
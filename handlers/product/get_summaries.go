@@ -0,0 +1,134 @@
+package product
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// getProductSummaries serves GET /products?view=summary from the product
+// summary projection. It lists active products only and returns one row per
+// product with its price range, primary image, rating and stock, without the
+// variant, image and rating trees of the full listing.
+func (h *ProductHandler) getProductSummaries(c *gin.Context) {
+	var brandIDs []uint
+	if brandSlug := c.Query("brand_slug"); brandSlug != "" {
+		var brand models.Brand
+		if err := h.db.Preload("Children").Where("slug = ?", brandSlug).First(&brand).Error; err != nil {
+			response.GenerateInternalServerErrorResponse(c, "product/get_all", err.Error())
+			return
+		}
+		brandIDs = brandAndChildren(&brand)
+	}
+	h.listProductSummaries(c, brandIDs)
+}
+
+// brandAndChildren returns the ID of a brand with its children loaded and of
+// its children
+func brandAndChildren(brand *models.Brand) []uint {
+	brandIDs := []uint{brand.ID}
+	for _, child := range brand.Children {
+		brandIDs = append(brandIDs, child.ID)
+	}
+	return brandIDs
+}
+
+// listProductSummaries responds with a page of product summaries filtered by
+// the query parameters and, when brandIDs is not nil, by brand
+func (h *ProductHandler) listProductSummaries(c *gin.Context, brandIDs []uint) {
+	page := 1
+	pageSize := 20
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if ps := c.Query("page_size"); ps != "" {
+		fmt.Sscanf(ps, "%d", &pageSize)
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	minField, maxField := "product_summaries.min_price", "product_summaries.max_price"
+	if c.Query("price_type") == "business" {
+		minField, maxField = "product_summaries.min_b2b_price", "product_summaries.max_b2b_price"
+	}
+
+	query := h.db.WithContext(c.Request.Context()).Model(&models.ProductSummary{}).
+		Where("product_summaries.is_active = ?", true).
+		Scopes(catalog.Published("product_summaries", time.Now()))
+
+	if name := c.Query("name"); name != "" {
+		query = query.Where("product_summaries.name ILIKE ?", "%"+name+"%")
+	}
+	if isFeatured := c.Query("is_featured"); isFeatured != "" {
+		query = query.Where("product_summaries.is_featured = ?", isFeatured == "true")
+	}
+	if isVAT := c.Query("is_vat"); isVAT != "" {
+		query = query.Where("product_summaries.is_vat = ?", isVAT == "true")
+	}
+	if inStock := c.Query("in_stock"); inStock != "" {
+		query = query.Where("product_summaries.in_stock = ?", inStock == "true")
+	}
+	if minPrice := c.Query("min_price"); minPrice != "" {
+		query = query.Where(maxField+" >= ?", minPrice)
+	}
+	if maxPrice := c.Query("max_price"); maxPrice != "" {
+		query = query.Where(minField+" <= ?", maxPrice)
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		query = query.Where("product_summaries.product_id IN (?)",
+			h.db.Table("product_categories").Select("product_id").Where("category_id IN (?)", catalog.CategoryAndDescendants(h.db, categoryID)))
+	}
+	if brandIDs != nil {
+		query = query.Where("product_summaries.brand_id IN ?", brandIDs)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/get_all", err.Error())
+		return
+	}
+
+	switch {
+	case c.Query("sort_by_price") == "asc" || c.Query("sort_by_price") == "desc":
+		query = query.Order(minField + " " + c.Query("sort_by_price"))
+	case c.Query("sort_by_stock") == "asc" || c.Query("sort_by_stock") == "desc":
+		query = query.Order("product_summaries.total_stock " + c.Query("sort_by_stock"))
+	case c.Query("sort_by_rating") == "asc" || c.Query("sort_by_rating") == "desc":
+		query = query.Order("product_summaries.average_rating " + c.Query("sort_by_rating"))
+	default:
+		query = query.Order("product_summaries.name ASC")
+	}
+
+	var summaries []models.ProductSummary
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&summaries).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/get_all", err.Error())
+		return
+	}
+	for i := range summaries {
+		if summaries[i].PrimaryImageURL != "" {
+			summaries[i].PrimaryImageURL = h.images.URL(summaries[i].PrimaryImageURL)
+		}
+	}
+	if err := i18n.TranslateSummaries(h.db, i18n.Locale(c), summaries); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to translate product summaries", "component", "product", "error", err)
+	}
+
+	response.GenerateSuccessResponse(c, "Products fetched successfully", PaginatedResponse{
+		Data:     summaries,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 )
@@ -93,16 +94,16 @@ func (h *ProductHandler) GetProductVariants(c *gin.Context) {
 	// Transform variants to include quantity information
 	var variantsWithQuantity []ProductVariantWithQuantity
 	for _, variant := range variants {
-		// Process image URLs using Appwrite service
-		for j := range variant.Images {
-			variant.Images[j].URL = h.appwriteService.GetFileURL(variant.Images[j].URL)
-		}
+		// Process image URLs
+		h.images.ResolveAll(variant.Images)
 
 		// Process brand image if exists
 		if variant.Product.Brand != nil {
 			variant.Product.Brand.Image = h.appwriteService.GetFileURL(variant.Product.Brand.Image)
 		}
 
+		variant.UnitPrice = pricing.PerMeasure(variant.BasePrice, &variant)
+
 		// Calculate stock levels from inventory items
 		totalStock := 0
 		reservedStock := 0
@@ -2,7 +2,12 @@ package product
 
 import (
 	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/bundle"
+	"github.com/YasserCherfaoui/MarketProGo/cache"
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -12,14 +17,26 @@ type ProductHandler struct {
 	gcsService      *gcs.GCService
 	appwriteService *aw.AppwriteService
 	reviewService   *ReviewIntegrationService
+	priceResolver   *pricing.Resolver
+	catalog         *cache.Catalog
+	availability    *cache.Availability
+	stock           *stock.Service
+	bundles         *bundle.Service
+	images          *storage.Images
 }
 
-func NewProductHandler(db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService) *ProductHandler {
+func NewProductHandler(db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService, images *storage.Images, catalog *cache.Catalog, availability *cache.Availability) *ProductHandler {
 	return &ProductHandler{
 		db:              db,
 		gcsService:      gcsService,
 		appwriteService: appwriteService,
 		reviewService:   NewReviewIntegrationService(db),
+		priceResolver:   pricing.NewResolver(db),
+		catalog:         catalog,
+		availability:    availability,
+		stock:           stock.NewService(db),
+		bundles:         bundle.NewService(db),
+		images:          images,
 	}
 }
 
@@ -0,0 +1,34 @@
+package product
+
+import (
+	"errors"
+	"mime/multipart"
+
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadImages validates and stores uploaded product images, by file name.
+// When one fails it responds, deletes those already stored and returns false.
+func (h *ProductHandler) uploadImages(c *gin.Context, code string, files []*multipart.FileHeader) (map[string]*storage.StoredImage, bool) {
+	uploaded := make(map[string]*storage.StoredImage, len(files))
+	for _, fileHeader := range files {
+		stored, err := h.images.Upload(c.Request.Context(), fileHeader)
+		if err != nil {
+			for _, image := range uploaded {
+				h.images.Delete(c.Request.Context(), image)
+			}
+			switch {
+			case errors.Is(err, storage.ErrImageTooLarge), errors.Is(err, storage.ErrImageTypeNotAllowed),
+				errors.Is(err, storage.ErrImageCorrupt), errors.Is(err, storage.ErrImageTooManyPixels):
+				response.GenerateBadRequestResponse(c, code, "Invalid image '"+fileHeader.Filename+"': "+err.Error())
+			default:
+				response.GenerateInternalServerErrorResponse(c, code, "Failed to upload image '"+fileHeader.Filename+"'")
+			}
+			return nil, false
+		}
+		uploaded[fileHeader.Filename] = stored
+	}
+	return uploaded, true
+}
@@ -0,0 +1,56 @@
+package product
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OverallRating is the overall rating of a product across its variants
+type OverallRating struct {
+	ProductID uint `json:"product_id"`
+	ratings.Aggregate
+	HasReviews bool `json:"has_reviews"`
+}
+
+// GetProductRating - GET /products/:id/rating returns the overall rating of a
+// published product and its breakdown per star rating, across all its
+// variants
+func (h *ProductHandler) GetProductRating(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "product/rating", "Invalid product ID")
+		return
+	}
+
+	var product models.Product
+	if err := db.Select("id").Where("is_active = ?", true).
+		Scopes(catalog.Published("products", time.Now())).
+		First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "product/rating", "Product not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "product/rating", "Failed to get product")
+		}
+		return
+	}
+
+	overall, err := ratings.Overall(db, product.ID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/rating", "Failed to get product rating")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Product rating retrieved successfully", OverallRating{
+		ProductID:  product.ID,
+		Aggregate:  overall,
+		HasReviews: overall.TotalReviews > 0,
+	})
+}
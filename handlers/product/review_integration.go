@@ -16,8 +16,12 @@ type ProductRatingSummary struct {
 	RatingBreakdown map[string]int         `json:"rating_breakdown"`
 	HasReviews      bool                   `json:"has_reviews"`
 	VariantRatings  []ProductVariantRating `json:"variant_ratings,omitempty"`
+	TopReviews      []models.ProductReview `json:"top_reviews,omitempty"` // most helpful approved reviews, on the product detail only
 }
 
+// topReviewsLimit is how many of the most helpful reviews the product detail embeds
+const topReviewsLimit = 3
+
 // ProductVariantRating represents rating data for a specific variant
 type ProductVariantRating struct {
 	VariantID       uint                   `json:"variant_id"`
@@ -54,7 +58,7 @@ func (ris *ReviewIntegrationService) GetProductRatingSummary(productID uint) (*P
 
 	var totalRating float64
 	var totalReviews int
-	allRatingBreakdown := make(map[string]int)
+	allRatingBreakdown := map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}
 
 	// Get rating data for each variant
 	for _, variant := range variants {
@@ -184,6 +188,88 @@ func (ris *ReviewIntegrationService) AddReviewDataToProduct(product *models.Prod
 	return nil
 }
 
+// AddReviewSummaryToProduct adds review data to a product detail response,
+// with the product's most helpful reviews, so the product page does not need
+// a second call for them
+func (ris *ReviewIntegrationService) AddReviewSummaryToProduct(product *models.Product) error {
+	if err := ris.AddReviewDataToProduct(product); err != nil {
+		return err
+	}
+	topReviews, err := ris.GetTopReviews(product.ID, topReviewsLimit)
+	if err != nil {
+		return err
+	}
+	product.RatingSummary.(*ProductRatingSummary).TopReviews = topReviews
+	return nil
+}
+
+// GetTopReviews returns the most helpful approved reviews of a product's
+// variants, newest first among equally helpful ones
+func (ris *ReviewIntegrationService) GetTopReviews(productID uint, limit int) ([]models.ProductReview, error) {
+	reviews := []models.ProductReview{}
+	err := ris.db.
+		Joins("JOIN product_variants ON product_variants.id = product_reviews.product_variant_id").
+		Where("product_variants.product_id = ? AND product_reviews.status = ?", productID, models.ReviewStatusApproved).
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, first_name, last_name, avatar")
+		}).
+		Preload("Images").
+		Preload("SellerResponse").
+		Order("product_reviews.helpful_count DESC, product_reviews.created_at DESC, product_reviews.id DESC").
+		Limit(limit).
+		Find(&reviews).Error
+	return reviews, err
+}
+
+// GetProductRatingSummaries returns the overall rating summary of each
+// product, without variant ratings, in one query whatever the number of
+// products. Products without reviews get an empty summary.
+func (ris *ReviewIntegrationService) GetProductRatingSummaries(productIDs []uint) (map[uint]*ProductRatingSummary, error) {
+	summaries := make(map[uint]*ProductRatingSummary, len(productIDs))
+	for _, id := range productIDs {
+		summaries[id] = &ProductRatingSummary{RatingBreakdown: map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}}
+	}
+	if len(productIDs) == 0 {
+		return summaries, nil
+	}
+
+	var ratings []struct {
+		ProductID       uint
+		AverageRating   float64
+		TotalReviews    int
+		RatingBreakdown string
+	}
+	err := ris.db.Table("product_ratings").
+		Select("product_variants.product_id, product_ratings.average_rating, product_ratings.total_reviews, product_ratings.rating_breakdown").
+		Joins("JOIN product_variants ON product_variants.id = product_ratings.product_variant_id AND product_variants.deleted_at IS NULL").
+		Where("product_variants.product_id IN ? AND product_ratings.deleted_at IS NULL", productIDs).
+		Scan(&ratings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Average the variant ratings weighted by their number of reviews
+	totals := make(map[uint]float64, len(productIDs))
+	for _, rating := range ratings {
+		summary := summaries[rating.ProductID]
+		totals[rating.ProductID] += rating.AverageRating * float64(rating.TotalReviews)
+		summary.TotalReviews += rating.TotalReviews
+		var breakdown map[string]int
+		if json.Unmarshal([]byte(rating.RatingBreakdown), &breakdown) == nil {
+			for stars, count := range breakdown {
+				summary.RatingBreakdown[stars] += count
+			}
+		}
+	}
+	for id, summary := range summaries {
+		if summary.TotalReviews > 0 {
+			summary.AverageRating = totals[id] / float64(summary.TotalReviews)
+			summary.HasReviews = true
+		}
+	}
+	return summaries, nil
+}
+
 // AddReviewDataToProducts adds review data to multiple products
 func (ris *ReviewIntegrationService) AddReviewDataToProducts(products []models.Product) error {
 	for i := range products {
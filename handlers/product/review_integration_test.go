@@ -237,6 +237,37 @@ func TestReviewIntegrationService_AddReviewDataToProduct(t *testing.T) {
 	assert.Equal(t, 3, variantRating.TotalReviews)
 }
 
+func TestReviewIntegrationService_AddReviewSummaryToProduct(t *testing.T) {
+	db := setupReviewIntegrationTestDB(t)
+	ris := NewReviewIntegrationService(db)
+
+	// Create test data
+	product, variants := createTestProductWithVariants(t, db, "10")
+	user := createTestUser(t, db)
+	createTestReviews(t, db, variants[0].ID, user.ID)
+
+	// Reviews of both variants compete; pending reviews never show
+	more := []models.ProductReview{
+		{ProductVariantID: variants[1].ID, UserID: user.ID, Rating: 2, Title: "Most helpful", Status: models.ReviewStatusApproved, HelpfulCount: 9},
+		{ProductVariantID: variants[0].ID, UserID: user.ID, Rating: 5, Title: "Second most helpful", Status: models.ReviewStatusApproved, HelpfulCount: 4},
+		{ProductVariantID: variants[0].ID, UserID: user.ID, Rating: 1, Title: "Pending", Status: models.ReviewStatusPending, HelpfulCount: 20},
+	}
+	require.NoError(t, db.Create(&more).Error)
+
+	require.NoError(t, db.Preload("Variants").First(product, product.ID).Error)
+	require.NoError(t, ris.AddReviewSummaryToProduct(product))
+
+	summary, ok := product.RatingSummary.(*ProductRatingSummary)
+	require.True(t, ok)
+	assert.Equal(t, 3, summary.TotalReviews)
+	assert.Equal(t, map[string]int{"1": 0, "2": 0, "3": 1, "4": 1, "5": 1}, summary.RatingBreakdown)
+	require.Len(t, summary.TopReviews, 3)
+	assert.Equal(t, "Most helpful", summary.TopReviews[0].Title)
+	assert.Equal(t, "Second most helpful", summary.TopReviews[1].Title)
+	assert.Equal(t, "Test", summary.TopReviews[0].User.FirstName)
+	assert.Empty(t, summary.TopReviews[0].User.Email, "reviewers' contact details are not exposed")
+}
+
 func TestReviewIntegrationService_AddReviewDataToProducts(t *testing.T) {
 	db := setupReviewIntegrationTestDB(t)
 	ris := NewReviewIntegrationService(db)
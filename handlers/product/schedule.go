@@ -0,0 +1,85 @@
+package product
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ScheduleProductRequest sets a product's launch window. A null time clears
+// that end of the window.
+type ScheduleProductRequest struct {
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+}
+
+// showUnpublished reports whether a listing request asks for products outside
+// their launch window and may see them
+func showUnpublished(c *gin.Context) bool {
+	return c.Query("include_unpublished") == "true" && permissions.ContextHas(c, permissions.ProductsWrite)
+}
+
+// ScheduleProduct - Admin endpoint to set when a product appears in and
+// disappears from the storefront
+func (h *ProductHandler) ScheduleProduct(c *gin.Context) {
+	var req ScheduleProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "product/schedule", err)
+		return
+	}
+	if req.PublishAt != nil && req.UnpublishAt != nil && !req.UnpublishAt.After(*req.PublishAt) {
+		response.GenerateBadRequestResponse(c, "product/schedule", "unpublish_at must be after publish_at")
+		return
+	}
+
+	var product models.Product
+	if err := h.db.First(&product, "id = ?", c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, "product/schedule", "Product not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "product/schedule", "Failed to get product")
+		}
+		return
+	}
+	if err := h.db.Model(&product).Updates(map[string]interface{}{
+		"publish_at":   req.PublishAt,
+		"unpublish_at": req.UnpublishAt,
+	}).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/schedule", "Failed to schedule product")
+		return
+	}
+	product.PublishAt, product.UnpublishAt = req.PublishAt, req.UnpublishAt
+	response.GenerateSuccessResponse(c, "Product scheduled successfully", product)
+}
+
+// GetScheduledProducts - Admin endpoint listing products with a launch or
+// withdrawal still to come, soonest first
+func (h *ProductHandler) GetScheduledProducts(c *gin.Context) {
+	now := time.Now()
+	var products []models.Product
+	if err := h.db.Preload("Images").
+		Where("publish_at > ? OR unpublish_at > ?", now, now).
+		Order("id ASC").
+		Find(&products).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "product/scheduled", "Failed to get scheduled products")
+		return
+	}
+	// The next change of a product is its launch if still to come, else its withdrawal
+	next := func(product *models.Product) time.Time {
+		if product.PublishAt != nil && product.PublishAt.After(now) {
+			return *product.PublishAt
+		}
+		return *product.UnpublishAt
+	}
+	sort.SliceStable(products, func(i, j int) bool { return next(&products[i]).Before(next(&products[j])) })
+	for i := range products {
+		h.images.ResolveAll(products[i].Images)
+	}
+	response.GenerateSuccessResponse(c, "Scheduled products fetched successfully", products)
+}
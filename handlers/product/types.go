@@ -69,6 +69,7 @@ type VariantData struct {
 
 type VariantUpdateData struct {
 	ID                   uint               `json:"id"`
+	Version              *int               `json:"version"` // version the edit was made on, see UpdateProductData
 	Name                 *string            `json:"name"`
 	SKU                  *string            `json:"sku"`
 	Barcode              *string            `json:"barcode"`
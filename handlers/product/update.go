@@ -2,9 +2,13 @@ package product
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
 	"strconv"
 
+	"github.com/YasserCherfaoui/MarketProGo/bundle"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/trash"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 )
@@ -14,11 +18,15 @@ import (
 // but this approach uses separate arrays for explicit actions (add, update, delete).
 
 type UpdateProductData struct {
+	// Version is the version of the product the edit was made on. When it
+	// has been edited since, the update is refused with 409 Conflict.
+	Version                *int                      `json:"version"`
 	Name                   *string                   `json:"name"`
 	Description            *string                   `json:"description"`
 	IsActive               *bool                     `json:"is_active"`
 	IsFeatured             *bool                     `json:"is_featured"`
 	IsVAT                  *bool                     `json:"is_vat"`
+	IsGiftCard             *bool                     `json:"is_gift_card"`
 	BrandID                *uint                     `json:"brand_id"`
 	CategoryIDs            []uint                    `json:"category_ids"`
 	Tags                   []string                  `json:"tags"`
@@ -73,16 +81,10 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	}
 
 	// Handle New Image Uploads
-	files := form.File["files"]
-	uploadedFileIDs := make(map[string]string)
-	for _, fileHeader := range files {
-		fileID, err := h.appwriteService.UploadFile(fileHeader)
-		if err != nil {
-			tx.Rollback()
-			response.GenerateInternalServerErrorResponse(c, "product/update", "Failed to upload image '"+fileHeader.Filename+"'")
-			return
-		}
-		uploadedFileIDs[fileHeader.Filename] = fileID
+	uploadedImages, ok := h.uploadImages(c, "product/update", form.File["files"])
+	if !ok {
+		tx.Rollback()
+		return
 	}
 
 	// Process JSON data for other updates
@@ -94,10 +96,15 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			response.GenerateBadRequestResponse(c, "product/update", "Invalid JSON in 'product_data' field: "+err.Error())
 			return
 		}
+		if data.Version != nil && *data.Version != product.Version {
+			tx.Rollback()
+			h.versionConflict(c, &models.Product{}, product.ID)
+			return
+		}
 
 		// Handle Images to Add
 		for _, imgData := range data.ImagesToAdd {
-			fileID, ok := uploadedFileIDs[imgData.FileName]
+			uploaded, ok := uploadedImages[imgData.FileName]
 			if !ok {
 				tx.Rollback()
 				response.GenerateBadRequestResponse(c, "product/update", "Image file '"+imgData.FileName+"' was specified but not found in upload")
@@ -105,7 +112,8 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			}
 			image := models.ProductImage{
 				ProductID: &product.ID,
-				URL:       fileID,
+				URL:       uploaded.Key,
+				ImageKeys: uploaded.ImageKeys,
 				IsPrimary: imgData.IsPrimary,
 				AltText:   imgData.AltText,
 			}
@@ -288,7 +296,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			}
 			// Add images for variant
 			for _, imgData := range varData.Images {
-				fileID, ok := uploadedFileIDs[imgData.FileName]
+				uploaded, ok := uploadedImages[imgData.FileName]
 				if !ok {
 					tx.Rollback()
 					response.GenerateBadRequestResponse(c, "product/update", "Image file '"+imgData.FileName+"' for variant '"+variant.Name+"' not found in upload")
@@ -296,7 +304,8 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 				}
 				image := models.ProductImage{
 					ProductVariantID: &variant.ID,
-					URL:              fileID,
+					URL:              uploaded.Key,
+					ImageKeys:        uploaded.ImageKeys,
 					IsPrimary:        imgData.IsPrimary,
 					AltText:          imgData.AltText,
 				}
@@ -340,6 +349,9 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		if data.IsVAT != nil {
 			product.IsVAT = *data.IsVAT
 		}
+		if data.IsGiftCard != nil {
+			product.IsGiftCard = *data.IsGiftCard
+		}
 		if data.BrandID != nil {
 			product.BrandID = data.BrandID
 		}
@@ -377,10 +389,9 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		// Handle Variant Deletion
 		if len(data.VariantsToDelete) > 0 {
 			for _, variantID := range data.VariantsToDelete {
-				// Also delete variant images and associations
-				tx.Where("product_variant_id = ?", variantID).Delete(&models.ProductImage{})
-				tx.Exec("DELETE FROM variant_option_values WHERE product_variant_id = ?", variantID)
-				if err := tx.Delete(&models.ProductVariant{}, variantID).Error; err != nil {
+				// Along with its images and price tiers, keeping its option
+				// values so that it can be restored from the trash
+				if err := trash.Delete(tx, trash.Variants, variantID); err != nil && !errors.Is(err, trash.ErrNotFound) {
 					tx.Rollback()
 					response.GenerateInternalServerErrorResponse(c, "product/update", "Failed to delete variant")
 					return
@@ -396,6 +407,11 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 				response.GenerateBadRequestResponse(c, "product/update", "Variant with ID "+strconv.Itoa(int(varUpdateData.ID))+" not found.")
 				return
 			}
+			if varUpdateData.Version != nil && *varUpdateData.Version != variant.Version {
+				tx.Rollback()
+				h.versionConflict(c, &models.ProductVariant{}, variant.ID)
+				return
+			}
 			if varUpdateData.Name != nil {
 				variant.Name = *varUpdateData.Name
 			}
@@ -432,11 +448,22 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			if varUpdateData.QuantityInStock != nil {
 				variant.QuantityInStock = *varUpdateData.QuantityInStock
 			}
-			if err := tx.Save(&variant).Error; err != nil {
+			if err := models.SaveVersioned(tx, &variant, &variant.Version); err != nil {
 				tx.Rollback()
+				if errors.Is(err, models.ErrVersionConflict) {
+					h.versionConflict(c, &models.ProductVariant{}, variant.ID)
+					return
+				}
 				response.GenerateInternalServerErrorResponse(c, "product/update", "Failed to update variant")
 				return
 			}
+			// Bundles are priced from their components, so changing either
+			// reprices them
+			if err := bundle.SyncPrices(tx, variant.ID); err != nil {
+				tx.Rollback()
+				response.GenerateInternalServerErrorResponse(c, "product/update", "Failed to update bundle prices")
+				return
+			}
 			// Update price tiers for this variant
 			if varUpdateData.PriceTiers != nil {
 				tx.Where("product_variant_id = ?", variant.ID).Delete(&models.ProductVariantPriceTier{})
@@ -485,7 +512,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			// --- Images CRUD ---
 			// Add new images
 			for _, imgData := range varUpdateData.ImagesToAdd {
-				fileID, ok := uploadedFileIDs[imgData.FileName]
+				uploaded, ok := uploadedImages[imgData.FileName]
 				if !ok {
 					tx.Rollback()
 					response.GenerateBadRequestResponse(c, "product/update", "Image file '"+imgData.FileName+"' for variant '"+variant.Name+"' not found in upload")
@@ -493,7 +520,8 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 				}
 				image := models.ProductImage{
 					ProductVariantID: &variant.ID,
-					URL:              fileID,
+					URL:              uploaded.Key,
+					ImageKeys:        uploaded.ImageKeys,
 					IsPrimary:        imgData.IsPrimary,
 					AltText:          imgData.AltText,
 				}
@@ -537,8 +565,12 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	}
 
 	// Save changes to the base product
-	if err := tx.Save(&product).Error; err != nil {
+	if err := models.SaveVersioned(tx, &product, &product.Version); err != nil {
 		tx.Rollback()
+		if errors.Is(err, models.ErrVersionConflict) {
+			h.versionConflict(c, &models.Product{}, product.ID)
+			return
+		}
 		response.GenerateInternalServerErrorResponse(c, "product/update", "Failed to save product")
 		return
 	}
@@ -553,3 +585,14 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 
 	response.GenerateSuccessResponse(c, "Product updated successfully", product)
 }
+
+// versionConflict responds 409 Conflict with the current product or variant,
+// whose version the admin UI merges its edit onto
+func (h *ProductHandler) versionConflict(c *gin.Context, current interface{}, id uint) {
+	if err := h.db.First(current, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "product/update", "Product not found")
+		return
+	}
+	response.GenerateResponse(c, http.StatusConflict, models.ErrVersionConflict.Error(), current,
+		response.NewAPIError("product/version_conflict", "The product was edited since it was loaded, reload it and apply the changes again"))
+}
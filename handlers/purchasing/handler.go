@@ -0,0 +1,18 @@
+package purchasing
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/purchasing"
+	"gorm.io/gorm"
+)
+
+type PurchasingHandler struct {
+	db         *gorm.DB
+	purchasing *purchasing.Service
+}
+
+func NewPurchasingHandler(db *gorm.DB) *PurchasingHandler {
+	return &PurchasingHandler{
+		db:         db,
+		purchasing: purchasing.NewService(db),
+	}
+}
@@ -0,0 +1,176 @@
+package purchasing
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/purchasing"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreatePurchaseOrder - Admin endpoint to create a draft purchase order
+func (h *PurchasingHandler) CreatePurchaseOrder(c *gin.Context) {
+	var req purchasing.OrderInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "purchasing/create_order", err.Error())
+		return
+	}
+
+	order, err := h.purchasing.CreateOrder(c.Request.Context(), req, getUserIDFromContext(c))
+	if err != nil {
+		purchaseOrderError(c, "purchasing/create_order", err)
+		return
+	}
+	response.GenerateCreatedResponse(c, "Purchase order created successfully", order)
+}
+
+// GetPurchaseOrders - Admin endpoint to list purchase orders
+func (h *PurchasingHandler) GetPurchaseOrders(c *gin.Context) {
+	page := 1
+	pageSize := 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(c.Query("page_size")); err == nil && ps > 0 {
+		pageSize = min(ps, 100)
+	}
+
+	query := h.db.Model(&models.PurchaseOrder{})
+	if supplierID := c.Query("supplier_id"); supplierID != "" {
+		query = query.Where("supplier_id = ?", supplierID)
+	}
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		query = query.Where("warehouse_id = ?", warehouseID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "purchasing/orders", "Failed to count purchase orders")
+		return
+	}
+	var orders []models.PurchaseOrder
+	if err := query.Preload("Supplier").Preload("Warehouse").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&orders).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "purchasing/orders", "Failed to get purchase orders")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Purchase orders retrieved successfully", map[string]interface{}{
+		"data":      orders,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetPurchaseOrder - Admin endpoint to get a purchase order with its lines and receipts
+func (h *PurchasingHandler) GetPurchaseOrder(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/order", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+	order, err := h.purchasing.GetOrder(c.Request.Context(), id)
+	if err != nil {
+		purchaseOrderError(c, "purchasing/order", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Purchase order retrieved successfully", order)
+}
+
+// UpdatePurchaseOrder - Admin endpoint to replace the details and lines of a draft purchase order
+func (h *PurchasingHandler) UpdatePurchaseOrder(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/update_order", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+	var req purchasing.OrderInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "purchasing/update_order", err.Error())
+		return
+	}
+
+	order, err := h.purchasing.UpdateOrder(c.Request.Context(), id, req)
+	if err != nil {
+		purchaseOrderError(c, "purchasing/update_order", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Purchase order updated successfully", order)
+}
+
+// SendPurchaseOrder - Admin endpoint to mark a draft purchase order as sent to the supplier
+func (h *PurchasingHandler) SendPurchaseOrder(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/send_order", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+	order, err := h.purchasing.SendOrder(c.Request.Context(), id)
+	if err != nil {
+		purchaseOrderError(c, "purchasing/send_order", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Purchase order sent successfully", order)
+}
+
+// CancelPurchaseOrder - Admin endpoint to cancel a purchase order nothing has been received against
+func (h *PurchasingHandler) CancelPurchaseOrder(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/cancel_order", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+	order, err := h.purchasing.CancelOrder(c.Request.Context(), id)
+	if err != nil {
+		purchaseOrderError(c, "purchasing/cancel_order", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Purchase order cancelled successfully", order)
+}
+
+// ReceiveGoods - Admin endpoint to book a delivery against a purchase order
+// into stock, with the batch number and expiry date of each line
+func (h *PurchasingHandler) ReceiveGoods(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/receive", "Invalid purchase order ID")
+	if !ok {
+		return
+	}
+	var req purchasing.ReceiptInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "purchasing/receive", err.Error())
+		return
+	}
+
+	receipt, err := h.purchasing.Receive(c.Request.Context(), id, req, getUserIDFromContext(c))
+	if err != nil {
+		purchaseOrderError(c, "purchasing/receive", err)
+		return
+	}
+	response.GenerateCreatedResponse(c, "Goods received successfully", receipt)
+}
+
+func purchaseOrderError(c *gin.Context, code string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, "Purchase order, supplier or warehouse not found")
+	case errors.Is(err, purchasing.ErrInvalidStatus), errors.Is(err, purchasing.ErrInvalidItems), errors.Is(err, purchasing.ErrInactiveSupplier):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to update purchase order")
+	}
+}
+
+func getUserIDFromContext(c *gin.Context) *uint {
+	if userID, exists := c.Get("user_id"); exists {
+		if uid, ok := userID.(uint); ok {
+			return &uid
+		}
+	}
+	return nil
+}
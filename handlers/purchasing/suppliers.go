@@ -0,0 +1,252 @@
+package purchasing
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/purchasing"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateSupplierRequest struct {
+	Name               string `json:"name" binding:"required"`
+	Code               string `json:"code" binding:"required"`
+	VATNumber          string `json:"vat_number"`
+	RegistrationNumber string `json:"registration_number"`
+	Phone              string `json:"phone"`
+	Email              string `json:"email" binding:"omitempty,email"`
+	Website            string `json:"website"`
+	PaymentTerms       *int   `json:"payment_terms" binding:"omitempty,min=0"`
+	LeadTimeDays       int    `json:"lead_time_days" binding:"min=0"`
+	Notes              string `json:"notes"`
+}
+
+type UpdateSupplierRequest struct {
+	Name               *string `json:"name"`
+	VATNumber          *string `json:"vat_number"`
+	RegistrationNumber *string `json:"registration_number"`
+	Phone              *string `json:"phone"`
+	Email              *string `json:"email" binding:"omitempty,email"`
+	Website            *string `json:"website"`
+	PaymentTerms       *int    `json:"payment_terms" binding:"omitempty,min=0"`
+	LeadTimeDays       *int    `json:"lead_time_days" binding:"omitempty,min=0"`
+	Notes              *string `json:"notes"`
+	IsActive           *bool   `json:"is_active"`
+}
+
+// CreateSupplier - Admin endpoint to add a supplier
+func (h *PurchasingHandler) CreateSupplier(c *gin.Context) {
+	var req CreateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "purchasing/create_supplier", err.Error())
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	var count int64
+	h.db.Model(&models.Supplier{}).Where("code = ?", code).Count(&count)
+	if count > 0 {
+		response.GenerateBadRequestResponse(c, "purchasing/create_supplier", "A supplier with this code already exists")
+		return
+	}
+
+	supplier := models.Supplier{
+		Name:               req.Name,
+		Code:               code,
+		VATNumber:          req.VATNumber,
+		RegistrationNumber: req.RegistrationNumber,
+		Phone:              req.Phone,
+		Email:              req.Email,
+		Website:            req.Website,
+		IsActive:           true,
+		PaymentTerms:       30,
+		LeadTimeDays:       req.LeadTimeDays,
+		Notes:              req.Notes,
+	}
+	if req.PaymentTerms != nil {
+		supplier.PaymentTerms = *req.PaymentTerms
+	}
+	if err := h.db.Create(&supplier).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "purchasing/create_supplier", "Failed to create supplier")
+		return
+	}
+	// GORM skips zero values for fields with a default, so persist them explicitly
+	if supplier.PaymentTerms == 0 {
+		h.db.Model(&supplier).Update("payment_terms", 0)
+	}
+
+	response.GenerateCreatedResponse(c, "Supplier created successfully", supplier)
+}
+
+// GetSuppliers - Admin endpoint to list suppliers
+func (h *PurchasingHandler) GetSuppliers(c *gin.Context) {
+	query := h.db.Model(&models.Supplier{})
+	if search := c.Query("search"); search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(code) LIKE ?", like, like)
+	}
+	if active := c.Query("is_active"); active != "" {
+		query = query.Where("is_active = ?", active == "true")
+	}
+
+	var suppliers []models.Supplier
+	if err := query.Order("name ASC").Find(&suppliers).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "purchasing/suppliers", "Failed to get suppliers")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Suppliers retrieved successfully", suppliers)
+}
+
+// GetSupplier - Admin endpoint to get a supplier with its contacts
+func (h *PurchasingHandler) GetSupplier(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/supplier", "Invalid supplier ID")
+	if !ok {
+		return
+	}
+	var supplier models.Supplier
+	if err := h.db.Preload("Contacts").Preload("Address").First(&supplier, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "purchasing/supplier", "Supplier not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Supplier retrieved successfully", supplier)
+}
+
+// UpdateSupplier - Admin endpoint to update a supplier
+func (h *PurchasingHandler) UpdateSupplier(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/update_supplier", "Invalid supplier ID")
+	if !ok {
+		return
+	}
+	var req UpdateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "purchasing/update_supplier", err.Error())
+		return
+	}
+
+	var supplier models.Supplier
+	if err := h.db.First(&supplier, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "purchasing/update_supplier", "Supplier not found")
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.VATNumber != nil {
+		updates["vat_number"] = *req.VATNumber
+	}
+	if req.RegistrationNumber != nil {
+		updates["registration_number"] = *req.RegistrationNumber
+	}
+	if req.Phone != nil {
+		updates["phone"] = *req.Phone
+	}
+	if req.Email != nil {
+		updates["email"] = *req.Email
+	}
+	if req.Website != nil {
+		updates["website"] = *req.Website
+	}
+	if req.PaymentTerms != nil {
+		updates["payment_terms"] = *req.PaymentTerms
+	}
+	if req.LeadTimeDays != nil {
+		updates["lead_time_days"] = *req.LeadTimeDays
+	}
+	if req.Notes != nil {
+		updates["notes"] = *req.Notes
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(&supplier).Updates(updates).Error; err != nil {
+			response.GenerateInternalServerErrorResponse(c, "purchasing/update_supplier", "Failed to update supplier")
+			return
+		}
+	}
+
+	h.db.First(&supplier, supplier.ID)
+	response.GenerateSuccessResponse(c, "Supplier updated successfully", supplier)
+}
+
+// DeleteSupplier - Admin endpoint to delete a supplier without open purchase orders
+func (h *PurchasingHandler) DeleteSupplier(c *gin.Context) {
+	id, ok := parseID(c, "purchasing/delete_supplier", "Invalid supplier ID")
+	if !ok {
+		return
+	}
+	var supplier models.Supplier
+	if err := h.db.First(&supplier, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "purchasing/delete_supplier", "Supplier not found")
+		return
+	}
+
+	var open int64
+	h.db.Model(&models.PurchaseOrder{}).
+		Where("supplier_id = ? AND status IN ?", supplier.ID, []models.POStatus{models.POStatusDraft, models.POStatusSent, models.POStatusPartiallyReceived}).
+		Count(&open)
+	if open > 0 {
+		response.GenerateBadRequestResponse(c, "purchasing/delete_supplier", "Supplier has open purchase orders")
+		return
+	}
+
+	if err := h.db.Delete(&supplier).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "purchasing/delete_supplier", "Failed to delete supplier")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Supplier deleted successfully", nil)
+}
+
+// GetSupplierPerformance - Admin endpoint to report supplier lead times and
+// fill rates for orders sent between from and to (YYYY-MM-DD)
+func (h *PurchasingHandler) GetSupplierPerformance(c *gin.Context) {
+	var filter purchasing.PerformanceFilter
+	if supplierID := c.Query("supplier_id"); supplierID != "" {
+		id, err := strconv.ParseUint(supplierID, 10, 32)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "purchasing/supplier_performance", "Invalid supplier ID")
+			return
+		}
+		filter.SupplierID = uint(id)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "purchasing/supplier_performance", "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "purchasing/supplier_performance", "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+		// Include the whole of the last day
+		t = t.AddDate(0, 0, 1)
+		filter.To = &t
+	}
+
+	reports, err := h.purchasing.SupplierPerformance(c.Request.Context(), filter)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "purchasing/supplier_performance", "Failed to build supplier performance report")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Supplier performance retrieved successfully", reports)
+}
+
+func parseID(c *gin.Context, code, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, message)
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,237 @@
+package quote
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AcceptQuoteRequest struct {
+	ShippingAddressID uint   `json:"shipping_address_id" binding:"required"`
+	PaymentMethod     string `json:"payment_method" binding:"required"`
+	ShippingMethod    string `json:"shipping_method"`
+	CustomerNotes     string `json:"customer_notes"`
+}
+
+// AcceptQuote converts a quoted offer into an order at the quoted prices
+func (h *QuoteHandler) AcceptQuote(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "quote/accept", "Unauthorized")
+		return
+	}
+	uid := userID.(uint)
+
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "quote/accept", "Invalid quote ID")
+		return
+	}
+
+	var req AcceptQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "quote/accept", err.Error())
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Preload("Items.ProductVariant.Product").Where("id = ? AND user_id = ?", quoteID, uid).First(&quote).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "quote/accept", "Quote not found")
+		return
+	}
+	h.expireIfNeeded(&quote)
+	if quote.Status == models.QuoteStatusExpired {
+		response.GenerateBadRequestResponse(c, "quote/accept", "Quote has expired")
+		return
+	}
+	if quote.Status != models.QuoteStatusQuoted {
+		response.GenerateBadRequestResponse(c, "quote/accept", fmt.Sprintf("Cannot accept a quote with status %s", quote.Status))
+		return
+	}
+
+	// Start transaction
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Verify shipping address belongs to user
+	var address models.Address
+	if err := tx.Where("id = ? AND user_id = ?", req.ShippingAddressID, uid).First(&address).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateNotFoundResponse(c, "quote/accept", "Shipping address not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, "quote/accept", "Failed to verify shipping address")
+		}
+		return
+	}
+
+	// Calculate VAT on the quoted prices for the shipping country
+	taxLines := make([]tax.Line, 0, len(quote.Items))
+	for _, item := range quote.Items {
+		taxLines = append(taxLines, tax.Line{
+			ProductVariantID: item.ProductVariantID,
+			IsVAT:            item.ProductVariant.Product.IsVAT,
+			Amount:           item.TotalPrice,
+		})
+	}
+	taxBreakdown, err := h.taxService.Calculate(taxLines, address.Country)
+	if err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "quote/accept", "Failed to calculate tax")
+		return
+	}
+
+	now := time.Now()
+	order := models.Order{
+		OrderNumber:       generateOrderNumber(),
+		UserID:            uid,
+		CompanyID:         quote.CompanyID,
+		Status:            models.OrderStatusPending,
+		PaymentStatus:     models.PaymentStatusPending,
+		TotalAmount:       quote.QuotedAmount,
+		TaxAmount:         taxBreakdown.TaxAmount,
+		TaxCountry:        taxBreakdown.Country,
+		TaxBreakdown:      taxBreakdown.ToJSON(),
+		FinalAmount:       taxBreakdown.GrossAmount,
+		ShippingAddressID: req.ShippingAddressID,
+		ShippingMethod:    req.ShippingMethod,
+		PaymentMethod:     req.PaymentMethod,
+		CustomerNotes:     req.CustomerNotes,
+		AdminNotes:        fmt.Sprintf("Created from quote %s", quote.QuoteNumber),
+		OrderDate:         now,
+	}
+	if err := tx.Create(&order).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "quote/accept", "Failed to create order")
+		return
+	}
+
+	var orderItems []models.OrderItem
+	for i, item := range quote.Items {
+		orderItems = append(orderItems, models.OrderItem{
+			OrderID:          order.ID,
+			ProductVariantID: item.ProductVariantID,
+			Quantity:         item.Quantity,
+			UnitPrice:        item.QuotedUnitPrice,
+			TaxRate:          taxBreakdown.Lines[i].Rate,
+			TaxAmount:        taxBreakdown.Lines[i].TaxAmount,
+			TotalAmount:      item.TotalPrice,
+			Status:           "active",
+		})
+	}
+	if err := tx.Create(&orderItems).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "quote/accept", "Failed to create order items")
+		return
+	}
+
+	// Guard against concurrent accepts by only updating a still-quoted quote
+	result := tx.Model(&models.Quote{}).
+		Where("id = ? AND status = ?", quote.ID, models.QuoteStatusQuoted).
+		Updates(map[string]interface{}{
+			"status":      models.QuoteStatusAccepted,
+			"accepted_at": now,
+			"order_id":    order.ID,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		response.GenerateBadRequestResponse(c, "quote/accept", "Quote is no longer available")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/accept", "Failed to commit transaction")
+		return
+	}
+
+	var completeOrder models.Order
+	if err := h.db.Preload("User").
+		Preload("ShippingAddress").
+		Preload("Items.ProductVariant.Product").
+		First(&completeOrder, order.ID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/accept", "Order created but failed to load details")
+		return
+	}
+
+	if h.emailTriggerSvc != nil {
+		go func() {
+			customerName := fmt.Sprintf("%s %s", completeOrder.User.FirstName, completeOrder.User.LastName)
+			orderData := map[string]interface{}{
+				"order_number":     completeOrder.OrderNumber,
+				"order_date":       completeOrder.OrderDate,
+				"total_amount":     completeOrder.FinalAmount,
+				"tax_amount":       completeOrder.TaxAmount,
+				"tax_breakdown":    completeOrder.TaxBreakdown,
+				"currency":         settings.Current().Currency,
+				"items":            completeOrder.Items,
+				"shipping_address": completeOrder.ShippingAddress,
+			}
+			if err := h.emailTriggerSvc.TriggerOrderConfirmation(completeOrder.ID, completeOrder.User.Email, customerName, orderData); err != nil {
+				fmt.Printf("Failed to send order confirmation email: %v\n", err)
+			}
+
+			quoteData := map[string]interface{}{
+				"quote_number":  quote.QuoteNumber,
+				"order_number":  completeOrder.OrderNumber,
+				"customer_name": customerName,
+				"total_amount":  fmt.Sprintf("%.2f", completeOrder.FinalAmount),
+				"currency":      settings.Current().Currency,
+				"item_count":    len(completeOrder.Items),
+			}
+			if err := h.emailTriggerSvc.TriggerQuoteAdminNotification("quote_accepted", quote.ID, quoteData); err != nil {
+				fmt.Printf("Failed to send quote accepted notification: %v\n", err)
+			}
+		}()
+	}
+
+	response.GenerateCreatedResponse(c, "Quote accepted and order placed successfully", completeOrder)
+}
+
+// DeclineQuote lets the customer decline a quoted offer or cancel a pending request
+func (h *QuoteHandler) DeclineQuote(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "quote/decline", "Unauthorized")
+		return
+	}
+
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "quote/decline", "Invalid quote ID")
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Where("id = ? AND user_id = ?", quoteID, userID.(uint)).First(&quote).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "quote/decline", "Quote not found")
+		return
+	}
+
+	switch quote.Status {
+	case models.QuoteStatusRequested:
+		quote.Status = models.QuoteStatusCancelled
+	case models.QuoteStatusQuoted:
+		quote.Status = models.QuoteStatusDeclined
+	default:
+		response.GenerateBadRequestResponse(c, "quote/decline", fmt.Sprintf("Cannot decline a quote with status %s", quote.Status))
+		return
+	}
+
+	if err := h.db.Model(&quote).Update("status", quote.Status).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/decline", "Failed to update quote")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Quote declined successfully", quote)
+}
@@ -0,0 +1,130 @@
+package quote
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuotes lists the current user's quotes
+func (h *QuoteHandler) GetQuotes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "quote/get_quotes", "Unauthorized")
+		return
+	}
+
+	h.expireStaleQuotes()
+
+	query := h.db.Where("user_id = ?", userID.(uint))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var quotes []models.Quote
+	if err := query.Preload("Items.ProductVariant.Product").Order("created_at DESC").Find(&quotes).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/get_quotes", "Failed to get quotes")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Quotes retrieved successfully", quotes)
+}
+
+// GetQuote returns a single quote owned by the current user
+func (h *QuoteHandler) GetQuote(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "quote/get_quote", "Unauthorized")
+		return
+	}
+
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "quote/get_quote", "Invalid quote ID")
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Preload("Items.ProductVariant.Product").
+		Preload("Items.ProductVariant.Images").
+		Preload("Order").
+		Where("id = ? AND user_id = ?", quoteID, userID.(uint)).
+		First(&quote).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "quote/get_quote", "Quote not found")
+		return
+	}
+	h.expireIfNeeded(&quote)
+
+	response.GenerateSuccessResponse(c, "Quote retrieved successfully", quote)
+}
+
+// GetAllQuotes - Admin endpoint to list all quotes with filtering and pagination
+func (h *QuoteHandler) GetAllQuotes(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	h.expireStaleQuotes()
+
+	query := h.db.Model(&models.Quote{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var quotes []models.Quote
+	if err := query.Preload("User").
+		Preload("Items.ProductVariant.Product").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&quotes).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/get_all_quotes", "Failed to get quotes")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Quotes retrieved successfully", map[string]interface{}{
+		"quotes":      quotes,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"total_pages": (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// GetQuoteByID - Admin endpoint to get any quote
+func (h *QuoteHandler) GetQuoteByID(c *gin.Context) {
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "quote/get_quote_by_id", "Invalid quote ID")
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Preload("User").
+		Preload("Company").
+		Preload("RespondedBy").
+		Preload("Items.ProductVariant.Product").
+		Preload("Items.ProductVariant.PriceTiers").
+		Preload("Order").
+		First(&quote, quoteID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "quote/get_quote_by_id", "Quote not found")
+		return
+	}
+	h.expireIfNeeded(&quote)
+
+	response.GenerateSuccessResponse(c, "Quote retrieved successfully", quote)
+}
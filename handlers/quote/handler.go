@@ -0,0 +1,58 @@
+package quote
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"gorm.io/gorm"
+)
+
+type QuoteHandler struct {
+	db              *gorm.DB
+	emailTriggerSvc *email.EmailTriggerService
+	priceResolver   *pricing.Resolver
+	taxService      *tax.TaxService
+}
+
+func NewQuoteHandler(db *gorm.DB, emailTriggerSvc *email.EmailTriggerService, taxService *tax.TaxService) *QuoteHandler {
+	return &QuoteHandler{
+		db:              db,
+		emailTriggerSvc: emailTriggerSvc,
+		priceResolver:   pricing.NewResolver(db),
+		taxService:      taxService,
+	}
+}
+
+// expireIfNeeded marks a quoted offer as expired once its validity window has passed
+func (h *QuoteHandler) expireIfNeeded(quote *models.Quote) {
+	if quote.IsExpired(time.Now()) {
+		quote.Status = models.QuoteStatusExpired
+		h.db.Model(&models.Quote{}).Where("id = ?", quote.ID).Update("status", models.QuoteStatusExpired)
+	}
+}
+
+// expireStaleQuotes marks every quoted offer past its validity window as expired
+func (h *QuoteHandler) expireStaleQuotes() {
+	h.db.Model(&models.Quote{}).
+		Where("status = ? AND valid_until IS NOT NULL AND valid_until < ?", models.QuoteStatusQuoted, time.Now()).
+		Update("status", models.QuoteStatusExpired)
+}
+
+func generateQuoteNumber() string {
+	now := time.Now()
+	return fmt.Sprintf("QT-%d%02d%02d-%d",
+		now.Year(), now.Month(), now.Day(),
+		now.UnixNano()%100000)
+}
+
+func generateOrderNumber() string {
+	// Generate order number with timestamp
+	now := time.Now()
+	return fmt.Sprintf("ORD-%d%02d%02d-%d",
+		now.Year(), now.Month(), now.Day(),
+		now.Unix()%10000) // Last 4 digits of timestamp for uniqueness
+}
@@ -0,0 +1,102 @@
+package quote
+
+import (
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type RequestQuoteRequest struct {
+	CustomerNotes string `json:"customer_notes"`
+}
+
+// RequestQuote creates a quote request from the B2B customer's current cart
+func (h *QuoteHandler) RequestQuote(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "quote/request", "Unauthorized")
+		return
+	}
+	uid := userID.(uint)
+
+	var req RequestQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "quote/request", err.Error())
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, uid).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "quote/request", "User not found")
+		return
+	}
+	if user.UserType != models.Wholesaler && user.CompanyID == nil {
+		response.GenerateForbiddenResponse(c, "quote/request", "Quotes are only available to B2B customers")
+		return
+	}
+
+	var cart models.Cart
+	if err := h.db.Preload("Items.ProductVariant.Product").
+		Preload("Items.ProductVariant.PriceTiers").
+		Where("user_id = ?", uid).
+		First(&cart).Error; err != nil || len(cart.Items) == 0 {
+		response.GenerateBadRequestResponse(c, "quote/request", "Cart is empty")
+		return
+	}
+
+	priceList, err := h.priceResolver.ActivePriceList(uid)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/request", "Failed to resolve prices")
+		return
+	}
+
+	quote := models.Quote{
+		QuoteNumber:   generateQuoteNumber(),
+		UserID:        uid,
+		CompanyID:     user.CompanyID,
+		Status:        models.QuoteStatusRequested,
+		CustomerNotes: req.CustomerNotes,
+	}
+	for _, item := range cart.Items {
+		if item.ProductVariant == nil || !item.ProductVariant.IsActive {
+			response.GenerateBadRequestResponse(c, "quote/request", fmt.Sprintf("Cart item %d is no longer available", item.ID))
+			return
+		}
+		listPrice := pricing.UnitPriceWithList(priceList, item.ProductVariant, item.Quantity, "b2b")
+		total := float64(item.Quantity) * listPrice
+		quote.Items = append(quote.Items, models.QuoteItem{
+			ProductVariantID: item.ProductVariantID,
+			Quantity:         item.Quantity,
+			ListUnitPrice:    listPrice,
+			TotalPrice:       total,
+		})
+		quote.RequestedAmount += total
+	}
+
+	if err := h.db.Create(&quote).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/request", "Failed to create quote")
+		return
+	}
+
+	if h.emailTriggerSvc != nil {
+		go func() {
+			quoteData := map[string]interface{}{
+				"quote_number":  quote.QuoteNumber,
+				"customer_name": fmt.Sprintf("%s %s", user.FirstName, user.LastName),
+				"total_amount":  fmt.Sprintf("%.2f", quote.RequestedAmount),
+				"currency":      settings.Current().Currency,
+				"item_count":    len(quote.Items),
+			}
+			if err := h.emailTriggerSvc.TriggerQuoteAdminNotification("quote_request", quote.ID, quoteData); err != nil {
+				fmt.Printf("Failed to send quote request notification: %v\n", err)
+			}
+		}()
+	}
+
+	h.db.Preload("Items.ProductVariant.Product").First(&quote, quote.ID)
+	response.GenerateCreatedResponse(c, "Quote requested successfully", quote)
+}
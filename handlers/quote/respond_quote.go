@@ -0,0 +1,177 @@
+package quote
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type QuoteItemPrice struct {
+	QuoteItemID uint    `json:"quote_item_id" binding:"required"`
+	UnitPrice   float64 `json:"unit_price" binding:"gte=0"`
+}
+
+type RespondQuoteRequest struct {
+	Items      []QuoteItemPrice `json:"items"`
+	ValidUntil *time.Time       `json:"valid_until"` // defaults to ValidDays from now
+	ValidDays  int              `json:"valid_days"`  // used when valid_until is omitted, defaults to 14
+	AdminNotes string           `json:"admin_notes"`
+	Reject     bool             `json:"reject"` // refuse to quote instead of pricing the request
+}
+
+// RespondQuote - Admin endpoint to price a quote request (or reject it) and set its validity window
+func (h *QuoteHandler) RespondQuote(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "quote/respond", "Unauthorized")
+		return
+	}
+	aid := adminID.(uint)
+
+	quoteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "quote/respond", "Invalid quote ID")
+		return
+	}
+
+	var req RespondQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "quote/respond", err.Error())
+		return
+	}
+
+	var quote models.Quote
+	if err := h.db.Preload("User").Preload("Items.ProductVariant").First(&quote, quoteID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "quote/respond", "Quote not found")
+		return
+	}
+	h.expireIfNeeded(&quote)
+
+	if quote.Status != models.QuoteStatusRequested && quote.Status != models.QuoteStatusQuoted && quote.Status != models.QuoteStatusExpired {
+		response.GenerateBadRequestResponse(c, "quote/respond", fmt.Sprintf("Cannot respond to a quote with status %s", quote.Status))
+		return
+	}
+
+	now := time.Now()
+	quote.RespondedByID = &aid
+	quote.RespondedAt = &now
+	quote.AdminNotes = req.AdminNotes
+
+	tx := h.db.Begin()
+	if req.Reject {
+		quote.Status = models.QuoteStatusRejected
+		quote.ValidUntil = nil
+	} else {
+		validUntil := req.ValidUntil
+		if validUntil == nil {
+			days := req.ValidDays
+			if days <= 0 {
+				days = 14
+			}
+			v := now.AddDate(0, 0, days)
+			validUntil = &v
+		}
+		if !validUntil.After(now) {
+			tx.Rollback()
+			response.GenerateBadRequestResponse(c, "quote/respond", "valid_until must be in the future")
+			return
+		}
+
+		prices := make(map[uint]float64, len(req.Items))
+		for _, item := range req.Items {
+			prices[item.QuoteItemID] = item.UnitPrice
+		}
+
+		quote.QuotedAmount = 0
+		for i := range quote.Items {
+			item := &quote.Items[i]
+			unitPrice, ok := prices[item.ID]
+			if !ok {
+				unitPrice = item.ListUnitPrice
+			}
+			delete(prices, item.ID)
+			item.QuotedUnitPrice = unitPrice
+			item.TotalPrice = float64(item.Quantity) * unitPrice
+			quote.QuotedAmount += item.TotalPrice
+			if err := tx.Model(&models.QuoteItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"quoted_unit_price": item.QuotedUnitPrice,
+				"total_price":       item.TotalPrice,
+			}).Error; err != nil {
+				tx.Rollback()
+				response.GenerateInternalServerErrorResponse(c, "quote/respond", "Failed to update quote items")
+				return
+			}
+		}
+		if len(prices) > 0 {
+			tx.Rollback()
+			response.GenerateBadRequestResponse(c, "quote/respond", "One or more quote items do not belong to this quote")
+			return
+		}
+
+		quote.Status = models.QuoteStatusQuoted
+		quote.ValidUntil = validUntil
+	}
+
+	if err := tx.Model(&models.Quote{}).Where("id = ?", quote.ID).Updates(map[string]interface{}{
+		"status":          quote.Status,
+		"quoted_amount":   quote.QuotedAmount,
+		"admin_notes":     quote.AdminNotes,
+		"responded_by_id": quote.RespondedByID,
+		"responded_at":    quote.RespondedAt,
+		"valid_until":     quote.ValidUntil,
+	}).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "quote/respond", "Failed to update quote")
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "quote/respond", "Failed to commit transaction")
+		return
+	}
+
+	if h.emailTriggerSvc != nil {
+		go h.sendQuoteResponseEmail(quote)
+	}
+
+	h.db.Preload("User").Preload("Items.ProductVariant.Product").First(&quote, quote.ID)
+	response.GenerateSuccessResponse(c, "Quote response saved successfully", quote)
+}
+
+func (h *QuoteHandler) sendQuoteResponseEmail(quote models.Quote) {
+	userName := strings.TrimSpace(quote.User.FirstName + " " + quote.User.LastName)
+	items := make([]map[string]interface{}, 0, len(quote.Items))
+	for _, item := range quote.Items {
+		items = append(items, map[string]interface{}{
+			"Name":      item.ProductVariant.Name,
+			"Quantity":  item.Quantity,
+			"UnitPrice": item.QuotedUnitPrice,
+			"Total":     item.TotalPrice,
+		})
+	}
+	validUntil := ""
+	if quote.ValidUntil != nil {
+		validUntil = quote.ValidUntil.Format("January 2, 2006")
+	}
+	data := map[string]interface{}{
+		"UserName":      userName,
+		"QuoteNumber":   quote.QuoteNumber,
+		"Status":        string(quote.Status),
+		"ValidUntil":    validUntil,
+		"Items":         items,
+		"QuotedAmount":  quote.QuotedAmount,
+		"Currency":      settings.Current().Currency,
+		"AdminNoteHTML": template.HTML(template.HTMLEscapeString(quote.AdminNotes)),
+		"QuoteURL":      settings.Current().URL("/account/quotes/%d", quote.ID),
+		"subject":       fmt.Sprintf("Your quote %s has been updated", quote.QuoteNumber),
+	}
+	if err := h.emailTriggerSvc.TriggerQuoteResponded(quote.User.Email, userName, data); err != nil {
+		fmt.Printf("Failed to send quote response email: %v\n", err)
+	}
+}
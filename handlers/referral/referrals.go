@@ -0,0 +1,96 @@
+package referral
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/referral"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetMyReferrals - Customer endpoint for their referral code, share link and
+// conversions
+func (h *ReferralHandler) GetMyReferrals(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == nil {
+		response.GenerateUnauthorizedResponse(c, "referral/dashboard", "User not authenticated")
+		return
+	}
+	dashboard, err := h.referrals.Dashboard(*userID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "referral/dashboard", "Failed to get referrals")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Referrals retrieved successfully", dashboard)
+}
+
+// GetMyCoupons - Customer endpoint for their unused coupons
+func (h *ReferralHandler) GetMyCoupons(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == nil {
+		response.GenerateUnauthorizedResponse(c, "referral/coupons", "User not authenticated")
+		return
+	}
+	coupons, err := h.referrals.Coupons(*userID)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "referral/coupons", "Failed to get coupons")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Coupons retrieved successfully", coupons)
+}
+
+// ListReferrals - Admin endpoint to list referrals, e.g. the rejected ones
+// waiting for review
+func (h *ReferralHandler) ListReferrals(c *gin.Context) {
+	page, pageSize := pagination(c)
+	query := h.db.Model(&models.Referral{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if referrerID := c.Query("referrer_id"); referrerID != "" {
+		query = query.Where("referrer_id = ?", referrerID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "referral/list", "Failed to count referrals")
+		return
+	}
+	var referrals []models.Referral
+	if err := query.Preload("Referrer").Preload("Referee").
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&referrals).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "referral/list", "Failed to get referrals")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Referrals retrieved successfully", map[string]interface{}{
+		"data":      referrals,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ApproveReferral - Admin endpoint to clear a referral rejected by the
+// anti-abuse checks
+func (h *ReferralHandler) ApproveReferral(c *gin.Context) {
+	id, ok := parseID(c, "referral/approve", "Invalid referral ID")
+	if !ok {
+		return
+	}
+	approved, err := h.referrals.Approve(id, getUserIDFromContext(c))
+	switch {
+	case err == nil:
+		response.GenerateSuccessResponse(c, "Referral approved successfully", approved)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, "referral/approve", "Referral not found")
+	case errors.Is(err, referral.ErrNotRejected):
+		response.GenerateBadRequestResponse(c, "referral/approve", err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, "referral/approve", "Failed to approve referral")
+	}
+}
@@ -1,11 +1,14 @@
 package review
 
 import (
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,10 +18,13 @@ import (
 type ModerationRequest struct {
 	Status models.ReviewStatus `json:"status" binding:"required"`
 	Reason string              `json:"reason" binding:"required,max=500"`
+	// IssueStrike gives the review's author a strike when rejecting or flagging
+	IssueStrike bool `json:"issue_strike"`
 }
 
 // GetAllReviews handles GET /api/v1/admin/reviews
 func (h *ReviewHandler) GetAllReviews(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
@@ -37,7 +43,7 @@ func (h *ReviewHandler) GetAllReviews(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Build query
-	query := h.db.Model(&models.ProductReview{}).
+	query := db.Model(&models.ProductReview{}).
 		Preload("User").
 		Preload("ProductVariant.Product").
 		Preload("Images").
@@ -124,6 +130,10 @@ func (h *ReviewHandler) ModerateReview(c *gin.Context) {
 		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_STATUS", "Invalid review status")
 		return
 	}
+	if req.IssueStrike && req.Status == models.ReviewStatusApproved {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "A strike can only be issued when rejecting or flagging a review")
+		return
+	}
 
 	// Find the review
 	var review models.ProductReview
@@ -165,24 +175,39 @@ func (h *ReviewHandler) ModerateReview(c *gin.Context) {
 	}
 	h.db.Create(&moderationLog)
 
-	// Update rating aggregation if status changed to/from approved
-	if (oldStatus == models.ReviewStatusApproved && req.Status != models.ReviewStatusApproved) ||
-		(oldStatus != models.ReviewStatusApproved && req.Status == models.ReviewStatusApproved) {
-		if err := h.UpdateProductRating(review.ProductVariantID); err != nil {
-			// Log the error but don't fail the request
-			// TODO: Add proper logging
+	if req.IssueStrike {
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			_, err := h.moderation.IssueStrike(tx, moderation.StrikeInput{
+				UserID:   review.UserID,
+				Source:   models.StrikeSourceReviewModeration,
+				ReviewID: &review.ID,
+				Reason:   req.Reason,
+				IssuedBy: adminIDUint,
+			})
+			return err
+		})
+		if err != nil {
+			response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Review moderated but the strike could not be issued")
+			return
 		}
 	}
 
+	// Update rating aggregation if status changed to/from approved
+	if err := h.ApplyRatingChange(review.ProductVariantID, ratings.Counted(oldStatus, review.Rating), ratings.Counted(req.Status, review.Rating)); err != nil {
+		// Log the error but don't fail the request
+		slog.ErrorContext(c.Request.Context(), "failed to update product rating", "component", "review", "product_variant_id", review.ProductVariantID, "error", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Review moderated successfully",
 		"data": gin.H{
-			"review_id":    review.ID,
-			"old_status":   oldStatus,
-			"new_status":   req.Status,
-			"moderated_by": adminID,
-			"moderated_at": review.ModeratedAt,
+			"review_id":     review.ID,
+			"old_status":    oldStatus,
+			"new_status":    req.Status,
+			"moderated_by":  adminID,
+			"moderated_at":  review.ModeratedAt,
+			"strike_issued": req.IssueStrike,
 		},
 	})
 }
@@ -242,9 +267,9 @@ func (h *ReviewHandler) AdminDeleteReview(c *gin.Context) {
 	h.db.Create(&deletionLog)
 
 	// Update rating aggregation
-	if err := h.UpdateProductRating(productVariantID); err != nil {
+	if err := h.ApplyRatingChange(productVariantID, ratings.Counted(review.Status, review.Rating), 0); err != nil {
 		// Log the error but don't fail the request
-		// TODO: Add proper logging
+		slog.ErrorContext(c.Request.Context(), "failed to update product rating", "component", "review", "product_variant_id", productVariantID, "error", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -260,6 +285,7 @@ func (h *ReviewHandler) AdminDeleteReview(c *gin.Context) {
 
 // GetModerationStats handles GET /api/v1/admin/reviews/stats
 func (h *ReviewHandler) GetModerationStats(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
 	// Get review counts by status
 	var stats struct {
 		Total    int64 `json:"total"`
@@ -270,16 +296,16 @@ func (h *ReviewHandler) GetModerationStats(c *gin.Context) {
 		Deleted  int64 `json:"deleted"`
 	}
 
-	h.db.Model(&models.ProductReview{}).Count(&stats.Total)
-	h.db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusPending).Count(&stats.Pending)
-	h.db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusApproved).Count(&stats.Approved)
-	h.db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusRejected).Count(&stats.Rejected)
-	h.db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusFlagged).Count(&stats.Flagged)
-	h.db.Unscoped().Model(&models.ProductReview{}).Where("deleted_at IS NOT NULL").Count(&stats.Deleted)
+	db.Model(&models.ProductReview{}).Count(&stats.Total)
+	db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusPending).Count(&stats.Pending)
+	db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusApproved).Count(&stats.Approved)
+	db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusRejected).Count(&stats.Rejected)
+	db.Model(&models.ProductReview{}).Where("status = ?", models.ReviewStatusFlagged).Count(&stats.Flagged)
+	db.Unscoped().Model(&models.ProductReview{}).Where("deleted_at IS NOT NULL").Count(&stats.Deleted)
 
 	// Get recent moderation activity (last 7 days)
 	var recentModerations []models.ReviewModerationLog
-	h.db.Preload("Review").
+	db.Preload("Review").
 		Preload("Admin").
 		Where("moderated_at >= ?", time.Now().AddDate(0, 0, -7)).
 		Order("moderated_at DESC").
@@ -11,12 +11,13 @@ import (
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetAllReviews(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	admin := createTestUser(db, models.Admin)
 	customer1 := createTestUser(db, models.Customer)
@@ -130,7 +131,7 @@ func TestGetAllReviews(t *testing.T) {
 func TestModerateReview(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	admin := createTestUser(db, models.Admin)
 	customer := createTestUser(db, models.Customer)
@@ -199,6 +200,37 @@ func TestModerateReview(t *testing.T) {
 		assert.Equal(t, models.ReviewStatusRejected, updatedReview.Status)
 	})
 
+	t.Run("Success - Reject review with a strike", func(t *testing.T) {
+		reviewToReject := createTestReview(t, db, customer.ID, productVariant.ID, 1, "Spam", "Visit my shop")
+
+		requestBody := ModerationRequest{
+			Status:      models.ReviewStatusRejected,
+			Reason:      "Advertising",
+			IssueStrike: true,
+		}
+		body, _ := json.Marshal(requestBody)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest("PUT", "/api/v1/admin/reviews/"+strconv.FormatUint(uint64(reviewToReject.ID), 10)+"/moderate", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(reviewToReject.ID), 10)}}
+		c.Set("user_id", admin.ID)
+
+		handler.ModerateReview(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var strike models.UserStrike
+		require.NoError(t, db.Where("user_id = ?", customer.ID).First(&strike).Error)
+		assert.Equal(t, models.StrikeSourceReviewModeration, strike.Source)
+		require.NotNil(t, strike.ReviewID)
+		assert.Equal(t, reviewToReject.ID, *strike.ReviewID)
+
+		var author models.User
+		require.NoError(t, db.First(&author, customer.ID).Error)
+		assert.Equal(t, 1, author.StrikeCount)
+	})
+
 	t.Run("Error - Invalid status", func(t *testing.T) {
 		requestBody := ModerationRequest{
 			Status: "INVALID_STATUS",
@@ -247,7 +279,7 @@ func TestModerateReview(t *testing.T) {
 func TestAdminDeleteReview(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	admin := createTestUser(db, models.Admin)
 	customer := createTestUser(db, models.Customer)
@@ -297,7 +329,7 @@ func TestAdminDeleteReview(t *testing.T) {
 func TestGetModerationStats(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	admin := createTestUser(db, models.Admin)
 	customer1 := createTestUser(db, models.Customer)
@@ -2,9 +2,13 @@ package review
 
 import (
 	"fmt"
+	"log"
 	"strconv"
 
+	"github.com/YasserCherfaoui/MarketProGo/files"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -61,40 +65,19 @@ func (h *ReviewHandler) CreateReview(c *gin.Context) {
 		return
 	}
 
-	// Verify purchase
-	var purchaseResult *PurchaseVerificationResult
-	var err error
-
-	if req.OrderItemID != nil {
-		// Verify purchase using specific order item
-		purchaseResult, err = h.VerifyPurchaseWithOrderItemID(userID, *req.OrderItemID)
-	} else {
-		// Verify purchase using product variant
-		purchaseResult, err = h.VerifyPurchase(userID, req.ProductVariantID)
-	}
-
+	// Check the user can review this product variant
+	eligibility, err := h.CheckReviewEligibility(userID, req.ProductVariantID, req.OrderItemID)
 	if err != nil {
 		GenerateReviewInternalServerErrorResponse(c, NewReviewError("DATABASE_ERROR", "Failed to verify purchase"))
 		return
 	}
 
-	if !purchaseResult.IsVerified {
-		GenerateReviewForbiddenResponse(c, NewReviewError(ErrReviewPurchaseRequired, purchaseResult.ErrorMessage))
-		return
-	}
-
-	// Check if user has already reviewed this product variant
-	var existingReview models.ProductReview
-	err = h.db.Where("user_id = ? AND product_variant_id = ?", userID, req.ProductVariantID).
-		First(&existingReview).Error
-
-	if err == nil {
-		GenerateReviewConflictResponse(c, NewReviewError(ErrReviewAlreadyExists))
-		return
-	}
-
-	if err != gorm.ErrRecordNotFound {
-		GenerateReviewInternalServerErrorResponse(c, NewReviewError("DATABASE_ERROR", "Failed to check existing review"))
+	if !eligibility.CanReview {
+		if eligibility.Reason == ErrReviewAlreadyExists {
+			GenerateReviewConflictResponse(c, NewReviewError(ErrReviewAlreadyExists))
+			return
+		}
+		GenerateReviewForbiddenResponse(c, NewReviewError(eligibility.Reason))
 		return
 	}
 
@@ -127,7 +110,7 @@ func (h *ReviewHandler) CreateReview(c *gin.Context) {
 	review := &models.ProductReview{
 		ProductVariantID:   req.ProductVariantID,
 		UserID:             userID,
-		OrderItemID:        req.OrderItemID,
+		OrderItemID:        eligibility.OrderItemID,
 		Rating:             req.Rating,
 		Title:              req.Title,
 		Content:            req.Content,
@@ -158,7 +141,7 @@ func (h *ReviewHandler) CreateReview(c *gin.Context) {
 	})
 
 	// Trigger rating aggregation (if review is auto-approved, otherwise will be triggered on moderation)
-	_ = h.UpdateProductRating(req.ProductVariantID)
+	_ = h.ApplyRatingChange(req.ProductVariantID, 0, ratings.Counted(review.Status, review.Rating))
 }
 
 // UploadReviewImages handles POST /api/v1/reviews/upload-images
@@ -177,14 +160,14 @@ func (h *ReviewHandler) UploadReviewImages(c *gin.Context) {
 		return
 	}
 
-	files := c.Request.MultipartForm.File["images"]
-	if len(files) == 0 {
+	images := c.Request.MultipartForm.File["images"]
+	if len(images) == 0 {
 		response.GenerateBadRequestResponse(c, "review/upload-images", "No images provided")
 		return
 	}
 
 	// Validate number of files (max 5)
-	if len(files) > 5 {
+	if len(images) > 5 {
 		response.GenerateBadRequestResponse(c, "review/upload-images", "Maximum 5 images allowed")
 		return
 	}
@@ -192,7 +175,7 @@ func (h *ReviewHandler) UploadReviewImages(c *gin.Context) {
 	var uploadedImages []string
 
 	// Process each uploaded file
-	for _, fileHeader := range files {
+	for _, fileHeader := range images {
 		// Validate file size (max 5MB per file)
 		if fileHeader.Size > 5<<20 { // 5MB
 			response.GenerateBadRequestResponse(c, "review/upload-images", "File size too large (max 5MB per file)")
@@ -213,8 +196,12 @@ func (h *ReviewHandler) UploadReviewImages(c *gin.Context) {
 			return
 		}
 
-		// Construct the file URL
+		// Construct the file URL, and track the file so it is deleted if no
+		// review ever uses it
 		fileURL := h.appwriteService.GetFileURL(fileID)
+		if err := files.Record(h.db, storage.BackendAppwrite, fileID, fileURL, files.SourceReviewImage); err != nil {
+			log.Printf("Failed to track review image %s: %v", fileID, err)
+		}
 		uploadedImages = append(uploadedImages, fileURL)
 	}
 
@@ -52,9 +52,13 @@ func setupTestDBWithReviewTables(t *testing.T) *gorm.DB {
 		&models.ProductReview{},
 		&models.ReviewImage{},
 		&models.SellerResponse{},
+		&models.ReviewReply{},
 		&models.ReviewHelpful{},
 		&models.ProductRating{},
+		&models.ProductOverallRating{},
 		&models.ReviewModerationLog{},
+		&models.UserStrike{},
+		&models.UserRestriction{},
 	)
 	require.NoError(t, err)
 
@@ -69,7 +73,7 @@ func setupTestDBWithReviewTables(t *testing.T) *gorm.DB {
 func TestCreateReview(t *testing.T) {
 	// Setup
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 	gin.SetMode(gin.TestMode)
 
 	tests := []struct {
@@ -305,7 +309,7 @@ func TestUploadReviewImages(t *testing.T) {
 	db := setupTestDB(t)
 	// Create a mock Appwrite service for testing
 	mockAppwriteService := &aw.AppwriteService{}
-	handler := NewReviewHandler(db, mockAppwriteService)
+	handler := NewReviewHandler(db, mockAppwriteService, nil)
 	gin.SetMode(gin.TestMode)
 
 	t.Run("successful image upload", func(t *testing.T) {
@@ -364,7 +368,7 @@ func TestUploadReviewImages(t *testing.T) {
 func TestGetReviewableProducts(t *testing.T) {
 	// Setup
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 	gin.SetMode(gin.TestMode)
 
 	t.Run("successful retrieval", func(t *testing.T) {
@@ -0,0 +1,124 @@
+package review
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReviewEligibility tells whether a user can review a product variant and,
+// when they can, which order item the review should be attached to
+type ReviewEligibility struct {
+	ProductVariantID uint   `json:"product_variant_id"`
+	CanReview        bool   `json:"can_review"`
+	Reason           string `json:"reason,omitempty"` // error code when the user cannot review
+	Message          string `json:"message,omitempty"`
+	OrderItemID      *uint  `json:"order_item_id,omitempty"`
+	OrderID          *uint  `json:"order_id,omitempty"`
+	ReviewID         *uint  `json:"review_id,omitempty"` // the user's existing review
+}
+
+// ineligible returns the eligibility of a user who cannot review for the
+// reason code
+func ineligible(productVariantID uint, code string) *ReviewEligibility {
+	return &ReviewEligibility{
+		ProductVariantID: productVariantID,
+		Reason:           code,
+		Message:          errorMessages[code],
+	}
+}
+
+// CheckReviewEligibility checks whether a user can review a product variant:
+// they must not be banned or restricted from reviewing, must not have reviewed it already, and must have a
+// paid purchase of it delivered in the last 2 years. When orderItemID is set
+// that order item must be the purchase.
+func (h *ReviewHandler) CheckReviewEligibility(userID, productVariantID uint, orderItemID *uint) (*ReviewEligibility, error) {
+	var banned int64
+	if err := h.db.Model(&models.User{}).Where("id = ? AND is_active = ?", userID, false).Count(&banned).Error; err != nil {
+		return nil, fmt.Errorf("failed to check reviewer: %w", err)
+	}
+	if banned > 0 {
+		return ineligible(productVariantID, ErrReviewerBanned), nil
+	}
+	restriction, err := moderation.ActiveRestriction(h.db, userID, models.CapabilityReview)
+	if err != nil {
+		return nil, err
+	}
+	if restriction != nil {
+		return ineligible(productVariantID, ErrReviewerBanned), nil
+	}
+
+	var existingReview models.ProductReview
+	err = h.db.Where("user_id = ? AND product_variant_id = ?", userID, productVariantID).First(&existingReview).Error
+	if err == nil {
+		eligibility := ineligible(productVariantID, ErrReviewAlreadyExists)
+		eligibility.ReviewID = &existingReview.ID
+		return eligibility, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to check existing review: %w", err)
+	}
+
+	var purchase *PurchaseVerificationResult
+	if orderItemID != nil {
+		purchase, err = h.VerifyPurchaseWithOrderItemID(userID, *orderItemID)
+	} else {
+		purchase, err = h.VerifyPurchase(userID, productVariantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !purchase.IsVerified {
+		return ineligible(productVariantID, purchase.ErrorCode), nil
+	}
+	if purchase.OrderItem.ProductVariantID != productVariantID {
+		return ineligible(productVariantID, ErrReviewPurchaseRequired), nil
+	}
+
+	return &ReviewEligibility{
+		ProductVariantID: productVariantID,
+		CanReview:        true,
+		OrderItemID:      &purchase.OrderItem.ID,
+		OrderID:          &purchase.OrderItem.OrderID,
+	}, nil
+}
+
+// GetReviewEligibility handles GET /api/v1/products/:id/review-eligibility
+// Tells the authenticated user whether they can review a product variant
+func (h *ReviewHandler) GetReviewEligibility(c *gin.Context) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "review/eligibility", "user not authenticated")
+		return
+	}
+	userID := userIDInterface.(uint)
+
+	productVariantID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateErrorResponse(c, 400, "INVALID_PRODUCT_VARIANT_ID", "Invalid product variant ID")
+		return
+	}
+
+	var productVariant models.ProductVariant
+	if err := h.db.Select("id").First(&productVariant, productVariantID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			GenerateReviewNotFoundResponse(c, NewReviewError(ErrProductVariantNotFound))
+			return
+		}
+		GenerateReviewInternalServerErrorResponse(c, NewReviewError("DATABASE_ERROR", "Failed to verify product variant"))
+		return
+	}
+
+	eligibility, err := h.CheckReviewEligibility(userID, productVariant.ID, nil)
+	if err != nil {
+		GenerateReviewInternalServerErrorResponse(c, NewReviewError("DATABASE_ERROR", "Failed to check review eligibility"))
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Review eligibility retrieved successfully", eligibility)
+}
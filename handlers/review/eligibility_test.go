@@ -0,0 +1,164 @@
+package review
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReviewEligibility(t *testing.T) {
+	db := setupTestDB(t)
+	handler := &ReviewHandler{db: db}
+
+	user := createTestUser(db, models.Customer)
+	product := createTestProduct(db)
+	variant := createTestProductVariant(db, product.ID)
+	otherVariant := createTestProductVariant(db, product.ID)
+
+	deliveredDate := time.Now().AddDate(0, -1, 0)
+	order := createTestOrder(db, user.ID, models.OrderStatusDelivered, &deliveredDate)
+	orderItem := createTestOrderItem(db, order.ID, variant.ID)
+
+	t.Run("Eligible with the order item to attach", func(t *testing.T) {
+		result, err := handler.CheckReviewEligibility(user.ID, variant.ID, nil)
+		require.NoError(t, err)
+		assert.True(t, result.CanReview)
+		require.NotNil(t, result.OrderItemID)
+		assert.Equal(t, orderItem.ID, *result.OrderItemID)
+		require.NotNil(t, result.OrderID)
+		assert.Equal(t, order.ID, *result.OrderID)
+	})
+
+	t.Run("Not purchased", func(t *testing.T) {
+		result, err := handler.CheckReviewEligibility(user.ID, otherVariant.ID, nil)
+		require.NoError(t, err)
+		assert.False(t, result.CanReview)
+		assert.Equal(t, ErrReviewPurchaseRequired, result.Reason)
+	})
+
+	t.Run("Order item of another variant", func(t *testing.T) {
+		result, err := handler.CheckReviewEligibility(user.ID, otherVariant.ID, &orderItem.ID)
+		require.NoError(t, err)
+		assert.False(t, result.CanReview)
+		assert.Equal(t, ErrReviewPurchaseRequired, result.Reason)
+	})
+
+	t.Run("Not delivered yet", func(t *testing.T) {
+		shipped := createTestOrder(db, user.ID, models.OrderStatusShipped, nil)
+		createTestOrderItem(db, shipped.ID, otherVariant.ID)
+
+		result, err := handler.CheckReviewEligibility(user.ID, otherVariant.ID, nil)
+		require.NoError(t, err)
+		assert.False(t, result.CanReview)
+		assert.Equal(t, ErrReviewPurchaseRequired, result.Reason)
+	})
+
+	t.Run("Purchase too old", func(t *testing.T) {
+		oldVariant := createTestProductVariant(db, product.ID)
+		oldDate := time.Now().AddDate(-3, 0, 0)
+		oldOrder := createTestOrder(db, user.ID, models.OrderStatusDelivered, &oldDate)
+		createTestOrderItem(db, oldOrder.ID, oldVariant.ID)
+
+		result, err := handler.CheckReviewEligibility(user.ID, oldVariant.ID, nil)
+		require.NoError(t, err)
+		assert.False(t, result.CanReview)
+		assert.Equal(t, ErrPurchaseTooOld, result.Reason)
+	})
+
+	t.Run("Already reviewed", func(t *testing.T) {
+		review := models.ProductReview{ProductVariantID: variant.ID, UserID: user.ID, Rating: 4, Status: models.ReviewStatusApproved}
+		require.NoError(t, db.Create(&review).Error)
+
+		result, err := handler.CheckReviewEligibility(user.ID, variant.ID, nil)
+		require.NoError(t, err)
+		assert.False(t, result.CanReview)
+		assert.Equal(t, ErrReviewAlreadyExists, result.Reason)
+		require.NotNil(t, result.ReviewID)
+		assert.Equal(t, review.ID, *result.ReviewID)
+	})
+
+	t.Run("Banned", func(t *testing.T) {
+		banned := createTestUser(db, models.Customer)
+		require.NoError(t, db.Model(&banned).Update("is_active", false).Error)
+		bannedOrder := createTestOrder(db, banned.ID, models.OrderStatusDelivered, &deliveredDate)
+		createTestOrderItem(db, bannedOrder.ID, variant.ID)
+
+		result, err := handler.CheckReviewEligibility(banned.ID, variant.ID, nil)
+		require.NoError(t, err)
+		assert.False(t, result.CanReview)
+		assert.Equal(t, ErrReviewerBanned, result.Reason)
+	})
+
+	t.Run("Restricted from reviewing", func(t *testing.T) {
+		restricted := createTestUser(db, models.Customer)
+		restrictedOrder := createTestOrder(db, restricted.ID, models.OrderStatusDelivered, &deliveredDate)
+		createTestOrderItem(db, restrictedOrder.ID, variant.ID)
+		require.NoError(t, db.Create(&models.UserRestriction{UserID: restricted.ID, Capability: models.CapabilityReview, Reason: "Spam"}).Error)
+
+		result, err := handler.CheckReviewEligibility(restricted.ID, variant.ID, nil)
+		require.NoError(t, err)
+		assert.False(t, result.CanReview)
+		assert.Equal(t, ErrReviewerBanned, result.Reason)
+
+		// A restriction that has expired no longer applies
+		expired := time.Now().Add(-time.Hour)
+		require.NoError(t, db.Model(&models.UserRestriction{}).Where("user_id = ?", restricted.ID).Update("expires_at", expired).Error)
+		result, err = handler.CheckReviewEligibility(restricted.ID, variant.ID, nil)
+		require.NoError(t, err)
+		assert.True(t, result.CanReview)
+	})
+}
+
+func TestGetReviewEligibility(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := &ReviewHandler{db: db}
+
+	user := createTestUser(db, models.Customer)
+	product := createTestProduct(db)
+	variant := createTestProductVariant(db, product.ID)
+	deliveredDate := time.Now().AddDate(0, 0, -3)
+	order := createTestOrder(db, user.ID, models.OrderStatusDelivered, &deliveredDate)
+	orderItem := createTestOrderItem(db, order.ID, variant.ID)
+
+	get := func(variantID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/v1/products/"+variantID+"/review-eligibility", nil)
+		c.Params = gin.Params{{Key: "id", Value: variantID}}
+		c.Set("user_id", user.ID)
+		handler.GetReviewEligibility(c)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		w := get(strconv.FormatUint(uint64(variant.ID), 10))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var body struct {
+			Data ReviewEligibility `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.True(t, body.Data.CanReview)
+		require.NotNil(t, body.Data.OrderItemID)
+		assert.Equal(t, orderItem.ID, *body.Data.OrderItemID)
+	})
+
+	t.Run("Error - Variant not found", func(t *testing.T) {
+		w := get("99999")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Error - Invalid variant ID", func(t *testing.T) {
+		w := get("abc")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
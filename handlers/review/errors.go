@@ -25,6 +25,8 @@ const (
 	ErrReviewPurchaseRequired = "PURCHASE_REQUIRED"
 	ErrReviewModerationFailed = "MODERATION_FAILED"
 	ErrReviewInvalidStatus    = "INVALID_REVIEW_STATUS"
+	ErrReviewerBanned         = "REVIEWER_BANNED"
+	ErrMessagingRestricted    = "MESSAGING_RESTRICTED"
 
 	// Validation errors
 	ErrReviewInvalidRating  = "INVALID_RATING"
@@ -72,6 +74,8 @@ var errorMessages = map[string]string{
 	ErrReviewPurchaseRequired: "You must purchase this product before reviewing it",
 	ErrReviewModerationFailed: "Failed to moderate review",
 	ErrReviewInvalidStatus:    "Invalid review status",
+	ErrReviewerBanned:         "You are not allowed to post reviews",
+	ErrMessagingRestricted:    "You are not allowed to reply to reviews",
 
 	ErrReviewInvalidRating:  "Rating must be between 1 and 5",
 	ErrReviewInvalidContent: "Review content is required and must be less than 1000 characters",
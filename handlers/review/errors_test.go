@@ -30,7 +30,9 @@ func setupErrorTestDB(t *testing.T) *gorm.DB {
 		&models.SellerResponse{},
 		&models.ReviewHelpful{},
 		&models.ProductRating{},
+		&models.ProductOverallRating{},
 		&models.ReviewModerationLog{},
+		&models.UserRestriction{},
 	)
 	require.NoError(t, err)
 
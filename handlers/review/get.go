@@ -36,6 +36,10 @@ func (h *ReviewHandler) GetReview(c *gin.Context) {
 		Preload("SellerResponse.User", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, first_name, last_name, email, phone, avatar")
 		}).
+		Preload("SellerResponse.Replies", approvedReplies).
+		Preload("SellerResponse.Replies.User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, first_name, last_name, avatar")
+		}).
 		Where("id = ?", reviewID)
 
 	// Only show approved reviews unless user is admin
@@ -131,6 +135,7 @@ func (h *ReviewHandler) GetReview(c *gin.Context) {
 				"phone":      review.SellerResponse.User.Phone,
 				"avatar":     review.SellerResponse.User.Avatar,
 			},
+			"replies":   formatReplies(review.SellerResponse.Replies),
 			"CreatedAt": review.SellerResponse.CreatedAt,
 			"UpdatedAt": review.SellerResponse.UpdatedAt,
 		}
@@ -142,11 +147,16 @@ func (h *ReviewHandler) GetReview(c *gin.Context) {
 	})
 }
 
+// likeEscaper escapes the wildcards of a LIKE pattern, so a search matches
+// them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 // GetProductReviews handles GET /api/v1/reviews/product/:productVariantId
 // Returns paginated reviews for a product with filtering options
 func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
 	// Add basic database connectivity check
-	if err := h.db.Raw("SELECT 1").Error; err != nil {
+	if err := db.Raw("SELECT 1").Error; err != nil {
 		fmt.Printf("Database connectivity error: %v\n", err)
 		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_CONNECTION_ERROR", "Database connection failed")
 		return
@@ -184,6 +194,22 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 		}
 	}
 
+	// Parse content filters
+	verifiedFilter := c.Query("verified")
+	imagesFilter := c.Query("with_images")
+	responseFilter := c.Query("with_response")
+	for _, filter := range []struct{ name, value string }{
+		{"verified", verifiedFilter},
+		{"with_images", imagesFilter},
+		{"with_response", responseFilter},
+	} {
+		if filter.value != "" && filter.value != "true" && filter.value != "false" {
+			response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_FILTER", "Invalid "+filter.name+" filter. Must be true or false")
+			return
+		}
+	}
+	keyword := strings.TrimSpace(c.Query("q"))
+
 	// Validate sort parameters and map to database column names
 	sortFieldMap := map[string]string{
 		"created_at":    "created_at",
@@ -207,13 +233,13 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 	}
 
 	// Check if required tables exist
-	if err := h.db.Raw("SELECT 1 FROM product_variants LIMIT 1").Error; err != nil {
+	if err := db.Raw("SELECT 1 FROM product_variants LIMIT 1").Error; err != nil {
 		fmt.Printf("Table product_variants not found: %v\n", err)
 		response.GenerateErrorResponse(c, http.StatusInternalServerError, "TABLE_NOT_FOUND", "Required database tables not found")
 		return
 	}
 
-	if err := h.db.Raw("SELECT 1 FROM product_reviews LIMIT 1").Error; err != nil {
+	if err := db.Raw("SELECT 1 FROM product_reviews LIMIT 1").Error; err != nil {
 		fmt.Printf("Table product_reviews not found: %v\n", err)
 		response.GenerateErrorResponse(c, http.StatusInternalServerError, "TABLE_NOT_FOUND", "Required database tables not found")
 		return
@@ -221,7 +247,7 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 
 	// Check if product variant exists first
 	var productVariant models.ProductVariant
-	if err := h.db.First(&productVariant, productVariantID).Error; err != nil {
+	if err := db.First(&productVariant, productVariantID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.GenerateErrorResponse(c, http.StatusNotFound, "PRODUCT_VARIANT_NOT_FOUND", "Product variant not found")
 			return
@@ -232,7 +258,7 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 	}
 
 	// Build query
-	query := h.db.Model(&models.ProductReview{}).
+	query := db.Model(&models.ProductReview{}).
 		Where("product_variant_id = ? AND status = ?", productVariantID, models.ReviewStatusApproved)
 
 	// Apply rating filter
@@ -240,6 +266,27 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 		query = query.Where("rating = ?", rating)
 	}
 
+	// Apply content filters
+	if verifiedFilter != "" {
+		query = query.Where("is_verified_purchase = ?", verifiedFilter == "true")
+	}
+	switch imagesFilter {
+	case "true":
+		query = query.Where("EXISTS (SELECT 1 FROM review_images WHERE review_images.product_review_id = product_reviews.id AND review_images.deleted_at IS NULL)")
+	case "false":
+		query = query.Where("NOT EXISTS (SELECT 1 FROM review_images WHERE review_images.product_review_id = product_reviews.id AND review_images.deleted_at IS NULL)")
+	}
+	switch responseFilter {
+	case "true":
+		query = query.Where("EXISTS (SELECT 1 FROM seller_responses WHERE seller_responses.product_review_id = product_reviews.id AND seller_responses.deleted_at IS NULL)")
+	case "false":
+		query = query.Where("NOT EXISTS (SELECT 1 FROM seller_responses WHERE seller_responses.product_review_id = product_reviews.id AND seller_responses.deleted_at IS NULL)")
+	}
+	if keyword != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(keyword)) + "%"
+		query = query.Where(`(LOWER(title) LIKE ? ESCAPE '\' OR LOWER(content) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+
 	// Get total count for pagination
 	var total int64
 	err = query.Count(&total).Error
@@ -259,6 +306,10 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 		Preload("SellerResponse.User", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, first_name, last_name, email, phone, avatar")
 		}).
+		Preload("SellerResponse.Replies", approvedReplies).
+		Preload("SellerResponse.Replies.User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, first_name, last_name, avatar")
+		}).
 		Order(sortBy + " " + strings.ToUpper(sortOrder)).
 		Offset(offset).
 		Limit(limit).
@@ -309,6 +360,7 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 					"phone":      review.SellerResponse.User.Phone,
 					"avatar":     review.SellerResponse.User.Avatar,
 				},
+				"replies":   formatReplies(review.SellerResponse.Replies),
 				"CreatedAt": review.SellerResponse.CreatedAt,
 				"UpdatedAt": review.SellerResponse.UpdatedAt,
 			}
@@ -324,7 +376,7 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 
 	// Get rating statistics for this product
 	var ratingStats models.ProductRating
-	err = h.db.Where("product_variant_id = ?", productVariantID).First(&ratingStats).Error
+	err = db.Where("product_variant_id = ?", productVariantID).First(&ratingStats).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		response.GenerateErrorResponse(c, http.StatusInternalServerError, "RETRIEVE_RATING_STATS_ERROR", "Failed to retrieve rating statistics")
 		return
@@ -377,9 +429,13 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 				"has_prev":    hasPrev,
 			},
 			"filters": gin.H{
-				"rating": ratingFilter,
-				"sort":   sortBy,
-				"order":  sortOrder,
+				"rating":        ratingFilter,
+				"verified":      verifiedFilter,
+				"with_images":   imagesFilter,
+				"with_response": responseFilter,
+				"q":             keyword,
+				"sort":          sortBy,
+				"order":         sortOrder,
 			},
 			"rating_stats": gin.H{
 				"average_rating":   ratingStats.AverageRating,
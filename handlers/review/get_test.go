@@ -17,7 +17,7 @@ func TestGetReview(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	// Create test data
 	user := createTestUser(db, models.Customer)
@@ -159,7 +159,7 @@ func TestGetProductReviews(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	// Create test data
 	user1 := createTestUser(db, models.Customer)
@@ -396,6 +396,85 @@ func TestGetProductReviews(t *testing.T) {
 	})
 }
 
+func TestGetProductReviewsFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDBWithReviewTables(t)
+	handler := NewReviewHandler(db, nil, nil)
+
+	seller := createTestUser(db, models.Vendor)
+	product := createTestProduct(db)
+	productVariant := createTestProductVariant(db, product.ID)
+
+	withImage := createTestReview(t, db, createTestUser(db, models.Customer).ID, productVariant.ID, 5, "Squeaky", "Grills without melting")
+	assert.NoError(t, db.Create(&models.ReviewImage{ProductReviewID: withImage.ID, URL: "https://example.com/grilled.jpg"}).Error)
+	answered := createTestReview(t, db, createTestUser(db, models.Customer).ID, productVariant.ID, 4, "Salty", "Soak it before GRILLING")
+	assert.NoError(t, db.Create(&models.SellerResponse{ProductReviewID: answered.ID, UserID: seller.ID, Content: "Thanks!"}).Error)
+	unverified := createTestReview(t, db, createTestUser(db, models.Customer).ID, productVariant.ID, 3, "Fine", "Nothing special, 100% cheese")
+	assert.NoError(t, db.Model(unverified).Update("is_verified_purchase", false).Error)
+
+	// A deleted image no longer counts
+	deletedImage := createTestReview(t, db, createTestUser(db, models.Customer).ID, productVariant.ID, 2, "Rubbery", "Too firm")
+	image := models.ReviewImage{ProductReviewID: deletedImage.ID, URL: "https://example.com/rubbery.jpg"}
+	assert.NoError(t, db.Create(&image).Error)
+	assert.NoError(t, db.Delete(&image).Error)
+
+	list := func(query string) (int, []uint) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "productVariantId", Value: strconv.FormatUint(uint64(productVariant.ID), 10)}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/reviews/product/1?"+query, nil)
+		handler.GetProductReviews(c)
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+
+		var response struct {
+			Data struct {
+				Reviews []struct {
+					ID uint `json:"ID"`
+				} `json:"reviews"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		var ids []uint
+		for _, review := range response.Data.Reviews {
+			ids = append(ids, review.ID)
+		}
+		return w.Code, ids
+	}
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"verified=true&sort=rating", []uint{withImage.ID, answered.ID, deletedImage.ID}},
+		{"verified=false", []uint{unverified.ID}},
+		{"with_images=true", []uint{withImage.ID}},
+		{"with_images=false&sort=rating", []uint{answered.ID, unverified.ID, deletedImage.ID}},
+		{"with_response=true", []uint{answered.ID}},
+		{"q=grill&sort=rating", []uint{withImage.ID, answered.ID}},
+		{"q=salty", []uint{answered.ID}},
+		{"q=grill&with_response=false", []uint{withImage.ID}},
+		{"q=smoked", nil},
+		// Wildcards in the search are matched literally
+		{"q=100%25", []uint{unverified.ID}},
+		{"q=%25%25", nil},
+		{"q=_", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			code, ids := list(tt.query)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+
+	t.Run("invalid filter", func(t *testing.T) {
+		code, _ := list("with_images=yes")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
 // Helper function for creating test reviews
 func createTestReview(t *testing.T, db *gorm.DB, userID, productVariantID uint, rating int, title, content string) *models.ProductReview {
 	review := &models.ProductReview{
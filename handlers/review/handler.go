@@ -2,6 +2,9 @@ package review
 
 import (
 	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -11,17 +14,29 @@ type ReviewHandler struct {
 	db              *gorm.DB
 	appwriteService *aw.AppwriteService
 	validator       *ReviewValidator
+	emailTriggerSvc *email.EmailTriggerService
+	moderation      *moderation.Service
+	ratings         *ratings.Service
 }
 
 // NewReviewHandler creates a new instance of ReviewHandler
-func NewReviewHandler(db *gorm.DB, appwriteService *aw.AppwriteService) *ReviewHandler {
+func NewReviewHandler(db *gorm.DB, appwriteService *aw.AppwriteService, emailTriggerSvc *email.EmailTriggerService) *ReviewHandler {
 	return &ReviewHandler{
 		db:              db,
 		appwriteService: appwriteService,
 		validator:       NewReviewValidator(),
+		emailTriggerSvc: emailTriggerSvc,
+		moderation:      moderation.NewService(db, nil, emailTriggerSvc),
+		ratings:         ratings.NewService(db),
 	}
 }
 
+// WithModeration sets the service that gives review authors strikes
+func (h *ReviewHandler) WithModeration(moderations *moderation.Service) *ReviewHandler {
+	h.moderation = moderations
+	return h
+}
+
 // GetReview and GetProductReviews are implemented in get.go
 
 // CreateReview is implemented in create.go
@@ -34,6 +49,8 @@ func NewReviewHandler(db *gorm.DB, appwriteService *aw.AppwriteService) *ReviewH
 
 // CreateSellerResponse and UpdateSellerResponse are implemented in response.go
 
+// CreateReviewReply, GetReviewReplies and ModerateReviewReply are implemented in replies.go
+
 // GetAllReviews is implemented in admin.go
 
 // ModerateReview is implemented in admin.go
@@ -19,7 +19,7 @@ func TestMarkReviewHelpful(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	// Create test data
 	reviewer := createTestUser(db, models.Customer)
@@ -300,7 +300,7 @@ func TestMarkReviewHelpful(t *testing.T) {
 func TestGetUserVoteStatus(t *testing.T) {
 	// Setup
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	// Create test data
 	user := createTestUser(db, models.Customer)
@@ -337,7 +337,7 @@ func TestGetUserVoteStatus(t *testing.T) {
 func TestUpdateReviewHelpfulCount(t *testing.T) {
 	// Setup
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	// Create test data
 	user1 := createTestUser(db, models.Customer)
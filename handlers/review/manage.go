@@ -1,6 +1,7 @@
 package review
 
 import (
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -60,6 +61,7 @@ func (h *ReviewHandler) UpdateReview(c *gin.Context) {
 	}
 
 	// Update review fields
+	oldRating := review.Rating
 	review.Rating = req.Rating
 	review.Title = strings.TrimSpace(req.Title)
 	review.Content = strings.TrimSpace(req.Content)
@@ -71,9 +73,9 @@ func (h *ReviewHandler) UpdateReview(c *gin.Context) {
 	}
 
 	// Update rating aggregation
-	if err := h.UpdateProductRating(review.ProductVariantID); err != nil {
+	if err := h.ApplyRatingChange(review.ProductVariantID, oldRating, review.Rating); err != nil {
 		// Log the error but don't fail the request
-		// TODO: Add proper logging
+		slog.ErrorContext(c.Request.Context(), "failed to update product rating", "component", "review", "product_variant_id", review.ProductVariantID, "error", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -132,9 +134,9 @@ func (h *ReviewHandler) DeleteReview(c *gin.Context) {
 	h.db.Where("product_review_id = ?", review.ID).Delete(&models.SellerResponse{})
 
 	// Update rating aggregation
-	if err := h.UpdateProductRating(productVariantID); err != nil {
+	if err := h.ApplyRatingChange(productVariantID, review.Rating, 0); err != nil {
 		// Log the error but don't fail the request
-		// TODO: Add proper logging
+		slog.ErrorContext(c.Request.Context(), "failed to update product rating", "component", "review", "product_variant_id", productVariantID, "error", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -16,7 +16,7 @@ import (
 func TestUpdateReview(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	customer := createTestUser(db, models.Customer)
 	otherCustomer := createTestUser(db, models.Customer)
@@ -128,7 +128,7 @@ func TestUpdateReview(t *testing.T) {
 func TestDeleteReview(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	customer := createTestUser(db, models.Customer)
 	otherCustomer := createTestUser(db, models.Customer)
@@ -198,7 +198,7 @@ func TestDeleteReview(t *testing.T) {
 func TestGetUserReviews(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	customer := createTestUser(db, models.Customer)
 	otherCustomer := createTestUser(db, models.Customer)
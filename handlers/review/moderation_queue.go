@@ -0,0 +1,305 @@
+package review
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BulkModerationRequest represents the request body for approving or
+// rejecting several reviews at once
+type BulkModerationRequest struct {
+	ReviewIDs []uint `json:"review_ids" binding:"required,min=1,max=100"`
+	Reason    string `json:"reason" binding:"max=500"`
+}
+
+// AssignReviewsRequest represents the request body for assigning reviews to a
+// moderator. A null moderator_id unassigns them.
+type AssignReviewsRequest struct {
+	ReviewIDs   []uint `json:"review_ids" binding:"required,min=1,max=100"`
+	ModeratorID *uint  `json:"moderator_id"`
+}
+
+// errReviewsNotFound is returned by bulk actions when some of the reviews do
+// not exist, so that none of them are changed
+var errReviewsNotFound = errors.New("reviews not found")
+
+// GetModerationQueue handles GET /api/v1/admin/reviews/moderation-queue
+//
+// Lists the reviews waiting for moderation, oldest first. Pending and flagged
+// reviews are listed unless status is given.
+func (h *ReviewHandler) GetModerationQueue(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.ProductReview{})
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("product_reviews.status = ?", status)
+	} else {
+		query = query.Where("product_reviews.status IN ?", []models.ReviewStatus{models.ReviewStatusPending, models.ReviewStatusFlagged})
+	}
+
+	switch c.Query("has_images") {
+	case "true":
+		query = query.Where("EXISTS (SELECT 1 FROM review_images WHERE review_images.product_review_id = product_reviews.id AND review_images.deleted_at IS NULL)")
+	case "false":
+		query = query.Where("NOT EXISTS (SELECT 1 FROM review_images WHERE review_images.product_review_id = product_reviews.id AND review_images.deleted_at IS NULL)")
+	}
+
+	// flagged_by is "report" for reviews with an open abuse report, or the ID
+	// of the moderator who flagged them
+	if flaggedBy := c.Query("flagged_by"); flaggedBy == "report" {
+		query = query.Where("EXISTS (SELECT 1 FROM abuse_reports WHERE abuse_reports.review_id = product_reviews.id AND abuse_reports.deleted_at IS NULL AND abuse_reports.status IN ?)",
+			[]models.AbuseReportStatus{models.AbuseReportStatusPending, models.AbuseReportStatusReviewing})
+	} else if moderatorID, err := strconv.ParseUint(flaggedBy, 10, 32); err == nil {
+		query = query.Where("product_reviews.status = ? AND product_reviews.moderated_by = ?", models.ReviewStatusFlagged, moderatorID)
+	}
+
+	if productID, err := strconv.ParseUint(c.Query("product_id"), 10, 32); err == nil {
+		query = query.Where("product_reviews.product_variant_id IN (?)", db.Model(&models.ProductVariant{}).Select("id").Where("product_id = ?", productID))
+	}
+	if productVariantID, err := strconv.ParseUint(c.Query("product_variant_id"), 10, 32); err == nil {
+		query = query.Where("product_reviews.product_variant_id = ?", productVariantID)
+	}
+
+	// assigned_to is a moderator ID, "me" or "unassigned"
+	switch assigned := c.Query("assigned_to"); assigned {
+	case "":
+	case "me":
+		query = query.Where("product_reviews.assigned_to = ?", c.GetUint("user_id"))
+	case "unassigned":
+		query = query.Where("product_reviews.assigned_to IS NULL")
+	default:
+		if moderatorID, err := strconv.ParseUint(assigned, 10, 32); err == nil {
+			query = query.Where("product_reviews.assigned_to = ?", moderatorID)
+		}
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var reviews []models.ProductReview
+	err := query.Preload("User").
+		Preload("ProductVariant.Product").
+		Preload("Images").
+		Preload("Assignee", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, first_name, last_name, email")
+		}).
+		Order("product_reviews.created_at ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&reviews).Error
+
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve moderation queue")
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"reviews": reviews,
+			"pagination": gin.H{
+				"page":       page,
+				"limit":      limit,
+				"total":      total,
+				"totalPages": totalPages,
+				"hasNext":    page < totalPages,
+				"hasPrev":    page > 1,
+			},
+		},
+	})
+}
+
+// BulkApproveReviews handles POST /api/v1/admin/reviews/bulk/approve
+func (h *ReviewHandler) BulkApproveReviews(c *gin.Context) {
+	h.bulkModerate(c, models.ReviewStatusApproved)
+}
+
+// BulkRejectReviews handles POST /api/v1/admin/reviews/bulk/reject
+func (h *ReviewHandler) BulkRejectReviews(c *gin.Context) {
+	h.bulkModerate(c, models.ReviewStatusRejected)
+}
+
+// bulkModerate sets the status of several reviews and logs each change in
+// one transaction, so either all of them are moderated or none are
+func (h *ReviewHandler) bulkModerate(c *gin.Context, status models.ReviewStatus) {
+	adminID := c.GetUint("user_id")
+	if adminID == 0 {
+		response.GenerateErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "Admin not authenticated")
+		return
+	}
+
+	var req BulkModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body, review_ids must list 1 to 100 reviews")
+		return
+	}
+	if status == models.ReviewStatusRejected && req.Reason == "" {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "A reason is required to reject reviews")
+		return
+	}
+	reviewIDs := uniqueIDs(req.ReviewIDs)
+
+	var changed []models.ProductReview
+	oldStatuses := map[uint]models.ReviewStatus{}
+	now := time.Now()
+	err := h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var reviews []models.ProductReview
+		if err := tx.Where("id IN ?", reviewIDs).Find(&reviews).Error; err != nil {
+			return err
+		}
+		if len(reviews) != len(reviewIDs) {
+			return errReviewsNotFound
+		}
+
+		for _, review := range reviews {
+			oldStatuses[review.ID] = review.Status
+			if err := tx.Model(&review).Updates(map[string]interface{}{
+				"status":            status,
+				"moderation_reason": req.Reason,
+				"moderated_by":      adminID,
+				"moderated_at":      now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.ReviewModerationLog{
+				ReviewID:    review.ID,
+				AdminID:     adminID,
+				OldStatus:   oldStatuses[review.ID],
+				NewStatus:   status,
+				Reason:      req.Reason,
+				ModeratedAt: now,
+			}).Error; err != nil {
+				return err
+			}
+			changed = append(changed, review)
+		}
+		return nil
+	})
+
+	if errors.Is(err, errReviewsNotFound) {
+		response.GenerateErrorResponse(c, http.StatusNotFound, "REVIEW_NOT_FOUND", "One or more reviews were not found")
+		return
+	}
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to moderate reviews")
+		return
+	}
+
+	// Update the ratings of the variants whose approved reviews changed
+	for _, review := range changed {
+		from, to := ratings.Counted(oldStatuses[review.ID], review.Rating), ratings.Counted(status, review.Rating)
+		if err := h.ApplyRatingChange(review.ProductVariantID, from, to); err != nil {
+			slog.ErrorContext(c.Request.Context(), "failed to update product rating", "component", "review", "product_variant_id", review.ProductVariantID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reviews moderated successfully",
+		"data": gin.H{
+			"review_ids":   reviewIDs,
+			"new_status":   status,
+			"moderated_by": adminID,
+			"moderated_at": now,
+		},
+	})
+}
+
+// AssignReviews handles PUT /api/v1/admin/reviews/assign
+func (h *ReviewHandler) AssignReviews(c *gin.Context) {
+	var req AssignReviewsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body, review_ids must list 1 to 100 reviews")
+		return
+	}
+	db := h.db.WithContext(c.Request.Context())
+	reviewIDs := uniqueIDs(req.ReviewIDs)
+
+	// Reviews can only be assigned to admins
+	if req.ModeratorID != nil {
+		var moderator models.User
+		err := db.Select("id").Where("id = ? AND user_type = ?", *req.ModeratorID, models.Admin).First(&moderator).Error
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_MODERATOR", "Moderator not found")
+			return
+		}
+		if err != nil {
+			response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve moderator")
+			return
+		}
+	}
+
+	var assignedAt *time.Time
+	if req.ModeratorID != nil {
+		now := time.Now()
+		assignedAt = &now
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ProductReview{}).Where("id IN ?", reviewIDs).Updates(map[string]interface{}{
+			"assigned_to": req.ModeratorID,
+			"assigned_at": assignedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(reviewIDs)) {
+			return errReviewsNotFound
+		}
+		return nil
+	})
+
+	if errors.Is(err, errReviewsNotFound) {
+		response.GenerateErrorResponse(c, http.StatusNotFound, "REVIEW_NOT_FOUND", "One or more reviews were not found")
+		return
+	}
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to assign reviews")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reviews assigned successfully",
+		"data": gin.H{
+			"review_ids":   reviewIDs,
+			"moderator_id": req.ModeratorID,
+			"assigned_at":  assignedAt,
+		},
+	})
+}
+
+// uniqueIDs returns ids without duplicates, in their original order
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,207 @@
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendAdminJSON(handler gin.HandlerFunc, method, path string, adminID uint, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Set("user_id", adminID)
+	handler(c)
+	return w
+}
+
+func queueReviewIDs(t *testing.T, handler *ReviewHandler, adminID uint, query string) []uint {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/v1/admin/reviews/moderation-queue?"+query, nil)
+	c.Set("user_id", adminID)
+	handler.GetModerationQueue(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data struct {
+			Reviews []models.ProductReview `json:"reviews"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	ids := []uint{}
+	for _, review := range body.Data.Reviews {
+		ids = append(ids, review.ID)
+	}
+	return ids
+}
+
+func createQueuedReview(t *testing.T, handler *ReviewHandler, userID, variantID uint, status models.ReviewStatus) models.ProductReview {
+	review := createTestReview(t, handler.db, userID, variantID, 3, "Queued", "Waiting for moderation")
+	require.NoError(t, handler.db.Model(review).Update("status", status).Error)
+	review.Status = status
+	return *review
+}
+
+func TestGetModerationQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDBWithReviewTables(t)
+	require.NoError(t, db.AutoMigrate(&models.AbuseReport{}))
+	handler := NewReviewHandler(db, nil, nil)
+
+	admin := createTestUser(db, models.Admin)
+	customer := createTestUser(db, models.Customer)
+	product := createTestProduct(db)
+	variant := createTestProductVariant(db, product.ID)
+	otherProduct := createTestProduct(db)
+	otherVariant := createTestProductVariant(db, otherProduct.ID)
+
+	pending := createQueuedReview(t, handler, customer.ID, variant.ID, models.ReviewStatusPending)
+	flagged := createQueuedReview(t, handler, customer.ID, otherVariant.ID, models.ReviewStatusFlagged)
+	approved := createQueuedReview(t, handler, customer.ID, variant.ID, models.ReviewStatusApproved)
+	withImage := createQueuedReview(t, handler, customer.ID, variant.ID, models.ReviewStatusPending)
+	require.NoError(t, db.Create(&models.ReviewImage{ProductReviewID: withImage.ID, URL: "https://example.com/a.jpg"}).Error)
+	require.NoError(t, db.Create(&models.AbuseReport{ReporterID: customer.ID, ReviewID: &flagged.ID, Category: models.AbuseCategorySpam, Description: "Spam", Status: models.AbuseReportStatusPending}).Error)
+	require.NoError(t, db.Model(&pending).Update("assigned_to", admin.ID).Error)
+
+	t.Run("Pending and flagged by default, oldest first", func(t *testing.T) {
+		assert.Equal(t, []uint{pending.ID, flagged.ID, withImage.ID}, queueReviewIDs(t, handler, admin.ID, ""))
+	})
+
+	t.Run("Status filter", func(t *testing.T) {
+		assert.Equal(t, []uint{approved.ID}, queueReviewIDs(t, handler, admin.ID, "status=APPROVED"))
+	})
+
+	t.Run("Has images filter", func(t *testing.T) {
+		assert.Equal(t, []uint{withImage.ID}, queueReviewIDs(t, handler, admin.ID, "has_images=true"))
+		assert.Equal(t, []uint{pending.ID, flagged.ID}, queueReviewIDs(t, handler, admin.ID, "has_images=false"))
+	})
+
+	t.Run("Flagged by report filter", func(t *testing.T) {
+		assert.Equal(t, []uint{flagged.ID}, queueReviewIDs(t, handler, admin.ID, "flagged_by=report"))
+	})
+
+	t.Run("Product filter", func(t *testing.T) {
+		assert.Equal(t, []uint{flagged.ID}, queueReviewIDs(t, handler, admin.ID, "product_id="+strconv.FormatUint(uint64(otherProduct.ID), 10)))
+	})
+
+	t.Run("Assignment filter", func(t *testing.T) {
+		assert.Equal(t, []uint{pending.ID}, queueReviewIDs(t, handler, admin.ID, "assigned_to=me"))
+		assert.Equal(t, []uint{flagged.ID, withImage.ID}, queueReviewIDs(t, handler, admin.ID, "assigned_to=unassigned"))
+	})
+}
+
+func TestBulkModerateReviews(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDBWithReviewTables(t)
+	handler := NewReviewHandler(db, nil, nil)
+
+	admin := createTestUser(db, models.Admin)
+	customer := createTestUser(db, models.Customer)
+	product := createTestProduct(db)
+	variant := createTestProductVariant(db, product.ID)
+	first := createQueuedReview(t, handler, customer.ID, variant.ID, models.ReviewStatusPending)
+	second := createQueuedReview(t, handler, customer.ID, variant.ID, models.ReviewStatusPending)
+
+	t.Run("Error - Unknown review changes nothing", func(t *testing.T) {
+		w := sendAdminJSON(handler.BulkApproveReviews, "POST", "/api/v1/admin/reviews/bulk/approve", admin.ID,
+			BulkModerationRequest{ReviewIDs: []uint{first.ID, 99999}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var reloaded models.ProductReview
+		require.NoError(t, db.First(&reloaded, first.ID).Error)
+		assert.Equal(t, models.ReviewStatusPending, reloaded.Status)
+	})
+
+	t.Run("Error - Reject requires a reason", func(t *testing.T) {
+		w := sendAdminJSON(handler.BulkRejectReviews, "POST", "/api/v1/admin/reviews/bulk/reject", admin.ID,
+			BulkModerationRequest{ReviewIDs: []uint{first.ID}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Success - Approve logs each review and updates the rating", func(t *testing.T) {
+		w := sendAdminJSON(handler.BulkApproveReviews, "POST", "/api/v1/admin/reviews/bulk/approve", admin.ID,
+			BulkModerationRequest{ReviewIDs: []uint{first.ID, second.ID, first.ID}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var approved int64
+		db.Model(&models.ProductReview{}).Where("status = ? AND moderated_by = ?", models.ReviewStatusApproved, admin.ID).Count(&approved)
+		assert.Equal(t, int64(2), approved)
+
+		var logs []models.ReviewModerationLog
+		require.NoError(t, db.Find(&logs).Error)
+		assert.Len(t, logs, 2)
+
+		var rating models.ProductRating
+		require.NoError(t, db.Where("product_variant_id = ?", variant.ID).First(&rating).Error)
+		assert.Equal(t, 2, rating.TotalReviews)
+	})
+
+	t.Run("Success - Reject", func(t *testing.T) {
+		w := sendAdminJSON(handler.BulkRejectReviews, "POST", "/api/v1/admin/reviews/bulk/reject", admin.ID,
+			BulkModerationRequest{ReviewIDs: []uint{second.ID}, Reason: "Off topic"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.ProductReview
+		require.NoError(t, db.First(&reloaded, second.ID).Error)
+		assert.Equal(t, models.ReviewStatusRejected, reloaded.Status)
+		assert.Equal(t, "Off topic", reloaded.ModerationReason)
+	})
+}
+
+func TestAssignReviews(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDBWithReviewTables(t)
+	handler := NewReviewHandler(db, nil, nil)
+
+	admin := createTestUser(db, models.Admin)
+	moderator := createTestUser(db, models.Admin)
+	customer := createTestUser(db, models.Customer)
+	product := createTestProduct(db)
+	variant := createTestProductVariant(db, product.ID)
+	review := createQueuedReview(t, handler, customer.ID, variant.ID, models.ReviewStatusPending)
+
+	t.Run("Error - Moderator must be an admin", func(t *testing.T) {
+		w := sendAdminJSON(handler.AssignReviews, "PUT", "/api/v1/admin/reviews/assign", admin.ID,
+			AssignReviewsRequest{ReviewIDs: []uint{review.ID}, ModeratorID: &customer.ID})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Success - Assign and unassign", func(t *testing.T) {
+		w := sendAdminJSON(handler.AssignReviews, "PUT", "/api/v1/admin/reviews/assign", admin.ID,
+			AssignReviewsRequest{ReviewIDs: []uint{review.ID}, ModeratorID: &moderator.ID})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var reloaded models.ProductReview
+		require.NoError(t, db.First(&reloaded, review.ID).Error)
+		require.NotNil(t, reloaded.AssignedTo)
+		assert.Equal(t, moderator.ID, *reloaded.AssignedTo)
+		assert.NotNil(t, reloaded.AssignedAt)
+
+		w = sendAdminJSON(handler.AssignReviews, "PUT", "/api/v1/admin/reviews/assign", admin.ID,
+			AssignReviewsRequest{ReviewIDs: []uint{review.ID}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		reloaded = models.ProductReview{}
+		require.NoError(t, db.First(&reloaded, review.ID).Error)
+		assert.Nil(t, reloaded.AssignedTo)
+		assert.Nil(t, reloaded.AssignedAt)
+	})
+
+	t.Run("Error - Unknown review", func(t *testing.T) {
+		w := sendAdminJSON(handler.AssignReviews, "PUT", "/api/v1/admin/reviews/assign", admin.ID,
+			AssignReviewsRequest{ReviewIDs: []uint{99999}, ModeratorID: &moderator.ID})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
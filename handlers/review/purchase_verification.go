@@ -15,6 +15,7 @@ type PurchaseVerificationResult struct {
 	Order          *models.Order          `json:"order,omitempty"`
 	ProductVariant *models.ProductVariant `json:"product_variant,omitempty"`
 	ErrorMessage   string                 `json:"error_message,omitempty"`
+	ErrorCode      string                 `json:"error_code,omitempty"`
 }
 
 // VerifyPurchase checks if a user has purchased a specific product variant
@@ -57,12 +58,14 @@ func (h *ReviewHandler) VerifyPurchase(userID uint, productVariantID uint) (*Pur
 		return &PurchaseVerificationResult{
 			IsVerified:   false,
 			ErrorMessage: "Purchase is too old to review (more than 2 years)",
+			ErrorCode:    ErrPurchaseTooOld,
 		}, nil
 	}
 
 	return &PurchaseVerificationResult{
 		IsVerified:   false,
 		ErrorMessage: "No verified purchase found for this product",
+		ErrorCode:    ErrReviewPurchaseRequired,
 	}, nil
 }
 
@@ -89,6 +92,7 @@ func (h *ReviewHandler) VerifyPurchaseWithOrderItemID(userID uint, orderItemID u
 			return &PurchaseVerificationResult{
 				IsVerified:   false,
 				ErrorMessage: "Order item not found or not eligible for review",
+				ErrorCode:    ErrReviewPurchaseRequired,
 			}, nil
 		}
 		return nil, fmt.Errorf("failed to verify purchase with order item ID: %w", err)
@@ -100,6 +104,7 @@ func (h *ReviewHandler) VerifyPurchaseWithOrderItemID(userID uint, orderItemID u
 		return &PurchaseVerificationResult{
 			IsVerified:   false,
 			ErrorMessage: "Purchase is too old to review (more than 2 years)",
+			ErrorCode:    ErrPurchaseTooOld,
 		}, nil
 	}
 
@@ -164,6 +169,7 @@ func (h *ReviewHandler) CheckIfUserCanReview(userID uint, productVariantID uint)
 		return &PurchaseVerificationResult{
 			IsVerified:   false,
 			ErrorMessage: "You have already reviewed this product",
+			ErrorCode:    ErrReviewAlreadyExists,
 		}, nil
 	}
 
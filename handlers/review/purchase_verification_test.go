@@ -28,6 +28,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&models.Order{},
 		&models.OrderItem{},
 		&models.ProductReview{},
+		&models.UserRestriction{},
 	)
 	require.NoError(t, err)
 
@@ -1,85 +1,49 @@
 package review
 
 import (
-	"encoding/json"
-	"fmt"
+	"log/slog"
 
-	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // UpdateProductRating recalculates and updates the ProductRating for a product variant
 func (h *ReviewHandler) UpdateProductRating(productVariantID uint) error {
-	var reviews []models.ProductReview
-	// Only count approved reviews that are not soft deleted
-	err := h.db.Where("product_variant_id = ? AND status = ? AND deleted_at IS NULL", productVariantID, models.ReviewStatusApproved).Find(&reviews).Error
-	if err != nil {
-		return fmt.Errorf("failed to fetch reviews: %w", err)
-	}
-
-	total := len(reviews)
-	if total == 0 {
-		// No reviews: set rating to zero and clear breakdown
-		rating := &models.ProductRating{
-			ProductVariantID: productVariantID,
-			AverageRating:    0,
-			TotalReviews:     0,
-			RatingBreakdown:  `{"1":0,"2":0,"3":0,"4":0,"5":0}`,
-		}
-
-		// Check if rating exists
-		var existingRating models.ProductRating
-		err := h.db.Where("product_variant_id = ?", productVariantID).First(&existingRating).Error
-		if err != nil {
-			if err.Error() == "record not found" {
-				// Create new rating
-				return h.db.Create(rating).Error
-			}
-			return fmt.Errorf("failed to check existing rating: %w", err)
-		}
-
-		// Update existing rating
-		return h.db.Model(&existingRating).Updates(map[string]interface{}{
-			"average_rating":   rating.AverageRating,
-			"total_reviews":    rating.TotalReviews,
-			"rating_breakdown": rating.RatingBreakdown,
-		}).Error
-	}
+	return ratings.Recalculate(h.db, productVariantID)
+}
 
-	breakdown := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
-	sum := 0
-	for _, r := range reviews {
-		breakdown[r.Rating]++
-		sum += r.Rating
-	}
-	avg := float64(sum) / float64(total)
+// ApplyRatingChange updates the ProductRating of a product variant for a
+// review whose contribution went from one rating to another, see ratings.Apply
+func (h *ReviewHandler) ApplyRatingChange(productVariantID uint, from, to int) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		return ratings.Apply(tx, productVariantID, from, to)
+	})
+}
 
-	breakdownJSON, err := json.Marshal(breakdown)
+// GetRatingDrift handles GET /api/v1/admin/reviews/ratings/drift, reporting
+// the product ratings that disagree with their approved reviews without
+// changing them
+func (h *ReviewHandler) GetRatingDrift(c *gin.Context) {
+	report, err := h.ratings.Reconcile(c.Request.Context(), false)
 	if err != nil {
-		return fmt.Errorf("failed to marshal breakdown: %w", err)
-	}
-
-	rating := &models.ProductRating{
-		ProductVariantID: productVariantID,
-		AverageRating:    avg,
-		TotalReviews:     total,
-		RatingBreakdown:  string(breakdownJSON),
+		slog.ErrorContext(c.Request.Context(), "failed to check product ratings", "component", "review", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "review/rating_drift", "Failed to check product ratings")
+		return
 	}
+	response.GenerateSuccessResponse(c, "Rating drift retrieved successfully", report)
+}
 
-	// Check if rating exists
-	var existingRating models.ProductRating
-	err = h.db.Where("product_variant_id = ?", productVariantID).First(&existingRating).Error
+// ReconcileRatings handles POST /api/v1/admin/reviews/ratings/reconcile,
+// rebuilding the product ratings that disagree with their approved reviews
+// without waiting for the nightly reconciliation
+func (h *ReviewHandler) ReconcileRatings(c *gin.Context) {
+	report, err := h.ratings.Reconcile(c.Request.Context(), true)
 	if err != nil {
-		if err.Error() == "record not found" {
-			// Create new rating
-			return h.db.Create(rating).Error
-		}
-		return fmt.Errorf("failed to check existing rating: %w", err)
+		slog.ErrorContext(c.Request.Context(), "failed to reconcile product ratings", "component", "review", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "review/reconcile_ratings", "Failed to reconcile product ratings")
+		return
 	}
-
-	// Update existing rating
-	return h.db.Model(&existingRating).Updates(map[string]interface{}{
-		"average_rating":   rating.AverageRating,
-		"total_reviews":    rating.TotalReviews,
-		"rating_breakdown": rating.RatingBreakdown,
-	}).Error
+	response.GenerateSuccessResponse(c, "Ratings reconciled successfully", report)
 }
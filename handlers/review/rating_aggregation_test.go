@@ -20,6 +20,7 @@ func setupRatingTestDB(t *testing.T) *gorm.DB {
 		&models.ProductVariant{},
 		&models.ProductReview{},
 		&models.ProductRating{},
+		&models.ProductOverallRating{},
 	)
 	require.NoError(t, err)
 	return db
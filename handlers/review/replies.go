@@ -0,0 +1,330 @@
+package review
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReviewReplyRequest represents the request body for replying below a seller response
+type ReviewReplyRequest struct {
+	Content string `json:"content" binding:"required,max=500"`
+}
+
+// CreateReviewReply handles POST /api/v1/reviews/:id/response/replies
+//
+// The review author may follow up on the seller response once, and the seller
+// who wrote the response may answer that follow-up once it is approved.
+// Replies are moderated before they are shown.
+func (h *ReviewHandler) CreateReviewReply(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "User not authenticated")
+		return
+	}
+
+	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REVIEW_ID", "Invalid review ID")
+		return
+	}
+
+	var req ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body or content too long")
+		return
+	}
+	if h.rejectMessagingRestricted(c, userID.(uint)) {
+		return
+	}
+
+	// Check if review exists and is approved
+	var review models.ProductReview
+	err = h.db.Preload("SellerResponse.Replies").Where("id = ? AND status = ?", reviewID, models.ReviewStatusApproved).First(&review).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateErrorResponse(c, http.StatusNotFound, "REVIEW_NOT_FOUND", "Review not found or not approved")
+			return
+		}
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve review")
+		return
+	}
+	if review.SellerResponse == nil {
+		response.GenerateErrorResponse(c, http.StatusNotFound, "RESPONSE_NOT_FOUND", "The seller has not responded to this review")
+		return
+	}
+
+	followUp, answer := threadReplies(review.SellerResponse.Replies)
+	reply := models.ReviewReply{
+		SellerResponseID: review.SellerResponse.ID,
+		UserID:           userID.(uint),
+		Content:          strings.TrimSpace(req.Content),
+		Status:           models.ReviewStatusPending,
+	}
+
+	switch userID.(uint) {
+	case review.UserID:
+		if followUp != nil {
+			response.GenerateErrorResponse(c, http.StatusBadRequest, "REPLY_EXISTS", "You have already followed up on this response")
+			return
+		}
+	case review.SellerResponse.UserID:
+		if followUp == nil || followUp.Status != models.ReviewStatusApproved {
+			response.GenerateErrorResponse(c, http.StatusBadRequest, "NO_FOLLOW_UP", "There is no approved follow-up to reply to")
+			return
+		}
+		if answer != nil {
+			response.GenerateErrorResponse(c, http.StatusBadRequest, "REPLY_EXISTS", "You have already replied to this follow-up")
+			return
+		}
+		reply.ParentID = &followUp.ID
+		reply.IsFromSeller = true
+	default:
+		response.GenerateErrorResponse(c, http.StatusForbidden, "NOT_THREAD_PARTICIPANT", "Only the review author and the responding seller can reply")
+		return
+	}
+
+	if err := h.db.Create(&reply).Error; err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to create reply")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Reply submitted for moderation",
+		"data":    reply,
+	})
+}
+
+// GetReviewReplies handles GET /api/v1/admin/reviews/replies
+func (h *ReviewHandler) GetReviewReplies(c *gin.Context) {
+	db := h.db.WithContext(c.Request.Context())
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	status := c.Query("status")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.ReviewReply{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var replies []models.ReviewReply
+	err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, first_name, last_name, email, avatar")
+	}).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&replies).Error
+
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve replies")
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"replies": replies,
+			"pagination": gin.H{
+				"page":       page,
+				"limit":      limit,
+				"total":      total,
+				"totalPages": totalPages,
+				"hasNext":    page < totalPages,
+				"hasPrev":    page > 1,
+			},
+		},
+	})
+}
+
+// ModerateReviewReply handles PUT /api/v1/admin/reviews/replies/:id/moderate
+func (h *ReviewHandler) ModerateReviewReply(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateErrorResponse(c, http.StatusUnauthorized, "UNAUTHORIZED", "Admin not authenticated")
+		return
+	}
+
+	replyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REPLY_ID", "Invalid reply ID")
+		return
+	}
+
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+
+	if req.Status != models.ReviewStatusApproved &&
+		req.Status != models.ReviewStatusRejected &&
+		req.Status != models.ReviewStatusFlagged {
+		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_STATUS", "Invalid reply status")
+		return
+	}
+
+	var reply models.ReviewReply
+	if err := h.db.First(&reply, replyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.GenerateErrorResponse(c, http.StatusNotFound, "REPLY_NOT_FOUND", "Reply not found")
+			return
+		}
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve reply")
+		return
+	}
+
+	oldStatus := reply.Status
+	adminIDUint := adminID.(uint)
+	now := time.Now()
+	reply.Status = req.Status
+	reply.ModerationReason = req.Reason
+	reply.ModeratedBy = &adminIDUint
+	reply.ModeratedAt = &now
+
+	if err := h.db.Save(&reply).Error; err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update reply")
+		return
+	}
+
+	// The other side only hears about a reply once it is visible
+	if oldStatus != models.ReviewStatusApproved && req.Status == models.ReviewStatusApproved {
+		h.notifyReviewReply(c, &reply)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reply moderated successfully",
+		"data": gin.H{
+			"reply_id":     reply.ID,
+			"old_status":   oldStatus,
+			"new_status":   req.Status,
+			"moderated_by": adminID,
+			"moderated_at": reply.ModeratedAt,
+		},
+	})
+}
+
+// notifyReviewReply emails the seller about an approved follow-up, or the
+// review author about an approved seller answer
+func (h *ReviewHandler) notifyReviewReply(c *gin.Context, reply *models.ReviewReply) {
+	if h.emailTriggerSvc == nil {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var sellerResponse models.SellerResponse
+	err := h.db.Preload("User").First(&sellerResponse, reply.SellerResponseID).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load seller response for reply email", "component", "review", "reply_id", reply.ID, "error", err)
+		return
+	}
+	var review models.ProductReview
+	err = h.db.Preload("User").Preload("ProductVariant.Product").First(&review, sellerResponse.ProductReviewID).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load review for reply email", "component", "review", "reply_id", reply.ID, "error", err)
+		return
+	}
+
+	recipient, replier := sellerResponse.User, review.User
+	if reply.IsFromSeller {
+		recipient, replier = review.User, sellerResponse.User
+	}
+	if recipient.Email == "" {
+		return
+	}
+
+	userName := strings.TrimSpace(recipient.FirstName + " " + recipient.LastName)
+	data := map[string]interface{}{
+		"UserName":    userName,
+		"ReplierName": strings.TrimSpace(replier.FirstName + " " + replier.LastName),
+		"ProductName": review.ProductVariant.Product.Name,
+		"Reply":       reply.Content,
+		"RepliedAt":   reply.CreatedAt.Format("2006-01-02 15:04:05"),
+		"CanReply":    !reply.IsFromSeller,
+		"ReviewURL":   settings.Current().URL("/products/%d", review.ProductVariant.ProductID),
+		"subject":     "New reply about a review of " + review.ProductVariant.Product.Name,
+	}
+	if err := h.emailTriggerSvc.WithContext(ctx).TriggerReviewReply(recipient.Email, userName, data); err != nil {
+		slog.ErrorContext(ctx, "failed to send review reply email", "component", "review", "reply_id", reply.ID, "error", err)
+	}
+}
+
+// rejectMessagingRestricted responds with forbidden when the user is
+// restricted from replying to reviews
+func (h *ReviewHandler) rejectMessagingRestricted(c *gin.Context, userID uint) bool {
+	restriction, err := moderation.ActiveRestriction(h.db.WithContext(c.Request.Context()), userID, models.CapabilityMessage)
+	if err != nil {
+		response.GenerateErrorResponse(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to check restrictions")
+		return true
+	}
+	if restriction != nil {
+		GenerateReviewForbiddenResponse(c, NewReviewError(ErrMessagingRestricted))
+		return true
+	}
+	return false
+}
+
+// threadReplies picks the author's follow-up and the seller's answer out of
+// the replies to a seller response
+func threadReplies(replies []models.ReviewReply) (followUp, answer *models.ReviewReply) {
+	for i := range replies {
+		if replies[i].IsFromSeller {
+			answer = &replies[i]
+		} else {
+			followUp = &replies[i]
+		}
+	}
+	return followUp, answer
+}
+
+// approvedReplies preloads the replies shown below a seller response, oldest first
+func approvedReplies(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", models.ReviewStatusApproved).Order("created_at ASC")
+}
+
+// formatReplies formats the approved replies of a seller response for the public review endpoints
+func formatReplies(replies []models.ReviewReply) []gin.H {
+	formatted := make([]gin.H, 0, len(replies))
+	for _, reply := range replies {
+		formatted = append(formatted, gin.H{
+			"ID":             reply.ID,
+			"parent_id":      reply.ParentID,
+			"is_from_seller": reply.IsFromSeller,
+			"content":        reply.Content,
+			"user": gin.H{
+				"ID":         reply.User.ID,
+				"first_name": reply.User.FirstName,
+				"last_name":  reply.User.LastName,
+				"name":       strings.TrimSpace(reply.User.FirstName + " " + reply.User.LastName),
+				"avatar":     reply.User.Avatar,
+			},
+			"CreatedAt": reply.CreatedAt,
+		})
+	}
+	return formatted
+}
@@ -0,0 +1,175 @@
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postReviewReply(handler *ReviewHandler, reviewID, userID uint, content string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(ReviewReplyRequest{Content: content})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	id := strconv.FormatUint(uint64(reviewID), 10)
+	req := httptest.NewRequest("POST", "/api/v1/reviews/"+id+"/response/replies", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	c.Set("user_id", userID)
+	handler.CreateReviewReply(c)
+	return w
+}
+
+func moderateReviewReply(handler *ReviewHandler, replyID, adminID uint, status models.ReviewStatus) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(ModerationRequest{Status: status, Reason: "Checked"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	id := strconv.FormatUint(uint64(replyID), 10)
+	req := httptest.NewRequest("PUT", "/api/v1/admin/reviews/replies/"+id+"/moderate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	c.Set("user_id", adminID)
+	handler.ModerateReviewReply(c)
+	return w
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response["error"].(map[string]interface{})["code"].(string)
+}
+
+func TestReviewReplyThread(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDBWithReviewTables(t)
+	handler := NewReviewHandler(db, nil, nil)
+
+	seller := createTestUser(db, models.Vendor)
+	customer := createTestUser(db, models.Customer)
+	stranger := createTestUser(db, models.Customer)
+	admin := createTestUser(db, models.Admin)
+	product := createTestProduct(db)
+	productVariant := createTestProductVariant(db, product.ID)
+	review := createTestReview(t, db, customer.ID, productVariant.ID, 2, "Late", "Arrived late")
+
+	t.Run("Error - No seller response yet", func(t *testing.T) {
+		w := postReviewReply(handler, review.ID, customer.ID, "Any news?")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "RESPONSE_NOT_FOUND", errorCode(t, w))
+	})
+
+	sellerResponse := models.SellerResponse{ProductReviewID: review.ID, UserID: seller.ID, Content: "Sorry about the delay"}
+	require.NoError(t, db.Create(&sellerResponse).Error)
+
+	t.Run("Error - Seller cannot answer before a follow-up", func(t *testing.T) {
+		w := postReviewReply(handler, review.ID, seller.ID, "Anything else?")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "NO_FOLLOW_UP", errorCode(t, w))
+	})
+
+	t.Run("Error - Others cannot reply", func(t *testing.T) {
+		w := postReviewReply(handler, review.ID, stranger.ID, "Same here")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "NOT_THREAD_PARTICIPANT", errorCode(t, w))
+	})
+
+	t.Run("Error - Restricted from messaging", func(t *testing.T) {
+		require.NoError(t, db.Create(&models.UserRestriction{UserID: stranger.ID, Capability: models.CapabilityMessage, Reason: "Spam"}).Error)
+		w := postReviewReply(handler, review.ID, stranger.ID, "Same here")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, ErrMessagingRestricted, errorCode(t, w))
+	})
+
+	var followUp models.ReviewReply
+	t.Run("Success - Author follows up", func(t *testing.T) {
+		w := postReviewReply(handler, review.ID, customer.ID, "It still has not arrived")
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		require.NoError(t, db.Where("seller_response_id = ?", sellerResponse.ID).First(&followUp).Error)
+		assert.False(t, followUp.IsFromSeller)
+		assert.Nil(t, followUp.ParentID)
+		assert.Equal(t, models.ReviewStatusPending, followUp.Status)
+	})
+
+	t.Run("Error - Author follows up only once", func(t *testing.T) {
+		w := postReviewReply(handler, review.ID, customer.ID, "Hello?")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "REPLY_EXISTS", errorCode(t, w))
+	})
+
+	t.Run("Error - Seller cannot answer a pending follow-up", func(t *testing.T) {
+		w := postReviewReply(handler, review.ID, seller.ID, "We are looking into it")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "NO_FOLLOW_UP", errorCode(t, w))
+	})
+
+	t.Run("Success - Seller answers the approved follow-up once", func(t *testing.T) {
+		w := moderateReviewReply(handler, followUp.ID, admin.ID, models.ReviewStatusApproved)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = postReviewReply(handler, review.ID, seller.ID, "A replacement is on its way")
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var answer models.ReviewReply
+		require.NoError(t, db.Where("seller_response_id = ? AND is_from_seller = ?", sellerResponse.ID, true).First(&answer).Error)
+		require.NotNil(t, answer.ParentID)
+		assert.Equal(t, followUp.ID, *answer.ParentID)
+
+		w = postReviewReply(handler, review.ID, seller.ID, "And a voucher")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "REPLY_EXISTS", errorCode(t, w))
+	})
+}
+
+func TestModerateReviewReply(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDBWithReviewTables(t)
+	handler := NewReviewHandler(db, nil, nil)
+
+	seller := createTestUser(db, models.Vendor)
+	customer := createTestUser(db, models.Customer)
+	admin := createTestUser(db, models.Admin)
+	product := createTestProduct(db)
+	productVariant := createTestProductVariant(db, product.ID)
+	review := createTestReview(t, db, customer.ID, productVariant.ID, 3, "Okay", "Could be better")
+	sellerResponse := models.SellerResponse{ProductReviewID: review.ID, UserID: seller.ID, Content: "Thanks"}
+	require.NoError(t, db.Create(&sellerResponse).Error)
+	reply := models.ReviewReply{SellerResponseID: sellerResponse.ID, UserID: customer.ID, Content: "Not really", Status: models.ReviewStatusPending}
+	require.NoError(t, db.Create(&reply).Error)
+
+	t.Run("Error - Invalid status", func(t *testing.T) {
+		w := moderateReviewReply(handler, reply.ID, admin.ID, models.ReviewStatusPending)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "INVALID_STATUS", errorCode(t, w))
+	})
+
+	t.Run("Error - Reply not found", func(t *testing.T) {
+		w := moderateReviewReply(handler, 99999, admin.ID, models.ReviewStatusApproved)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "REPLY_NOT_FOUND", errorCode(t, w))
+	})
+
+	t.Run("Success - Rejected replies are not shown", func(t *testing.T) {
+		w := moderateReviewReply(handler, reply.ID, admin.ID, models.ReviewStatusRejected)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var loaded models.ReviewReply
+		require.NoError(t, db.First(&loaded, reply.ID).Error)
+		assert.Equal(t, models.ReviewStatusRejected, loaded.Status)
+		require.NotNil(t, loaded.ModeratedBy)
+		assert.Equal(t, admin.ID, *loaded.ModeratedBy)
+
+		var response models.SellerResponse
+		require.NoError(t, db.Preload("Replies", approvedReplies).First(&response, sellerResponse.ID).Error)
+		assert.Empty(t, response.Replies)
+	})
+}
@@ -38,6 +38,9 @@ func (h *ReviewHandler) CreateSellerResponse(c *gin.Context) {
 		response.GenerateErrorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body or content too long")
 		return
 	}
+	if h.rejectMessagingRestricted(c, userID.(uint)) {
+		return
+	}
 
 	// Check if review exists and is approved
 	var review models.ProductReview
@@ -16,7 +16,7 @@ import (
 func TestCreateSellerResponse(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	seller := createTestUser(db, models.Vendor)
 	otherSeller := createTestUser(db, models.Vendor)
@@ -116,7 +116,7 @@ func TestCreateSellerResponse(t *testing.T) {
 func TestUpdateSellerResponse(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db := setupTestDBWithReviewTables(t)
-	handler := NewReviewHandler(db, nil)
+	handler := NewReviewHandler(db, nil, nil)
 
 	seller := createTestUser(db, models.Vendor)
 	customer := createTestUser(db, models.Customer)
@@ -0,0 +1,13 @@
+package role
+
+import (
+	"gorm.io/gorm"
+)
+
+type RoleHandler struct {
+	db *gorm.DB
+}
+
+func NewRoleHandler(db *gorm.DB) *RoleHandler {
+	return &RoleHandler{db: db}
+}
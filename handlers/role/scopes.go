@@ -0,0 +1,81 @@
+package role
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// roles lists the user types whose scopes can be managed
+var roles = []models.UserType{
+	models.Admin,
+	models.SupportAgent,
+	models.Vendor,
+	models.Wholesaler,
+	models.Customer,
+}
+
+type RoleScopesResponse struct {
+	Role   models.UserType     `json:"role"`
+	Scopes []permissions.Scope `json:"scopes"`
+}
+
+type UpdateRoleScopesRequest struct {
+	Scopes []permissions.Scope `json:"scopes"`
+}
+
+// GetRoles - Admin endpoint to list roles with their scopes
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	result := make([]RoleScopesResponse, 0, len(roles))
+	for _, r := range roles {
+		result = append(result, RoleScopesResponse{Role: r, Scopes: permissions.ScopesFor(r)})
+	}
+
+	response.GenerateSuccessResponse(c, "Roles retrieved successfully", gin.H{
+		"roles":  result,
+		"scopes": permissions.AllScopes,
+	})
+}
+
+// UpdateRoleScopes - Admin endpoint to replace the scopes granted to a role
+func (h *RoleHandler) UpdateRoleScopes(c *gin.Context) {
+	role := models.UserType(c.Param("role"))
+	known := false
+	for _, r := range roles {
+		if r == role {
+			known = true
+			break
+		}
+	}
+	if !known {
+		response.GenerateNotFoundResponse(c, "role/update_scopes", "Role not found")
+		return
+	}
+	if role == models.Admin {
+		response.GenerateBadRequestResponse(c, "role/update_scopes", "Admin always holds every scope")
+		return
+	}
+
+	var req UpdateRoleScopesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "role/update_scopes", err.Error())
+		return
+	}
+	for _, s := range req.Scopes {
+		if !permissions.IsValid(s) {
+			response.GenerateBadRequestResponse(c, "role/update_scopes", "Unknown scope: "+string(s))
+			return
+		}
+	}
+
+	if err := permissions.SetRoleScopes(h.db, role, req.Scopes); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "role/update_scopes", "Failed to update role scopes")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Role scopes updated successfully", RoleScopesResponse{
+		Role:   role,
+		Scopes: permissions.ScopesFor(role),
+	})
+}
@@ -0,0 +1,11 @@
+package search
+
+import "gorm.io/gorm"
+
+type SearchHandler struct {
+	db *gorm.DB
+}
+
+func NewSearchHandler(db *gorm.DB) *SearchHandler {
+	return &SearchHandler{db: db}
+}
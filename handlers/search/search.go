@@ -0,0 +1,259 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SearchResult is one entity matched by the admin search
+type SearchResult struct {
+	ID       uint   `json:"id"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Link     string `json:"link"`
+}
+
+// SearchGroup holds the results of one entity type
+type SearchGroup struct {
+	Type       string         `json:"type"`
+	Results    []SearchResult `json:"results"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"page_size"`
+	TotalCount int64          `json:"total_count"`
+	TotalPages int64          `json:"total_pages"`
+}
+
+// searchTerm is the parsed q parameter
+type searchTerm struct {
+	like string // lower-cased LIKE pattern
+	id   uint   // set when q is a plain number, to match IDs exactly
+}
+
+// searchGroup describes one searchable entity type and the scopes needed to see it
+type searchGroup struct {
+	name   string
+	scopes []permissions.Scope
+	search func(h *SearchHandler, term searchTerm, page, pageSize int) ([]SearchResult, int64, error)
+}
+
+// searchGroups lists the entity types in the order they are returned
+var searchGroups = []searchGroup{
+	{"orders", []permissions.Scope{permissions.OrdersRead, permissions.OrdersWrite}, (*SearchHandler).searchOrders},
+	{"users", []permissions.Scope{permissions.SupportRead, permissions.OrdersRead, permissions.UsersImpersonate}, (*SearchHandler).searchUsers},
+	{"products", []permissions.Scope{permissions.ProductsWrite, permissions.InventoryRead}, (*SearchHandler).searchProducts},
+	{"payments", []permissions.Scope{permissions.OrdersRead, permissions.PaymentsRefund}, (*SearchHandler).searchPayments},
+	{"tickets", []permissions.Scope{permissions.SupportRead}, (*SearchHandler).searchTickets},
+	{"disputes", []permissions.Scope{permissions.SupportRead}, (*SearchHandler).searchDisputes},
+}
+
+// Search - Admin endpoint that searches orders, users, products, payments,
+// tickets and disputes at once. Only the groups the caller's role may read are
+// searched; `types` narrows the groups and page/page_size apply to each group.
+func (h *SearchHandler) Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if len([]rune(q)) < 2 {
+		response.GenerateBadRequestResponse(c, "search/admin", "Search query must be at least 2 characters")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "5"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 5
+	}
+
+	requested := map[string]bool{}
+	if types := c.Query("types"); types != "" {
+		for _, t := range strings.Split(strings.ToLower(types), ",") {
+			requested[strings.TrimSpace(t)] = true
+		}
+	}
+
+	term := searchTerm{like: "%" + strings.ToLower(q) + "%"}
+	if id, err := strconv.ParseUint(strings.TrimPrefix(q, "#"), 10, 32); err == nil {
+		term.id = uint(id)
+	}
+
+	groups := []SearchGroup{}
+	for _, group := range searchGroups {
+		if len(requested) > 0 && !requested[group.name] {
+			continue
+		}
+		if !contextHasAny(c, group.scopes) {
+			continue
+		}
+		results, total, err := group.search(h, term, page, pageSize)
+		if err != nil {
+			response.GenerateInternalServerErrorResponse(c, "search/admin", fmt.Sprintf("Failed to search %s", group.name))
+			return
+		}
+		groups = append(groups, SearchGroup{
+			Type:       group.name,
+			Results:    results,
+			Page:       page,
+			PageSize:   pageSize,
+			TotalCount: total,
+			TotalPages: (total + int64(pageSize) - 1) / int64(pageSize),
+		})
+	}
+
+	response.GenerateSuccessResponse(c, "Search results retrieved successfully", gin.H{
+		"query":  q,
+		"groups": groups,
+	})
+}
+
+func (h *SearchHandler) searchOrders(term searchTerm, page, pageSize int) ([]SearchResult, int64, error) {
+	query := h.db.Model(&models.Order{}).
+		Joins("LEFT JOIN users ON orders.user_id = users.id").
+		Where("LOWER(orders.order_number) LIKE ? OR LOWER(users.email) LIKE ? OR orders.id = ?", term.like, term.like, term.id)
+
+	var orders []models.Order
+	total, err := findPage(query.Preload("User").Order("orders.created_at DESC"), page, pageSize, &orders)
+	results := make([]SearchResult, 0, len(orders))
+	for _, order := range orders {
+		results = append(results, SearchResult{
+			ID:       order.ID,
+			Title:    order.OrderNumber,
+			Subtitle: fmt.Sprintf("%s · %s · %.2f", order.User.Email, order.Status, order.FinalAmount),
+			Link:     fmt.Sprintf("/admin/orders/%d", order.ID),
+		})
+	}
+	return results, total, err
+}
+
+func (h *SearchHandler) searchUsers(term searchTerm, page, pageSize int) ([]SearchResult, int64, error) {
+	query := h.db.Model(&models.User{}).
+		Where("LOWER(email) LIKE ? OR LOWER(first_name || ' ' || last_name) LIKE ? OR phone LIKE ? OR id = ?", term.like, term.like, term.like, term.id)
+
+	var users []models.User
+	total, err := findPage(query.Order("created_at DESC"), page, pageSize, &users)
+	results := make([]SearchResult, 0, len(users))
+	for _, user := range users {
+		title := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		if title == "" {
+			title = user.Email
+		}
+		results = append(results, SearchResult{
+			ID:       user.ID,
+			Title:    title,
+			Subtitle: fmt.Sprintf("%s · %s", user.Email, user.UserType),
+			Link:     fmt.Sprintf("/admin/users/%d", user.ID),
+		})
+	}
+	return results, total, err
+}
+
+func (h *SearchHandler) searchProducts(term searchTerm, page, pageSize int) ([]SearchResult, int64, error) {
+	variants := h.db.Model(&models.ProductVariant{}).Select("product_id").
+		Where("LOWER(sku) LIKE ? OR LOWER(barcode) LIKE ?", term.like, term.like)
+	query := h.db.Model(&models.Product{}).
+		Where("LOWER(name) LIKE ? OR id IN (?) OR id = ?", term.like, variants, term.id)
+
+	var products []models.Product
+	total, err := findPage(query.Preload("Variants").Order("created_at DESC"), page, pageSize, &products)
+	results := make([]SearchResult, 0, len(products))
+	for _, product := range products {
+		skus := make([]string, 0, len(product.Variants))
+		for _, variant := range product.Variants {
+			skus = append(skus, variant.SKU)
+		}
+		results = append(results, SearchResult{
+			ID:       product.ID,
+			Title:    product.Name,
+			Subtitle: strings.Join(skus, ", "),
+			Link:     fmt.Sprintf("/admin/products/%d", product.ID),
+		})
+	}
+	return results, total, err
+}
+
+func (h *SearchHandler) searchPayments(term searchTerm, page, pageSize int) ([]SearchResult, int64, error) {
+	query := h.db.Model(&models.Payment{}).
+		Joins("LEFT JOIN orders ON payments.order_id = orders.id").
+		Where("LOWER(payments.revolut_order_id) LIKE ? OR LOWER(payments.revolut_payment_id) LIKE ? OR LOWER(orders.order_number) LIKE ?", term.like, term.like, term.like)
+
+	var payments []models.Payment
+	total, err := findPage(query.Preload("Order").Order("payments.created_at DESC"), page, pageSize, &payments)
+	results := make([]SearchResult, 0, len(payments))
+	for _, payment := range payments {
+		title := payment.RevolutPaymentID
+		if title == "" {
+			title = payment.RevolutOrderID
+		}
+		results = append(results, SearchResult{
+			ID:       payment.ID,
+			Title:    title,
+			Subtitle: fmt.Sprintf("%s · %s · %.2f %s", payment.Order.OrderNumber, payment.Status, payment.Amount, payment.Currency),
+			Link:     fmt.Sprintf("/admin/orders/%d", payment.OrderID),
+		})
+	}
+	return results, total, err
+}
+
+func (h *SearchHandler) searchTickets(term searchTerm, page, pageSize int) ([]SearchResult, int64, error) {
+	query := h.db.Model(&models.SupportTicket{}).
+		Where("LOWER(title) LIKE ? OR id = ?", term.like, term.id)
+
+	var tickets []models.SupportTicket
+	total, err := findPage(query.Order("created_at DESC"), page, pageSize, &tickets)
+	results := make([]SearchResult, 0, len(tickets))
+	for _, ticket := range tickets {
+		results = append(results, SearchResult{
+			ID:       ticket.ID,
+			Title:    fmt.Sprintf("#%d %s", ticket.ID, ticket.Title),
+			Subtitle: fmt.Sprintf("%s · %s · %s", ticket.Status, ticket.Priority, ticket.Category),
+			Link:     fmt.Sprintf("/admin/tickets/%d", ticket.ID),
+		})
+	}
+	return results, total, err
+}
+
+func (h *SearchHandler) searchDisputes(term searchTerm, page, pageSize int) ([]SearchResult, int64, error) {
+	query := h.db.Model(&models.Dispute{}).
+		Where("LOWER(title) LIKE ? OR id = ?", term.like, term.id)
+
+	var disputes []models.Dispute
+	total, err := findPage(query.Order("created_at DESC"), page, pageSize, &disputes)
+	results := make([]SearchResult, 0, len(disputes))
+	for _, dispute := range disputes {
+		results = append(results, SearchResult{
+			ID:       dispute.ID,
+			Title:    fmt.Sprintf("#%d %s", dispute.ID, dispute.Title),
+			Subtitle: fmt.Sprintf("%s · %s · %s", dispute.Status, dispute.Priority, dispute.Category),
+			Link:     fmt.Sprintf("/admin/disputes/%d", dispute.ID),
+		})
+	}
+	return results, total, err
+}
+
+// findPage counts the rows matched by query and loads one page of them into dest
+func findPage(query *gorm.DB, page, pageSize int, dest interface{}) (int64, error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return total, query.Offset((page - 1) * pageSize).Limit(pageSize).Find(dest).Error
+}
+
+func contextHasAny(c *gin.Context, scopes []permissions.Scope) bool {
+	for _, scope := range scopes {
+		if permissions.ContextHas(c, scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTest(t *testing.T, userType models.UserType) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{}, &models.Order{}, &models.Payment{}, &models.Product{}, &models.ProductVariant{},
+		&models.SupportTicket{}, &models.Dispute{},
+	))
+
+	handler := NewSearchHandler(db)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_type", userType) })
+	router.GET("/admin/search", handler.Search)
+	return router, db
+}
+
+func search(t *testing.T, router *gin.Engine, query string) map[string]SearchGroup {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/search?"+query, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Data struct {
+			Groups []SearchGroup `json:"groups"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	groups := map[string]SearchGroup{}
+	for _, group := range body.Data.Groups {
+		groups[group.Type] = group
+	}
+	return groups
+}
+
+func TestSearchAcrossEntities(t *testing.T) {
+	router, db := setupTest(t, models.Admin)
+	user := models.User{Email: "jane.doe@example.com", Password: "x", FirstName: "Jane", LastName: "Doe", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+	order := models.Order{OrderNumber: "ORD-2024-0042", UserID: user.ID, Status: models.OrderStatusPending, PaymentStatus: models.PaymentStatusPending}
+	require.NoError(t, db.Create(&order).Error)
+	require.NoError(t, db.Create(&models.Payment{OrderID: order.ID, RevolutOrderID: "rev-ord-777", RevolutPaymentID: "rev-pay-777", Amount: 10, Currency: "GBP"}).Error)
+	product := models.Product{Name: "Saffron Rice"}
+	require.NoError(t, db.Create(&product).Error)
+	require.NoError(t, db.Create(&models.ProductVariant{ProductID: product.ID, Name: "1kg", SKU: "RICE-SAF-1KG", BasePrice: 4}).Error)
+	require.NoError(t, db.Create(&models.SupportTicket{UserID: user.ID, Title: "Rice arrived damaged", Description: "x", Category: models.TicketCategoryShipping}).Error)
+
+	groups := search(t, router, "q=jane")
+	require.Len(t, groups["users"].Results, 1)
+	assert.Equal(t, "Jane Doe", groups["users"].Results[0].Title)
+	require.Len(t, groups["orders"].Results, 1)
+	assert.Equal(t, "ORD-2024-0042", groups["orders"].Results[0].Title)
+	assert.Empty(t, groups["products"].Results)
+
+	groups = search(t, router, "q=rice-saf")
+	require.Len(t, groups["products"].Results, 1)
+	assert.Equal(t, "Saffron Rice", groups["products"].Results[0].Title)
+	assert.Equal(t, "RICE-SAF-1KG", groups["products"].Results[0].Subtitle)
+
+	groups = search(t, router, "q=rev-pay&types=payments")
+	require.Len(t, groups, 1)
+	require.Len(t, groups["payments"].Results, 1)
+	assert.Equal(t, "rev-pay-777", groups["payments"].Results[0].Title)
+
+	groups = search(t, router, "q=rice&types=tickets,products&page_size=1")
+	assert.Equal(t, int64(1), groups["tickets"].TotalCount)
+	assert.Equal(t, int64(1), groups["products"].TotalPages)
+}
+
+func TestSearchRespectsScopes(t *testing.T) {
+	router, db := setupTest(t, models.Vendor)
+	require.NoError(t, db.Create(&models.SupportTicket{UserID: 1, Title: "Olive oil leak", Description: "x", Category: models.TicketCategoryProduct}).Error)
+	require.NoError(t, db.Create(&models.Product{Name: "Olive Oil"}).Error)
+
+	groups := search(t, router, "q=olive")
+	assert.Contains(t, groups, "products")
+	assert.NotContains(t, groups, "tickets")
+	assert.NotContains(t, groups, "users")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/search?q=o", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
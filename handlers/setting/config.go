@@ -0,0 +1,30 @@
+package setting
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetConfig - Admin endpoint showing the configuration the app started with,
+// secrets redacted, and any problems validation finds with it
+func (h *SettingHandler) GetConfig(c *gin.Context) {
+	if h.config == nil {
+		response.GenerateNotFoundResponse(c, "settings/config", "Configuration is not available")
+		return
+	}
+
+	problems := []cfg.Problem{}
+	var invalid *cfg.ValidationError
+	if err := h.config.Validate(); errors.As(err, &invalid) {
+		problems = invalid.Problems
+	}
+	response.GenerateSuccessResponse(c, "Configuration retrieved successfully", gin.H{
+		"mode":     h.config.Mode(),
+		"valid":    len(problems) == 0,
+		"problems": problems,
+		"config":   h.config.Redacted(),
+	})
+}
@@ -0,0 +1,21 @@
+package setting
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"gorm.io/gorm"
+)
+
+type SettingHandler struct {
+	db     *gorm.DB
+	config *cfg.AppConfig
+}
+
+func NewSettingHandler(db *gorm.DB) *SettingHandler {
+	return &SettingHandler{db: db}
+}
+
+// WithConfig sets the startup configuration shown by the config diagnostic
+func (h *SettingHandler) WithConfig(config *cfg.AppConfig) *SettingHandler {
+	h.config = config
+	return h
+}
@@ -0,0 +1,48 @@
+package setting
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetMaintenance - Public endpoint the storefront polls to show a banner
+// for an active or upcoming maintenance
+func (h *SettingHandler) GetMaintenance(c *gin.Context) {
+	m := settings.CurrentMaintenance()
+	now := time.Now()
+	forced := h.config != nil && h.config.Maintenance.Enabled
+	response.GenerateSuccessResponse(c, "Maintenance retrieved successfully", gin.H{
+		"active":    forced || m.Active(now),
+		"read_only": forced || m.Active(now),
+		"scheduled": !forced && m.Scheduled(now),
+		"message":   m.Message,
+		"starts_at": m.StartsAt,
+		"ends_at":   m.EndsAt,
+	})
+}
+
+// UpdateMaintenance - Admin endpoint to switch maintenance on or off, or to
+// schedule a window. It replaces the whole maintenance setting.
+func (h *SettingHandler) UpdateMaintenance(c *gin.Context) {
+	var req settings.Maintenance
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateValidationErrorResponse(c, "settings/maintenance", err)
+		return
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		response.GenerateBadRequestResponse(c, "settings/maintenance", "ends_at must be after starts_at")
+		return
+	}
+
+	updated, err := settings.SaveMaintenance(h.db.WithContext(c.Request.Context()), req, c.GetUint("user_id"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to update maintenance", "component", "settings", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "settings/maintenance", "Failed to update maintenance")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Maintenance updated successfully", updated)
+}
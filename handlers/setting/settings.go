@@ -0,0 +1,32 @@
+package setting
+
+import (
+	"log/slog"
+
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSettings - Public endpoint for the business settings the storefront
+// shows, such as the currency and support email
+func (h *SettingHandler) GetSettings(c *gin.Context) {
+	response.GenerateSuccessResponse(c, "Settings retrieved successfully", settings.Current())
+}
+
+// UpdateSettings - Admin endpoint to change business settings
+func (h *SettingHandler) UpdateSettings(c *gin.Context) {
+	var req settings.Update
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "settings/update", err.Error())
+		return
+	}
+
+	updated, err := settings.Save(h.db.WithContext(c.Request.Context()), req, c.GetUint("user_id"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to update settings", "component", "settings", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "settings/update", "Failed to update settings")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Settings updated successfully", updated)
+}
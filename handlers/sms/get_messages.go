@@ -0,0 +1,62 @@
+package sms
+
+import (
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetMessages - Admin endpoint to browse the text message delivery log,
+// filterable by user, recipient, channel, template and status
+func (h *SMSHandler) GetMessages(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.Model(&models.SMSMessage{})
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if to := c.Query("to"); to != "" {
+		query = query.Where("\"to\" = ?", to)
+	}
+	if channel := c.Query("channel"); channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	if template := c.Query("template"); template != "" {
+		query = query.Where("template = ?", template)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "sms/messages", "Failed to count messages")
+		return
+	}
+
+	var messages []models.SMSMessage
+	if err := query.Order("created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&messages).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "sms/messages", "Failed to fetch messages")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Text messages retrieved successfully", gin.H{
+		"messages":    messages,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"total_pages": (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
@@ -0,0 +1,15 @@
+package sms
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"gorm.io/gorm"
+)
+
+type SMSHandler struct {
+	db    *gorm.DB
+	texts *sms.Service
+}
+
+func NewSMSHandler(db *gorm.DB, texts *sms.Service) *SMSHandler {
+	return &SMSHandler{db: db, texts: texts}
+}
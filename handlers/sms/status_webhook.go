@@ -0,0 +1,40 @@
+package sms
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleTwilioStatus records Twilio's delivery reports of the messages sent,
+// authenticated by their X-Twilio-Signature
+func (h *SMSHandler) HandleTwilioStatus(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		response.GenerateBadRequestResponse(c, "sms/status", "Invalid form")
+		return
+	}
+	if h.texts == nil || !h.texts.ValidStatusCallback(c.Request.PostForm, c.GetHeader("X-Twilio-Signature")) {
+		response.GenerateUnauthorizedResponse(c, "sms/status", "Invalid signature")
+		return
+	}
+
+	form := c.Request.PostForm
+	detail := form.Get("ErrorCode")
+	if detail != "" && form.Get("ErrorMessage") != "" {
+		detail += ": " + form.Get("ErrorMessage")
+	}
+	_, err := h.texts.UpdateStatus(form.Get("MessageSid"), form.Get("MessageStatus"), detail)
+	switch {
+	case errors.Is(err, sms.ErrUnknownMessage):
+		// Not ours to track, such as a message sent from the Twilio console
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "failed to record text message status", "component", "sms", "error", err)
+		response.GenerateInternalServerErrorResponse(c, "sms/status", "Failed to record status")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
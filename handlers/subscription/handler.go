@@ -0,0 +1,52 @@
+package subscription
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/subscription"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SubscriptionHandler struct {
+	db            *gorm.DB
+	subscriptions *subscription.Service
+}
+
+func NewSubscriptionHandler(db *gorm.DB, subscriptions *subscription.Service) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		db:            db,
+		subscriptions: subscriptions,
+	}
+}
+
+// subscriptionError responds to an error of the subscription service
+func subscriptionError(c *gin.Context, code string, err error, message string) {
+	switch {
+	case errors.Is(err, subscription.ErrNotFound):
+		response.GenerateNotFoundResponse(c, code, "Subscription not found")
+	case errors.Is(err, subscription.ErrAddressNotFound):
+		response.GenerateNotFoundResponse(c, code, err.Error())
+	case errors.Is(err, subscription.ErrInvalidInterval), errors.Is(err, subscription.ErrNoItems),
+		errors.Is(err, subscription.ErrInvalidItem), errors.Is(err, subscription.ErrIncompletePaymentMethod),
+		errors.Is(err, subscription.ErrNotActive), errors.Is(err, subscription.ErrNotPaused),
+		errors.Is(err, subscription.ErrCancelled), errors.Is(err, delivery.ErrSlotRequired):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, message)
+	}
+}
+
+// parseID parses the subscription ID path parameter, responding with a bad
+// request when it is not a number
+func parseID(c *gin.Context, code string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, "Invalid subscription ID")
+		return 0, false
+	}
+	return uint(id), true
+}
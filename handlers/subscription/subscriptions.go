@@ -0,0 +1,205 @@
+package subscription
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/subscription"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionItemRequest struct {
+	ProductVariantID uint `json:"product_variant_id" binding:"required"`
+	Quantity         int  `json:"quantity" binding:"required,min=1"`
+}
+
+type CreateSubscriptionRequest struct {
+	Interval          models.SubscriptionInterval `json:"interval" binding:"required,oneof=WEEKLY BIWEEKLY MONTHLY"`
+	Items             []SubscriptionItemRequest   `json:"items" binding:"required,min=1,dive"`
+	StartAt           *time.Time                  `json:"start_at"`            // first order; defaults to now
+	ShippingAddressID uint                        `json:"shipping_address_id"` // defaults to the user's default shipping address
+	BillingAddressID  *uint                       `json:"billing_address_id"`  // defaults to the shipping address
+	CustomerNotes     string                      `json:"customer_notes"`
+
+	// Revolut saved payment method the orders are paid with, saved by the
+	// storefront's card widget for merchant payments. Without one the customer
+	// is emailed a link to pay for each order.
+	RevolutCustomerID    string `json:"revolut_customer_id" binding:"max=100"`
+	SavedPaymentMethodID string `json:"saved_payment_method_id" binding:"max=100"`
+}
+
+type PauseSubscriptionRequest struct {
+	ResumeAt *time.Time `json:"resume_at"` // null until resumed by the customer
+}
+
+// CreateSubscription subscribes the current user to a recurring order
+func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
+	var req CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "subscription/create", err.Error())
+		return
+	}
+
+	items := make([]subscription.ItemInput, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, subscription.ItemInput{ProductVariantID: item.ProductVariantID, Quantity: item.Quantity})
+	}
+	created, err := h.subscriptions.Create(c.Request.Context(), subscription.Input{
+		UserID:               c.GetUint("user_id"),
+		Interval:             req.Interval,
+		Items:                items,
+		StartAt:              req.StartAt,
+		ShippingAddressID:    req.ShippingAddressID,
+		BillingAddressID:     req.BillingAddressID,
+		CustomerNotes:        req.CustomerNotes,
+		RevolutCustomerID:    req.RevolutCustomerID,
+		SavedPaymentMethodID: req.SavedPaymentMethodID,
+	})
+	if err != nil {
+		subscriptionError(c, "subscription/create", err, "Failed to create subscription")
+		return
+	}
+	response.GenerateCreatedResponse(c, "Subscription created successfully", created)
+}
+
+// GetSubscriptions lists the current user's subscriptions
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	subscriptions, err := h.subscriptions.List(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "subscription/get_subscriptions", "Failed to get subscriptions")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Subscriptions retrieved successfully", subscriptions)
+}
+
+// GetSubscription returns one of the current user's subscriptions
+func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
+	id, ok := parseID(c, "subscription/get_subscription")
+	if !ok {
+		return
+	}
+	found, err := h.subscriptions.Get(c.Request.Context(), c.GetUint("user_id"), id)
+	if err != nil {
+		subscriptionError(c, "subscription/get_subscription", err, "Failed to get subscription")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Subscription retrieved successfully", found)
+}
+
+// SkipSubscription skips the next order of one of the current user's
+// subscriptions
+func (h *SubscriptionHandler) SkipSubscription(c *gin.Context) {
+	id, ok := parseID(c, "subscription/skip")
+	if !ok {
+		return
+	}
+	skipped, err := h.subscriptions.Skip(c.Request.Context(), c.GetUint("user_id"), id)
+	if err != nil {
+		subscriptionError(c, "subscription/skip", err, "Failed to skip order")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Next order skipped successfully", skipped)
+}
+
+// PauseSubscription pauses one of the current user's subscriptions, until
+// resume_at when given
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	id, ok := parseID(c, "subscription/pause")
+	if !ok {
+		return
+	}
+	var req PauseSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.GenerateBadRequestResponse(c, "subscription/pause", err.Error())
+			return
+		}
+	}
+	if req.ResumeAt != nil && !req.ResumeAt.After(time.Now()) {
+		response.GenerateBadRequestResponse(c, "subscription/pause", "resume_at must be in the future")
+		return
+	}
+	paused, err := h.subscriptions.Pause(c.Request.Context(), c.GetUint("user_id"), id, req.ResumeAt)
+	if err != nil {
+		subscriptionError(c, "subscription/pause", err, "Failed to pause subscription")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Subscription paused successfully", paused)
+}
+
+// ResumeSubscription resumes one of the current user's paused subscriptions
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	id, ok := parseID(c, "subscription/resume")
+	if !ok {
+		return
+	}
+	resumed, err := h.subscriptions.Resume(c.Request.Context(), c.GetUint("user_id"), id)
+	if err != nil {
+		subscriptionError(c, "subscription/resume", err, "Failed to resume subscription")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Subscription resumed successfully", resumed)
+}
+
+// CancelSubscription cancels one of the current user's subscriptions
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	id, ok := parseID(c, "subscription/cancel")
+	if !ok {
+		return
+	}
+	cancelled, err := h.subscriptions.Cancel(c.Request.Context(), c.GetUint("user_id"), id)
+	if err != nil {
+		subscriptionError(c, "subscription/cancel", err, "Failed to cancel subscription")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Subscription cancelled successfully", cancelled)
+}
+
+// GetAllSubscriptions - Admin endpoint to list subscriptions with filtering
+// and pagination
+func (h *SubscriptionHandler) GetAllSubscriptions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := h.db.WithContext(c.Request.Context()).Model(&models.Subscription{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	// Subscriptions whose last run placed no order
+	if c.Query("failing") == "true" {
+		query = query.Where("failed_runs > 0")
+	}
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var subscriptions []models.Subscription
+	if err := query.Preload("Items.ProductVariant.Product").
+		Order("next_run_at").
+		Limit(limit).
+		Offset(offset).
+		Find(&subscriptions).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "subscription/get_all_subscriptions", "Failed to get subscriptions")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Subscriptions retrieved successfully", map[string]interface{}{
+		"subscriptions": subscriptions,
+		"page":          page,
+		"limit":         limit,
+		"total_count":   totalCount,
+		"total_pages":   (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
@@ -1,13 +1,19 @@
 package support
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/dto"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/trash"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -39,13 +45,16 @@ type UpdateAbuseReportRequest struct {
 	Severity      models.AbuseSeverity     `json:"severity,omitempty"`
 	Resolution    string                   `json:"resolution,omitempty"`
 	InternalNotes string                   `json:"internal_notes,omitempty"`
+	// Action "remove" takes down the reported review or product; the report
+	// must be resolved in the same request
+	Action models.EnforcementActionType `json:"action,omitempty"`
 }
 
 // CreateAbuseReport creates a new abuse report
 func (h *SupportHandler) CreateAbuseReport(c *gin.Context) {
 	var request CreateAbuseReportRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/create-abuse-report", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/create-abuse-report", err)
 		return
 	}
 
@@ -97,7 +106,7 @@ func (h *SupportHandler) CreateAbuseReport(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Abuse report created successfully", abuseReport)
+	response.GenerateSuccessResponse(c, "Abuse report created successfully", dto.NewAbuseReport(&abuseReport, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // GetAbuseReport retrieves a specific abuse report
@@ -121,17 +130,21 @@ func (h *SupportHandler) GetAbuseReport(c *gin.Context) {
 		return
 	}
 
-	userType, _ := c.Get("user_type")
-	if abuseReport.ReporterID != userID.(uint) && userType != models.Admin {
+	if abuseReport.ReporterID != userID.(uint) && !permissions.ContextHas(c, permissions.SupportRead) {
 		response.GenerateForbiddenResponse(c, "support/get-abuse-report", "Access denied")
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Abuse report retrieved successfully", abuseReport)
+	response.GenerateSuccessResponse(c, "Abuse report retrieved successfully", dto.NewAbuseReport(&abuseReport, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // applyAbuseFilters applies filters/sort/pagination to the abuse report query
-func (h *SupportHandler) applyAbuseFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, int, int) {
+func (h *SupportHandler) applyAbuseFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, Pagination, error) {
+	return paginate(c, h.filterAbuseReports(c, query))
+}
+
+// filterAbuseReports applies the abuse reports list filters and sort order
+func (h *SupportHandler) filterAbuseReports(c *gin.Context, query *gorm.DB) *gorm.DB {
 	// Filters
 	if status := c.Query("status"); status != "" {
 		statuses := strings.Split(strings.ToUpper(status), ",")
@@ -179,20 +192,7 @@ func (h *SupportHandler) applyAbuseFilters(c *gin.Context, query *gorm.DB) (*gor
 	default:
 		query = query.Order("created_at DESC")
 	}
-
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize <= 0 || pageSize > 200 {
-		pageSize = 20
-	}
-	offset := (page - 1) * pageSize
-	query = query.Offset(offset).Limit(pageSize)
-
-	return query, page, pageSize
+	return query
 }
 
 // GetUserAbuseReports retrieves all abuse reports for the current user
@@ -205,32 +205,45 @@ func (h *SupportHandler) GetUserAbuseReports(c *gin.Context) {
 
 	var abuseReports []models.AbuseReport
 	q := h.db.Where("reporter_id = ?", userID).Model(&models.AbuseReport{})
-	q, _, _ = h.applyAbuseFilters(c, q)
+	q, pagination, err := h.applyAbuseFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-user-abuse-reports", err.Error())
+		return
+	}
 	if err := q.Preload("Reporter").Preload("ReportedUser").Preload("Product").Preload("Review").Preload("Order").Order("created_at DESC").Find(&abuseReports).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-user-abuse-reports", err.Error())
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "User abuse reports retrieved successfully", abuseReports)
+	response.GenerateSuccessResponse(c, "User abuse reports retrieved successfully", gin.H{
+		"abuse_reports": dto.NewAbuseReports(abuseReports, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination":    pagination,
+	})
 }
 
 // GetAllAbuseReports retrieves all abuse reports (admin only)
 func (h *SupportHandler) GetAllAbuseReports(c *gin.Context) {
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportRead) {
 		response.GenerateForbiddenResponse(c, "support/get-all-abuse-reports", "Admin access required")
 		return
 	}
 
 	var abuseReports []models.AbuseReport
 	q := h.db.Model(&models.AbuseReport{})
-	q, _, _ = h.applyAbuseFilters(c, q)
+	q, pagination, err := h.applyAbuseFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-all-abuse-reports", err.Error())
+		return
+	}
 	if err := q.Preload("Reporter").Preload("ReportedUser").Preload("Product").Preload("Review").Preload("Order").Find(&abuseReports).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-all-abuse-reports", err.Error())
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "All abuse reports retrieved successfully", abuseReports)
+	response.GenerateSuccessResponse(c, "All abuse reports retrieved successfully", gin.H{
+		"abuse_reports": dto.NewAbuseReports(abuseReports, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination":    pagination,
+	})
 }
 
 // UpdateAbuseReport updates an abuse report
@@ -243,7 +256,7 @@ func (h *SupportHandler) UpdateAbuseReport(c *gin.Context) {
 
 	var request UpdateAbuseReportRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/update-abuse-report", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/update-abuse-report", err)
 		return
 	}
 
@@ -254,12 +267,26 @@ func (h *SupportHandler) UpdateAbuseReport(c *gin.Context) {
 	}
 
 	// Only admins can update abuse reports
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/update-abuse-report", "Admin access required")
 		return
 	}
 
+	if request.Action != "" {
+		if request.Action != models.EnforcementActionRemove {
+			response.GenerateBadRequestResponse(c, "support/update-abuse-report", "Unknown action")
+			return
+		}
+		if request.Status != models.AbuseReportStatusResolved {
+			response.GenerateBadRequestResponse(c, "support/update-abuse-report", "The report must be resolved to remove its content")
+			return
+		}
+		if abuseReport.ReviewID == nil && abuseReport.ProductID == nil {
+			response.GenerateBadRequestResponse(c, "support/update-abuse-report", "Only reported reviews and products can be removed")
+			return
+		}
+	}
+
 	// Update fields
 	updates := make(map[string]interface{})
 	if request.Status != "" {
@@ -283,29 +310,30 @@ func (h *SupportHandler) UpdateAbuseReport(c *gin.Context) {
 		updates["internal_notes"] = request.InternalNotes
 	}
 
-	if err := h.db.Model(&abuseReport).Updates(updates).Error; err != nil {
-		response.GenerateInternalServerErrorResponse(c, "support/update-abuse-report", err.Error())
-		return
-	}
-
-	// Email notify reporter on status changes
-	if _, ok := updates["status"]; ok && h.emailTriggerSvc != nil {
-		var reporter models.User
-		if err := h.db.First(&reporter, abuseReport.ReporterID).Error; err == nil {
-			name := strings.TrimSpace(reporter.FirstName + " " + reporter.LastName)
-			data := map[string]interface{}{
-				"UserName":            name,
-				"ReportID":            abuseReport.ID,
-				"OldStatus":           "",
-				"NewStatus":           updates["status"],
-				"UserDescriptionHTML": template.HTML(abuseReport.Description),
-				"Category":            string(abuseReport.Category),
-				"Severity":            string(abuseReport.Severity),
-				"AdminNoteHTML":       template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
-				"subject":             fmt.Sprintf("Your abuse report #%d status updated", abuseReport.ID),
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&abuseReport).Updates(updates).Error; err != nil {
+			return err
+		}
+		h.queueAbuseStatusEmail(tx, &abuseReport, updates)
+		if request.Action == models.EnforcementActionRemove {
+			reason := request.Resolution
+			if reason == "" {
+				reason = abuseReport.Resolution
+			}
+			takedown, err := h.moderation.TakeDown(tx, &abuseReport, c.GetUint("user_id"), reason)
+			if err != nil {
+				return err
 			}
-			_ = h.emailTriggerSvc.TriggerAbuseStatusUpdated(reporter.Email, name, data)
+			h.queueContentRemovedEmail(tx, &abuseReport, takedown)
 		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, moderation.ErrAlreadyEnforced) {
+			response.GenerateBadRequestResponse(c, "support/update-abuse-report", "The reported content has already been removed")
+			return
+		}
+		response.GenerateInternalServerErrorResponse(c, "support/update-abuse-report", err.Error())
+		return
 	}
 
 	// Load updated abuse report
@@ -314,7 +342,7 @@ func (h *SupportHandler) UpdateAbuseReport(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Abuse report updated successfully", abuseReport)
+	response.GenerateSuccessResponse(c, "Abuse report updated successfully", dto.NewAbuseReport(&abuseReport, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // DeleteAbuseReport deletes an abuse report (admin only)
@@ -325,8 +353,7 @@ func (h *SupportHandler) DeleteAbuseReport(c *gin.Context) {
 		return
 	}
 
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/delete-abuse-report", "Admin access required")
 		return
 	}
@@ -337,10 +364,59 @@ func (h *SupportHandler) DeleteAbuseReport(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&abuseReport).Error; err != nil {
+	// With its attachments, so that it can be restored
+	if err := h.db.Transaction(func(tx *gorm.DB) error { return trash.Delete(tx, trash.AbuseReports, abuseReport.ID) }); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/delete-abuse-report", err.Error())
 		return
 	}
 
 	response.GenerateSuccessResponse(c, "Abuse report deleted successfully", nil)
 }
+
+// queueAbuseStatusEmail records the status update email for an abuse report in the outbox of tx
+func (h *SupportHandler) queueAbuseStatusEmail(tx *gorm.DB, abuseReport *models.AbuseReport, updates map[string]interface{}) {
+	if _, ok := updates["status"]; !ok || h.emailTriggerSvc == nil {
+		return
+	}
+	var reporter models.User
+	if err := tx.First(&reporter, abuseReport.ReporterID).Error; err == nil {
+		name := strings.TrimSpace(reporter.FirstName + " " + reporter.LastName)
+		data := map[string]interface{}{
+			"UserName":            name,
+			"ReportID":            abuseReport.ID,
+			"OldStatus":           "",
+			"NewStatus":           updates["status"],
+			"UserDescriptionHTML": template.HTML(abuseReport.Description),
+			"Category":            string(abuseReport.Category),
+			"Severity":            string(abuseReport.Severity),
+			"AdminNoteHTML":       template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
+			"subject":             fmt.Sprintf("Your abuse report #%d status updated", abuseReport.ID),
+		}
+		if err := h.emailTriggerSvc.InTx(tx).TriggerAbuseStatusUpdated(reporter.Email, name, data); err != nil {
+			log.Printf("Failed to queue abuse report status email for report %d: %v", abuseReport.ID, err)
+		}
+	}
+}
+
+// queueContentRemovedEmail records the email telling the owner of taken down
+// content why it was removed in the outbox of tx
+func (h *SupportHandler) queueContentRemovedEmail(tx *gorm.DB, abuseReport *models.AbuseReport, takedown *moderation.Takedown) {
+	if takedown.Owner == nil || h.emailTriggerSvc == nil {
+		return
+	}
+	owner := takedown.Owner
+	name := strings.TrimSpace(owner.FirstName + " " + owner.LastName)
+	contentType := string(takedown.Action.TargetType)
+	data := map[string]interface{}{
+		"UserName":     name,
+		"ContentType":  contentType,
+		"ContentTitle": takedown.ContentTitle,
+		"Category":     string(abuseReport.Category),
+		"Reason":       takedown.Action.Reason,
+		"RemovedAt":    time.Now().Format("January 2, 2006"),
+		"subject":      fmt.Sprintf("Your %s has been removed", contentType),
+	}
+	if err := h.emailTriggerSvc.InTx(tx).TriggerContentRemoved(owner.Email, name, data); err != nil {
+		log.Printf("Failed to queue content removed email for report %d: %v", abuseReport.ID, err)
+	}
+}
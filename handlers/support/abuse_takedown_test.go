@@ -0,0 +1,115 @@
+package support
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTakedownTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{}, &models.Order{}, &models.Product{}, &models.ProductVariant{},
+		&models.ProductReview{}, &models.ReviewModerationLog{}, &models.ProductRating{}, &models.ProductOverallRating{},
+		&models.AbuseReport{}, &models.AbuseReportAttachment{}, &models.EnforcementAction{},
+		&models.UserStrike{},
+	))
+
+	handler := NewSupportHandler(db, nil, nil, nil)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(99))
+		c.Set("user_type", models.Admin)
+	})
+	router.PUT("/admin/abuse-reports/:id", handler.UpdateAbuseReport)
+	return router, db
+}
+
+func TestUpdateAbuseReportRemovesContent(t *testing.T) {
+	router, db := setupTakedownTest(t)
+	author := models.User{Email: "author@example.com", FirstName: "Amina", IsActive: true}
+	seller := models.User{Email: "seller@example.com", FirstName: "Karim", IsActive: true}
+	require.NoError(t, db.Create(&author).Error)
+	require.NoError(t, db.Create(&seller).Error)
+	product := models.Product{Name: "Counterfeit watch", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{ProductID: product.ID, Name: "Default", SKU: "WATCH-1"}
+	require.NoError(t, db.Create(&variant).Error)
+	review := models.ProductReview{ProductVariantID: variant.ID, UserID: author.ID, Rating: 1, Title: "Buy elsewhere", Content: "Spam link", Status: models.ReviewStatusApproved}
+	require.NoError(t, db.Create(&review).Error)
+
+	reviewReport := models.AbuseReport{ReporterID: seller.ID, ReviewID: &review.ID, Category: models.AbuseCategorySpam, Description: "Spam", Status: models.AbuseReportStatusPending}
+	productReport := models.AbuseReport{ReporterID: author.ID, ReportedUserID: &seller.ID, ProductID: &product.ID, Category: models.AbuseCategorySpam, Description: "Fake", Status: models.AbuseReportStatusPending}
+	orderReport := models.AbuseReport{ReporterID: author.ID, Category: models.AbuseCategorySpam, Description: "Other", Status: models.AbuseReportStatusPending}
+	for _, report := range []*models.AbuseReport{&reviewReport, &productReport, &orderReport} {
+		require.NoError(t, db.Create(report).Error)
+	}
+	path := func(id uint) string { return fmt.Sprintf("/admin/abuse-reports/%d", id) }
+
+	t.Run("Error - Report must be resolved", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPut, path(reviewReport.ID), `{"status": "REVIEWING", "action": "remove"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Error - Report must be about a review or product", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPut, path(orderReport.ID), `{"status": "RESOLVED", "action": "remove"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Success - Review is flagged and its author gets a strike", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPut, path(reviewReport.ID), `{"status": "RESOLVED", "resolution": "Spam link", "action": "remove"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var reloaded models.ProductReview
+		require.NoError(t, db.First(&reloaded, review.ID).Error)
+		assert.Equal(t, models.ReviewStatusFlagged, reloaded.Status)
+		assert.Equal(t, "Spam link", reloaded.ModerationReason)
+
+		var action models.EnforcementAction
+		require.NoError(t, db.Where("abuse_report_id = ?", reviewReport.ID).First(&action).Error)
+		assert.Equal(t, models.EnforcementTargetReview, action.TargetType)
+		assert.Equal(t, review.ID, action.TargetID)
+		require.NotNil(t, action.UserID)
+		assert.Equal(t, author.ID, *action.UserID)
+
+		require.NoError(t, db.First(&author, author.ID).Error)
+		assert.Equal(t, 1, author.StrikeCount)
+		var strike models.UserStrike
+		require.NoError(t, db.Where("user_id = ?", author.ID).First(&strike).Error)
+		assert.Equal(t, models.StrikeSourceAbuseReport, strike.Source)
+		require.NotNil(t, strike.EnforcementActionID)
+		assert.Equal(t, action.ID, *strike.EnforcementActionID)
+
+		var rating models.ProductRating
+		require.NoError(t, db.Where("product_variant_id = ?", variant.ID).First(&rating).Error)
+		assert.Equal(t, 0, rating.TotalReviews)
+	})
+
+	t.Run("Error - Content is only removed once per report", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPut, path(reviewReport.ID), `{"status": "RESOLVED", "action": "remove"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		require.NoError(t, db.First(&author, author.ID).Error)
+		assert.Equal(t, 1, author.StrikeCount)
+	})
+
+	t.Run("Success - Product is unpublished and the reported user gets a strike", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPut, path(productReport.ID), `{"status": "RESOLVED", "action": "remove"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		require.NoError(t, db.First(&product, product.ID).Error)
+		assert.False(t, product.IsActive)
+
+		require.NoError(t, db.First(&seller, seller.ID).Error)
+		assert.Equal(t, 1, seller.StrikeCount)
+	})
+}
@@ -0,0 +1,39 @@
+package support
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/uploads"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadAttachment uploads a file for a ticket, dispute or abuse report. The
+// returned file details are passed in the attachments of the create request;
+// uploads that are never attached are deleted after a day.
+func (h *SupportHandler) UploadAttachment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "support/upload-attachment", "User not authenticated")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "support/upload-attachment", "A file is required")
+		return
+	}
+
+	upload, err := h.uploads.Upload(userID.(uint), fileHeader)
+	if err != nil {
+		switch {
+		case errors.Is(err, uploads.ErrFileTooLarge), errors.Is(err, uploads.ErrFileTypeNotAllowed), errors.Is(err, uploads.ErrInfected):
+			response.GenerateBadRequestResponse(c, "support/upload-attachment", err.Error())
+		default:
+			response.GenerateInternalServerErrorResponse(c, "support/upload-attachment", "Failed to upload attachment")
+		}
+		return
+	}
+
+	response.GenerateCreatedResponse(c, "Attachment uploaded successfully", upload)
+}
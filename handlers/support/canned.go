@@ -0,0 +1,313 @@
+package support
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errCannedResponseNotFound is returned when a canned response does not exist or is inactive
+var errCannedResponseNotFound = errors.New("canned response not found")
+
+// CannedResponseRequest represents the request body for creating or updating a canned response
+type CannedResponseRequest struct {
+	Title    string `json:"title" binding:"required"`
+	Body     string `json:"body" binding:"required"`
+	Category string `json:"category"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// PreviewCannedResponseRequest represents the request body for previewing a canned response
+type PreviewCannedResponseRequest struct {
+	TicketID  *uint `json:"ticket_id"`
+	DisputeID *uint `json:"dispute_id"`
+}
+
+// CannedResponseData holds the variables a canned response body can use
+type CannedResponseData struct {
+	UserName     string
+	FirstName    string
+	AgentName    string
+	TicketID     uint
+	TicketTitle  string
+	DisputeID    uint
+	DisputeTitle string
+	OrderID      uint
+}
+
+// GetCannedResponses lists canned responses, filtered by category, search text and active state
+func (h *SupportHandler) GetCannedResponses(c *gin.Context) {
+	query := h.db.Model(&models.CannedResponse{})
+	if category := strings.ToUpper(c.Query("category")); category != "" {
+		// Responses without a category apply to every category
+		query = query.Where("category = ? OR category = ''", category)
+	}
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(title) LIKE ? OR LOWER(body) LIKE ?", like, like)
+	}
+	if active := c.Query("active"); active != "" {
+		query = query.Where("is_active = ?", active == "true")
+	}
+
+	var cannedResponses []models.CannedResponse
+	if err := query.Order("usage_count DESC, title ASC").Find(&cannedResponses).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-canned-responses", "Failed to get canned responses")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Canned responses retrieved successfully", cannedResponses)
+}
+
+// GetCannedResponse retrieves a single canned response
+func (h *SupportHandler) GetCannedResponse(c *gin.Context) {
+	cannedResponse, ok := h.findCannedResponse(c, "support/get-canned-response")
+	if !ok {
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Canned response retrieved successfully", cannedResponse)
+}
+
+// CreateCannedResponse adds a canned response to the library
+func (h *SupportHandler) CreateCannedResponse(c *gin.Context) {
+	var request CannedResponseRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.GenerateValidationErrorResponse(c, "support/create-canned-response", err)
+		return
+	}
+	if _, err := renderCannedResponse(request.Body, CannedResponseData{}); err != nil {
+		response.GenerateBadRequestResponse(c, "support/create-canned-response", err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "support/create-canned-response", "User not authenticated")
+		return
+	}
+
+	cannedResponse := models.CannedResponse{IsActive: true, CreatedBy: userID.(uint)}
+	applyCannedResponseRequest(&cannedResponse, &request)
+
+	if err := h.db.Create(&cannedResponse).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/create-canned-response", err.Error())
+		return
+	}
+
+	response.GenerateCreatedResponse(c, "Canned response created successfully", cannedResponse)
+}
+
+// UpdateCannedResponse replaces a canned response
+func (h *SupportHandler) UpdateCannedResponse(c *gin.Context) {
+	cannedResponse, ok := h.findCannedResponse(c, "support/update-canned-response")
+	if !ok {
+		return
+	}
+
+	var request CannedResponseRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.GenerateValidationErrorResponse(c, "support/update-canned-response", err)
+		return
+	}
+	if _, err := renderCannedResponse(request.Body, CannedResponseData{}); err != nil {
+		response.GenerateBadRequestResponse(c, "support/update-canned-response", err.Error())
+		return
+	}
+	applyCannedResponseRequest(cannedResponse, &request)
+
+	if err := h.db.Save(cannedResponse).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/update-canned-response", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Canned response updated successfully", cannedResponse)
+}
+
+// DeleteCannedResponse removes a canned response from the library
+func (h *SupportHandler) DeleteCannedResponse(c *gin.Context) {
+	cannedResponse, ok := h.findCannedResponse(c, "support/delete-canned-response")
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(cannedResponse).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/delete-canned-response", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Canned response deleted successfully", nil)
+}
+
+// PreviewCannedResponse expands a canned response for a ticket or dispute without sending it
+func (h *SupportHandler) PreviewCannedResponse(c *gin.Context) {
+	cannedResponse, ok := h.findCannedResponse(c, "support/preview-canned-response")
+	if !ok {
+		return
+	}
+
+	var request PreviewCannedResponseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			response.GenerateValidationErrorResponse(c, "support/preview-canned-response", err)
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+	agentID, _ := userID.(uint)
+	data := CannedResponseData{}
+	switch {
+	case request.TicketID != nil:
+		var ticket models.SupportTicket
+		if err := h.db.First(&ticket, *request.TicketID).Error; err != nil {
+			response.GenerateNotFoundResponse(c, "support/preview-canned-response", "Ticket not found")
+			return
+		}
+		data = h.ticketCannedResponseData(&ticket, agentID)
+	case request.DisputeID != nil:
+		var dispute models.Dispute
+		if err := h.db.First(&dispute, *request.DisputeID).Error; err != nil {
+			response.GenerateNotFoundResponse(c, "support/preview-canned-response", "Dispute not found")
+			return
+		}
+		data = h.disputeCannedResponseData(&dispute, agentID)
+	}
+
+	message, err := renderCannedResponse(cannedResponse.Body, data)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "support/preview-canned-response", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Canned response preview rendered successfully", gin.H{
+		"message": message,
+	})
+}
+
+// expandCannedResponse renders an active canned response with data and counts the use
+func (h *SupportHandler) expandCannedResponse(id uint, data CannedResponseData) (string, error) {
+	var cannedResponse models.CannedResponse
+	if err := h.db.Where("is_active = ?", true).First(&cannedResponse, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errCannedResponseNotFound
+		}
+		return "", err
+	}
+
+	message, err := renderCannedResponse(cannedResponse.Body, data)
+	if err != nil {
+		return "", err
+	}
+	h.db.Model(&cannedResponse).UpdateColumn("usage_count", gorm.Expr("usage_count + ?", 1))
+	return message, nil
+}
+
+// composeResponseMessage builds the message of a ticket or dispute response:
+// the expanded canned response, if any, followed by the agent's own text
+func (h *SupportHandler) composeResponseMessage(c *gin.Context, code, message string, cannedResponseID *uint, isAdmin bool, data func() CannedResponseData) (string, bool) {
+	if cannedResponseID != nil {
+		if !isAdmin {
+			response.GenerateForbiddenResponse(c, code, "Only support agents can use canned responses")
+			return "", false
+		}
+		expanded, err := h.expandCannedResponse(*cannedResponseID, data())
+		if err != nil {
+			if errors.Is(err, errCannedResponseNotFound) {
+				response.GenerateNotFoundResponse(c, code, "Canned response not found")
+			} else {
+				response.GenerateBadRequestResponse(c, code, err.Error())
+			}
+			return "", false
+		}
+		if extra := strings.TrimSpace(message); extra != "" {
+			expanded += "\n\n" + extra
+		}
+		message = expanded
+	}
+
+	if strings.TrimSpace(message) == "" {
+		response.GenerateBadRequestResponse(c, code, "message or canned_response_id is required")
+		return "", false
+	}
+	return message, true
+}
+
+func (h *SupportHandler) ticketCannedResponseData(ticket *models.SupportTicket, agentID uint) CannedResponseData {
+	data := h.cannedResponseData(ticket.UserID, agentID, ticket.OrderID)
+	data.TicketID = ticket.ID
+	data.TicketTitle = ticket.Title
+	return data
+}
+
+func (h *SupportHandler) disputeCannedResponseData(dispute *models.Dispute, agentID uint) CannedResponseData {
+	data := h.cannedResponseData(dispute.UserID, agentID, dispute.OrderID)
+	data.DisputeID = dispute.ID
+	data.DisputeTitle = dispute.Title
+	return data
+}
+
+func (h *SupportHandler) cannedResponseData(ownerID, agentID uint, orderID *uint) CannedResponseData {
+	var data CannedResponseData
+	var owner models.User
+	if err := h.db.First(&owner, ownerID).Error; err == nil {
+		data.FirstName = owner.FirstName
+		data.UserName = strings.TrimSpace(owner.FirstName + " " + owner.LastName)
+	}
+	var agent models.User
+	if err := h.db.First(&agent, agentID).Error; err == nil {
+		data.AgentName = strings.TrimSpace(agent.FirstName + " " + agent.LastName)
+	}
+	if orderID != nil {
+		data.OrderID = *orderID
+	}
+	return data
+}
+
+// findCannedResponse loads the canned response named by the :id parameter,
+// writing an error response when it cannot
+func (h *SupportHandler) findCannedResponse(c *gin.Context, code string) (*models.CannedResponse, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, "Invalid canned response ID")
+		return nil, false
+	}
+
+	var cannedResponse models.CannedResponse
+	if err := h.db.First(&cannedResponse, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, code, "Canned response not found")
+		return nil, false
+	}
+	return &cannedResponse, true
+}
+
+func applyCannedResponseRequest(cannedResponse *models.CannedResponse, request *CannedResponseRequest) {
+	cannedResponse.Title = request.Title
+	cannedResponse.Body = request.Body
+	cannedResponse.Category = strings.ToUpper(strings.TrimSpace(request.Category))
+	if request.IsActive != nil {
+		cannedResponse.IsActive = *request.IsActive
+	}
+}
+
+// renderCannedResponse expands a canned response body. Unknown variables are
+// reported as errors so typos are caught when the response is saved.
+func renderCannedResponse(body string, data CannedResponseData) (string, error) {
+	tmpl, err := template.New("canned_response").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid canned response template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid canned response template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,44 @@
+package support
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRenderCannedResponse(t *testing.T) {
+	message, err := renderCannedResponse("Hi {{.FirstName}}, we are looking into ticket #{{.TicketID}}.", CannedResponseData{FirstName: "Amina", TicketID: 42})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi Amina, we are looking into ticket #42.", message)
+
+	_, err = renderCannedResponse("Hi {{.Nmae}}", CannedResponseData{})
+	assert.Error(t, err)
+	_, err = renderCannedResponse("Hi {{.FirstName", CannedResponseData{})
+	assert.Error(t, err)
+}
+
+func TestExpandCannedResponse(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.CannedResponse{}))
+	handler := &SupportHandler{db: db}
+
+	active := models.CannedResponse{Title: "Refund", Body: "Hello {{.UserName}}, your refund for order {{.OrderID}} is on its way.", IsActive: true}
+	inactive := models.CannedResponse{Title: "Old", Body: "Old reply"}
+	require.NoError(t, db.Create(&active).Error)
+	require.NoError(t, db.Create(&inactive).Error)
+
+	message, err := handler.expandCannedResponse(active.ID, CannedResponseData{UserName: "Amina Bel", OrderID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello Amina Bel, your refund for order 7 is on its way.", message)
+
+	require.NoError(t, db.First(&active, active.ID).Error)
+	assert.Equal(t, 1, active.UsageCount)
+
+	_, err = handler.expandCannedResponse(inactive.ID, CannedResponseData{})
+	assert.ErrorIs(t, err, errCannedResponseNotFound)
+}
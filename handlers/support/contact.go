@@ -3,11 +3,14 @@ package support
 import (
 	"fmt"
 	"html/template"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/dto"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -41,7 +44,7 @@ type ReplyToContactInquiryRequest struct {
 func (h *SupportHandler) CreateContactInquiry(c *gin.Context) {
 	var request CreateContactInquiryRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/create-contact-inquiry", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/create-contact-inquiry", err)
 		return
 	}
 
@@ -76,7 +79,7 @@ func (h *SupportHandler) CreateContactInquiry(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Contact inquiry submitted successfully", contactInquiry)
+	response.GenerateSuccessResponse(c, "Contact inquiry submitted successfully", dto.NewContactInquiry(&contactInquiry, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // GetContactInquiry retrieves a specific contact inquiry
@@ -100,17 +103,21 @@ func (h *SupportHandler) GetContactInquiry(c *gin.Context) {
 		return
 	}
 
-	userType, _ := c.Get("user_type")
-	if (contactInquiry.UserID == nil || *contactInquiry.UserID != userID.(uint)) && userType != models.Admin {
+	if (contactInquiry.UserID == nil || *contactInquiry.UserID != userID.(uint)) && !permissions.ContextHas(c, permissions.SupportRead) {
 		response.GenerateForbiddenResponse(c, "support/get-contact-inquiry", "Access denied")
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Contact inquiry retrieved successfully", contactInquiry)
+	response.GenerateSuccessResponse(c, "Contact inquiry retrieved successfully", dto.NewContactInquiry(&contactInquiry, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // applyContactFilters applies filters/sort/pagination to the contact queries
-func (h *SupportHandler) applyContactFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, int, int) {
+func (h *SupportHandler) applyContactFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, Pagination, error) {
+	return paginate(c, h.filterContactInquiries(c, query))
+}
+
+// filterContactInquiries applies the contact inquiries list filters and sort order
+func (h *SupportHandler) filterContactInquiries(c *gin.Context, query *gorm.DB) *gorm.DB {
 	if status := c.Query("status"); status != "" {
 		query = query.Where("status IN ?", strings.Split(strings.ToUpper(status), ","))
 	}
@@ -151,17 +158,7 @@ func (h *SupportHandler) applyContactFilters(c *gin.Context, query *gorm.DB) (*g
 	default:
 		query = query.Order("created_at DESC")
 	}
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize <= 0 || pageSize > 200 {
-		pageSize = 20
-	}
-	query = query.Offset((page - 1) * pageSize).Limit(pageSize)
-	return query, page, pageSize
+	return query
 }
 
 // GetUserContactInquiries retrieves all contact inquiries for the current user
@@ -173,29 +170,42 @@ func (h *SupportHandler) GetUserContactInquiries(c *gin.Context) {
 	}
 	var contactInquiries []models.ContactInquiry
 	q := h.db.Where("user_id = ?", userID).Model(&models.ContactInquiry{})
-	q, _, _ = h.applyContactFilters(c, q)
+	q, pagination, err := h.applyContactFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-user-contact-inquiries", err.Error())
+		return
+	}
 	if err := q.Preload("User").Order("created_at DESC").Find(&contactInquiries).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-user-contact-inquiries", err.Error())
 		return
 	}
-	response.GenerateSuccessResponse(c, "User contact inquiries retrieved successfully", contactInquiries)
+	response.GenerateSuccessResponse(c, "User contact inquiries retrieved successfully", gin.H{
+		"contact_inquiries": dto.NewContactInquiries(contactInquiries, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination":        pagination,
+	})
 }
 
 // GetAllContactInquiries retrieves all contact inquiries (admin only)
 func (h *SupportHandler) GetAllContactInquiries(c *gin.Context) {
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportRead) {
 		response.GenerateForbiddenResponse(c, "support/get-all-contact-inquiries", "Admin access required")
 		return
 	}
 	var contactInquiries []models.ContactInquiry
 	q := h.db.Model(&models.ContactInquiry{})
-	q, _, _ = h.applyContactFilters(c, q)
+	q, pagination, err := h.applyContactFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-all-contact-inquiries", err.Error())
+		return
+	}
 	if err := q.Preload("User").Find(&contactInquiries).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-all-contact-inquiries", err.Error())
 		return
 	}
-	response.GenerateSuccessResponse(c, "All contact inquiries retrieved successfully", contactInquiries)
+	response.GenerateSuccessResponse(c, "All contact inquiries retrieved successfully", gin.H{
+		"contact_inquiries": dto.NewContactInquiries(contactInquiries, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination":        pagination,
+	})
 }
 
 // UpdateContactInquiry updates a contact inquiry
@@ -208,7 +218,7 @@ func (h *SupportHandler) UpdateContactInquiry(c *gin.Context) {
 
 	var request UpdateContactInquiryRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/update-contact-inquiry", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/update-contact-inquiry", err)
 		return
 	}
 
@@ -219,8 +229,7 @@ func (h *SupportHandler) UpdateContactInquiry(c *gin.Context) {
 	}
 
 	// Only admins can update contact inquiries
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/update-contact-inquiry", "Admin access required")
 		return
 	}
@@ -246,48 +255,24 @@ func (h *SupportHandler) UpdateContactInquiry(c *gin.Context) {
 		updates["internal_notes"] = request.InternalNotes
 	}
 
-	if err := h.db.Model(&contactInquiry).Updates(updates).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&contactInquiry).Updates(updates).Error; err != nil {
+			return err
+		}
+		h.queueContactStatusEmail(tx, &contactInquiry, updates)
+		return nil
+	}); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/update-contact-inquiry", err.Error())
 		return
 	}
 
-	// Email notify user on status change
-	if _, ok := updates["status"]; ok && h.emailTriggerSvc != nil {
-		name := contactInquiry.Name
-		emailAddr := contactInquiry.Email
-		if contactInquiry.UserID != nil {
-			var u models.User
-			if err := h.db.First(&u, *contactInquiry.UserID).Error; err == nil {
-				if name == "" {
-					name = strings.TrimSpace(u.FirstName + " " + u.LastName)
-				}
-				if emailAddr == "" {
-					emailAddr = u.Email
-				}
-			}
-		}
-		data := map[string]interface{}{
-			"Name":            name,
-			"InquiryID":       contactInquiry.ID,
-			"Subject":         contactInquiry.Subject,
-			"OldStatus":       "",
-			"NewStatus":       updates["status"],
-			"UserMessageHTML": template.HTML(contactInquiry.Message),
-			"Category":        string(contactInquiry.Category),
-			"Priority":        string(contactInquiry.Priority),
-			"AdminNoteHTML":   template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
-			"subject":         fmt.Sprintf("Your inquiry #%d status updated", contactInquiry.ID),
-		}
-		_ = h.emailTriggerSvc.TriggerContactStatusUpdated(emailAddr, name, data)
-	}
-
 	// Load updated contact inquiry
 	if err := h.db.Preload("User").First(&contactInquiry, inquiryID).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/update-contact-inquiry", "Failed to load updated contact inquiry")
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Contact inquiry updated successfully", contactInquiry)
+	response.GenerateSuccessResponse(c, "Contact inquiry updated successfully", dto.NewContactInquiry(&contactInquiry, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // DeleteContactInquiry deletes a contact inquiry (admin only)
@@ -298,8 +283,7 @@ func (h *SupportHandler) DeleteContactInquiry(c *gin.Context) {
 		return
 	}
 
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/delete-contact-inquiry", "Admin access required")
 		return
 	}
@@ -321,8 +305,7 @@ func (h *SupportHandler) DeleteContactInquiry(c *gin.Context) {
 // ReplyToContactInquiry allows admin to reply and sends an email to the inquirer
 func (h *SupportHandler) ReplyToContactInquiry(c *gin.Context) {
 	// Admin check
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/reply-contact-inquiry", "Admin access required")
 		return
 	}
@@ -335,7 +318,7 @@ func (h *SupportHandler) ReplyToContactInquiry(c *gin.Context) {
 
 	var req ReplyToContactInquiryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.GenerateBadRequestResponse(c, "support/reply-contact-inquiry", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/reply-contact-inquiry", err)
 		return
 	}
 
@@ -389,7 +372,6 @@ func (h *SupportHandler) ReplyToContactInquiry(c *gin.Context) {
 		"AdminResponseHTML": template.HTML(req.ResponseHTML),
 		"AdminName":         adminName,
 		"RespondedAt":       now.Format("2006-01-02 15:04:05"),
-		"SupportEmail":      "enquirees@algeriamarket.co.uk",
 		"InquiryID":         inquiry.ID,
 		"subject":           "Response to your inquiry: " + inquiry.Subject,
 	}
@@ -411,5 +393,40 @@ func (h *SupportHandler) ReplyToContactInquiry(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Inquiry replied and email sent", inquiry)
+	response.GenerateSuccessResponse(c, "Inquiry replied and email sent", dto.NewContactInquiry(&inquiry, dto.AudienceFor(c, permissions.SupportRead)))
+}
+
+// queueContactStatusEmail records the status update email for a contact inquiry in the outbox of tx
+func (h *SupportHandler) queueContactStatusEmail(tx *gorm.DB, contactInquiry *models.ContactInquiry, updates map[string]interface{}) {
+	if _, ok := updates["status"]; !ok || h.emailTriggerSvc == nil {
+		return
+	}
+	name := contactInquiry.Name
+	emailAddr := contactInquiry.Email
+	if contactInquiry.UserID != nil {
+		var u models.User
+		if err := tx.First(&u, *contactInquiry.UserID).Error; err == nil {
+			if name == "" {
+				name = strings.TrimSpace(u.FirstName + " " + u.LastName)
+			}
+			if emailAddr == "" {
+				emailAddr = u.Email
+			}
+		}
+	}
+	data := map[string]interface{}{
+		"Name":            name,
+		"InquiryID":       contactInquiry.ID,
+		"Subject":         contactInquiry.Subject,
+		"OldStatus":       "",
+		"NewStatus":       updates["status"],
+		"UserMessageHTML": template.HTML(contactInquiry.Message),
+		"Category":        string(contactInquiry.Category),
+		"Priority":        string(contactInquiry.Priority),
+		"AdminNoteHTML":   template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
+		"subject":         fmt.Sprintf("Your inquiry #%d status updated", contactInquiry.ID),
+	}
+	if err := h.emailTriggerSvc.InTx(tx).TriggerContactStatusUpdated(emailAddr, name, data); err != nil {
+		log.Printf("Failed to queue inquiry status email for inquiry %d: %v", contactInquiry.ID, err)
+	}
 }
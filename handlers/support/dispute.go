@@ -3,11 +3,16 @@ package support
 import (
 	"fmt"
 	"html/template"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/dto"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/trash"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -47,12 +52,18 @@ type UpdateDisputeRequest struct {
 
 // DisputeResponseRequest represents a response to a dispute
 type DisputeResponseRequest struct {
-	Message    string `json:"message" binding:"required"`
-	IsInternal bool   `json:"is_internal"`
+	Message          string `json:"message"`
+	CannedResponseID *uint  `json:"canned_response_id"` // expanded server-side; message is appended to it
+	IsInternal       bool   `json:"is_internal"`
 }
 
 // applyDisputeFilters applies filters/sort/pagination on disputes
-func (h *SupportHandler) applyDisputeFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, int, int) {
+func (h *SupportHandler) applyDisputeFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, Pagination, error) {
+	return paginate(c, h.filterDisputes(c, query))
+}
+
+// filterDisputes applies the disputes list filters and sort order
+func (h *SupportHandler) filterDisputes(c *gin.Context, query *gorm.DB) *gorm.DB {
 	if status := c.Query("status"); status != "" {
 		query = query.Where("status IN ?", strings.Split(strings.ToUpper(status), ","))
 	}
@@ -105,24 +116,14 @@ func (h *SupportHandler) applyDisputeFilters(c *gin.Context, query *gorm.DB) (*g
 	default:
 		query = query.Order("created_at DESC")
 	}
-	// pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize <= 0 || pageSize > 200 {
-		pageSize = 20
-	}
-	query = query.Offset((page - 1) * pageSize).Limit(pageSize)
-	return query, page, pageSize
+	return query
 }
 
 // CreateDispute creates a new dispute
 func (h *SupportHandler) CreateDispute(c *gin.Context) {
 	var request CreateDisputeRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/create-dispute", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/create-dispute", err)
 		return
 	}
 
@@ -151,6 +152,7 @@ func (h *SupportHandler) CreateDispute(c *gin.Context) {
 		response.GenerateInternalServerErrorResponse(c, "support/create-dispute", err.Error())
 		return
 	}
+	h.scheduleSLA(models.SLASubjectDispute, dispute.ID)
 
 	// Handle attachments if provided
 	if len(request.Attachments) > 0 {
@@ -175,7 +177,7 @@ func (h *SupportHandler) CreateDispute(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Dispute created successfully", dispute)
+	response.GenerateSuccessResponse(c, "Dispute created successfully", dto.NewDispute(&dispute, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // GetDispute retrieves a specific dispute
@@ -201,14 +203,13 @@ func (h *SupportHandler) GetDispute(c *gin.Context) {
 
 	// Only allow users to view their own disputes or admins to view any dispute
 	if dispute.UserID != userID.(uint) {
-		userType, _ := c.Get("user_type")
-		if userType != models.Admin {
+		if !permissions.ContextHas(c, permissions.SupportRead) {
 			response.GenerateForbiddenResponse(c, "support/get-dispute", "Access denied")
 			return
 		}
 	}
 
-	response.GenerateSuccessResponse(c, "Dispute retrieved successfully", dispute)
+	response.GenerateSuccessResponse(c, "Dispute retrieved successfully", dto.NewDispute(&dispute, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // GetUserDisputes retrieves all disputes for the current user
@@ -220,29 +221,42 @@ func (h *SupportHandler) GetUserDisputes(c *gin.Context) {
 	}
 	var disputes []models.Dispute
 	q := h.db.Where("user_id = ?", userID).Model(&models.Dispute{})
-	q, _, _ = h.applyDisputeFilters(c, q)
+	q, pagination, err := h.applyDisputeFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-user-disputes", err.Error())
+		return
+	}
 	if err := q.Preload("User").Preload("Order").Preload("Payment").Order("created_at DESC").Find(&disputes).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-user-disputes", err.Error())
 		return
 	}
-	response.GenerateSuccessResponse(c, "User disputes retrieved successfully", disputes)
+	response.GenerateSuccessResponse(c, "User disputes retrieved successfully", gin.H{
+		"disputes":   dto.NewDisputes(disputes, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination": pagination,
+	})
 }
 
 // GetAllDisputes retrieves all disputes (admin only)
 func (h *SupportHandler) GetAllDisputes(c *gin.Context) {
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportRead) {
 		response.GenerateForbiddenResponse(c, "support/get-all-disputes", "Admin access required")
 		return
 	}
 	var disputes []models.Dispute
 	q := h.db.Model(&models.Dispute{})
-	q, _, _ = h.applyDisputeFilters(c, q)
+	q, pagination, err := h.applyDisputeFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-all-disputes", err.Error())
+		return
+	}
 	if err := q.Preload("User").Preload("Order").Preload("Payment").Find(&disputes).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-all-disputes", err.Error())
 		return
 	}
-	response.GenerateSuccessResponse(c, "All disputes retrieved successfully", disputes)
+	response.GenerateSuccessResponse(c, "All disputes retrieved successfully", gin.H{
+		"disputes":   dto.NewDisputes(disputes, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination": pagination,
+	})
 }
 
 // UpdateDispute updates a dispute
@@ -255,7 +269,7 @@ func (h *SupportHandler) UpdateDispute(c *gin.Context) {
 
 	var request UpdateDisputeRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/update-dispute", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/update-dispute", err)
 		return
 	}
 
@@ -272,8 +286,7 @@ func (h *SupportHandler) UpdateDispute(c *gin.Context) {
 		return
 	}
 
-	userType, _ := c.Get("user_type")
-	if dispute.UserID != userID.(uint) && userType != models.Admin {
+	if dispute.UserID != userID.(uint) && !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/update-dispute", "Access denied")
 		return
 	}
@@ -307,27 +320,30 @@ func (h *SupportHandler) UpdateDispute(c *gin.Context) {
 		updates["internal_notes"] = request.InternalNotes
 	}
 
-	if err := h.db.Model(&dispute).Updates(updates).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dispute).Updates(updates).Error; err != nil {
+			return err
+		}
+		h.queueDisputeStatusEmail(tx, &dispute, updates)
+		return nil
+	}); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/update-dispute", err.Error())
 		return
 	}
+	_, categoryChanged := updates["category"]
+	_, priorityChanged := updates["priority"]
+	if categoryChanged || priorityChanged {
+		h.scheduleSLA(models.SLASubjectDispute, dispute.ID)
+	}
 
-	// send status update email if status changed
-	if _, ok := updates["status"]; ok && h.emailTriggerSvc != nil {
-		var user models.User
-		if err := h.db.First(&user, dispute.UserID).Error; err == nil {
-			data := map[string]interface{}{
-				"UserName":        strings.TrimSpace(user.FirstName + " " + user.LastName),
-				"DisputeID":       dispute.ID,
-				"DisputeTitle":    dispute.Title,
-				"OldStatus":       "",
-				"NewStatus":       updates["status"],
-				"UserMessageHTML": template.HTML(dispute.Description),
-				"AdminNoteHTML":   template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
-				"subject":         fmt.Sprintf("Your dispute #%d status updated", dispute.ID),
-			}
-			_ = h.emailTriggerSvc.TriggerDisputeStatusUpdated(user.Email, data["UserName"].(string), data)
-		}
+	if status, ok := updates["status"]; ok {
+		h.notify(dispute.UserID, notification.Message{
+			Type:  models.NotificationTypeDisputeStatus,
+			Title: fmt.Sprintf("Dispute #%d is %v", dispute.ID, status),
+			Body:  dispute.Title,
+			Link:  fmt.Sprintf("/support/disputes/%d", dispute.ID),
+			Data:  models.JSON{"dispute_id": dispute.ID, "status": status},
+		})
 	}
 
 	// Load updated dispute
@@ -336,7 +352,7 @@ func (h *SupportHandler) UpdateDispute(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Dispute updated successfully", dispute)
+	response.GenerateSuccessResponse(c, "Dispute updated successfully", dto.NewDispute(&dispute, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // AddDisputeResponse adds a response to a dispute
@@ -349,7 +365,7 @@ func (h *SupportHandler) AddDisputeResponse(c *gin.Context) {
 
 	var request DisputeResponseRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/add-dispute-response", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/add-dispute-response", err)
 		return
 	}
 
@@ -365,8 +381,7 @@ func (h *SupportHandler) AddDisputeResponse(c *gin.Context) {
 		return
 	}
 
-	userType, _ := c.Get("user_type")
-	isAdmin := userType == models.Admin
+	isAdmin := permissions.ContextHas(c, permissions.SupportAdmin)
 
 	// Check permissions
 	if dispute.UserID != userID.(uint) && !isAdmin {
@@ -374,49 +389,88 @@ func (h *SupportHandler) AddDisputeResponse(c *gin.Context) {
 		return
 	}
 
+	message, ok := h.composeResponseMessage(c, "support/add-dispute-response", request.Message, request.CannedResponseID, isAdmin, func() CannedResponseData {
+		return h.disputeCannedResponseData(&dispute, userID.(uint))
+	})
+	if !ok {
+		return
+	}
+
 	// Create response
 	disputeResponse := models.DisputeResponse{
 		DisputeID:   uint(disputeID),
 		UserID:      userID.(uint),
-		Message:     request.Message,
+		Message:     message,
 		IsInternal:  request.IsInternal,
 		IsFromAdmin: isAdmin,
 	}
 
-	if err := h.db.Create(&disputeResponse).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&disputeResponse).Error; err != nil {
+			return err
+		}
+		h.queueDisputeResponseEmail(tx, &dispute, &disputeResponse)
+		return nil
+	}); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/add-dispute-response", err.Error())
 		return
 	}
 
-	// Email notify dispute owner on non-internal responses
-	if !request.IsInternal && h.emailTriggerSvc != nil {
-		var owner models.User
-		if err := h.db.First(&owner, dispute.UserID).Error; err == nil {
-			responderName := "User"
-			var responder models.User
-			if err := h.db.First(&responder, userID.(uint)).Error; err == nil {
-				responderName = strings.TrimSpace(responder.FirstName + " " + responder.LastName)
-			}
-			data := map[string]interface{}{
-				"UserName":        strings.TrimSpace(owner.FirstName + " " + owner.LastName),
-				"DisputeID":       dispute.ID,
-				"DisputeTitle":    dispute.Title,
-				"UserMessageHTML": template.HTML(dispute.Description),
-				"ResponderName":   responderName,
-				"RespondedAt":     time.Now().Format("2006-01-02 15:04:05"),
-				"ResponseHTML":    template.HTML(request.Message),
-				"subject":         fmt.Sprintf("New response on your dispute #%d", dispute.ID),
-			}
-			_ = h.emailTriggerSvc.TriggerDisputeResponse(owner.Email, data["UserName"].(string), data)
-		}
+	h.afterDisputeResponse(&dispute, &disputeResponse)
+
+	response.GenerateSuccessResponse(c, "Response added successfully", dto.NewDisputeResponse(&disputeResponse, dto.AudienceFor(c, permissions.SupportRead)))
+}
+
+// afterDisputeResponse tells the dispute owner about a new response, tracks the
+// first staff response against the SLA and moves open disputes into progress
+func (h *SupportHandler) afterDisputeResponse(dispute *models.Dispute, resp *models.DisputeResponse) {
+	if !resp.IsInternal && dispute.UserID != resp.UserID {
+		h.notify(dispute.UserID, notification.Message{
+			Type:  models.NotificationTypeDisputeResponse,
+			Title: fmt.Sprintf("New response on dispute #%d", dispute.ID),
+			Body:  dispute.Title,
+			Link:  fmt.Sprintf("/support/disputes/%d", dispute.ID),
+			Data:  models.JSON{"dispute_id": dispute.ID, "response_id": resp.ID},
+		})
+	}
+
+	if resp.IsFromAdmin && !resp.IsInternal {
+		h.recordFirstResponse(models.SLASubjectDispute, dispute.ID)
 	}
 
 	// Update dispute status if admin responded
-	if isAdmin && dispute.Status == models.DisputeStatusOpen {
-		h.db.Model(&dispute).Update("status", models.DisputeStatusInProgress)
+	if resp.IsFromAdmin && dispute.Status == models.DisputeStatusOpen {
+		h.db.Model(dispute).Update("status", models.DisputeStatusInProgress)
 	}
+}
 
-	response.GenerateSuccessResponse(c, "Response added successfully", disputeResponse)
+// queueDisputeResponseEmail records the email telling the dispute owner about a
+// new response in the outbox of tx. Internal notes are not emailed.
+func (h *SupportHandler) queueDisputeResponseEmail(tx *gorm.DB, dispute *models.Dispute, resp *models.DisputeResponse) {
+	if resp.IsInternal || h.emailTriggerSvc == nil {
+		return
+	}
+	var owner models.User
+	if err := tx.First(&owner, dispute.UserID).Error; err == nil {
+		responderName := "User"
+		var responder models.User
+		if err := tx.First(&responder, resp.UserID).Error; err == nil {
+			responderName = strings.TrimSpace(responder.FirstName + " " + responder.LastName)
+		}
+		data := map[string]interface{}{
+			"UserName":        strings.TrimSpace(owner.FirstName + " " + owner.LastName),
+			"DisputeID":       dispute.ID,
+			"DisputeTitle":    dispute.Title,
+			"UserMessageHTML": template.HTML(dispute.Description),
+			"ResponderName":   responderName,
+			"RespondedAt":     time.Now().Format("2006-01-02 15:04:05"),
+			"ResponseHTML":    template.HTML(resp.Message),
+			"subject":         fmt.Sprintf("New response on your dispute #%d", dispute.ID),
+		}
+		if err := h.emailTriggerSvc.InTx(tx).TriggerDisputeResponse(owner.Email, data["UserName"].(string), data); err != nil {
+			log.Printf("Failed to queue dispute response email for dispute %d: %v", dispute.ID, err)
+		}
+	}
 }
 
 // DeleteDispute deletes a dispute (admin only)
@@ -427,8 +481,7 @@ func (h *SupportHandler) DeleteDispute(c *gin.Context) {
 		return
 	}
 
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/delete-dispute", "Admin access required")
 		return
 	}
@@ -439,10 +492,34 @@ func (h *SupportHandler) DeleteDispute(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&dispute).Error; err != nil {
+	// With its responses and attachments, so that it can be restored
+	if err := h.db.Transaction(func(tx *gorm.DB) error { return trash.Delete(tx, trash.Disputes, dispute.ID) }); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/delete-dispute", err.Error())
 		return
 	}
 
 	response.GenerateSuccessResponse(c, "Dispute deleted successfully", nil)
 }
+
+// queueDisputeStatusEmail records the status update email for a dispute in the outbox of tx
+func (h *SupportHandler) queueDisputeStatusEmail(tx *gorm.DB, dispute *models.Dispute, updates map[string]interface{}) {
+	if _, ok := updates["status"]; !ok || h.emailTriggerSvc == nil {
+		return
+	}
+	var user models.User
+	if err := tx.First(&user, dispute.UserID).Error; err == nil {
+		data := map[string]interface{}{
+			"UserName":        strings.TrimSpace(user.FirstName + " " + user.LastName),
+			"DisputeID":       dispute.ID,
+			"DisputeTitle":    dispute.Title,
+			"OldStatus":       "",
+			"NewStatus":       updates["status"],
+			"UserMessageHTML": template.HTML(dispute.Description),
+			"AdminNoteHTML":   template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
+			"subject":         fmt.Sprintf("Your dispute #%d status updated", dispute.ID),
+		}
+		if err := h.emailTriggerSvc.InTx(tx).TriggerDisputeStatusUpdated(user.Email, data["UserName"].(string), data); err != nil {
+			log.Printf("Failed to queue dispute status email for dispute %d: %v", dispute.ID, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package support
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/export"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportTickets streams the tickets matching the list filters as CSV or XLSX
+func (h *SupportHandler) ExportTickets(c *gin.Context) {
+	query := h.filterTickets(c, h.db.Model(&models.SupportTicket{}))
+	streamExport(c, h.db, "support/export-tickets", "tickets", query,
+		[]string{"ID", "Created At", "User ID", "Order ID", "Title", "Category", "Priority", "Status", "Assigned To", "Escalated", "First Response Due", "First Responded At", "Resolution Due", "Resolved At", "SLA Breached", "Resolution"},
+		func(t *models.SupportTicket) []string {
+			return []string{
+				strconv.FormatUint(uint64(t.ID), 10), exportTime(&t.CreatedAt), strconv.FormatUint(uint64(t.UserID), 10),
+				exportID(t.OrderID), t.Title, string(t.Category), string(t.Priority), string(t.Status), exportID(t.AssignedTo),
+				strconv.FormatBool(t.IsEscalated), exportTime(t.FirstResponseDueAt), exportTime(t.FirstRespondedAt),
+				exportTime(t.ResolutionDueAt), exportTime(t.ResolvedAt),
+				strconv.FormatBool(t.FirstResponseBreached || t.ResolutionBreached), t.Resolution,
+			}
+		})
+}
+
+// ExportDisputes streams the disputes matching the list filters as CSV or XLSX
+func (h *SupportHandler) ExportDisputes(c *gin.Context) {
+	query := h.filterDisputes(c, h.db.Model(&models.Dispute{}))
+	streamExport(c, h.db, "support/export-disputes", "disputes", query,
+		[]string{"ID", "Created At", "User ID", "Order ID", "Payment ID", "Title", "Category", "Priority", "Status", "Amount", "Currency", "Assigned To", "Escalated", "Resolved At", "SLA Breached", "Resolution"},
+		func(d *models.Dispute) []string {
+			amount := ""
+			if d.Amount != nil {
+				amount = strconv.FormatFloat(*d.Amount, 'f', 2, 64)
+			}
+			return []string{
+				strconv.FormatUint(uint64(d.ID), 10), exportTime(&d.CreatedAt), strconv.FormatUint(uint64(d.UserID), 10),
+				exportID(d.OrderID), exportID(d.PaymentID), d.Title, string(d.Category), string(d.Priority), string(d.Status),
+				amount, d.Currency, exportID(d.AssignedTo), strconv.FormatBool(d.IsEscalated), exportTime(d.ResolvedAt),
+				strconv.FormatBool(d.FirstResponseBreached || d.ResolutionBreached), d.Resolution,
+			}
+		})
+}
+
+// ExportAbuseReports streams the abuse reports matching the list filters as CSV or XLSX
+func (h *SupportHandler) ExportAbuseReports(c *gin.Context) {
+	query := h.filterAbuseReports(c, h.db.Model(&models.AbuseReport{}))
+	streamExport(c, h.db, "support/export-abuse-reports", "abuse-reports", query,
+		[]string{"ID", "Created At", "Reporter ID", "Reported User ID", "Product ID", "Review ID", "Order ID", "Category", "Severity", "Status", "Assigned To", "Resolved At", "Description", "Resolution"},
+		func(r *models.AbuseReport) []string {
+			return []string{
+				strconv.FormatUint(uint64(r.ID), 10), exportTime(&r.CreatedAt), strconv.FormatUint(uint64(r.ReporterID), 10),
+				exportID(r.ReportedUserID), exportID(r.ProductID), exportID(r.ReviewID), exportID(r.OrderID),
+				string(r.Category), string(r.Severity), string(r.Status), exportID(r.AssignedTo), exportTime(r.ResolvedAt),
+				r.Description, r.Resolution,
+			}
+		})
+}
+
+// ExportContactInquiries streams the contact inquiries matching the list filters as CSV or XLSX
+func (h *SupportHandler) ExportContactInquiries(c *gin.Context) {
+	query := h.filterContactInquiries(c, h.db.Model(&models.ContactInquiry{}))
+	streamExport(c, h.db, "support/export-contact-inquiries", "contact-inquiries", query,
+		[]string{"ID", "Created At", "User ID", "Name", "Email", "Phone", "Subject", "Category", "Priority", "Status", "Assigned To", "Responded At", "Message"},
+		func(i *models.ContactInquiry) []string {
+			return []string{
+				strconv.FormatUint(uint64(i.ID), 10), exportTime(&i.CreatedAt), exportID(i.UserID), i.Name, i.Email, i.Phone,
+				i.Subject, string(i.Category), string(i.Priority), string(i.Status), exportID(i.AssignedTo),
+				exportTime(i.RespondedAt), i.Message,
+			}
+		})
+}
+
+// streamExport writes the rows of query to the response one at a time, in the
+// format given by the format query parameter, so large queues are never held
+// in memory. Errors after the first byte is sent can only be logged.
+func streamExport[T any](c *gin.Context, db *gorm.DB, code, name string, query *gorm.DB, header []string, row func(*T) []string) {
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, err.Error())
+		return
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	fileName := format.FileName(fmt.Sprintf("%s-%s", name, time.Now().Format("20060102-150405")))
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Status(200)
+
+	writer, err := export.NewWriter(format, c.Writer, name)
+	if err != nil {
+		log.Printf("Failed to start %s export: %v", name, err)
+		return
+	}
+	if err := writer.WriteRow(header); err != nil {
+		log.Printf("Failed to write %s export: %v", name, err)
+		return
+	}
+	for rows.Next() {
+		var item T
+		if err := db.ScanRows(rows, &item); err != nil {
+			log.Printf("Failed to read %s export row: %v", name, err)
+			return
+		}
+		if err := writer.WriteRow(row(&item)); err != nil {
+			log.Printf("Failed to write %s export: %v", name, err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Failed to read %s export rows: %v", name, err)
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Failed to finish %s export: %v", name, err)
+	}
+}
+
+// exportID formats an optional ID for an export cell
+func exportID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+// exportTime formats an optional time for an export cell
+func exportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
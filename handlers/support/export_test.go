@@ -0,0 +1,74 @@
+package support
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupExportTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.SupportTicket{}))
+
+	handler := NewSupportHandler(db, nil, nil, nil)
+	router := gin.New()
+	router.GET("/admin/tickets/export", handler.ExportTickets)
+	return router, db
+}
+
+func TestExportTicketsCSV(t *testing.T) {
+	router, db := setupExportTest(t)
+	for _, ticket := range []models.SupportTicket{
+		{UserID: 1, Title: "Refund, please", Description: "x", Category: models.TicketCategoryPayment, Priority: models.TicketPriorityHigh, Status: models.TicketStatusOpen},
+		{UserID: 2, Title: "Late parcel", Description: "x", Category: models.TicketCategoryShipping, Priority: models.TicketPriorityLow, Status: models.TicketStatusOpen},
+		{UserID: 3, Title: "Closed one", Description: "x", Category: models.TicketCategoryPayment, Priority: models.TicketPriorityLow, Status: models.TicketStatusClosed},
+	} {
+		require.NoError(t, db.Create(&ticket).Error)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/tickets/export?status=open&sort_by=date&sort_order=asc", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment; filename=\"tickets-")
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "ID", records[0][0])
+	assert.Equal(t, "Refund, please", records[1][4])
+	assert.Equal(t, "Late parcel", records[2][4])
+}
+
+func TestExportTicketsXLSX(t *testing.T) {
+	router, db := setupExportTest(t)
+	require.NoError(t, db.Create(&models.SupportTicket{UserID: 1, Title: "Broken item", Description: "x", Category: models.TicketCategoryProduct, Status: models.TicketStatusOpen}).Error)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/tickets/export?format=xlsx", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	file, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	defer file.Close()
+	rows, err := file.GetRows("tickets")
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "Broken item", rows[1][4])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/tickets/export?format=pdf", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,9 +1,19 @@
 package support
 
 import (
+	"log"
+	"strconv"
+	"time"
+
 	"github.com/YasserCherfaoui/MarketProGo/aw"
 	"github.com/YasserCherfaoui/MarketProGo/email"
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/sla"
+	"github.com/YasserCherfaoui/MarketProGo/uploads"
+	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +23,14 @@ type SupportHandler struct {
 	gcsService      *gcs.GCService
 	appwriteService *aw.AppwriteService
 	emailTriggerSvc *email.EmailTriggerService
+	notifier        *notification.Service
+	slaService      *sla.Service
+	moderation      *moderation.Service
+	inboundSecret   string
+	uploads         *uploads.Service
+
+	// previousInboundSecret is also accepted while the secret is rotated
+	previousInboundSecret string
 }
 
 // NewSupportHandler creates a new support handler
@@ -22,5 +40,83 @@ func NewSupportHandler(db *gorm.DB, gcsService *gcs.GCService, appwriteService *
 		gcsService:      gcsService,
 		appwriteService: appwriteService,
 		emailTriggerSvc: emailTriggerSvc,
+		notifier:        notification.NewService(db),
+		slaService:      sla.NewService(db),
+		moderation:      moderation.NewService(db, nil, emailTriggerSvc),
+		uploads:         uploads.NewService(db, uploads.NewAppwriteStore(appwriteService)),
+	}
+}
+
+// WithInboundEmailSecret sets the key inbound support email webhooks are
+// signed with, and the previous key also accepted while it is rotated
+func (h *SupportHandler) WithInboundEmailSecret(secret, previous string) *SupportHandler {
+	h.inboundSecret = secret
+	h.previousInboundSecret = previous
+	return h
+}
+
+// WithModeration sets the service that takes down reported content and
+// gives its owners strikes
+func (h *SupportHandler) WithModeration(moderations *moderation.Service) *SupportHandler {
+	h.moderation = moderations
+	return h
+}
+
+// notify writes an in-app notification; failures are logged and never fail the request
+func (h *SupportHandler) notify(userID uint, msg notification.Message) {
+	if _, err := h.notifier.Notify(userID, msg); err != nil {
+		log.Printf("Failed to create notification for user %d: %v", userID, err)
+	}
+}
+
+// scheduleSLA sets the SLA deadlines of a ticket or dispute; failures are logged and never fail the request
+func (h *SupportHandler) scheduleSLA(subject models.SLASubject, id uint) {
+	if err := h.slaService.Schedule(subject, id); err != nil {
+		log.Printf("Failed to schedule SLA for %s %d: %v", subject, id, err)
+	}
+}
+
+// recordFirstResponse marks the first staff response against the SLA; failures are logged and never fail the request
+func (h *SupportHandler) recordFirstResponse(subject models.SLASubject, id uint) {
+	if err := h.slaService.RecordFirstResponse(subject, id, time.Now()); err != nil {
+		log.Printf("Failed to record first response for %s %d: %v", subject, id, err)
+	}
+}
+
+// Pagination is the pagination metadata returned with support listings
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+	HasNext    bool  `json:"has_next"`
+	HasPrev    bool  `json:"has_prev"`
+}
+
+// paginate counts the rows matched by a list query and applies the page and
+// page_size query parameters to it
+func paginate(c *gin.Context, query *gorm.DB) (*gorm.DB, Pagination, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 200 {
+		pageSize = 20
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, Pagination{}, err
+	}
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	pagination := Pagination{
+		Page:       page,
+		Limit:      pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
 	}
+	return query.Offset((page - 1) * pageSize).Limit(pageSize), pagination, nil
 }
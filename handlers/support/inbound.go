@@ -0,0 +1,415 @@
+package support
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/files"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxInboundAttachmentSize is the largest inbound email attachment that is stored
+const maxInboundAttachmentSize = 10 << 20
+
+// InboundEmailRequest is an email received by the support mailbox, as posted
+// by the mail provider's inbound webhook
+type InboundEmailRequest struct {
+	From        string                   `json:"from" binding:"required"`
+	To          []string                 `json:"to"`
+	Subject     string                   `json:"subject"`
+	Text        string                   `json:"text"`
+	HTML        string                   `json:"html"`
+	MessageID   string                   `json:"message_id"`
+	Attachments []InboundEmailAttachment `json:"attachments"`
+	// Authentication is the provider's verdict on whether the email really
+	// comes from its From address
+	Authentication InboundEmailAuthentication `json:"authentication"`
+}
+
+// InboundEmailAuthentication holds the SPF, DKIM and DMARC results the mail
+// provider found for an inbound email, such as "pass", "fail" or "none"
+type InboundEmailAuthentication struct {
+	SPF   string `json:"spf"`
+	DKIM  string `json:"dkim"`
+	DMARC string `json:"dmarc"`
+}
+
+// passed reports whether the email passed DMARC. SPF or DKIM passing alone is
+// not enough: they may vouch for a domain other than the one in From, which
+// DMARC checks is aligned with it.
+func (a InboundEmailAuthentication) passed() bool {
+	return strings.EqualFold(strings.TrimSpace(a.DMARC), "pass")
+}
+
+// InboundEmailAttachment is a file attached to an inbound email
+type InboundEmailAttachment struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"` // base64 encoded
+}
+
+// supportReference is the ticket or dispute an inbound email replies to
+type supportReference struct {
+	kind string // "ticket" or "dispute"
+	id   uint
+}
+
+var (
+	// subjectReferencePattern matches the "ticket #12" and "dispute #7" tokens
+	// used in support notification subjects
+	subjectReferencePattern = regexp.MustCompile(`(?i)\b(ticket|dispute)\s*#\s*(\d+)`)
+	// addressReferencePattern matches plus addresses such as support+ticket-12@example.com
+	addressReferencePattern = regexp.MustCompile(`(?i)\+(ticket|dispute)-(\d+)@`)
+	// quoteHeaderPattern matches the line mail clients put above a quoted reply
+	quoteHeaderPattern = regexp.MustCompile(`(?i)^(on .+ wrote:|-+ ?original message ?-+|from: .+)$`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+)
+
+// HandleInboundEmail turns an email sent to the support mailbox into a ticket
+// or dispute response. Replies are matched to their ticket or dispute by the
+// "ticket #ID" token in the subject or a support+ticket-ID@ address; emails
+// without one open a new ticket for registered users and a contact inquiry
+// for anyone else. The body must be signed with EMAIL_INBOUND_SECRET, or
+// EMAIL_INBOUND_SECRET_PREVIOUS while it is rotated, in the X-Email-Signature
+// header, and emails that did not pass DMARC are ignored
+// since anyone can put any address in From.
+func (h *SupportHandler) HandleInboundEmail(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "support/inbound-email", "Failed to read request body")
+		return
+	}
+	signature := c.GetHeader("X-Email-Signature")
+	switch {
+	case email.VerifyWebhookSignature(h.inboundSecret, body, signature):
+		metrics.SecretMatched("email_inbound", false)
+	case email.VerifyWebhookSignature(h.previousInboundSecret, body, signature):
+		metrics.SecretMatched("email_inbound", true)
+	default:
+		response.GenerateUnauthorizedResponse(c, "support/inbound-email", "Invalid webhook signature")
+		return
+	}
+
+	var request InboundEmailRequest
+	if err := json.Unmarshal(body, &request); err != nil || request.From == "" {
+		response.GenerateBadRequestResponse(c, "support/inbound-email", "Invalid request body")
+		return
+	}
+	sender, err := mail.ParseAddress(request.From)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "support/inbound-email", "Invalid sender address")
+		return
+	}
+	if !request.Authentication.passed() {
+		ignoreInboundEmail(c, "sender not authenticated")
+		return
+	}
+
+	message := inboundMessageText(request.Text, request.HTML)
+	if message == "" && len(request.Attachments) == 0 {
+		ignoreInboundEmail(c, "empty message")
+		return
+	}
+	if message == "" {
+		message = "(attachment only)"
+	}
+
+	if request.MessageID != "" && h.inboundEmailProcessed(request.MessageID) {
+		ignoreInboundEmail(c, "already processed")
+		return
+	}
+
+	var user *models.User
+	var found models.User
+	if err := h.db.Where("LOWER(email) = ?", strings.ToLower(sender.Address)).First(&found).Error; err == nil {
+		user = &found
+	}
+
+	reference, hasReference := parseSupportReference(request.Subject, request.To)
+	switch {
+	case hasReference && user == nil:
+		ignoreInboundEmail(c, "unknown sender")
+	case hasReference && reference.kind == "dispute":
+		h.appendInboundDisputeResponse(c, reference.id, user, message, &request)
+	case hasReference:
+		h.appendInboundTicketResponse(c, reference.id, user, message, &request)
+	case user != nil:
+		h.createInboundTicket(c, user, message, &request)
+	default:
+		h.createInboundContactInquiry(c, sender, message, &request)
+	}
+}
+
+func (h *SupportHandler) appendInboundTicketResponse(c *gin.Context, ticketID uint, user *models.User, message string, request *InboundEmailRequest) {
+	var ticket models.SupportTicket
+	if err := h.db.First(&ticket, ticketID).Error; err != nil {
+		ignoreInboundEmail(c, "ticket not found")
+		return
+	}
+	// Staff answer from the dashboard; only the customer replies by email
+	if user.ID != ticket.UserID {
+		ignoreInboundEmail(c, "sender cannot reply to this ticket")
+		return
+	}
+
+	ticketResponse := models.TicketResponse{
+		TicketID:       ticket.ID,
+		UserID:         user.ID,
+		Message:        message,
+		EmailMessageID: request.MessageID,
+	}
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ticketResponse).Error; err != nil {
+			return err
+		}
+		h.queueTicketResponseEmail(tx, &ticket, &ticketResponse)
+		return nil
+	}); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/inbound-email", err.Error())
+		return
+	}
+	for _, attachment := range h.storeInboundAttachments(c.Request.Context(), "tickets", ticket.ID, request.Attachments) {
+		ticketAttachment := models.TicketAttachment{TicketID: ticket.ID, FileName: attachment.FileName, FileURL: attachment.FileURL, FileSize: attachment.FileSize, FileType: attachment.FileType}
+		if err := h.db.Create(&ticketAttachment).Error; err != nil {
+			log.Printf("Failed to save inbound attachment for ticket %d: %v", ticket.ID, err)
+		}
+	}
+	h.afterTicketResponse(&ticket, &ticketResponse)
+
+	response.GenerateCreatedResponse(c, "Inbound email added to ticket", gin.H{
+		"action":      "ticket_response",
+		"ticket_id":   ticket.ID,
+		"response_id": ticketResponse.ID,
+	})
+}
+
+func (h *SupportHandler) appendInboundDisputeResponse(c *gin.Context, disputeID uint, user *models.User, message string, request *InboundEmailRequest) {
+	var dispute models.Dispute
+	if err := h.db.First(&dispute, disputeID).Error; err != nil {
+		ignoreInboundEmail(c, "dispute not found")
+		return
+	}
+	if user.ID != dispute.UserID {
+		ignoreInboundEmail(c, "sender cannot reply to this dispute")
+		return
+	}
+
+	disputeResponse := models.DisputeResponse{
+		DisputeID:      dispute.ID,
+		UserID:         user.ID,
+		Message:        message,
+		EmailMessageID: request.MessageID,
+	}
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&disputeResponse).Error; err != nil {
+			return err
+		}
+		h.queueDisputeResponseEmail(tx, &dispute, &disputeResponse)
+		return nil
+	}); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/inbound-email", err.Error())
+		return
+	}
+	for _, attachment := range h.storeInboundAttachments(c.Request.Context(), "disputes", dispute.ID, request.Attachments) {
+		disputeAttachment := models.DisputeAttachment{DisputeID: dispute.ID, FileName: attachment.FileName, FileURL: attachment.FileURL, FileSize: attachment.FileSize, FileType: attachment.FileType}
+		if err := h.db.Create(&disputeAttachment).Error; err != nil {
+			log.Printf("Failed to save inbound attachment for dispute %d: %v", dispute.ID, err)
+		}
+	}
+	h.afterDisputeResponse(&dispute, &disputeResponse)
+
+	response.GenerateCreatedResponse(c, "Inbound email added to dispute", gin.H{
+		"action":      "dispute_response",
+		"dispute_id":  dispute.ID,
+		"response_id": disputeResponse.ID,
+	})
+}
+
+func (h *SupportHandler) createInboundTicket(c *gin.Context, user *models.User, message string, request *InboundEmailRequest) {
+	title := strings.TrimSpace(request.Subject)
+	if title == "" {
+		title = "Email from " + user.Email
+	}
+
+	ticket := models.SupportTicket{
+		UserID:      user.ID,
+		Title:       title,
+		Description: message,
+		Category:    models.TicketCategoryGeneral,
+		Priority:    models.TicketPriorityMedium,
+		Status:      models.TicketStatusOpen,
+	}
+	if err := h.createTicket(&ticket); err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/inbound-email", err.Error())
+		return
+	}
+	h.scheduleSLA(models.SLASubjectTicket, ticket.ID)
+
+	for _, attachment := range h.storeInboundAttachments(c.Request.Context(), "tickets", ticket.ID, request.Attachments) {
+		ticketAttachment := models.TicketAttachment{TicketID: ticket.ID, FileName: attachment.FileName, FileURL: attachment.FileURL, FileSize: attachment.FileSize, FileType: attachment.FileType}
+		if err := h.db.Create(&ticketAttachment).Error; err != nil {
+			log.Printf("Failed to save inbound attachment for ticket %d: %v", ticket.ID, err)
+		}
+	}
+
+	response.GenerateCreatedResponse(c, "Inbound email converted to ticket", gin.H{
+		"action":    "ticket_created",
+		"ticket_id": ticket.ID,
+	})
+}
+
+func (h *SupportHandler) createInboundContactInquiry(c *gin.Context, sender *mail.Address, message string, request *InboundEmailRequest) {
+	name := sender.Name
+	if name == "" {
+		name = sender.Address
+	}
+	subject := strings.TrimSpace(request.Subject)
+	if subject == "" {
+		subject = "Email from " + sender.Address
+	}
+
+	inquiry := models.ContactInquiry{
+		Name:     name,
+		Email:    sender.Address,
+		Subject:  subject,
+		Message:  message,
+		Category: models.ContactCategorySupport,
+		Status:   models.ContactStatusNew,
+		Priority: models.ContactPriorityNormal,
+	}
+	if err := h.db.Create(&inquiry).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/inbound-email", err.Error())
+		return
+	}
+
+	response.GenerateCreatedResponse(c, "Inbound email converted to contact inquiry", gin.H{
+		"action":     "contact_inquiry_created",
+		"inquiry_id": inquiry.ID,
+	})
+}
+
+// inboundEmailProcessed reports whether an email was already added as a response,
+// so provider retries do not create duplicates
+func (h *SupportHandler) inboundEmailProcessed(messageID string) bool {
+	var count int64
+	h.db.Model(&models.TicketResponse{}).Where("email_message_id = ?", messageID).Count(&count)
+	if count > 0 {
+		return true
+	}
+	h.db.Model(&models.DisputeResponse{}).Where("email_message_id = ?", messageID).Count(&count)
+	return count > 0
+}
+
+// storeInboundAttachments uploads inbound attachments to storage. Attachments
+// that cannot be decoded or uploaded are logged and skipped.
+func (h *SupportHandler) storeInboundAttachments(ctx context.Context, folder string, id uint, attachments []InboundEmailAttachment) []TicketAttachmentRequest {
+	if len(attachments) == 0 {
+		return nil
+	}
+	if h.gcsService == nil || h.gcsService.Client == nil {
+		log.Printf("Skipping %d inbound attachments for %s %d: storage not configured", len(attachments), folder, id)
+		return nil
+	}
+
+	var stored []TicketAttachmentRequest
+	for _, attachment := range attachments {
+		content, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			log.Printf("Skipping inbound attachment %q: invalid base64 content", attachment.FileName)
+			continue
+		}
+		if len(content) > maxInboundAttachmentSize {
+			log.Printf("Skipping inbound attachment %q: %d bytes exceeds the size limit", attachment.FileName, len(content))
+			continue
+		}
+
+		fileName := filepath.Base(attachment.FileName)
+		if fileName == "." || fileName == "/" {
+			fileName = "attachment"
+		}
+		objectName := fmt.Sprintf("support/%s/%d/%d_%s", folder, id, time.Now().UnixNano(), strings.ReplaceAll(fileName, " ", "_"))
+		attrs, err := h.gcsService.UploadFile(ctx, bytes.NewReader(content), objectName, attachment.ContentType)
+		if err != nil {
+			log.Printf("Failed to upload inbound attachment %q: %v", attachment.FileName, err)
+			continue
+		}
+
+		fileURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", attrs.Bucket, attrs.Name)
+		if err := files.Record(h.db.WithContext(ctx), storage.BackendGCS, attrs.Name, fileURL, files.SourceTicketAttachment); err != nil {
+			log.Printf("Failed to track inbound attachment %q: %v", attrs.Name, err)
+		}
+		stored = append(stored, TicketAttachmentRequest{
+			FileName: fileName,
+			FileURL:  fileURL,
+			FileSize: int64(len(content)),
+			FileType: attachment.ContentType,
+		})
+	}
+	return stored
+}
+
+func ignoreInboundEmail(c *gin.Context, reason string) {
+	response.GenerateSuccessResponse(c, "Inbound email ignored", gin.H{
+		"action": "ignored",
+		"reason": reason,
+	})
+}
+
+// parseSupportReference finds the ticket or dispute an email replies to from
+// its subject, then its recipient addresses
+func parseSupportReference(subject string, to []string) (supportReference, bool) {
+	if match := subjectReferencePattern.FindStringSubmatch(subject); match != nil {
+		if id, err := strconv.ParseUint(match[2], 10, 32); err == nil {
+			return supportReference{kind: strings.ToLower(match[1]), id: uint(id)}, true
+		}
+	}
+	for _, address := range to {
+		if match := addressReferencePattern.FindStringSubmatch(address); match != nil {
+			if id, err := strconv.ParseUint(match[2], 10, 32); err == nil {
+				return supportReference{kind: strings.ToLower(match[1]), id: uint(id)}, true
+			}
+		}
+	}
+	return supportReference{}, false
+}
+
+// inboundMessageText returns the new text of an email, without the quoted
+// message it replies to
+func inboundMessageText(text, htmlBody string) string {
+	if strings.TrimSpace(text) == "" && htmlBody != "" {
+		text = htmlBody
+		for _, tag := range []string{"<br>", "<br/>", "<br />", "</p>", "</div>"} {
+			text = strings.ReplaceAll(text, tag, tag+"\n")
+		}
+		text = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, ""))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") || quoteHeaderPattern.MatchString(trimmed) {
+			break
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
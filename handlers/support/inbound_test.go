@@ -0,0 +1,175 @@
+package support
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testInboundSecret = "inbound-secret"
+
+// authenticated is the verdict of an email that really comes from its sender
+var authenticated = InboundEmailAuthentication{SPF: "pass", DKIM: "pass", DMARC: "pass"}
+
+func setupInboundTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{}, &models.Notification{}, &models.SLAPolicy{},
+		&models.SupportTicket{}, &models.TicketResponse{}, &models.TicketAttachment{},
+		&models.Dispute{}, &models.DisputeResponse{}, &models.DisputeAttachment{},
+		&models.ContactInquiry{}, &models.WebhookSubscription{}, &models.OutboxMessage{},
+	))
+
+	return inboundRouter(db, testInboundSecret, ""), db
+}
+
+func inboundRouter(db *gorm.DB, secret, previous string) *gin.Engine {
+	handler := NewSupportHandler(db, nil, nil, nil).WithInboundEmailSecret(secret, previous)
+	router := gin.New()
+	router.POST("/support/inbound-email", handler.HandleInboundEmail)
+	return router
+}
+
+func postInboundEmail(t *testing.T, router *gin.Engine, request InboundEmailRequest) *httptest.ResponseRecorder {
+	body, err := json.Marshal(request)
+	require.NoError(t, err)
+	mac := hmac.New(sha256.New, []byte(testInboundSecret))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/support/inbound-email", bytes.NewReader(body))
+	req.Header.Set("X-Email-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestInboundEmailReplyAddsTicketResponse(t *testing.T) {
+	router, db := setupInboundTest(t)
+	owner := models.User{Email: "amina@example.com", FirstName: "Amina", UserType: models.Customer}
+	require.NoError(t, db.Create(&owner).Error)
+	ticket := models.SupportTicket{UserID: owner.ID, Title: "Missing item", Description: "One item missing", Category: models.TicketCategoryOrder, Status: models.TicketStatusOpen}
+	require.NoError(t, db.Create(&ticket).Error)
+
+	reply := InboundEmailRequest{
+		From:           "Amina <Amina@Example.com>",
+		Subject:        "Re: New response on your ticket #1",
+		Text:           "It was the olive oil.\n\nOn Mon, 1 Jan 2024 Support wrote:\n> Which item is missing?",
+		MessageID:      "<reply-1@example.com>",
+		Authentication: authenticated,
+	}
+	w := postInboundEmail(t, router, reply)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var responses []models.TicketResponse
+	require.NoError(t, db.Where("ticket_id = ?", ticket.ID).Find(&responses).Error)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "It was the olive oil.", responses[0].Message)
+	assert.Equal(t, owner.ID, responses[0].UserID)
+	assert.False(t, responses[0].IsFromAdmin)
+
+	// Provider retries of the same email are ignored
+	w = postInboundEmail(t, router, reply)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&models.TicketResponse{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Other customers cannot reply to the ticket
+	require.NoError(t, db.Create(&models.User{Email: "other@example.com", UserType: models.Customer}).Error)
+	w = postInboundEmail(t, router, InboundEmailRequest{From: "other@example.com", Subject: "Re: ticket #1", Text: "Hello", Authentication: authenticated})
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.TicketResponse{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestInboundEmailWithoutReference(t *testing.T) {
+	router, db := setupInboundTest(t)
+	require.NoError(t, db.Create(&models.User{Email: "amina@example.com", UserType: models.Customer}).Error)
+
+	w := postInboundEmail(t, router, InboundEmailRequest{From: "amina@example.com", Subject: "Delivery question", HTML: "<p>When will my order arrive?</p>", Authentication: authenticated})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var ticket models.SupportTicket
+	require.NoError(t, db.First(&ticket).Error)
+	assert.Equal(t, "Delivery question", ticket.Title)
+	assert.Equal(t, "When will my order arrive?", ticket.Description)
+	assert.NotNil(t, ticket.FirstResponseDueAt)
+
+	w = postInboundEmail(t, router, InboundEmailRequest{From: "Guest <guest@example.com>", Subject: "Wholesale", Text: "Do you sell wholesale?", Authentication: authenticated})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var inquiry models.ContactInquiry
+	require.NoError(t, db.First(&inquiry).Error)
+	assert.Equal(t, "Guest", inquiry.Name)
+	assert.Equal(t, "guest@example.com", inquiry.Email)
+}
+
+func TestInboundEmailIgnoresUnauthenticatedAndStaffSenders(t *testing.T) {
+	router, db := setupInboundTest(t)
+	owner := models.User{Email: "amina@example.com", UserType: models.Customer}
+	require.NoError(t, db.Create(&owner).Error)
+	require.NoError(t, db.Create(&models.User{Email: "agent@example.com", UserType: models.Admin}).Error)
+	ticket := models.SupportTicket{UserID: owner.ID, Title: "Missing item", Description: "One item missing", Category: models.TicketCategoryOrder, Status: models.TicketStatusOpen}
+	require.NoError(t, db.Create(&ticket).Error)
+
+	// A From address that did not pass DMARC may be spoofed
+	for _, verdict := range []InboundEmailAuthentication{{}, {SPF: "pass", DKIM: "pass", DMARC: "fail"}, {SPF: "pass", DMARC: "none"}} {
+		w := postInboundEmail(t, router, InboundEmailRequest{From: "amina@example.com", Subject: "Re: ticket #1", Text: "Refund me", Authentication: verdict})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "sender not authenticated")
+	}
+
+	// Staff answer from the dashboard, never by email
+	w := postInboundEmail(t, router, InboundEmailRequest{From: "agent@example.com", Subject: "Re: ticket #1", Text: "Refund approved", Authentication: authenticated})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var count int64
+	db.Model(&models.TicketResponse{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestInboundEmailRejectsBadSignature(t *testing.T) {
+	router, _ := setupInboundTest(t)
+	req := httptest.NewRequest(http.MethodPost, "/support/inbound-email", bytes.NewReader([]byte(`{"from":"a@example.com"}`)))
+	req.Header.Set("X-Email-Signature", "sha256=deadbeef")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestInboundEmailAcceptsPreviousSecret(t *testing.T) {
+	_, db := setupInboundTest(t)
+	request := InboundEmailRequest{From: "guest@example.com", Subject: "Wholesale", Text: "Do you sell wholesale?", Authentication: authenticated}
+
+	// Mail signed with the old secret is still accepted while it is rotated
+	w := postInboundEmail(t, inboundRouter(db, "new-inbound-secret", testInboundSecret), request)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = postInboundEmail(t, inboundRouter(db, "new-inbound-secret", ""), request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestParseSupportReference(t *testing.T) {
+	reference, ok := parseSupportReference("Re: Your dispute #42 status updated", nil)
+	require.True(t, ok)
+	assert.Equal(t, supportReference{kind: "dispute", id: 42}, reference)
+
+	reference, ok = parseSupportReference("Re: question", []string{"support+ticket-7@example.com"})
+	require.True(t, ok)
+	assert.Equal(t, supportReference{kind: "ticket", id: 7}, reference)
+
+	_, ok = parseSupportReference("Hello", []string{"support@example.com"})
+	assert.False(t, ok)
+}
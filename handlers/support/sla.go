@@ -0,0 +1,207 @@
+package support
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/dto"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// SLAPolicyRequest represents the request body for creating or replacing an SLA policy
+type SLAPolicyRequest struct {
+	Subject              models.SLASubject `json:"subject" binding:"required,oneof=ticket dispute"`
+	Category             string            `json:"category"`
+	Priority             string            `json:"priority"`
+	FirstResponseMinutes int               `json:"first_response_minutes" binding:"required,min=1"`
+	ResolutionMinutes    int               `json:"resolution_minutes" binding:"required,min=1"`
+	IsActive             *bool             `json:"is_active"`
+}
+
+// GetSLAPolicies lists the SLA policies, optionally for one subject
+func (h *SupportHandler) GetSLAPolicies(c *gin.Context) {
+	query := h.db.Model(&models.SLAPolicy{})
+	if subject := c.Query("subject"); subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+
+	var policies []models.SLAPolicy
+	if err := query.Order("subject ASC, category ASC, priority ASC").Find(&policies).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-sla-policies", "Failed to get SLA policies")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "SLA policies retrieved successfully", policies)
+}
+
+// CreateSLAPolicy adds an SLA policy. Deadlines of existing records are not changed.
+func (h *SupportHandler) CreateSLAPolicy(c *gin.Context) {
+	var request SLAPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.GenerateValidationErrorResponse(c, "support/create-sla-policy", err)
+		return
+	}
+	if request.ResolutionMinutes < request.FirstResponseMinutes {
+		response.GenerateBadRequestResponse(c, "support/create-sla-policy", "Resolution time cannot be shorter than first response time")
+		return
+	}
+
+	policy := models.SLAPolicy{IsActive: true}
+	applySLAPolicyRequest(&policy, &request)
+
+	var existing int64
+	h.db.Model(&models.SLAPolicy{}).
+		Where("subject = ? AND category = ? AND priority = ?", policy.Subject, policy.Category, policy.Priority).
+		Count(&existing)
+	if existing > 0 {
+		response.GenerateBadRequestResponse(c, "support/create-sla-policy", "An SLA policy already exists for this subject, category and priority")
+		return
+	}
+
+	if err := h.db.Create(&policy).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/create-sla-policy", err.Error())
+		return
+	}
+
+	response.GenerateCreatedResponse(c, "SLA policy created successfully", policy)
+}
+
+// UpdateSLAPolicy replaces an SLA policy's targets
+func (h *SupportHandler) UpdateSLAPolicy(c *gin.Context) {
+	policyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "support/update-sla-policy", "Invalid SLA policy ID")
+		return
+	}
+
+	var request SLAPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.GenerateValidationErrorResponse(c, "support/update-sla-policy", err)
+		return
+	}
+	if request.ResolutionMinutes < request.FirstResponseMinutes {
+		response.GenerateBadRequestResponse(c, "support/update-sla-policy", "Resolution time cannot be shorter than first response time")
+		return
+	}
+
+	var policy models.SLAPolicy
+	if err := h.db.First(&policy, policyID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "support/update-sla-policy", "SLA policy not found")
+		return
+	}
+	applySLAPolicyRequest(&policy, &request)
+
+	if err := h.db.Save(&policy).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/update-sla-policy", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "SLA policy updated successfully", policy)
+}
+
+// DeleteSLAPolicy removes an SLA policy, so matching records fall back to a broader policy or the defaults
+func (h *SupportHandler) DeleteSLAPolicy(c *gin.Context) {
+	policyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "support/delete-sla-policy", "Invalid SLA policy ID")
+		return
+	}
+
+	result := h.db.Delete(&models.SLAPolicy{}, policyID)
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/delete-sla-policy", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateNotFoundResponse(c, "support/delete-sla-policy", "SLA policy not found")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "SLA policy deleted successfully", nil)
+}
+
+// GetSLAReport reports SLA compliance for tickets and disputes created in a
+// period, the last 30 days by default. Dates use the YYYY-MM-DD format.
+func (h *SupportHandler) GetSLAReport(c *gin.Context) {
+	now := time.Now()
+	to := now
+	from := now.AddDate(0, 0, -30)
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "support/sla-report", "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+		from = parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.GenerateBadRequestResponse(c, "support/sla-report", "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		response.GenerateBadRequestResponse(c, "support/sla-report", "from must be before to")
+		return
+	}
+
+	report, err := h.slaService.Report(from, to, now)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/sla-report", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "SLA report generated successfully", report)
+}
+
+// GetSLABreaches lists open tickets and disputes that have breached an SLA
+func (h *SupportHandler) GetSLABreaches(c *gin.Context) {
+	breached := "first_response_breached = ? OR resolution_breached = ?"
+	closed := []string{"RESOLVED", "CLOSED"}
+	subject := c.Query("subject")
+
+	result := gin.H{}
+	if subject == "" || subject == string(models.SLASubjectTicket) {
+		var tickets []models.SupportTicket
+		if err := h.db.Preload("User").
+			Where("status NOT IN ?", closed).
+			Where(breached, true, true).
+			Order("resolution_due_at ASC").
+			Find(&tickets).Error; err != nil {
+			response.GenerateInternalServerErrorResponse(c, "support/sla-breaches", "Failed to get breached tickets")
+			return
+		}
+		result["tickets"] = dto.NewTickets(tickets, dto.Staff)
+	}
+	if subject == "" || subject == string(models.SLASubjectDispute) {
+		var disputes []models.Dispute
+		if err := h.db.Preload("User").
+			Where("status NOT IN ?", closed).
+			Where(breached, true, true).
+			Order("resolution_due_at ASC").
+			Find(&disputes).Error; err != nil {
+			response.GenerateInternalServerErrorResponse(c, "support/sla-breaches", "Failed to get breached disputes")
+			return
+		}
+		result["disputes"] = dto.NewDisputes(disputes, dto.Staff)
+	}
+
+	response.GenerateSuccessResponse(c, "SLA breaches retrieved successfully", result)
+}
+
+func applySLAPolicyRequest(policy *models.SLAPolicy, request *SLAPolicyRequest) {
+	policy.Subject = request.Subject
+	policy.Category = strings.ToUpper(strings.TrimSpace(request.Category))
+	policy.Priority = strings.ToUpper(strings.TrimSpace(request.Priority))
+	policy.FirstResponseMinutes = request.FirstResponseMinutes
+	policy.ResolutionMinutes = request.ResolutionMinutes
+	if request.IsActive != nil {
+		policy.IsActive = *request.IsActive
+	}
+}
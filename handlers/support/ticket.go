@@ -3,12 +3,18 @@ package support
 import (
 	"fmt"
 	"html/template"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/dto"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/trash"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -44,12 +50,18 @@ type UpdateTicketRequest struct {
 
 // TicketResponseRequest represents a response to a ticket
 type TicketResponseRequest struct {
-	Message    string `json:"message" binding:"required"`
-	IsInternal bool   `json:"is_internal"`
+	Message          string `json:"message"`
+	CannedResponseID *uint  `json:"canned_response_id"` // expanded server-side; message is appended to it
+	IsInternal       bool   `json:"is_internal"`
 }
 
 // applyTicketFilters applies filters/sort/pagination for tickets
-func (h *SupportHandler) applyTicketFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, int, int) {
+func (h *SupportHandler) applyTicketFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, Pagination, error) {
+	return paginate(c, h.filterTickets(c, query))
+}
+
+// filterTickets applies the tickets list filters and sort order
+func (h *SupportHandler) filterTickets(c *gin.Context, query *gorm.DB) *gorm.DB {
 	if status := c.Query("status"); status != "" {
 		query = query.Where("status IN ?", strings.Split(strings.ToUpper(status), ","))
 	}
@@ -97,24 +109,14 @@ func (h *SupportHandler) applyTicketFilters(c *gin.Context, query *gorm.DB) (*go
 	default:
 		query = query.Order("created_at DESC")
 	}
-	// pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize <= 0 || pageSize > 200 {
-		pageSize = 20
-	}
-	query = query.Offset((page - 1) * pageSize).Limit(pageSize)
-	return query, page, pageSize
+	return query
 }
 
 // CreateTicket creates a new support ticket
 func (h *SupportHandler) CreateTicket(c *gin.Context) {
 	var request CreateTicketRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/create-ticket", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/create-ticket", err)
 		return
 	}
 
@@ -136,10 +138,11 @@ func (h *SupportHandler) CreateTicket(c *gin.Context) {
 		OrderID:     request.OrderID,
 	}
 
-	if err := h.db.Create(&ticket).Error; err != nil {
+	if err := h.createTicket(&ticket); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/create-ticket", err.Error())
 		return
 	}
+	h.scheduleSLA(models.SLASubjectTicket, ticket.ID)
 
 	// Handle attachments if provided
 	if len(request.Attachments) > 0 {
@@ -164,7 +167,17 @@ func (h *SupportHandler) CreateTicket(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Support ticket created successfully", ticket)
+	response.GenerateSuccessResponse(c, "Support ticket created successfully", dto.NewTicket(&ticket, dto.AudienceFor(c, permissions.SupportRead)))
+}
+
+// createTicket saves a new ticket and publishes ticket.created in the same transaction
+func (h *SupportHandler) createTicket(ticket *models.SupportTicket) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ticket).Error; err != nil {
+			return err
+		}
+		return webhook.Publish(tx, webhook.EventTicketCreated, webhook.TicketData(ticket))
+	})
 }
 
 // GetTicket retrieves a specific support ticket
@@ -190,14 +203,13 @@ func (h *SupportHandler) GetTicket(c *gin.Context) {
 
 	// Only allow users to view their own tickets or admins to view any ticket
 	if ticket.UserID != userID.(uint) {
-		userType, _ := c.Get("user_type")
-		if userType != models.Admin {
+		if !permissions.ContextHas(c, permissions.SupportRead) {
 			response.GenerateForbiddenResponse(c, "support/get-ticket", "Access denied")
 			return
 		}
 	}
 
-	response.GenerateSuccessResponse(c, "Ticket retrieved successfully", ticket)
+	response.GenerateSuccessResponse(c, "Ticket retrieved successfully", dto.NewTicket(&ticket, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // GetUserTickets retrieves all tickets for the current user
@@ -210,32 +222,45 @@ func (h *SupportHandler) GetUserTickets(c *gin.Context) {
 
 	var tickets []models.SupportTicket
 	q := h.db.Where("user_id = ?", userID).Model(&models.SupportTicket{})
-	q, _, _ = h.applyTicketFilters(c, q)
+	q, pagination, err := h.applyTicketFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-user-tickets", err.Error())
+		return
+	}
 	if err := q.Preload("User").Preload("Order").Order("created_at DESC").Find(&tickets).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-user-tickets", err.Error())
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "User tickets retrieved successfully", tickets)
+	response.GenerateSuccessResponse(c, "User tickets retrieved successfully", gin.H{
+		"tickets":    dto.NewTickets(tickets, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination": pagination,
+	})
 }
 
 // GetAllTickets retrieves all tickets (admin only)
 func (h *SupportHandler) GetAllTickets(c *gin.Context) {
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportRead) {
 		response.GenerateForbiddenResponse(c, "support/get-all-tickets", "Admin access required")
 		return
 	}
 
 	var tickets []models.SupportTicket
 	q := h.db.Model(&models.SupportTicket{})
-	q, _, _ = h.applyTicketFilters(c, q)
+	q, pagination, err := h.applyTicketFilters(c, q)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-all-tickets", err.Error())
+		return
+	}
 	if err := q.Preload("User").Preload("Order").Find(&tickets).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/get-all-tickets", err.Error())
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "All tickets retrieved successfully", tickets)
+	response.GenerateSuccessResponse(c, "All tickets retrieved successfully", gin.H{
+		"tickets":    dto.NewTickets(tickets, dto.AudienceFor(c, permissions.SupportRead)),
+		"pagination": pagination,
+	})
 }
 
 // UpdateTicket updates a support ticket
@@ -248,7 +273,7 @@ func (h *SupportHandler) UpdateTicket(c *gin.Context) {
 
 	var request UpdateTicketRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/update-ticket", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/update-ticket", err)
 		return
 	}
 
@@ -265,8 +290,7 @@ func (h *SupportHandler) UpdateTicket(c *gin.Context) {
 		return
 	}
 
-	userType, _ := c.Get("user_type")
-	if ticket.UserID != userID.(uint) && userType != models.Admin {
+	if ticket.UserID != userID.(uint) && !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/update-ticket", "Access denied")
 		return
 	}
@@ -300,28 +324,30 @@ func (h *SupportHandler) UpdateTicket(c *gin.Context) {
 		updates["internal_notes"] = request.InternalNotes
 	}
 
-	if err := h.db.Model(&ticket).Updates(updates).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ticket).Updates(updates).Error; err != nil {
+			return err
+		}
+		h.queueTicketStatusEmail(tx, &ticket, updates)
+		return nil
+	}); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/update-ticket", err.Error())
 		return
 	}
+	_, categoryChanged := updates["category"]
+	_, priorityChanged := updates["priority"]
+	if categoryChanged || priorityChanged {
+		h.scheduleSLA(models.SLASubjectTicket, ticket.ID)
+	}
 
-	// send status update email if status changed
-	if _, ok := updates["status"]; ok && h.emailTriggerSvc != nil {
-		// Load user for email
-		var user models.User
-		if err := h.db.First(&user, ticket.UserID).Error; err == nil {
-			data := map[string]interface{}{
-				"UserName":        strings.TrimSpace(user.FirstName + " " + user.LastName),
-				"TicketID":        ticket.ID,
-				"TicketTitle":     ticket.Title,
-				"OldStatus":       "",
-				"NewStatus":       updates["status"],
-				"UserMessageHTML": template.HTML(ticket.Description),
-				"AdminNoteHTML":   template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
-				"subject":         fmt.Sprintf("Your ticket #%d status updated", ticket.ID),
-			}
-			_ = h.emailTriggerSvc.TriggerTicketStatusUpdated(user.Email, data["UserName"].(string), data)
-		}
+	if status, ok := updates["status"]; ok {
+		h.notify(ticket.UserID, notification.Message{
+			Type:  models.NotificationTypeTicketStatus,
+			Title: fmt.Sprintf("Ticket #%d is %v", ticket.ID, status),
+			Body:  ticket.Title,
+			Link:  fmt.Sprintf("/support/tickets/%d", ticket.ID),
+			Data:  models.JSON{"ticket_id": ticket.ID, "status": status},
+		})
 	}
 
 	// Load updated ticket
@@ -330,7 +356,7 @@ func (h *SupportHandler) UpdateTicket(c *gin.Context) {
 		return
 	}
 
-	response.GenerateSuccessResponse(c, "Ticket updated successfully", ticket)
+	response.GenerateSuccessResponse(c, "Ticket updated successfully", dto.NewTicket(&ticket, dto.AudienceFor(c, permissions.SupportRead)))
 }
 
 // AddTicketResponse adds a response to a support ticket
@@ -343,7 +369,7 @@ func (h *SupportHandler) AddTicketResponse(c *gin.Context) {
 
 	var request TicketResponseRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		response.GenerateBadRequestResponse(c, "support/add-ticket-response", err.Error())
+		response.GenerateValidationErrorResponse(c, "support/add-ticket-response", err)
 		return
 	}
 
@@ -359,8 +385,7 @@ func (h *SupportHandler) AddTicketResponse(c *gin.Context) {
 		return
 	}
 
-	userType, _ := c.Get("user_type")
-	isAdmin := userType == models.Admin
+	isAdmin := permissions.ContextHas(c, permissions.SupportAdmin)
 
 	// Check permissions
 	if ticket.UserID != userID.(uint) && !isAdmin {
@@ -368,50 +393,89 @@ func (h *SupportHandler) AddTicketResponse(c *gin.Context) {
 		return
 	}
 
+	message, ok := h.composeResponseMessage(c, "support/add-ticket-response", request.Message, request.CannedResponseID, isAdmin, func() CannedResponseData {
+		return h.ticketCannedResponseData(&ticket, userID.(uint))
+	})
+	if !ok {
+		return
+	}
+
 	// Create response
 	ticketResponse := models.TicketResponse{
 		TicketID:    uint(ticketID),
 		UserID:      userID.(uint),
-		Message:     request.Message,
+		Message:     message,
 		IsInternal:  request.IsInternal,
 		IsFromAdmin: isAdmin,
 	}
 
-	if err := h.db.Create(&ticketResponse).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ticketResponse).Error; err != nil {
+			return err
+		}
+		h.queueTicketResponseEmail(tx, &ticket, &ticketResponse)
+		return nil
+	}); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/add-ticket-response", err.Error())
 		return
 	}
 
-	// Email notify ticket owner on non-internal responses
-	if !request.IsInternal && h.emailTriggerSvc != nil {
-		var owner models.User
-		if err := h.db.First(&owner, ticket.UserID).Error; err == nil {
-			// responder name
-			responderName := "User"
-			var responder models.User
-			if err := h.db.First(&responder, userID.(uint)).Error; err == nil {
-				responderName = strings.TrimSpace(responder.FirstName + " " + responder.LastName)
-			}
-			data := map[string]interface{}{
-				"UserName":        strings.TrimSpace(owner.FirstName + " " + owner.LastName),
-				"TicketID":        ticket.ID,
-				"TicketTitle":     ticket.Title,
-				"UserMessageHTML": template.HTML(ticket.Description),
-				"ResponderName":   responderName,
-				"RespondedAt":     time.Now().Format("2006-01-02 15:04:05"),
-				"ResponseHTML":    template.HTML(request.Message),
-				"subject":         fmt.Sprintf("New response on your ticket #%d", ticket.ID),
-			}
-			_ = h.emailTriggerSvc.TriggerTicketResponse(owner.Email, data["UserName"].(string), data)
-		}
+	h.afterTicketResponse(&ticket, &ticketResponse)
+
+	response.GenerateSuccessResponse(c, "Response added successfully", dto.NewTicketResponse(&ticketResponse, dto.AudienceFor(c, permissions.SupportRead)))
+}
+
+// afterTicketResponse tells the ticket owner about a new response, tracks the
+// first staff response against the SLA and moves open tickets into progress
+func (h *SupportHandler) afterTicketResponse(ticket *models.SupportTicket, resp *models.TicketResponse) {
+	if !resp.IsInternal && ticket.UserID != resp.UserID {
+		h.notify(ticket.UserID, notification.Message{
+			Type:  models.NotificationTypeTicketResponse,
+			Title: fmt.Sprintf("New response on ticket #%d", ticket.ID),
+			Body:  ticket.Title,
+			Link:  fmt.Sprintf("/support/tickets/%d", ticket.ID),
+			Data:  models.JSON{"ticket_id": ticket.ID, "response_id": resp.ID},
+		})
+	}
+
+	if resp.IsFromAdmin && !resp.IsInternal {
+		h.recordFirstResponse(models.SLASubjectTicket, ticket.ID)
 	}
 
 	// Update ticket status if admin responded
-	if isAdmin && ticket.Status == models.TicketStatusOpen {
-		h.db.Model(&ticket).Update("status", models.TicketStatusInProgress)
+	if resp.IsFromAdmin && ticket.Status == models.TicketStatusOpen {
+		h.db.Model(ticket).Update("status", models.TicketStatusInProgress)
 	}
+}
 
-	response.GenerateSuccessResponse(c, "Response added successfully", ticketResponse)
+// queueTicketResponseEmail records the email telling the ticket owner about a
+// new response in the outbox of tx. Internal notes are not emailed.
+func (h *SupportHandler) queueTicketResponseEmail(tx *gorm.DB, ticket *models.SupportTicket, resp *models.TicketResponse) {
+	if resp.IsInternal || h.emailTriggerSvc == nil {
+		return
+	}
+	var owner models.User
+	if err := tx.First(&owner, ticket.UserID).Error; err == nil {
+		// responder name
+		responderName := "User"
+		var responder models.User
+		if err := tx.First(&responder, resp.UserID).Error; err == nil {
+			responderName = strings.TrimSpace(responder.FirstName + " " + responder.LastName)
+		}
+		data := map[string]interface{}{
+			"UserName":        strings.TrimSpace(owner.FirstName + " " + owner.LastName),
+			"TicketID":        ticket.ID,
+			"TicketTitle":     ticket.Title,
+			"UserMessageHTML": template.HTML(ticket.Description),
+			"ResponderName":   responderName,
+			"RespondedAt":     time.Now().Format("2006-01-02 15:04:05"),
+			"ResponseHTML":    template.HTML(resp.Message),
+			"subject":         fmt.Sprintf("New response on your ticket #%d", ticket.ID),
+		}
+		if err := h.emailTriggerSvc.InTx(tx).TriggerTicketResponse(owner.Email, data["UserName"].(string), data); err != nil {
+			log.Printf("Failed to queue ticket response email for ticket %d: %v", ticket.ID, err)
+		}
+	}
 }
 
 // DeleteTicket deletes a support ticket (admin only)
@@ -422,8 +486,7 @@ func (h *SupportHandler) DeleteTicket(c *gin.Context) {
 		return
 	}
 
-	userType, exists := c.Get("user_type")
-	if !exists || userType != models.Admin {
+	if !permissions.ContextHas(c, permissions.SupportAdmin) {
 		response.GenerateForbiddenResponse(c, "support/delete-ticket", "Admin access required")
 		return
 	}
@@ -434,10 +497,35 @@ func (h *SupportHandler) DeleteTicket(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Delete(&ticket).Error; err != nil {
+	// With its responses and attachments, so that it can be restored
+	if err := h.db.Transaction(func(tx *gorm.DB) error { return trash.Delete(tx, trash.Tickets, ticket.ID) }); err != nil {
 		response.GenerateInternalServerErrorResponse(c, "support/delete-ticket", err.Error())
 		return
 	}
 
 	response.GenerateSuccessResponse(c, "Ticket deleted successfully", nil)
 }
+
+// queueTicketStatusEmail records the status update email for a ticket in the outbox of tx
+func (h *SupportHandler) queueTicketStatusEmail(tx *gorm.DB, ticket *models.SupportTicket, updates map[string]interface{}) {
+	if _, ok := updates["status"]; !ok || h.emailTriggerSvc == nil {
+		return
+	}
+	// Load user for email
+	var user models.User
+	if err := tx.First(&user, ticket.UserID).Error; err == nil {
+		data := map[string]interface{}{
+			"UserName":        strings.TrimSpace(user.FirstName + " " + user.LastName),
+			"TicketID":        ticket.ID,
+			"TicketTitle":     ticket.Title,
+			"OldStatus":       "",
+			"NewStatus":       updates["status"],
+			"UserMessageHTML": template.HTML(ticket.Description),
+			"AdminNoteHTML":   template.HTML(fmt.Sprintf("%v", updates["internal_notes"])),
+			"subject":         fmt.Sprintf("Your ticket #%d status updated", ticket.ID),
+		}
+		if err := h.emailTriggerSvc.InTx(tx).TriggerTicketStatusUpdated(user.Email, data["UserName"].(string), data); err != nil {
+			log.Printf("Failed to queue ticket status email for ticket %d: %v", ticket.ID, err)
+		}
+	}
+}
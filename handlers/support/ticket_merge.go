@@ -0,0 +1,283 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/dto"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MergeTicketRequest represents the request to merge a duplicate ticket into another ticket
+type MergeTicketRequest struct {
+	TargetTicketID uint `json:"target_ticket_id" binding:"required"`
+}
+
+// LinkTicketOrderRequest represents the request to link a ticket to an order
+type LinkTicketOrderRequest struct {
+	OrderID uint `json:"order_id" binding:"required"`
+}
+
+// LinkTicketDisputeRequest represents the request to link a ticket to a dispute
+type LinkTicketDisputeRequest struct {
+	DisputeID uint `json:"dispute_id" binding:"required"`
+}
+
+// MergeTicket merges the duplicate ticket :id into the target ticket. Responses
+// and attachments move to the target, the source description is kept as an
+// internal note and the source ticket is closed.
+func (h *SupportHandler) MergeTicket(c *gin.Context) {
+	source, ok := h.findTicket(c, "support/merge-ticket")
+	if !ok {
+		return
+	}
+
+	var request MergeTicketRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.GenerateValidationErrorResponse(c, "support/merge-ticket", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "support/merge-ticket", "User not authenticated")
+		return
+	}
+
+	if request.TargetTicketID == source.ID {
+		response.GenerateBadRequestResponse(c, "support/merge-ticket", "A ticket cannot be merged into itself")
+		return
+	}
+	if source.MergedIntoID != nil {
+		response.GenerateBadRequestResponse(c, "support/merge-ticket", fmt.Sprintf("Ticket was already merged into ticket #%d", *source.MergedIntoID))
+		return
+	}
+
+	var target models.SupportTicket
+	if err := h.db.First(&target, request.TargetTicketID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "support/merge-ticket", "Target ticket not found")
+		return
+	}
+	if target.MergedIntoID != nil {
+		response.GenerateBadRequestResponse(c, "support/merge-ticket", "Target ticket was itself merged into another ticket")
+		return
+	}
+	if target.UserID != source.UserID {
+		response.GenerateBadRequestResponse(c, "support/merge-ticket", "Only tickets from the same customer can be merged")
+		return
+	}
+
+	merge := models.TicketMerge{
+		SourceTicketID: source.ID,
+		TargetTicketID: target.ID,
+		MergedBy:       userID.(uint),
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TicketResponse{}).Where("ticket_id = ?", source.ID).Update("ticket_id", target.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		merge.ResponsesMoved = result.RowsAffected
+
+		result = tx.Model(&models.TicketAttachment{}).Where("ticket_id = ?", source.ID).Update("ticket_id", target.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		merge.AttachmentsMoved = result.RowsAffected
+
+		// Keep the duplicate's original request visible on the target
+		note := models.TicketResponse{
+			TicketID:    target.ID,
+			UserID:      userID.(uint),
+			Message:     fmt.Sprintf("Merged ticket #%d: %s\n\n%s", source.ID, source.Title, source.Description),
+			IsInternal:  true,
+			IsFromAdmin: true,
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+
+		// Carry over the order and dispute links the target does not have yet
+		targetUpdates := map[string]interface{}{}
+		if target.OrderID == nil && source.OrderID != nil {
+			targetUpdates["order_id"] = *source.OrderID
+		}
+		if target.DisputeID == nil && source.DisputeID != nil {
+			targetUpdates["dispute_id"] = *source.DisputeID
+		}
+		if len(targetUpdates) > 0 {
+			if err := tx.Model(&target).Updates(targetUpdates).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if err := tx.Model(source).Updates(map[string]interface{}{
+			"status":         models.TicketStatusClosed,
+			"merged_into_id": target.ID,
+			"resolution":     fmt.Sprintf("Merged into ticket #%d", target.ID),
+			"resolved_at":    &now,
+			"resolved_by":    userID,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&merge).Error
+	})
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/merge-ticket", err.Error())
+		return
+	}
+
+	h.notify(source.UserID, notification.Message{
+		Type:  models.NotificationTypeTicketStatus,
+		Title: fmt.Sprintf("Ticket #%d was merged into ticket #%d", source.ID, target.ID),
+		Body:  target.Title,
+		Link:  fmt.Sprintf("/support/tickets/%d", target.ID),
+		Data:  models.JSON{"ticket_id": target.ID, "merged_ticket_id": source.ID},
+	})
+
+	if err := h.db.Preload("User").Preload("Order").Preload("Attachments").Preload("Responses.User").First(&target, target.ID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/merge-ticket", "Failed to load merged ticket")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Ticket merged successfully", gin.H{
+		"merge":  merge,
+		"ticket": dto.NewTicket(&target, dto.AudienceFor(c, permissions.SupportRead)),
+	})
+}
+
+// GetTicketMerges lists the merges a ticket took part in, as source or target
+func (h *SupportHandler) GetTicketMerges(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "support/get-ticket-merges", "Invalid ticket ID")
+		return
+	}
+
+	var merges []models.TicketMerge
+	if err := h.db.Where("source_ticket_id = ? OR target_ticket_id = ?", ticketID, ticketID).
+		Order("created_at DESC").
+		Find(&merges).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "support/get-ticket-merges", "Failed to get ticket merges")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Ticket merges retrieved successfully", merges)
+}
+
+// LinkTicketOrder links a ticket to one of its owner's orders
+func (h *SupportHandler) LinkTicketOrder(c *gin.Context) {
+	ticket, ok := h.findTicket(c, "support/link-ticket-order")
+	if !ok {
+		return
+	}
+
+	var request LinkTicketOrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.GenerateValidationErrorResponse(c, "support/link-ticket-order", err)
+		return
+	}
+
+	var order models.Order
+	if err := h.db.First(&order, request.OrderID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "support/link-ticket-order", "Order not found")
+		return
+	}
+	if order.UserID != ticket.UserID {
+		response.GenerateBadRequestResponse(c, "support/link-ticket-order", "Order belongs to a different customer")
+		return
+	}
+
+	h.updateTicketLink(c, "support/link-ticket-order", ticket, "order_id", order.ID, "Ticket linked to order successfully")
+}
+
+// UnlinkTicketOrder removes the order link from a ticket
+func (h *SupportHandler) UnlinkTicketOrder(c *gin.Context) {
+	ticket, ok := h.findTicket(c, "support/unlink-ticket-order")
+	if !ok {
+		return
+	}
+
+	h.updateTicketLink(c, "support/unlink-ticket-order", ticket, "order_id", nil, "Ticket unlinked from order successfully")
+}
+
+// LinkTicketDispute links a ticket to one of its owner's disputes
+func (h *SupportHandler) LinkTicketDispute(c *gin.Context) {
+	ticket, ok := h.findTicket(c, "support/link-ticket-dispute")
+	if !ok {
+		return
+	}
+
+	var request LinkTicketDisputeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.GenerateValidationErrorResponse(c, "support/link-ticket-dispute", err)
+		return
+	}
+
+	var dispute models.Dispute
+	if err := h.db.First(&dispute, request.DisputeID).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "support/link-ticket-dispute", "Dispute not found")
+		return
+	}
+	if dispute.UserID != ticket.UserID {
+		response.GenerateBadRequestResponse(c, "support/link-ticket-dispute", "Dispute belongs to a different customer")
+		return
+	}
+
+	h.updateTicketLink(c, "support/link-ticket-dispute", ticket, "dispute_id", dispute.ID, "Ticket linked to dispute successfully")
+}
+
+// UnlinkTicketDispute removes the dispute link from a ticket
+func (h *SupportHandler) UnlinkTicketDispute(c *gin.Context) {
+	ticket, ok := h.findTicket(c, "support/unlink-ticket-dispute")
+	if !ok {
+		return
+	}
+
+	h.updateTicketLink(c, "support/unlink-ticket-dispute", ticket, "dispute_id", nil, "Ticket unlinked from dispute successfully")
+}
+
+// updateTicketLink sets or clears a ticket's link column and responds with the reloaded ticket
+func (h *SupportHandler) updateTicketLink(c *gin.Context, code string, ticket *models.SupportTicket, column string, value interface{}, message string) {
+	if err := h.db.Model(ticket).Update(column, value).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, err.Error())
+		return
+	}
+
+	if err := h.db.Preload("User").Preload("Order").Preload("Dispute").Preload("Attachments").First(ticket, ticket.ID).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to load updated ticket")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, message, dto.NewTicket(ticket, dto.AudienceFor(c, permissions.SupportRead)))
+}
+
+// findTicket loads the ticket named by the :id parameter, writing an error
+// response when it cannot
+func (h *SupportHandler) findTicket(c *gin.Context, code string) (*models.SupportTicket, bool) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, "Invalid ticket ID")
+		return nil, false
+	}
+
+	var ticket models.SupportTicket
+	if err := h.db.First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.GenerateNotFoundResponse(c, code, "Ticket not found")
+		} else {
+			response.GenerateInternalServerErrorResponse(c, code, err.Error())
+		}
+		return nil, false
+	}
+	return &ticket, true
+}
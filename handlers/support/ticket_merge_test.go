@@ -0,0 +1,111 @@
+package support
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupMergeTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{}, &models.Notification{}, &models.Order{}, &models.Dispute{},
+		&models.SupportTicket{}, &models.TicketResponse{}, &models.TicketAttachment{}, &models.TicketMerge{},
+	))
+
+	handler := NewSupportHandler(db, nil, nil, nil)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", uint(99)) })
+	router.POST("/admin/tickets/:id/merge", handler.MergeTicket)
+	router.PUT("/admin/tickets/:id/dispute", handler.LinkTicketDispute)
+	router.DELETE("/admin/tickets/:id/dispute", handler.UnlinkTicketDispute)
+	return router, db
+}
+
+func sendJSON(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMergeTicket(t *testing.T) {
+	router, db := setupMergeTest(t)
+	target := models.SupportTicket{UserID: 1, Title: "Late delivery", Description: "Order is late", Category: models.TicketCategoryShipping, Status: models.TicketStatusOpen}
+	source := models.SupportTicket{UserID: 1, Title: "Where is my order", Description: "Still waiting", Category: models.TicketCategoryShipping, Status: models.TicketStatusOpen}
+	other := models.SupportTicket{UserID: 2, Title: "Other customer", Description: "Hello", Category: models.TicketCategoryGeneral, Status: models.TicketStatusOpen}
+	for _, ticket := range []*models.SupportTicket{&target, &source, &other} {
+		require.NoError(t, db.Create(ticket).Error)
+	}
+	require.NoError(t, db.Create(&models.TicketResponse{TicketID: source.ID, UserID: 1, Message: "Any news?"}).Error)
+	require.NoError(t, db.Create(&models.TicketAttachment{TicketID: source.ID, FileName: "receipt.pdf", FileURL: "https://example.com/receipt.pdf"}).Error)
+
+	w := sendJSON(router, http.MethodPost, "/admin/tickets/2/merge", `{"target_ticket_id": 3}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendJSON(router, http.MethodPost, "/admin/tickets/2/merge", `{"target_ticket_id": 1}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var merged models.SupportTicket
+	require.NoError(t, db.First(&merged, source.ID).Error)
+	assert.Equal(t, models.TicketStatusClosed, merged.Status)
+	require.NotNil(t, merged.MergedIntoID)
+	assert.Equal(t, target.ID, *merged.MergedIntoID)
+
+	var responses []models.TicketResponse
+	require.NoError(t, db.Where("ticket_id = ?", target.ID).Order("id").Find(&responses).Error)
+	require.Len(t, responses, 2)
+	assert.Equal(t, "Any news?", responses[0].Message)
+	assert.True(t, responses[1].IsInternal)
+	assert.Contains(t, responses[1].Message, "Still waiting")
+
+	var attachments int64
+	db.Model(&models.TicketAttachment{}).Where("ticket_id = ?", target.ID).Count(&attachments)
+	assert.Equal(t, int64(1), attachments)
+
+	var merge models.TicketMerge
+	require.NoError(t, db.First(&merge).Error)
+	assert.Equal(t, int64(1), merge.ResponsesMoved)
+	assert.Equal(t, int64(1), merge.AttachmentsMoved)
+	assert.Equal(t, uint(99), merge.MergedBy)
+
+	// A merged ticket cannot be merged again
+	w = sendJSON(router, http.MethodPost, "/admin/tickets/2/merge", `{"target_ticket_id": 1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLinkTicketDispute(t *testing.T) {
+	router, db := setupMergeTest(t)
+	ticket := models.SupportTicket{UserID: 1, Title: "Refund", Description: "Refund please", Category: models.TicketCategoryPayment, Status: models.TicketStatusOpen}
+	require.NoError(t, db.Create(&ticket).Error)
+	own := models.Dispute{UserID: 1, Title: "Charged twice", Description: "Double charge", Category: models.DisputeCategoryPayment}
+	foreign := models.Dispute{UserID: 2, Title: "Not mine", Description: "Other", Category: models.DisputeCategoryPayment}
+	require.NoError(t, db.Create(&own).Error)
+	require.NoError(t, db.Create(&foreign).Error)
+
+	w := sendJSON(router, http.MethodPut, "/admin/tickets/1/dispute", `{"dispute_id": 2}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendJSON(router, http.MethodPut, "/admin/tickets/1/dispute", `{"dispute_id": 1}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, db.First(&ticket, ticket.ID).Error)
+	require.NotNil(t, ticket.DisputeID)
+	assert.Equal(t, own.ID, *ticket.DisputeID)
+
+	w = sendJSON(router, http.MethodDelete, "/admin/tickets/1/dispute", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var reloaded models.SupportTicket
+	require.NoError(t, db.First(&reloaded, ticket.ID).Error)
+	assert.Nil(t, reloaded.DisputeID)
+}
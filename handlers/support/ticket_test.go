@@ -0,0 +1,56 @@
+package support
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGetAllTicketsPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Order{}, &models.SupportTicket{}))
+	for i := 0; i < 5; i++ {
+		status := models.TicketStatusOpen
+		if i == 4 {
+			status = models.TicketStatusClosed
+		}
+		require.NoError(t, db.Create(&models.SupportTicket{UserID: 1, Title: "Ticket", Description: "x", Category: models.TicketCategoryGeneral, Status: status}).Error)
+	}
+
+	handler := NewSupportHandler(db, nil, nil, nil)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_type", models.Admin) })
+	router.GET("/admin/tickets", handler.GetAllTickets)
+
+	list := func(query string) (int, Pagination) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/tickets?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body struct {
+			Data struct {
+				Tickets    []models.SupportTicket `json:"tickets"`
+				Pagination Pagination             `json:"pagination"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return len(body.Data.Tickets), body.Data.Pagination
+	}
+
+	count, pagination := list("status=open&page_size=3")
+	assert.Equal(t, 3, count)
+	assert.Equal(t, Pagination{Page: 1, Limit: 3, Total: 4, TotalPages: 2, HasNext: true}, pagination)
+
+	count, pagination = list("status=open&page_size=3&page=2")
+	assert.Equal(t, 1, count)
+	assert.Equal(t, Pagination{Page: 2, Limit: 3, Total: 4, TotalPages: 2, HasPrev: true}, pagination)
+}
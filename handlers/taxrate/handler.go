@@ -0,0 +1,13 @@
+package taxrate
+
+import (
+	"gorm.io/gorm"
+)
+
+type TaxRateHandler struct {
+	db *gorm.DB
+}
+
+func NewTaxRateHandler(db *gorm.DB) *TaxRateHandler {
+	return &TaxRateHandler{db: db}
+}
@@ -0,0 +1,143 @@
+package taxrate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTaxRateRequest struct {
+	CountryCode string  `json:"country_code" binding:"required,len=2"`
+	CountryName string  `json:"country_name"`
+	Name        string  `json:"name"`
+	Rate        float64 `json:"rate" binding:"min=0,max=100"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+type UpdateTaxRateRequest struct {
+	CountryName *string  `json:"country_name"`
+	Name        *string  `json:"name"`
+	Rate        *float64 `json:"rate" binding:"omitempty,min=0,max=100"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// GetTaxRates - Admin endpoint to list configured VAT rates
+func (h *TaxRateHandler) GetTaxRates(c *gin.Context) {
+	var rates []models.TaxRate
+	if err := h.db.Order("country_code ASC").Find(&rates).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "tax_rate/list", "Failed to fetch tax rates")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Tax rates retrieved successfully", rates)
+}
+
+// CreateTaxRate - Admin endpoint to configure the VAT rate for a country
+func (h *TaxRateHandler) CreateTaxRate(c *gin.Context) {
+	var req CreateTaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "tax_rate/create", err.Error())
+		return
+	}
+
+	countryCode := strings.ToUpper(req.CountryCode)
+	var count int64
+	h.db.Model(&models.TaxRate{}).Where("country_code = ?", countryCode).Count(&count)
+	if count > 0 {
+		response.GenerateBadRequestResponse(c, "tax_rate/create", "A tax rate already exists for this country")
+		return
+	}
+
+	rate := models.TaxRate{
+		CountryCode: countryCode,
+		CountryName: req.CountryName,
+		Name:        req.Name,
+		Rate:        req.Rate,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		rate.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Create(&rate).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "tax_rate/create", "Failed to create tax rate")
+		return
+	}
+	// GORM skips false for fields with a default, so persist it explicitly
+	if !rate.IsActive {
+		h.db.Model(&rate).Update("is_active", false)
+	}
+
+	response.GenerateCreatedResponse(c, "Tax rate created successfully", rate)
+}
+
+// UpdateTaxRate - Admin endpoint to update a VAT rate
+func (h *TaxRateHandler) UpdateTaxRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "tax_rate/update", "Invalid tax rate ID")
+		return
+	}
+
+	var req UpdateTaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "tax_rate/update", err.Error())
+		return
+	}
+
+	var rate models.TaxRate
+	if err := h.db.First(&rate, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "tax_rate/update", "Tax rate not found")
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.CountryName != nil {
+		updates["country_name"] = *req.CountryName
+	}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Rate != nil {
+		updates["rate"] = *req.Rate
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(&rate).Updates(updates).Error; err != nil {
+			response.GenerateInternalServerErrorResponse(c, "tax_rate/update", "Failed to update tax rate")
+			return
+		}
+	}
+
+	h.db.First(&rate, rate.ID)
+	response.GenerateSuccessResponse(c, "Tax rate updated successfully", rate)
+}
+
+// DeleteTaxRate - Admin endpoint to delete a VAT rate
+func (h *TaxRateHandler) DeleteTaxRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "tax_rate/delete", "Invalid tax rate ID")
+		return
+	}
+
+	var rate models.TaxRate
+	if err := h.db.First(&rate, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, "tax_rate/delete", "Tax rate not found")
+		return
+	}
+
+	// Hard delete so the country code can be configured again
+	if err := h.db.Unscoped().Delete(&rate).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "tax_rate/delete", "Failed to delete tax rate")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Tax rate deleted successfully", nil)
+}
@@ -0,0 +1,13 @@
+package translation
+
+import (
+	"gorm.io/gorm"
+)
+
+type TranslationHandler struct {
+	db *gorm.DB
+}
+
+func NewTranslationHandler(db *gorm.DB) *TranslationHandler {
+	return &TranslationHandler{db: db}
+}
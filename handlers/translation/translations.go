@@ -0,0 +1,136 @@
+package translation
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// respondSetError maps the errors of saving a translation to a response
+func respondSetError(c *gin.Context, code, notFound string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.GenerateNotFoundResponse(c, code, notFound)
+	case errors.Is(err, i18n.ErrUnsupportedLocale), errors.Is(err, i18n.ErrDefaultLocale), errors.Is(err, i18n.ErrEmptyTranslation):
+		response.GenerateBadRequestResponse(c, code, err.Error())
+	default:
+		response.GenerateInternalServerErrorResponse(c, code, "Failed to save translation")
+	}
+}
+
+// GetProductTranslations - Admin endpoint to list a product's translations
+func (h *TranslationHandler) GetProductTranslations(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "translation/product_list", "Invalid product ID")
+		return
+	}
+	var translations []models.ProductTranslation
+	if err := h.db.Where("product_id = ?", id).Order("locale ASC").Find(&translations).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "translation/product_list", "Failed to fetch translations")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Translations retrieved successfully", translations)
+}
+
+// SetProductTranslation - Admin endpoint to create or replace a product's
+// translation into a locale
+func (h *TranslationHandler) SetProductTranslation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "translation/product_set", "Invalid product ID")
+		return
+	}
+	var req i18n.Translation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "translation/product_set", err.Error())
+		return
+	}
+	translation, err := i18n.SetProductTranslation(h.db, uint(id), c.Param("locale"), req)
+	if err != nil {
+		respondSetError(c, "translation/product_set", "Product not found", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Translation saved successfully", translation)
+}
+
+// DeleteProductTranslation - Admin endpoint to delete a product's translation
+// into a locale
+func (h *TranslationHandler) DeleteProductTranslation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "translation/product_delete", "Invalid product ID")
+		return
+	}
+	result := h.db.Where("product_id = ? AND locale = ?", id, c.Param("locale")).Delete(&models.ProductTranslation{})
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "translation/product_delete", "Failed to delete translation")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateNotFoundResponse(c, "translation/product_delete", "Translation not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Translation deleted successfully", nil)
+}
+
+// GetCategoryTranslations - Admin endpoint to list a category's translations
+func (h *TranslationHandler) GetCategoryTranslations(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "translation/category_list", "Invalid category ID")
+		return
+	}
+	var translations []models.CategoryTranslation
+	if err := h.db.Where("category_id = ?", id).Order("locale ASC").Find(&translations).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "translation/category_list", "Failed to fetch translations")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Translations retrieved successfully", translations)
+}
+
+// SetCategoryTranslation - Admin endpoint to create or replace a category's
+// translation into a locale
+func (h *TranslationHandler) SetCategoryTranslation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "translation/category_set", "Invalid category ID")
+		return
+	}
+	var req i18n.Translation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "translation/category_set", err.Error())
+		return
+	}
+	translation, err := i18n.SetCategoryTranslation(h.db, uint(id), c.Param("locale"), req)
+	if err != nil {
+		respondSetError(c, "translation/category_set", "Category not found", err)
+		return
+	}
+	response.GenerateSuccessResponse(c, "Translation saved successfully", translation)
+}
+
+// DeleteCategoryTranslation - Admin endpoint to delete a category's
+// translation into a locale
+func (h *TranslationHandler) DeleteCategoryTranslation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "translation/category_delete", "Invalid category ID")
+		return
+	}
+	result := h.db.Where("category_id = ? AND locale = ?", id, c.Param("locale")).Delete(&models.CategoryTranslation{})
+	if result.Error != nil {
+		response.GenerateInternalServerErrorResponse(c, "translation/category_delete", "Failed to delete translation")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.GenerateNotFoundResponse(c, "translation/category_delete", "Translation not found")
+		return
+	}
+	response.GenerateSuccessResponse(c, "Translation deleted successfully", nil)
+}
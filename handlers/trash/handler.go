@@ -0,0 +1,97 @@
+package trash
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/trash"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TrashHandler struct {
+	db *gorm.DB
+}
+
+func NewTrashHandler(db *gorm.DB) *TrashHandler {
+	return &TrashHandler{db: db}
+}
+
+// kind reads the kind path parameter and checks the caller may manage it,
+// responding when it is unknown or they may not
+func kind(c *gin.Context, code string) (trash.Kind, bool) {
+	k := trash.Kind(c.Param("kind"))
+	scope, err := trash.Scope(k)
+	if err != nil {
+		response.GenerateNotFoundResponse(c, code, "Unknown kind '"+string(k)+"'")
+		return "", false
+	}
+	if !permissions.ContextHas(c, scope) {
+		response.GenerateForbiddenResponse(c, code, "missing required permission")
+		return "", false
+	}
+	return k, true
+}
+
+// ListDeleted - Admin endpoint listing the deleted entities of a kind that
+// can still be restored, most recently deleted first
+func (h *TrashHandler) ListDeleted(c *gin.Context) {
+	k, ok := kind(c, "trash/list")
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	items, totalCount, err := trash.List(h.db.WithContext(c.Request.Context()), k, page, limit)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "failed to list deleted entities", "component", "trash", "kind", k, "error", err)
+		response.GenerateInternalServerErrorResponse(c, "trash/list", "Failed to list deleted "+string(k))
+		return
+	}
+	response.GenerateSuccessResponse(c, "Deleted "+string(k)+" retrieved successfully", gin.H{
+		"items":       items,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"total_pages": (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// Restore - Admin endpoint restoring a deleted entity with what was deleted
+// along with it
+func (h *TrashHandler) Restore(c *gin.Context) {
+	k, ok := kind(c, "trash/restore")
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, "trash/restore", "Invalid ID")
+		return
+	}
+
+	err = h.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		return trash.Restore(tx, k, uint(id))
+	})
+	switch {
+	case errors.Is(err, trash.ErrNotFound):
+		response.GenerateNotFoundResponse(c, "trash/restore", "Not found")
+	case errors.Is(err, trash.ErrNotDeleted), errors.Is(err, trash.ErrParentDeleted):
+		response.GenerateBadRequestResponse(c, "trash/restore", "Cannot restore: "+err.Error())
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "failed to restore entity", "component", "trash", "kind", k, "id", id, "error", err)
+		response.GenerateInternalServerErrorResponse(c, "trash/restore", "Failed to restore")
+	default:
+		response.GenerateSuccessResponse(c, "Restored successfully", gin.H{"kind": k, "id": id})
+	}
+}
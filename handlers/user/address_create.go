@@ -1,19 +1,23 @@
 package user
 
 import (
+	addressService "github.com/YasserCherfaoui/MarketProGo/address"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 )
 
 type CreateAddressRequest struct {
-	StreetAddress1 string `json:"street_address1" binding:"required"`
-	StreetAddress2 string `json:"street_address2"`
-	City           string `json:"city" binding:"required"`
-	State          string `json:"state"`
-	PostalCode     string `json:"postal_code" binding:"required"`
-	Country        string `json:"country" binding:"required"`
-	IsDefault      bool   `json:"is_default"`
+	StreetAddress1   string `json:"street_address1" binding:"required"`
+	StreetAddress2   string `json:"street_address2"`
+	City             string `json:"city" binding:"required"`
+	State            string `json:"state"`
+	PostalCode       string `json:"postal_code" binding:"required"`
+	Country          string `json:"country" binding:"required"`
+	Label            string `json:"label"`
+	Phone            string `json:"phone"`
+	IsDefault        bool   `json:"is_default"`         // default shipping address
+	IsDefaultBilling bool   `json:"is_default_billing"` // default billing address
 }
 
 func (h *UserHandler) CreateAddress(c *gin.Context) {
@@ -30,6 +34,25 @@ func (h *UserHandler) CreateAddress(c *gin.Context) {
 		return
 	}
 
+	address := models.Address{
+		StreetAddress1:   req.StreetAddress1,
+		StreetAddress2:   req.StreetAddress2,
+		City:             req.City,
+		State:            req.State,
+		PostalCode:       req.PostalCode,
+		Country:          req.Country,
+		Label:            req.Label,
+		Phone:            req.Phone,
+		IsDefault:        req.IsDefault,
+		IsDefaultBilling: req.IsDefaultBilling,
+		UserID:           &uid,
+	}
+	if err := addressService.Normalize(&address); err != nil {
+		response.GenerateBadRequestResponse(c, "user/create_address", err.Error())
+		return
+	}
+	addressService.Geocode(c.Request.Context(), h.geocoder, &address)
+
 	// Start transaction
 	tx := h.db.Begin()
 	defer func() {
@@ -38,27 +61,15 @@ func (h *UserHandler) CreateAddress(c *gin.Context) {
 		}
 	}()
 
-	// If this is set as default, unset other default addresses for this user
-	if req.IsDefault {
-		if err := tx.Model(&models.Address{}).
-			Where("user_id = ? AND is_default = ?", uid, true).
-			Update("is_default", false).Error; err != nil {
-			tx.Rollback()
-			response.GenerateInternalServerErrorResponse(c, "user/create_address", "Failed to update existing default addresses")
-			return
-		}
+	// A user's first address is their default for shipping and billing
+	var count int64
+	if err := tx.Model(&models.Address{}).Where("user_id = ?", uid).Count(&count).Error; err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "user/create_address", "Failed to check existing addresses")
+		return
 	}
-
-	// Create new address
-	address := models.Address{
-		StreetAddress1: req.StreetAddress1,
-		StreetAddress2: req.StreetAddress2,
-		City:           req.City,
-		State:          req.State,
-		PostalCode:     req.PostalCode,
-		Country:        req.Country,
-		IsDefault:      req.IsDefault,
-		UserID:         &uid,
+	if count == 0 {
+		address.IsDefault, address.IsDefaultBilling = true, true
 	}
 
 	if err := tx.Create(&address).Error; err != nil {
@@ -67,6 +78,13 @@ func (h *UserHandler) CreateAddress(c *gin.Context) {
 		return
 	}
 
+	// Unset the user's other default addresses
+	if err := addressService.ClaimDefaults(tx, &address); err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "user/create_address", "Failed to update existing default addresses")
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "user/create_address", "Failed to commit transaction")
@@ -0,0 +1,43 @@
+package user
+
+import (
+	"errors"
+
+	addressService "github.com/YasserCherfaoui/MarketProGo/address"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DefaultAddressesResponse struct {
+	Shipping *models.Address `json:"shipping"`
+	Billing  *models.Address `json:"billing"`
+}
+
+// GetDefaultAddresses returns the addresses checkout prefills: the user's
+// default shipping and billing addresses, null when they have none saved
+func (h *UserHandler) GetDefaultAddresses(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.GenerateUnauthorizedResponse(c, "user/default_addresses", "User not authenticated")
+		return
+	}
+	uid := userID.(uint)
+
+	shipping, err := addressService.Default(h.db, uid, addressService.Shipping)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		response.GenerateInternalServerErrorResponse(c, "user/default_addresses", "Failed to get default addresses")
+		return
+	}
+	billing, err := addressService.Default(h.db, uid, addressService.Billing)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		response.GenerateInternalServerErrorResponse(c, "user/default_addresses", "Failed to get default addresses")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Default addresses retrieved successfully", DefaultAddressesResponse{
+		Shipping: shipping,
+		Billing:  billing,
+	})
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	addressService "github.com/YasserCherfaoui/MarketProGo/address"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
@@ -45,7 +46,7 @@ func (h *UserHandler) DeleteAddress(c *gin.Context) {
 	// Check if this address is being used in any orders
 	var orderCount int64
 	if err := tx.Model(&models.Order{}).
-		Where("shipping_address_id = ?", addressID).
+		Where("shipping_address_id = ? OR billing_address_id = ?", addressID, addressID).
 		Count(&orderCount).Error; err != nil {
 		tx.Rollback()
 		response.GenerateInternalServerErrorResponse(c, "user/delete_address", "Failed to check address usage")
@@ -65,15 +66,11 @@ func (h *UserHandler) DeleteAddress(c *gin.Context) {
 		return
 	}
 
-	// If this was the default address, set another address as default if available
-	if address.IsDefault {
-		var nextAddress models.Address
-		if err := tx.Where("user_id = ?", uid).
-			Order("created_at ASC").
-			First(&nextAddress).Error; err == nil {
-			// Set the oldest remaining address as default
-			tx.Model(&nextAddress).Update("is_default", true)
-		}
+	// If this was a default address, make the oldest remaining address the default instead
+	if err := addressService.ReassignDefaults(tx, &address); err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "user/delete_address", "Failed to reassign default address")
+		return
 	}
 
 	// Commit transaction
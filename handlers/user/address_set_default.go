@@ -1,12 +1,15 @@
 package user
 
 import (
+	addressService "github.com/YasserCherfaoui/MarketProGo/address"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// SetDefaultAddress makes an address the user's default shipping address, or
+// their default billing address with type=billing
 func (h *UserHandler) SetDefaultAddress(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -20,6 +23,11 @@ func (h *UserHandler) SetDefaultAddress(c *gin.Context) {
 		response.GenerateBadRequestResponse(c, "user/set_default_address", "Address ID is required")
 		return
 	}
+	kind, ok := addressService.ParseKind(c.Query("type"))
+	if !ok {
+		response.GenerateBadRequestResponse(c, "user/set_default_address", "Invalid type. Must be 'shipping' or 'billing'")
+		return
+	}
 
 	// Start transaction
 	tx := h.db.Begin()
@@ -43,23 +51,14 @@ func (h *UserHandler) SetDefaultAddress(c *gin.Context) {
 	}
 
 	// If already default, no need to update
-	if address.IsDefault {
+	if (kind == addressService.Shipping && address.IsDefault) || (kind == addressService.Billing && address.IsDefaultBilling) {
 		tx.Rollback()
 		response.GenerateSuccessResponse(c, "Address is already set as default", address)
 		return
 	}
 
-	// Unset other default addresses for this user
-	if err := tx.Model(&models.Address{}).
-		Where("user_id = ? AND is_default = ?", uid, true).
-		Update("is_default", false).Error; err != nil {
-		tx.Rollback()
-		response.GenerateInternalServerErrorResponse(c, "user/set_default_address", "Failed to update existing default addresses")
-		return
-	}
-
-	// Set this address as default
-	if err := tx.Model(&address).Update("is_default", true).Error; err != nil {
+	// Set this address as default, unsetting the user's other default
+	if err := addressService.SetDefault(tx, uid, address.ID, kind); err != nil {
 		tx.Rollback()
 		response.GenerateInternalServerErrorResponse(c, "user/set_default_address", "Failed to set address as default")
 		return
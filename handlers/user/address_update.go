@@ -1,6 +1,7 @@
 package user
 
 import (
+	addressService "github.com/YasserCherfaoui/MarketProGo/address"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/YasserCherfaoui/MarketProGo/utils/response"
 	"github.com/gin-gonic/gin"
@@ -8,13 +9,16 @@ import (
 )
 
 type UpdateAddressRequest struct {
-	StreetAddress1 *string `json:"street_address1"`
-	StreetAddress2 *string `json:"street_address2"`
-	City           *string `json:"city"`
-	State          *string `json:"state"`
-	PostalCode     *string `json:"postal_code"`
-	Country        *string `json:"country"`
-	IsDefault      *bool   `json:"is_default"`
+	StreetAddress1   *string `json:"street_address1"`
+	StreetAddress2   *string `json:"street_address2"`
+	City             *string `json:"city"`
+	State            *string `json:"state"`
+	PostalCode       *string `json:"postal_code"`
+	Country          *string `json:"country"`
+	Label            *string `json:"label"`
+	Phone            *string `json:"phone"`
+	IsDefault        *bool   `json:"is_default"`
+	IsDefaultBilling *bool   `json:"is_default_billing"`
 }
 
 func (h *UserHandler) UpdateAddress(c *gin.Context) {
@@ -58,54 +62,69 @@ func (h *UserHandler) UpdateAddress(c *gin.Context) {
 		return
 	}
 
-	// If setting this as default, unset other default addresses
-	if req.IsDefault != nil && *req.IsDefault {
-		if err := tx.Model(&models.Address{}).
-			Where("user_id = ? AND id != ? AND is_default = ?", uid, addressID, true).
-			Update("is_default", false).Error; err != nil {
-			tx.Rollback()
-			response.GenerateInternalServerErrorResponse(c, "user/update_address", "Failed to update existing default addresses")
-			return
-		}
-	}
-
 	// Update fields if provided
-	updates := make(map[string]interface{})
-	if req.StreetAddress1 != nil {
-		updates["street_address1"] = *req.StreetAddress1
-	}
-	if req.StreetAddress2 != nil {
-		updates["street_address2"] = *req.StreetAddress2
-	}
-	if req.City != nil {
-		updates["city"] = *req.City
-	}
-	if req.State != nil {
-		updates["state"] = *req.State
-	}
-	if req.PostalCode != nil {
-		updates["postal_code"] = *req.PostalCode
-	}
-	if req.Country != nil {
-		updates["country"] = *req.Country
+	updated := address
+	location := updated.StreetAddress1 + updated.StreetAddress2 + updated.City + updated.State + updated.PostalCode + updated.Country
+	fields := 0
+	for _, f := range []struct {
+		value *string
+		field *string
+	}{
+		{req.StreetAddress1, &updated.StreetAddress1},
+		{req.StreetAddress2, &updated.StreetAddress2},
+		{req.City, &updated.City},
+		{req.State, &updated.State},
+		{req.PostalCode, &updated.PostalCode},
+		{req.Country, &updated.Country},
+		{req.Label, &updated.Label},
+		{req.Phone, &updated.Phone},
+	} {
+		if f.value != nil {
+			*f.field = *f.value
+			fields++
+		}
 	}
 	if req.IsDefault != nil {
-		updates["is_default"] = *req.IsDefault
+		updated.IsDefault = *req.IsDefault
+		fields++
+	}
+	if req.IsDefaultBilling != nil {
+		updated.IsDefaultBilling = *req.IsDefaultBilling
+		fields++
 	}
 
-	if len(updates) == 0 {
+	if fields == 0 {
 		tx.Rollback()
 		response.GenerateBadRequestResponse(c, "user/update_address", "No fields to update")
 		return
 	}
+	if err := addressService.Normalize(&updated); err != nil {
+		tx.Rollback()
+		response.GenerateBadRequestResponse(c, "user/update_address", err.Error())
+		return
+	}
+	// Only look the address up again when its location changed
+	if updated.StreetAddress1+updated.StreetAddress2+updated.City+updated.State+updated.PostalCode+updated.Country != location {
+		addressService.Geocode(c.Request.Context(), h.geocoder, &updated)
+	}
 
 	// Update the address
-	if err := tx.Model(&address).Updates(updates).Error; err != nil {
+	if err := tx.Model(&address).Select(
+		"street_address1", "street_address2", "city", "state", "postal_code", "country", "label", "phone",
+		"is_default", "is_default_billing", "latitude", "longitude", "geocoded_at",
+	).Updates(&updated).Error; err != nil {
 		tx.Rollback()
 		response.GenerateInternalServerErrorResponse(c, "user/update_address", "Failed to update address")
 		return
 	}
 
+	// If setting this as default, unset other default addresses
+	if err := addressService.ClaimDefaults(tx, &updated); err != nil {
+		tx.Rollback()
+		response.GenerateInternalServerErrorResponse(c, "user/update_address", "Failed to update existing default addresses")
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "user/update_address", "Failed to commit transaction")
@@ -1,11 +1,15 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"github.com/YasserCherfaoui/MarketProGo/address"
+	"gorm.io/gorm"
+)
 
 type UserHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	geocoder address.Geocoder // nil when geocoding is off
 }
 
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(db *gorm.DB, geocoder address.Geocoder) *UserHandler {
+	return &UserHandler{db: db, geocoder: geocoder}
 }
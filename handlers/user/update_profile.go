@@ -12,6 +12,9 @@ type UpdateProfileRequest struct {
 	LastName  string `json:"last_name" binding:"required"`
 	Phone     string `json:"phone"`
 	Avatar    string `json:"avatar"`
+	Locale    string `json:"locale" binding:"omitempty,oneof=en fr ar"` // kept when empty
+
+	OrderUpdatesChannel string `json:"order_updates_channel" binding:"omitempty,oneof=email sms whatsapp"` // kept when empty
 }
 
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
@@ -59,6 +62,16 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		"phone":      req.Phone,
 		"avatar":     req.Avatar,
 	}
+	if req.Locale != "" {
+		updates["locale"] = req.Locale
+	}
+	if req.OrderUpdatesChannel != "" {
+		updates["order_updates_channel"] = req.OrderUpdatesChannel
+	}
+	// A new number has to be verified again
+	if req.Phone != user.Phone {
+		updates["phone_verified_at"] = nil
+	}
 
 	if err := h.db.Model(&user).Updates(updates).Error; err != nil {
 		response.GenerateInternalServerErrorResponse(c, "UPDATE_FAILED", "Failed to update profile")
@@ -74,8 +87,11 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 			"last_name":  user.LastName,
 			"phone":      user.Phone,
 			"avatar":     user.Avatar,
+			"locale":     user.Locale,
 			"user_type":  user.UserType,
 			"is_active":  user.IsActive,
+
+			"order_updates_channel": user.OrderUpdatesChannel,
 		},
 	})
 }
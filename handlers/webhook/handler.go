@@ -0,0 +1,15 @@
+package webhook
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	db       *gorm.DB
+	webhooks *webhook.Service
+}
+
+func NewWebhookHandler(db *gorm.DB, webhooks *webhook.Service) *WebhookHandler {
+	return &WebhookHandler{db: db, webhooks: webhooks}
+}
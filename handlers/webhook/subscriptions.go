@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+type UpdateSubscriptionRequest struct {
+	URL         *string  `json:"url"`
+	Description *string  `json:"description"`
+	Events      []string `json:"events"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// SubscriptionWithSecret is returned when a secret is created or rotated, the
+// only times it can be read
+type SubscriptionWithSecret struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// GetEvents - Admin endpoint listing the events that can be subscribed to
+func (h *WebhookHandler) GetEvents(c *gin.Context) {
+	response.GenerateSuccessResponse(c, "Webhook events retrieved successfully", webhook.Events)
+}
+
+// GetSubscriptions - Admin endpoint to list webhook subscriptions
+func (h *WebhookHandler) GetSubscriptions(c *gin.Context) {
+	var subscriptions []models.WebhookSubscription
+	if err := h.db.Order("id ASC").Find(&subscriptions).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/list", "Failed to fetch webhook subscriptions")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Webhook subscriptions retrieved successfully", subscriptions)
+}
+
+// GetSubscription - Admin endpoint to get a webhook subscription
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	subscription, ok := h.findSubscription(c, "webhook/get")
+	if !ok {
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Webhook subscription retrieved successfully", subscription)
+}
+
+// CreateSubscription - Admin endpoint to register a URL for webhook events.
+// The signing secret is generated and returned once.
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "webhook/create", err.Error())
+		return
+	}
+	if err := validateSubscription(req.URL, req.Events); err != nil {
+		response.GenerateBadRequestResponse(c, "webhook/create", err.Error())
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/create", "Failed to generate webhook secret")
+		return
+	}
+	subscription := models.WebhookSubscription{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Secret:      secret,
+		IsActive:    true,
+		CreatedByID: c.GetUint("user_id"),
+	}
+	if err := h.db.Create(&subscription).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/create", "Failed to create webhook subscription")
+		return
+	}
+	// GORM skips false for fields with a default, so persist it explicitly
+	if req.IsActive != nil && !*req.IsActive {
+		subscription.IsActive = false
+		h.db.Model(&subscription).Update("is_active", false)
+	}
+
+	response.GenerateCreatedResponse(c, "Webhook subscription created successfully", SubscriptionWithSecret{WebhookSubscription: subscription, Secret: secret})
+}
+
+// UpdateSubscription - Admin endpoint to change a subscription's URL, events or status
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	subscription, ok := h.findSubscription(c, "webhook/update")
+	if !ok {
+		return
+	}
+
+	var req UpdateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateBadRequestResponse(c, "webhook/update", err.Error())
+		return
+	}
+
+	if req.URL != nil {
+		subscription.URL = *req.URL
+	}
+	if req.Description != nil {
+		subscription.Description = *req.Description
+	}
+	if req.Events != nil {
+		subscription.Events = req.Events
+	}
+	if req.IsActive != nil {
+		subscription.IsActive = *req.IsActive
+	}
+	if err := validateSubscription(subscription.URL, subscription.Events); err != nil {
+		response.GenerateBadRequestResponse(c, "webhook/update", err.Error())
+		return
+	}
+
+	if err := h.db.Select("url", "description", "events", "is_active").Save(subscription).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/update", "Failed to update webhook subscription")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Webhook subscription updated successfully", subscription)
+}
+
+// DeleteSubscription - Admin endpoint to remove a subscription. Pending
+// deliveries to it are dropped.
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	subscription, ok := h.findSubscription(c, "webhook/delete")
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(subscription).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/delete", "Failed to delete webhook subscription")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Webhook subscription deleted successfully", nil)
+}
+
+// RotateSecret - Admin endpoint to replace a subscription's signing secret.
+// Deliveries still pending are signed with the new secret.
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	subscription, ok := h.findSubscription(c, "webhook/rotate_secret")
+	if !ok {
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/rotate_secret", "Failed to generate webhook secret")
+		return
+	}
+	if err := h.db.Model(subscription).Update("secret", secret).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/rotate_secret", "Failed to rotate webhook secret")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Webhook secret rotated successfully", SubscriptionWithSecret{WebhookSubscription: *subscription, Secret: secret})
+}
+
+// TestSubscription - Admin endpoint that sends a webhook.test event to the
+// subscription straight away and returns the delivery, including the
+// receiver's status code or the error
+func (h *WebhookHandler) TestSubscription(c *gin.Context) {
+	subscription, ok := h.findSubscription(c, "webhook/test")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhooks.SendTest(c.Request.Context(), subscription)
+	if err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/test", err.Error())
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Test webhook sent", delivery)
+}
+
+// GetDeliveries - Admin endpoint listing delivery attempts to a subscription,
+// newest first, optionally filtered by event and success
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	subscription, ok := h.findSubscription(c, "webhook/deliveries")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := h.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscription.ID)
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+	if success := c.Query("success"); success != "" {
+		query = query.Where("success = ?", success == "true")
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/deliveries", "Failed to count deliveries")
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&deliveries).Error; err != nil {
+		response.GenerateInternalServerErrorResponse(c, "webhook/deliveries", "Failed to fetch deliveries")
+		return
+	}
+
+	response.GenerateSuccessResponse(c, "Webhook deliveries retrieved successfully", gin.H{
+		"deliveries":  deliveries,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"total_pages": (totalCount + int64(limit) - 1) / int64(limit),
+	})
+}
+
+func (h *WebhookHandler) findSubscription(c *gin.Context, code string) (*models.WebhookSubscription, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.GenerateBadRequestResponse(c, code, "Invalid webhook subscription ID")
+		return nil, false
+	}
+
+	var subscription models.WebhookSubscription
+	if err := h.db.First(&subscription, id).Error; err != nil {
+		response.GenerateNotFoundResponse(c, code, "Webhook subscription not found")
+		return nil, false
+	}
+	return &subscription, true
+}
+
+// validateSubscription checks that target is an absolute http(s) URL and that
+// every event can be subscribed to
+func validateSubscription(target string, events []string) error {
+	parsed, err := url.ParseRequestURI(target)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("at least one event is required")
+	}
+	for _, event := range events {
+		if !webhook.IsEvent(event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package i18n localizes API content. The catalog is written in the default
+// locale, English; product and category names and descriptions can be
+// translated into the other supported locales, and are served in the locale
+// negotiated from a request's Accept-Language header. Emails are rendered in
+// the locale of their recipient (see email.HTMLTemplateEngine.Localized).
+package i18n
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// Supported locales
+const (
+	English = "en"
+	French  = "fr"
+	Arabic  = "ar"
+
+	// Default is the locale the catalog and the email templates are written in
+	Default = English
+)
+
+// Supported lists the supported locales, the default first
+var Supported = []string{English, French, Arabic}
+
+// ContextKey is the gin context key the negotiated locale is set under
+const ContextKey = "locale"
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.French, language.Arabic})
+
+// IsSupported reports whether locale is a supported locale
+func IsSupported(locale string) bool {
+	for _, supported := range Supported {
+		if supported == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Direction returns the direction text in locale is written in, "rtl" for
+// Arabic and "ltr" otherwise
+func Direction(locale string) string {
+	if locale == Arabic {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+// Negotiate returns the supported locale that best matches an Accept-Language
+// header, or Default when none does
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return Supported[index]
+}
+
+// Locale returns the locale negotiated for a request by the Locale
+// middleware, negotiating it from the request's headers when the middleware
+// did not run
+func Locale(c *gin.Context) string {
+	if locale := c.GetString(ContextKey); locale != "" {
+		return locale
+	}
+	return Negotiate(c.GetHeader("Accept-Language"))
+}
@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          English,
+		"fr-FR,fr;q=0.9,en;q=0.8":   French,
+		"ar-DZ":                     Arabic,
+		"de-DE,de;q=0.9,fr;q=0.5":   French,
+		"en-GB;q=0.5,ar;q=0.9":      Arabic,
+		"de-DE":                     English,
+		"not a language;;q=invalid": English,
+	}
+	for header, want := range cases {
+		assert.Equal(t, want, Negotiate(header), header)
+	}
+}
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Product{}, &models.Category{}, &models.ProductTranslation{}, &models.CategoryTranslation{}))
+	return db
+}
+
+func TestTranslateProducts(t *testing.T) {
+	db := setup(t)
+	olives := models.Category{Name: "Olives", Slug: "olives", Description: "Cured olives"}
+	require.NoError(t, db.Create(&olives).Error)
+	product := models.Product{Name: "Green olives", Description: "Picholine olives in brine"}
+	require.NoError(t, db.Create(&product).Error)
+	untranslated := models.Product{Name: "Black olives"}
+	require.NoError(t, db.Create(&untranslated).Error)
+
+	_, err := SetProductTranslation(db, product.ID, French, Translation{Name: "Olives vertes"})
+	require.NoError(t, err)
+	saved, err := SetProductTranslation(db, product.ID, French, Translation{Name: "Olives vertes", Description: "Olives picholine en saumure"})
+	require.NoError(t, err, "setting a translation again replaces it")
+	assert.Equal(t, "Olives picholine en saumure", saved.Description)
+	_, err = SetCategoryTranslation(db, olives.ID, French, Translation{Name: "Olives et tapenades"})
+	require.NoError(t, err)
+
+	products := []models.Product{product, untranslated}
+	products[0].Categories = []*models.Category{&olives}
+	products[0].Breadcrumbs = []models.Breadcrumb{{ID: olives.ID, Name: "Olives", Slug: "olives"}}
+	require.NoError(t, TranslateProducts(db, French, products))
+	assert.Equal(t, "Olives vertes", products[0].Name)
+	assert.Equal(t, "Olives picholine en saumure", products[0].Description)
+	assert.Equal(t, "Olives et tapenades", products[0].Categories[0].Name)
+	assert.Equal(t, "Cured olives", products[0].Categories[0].Description, "empty fields are not translated")
+	assert.Equal(t, "Olives et tapenades", products[0].Breadcrumbs[0].Name)
+	assert.Equal(t, "Black olives", products[1].Name)
+
+	english := []models.Product{untranslated}
+	require.NoError(t, TranslateProducts(db, Arabic, english))
+	assert.Equal(t, "Black olives", english[0].Name)
+}
+
+func TestSetTranslationValidates(t *testing.T) {
+	db := setup(t)
+	product := models.Product{Name: "Green olives"}
+	require.NoError(t, db.Create(&product).Error)
+
+	_, err := SetProductTranslation(db, product.ID, English, Translation{Name: "Olives"})
+	assert.ErrorIs(t, err, ErrDefaultLocale)
+	_, err = SetProductTranslation(db, product.ID, "de", Translation{Name: "Oliven"})
+	assert.ErrorIs(t, err, ErrUnsupportedLocale)
+	_, err = SetProductTranslation(db, product.ID, French, Translation{})
+	assert.ErrorIs(t, err, ErrEmptyTranslation)
+	_, err = SetProductTranslation(db, product.ID+1, French, Translation{Name: "Olives"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
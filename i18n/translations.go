@@ -0,0 +1,202 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnsupportedLocale = errors.New("unsupported locale")
+	ErrDefaultLocale     = errors.New("content is written in the default locale and cannot be translated into it")
+	ErrEmptyTranslation  = errors.New("a translation needs a name or a description")
+)
+
+// Translation is the translated text of a product or category. Empty fields
+// fall back to the untranslated text.
+type Translation struct {
+	Name        string `json:"name" binding:"max=255"`
+	Description string `json:"description"`
+}
+
+// validate checks that a translation can be stored for locale
+func (t Translation) validate(locale string) error {
+	switch {
+	case locale == Default:
+		return ErrDefaultLocale
+	case !IsSupported(locale):
+		return fmt.Errorf("%w: %q", ErrUnsupportedLocale, locale)
+	case t.Name == "" && t.Description == "":
+		return ErrEmptyTranslation
+	}
+	return nil
+}
+
+// translate replaces name and description with the non-empty fields of t
+func (t Translation) translate(name, description *string) {
+	if t.Name != "" {
+		*name = t.Name
+	}
+	if t.Description != "" && description != nil {
+		*description = t.Description
+	}
+}
+
+// TranslateProducts translates the products, their categories and their
+// breadcrumbs into locale, in place. Products of the default locale are left
+// as they are.
+func TranslateProducts(db *gorm.DB, locale string, products []models.Product) error {
+	if locale == Default || len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]uint, len(products))
+	var categoryIDs []uint
+	for i := range products {
+		productIDs[i] = products[i].ID
+		for _, category := range products[i].Categories {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+		for _, breadcrumb := range products[i].Breadcrumbs {
+			categoryIDs = append(categoryIDs, breadcrumb.ID)
+		}
+	}
+
+	translations, err := productTranslations(db, locale, productIDs)
+	if err != nil {
+		return err
+	}
+	byCategory, err := categoryTranslations(db, locale, categoryIDs)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		product := &products[i]
+		translations[product.ID].translate(&product.Name, &product.Description)
+		for _, category := range product.Categories {
+			byCategory[category.ID].translate(&category.Name, &category.Description)
+		}
+		for j := range product.Breadcrumbs {
+			byCategory[product.Breadcrumbs[j].ID].translate(&product.Breadcrumbs[j].Name, nil)
+		}
+	}
+	return nil
+}
+
+// TranslateSummaries translates the names of product summaries into locale,
+// in place
+func TranslateSummaries(db *gorm.DB, locale string, summaries []models.ProductSummary) error {
+	if locale == Default || len(summaries) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(summaries))
+	for i := range summaries {
+		ids[i] = summaries[i].ProductID
+	}
+	translations, err := productTranslations(db, locale, ids)
+	if err != nil {
+		return err
+	}
+	for i := range summaries {
+		translations[summaries[i].ProductID].translate(&summaries[i].Name, nil)
+	}
+	return nil
+}
+
+// TranslateCategories translates the categories into locale, in place
+func TranslateCategories(db *gorm.DB, locale string, categories []*models.Category) error {
+	if locale == Default || len(categories) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
+	}
+	translations, err := categoryTranslations(db, locale, ids)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		translations[category.ID].translate(&category.Name, &category.Description)
+	}
+	return nil
+}
+
+// productTranslations loads the translations into locale of products, by
+// product ID
+func productTranslations(db *gorm.DB, locale string, ids []uint) (map[uint]Translation, error) {
+	var rows []models.ProductTranslation
+	if err := db.Where("product_id IN ? AND locale = ?", ids, locale).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load product translations: %w", err)
+	}
+	translations := make(map[uint]Translation, len(rows))
+	for _, row := range rows {
+		translations[row.ProductID] = Translation{Name: row.Name, Description: row.Description}
+	}
+	return translations, nil
+}
+
+// categoryTranslations loads the translations into locale of categories, by
+// category ID
+func categoryTranslations(db *gorm.DB, locale string, ids []uint) (map[uint]Translation, error) {
+	translations := map[uint]Translation{}
+	if len(ids) == 0 {
+		return translations, nil
+	}
+	var rows []models.CategoryTranslation
+	if err := db.Where("category_id IN ? AND locale = ?", ids, locale).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load category translations: %w", err)
+	}
+	for _, row := range rows {
+		translations[row.CategoryID] = Translation{Name: row.Name, Description: row.Description}
+	}
+	return translations, nil
+}
+
+// SetProductTranslation creates or replaces the translation of a product into
+// locale
+func SetProductTranslation(db *gorm.DB, productID uint, locale string, translation Translation) (*models.ProductTranslation, error) {
+	if err := translation.validate(locale); err != nil {
+		return nil, err
+	}
+	if err := db.Select("id").First(&models.Product{}, productID).Error; err != nil {
+		return nil, err
+	}
+	row := models.ProductTranslation{ProductID: productID, Locale: locale, Name: translation.Name, Description: translation.Description}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "updated_at"}),
+	}).Create(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save product translation: %w", err)
+	}
+	if err := db.First(&row, "product_id = ? AND locale = ?", productID, locale).Error; err != nil {
+		return nil, fmt.Errorf("failed to load product translation: %w", err)
+	}
+	return &row, nil
+}
+
+// SetCategoryTranslation creates or replaces the translation of a category
+// into locale
+func SetCategoryTranslation(db *gorm.DB, categoryID uint, locale string, translation Translation) (*models.CategoryTranslation, error) {
+	if err := translation.validate(locale); err != nil {
+		return nil, err
+	}
+	if err := db.Select("id").First(&models.Category{}, categoryID).Error; err != nil {
+		return nil, err
+	}
+	row := models.CategoryTranslation{CategoryID: categoryID, Locale: locale, Name: translation.Name, Description: translation.Description}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "category_id"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "updated_at"}),
+	}).Create(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save category translation: %w", err)
+	}
+	if err := db.First(&row, "category_id = ? AND locale = ?", categoryID, locale).Error; err != nil {
+		return nil, fmt.Errorf("failed to load category translation: %w", err)
+	}
+	return &row, nil
+}
@@ -0,0 +1,131 @@
+package lockout
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+)
+
+const unlockTokenTTL = 24 * time.Hour
+
+// Guard throttles login attempts per account and per IP address
+type Guard struct {
+	store  Store
+	config cfg.LockoutConfig
+}
+
+// NewGuard creates a login guard. A nil config uses the defaults.
+func NewGuard(store Store, config *cfg.LockoutConfig) *Guard {
+	c := cfg.LockoutConfig{
+		MaxAccountAttempts: 5,
+		MaxIPAttempts:      20,
+		WindowMinutes:      15,
+		LockMinutes:        30,
+	}
+	if config != nil {
+		c = *config
+	}
+	return &Guard{store: store, config: c}
+}
+
+func (g *Guard) window() time.Duration {
+	return time.Duration(g.config.WindowMinutes) * time.Minute
+}
+
+func (g *Guard) lockDuration() time.Duration {
+	return time.Duration(g.config.LockMinutes) * time.Minute
+}
+
+func accountKey(prefix, email string) string {
+	return fmt.Sprintf("login:%s:account:%s", prefix, strings.ToLower(strings.TrimSpace(email)))
+}
+
+func ipKey(prefix, ip string) string {
+	return fmt.Sprintf("login:%s:ip:%s", prefix, ip)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IPBlocked reports whether an IP is temporarily blocked and for how long
+func (g *Guard) IPBlocked(ip string) (bool, time.Duration, error) {
+	_, ttl, ok, err := g.store.Get(ipKey("lock", ip))
+	return ok, ttl, err
+}
+
+// AccountLocked reports whether an account is locked and for how long
+func (g *Guard) AccountLocked(email string) (bool, time.Duration, error) {
+	_, ttl, ok, err := g.store.Get(accountKey("lock", email))
+	return ok, ttl, err
+}
+
+// RegisterFailure records a failed login. It returns true when this failure
+// locked the account.
+func (g *Guard) RegisterFailure(email, ip string) (bool, error) {
+	ipCount, err := g.store.Incr(ipKey("fail", ip), g.window())
+	if err != nil {
+		return false, err
+	}
+	if g.config.MaxIPAttempts > 0 && ipCount >= int64(g.config.MaxIPAttempts) {
+		if err := g.store.Set(ipKey("lock", ip), "1", g.lockDuration()); err != nil {
+			return false, err
+		}
+		if err := g.store.Del(ipKey("fail", ip)); err != nil {
+			return false, err
+		}
+	}
+
+	accountCount, err := g.store.Incr(accountKey("fail", email), g.window())
+	if err != nil {
+		return false, err
+	}
+	if g.config.MaxAccountAttempts > 0 && accountCount >= int64(g.config.MaxAccountAttempts) {
+		if err := g.store.Set(accountKey("lock", email), "1", g.lockDuration()); err != nil {
+			return false, err
+		}
+		if err := g.store.Del(accountKey("fail", email)); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// Reset clears the failure count after a successful login
+func (g *Guard) Reset(email string) error {
+	return g.store.Del(accountKey("fail", email))
+}
+
+// CreateUnlockToken returns a single-use token that unlocks the account
+func (g *Guard) CreateUnlockToken(email string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := g.store.Set("login:unlock:"+hashToken(token), strings.ToLower(strings.TrimSpace(email)), unlockTokenTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Unlock consumes an unlock token and lifts the account lock. It returns the
+// unlocked email, or ok false when the token is invalid or expired.
+func (g *Guard) Unlock(token string) (string, bool, error) {
+	key := "login:unlock:" + hashToken(token)
+	email, _, ok, err := g.store.Get(key)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if err := g.store.Del(key, accountKey("lock", email), accountKey("fail", email)); err != nil {
+		return "", false, err
+	}
+	return email, true, nil
+}
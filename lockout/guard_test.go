@@ -0,0 +1,75 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountLockout(t *testing.T) {
+	guard := NewGuard(NewMemoryStore(), &cfg.LockoutConfig{MaxAccountAttempts: 3, MaxIPAttempts: 100, WindowMinutes: 15, LockMinutes: 30})
+
+	for i := 0; i < 2; i++ {
+		locked, err := guard.RegisterFailure("User@Example.com", "10.0.0.1")
+		require.NoError(t, err)
+		assert.False(t, locked)
+	}
+	locked, err := guard.RegisterFailure("user@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	isLocked, ttl, err := guard.AccountLocked("user@example.com")
+	require.NoError(t, err)
+	assert.True(t, isLocked)
+	assert.InDelta(t, (30 * time.Minute).Seconds(), ttl.Seconds(), 1)
+
+	token, err := guard.CreateUnlockToken("user@example.com")
+	require.NoError(t, err)
+	email, ok, err := guard.Unlock(token)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "user@example.com", email)
+
+	isLocked, _, err = guard.AccountLocked("user@example.com")
+	require.NoError(t, err)
+	assert.False(t, isLocked)
+
+	// Unlock tokens are single use
+	_, ok, err = guard.Unlock(token)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestIPThrottle(t *testing.T) {
+	guard := NewGuard(NewMemoryStore(), &cfg.LockoutConfig{MaxAccountAttempts: 100, MaxIPAttempts: 3, WindowMinutes: 15, LockMinutes: 30})
+
+	for i, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		_, err := guard.RegisterFailure(email, "10.0.0.2")
+		require.NoError(t, err)
+		blocked, _, err := guard.IPBlocked("10.0.0.2")
+		require.NoError(t, err)
+		assert.Equal(t, i == 2, blocked)
+	}
+
+	blocked, _, err := guard.IPBlocked("10.0.0.3")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	count, err := store.Incr("k", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	now = now.Add(2 * time.Minute)
+	count, err = store.Incr("k", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
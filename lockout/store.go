@@ -0,0 +1,133 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is the counter storage used by the guard
+type Store interface {
+	// Incr increments a counter, starting its expiry window on first use
+	Incr(key string, window time.Duration) (int64, error)
+	Set(key, value string, ttl time.Duration) error
+	// Get returns the value and remaining TTL, with ok false when the key is missing
+	Get(key string) (value string, ttl time.Duration, ok bool, err error)
+	Del(keys ...string) error
+}
+
+// RedisStore keeps counters in Redis so limits are shared across instances
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a Redis backed store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Incr(key string, window time.Duration) (int64, error) {
+	ctx := context.Background()
+	count, err := s.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := s.client.Expire(ctx, key, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+func (s *RedisStore) Set(key, value string, ttl time.Duration) error {
+	return s.client.Set(context.Background(), key, value, ttl).Err()
+}
+
+func (s *RedisStore) Get(key string) (string, time.Duration, bool, error) {
+	ctx := context.Background()
+	value, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", 0, false, nil
+	}
+	if err != nil {
+		return "", 0, false, err
+	}
+	ttl, err := s.client.TTL(ctx, key).Result()
+	if err != nil {
+		return "", 0, false, err
+	}
+	return value, ttl, true, nil
+}
+
+func (s *RedisStore) Del(keys ...string) error {
+	return s.client.Del(context.Background(), keys...).Err()
+}
+
+// MemoryStore is an in-process store used when Redis is not available
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	value     string
+	count     int64
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// entry returns a live entry, dropping it if expired. Callers must hold the lock.
+func (s *MemoryStore) entry(key string) (memoryEntry, bool) {
+	e, ok := s.entries[key]
+	if ok && !e.expiresAt.IsZero() && !s.now().Before(e.expiresAt) {
+		delete(s.entries, key)
+		return memoryEntry{}, false
+	}
+	return e, ok
+}
+
+func (s *MemoryStore) Incr(key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entry(key)
+	if !ok {
+		e = memoryEntry{expiresAt: s.now().Add(window)}
+	}
+	e.count++
+	s.entries[key] = e
+	return e.count, nil
+}
+
+func (s *MemoryStore) Set(key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{value: value, expiresAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Get(key string) (string, time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entry(key)
+	if !ok {
+		return "", 0, false, nil
+	}
+	return e.value, e.expiresAt.Sub(s.now()), true, nil
+}
+
+func (s *MemoryStore) Del(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+// Package loyalty keeps the loyalty points ledger: points earned on delivered
+// orders, redeemed as checkout discounts, given back or taken back when orders
+// are cancelled, returned or refunded, and expired.
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientPoints = errors.New("not enough loyalty points")
+	ErrInvalidPoints      = errors.New("points must not be zero")
+)
+
+// expiringSoonDays is how far ahead a summary warns of expiring points
+const expiringSoonDays = 30
+
+// Service keeps the loyalty points ledger
+type Service struct {
+	db     *gorm.DB
+	config cfg.LoyaltyConfig
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, config *cfg.LoyaltyConfig) *Service {
+	s := &Service{db: db, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	if s.config.PointValue <= 0 {
+		s.config.PointValue = 0.01
+	}
+	if s.config.MaxRedeemPercent <= 0 || s.config.MaxRedeemPercent > 100 {
+		s.config.MaxRedeemPercent = 100
+	}
+	return s
+}
+
+// Summary is a user's points balance
+type Summary struct {
+	Balance          int        `json:"balance"`
+	Value            float64    `json:"value"` // balance as a checkout discount
+	PointValue       float64    `json:"point_value"`
+	MaxRedeemPercent float64    `json:"max_redeem_percent"`
+	ExpiringPoints   int        `json:"expiring_points"` // points expiring in the next 30 days
+	NextExpiry       *time.Time `json:"next_expiry,omitempty"`
+}
+
+// Balance returns a user's points balance: the unspent points of their
+// credits that have not expired. It matches the sum of their ledger once the
+// expiry worker has caught up.
+func (s *Service) Balance(tx *gorm.DB, userID uint) (int, error) {
+	var balance int
+	err := tx.Model(&models.LoyaltyTransaction{}).
+		Select("COALESCE(SUM(remaining), 0)").
+		Where("user_id = ? AND remaining > 0 AND (expires_at IS NULL OR expires_at > ?)", userID, s.now()).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// Summary returns a user's balance, its value and the points about to expire
+func (s *Service) Summary(userID uint) (*Summary, error) {
+	balance, err := s.Balance(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{
+		Balance:          balance,
+		Value:            round(float64(balance) * s.config.PointValue),
+		PointValue:       s.config.PointValue,
+		MaxRedeemPercent: s.config.MaxRedeemPercent,
+	}
+
+	now := s.now()
+	var expiring []models.LoyaltyTransaction
+	if err := s.db.Where("user_id = ? AND remaining > 0 AND expires_at > ? AND expires_at <= ?",
+		userID, now, now.AddDate(0, 0, expiringSoonDays)).
+		Order("expires_at").Find(&expiring).Error; err != nil {
+		return nil, err
+	}
+	for _, credit := range expiring {
+		summary.ExpiringPoints += credit.Remaining
+	}
+	if len(expiring) > 0 {
+		summary.NextExpiry = expiring[0].ExpiresAt
+	}
+	return summary, nil
+}
+
+// Quote returns how many of the points a user asked to redeem can be spent
+// on goods worth amount, and the discount they give. Points are capped at the
+// configured share of the amount. The user is locked until tx ends, so two
+// checkouts cannot spend the same points.
+func (s *Service) Quote(tx *gorm.DB, userID uint, requested int, amount float64) (int, float64, error) {
+	if requested <= 0 || amount <= 0 {
+		return 0, 0, nil
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+		return 0, 0, err
+	}
+	balance, err := s.Balance(tx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if requested > balance {
+		return 0, 0, fmt.Errorf("%w: %d available", ErrInsufficientPoints, balance)
+	}
+	maxPoints := int(math.Floor(amount*s.config.MaxRedeemPercent/100/s.config.PointValue + 1e-9))
+	points := min(requested, maxPoints)
+	return points, round(float64(points) * s.config.PointValue), nil
+}
+
+// Redeem records the points spent on an order at checkout
+func (s *Service) Redeem(tx *gorm.DB, order *models.Order) error {
+	if order.PointsRedeemed <= 0 {
+		return nil
+	}
+	if err := s.spend(tx, order.UserID, order.PointsRedeemed, nil); err != nil {
+		return err
+	}
+	return tx.Create(&models.LoyaltyTransaction{
+		UserID:      order.UserID,
+		OrderID:     &order.ID,
+		Type:        models.LoyaltyRedeem,
+		Points:      -order.PointsRedeemed,
+		Description: fmt.Sprintf("Redeemed on order %s", order.OrderNumber),
+	}).Error
+}
+
+// Earn credits the points of a delivered order, once, for what was paid for
+// its goods
+func (s *Service) Earn(tx *gorm.DB, order *models.Order) error {
+	points := int(math.Floor((order.FinalAmount-order.ShippingAmount)*s.config.PointsPerPound + 1e-9))
+	if points <= 0 {
+		return nil
+	}
+	done, err := s.recorded(tx, order.ID, models.LoyaltyEarn)
+	if err != nil || done {
+		return err
+	}
+	return tx.Create(s.credit(order.UserID, &order.ID, models.LoyaltyEarn, points,
+		fmt.Sprintf("Earned on order %s", order.OrderNumber))).Error
+}
+
+// Reverse undoes the points of a cancelled, returned or refunded order, once:
+// the points redeemed on it are given back and the points earned on it are
+// taken back. Earned points already spent or expired are only taken back as
+// far as the balance allows, so it never goes negative.
+func (s *Service) Reverse(tx *gorm.DB, order *models.Order) error {
+	if order.PointsRedeemed > 0 {
+		done, err := s.recorded(tx, order.ID, models.LoyaltyRefund)
+		if err != nil {
+			return err
+		}
+		if !done {
+			if err := tx.Create(s.credit(order.UserID, &order.ID, models.LoyaltyRefund, order.PointsRedeemed,
+				fmt.Sprintf("Given back from order %s", order.OrderNumber))).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var earned models.LoyaltyTransaction
+	err := tx.Where("order_id = ? AND type = ?", order.ID, models.LoyaltyEarn).First(&earned).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	done, err := s.recorded(tx, order.ID, models.LoyaltyRevoke)
+	if err != nil || done {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, order.UserID).Error; err != nil {
+		return err
+	}
+	balance, err := s.Balance(tx, order.UserID)
+	if err != nil {
+		return err
+	}
+	points := min(earned.Points, balance)
+	if points == 0 {
+		return nil
+	}
+	if err := s.spend(tx, order.UserID, points, &earned.ID); err != nil {
+		return err
+	}
+	return tx.Create(&models.LoyaltyTransaction{
+		UserID:      order.UserID,
+		OrderID:     &order.ID,
+		Type:        models.LoyaltyRevoke,
+		Points:      -points,
+		Description: fmt.Sprintf("Taken back from order %s", order.OrderNumber),
+	}).Error
+}
+
+// Reward credits points to a user in tx, e.g. for a referral
+func (s *Service) Reward(tx *gorm.DB, userID uint, points int, description string) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+	return tx.Create(s.credit(userID, nil, models.LoyaltyReward, points, description)).Error
+}
+
+// Adjust credits or debits a user's points by hand. Debits cannot take the
+// balance below zero.
+func (s *Service) Adjust(userID uint, points int, description string, adminID *uint) (*models.LoyaltyTransaction, error) {
+	if points == 0 {
+		return nil, ErrInvalidPoints
+	}
+	var entry *models.LoyaltyTransaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+		if points > 0 {
+			entry = s.credit(userID, nil, models.LoyaltyAdjust, points, description)
+		} else {
+			balance, err := s.Balance(tx, userID)
+			if err != nil {
+				return err
+			}
+			if -points > balance {
+				return fmt.Errorf("%w: %d available", ErrInsufficientPoints, balance)
+			}
+			if err := s.spend(tx, userID, -points, nil); err != nil {
+				return err
+			}
+			entry = &models.LoyaltyTransaction{UserID: userID, Type: models.LoyaltyAdjust, Points: points, Description: description}
+		}
+		entry.CreatedByID = adminID
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// StartExpiryWorker expires points past their expiry date every interval
+// until ctx is canceled
+func (s *Service) StartExpiryWorker(ctx context.Context, interval time.Duration) {
+	for {
+		if count, err := s.ExpirePoints(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to expire loyalty points", "component", "loyalty", "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "expired loyalty points", "component", "loyalty", "points", count)
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// ExpirePoints debits the unspent points of every credit past its expiry
+// date, returning the number of points expired
+func (s *Service) ExpirePoints(ctx context.Context) (int, error) {
+	var credits []models.LoyaltyTransaction
+	if err := s.db.WithContext(ctx).
+		Where("remaining > 0 AND expires_at <= ?", s.now()).
+		Order("id").Find(&credits).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, credit := range credits {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Only expire what has not been spent since the credit was read
+			result := tx.Model(&models.LoyaltyTransaction{}).
+				Where("id = ? AND remaining = ?", credit.ID, credit.Remaining).
+				Update("remaining", 0)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			expired += credit.Remaining
+			return tx.Create(&models.LoyaltyTransaction{
+				UserID:      credit.UserID,
+				Type:        models.LoyaltyExpire,
+				Points:      -credit.Remaining,
+				Description: "Points expired",
+			}).Error
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+// credit returns a ledger entry adding points that expire after the
+// configured number of days
+func (s *Service) credit(userID uint, orderID *uint, kind models.LoyaltyTransactionType, points int, description string) *models.LoyaltyTransaction {
+	entry := &models.LoyaltyTransaction{
+		UserID:      userID,
+		OrderID:     orderID,
+		Type:        kind,
+		Points:      points,
+		Remaining:   points,
+		Description: description,
+	}
+	if s.config.ExpiryDays > 0 {
+		expiresAt := s.now().AddDate(0, 0, s.config.ExpiryDays)
+		entry.ExpiresAt = &expiresAt
+	}
+	return entry
+}
+
+// spend takes points from the unspent points of a user's credits, from the
+// given credit first and then the credits expiring soonest
+func (s *Service) spend(tx *gorm.DB, userID uint, points int, first *uint) error {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining > 0 AND (expires_at IS NULL OR expires_at > ?)", userID, s.now())
+	if first != nil {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{SQL: "CASE WHEN id = ? THEN 0 ELSE 1 END", Vars: []interface{}{*first}}})
+	}
+	var credits []models.LoyaltyTransaction
+	if err := query.Order("expires_at IS NULL, expires_at, id").Find(&credits).Error; err != nil {
+		return err
+	}
+	for _, credit := range credits {
+		if points == 0 {
+			break
+		}
+		take := min(points, credit.Remaining)
+		if err := tx.Model(&models.LoyaltyTransaction{}).Where("id = ?", credit.ID).
+			Update("remaining", credit.Remaining-take).Error; err != nil {
+			return err
+		}
+		points -= take
+	}
+	if points > 0 {
+		return ErrInsufficientPoints
+	}
+	return nil
+}
+
+// recorded reports whether an order already has a ledger entry of a type
+func (s *Service) recorded(tx *gorm.DB, orderID uint, kind models.LoyaltyTransactionType) (bool, error) {
+	var count int64
+	err := tx.Model(&models.LoyaltyTransaction{}).
+		Where("order_id = ? AND type = ?", orderID, kind).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,177 @@
+package loyalty
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Order{}, &models.LoyaltyTransaction{}))
+	return db
+}
+
+// setupService earns a point per pound, worth a penny each, spends up to 20%
+// of an order and expires points after a year
+func setupService(db *gorm.DB) *Service {
+	s := NewService(db, &cfg.LoyaltyConfig{PointsPerPound: 1, PointValue: 0.01, MaxRedeemPercent: 20, ExpiryDays: 365})
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func createUser(t *testing.T, db *gorm.DB) *models.User {
+	user := models.User{Email: "customer@example.com", Password: "x", UserType: models.Customer, IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	return &user
+}
+
+func createOrder(t *testing.T, db *gorm.DB, user *models.User, number string, final, shipping float64) *models.Order {
+	order := models.Order{OrderNumber: number, UserID: user.ID, Status: models.OrderStatusPending, FinalAmount: final, ShippingAmount: shipping}
+	require.NoError(t, db.Create(&order).Error)
+	return &order
+}
+
+func balance(t *testing.T, s *Service, userID uint) int {
+	points, err := s.Balance(s.db, userID)
+	require.NoError(t, err)
+	return points
+}
+
+func TestEarnOnce(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+	order := createOrder(t, db, user, "ORD-1", 54.99, 5)
+
+	require.NoError(t, s.Earn(db, order))
+	require.NoError(t, s.Earn(db, order))
+	assert.Equal(t, 49, balance(t, s, user.ID), "shipping does not earn points")
+}
+
+func TestQuoteCapsRedemption(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+	_, err := s.Adjust(user.ID, 5000, "Welcome bonus", nil)
+	require.NoError(t, err)
+
+	points, discount, err := s.Quote(db, user.ID, 3000, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2000, points, "points pay for at most 20% of the order")
+	assert.InDelta(t, 20.0, discount, 0.001)
+
+	points, discount, err = s.Quote(db, user.ID, 150, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 150, points)
+	assert.InDelta(t, 1.5, discount, 0.001)
+
+	_, _, err = s.Quote(db, user.ID, 6000, 1000)
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+}
+
+func TestRedeemAndCancel(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+	_, err := s.Adjust(user.ID, 500, "Welcome bonus", nil)
+	require.NoError(t, err)
+
+	order := createOrder(t, db, user, "ORD-1", 95, 0)
+	order.PointsRedeemed = 500
+	require.NoError(t, s.Redeem(db, order))
+	assert.Zero(t, balance(t, s, user.ID))
+
+	require.NoError(t, s.Reverse(db, order))
+	require.NoError(t, s.Reverse(db, order))
+	assert.Equal(t, 500, balance(t, s, user.ID), "redeemed points are given back once")
+}
+
+func TestReverseRevokesEarnedPoints(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+
+	first := createOrder(t, db, user, "ORD-1", 300, 0)
+	require.NoError(t, s.Earn(db, first))
+	second := createOrder(t, db, user, "ORD-2", 100, 0)
+	require.NoError(t, s.Earn(db, second))
+
+	// Spend most of the points before the first order is returned
+	third := createOrder(t, db, user, "ORD-3", 100, 0)
+	third.PointsRedeemed = 350
+	require.NoError(t, s.Redeem(db, third))
+	assert.Equal(t, 50, balance(t, s, user.ID))
+
+	require.NoError(t, s.Reverse(db, first))
+	assert.Zero(t, balance(t, s, user.ID), "points are only taken back as far as the balance allows")
+
+	var revoked models.LoyaltyTransaction
+	require.NoError(t, db.Where("order_id = ? AND type = ?", first.ID, models.LoyaltyRevoke).First(&revoked).Error)
+	assert.Equal(t, -50, revoked.Points)
+}
+
+func TestExpirePoints(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+
+	old := createOrder(t, db, user, "ORD-1", 100, 0)
+	require.NoError(t, s.Earn(db, old))
+	s.now = func() time.Time { return testNow.AddDate(0, 6, 0) }
+	recent := createOrder(t, db, user, "ORD-2", 40, 0)
+	require.NoError(t, s.Earn(db, recent))
+
+	// Spending takes the points expiring soonest first
+	spend := createOrder(t, db, user, "ORD-3", 100, 0)
+	spend.PointsRedeemed = 30
+	require.NoError(t, s.Redeem(db, spend))
+
+	s.now = func() time.Time { return testNow.AddDate(1, 0, 1) }
+	assert.Equal(t, 40, balance(t, s, user.ID), "expired points are not part of the balance")
+
+	expired, err := s.ExpirePoints(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 70, expired)
+	expired, err = s.ExpirePoints(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+
+	var ledger int
+	require.NoError(t, db.Model(&models.LoyaltyTransaction{}).Select("SUM(points)").Where("user_id = ?", user.ID).Scan(&ledger).Error)
+	assert.Equal(t, 40, ledger, "the ledger matches the balance once points are expired")
+}
+
+func TestAdjust(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+
+	_, err := s.Adjust(user.ID, 0, "Nothing", nil)
+	assert.ErrorIs(t, err, ErrInvalidPoints)
+	_, err = s.Adjust(user.ID, -10, "Too much", nil)
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+	_, err = s.Adjust(999, 10, "Nobody", nil)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = s.Adjust(user.ID, 100, "Goodwill", nil)
+	require.NoError(t, err)
+	_, err = s.Adjust(user.ID, -40, "Correction", nil)
+	require.NoError(t, err)
+
+	summary, err := s.Summary(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60, summary.Balance)
+	assert.InDelta(t, 0.6, summary.Value, 0.001)
+	assert.Zero(t, summary.ExpiringPoints)
+}
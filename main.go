@@ -2,51 +2,90 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/cache"
+	"github.com/YasserCherfaoui/MarketProGo/campaign"
+	"github.com/YasserCherfaoui/MarketProGo/cart"
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
 	"github.com/YasserCherfaoui/MarketProGo/cfg"
 	"github.com/YasserCherfaoui/MarketProGo/database"
 	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/feeds"
+	"github.com/YasserCherfaoui/MarketProGo/files"
+	"github.com/YasserCherfaoui/MarketProGo/fraud"
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
+	"github.com/YasserCherfaoui/MarketProGo/giftcard"
+	authHandler "github.com/YasserCherfaoui/MarketProGo/handlers/auth"
 	emailHandler "github.com/YasserCherfaoui/MarketProGo/handlers/email"
+	orderHandler "github.com/YasserCherfaoui/MarketProGo/handlers/order"
+	"github.com/YasserCherfaoui/MarketProGo/lockout"
+	"github.com/YasserCherfaoui/MarketProGo/logging"
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/outbox"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
 	"github.com/YasserCherfaoui/MarketProGo/redis"
 	"github.com/YasserCherfaoui/MarketProGo/routes"
-	"github.com/gin-contrib/cors"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/sla"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/YasserCherfaoui/MarketProGo/subscription"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"github.com/YasserCherfaoui/MarketProGo/tracing"
+	"github.com/YasserCherfaoui/MarketProGo/trash"
+	"github.com/YasserCherfaoui/MarketProGo/uploads"
+	"github.com/YasserCherfaoui/MarketProGo/warehouseapi"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	// Stop on SIGINT or SIGTERM, which deploys send before killing the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workers := worker.NewManager(ctx)
+
 	cfg, err := cfg.LoadConfig()
 	if err != nil {
 		log.Fatalf("FATAL: Could not load configuration: %v", err)
 	}
+	logging.Setup(&cfg.Log)
 
-	// Validate Revolut configuration
-	if cfg.Revolut.APIKey == "" {
-		log.Fatal("ERROR: REVOLUT_API_KEY environment variable is not set. Please configure your Revolut API credentials.")
+	shutdownTracing, err := tracing.Setup(ctx, &cfg.Tracing)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to initialize tracing: %v", err)
 	}
-	if cfg.Revolut.BaseURL == "" {
-		log.Fatal("ERROR: Revolut base URL is not configured.")
+
+	// Check the whole configuration up front, reporting every problem at once
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("FATAL: Invalid %s configuration: %v", cfg.Mode(), err)
 	}
 
 	log.Printf("Revolut configuration loaded - BaseURL: %s, IsSandbox: %t", cfg.Revolut.BaseURL, cfg.Revolut.IsSandbox)
 
-	r := gin.Default()
-	config := cors.Config{
-		AllowOrigins:     []string{"*", "http://localhost:5173", "http://127.0.0.1:5173"}, // Adjust origins
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour, // Cache preflight request for 12 hours
-	}
+	r := gin.New()
+	r.Use(gin.Recovery(), middlewares.Tracing(cfg.Tracing.ServiceName), middlewares.RequestID(), middlewares.Locale(), middlewares.RequestLogger(), middlewares.Metrics())
 	// GCS
-	ctx, cancelInit := context.WithTimeout(context.Background(), 30*time.Second) // 30s timeout for init
+	initCtx, cancelInit := context.WithTimeout(ctx, 30*time.Second) // 30s timeout for init
 	defer cancelInit()
-	gcsService, err := gcs.NewGCSService(ctx, cfg.GCSCredentialsFile, cfg.GCSProjectID, cfg.GCSBucketName)
+	gcsService, err := gcs.NewGCSService(initCtx, cfg.GCSCredentialsFile, cfg.GCSProjectID, cfg.GCSBucketName)
 	if err != nil {
 		log.Fatalf("FATAL: Failed to initialize GCS service: %v", err)
 	}
@@ -60,11 +99,33 @@ func main() {
 	appwriteClient := aw.NewAppwriteClient(cfg)
 	appwriteService := aw.NewAppwriteService(appwriteClient)
 
-	r.Use(cors.New(config))
+	// Product image storage
+	imageStore, err := storage.New(&cfg.Storage, appwriteService, gcsService)
+	if err != nil {
+		log.Fatalf("FATAL: Failed to initialize image storage: %v", err)
+	}
+
+	r.Use(middlewares.CORS(&cfg.CORS,
+		[]string{middlewares.RequestIDHeader, "traceparent", "tracestate", orderHandler.IdempotencyKeyHeader, authHandler.RefreshTokenHeader},
+		[]string{"Content-Length", middlewares.RequestIDHeader, orderHandler.ReplayedHeader, middlewares.APIVersionHeader, "Deprecation", "Sunset", "Link", "Retry-After"},
+	))
 	db, err := database.ConnectDB()
 	if err != nil {
 		panic(err)
 	}
+	if err := database.UseReplicas(db, cfg.Database.ReplicaDSNs); err != nil {
+		log.Fatalf("FATAL: Failed to connect to read replicas: %v", err)
+	}
+
+	// Load the role→scope permission mapping
+	if err := permissions.Load(db); err != nil {
+		log.Printf("WARNING: Failed to load role permissions, using defaults: %v", err)
+	}
+
+	// Load the business settings admins edit at runtime
+	if err := settings.Load(db); err != nil {
+		log.Printf("WARNING: Failed to load business settings, using defaults: %v", err)
+	}
 
 	// Initialize Redis service for email queue
 	redisService, err := redis.NewRedisService(&redis.RedisConfig{
@@ -140,8 +201,23 @@ func main() {
 		}
 	}()
 
+	// Use SMTP as a fallback for Graph, or instead of the mock provider when Graph is not available
+	if cfg.SMTP.Host != "" {
+		smtpProvider, err := email.NewSMTPEmailProvider(&cfg.SMTP)
+		if err != nil {
+			log.Printf("⚠️ EMAIL: Failed to initialize SMTP provider: %v", err)
+		} else if _, isGraph := emailProvider.(*email.GraphEmailProvider); isGraph {
+			log.Printf("✅ EMAIL: SMTP fallback enabled for Graph quota and permission errors")
+			emailProvider = email.NewFallbackEmailProvider(emailProvider, smtpProvider)
+		} else {
+			log.Printf("✅ EMAIL: Using SMTP provider (%s:%d)", cfg.SMTP.Host, cfg.SMTP.Port)
+			emailProvider = smtpProvider
+		}
+	}
+
 	// Initialize template engine
-	templateEngine = email.NewHTMLTemplateEngine("templates/emails")
+	htmlTemplateEngine := email.NewHTMLTemplateEngine("templates/emails").WithDatabase(db).WithDefaultLocale(cfg.Email.DefaultLocale)
+	templateEngine = htmlTemplateEngine
 	if err := templateEngine.ReloadTemplates(); err != nil {
 		log.Printf("WARNING: Failed to load email templates: %v", err)
 	}
@@ -172,61 +248,229 @@ func main() {
 	emailTriggerService := email.NewEmailTriggerService(emailService, db)
 
 	// Initialize email handler
-	emailHandler := emailHandler.NewEmailHandler(emailService, db)
+	emailHandler := emailHandler.NewEmailHandler(emailService, htmlTemplateEngine, db).WithWebhookSecret(cfg.Email.WebhookSecret, cfg.Email.PreviousWebhookSecret)
 
-	// Start email queue processor in background
-	go func() {
-		log.Printf("🚀 EMAIL: Starting email queue processor...")
-		for {
-			// Dequeue email from queue
-			email, err := emailQueue.Dequeue()
-			if err != nil {
-				log.Printf("❌ EMAIL: Queue dequeue error: %v", err)
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			if email == nil {
-				// Queue is empty, wait a bit before checking again
-				time.Sleep(2 * time.Second)
-				continue
-			}
+	// Start email queue worker in background
+	emailWorker := email.NewQueueWorker(emailQueue, emailProvider, &cfg.EmailWorker).WithDatabase(db)
+	workers.Go("email-queue", emailWorker.Start)
+	metrics.ObserveEmailQueue(emailQueue.GetQueueSize, emailQueue.DeadLetterSize)
 
-			log.Printf("📧 EMAIL: Processing email ID: %d, Subject: %s, To: %s",
-				email.ID, email.Subject, email.Recipients[0].Email)
-
-			// Send email via provider
-			err = emailProvider.SendEmail(email)
-			if err != nil {
-				log.Printf("❌ EMAIL: Failed to send email ID %d: %v", email.ID, err)
-				// Mark as failed in queue
-				if err := emailQueue.MarkAsFailed(fmt.Sprintf("%d", email.ID), err.Error()); err != nil {
-					log.Printf("❌ EMAIL: Failed to mark email as failed: %v", err)
-				}
-			} else {
-				log.Printf("✅ EMAIL: Successfully sent email ID %d to %s",
-					email.ID, email.Recipients[0].Email)
-				// Mark as processed in queue
-				if err := emailQueue.MarkAsProcessed(fmt.Sprintf("%d", email.ID)); err != nil {
-					log.Printf("❌ EMAIL: Failed to mark email as processed: %v", err)
-				}
-			}
-		}
-	}()
+	// Requeue failed emails every 5 minutes
+	workers.Go("email-retry", func(ctx context.Context) {
+		emailService.StartRetryWorker(ctx, 5*time.Minute)
+	})
 
-	// Start email retry worker in background
-	go func() {
-		log.Printf("🔄 EMAIL: Starting email retry worker...")
-		for {
-			// Check for failed emails every 5 minutes
-			time.Sleep(5 * time.Minute)
+	// Dispatch scheduled emails once they are due
+	workers.Go("email-scheduler", func(ctx context.Context) {
+		emailService.StartScheduler(ctx, 30*time.Second)
+	})
 
-			if err := emailService.RetryFailedEmails(); err != nil {
-				log.Printf("❌ EMAIL: Failed to retry emails: %v", err)
-			}
+	// Poll the provider for bounces and complaints, for providers without webhooks
+	workers.Go("email-suppressions", func(ctx context.Context) {
+		emailService.StartSuppressionPoller(ctx, 15*time.Minute)
+	})
+
+	// Deliver emails and webhooks recorded in the outbox by committed transactions
+	outboxDispatcher := outbox.NewDispatcher(db)
+	outboxDispatcher.Handle(email.KindEmail, email.OutboxHandler(emailQueue))
+	outboxDispatcher.Handle(outbox.KindWebhook, outbox.WebhookHandler(nil))
+	outboxDispatcher.Handle(webhook.KindSubscription, webhook.NewService(db, nil).Handler())
+	workers.Go("outbox", func(ctx context.Context) {
+		outboxDispatcher.Start(ctx, 5*time.Second)
+	})
+
+	// Initialize cart service and start stale cart item expiry in background
+	cartService := cart.NewCartService(db, &cfg.Cart)
+	workers.Go("cart-expiry", func(ctx context.Context) {
+		cartService.StartExpiryWorker(ctx, 1*time.Hour)
+	})
+
+	// Mark batches past their expiry date as expired so they are no longer sold
+	stockService := stock.NewService(db)
+	workers.Go("stock-expiry", func(ctx context.Context) {
+		stockService.StartExpiryWorker(ctx, 1*time.Hour)
+	})
+
+	// Expire loyalty points past their expiry date
+	loyaltyService := loyalty.NewService(db, &cfg.Loyalty)
+	workers.Go("loyalty-expiry", func(ctx context.Context) {
+		loyaltyService.StartExpiryWorker(ctx, 1*time.Hour)
+	})
+
+	// Void manual capture authorizations left uncaptured past the configured
+	// window, before card issuers release them
+	paymentService := payment.NewRevolutPaymentService(db, &cfg.Revolut, giftcard.NewService(db, &cfg.GiftCard, emailTriggerService), fraud.NewService(db, &cfg.Fraud))
+	workers.Go("authorization-voids", func(ctx context.Context) {
+		paymentService.StartAuthorizationVoider(ctx, 1*time.Hour)
+	})
+
+	// Reconcile payments with Revolut nightly, fixing those a missed webhook
+	// left behind and emailing admins the discrepancies
+	workers.Go("payment-reconciliation", paymentService.WithEmails(emailTriggerService).StartReconciler)
+
+	// Remind customers of their subscription orders and place them on schedule
+	subscriptionService := subscription.NewService(db, &cfg.Subscription, tax.NewTaxService(db, &cfg.Tax), fulfillment.NewAllocator(&cfg.Fulfillment), paymentService, emailTriggerService)
+	workers.Go("subscriptions", func(ctx context.Context) {
+		subscriptionService.StartScheduler(ctx, 10*time.Minute)
+	})
+
+	// Pick up business settings edited on other instances
+	workers.Go("settings-refresh", func(ctx context.Context) {
+		settings.StartRefresher(ctx, db, 1*time.Minute)
+	})
+
+	// Flag, escalate and report support SLA breaches in background
+	slaService := sla.NewService(db)
+	workers.Go("sla-breaches", func(ctx context.Context) {
+		slaService.StartBreachChecker(ctx, 5*time.Minute)
+	})
+
+	// Product images are tracked so the files of deleted images are cleaned up
+	productImages := storage.NewImages(files.Track(db, imageStore, files.SourceProductImage), &cfg.Storage)
+
+	// Delete uploaded files no image, attachment or document references any more
+	if cfg.FileCleanup.IntervalHours > 0 {
+		fileCleanup := files.NewService(db, []storage.Storage{storage.NewAppwrite(appwriteService, cfg.Storage.SigningKey), storage.NewGCS(gcsService)}, &cfg.FileCleanup)
+		workers.Go("file-cleanup", func(ctx context.Context) {
+			fileCleanup.StartCleanup(ctx, time.Duration(cfg.FileCleanup.IntervalHours)*time.Hour)
+		})
+	}
+
+	// Rebuild product ratings that drifted from their approved reviews
+	ratingsService := ratings.NewService(db)
+	workers.Go("ratings-reconciliation", func(ctx context.Context) {
+		ratingsService.StartReconciler(ctx, cfg.Ratings.ReconciliationHour)
+	})
+
+	// Purge products and support records deleted longer ago than they can be restored
+	if cfg.Trash.PurgeIntervalHours > 0 {
+		trashService := trash.NewService(db, &cfg.Trash)
+		workers.Go("trash-purge", func(ctx context.Context) {
+			trashService.StartPurger(ctx, time.Duration(cfg.Trash.PurgeIntervalHours)*time.Hour)
+		})
+	}
+
+	// Delete support attachments that were uploaded but never attached
+	uploadService := uploads.NewService(db, uploads.NewAppwriteStore(appwriteService))
+	workers.Go("upload-cleanup", func(ctx context.Context) {
+		uploadService.StartOrphanCleanup(ctx, 6*time.Hour, 24*time.Hour)
+	})
+
+	// Keep the product summary projection in step with catalog writes, and
+	// rebuild it on startup and within a minute of writes it cannot attribute
+	productSummaries := catalog.NewSummaries(db)
+	if err := db.Use(catalog.NewSummaryPlugin(productSummaries)); err != nil {
+		log.Fatalf("FATAL: Failed to register product summary refresh: %v", err)
+	}
+	workers.Go("product-summaries", func(ctx context.Context) {
+		productSummaries.StartRefresher(ctx, time.Minute)
+	})
+
+	// Send scheduled marketing campaigns in throttled batches through the bulk
+	// email lane
+	campaignService := campaign.NewService(db, templateEngine, emailService, &cfg.Campaign)
+	workers.Go("campaigns", campaignService.StartDispatcher)
+
+	// Initialize login brute-force protection, shared through Redis when available
+	var lockoutStore lockout.Store
+	if redisService != nil {
+		lockoutStore = lockout.NewRedisStore(redisService.GetClient())
+	} else {
+		lockoutStore = lockout.NewMemoryStore()
+		log.Printf("Using in-memory login lockout store (Redis not available)")
+	}
+	loginGuard := lockout.NewGuard(lockoutStore, &cfg.Lockout)
+
+	// Initialize request rate limiting, shared through Redis when available
+	var rateLimitStore ratelimit.Store
+	if redisService != nil {
+		rateLimitStore = ratelimit.NewRedisStore(redisService.GetClient())
+	} else {
+		rateLimitStore = ratelimit.NewMemoryStore()
+		log.Printf("Using in-memory rate limit store (Redis not available)")
+	}
+	limiter := ratelimit.NewLimiter(rateLimitStore, &cfg.RateLimit)
+
+	// Cache catalog reads in Redis; without Redis the cache is disabled since
+	// a per-instance cache could not be invalidated across instances
+	var catalogStore cache.Store
+	if redisService != nil {
+		catalogStore = cache.NewRedisStore(redisService.GetClient())
+	}
+	catalogCache := cache.NewCatalog(catalogStore, &cfg.Cache)
+	availabilityCache := cache.NewAvailability(catalogStore, &cfg.Cache)
+	analyticsCache := cache.NewAnalytics(catalogStore, &cfg.Cache)
+	if err := db.Use(cache.NewInvalidationPlugin(catalogCache)); err != nil {
+		log.Fatalf("FATAL: Failed to register catalog cache invalidation: %v", err)
+	}
+
+	// Refresh cached listings when scheduled product launches open or close
+	productLaunches := catalog.NewLaunches(db, catalogCache)
+	workers.Go("product-launches", func(ctx context.Context) {
+		productLaunches.StartWatcher(ctx, time.Minute)
+	})
+
+	// Regenerate the sitemap and Google Merchant feed on a schedule, also
+	// publishing them to GCS when configured
+	var feedPublisher feeds.Publisher
+	if cfg.Feed.UploadToGCS {
+		feedPublisher = feeds.NewGCSPublisher(gcsService)
+	}
+	feedService := feeds.NewService(db, &cfg.Feed, tax.NewTaxService(db, &cfg.Tax), feedPublisher).WithImages(productImages)
+	workers.Go("feeds", func(ctx context.Context) {
+		feedService.StartGenerator(ctx, time.Duration(cfg.Feed.IntervalMinutes)*time.Minute)
+	})
+
+	routes.AppRoutes(r, db, gcsService, appwriteService, productImages, cfg, emailTriggerService, cartService, loginGuard, limiter, catalogCache, availabilityCache, analyticsCache, campaignService, feedService, emailHandler)
+	routes.MetricsRoutes(r, cfg.Metrics.Token)
+
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	go func() {
+		log.Printf("Listening on %s", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("FATAL: HTTP server failed: %v", err)
 		}
 	}()
 
-	routes.AppRoutes(r, db, gcsService, appwriteService, cfg, emailTriggerService)
-	routes.SetupEmailRoutes(r, emailHandler)
-	r.Run()
+	// The internal warehouse API is only served when a gRPC port is set
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Port != "" {
+		grpcServer, err = warehouseapi.NewGRPCServer(&cfg.GRPC, db)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to set up the gRPC server: %v", err)
+		}
+		listener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+		if err != nil {
+			log.Fatalf("FATAL: Failed to listen on gRPC port %s: %v", cfg.GRPC.Port, err)
+		}
+		go func() {
+			log.Printf("gRPC listening on %s", listener.Addr())
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("FATAL: gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	stop()
+	log.Printf("Shutting down, waiting up to %ds for requests and workers", cfg.ShutdownTimeoutSeconds)
+	timeout := time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second
+
+	// Stop accepting requests and let in-flight ones finish first, since they
+	// may still queue emails, then drain the background workers
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), timeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: HTTP server shutdown: %v", err)
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	if err := workers.Shutdown(timeout); err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("ERROR: Failed to flush traces: %v", err)
+	}
+	log.Printf("Shutdown complete")
 }
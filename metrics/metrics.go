@@ -0,0 +1,183 @@
+// Package metrics holds the Prometheus registry shared by the application and
+// the collectors each subsystem reports to. It is served at /metrics.
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "marketpro"
+
+// Registry is the registry every collector is registered with
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	// HTTPRequestDuration is the latency of HTTP requests per route
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DBQueryDuration is the time spent on database queries
+	DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of database queries by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// DBQueryErrors counts failed database queries
+	DBQueryErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Database queries that returned an error other than record not found.",
+	}, []string{"operation", "table"})
+
+	// EmailsSent counts emails accepted by the provider
+	EmailsSent = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_sent_total",
+		Help:      "Emails accepted by the email provider.",
+	})
+
+	// EmailSendFailures counts failed send attempts by reason: throttled, error or dead_lettered
+	EmailSendFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "email_send_failures_total",
+		Help:      "Failed email send attempts by reason.",
+	}, []string{"reason"})
+
+	// PaymentsCreated counts payment creation attempts by outcome: success or failed
+	PaymentsCreated = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_created_total",
+		Help:      "Payment creation attempts by outcome.",
+	}, []string{"outcome"})
+
+	// PaymentWebhooks counts payment provider webhooks by event and outcome
+	PaymentWebhooks = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_webhooks_total",
+		Help:      "Payment provider webhooks by event and outcome.",
+	}, []string{"event", "outcome"})
+
+	// InventoryAdjustments counts stock adjustments by source: adjust, bulk or transfer
+	InventoryAdjustments = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "inventory_adjustments_total",
+		Help:      "Stock adjustments by source.",
+	}, []string{"source"})
+
+	// InventoryAdjustedUnits counts units changed by stock adjustments, by source
+	// and direction: in, out, or moved between warehouses
+	InventoryAdjustedUnits = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "inventory_adjusted_units_total",
+		Help:      "Units added to or removed from stock by source and direction.",
+	}, []string{"source", "direction"})
+
+	// CacheRequests counts read cache lookups by cache and result: hit, miss or error
+	CacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Read cache lookups by cache and result.",
+	}, []string{"cache", "result"})
+
+	// RateLimitThrottled counts requests rejected by the rate limiter per route class
+	RateLimitThrottled = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_throttled_total",
+		Help:      "Requests rejected by the rate limiter by route class.",
+	}, []string{"class"})
+
+	// SecretMatches counts signatures and tokens verified per secret, by
+	// whether the current or the previous key matched during a rotation
+	SecretMatches = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "secret_matches_total",
+		Help:      "Verified webhook signatures and tokens by secret and key: current or previous.",
+	}, []string{"secret", "key"})
+
+	// OutboxDispatches counts outbox delivery attempts by kind and outcome:
+	// dispatched, retried or failed
+	OutboxDispatches = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_dispatches_total",
+		Help:      "Outbox message delivery attempts by kind and outcome.",
+	}, []string{"kind", "outcome"})
+)
+
+// ObserveHTTPRequest records the latency of a served request
+func ObserveHTTPRequest(method, route string, status int, seconds float64) {
+	if route == "" {
+		route = "unmatched"
+	}
+	HTTPRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(seconds)
+}
+
+// InventoryAdjusted records a stock adjustment of quantity units. Positive
+// quantities are counted as "in", negative ones as "out".
+func InventoryAdjusted(source string, quantity int) {
+	InventoryAdjustments.WithLabelValues(source).Inc()
+	direction := "in"
+	if quantity < 0 {
+		direction = "out"
+		quantity = -quantity
+	}
+	InventoryAdjustedUnits.WithLabelValues(source, direction).Add(float64(quantity))
+}
+
+// InventoryTransferred records a transfer of quantity units between warehouses
+func InventoryTransferred(quantity int) {
+	InventoryAdjustments.WithLabelValues("transfer").Inc()
+	InventoryAdjustedUnits.WithLabelValues("transfer", "moved").Add(float64(quantity))
+}
+
+// SecretMatched records that a signature or token was verified with the
+// current or, during a rotation, the previous key of secret
+func SecretMatched(secret string, previous bool) {
+	key := "current"
+	if previous {
+		key = "previous"
+	}
+	SecretMatches.WithLabelValues(secret, key).Inc()
+}
+
+// ObserveEmailQueue reports the email queue and dead-letter sizes at each scrape
+func ObserveEmailQueue(queueSize, deadLetterSize func() (int64, error)) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "email_queue_depth",
+		Help:      "Emails waiting in the send queue.",
+	}, sizeOf(queueSize))
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "email_dead_letter_depth",
+		Help:      "Emails in the dead-letter queue.",
+	}, sizeOf(deadLetterSize))
+}
+
+func sizeOf(fn func() (int64, error)) func() float64 {
+	return func() float64 {
+		size, err := fn()
+		if err != nil {
+			return -1
+		}
+		return float64(size)
+	}
+}
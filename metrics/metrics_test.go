@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPluginTimesQueries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin()))
+	require.NoError(t, db.AutoMigrate(&widget{}))
+
+	require.NoError(t, db.Create(&widget{Name: "a"}).Error)
+	var found widget
+	require.NoError(t, db.First(&found).Error)
+	assert.Error(t, db.Table("missing").Take(&found).Error)
+
+	var sample dto.Metric
+	require.NoError(t, DBQueryDuration.WithLabelValues("create", "widgets").(prometheus.Histogram).Write(&sample))
+	assert.Equal(t, uint64(1), sample.GetHistogram().GetSampleCount())
+	assert.Equal(t, float64(1), testutil.ToFloat64(DBQueryErrors.WithLabelValues("query", "missing")))
+}
+
+func TestInventoryAdjusted(t *testing.T) {
+	InventoryAdjusted("adjust", 5)
+	InventoryAdjusted("adjust", -2)
+	InventoryTransferred(3)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(InventoryAdjustments.WithLabelValues("adjust")))
+	assert.Equal(t, float64(5), testutil.ToFloat64(InventoryAdjustedUnits.WithLabelValues("adjust", "in")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(InventoryAdjustedUnits.WithLabelValues("adjust", "out")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(InventoryAdjustedUnits.WithLabelValues("transfer", "moved")))
+}
@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/YasserCherfaoui/MarketProGo/audit"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bodyCaptureWriter keeps a copy of the response body for the audit trail
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// AuditTrail records successful mutations made by staff (any role holding a
+// permission scope) on an entity type. newModel returns an empty model used to
+// snapshot the entity, identified by the :id route parameter or the id in the
+// response data, before and after the handler runs.
+func AuditTrail(db *gorm.DB, entityType string, newModel func() interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		entityID := c.Param("id")
+		var before models.JSON
+		if entityID != "" && newModel != nil {
+			before = audit.Snapshot(db, newModel(), entityID)
+		}
+
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+		userType, _ := c.Get("user_type")
+		role, _ := userType.(models.UserType)
+		if len(permissions.ScopesFor(role)) == 0 {
+			return
+		}
+
+		var responseData interface{}
+		var body struct {
+			Data interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(writer.body.Bytes(), &body); err == nil {
+			responseData = body.Data
+		}
+		if entityID == "" {
+			if data, ok := responseData.(map[string]interface{}); ok {
+				// Models embedding gorm.Model serialise their key as "ID"
+				for _, key := range []string{"id", "ID"} {
+					if id, ok := data[key].(float64); ok {
+						entityID = strconv.FormatUint(uint64(id), 10)
+						break
+					}
+				}
+			}
+		}
+
+		var after models.JSON
+		if entityID != "" && newModel != nil {
+			after = audit.Snapshot(db, newModel(), entityID)
+		} else {
+			// Without an entity to reload, keep what the handler returned
+			after = audit.ToJSON(responseData)
+		}
+
+		action := models.AuditActionUpdate
+		switch {
+		case c.Request.Method == http.MethodDelete:
+			action = models.AuditActionDelete
+		case c.Request.Method == http.MethodPost && c.Param("id") == "":
+			action = models.AuditActionCreate
+		}
+
+		entry := models.AuditLog{
+			ActorType:  role,
+			Action:     action,
+			EntityType: entityType,
+			EntityID:   entityID,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			StatusCode: status,
+			Before:     before,
+			After:      after,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			if id, ok := userID.(uint); ok {
+				entry.ActorID = &id
+			}
+		}
+		if impersonatorID, ok := c.Get("impersonator_id"); ok {
+			if id, ok := impersonatorID.(uint); ok {
+				entry.ImpersonatorID = &id
+			}
+		}
+
+		if err := audit.Record(db, &entry); err != nil {
+			log.Printf("Failed to write audit log: %v", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuditTrail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Warehouse{}, &models.AuditLog{}))
+
+	actAs := func(userType models.UserType) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user_id", uint(3))
+			c.Set("user_type", userType)
+			c.Next()
+		}
+	}
+	track := AuditTrail(db, "warehouse", func() interface{} { return &models.Warehouse{} })
+
+	router := gin.New()
+	router.POST("/warehouses", actAs(models.Admin), track, func(c *gin.Context) {
+		warehouse := models.Warehouse{Name: "Main", Code: "MAIN", IsActive: true}
+		db.Create(&warehouse)
+		response.GenerateCreatedResponse(c, "Warehouse created", warehouse)
+	})
+	router.PUT("/warehouses/:id", actAs(models.Admin), track, func(c *gin.Context) {
+		db.Model(&models.Warehouse{}).Where("id = ?", c.Param("id")).Update("name", "Central")
+		response.GenerateSuccessResponse(c, "Warehouse updated", nil)
+	})
+	router.DELETE("/warehouses/:id", actAs(models.Customer), track, func(c *gin.Context) {
+		response.GenerateSuccessResponse(c, "Warehouse deleted", nil)
+	})
+	router.PATCH("/warehouses/:id", actAs(models.Admin), track, func(c *gin.Context) {
+		response.GenerateBadRequestResponse(c, "warehouse/update", "Invalid request")
+	})
+
+	send := func(method, path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, send("POST", "/warehouses"))
+	assert.Equal(t, http.StatusOK, send("PUT", "/warehouses/1"))
+	// Non-staff actors and failed requests are not recorded
+	assert.Equal(t, http.StatusOK, send("DELETE", "/warehouses/1"))
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", "/warehouses/1"))
+
+	var logs []models.AuditLog
+	require.NoError(t, db.Order("id").Find(&logs).Error)
+	require.Len(t, logs, 2)
+
+	assert.Equal(t, models.AuditActionCreate, logs[0].Action)
+	assert.Equal(t, "1", logs[0].EntityID)
+	assert.Nil(t, logs[0].Before)
+	assert.Equal(t, "Main", logs[0].After["name"])
+	require.NotNil(t, logs[0].ActorID)
+	assert.Equal(t, uint(3), *logs[0].ActorID)
+
+	assert.Equal(t, models.AuditActionUpdate, logs[1].Action)
+	assert.Equal(t, "warehouse", logs[1].EntityType)
+	assert.Equal(t, map[string]interface{}{"from": "Main", "to": "Central"}, logs[1].Changes["name"])
+	assert.Len(t, logs[1].Changes, 1)
+}
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"os"
 	"strings"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
@@ -95,6 +96,13 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Admins must sign in with a second factor when enforcement is on
+		if os.Getenv("ADMIN_REQUIRE_2FA") == "true" && !claims.MFA {
+			response.GenerateForbiddenResponse(c, "auth/middleware", "two-factor authentication required")
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS lets browsers call the API from the configured origins and from
+// origins matching the configured patterns, such as preview deployments.
+// headers are request headers the API reads, allowed on top of the
+// configured ones; exposed are response headers browsers may read.
+func CORS(config *cfg.CORSConfig, headers, exposed []string) gin.HandlerFunc {
+	corsConfig := cors.Config{
+		AllowMethods:     config.AllowedMethods,
+		AllowHeaders:     append(append([]string{}, config.AllowedHeaders...), headers...),
+		ExposeHeaders:    exposed,
+		AllowCredentials: config.AllowCredentials,
+		MaxAge:           time.Duration(config.MaxAgeHours) * time.Hour,
+	}
+
+	var origins []string
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			corsConfig.AllowAllOrigins = true
+			corsConfig.AllowCredentials = false
+			return cors.New(corsConfig)
+		}
+		origins = append(origins, strings.TrimSuffix(origin, "/"))
+	}
+	corsConfig.AllowOrigins = origins
+
+	// Patterns match whole origins; invalid ones are reported by cfg.Validate
+	var patterns []*regexp.Regexp
+	for _, pattern := range config.OriginPatterns {
+		if re, err := regexp.Compile("^(?:" + pattern + ")$"); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+	corsConfig.AllowOriginFunc = func(origin string) bool {
+		for _, re := range patterns {
+			if re.MatchString(origin) {
+				return true
+			}
+		}
+		return false
+	}
+	return cors.New(corsConfig)
+}
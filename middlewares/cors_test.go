@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(config cfg.CORSConfig) *gin.Engine {
+		router := gin.New()
+		router.Use(CORS(&config, []string{RequestIDHeader}, []string{RequestIDHeader}))
+		router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	preflight := func(router *gin.Engine, origin string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodOptions, "/test", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req.Header.Set("Access-Control-Request-Headers", RequestIDHeader)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	router := newRouter(cfg.CORSConfig{
+		AllowedOrigins:   []string{"https://algeriamarket.co.uk/"},
+		OriginPatterns:   []string{`https://marketpro-[a-z0-9-]+\.vercel\.app`},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization"},
+		AllowCredentials: true,
+		MaxAgeHours:      12,
+	})
+
+	// Listed origins, trailing slash or not
+	w := preflight(router, "https://algeriamarket.co.uk")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://algeriamarket.co.uk", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Authorization,X-Request-Id", w.Header().Get("Access-Control-Allow-Headers"), "configured headers and those the API reads")
+
+	// Preview deployments matching a pattern
+	w = preflight(router, "https://marketpro-pr-42.vercel.app")
+	assert.Equal(t, "https://marketpro-pr-42.vercel.app", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Patterns match the whole origin
+	w = preflight(router, "https://marketpro-pr-42.vercel.app.evil.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = preflight(router, "http://localhost:5173")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// * allows any origin, without credentials
+	router = newRouter(cfg.CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowCredentials: true})
+	w = preflight(router, "https://anywhere.example")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
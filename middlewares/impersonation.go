@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ImpersonationGuard inspects every request for an impersonation token. Such
+// requests are limited to read-only methods and each one is written to the
+// impersonation log. Requests without an impersonation token pass through.
+func ImpersonationGuard(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			c.Next()
+			return
+		}
+		claims, err := auth.ValidateToken(token)
+		if err != nil || claims.ImpersonatorID == nil {
+			c.Next()
+			return
+		}
+
+		c.Set("impersonator_id", *claims.ImpersonatorID)
+
+		entry := models.ImpersonationLog{
+			CreatedAt:      time.Now(),
+			ImpersonatorID: *claims.ImpersonatorID,
+			UserID:         claims.UserID,
+			Action:         models.ImpersonationActionRequest,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			Query:          c.Request.URL.RawQuery,
+			IPAddress:      c.ClientIP(),
+			UserAgent:      c.Request.UserAgent(),
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			entry.StatusCode = c.Writer.Status()
+		default:
+			response.GenerateForbiddenResponse(c, "auth/impersonation", "This action is not allowed while impersonating a user")
+			c.Abort()
+			entry.Action = models.ImpersonationActionBlocked
+			entry.StatusCode = http.StatusForbidden
+		}
+
+		if err := db.Create(&entry).Error; err != nil {
+			log.Printf("Failed to write impersonation log: %v", err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestImpersonationGuard(t *testing.T) {
+	setupAuthTest()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.ImpersonationLog{}))
+
+	router := gin.New()
+	router.Use(ImpersonationGuard(db))
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.DELETE("/orders/1", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	token, _, err := auth.GenerateImpersonationToken(models.User{Model: gorm.Model{ID: 7}, UserType: models.Customer}, 2, time.Minute)
+	require.NoError(t, err)
+
+	send := func(method, path, authToken string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+authToken)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("GET", "/orders", token))
+	assert.Equal(t, http.StatusForbidden, send("DELETE", "/orders/1", token))
+
+	// Regular tokens are neither restricted nor logged
+	assert.Equal(t, http.StatusOK, send("DELETE", "/orders/1", generateTestToken(7, models.Customer)))
+
+	var logs []models.ImpersonationLog
+	require.NoError(t, db.Order("id").Find(&logs).Error)
+	require.Len(t, logs, 2)
+	assert.Equal(t, models.ImpersonationActionRequest, logs[0].Action)
+	assert.Equal(t, uint(2), logs[0].ImpersonatorID)
+	assert.Equal(t, uint(7), logs[0].UserID)
+	assert.Equal(t, models.ImpersonationActionBlocked, logs[1].Action)
+	assert.Equal(t, http.StatusForbidden, logs[1].StatusCode)
+}
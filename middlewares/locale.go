@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// Locale negotiates the language of a request's content from its
+// Accept-Language header. The locale is set as i18n.ContextKey in the gin
+// context and echoed in the Content-Language header.
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(i18n.ContextKey, locale)
+		c.Header("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceExempt are the writes still served during maintenance, relative
+// to the version prefix: signing in, so that admins can, and the provider
+// callbacks, which are verified by their signature and would otherwise lose
+// payments, bounces and delivery statuses the providers may not send again
+var maintenanceExempt = map[string]bool{
+	"/auth/login":      true,
+	"/auth/refresh":    true,
+	"/auth/2fa/verify": true,
+
+	"/payments/webhook":      true,
+	"/email/webhooks/events": true,
+	"/sms/webhooks/twilio":   true,
+	"/support/inbound-email": true,
+}
+
+// MaintenanceMode makes the API read-only while maintenance is active, when
+// forced or as the settings say. Other methods are answered with 503 and a
+// Retry-After header: the seconds until the scheduled end, or retryAfter.
+// Admins signed in as themselves pass; an admin impersonating a user stays
+// read-only like the user.
+func MaintenanceMode(forced bool, retryAfter time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		m := settings.CurrentMaintenance()
+		now := time.Now()
+		if !forced && !m.Active(now) {
+			c.Next()
+			return
+		}
+		path := strings.TrimPrefix(c.FullPath(), "/api/"+Version(c))
+		if maintenanceExempt[path] || isAdmin(c) {
+			c.Next()
+			return
+		}
+
+		wait := retryAfter
+		if m.EndsAt != nil && m.EndsAt.After(now) {
+			wait = m.EndsAt.Sub(now)
+		}
+		c.Header("Retry-After", strconv.Itoa(int(wait.Round(time.Second).Seconds())))
+		message := "The service is under maintenance, please try again later"
+		if m.Message != "" {
+			message = m.Message
+		}
+		response.GenerateErrorResponse(c, http.StatusServiceUnavailable, "maintenance", message)
+		c.Abort()
+	}
+}
+
+// isAdmin reports whether the request carries an admin's own token
+func isAdmin(c *gin.Context) bool {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		return false
+	}
+	claims, err := auth.ValidateToken(token)
+	return err == nil && claims.UserType == models.Admin && claims.ImpersonatorID == nil
+}
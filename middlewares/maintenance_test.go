@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	setupAuthTest()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Setting{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM settings")
+		settings.Load(db)
+	})
+
+	router := gin.New()
+	api := router.Group("/api/v1", APIVersion("v1"), MaintenanceMode(false, 5*time.Minute))
+	api.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.POST("/orders", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	api.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for _, path := range []string{"/payments/webhook", "/email/webhooks/events", "/sms/webhooks/twilio", "/support/inbound-email"} {
+		api.POST(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	adminToken, err := auth.GenerateToken(1, models.Admin, nil)
+	require.NoError(t, err)
+	customerToken, err := auth.GenerateToken(7, models.Customer, nil)
+	require.NoError(t, err)
+	impersonationToken, _, err := auth.GenerateImpersonationToken(models.User{Model: gorm.Model{ID: 3}, UserType: models.Admin}, 1, time.Minute)
+	require.NoError(t, err)
+
+	send := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/api/v1/orders", customerToken).Code, "off by default")
+
+	_, err = settings.SaveMaintenance(db, settings.Maintenance{Enabled: true, Message: "Back at noon"}, 1)
+	require.NoError(t, err)
+
+	w := send(http.MethodPost, "/api/v1/orders", customerToken)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "300", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Back at noon")
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/v1/orders", customerToken).Code, "reads are served")
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/auth/login", "").Code, "admins can sign in")
+	for _, path := range []string{"/api/v1/payments/webhook", "/api/v1/email/webhooks/events", "/api/v1/sms/webhooks/twilio", "/api/v1/support/inbound-email"} {
+		assert.Equal(t, http.StatusOK, send(http.MethodPost, path, "").Code, "provider callbacks are served: %s", path)
+	}
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/api/v1/orders", adminToken).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, send(http.MethodPost, "/api/v1/orders", impersonationToken).Code,
+		"impersonating an admin does not lift maintenance")
+
+	// A scheduled window is active until it ends, which sets Retry-After
+	now := time.Now()
+	startsAt, endsAt := now.Add(-time.Minute), now.Add(time.Hour)
+	_, err = settings.SaveMaintenance(db, settings.Maintenance{StartsAt: &startsAt, EndsAt: &endsAt}, 1)
+	require.NoError(t, err)
+	w = send(http.MethodPost, "/api/v1/orders", customerToken)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, []string{"3599", "3600"}, w.Header().Get("Retry-After"))
+
+	startsAt = now.Add(time.Hour)
+	_, err = settings.SaveMaintenance(db, settings.Maintenance{StartsAt: &startsAt}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/api/v1/orders", customerToken).Code, "upcoming windows do not block")
+}
@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records the latency of every request by route template, so
+// /orders/1 and /orders/2 are reported together as /orders/:id
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTPRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start).Seconds())
+	}
+}
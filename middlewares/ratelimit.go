@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit rejects requests over the limit for class with 429 Too Many
+// Requests and a Retry-After header. Authenticated clients are limited per
+// user, anonymous clients per IP address. Requests are let through if the
+// limiter's store fails.
+func RateLimit(limiter *ratelimit.Limiter, class ratelimit.Class) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil || !limiter.Enabled() {
+			c.Next()
+			return
+		}
+
+		allowed, wait, err := limiter.Allow(class, rateLimitUserID(c), c.ClientIP())
+		if err != nil {
+			log.Printf("Rate limiter unavailable: %v", err)
+		}
+		if !allowed {
+			seconds := int(math.Ceil(wait.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			response.GenerateErrorResponse(c, http.StatusTooManyRequests, "rate_limit/exceeded",
+				"too many requests, retry in "+strconv.Itoa(seconds)+" seconds")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitUserID returns the authenticated user, read from the context when an
+// auth middleware already ran and from the bearer token otherwise
+func rateLimitUserID(c *gin.Context) uint {
+	if userID := c.GetUint("user_id"); userID != 0 {
+		return userID
+	}
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		return 0
+	}
+	claims, err := auth.ValidateToken(strings.TrimPrefix(token, "Bearer "))
+	if err != nil {
+		return 0
+	}
+	return claims.UserID
+}
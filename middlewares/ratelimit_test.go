@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	setupAuthTest()
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), &cfg.RateLimitConfig{Enabled: true, IPPerMinute: 1, UserPerMinute: 2})
+	router := gin.New()
+	router.GET("/test", RateLimit(limiter, ratelimit.ClassGlobal), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send("").Code)
+	w := send("")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Authenticated requests are counted per user rather than per IP
+	token := generateTestToken(1, models.Customer)
+	assert.Equal(t, http.StatusOK, send(token).Code)
+	assert.Equal(t, http.StatusOK, send(token).Code)
+	assert.Equal(t, http.StatusTooManyRequests, send(token).Code)
+}
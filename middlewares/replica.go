@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/database"
+	"github.com/gin-gonic/gin"
+)
+
+// ReadReplica lets the request's queries read from a replica when replicas
+// are configured. Handlers must query with db.WithContext(c.Request.Context()).
+func ReadReplica() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(database.WithReplicaReads(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// ForcePrimary keeps every query of the request on the primary, for flows
+// that read what they have just written
+func ForcePrimary() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(database.WithPrimary(c.Request.Context()))
+		c.Next()
+	}
+}
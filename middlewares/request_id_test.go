@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var fromContext, fromRequest string
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		fromContext = c.GetString("request_id")
+		fromRequest = logging.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	send := func(requestID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A caller's ID is kept
+	w := send("checkout-42")
+	assert.Equal(t, "checkout-42", w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "checkout-42", fromContext)
+	assert.Equal(t, "checkout-42", fromRequest)
+
+	// Missing or malformed IDs are replaced
+	w = send("")
+	assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+	assert.Equal(t, w.Header().Get(RequestIDHeader), fromRequest)
+
+	w = send("bad id\nwith newline")
+	assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"os"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope authenticates the request and ensures the user's role holds at
+// least one of the given scopes
+func RequireScope(scopes ...permissions.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("Authorization")
+		if token == "" {
+			response.GenerateUnauthorizedResponse(c, "auth/middleware", "token is required")
+			c.Abort()
+			return
+		}
+
+		// Remove the Bearer prefix
+		token = strings.TrimPrefix(token, "Bearer ")
+		claims, err := auth.ValidateToken(token)
+		if err != nil {
+			response.GenerateUnauthorizedResponse(c, "auth/middleware", "token is invalid")
+			c.Abort()
+			return
+		}
+
+		// Set user context
+		c.Set("user", claims)
+		c.Set("user_id", claims.UserID)
+		c.Set("user_type", claims.UserType)
+
+		if !permissions.HasAny(claims.UserType, scopes...) {
+			response.GenerateForbiddenResponse(c, "auth/middleware", "missing required permission")
+			c.Abort()
+			return
+		}
+
+		// Admins must sign in with a second factor when enforcement is on
+		if claims.UserType == models.Admin && os.Getenv("ADMIN_REQUIRE_2FA") == "true" && !claims.MFA {
+			response.GenerateForbiddenResponse(c, "auth/middleware", "two-factor authentication required")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScope(t *testing.T) {
+	setupAuthTest()
+
+	tests := []struct {
+		name           string
+		userType       models.UserType
+		scope          permissions.Scope
+		expectedStatus int
+	}{
+		{"Admin holds every scope", models.Admin, permissions.PaymentsRefund, http.StatusOK},
+		{"Support agent can administer support", models.SupportAgent, permissions.SupportAdmin, http.StatusOK},
+		{"Support agent cannot refund payments", models.SupportAgent, permissions.PaymentsRefund, http.StatusForbidden},
+		{"Vendor can write products", models.Vendor, permissions.ProductsWrite, http.StatusOK},
+		{"Customer has no admin scopes", models.Customer, permissions.InventoryRead, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/test", RequireScope(tt.scope), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(1, tt.userType))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRequireScopeWithoutToken(t *testing.T) {
+	setupAuthTest()
+
+	router := gin.New()
+	router.GET("/test", RequireScope(permissions.SupportRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,17 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Tracing starts a server span for every request except metric scrapes,
+// continuing the caller's trace when the request carries a traceparent header.
+// It must run before RequestID so the request ID is recorded on the span.
+func Tracing(serviceName string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/metrics"
+	}))
+}
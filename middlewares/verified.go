@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"log"
+
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/YasserCherfaoui/MarketProGo/verification"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail rejects requests of users who have not verified their
+// email address with 403 Forbidden, when verification is required. It must
+// run after an auth middleware.
+func RequireVerifiedEmail(verifier *verification.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil || !verifier.Required() {
+			c.Next()
+			return
+		}
+
+		verified, err := verifier.IsVerified(c.GetUint("user_id"))
+		if err != nil {
+			log.Printf("Failed to check email verification: %v", err)
+			response.GenerateInternalServerErrorResponse(c, "auth/verification", "failed to check email verification")
+			c.Abort()
+			return
+		}
+		if !verified {
+			response.GenerateForbiddenResponse(c, "auth/email-not-verified", "verify your email address to continue")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireVerifiedPhone rejects requests of users who have not verified a
+// phone number with 403 Forbidden, when a verified phone number is required
+// at checkout. It must run after an auth middleware.
+func RequireVerifiedPhone(phones *sms.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if phones == nil || !phones.RequiredAtCheckout() {
+			c.Next()
+			return
+		}
+
+		verified, err := phones.IsPhoneVerified(c.GetUint("user_id"))
+		if err != nil {
+			log.Printf("Failed to check phone verification: %v", err)
+			response.GenerateInternalServerErrorResponse(c, "auth/verification", "failed to check phone verification")
+			c.Abort()
+			return
+		}
+		if !verified {
+			response.GenerateForbiddenResponse(c, "auth/phone-not-verified", "verify your phone number to continue")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
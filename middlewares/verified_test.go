@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/verification"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	verifiedAt := time.Now()
+	verified := models.User{Email: "verified@example.com", Password: "x", UserType: models.Customer, VerifiedAt: &verifiedAt}
+	unverified := models.User{Email: "unverified@example.com", Password: "x", UserType: models.Customer}
+	require.NoError(t, db.Create(&verified).Error)
+	require.NoError(t, db.Create(&unverified).Error)
+
+	send := func(config *cfg.VerificationConfig, userID uint) int {
+		router := gin.New()
+		router.POST("/orders/place", func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Next()
+		}, RequireVerifiedEmail(verification.NewService(db, config)), func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders/place", nil))
+		return w.Code
+	}
+
+	required := &cfg.VerificationConfig{Secret: "secret", Required: true}
+	assert.Equal(t, http.StatusCreated, send(required, verified.ID))
+	assert.Equal(t, http.StatusForbidden, send(required, unverified.ID))
+	assert.Equal(t, http.StatusCreated, send(&cfg.VerificationConfig{Secret: "secret"}, unverified.ID),
+		"unverified users are let through when verification is not required")
+}
+
+func TestRequireVerifiedPhone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	verifiedAt := time.Now()
+	verified := models.User{Email: "verified@example.com", Password: "x", UserType: models.Customer, Phone: "+447700900123", PhoneVerifiedAt: &verifiedAt}
+	unverified := models.User{Email: "unverified@example.com", Password: "x", UserType: models.Customer}
+	require.NoError(t, db.Create(&verified).Error)
+	require.NoError(t, db.Create(&unverified).Error)
+
+	send := func(config *cfg.SMSConfig, userID uint) int {
+		router := gin.New()
+		router.POST("/checkout", func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Next()
+		}, RequireVerifiedPhone(sms.NewService(db, sms.NewMockSMSProvider(), config)), func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/checkout", nil))
+		return w.Code
+	}
+
+	required := &cfg.SMSConfig{RequiredAtCheckout: true}
+	assert.Equal(t, http.StatusCreated, send(required, verified.ID))
+	assert.Equal(t, http.StatusForbidden, send(required, unverified.ID))
+	assert.Equal(t, http.StatusCreated, send(&cfg.SMSConfig{}, unverified.ID),
+		"users without a verified phone are let through when it is not required")
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersionHeader names the API version that served a response
+const APIVersionHeader = "API-Version"
+
+// APIVersion records which API version a route group serves, as
+// "api_version" in the gin context and in the API-Version response header.
+// Handlers shared between versions read it with Version.
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("api_version", version)
+		c.Header(APIVersionHeader, version)
+		c.Next()
+	}
+}
+
+// Version returns the API version serving the request, empty outside a
+// versioned group
+func Version(c *gin.Context) string {
+	return c.GetString("api_version")
+}
+
+// Deprecated announces that the routes it guards will be removed. Responses
+// carry "Deprecation: true", the Sunset date when it is set and a Link to
+// the same path under the successor prefix, such as /api/v2 for /api/v1.
+func Deprecated(sunset time.Time, prefix, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if path := c.Request.URL.Path; strings.HasPrefix(path, prefix) {
+			c.Header("Link", "<"+successor+strings.TrimPrefix(path, prefix)+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, Version(c))
+	}
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	router.Group("/api/v1", APIVersion("v1"), Deprecated(sunset, "/api/v1", "/api/v2")).GET("/products/:id", handler)
+	router.Group("/api/v2", APIVersion("v2")).GET("/products/:id", handler)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Shared handlers see the version serving them
+	w := send("/api/v1/products/7")
+	assert.Equal(t, "v1", w.Body.String())
+	assert.Equal(t, "v1", w.Header().Get(APIVersionHeader))
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/products/7>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = send("/api/v2/products/7")
+	assert.Equal(t, "v2", w.Body.String())
+	assert.Equal(t, "v2", w.Header().Get(APIVersionHeader))
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Link"))
+}
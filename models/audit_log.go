@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Audit log actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog records a mutation made by staff, with the entity state before and after
+type AuditLog struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+	ActorID        *uint     `gorm:"index" json:"actor_id"`
+	Actor          *User     `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+	ActorType      UserType  `gorm:"type:varchar(10)" json:"actor_type"`
+	ImpersonatorID *uint     `json:"impersonator_id,omitempty"`
+	Action         string    `gorm:"type:varchar(20);index;not null" json:"action"`
+	EntityType     string    `gorm:"type:varchar(50);index;not null" json:"entity_type"`
+	EntityID       string    `gorm:"type:varchar(50);index" json:"entity_id"`
+	Method         string    `gorm:"type:varchar(10)" json:"method"`
+	Path           string    `json:"path"`
+	StatusCode     int       `json:"status_code"`
+	Before         JSON      `gorm:"type:json" json:"before"`
+	After          JSON      `gorm:"type:json" json:"after"`
+	Changes        JSON      `gorm:"type:json" json:"changes"` // field -> {"from": ..., "to": ...}
+	IPAddress      string    `json:"ip_address"`
+	UserAgent      string    `json:"user_agent"`
+}
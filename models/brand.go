@@ -7,7 +7,9 @@ import (
 type Brand struct {
 	gorm.Model
 	Name        string `gorm:"not null;unique" json:"name"`
-	Image       string `gorm:"not null" json:"image"`
+	Image       string `gorm:"not null" json:"image"` // Appwrite file ID
+	Logo        string `json:"logo"`                  // Appwrite file ID of the brand logo
+	Description string `gorm:"type:text" json:"description"`
 	Slug        string `gorm:"not null;unique" json:"slug"`
 	IsDisplayed bool   `gorm:"default:true" json:"is_displayed"`
 
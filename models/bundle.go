@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// BundleComponent is Quantity units of a variant that make up one unit of a
+// bundle variant. A bundle has no stock of its own: it is sold from the stock
+// of its components.
+type BundleComponent struct {
+	gorm.Model
+	BundleVariantID    uint           `gorm:"not null;uniqueIndex:idx_bundle_component" json:"bundle_variant_id"`
+	ComponentVariantID uint           `gorm:"not null;uniqueIndex:idx_bundle_component;index" json:"component_variant_id"`
+	ComponentVariant   ProductVariant `json:"component_variant" gorm:"foreignKey:ComponentVariantID"`
+	Quantity           int            `gorm:"not null" json:"quantity"`
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SegmentFilter selects the customers of a segment. Unset fields do not
+// filter; set fields must all match.
+type SegmentFilter struct {
+	MinOrders           *int       `json:"min_orders,omitempty"`
+	MaxOrders           *int       `json:"max_orders,omitempty"`
+	MinSpend            *float64   `json:"min_spend,omitempty"` // total of non-cancelled orders
+	MaxSpend            *float64   `json:"max_spend,omitempty"`
+	LastPurchaseAfter   *time.Time `json:"last_purchase_after,omitempty"`
+	LastPurchaseBefore  *time.Time `json:"last_purchase_before,omitempty"`
+	PurchasedWithinDays *int       `json:"purchased_within_days,omitempty"`  // bought in the last N days
+	NotPurchasedForDays *int       `json:"not_purchased_for_days,omitempty"` // lapsed: no order in the last N days, including never
+	WishlistProductIDs  []uint     `json:"wishlist_product_ids,omitempty"`   // has any of the products in their wishlist
+	UserTypes           []UserType `json:"user_types,omitempty"`
+}
+
+// Value implements the driver.Valuer interface
+func (f SegmentFilter) Value() (driver.Value, error) {
+	return json.Marshal(f)
+}
+
+// Scan implements the sql.Scanner interface
+func (f *SegmentFilter) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*f = SegmentFilter{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, f)
+	case string:
+		return json.Unmarshal([]byte(v), f)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+}
+
+// Segment is a saved set of customers that campaigns are sent to. Its
+// customers are worked out from the filter when a campaign starts sending.
+type Segment struct {
+	gorm.Model
+	Name        string        `gorm:"not null" json:"name"`
+	Description string        `json:"description"`
+	Filter      SegmentFilter `gorm:"type:json" json:"filter"`
+}
+
+// CampaignStatus tracks a campaign from draft to sent
+type CampaignStatus string
+
+const (
+	CampaignStatusDraft     CampaignStatus = "draft"
+	CampaignStatusScheduled CampaignStatus = "scheduled"
+	CampaignStatusSending   CampaignStatus = "sending"
+	CampaignStatusSent      CampaignStatus = "sent"
+	CampaignStatusCancelled CampaignStatus = "cancelled"
+)
+
+// Campaign is a marketing email sent to the customers of a segment
+type Campaign struct {
+	gorm.Model
+	Name           string         `gorm:"not null" json:"name"`
+	SegmentID      uint           `gorm:"not null;index" json:"segment_id"`
+	Segment        *Segment       `json:"segment,omitempty"`
+	Template       string         `gorm:"not null;default:'promotional'" json:"template"`
+	Subject        string         `gorm:"not null" json:"subject"`
+	Content        string         `json:"content"`
+	ShopURL        string         `json:"shop_url"`              // target of the template's call to action
+	Data           JSON           `gorm:"type:json" json:"data"` // extra template data
+	Status         CampaignStatus `gorm:"type:varchar(20);not null;default:'draft';index" json:"status"`
+	ScheduledAt    *time.Time     `gorm:"index" json:"scheduled_at"`
+	StartedAt      *time.Time     `json:"started_at"`
+	CompletedAt    *time.Time     `json:"completed_at"`
+	RecipientCount int            `json:"recipient_count"`
+	CreatedByID    *uint          `json:"created_by_id"`
+}
+
+// CampaignRecipientStatus tracks the email of a single campaign recipient
+type CampaignRecipientStatus string
+
+const (
+	CampaignRecipientPending    CampaignRecipientStatus = "pending"
+	CampaignRecipientQueued     CampaignRecipientStatus = "queued"
+	CampaignRecipientSuppressed CampaignRecipientStatus = "suppressed"
+	CampaignRecipientFailed     CampaignRecipientStatus = "failed"
+	CampaignRecipientSkipped    CampaignRecipientStatus = "skipped" // the campaign was cancelled first
+)
+
+// CampaignRecipient is a customer a campaign is sent to, with their
+// engagement with it
+type CampaignRecipient struct {
+	ID             uint                    `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time               `json:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at"`
+	CampaignID     uint                    `gorm:"not null;uniqueIndex:idx_campaign_recipient" json:"campaign_id"`
+	UserID         uint                    `gorm:"not null;uniqueIndex:idx_campaign_recipient;index" json:"user_id"`
+	Email          string                  `gorm:"not null" json:"email"`
+	Name           string                  `json:"name"`
+	Status         CampaignRecipientStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Error          string                  `json:"error,omitempty"`
+	EmailID        *uint                   `json:"email_id,omitempty"`
+	SentAt         *time.Time              `json:"sent_at"`
+	OpenedAt       *time.Time              `json:"opened_at"`
+	ClickedAt      *time.Time              `json:"clicked_at"`
+	ClickCount     int                     `gorm:"not null;default:0" json:"click_count"`
+	UnsubscribedAt *time.Time              `json:"unsubscribed_at"`
+}
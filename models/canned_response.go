@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// CannedResponse is a reusable reply support agents can send on tickets and
+// disputes. Body is a text/template expanded with the record's details, e.g.
+// "Hi {{.FirstName}}, about ticket #{{.TicketID}}...".
+type CannedResponse struct {
+	gorm.Model
+	Title      string `json:"title" gorm:"not null"`
+	Body       string `json:"body" gorm:"type:text;not null"`
+	Category   string `json:"category" gorm:"type:varchar(50);index"` // empty for any category
+	IsActive   bool   `json:"is_active"`
+	UsageCount int    `json:"usage_count" gorm:"default:0"`
+	CreatedBy  uint   `json:"created_by"`
+}
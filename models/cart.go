@@ -9,6 +9,8 @@ type Cart struct {
 	UserID *uint      `json:"user_id"` // nullable for guests
 	User   *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Items  []CartItem `json:"items"`
+
+	RecoveryEmailID *uint `json:"-"` // pending abandoned-cart email, canceled on activity or checkout
 }
 
 type CartItem struct {
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CheckoutStatus string
+
+const (
+	CheckoutStatusProcessing CheckoutStatus = "PROCESSING"
+	CheckoutStatusCompleted  CheckoutStatus = "COMPLETED"
+)
+
+// Checkout records a checkout by its idempotency key, so a retried checkout
+// replays the first one's response instead of placing another order. Keys are
+// scoped to the user. A checkout that fails is deleted so its key can be
+// retried.
+type Checkout struct {
+	gorm.Model
+	UserID         uint           `gorm:"not null;uniqueIndex:idx_checkout_key" json:"user_id"`
+	IdempotencyKey string         `gorm:"size:255;not null;uniqueIndex:idx_checkout_key" json:"idempotency_key"`
+	RequestHash    string         `gorm:"size:64;not null" json:"-"` // SHA-256 of the request, to refuse a key reused for another checkout
+	Status         CheckoutStatus `gorm:"type:varchar(20);not null" json:"status"`
+	OrderID        *uint          `gorm:"index" json:"order_id,omitempty"`
+	PaymentID      *uint          `json:"payment_id,omitempty"`
+	Response       string         `gorm:"type:text" json:"-"` // data of the response, replayed on retries
+	CompletedAt    *time.Time     `json:"completed_at,omitempty"`
+}
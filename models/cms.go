@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Banner is a home page banner, shown in Position order while it is active
+// and inside its scheduling window
+type Banner struct {
+	gorm.Model
+	Title      string     `gorm:"not null" json:"title"`
+	Subtitle   string     `json:"subtitle"`
+	Image      string     `gorm:"not null" json:"image"` // Appwrite file ID
+	Link       string     `json:"link"`
+	ButtonText string     `json:"button_text"`
+	Position   int        `gorm:"not null;default:0;index" json:"position"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"` // shown from, or straight away when empty
+	EndsAt     *time.Time `json:"ends_at,omitempty"`   // shown until, or indefinitely when empty
+	IsActive   bool       `gorm:"default:true" json:"is_active"`
+}
+
+// Collection is a curated list of products shown on the home page
+type Collection struct {
+	gorm.Model
+	Title       string              `gorm:"not null" json:"title"`
+	Slug        string              `gorm:"type:varchar(100);uniqueIndex;not null" json:"slug"`
+	Description string              `json:"description"`
+	Image       string              `json:"image"` // Appwrite file ID
+	Position    int                 `gorm:"not null;default:0;index" json:"position"`
+	StartsAt    *time.Time          `json:"starts_at,omitempty"`
+	EndsAt      *time.Time          `json:"ends_at,omitempty"`
+	IsActive    bool                `gorm:"default:true" json:"is_active"`
+	Items       []CollectionProduct `gorm:"foreignKey:CollectionID" json:"items,omitempty"`
+}
+
+// CollectionProduct places a product in a collection
+type CollectionProduct struct {
+	CollectionID uint     `gorm:"primaryKey" json:"collection_id"`
+	ProductID    uint     `gorm:"primaryKey" json:"product_id"`
+	Position     int      `gorm:"not null;default:0" json:"position"`
+	Product      *Product `json:"product,omitempty"`
+}
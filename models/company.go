@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Company roles of B2B users
+const (
+	CompanyRoleOwner = "owner" // manages the company's buyers and approves their orders
+	CompanyRoleBuyer = "buyer" // orders for the company within their spend limit
+)
+
+// CompanyInvitation invites someone by email to order for a company as a
+// buyer. The token is only ever sent in the invitation email.
+type CompanyInvitation struct {
+	gorm.Model
+	CompanyID   uint       `gorm:"index;not null" json:"company_id"`
+	Company     *Company   `json:"company,omitempty"`
+	Email       string     `gorm:"index;not null" json:"email"`
+	SpendLimit  float64    `json:"spend_limit"` // given to the buyer when they accept
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"`
+	InvitedByID uint       `json:"invited_by_id"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy  *uint      `json:"accepted_by,omitempty"` // user who accepted
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
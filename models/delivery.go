@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeliveryZone is an area delivered to on a schedule of slots. Addresses are
+// matched to the zone with the longest postcode prefix they start with.
+type DeliveryZone struct {
+	gorm.Model
+	Name             string           `gorm:"not null" json:"name"`
+	Country          string           `gorm:"type:varchar(2);not null;default:'GB'" json:"country"`
+	PostcodePrefixes string           `json:"postcode_prefixes"`                 // comma-separated, e.g. "LS,BD1"; empty matches the whole country
+	RequireSlot      bool             `gorm:"default:false" json:"require_slot"` // orders to the zone must book a slot
+	IsActive         bool             `gorm:"default:true" json:"is_active"`
+	Windows          []DeliveryWindow `gorm:"foreignKey:ZoneID" json:"windows,omitempty"`
+	// Warehouses whose stock is shown as available in the zone; none means all
+	Warehouses []Warehouse `gorm:"many2many:delivery_zone_warehouses;" json:"warehouses,omitempty"`
+}
+
+// DeliveryWindow is a weekly delivery window of a zone, e.g. Mondays 9:00 to
+// 12:00. Dated slots are created from the windows with their capacity.
+type DeliveryWindow struct {
+	gorm.Model
+	ZoneID    uint         `gorm:"not null;index" json:"zone_id"`
+	Weekday   time.Weekday `gorm:"not null" json:"weekday"`                    // 0 is Sunday
+	StartTime string       `gorm:"type:varchar(5);not null" json:"start_time"` // "09:00"
+	EndTime   string       `gorm:"type:varchar(5);not null" json:"end_time"`
+	Capacity  int          `gorm:"not null" json:"capacity"` // orders per slot
+	Fee       float64      `json:"fee"`
+	IsActive  bool         `gorm:"default:true" json:"is_active"`
+}
+
+// DeliverySlot is a window of a zone on a given date. Its capacity starts as
+// the window's and can be changed, or the slot closed, for that date alone.
+type DeliverySlot struct {
+	gorm.Model
+	ZoneID       uint          `gorm:"not null;uniqueIndex:idx_delivery_slot" json:"zone_id"`
+	Zone         *DeliveryZone `json:"zone,omitempty"`
+	WindowID     *uint         `gorm:"uniqueIndex:idx_delivery_slot" json:"window_id,omitempty"`
+	Date         time.Time     `gorm:"type:date;not null;uniqueIndex:idx_delivery_slot" json:"date"`
+	StartsAt     time.Time     `gorm:"not null;index" json:"starts_at"`
+	EndsAt       time.Time     `gorm:"not null" json:"ends_at"`
+	Capacity     int           `gorm:"not null" json:"capacity"`
+	Booked       int           `gorm:"not null;default:0" json:"booked"`
+	Fee          float64       `json:"fee"`
+	IsClosed     bool          `gorm:"default:false" json:"is_closed"`
+	ClosedReason string        `json:"closed_reason,omitempty"`
+}
+
+// Remaining returns the number of orders the slot can still take
+func (s *DeliverySlot) Remaining() int {
+	if s.IsClosed || s.Booked >= s.Capacity {
+		return 0
+	}
+	return s.Capacity - s.Booked
+}
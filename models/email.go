@@ -9,26 +9,65 @@ import (
 // Email represents an email record in the database
 type Email struct {
 	gorm.Model
-	Type         EmailType        `json:"type"`
-	Template     string           `json:"template"`
-	Recipients   []EmailRecipient `json:"recipients" gorm:"-"`
-	SenderEmail  string           `json:"sender_email" gorm:"default:'enquirees@algeriamarket.co.uk'"`
-	SenderName   string           `json:"sender_name" gorm:"default:'Algeria Market'"`
-	Subject      string           `json:"subject"`
-	HTMLContent  string           `json:"html_content"`
-	TextContent  string           `json:"text_content"`
-	Status       EmailStatus      `json:"status"`
-	ProviderID   string           `json:"provider_id"`
-	SentAt       *time.Time       `json:"sent_at"`
-	DeliveredAt  *time.Time       `json:"delivered_at"`
-	OpenedAt     *time.Time       `json:"opened_at"`
-	ClickedAt    *time.Time       `json:"clicked_at"`
-	BouncedAt    *time.Time       `json:"bounced_at"`
-	BounceReason string           `json:"bounce_reason"`
-	RetryCount   int              `json:"retry_count"`
-	Metadata     EmailJSON        `json:"metadata"`
+	Type           EmailType        `json:"type"`
+	Template       string           `json:"template"`
+	Recipients     []EmailRecipient `json:"recipients" gorm:"-"`
+	SenderEmail    string           `json:"sender_email" gorm:"default:'enquirees@algeriamarket.co.uk'"`
+	SenderName     string           `json:"sender_name" gorm:"default:'Algeria Market'"`
+	Subject        string           `json:"subject"`
+	HTMLContent    string           `json:"html_content"`
+	TextContent    string           `json:"text_content"`
+	Status         EmailStatus      `json:"status"`
+	ProviderID     string           `json:"provider_id"`
+	SentAt         *time.Time       `json:"sent_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at"`
+	OpenedAt       *time.Time       `json:"opened_at"`
+	ClickedAt      *time.Time       `json:"clicked_at"`
+	BouncedAt      *time.Time       `json:"bounced_at"`
+	BounceReason   string           `json:"bounce_reason"`
+	RetryCount     int              `json:"retry_count"`
+	LastError      string           `json:"last_error"`
+	DeadLetteredAt *time.Time       `json:"dead_lettered_at"`
+	SendAt         *time.Time       `json:"send_at" gorm:"index"` // set for emails scheduled for later delivery
+	Priority       EmailPriority    `json:"priority" gorm:"type:varchar(20);default:'transactional'"`
+	Metadata       EmailJSON        `json:"metadata"`
+	RequestID      string           `json:"request_id" gorm:"size:64;index"` // HTTP request that triggered the email
+	OrderID        *uint            `json:"order_id,omitempty" gorm:"index"` // order the email is about
+
+	// The first recipient, stored for searching and resending since
+	// Recipients is not saved
+	RecipientEmail string    `json:"recipient_email" gorm:"size:255;index"`
+	RecipientName  string    `json:"recipient_name"`
+	TemplateData   EmailJSON `json:"-"`                                     // data the email was rendered with, to render it again
+	ResentFromID   *uint     `json:"resent_from_id,omitempty" gorm:"index"` // email this one is a copy of
 }
 
+// BeforeCreate stores the first recipient of the email
+func (e *Email) BeforeCreate(tx *gorm.DB) error {
+	if e.RecipientEmail == "" && len(e.Recipients) > 0 {
+		e.RecipientEmail = e.Recipients[0].Email
+		e.RecipientName = e.Recipients[0].Name
+	}
+	return nil
+}
+
+// AfterFind restores Recipients from the stored recipient, so emails loaded
+// from the database can be sent again
+func (e *Email) AfterFind(tx *gorm.DB) error {
+	if len(e.Recipients) == 0 && e.RecipientEmail != "" {
+		e.Recipients = []EmailRecipient{{Email: e.RecipientEmail, Name: e.RecipientName}}
+	}
+	return nil
+}
+
+// EmailPriority selects the queue lane an email is sent from
+type EmailPriority string
+
+const (
+	EmailPriorityTransactional EmailPriority = "transactional"
+	EmailPriorityBulk          EmailPriority = "bulk" // campaigns, sent only when no transactional email is waiting
+)
+
 // EmailRecipient represents an email recipient
 type EmailRecipient struct {
 	Email  string `json:"email"`
@@ -58,19 +97,37 @@ const (
 	EmailTypeDisputeResponse        EmailType = "dispute_response"
 	EmailTypeDisputeStatusUpdated   EmailType = "dispute_status_updated"
 	EmailTypeAbuseStatusUpdated     EmailType = "abuse_status_updated"
+	EmailTypeQuoteResponded         EmailType = "quote_responded"
+	EmailTypeReviewRequest          EmailType = "review_request"
+	EmailTypeGiftCard               EmailType = "gift_card"
+	EmailTypeOrderMessage           EmailType = "order_message"
+	EmailTypeCompanyInvitation      EmailType = "company_invitation"
+	EmailTypeOrderApprovalRequest   EmailType = "order_approval_request"
+	EmailTypeOrderApprovalDecision  EmailType = "order_approval_decision"
+	EmailTypeReviewReply            EmailType = "review_reply"
+	EmailTypeContentRemoved         EmailType = "content_removed"
+	EmailTypeAccountEnforcement     EmailType = "account_enforcement"
+	EmailTypeSubscriptionReminder   EmailType = "subscription_reminder"
+	EmailTypeSubscriptionOrder      EmailType = "subscription_order"
+	EmailTypeEmailVerification      EmailType = "email_verification"
 )
 
 // EmailStatus represents the status of an email
 type EmailStatus string
 
 const (
-	EmailStatusPending   EmailStatus = "pending"
-	EmailStatusSent      EmailStatus = "sent"
-	EmailStatusDelivered EmailStatus = "delivered"
-	EmailStatusOpened    EmailStatus = "opened"
-	EmailStatusClicked   EmailStatus = "clicked"
-	EmailStatusBounced   EmailStatus = "bounced"
-	EmailStatusFailed    EmailStatus = "failed"
+	EmailStatusPending      EmailStatus = "pending"
+	EmailStatusSent         EmailStatus = "sent"
+	EmailStatusDelivered    EmailStatus = "delivered"
+	EmailStatusOpened       EmailStatus = "opened"
+	EmailStatusClicked      EmailStatus = "clicked"
+	EmailStatusBounced      EmailStatus = "bounced"
+	EmailStatusFailed       EmailStatus = "failed"
+	EmailStatusDeadLettered EmailStatus = "dead_lettered" // failed after the maximum number of retries
+	EmailStatusScheduled    EmailStatus = "scheduled"
+	EmailStatusCanceled     EmailStatus = "canceled"
+	EmailStatusComplained   EmailStatus = "complained" // the recipient reported the email as spam
+	EmailStatusSuppressed   EmailStatus = "suppressed" // not sent, the recipient is on the suppression list
 )
 
 // EmailTemplate represents an email template
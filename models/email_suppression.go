@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// SuppressionReason is why an address stopped receiving email
+type SuppressionReason string
+
+const (
+	SuppressionReasonBounce    SuppressionReason = "bounce"
+	SuppressionReasonComplaint SuppressionReason = "complaint"
+	SuppressionReasonManual    SuppressionReason = "manual"
+)
+
+// Suppression sources
+const (
+	SuppressionSourceWebhook = "webhook"
+	SuppressionSourcePoll    = "poll"
+	SuppressionSourceAdmin   = "admin"
+)
+
+// EmailSuppression is an address that no email is sent to, after a hard
+// bounce, a spam complaint or an admin decision
+type EmailSuppression struct {
+	ID        uint              `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Email     string            `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"` // stored lower-cased
+	Reason    SuppressionReason `gorm:"type:varchar(20);index;not null" json:"reason"`
+	Source    string            `gorm:"type:varchar(20)" json:"source"`
+	Detail    string            `json:"detail"`
+	EmailID   *uint             `json:"email_id,omitempty"` // the email that bounced or was complained about
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FraudDecision is what a fraud check decided for a payment
+type FraudDecision string
+
+const (
+	FraudDecisionAllow  FraudDecision = "ALLOW"  // captured as usual
+	FraudDecisionReview FraudDecision = "REVIEW" // authorized only, held until an admin reviews it
+	FraudDecisionBlock  FraudDecision = "BLOCK"  // refused before the payment was created
+)
+
+// FraudReviewStatus is where a held payment is in the manual review queue
+type FraudReviewStatus string
+
+const (
+	FraudReviewPending  FraudReviewStatus = "PENDING"
+	FraudReviewApproved FraudReviewStatus = "APPROVED"
+	FraudReviewRejected FraudReviewStatus = "REJECTED"
+)
+
+// FraudCheck is the fraud score of a payment when it was created, with the
+// signals it was scored on. Checks are also the history velocity rules count.
+type FraudCheck struct {
+	gorm.Model
+	OrderID         uint          `gorm:"index;not null" json:"order_id"`
+	Order           *Order        `json:"order,omitempty"`
+	PaymentID       *uint         `gorm:"index" json:"payment_id,omitempty"`
+	Payment         *Payment      `json:"payment,omitempty"`
+	UserID          uint          `gorm:"index" json:"user_id"`
+	Email           string        `gorm:"index" json:"email"`
+	IPAddress       string        `gorm:"index" json:"ip_address"`
+	CardFingerprint string        `gorm:"index" json:"card_fingerprint,omitempty"`
+	BillingCountry  string        `json:"billing_country"`
+	ShippingCountry string        `json:"shipping_country"`
+	Amount          float64       `json:"amount"`
+	Score           int           `json:"score"`
+	Reasons         []string      `gorm:"serializer:json;type:text" json:"reasons"` // rules that tripped
+	Decision        FraudDecision `gorm:"type:varchar(10);index;not null" json:"decision"`
+
+	// Manual review of held payments
+	ReviewStatus FraudReviewStatus `gorm:"type:varchar(10);index" json:"review_status,omitempty"`
+	ReviewedByID *uint             `json:"reviewed_by_id,omitempty"`
+	ReviewedAt   *time.Time        `json:"reviewed_at,omitempty"`
+	ReviewNote   string            `gorm:"type:text" json:"review_note,omitempty"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShipmentStatus is the state of a shipment
+type ShipmentStatus string
+
+const (
+	ShipmentStatusPending   ShipmentStatus = "PENDING"
+	ShipmentStatusPicking   ShipmentStatus = "PICKING" // on a pick list
+	ShipmentStatusPicked    ShipmentStatus = "PICKED"  // every line has been picked
+	ShipmentStatusPacked    ShipmentStatus = "PACKED"  // every line has been packed, ready to ship
+	ShipmentStatusShipped   ShipmentStatus = "SHIPPED"
+	ShipmentStatusCancelled ShipmentStatus = "CANCELLED"
+)
+
+// OpenShipmentStatuses are the statuses of shipments that have not shipped
+// or been cancelled
+var OpenShipmentStatuses = []ShipmentStatus{
+	ShipmentStatusPending,
+	ShipmentStatusPicking,
+	ShipmentStatusPicked,
+	ShipmentStatusPacked,
+}
+
+// Shipment is the part of an order sent from one warehouse. An order whose
+// lines are allocated from several warehouses is split into several shipments.
+type Shipment struct {
+	gorm.Model
+	OrderID        uint              `gorm:"not null;index" json:"order_id"`
+	WarehouseID    uint              `gorm:"not null;index" json:"warehouse_id"`
+	Warehouse      *Warehouse        `json:"warehouse,omitempty"`
+	Status         ShipmentStatus    `gorm:"type:varchar(20);not null;default:'PENDING'" json:"status"`
+	TrackingNumber string            `json:"tracking_number"`
+	PickListID     *uint             `gorm:"index" json:"pick_list_id,omitempty"`
+	PickedAt       *time.Time        `json:"picked_at"`
+	PackedAt       *time.Time        `json:"packed_at"`
+	ShippedAt      *time.Time        `json:"shipped_at"`
+	Allocations    []StockAllocation `gorm:"foreignKey:ShipmentID" json:"allocations,omitempty"`
+}
+
+// PickList is a batch of shipments picked together. Its lines are the
+// shipments' allocations grouped by warehouse and bin.
+type PickList struct {
+	gorm.Model
+	WarehouseID *uint      `gorm:"index" json:"warehouse_id,omitempty"` // set when the list covers one warehouse
+	CreatedByID *uint      `json:"created_by_id,omitempty"`
+	Shipments   []Shipment `gorm:"foreignKey:PickListID" json:"shipments,omitempty"`
+}
+
+// AllocationStatus is the state of a stock allocation
+type AllocationStatus string
+
+const (
+	AllocationStatusAllocated AllocationStatus = "ALLOCATED" // stock is reserved
+	AllocationStatusFulfilled AllocationStatus = "FULFILLED" // stock has left the warehouse
+	AllocationStatusReleased  AllocationStatus = "RELEASED"  // the reservation was given back
+)
+
+// StockAllocation reserves a quantity of an inventory item (a batch in a
+// warehouse) for an order line. A line can be allocated from several batches.
+type StockAllocation struct {
+	gorm.Model
+	OrderID         uint             `gorm:"not null;index" json:"order_id"`
+	OrderItemID     uint             `gorm:"not null;index" json:"order_item_id"`
+	ShipmentID      uint             `gorm:"not null;index" json:"shipment_id"`
+	InventoryItemID uint             `gorm:"not null;index" json:"inventory_item_id"`
+	InventoryItem   *InventoryItem   `json:"inventory_item,omitempty"`
+	WarehouseID     uint             `gorm:"not null" json:"warehouse_id"`
+	Quantity        int              `gorm:"not null" json:"quantity"`
+	Status          AllocationStatus `gorm:"type:varchar(20);not null;default:'ALLOCATED';index" json:"status"`
+	PickedAt        *time.Time       `json:"picked_at"`
+	PickedByID      *uint            `json:"picked_by_id,omitempty"`
+	PackedAt        *time.Time       `json:"packed_at"`
+	PackedByID      *uint            `json:"packed_by_id,omitempty"`
+}
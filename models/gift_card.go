@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GiftCardStatus string
+
+const (
+	GiftCardPending GiftCardStatus = "pending" // bought on an order that is not paid yet
+	GiftCardActive  GiftCardStatus = "active"
+	GiftCardVoided  GiftCardStatus = "voided"
+)
+
+// GiftCard is a stored-value card spent at checkout. Only a hash of its code
+// is stored; the code itself is emailed to the recipient once, when the card
+// is activated.
+type GiftCard struct {
+	gorm.Model
+	CodeHash         *string        `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	Last4            string         `gorm:"type:varchar(4)" json:"last4"`
+	InitialAmount    float64        `gorm:"not null" json:"initial_amount"`
+	Balance          float64        `gorm:"not null;default:0" json:"balance"`
+	Status           GiftCardStatus `gorm:"type:varchar(10);not null;index" json:"status"`
+	ExpiresAt        *time.Time     `json:"expires_at,omitempty"`
+	ActivatedAt      *time.Time     `json:"activated_at,omitempty"`
+	OrderID          *uint          `gorm:"index" json:"order_id,omitempty"` // the order the card was bought on
+	ProductVariantID *uint          `json:"product_variant_id,omitempty"`
+	PurchaserID      *uint          `gorm:"index" json:"purchaser_id,omitempty"`
+	IssuedByID       *uint          `json:"issued_by_id,omitempty"` // the admin who issued it by hand
+	RecipientEmail   string         `gorm:"not null" json:"recipient_email"`
+	RecipientName    string         `json:"recipient_name"`
+	Message          string         `json:"message"`
+	VoidedAt         *time.Time     `json:"voided_at,omitempty"`
+	VoidReason       string         `json:"void_reason,omitempty"`
+}
+
+type GiftCardTransactionType string
+
+const (
+	GiftCardIssue  GiftCardTransactionType = "issue"
+	GiftCardRedeem GiftCardTransactionType = "redeem" // spent on an order
+	GiftCardRefund GiftCardTransactionType = "refund" // given back when the order is cancelled or refunded
+	GiftCardVoid   GiftCardTransactionType = "void"
+)
+
+// GiftCardTransaction is an entry in a gift card's balance ledger
+type GiftCardTransaction struct {
+	ID          uint                    `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time               `json:"created_at"`
+	GiftCardID  uint                    `gorm:"not null;index" json:"gift_card_id"`
+	OrderID     *uint                   `gorm:"index" json:"order_id,omitempty"`
+	Type        GiftCardTransactionType `gorm:"type:varchar(10);not null" json:"type"`
+	Amount      float64                 `gorm:"not null" json:"amount"`  // negative for debits
+	Balance     float64                 `gorm:"not null" json:"balance"` // after the entry
+	Description string                  `json:"description"`
+	CreatedByID *uint                   `json:"created_by_id,omitempty"`
+}
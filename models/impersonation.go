@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Impersonation log actions
+const (
+	ImpersonationActionStart   = "start"
+	ImpersonationActionRequest = "request"
+	ImpersonationActionBlocked = "blocked"
+)
+
+// ImpersonationLog records the start of an impersonation session and every
+// request made with an impersonation token
+type ImpersonationLog struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+	ImpersonatorID uint      `gorm:"index;not null" json:"impersonator_id"`
+	Impersonator   *User     `gorm:"foreignKey:ImpersonatorID" json:"impersonator,omitempty"`
+	UserID         uint      `gorm:"index;not null" json:"user_id"`
+	User           *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Action         string    `gorm:"type:varchar(20);not null" json:"action"`
+	Method         string    `gorm:"type:varchar(10)" json:"method"`
+	Path           string    `json:"path"`
+	Query          string    `json:"query"`
+	StatusCode     int       `json:"status_code"`
+	Reason         string    `json:"reason"`
+	IPAddress      string    `json:"ip_address"`
+	UserAgent      string    `json:"user_agent"`
+}
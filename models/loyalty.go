@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type LoyaltyTransactionType string
+
+const (
+	LoyaltyEarn   LoyaltyTransactionType = "earn"   // points for a delivered order
+	LoyaltyRedeem LoyaltyTransactionType = "redeem" // points spent as a checkout discount
+	LoyaltyRefund LoyaltyTransactionType = "refund" // redeemed points given back when the order is cancelled or refunded
+	LoyaltyRevoke LoyaltyTransactionType = "revoke" // earned points taken back when the order is returned or refunded
+	LoyaltyExpire LoyaltyTransactionType = "expire"
+	LoyaltyAdjust LoyaltyTransactionType = "adjust" // manual adjustment by an admin
+	LoyaltyReward LoyaltyTransactionType = "reward" // referral reward
+)
+
+// LoyaltyTransaction is an entry in a user's loyalty points ledger. The
+// balance is the sum of the points of the user's entries. Credits keep the
+// points not yet spent in Remaining, which are spent oldest first and expire
+// at ExpiresAt.
+type LoyaltyTransaction struct {
+	ID          uint                   `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UserID      uint                   `gorm:"not null;index" json:"user_id"`
+	OrderID     *uint                  `gorm:"index" json:"order_id,omitempty"`
+	Type        LoyaltyTransactionType `gorm:"type:varchar(10);not null" json:"type"`
+	Points      int                    `gorm:"not null" json:"points"` // negative for debits
+	Remaining   int                    `gorm:"not null;default:0" json:"-"`
+	ExpiresAt   *time.Time             `gorm:"index" json:"expires_at,omitempty"`
+	Description string                 `json:"description"`
+	CreatedByID *uint                  `json:"created_by_id,omitempty"`
+}
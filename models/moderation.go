@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EnforcementTarget is the kind of content an enforcement action was taken on
+type EnforcementTarget string
+
+const (
+	EnforcementTargetReview  EnforcementTarget = "review"
+	EnforcementTargetProduct EnforcementTarget = "product"
+)
+
+// EnforcementActionType is what was done to the content
+type EnforcementActionType string
+
+const (
+	// EnforcementActionRemove flags a review or unpublishes a product
+	EnforcementActionRemove EnforcementActionType = "remove"
+)
+
+// EnforcementAction records content taken down after an abuse report, and
+// whose content it was
+type EnforcementAction struct {
+	gorm.Model
+	AbuseReportID *uint                 `json:"abuse_report_id,omitempty" gorm:"index"`
+	UserID        *uint                 `json:"user_id,omitempty" gorm:"index"` // owner of the content, when known
+	User          *User                 `json:"user,omitempty"`
+	TargetType    EnforcementTarget     `json:"target_type" gorm:"type:varchar(20);not null;index:idx_enforcement_actions_target"`
+	TargetID      uint                  `json:"target_id" gorm:"not null;index:idx_enforcement_actions_target"`
+	Action        EnforcementActionType `json:"action" gorm:"type:varchar(20);not null"`
+	Reason        string                `json:"reason" gorm:"type:text"`
+	ActorID       uint                  `json:"actor_id"` // admin who resolved the report
+}
+
+// StrikeSource is how a user came to get a strike
+type StrikeSource string
+
+const (
+	StrikeSourceAbuseReport      StrikeSource = "abuse_report"      // their content was taken down after a report
+	StrikeSourceReviewModeration StrikeSource = "review_moderation" // a moderator rejected or flagged their review
+	StrikeSourceManual           StrikeSource = "manual"            // issued by an admin
+)
+
+// UserStrike is a mark against a user for breaking the content guidelines.
+// Active strikes count towards the thresholds that restrict the user.
+type UserStrike struct {
+	gorm.Model
+	UserID              uint         `json:"user_id" gorm:"not null;index"`
+	User                *User        `json:"user,omitempty"`
+	Source              StrikeSource `json:"source" gorm:"type:varchar(30);not null"`
+	EnforcementActionID *uint        `json:"enforcement_action_id,omitempty" gorm:"index"`
+	ReviewID            *uint        `json:"review_id,omitempty"`
+	Reason              string       `json:"reason" gorm:"type:text"`
+	IssuedBy            uint         `json:"issued_by"`
+	ExpiresAt           *time.Time   `json:"expires_at,omitempty"` // nil never expires
+	RevokedAt           *time.Time   `json:"revoked_at,omitempty"`
+	RevokedBy           *uint        `json:"revoked_by,omitempty"`
+}
+
+// IsActive reports whether the strike still counts against the user
+func (s *UserStrike) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
+// UserCapability is something a restriction stops a user from doing
+type UserCapability string
+
+const (
+	CapabilityReview  UserCapability = "review"  // write reviews
+	CapabilityMessage UserCapability = "message" // reply to reviews and review responses
+	CapabilityLogin   UserCapability = "login"   // log in at all; a ban
+)
+
+// UserRestriction stops a user from using a capability until it expires or
+// is lifted. Restrictions are applied by admins, or automatically when a
+// user's active strikes reach a threshold.
+type UserRestriction struct {
+	gorm.Model
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	User       *User          `json:"user,omitempty"`
+	Capability UserCapability `json:"capability" gorm:"type:varchar(20);not null"`
+	Reason     string         `json:"reason" gorm:"type:text"`
+	Automatic  bool           `json:"automatic"`            // applied by a strike threshold
+	IssuedBy   *uint          `json:"issued_by,omitempty"`  // nil when automatic
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"` // nil until lifted
+	LiftedAt   *time.Time     `json:"lifted_at,omitempty"`
+	LiftedBy   *uint          `json:"lifted_by,omitempty"`
+}
+
+// IsActive reports whether the restriction is in force
+func (r *UserRestriction) IsActive(now time.Time) bool {
+	return r.LiftedAt == nil && (r.ExpiresAt == nil || now.Before(*r.ExpiresAt))
+}
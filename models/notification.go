@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// NotificationType is the event an in-app notification is about
+type NotificationType string
+
+const (
+	NotificationTypeOrderUpdate     NotificationType = "order_update"
+	NotificationTypeTicketResponse  NotificationType = "ticket_response"
+	NotificationTypeTicketStatus    NotificationType = "ticket_status"
+	NotificationTypeDisputeResponse NotificationType = "dispute_response"
+	NotificationTypeDisputeStatus   NotificationType = "dispute_status"
+	NotificationTypeStockAlert      NotificationType = "stock_alert"
+	NotificationTypeSLABreach       NotificationType = "sla_breach"
+	NotificationTypeOrderMessage    NotificationType = "order_message"
+	NotificationTypeFraudReview     NotificationType = "fraud_review"
+)
+
+// Notification is an in-app message shown in a user's notification center
+type Notification struct {
+	ID        uint             `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time        `gorm:"index" json:"created_at"`
+	UserID    uint             `gorm:"index;not null" json:"user_id"`
+	Type      NotificationType `gorm:"type:varchar(30);index;not null" json:"type"`
+	Title     string           `gorm:"not null" json:"title"`
+	Body      string           `json:"body"`
+	Link      string           `json:"link"` // frontend path the notification opens
+	Data      JSON             `gorm:"type:json" json:"data"`
+	ReadAt    *time.Time       `gorm:"index" json:"read_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OAuthIdentity links a user to their account with a social sign-in
+// provider, so they can sign in without a password
+type OAuthIdentity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Provider  string    `json:"provider" gorm:"type:varchar(20);not null;uniqueIndex:idx_oauth_identity"` // google or apple
+	Subject   string    `json:"-" gorm:"not null;uniqueIndex:idx_oauth_identity"`                         // the provider's ID for the user
+	Email     string    `json:"email"`                                                                    // address the provider gave when the identity was linked
+}
@@ -9,12 +9,13 @@ import (
 type OrderStatus string
 
 const (
-	OrderStatusPending    OrderStatus = "PENDING"
-	OrderStatusProcessing OrderStatus = "PROCESSING"
-	OrderStatusShipped    OrderStatus = "SHIPPED"
-	OrderStatusDelivered  OrderStatus = "DELIVERED"
-	OrderStatusCancelled  OrderStatus = "CANCELLED"
-	OrderStatusReturned   OrderStatus = "RETURNED"
+	OrderStatusPending         OrderStatus = "PENDING"
+	OrderStatusPendingApproval OrderStatus = "PENDING_APPROVAL" // a company buyer's order waiting for an owner to approve it
+	OrderStatusProcessing      OrderStatus = "PROCESSING"
+	OrderStatusShipped         OrderStatus = "SHIPPED"
+	OrderStatusDelivered       OrderStatus = "DELIVERED"
+	OrderStatusCancelled       OrderStatus = "CANCELLED"
+	OrderStatusReturned        OrderStatus = "RETURNED"
 )
 
 type PaymentStatus string
@@ -42,34 +43,78 @@ type Order struct {
 	DiscountAmount float64       `json:"discount_amount"`
 	FinalAmount    float64       `gorm:"not null" json:"final_amount"`
 
+	// Loyalty points spent on the order; their value is part of DiscountAmount
+	PointsRedeemed int     `json:"points_redeemed"`
+	PointsDiscount float64 `json:"points_discount"`
+
+	// Reward coupon used on the order; its value is part of DiscountAmount
+	CouponID       *uint   `json:"coupon_id,omitempty"`
+	CouponDiscount float64 `json:"coupon_discount"`
+
+	// Gift card paying part or all of FinalAmount; the rest is paid with
+	// PaymentMethod
+	GiftCardID     *uint   `json:"gift_card_id,omitempty"`
+	GiftCardAmount float64 `json:"gift_card_amount"`
+
+	// Tax
+	TaxCountry   string `json:"tax_country"`                    // country the VAT was calculated for
+	TaxBreakdown JSON   `json:"tax_breakdown" gorm:"type:json"` // per-rate net/tax totals
+
 	// Shipping
-	ShippingAddressID uint    `json:"shipping_address_id"`
-	ShippingAddress   Address `json:"shipping_address"`
-	ShippingMethod    string  `json:"shipping_method"`
-	TrackingNumber    string  `json:"tracking_number"`
+	ShippingAddressID uint          `json:"shipping_address_id"`
+	ShippingAddress   Address       `json:"shipping_address"`
+	BillingAddressID  *uint         `json:"billing_address_id"`
+	BillingAddress    *Address      `json:"billing_address,omitempty" gorm:"foreignKey:BillingAddressID"`
+	ShippingMethod    string        `json:"shipping_method"`
+	DeliverySlotID    *uint         `gorm:"index" json:"delivery_slot_id,omitempty"`
+	DeliverySlot      *DeliverySlot `json:"delivery_slot,omitempty"`
+	TrackingNumber    string        `json:"tracking_number"`
 
 	// Payment
 	PaymentMethod    string     `json:"payment_method"`
 	PaymentReference string     `json:"payment_reference"`
 	PaymentDate      *time.Time `json:"payment_date"`
 
+	// Capture mode of the order's payments, automatic or manual; empty
+	// leaves it to the payment service
+	CaptureMode string `gorm:"type:varchar(10)" json:"capture_mode,omitempty"`
+
 	// Revolut payment fields
 	RevolutOrderID   string `json:"revolut_order_id"`
 	RevolutPaymentID string `json:"revolut_payment_id"`
 	CheckoutURL      string `json:"checkout_url"`
 	PaymentProvider  string `json:"payment_provider" gorm:"default:'revolut'"`
+	// PaymentRetryStartedAt is when a payment retry claimed the order, so a
+	// concurrent retry does not open a second checkout; cleared when it ends
+	PaymentRetryStartedAt *time.Time `json:"-"`
 
 	// Order Items
 	Items []OrderItem `json:"items"`
 
+	// Shipments, one per fulfilling warehouse, created when the order is confirmed
+	Shipments []Shipment `json:"shipments,omitempty" gorm:"foreignKey:OrderID"`
+
+	// Conversation with the store about the order
+	Messages       []OrderMessage `json:"messages,omitempty" gorm:"foreignKey:OrderID"`
+	UnreadMessages int64          `json:"unread_messages" gorm:"-"` // messages from the other side the viewer has not read
+
+	// Company owner's decision on an order that waited for approval
+	ApproverID        *uint      `json:"approver_id,omitempty"`
+	ApprovalDecidedAt *time.Time `json:"approval_decided_at,omitempty"`
+	ApprovalNote      string     `json:"approval_note,omitempty"`
+
+	// Subscription the order was placed for on its schedule
+	SubscriptionID *uint `gorm:"index" json:"subscription_id,omitempty"`
+
 	// Notes
 	CustomerNotes string `json:"customer_notes"`
 	AdminNotes    string `json:"admin_notes"`
 
 	// Dates
-	OrderDate     time.Time  `gorm:"not null" json:"order_date"`
-	ShippedDate   *time.Time `json:"shipped_date"`
-	DeliveredDate *time.Time `json:"delivered_date"`
+	OrderDate        time.Time  `gorm:"not null" json:"order_date"`
+	ShippedDate      *time.Time `json:"shipped_date"`
+	OutForDeliveryAt *time.Time `json:"out_for_delivery_at"` // when the driver set off with the shipped order
+	DeliveredDate    *time.Time `json:"delivered_date"`
 }
 
 type OrderItem struct {
@@ -87,6 +132,7 @@ type OrderItem struct {
 
 	Quantity       int     `gorm:"not null" json:"quantity"`
 	UnitPrice      float64 `gorm:"not null" json:"unit_price"`
+	TaxRate        float64 `json:"tax_rate"` // VAT percentage applied to this line
 	TaxAmount      float64 `json:"tax_amount"`
 	DiscountAmount float64 `json:"discount_amount"`
 	TotalAmount    float64 `gorm:"not null" json:"total_amount"`
@@ -108,9 +154,16 @@ type Invoice struct {
 	DueDate          time.Time  `json:"due_date"`
 	Amount           float64    `gorm:"not null" json:"amount"`
 	TaxAmount        float64    `json:"tax_amount"`
+	TaxBreakdown     JSON       `json:"tax_breakdown" gorm:"type:json"`
+	Currency         string     `json:"currency"`
 	Status           string     `gorm:"default:'pending'" json:"status"` // pending, paid, overdue, cancelled
 	PaymentDate      *time.Time `json:"payment_date"`
 	PaymentMethod    string     `json:"payment_method"`
 	PaymentReference string     `json:"payment_reference"`
 	Notes            string     `json:"notes"`
+
+	// The business details in effect when the invoice was issued
+	IssuerName  string `json:"issuer_name"`
+	IssuerEmail string `json:"issuer_email"`
+	IssuerURL   string `json:"issuer_url"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderStatusChange records an order moving from one status to another
+type OrderStatusChange struct {
+	ID         uint        `gorm:"primarykey" json:"id"`
+	OrderID    uint        `gorm:"index;not null" json:"order_id"`
+	FromStatus OrderStatus `gorm:"type:varchar(20);not null" json:"from_status"`
+	ToStatus   OrderStatus `gorm:"type:varchar(20);not null" json:"to_status"`
+	ActorID    *uint       `json:"actor_id,omitempty"` // nil for changes made by the system
+	Reason     string      `json:"reason"`
+	Forced     bool        `gorm:"default:false" json:"forced"` // made by an admin outside the usual transitions
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// OrderNote is an internal note staff keep on an order. Customers never see
+// it.
+type OrderNote struct {
+	gorm.Model
+	OrderID  uint   `gorm:"index;not null" json:"order_id"`
+	AuthorID uint   `gorm:"not null" json:"author_id"`
+	Author   *User  `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+	Body     string `gorm:"type:text;not null" json:"body"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderMessage is a message in the conversation between a customer and the
+// store about one of their orders. It is separate from support tickets.
+type OrderMessage struct {
+	gorm.Model
+	OrderID     uint                     `gorm:"index;not null" json:"order_id"`
+	SenderID    uint                     `gorm:"not null" json:"sender_id"`
+	Sender      *User                    `gorm:"foreignKey:SenderID" json:"sender,omitempty"`
+	IsFromAdmin bool                     `gorm:"default:false;index" json:"is_from_admin"`
+	Body        string                   `gorm:"type:text;not null" json:"body"`
+	ReadAt      *time.Time               `gorm:"index" json:"read_at"` // when the other side first opened the conversation after it was sent
+	Attachments []OrderMessageAttachment `gorm:"foreignKey:MessageID" json:"attachments"`
+}
+
+// OrderMessageAttachment is a file sent with an order message. Files are
+// uploaded through the support attachment upload first.
+type OrderMessageAttachment struct {
+	gorm.Model
+	MessageID uint   `gorm:"index;not null" json:"message_id"`
+	FileName  string `gorm:"not null" json:"file_name"`
+	FileURL   string `gorm:"not null" json:"file_url"`
+	FileSize  int64  `json:"file_size"`
+	FileType  string `json:"file_type"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// OutboxMessage is a side effect (an email or a webhook) recorded in the same
+// transaction as the business change that causes it, and delivered afterwards
+// by the outbox dispatcher. Messages are only written if the change commits.
+type OutboxMessage struct {
+	ID            uint                `gorm:"primarykey" json:"id"`
+	Kind          string              `gorm:"size:32;not null;index" json:"kind"` // selects the handler: email, webhook
+	Topic         string              `gorm:"size:100;index" json:"topic"`        // event name, e.g. order.paid
+	Payload       EmailJSON           `json:"payload"`
+	Status        OutboxMessageStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_outbox_due,priority:1" json:"status"`
+	Attempts      int                 `json:"attempts"`
+	NextAttemptAt time.Time           `gorm:"index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError     string              `json:"last_error"`
+	RequestID     string              `gorm:"size:64;index" json:"request_id"` // HTTP request that caused the message
+	DispatchedAt  *time.Time          `json:"dispatched_at"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// OutboxMessageStatus is the delivery state of an outbox message
+type OutboxMessageStatus string
+
+const (
+	OutboxStatusPending    OutboxMessageStatus = "pending"
+	OutboxStatusProcessing OutboxMessageStatus = "processing" // claimed by a dispatcher; retried if it stays claimed
+	OutboxStatusDispatched OutboxMessageStatus = "dispatched"
+	OutboxStatusFailed     OutboxMessageStatus = "failed" // gave up after the maximum number of attempts
+)
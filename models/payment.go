@@ -22,6 +22,13 @@ const (
 	RevolutPaymentStatusDisputed   RevolutPaymentStatus = "DISPUTED"
 )
 
+// Capture modes of a payment. Manual capture payments stop at AUTHORIZED
+// until they are captured, partially or in full, or voided.
+const (
+	CaptureModeAutomatic = "automatic"
+	CaptureModeManual    = "manual"
+)
+
 // JSON is a custom type for storing JSON data
 type JSON map[string]interface{}
 
@@ -61,6 +68,9 @@ type Payment struct {
 	PaymentMethod    string               `json:"payment_method"`
 	CustomerID       string               `json:"customer_id"`
 	CheckoutURL      string               `json:"checkout_url"`
+	CaptureMode      string               `json:"capture_mode" gorm:"type:varchar(10);not null;default:'automatic'"`
+	AuthorizedAt     *time.Time           `json:"authorized_at" gorm:"index"`
+	CapturedAmount   float64              `json:"captured_amount" gorm:"default:0"` // less than Amount after a partial capture; 0 when captured automatically
 	CompletedAt      *time.Time           `json:"completed_at"`
 	FailureReason    string               `json:"failure_reason"`
 	RefundStatus     string               `json:"refund_status"`
@@ -85,6 +95,9 @@ func (p *Payment) BeforeCreate(tx *gorm.DB) error {
 	if p.Currency == "" {
 		p.Currency = "GBP"
 	}
+	if p.CaptureMode == "" {
+		p.CaptureMode = CaptureModeAutomatic
+	}
 	if p.RefundedAmount == 0 {
 		p.RefundedAmount = 0
 	}
@@ -106,9 +119,18 @@ func (p *Payment) IsRefunded() bool {
 	return p.Status == RevolutPaymentStatusRefunded
 }
 
+// SettledAmount returns the amount the customer was charged: the captured
+// amount after a manual capture, the full amount otherwise
+func (p *Payment) SettledAmount() float64 {
+	if p.CapturedAmount > 0 {
+		return p.CapturedAmount
+	}
+	return p.Amount
+}
+
 // CanRefund returns true if the payment can be refunded
 func (p *Payment) CanRefund() bool {
-	return p.IsCompleted() && !p.IsRefunded() && p.RefundedAmount < p.Amount
+	return p.IsCompleted() && !p.IsRefunded() && p.RefundedAmount < p.SettledAmount()
 }
 
 // GetRefundableAmount returns the amount that can be refunded
@@ -116,7 +138,7 @@ func (p *Payment) GetRefundableAmount() float64 {
 	if !p.CanRefund() {
 		return 0
 	}
-	return p.Amount - p.RefundedAmount
+	return p.SettledAmount() - p.RefundedAmount
 }
 
 // PaymentLog represents a log entry for payment events
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PriceList holds negotiated variant prices for a company or an individual B2B user.
+// A list applies to every user of CompanyID, or only to UserID when set.
+type PriceList struct {
+	gorm.Model
+	Name        string     `gorm:"not null" json:"name"`
+	Description string     `json:"description"`
+	CompanyID   *uint      `json:"company_id,omitempty"`
+	Company     *Company   `json:"company,omitempty"`
+	UserID      *uint      `json:"user_id,omitempty"`
+	User        *User      `json:"user,omitempty"`
+	Currency    string     `gorm:"default:'GBP'" json:"currency"`
+	Priority    int        `gorm:"default:0" json:"priority"` // higher wins when several lists are effective
+	IsActive    bool       `gorm:"default:true" json:"is_active"`
+	StartsAt    *time.Time `json:"starts_at"` // nil means effective immediately
+	EndsAt      *time.Time `json:"ends_at"`   // nil means no end date
+
+	Items []PriceListItem `json:"items"`
+}
+
+// PriceListItem overrides the price of a variant from MinQuantity upwards
+type PriceListItem struct {
+	gorm.Model
+	PriceListID      uint           `gorm:"not null" json:"price_list_id"`
+	PriceList        PriceList      `json:"-"`
+	ProductVariantID uint           `gorm:"not null" json:"product_variant_id"`
+	ProductVariant   ProductVariant `json:"product_variant" gorm:"foreignKey:ProductVariantID"`
+	MinQuantity      int            `gorm:"default:1" json:"min_quantity"`
+	Price            float64        `gorm:"not null" json:"price"`
+}
+
+// IsEffective reports whether the list applies at the given time
+func (p *PriceList) IsEffective(at time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && at.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !at.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
@@ -14,8 +14,17 @@ type Product struct {
 	IsActive    bool   `gorm:"default:true" json:"is_active"`
 	IsFeatured  bool   `gorm:"default:false" json:"is_featured"`
 	IsVAT       bool   `gorm:"default:false" json:"is_vat"`
+	IsGiftCard  bool   `gorm:"default:false" json:"is_gift_card"` // each unit bought issues a gift card worth its price
 	BrandID     *uint  `json:"brand_id"`
 
+	// Launch window: the storefront shows an active product from PublishAt,
+	// or straight away when empty, until UnpublishAt, or indefinitely
+	PublishAt   *time.Time `gorm:"index" json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `gorm:"index" json:"unpublish_at,omitempty"`
+
+	// Version is incremented by every edit, see SaveVersioned
+	Version int `gorm:"not null;default:1" json:"version"`
+
 	// Relationships
 	Brand          *Brand                 `json:"brand,omitempty" gorm:"foreignKey:BrandID"`
 	Categories     []*Category            `gorm:"many2many:product_categories;" json:"categories,omitempty"`
@@ -27,6 +36,17 @@ type Product struct {
 
 	// Review integration (not stored in database)
 	RatingSummary interface{} `json:"rating_summary,omitempty" gorm:"-"`
+
+	// Category trail of the product's deepest category (not stored in database)
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs,omitempty" gorm:"-"`
+}
+
+// IsPublished reports whether now is inside the product's launch window
+func (p *Product) IsPublished(now time.Time) bool {
+	if p.PublishAt != nil && p.PublishAt.After(now) {
+		return false
+	}
+	return p.UnpublishAt == nil || p.UnpublishAt.After(now)
 }
 
 // ProductVariant represents a specific version of a product, like size or color.
@@ -46,6 +66,14 @@ type ProductVariant struct {
 	IsActive        bool        `gorm:"default:true" json:"is_active"`      // if the variant is active
 	MinQuantity     int         `gorm:"default:1" json:"min_quantity"`      // minimum quantity to buy
 	QuantityInStock int         `gorm:"default:0" json:"quantity_in_stock"` // quantity in stock
+	Version         int         `gorm:"not null;default:1" json:"version"`  // incremented by every edit, see SaveVersioned
+
+	// Bundles are made up of other variants. Their prices are the sum of
+	// their components' unless BundlePrice overrides them, and their stock
+	// is what their components' stock can make up.
+	BundlePrice      *float64          `json:"bundle_price,omitempty"`
+	BundleComponents []BundleComponent `gorm:"foreignKey:BundleVariantID" json:"bundle_components,omitempty"`
+
 	// Relationships
 	Images         []ProductImage            `gorm:"foreignKey:ProductVariantID" json:"images"`
 	OptionValues   []*ProductOptionValue     `gorm:"many2many:variant_option_values;" json:"option_values"`
@@ -54,6 +82,21 @@ type ProductVariant struct {
 
 	// Review integration (not stored in database)
 	RatingSummary interface{} `json:"rating_summary,omitempty" gorm:"-"`
+
+	// Price resolved for the authenticated customer (not stored in database)
+	CustomerPrice *float64 `json:"customer_price,omitempty" gorm:"-"`
+
+	// Base price and customer price per 100g, kg, 100ml or litre, when the
+	// variant has a weight or volume (not stored in database)
+	UnitPrice         *UnitPrice `json:"unit_price,omitempty" gorm:"-"`
+	CustomerUnitPrice *UnitPrice `json:"customer_unit_price,omitempty" gorm:"-"`
+}
+
+// UnitPrice is a price per standard measure, like 1.25 per 100g, so sizes
+// can be compared
+type UnitPrice struct {
+	Price float64 `json:"price"`
+	Per   string  `json:"per"` // 100g, kg, 100ml or l
 }
 
 // New: ProductVariantPriceTier represents a price break for a variant based on quantity.
@@ -101,6 +144,24 @@ type ProductImage struct {
 	URL              string `gorm:"not null" json:"url"`
 	IsPrimary        bool   `gorm:"default:false" json:"is_primary"`
 	AltText          string `json:"alt_text"`
+	ImageKeys
+	// URLs of the image's sizes, set for responses (not stored in database)
+	Sizes *ImageSizes `json:"sizes,omitempty" gorm:"-"`
+}
+
+// ImageKeys are the storage keys of the resized copies of an image. A size
+// the original is not larger than has no copy, and is served the original.
+type ImageKeys struct {
+	ThumbnailKey string `json:"-"`
+	MediumKey    string `json:"-"`
+	LargeKey     string `json:"-"`
+}
+
+// ImageSizes are the URLs of an image resized to fit 150, 600 and 1200 pixels
+type ImageSizes struct {
+	Thumbnail string `json:"thumbnail"`
+	Medium    string `json:"medium"`
+	Large     string `json:"large"`
 }
 
 type Category struct {
@@ -111,11 +172,22 @@ type Category struct {
 	Image        string      `json:"image"`
 	ParentID     *uint       `json:"parent_id"`
 	Parent       *Category   `json:"parent,omitempty"`
+	Path         string      `gorm:"type:varchar(255);not null;default:'';index" json:"path"` // IDs from the root down to the category, like "/1/4/9/"
+	Depth        int         `gorm:"not null;default:0" json:"depth"`                         // 0 for top level categories
+	Position     int         `gorm:"not null;default:0" json:"position"`                      // order among its siblings
 	IsFeatureOne bool        `gorm:"default:false" json:"is_feature_one"`
 	Children     []*Category `gorm:"foreignKey:ParentID" json:"children,omitempty"`
 	Products     []*Product  `gorm:"many2many:product_categories;" json:"products,omitempty"`
 }
 
+// Breadcrumb is one category in the trail from the top level down to a
+// product's category
+type Breadcrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
 // InventoryItem tracks stock for a specific product variant in a warehouse.
 type InventoryItem struct {
 	gorm.Model
@@ -127,7 +199,12 @@ type InventoryItem struct {
 	Reserved         int            `gorm:"default:0" json:"reserved"`
 	BatchNumber      string         `json:"batch_number"`
 	ExpiryDate       *time.Time     `json:"expiry_date"`
+	BinLocation      string         `json:"bin_location"`                   // aisle, shelf or bin the batch is stored in
 	Status           string         `gorm:"default:'active'" json:"status"` // active, expired, damaged
+
+	// Version is incremented by edits of the item's details, see
+	// SaveVersioned. Stock changes lock the row instead.
+	Version int `gorm:"not null;default:1" json:"version"`
 }
 
 type Warehouse struct {
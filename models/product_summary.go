@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ProductSummary is a denormalized row per product used by the storefront
+// listing, so it can be served without loading variant, image and rating trees.
+// It is kept up to date by the catalog package on every write.
+type ProductSummary struct {
+	ProductID       uint       `gorm:"primaryKey;autoIncrement:false" json:"product_id"`
+	Name            string     `gorm:"index" json:"name"`
+	BrandID         *uint      `gorm:"index" json:"brand_id"`
+	IsActive        bool       `gorm:"index" json:"is_active"`
+	PublishAt       *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt     *time.Time `json:"unpublish_at,omitempty"`
+	IsFeatured      bool       `json:"is_featured"`
+	IsVAT           bool       `json:"is_vat"`
+	MinPrice        float64    `gorm:"index" json:"min_price"` // lowest active variant base price
+	MaxPrice        float64    `json:"max_price"`
+	MinB2BPrice     float64    `json:"min_b2b_price"`
+	MaxB2BPrice     float64    `json:"max_b2b_price"`
+	PrimaryImageURL string     `json:"primary_image_url"`
+	AverageRating   float64    `gorm:"index" json:"average_rating"` // weighted over all variants
+	TotalReviews    int        `json:"total_reviews"`
+	TotalStock      int        `json:"total_stock"`
+	InStock         bool       `gorm:"index" json:"in_stock"`
+	VariantCount    int        `json:"variant_count"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
@@ -9,29 +9,30 @@ import (
 type POStatus string
 
 const (
-	POStatusDraft     POStatus = "DRAFT"
-	POStatusPending   POStatus = "PENDING"
-	POStatusApproved  POStatus = "APPROVED"
-	POStatusOrdered   POStatus = "ORDERED"
-	POStatusShipped   POStatus = "SHIPPED"
-	POStatusReceived  POStatus = "RECEIVED"
-	POStatusCancelled POStatus = "CANCELLED"
+	POStatusDraft             POStatus = "DRAFT"              // being prepared, can still be edited
+	POStatusSent              POStatus = "SENT"               // sent to the supplier, awaiting delivery
+	POStatusPartiallyReceived POStatus = "PARTIALLY_RECEIVED" // some stock has been received
+	POStatusReceived          POStatus = "RECEIVED"           // everything ordered has been received
+	POStatusCancelled         POStatus = "CANCELLED"
 )
 
 type PurchaseOrder struct {
 	gorm.Model
-	PONumber       string    `gorm:"uniqueIndex;not null" json:"po_number"`
-	SupplierID     uint      `json:"supplier_id"`
-	Supplier       Supplier  `json:"supplier"`
-	Status         POStatus  `gorm:"type:varchar(20);not null" json:"status"`
-	OrderDate      time.Time `gorm:"not null" json:"order_date"`
-	ExpectedDate   time.Time `json:"expected_date"`
-	Currency       string    `gorm:"default:'GBP'" json:"currency"`
-	ExchangeRate   float64   `json:"exchange_rate"`
-	TotalAmount    float64   `gorm:"not null" json:"total_amount"`
-	TaxAmount      float64   `json:"tax_amount"`
-	ShippingAmount float64   `json:"shipping_amount"`
-	FinalAmount    float64   `gorm:"not null" json:"final_amount"`
+	PONumber       string     `gorm:"uniqueIndex;not null" json:"po_number"`
+	SupplierID     uint       `json:"supplier_id"`
+	Supplier       Supplier   `json:"supplier"`
+	WarehouseID    uint       `gorm:"index" json:"warehouse_id"` // where the stock is delivered
+	Warehouse      *Warehouse `json:"warehouse,omitempty"`
+	Status         POStatus   `gorm:"type:varchar(20);not null" json:"status"`
+	OrderDate      time.Time  `gorm:"not null" json:"order_date"`
+	ExpectedDate   *time.Time `json:"expected_date"`
+	Currency       string     `gorm:"default:'GBP'" json:"currency"`
+	ExchangeRate   float64    `json:"exchange_rate"`
+	TotalAmount    float64    `gorm:"not null" json:"total_amount"`
+	TaxAmount      float64    `json:"tax_amount"`
+	ShippingAmount float64    `json:"shipping_amount"`
+	FinalAmount    float64    `gorm:"not null" json:"final_amount"`
+	CreatedByID    *uint      `json:"created_by_id,omitempty"`
 
 	// Shipping
 	ShippingMethod  string `json:"shipping_method"`
@@ -41,6 +42,9 @@ type PurchaseOrder struct {
 	// Items
 	Items []POItem `json:"items" gorm:"foreignKey:POID"`
 
+	// Deliveries booked in against the order
+	Receipts []GoodsReceipt `json:"receipts,omitempty" gorm:"foreignKey:POID"`
+
 	// Documents
 	Documents []Document `json:"documents" gorm:"many2many:purchase_order_documents;"`
 
@@ -49,23 +53,53 @@ type PurchaseOrder struct {
 	InternalNotes string `json:"internal_notes"`
 
 	// Dates
-	ApprovedDate *time.Time `json:"approved_date"`
-	ShippedDate  *time.Time `json:"shipped_date"`
-	ReceivedDate *time.Time `json:"received_date"`
+	ApprovedDate  *time.Time `json:"approved_date"`
+	SentDate      *time.Time `json:"sent_date"`
+	ShippedDate   *time.Time `json:"shipped_date"`
+	ReceivedDate  *time.Time `json:"received_date"` // when the last item was received
+	CancelledDate *time.Time `json:"cancelled_date"`
 }
 
 type POItem struct {
 	gorm.Model
-	POID             uint          `json:"po_id"`
-	PurchaseOrder    PurchaseOrder `gorm:"foreignKey:POID" json:"-"`
-	ProductID        uint          `json:"product_id"`
-	Product          Product       `json:"product"`
-	Quantity         int           `gorm:"not null" json:"quantity"`
-	UnitPrice        float64       `gorm:"not null" json:"unit_price"`
-	TaxAmount        float64       `json:"tax_amount"`
-	TotalAmount      float64       `gorm:"not null" json:"total_amount"`
-	ReceivedQuantity int           `gorm:"default:0" json:"received_quantity"`
-	Status           string        `gorm:"default:'pending'" json:"status"` // pending, partial, complete
+	POID             uint            `json:"po_id"`
+	PurchaseOrder    PurchaseOrder   `gorm:"foreignKey:POID" json:"-"`
+	ProductID        uint            `json:"product_id"`
+	Product          Product         `json:"product"`
+	ProductVariantID uint            `gorm:"index" json:"product_variant_id"`
+	ProductVariant   *ProductVariant `json:"product_variant,omitempty"`
+	Quantity         int             `gorm:"not null" json:"quantity"`
+	UnitPrice        float64         `gorm:"not null" json:"unit_price"` // unit cost from the supplier
+	TaxAmount        float64         `json:"tax_amount"`
+	TotalAmount      float64         `gorm:"not null" json:"total_amount"`
+	ReceivedQuantity int             `gorm:"default:0" json:"received_quantity"`
+	Status           string          `gorm:"default:'pending'" json:"status"` // pending, partial, complete
+}
+
+// Outstanding returns the quantity still to be received
+func (i *POItem) Outstanding() int {
+	return max(i.Quantity-i.ReceivedQuantity, 0)
+}
+
+// GoodsReceipt records a delivery booked in against a purchase order
+type GoodsReceipt struct {
+	gorm.Model
+	POID         uint               `gorm:"not null;index" json:"po_id"`
+	ReceivedByID *uint              `json:"received_by_id,omitempty"`
+	ReceivedAt   time.Time          `gorm:"not null" json:"received_at"`
+	Notes        string             `json:"notes"`
+	Items        []GoodsReceiptItem `json:"items"`
+}
+
+// GoodsReceiptItem is the quantity of a purchase order line received into a batch
+type GoodsReceiptItem struct {
+	gorm.Model
+	GoodsReceiptID  uint       `gorm:"not null;index" json:"goods_receipt_id"`
+	POItemID        uint       `gorm:"not null;index" json:"po_item_id"`
+	InventoryItemID uint       `gorm:"not null" json:"inventory_item_id"`
+	Quantity        int        `gorm:"not null" json:"quantity"`
+	BatchNumber     string     `json:"batch_number"`
+	ExpiryDate      *time.Time `json:"expiry_date"`
 }
 
 type Supplier struct {
@@ -78,16 +112,19 @@ type Supplier struct {
 	Email              string `json:"email"`
 	Website            string `json:"website"`
 	IsActive           bool   `gorm:"default:true" json:"is_active"`
+	PaymentTerms       int    `gorm:"default:30" json:"payment_terms"` // days
+	LeadTimeDays       int    `gorm:"default:0" json:"lead_time_days"` // quoted lead time, sets the expected date of sent orders
+	Notes              string `json:"notes"`
 
 	// Address
-	AddressID uint    `json:"address_id"`
-	Address   Address `json:"address"`
+	AddressID *uint    `json:"address_id"`
+	Address   *Address `json:"address,omitempty"`
 
 	// Contacts
-	Contacts []SupplierContact `json:"contacts"`
+	Contacts []SupplierContact `json:"contacts,omitempty"`
 
 	// Purchase Orders
-	PurchaseOrders []PurchaseOrder `json:"purchase_orders"`
+	PurchaseOrders []PurchaseOrder `json:"purchase_orders,omitempty"`
 }
 
 type SupplierContact struct {
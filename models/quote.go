@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type QuoteStatus string
+
+const (
+	QuoteStatusRequested QuoteStatus = "REQUESTED" // submitted by the customer, awaiting admin pricing
+	QuoteStatusQuoted    QuoteStatus = "QUOTED"    // admin responded with prices and a validity window
+	QuoteStatusAccepted  QuoteStatus = "ACCEPTED"  // customer accepted and the quote was converted into an order
+	QuoteStatusDeclined  QuoteStatus = "DECLINED"  // customer declined the quoted prices
+	QuoteStatusRejected  QuoteStatus = "REJECTED"  // admin refused to quote
+	QuoteStatusExpired   QuoteStatus = "EXPIRED"   // validity window passed before acceptance
+	QuoteStatusCancelled QuoteStatus = "CANCELLED" // withdrawn by the customer before a response
+)
+
+// Quote is a B2B request for quotation built from a customer's cart
+type Quote struct {
+	gorm.Model
+	QuoteNumber string      `gorm:"uniqueIndex;not null" json:"quote_number"`
+	UserID      uint        `gorm:"not null" json:"user_id"`
+	User        User        `json:"user"`
+	CompanyID   *uint       `json:"company_id,omitempty"`
+	Company     *Company    `json:"company,omitempty"`
+	Status      QuoteStatus `gorm:"type:varchar(20);not null" json:"status"`
+
+	// Totals at list price (requested) and at the prices offered by the admin (quoted)
+	RequestedAmount float64 `json:"requested_amount"`
+	QuotedAmount    float64 `json:"quoted_amount"`
+
+	CustomerNotes string `json:"customer_notes"`
+	AdminNotes    string `json:"admin_notes"`
+
+	// Response
+	RespondedByID *uint      `json:"responded_by_id,omitempty"`
+	RespondedBy   *User      `json:"responded_by,omitempty" gorm:"foreignKey:RespondedByID"`
+	RespondedAt   *time.Time `json:"responded_at"`
+	ValidUntil    *time.Time `json:"valid_until"`
+
+	// Conversion
+	AcceptedAt *time.Time `json:"accepted_at"`
+	OrderID    *uint      `json:"order_id,omitempty"`
+	Order      *Order     `json:"order,omitempty"`
+
+	Items []QuoteItem `json:"items"`
+}
+
+type QuoteItem struct {
+	gorm.Model
+	QuoteID uint  `gorm:"not null" json:"quote_id"`
+	Quote   Quote `json:"-"`
+
+	ProductVariantID uint           `gorm:"not null" json:"product_variant_id"`
+	ProductVariant   ProductVariant `json:"product_variant" gorm:"foreignKey:ProductVariantID"`
+
+	Quantity        int     `gorm:"not null" json:"quantity"`
+	ListUnitPrice   float64 `json:"list_unit_price"`   // Price at time of request
+	QuotedUnitPrice float64 `json:"quoted_unit_price"` // Price offered by the admin
+	TotalPrice      float64 `json:"total_price"`       // QuotedUnitPrice (or ListUnitPrice before a response) * Quantity
+}
+
+// IsExpired reports whether a quoted offer has passed its validity window
+func (q *Quote) IsExpired(now time.Time) bool {
+	return q.Status == QuoteStatusQuoted && q.ValidUntil != nil && now.After(*q.ValidUntil)
+}
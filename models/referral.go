@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReferralStatus string
+
+const (
+	ReferralSignedUp ReferralStatus = "signed_up" // the referee has an account
+	ReferralOrdered  ReferralStatus = "ordered"   // the referee has placed their first order
+	ReferralRewarded ReferralStatus = "rewarded"  // a qualifying order was delivered and rewards issued
+	ReferralRejected ReferralStatus = "rejected"  // flagged by the anti-abuse checks; an admin can approve it
+)
+
+// Referral attributes a user who signed up with a referral code to the user
+// who shared it
+type Referral struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	ReferrerID     uint           `gorm:"not null;index" json:"referrer_id"`
+	Referrer       *User          `json:"referrer,omitempty"`
+	RefereeID      uint           `gorm:"not null;uniqueIndex" json:"referee_id"` // a user is referred at most once
+	Referee        *User          `json:"referee,omitempty"`
+	Code           string         `gorm:"type:varchar(16);not null" json:"code"`
+	Status         ReferralStatus `gorm:"type:varchar(10);not null;index" json:"status"`
+	FirstOrderID   *uint          `json:"first_order_id,omitempty"`
+	OrderID        *uint          `json:"order_id,omitempty"` // the qualifying order
+	OrderedAt      *time.Time     `json:"ordered_at,omitempty"`
+	RewardedAt     *time.Time     `json:"rewarded_at,omitempty"`
+	RejectReason   string         `json:"reject_reason,omitempty"`
+	ApprovedByID   *uint          `json:"approved_by_id,omitempty"` // an admin cleared the anti-abuse checks
+	RewardType     string         `json:"reward_type,omitempty"`    // "points" or "coupon"
+	ReferrerReward float64        `json:"referrer_reward"`          // points, or coupon pounds
+	RefereeReward  float64        `json:"referee_reward"`
+	SignupIP       string         `json:"-"`
+	SignupAgent    string         `json:"-"`
+}
+
+// Coupon is a single-use discount code, issued to a user as a reward
+type Coupon struct {
+	gorm.Model
+	Code           string     `gorm:"type:varchar(20);uniqueIndex;not null" json:"code"`
+	UserID         *uint      `gorm:"index" json:"user_id,omitempty"` // only this user may use it
+	Amount         float64    `gorm:"not null" json:"amount"`         // off the goods, in pounds
+	MinOrderAmount float64    `json:"min_order_amount"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	UsedAt         *time.Time `json:"used_at,omitempty"`
+	OrderID        *uint      `json:"order_id,omitempty"`
+	ReferralID     *uint      `json:"referral_id,omitempty"`
+	Description    string     `json:"description"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a long-lived token used to obtain new access tokens. Each
+// token represents a login session on one device and is rotated on every use.
+type RefreshToken struct {
+	gorm.Model
+	UserID       uint       `json:"user_id" gorm:"index;not null"`
+	User         *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	FamilyID     string     `json:"-" gorm:"index;not null"` // shared by all rotations of one session
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"index;not null"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"-"` // set when the token was rotated
+	UserAgent    string     `json:"user_agent"`
+	IPAddress    string     `json:"ip_address"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	MFAVerified  bool       `json:"mfa_verified"` // session was opened with a second factor
+}
+
+// IsActive reports whether the token can still be exchanged
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
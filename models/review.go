@@ -37,6 +37,11 @@ type ProductReview struct {
 	ModeratedAt      *time.Time   `json:"moderated_at"`
 	ModerationReason string       `json:"moderation_reason" validate:"max=500"`
 
+	// Moderation queue assignment
+	AssignedTo *uint      `json:"assigned_to,omitempty" gorm:"index"`
+	Assignee   *User      `json:"assignee,omitempty" gorm:"foreignKey:AssignedTo"`
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+
 	// Engagement
 	HelpfulCount int `json:"helpful_count" gorm:"default:0"`
 
@@ -61,6 +66,26 @@ type SellerResponse struct {
 	UserID          uint   `json:"user_id" gorm:"index"`                 // Seller user ID
 	User            User   `json:"user"`
 	Content         string `json:"content" validate:"required,max=500"`
+
+	// Replies holds the review author's follow-up and the seller's answer to it
+	Replies []ReviewReply `json:"replies,omitempty" gorm:"foreignKey:SellerResponseID"`
+}
+
+// ReviewReply is an entry in the short thread below a seller response: the
+// review author may follow up once, and the seller may answer that follow-up
+// once. Each entry is moderated before it is shown.
+type ReviewReply struct {
+	gorm.Model
+	SellerResponseID uint         `json:"seller_response_id" gorm:"uniqueIndex:idx_review_replies_thread"` // One follow-up and one answer per response
+	ParentID         *uint        `json:"parent_id" gorm:"index"`                                          // the follow-up a seller answer replies to
+	UserID           uint         `json:"user_id" gorm:"index"`
+	User             User         `json:"user"`
+	IsFromSeller     bool         `json:"is_from_seller" gorm:"uniqueIndex:idx_review_replies_thread"`
+	Content          string       `json:"content" validate:"required,max=500"`
+	Status           ReviewStatus `json:"status" gorm:"type:varchar(20);default:'PENDING'"`
+	ModeratedBy      *uint        `json:"moderated_by"`
+	ModeratedAt      *time.Time   `json:"moderated_at"`
+	ModerationReason string       `json:"moderation_reason" validate:"max=500"`
 }
 
 // ReviewHelpful tracks whether users found a review helpful or not
@@ -80,6 +105,16 @@ type ProductRating struct {
 	RatingBreakdown  string  `json:"rating_breakdown"` // JSON: {"1":0,"2":1,"3":2,"4":5,"5":10}
 }
 
+// ProductOverallRating stores the rating of a product across the ratings of
+// its variants, so product pages can show an overall score in one read
+type ProductOverallRating struct {
+	gorm.Model
+	ProductID       uint    `json:"product_id" gorm:"uniqueIndex"`
+	AverageRating   float64 `json:"average_rating" gorm:"type:decimal(3,1);default:0.0"`
+	TotalReviews    int     `json:"total_reviews" gorm:"default:0"`
+	RatingBreakdown string  `json:"rating_breakdown"` // JSON: {"1":0,"2":1,"3":2,"4":5,"5":10}
+}
+
 // TableName overrides the table name for ProductReview
 func (ProductReview) TableName() string {
 	return "product_reviews"
@@ -95,6 +130,11 @@ func (SellerResponse) TableName() string {
 	return "seller_responses"
 }
 
+// TableName overrides the table name for ReviewReply
+func (ReviewReply) TableName() string {
+	return "review_replies"
+}
+
 // TableName overrides the table name for ReviewHelpful
 func (ReviewHelpful) TableName() string {
 	return "review_helpful_votes"
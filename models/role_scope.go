@@ -0,0 +1,8 @@
+package models
+
+// RoleScope grants a permission scope to every user of a role
+type RoleScope struct {
+	ID    uint     `gorm:"primarykey" json:"id"`
+	Role  UserType `gorm:"type:varchar(10);not null;uniqueIndex:idx_role_scope" json:"role"`
+	Scope string   `gorm:"not null;uniqueIndex:idx_role_scope" json:"scope"`
+}
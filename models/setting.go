@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Setting is a business setting admins edit at runtime, such as the company
+// name shown in emails. Settings without a row keep their default.
+type Setting struct {
+	Key         string    `gorm:"primaryKey;size:64" json:"key"`
+	Value       string    `gorm:"not null" json:"value"`
+	UpdatedByID *uint     `json:"updated_by_id,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
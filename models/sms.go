@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// SMSStatus is the outcome of handing a text message to the provider
+type SMSStatus string
+
+const (
+	SMSStatusSent        SMSStatus = "sent"
+	SMSStatusDelivered   SMSStatus = "delivered"
+	SMSStatusRead        SMSStatus = "read" // WhatsApp only
+	SMSStatusUndelivered SMSStatus = "undelivered"
+	SMSStatusFailed      SMSStatus = "failed"
+)
+
+// Channels customers can get order updates on
+const (
+	ChannelEmail    = "email"
+	ChannelSMS      = "sms"
+	ChannelWhatsApp = "whatsapp"
+)
+
+// SMSMessage records a text message sent, for audit. Verification codes are
+// masked in Body. Status follows the provider's delivery reports.
+type SMSMessage struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Channel    string    `json:"channel" gorm:"type:varchar(10);not null;default:'sms'"` // sms or whatsapp
+	UserID     *uint     `json:"user_id" gorm:"index"`
+	To         string    `json:"to" gorm:"type:varchar(20);not null;index"`
+	Template   string    `json:"template" gorm:"type:varchar(50)"`
+	Locale     string    `json:"locale" gorm:"type:varchar(8)"`
+	Body       string    `json:"body"`
+	Provider   string    `json:"provider" gorm:"type:varchar(20)"`
+	ProviderID string    `json:"provider_id" gorm:"index"` // the provider's ID for the message
+	Status     SMSStatus `json:"status" gorm:"type:varchar(10);not null"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Purposes of phone verifications
+const (
+	PhoneVerificationSignup   = "signup"
+	PhoneVerificationCheckout = "checkout"
+)
+
+// PhoneVerification is a one-time code texted to a phone number to prove the
+// user has it
+type PhoneVerification struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	Phone      string     `json:"phone" gorm:"type:varchar(20);not null;index"` // E.164
+	Purpose    string     `json:"purpose" gorm:"type:varchar(20);not null"`
+	CodeHash   string     `json:"-" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	Attempts   int        `json:"attempts" gorm:"not null;default:0"` // wrong codes tried
+	VerifiedAt *time.Time `json:"verified_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StockCountStatus is the state of a stocktake
+type StockCountStatus string
+
+const (
+	StockCountStatusOpen      StockCountStatus = "OPEN"      // counts are being recorded
+	StockCountStatusApproved  StockCountStatus = "APPROVED"  // variances have been posted to stock
+	StockCountStatusCancelled StockCountStatus = "CANCELLED" // closed without changing stock
+)
+
+// StockCount is a stocktake or cycle count of one warehouse. Counted
+// quantities are compared with the system quantity and, once the count is
+// approved, the differences are posted as stock adjustments.
+type StockCount struct {
+	gorm.Model
+	WarehouseID  uint             `gorm:"not null;index" json:"warehouse_id"`
+	Warehouse    *Warehouse       `json:"warehouse,omitempty"`
+	Status       StockCountStatus `gorm:"type:varchar(20);not null;default:'OPEN';index" json:"status"`
+	Notes        string           `json:"notes"`
+	OpenedByID   *uint            `json:"opened_by_id"`
+	OpenedBy     *User            `gorm:"foreignKey:OpenedByID" json:"opened_by,omitempty"`
+	ApprovedByID *uint            `json:"approved_by_id"`
+	ApprovedBy   *User            `gorm:"foreignKey:ApprovedByID" json:"approved_by,omitempty"`
+	ApprovedAt   *time.Time       `json:"approved_at"`
+	CancelledAt  *time.Time       `json:"cancelled_at"`
+	Lines        []StockCountLine `gorm:"foreignKey:StockCountID" json:"lines,omitempty"`
+}
+
+// StockCountLine is the count of one batch. Lines are created for every batch
+// in the warehouse when the count is opened; batches found that the system
+// does not know about get a line without an inventory item.
+type StockCountLine struct {
+	gorm.Model
+	StockCountID     uint            `gorm:"not null;index" json:"stock_count_id"`
+	InventoryItemID  *uint           `gorm:"index" json:"inventory_item_id"`
+	ProductVariantID uint            `gorm:"not null" json:"product_variant_id"`
+	ProductVariant   *ProductVariant `json:"product_variant,omitempty"`
+	BatchNumber      string          `json:"batch_number"`
+	SystemQuantity   int             `gorm:"not null;default:0" json:"system_quantity"` // quantity in stock when counted
+	CountedQuantity  *int            `json:"counted_quantity"`                          // nil until counted
+	Variance         int             `gorm:"not null;default:0" json:"variance"`        // counted minus system quantity
+	CountedByID      *uint           `json:"counted_by_id"`
+	CountedAt        *time.Time      `json:"counted_at"`
+}
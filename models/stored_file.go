@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// StoredFile is a file uploaded to a storage backend. The file cleanup job
+// marks the files still referenced and deletes the others, with their row.
+type StoredFile struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+	Backend   string     `json:"backend" gorm:"not null;uniqueIndex:idx_stored_file"` // appwrite or gcs
+	Key       string     `json:"key" gorm:"not null;uniqueIndex:idx_stored_file"`     // identifier in the backend
+	URL       string     `json:"url" gorm:"index"`                                    // URL referencing rows store instead of the key
+	Source    string     `json:"source"`                                              // what it was uploaded for, e.g. product_image
+	MarkedAt  *time.Time `json:"marked_at"`                                           // when it was last found referenced
+}
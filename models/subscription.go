@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionInterval string
+
+const (
+	SubscriptionWeekly   SubscriptionInterval = "WEEKLY"
+	SubscriptionBiweekly SubscriptionInterval = "BIWEEKLY"
+	SubscriptionMonthly  SubscriptionInterval = "MONTHLY"
+)
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusActive    SubscriptionStatus = "ACTIVE"    // orders are placed on schedule
+	SubscriptionStatusPaused    SubscriptionStatus = "PAUSED"    // no orders until resumed, by the customer or on ResumeAt
+	SubscriptionStatusCancelled SubscriptionStatus = "CANCELLED" // ended by the customer
+)
+
+// Subscription places an order of the same items for a customer every
+// interval. Orders are paid with the customer's Revolut saved payment method
+// when they gave one; otherwise they are emailed a link to pay.
+type Subscription struct {
+	gorm.Model
+	UserID   uint                 `gorm:"not null;index" json:"user_id"`
+	User     User                 `json:"-"`
+	Status   SubscriptionStatus   `gorm:"type:varchar(20);not null;index" json:"status"`
+	Interval SubscriptionInterval `gorm:"type:varchar(20);not null" json:"interval"`
+
+	// When the next order is placed, moved on by an interval on every run and skip
+	NextRunAt time.Time `gorm:"not null;index" json:"next_run_at"`
+
+	ShippingAddressID uint     `gorm:"not null" json:"shipping_address_id"`
+	ShippingAddress   *Address `json:"shipping_address,omitempty" gorm:"foreignKey:ShippingAddressID"`
+	BillingAddressID  *uint    `json:"billing_address_id,omitempty"`
+	BillingAddress    *Address `json:"billing_address,omitempty" gorm:"foreignKey:BillingAddressID"`
+	CustomerNotes     string   `json:"customer_notes"`
+
+	// Revolut saved payment method the orders are paid with
+	RevolutCustomerID    string `gorm:"size:100" json:"revolut_customer_id,omitempty"`
+	SavedPaymentMethodID string `gorm:"size:100" json:"saved_payment_method_id,omitempty"`
+
+	RemindedAt  *time.Time `json:"reminded_at,omitempty"` // when the reminder of the next order was sent
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	LastOrderID *uint      `json:"last_order_id,omitempty"`
+	FailedRuns  int        `gorm:"not null;default:0" json:"failed_runs"` // runs in a row that placed no order
+	LastError   string     `json:"last_error,omitempty"`
+	PausedAt    *time.Time `json:"paused_at,omitempty"`
+	ResumeAt    *time.Time `json:"resume_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+
+	Items []SubscriptionItem `json:"items"`
+}
+
+type SubscriptionItem struct {
+	gorm.Model
+	SubscriptionID uint `gorm:"not null;index" json:"subscription_id"`
+
+	ProductVariantID uint           `gorm:"not null" json:"product_variant_id"`
+	ProductVariant   ProductVariant `json:"product_variant" gorm:"foreignKey:ProductVariantID"`
+	Quantity         int            `gorm:"not null" json:"quantity"`
+}
+
+// PaysWithSavedMethod reports whether the subscription's orders are paid
+// without the customer
+func (s *Subscription) PaysWithSavedMethod() bool {
+	return s.RevolutCustomerID != "" && s.SavedPaymentMethodID != ""
+}
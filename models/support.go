@@ -13,6 +13,9 @@ type SupportTicket struct {
 	User            *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	OrderID         *uint          `json:"order_id,omitempty"`
 	Order           *Order         `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+	DisputeID       *uint          `json:"dispute_id,omitempty" gorm:"index"`
+	Dispute         *Dispute       `json:"dispute,omitempty" gorm:"foreignKey:DisputeID"`
+	MergedIntoID    *uint          `json:"merged_into_id,omitempty" gorm:"index"`
 	Title           string         `json:"title" gorm:"not null"`
 	Description     string         `json:"description" gorm:"type:text;not null"`
 	Category        TicketCategory `json:"category" gorm:"type:varchar(50);not null"`
@@ -30,6 +33,13 @@ type SupportTicket struct {
 	EscalatedBy     *uint          `json:"escalated_by,omitempty"`
 	EscalatedByUser *User          `json:"escalated_by_user,omitempty" gorm:"foreignKey:EscalatedBy"`
 
+	// SLA deadlines, set from the matching SLAPolicy
+	FirstResponseDueAt    *time.Time `json:"first_response_due_at" gorm:"index"`
+	ResolutionDueAt       *time.Time `json:"resolution_due_at" gorm:"index"`
+	FirstRespondedAt      *time.Time `json:"first_responded_at"`
+	FirstResponseBreached bool       `json:"first_response_breached" gorm:"default:false"`
+	ResolutionBreached    bool       `json:"resolution_breached" gorm:"default:false"`
+
 	// Attachments and responses
 	Attachments []TicketAttachment `json:"attachments" gorm:"foreignKey:TicketID"`
 	Responses   []TicketResponse   `json:"responses" gorm:"foreignKey:TicketID"`
@@ -72,6 +82,17 @@ const (
 	TicketStatusClosed     TicketStatus = "CLOSED"
 )
 
+// TicketMerge records a duplicate ticket merged into another ticket
+type TicketMerge struct {
+	ID               uint      `json:"id" gorm:"primarykey"`
+	CreatedAt        time.Time `json:"created_at"`
+	SourceTicketID   uint      `json:"source_ticket_id" gorm:"index;not null"`
+	TargetTicketID   uint      `json:"target_ticket_id" gorm:"index;not null"`
+	MergedBy         uint      `json:"merged_by"`
+	ResponsesMoved   int64     `json:"responses_moved"`
+	AttachmentsMoved int64     `json:"attachments_moved"`
+}
+
 // TicketAttachment represents files attached to a ticket
 type TicketAttachment struct {
 	gorm.Model
@@ -93,6 +114,8 @@ type TicketResponse struct {
 	Message     string         `json:"message" gorm:"type:text;not null"`
 	IsInternal  bool           `json:"is_internal" gorm:"default:false"`
 	IsFromAdmin bool           `json:"is_from_admin" gorm:"default:false"`
+	// EmailMessageID is the Message-ID of the inbound email the response came from
+	EmailMessageID string `json:"email_message_id,omitempty" gorm:"type:varchar(255);index"`
 }
 
 // AbuseReport represents a report of abuse or inappropriate content
@@ -252,6 +275,13 @@ type Dispute struct {
 	EscalatedBy     *uint           `json:"escalated_by,omitempty"`
 	EscalatedByUser *User           `json:"escalated_by_user,omitempty" gorm:"foreignKey:EscalatedBy"`
 
+	// SLA deadlines, set from the matching SLAPolicy
+	FirstResponseDueAt    *time.Time `json:"first_response_due_at" gorm:"index"`
+	ResolutionDueAt       *time.Time `json:"resolution_due_at" gorm:"index"`
+	FirstRespondedAt      *time.Time `json:"first_responded_at"`
+	FirstResponseBreached bool       `json:"first_response_breached" gorm:"default:false"`
+	ResolutionBreached    bool       `json:"resolution_breached" gorm:"default:false"`
+
 	// Attachments and responses
 	Attachments []DisputeAttachment `json:"attachments" gorm:"foreignKey:DisputeID"`
 	Responses   []DisputeResponse   `json:"responses" gorm:"foreignKey:DisputeID"`
@@ -314,4 +344,6 @@ type DisputeResponse struct {
 	Message     string   `json:"message" gorm:"type:text;not null"`
 	IsInternal  bool     `json:"is_internal" gorm:"default:false"`
 	IsFromAdmin bool     `json:"is_from_admin" gorm:"default:false"`
+	// EmailMessageID is the Message-ID of the inbound email the response came from
+	EmailMessageID string `json:"email_message_id,omitempty" gorm:"type:varchar(255);index"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// SLASubject is the kind of support record an SLA policy applies to
+type SLASubject string
+
+const (
+	SLASubjectTicket  SLASubject = "ticket"
+	SLASubjectDispute SLASubject = "dispute"
+)
+
+// SLAPolicy sets the first-response and resolution targets for support
+// tickets or disputes. An empty Category or Priority matches any value; when
+// several policies match, the most specific one wins.
+type SLAPolicy struct {
+	ID                   uint       `gorm:"primarykey" json:"id"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	Subject              SLASubject `gorm:"type:varchar(20);not null;uniqueIndex:idx_sla_policy_scope" json:"subject"`
+	Category             string     `gorm:"type:varchar(50);uniqueIndex:idx_sla_policy_scope" json:"category"`
+	Priority             string     `gorm:"type:varchar(20);uniqueIndex:idx_sla_policy_scope" json:"priority"`
+	FirstResponseMinutes int        `gorm:"not null" json:"first_response_minutes"`
+	ResolutionMinutes    int        `gorm:"not null" json:"resolution_minutes"`
+	IsActive             bool       `json:"is_active"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SupportUpload is a file uploaded for a support ticket, dispute or abuse
+// report. Uploads that are never attached are removed by the orphan cleanup job.
+type SupportUpload struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	FileID    string    `json:"-" gorm:"not null"` // identifier in the storage backend
+	FileName  string    `json:"file_name" gorm:"not null"`
+	FileURL   string    `json:"file_url" gorm:"not null;index"`
+	FileSize  int64     `json:"file_size"`
+	FileType  string    `json:"file_type"`
+}
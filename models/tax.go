@@ -0,0 +1,15 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// TaxRate is a configurable VAT rate for a country
+type TaxRate struct {
+	gorm.Model
+	CountryCode string  `gorm:"type:varchar(2);uniqueIndex;not null" json:"country_code"` // ISO 3166-1 alpha-2, e.g. "GB"
+	CountryName string  `json:"country_name"`                                             // matched case-insensitively against address countries
+	Name        string  `json:"name"`                                                     // e.g. "UK Standard VAT"
+	Rate        float64 `gorm:"not null" json:"rate"`                                     // percentage, e.g. 20 for 20%
+	IsActive    bool    `gorm:"default:true" json:"is_active"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ProductTranslation holds a product's name and description in a locale
+// other than the catalog's default. Empty fields fall back to the product's own.
+type ProductTranslation struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	ProductID   uint      `json:"product_id" gorm:"not null;uniqueIndex:idx_product_translation"`
+	Locale      string    `json:"locale" gorm:"type:varchar(8);not null;uniqueIndex:idx_product_translation"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+}
+
+// CategoryTranslation holds a category's name and description in a locale
+// other than the catalog's default. Empty fields fall back to the category's own.
+type CategoryTranslation struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CategoryID  uint      `json:"category_id" gorm:"not null;uniqueIndex:idx_category_translation"`
+	Locale      string    `json:"locale" gorm:"type:varchar(8);not null;uniqueIndex:idx_category_translation"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+}
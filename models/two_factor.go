@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorAuth holds a user's TOTP enrollment. The secret is stored encrypted.
+type TwoFactorAuth struct {
+	gorm.Model
+	UserID          uint       `json:"user_id" gorm:"uniqueIndex;not null"`
+	SecretEncrypted string     `json:"-" gorm:"not null"`
+	Enabled         bool       `json:"enabled" gorm:"default:false"`
+	EnabledAt       *time.Time `json:"enabled_at"`
+	LastUsedStep    int64      `json:"-"` // last accepted time step, prevents code replay
+}
+
+// TwoFactorBackupCode is a single-use recovery code, stored hashed
+type TwoFactorBackupCode struct {
+	gorm.Model
+	UserID   uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// TwoFactorChallenge is issued after a correct password when 2FA is enabled and
+// must be completed with a TOTP or backup code to finish logging in
+type TwoFactorChallenge struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index;not null"`
+	Attempts  int        `json:"attempts"`
+	UsedAt    *time.Time `json:"used_at"`
+	UserAgent string     `json:"user_agent"`
+	IPAddress string     `json:"ip_address"`
+}
@@ -13,6 +13,8 @@ const (
 	Wholesaler UserType = "WHOLESALER"
 	Vendor     UserType = "VENDOR"
 	Admin      UserType = "ADMIN"
+	// SupportAgent handles customer support without full admin access
+	SupportAgent UserType = "SUPPORT"
 )
 
 type User struct {
@@ -27,9 +29,24 @@ type User struct {
 	IsActive  bool      `gorm:"default:true" json:"is_active"`
 	LastLogin time.Time `json:"last_login"`
 
+	VerifiedAt      *time.Time `json:"verified_at"`       // when the user confirmed their email address
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at"` // when the user confirmed Phone with a text message code
+
+	TwoFactorEnabled bool `gorm:"default:false" json:"two_factor_enabled"`
+	MarketingOptOut  bool `gorm:"default:false" json:"marketing_opt_out"` // unsubscribed from campaigns
+
+	Locale string `gorm:"type:varchar(8);not null;default:'en'" json:"locale"` // language of the emails sent to the user: en, fr or ar
+
+	OrderUpdatesChannel string `gorm:"type:varchar(10);not null;default:'email'" json:"order_updates_channel"` // email, sms or whatsapp; texts need a verified phone
+
+	StrikeCount int `gorm:"default:0" json:"strike_count"` // active strikes, see UserStrike
+
+	ReferralCode *string `gorm:"type:varchar(16);uniqueIndex" json:"referral_code,omitempty"` // created the first time the user asks for it
+
 	// B2B specific fields
-	CompanyID *uint  `json:"company_id"`
-	Role      string `json:"role"`
+	CompanyID  *uint   `json:"company_id"`
+	Role       string  `json:"role"`        // company role, owner or buyer
+	SpendLimit float64 `json:"spend_limit"` // most a company buyer may order per calendar month; 0 for no limit
 
 	// Addresses
 	Addresses []*Address `json:"addresses" gorm:"foreignKey:UserID"`
@@ -48,6 +65,10 @@ type Company struct {
 	CreditLimit        float64 `json:"credit_limit"`
 	PaymentTerms       int     `json:"payment_terms"` // in days
 
+	// Orders by buyers above this amount wait for an owner's approval; 0
+	// for none
+	ApprovalThreshold float64 `json:"approval_threshold"`
+
 	// Address
 	AddressID uint `json:"address_id"`
 
@@ -62,8 +83,18 @@ type Address struct {
 	City           string `gorm:"not null" json:"city"`
 	State          string `json:"state"`
 	PostalCode     string `gorm:"not null" json:"postal_code"`
-	Country        string `gorm:"not null" json:"country"`
-	IsDefault      bool   `gorm:"default:false" json:"is_default"`
+	Country        string `gorm:"not null" json:"country"` // ISO 3166-1 alpha-2 code when saved by a user
+	Label          string `json:"label"`                   // e.g. "Home" or "Office"
+	Phone          string `json:"phone"`
+
+	// IsDefault marks the user's default shipping address
+	IsDefault        bool `gorm:"default:false" json:"is_default"`
+	IsDefaultBilling bool `gorm:"default:false" json:"is_default_billing"`
+
+	// Set by the geocoding provider, when one is configured
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+	GeocodedAt *time.Time `json:"geocoded_at,omitempty"`
 
 	// Relations
 	UserID *uint `json:"user_id"`
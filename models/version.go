@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict is returned by SaveVersioned when the record was edited
+// by someone else since it was read
+var ErrVersionConflict = errors.New("the record was modified by someone else")
+
+// SaveVersioned saves a versioned record, a product, variant or inventory
+// item, read at *version and increments its version. It only updates the row
+// while it is still at that version, and otherwise returns ErrVersionConflict
+// and leaves the record as it was. Columns limits the update to those columns,
+// all of them are saved by default. Associations are not saved.
+func SaveVersioned(tx *gorm.DB, value interface{}, version *int, columns ...string) error {
+	read := *version
+	*version = read + 1
+
+	query := tx.Model(value).Omit(clause.Associations).Where("version = ?", read)
+	if len(columns) == 0 {
+		query = query.Select("*")
+	} else {
+		query = query.Select(append(columns, "version"))
+	}
+	result := query.Updates(value)
+	if result.Error != nil || result.RowsAffected == 0 {
+		*version = read
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
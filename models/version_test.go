@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSaveVersioned(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Product{}, &ProductVariant{}, &Warehouse{}, &InventoryItem{}))
+
+	product := Product{Name: "Halloumi"}
+	require.NoError(t, db.Create(&product).Error)
+	assert.Equal(t, 1, product.Version)
+
+	var first, second Product
+	require.NoError(t, db.First(&first, product.ID).Error)
+	require.NoError(t, db.First(&second, product.ID).Error)
+
+	first.Name = "Halloumi 250g"
+	require.NoError(t, SaveVersioned(db, &first, &first.Version))
+	assert.Equal(t, 2, first.Version)
+
+	// The second edit was made on version 1 and would overwrite the first
+	second.Description = "Grilling cheese"
+	assert.ErrorIs(t, SaveVersioned(db, &second, &second.Version), ErrVersionConflict)
+	assert.Equal(t, 1, second.Version)
+
+	var saved Product
+	require.NoError(t, db.First(&saved, product.ID).Error)
+	assert.Equal(t, "Halloumi 250g", saved.Name)
+	assert.Empty(t, saved.Description)
+	assert.Equal(t, 2, saved.Version)
+
+	// Saving some columns leaves the others as they are in the database
+	item := InventoryItem{ProductVariantID: 1, WarehouseID: 1, Quantity: 10}
+	require.NoError(t, db.Create(&item).Error)
+	require.NoError(t, db.Model(&InventoryItem{}).Where("id = ?", item.ID).Update("quantity", 4).Error)
+	item.BinLocation = "A-01"
+	require.NoError(t, SaveVersioned(db, &item, &item.Version, "bin_location"))
+	var stored InventoryItem
+	require.NoError(t, db.First(&stored, item.ID).Error)
+	assert.Equal(t, "A-01", stored.BinLocation)
+	assert.Equal(t, 4, stored.Quantity)
+	assert.Equal(t, 2, stored.Version)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription registers an external URL to receive webhook events.
+// Deliveries are signed with Secret, which is only returned on creation and
+// when it is rotated.
+type WebhookSubscription struct {
+	gorm.Model
+	URL         string   `gorm:"size:500;not null" json:"url"`
+	Description string   `json:"description"`
+	Events      []string `gorm:"serializer:json;type:text" json:"events"` // event names, e.g. order.paid
+	Secret      string   `gorm:"size:100;not null" json:"-"`
+	IsActive    bool     `gorm:"default:true;index" json:"is_active"`
+	CreatedByID uint     `json:"created_by_id"`
+}
+
+// Subscribes reports whether the subscription receives event
+func (s *WebhookSubscription) Subscribes(event string) bool {
+	for _, subscribed := range s.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery logs one attempt to deliver an event to a subscription
+type WebhookDelivery struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	SubscriptionID  uint      `gorm:"not null;index" json:"subscription_id"`
+	OutboxMessageID *uint     `gorm:"index" json:"outbox_message_id"` // nil for test deliveries
+	Event           string    `gorm:"size:100;index" json:"event"`
+	Attempt         int       `json:"attempt"`
+	StatusCode      int       `json:"status_code"` // 0 when no response was received
+	Success         bool      `gorm:"index" json:"success"`
+	Error           string    `json:"error"`
+	DurationMs      int64     `json:"duration_ms"`
+	CreatedAt       time.Time `gorm:"index" json:"created_at"`
+}
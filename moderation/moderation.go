@@ -0,0 +1,94 @@
+// Package moderation enforces the content guidelines. It takes down reported
+// content and gives users strikes for it; once a user's active strikes reach
+// a configured threshold they are restricted from reviewing, messaging or
+// logging in. Admins can also issue and revoke strikes and restrict users
+// directly.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoTarget is returned when a report is about neither a review nor a product
+	ErrNoTarget = errors.New("the report is not about a review or a product")
+	// ErrAlreadyEnforced is returned when a report's content was already taken down
+	ErrAlreadyEnforced = errors.New("the reported content has already been taken down")
+	ErrStrikeNotFound  = errors.New("strike not found")
+	ErrStrikeRevoked   = errors.New("strike has already been revoked")
+	// ErrAlreadyRestricted is returned when a user already has an active
+	// restriction of the capability
+	ErrAlreadyRestricted    = errors.New("user is already restricted")
+	ErrRestrictionNotFound  = errors.New("restriction not found")
+	ErrRestrictionNotActive = errors.New("restriction is no longer active")
+	ErrUnknownCapability    = errors.New("unknown capability")
+)
+
+// Service takes down reported content and manages user strikes and
+// restrictions
+type Service struct {
+	db     *gorm.DB
+	config cfg.ModerationConfig
+	emails *email.EmailTriggerService
+	now    func() time.Time
+}
+
+// NewService creates a moderation service. Without a config strikes never
+// expire and never restrict users automatically.
+func NewService(db *gorm.DB, config *cfg.ModerationConfig, emails *email.EmailTriggerService) *Service {
+	s := &Service{db: db, emails: emails, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	return s
+}
+
+// ActiveRestriction returns the restriction stopping a user from using a
+// capability, or nil when they can use it. A ban stops every capability.
+func ActiveRestriction(db *gorm.DB, userID uint, capability models.UserCapability) (*models.UserRestriction, error) {
+	var restriction models.UserRestriction
+	err := db.Where("user_id = ? AND capability IN ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)",
+		userID, []models.UserCapability{capability, models.CapabilityLogin}, time.Now()).
+		Order("expires_at IS NULL DESC, expires_at DESC").
+		First(&restriction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check restrictions: %w", err)
+	}
+	return &restriction, nil
+}
+
+// Standing is a user's strikes and restrictions, newest first
+type Standing struct {
+	UserID       uint                     `json:"user_id"`
+	StrikeCount  int                      `json:"strike_count"` // active strikes
+	Strikes      []models.UserStrike      `json:"strikes"`
+	Restrictions []models.UserRestriction `json:"restrictions"`
+}
+
+// Standing returns the strikes and restrictions of a user
+func (s *Service) Standing(ctx context.Context, userID uint) (*Standing, error) {
+	db := s.db.WithContext(ctx)
+	var user models.User
+	if err := db.Select("id", "strike_count").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	standing := &Standing{UserID: user.ID, StrikeCount: user.StrikeCount}
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&standing.Strikes).Error; err != nil {
+		return nil, fmt.Errorf("failed to load strikes: %w", err)
+	}
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&standing.Restrictions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load restrictions: %w", err)
+	}
+	return standing, nil
+}
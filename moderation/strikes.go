@@ -0,0 +1,301 @@
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// StrikeInput describes a strike to issue
+type StrikeInput struct {
+	UserID              uint
+	Source              models.StrikeSource
+	EnforcementActionID *uint
+	ReviewID            *uint
+	Reason              string
+	IssuedBy            uint
+	ExpiresAt           *time.Time // defaults to the configured strike expiry
+}
+
+// RestrictionInput describes a restriction an admin applies
+type RestrictionInput struct {
+	UserID     uint
+	Capability models.UserCapability
+	Reason     string
+	IssuedBy   uint
+	ExpiresAt  *time.Time // nil until lifted
+}
+
+// capabilityDescriptions say what a user can no longer do, for emails
+var capabilityDescriptions = map[models.UserCapability]string{
+	models.CapabilityReview:  "write reviews",
+	models.CapabilityMessage: "reply to reviews and review responses",
+	models.CapabilityLogin:   "log in to your account",
+}
+
+// IssueStrike gives a user a strike in tx and applies the restrictions whose
+// threshold their active strikes have reached. The user is emailed about the
+// strike and any restriction it led to.
+func (s *Service) IssueStrike(tx *gorm.DB, in StrikeInput) (*models.UserStrike, error) {
+	now := s.now()
+	strike := &models.UserStrike{
+		UserID:              in.UserID,
+		Source:              in.Source,
+		EnforcementActionID: in.EnforcementActionID,
+		ReviewID:            in.ReviewID,
+		Reason:              in.Reason,
+		IssuedBy:            in.IssuedBy,
+		ExpiresAt:           in.ExpiresAt,
+	}
+	if strike.ExpiresAt == nil && s.config.StrikeExpiryDays > 0 {
+		expiresAt := now.AddDate(0, 0, s.config.StrikeExpiryDays)
+		strike.ExpiresAt = &expiresAt
+	}
+	if err := tx.Create(strike).Error; err != nil {
+		return nil, fmt.Errorf("failed to issue strike: %w", err)
+	}
+
+	count, err := s.syncStrikeCount(tx, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	restrictions, err := s.applyThresholds(tx, in.UserID, count, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(tx, in.UserID, strikeNotice(in.Reason, count, restrictions))
+	return strike, nil
+}
+
+// RevokeStrike revokes a strike of a user in tx. Restrictions the strike led
+// to stay in force until they expire or are lifted.
+func (s *Service) RevokeStrike(tx *gorm.DB, userID, strikeID, adminID uint) (*models.UserStrike, error) {
+	var strike models.UserStrike
+	if err := tx.Where("id = ? AND user_id = ?", strikeID, userID).First(&strike).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStrikeNotFound
+		}
+		return nil, fmt.Errorf("failed to load strike: %w", err)
+	}
+	if strike.RevokedAt != nil {
+		return nil, ErrStrikeRevoked
+	}
+
+	now := s.now()
+	if err := tx.Model(&strike).Updates(map[string]interface{}{
+		"revoked_at": now,
+		"revoked_by": adminID,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke strike: %w", err)
+	}
+	if _, err := s.syncStrikeCount(tx, userID); err != nil {
+		return nil, err
+	}
+	return &strike, nil
+}
+
+// Restrict stops a user from using a capability in tx and emails them about
+// it. Restricting login bans the user and ends their sessions.
+func (s *Service) Restrict(tx *gorm.DB, in RestrictionInput) (*models.UserRestriction, error) {
+	if _, ok := capabilityDescriptions[in.Capability]; !ok {
+		return nil, ErrUnknownCapability
+	}
+	restriction, err := s.restrict(tx, &models.UserRestriction{
+		UserID:     in.UserID,
+		Capability: in.Capability,
+		Reason:     in.Reason,
+		IssuedBy:   &in.IssuedBy,
+		ExpiresAt:  in.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	tx.Model(&models.User{}).Where("id = ?", in.UserID).Select("strike_count").Scan(&count)
+	s.notify(tx, in.UserID, restrictionNotice(in.Reason, count, []models.UserRestriction{*restriction}))
+	return restriction, nil
+}
+
+// LiftRestriction lifts a restriction of a user in tx
+func (s *Service) LiftRestriction(tx *gorm.DB, userID, restrictionID, adminID uint) (*models.UserRestriction, error) {
+	var restriction models.UserRestriction
+	if err := tx.Where("id = ? AND user_id = ?", restrictionID, userID).First(&restriction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRestrictionNotFound
+		}
+		return nil, fmt.Errorf("failed to load restriction: %w", err)
+	}
+	if !restriction.IsActive(s.now()) {
+		return nil, ErrRestrictionNotActive
+	}
+
+	if err := tx.Model(&restriction).Updates(map[string]interface{}{
+		"lifted_at": s.now(),
+		"lifted_by": adminID,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to lift restriction: %w", err)
+	}
+	return &restriction, nil
+}
+
+// restrict records a restriction unless the user already has an active one
+// of the same capability, revoking the user's sessions for a ban
+func (s *Service) restrict(tx *gorm.DB, restriction *models.UserRestriction) (*models.UserRestriction, error) {
+	var active int64
+	if err := tx.Model(&models.UserRestriction{}).
+		Where("user_id = ? AND capability = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", restriction.UserID, restriction.Capability, s.now()).
+		Count(&active).Error; err != nil {
+		return nil, fmt.Errorf("failed to check restrictions: %w", err)
+	}
+	if active > 0 {
+		return nil, ErrAlreadyRestricted
+	}
+
+	if err := tx.Create(restriction).Error; err != nil {
+		return nil, fmt.Errorf("failed to restrict user: %w", err)
+	}
+	if restriction.Capability == models.CapabilityLogin {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", restriction.UserID).
+			Update("revoked_at", s.now()).Error; err != nil {
+			return nil, fmt.Errorf("failed to end sessions: %w", err)
+		}
+	}
+	return restriction, nil
+}
+
+// syncStrikeCount stores the number of active strikes of a user on the user
+func (s *Service) syncStrikeCount(tx *gorm.DB, userID uint) (int, error) {
+	var count int64
+	if err := tx.Model(&models.UserStrike{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, s.now()).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count strikes: %w", err)
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("strike_count", count).Error; err != nil {
+		return 0, fmt.Errorf("failed to update strike count: %w", err)
+	}
+	return int(count), nil
+}
+
+// applyThresholds restricts the capabilities whose strike threshold count has
+// reached, skipping those the user is already restricted from
+func (s *Service) applyThresholds(tx *gorm.DB, userID uint, count int, now time.Time) ([]models.UserRestriction, error) {
+	thresholds := []struct {
+		capability models.UserCapability
+		strikes    int
+		days       int
+	}{
+		{models.CapabilityReview, s.config.ReviewStrikes, s.config.RestrictionDays},
+		{models.CapabilityMessage, s.config.MessageStrikes, s.config.RestrictionDays},
+		{models.CapabilityLogin, s.config.BanStrikes, s.config.BanDays},
+	}
+
+	var applied []models.UserRestriction
+	for _, threshold := range thresholds {
+		if threshold.strikes <= 0 || count < threshold.strikes {
+			continue
+		}
+		restriction := &models.UserRestriction{
+			UserID:     userID,
+			Capability: threshold.capability,
+			Reason:     fmt.Sprintf("Reached %d active strikes", threshold.strikes),
+			Automatic:  true,
+		}
+		if threshold.days > 0 {
+			expiresAt := now.AddDate(0, 0, threshold.days)
+			restriction.ExpiresAt = &expiresAt
+		}
+		restriction, err := s.restrict(tx, restriction)
+		if errors.Is(err, ErrAlreadyRestricted) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, *restriction)
+	}
+	return applied, nil
+}
+
+// notice is the content of an account enforcement email
+type notice struct {
+	title, message, reason string
+	strikeCount            int
+	expiresAt              *time.Time
+}
+
+// strikeNotice describes a strike and the restrictions it led to
+func strikeNotice(reason string, count int, restrictions []models.UserRestriction) notice {
+	if len(restrictions) > 0 {
+		n := restrictionNotice(reason, count, restrictions)
+		n.message = "Your account has received a strike for breaking our content guidelines. " + n.message
+		return n
+	}
+	return notice{
+		title:       "Your Account Received a Strike",
+		message:     "Your account has received a strike for breaking our content guidelines.",
+		reason:      reason,
+		strikeCount: count,
+	}
+}
+
+// restrictionNotice describes restrictions applied to an account
+func restrictionNotice(reason string, count int, restrictions []models.UserRestriction) notice {
+	n := notice{title: "Your Account Has Been Restricted", reason: reason, strikeCount: count}
+	var actions []string
+	permanent := false
+	for _, restriction := range restrictions {
+		if restriction.Capability == models.CapabilityLogin {
+			n.title = "Your Account Has Been Banned"
+		}
+		actions = append(actions, capabilityDescriptions[restriction.Capability])
+		// The email shows when the longest restriction ends
+		if restriction.ExpiresAt == nil {
+			permanent = true
+		} else if n.expiresAt == nil || restriction.ExpiresAt.After(*n.expiresAt) {
+			n.expiresAt = restriction.ExpiresAt
+		}
+	}
+	if permanent {
+		n.expiresAt = nil
+	}
+	n.message = "You can no longer " + strings.Join(actions, ", ") + "."
+	return n
+}
+
+// notify queues an account enforcement email to the user in tx's outbox;
+// failures are logged and never fail the enforcement
+func (s *Service) notify(tx *gorm.DB, userID uint, n notice) {
+	if s.emails == nil {
+		return
+	}
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		log.Printf("Failed to load user %d for enforcement email: %v", userID, err)
+		return
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	data := map[string]interface{}{
+		"UserName":    name,
+		"Title":       n.title,
+		"Message":     n.message,
+		"Reason":      n.reason,
+		"StrikeCount": n.strikeCount,
+		"ExpiresAt":   "",
+		"subject":     n.title,
+	}
+	if n.expiresAt != nil {
+		data["ExpiresAt"] = n.expiresAt.Format("January 2, 2006")
+	}
+	if err := s.emails.InTx(tx).TriggerAccountEnforcement(user.Email, name, data); err != nil {
+		log.Printf("Failed to queue enforcement email for user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,138 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.UserStrike{}, &models.UserRestriction{}))
+	return db
+}
+
+// setupService stops reviews from 2 strikes and bans from 3; review
+// restrictions last 30 days and bans until lifted
+func setupService(db *gorm.DB) *Service {
+	return NewService(db, &cfg.ModerationConfig{
+		StrikeExpiryDays: 180,
+		ReviewStrikes:    2,
+		BanStrikes:       3,
+		RestrictionDays:  30,
+	}, nil)
+}
+
+func createUser(t *testing.T, db *gorm.DB) models.User {
+	user := models.User{Email: "user@example.com", FirstName: "Amina"}
+	require.NoError(t, db.Create(&user).Error)
+	return user
+}
+
+func issue(t *testing.T, s *Service, userID uint) *models.UserStrike {
+	strike, err := s.IssueStrike(s.db, StrikeInput{UserID: userID, Source: models.StrikeSourceManual, Reason: "Spam", IssuedBy: 99})
+	require.NoError(t, err)
+	return strike
+}
+
+func TestIssueStrikeAppliesThresholds(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+	require.NoError(t, db.Create(&models.RefreshToken{UserID: user.ID, TokenHash: "hash", FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	first := issue(t, s, user.ID)
+	require.NotNil(t, first.ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 180), *first.ExpiresAt, time.Minute)
+	restriction, err := ActiveRestriction(db, user.ID, models.CapabilityReview)
+	require.NoError(t, err)
+	assert.Nil(t, restriction)
+
+	issue(t, s, user.ID)
+	restriction, err = ActiveRestriction(db, user.ID, models.CapabilityReview)
+	require.NoError(t, err)
+	require.NotNil(t, restriction)
+	assert.True(t, restriction.Automatic)
+	require.NotNil(t, restriction.ExpiresAt)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *restriction.ExpiresAt, time.Minute)
+
+	// Messaging has no threshold
+	restriction, err = ActiveRestriction(db, user.ID, models.CapabilityMessage)
+	require.NoError(t, err)
+	assert.Nil(t, restriction)
+
+	issue(t, s, user.ID)
+	ban, err := ActiveRestriction(db, user.ID, models.CapabilityMessage)
+	require.NoError(t, err)
+	require.NotNil(t, ban, "a ban stops every capability")
+	assert.Equal(t, models.CapabilityLogin, ban.Capability)
+	assert.Nil(t, ban.ExpiresAt)
+
+	var token models.RefreshToken
+	require.NoError(t, db.First(&token).Error)
+	assert.NotNil(t, token.RevokedAt, "a ban ends the user's sessions")
+
+	// The review restriction is not applied twice
+	var reviewRestrictions int64
+	db.Model(&models.UserRestriction{}).Where("user_id = ? AND capability = ?", user.ID, models.CapabilityReview).Count(&reviewRestrictions)
+	assert.Equal(t, int64(1), reviewRestrictions)
+
+	require.NoError(t, db.First(&user, user.ID).Error)
+	assert.Equal(t, 3, user.StrikeCount)
+}
+
+func TestRevokeStrike(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+	strike := issue(t, s, user.ID)
+
+	// Expired strikes do not count
+	expired := time.Now().Add(-time.Hour)
+	_, err := s.IssueStrike(db, StrikeInput{UserID: user.ID, Source: models.StrikeSourceManual, Reason: "Old", IssuedBy: 99, ExpiresAt: &expired})
+	require.NoError(t, err)
+	require.NoError(t, db.First(&user, user.ID).Error)
+	assert.Equal(t, 1, user.StrikeCount)
+
+	_, err = s.RevokeStrike(db, user.ID, strike.ID, 99)
+	require.NoError(t, err)
+	require.NoError(t, db.First(&user, user.ID).Error)
+	assert.Equal(t, 0, user.StrikeCount)
+
+	_, err = s.RevokeStrike(db, user.ID, strike.ID, 99)
+	assert.ErrorIs(t, err, ErrStrikeRevoked)
+	_, err = s.RevokeStrike(db, user.ID+1, strike.ID, 99)
+	assert.ErrorIs(t, err, ErrStrikeNotFound)
+}
+
+func TestRestrictAndLift(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db)
+	user := createUser(t, db)
+
+	_, err := s.Restrict(db, RestrictionInput{UserID: user.ID, Capability: "post", Reason: "Spam", IssuedBy: 99})
+	assert.ErrorIs(t, err, ErrUnknownCapability)
+
+	restriction, err := s.Restrict(db, RestrictionInput{UserID: user.ID, Capability: models.CapabilityMessage, Reason: "Harassment", IssuedBy: 99})
+	require.NoError(t, err)
+	assert.False(t, restriction.Automatic)
+
+	_, err = s.Restrict(db, RestrictionInput{UserID: user.ID, Capability: models.CapabilityMessage, Reason: "Again", IssuedBy: 99})
+	assert.ErrorIs(t, err, ErrAlreadyRestricted)
+
+	_, err = s.LiftRestriction(db, user.ID, restriction.ID, 99)
+	require.NoError(t, err)
+	active, err := ActiveRestriction(db, user.ID, models.CapabilityMessage)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	_, err = s.LiftRestriction(db, user.ID, restriction.ID, 99)
+	assert.ErrorIs(t, err, ErrRestrictionNotActive)
+}
@@ -0,0 +1,129 @@
+package moderation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratings"
+	"gorm.io/gorm"
+)
+
+// Takedown is the outcome of taking down reported content
+type Takedown struct {
+	Action       *models.EnforcementAction
+	Owner        *models.User // nil when the owner is not known
+	ContentTitle string
+}
+
+// TakeDown removes the content an abuse report is about in tx: a review is
+// flagged, which hides it, and a product is deactivated. It records the
+// enforcement action and gives the owner of the content, the review's author
+// or, for a product, the reported user, a strike.
+func (s *Service) TakeDown(tx *gorm.DB, report *models.AbuseReport, actorID uint, reason string) (*Takedown, error) {
+	if report.ReviewID == nil && report.ProductID == nil {
+		return nil, ErrNoTarget
+	}
+	var existing int64
+	if err := tx.Model(&models.EnforcementAction{}).Where("abuse_report_id = ?", report.ID).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check enforcement actions: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyEnforced
+	}
+
+	action := &models.EnforcementAction{
+		AbuseReportID: &report.ID,
+		Action:        models.EnforcementActionRemove,
+		Reason:        reason,
+		ActorID:       actorID,
+	}
+	takedown := &Takedown{Action: action}
+
+	var err error
+	if report.ReviewID != nil {
+		err = s.takeDownReview(tx, *report.ReviewID, takedown, actorID, reason)
+	} else {
+		err = s.takeDownProduct(tx, *report.ProductID, takedown, report.ReportedUserID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Create(action).Error; err != nil {
+		return nil, fmt.Errorf("failed to record enforcement action: %w", err)
+	}
+	if action.UserID != nil {
+		strike := StrikeInput{
+			UserID:              *action.UserID,
+			Source:              models.StrikeSourceAbuseReport,
+			EnforcementActionID: &action.ID,
+			Reason:              reason,
+			ReviewID:            report.ReviewID,
+			IssuedBy:            actorID,
+		}
+		if _, err := s.IssueStrike(tx, strike); err != nil {
+			return nil, err
+		}
+		var owner models.User
+		if err := tx.First(&owner, *action.UserID).Error; err == nil {
+			takedown.Owner = &owner
+		}
+	}
+	return takedown, nil
+}
+
+// takeDownReview flags a review and logs the moderation, updating the
+// variant's rating when the review was counted in it
+func (s *Service) takeDownReview(tx *gorm.DB, reviewID uint, takedown *Takedown, actorID uint, reason string) error {
+	var review models.ProductReview
+	if err := tx.First(&review, reviewID).Error; err != nil {
+		return fmt.Errorf("failed to load review: %w", err)
+	}
+	oldStatus := review.Status
+	now := time.Now()
+	if err := tx.Model(&review).Updates(map[string]interface{}{
+		"status":            models.ReviewStatusFlagged,
+		"moderated_by":      actorID,
+		"moderated_at":      now,
+		"moderation_reason": reason,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to flag review: %w", err)
+	}
+	if err := tx.Create(&models.ReviewModerationLog{
+		ReviewID:    review.ID,
+		AdminID:     actorID,
+		OldStatus:   oldStatus,
+		NewStatus:   models.ReviewStatusFlagged,
+		Reason:      reason,
+		ModeratedAt: now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to log review moderation: %w", err)
+	}
+	if err := ratings.Apply(tx, review.ProductVariantID, ratings.Counted(oldStatus, review.Rating), 0); err != nil {
+		return err
+	}
+
+	takedown.Action.TargetType = models.EnforcementTargetReview
+	takedown.Action.TargetID = review.ID
+	takedown.Action.UserID = &review.UserID
+	takedown.ContentTitle = review.Title
+	return nil
+}
+
+// takeDownProduct deactivates a product, which removes it from the storefront
+func (s *Service) takeDownProduct(tx *gorm.DB, productID uint, takedown *Takedown, ownerID *uint) error {
+	var product models.Product
+	if err := tx.First(&product, productID).Error; err != nil {
+		return fmt.Errorf("failed to load product: %w", err)
+	}
+	if err := tx.Model(&product).Update("is_active", false).Error; err != nil {
+		return fmt.Errorf("failed to unpublish product: %w", err)
+	}
+
+	takedown.Action.TargetType = models.EnforcementTargetProduct
+	takedown.Action.TargetID = product.ID
+	takedown.Action.UserID = ownerID
+	takedown.ContentTitle = product.Name
+	return nil
+}
@@ -0,0 +1,139 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a notification does not exist or belongs to another user
+var ErrNotFound = errors.New("notification not found")
+
+// Message is the content of a notification
+type Message struct {
+	Type  models.NotificationType
+	Title string
+	Body  string
+	Link  string
+	Data  models.JSON
+}
+
+// Service writes and reads in-app notifications
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a notification service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Notify sends a notification to a single user
+func (s *Service) Notify(userID uint, msg Message) (*models.Notification, error) {
+	notification := newNotification(userID, msg)
+	if err := s.db.Create(notification).Error; err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	return notification, nil
+}
+
+// NotifyAdmins sends a notification to every active admin and returns how many were notified
+func (s *Service) NotifyAdmins(msg Message) (int, error) {
+	var adminIDs []uint
+	if err := s.db.Model(&models.User{}).
+		Where("user_type = ? AND is_active = ?", models.Admin, true).
+		Pluck("id", &adminIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to load admins: %w", err)
+	}
+	if len(adminIDs) == 0 {
+		return 0, nil
+	}
+
+	notifications := make([]*models.Notification, len(adminIDs))
+	for i, id := range adminIDs {
+		notifications[i] = newNotification(id, msg)
+	}
+	if err := s.db.Create(&notifications).Error; err != nil {
+		return 0, fmt.Errorf("failed to create notifications: %w", err)
+	}
+	return len(notifications), nil
+}
+
+// List returns a page of a user's notifications, newest first, with the total count
+func (s *Service) List(userID uint, unreadOnly bool, page, limit int) ([]models.Notification, int64, error) {
+	query := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return notifications, total, nil
+}
+
+// UnreadCount returns the number of unread notifications for the badge
+func (s *Service) UnreadCount(userID uint) (int64, error) {
+	var count int64
+	if err := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *Service) MarkRead(userID, notificationID uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	if notification.ReadAt != nil {
+		return &notification, nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&notification).Update("read_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+	notification.ReadAt = &now
+	return &notification, nil
+}
+
+// MarkAllRead marks all of the user's notifications as read and returns how many changed
+func (s *Service) MarkAllRead(userID uint) (int64, error) {
+	result := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func newNotification(userID uint, msg Message) *models.Notification {
+	return &models.Notification{
+		UserID: userID,
+		Type:   msg.Type,
+		Title:  msg.Title,
+		Body:   msg.Body,
+		Link:   msg.Link,
+		Data:   msg.Data,
+	}
+}
@@ -0,0 +1,83 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Notification{}))
+	return db
+}
+
+func TestNotifyAndMarkRead(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	first, err := service.Notify(1, Message{Type: models.NotificationTypeOrderUpdate, Title: "Order shipped", Data: models.JSON{"order_id": 5}})
+	require.NoError(t, err)
+	_, err = service.Notify(1, Message{Type: models.NotificationTypeTicketResponse, Title: "New response"})
+	require.NoError(t, err)
+	_, err = service.Notify(2, Message{Type: models.NotificationTypeOrderUpdate, Title: "Someone else's order"})
+	require.NoError(t, err)
+
+	count, err := service.UnreadCount(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Users cannot mark each other's notifications
+	_, err = service.MarkRead(2, first.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	read, err := service.MarkRead(1, first.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, read.ReadAt)
+
+	unread, total, err := service.List(1, true, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, unread, 1)
+	assert.Equal(t, "New response", unread[0].Title)
+
+	all, total, err := service.List(1, false, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, all, 2)
+
+	updated, err := service.MarkAllRead(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+	count, _ = service.UnreadCount(1)
+	assert.Equal(t, int64(0), count)
+	count, _ = service.UnreadCount(2)
+	assert.Equal(t, int64(1), count, "other users are untouched")
+}
+
+func TestNotifyAdmins(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	users := []models.User{
+		{Email: "admin@example.com", Password: "x", UserType: models.Admin, IsActive: true},
+		{Email: "admin2@example.com", Password: "x", UserType: models.Admin, IsActive: true},
+		{Email: "buyer@example.com", Password: "x", UserType: models.Customer, IsActive: true},
+	}
+	require.NoError(t, db.Create(&users).Error)
+
+	notified, err := service.NotifyAdmins(Message{Type: models.NotificationTypeStockAlert, Title: "Low stock: Olive oil"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, notified)
+
+	count, _ := service.UnreadCount(users[2].ID)
+	assert.Equal(t, int64(0), count)
+	count, _ = service.UnreadCount(users[0].ID)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,119 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/password"
+	"gorm.io/gorm"
+)
+
+// SignIn returns the user of an identity. An identity signing in for the
+// first time is linked to the account with its email address, which the
+// provider must have verified, or to a new customer account created in
+// locale. created reports whether the account was created.
+func (s *Service) SignIn(identity *Identity, locale string) (user *models.User, created bool, err error) {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var linked models.OAuthIdentity
+		err := tx.Preload("User").Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&linked).Error
+		if err == nil {
+			if linked.User == nil {
+				return ErrAccountClosed
+			}
+			user = linked.User
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if identity.Email == "" || !identity.EmailVerified {
+			return ErrEmailNotVerified
+		}
+		now := s.now()
+		var existing models.User
+		err = tx.Where("LOWER(email) = ?", identity.Email).First(&existing).Error
+		switch {
+		case err == nil:
+			user = &existing
+			// the provider vouches for the address
+			if user.VerifiedAt == nil {
+				if err := tx.Model(user).Update("verified_at", now).Error; err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if user, err = newCustomer(tx, identity, locale, now); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		return tx.Create(&models.OAuthIdentity{
+			UserID:   user.ID,
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return user, created, nil
+}
+
+// newCustomer creates the customer account of an identity. It gets a random
+// password nobody knows; the customer can set one with a password reset.
+func newCustomer(tx *gorm.DB, identity *Identity, locale string, now time.Time) (*models.User, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	hashed, err := password.Hash(hex.EncodeToString(b))
+	if err != nil {
+		return nil, err
+	}
+	user := models.User{
+		Email:      identity.Email,
+		Password:   hashed,
+		FirstName:  identity.FirstName,
+		LastName:   identity.LastName,
+		UserType:   models.Customer,
+		IsActive:   true,
+		Locale:     locale,
+		VerifiedAt: &now,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// WithAppleUser fills in the name Apple sends in the user form field of the
+// first sign-in callback, as its ID tokens carry no name
+func (i *Identity) WithAppleUser(raw string) *Identity {
+	if raw == "" {
+		return i
+	}
+	var user struct {
+		Name struct {
+			FirstName string `json:"firstName"`
+			LastName  string `json:"lastName"`
+		} `json:"name"`
+	}
+	if json.Unmarshal([]byte(raw), &user) == nil {
+		if i.FirstName == "" {
+			i.FirstName = user.Name.FirstName
+		}
+		if i.LastName == "" {
+			i.LastName = user.Name.LastName
+		}
+	}
+	return i
+}
@@ -0,0 +1,274 @@
+// Package oauth signs customers in with their Google or Apple account. A
+// sign-in is an OpenID Connect authorization code flow: the user is sent to
+// the provider with a signed state, and the code the provider sends back is
+// exchanged for an ID token naming the user. Identities are linked to the
+// account with the same verified email address, or to a new customer account.
+package oauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// Providers
+const (
+	Google = "google"
+	Apple  = "apple"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown sign-in provider")
+	ErrInvalidState     = errors.New("sign-in expired or was not started here, please try again")
+	ErrInvalidIDToken   = errors.New("the provider's response could not be verified")
+	ErrEmailNotVerified = errors.New("the provider has not verified your email address")
+	ErrAccountClosed    = errors.New("the account linked to this sign-in has been closed")
+)
+
+// stateTTL is how long a user has to sign in with the provider
+const stateTTL = 10 * time.Minute
+
+// appleSecretTTL is how long the client secrets signed for Apple are valid
+const appleSecretTTL = 5 * time.Minute
+
+// provider is an OpenID Connect provider
+type provider struct {
+	config  oauth2.Config
+	issuers []string
+	options []oauth2.AuthCodeOption
+	// secret returns the client secret, for providers that want a freshly
+	// signed one
+	secret func(now time.Time) (string, error)
+}
+
+// Service runs social sign-ins
+type Service struct {
+	db        *gorm.DB
+	config    cfg.OAuthConfig
+	providers map[string]*provider
+	now       func() time.Time
+}
+
+func NewService(db *gorm.DB, config *cfg.OAuthConfig) *Service {
+	s := &Service{db: db, providers: map[string]*provider{}, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	callback := strings.TrimSuffix(s.config.CallbackURL, "/")
+
+	if google := s.config.Google; google.ClientID != "" {
+		s.providers[Google] = &provider{
+			config: oauth2.Config{
+				ClientID:     google.ClientID,
+				ClientSecret: google.ClientSecret,
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   "https://accounts.google.com/o/oauth2/v2/auth",
+					TokenURL:  "https://oauth2.googleapis.com/token",
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+				RedirectURL: callback + "/" + Google + "/callback",
+				Scopes:      []string{"openid", "email", "profile"},
+			},
+			issuers: []string{"https://accounts.google.com", "accounts.google.com"},
+		}
+	}
+
+	if apple := s.config.Apple; apple.ClientID != "" {
+		s.providers[Apple] = &provider{
+			config: oauth2.Config{
+				ClientID: apple.ClientID,
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   "https://appleid.apple.com/auth/authorize",
+					TokenURL:  "https://appleid.apple.com/auth/token",
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+				RedirectURL: callback + "/" + Apple + "/callback",
+				Scopes:      []string{"name", "email"},
+			},
+			issuers: []string{"https://appleid.apple.com"},
+			// Apple posts the callback when a name or email is asked for
+			options: []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("response_mode", "form_post")},
+			secret: func(now time.Time) (string, error) {
+				return appleClientSecret(apple, now)
+			},
+		}
+	}
+	return s
+}
+
+// Enabled returns the names of the configured providers
+func (s *Service) Enabled() []string {
+	var names []string
+	for _, name := range []string{Google, Apple} {
+		if s.providers[name] != nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RedirectURL is the storefront page signed-in users are sent to
+func (s *Service) RedirectURL() string {
+	return s.config.RedirectURL
+}
+
+// AuthURL returns the provider's sign-in page to send the user to, and the
+// state it will send back
+func (s *Service) AuthURL(name string) (string, string, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	state, err := s.newState(name)
+	if err != nil {
+		return "", "", err
+	}
+	return p.config.AuthCodeURL(state, p.options...), state, nil
+}
+
+// newState returns the state of a sign-in with provider: a nonce, when it
+// expires and a signature, so that callbacks are only accepted for sign-ins
+// started here
+func (s *Service) newState(provider string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(nonce) + "." + strconv.FormatInt(s.now().Add(stateTTL).Unix(), 10)
+	return value + "." + s.sign(provider, value), nil
+}
+
+func (s *Service) sign(provider, value string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.StateSecret))
+	mac.Write([]byte("oauth:" + provider + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckState checks that a callback's state was issued for a sign-in with
+// provider that has not expired
+func (s *Service) CheckState(provider, state string) error {
+	i := strings.LastIndex(state, ".")
+	if i < 0 || s.config.StateSecret == "" {
+		return ErrInvalidState
+	}
+	value, sig := state[:i], state[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.sign(provider, value))) {
+		return ErrInvalidState
+	}
+	_, expires, _ := strings.Cut(value, ".")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.now().Unix() > expiresAt {
+		return ErrInvalidState
+	}
+	return nil
+}
+
+// Identity is a user as named by a provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// idTokenClaims are the claims of an ID token used here
+type idTokenClaims struct {
+	jwt.StandardClaims
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"` // a bool from Google, a string from Apple
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+}
+
+// Exchange exchanges the code of a callback for the identity of the user who
+// signed in
+func (s *Service) Exchange(ctx context.Context, name, code string) (*Identity, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	config := p.config
+	if p.secret != nil {
+		secret, err := p.secret(s.now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign %s client secret: %w", name, err)
+		}
+		config.ClientSecret = secret
+	}
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange %s code: %w", name, err)
+	}
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	// The ID token comes straight from the provider's token endpoint over
+	// TLS, which OpenID Connect accepts in place of checking its signature
+	var claims idTokenClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(raw, &claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	if !p.issuedBy(claims.Issuer) || claims.Audience != p.config.ClientID || claims.Subject == "" ||
+		!claims.VerifyExpiresAt(s.now().Unix(), true) {
+		return nil, ErrInvalidIDToken
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+	return &Identity{
+		Provider:      name,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: verified,
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	}, nil
+}
+
+func (p *provider) issuedBy(issuer string) bool {
+	for _, known := range p.issuers {
+		if issuer == known {
+			return true
+		}
+	}
+	return false
+}
+
+// appleClientSecret signs the client secret Apple expects: a short-lived
+// ES256 JWT from the team, signed with one of its keys
+func appleClientSecret(config cfg.OAuthProviderConfig, now time.Time) (string, error) {
+	key, err := jwt.ParseECPrivateKeyFromPEM([]byte(config.PrivateKey))
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.StandardClaims{
+		Issuer:    config.TeamID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(appleSecretTTL).Unix(),
+		Audience:  "https://appleid.apple.com",
+		Subject:   config.ClientID,
+	})
+	token.Header["kid"] = config.KeyID
+	return token.SignedString(key)
+}
@@ -0,0 +1,163 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)
+
+func setup(t *testing.T, config *cfg.OAuthConfig) *Service {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.OAuthIdentity{}))
+	s := NewService(db, config)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// tokenServer answers code exchanges with an ID token of claims
+func tokenServer(t *testing.T, claims jwt.MapClaims) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "the-code", r.PostForm.Get("code"))
+		idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("provider key"))
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSignInWithGoogle(t *testing.T) {
+	s := setup(t, &cfg.OAuthConfig{Google: cfg.OAuthProviderConfig{ClientID: "google-client", ClientSecret: "secret"}})
+	exchange := func(claims jwt.MapClaims) (*Identity, error) {
+		s.providers[Google].config.Endpoint.TokenURL = tokenServer(t, claims).URL
+		return s.Exchange(context.Background(), Google, "the-code")
+	}
+	claims := func(subject, email string, verified bool) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": "https://accounts.google.com", "aud": "google-client", "sub": subject,
+			"exp": testNow.Add(time.Hour).Unix(), "email": email, "email_verified": verified,
+			"given_name": "Karim", "family_name": "Mansouri",
+		}
+	}
+
+	identity, err := exchange(claims("g-1", "Karim@Example.com", true))
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Provider: Google, Subject: "g-1", Email: "karim@example.com", EmailVerified: true,
+		FirstName: "Karim", LastName: "Mansouri"}, identity)
+
+	user, created, err := s.SignIn(identity, "fr")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, models.Customer, user.UserType)
+	assert.Equal(t, "fr", user.Locale)
+	require.NotNil(t, user.VerifiedAt)
+
+	again, created, err := s.SignIn(identity, "en")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, again.ID)
+
+	// A second Google account with the same address is linked to the account
+	other, err := exchange(claims("g-2", "karim@example.com", true))
+	require.NoError(t, err)
+	linked, created, err := s.SignIn(other, "en")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, linked.ID)
+
+	unverified, err := exchange(claims("g-3", "karim@example.com", false))
+	require.NoError(t, err)
+	_, _, err = s.SignIn(unverified, "en")
+	assert.ErrorIs(t, err, ErrEmailNotVerified, "an unverified address must not take over an account")
+
+	for _, bad := range []jwt.MapClaims{
+		{"iss": "https://evil.example", "aud": "google-client", "sub": "g-4", "exp": testNow.Add(time.Hour).Unix()},
+		{"iss": "accounts.google.com", "aud": "another-client", "sub": "g-4", "exp": testNow.Add(time.Hour).Unix()},
+		{"iss": "accounts.google.com", "aud": "google-client", "sub": "g-4", "exp": testNow.Add(-time.Hour).Unix()},
+	} {
+		_, err := exchange(bad)
+		assert.ErrorIs(t, err, ErrInvalidIDToken)
+	}
+
+	var identities int64
+	require.NoError(t, s.db.Model(&models.OAuthIdentity{}).Count(&identities).Error)
+	assert.Equal(t, int64(2), identities)
+}
+
+func TestState(t *testing.T) {
+	s := setup(t, &cfg.OAuthConfig{
+		CallbackURL: "https://api.example.com/api/v1/auth/oauth/",
+		StateSecret: "state secret",
+		Google:      cfg.OAuthProviderConfig{ClientID: "google-client", ClientSecret: "secret"},
+	})
+
+	_, _, err := s.AuthURL(Apple)
+	assert.ErrorIs(t, err, ErrUnknownProvider, "providers without a client are off")
+	assert.Equal(t, []string{Google}, s.Enabled())
+
+	target, state, err := s.AuthURL(Google)
+	require.NoError(t, err)
+	u, err := url.Parse(target)
+	require.NoError(t, err)
+	assert.Equal(t, state, u.Query().Get("state"))
+	assert.Equal(t, "https://api.example.com/api/v1/auth/oauth/google/callback", u.Query().Get("redirect_uri"))
+
+	assert.NoError(t, s.CheckState(Google, state))
+	assert.ErrorIs(t, s.CheckState(Apple, state), ErrInvalidState)
+	assert.ErrorIs(t, s.CheckState(Google, state+"x"), ErrInvalidState)
+	s.now = func() time.Time { return testNow.Add(stateTTL + time.Second) }
+	assert.ErrorIs(t, s.CheckState(Google, state), ErrInvalidState)
+}
+
+func TestAppleClientSecret(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	config := cfg.OAuthProviderConfig{ClientID: "com.example.web", TeamID: "TEAM123", KeyID: "KEY456",
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))}
+
+	secret, err := appleClientSecret(config, testNow)
+	require.NoError(t, err)
+	claims := jwt.StandardClaims{}
+	parser := jwt.Parser{ValidMethods: []string{"ES256"}, SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(secret, &claims, func(*jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "KEY456", token.Header["kid"])
+	assert.Equal(t, jwt.StandardClaims{Issuer: "TEAM123", Subject: "com.example.web", Audience: "https://appleid.apple.com",
+		IssuedAt: testNow.Unix(), ExpiresAt: testNow.Add(appleSecretTTL).Unix()}, claims)
+}
+
+func TestWithAppleUser(t *testing.T) {
+	identity := (&Identity{Provider: Apple}).WithAppleUser(`{"name":{"firstName":"Lina","lastName":"Saidi"},"email":"lina@privaterelay.appleid.com"}`)
+	assert.Equal(t, "Lina", identity.FirstName)
+	assert.Equal(t, "Saidi", identity.LastName)
+}
@@ -0,0 +1,47 @@
+// Package orderhistory records the status changes of orders, which the admin
+// order timeline shows alongside payments, emails and shipments.
+package orderhistory
+
+import (
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// Change describes who made a status change and why
+type Change struct {
+	ActorID *uint // nil for changes made by the system
+	Reason  string
+	Forced  bool
+}
+
+// Record stores an order's move from one status to another in tx, so it is
+// kept exactly when the change is committed. Staying in the same status is
+// not recorded.
+func Record(tx *gorm.DB, orderID uint, from, to models.OrderStatus, change Change) error {
+	if from == to {
+		return nil
+	}
+	entry := models.OrderStatusChange{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    change.ActorID,
+		Reason:     change.Reason,
+		Forced:     change.Forced,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record order status change: %w", err)
+	}
+	return nil
+}
+
+// History returns an order's status changes, oldest first
+func History(db *gorm.DB, orderID uint) ([]models.OrderStatusChange, error) {
+	changes := []models.OrderStatusChange{}
+	if err := db.Where("order_id = ?", orderID).Order("created_at, id").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,195 @@
+// Package outbox implements a transactional outbox. Side effects of a
+// business change, such as emails and webhooks, are recorded as messages in
+// the same transaction as the change, so they exist exactly when the change
+// commits. A dispatcher worker then delivers them, retrying with backoff.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/logging"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAttempts is how many deliveries of a message are tried before it is
+	// marked failed
+	MaxAttempts = 10
+
+	batchSize    = 50
+	retryBase    = 30 * time.Second
+	retryMax     = time.Hour
+	claimTimeout = 5 * time.Minute // a claimed message is retried after this, in case its dispatcher died
+)
+
+// Handler delivers a message. Returning an error schedules a retry, so
+// handlers must be safe to run more than once for the same message.
+type Handler func(ctx context.Context, msg *models.OutboxMessage) error
+
+// Enqueue records a message for the handler of kind in tx, which should be the
+// transaction making the change the message is about. payload is stored as
+// JSON and the request ID carried by tx's context is kept for the logs.
+func Enqueue(tx *gorm.DB, kind, topic string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s outbox message: %w", kind, err)
+	}
+	msg := models.OutboxMessage{
+		Kind:          kind,
+		Topic:         topic,
+		Payload:       body,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+		RequestID:     logging.RequestID(tx.Statement.Context),
+	}
+	if err := tx.Create(&msg).Error; err != nil {
+		return fmt.Errorf("failed to enqueue %s outbox message: %w", kind, err)
+	}
+	return nil
+}
+
+// Dispatcher delivers due outbox messages to the handler registered for their kind
+type Dispatcher struct {
+	db       *gorm.DB
+	handlers map[string]Handler
+}
+
+// NewDispatcher creates a dispatcher with no handlers
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{db: db, handlers: make(map[string]Handler)}
+}
+
+// Handle registers the handler for messages of kind. It must be called before
+// the dispatcher is started.
+func (d *Dispatcher) Handle(kind string, handler Handler) {
+	d.handlers[kind] = handler
+}
+
+// Start dispatches due messages each interval until ctx is canceled
+func (d *Dispatcher) Start(ctx context.Context, interval time.Duration) {
+	for worker.Sleep(ctx, interval) {
+		if _, err := d.DispatchDue(ctx); err != nil {
+			slog.Error("failed to dispatch outbox messages", "component", "outbox", "error", err)
+		}
+	}
+}
+
+// DispatchDue delivers a batch of messages that are due, and returns how many
+// it attempted. Messages claimed by another dispatcher are skipped.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	var due []models.OutboxMessage
+	if err := d.db.WithContext(ctx).
+		Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND updated_at < ?)",
+			models.OutboxStatusPending, now, models.OutboxStatusProcessing, now.Add(-claimTimeout)).
+		Order("id ASC").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		claimed, err := d.claim(ctx, &due[i])
+		if err != nil {
+			return attempted, err
+		}
+		if !claimed {
+			continue
+		}
+		d.deliver(ctx, &due[i])
+		attempted++
+	}
+	return attempted, nil
+}
+
+// claim marks msg as processing and counts the attempt. The attempt count is
+// checked so that only one dispatcher wins a message.
+func (d *Dispatcher) claim(ctx context.Context, msg *models.OutboxMessage) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ? AND attempts = ?", msg.ID, msg.Status, msg.Attempts).
+		Updates(map[string]interface{}{
+			"status":     models.OutboxStatusProcessing,
+			"attempts":   msg.Attempts + 1,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	msg.Status = models.OutboxStatusProcessing
+	msg.Attempts++
+	return true, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, msg *models.OutboxMessage) {
+	logger := slog.With("component", "outbox", "outbox_id", msg.ID, "kind", msg.Kind, "topic", msg.Topic, "attempt", msg.Attempts, "request_id", msg.RequestID)
+
+	err := d.run(logging.WithRequestID(ctx, msg.RequestID), msg)
+	now := time.Now()
+	updates := map[string]interface{}{"updated_at": now}
+	switch {
+	case err == nil:
+		updates["status"] = models.OutboxStatusDispatched
+		updates["dispatched_at"] = now
+		updates["last_error"] = ""
+		metrics.OutboxDispatches.WithLabelValues(msg.Kind, "dispatched").Inc()
+	case msg.Attempts >= MaxAttempts:
+		updates["status"] = models.OutboxStatusFailed
+		updates["last_error"] = err.Error()
+		metrics.OutboxDispatches.WithLabelValues(msg.Kind, "failed").Inc()
+		logger.Error("giving up on outbox message", "error", err)
+	default:
+		updates["status"] = models.OutboxStatusPending
+		updates["next_attempt_at"] = now.Add(Backoff(msg.Attempts))
+		updates["last_error"] = err.Error()
+		metrics.OutboxDispatches.WithLabelValues(msg.Kind, "retried").Inc()
+		logger.Warn("outbox message delivery failed, will retry", "error", err)
+	}
+
+	// The update is not tied to ctx so a delivered message is recorded as such
+	// even when shutdown starts mid-batch
+	if err := d.db.WithContext(context.WithoutCancel(ctx)).Model(msg).Updates(updates).Error; err != nil {
+		logger.Error("failed to record outbox delivery", "error", err)
+	}
+}
+
+// run calls the handler for msg, turning a panic into an error
+func (d *Dispatcher) run(ctx context.Context, msg *models.OutboxMessage) (err error) {
+	handler, ok := d.handlers[msg.Kind]
+	if !ok {
+		return fmt.Errorf("no outbox handler for kind %q", msg.Kind)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint("outbox handler panicked: ", r))
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+// Backoff is the delay before retrying a message that has failed attempts
+// times: 30s doubling up to an hour
+func Backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.OutboxMessage{}))
+	return db
+}
+
+func messageOf(t *testing.T, db *gorm.DB) models.OutboxMessage {
+	var msg models.OutboxMessage
+	require.NoError(t, db.First(&msg).Error)
+	return msg
+}
+
+func TestDispatchRetriesThenGivesUp(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, Enqueue(db, "test", "order.paid", map[string]int{"order_id": 1}))
+
+	calls := 0
+	dispatcher := NewDispatcher(db)
+	dispatcher.Handle("test", func(ctx context.Context, msg *models.OutboxMessage) error {
+		calls++
+		assert.JSONEq(t, `{"order_id":1}`, string(msg.Payload))
+		return errors.New("unavailable")
+	})
+
+	attempted, err := dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	msg := messageOf(t, db)
+	assert.Equal(t, models.OutboxStatusPending, msg.Status)
+	assert.Equal(t, 1, msg.Attempts)
+	assert.Equal(t, "unavailable", msg.LastError)
+	assert.True(t, msg.NextAttemptAt.After(time.Now()))
+
+	// Not due again until the backoff has passed
+	attempted, err = dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+
+	require.NoError(t, db.Model(&msg).Updates(map[string]interface{}{"attempts": MaxAttempts - 1, "next_attempt_at": time.Now().Add(-time.Second)}).Error)
+	_, err = dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxStatusFailed, messageOf(t, db).Status)
+	assert.Equal(t, 2, calls)
+}
+
+func TestDispatchMarksDelivered(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, Enqueue(db, "test", "ticket.updated", nil))
+	require.NoError(t, Enqueue(db, "unknown", "ticket.updated", nil))
+
+	dispatcher := NewDispatcher(db)
+	dispatcher.Handle("test", func(ctx context.Context, msg *models.OutboxMessage) error { return nil })
+	_, err := dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+
+	var delivered, unhandled models.OutboxMessage
+	require.NoError(t, db.First(&delivered, "kind = ?", "test").Error)
+	require.NoError(t, db.First(&unhandled, "kind = ?", "unknown").Error)
+	assert.Equal(t, models.OutboxStatusDispatched, delivered.Status)
+	assert.NotNil(t, delivered.DispatchedAt)
+	assert.Equal(t, models.OutboxStatusPending, unhandled.Status)
+	assert.Contains(t, unhandled.LastError, "no outbox handler")
+}
+
+func TestEnqueueIsPartOfTransaction(t *testing.T) {
+	db := setupDB(t)
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, Enqueue(tx, "test", "order.paid", nil))
+		return errors.New("rollback")
+	})
+	var count int64
+	db.Model(&models.OutboxMessage{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var got *http.Request
+	var body string
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	db := setupDB(t)
+	require.NoError(t, EnqueueWebhook(db, "order.paid", server.URL, map[string]uint{"order_id": 9}, map[string]string{"X-Signature": "abc"}))
+	msg := messageOf(t, db)
+	handler := WebhookHandler(server.Client())
+
+	err := handler(context.Background(), &msg)
+	assert.ErrorContains(t, err, "webhook returned 500")
+
+	status = http.StatusNoContent
+	require.NoError(t, handler(context.Background(), &msg))
+	assert.JSONEq(t, `{"order_id":9}`, body)
+	assert.Equal(t, "order.paid", got.Header.Get("X-Event"))
+	assert.Equal(t, "abc", got.Header.Get("X-Signature"))
+	assert.NotEmpty(t, got.Header.Get("X-Delivery-ID"))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, time.Hour, Backoff(20))
+}
@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/tracing"
+	"gorm.io/gorm"
+)
+
+// KindWebhook is the kind of messages posted to an external URL
+const KindWebhook = "webhook"
+
+// Webhook is the payload of a webhook message
+type Webhook struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// EnqueueWebhook records a POST of body, encoded as JSON, to url for event.
+// The event name is sent in the X-Event header and the message ID in
+// X-Delivery-ID, so receivers can drop redelivered messages.
+func EnqueueWebhook(tx *gorm.DB, event, url string, body interface{}, headers map[string]string) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s webhook: %w", event, err)
+	}
+	return Enqueue(tx, KindWebhook, event, Webhook{URL: url, Headers: headers, Body: encoded})
+}
+
+// WebhookHandler delivers webhook messages with client. Any response other
+// than 2xx is a failure and is retried. A nil client uses DefaultWebhookClient.
+func WebhookHandler(client *http.Client) Handler {
+	if client == nil {
+		client = DefaultWebhookClient()
+	}
+	return func(ctx context.Context, msg *models.OutboxMessage) error {
+		var hook Webhook
+		if err := json.Unmarshal(msg.Payload, &hook); err != nil {
+			return fmt.Errorf("invalid webhook payload: %w", err)
+		}
+		_, err := PostWebhook(ctx, client, msg.Topic, fmt.Sprint(msg.ID), hook)
+		return err
+	}
+}
+
+// DefaultWebhookClient is a traced client with a 10 second timeout
+func DefaultWebhookClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}
+}
+
+// PostWebhook posts hook for event and returns the response status code, or 0
+// when no response was received. Responses other than 2xx are returned as an
+// error together with their status code.
+func PostWebhook(ctx context.Context, client *http.Client, event, deliveryID string, hook Webhook) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(hook.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", event)
+	req.Header.Set("X-Delivery-ID", deliveryID)
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeRevolut records the order calls made to the Revolut API
+type fakeRevolut struct {
+	mu     sync.Mutex
+	calls  []string          // path and body of each call
+	fail   map[string]bool   // paths answered with an error
+	states map[string]string // order states returned by path, PENDING by default
+}
+
+func (f *fakeRevolut) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.calls = append(f.calls, strings.TrimSpace(r.URL.Path+" "+string(body)))
+	id := fmt.Sprintf("rev-%d", len(f.calls))
+	failed, state := f.fail[r.URL.Path], f.states[r.URL.Path]
+	f.mu.Unlock()
+	if failed {
+		http.Error(w, `{"code":"unexpected_error"}`, http.StatusBadGateway)
+		return
+	}
+	if state == "" {
+		state = "PENDING"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": id, "state": state, "checkout_url": "https://checkout.test/" + id})
+}
+
+func setupCaptureTest(t *testing.T, config cfg.RevolutConfig) (*gorm.DB, *RevolutPaymentService, *fakeRevolut) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Address{}, &models.Order{}, &models.Payment{}, &models.PaymentLog{},
+		&models.Shipment{}, &models.StockAllocation{}))
+
+	fake := &fakeRevolut{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	config.APIKey, config.BaseURL = "test_api_key", server.URL
+	return db, NewRevolutPaymentService(db, &config, nil, nil), fake
+}
+
+func createAuthorizedPayment(t *testing.T, db *gorm.DB, ref string, authorizedAt time.Time) *models.Payment {
+	order := models.Order{OrderNumber: "ORD-" + ref, Status: models.OrderStatusPending, PaymentStatus: models.PaymentStatusPending, FinalAmount: 100}
+	require.NoError(t, db.Create(&order).Error)
+	payment := models.Payment{
+		OrderID:          order.ID,
+		RevolutOrderID:   ref,
+		RevolutPaymentID: ref,
+		Amount:           100,
+		Status:           models.RevolutPaymentStatusAuthorized,
+		CaptureMode:      models.CaptureModeManual,
+		AuthorizedAt:     &authorizedAt,
+	}
+	require.NoError(t, db.Create(&payment).Error)
+	return &payment
+}
+
+func TestCaptureMode(t *testing.T) {
+	db, service, _ := setupCaptureTest(t, cfg.RevolutConfig{B2BManualCapture: true})
+	ctx := context.Background()
+
+	customer := models.User{Email: "customer@example.com", UserType: models.Customer}
+	wholesaler := models.User{Email: "wholesaler@example.com", UserType: models.Wholesaler}
+	require.NoError(t, db.Create(&customer).Error)
+	require.NoError(t, db.Create(&wholesaler).Error)
+	companyID := uint(7)
+
+	testCases := []struct {
+		name      string
+		requested string
+		order     models.Order
+		expected  string
+	}{
+		{"customer order", "", models.Order{UserID: customer.ID}, models.CaptureModeAutomatic},
+		{"wholesaler order", "", models.Order{UserID: wholesaler.ID}, models.CaptureModeManual},
+		{"company order", "", models.Order{UserID: customer.ID, CompanyID: &companyID}, models.CaptureModeManual},
+		{"set on the order", "", models.Order{UserID: wholesaler.ID, CaptureMode: models.CaptureModeAutomatic}, models.CaptureModeAutomatic},
+		{"requested", models.CaptureModeManual, models.Order{UserID: customer.ID}, models.CaptureModeManual},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mode, err := service.captureMode(ctx, &PaymentRequest{CaptureMode: tc.requested}, &tc.order)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, mode)
+		})
+	}
+
+	_, err := service.captureMode(ctx, &PaymentRequest{CaptureMode: "later"}, &models.Order{})
+	assert.ErrorIs(t, err, ErrInvalidCaptureMode)
+
+	service.config.B2BManualCapture = false
+	mode, err := service.captureMode(ctx, &PaymentRequest{}, &models.Order{UserID: wholesaler.ID})
+	require.NoError(t, err)
+	assert.Equal(t, models.CaptureModeAutomatic, mode)
+}
+
+func TestCapturePaymentPartial(t *testing.T) {
+	db, service, fake := setupCaptureTest(t, cfg.RevolutConfig{})
+	ctx := context.Background()
+	payment := createAuthorizedPayment(t, db, "rev-partial", time.Now())
+
+	assert.ErrorIs(t, service.CapturePayment(ctx, "1", 150), ErrInvalidCaptureAmount)
+	require.NoError(t, service.CapturePayment(ctx, "1", 60.5))
+	assert.Equal(t, []string{`/api/1.0/orders/rev-partial/capture {"amount":6050}`}, fake.calls)
+
+	var captured models.Payment
+	require.NoError(t, db.First(&captured, payment.ID).Error)
+	assert.Equal(t, models.RevolutPaymentStatusCompleted, captured.Status)
+	assert.Equal(t, 60.5, captured.CapturedAmount)
+	assert.Equal(t, 60.5, captured.GetRefundableAmount())
+
+	// A completed payment cannot be captured again
+	assert.ErrorIs(t, service.CapturePayment(ctx, "1", 0), ErrNotCapturable)
+
+	// A full capture leaves the amount out
+	createAuthorizedPayment(t, db, "rev-full", time.Now())
+	require.NoError(t, service.CapturePayment(ctx, "2", 0))
+	assert.Equal(t, "/api/1.0/orders/rev-full/capture", fake.calls[1])
+}
+
+func TestVoidExpiredAuthorizations(t *testing.T) {
+	db, service, fake := setupCaptureTest(t, cfg.RevolutConfig{AuthorizationVoidHours: 24})
+	ctx := context.Background()
+	expired := createAuthorizedPayment(t, db, "rev-expired", time.Now().Add(-25*time.Hour))
+	recent := createAuthorizedPayment(t, db, "rev-recent", time.Now().Add(-time.Hour))
+
+	voided, err := service.VoidExpiredAuthorizations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, voided)
+	assert.Equal(t, []string{"/api/1.0/orders/rev-expired/cancel"}, fake.calls)
+
+	var payment models.Payment
+	require.NoError(t, db.First(&payment, expired.ID).Error)
+	assert.Equal(t, models.RevolutPaymentStatusCancelled, payment.Status)
+	var order models.Order
+	require.NoError(t, db.First(&order, expired.OrderID).Error)
+	assert.Equal(t, models.OrderStatusCancelled, order.Status)
+
+	var untouched models.Payment
+	require.NoError(t, db.First(&untouched, recent.ID).Error)
+	assert.Equal(t, models.RevolutPaymentStatusAuthorized, untouched.Status)
+}
@@ -0,0 +1,282 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+)
+
+// reconciliationPageSize is how many Revolut orders are listed per request
+const reconciliationPageSize = 100
+
+// Discrepancy issues
+const (
+	DiscrepancyMissing  = "missing"  // a Revolut order with no local payment
+	DiscrepancyStatus   = "status"   // the local status disagrees with the Revolut state
+	DiscrepancyAmount   = "amount"   // the local amount disagrees with Revolut
+	DiscrepancyCurrency = "currency" // the local currency disagrees with Revolut
+)
+
+// Discrepancy is a Revolut order that disagreed with its local payment
+type Discrepancy struct {
+	RevolutOrderID string  `json:"revolut_order_id"`
+	PaymentID      uint    `json:"payment_id,omitempty"`
+	OrderID        uint    `json:"order_id,omitempty"`
+	Issue          string  `json:"issue"`
+	LocalStatus    string  `json:"local_status,omitempty"`
+	RevolutState   string  `json:"revolut_state"`
+	LocalAmount    float64 `json:"local_amount,omitempty"`
+	RevolutAmount  float64 `json:"revolut_amount"`
+	Currency       string  `json:"currency"`
+	Fixed          bool    `json:"fixed"` // the local payment was brought in line with Revolut
+	Error          string  `json:"error,omitempty"`
+}
+
+// String describes the discrepancy in a line of the emailed report
+func (d Discrepancy) String() string {
+	var text string
+	switch d.Issue {
+	case DiscrepancyMissing:
+		text = fmt.Sprintf("Revolut order %s (%s %.2f, %s) has no local payment", d.RevolutOrderID, d.Currency, d.RevolutAmount, d.RevolutState)
+	case DiscrepancyStatus:
+		text = fmt.Sprintf("Payment #%d of order #%d is %s locally but %s in Revolut", d.PaymentID, d.OrderID, d.LocalStatus, d.RevolutState)
+	default:
+		text = fmt.Sprintf("Payment #%d of order #%d is %.2f locally but %s %.2f in Revolut", d.PaymentID, d.OrderID, d.LocalAmount, d.Currency, d.RevolutAmount)
+	}
+	if d.Fixed {
+		return text + " - fixed"
+	}
+	if d.Error != "" {
+		return text + " - fix failed: " + d.Error
+	}
+	return text
+}
+
+// ReconciliationReport is the outcome of comparing the Revolut orders created
+// in a period with the local payments
+type ReconciliationReport struct {
+	From          time.Time     `json:"from"`
+	To            time.Time     `json:"to"`
+	Checked       int           `json:"checked"`
+	Fixed         int           `json:"fixed"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// WithEmails sets the email triggers used to send reconciliation reports to
+// admins
+func (s *RevolutPaymentService) WithEmails(emails *email.EmailTriggerService) *RevolutPaymentService {
+	s.emails = emails
+	return s
+}
+
+// Reconcile compares the Revolut orders created between from and to with the
+// local payments. A payment left behind by a missed webhook, pending or
+// authorized locally but settled in Revolut, is brought in line the way the
+// webhook would have. Other disagreements are only reported.
+func (s *RevolutPaymentService) Reconcile(ctx context.Context, from, to time.Time) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{From: from, To: to, Discrepancies: []Discrepancy{}}
+	seen := make(map[string]bool)
+	for {
+		orders, err := s.client.ListOrders(ctx, from, to, reconciliationPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Revolut orders: %w", err)
+		}
+
+		ids := make([]string, 0, len(orders))
+		for _, order := range orders {
+			if !seen[order.ID] {
+				ids = append(ids, order.ID)
+			}
+		}
+		// Orders sharing the created_at the page ended on are listed again
+		if len(ids) == 0 {
+			break
+		}
+		var payments []models.Payment
+		if err := s.db.WithContext(ctx).Where("revolut_order_id IN ?", ids).Find(&payments).Error; err != nil {
+			return nil, fmt.Errorf("failed to load payments: %w", err)
+		}
+		byOrderID := make(map[string]*models.Payment, len(payments))
+		for i := range payments {
+			byOrderID[payments[i].RevolutOrderID] = &payments[i]
+		}
+
+		for _, order := range orders {
+			if seen[order.ID] {
+				continue
+			}
+			seen[order.ID] = true
+			report.Checked++
+			for _, d := range s.reconcileOrder(ctx, order.ID, order.State, order.Amount, order.Currency, byOrderID[order.ID]) {
+				if d.Fixed {
+					report.Fixed++
+				}
+				report.Discrepancies = append(report.Discrepancies, d)
+			}
+		}
+
+		if len(orders) < reconciliationPageSize {
+			break
+		}
+		last, err := time.Parse(time.RFC3339, orders[len(orders)-1].CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Revolut order created_at: %w", err)
+		}
+		to = last
+	}
+	return report, nil
+}
+
+// reconcileOrder compares a Revolut order with its local payment, fixing the
+// payment's status when a webhook was missed
+func (s *RevolutPaymentService) reconcileOrder(ctx context.Context, revolutOrderID, state string, amount int64, currency string, payment *models.Payment) []Discrepancy {
+	remote := Discrepancy{RevolutOrderID: revolutOrderID, RevolutState: state, RevolutAmount: float64(amount) / 100, Currency: currency}
+	if payment == nil {
+		remote.Issue = DiscrepancyMissing
+		return []Discrepancy{remote}
+	}
+	remote.PaymentID, remote.OrderID = payment.ID, payment.OrderID
+	remote.LocalStatus, remote.LocalAmount = string(payment.Status), payment.Amount
+
+	var discrepancies []Discrepancy
+	// Payments are created with their amount truncated to minor units
+	if math.Abs(payment.Amount*100-float64(amount)) >= 1 {
+		d := remote
+		d.Issue = DiscrepancyAmount
+		discrepancies = append(discrepancies, d)
+	} else if payment.Currency != "" && currency != "" && payment.Currency != currency {
+		d := remote
+		d.Issue = DiscrepancyCurrency
+		discrepancies = append(discrepancies, d)
+	}
+
+	status := s.mapRevolutStatusToPaymentStatus(state)
+	if statusesAgree(payment.Status, status, state) {
+		return discrepancies
+	}
+	d := remote
+	d.Issue = DiscrepancyStatus
+	if canReconcile(payment.Status, status) {
+		if err := s.applyRevolutStatus(ctx, payment, status); err != nil {
+			slog.WarnContext(ctx, "failed to reconcile payment", "component", "payment", "payment_id", payment.ID, "error", err)
+			d.Error = err.Error()
+		} else {
+			d.Fixed = true
+			s.logPaymentEvent(ctx, payment.ID, "payment_reconciled", "Payment status reconciled with Revolut", map[string]interface{}{
+				"old_status":    d.LocalStatus,
+				"new_status":    status,
+				"revolut_state": state,
+			})
+		}
+	}
+	return append(discrepancies, d)
+}
+
+// statusesAgree reports whether a local payment status is consistent with the
+// Revolut state. Refunds and disputes are tracked locally on orders Revolut
+// still reports as completed, and states with no local equivalent, such as
+// PROCESSING, are left to settle.
+func statusesAgree(local, remote models.RevolutPaymentStatus, state string) bool {
+	if local == remote {
+		return true
+	}
+	if remote == models.RevolutPaymentStatusCompleted &&
+		(local == models.RevolutPaymentStatusRefunded || local == models.RevolutPaymentStatusDisputed) {
+		return true
+	}
+	return remote == models.RevolutPaymentStatusPending && state != "PENDING"
+}
+
+// canReconcile reports whether a payment in local status can be moved to the
+// Revolut status as the missed webhook would have. Failed payments are only
+// moved forward when a later attempt on the same Revolut order went through,
+// since their order may be being retried with a new payment.
+func canReconcile(local, remote models.RevolutPaymentStatus) bool {
+	switch local {
+	case models.RevolutPaymentStatusPending, models.RevolutPaymentStatusAuthorized:
+		return remote != models.RevolutPaymentStatusPending && remote != models.RevolutPaymentStatusRefunded
+	case models.RevolutPaymentStatusFailed:
+		return remote == models.RevolutPaymentStatusAuthorized || remote == models.RevolutPaymentStatusCompleted
+	default:
+		return false
+	}
+}
+
+// applyRevolutStatus moves a payment to status through the webhook handler
+// for it
+func (s *RevolutPaymentService) applyRevolutStatus(ctx context.Context, payment *models.Payment, status models.RevolutPaymentStatus) error {
+	switch status {
+	case models.RevolutPaymentStatusCompleted:
+		return s.handleOrderCompleted(ctx, payment, nil)
+	case models.RevolutPaymentStatusAuthorized:
+		return s.handleOrderAuthorized(ctx, payment, nil)
+	case models.RevolutPaymentStatusFailed:
+		return s.handleOrderPaymentFailed(ctx, payment, nil)
+	case models.RevolutPaymentStatusCancelled:
+		return s.handleOrderCancelled(ctx, payment, nil)
+	default:
+		return fmt.Errorf("cannot reconcile to status %s", status)
+	}
+}
+
+// sendReconciliationReport emails a report with discrepancies to admins
+func (s *RevolutPaymentService) sendReconciliationReport(report *ReconciliationReport) error {
+	if s.emails == nil || len(report.Discrepancies) == 0 {
+		return nil
+	}
+	lines := make([]string, len(report.Discrepancies))
+	for i, d := range report.Discrepancies {
+		lines[i] = d.String()
+	}
+	unresolved := len(report.Discrepancies) - report.Fixed
+	priority := "medium"
+	if unresolved > 0 {
+		priority = "high"
+	}
+	return s.emails.TriggerPaymentReconciliationAdminNotification(map[string]interface{}{
+		"priority":      priority,
+		"period":        fmt.Sprintf("%s to %s", report.From.UTC().Format("2006-01-02 15:04"), report.To.UTC().Format("2006-01-02 15:04 UTC")),
+		"checked":       report.Checked,
+		"fixed":         report.Fixed,
+		"unresolved":    unresolved,
+		"discrepancies": lines,
+	})
+}
+
+// StartReconciler reconciles the Revolut orders of the configured lookback
+// window each night at the configured UTC hour until ctx is canceled, emailing
+// admins when anything disagreed
+func (s *RevolutPaymentService) StartReconciler(ctx context.Context) {
+	hour := s.config.ReconciliationHour
+	if hour < 0 || hour > 23 {
+		return
+	}
+	lookback := time.Duration(s.config.ReconciliationLookbackHours) * time.Hour
+	for {
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		if !worker.Sleep(ctx, next.Sub(now)) {
+			return
+		}
+
+		report, err := s.Reconcile(ctx, next.Add(-lookback), next)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to reconcile payments", "component", "payment", "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "reconciled payments with Revolut", "component", "payment",
+			"checked", report.Checked, "discrepancies", len(report.Discrepancies), "fixed", report.Fixed)
+		if err := s.sendReconciliationReport(report); err != nil {
+			slog.WarnContext(ctx, "failed to send reconciliation report", "component", "payment", "error", err)
+		}
+	}
+}
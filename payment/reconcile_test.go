@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/payment/revolut"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	db, service, _ := setupCaptureTest(t, cfg.RevolutConfig{})
+	require.NoError(t, db.AutoMigrate(&models.GiftCard{}, &models.WebhookSubscription{}))
+	service.giftCards = giftcard.NewService(db, nil, nil)
+	ctx := context.Background()
+
+	revolutOrders := []revolut.OrderResponse{
+		{ID: "rev-missed", State: "COMPLETED", Amount: 5000, Currency: "GBP"},
+		{ID: "rev-amount", State: "COMPLETED", Amount: 3000, Currency: "GBP"},
+		{ID: "rev-unknown", State: "COMPLETED", Amount: 1200, Currency: "GBP"},
+		{ID: "rev-refunded", State: "COMPLETED", Amount: 1999, Currency: "GBP"},
+		{ID: "rev-retried", State: "CANCELLED", Amount: 800, Currency: "GBP"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/1.0/orders", r.URL.Path)
+		json.NewEncoder(w).Encode(revolutOrders)
+	}))
+	t.Cleanup(server.Close)
+	service.client = revolut.NewClient(&cfg.RevolutConfig{BaseURL: server.URL})
+
+	payments := map[string]*models.Payment{}
+	for _, p := range []struct {
+		ref    string
+		amount float64
+		status models.RevolutPaymentStatus
+	}{
+		{"rev-missed", 50, models.RevolutPaymentStatusPending},
+		{"rev-amount", 25, models.RevolutPaymentStatusCompleted},
+		{"rev-refunded", 19.99, models.RevolutPaymentStatusRefunded},
+		{"rev-retried", 8, models.RevolutPaymentStatusFailed},
+	} {
+		order := models.Order{OrderNumber: "ORD-" + p.ref, Status: models.OrderStatusPending, PaymentStatus: models.PaymentStatusPending, FinalAmount: p.amount}
+		require.NoError(t, db.Create(&order).Error)
+		payment := models.Payment{OrderID: order.ID, RevolutOrderID: p.ref, RevolutPaymentID: p.ref, Amount: p.amount, Currency: "GBP", Status: p.status}
+		require.NoError(t, db.Create(&payment).Error)
+		payments[p.ref] = &payment
+	}
+
+	report, err := service.Reconcile(ctx, time.Now().Add(-48*time.Hour), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Checked)
+	assert.Equal(t, 1, report.Fixed)
+
+	issues := map[string]Discrepancy{}
+	for _, d := range report.Discrepancies {
+		issues[d.RevolutOrderID] = d
+	}
+	assert.Len(t, issues, 4)
+	assert.Equal(t, DiscrepancyStatus, issues["rev-missed"].Issue)
+	assert.True(t, issues["rev-missed"].Fixed)
+	assert.Equal(t, DiscrepancyAmount, issues["rev-amount"].Issue)
+	assert.Equal(t, DiscrepancyMissing, issues["rev-unknown"].Issue)
+	// A failed payment is not cancelled while its order may be retried
+	assert.Equal(t, DiscrepancyStatus, issues["rev-retried"].Issue)
+	assert.False(t, issues["rev-retried"].Fixed)
+
+	var payment models.Payment
+	require.NoError(t, db.First(&payment, payments["rev-missed"].ID).Error)
+	assert.Equal(t, models.RevolutPaymentStatusCompleted, payment.Status)
+	var order models.Order
+	require.NoError(t, db.First(&order, payment.OrderID).Error)
+	assert.Equal(t, models.PaymentStatusPaid, order.PaymentStatus)
+}
@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPayment(t *testing.T) {
+	db, service, fake := setupCaptureTest(t, cfg.RevolutConfig{MaxPaymentAttempts: 3})
+	ctx := context.Background()
+
+	user := models.User{Email: "customer@example.com", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+	order := models.Order{OrderNumber: "ORD-RETRY", UserID: user.ID, Status: models.OrderStatusPending,
+		PaymentStatus: models.PaymentStatusFailed, FinalAmount: 42}
+	require.NoError(t, db.Create(&order).Error)
+	failed := models.Payment{OrderID: order.ID, RevolutOrderID: "rev-failed", RevolutPaymentID: "rev-failed",
+		Amount: 42, Currency: "EUR", Status: models.RevolutPaymentStatusFailed}
+	stale := models.Payment{OrderID: order.ID, RevolutOrderID: "rev-stale", RevolutPaymentID: "rev-stale",
+		Amount: 42, Currency: "EUR", Status: models.RevolutPaymentStatusPending}
+	require.NoError(t, db.Create(&failed).Error)
+	require.NoError(t, db.Create(&stale).Error)
+
+	request := func() *PaymentRequest {
+		return &PaymentRequest{OrderID: order.ID, Amount: 42, CustomerInfo: &CustomerInfo{ID: user.ID, Email: user.Email, Name: "Amina Haddad"}}
+	}
+
+	resp, err := service.RetryPayment(ctx, request())
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", resp.Currency)
+	assert.NotEmpty(t, resp.CheckoutURL)
+	require.Len(t, fake.calls, 2)
+	assert.Equal(t, "/api/1.0/orders/rev-stale/cancel", fake.calls[0])
+
+	require.NoError(t, db.First(&stale, stale.ID).Error)
+	assert.Equal(t, models.RevolutPaymentStatusCancelled, stale.Status)
+	require.NoError(t, db.First(&order, order.ID).Error)
+	assert.Equal(t, models.PaymentStatusPending, order.PaymentStatus)
+	assert.Equal(t, resp.CheckoutURL, order.CheckoutURL)
+
+	// Three payments is the limit
+	_, err = service.RetryPayment(ctx, request())
+	assert.ErrorIs(t, err, ErrRetryLimitReached)
+
+	// A paid order cannot be retried
+	require.NoError(t, db.Model(&order).Update("payment_status", models.PaymentStatusPaid).Error)
+	_, err = service.RetryPayment(ctx, request())
+	assert.ErrorIs(t, err, ErrOrderNotPayable)
+}
+
+func TestRetryPaymentKeepsOpenCheckouts(t *testing.T) {
+	db, service, fake := setupCaptureTest(t, cfg.RevolutConfig{MaxPaymentAttempts: 5})
+	ctx := context.Background()
+
+	user := models.User{Email: "customer@example.com", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+	order := models.Order{OrderNumber: "ORD-OPEN", UserID: user.ID, Status: models.OrderStatusPending,
+		PaymentStatus: models.PaymentStatusPending, FinalAmount: 42}
+	require.NoError(t, db.Create(&order).Error)
+	stale := models.Payment{OrderID: order.ID, RevolutOrderID: "rev-stale", RevolutPaymentID: "rev-stale",
+		Amount: 42, Currency: "EUR", Status: models.RevolutPaymentStatusPending}
+	require.NoError(t, db.Create(&stale).Error)
+	request := &PaymentRequest{OrderID: order.ID, Amount: 42, CustomerInfo: &CustomerInfo{ID: user.ID, Email: user.Email, Name: "Amina Haddad"}}
+
+	// The cancel failed and Revolut still shows the checkout as payable
+	fake.fail = map[string]bool{"/api/1.0/orders/rev-stale/cancel": true}
+	_, err := service.RetryPayment(ctx, request)
+	assert.ErrorIs(t, err, ErrCheckoutStillOpen)
+	assert.Equal(t, []string{"/api/1.0/orders/rev-stale/cancel", "/api/orders/rev-stale"}, fake.calls)
+	require.NoError(t, db.First(&stale, stale.ID).Error)
+	assert.Equal(t, models.RevolutPaymentStatusPending, stale.Status)
+	require.NoError(t, db.First(&order, order.ID).Error)
+	assert.Nil(t, order.PaymentRetryStartedAt, "the claim is released")
+
+	// The checkout was paid in the meantime
+	fake.states = map[string]string{"/api/orders/rev-stale": "completed"}
+	_, err = service.RetryPayment(ctx, request)
+	assert.ErrorIs(t, err, ErrOrderNotPayable)
+
+	// Another retry is working on the order
+	claimedAt := time.Now()
+	require.NoError(t, db.Model(&order).Update("payment_retry_started_at", claimedAt).Error)
+	_, err = service.RetryPayment(ctx, request)
+	assert.ErrorIs(t, err, ErrRetryInProgress)
+
+	// An expired checkout cannot be cancelled but is closed, and a claim
+	// left by a retry that never finished is taken over
+	require.NoError(t, db.Model(&order).Update("payment_retry_started_at", claimedAt.Add(-time.Hour)).Error)
+	fake.states = map[string]string{"/api/orders/rev-stale": "cancelled"}
+	fake.calls = nil
+	resp, err := service.RetryPayment(ctx, request)
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.CheckoutURL)
+	require.NoError(t, db.First(&stale, stale.ID).Error)
+	assert.Equal(t, models.RevolutPaymentStatusCancelled, stale.Status)
+}
@@ -8,9 +8,11 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/tracing"
 )
 
 // Client represents a Revolut API client
@@ -26,11 +28,11 @@ func NewClient(config *cfg.RevolutConfig) *Client {
 	// Create HTTP client with proper timeouts
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
+		Transport: tracing.Transport(&http.Transport{
 			MaxIdleConns:       10,
 			IdleConnTimeout:    30 * time.Second,
 			DisableCompression: true,
-		},
+		}),
 	}
 
 	return &Client{
@@ -49,6 +51,21 @@ type Customer struct {
 	Email    string `json:"email,omitempty"`
 }
 
+// Shipping represents the delivery details of an order
+type Shipping struct {
+	Address *ShippingAddress `json:"address,omitempty"`
+}
+
+// ShippingAddress represents the address an order is delivered to
+type ShippingAddress struct {
+	StreetLine1 string `json:"street_line_1"`
+	StreetLine2 string `json:"street_line_2,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city"`
+	CountryCode string `json:"country_code"`
+	Postcode    string `json:"postcode"`
+}
+
 // LineItem represents a line item in an order
 type LineItem struct {
 	Name            string   `json:"name"`
@@ -149,7 +166,7 @@ type ErrorResponse struct {
 }
 
 // CreateOrder creates a new order using the Revolut Merchant API
-func (c *Client) CreateOrder(req *OrderRequest) (*OrderResponse, error) {
+func (c *Client) CreateOrder(ctx context.Context, req *OrderRequest) (*OrderResponse, error) {
 	url := fmt.Sprintf("%s/api/orders", c.baseURL)
 
 	jsonData, err := json.Marshal(req)
@@ -157,7 +174,7 @@ func (c *Client) CreateOrder(req *OrderRequest) (*OrderResponse, error) {
 		return nil, fmt.Errorf("failed to marshal order request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -200,10 +217,10 @@ func (c *Client) CreateOrder(req *OrderRequest) (*OrderResponse, error) {
 }
 
 // GetOrder retrieves an order by ID
-func (c *Client) GetOrder(orderID string) (*OrderResponse, error) {
+func (c *Client) GetOrder(ctx context.Context, orderID string) (*OrderResponse, error) {
 	url := fmt.Sprintf("%s/api/orders/%s", c.baseURL, orderID)
 
-	httpReq, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -240,8 +257,54 @@ func (c *Client) GetOrder(orderID string) (*OrderResponse, error) {
 	return &orderResp, nil
 }
 
+// ListOrders lists up to limit orders created between from and to, newest
+// first. Older orders are paged through by passing the created_at of the last
+// order returned as the next to.
+func (c *Client) ListOrders(ctx context.Context, from, to time.Time, limit int) ([]OrderResponse, error) {
+	query := url.Values{}
+	query.Set("from_created_date", from.UTC().Format(time.RFC3339))
+	query.Set("to_created_date", to.UTC().Format(time.RFC3339))
+	query.Set("limit", fmt.Sprint(limit))
+	endpoint := fmt.Sprintf("%s/api/1.0/orders?%s", c.baseURL, query.Encode())
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err != nil {
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("API request failed: %s - %s", errorResp.Code, errorResp.Message)
+	}
+
+	var orders []OrderResponse
+	if err := json.Unmarshal(body, &orders); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal orders response: %w", err)
+	}
+
+	return orders, nil
+}
+
 // RefundPayment refunds a payment
-func (c *Client) RefundPayment(paymentID string, req *RefundRequest) (*RefundResponse, error) {
+func (c *Client) RefundPayment(ctx context.Context, paymentID string, req *RefundRequest) (*RefundResponse, error) {
 	url := fmt.Sprintf("%s/api/1.0/payments/%s/refund", c.baseURL, paymentID)
 
 	jsonData, err := json.Marshal(req)
@@ -249,7 +312,7 @@ func (c *Client) RefundPayment(paymentID string, req *RefundRequest) (*RefundRes
 		return nil, fmt.Errorf("failed to marshal refund request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -285,17 +348,31 @@ func (c *Client) RefundPayment(paymentID string, req *RefundRequest) (*RefundRes
 	return &refundResp, nil
 }
 
-// CaptureOrder captures an order
-func (c *Client) CaptureOrder(paymentID string) (*CaptureResponse, error) {
+// CaptureOrder captures an authorized order. amount is in minor units; 0
+// captures the full authorized amount, less captures part of it and releases
+// the rest.
+func (c *Client) CaptureOrder(ctx context.Context, paymentID string, amount int64) (*CaptureResponse, error) {
 	url := fmt.Sprintf("%s/api/1.0/orders/%s/capture", c.baseURL, paymentID)
 
-	httpReq, err := http.NewRequestWithContext(context.Background(), "POST", url, nil)
+	var reqBody io.Reader
+	if amount > 0 {
+		jsonData, err := json.Marshal(map[string]int64{"amount": amount})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	// Set headers
 	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if reqBody != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -323,3 +400,110 @@ func (c *Client) CaptureOrder(paymentID string) (*CaptureResponse, error) {
 
 	return &captureResp, nil
 }
+
+// CancelOrder cancels an order that has not been captured yet, voiding its
+// authorization
+func (c *Client) CancelOrder(ctx context.Context, orderID string) (*OrderResponse, error) {
+	url := fmt.Sprintf("%s/api/1.0/orders/%s/cancel", c.baseURL, orderID)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err != nil {
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("API request failed: %s - %s", errorResp.Code, errorResp.Message)
+	}
+
+	var orderResp OrderResponse
+	if err := json.Unmarshal(body, &orderResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order response: %w", err)
+	}
+
+	return &orderResp, nil
+}
+
+// SavedPaymentMethod is a customer's payment method saved with Revolut, paid
+// with by the merchant without the customer
+type SavedPaymentMethod struct {
+	Type      string `json:"type"` // "card" or "revolut_pay"
+	ID        string `json:"id"`
+	Initiator string `json:"initiator"` // "merchant" or "customer"
+}
+
+// PaymentResult represents a payment made for an order
+type PaymentResult struct {
+	ID            string `json:"id"`
+	OrderID       string `json:"order_id"`
+	State         string `json:"state"`
+	DeclineReason string `json:"decline_reason,omitempty"`
+}
+
+// PayOrder pays an order with a payment method the customer saved for the
+// merchant. The order must have been created for the customer who saved it.
+func (c *Client) PayOrder(ctx context.Context, orderID string, method SavedPaymentMethod) (*PaymentResult, error) {
+	url := fmt.Sprintf("%s/api/orders/%s/payments", c.baseURL, orderID)
+
+	jsonData, err := json.Marshal(map[string]SavedPaymentMethod{"saved_payment_method": method})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payment request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	httpReq.Header.Set("Revolut-Api-Version", "2024-09-01")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err != nil {
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("API request failed: %s - %s", errorResp.Code, errorResp.Message)
+	}
+
+	var result PaymentResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment response: %w", err)
+	}
+	if result.State == "declined" || result.State == "failed" {
+		return &result, fmt.Errorf("payment %s: %s", result.State, result.DeclineReason)
+	}
+
+	return &result, nil
+}
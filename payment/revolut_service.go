@@ -7,14 +7,24 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/fraud"
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
+	"github.com/YasserCherfaoui/MarketProGo/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/orderhistory"
 	"github.com/YasserCherfaoui/MarketProGo/payment/revolut"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
 	"gorm.io/gorm"
 )
 
@@ -24,10 +34,16 @@ type RevolutPaymentService struct {
 	db            *gorm.DB
 	webhookSecret string
 	config        *cfg.RevolutConfig
+	giftCards     *giftcard.Service
+	fraud         *fraud.Service             // nil skips fraud checks
+	emails        *email.EmailTriggerService // nil sends no reconciliation reports
+
+	// previousWebhookSecret is also accepted while the secret is rotated
+	previousWebhookSecret string
 }
 
 // NewRevolutPaymentService creates a new Revolut payment service
-func NewRevolutPaymentService(db *gorm.DB, config *cfg.RevolutConfig) *RevolutPaymentService {
+func NewRevolutPaymentService(db *gorm.DB, config *cfg.RevolutConfig, giftCards *giftcard.Service, fraudChecks *fraud.Service) *RevolutPaymentService {
 	client := revolut.NewClient(config)
 
 	return &RevolutPaymentService{
@@ -35,7 +51,23 @@ func NewRevolutPaymentService(db *gorm.DB, config *cfg.RevolutConfig) *RevolutPa
 		db:            db,
 		webhookSecret: config.WebhookSecret,
 		config:        config,
+		giftCards:     giftCards,
+		fraud:         fraudChecks,
+
+		previousWebhookSecret: config.PreviousWebhookSecret,
+	}
+}
+
+// InTx returns a service that records payments and fraud checks in tx. The
+// Revolut order itself is created straight away; if tx rolls back it is never
+// paid and expires at Revolut.
+func (s *RevolutPaymentService) InTx(tx *gorm.DB) PaymentService {
+	deferred := *s
+	deferred.db = tx
+	if s.fraud != nil {
+		deferred.fraud = s.fraud.InTx(tx)
 	}
+	return &deferred
 }
 
 // CreatePayment creates a new payment using Revolut
@@ -56,21 +88,23 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 		return nil, fmt.Errorf("Revolut base URL is not configured")
 	}
 
-	// Debug logging
-	log.Printf("Creating Revolut payment for order %d, amount: %.2f %s", req.OrderID, req.Amount, req.Currency)
-	log.Printf("Revolut config - BaseURL: %s, IsSandbox: %t", s.config.BaseURL, s.config.IsSandbox)
-	log.Printf("API Key length: %d", len(s.config.APIKey))
-	log.Printf("API Key prefix: %s", s.config.APIKey[:10]+"...")
+	logger := slog.With("component", "payment", "order_id", req.OrderID)
+	logger.InfoContext(ctx, "creating Revolut payment", "amount", req.Amount, "currency", req.Currency,
+		"base_url", s.config.BaseURL, "sandbox", s.config.IsSandbox)
 
 	// Get order details
 	var order models.Order
-	if err := s.db.WithContext(ctx).First(&order, req.OrderID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("ShippingAddress").Preload("BillingAddress").First(&order, req.OrderID).Error; err != nil {
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
+	// A company buyer's order cannot be paid until an owner approves it
+	if order.Status == models.OrderStatusPendingApproval {
+		return nil, ErrOrderNotPayable
+	}
 
 	// Convert amount to minor units (cents) as required by Revolut API
 	amountInMinorUnits := int64(req.Amount * 100)
-	log.Printf("Converted amount: %d minor units", amountInMinorUnits)
+	logger.DebugContext(ctx, "converted amount", "minor_units", amountInMinorUnits)
 
 	// Validate minimum amount (Revolut requires at least 1 cent)
 	if amountInMinorUnits < 1 {
@@ -80,11 +114,10 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 	// Validate and normalize currency
 	currency := req.Currency
 	if currency == "" {
-		currency = "GBP" // Default to GBP
+		currency = settings.Current().Currency
 	}
 	// Ensure currency is uppercase
 	currency = strings.ToUpper(currency)
-	log.Printf("Using currency: %s", currency)
 
 	// Validate description
 	description := req.Description
@@ -95,7 +128,6 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 	if len(description) > 255 {
 		description = description[:252] + "..."
 	}
-	log.Printf("Using description: %s", description)
 
 	// Create customer info for Revolut
 	customer := &revolut.Customer{
@@ -104,6 +136,10 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 		Phone:    req.CustomerInfo.Phone,
 	}
 
+	if req.SavedPaymentMethod != nil {
+		customer.ID = req.SavedPaymentMethod.CustomerID
+	}
+
 	// Validate customer data
 	if customer.FullName == "" {
 		return nil, fmt.Errorf("customer full name is required")
@@ -112,8 +148,40 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 		return nil, fmt.Errorf("customer email is required")
 	}
 
-	log.Printf("Customer data: ID=%s, Name=%s, Email=%s, Phone=%s",
-		customer.ID, customer.FullName, customer.Email, customer.Phone)
+	captureMode, err := s.captureMode(ctx, req, &order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Score the payment; held payments are only authorized until reviewed
+	var fraudCheck *models.FraudCheck
+	if s.fraud != nil {
+		billingCountry := req.CustomerInfo.Country
+		if order.BillingAddress != nil {
+			billingCountry = order.BillingAddress.Country
+		}
+		check, err := s.fraud.Check(ctx, fraud.Input{
+			OrderID:         order.ID,
+			UserID:          req.CustomerInfo.ID,
+			Email:           req.CustomerInfo.Email,
+			IPAddress:       req.ClientIP,
+			CardFingerprint: req.CardFingerprint,
+			BillingCountry:  billingCountry,
+			ShippingCountry: order.ShippingAddress.Country,
+			Amount:          req.Amount,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to run fraud checks: %w", err)
+		}
+		switch check.Decision {
+		case models.FraudDecisionBlock:
+			metrics.PaymentsCreated.WithLabelValues("blocked").Inc()
+			return nil, ErrPaymentBlocked
+		case models.FraudDecisionReview:
+			captureMode = models.CaptureModeManual
+		}
+		fraudCheck = check
+	}
 
 	// Create Revolut order request - simplified to avoid internal server errors
 	revolutReq := &revolut.OrderRequest{
@@ -121,10 +189,22 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 		Currency:         currency,
 		Description:      description,
 		Customer:         customer,
-		CaptureMode:      "automatic",
+		CaptureMode:      captureMode,
 		EnforceChallenge: "automatic",
 	}
 
+	// Pass on the delivery address so Revolut's fraud checks can use it
+	if shipping := order.ShippingAddress; shipping.ID != 0 && len(shipping.Country) == 2 {
+		revolutReq.Shipping = &revolut.Shipping{Address: &revolut.ShippingAddress{
+			StreetLine1: shipping.StreetAddress1,
+			StreetLine2: shipping.StreetAddress2,
+			Region:      shipping.State,
+			City:        shipping.City,
+			CountryCode: shipping.Country,
+			Postcode:    shipping.PostalCode,
+		}}
+	}
+
 	// Only add redirect URL if it's provided
 	if req.ReturnURL != "" {
 		revolutReq.RedirectURL = req.ReturnURL
@@ -136,17 +216,19 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 	}
 
 	// Debug: Log the request as JSON to see exactly what's being sent
-	reqJSON, _ := json.MarshalIndent(revolutReq, "", "  ")
-	log.Printf("Revolut order request JSON:\n%s", string(reqJSON))
+	if reqJSON, err := json.Marshal(revolutReq); err == nil {
+		logger.DebugContext(ctx, "Revolut order request", "currency", currency, "description", description, "request", string(reqJSON))
+	}
 
 	// Create order in Revolut
-	revolutResp, err := s.client.CreateOrder(revolutReq)
+	revolutResp, err := s.client.CreateOrder(ctx, revolutReq)
 	if err != nil {
-		log.Printf("Revolut API error: %v", err)
+		logger.ErrorContext(ctx, "Revolut API error", "error", err)
+		metrics.PaymentsCreated.WithLabelValues("failed").Inc()
 		return nil, fmt.Errorf("failed to create Revolut order: %w", err)
 	}
 
-	log.Printf("Revolut order created successfully: %s", revolutResp.ID)
+	logger.InfoContext(ctx, "Revolut order created", "revolut_order_id", revolutResp.ID)
 
 	// Create payment record in database
 	payment := &models.Payment{
@@ -158,13 +240,22 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 		Status:           models.RevolutPaymentStatusPending,
 		CustomerID:       strconv.FormatUint(uint64(req.CustomerInfo.ID), 10),
 		CheckoutURL:      revolutResp.CheckoutURL,
+		CaptureMode:      captureMode,
 		Metadata:         models.JSON(map[string]interface{}{}),
 		CreatedBy:        req.CustomerInfo.ID,
 	}
 
 	if err := s.db.WithContext(ctx).Create(payment).Error; err != nil {
+		metrics.PaymentsCreated.WithLabelValues("failed").Inc()
 		return nil, fmt.Errorf("failed to create payment record: %w", err)
 	}
+	metrics.PaymentsCreated.WithLabelValues("success").Inc()
+
+	if fraudCheck != nil && fraudCheck.ID != 0 {
+		if err := s.fraud.AttachPayment(ctx, fraudCheck.ID, payment.ID); err != nil {
+			logger.WarnContext(ctx, "failed to link fraud check to payment", "fraud_check_id", fraudCheck.ID, "error", err)
+		}
+	}
 
 	// Update order with Revolut information
 	order.RevolutOrderID = revolutResp.ID
@@ -172,7 +263,7 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 	order.PaymentProvider = "revolut"
 
 	if err := s.db.WithContext(ctx).Save(&order).Error; err != nil {
-		log.Printf("Warning: failed to update order with Revolut info: %v", err)
+		logger.WarnContext(ctx, "failed to update order with Revolut info", "error", err)
 	}
 
 	// Log payment creation
@@ -180,19 +271,160 @@ func (s *RevolutPaymentService) CreatePayment(ctx context.Context, req *PaymentR
 		"revolut_order_id": revolutResp.ID,
 		"checkout_url":     revolutResp.CheckoutURL,
 		"token":            revolutResp.Token,
+		"capture_mode":     captureMode,
 	})
 
+	// Pay with the customer's saved payment method; the webhook completes the
+	// payment as for checkout payments
+	var paymentMethod string
+	if saved := req.SavedPaymentMethod; saved != nil {
+		result, err := s.client.PayOrder(ctx, revolutResp.ID, revolut.SavedPaymentMethod{Type: "card", ID: saved.ID, Initiator: "merchant"})
+		if err != nil {
+			logger.WarnContext(ctx, "failed to pay with saved payment method", "revolut_order_id", revolutResp.ID, "error", err)
+			s.logPaymentEvent(ctx, payment.ID, "saved_payment_failed", err.Error(), nil)
+		} else {
+			paymentMethod = "saved_card"
+			s.logPaymentEvent(ctx, payment.ID, "saved_payment_started", "Paid with saved payment method", map[string]interface{}{
+				"revolut_payment_id": result.ID,
+				"state":              result.State,
+			})
+		}
+	}
+
 	return &PaymentResponse{
-		PaymentID:   strconv.FormatUint(uint64(payment.ID), 10),
-		OrderID:     revolutResp.ID,
-		Amount:      req.Amount,
-		Currency:    req.Currency,
-		Status:      string(payment.Status),
-		CheckoutURL: revolutResp.CheckoutURL,
-		CreatedAt:   payment.CreatedAt,
+		PaymentID:     strconv.FormatUint(uint64(payment.ID), 10),
+		OrderID:       revolutResp.ID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Status:        string(payment.Status),
+		CaptureMode:   captureMode,
+		CheckoutURL:   revolutResp.CheckoutURL,
+		CreatedAt:     payment.CreatedAt,
+		PaymentMethod: paymentMethod,
+		HeldForReview: fraudCheck != nil && fraudCheck.Decision == models.FraudDecisionReview,
 	}, nil
 }
 
+// retryClaimTimeout is how long a payment retry holds its order. A claim
+// older than that is from a retry that never finished, and is taken over.
+const retryClaimTimeout = 2 * time.Minute
+
+// RetryPayment starts a new Revolut payment for an order whose payment failed
+// or was abandoned. The order's pending payments are closed first, and only
+// once Revolut confirms their checkouts are cancelled, so only the new
+// checkout can be paid. Orders with an authorized or completed payment,
+// orders that used up their payment attempts and orders another retry is
+// working on are refused.
+func (s *RevolutPaymentService) RetryPayment(ctx context.Context, req *PaymentRequest) (*PaymentResponse, error) {
+	db := s.db.WithContext(ctx)
+	var order models.Order
+	if err := db.First(&order, req.OrderID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if order.Status != models.OrderStatusPending ||
+		(order.PaymentStatus != models.PaymentStatusPending && order.PaymentStatus != models.PaymentStatusFailed) {
+		return nil, ErrOrderNotPayable
+	}
+
+	// Claim the order with a guarded UPDATE rather than a row lock, since
+	// CreatePayment saves the order outside of any transaction held here
+	now := time.Now().Truncate(time.Microsecond) // as stored, so the release below matches it
+	claim := db.Model(&models.Order{}).
+		Where("id = ? AND (payment_retry_started_at IS NULL OR payment_retry_started_at < ?)", order.ID, now.Add(-retryClaimTimeout)).
+		Update("payment_retry_started_at", now)
+	if claim.Error != nil {
+		return nil, fmt.Errorf("failed to claim order for payment retry: %w", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return nil, ErrRetryInProgress
+	}
+	defer func() {
+		err := s.db.Model(&models.Order{}).Where("id = ? AND payment_retry_started_at = ?", order.ID, now).
+			Update("payment_retry_started_at", nil).Error
+		if err != nil {
+			slog.WarnContext(ctx, "failed to release payment retry claim", "component", "payment", "order_id", order.ID, "error", err)
+		}
+	}()
+
+	var payments []models.Payment
+	if err := db.Where("order_id = ?", order.ID).Order("id ASC").Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get order payments: %w", err)
+	}
+	for _, payment := range payments {
+		if payment.Status == models.RevolutPaymentStatusAuthorized || payment.Status == models.RevolutPaymentStatusCompleted {
+			return nil, ErrOrderNotPayable
+		}
+	}
+	if s.config.MaxPaymentAttempts > 0 && len(payments) >= s.config.MaxPaymentAttempts {
+		return nil, ErrRetryLimitReached
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		if payment.Status != models.RevolutPaymentStatusPending {
+			continue
+		}
+		if err := s.closeStaleCheckout(ctx, payment); err != nil {
+			return nil, err
+		}
+		payment.Status = models.RevolutPaymentStatusCancelled
+		if err := db.Save(payment).Error; err != nil {
+			return nil, fmt.Errorf("failed to cancel stale payment: %w", err)
+		}
+		s.logPaymentEvent(ctx, payment.ID, "payment_superseded", "Payment cancelled by a payment retry", nil)
+	}
+
+	if req.Currency == "" && len(payments) > 0 {
+		req.Currency = payments[len(payments)-1].Currency
+	}
+	if order.PaymentStatus == models.PaymentStatusFailed {
+		if err := db.Model(&order).Update("payment_status", models.PaymentStatusPending).Error; err != nil {
+			return nil, fmt.Errorf("failed to reset order payment status: %w", err)
+		}
+	}
+
+	resp, err := s.CreatePayment(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if paymentID, err := strconv.ParseUint(resp.PaymentID, 10, 64); err == nil {
+		s.logPaymentEvent(ctx, uint(paymentID), "payment_retried", "Payment retried", map[string]interface{}{
+			"attempt": len(payments) + 1,
+		})
+	}
+	return resp, nil
+}
+
+// closeStaleCheckout cancels the Revolut order of a pending payment. When the
+// cancel fails, the order is looked up, and the payment only counts as closed
+// if Revolut reports it cancelled, failed or expired: otherwise its checkout
+// could still be paid alongside the new one.
+func (s *RevolutPaymentService) closeStaleCheckout(ctx context.Context, payment *models.Payment) error {
+	if payment.RevolutOrderID == "" {
+		return nil
+	}
+	_, cancelErr := s.client.CancelOrder(ctx, payment.RevolutOrderID)
+	if cancelErr == nil {
+		return nil
+	}
+	revolutOrder, err := s.client.GetOrder(ctx, payment.RevolutOrderID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to cancel stale Revolut order", "component", "payment", "payment_id", payment.ID, "error", cancelErr)
+		return fmt.Errorf("%w: %v", ErrCheckoutStillOpen, cancelErr)
+	}
+	switch strings.ToUpper(revolutOrder.State) {
+	case "CANCELLED", "FAILED", "EXPIRED":
+		return nil
+	case "AUTHORISED", "AUTHORIZED", "COMPLETED":
+		// Paid while the customer was asking for a new checkout
+		return ErrOrderNotPayable
+	default:
+		slog.WarnContext(ctx, "failed to cancel stale Revolut order", "component", "payment", "payment_id", payment.ID,
+			"state", revolutOrder.State, "error", cancelErr)
+		return fmt.Errorf("%w: %v", ErrCheckoutStillOpen, cancelErr)
+	}
+}
+
 // GetPaymentStatus retrieves the current status of a payment
 func (s *RevolutPaymentService) GetPaymentStatus(ctx context.Context, paymentID string) (string, error) {
 	// Get payment from database
@@ -203,9 +435,9 @@ func (s *RevolutPaymentService) GetPaymentStatus(ctx context.Context, paymentID
 
 	// If we have a Revolut order ID, check with Revolut API
 	if payment.RevolutOrderID != "" {
-		revolutOrder, err := s.client.GetOrder(payment.RevolutOrderID)
+		revolutOrder, err := s.client.GetOrder(ctx, payment.RevolutOrderID)
 		if err != nil {
-			log.Printf("Warning: failed to get Revolut order status: %v", err)
+			slog.WarnContext(ctx, "failed to get Revolut order status", "component", "payment", "payment_id", payment.ID, "error", err)
 			// Return database status if API call fails
 			return string(payment.Status), nil
 		}
@@ -216,6 +448,10 @@ func (s *RevolutPaymentService) GetPaymentStatus(ctx context.Context, paymentID
 			oldStatus := payment.Status
 			payment.Status = newStatus
 
+			if newStatus == models.RevolutPaymentStatusAuthorized && payment.AuthorizedAt == nil {
+				now := time.Now()
+				payment.AuthorizedAt = &now
+			}
 			if newStatus == models.RevolutPaymentStatusCompleted {
 				now := time.Now()
 				payment.CompletedAt = &now
@@ -225,7 +461,7 @@ func (s *RevolutPaymentService) GetPaymentStatus(ctx context.Context, paymentID
 			}
 
 			if err := s.db.WithContext(ctx).Save(&payment).Error; err != nil {
-				log.Printf("Warning: failed to update payment status: %v", err)
+				slog.WarnContext(ctx, "failed to update payment status", "component", "payment", "payment_id", payment.ID, "error", err)
 			} else {
 				// Log status change
 				s.logPaymentEvent(ctx, payment.ID, "status_changed", "Payment status updated", map[string]interface{}{
@@ -241,8 +477,9 @@ func (s *RevolutPaymentService) GetPaymentStatus(ctx context.Context, paymentID
 	return string(payment.Status), nil
 }
 
-// CapturePayment captures an authorized payment
-func (s *RevolutPaymentService) CapturePayment(ctx context.Context, paymentID string) error {
+// CapturePayment captures an authorized payment. amount 0 captures the full
+// authorized amount; less captures part of it and Revolut releases the rest.
+func (s *RevolutPaymentService) CapturePayment(ctx context.Context, paymentID string, amount float64) error {
 	// Get payment from database
 	var payment models.Payment
 	if err := s.db.WithContext(ctx).First(&payment, paymentID).Error; err != nil {
@@ -252,15 +489,29 @@ func (s *RevolutPaymentService) CapturePayment(ctx context.Context, paymentID st
 	if payment.RevolutPaymentID == "" {
 		return fmt.Errorf("no Revolut payment ID available for capture")
 	}
+	if payment.Status != models.RevolutPaymentStatusAuthorized {
+		return ErrNotCapturable
+	}
+	if amount == 0 {
+		amount = payment.Amount
+	}
+	if amount < 0.01 || amount > payment.Amount+0.005 {
+		return ErrInvalidCaptureAmount
+	}
 
-	// Capture payment in Revolut
-	_, err := s.client.CaptureOrder(payment.RevolutOrderID)
+	// Capture payment in Revolut, leaving the amount out for a full capture
+	var minorUnits int64
+	if amount < payment.Amount {
+		minorUnits = int64(math.Round(amount * 100))
+	}
+	_, err := s.client.CaptureOrder(ctx, payment.RevolutOrderID, minorUnits)
 	if err != nil {
 		return fmt.Errorf("failed to capture payment: %w", err)
 	}
 
 	// Update payment status
 	payment.Status = models.RevolutPaymentStatusCompleted
+	payment.CapturedAmount = amount
 	now := time.Now()
 	payment.CompletedAt = &now
 
@@ -269,7 +520,10 @@ func (s *RevolutPaymentService) CapturePayment(ctx context.Context, paymentID st
 	}
 
 	// Log capture event
-	s.logPaymentEvent(ctx, payment.ID, "payment_captured", "Payment captured successfully", nil)
+	s.logPaymentEvent(ctx, payment.ID, "payment_captured", "Payment captured successfully", map[string]interface{}{
+		"captured_amount": amount,
+		"partial":         amount < payment.Amount,
+	})
 
 	return nil
 }
@@ -299,14 +553,14 @@ func (s *RevolutPaymentService) RefundPayment(ctx context.Context, req *RefundRe
 	}
 
 	// Process refund in Revolut
-	revolutResp, err := s.client.RefundPayment(payment.RevolutPaymentID, revolutRefundReq)
+	revolutResp, err := s.client.RefundPayment(ctx, payment.RevolutPaymentID, revolutRefundReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process refund: %w", err)
 	}
 
 	// Update payment record
 	payment.RefundedAmount += req.Amount
-	if payment.RefundedAmount >= payment.Amount {
+	if payment.RefundedAmount >= payment.SettledAmount() {
 		payment.Status = models.RevolutPaymentStatusRefunded
 	}
 	payment.RefundStatus = revolutResp.State
@@ -357,13 +611,124 @@ func (s *RevolutPaymentService) CancelPayment(ctx context.Context, paymentID str
 	return nil
 }
 
+// CancelProviderOrder cancels a Revolut order by its ID, without a payment
+// to update
+func (s *RevolutPaymentService) CancelProviderOrder(ctx context.Context, revolutOrderID string) error {
+	if _, err := s.client.CancelOrder(ctx, revolutOrderID); err != nil {
+		return fmt.Errorf("failed to cancel Revolut order: %w", err)
+	}
+	return nil
+}
+
+// VoidPayment cancels an uncaptured Revolut order, releasing any
+// authorization on the customer's card, and cancels the order it paid for
+func (s *RevolutPaymentService) VoidPayment(ctx context.Context, paymentID string) error {
+	var payment models.Payment
+	if err := s.db.WithContext(ctx).First(&payment, paymentID).Error; err != nil {
+		return fmt.Errorf("payment not found: %w", err)
+	}
+
+	if payment.Status != models.RevolutPaymentStatusPending && payment.Status != models.RevolutPaymentStatusAuthorized {
+		return fmt.Errorf("only pending or authorized payments can be voided")
+	}
+
+	if _, err := s.client.CancelOrder(ctx, payment.RevolutOrderID); err != nil {
+		return fmt.Errorf("failed to cancel Revolut order: %w", err)
+	}
+
+	oldStatus := payment.Status
+	payment.Status = models.RevolutPaymentStatusCancelled
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&payment).Error; err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+		var order models.Order
+		if err := tx.Select("id", "order_number", "status").First(&order, payment.OrderID).Error; err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+		if err := tx.Model(&order).Update("status", models.OrderStatusCancelled).Error; err != nil {
+			return fmt.Errorf("failed to cancel order: %w", err)
+		}
+		if err := releaseStock(tx, &order); err != nil {
+			return fmt.Errorf("failed to release order stock: %w", err)
+		}
+		return orderhistory.Record(tx, order.ID, order.Status, models.OrderStatusCancelled, orderhistory.Change{Reason: "Payment voided"})
+	}); err != nil {
+		return err
+	}
+
+	s.logPaymentEvent(ctx, payment.ID, "payment_voided", "Payment voided", map[string]interface{}{
+		"old_status": oldStatus,
+		"new_status": payment.Status,
+	})
+
+	return nil
+}
+
+// VoidExpiredAuthorizations voids manual capture payments authorized longer
+// ago than the configured window, before the card issuer releases the funds
+// on its own, and closes any fraud review still waiting on them. It returns
+// how many payments were voided.
+func (s *RevolutPaymentService) VoidExpiredAuthorizations(ctx context.Context) (int, error) {
+	if s.config.AuthorizationVoidHours <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-time.Duration(s.config.AuthorizationVoidHours) * time.Hour)
+	var payments []models.Payment
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND capture_mode = ? AND authorized_at < ?", models.RevolutPaymentStatusAuthorized, models.CaptureModeManual, cutoff).
+		Find(&payments).Error; err != nil {
+		return 0, fmt.Errorf("failed to load expired authorizations: %w", err)
+	}
+
+	voided := 0
+	for _, payment := range payments {
+		if err := s.VoidPayment(ctx, strconv.FormatUint(uint64(payment.ID), 10)); err != nil {
+			slog.WarnContext(ctx, "failed to void expired authorization", "component", "payment", "payment_id", payment.ID, "error", err)
+			continue
+		}
+		voided++
+		if s.fraud != nil {
+			note := fmt.Sprintf("authorization voided after %d hours without capture", s.config.AuthorizationVoidHours)
+			if err := s.fraud.CloseForPayment(ctx, payment.ID, note); err != nil {
+				slog.WarnContext(ctx, "failed to close fraud review", "component", "payment", "payment_id", payment.ID, "error", err)
+			}
+		}
+	}
+	return voided, nil
+}
+
+// StartAuthorizationVoider voids expired authorizations each interval until
+// ctx is canceled
+func (s *RevolutPaymentService) StartAuthorizationVoider(ctx context.Context, interval time.Duration) {
+	for {
+		if voided, err := s.VoidExpiredAuthorizations(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to void expired authorizations", "component", "payment", "error", err)
+		} else if voided > 0 {
+			slog.InfoContext(ctx, "voided expired authorizations", "component", "payment", "count", voided)
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
 // HandleWebhook processes webhook notifications from Revolut
 // Headers expected:
 // - Revolut-Signature: v1=signature (hex-encoded HMAC-SHA256)
 // - Revolut-Request-Timestamp: UNIX timestamp of the webhook event
-func (s *RevolutPaymentService) HandleWebhook(ctx context.Context, payload []byte, signature string, timestamp string) error {
+func (s *RevolutPaymentService) HandleWebhook(ctx context.Context, payload []byte, signature string, timestamp string) (err error) {
+	event, outcome := "unknown", "processed"
+	defer func() {
+		if err != nil && outcome == "processed" {
+			outcome = "error"
+		}
+		metrics.PaymentWebhooks.WithLabelValues(event, outcome).Inc()
+	}()
+
 	// Validate webhook signature
 	if !s.validateWebhookSignature(payload, signature, timestamp) {
+		outcome = "invalid_signature"
 		return fmt.Errorf("invalid webhook signature")
 	}
 
@@ -372,6 +737,9 @@ func (s *RevolutPaymentService) HandleWebhook(ctx context.Context, payload []byt
 	if err := json.Unmarshal(payload, &webhookData); err != nil {
 		return fmt.Errorf("failed to parse webhook payload: %w", err)
 	}
+	if name, ok := webhookData["event"].(string); ok && name != "" {
+		event = name
+	}
 
 	// Extract order ID from webhook
 	orderID, ok := webhookData["order_id"].(string)
@@ -454,45 +822,82 @@ func (s *RevolutPaymentService) mapRevolutStatusToPaymentStatus(revolutState str
 
 // validateWebhookSignature validates the webhook signature according to Revolut's security requirements
 // Based on: https://developer.revolut.com/docs/guides/accept-payments/tutorials/work-with-webhooks/verify-the-payload-signature
+// While the secret is rotated, signatures made with the previous secret are
+// accepted too. Revolut itself sends one comma-separated signature per secret
+// while it rotates a secret.
 func (s *RevolutPaymentService) validateWebhookSignature(payload []byte, signature string, timestamp string) bool {
 	if s.webhookSecret == "" {
-		log.Printf("Warning: webhook secret not configured, skipping signature validation")
+		slog.Warn("webhook secret not configured, skipping signature validation", "component", "payment")
 		return true
 	}
 
-	// Parse the signature format: v1=signature
-	if len(signature) < 3 || signature[:2] != "v1" || signature[2] != '=' {
-		log.Printf("Invalid signature format: %s", signature)
-		return false
-	}
-
-	// Extract the actual signature (remove "v1=" prefix)
-	actualSignature := signature[3:]
-
 	// Step 1: Prepare the payload to sign
 	// payload_to_sign = v1.{timestamp}.{raw-payload}
 	payloadToSign := fmt.Sprintf("v1.%s.%s", timestamp, string(payload))
-	log.Printf("[DEBUG] Payload to sign: %s", payloadToSign)
+	slog.Debug("webhook payload to sign", "component", "payment", "payload", payloadToSign)
 
-	// Step 2: Compute the expected signature using HMAC-SHA256
-	// Use the webhook secret as the key and the prepared payload as the message
-	h := hmac.New(sha256.New, []byte(s.webhookSecret))
-	h.Write([]byte(payloadToSign))
-	expectedSignature := hex.EncodeToString(h.Sum(nil))
+	// Step 2: Compute the expected signature with each secret using HMAC-SHA256
+	expected := []string{revolutSignature(s.webhookSecret, payloadToSign)}
+	if s.previousWebhookSecret != "" {
+		expected = append(expected, revolutSignature(s.previousWebhookSecret, payloadToSign))
+	}
 
-	// Step 3: Compare signatures using constant-time comparison
-	isValid := hmac.Equal([]byte(actualSignature), []byte(expectedSignature))
+	for _, received := range strings.Split(signature, ",") {
+		// Parse the signature format: v1=signature
+		received = strings.TrimSpace(received)
+		if len(received) < 3 || received[:2] != "v1" || received[2] != '=' {
+			slog.Warn("invalid webhook signature format", "component", "payment", "signature", received)
+			continue
+		}
 
-	if !isValid {
-		log.Printf("Signature validation failed. Expected: %s, Received: %s", expectedSignature, actualSignature)
-		log.Printf("[DEBUG] Webhook secret length: %d", len(s.webhookSecret))
-		log.Printf("[DEBUG] Payload length: %d", len(payload))
-		log.Printf("[DEBUG] Timestamp: %s", timestamp)
-	} else {
-		log.Printf("[DEBUG] Signature validation successful")
+		// Step 3: Compare signatures using constant-time comparison
+		for i, want := range expected {
+			if hmac.Equal([]byte(received[3:]), []byte(want)) {
+				metrics.SecretMatched("revolut_webhook", i > 0)
+				slog.Debug("webhook signature validation successful", "component", "payment", "previous_secret", i > 0)
+				return true
+			}
+		}
 	}
 
-	return isValid
+	slog.Warn("webhook signature validation failed", "component", "payment",
+		"received", signature, "payload_length", len(payload), "timestamp", timestamp)
+	return false
+}
+
+// revolutSignature signs a webhook payload with secret
+func revolutSignature(secret, payloadToSign string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(payloadToSign))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// captureMode returns how a payment is captured: as requested, else as set
+// on its order, else manually for B2B orders when configured
+func (s *RevolutPaymentService) captureMode(ctx context.Context, req *PaymentRequest, order *models.Order) (string, error) {
+	mode := req.CaptureMode
+	if mode == "" {
+		mode = order.CaptureMode
+	}
+	if mode == "" {
+		mode = models.CaptureModeAutomatic
+		if s.config.B2BManualCapture {
+			b2b := order.CompanyID != nil
+			if !b2b {
+				var user models.User
+				if err := s.db.WithContext(ctx).Select("id", "user_type").First(&user, order.UserID).Error; err == nil {
+					b2b = user.UserType == models.Wholesaler
+				}
+			}
+			if b2b {
+				mode = models.CaptureModeManual
+			}
+		}
+	}
+	if mode != models.CaptureModeAutomatic && mode != models.CaptureModeManual {
+		return "", ErrInvalidCaptureMode
+	}
+	return mode, nil
 }
 
 // processWebhookEvent processes a webhook event and updates payment status
@@ -526,7 +931,7 @@ func (s *RevolutPaymentService) processWebhookEvent(ctx context.Context, payment
 		return s.handleOrderCancelled(ctx, payment, webhookData)
 	default:
 		// Log unknown event but don't fail
-		log.Printf("Unknown webhook event: %s", event)
+		slog.WarnContext(ctx, "unknown webhook event", "component", "payment", "payment_id", payment.ID, "event", event)
 		return nil
 	}
 }
@@ -542,21 +947,33 @@ func (s *RevolutPaymentService) handleOrderCompleted(ctx context.Context, paymen
 	// No need to extract from webhook since payment_id doesn't exist in webhook payload
 	// The order_id in webhook corresponds to the RevolutPaymentID we already have
 
-	// Update order status to PAID
-	var order models.Order
-	if err := s.db.WithContext(ctx).First(&order, payment.OrderID).Error; err != nil {
-		log.Printf("Warning: failed to get order for payment %d: %v", payment.ID, err)
-	} else {
-		order.PaymentStatus = models.PaymentStatusPaid
-		order.PaymentDate = &now
-		if err := s.db.WithContext(ctx).Save(&order).Error; err != nil {
-			log.Printf("Warning: failed to update order payment status: %v", err)
+	// Update order status to PAID and save the payment in one transaction,
+	// together with the order.paid event for webhook subscribers
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.First(&order, payment.OrderID).Error; err != nil {
+			slog.WarnContext(ctx, "failed to get order for payment", "component", "payment", "payment_id", payment.ID, "error", err)
+		} else if order.PaymentStatus != models.PaymentStatusPaid {
+			order.PaymentStatus = models.PaymentStatusPaid
+			order.PaymentDate = &now
+			if err := tx.Save(&order).Error; err != nil {
+				return fmt.Errorf("failed to update order payment status: %w", err)
+			}
+			if err := s.giftCards.Activate(tx, &order); err != nil {
+				return fmt.Errorf("failed to activate gift cards: %w", err)
+			}
+			if err := webhook.Publish(tx, webhook.EventOrderPaid, webhook.OrderData(&order)); err != nil {
+				return err
+			}
 		}
-	}
 
-	// Save payment changes
-	if err := s.db.WithContext(ctx).Save(payment).Error; err != nil {
-		return fmt.Errorf("failed to update payment: %w", err)
+		// Save payment changes
+		if err := tx.Save(payment).Error; err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
 	// Log the status change
@@ -583,11 +1000,11 @@ func (s *RevolutPaymentService) handleOrderPaymentFailed(ctx context.Context, pa
 	// Update order status to FAILED
 	var order models.Order
 	if err := s.db.WithContext(ctx).First(&order, payment.OrderID).Error; err != nil {
-		log.Printf("Warning: failed to get order for payment %d: %v", payment.ID, err)
+		slog.WarnContext(ctx, "failed to get order for payment", "component", "payment", "payment_id", payment.ID, "error", err)
 	} else {
 		order.PaymentStatus = models.PaymentStatusFailed
 		if err := s.db.WithContext(ctx).Save(&order).Error; err != nil {
-			log.Printf("Warning: failed to update order payment status: %v", err)
+			slog.WarnContext(ctx, "failed to update order payment status", "component", "payment", "payment_id", payment.ID, "error", err)
 		}
 	}
 
@@ -610,6 +1027,10 @@ func (s *RevolutPaymentService) handleOrderPaymentFailed(ctx context.Context, pa
 func (s *RevolutPaymentService) handleOrderAuthorized(ctx context.Context, payment *models.Payment, webhookData map[string]interface{}) error {
 	oldStatus := payment.Status
 	payment.Status = models.RevolutPaymentStatusAuthorized
+	if payment.AuthorizedAt == nil {
+		now := time.Now()
+		payment.AuthorizedAt = &now
+	}
 
 	// Save payment changes
 	if err := s.db.WithContext(ctx).Save(payment).Error; err != nil {
@@ -633,11 +1054,16 @@ func (s *RevolutPaymentService) handleOrderCancelled(ctx context.Context, paymen
 	// Update order status to CANCELLED
 	var order models.Order
 	if err := s.db.WithContext(ctx).First(&order, payment.OrderID).Error; err != nil {
-		log.Printf("Warning: failed to get order for payment %d: %v", payment.ID, err)
+		slog.WarnContext(ctx, "failed to get order for payment", "component", "payment", "payment_id", payment.ID, "error", err)
 	} else {
+		previousStatus := order.Status
 		order.Status = models.OrderStatusCancelled
 		if err := s.db.WithContext(ctx).Save(&order).Error; err != nil {
-			log.Printf("Warning: failed to update order status: %v", err)
+			slog.WarnContext(ctx, "failed to update order status", "component", "payment", "payment_id", payment.ID, "error", err)
+		} else if err := orderhistory.Record(s.db.WithContext(ctx), order.ID, previousStatus, order.Status, orderhistory.Change{Reason: "Payment cancelled by Revolut"}); err != nil {
+			slog.WarnContext(ctx, "failed to record order status change", "component", "payment", "payment_id", payment.ID, "error", err)
+		} else if err := releaseStock(s.db.WithContext(ctx), &order); err != nil {
+			slog.WarnContext(ctx, "failed to release order stock", "component", "payment", "payment_id", payment.ID, "error", err)
 		}
 	}
 
@@ -655,6 +1081,12 @@ func (s *RevolutPaymentService) handleOrderCancelled(ctx context.Context, paymen
 	return nil
 }
 
+// releaseStock gives back the stock reserved for an order cancelled with its
+// payment, as a checkout reserves stock before the order is paid
+func releaseStock(tx *gorm.DB, order *models.Order) error {
+	return fulfillment.NewAllocator(nil).Release(tx, order, nil)
+}
+
 // logPaymentEvent logs a payment event
 func (s *RevolutPaymentService) logPaymentEvent(ctx context.Context, paymentID uint, event, message string, metadata map[string]interface{}) {
 	paymentLog := &models.PaymentLog{
@@ -666,7 +1098,7 @@ func (s *RevolutPaymentService) logPaymentEvent(ctx context.Context, paymentID u
 	}
 
 	if err := s.db.WithContext(ctx).Create(paymentLog).Error; err != nil {
-		log.Printf("Warning: failed to log payment event: %v", err)
+		slog.WarnContext(ctx, "failed to log payment event", "component", "payment", "payment_id", paymentID, "error", err)
 	}
 }
 
@@ -146,6 +146,33 @@ func TestRevolutPaymentService_WebhookSignatureValidationWithTimestamp(t *testin
 	assert.False(t, isValid, "Signature validation should fail with wrong payload")
 }
 
+func TestRevolutPaymentService_WebhookSignatureDuringRotation(t *testing.T) {
+	config := &cfg.RevolutConfig{
+		WebhookSecret:         "wsk_new",
+		PreviousWebhookSecret: "wsk_old",
+	}
+	service := &RevolutPaymentService{
+		webhookSecret:         config.WebhookSecret,
+		previousWebhookSecret: config.PreviousWebhookSecret,
+		config:                config,
+	}
+
+	payload := []byte(`{"event": "ORDER_COMPLETED", "order_id": "test-order-id"}`)
+	timestamp := "1683650202360"
+	payloadToSign := fmt.Sprintf("v1.%s.%s", timestamp, string(payload))
+	newSignature := "v1=" + revolutSignature("wsk_new", payloadToSign)
+	oldSignature := "v1=" + revolutSignature("wsk_old", payloadToSign)
+
+	assert.True(t, service.validateWebhookSignature(payload, newSignature, timestamp))
+	assert.True(t, service.validateWebhookSignature(payload, oldSignature, timestamp), "the previous secret is accepted while rotating")
+	assert.True(t, service.validateWebhookSignature(payload, "v1=stale,"+newSignature, timestamp), "any of several signatures may match")
+	assert.False(t, service.validateWebhookSignature(payload, "v1="+revolutSignature("wsk_other", payloadToSign), timestamp))
+
+	// Once the rotation is over the previous secret no longer works
+	service.previousWebhookSecret = ""
+	assert.False(t, service.validateWebhookSignature(payload, oldSignature, timestamp))
+}
+
 func TestRevolutOrderRequest_JSONStructure(t *testing.T) {
 	// Test the JSON structure of a minimal order request
 	req := &revolut.OrderRequest{
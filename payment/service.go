@@ -2,9 +2,25 @@ package payment
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPaymentBlocked is returned when fraud checks refuse a payment
+	ErrPaymentBlocked = errors.New("payment was declined by fraud checks")
+
+	ErrInvalidCaptureMode   = errors.New("capture mode must be automatic or manual")
+	ErrNotCapturable        = errors.New("only authorized payments can be captured")
+	ErrInvalidCaptureAmount = errors.New("capture amount must be greater than 0 and at most the authorized amount")
+
+	ErrOrderNotPayable   = errors.New("order is not awaiting payment")
+	ErrRetryLimitReached = errors.New("order has reached the maximum number of payment attempts")
+	ErrRetryInProgress   = errors.New("a payment retry is already in progress for this order")
+	ErrCheckoutStillOpen = errors.New("the previous checkout of the order could not be closed")
 )
 
 // CustomerInfo represents customer information for payment processing
@@ -29,6 +45,25 @@ type PaymentRequest struct {
 	ReturnURL    string            `json:"return_url,omitempty"`
 	CancelURL    string            `json:"cancel_url,omitempty"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
+
+	// CaptureMode is automatic or manual; empty uses the order's capture
+	// mode, or manual for B2B orders when configured
+	CaptureMode string `json:"capture_mode,omitempty"`
+
+	// Fraud check signals
+	ClientIP        string `json:"client_ip,omitempty"`
+	CardFingerprint string `json:"card_fingerprint,omitempty"`
+
+	// SavedPaymentMethod is paid with by the merchant once the payment is
+	// created; when it is declined the customer pays at the checkout URL
+	SavedPaymentMethod *SavedPaymentMethod `json:"saved_payment_method,omitempty"`
+}
+
+// SavedPaymentMethod is a payment method a customer saved with the provider
+// for the merchant to pay with, such as for subscription orders
+type SavedPaymentMethod struct {
+	CustomerID string `json:"customer_id"` // the provider's ID of the customer who saved it
+	ID         string `json:"id"`
 }
 
 // PaymentResponse represents a response from creating a payment
@@ -38,10 +73,12 @@ type PaymentResponse struct {
 	Amount        float64    `json:"amount"`
 	Currency      string     `json:"currency"`
 	Status        string     `json:"status"`
+	CaptureMode   string     `json:"capture_mode"`
 	CheckoutURL   string     `json:"checkout_url,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
 	PaymentMethod string     `json:"payment_method,omitempty"`
+	HeldForReview bool       `json:"held_for_review,omitempty"` // authorized only, captured once an admin approves it
 }
 
 // RefundRequest represents a request to refund a payment
@@ -70,8 +107,13 @@ type PaymentService interface {
 	// GetPaymentStatus retrieves the current status of a payment
 	GetPaymentStatus(ctx context.Context, paymentID string) (string, error)
 
-	// CapturePayment captures an authorized payment
-	CapturePayment(ctx context.Context, paymentID string) error
+	// RetryPayment starts a new payment for an unpaid order, cancelling its
+	// stale pending payments
+	RetryPayment(ctx context.Context, req *PaymentRequest) (*PaymentResponse, error)
+
+	// CapturePayment captures an authorized payment; amount 0 captures the
+	// full authorized amount and less captures part of it
+	CapturePayment(ctx context.Context, paymentID string, amount float64) error
 
 	// RefundPayment refunds a payment
 	RefundPayment(ctx context.Context, req *RefundRequest) (*RefundResponse, error)
@@ -79,6 +121,14 @@ type PaymentService interface {
 	// CancelPayment cancels a pending payment
 	CancelPayment(ctx context.Context, paymentID string) error
 
+	// VoidPayment cancels a payment that has not been captured with the
+	// provider, releasing its authorization, and cancels its order
+	VoidPayment(ctx context.Context, paymentID string) error
+
+	// CancelProviderOrder cancels a Revolut order no payment is recorded
+	// for, such as one created in a transaction that then rolled back
+	CancelProviderOrder(ctx context.Context, revolutOrderID string) error
+
 	// HandleWebhook processes webhook notifications from the payment provider
 	HandleWebhook(ctx context.Context, payload []byte, signature string, timestamp string) error
 
@@ -87,6 +137,10 @@ type PaymentService interface {
 
 	// ListPayments retrieves a list of payments with optional filtering
 	ListPayments(ctx context.Context, orderID *uint, status *string, limit, offset int) ([]*models.Payment, int64, error)
+
+	// InTx returns a service that records payments in tx, so a payment can be
+	// created in the transaction that places its order
+	InTx(tx *gorm.DB) PaymentService
 }
 
 // PaymentEvent represents a payment event for logging
@@ -0,0 +1,212 @@
+package permissions
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Scope is a single granular permission, named "<resource>:<action>"
+type Scope string
+
+const (
+	OrdersRead        Scope = "orders:read"
+	OrdersWrite       Scope = "orders:write"
+	InventoryRead     Scope = "inventory:read"
+	InventoryWrite    Scope = "inventory:write"
+	PurchasingRead    Scope = "purchasing:read"
+	PurchasingWrite   Scope = "purchasing:write"
+	MarketingRead     Scope = "marketing:read"
+	MarketingWrite    Scope = "marketing:write"
+	ProductsWrite     Scope = "products:write"
+	PaymentsRefund    Scope = "payments:refund"
+	SupportRead       Scope = "support:read"
+	SupportAdmin      Scope = "support:admin"
+	UsersImpersonate  Scope = "users:impersonate"
+	PermissionsAdmin  Scope = "permissions:admin"
+	AuditRead         Scope = "audit:read"
+	ImpersonationRead Scope = "impersonation:read"
+	RateLimitsRead    Scope = "rate_limits:read"
+	WebhooksRead      Scope = "webhooks:read"
+	WebhooksWrite     Scope = "webhooks:write"
+	SMSRead           Scope = "sms:read"
+)
+
+// AllScopes lists every scope known to the application
+var AllScopes = []Scope{
+	OrdersRead,
+	OrdersWrite,
+	InventoryRead,
+	InventoryWrite,
+	PurchasingRead,
+	PurchasingWrite,
+	MarketingRead,
+	MarketingWrite,
+	ProductsWrite,
+	PaymentsRefund,
+	SupportRead,
+	SupportAdmin,
+	UsersImpersonate,
+	PermissionsAdmin,
+	AuditRead,
+	ImpersonationRead,
+	RateLimitsRead,
+	WebhooksRead,
+	WebhooksWrite,
+	SMSRead,
+}
+
+// DefaultRoleScopes is the role→scope mapping seeded into the role_scopes table.
+// Admins implicitly hold every scope and are not listed.
+var DefaultRoleScopes = map[models.UserType][]Scope{
+	models.SupportAgent: {SupportRead, SupportAdmin, OrdersRead, UsersImpersonate},
+	models.Vendor:       {ProductsWrite, InventoryRead, InventoryWrite},
+}
+
+var (
+	mu         sync.RWMutex
+	roleScopes = cloneDefaults()
+)
+
+func cloneDefaults() map[models.UserType]map[Scope]bool {
+	m := make(map[models.UserType]map[Scope]bool)
+	for role, scopes := range DefaultRoleScopes {
+		m[role] = make(map[Scope]bool)
+		for _, s := range scopes {
+			m[role][s] = true
+		}
+	}
+	return m
+}
+
+// IsValid reports whether s is a known scope
+func IsValid(s Scope) bool {
+	for _, known := range AllScopes {
+		if known == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether a role holds a scope
+func Has(role models.UserType, scope Scope) bool {
+	if role == models.Admin {
+		return true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return roleScopes[role][scope]
+}
+
+// HasAny reports whether a role holds at least one of the scopes
+func HasAny(role models.UserType, scopes ...Scope) bool {
+	for _, s := range scopes {
+		if Has(role, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextHas checks the scope for the authenticated user of a request
+func ContextHas(c *gin.Context, scope Scope) bool {
+	userType, exists := c.Get("user_type")
+	if !exists {
+		return false
+	}
+	role, ok := userType.(models.UserType)
+	return ok && Has(role, scope)
+}
+
+// ScopesFor returns the scopes held by a role
+func ScopesFor(role models.UserType) []Scope {
+	if role == models.Admin {
+		return append([]Scope(nil), AllScopes...)
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	scopes := make([]Scope, 0, len(roleScopes[role]))
+	for _, s := range AllScopes {
+		if roleScopes[role][s] {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// Load replaces the in-memory mapping with the role_scopes table, seeding the
+// table with the defaults when it is empty
+func Load(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.RoleScope{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count role scopes: %w", err)
+	}
+	if count == 0 {
+		var seed []models.RoleScope
+		for role, scopes := range DefaultRoleScopes {
+			for _, s := range scopes {
+				seed = append(seed, models.RoleScope{Role: role, Scope: string(s)})
+			}
+		}
+		if err := db.Create(&seed).Error; err != nil {
+			return fmt.Errorf("failed to seed role scopes: %w", err)
+		}
+	}
+
+	var rows []models.RoleScope
+	if err := db.Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load role scopes: %w", err)
+	}
+
+	m := make(map[models.UserType]map[Scope]bool)
+	for _, row := range rows {
+		if m[row.Role] == nil {
+			m[row.Role] = make(map[Scope]bool)
+		}
+		m[row.Role][Scope(row.Scope)] = true
+	}
+
+	mu.Lock()
+	roleScopes = m
+	mu.Unlock()
+	return nil
+}
+
+// SetRoleScopes replaces the scopes of a role in the database and in memory
+func SetRoleScopes(db *gorm.DB, role models.UserType, scopes []Scope) error {
+	for _, s := range scopes {
+		if !IsValid(s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RoleScope{}).Error; err != nil {
+			return err
+		}
+		if len(scopes) == 0 {
+			return nil
+		}
+		rows := make([]models.RoleScope, 0, len(scopes))
+		for _, s := range scopes {
+			rows = append(rows, models.RoleScope{Role: role, Scope: string(s)})
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update role scopes: %w", err)
+	}
+
+	set := make(map[Scope]bool, len(scopes))
+	for _, s := range scopes {
+		set[s] = true
+	}
+	mu.Lock()
+	roleScopes[role] = set
+	mu.Unlock()
+	return nil
+}
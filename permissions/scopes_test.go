@@ -0,0 +1,39 @@
+package permissions
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLoadAndSetRoleScopes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.RoleScope{}))
+
+	// An empty table is seeded with the defaults
+	require.NoError(t, Load(db))
+	var count int64
+	db.Model(&models.RoleScope{}).Count(&count)
+	assert.Equal(t, int64(len(DefaultRoleScopes[models.SupportAgent])+len(DefaultRoleScopes[models.Vendor])), count)
+	assert.True(t, Has(models.SupportAgent, SupportAdmin))
+	assert.False(t, Has(models.SupportAgent, PaymentsRefund))
+	assert.True(t, Has(models.Admin, PaymentsRefund))
+
+	require.NoError(t, SetRoleScopes(db, models.SupportAgent, []Scope{SupportRead, PaymentsRefund}))
+	assert.True(t, Has(models.SupportAgent, PaymentsRefund))
+	assert.False(t, Has(models.SupportAgent, SupportAdmin))
+
+	// The change survives a reload from the database
+	require.NoError(t, Load(db))
+	assert.Equal(t, []Scope{PaymentsRefund, SupportRead}, ScopesFor(models.SupportAgent))
+
+	assert.Error(t, SetRoleScopes(db, models.SupportAgent, []Scope{"unknown:scope"}))
+
+	// Restore defaults for other tests in the package
+	require.NoError(t, SetRoleScopes(db, models.SupportAgent, DefaultRoleScopes[models.SupportAgent]))
+}
@@ -0,0 +1,196 @@
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// Resolver resolves the unit price a customer pays for a variant using the order:
+// customer price list → price tiers → B2B price → base price
+type Resolver struct {
+	db *gorm.DB
+}
+
+// Sources a resolved price comes from
+const (
+	SourcePriceList = "price_list"
+	SourcePriceTier = "price_tier"
+	SourceB2B       = "b2b"
+	SourceBase      = "base"
+)
+
+// Resolution is a resolved unit price and where it comes from
+type Resolution struct {
+	Price  float64 `json:"price"`
+	Source string  `json:"source"` // price_list, price_tier, b2b or base
+}
+
+// NewResolver creates a new price resolver
+func NewResolver(db *gorm.DB) *Resolver {
+	return &Resolver{db: db}
+}
+
+// ResolveUnitPrice returns the catalogue price for a variant at the given quantity.
+// Price tiers take precedence; without tiers the B2B price is used for "b2b" lines.
+func ResolveUnitPrice(variant *models.ProductVariant, quantity int, priceType string) float64 {
+	return resolveCatalogue(variant, quantity, priceType).Price
+}
+
+func resolveCatalogue(variant *models.ProductVariant, quantity int, priceType string) Resolution {
+	if len(variant.PriceTiers) > 0 {
+		tiers := make([]models.ProductVariantPriceTier, len(variant.PriceTiers))
+		copy(tiers, variant.PriceTiers)
+		sort.Slice(tiers, func(i, j int) bool {
+			return tiers[i].MinQuantity > tiers[j].MinQuantity
+		})
+		for _, tier := range tiers {
+			if quantity >= tier.MinQuantity {
+				return Resolution{Price: tier.Price, Source: SourcePriceTier}
+			}
+		}
+		return Resolution{Price: variant.BasePrice, Source: SourceBase}
+	}
+	if priceType == "b2b" && variant.B2BPrice > 0 {
+		return Resolution{Price: variant.B2BPrice, Source: SourceB2B}
+	}
+	return Resolution{Price: variant.BasePrice, Source: SourceBase}
+}
+
+// PriceFromList returns the list price for a variant at the given quantity, if the
+// list has an entry for it. The entry with the highest MinQuantity not above quantity wins.
+func PriceFromList(list *models.PriceList, variantID uint, quantity int) (float64, bool) {
+	if list == nil {
+		return 0, false
+	}
+	found := false
+	bestMin := 0
+	var price float64
+	for _, item := range list.Items {
+		if item.ProductVariantID != variantID || quantity < item.MinQuantity {
+			continue
+		}
+		if !found || item.MinQuantity > bestMin {
+			found = true
+			bestMin = item.MinQuantity
+			price = item.Price
+		}
+	}
+	return price, found
+}
+
+// ActivePriceList returns the effective price list for a user, with items preloaded.
+// User-specific lists win over company lists, then higher priority wins. It returns
+// nil when no list applies.
+func (r *Resolver) ActivePriceList(userID uint) (*models.PriceList, error) {
+	if userID == 0 {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := r.db.Select("id", "company_id").First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	now := time.Now()
+	query := r.db.Preload("Items").
+		Where("is_active = ?", true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now)
+	if user.CompanyID != nil {
+		query = query.Where("user_id = ? OR (user_id IS NULL AND company_id = ?)", userID, *user.CompanyID)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var lists []models.PriceList
+	if err := query.Order("priority DESC").Order("id DESC").Find(&lists).Error; err != nil {
+		return nil, fmt.Errorf("failed to load price lists: %w", err)
+	}
+	if len(lists) == 0 {
+		return nil, nil
+	}
+
+	// Prefer lists assigned directly to the user
+	for i := range lists {
+		if lists[i].UserID != nil {
+			return &lists[i], nil
+		}
+	}
+	return &lists[0], nil
+}
+
+// UnitPriceWithList applies the resolution order using an already loaded price list
+func UnitPriceWithList(list *models.PriceList, variant *models.ProductVariant, quantity int, priceType string) float64 {
+	return ResolveWithList(list, variant, quantity, priceType).Price
+}
+
+// ResolveWithList applies the resolution order using an already loaded price
+// list and reports which step the price comes from
+func ResolveWithList(list *models.PriceList, variant *models.ProductVariant, quantity int, priceType string) Resolution {
+	if price, ok := PriceFromList(list, variant.ID, quantity); ok {
+		return Resolution{Price: price, Source: SourcePriceList}
+	}
+	return resolveCatalogue(variant, quantity, priceType)
+}
+
+// Tier is a quantity range bought at the same unit price. MaxQuantity is nil
+// for the last, open ended, range.
+type Tier struct {
+	MinQuantity int  `json:"min_quantity"`
+	MaxQuantity *int `json:"max_quantity"`
+	Resolution
+}
+
+// Tiers returns the unit price of a variant at every quantity from its minimum
+// up, as ranges: the table of quantity discounts a customer with list and
+// priceType gets. The variant should have its PriceTiers preloaded.
+func Tiers(list *models.PriceList, variant *models.ProductVariant, priceType string) []Tier {
+	// The price can only change where a price tier or list entry starts
+	from := max(variant.MinQuantity, 1)
+	breaks := []int{from}
+	for _, tier := range variant.PriceTiers {
+		breaks = append(breaks, tier.MinQuantity)
+	}
+	if list != nil {
+		for _, item := range list.Items {
+			if item.ProductVariantID == variant.ID {
+				breaks = append(breaks, item.MinQuantity)
+			}
+		}
+	}
+	sort.Ints(breaks)
+
+	var tiers []Tier
+	for _, quantity := range breaks {
+		if quantity < from || (len(tiers) > 0 && quantity == tiers[len(tiers)-1].MinQuantity) {
+			continue
+		}
+		resolution := ResolveWithList(list, variant, quantity, priceType)
+		if len(tiers) > 0 && tiers[len(tiers)-1].Resolution == resolution {
+			continue
+		}
+		if len(tiers) > 0 {
+			last := quantity - 1
+			tiers[len(tiers)-1].MaxQuantity = &last
+		}
+		tiers = append(tiers, Tier{MinQuantity: quantity, Resolution: resolution})
+	}
+	return tiers
+}
+
+// UnitPrice resolves the price a user pays for a variant. The variant should have
+// its PriceTiers preloaded.
+func (r *Resolver) UnitPrice(userID uint, variant *models.ProductVariant, quantity int, priceType string) (float64, error) {
+	list, err := r.ActivePriceList(userID)
+	if err != nil {
+		return 0, err
+	}
+	return UnitPriceWithList(list, variant, quantity, priceType), nil
+}
@@ -0,0 +1,117 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&models.Company{},
+		&models.User{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.ProductVariantPriceTier{},
+		&models.PriceList{},
+		&models.PriceListItem{},
+	)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestResolveUnitPrice(t *testing.T) {
+	variant := &models.ProductVariant{BasePrice: 10, B2BPrice: 8}
+	assert.Equal(t, 10.0, ResolveUnitPrice(variant, 1, "customer"))
+	assert.Equal(t, 8.0, ResolveUnitPrice(variant, 1, "b2b"))
+
+	variant.PriceTiers = []models.ProductVariantPriceTier{
+		{MinQuantity: 5, Price: 9},
+		{MinQuantity: 20, Price: 7},
+	}
+	assert.Equal(t, 10.0, ResolveUnitPrice(variant, 2, "customer"))
+	assert.Equal(t, 9.0, ResolveUnitPrice(variant, 5, "customer"))
+	assert.Equal(t, 7.0, ResolveUnitPrice(variant, 25, "b2b"))
+}
+
+func TestResolveUnitPrice_B2BFallsBackToBase(t *testing.T) {
+	variant := &models.ProductVariant{BasePrice: 10}
+	assert.Equal(t, 10.0, ResolveUnitPrice(variant, 1, "b2b"))
+}
+
+func TestResolverUnitPrice(t *testing.T) {
+	db := setupTestDB(t)
+	resolver := NewResolver(db)
+
+	company := models.Company{Name: "Acme"}
+	require.NoError(t, db.Create(&company).Error)
+	buyer := models.User{Email: "buyer@acme.test", Password: "x", UserType: models.Wholesaler, CompanyID: &company.ID}
+	require.NoError(t, db.Create(&buyer).Error)
+	other := models.User{Email: "other@example.test", Password: "x", UserType: models.Customer}
+	require.NoError(t, db.Create(&other).Error)
+
+	product := models.Product{Name: "Olive Oil", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{ProductID: product.ID, Name: "1L", SKU: "OIL-1L", BasePrice: 12, B2BPrice: 10, IsActive: true}
+	require.NoError(t, db.Create(&variant).Error)
+
+	list := models.PriceList{
+		Name:      "Acme contract",
+		CompanyID: &company.ID,
+		IsActive:  true,
+		Items: []models.PriceListItem{
+			{ProductVariantID: variant.ID, MinQuantity: 1, Price: 9},
+			{ProductVariantID: variant.ID, MinQuantity: 50, Price: 8},
+		},
+	}
+	require.NoError(t, db.Create(&list).Error)
+
+	price, err := resolver.UnitPrice(buyer.ID, &variant, 10, "b2b")
+	require.NoError(t, err)
+	assert.Equal(t, 9.0, price)
+
+	price, err = resolver.UnitPrice(buyer.ID, &variant, 60, "b2b")
+	require.NoError(t, err)
+	assert.Equal(t, 8.0, price)
+
+	// Users outside the company fall back to catalogue pricing
+	price, err = resolver.UnitPrice(other.ID, &variant, 10, "customer")
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, price)
+
+	// Lists outside their effective window are ignored
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, db.Model(&list).Update("ends_at", past).Error)
+	price, err = resolver.UnitPrice(buyer.ID, &variant, 10, "b2b")
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, price)
+}
+
+func TestTiers(t *testing.T) {
+	variant := &models.ProductVariant{Model: gorm.Model{ID: 1}, BasePrice: 10, B2BPrice: 9, MinQuantity: 2,
+		PriceTiers: []models.ProductVariantPriceTier{{MinQuantity: 1, Price: 10}, {MinQuantity: 12, Price: 8}, {MinQuantity: 50, Price: 7}}}
+	list := &models.PriceList{Items: []models.PriceListItem{
+		{ProductVariantID: 1, MinQuantity: 24, Price: 7.5},
+		{ProductVariantID: 2, MinQuantity: 1, Price: 1},
+	}}
+
+	last, listFrom := 11, 23
+	assert.Equal(t, []Tier{
+		{MinQuantity: 2, MaxQuantity: &last, Resolution: Resolution{Price: 10, Source: SourcePriceTier}},
+		{MinQuantity: 12, MaxQuantity: &listFrom, Resolution: Resolution{Price: 8, Source: SourcePriceTier}},
+		{MinQuantity: 24, Resolution: Resolution{Price: 7.5, Source: SourcePriceList}},
+	}, Tiers(list, variant, "customer"), "the list price wins from 24 units, even over the cheaper tier at 50")
+
+	variant.PriceTiers = nil
+	assert.Equal(t, []Tier{{MinQuantity: 2, Resolution: Resolution{Price: 9, Source: SourceB2B}}}, Tiers(nil, variant, "b2b"))
+	assert.Equal(t, Resolution{Price: 10, Source: SourceBase}, ResolveWithList(list, variant, 3, "customer"))
+}
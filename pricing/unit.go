@@ -0,0 +1,49 @@
+package pricing
+
+import (
+	"math"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// grams and millilitres in one of each weight and volume unit
+var (
+	massUnits = map[string]float64{
+		"mg": 0.001, "g": 1, "gram": 1, "grams": 1, "kg": 1000, "kilogram": 1000, "kilograms": 1000,
+		"oz": 28.349523125, "lb": 453.59237, "lbs": 453.59237,
+	}
+	volumeUnits = map[string]float64{
+		"ml": 1, "millilitre": 1, "milliliter": 1, "millilitres": 1, "milliliters": 1, "cl": 10, "dl": 100,
+		"l": 1000, "litre": 1000, "liter": 1000, "litres": 1000, "liters": 1000,
+	}
+)
+
+// PerMeasure converts the price of a variant into its price per standard
+// measure using its Weight and WeightUnit: per 100g or 100ml below a kilogram
+// or litre, and per kg or l from there. It returns nil when the variant has
+// no weight or its unit is not a weight or volume.
+func PerMeasure(price float64, variant *models.ProductVariant) *models.UnitPrice {
+	if variant.Weight <= 0 {
+		return nil
+	}
+	unit := strings.ToLower(strings.TrimSpace(variant.WeightUnit))
+	small, large := "100g", "kg"
+	amount, ok := massUnits[unit]
+	if !ok {
+		if amount, ok = volumeUnits[unit]; !ok {
+			return nil
+		}
+		small, large = "100ml", "l"
+	}
+
+	amount *= variant.Weight // in grams or millilitres
+	if amount < 1000 {
+		return &models.UnitPrice{Price: round(price / amount * 100), Per: small}
+	}
+	return &models.UnitPrice{Price: round(price / amount * 1000), Per: large}
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
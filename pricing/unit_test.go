@@ -0,0 +1,28 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerMeasure(t *testing.T) {
+	cases := []struct {
+		weight float64
+		unit   string
+		want   *models.UnitPrice
+	}{
+		{250, "g", &models.UnitPrice{Price: 1.2, Per: "100g"}},
+		{1.5, "KG", &models.UnitPrice{Price: 2, Per: "kg"}},
+		{500, "ml", &models.UnitPrice{Price: 0.6, Per: "100ml"}},
+		{75, "cl", &models.UnitPrice{Price: 0.4, Per: "100ml"}},
+		{2, "litres", &models.UnitPrice{Price: 1.5, Per: "l"}},
+		{1, "lb", &models.UnitPrice{Price: 0.66, Per: "100g"}},
+		{3, "pieces", nil},
+		{0, "g", nil},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, PerMeasure(3, &models.ProductVariant{Weight: c.weight, WeightUnit: c.unit}), "%g %s", c.weight, c.unit)
+	}
+}
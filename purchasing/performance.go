@@ -0,0 +1,147 @@
+package purchasing
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// PerformanceFilter selects the purchase orders a performance report covers,
+// by the date they were sent
+type PerformanceFilter struct {
+	SupplierID uint       // 0 for all suppliers
+	From       *time.Time // inclusive
+	To         *time.Time // exclusive
+}
+
+// SupplierPerformance measures how reliably a supplier delivers
+type SupplierPerformance struct {
+	SupplierID   uint   `json:"supplier_id"`
+	SupplierName string `json:"supplier_name"`
+	SupplierCode string `json:"supplier_code"`
+
+	Orders         int     `json:"orders"`          // orders sent
+	ReceivedOrders int     `json:"received_orders"` // orders received in full
+	OpenOrders     int     `json:"open_orders"`     // orders still awaiting stock
+	OrderValue     float64 `json:"order_value"`
+
+	// FillRate is the percentage of the quantity ordered that was received,
+	// over orders that have been delivered or are overdue
+	OrderedQuantity  int     `json:"ordered_quantity"`
+	ReceivedQuantity int     `json:"received_quantity"`
+	FillRate         float64 `json:"fill_rate"`
+
+	// AverageLeadTimeDays is the average time from sending an order to its
+	// first delivery, and OnTimeRate the percentage of first deliveries made
+	// by the expected date
+	AverageLeadTimeDays float64 `json:"average_lead_time_days"`
+	OnTimeRate          float64 `json:"on_time_rate"`
+	QuotedLeadTimeDays  int     `json:"quoted_lead_time_days"`
+}
+
+// SupplierPerformance reports the lead time and fill rate of suppliers, best
+// fill rate first
+func (s *Service) SupplierPerformance(ctx context.Context, filter PerformanceFilter) ([]SupplierPerformance, error) {
+	query := s.db.WithContext(ctx).
+		Preload("Supplier").
+		Preload("Items").
+		Preload("Receipts", func(db *gorm.DB) *gorm.DB { return db.Order("received_at") }).
+		Where("sent_date IS NOT NULL AND status <> ?", models.POStatusCancelled)
+	if filter.SupplierID != 0 {
+		query = query.Where("supplier_id = ?", filter.SupplierID)
+	}
+	if filter.From != nil {
+		query = query.Where("sent_date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("sent_date < ?", *filter.To)
+	}
+	var orders []models.PurchaseOrder
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	type totals struct {
+		report    SupplierPerformance
+		leadTime  float64
+		delivered int
+		onTime    int
+		timed     int
+	}
+	bySupplier := make(map[uint]*totals)
+	for _, order := range orders {
+		t := bySupplier[order.SupplierID]
+		if t == nil {
+			t = &totals{report: SupplierPerformance{SupplierID: order.SupplierID}}
+			t.report.SupplierName = order.Supplier.Name
+			t.report.SupplierCode = order.Supplier.Code
+			t.report.QuotedLeadTimeDays = order.Supplier.LeadTimeDays
+			bySupplier[order.SupplierID] = t
+		}
+
+		t.report.Orders++
+		t.report.OrderValue += order.TotalAmount
+		if order.Status == models.POStatusReceived {
+			t.report.ReceivedOrders++
+		} else {
+			t.report.OpenOrders++
+		}
+
+		overdue := order.ExpectedDate != nil && order.ExpectedDate.Before(now)
+		if len(order.Receipts) > 0 || overdue {
+			for _, item := range order.Items {
+				t.report.OrderedQuantity += item.Quantity
+				t.report.ReceivedQuantity += min(item.ReceivedQuantity, item.Quantity)
+			}
+		}
+
+		if len(order.Receipts) > 0 {
+			first := order.Receipts[0].ReceivedAt
+			t.leadTime += first.Sub(*order.SentDate).Hours() / 24
+			t.delivered++
+			if order.ExpectedDate != nil {
+				t.timed++
+				if !first.After(endOfDay(*order.ExpectedDate)) {
+					t.onTime++
+				}
+			}
+		} else if overdue {
+			// An overdue order with no delivery yet is late
+			t.timed++
+		}
+	}
+
+	reports := make([]SupplierPerformance, 0, len(bySupplier))
+	for _, t := range bySupplier {
+		report := t.report
+		report.OrderValue = round(report.OrderValue)
+		if report.OrderedQuantity > 0 {
+			report.FillRate = round(float64(report.ReceivedQuantity) / float64(report.OrderedQuantity) * 100)
+		}
+		if t.delivered > 0 {
+			report.AverageLeadTimeDays = round(t.leadTime / float64(t.delivered))
+		}
+		if t.timed > 0 {
+			report.OnTimeRate = round(float64(t.onTime) / float64(t.timed) * 100)
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].FillRate != reports[j].FillRate {
+			return reports[i].FillRate > reports[j].FillRate
+		}
+		return reports[i].SupplierID < reports[j].SupplierID
+	})
+	return reports, nil
+}
+
+// endOfDay returns the last instant of t's day, so a delivery on the expected
+// date counts as on time
+func endOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 23, 59, 59, int(time.Second-time.Nanosecond), t.Location())
+}
@@ -0,0 +1,398 @@
+// Package purchasing manages purchase orders to suppliers. A draft order is
+// sent to the supplier and its deliveries are booked in with goods receipts,
+// which add the stock to the order's warehouse as new or existing batches.
+// Receipts also feed the supplier performance report.
+package purchasing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MovementReceived is the stock movement type of goods received from a supplier
+const MovementReceived = "received"
+
+var (
+	// ErrInvalidStatus is returned when an action is not allowed in the
+	// purchase order's status
+	ErrInvalidStatus = errors.New("action not allowed in the purchase order's status")
+	// ErrInvalidItems is returned for orders or receipts with invalid lines
+	ErrInvalidItems = errors.New("invalid items")
+	// ErrInactiveSupplier is returned when ordering from an inactive supplier
+	ErrInactiveSupplier = errors.New("supplier is not active")
+)
+
+// OrderItem is a line of a purchase order to create or update
+type OrderItem struct {
+	ProductVariantID uint    `json:"product_variant_id" binding:"required"`
+	Quantity         int     `json:"quantity" binding:"required,min=1"`
+	UnitPrice        float64 `json:"unit_price" binding:"min=0"`
+	TaxAmount        float64 `json:"tax_amount" binding:"min=0"`
+}
+
+// OrderInput creates or updates a draft purchase order
+type OrderInput struct {
+	SupplierID     uint        `json:"supplier_id" binding:"required"`
+	WarehouseID    uint        `json:"warehouse_id" binding:"required"`
+	Currency       string      `json:"currency"`
+	ShippingAmount float64     `json:"shipping_amount" binding:"min=0"`
+	ShippingMethod string      `json:"shipping_method"`
+	ExpectedDate   *time.Time  `json:"expected_date"`
+	Notes          string      `json:"notes"`
+	Items          []OrderItem `json:"items" binding:"required,min=1,dive"`
+}
+
+// ReceiptItem is a quantity of a purchase order line received into a batch
+type ReceiptItem struct {
+	POItemID    uint       `json:"po_item_id" binding:"required"`
+	Quantity    int        `json:"quantity" binding:"required,min=1"`
+	BatchNumber string     `json:"batch_number"`
+	ExpiryDate  *time.Time `json:"expiry_date"`
+	BinLocation string     `json:"bin_location"` // bin to store a new batch in
+}
+
+// ReceiptInput records a delivery
+type ReceiptInput struct {
+	Notes string        `json:"notes"`
+	Items []ReceiptItem `json:"items" binding:"required,min=1,dive"`
+}
+
+// Service manages purchase orders
+type Service struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewService creates a purchasing service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db, now: time.Now}
+}
+
+// CreateOrder creates a draft purchase order
+func (s *Service) CreateOrder(ctx context.Context, input OrderInput, userID *uint) (*models.PurchaseOrder, error) {
+	order := &models.PurchaseOrder{
+		PONumber:    generatePONumber(),
+		Status:      models.POStatusDraft,
+		OrderDate:   s.now(),
+		CreatedByID: userID,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.applyInput(tx, order, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, order.ID)
+}
+
+// UpdateOrder replaces the supplier, warehouse, dates and lines of a draft order
+func (s *Service) UpdateOrder(ctx context.Context, id uint, input OrderInput) (*models.PurchaseOrder, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.POStatusDraft {
+			return ErrInvalidStatus
+		}
+		if err := tx.Where("po_id = ?", order.ID).Delete(&models.POItem{}).Error; err != nil {
+			return err
+		}
+		order.Items = nil
+		return s.applyInput(tx, order, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, id)
+}
+
+func (s *Service) applyInput(tx *gorm.DB, order *models.PurchaseOrder, input OrderInput) error {
+	var supplier models.Supplier
+	if err := tx.First(&supplier, input.SupplierID).Error; err != nil {
+		return err
+	}
+	if !supplier.IsActive {
+		return ErrInactiveSupplier
+	}
+	var warehouse models.Warehouse
+	if err := tx.First(&warehouse, input.WarehouseID).Error; err != nil {
+		return err
+	}
+	if len(input.Items) == 0 {
+		return fmt.Errorf("%w: a purchase order needs at least one item", ErrInvalidItems)
+	}
+
+	order.SupplierID = supplier.ID
+	order.WarehouseID = warehouse.ID
+	order.ExpectedDate = input.ExpectedDate
+	order.ShippingAmount = input.ShippingAmount
+	order.ShippingMethod = input.ShippingMethod
+	order.Notes = input.Notes
+	if input.Currency != "" {
+		order.Currency = input.Currency
+	}
+	order.TotalAmount, order.TaxAmount = 0, 0
+	var lines []models.POItem
+	for _, item := range input.Items {
+		if item.Quantity <= 0 || item.UnitPrice < 0 || item.TaxAmount < 0 {
+			return fmt.Errorf("%w: quantities must be positive and prices not negative", ErrInvalidItems)
+		}
+		var variant models.ProductVariant
+		if err := tx.Select("id", "product_id").First(&variant, item.ProductVariantID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("%w: product variant %d not found", ErrInvalidItems, item.ProductVariantID)
+			}
+			return err
+		}
+		line := models.POItem{
+			ProductID:        variant.ProductID,
+			ProductVariantID: variant.ID,
+			Quantity:         item.Quantity,
+			UnitPrice:        item.UnitPrice,
+			TaxAmount:        item.TaxAmount,
+			TotalAmount:      round(float64(item.Quantity) * item.UnitPrice),
+			Status:           "pending",
+		}
+		order.TotalAmount += line.TotalAmount
+		order.TaxAmount += line.TaxAmount
+		lines = append(lines, line)
+	}
+	order.TotalAmount = round(order.TotalAmount)
+	order.TaxAmount = round(order.TaxAmount)
+	order.FinalAmount = round(order.TotalAmount + order.TaxAmount + order.ShippingAmount)
+	if err := tx.Omit(clause.Associations).Save(order).Error; err != nil {
+		return err
+	}
+	for i := range lines {
+		lines[i].POID = order.ID
+	}
+	return tx.Omit(clause.Associations).Create(&lines).Error
+}
+
+// GetOrder loads a purchase order with its lines and receipts
+func (s *Service) GetOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	if err := s.db.WithContext(ctx).
+		Preload("Supplier").
+		Preload("Warehouse").
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.ProductVariant").
+		Preload("Receipts", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Receipts.Items").
+		First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// SendOrder marks a draft order as sent to the supplier. Without an expected
+// date the order is expected after the supplier's lead time.
+func (s *Service) SendOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.POStatusDraft {
+			return ErrInvalidStatus
+		}
+		var supplier models.Supplier
+		if err := tx.First(&supplier, order.SupplierID).Error; err != nil {
+			return err
+		}
+		if !supplier.IsActive {
+			return ErrInactiveSupplier
+		}
+
+		now := s.now()
+		updates := map[string]interface{}{"status": models.POStatusSent, "sent_date": &now}
+		if order.ExpectedDate == nil && supplier.LeadTimeDays > 0 {
+			expected := now.AddDate(0, 0, supplier.LeadTimeDays)
+			updates["expected_date"] = &expected
+		}
+		return tx.Model(order).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, id)
+}
+
+// CancelOrder cancels an order nothing has been received against
+func (s *Service) CancelOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.POStatusDraft && order.Status != models.POStatusSent {
+			return ErrInvalidStatus
+		}
+		now := s.now()
+		return tx.Model(order).Updates(map[string]interface{}{"status": models.POStatusCancelled, "cancelled_date": &now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, id)
+}
+
+// Receive books a delivery against a sent order. Each line's stock is added
+// to the batch with its batch number in the order's warehouse, creating the
+// batch when needed, and recorded as a received movement. The order becomes
+// partially received, or received once nothing is outstanding.
+func (s *Service) Receive(ctx context.Context, id uint, input ReceiptInput, userID *uint) (*models.GoodsReceipt, error) {
+	var receipt *models.GoodsReceipt
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.POStatusSent && order.Status != models.POStatusPartiallyReceived {
+			return ErrInvalidStatus
+		}
+		if len(input.Items) == 0 {
+			return fmt.Errorf("%w: a receipt needs at least one item", ErrInvalidItems)
+		}
+		var lines []models.POItem
+		if err := tx.Where("po_id = ?", order.ID).Order("id").Find(&lines).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*models.POItem, len(lines))
+		for i := range lines {
+			byID[lines[i].ID] = &lines[i]
+		}
+
+		now := s.now()
+		receipt = &models.GoodsReceipt{POID: order.ID, ReceivedByID: userID, ReceivedAt: now, Notes: input.Notes}
+		if err := tx.Create(receipt).Error; err != nil {
+			return err
+		}
+
+		var variantIDs []uint
+		for _, received := range input.Items {
+			line := byID[received.POItemID]
+			if line == nil {
+				return fmt.Errorf("%w: item %d is not on this purchase order", ErrInvalidItems, received.POItemID)
+			}
+			if received.Quantity <= 0 || received.Quantity > line.Outstanding() {
+				return fmt.Errorf("%w: item %d has %d outstanding", ErrInvalidItems, line.ID, line.Outstanding())
+			}
+
+			item, err := receiveIntoBatch(tx, order, line, &received)
+			if err != nil {
+				return err
+			}
+			receiptItem := models.GoodsReceiptItem{
+				GoodsReceiptID:  receipt.ID,
+				POItemID:        line.ID,
+				InventoryItemID: item.ID,
+				Quantity:        received.Quantity,
+				BatchNumber:     received.BatchNumber,
+				ExpiryDate:      received.ExpiryDate,
+			}
+			if err := tx.Create(&receiptItem).Error; err != nil {
+				return err
+			}
+			receipt.Items = append(receipt.Items, receiptItem)
+
+			line.ReceivedQuantity += received.Quantity
+			line.Status = "partial"
+			if line.Outstanding() == 0 {
+				line.Status = "complete"
+			}
+			if err := tx.Model(line).Updates(map[string]interface{}{"received_quantity": line.ReceivedQuantity, "status": line.Status}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.StockMovement{
+				InventoryItemID: item.ID,
+				MovementType:    MovementReceived,
+				Quantity:        received.Quantity,
+				Reason:          "Received from purchase order " + order.PONumber,
+				Reference:       order.PONumber,
+				UserID:          userID,
+			}).Error; err != nil {
+				return err
+			}
+			variantIDs = append(variantIDs, line.ProductVariantID)
+		}
+
+		updates := map[string]interface{}{"status": models.POStatusReceived, "received_date": &now}
+		for _, line := range lines {
+			if line.Outstanding() > 0 {
+				updates = map[string]interface{}{"status": models.POStatusPartiallyReceived}
+				break
+			}
+		}
+		if err := tx.Model(order).Updates(updates).Error; err != nil {
+			return err
+		}
+		return stock.SyncVariantStock(tx, variantIDs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// receiveIntoBatch adds a received quantity to its batch in the order's
+// warehouse, creating the batch when it does not exist
+func receiveIntoBatch(tx *gorm.DB, order *models.PurchaseOrder, line *models.POItem, received *ReceiptItem) (*models.InventoryItem, error) {
+	var item models.InventoryItem
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_variant_id = ? AND warehouse_id = ? AND batch_number = ?", line.ProductVariantID, order.WarehouseID, received.BatchNumber).
+		First(&item).Error
+	if err == gorm.ErrRecordNotFound {
+		item = models.InventoryItem{
+			ProductVariantID: line.ProductVariantID,
+			WarehouseID:      order.WarehouseID,
+			Quantity:         received.Quantity,
+			BatchNumber:      received.BatchNumber,
+			ExpiryDate:       received.ExpiryDate,
+			BinLocation:      received.BinLocation,
+			Status:           stock.StatusActive,
+		}
+		return &item, tx.Omit("ProductVariant", "Warehouse").Create(&item).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"quantity": gorm.Expr("quantity + ?", received.Quantity)}
+	if received.ExpiryDate != nil && item.ExpiryDate == nil {
+		updates["expiry_date"] = received.ExpiryDate
+	}
+	if err := tx.Model(&item).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	item.Quantity += received.Quantity
+	return &item, nil
+}
+
+func lockOrder(tx *gorm.DB, id uint) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func generatePONumber() string {
+	now := time.Now()
+	return fmt.Sprintf("PO-%d%02d%02d-%d",
+		now.Year(), now.Month(), now.Day(),
+		now.UnixNano()%100000)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
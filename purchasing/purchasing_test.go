@@ -0,0 +1,246 @@
+package purchasing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Address{},
+		&models.Warehouse{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.BundleComponent{},
+		&models.InventoryItem{},
+		&models.StockMovement{},
+		&models.User{},
+		&models.Supplier{},
+		&models.SupplierContact{},
+		&models.PurchaseOrder{},
+		&models.POItem{},
+		&models.GoodsReceipt{},
+		&models.GoodsReceiptItem{},
+	))
+	return db
+}
+
+type fixture struct {
+	supplier  models.Supplier
+	warehouse models.Warehouse
+	variants  []models.ProductVariant
+}
+
+func createFixture(t *testing.T, db *gorm.DB) fixture {
+	var f fixture
+	f.supplier = models.Supplier{Name: "Cyprus Dairies", Code: "CYD", IsActive: true, LeadTimeDays: 5}
+	require.NoError(t, db.Create(&f.supplier).Error)
+	f.warehouse = models.Warehouse{Name: "London Warehouse", Code: "WH-LON", Address: models.Address{StreetAddress1: "1 Dock Road", City: "London", Country: "GB"}}
+	require.NoError(t, db.Create(&f.warehouse).Error)
+	product := models.Product{Name: "Halloumi", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	for _, sku := range []string{"HAL-250", "HAL-1000"} {
+		variant := models.ProductVariant{ProductID: product.ID, Name: sku, SKU: sku}
+		require.NoError(t, db.Omit("Product").Create(&variant).Error)
+		f.variants = append(f.variants, variant)
+	}
+	return f
+}
+
+func newTestService(db *gorm.DB, now time.Time) *Service {
+	service := NewService(db)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func (f fixture) orderInput() OrderInput {
+	return OrderInput{
+		SupplierID:     f.supplier.ID,
+		WarehouseID:    f.warehouse.ID,
+		ShippingAmount: 10,
+		Items: []OrderItem{
+			{ProductVariantID: f.variants[0].ID, Quantity: 10, UnitPrice: 1.25},
+			{ProductVariantID: f.variants[1].ID, Quantity: 4, UnitPrice: 4.5, TaxAmount: 3.6},
+		},
+	}
+}
+
+func TestCreateAndSendOrder(t *testing.T) {
+	db := setupTestDB(t)
+	f := createFixture(t, db)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	service := newTestService(db, now)
+	ctx := context.Background()
+
+	order, err := service.CreateOrder(ctx, f.orderInput(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.POStatusDraft, order.Status)
+	require.Len(t, order.Items, 2)
+	assert.Equal(t, 12.5, order.Items[0].TotalAmount)
+	assert.Equal(t, 30.5, order.TotalAmount)
+	assert.Equal(t, 44.1, order.FinalAmount)
+
+	// Drafts can be edited; the lines are replaced
+	input := f.orderInput()
+	input.Items = input.Items[:1]
+	order, err = service.UpdateOrder(ctx, order.ID, input)
+	require.NoError(t, err)
+	require.Len(t, order.Items, 1)
+	assert.Equal(t, 12.5, order.TotalAmount)
+
+	order, err = service.SendOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.POStatusSent, order.Status)
+	require.NotNil(t, order.SentDate)
+	require.NotNil(t, order.ExpectedDate)
+	assert.True(t, order.ExpectedDate.Equal(now.AddDate(0, 0, 5)), "expected after the supplier's lead time")
+
+	_, err = service.UpdateOrder(ctx, order.ID, input)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	_, err = service.SendOrder(ctx, order.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	input.Items = []OrderItem{{ProductVariantID: 999, Quantity: 1}}
+	_, err = service.CreateOrder(ctx, input, nil)
+	assert.ErrorIs(t, err, ErrInvalidItems)
+
+	require.NoError(t, db.Model(&f.supplier).Update("is_active", false).Error)
+	_, err = service.CreateOrder(ctx, f.orderInput(), nil)
+	assert.ErrorIs(t, err, ErrInactiveSupplier)
+}
+
+func TestReceive(t *testing.T) {
+	db := setupTestDB(t)
+	f := createFixture(t, db)
+	service := newTestService(db, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	ctx := context.Background()
+
+	order, err := service.CreateOrder(ctx, f.orderInput(), nil)
+	require.NoError(t, err)
+	first, second := order.Items[0], order.Items[1]
+	expiry := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err = service.Receive(ctx, order.ID, ReceiptInput{Items: []ReceiptItem{{POItemID: first.ID, Quantity: 1}}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidStatus, "drafts cannot be received")
+	_, err = service.SendOrder(ctx, order.ID)
+	require.NoError(t, err)
+
+	_, err = service.Receive(ctx, order.ID, ReceiptInput{Items: []ReceiptItem{{POItemID: first.ID, Quantity: 11}}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidItems, "cannot receive more than ordered")
+
+	// The first delivery is split over two batches of the first line
+	receipt, err := service.Receive(ctx, order.ID, ReceiptInput{Items: []ReceiptItem{
+		{POItemID: first.ID, Quantity: 6, BatchNumber: "L100", ExpiryDate: &expiry},
+		{POItemID: first.ID, Quantity: 4, BatchNumber: "L101", ExpiryDate: &expiry},
+		{POItemID: second.ID, Quantity: 1, BatchNumber: "L200"},
+	}}, nil)
+	require.NoError(t, err)
+	require.Len(t, receipt.Items, 3)
+
+	order, err = service.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.POStatusPartiallyReceived, order.Status)
+	assert.Equal(t, "complete", order.Items[0].Status)
+	assert.Equal(t, "partial", order.Items[1].Status)
+	assert.Nil(t, order.ReceivedDate)
+
+	var batch models.InventoryItem
+	require.NoError(t, db.Where("batch_number = ?", "L100").First(&batch).Error)
+	assert.Equal(t, 6, batch.Quantity)
+	assert.Equal(t, f.warehouse.ID, batch.WarehouseID)
+	require.NotNil(t, batch.ExpiryDate)
+	assert.True(t, batch.ExpiryDate.Equal(expiry))
+
+	// Receiving into an existing batch adds to it
+	_, err = service.Receive(ctx, order.ID, ReceiptInput{Items: []ReceiptItem{{POItemID: second.ID, Quantity: 3, BatchNumber: "L200"}}}, nil)
+	require.NoError(t, err)
+	var existing models.InventoryItem
+	require.NoError(t, db.Where("batch_number = ?", "L200").First(&existing).Error)
+	assert.Equal(t, 4, existing.Quantity)
+
+	order, err = service.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.POStatusReceived, order.Status)
+	assert.NotNil(t, order.ReceivedDate)
+	assert.Len(t, order.Receipts, 2)
+
+	var variant models.ProductVariant
+	require.NoError(t, db.First(&variant, f.variants[0].ID).Error)
+	assert.Equal(t, 10, variant.QuantityInStock)
+
+	var movements []models.StockMovement
+	require.NoError(t, db.Where("movement_type = ?", MovementReceived).Find(&movements).Error)
+	require.Len(t, movements, 4)
+	assert.Equal(t, order.PONumber, movements[0].Reference)
+
+	_, err = service.Receive(ctx, order.ID, ReceiptInput{Items: []ReceiptItem{{POItemID: second.ID, Quantity: 1}}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	_, err = service.CancelOrder(ctx, order.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatus, "received orders cannot be cancelled")
+}
+
+func TestSupplierPerformance(t *testing.T) {
+	db := setupTestDB(t)
+	f := createFixture(t, db)
+	sent := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	service := newTestService(db, sent)
+	ctx := context.Background()
+
+	send := func() *models.PurchaseOrder {
+		order, err := service.CreateOrder(ctx, f.orderInput(), nil)
+		require.NoError(t, err)
+		order, err = service.SendOrder(ctx, order.ID)
+		require.NoError(t, err)
+		return order
+	}
+
+	// Delivered in full after 4 days, on time
+	onTime := send()
+	service.now = func() time.Time { return sent.AddDate(0, 0, 4) }
+	_, err := service.Receive(ctx, onTime.ID, ReceiptInput{Items: []ReceiptItem{
+		{POItemID: onTime.Items[0].ID, Quantity: 10},
+		{POItemID: onTime.Items[1].ID, Quantity: 4},
+	}}, nil)
+	require.NoError(t, err)
+
+	// Half delivered after 8 days, late
+	service.now = func() time.Time { return sent }
+	late := send()
+	service.now = func() time.Time { return sent.AddDate(0, 0, 8) }
+	_, err = service.Receive(ctx, late.ID, ReceiptInput{Items: []ReceiptItem{{POItemID: late.Items[0].ID, Quantity: 7}}}, nil)
+	require.NoError(t, err)
+
+	// Not due yet, so it does not count against the fill rate
+	service.now = func() time.Time { return sent.AddDate(0, 0, 8) }
+	send()
+
+	reports, err := service.SupplierPerformance(ctx, PerformanceFilter{})
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	report := reports[0]
+	assert.Equal(t, "CYD", report.SupplierCode)
+	assert.Equal(t, 3, report.Orders)
+	assert.Equal(t, 1, report.ReceivedOrders)
+	assert.Equal(t, 2, report.OpenOrders)
+	assert.Equal(t, 28, report.OrderedQuantity)
+	assert.Equal(t, 21, report.ReceivedQuantity)
+	assert.Equal(t, 75.0, report.FillRate)
+	assert.Equal(t, 6.0, report.AverageLeadTimeDays)
+	assert.Equal(t, 50.0, report.OnTimeRate)
+	assert.Equal(t, 5, report.QuotedLeadTimeDays)
+
+	from := sent.AddDate(0, 0, 1)
+	reports, err = service.SupplierPerformance(ctx, PerformanceFilter{From: &from})
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, 1, reports[0].Orders)
+}
@@ -0,0 +1,116 @@
+// Package ratelimit throttles API requests with token buckets, kept in Redis
+// when it is available so limits hold across instances.
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+)
+
+// Class is a group of routes sharing a limit
+type Class string
+
+const (
+	// ClassGlobal applies to every request, per user when authenticated and per IP otherwise
+	ClassGlobal  Class = "global"
+	ClassAuth    Class = "auth"
+	ClassReview  Class = "review"
+	ClassContact Class = "contact"
+)
+
+// Limiter decides whether a client may make another request
+type Limiter struct {
+	store  Store
+	config cfg.RateLimitConfig
+
+	mu        sync.Mutex
+	throttled map[Class]*atomic.Int64
+}
+
+// NewLimiter creates a limiter. A nil config uses the defaults.
+func NewLimiter(store Store, config *cfg.RateLimitConfig) *Limiter {
+	c := cfg.RateLimitConfig{
+		Enabled:          true,
+		IPPerMinute:      120,
+		UserPerMinute:    300,
+		AuthPerMinute:    10,
+		ReviewPerMinute:  5,
+		ContactPerMinute: 3,
+	}
+	if config != nil {
+		c = *config
+	}
+	return &Limiter{store: store, config: c, throttled: make(map[Class]*atomic.Int64)}
+}
+
+// Enabled reports whether requests are limited at all
+func (l *Limiter) Enabled() bool {
+	return l.config.Enabled
+}
+
+// Limit returns the requests per minute allowed for a class. A limit of zero
+// or less means the class is not limited.
+func (l *Limiter) Limit(class Class, authenticated bool) int {
+	switch class {
+	case ClassAuth:
+		return l.config.AuthPerMinute
+	case ClassReview:
+		return l.config.ReviewPerMinute
+	case ClassContact:
+		return l.config.ContactPerMinute
+	}
+	if authenticated {
+		return l.config.UserPerMinute
+	}
+	return l.config.IPPerMinute
+}
+
+// Allow takes a request from the client's bucket for class. The client is a
+// user ID when userID is non-zero, the IP address otherwise. When the request
+// is throttled it returns false and how long the client should wait.
+func (l *Limiter) Allow(class Class, userID uint, ip string) (bool, time.Duration, error) {
+	limit := l.Limit(class, userID != 0)
+	if !l.config.Enabled || limit <= 0 {
+		return true, 0, nil
+	}
+	key := fmt.Sprintf("ratelimit:%s:ip:%s", class, ip)
+	if userID != 0 {
+		key = fmt.Sprintf("ratelimit:%s:user:%d", class, userID)
+	}
+	allowed, wait, err := l.store.Take(key, limit, time.Minute)
+	if err != nil {
+		return true, 0, err
+	}
+	if !allowed {
+		l.counter(class).Add(1)
+		metrics.RateLimitThrottled.WithLabelValues(string(class)).Inc()
+	}
+	return allowed, wait, nil
+}
+
+func (l *Limiter) counter(class Class) *atomic.Int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counter, ok := l.throttled[class]
+	if !ok {
+		counter = new(atomic.Int64)
+		l.throttled[class] = counter
+	}
+	return counter
+}
+
+// Throttled returns the number of requests rejected per class since startup
+func (l *Limiter) Throttled() map[Class]int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counts := make(map[Class]int64, len(l.throttled))
+	for class, counter := range l.throttled {
+		counts[class] = counter.Load()
+	}
+	return counts
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreRefills(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := store.Take("k", 3, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, wait, err := store.Take("k", 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 20*time.Second, wait)
+
+	// One token comes back every 20 seconds
+	now = now.Add(20 * time.Second)
+	allowed, _, err = store.Take("k", 3, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, _, err = store.Take("k", 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestLimiterClasses(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), &cfg.RateLimitConfig{Enabled: true, IPPerMinute: 2, UserPerMinute: 4, AuthPerMinute: 1})
+
+	// Anonymous clients share a bucket per IP, users get their own
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Allow(ClassGlobal, 0, "10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, _, _ := limiter.Allow(ClassGlobal, 0, "10.0.0.1")
+	assert.False(t, allowed)
+	allowed, _, _ = limiter.Allow(ClassGlobal, 7, "10.0.0.1")
+	assert.True(t, allowed)
+
+	// Route classes have separate buckets
+	allowed, _, _ = limiter.Allow(ClassAuth, 0, "10.0.0.1")
+	assert.True(t, allowed)
+	allowed, _, _ = limiter.Allow(ClassAuth, 0, "10.0.0.1")
+	assert.False(t, allowed)
+
+	// A zero limit leaves the class unlimited
+	for i := 0; i < 10; i++ {
+		allowed, _, _ = limiter.Allow(ClassContact, 0, "10.0.0.1")
+		assert.True(t, allowed)
+	}
+
+	assert.Equal(t, map[Class]int64{ClassGlobal: 1, ClassAuth: 1}, limiter.Throttled())
+}
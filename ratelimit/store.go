@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store holds token buckets
+type Store interface {
+	// Take removes a token from the bucket at key. The bucket holds up to
+	// capacity tokens and refills capacity tokens every period. When the bucket
+	// is empty it returns false and the time until the next token.
+	Take(key string, capacity int, period time.Duration) (bool, time.Duration, error)
+}
+
+// takeScript refills and takes from a bucket atomically, using the Redis
+// clock so every instance agrees on the time
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = capacity / tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or capacity
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], tonumber(ARGV[2]))
+return {allowed, wait}
+`)
+
+// RedisStore keeps buckets in Redis so limits are shared across instances
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a Redis backed store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Take(key string, capacity int, period time.Duration) (bool, time.Duration, error) {
+	result, err := takeScript.Run(context.Background(), s.client, []string{key}, capacity, period.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// MemoryStore is an in-process store used when Redis is not available
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// NewMemoryStore creates an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]bucket), now: time.Now}
+}
+
+func (s *MemoryStore) Take(key string, capacity int, period time.Duration) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	rate := float64(capacity) / float64(period)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(capacity), at: now}
+	}
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = math.Min(float64(capacity), b.tokens+float64(elapsed)*rate)
+	}
+	b.at = now
+	s.prune(now, period)
+
+	if b.tokens < 1 {
+		s.buckets[key] = b
+		return false, time.Duration(math.Ceil((1 - b.tokens) / rate)), nil
+	}
+	b.tokens--
+	s.buckets[key] = b
+	return true, 0, nil
+}
+
+// prune drops buckets idle for longer than period, which are full again.
+// Callers must hold the lock.
+func (s *MemoryStore) prune(now time.Time, period time.Duration) {
+	if len(s.buckets) < 10000 {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.at) > period {
+			delete(s.buckets, key)
+		}
+	}
+}
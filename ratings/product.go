@@ -0,0 +1,119 @@
+package ratings
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RollUp rebuilds the ProductOverallRating of a product from the ratings of
+// its variants that are not deleted. The overall rating row is created when
+// missing and locked before the variant ratings are read, so that rolling up
+// the same product for changes to two of its variants happens one after the
+// other, the second seeing the variant rating the first committed.
+func RollUp(tx *gorm.DB, productID uint) error {
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "product_id"}}, DoNothing: true}).
+		Create(&models.ProductOverallRating{ProductID: productID, RatingBreakdown: `{"1":0,"2":0,"3":0,"4":0,"5":0}`}).Error
+	if err != nil {
+		return fmt.Errorf("failed to create overall rating: %w", err)
+	}
+	var overall models.ProductOverallRating
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("product_id = ?", productID).First(&overall).Error; err != nil {
+		return fmt.Errorf("failed to lock overall rating: %w", err)
+	}
+
+	var variantRatings []models.ProductRating
+	err = tx.Joins("JOIN product_variants ON product_variants.id = product_ratings.product_variant_id AND product_variants.deleted_at IS NULL").
+		Where("product_variants.product_id = ?", productID).
+		Find(&variantRatings).Error
+	if err != nil {
+		return fmt.Errorf("failed to load variant ratings: %w", err)
+	}
+	breakdown := newBreakdown()
+	for i := range variantRatings {
+		// A variant rating that cannot be read is left out until the
+		// reconciliation rebuilds it
+		current, err := stored(&variantRatings[i])
+		if err != nil {
+			continue
+		}
+		for stars, count := range current.RatingBreakdown {
+			breakdown[stars] += count
+		}
+	}
+
+	columns, err := aggregateColumns(newAggregate(breakdown))
+	if err != nil {
+		return err
+	}
+	return tx.Model(&overall).Updates(columns).Error
+}
+
+// Overall returns the overall rating of a product, empty when it has none
+func Overall(db *gorm.DB, productID uint) (Aggregate, error) {
+	var overall models.ProductOverallRating
+	err := db.Where("product_id = ?", productID).First(&overall).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return newAggregate(newBreakdown()), nil
+	}
+	if err != nil {
+		return Aggregate{}, fmt.Errorf("failed to load overall rating: %w", err)
+	}
+	return storedOverall(&overall)
+}
+
+// rollUpVariant rolls up the overall rating of the product a variant belongs
+// to, deleted or not
+func rollUpVariant(tx *gorm.DB, productVariantID uint) error {
+	var variant models.ProductVariant
+	err := tx.Unscoped().Select("id, product_id").First(&variant, productVariantID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find variant product: %w", err)
+	}
+	return RollUp(tx, variant.ProductID)
+}
+
+// approvedProductBreakdowns counts the approved reviews of the variants that
+// are not deleted per product and rating
+func approvedProductBreakdowns(db *gorm.DB) (map[uint]Breakdown, error) {
+	var rows []struct {
+		ProductID uint
+		Rating    int
+		Count     int
+	}
+	err := db.Model(&models.ProductReview{}).
+		Select("product_variants.product_id, product_reviews.rating, COUNT(*) AS count").
+		Joins("JOIN product_variants ON product_variants.id = product_reviews.product_variant_id AND product_variants.deleted_at IS NULL").
+		Where("product_reviews.status = ?", models.ReviewStatusApproved).
+		Group("product_variants.product_id, product_reviews.rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count product reviews: %w", err)
+	}
+
+	breakdowns := map[uint]Breakdown{}
+	for _, row := range rows {
+		breakdown, ok := breakdowns[row.ProductID]
+		if !ok {
+			breakdown = newBreakdown()
+			breakdowns[row.ProductID] = breakdown
+		}
+		breakdown[row.Rating] = row.Count
+	}
+	return breakdowns, nil
+}
+
+// storedOverall reads the aggregate of a ProductOverallRating
+func storedOverall(overall *models.ProductOverallRating) (Aggregate, error) {
+	return stored(&models.ProductRating{
+		AverageRating:   overall.AverageRating,
+		TotalReviews:    overall.TotalReviews,
+		RatingBreakdown: overall.RatingBreakdown,
+	})
+}
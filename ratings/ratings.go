@@ -0,0 +1,195 @@
+// Package ratings maintains the ProductRating aggregates of product variants
+// from their approved reviews, and the ProductOverallRating of each product
+// from the ratings of its variants. Review changes are applied to the
+// aggregates as they happen, and a nightly reconciliation rebuilds the ones
+// that drifted.
+package ratings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Breakdown counts the approved reviews of a variant per star rating, 1 to 5
+type Breakdown map[int]int
+
+func newBreakdown() Breakdown {
+	return Breakdown{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+}
+
+// Aggregate is the rating of a variant, stored as its ProductRating
+type Aggregate struct {
+	AverageRating   float64   `json:"average_rating"`
+	TotalReviews    int       `json:"total_reviews"`
+	RatingBreakdown Breakdown `json:"rating_breakdown"`
+}
+
+func newAggregate(breakdown Breakdown) Aggregate {
+	aggregate := Aggregate{RatingBreakdown: breakdown}
+	sum := 0
+	for stars, count := range breakdown {
+		aggregate.TotalReviews += count
+		sum += stars * count
+	}
+	if aggregate.TotalReviews > 0 {
+		aggregate.AverageRating = float64(sum) / float64(aggregate.TotalReviews)
+	}
+	return aggregate
+}
+
+// stored reads the aggregate of a ProductRating. It fails when its breakdown
+// cannot be read.
+func stored(rating *models.ProductRating) (Aggregate, error) {
+	breakdown := newBreakdown()
+	if err := json.Unmarshal([]byte(rating.RatingBreakdown), &breakdown); err != nil {
+		return Aggregate{}, fmt.Errorf("failed to read rating breakdown: %w", err)
+	}
+	return Aggregate{
+		AverageRating:   rating.AverageRating,
+		TotalReviews:    rating.TotalReviews,
+		RatingBreakdown: breakdown,
+	}, nil
+}
+
+// Recalculate rebuilds the ProductRating of a product variant from its
+// approved reviews, and rolls up its product's overall rating
+func Recalculate(db *gorm.DB, productVariantID uint) error {
+	breakdowns, err := approvedBreakdowns(db.Where("product_variant_id = ?", productVariantID))
+	if err != nil {
+		return err
+	}
+	breakdown, ok := breakdowns[productVariantID]
+	if !ok {
+		breakdown = newBreakdown()
+	}
+	if err := save(db, productVariantID, newAggregate(breakdown)); err != nil {
+		return err
+	}
+	return rollUpVariant(db, productVariantID)
+}
+
+// Apply updates the ProductRating of a product variant for a review whose
+// contribution went from one rating to another, 0 meaning it did not count:
+// from 0 to its rating when it is approved, from its rating to 0 when it is
+// deleted or no longer approved, or between ratings when it is edited. It is
+// called after the review is written, and rebuilds the rating from the reviews
+// when there is none yet or it has drifted. The product's overall rating is
+// rolled up from the variant ratings afterwards.
+func Apply(tx *gorm.DB, productVariantID uint, from, to int) error {
+	if from == to {
+		return nil
+	}
+	var rating models.ProductRating
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("product_variant_id = ?", productVariantID).First(&rating).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Recalculate(tx, productVariantID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check existing rating: %w", err)
+	}
+
+	aggregate, err := stored(&rating)
+	if err != nil {
+		return Recalculate(tx, productVariantID)
+	}
+	breakdown := aggregate.RatingBreakdown
+	if from != 0 {
+		breakdown[from]--
+		if breakdown[from] < 0 {
+			return Recalculate(tx, productVariantID)
+		}
+	}
+	if to != 0 {
+		breakdown[to]++
+	}
+	if err := update(tx, &rating, newAggregate(breakdown)); err != nil {
+		return err
+	}
+	return rollUpVariant(tx, productVariantID)
+}
+
+// Counted returns the rating a review with a status counts towards its
+// variant's rating with, 0 when it is not approved
+func Counted(status models.ReviewStatus, rating int) int {
+	if status != models.ReviewStatusApproved {
+		return 0
+	}
+	return rating
+}
+
+// approvedBreakdowns counts the approved reviews of the variants db selects
+// per variant and rating
+func approvedBreakdowns(db *gorm.DB) (map[uint]Breakdown, error) {
+	var rows []struct {
+		ProductVariantID uint
+		Rating           int
+		Count            int
+	}
+	err := db.Model(&models.ProductReview{}).
+		Select("product_variant_id, rating, COUNT(*) AS count").
+		Where("status = ?", models.ReviewStatusApproved).
+		Group("product_variant_id, rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	breakdowns := map[uint]Breakdown{}
+	for _, row := range rows {
+		breakdown, ok := breakdowns[row.ProductVariantID]
+		if !ok {
+			breakdown = newBreakdown()
+			breakdowns[row.ProductVariantID] = breakdown
+		}
+		breakdown[row.Rating] = row.Count
+	}
+	return breakdowns, nil
+}
+
+// save creates or updates the ProductRating of a product variant
+func save(db *gorm.DB, productVariantID uint, aggregate Aggregate) error {
+	var existing models.ProductRating
+	err := db.Where("product_variant_id = ?", productVariantID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		breakdown, err := json.Marshal(aggregate.RatingBreakdown)
+		if err != nil {
+			return fmt.Errorf("failed to marshal breakdown: %w", err)
+		}
+		return db.Create(&models.ProductRating{
+			ProductVariantID: productVariantID,
+			AverageRating:    aggregate.AverageRating,
+			TotalReviews:     aggregate.TotalReviews,
+			RatingBreakdown:  string(breakdown),
+		}).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check existing rating: %w", err)
+	}
+	return update(db, &existing, aggregate)
+}
+
+func update(db *gorm.DB, rating *models.ProductRating, aggregate Aggregate) error {
+	columns, err := aggregateColumns(aggregate)
+	if err != nil {
+		return err
+	}
+	return db.Model(rating).Updates(columns).Error
+}
+
+// aggregateColumns returns the columns an aggregate is stored in
+func aggregateColumns(aggregate Aggregate) (map[string]interface{}, error) {
+	breakdown, err := json.Marshal(aggregate.RatingBreakdown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal breakdown: %w", err)
+	}
+	return map[string]interface{}{
+		"average_rating":   aggregate.AverageRating,
+		"total_reviews":    aggregate.TotalReviews,
+		"rating_breakdown": string(breakdown),
+	}, nil
+}
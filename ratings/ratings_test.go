@@ -0,0 +1,206 @@
+package ratings
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.ProductReview{},
+		&models.ProductRating{},
+		&models.ProductOverallRating{},
+	))
+	return db
+}
+
+func createVariant(t *testing.T, db *gorm.DB, sku string) uint {
+	product := models.Product{Name: "Halloumi", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	return addVariant(t, db, product.ID, sku)
+}
+
+func addVariant(t *testing.T, db *gorm.DB, productID uint, sku string) uint {
+	variant := models.ProductVariant{ProductID: productID, Name: sku, SKU: sku, BasePrice: 3.5}
+	require.NoError(t, db.Omit("Product").Create(&variant).Error)
+	return variant.ID
+}
+
+func createReview(t *testing.T, db *gorm.DB, variantID uint, rating int, status models.ReviewStatus) models.ProductReview {
+	var users int64
+	require.NoError(t, db.Model(&models.User{}).Count(&users).Error)
+	user := models.User{Email: fmt.Sprintf("reviewer%d@example.com", users+1), UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+	review := models.ProductReview{ProductVariantID: variantID, UserID: user.ID, Rating: rating, Title: "Squeaky", Content: "Grills well", Status: status}
+	require.NoError(t, db.Omit("User", "ProductVariant").Create(&review).Error)
+	return review
+}
+
+func aggregate(t *testing.T, db *gorm.DB, variantID uint) Aggregate {
+	var rating models.ProductRating
+	require.NoError(t, db.Where("product_variant_id = ?", variantID).First(&rating).Error)
+	current, err := stored(&rating)
+	require.NoError(t, err)
+	return current
+}
+
+func TestApply(t *testing.T) {
+	db := setupTestDB(t)
+	variantID := createVariant(t, db, "HAL-250")
+	createReview(t, db, variantID, 5, models.ReviewStatusApproved)
+	pending := createReview(t, db, variantID, 2, models.ReviewStatusPending)
+
+	// Without a rating yet, it is built from the reviews
+	require.NoError(t, Apply(db, variantID, 0, 5))
+	assert.Equal(t, 1, aggregate(t, db, variantID).TotalReviews)
+
+	// Approving the pending review adds it
+	require.NoError(t, db.Model(&pending).Update("status", models.ReviewStatusApproved).Error)
+	require.NoError(t, Apply(db, variantID, Counted(models.ReviewStatusPending, 2), Counted(models.ReviewStatusApproved, 2)))
+	current := aggregate(t, db, variantID)
+	assert.Equal(t, 2, current.TotalReviews)
+	assert.InDelta(t, 3.5, current.AverageRating, 0.001)
+	assert.Equal(t, Breakdown{1: 0, 2: 1, 3: 0, 4: 0, 5: 1}, current.RatingBreakdown)
+
+	// Editing its rating moves it to another level
+	require.NoError(t, db.Model(&pending).Update("rating", 4).Error)
+	require.NoError(t, Apply(db, variantID, 2, 4))
+	current = aggregate(t, db, variantID)
+	assert.Equal(t, Breakdown{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}, current.RatingBreakdown)
+	assert.InDelta(t, 4.5, current.AverageRating, 0.001)
+
+	// Taking out a review the rating does not count rebuilds it
+	require.NoError(t, Apply(db, variantID, 3, 0))
+	assert.Equal(t, Breakdown{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}, aggregate(t, db, variantID).RatingBreakdown)
+
+	require.NoError(t, db.Delete(&pending).Error)
+	require.NoError(t, Apply(db, variantID, 4, 0))
+	current = aggregate(t, db, variantID)
+	assert.Equal(t, 1, current.TotalReviews)
+	assert.InDelta(t, 5.0, current.AverageRating, 0.001)
+}
+
+func TestReconcile(t *testing.T) {
+	db := setupTestDB(t)
+	accurate := createVariant(t, db, "HAL-250")
+	drifted := createVariant(t, db, "HAL-500")
+	unrated := createVariant(t, db, "HAL-1KG")
+	for _, variantID := range []uint{accurate, drifted, unrated} {
+		createReview(t, db, variantID, 4, models.ReviewStatusApproved)
+		createReview(t, db, variantID, 5, models.ReviewStatusApproved)
+		createReview(t, db, variantID, 1, models.ReviewStatusRejected)
+	}
+	require.NoError(t, Recalculate(db, accurate))
+	require.NoError(t, Recalculate(db, drifted))
+
+	// A bulk change that bypassed the incremental updates
+	require.NoError(t, db.Model(&models.ProductReview{}).Where("product_variant_id = ? AND rating = ?", drifted, 5).
+		Update("status", models.ReviewStatusRejected).Error)
+
+	service := NewService(db)
+	report, err := service.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Checked)
+	assert.Zero(t, report.Fixed)
+	require.Len(t, report.Drifts, 2)
+	assert.Equal(t, drifted, report.Drifts[0].ProductVariantID)
+	require.NotNil(t, report.Drifts[0].Stored)
+	assert.Equal(t, 2, report.Drifts[0].Stored.TotalReviews)
+	assert.Equal(t, 1, report.Drifts[0].Actual.TotalReviews)
+	assert.Equal(t, unrated, report.Drifts[1].ProductVariantID)
+	assert.Nil(t, report.Drifts[1].Stored)
+	assert.Equal(t, 1, aggregate(t, db, drifted).RatingBreakdown[5], "checking changes nothing")
+
+	report, err = service.Reconcile(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Fixed)
+	assert.True(t, report.Drifts[0].Fixed)
+	assert.Equal(t, Breakdown{1: 0, 2: 0, 3: 0, 4: 1, 5: 0}, aggregate(t, db, drifted).RatingBreakdown)
+	assert.InDelta(t, 4.5, aggregate(t, db, unrated).AverageRating, 0.001)
+
+	report, err = service.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Drifts)
+}
+
+func TestRollUp(t *testing.T) {
+	db := setupTestDB(t)
+	product := models.Product{Name: "Halloumi", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	small := addVariant(t, db, product.ID, "HAL-250")
+	large := addVariant(t, db, product.ID, "HAL-500")
+
+	overall, err := Overall(db, product.ID)
+	require.NoError(t, err)
+	assert.Zero(t, overall.TotalReviews, "a product without ratings has an empty one")
+
+	createReview(t, db, small, 5, models.ReviewStatusApproved)
+	require.NoError(t, Apply(db, small, 0, 5))
+	createReview(t, db, large, 2, models.ReviewStatusApproved)
+	require.NoError(t, Apply(db, large, 0, 2))
+	createReview(t, db, large, 4, models.ReviewStatusApproved)
+	require.NoError(t, Apply(db, large, 0, 4))
+
+	overall, err = Overall(db, product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, overall.TotalReviews)
+	assert.InDelta(t, 11.0/3, overall.AverageRating, 0.001)
+	assert.Equal(t, Breakdown{1: 0, 2: 1, 3: 0, 4: 1, 5: 1}, overall.RatingBreakdown)
+
+	// A deleted variant no longer counts once the product is rolled up again
+	require.NoError(t, db.Delete(&models.ProductVariant{}, large).Error)
+	require.NoError(t, RollUp(db, product.ID))
+	overall, err = Overall(db, product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, overall.TotalReviews)
+	assert.InDelta(t, 5.0, overall.AverageRating, 0.001)
+}
+
+func TestReconcileProducts(t *testing.T) {
+	db := setupTestDB(t)
+	product := models.Product{Name: "Halloumi", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	small := addVariant(t, db, product.ID, "HAL-250")
+	large := addVariant(t, db, product.ID, "HAL-500")
+	createReview(t, db, small, 5, models.ReviewStatusApproved)
+	createReview(t, db, large, 3, models.ReviewStatusApproved)
+	require.NoError(t, Recalculate(db, small))
+	require.NoError(t, Recalculate(db, large))
+
+	// Deleting a variant leaves the overall rating counting its reviews
+	require.NoError(t, db.Delete(&models.ProductVariant{}, large).Error)
+
+	service := NewService(db)
+	report, err := service.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.CheckedProducts)
+	require.Len(t, report.ProductDrifts, 1)
+	drift := report.ProductDrifts[0]
+	assert.Equal(t, product.ID, drift.ProductID)
+	require.NotNil(t, drift.Stored)
+	assert.Equal(t, 2, drift.Stored.TotalReviews)
+	assert.Equal(t, 1, drift.Actual.TotalReviews)
+
+	report, err = service.Reconcile(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.FixedProducts)
+	overall, err := Overall(db, product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Breakdown{1: 0, 2: 0, 3: 0, 4: 0, 5: 1}, overall.RatingBreakdown)
+
+	report, err = service.Reconcile(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, report.ProductDrifts)
+}
@@ -0,0 +1,230 @@
+package ratings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+// averageTolerance is how far a stored average may be from the actual one,
+// since it is stored with one decimal
+const averageTolerance = 0.05 + 1e-9
+
+// Drift is a ProductRating that disagreed with its variant's approved reviews
+type Drift struct {
+	ProductVariantID uint       `json:"product_variant_id"`
+	Stored           *Aggregate `json:"stored"` // nil when the variant had no rating
+	Actual           Aggregate  `json:"actual"`
+	Fixed            bool       `json:"fixed"` // the rating was rebuilt
+	Error            string     `json:"error,omitempty"`
+}
+
+// ProductDrift is a ProductOverallRating that disagreed with the approved
+// reviews of its product's variants
+type ProductDrift struct {
+	ProductID uint       `json:"product_id"`
+	Stored    *Aggregate `json:"stored"` // nil when the product had no overall rating
+	Actual    Aggregate  `json:"actual"`
+	Fixed     bool       `json:"fixed"` // the overall rating was rolled up again
+	Error     string     `json:"error,omitempty"`
+}
+
+// Report is the outcome of comparing the ratings with the approved reviews
+type Report struct {
+	CheckedAt       time.Time      `json:"checked_at"`
+	Checked         int            `json:"checked"`
+	Fixed           int            `json:"fixed"`
+	Drifts          []Drift        `json:"drifts"`
+	CheckedProducts int            `json:"checked_products"`
+	FixedProducts   int            `json:"fixed_products"`
+	ProductDrifts   []ProductDrift `json:"product_drifts"`
+}
+
+// Service reconciles the ratings with the reviews they are built from
+type Service struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewService creates a ratings service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db, now: time.Now}
+}
+
+// Reconcile compares the rating of every variant that has one or has approved
+// reviews with its approved reviews, and the overall rating of every product
+// with the approved reviews of its variants that are not deleted. With fix,
+// the variant ratings that drifted are rebuilt from the reviews and then the
+// overall ratings that drifted are rolled up again, each in its own
+// transaction.
+func (s *Service) Reconcile(ctx context.Context, fix bool) (*Report, error) {
+	db := s.db.WithContext(ctx)
+	report := &Report{CheckedAt: s.now(), Drifts: []Drift{}, ProductDrifts: []ProductDrift{}}
+
+	breakdowns, err := approvedBreakdowns(db)
+	if err != nil {
+		return nil, err
+	}
+	var ratings []models.ProductRating
+	if err := db.Find(&ratings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load ratings: %w", err)
+	}
+
+	checked := map[uint]bool{}
+	for i := range ratings {
+		rating := &ratings[i]
+		checked[rating.ProductVariantID] = true
+		breakdown, ok := breakdowns[rating.ProductVariantID]
+		if !ok {
+			breakdown = newBreakdown()
+		}
+		actual := newAggregate(breakdown)
+		current, err := stored(rating)
+		if err != nil {
+			report.Drifts = append(report.Drifts, Drift{ProductVariantID: rating.ProductVariantID, Actual: actual})
+			continue
+		}
+		if !agrees(current, actual) {
+			report.Drifts = append(report.Drifts, Drift{ProductVariantID: rating.ProductVariantID, Stored: &current, Actual: actual})
+		}
+	}
+	for variantID, breakdown := range breakdowns {
+		if !checked[variantID] {
+			checked[variantID] = true
+			report.Drifts = append(report.Drifts, Drift{ProductVariantID: variantID, Actual: newAggregate(breakdown)})
+		}
+	}
+	report.Checked = len(checked)
+	sort.Slice(report.Drifts, func(i, j int) bool { return report.Drifts[i].ProductVariantID < report.Drifts[j].ProductVariantID })
+
+	if err := s.compareProducts(db, report); err != nil {
+		return nil, err
+	}
+
+	if !fix {
+		return report, nil
+	}
+	for i := range report.Drifts {
+		drift := &report.Drifts[i]
+		// Rebuilt from the reviews as they are now, in case they changed
+		// since they were counted
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return Recalculate(tx, drift.ProductVariantID)
+		})
+		if err != nil {
+			drift.Error = err.Error()
+			continue
+		}
+		drift.Fixed = true
+		report.Fixed++
+	}
+	for i := range report.ProductDrifts {
+		drift := &report.ProductDrifts[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return RollUp(tx, drift.ProductID)
+		})
+		if err != nil {
+			drift.Error = err.Error()
+			continue
+		}
+		drift.Fixed = true
+		report.FixedProducts++
+	}
+	return report, nil
+}
+
+// compareProducts adds the overall ratings that disagree with the approved
+// reviews to the report
+func (s *Service) compareProducts(db *gorm.DB, report *Report) error {
+	breakdowns, err := approvedProductBreakdowns(db)
+	if err != nil {
+		return err
+	}
+	var overalls []models.ProductOverallRating
+	if err := db.Find(&overalls).Error; err != nil {
+		return fmt.Errorf("failed to load overall ratings: %w", err)
+	}
+
+	checked := map[uint]bool{}
+	for i := range overalls {
+		overall := &overalls[i]
+		checked[overall.ProductID] = true
+		breakdown, ok := breakdowns[overall.ProductID]
+		if !ok {
+			breakdown = newBreakdown()
+		}
+		actual := newAggregate(breakdown)
+		current, err := storedOverall(overall)
+		if err != nil {
+			report.ProductDrifts = append(report.ProductDrifts, ProductDrift{ProductID: overall.ProductID, Actual: actual})
+			continue
+		}
+		if !agrees(current, actual) {
+			report.ProductDrifts = append(report.ProductDrifts, ProductDrift{ProductID: overall.ProductID, Stored: &current, Actual: actual})
+		}
+	}
+	for productID, breakdown := range breakdowns {
+		if !checked[productID] {
+			checked[productID] = true
+			report.ProductDrifts = append(report.ProductDrifts, ProductDrift{ProductID: productID, Actual: newAggregate(breakdown)})
+		}
+	}
+	report.CheckedProducts = len(checked)
+	sort.Slice(report.ProductDrifts, func(i, j int) bool { return report.ProductDrifts[i].ProductID < report.ProductDrifts[j].ProductID })
+	return nil
+}
+
+// agrees reports whether a stored rating matches the actual one
+func agrees(stored, actual Aggregate) bool {
+	if stored.TotalReviews != actual.TotalReviews || math.Abs(stored.AverageRating-actual.AverageRating) > averageTolerance {
+		return false
+	}
+	for stars := 1; stars <= 5; stars++ {
+		if stored.RatingBreakdown[stars] != actual.RatingBreakdown[stars] {
+			return false
+		}
+	}
+	return len(stored.RatingBreakdown) == len(actual.RatingBreakdown)
+}
+
+// StartReconciler reconciles and fixes the ratings each night at the given
+// UTC hour until ctx is canceled, logging the ratings that drifted
+func (s *Service) StartReconciler(ctx context.Context, hour int) {
+	if hour < 0 || hour > 23 {
+		return
+	}
+	for {
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		if !worker.Sleep(ctx, next.Sub(now)) {
+			return
+		}
+
+		report, err := s.Reconcile(ctx, true)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to reconcile ratings", "component", "ratings", "error", err)
+			continue
+		}
+		for _, drift := range report.Drifts {
+			slog.WarnContext(ctx, "product rating drifted from its reviews", "component", "ratings",
+				"product_variant_id", drift.ProductVariantID, "total_reviews", drift.Actual.TotalReviews, "fixed", drift.Fixed, "error", drift.Error)
+		}
+		for _, drift := range report.ProductDrifts {
+			slog.WarnContext(ctx, "overall product rating drifted from its reviews", "component", "ratings",
+				"product_id", drift.ProductID, "total_reviews", drift.Actual.TotalReviews, "fixed", drift.Fixed, "error", drift.Error)
+		}
+		slog.InfoContext(ctx, "reconciled product ratings", "component", "ratings",
+			"checked", report.Checked, "drifted", len(report.Drifts), "fixed", report.Fixed,
+			"checked_products", report.CheckedProducts, "drifted_products", len(report.ProductDrifts), "fixed_products", report.FixedProducts)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -47,6 +48,10 @@ func NewRedisService(config *RedisConfig) (*RedisService, error) {
 		},
 	})
 
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		return nil, fmt.Errorf("failed to instrument Redis tracing: %w", err)
+	}
+
 	// Test connection
 	_, err = client.Ping(context.Background()).Result()
 	if err != nil {
@@ -0,0 +1,72 @@
+package referral
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidCoupon = errors.New("invalid or expired coupon")
+	ErrCouponMinimum = errors.New("order does not meet the coupon's minimum amount")
+)
+
+// Coupons lists a user's unused, unexpired coupons
+func (s *Service) Coupons(userID uint) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := s.db.Where("user_id = ? AND used_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, s.now()).
+		Order("created_at DESC").
+		Find(&coupons).Error
+	return coupons, err
+}
+
+// QuoteCoupon locks a coupon for checkout and returns the discount it gives
+// off amount, the order's goods total
+func (s *Service) QuoteCoupon(tx *gorm.DB, userID uint, code string, amount float64) (*models.Coupon, float64, error) {
+	var coupon models.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ? AND used_at IS NULL", strings.ToUpper(strings.TrimSpace(code))).
+		First(&coupon).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, ErrInvalidCoupon
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if (coupon.UserID != nil && *coupon.UserID != userID) ||
+		(coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(s.now())) {
+		return nil, 0, ErrInvalidCoupon
+	}
+	if amount < coupon.MinOrderAmount {
+		return nil, 0, ErrCouponMinimum
+	}
+	return &coupon, min(coupon.Amount, amount), nil
+}
+
+// UseCoupon marks a coupon as used by an order
+func (s *Service) UseCoupon(tx *gorm.DB, coupon *models.Coupon, order *models.Order) error {
+	now := s.now()
+	result := tx.Model(&models.Coupon{}).
+		Where("id = ? AND used_at IS NULL", coupon.ID).
+		Updates(map[string]interface{}{"used_at": now, "order_id": order.ID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidCoupon
+	}
+	return nil
+}
+
+// ReleaseCoupon makes the coupon used by a cancelled order usable again
+func (s *Service) ReleaseCoupon(tx *gorm.DB, order *models.Order) error {
+	if order.CouponID == nil {
+		return nil
+	}
+	return tx.Model(&models.Coupon{}).
+		Where("id = ? AND order_id = ?", *order.CouponID, order.ID).
+		Updates(map[string]interface{}{"used_at": nil, "order_id": nil}).Error
+}
@@ -0,0 +1,415 @@
+// Package referral runs the referral program: referral codes, attribution of
+// sign-ups and first orders to the referrer, anti-abuse checks and rewards
+// issued as loyalty points or coupons once a referred customer's order is
+// delivered.
+package referral
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidCode = errors.New("invalid referral code")
+	ErrNotRejected = errors.New("only rejected referrals can be approved")
+)
+
+const (
+	RewardPoints = "points"
+	RewardCoupon = "coupon"
+)
+
+// Reasons a referral is rejected by the anti-abuse checks
+const (
+	reasonSameDevice  = "signed up from a device the referrer uses"
+	reasonSameAddress = "qualifying order ships to an address of the referrer"
+)
+
+// codeAlphabet leaves out letters and digits that are easily confused
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Service runs the referral program
+type Service struct {
+	db     *gorm.DB
+	config cfg.ReferralConfig
+	points *loyalty.Service
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, config *cfg.ReferralConfig, points *loyalty.Service) *Service {
+	s := &Service{db: db, points: points, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	if s.config.RewardType != RewardCoupon {
+		s.config.RewardType = RewardPoints
+	}
+	return s
+}
+
+// Code returns a user's referral code, creating it the first time
+func (s *Service) Code(userID uint) (string, error) {
+	var user models.User
+	if err := s.db.Select("id", "referral_code").First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	for attempt := 0; user.ReferralCode == nil && attempt < 5; attempt++ {
+		code := generateCode(8)
+		result := s.db.Model(&models.User{}).
+			Where("id = ? AND referral_code IS NULL", userID).
+			Update("referral_code", code)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrDuplicatedKey) && !strings.Contains(strings.ToLower(result.Error.Error()), "unique") {
+			return "", result.Error
+		}
+		// Read back the code, ours or one set by a concurrent request
+		if err := s.db.Select("id", "referral_code").First(&user, userID).Error; err != nil {
+			return "", err
+		}
+	}
+	if user.ReferralCode == nil {
+		return "", errors.New("failed to create a unique referral code")
+	}
+	return *user.ReferralCode, nil
+}
+
+// ShareURL returns the sign-up link for a referral code
+func (s *Service) ShareURL(code string) string {
+	if s.config.ShareURL == "" {
+		return ""
+	}
+	return s.config.ShareURL + code
+}
+
+// Referrer returns the active user a referral code belongs to
+func (s *Service) Referrer(tx *gorm.DB, code string) (*models.User, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, ErrInvalidCode
+	}
+	var referrer models.User
+	if err := tx.Where("referral_code = ? AND is_active = ?", code, true).First(&referrer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCode
+		}
+		return nil, err
+	}
+	return &referrer, nil
+}
+
+// Attribute records that a new user signed up with a referrer's code. The
+// referral is rejected when the sign-up came from a device the referrer has
+// logged in from.
+func (s *Service) Attribute(tx *gorm.DB, referrer, referee *models.User, ip, userAgent string) (*models.Referral, error) {
+	referral := models.Referral{
+		ReferrerID:  referrer.ID,
+		RefereeID:   referee.ID,
+		Code:        *referrer.ReferralCode,
+		Status:      models.ReferralSignedUp,
+		SignupIP:    ip,
+		SignupAgent: userAgent,
+	}
+	var sameDevice int64
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND ip_address = ? AND user_agent = ?", referrer.ID, ip, userAgent).
+		Count(&sameDevice).Error; err != nil {
+		return nil, err
+	}
+	if sameDevice > 0 {
+		referral.Status = models.ReferralRejected
+		referral.RejectReason = reasonSameDevice
+	}
+	if err := tx.Create(&referral).Error; err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// RecordOrder attributes a referred customer's first order to their referrer
+func (s *Service) RecordOrder(tx *gorm.DB, order *models.Order) error {
+	now := s.now()
+	if err := tx.Model(&models.Referral{}).
+		Where("referee_id = ? AND first_order_id IS NULL", order.UserID).
+		Updates(map[string]interface{}{"first_order_id": order.ID, "ordered_at": now}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Referral{}).
+		Where("referee_id = ? AND status = ?", order.UserID, models.ReferralSignedUp).
+		Update("status", models.ReferralOrdered).Error
+}
+
+// Qualify rewards the referral of a customer whose delivered order is worth
+// at least the minimum in goods. The referral is rejected instead when the
+// order ships to one of the referrer's addresses, unless an admin approved it.
+func (s *Service) Qualify(tx *gorm.DB, order *models.Order) error {
+	if order.FinalAmount-order.ShippingAmount < s.config.MinOrderAmount {
+		return nil
+	}
+	var referral models.Referral
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("referee_id = ? AND status IN ?", order.UserID, []models.ReferralStatus{models.ReferralSignedUp, models.ReferralOrdered}).
+		First(&referral).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	referral.OrderID = &order.ID
+
+	if referral.ApprovedByID == nil {
+		shared, err := s.sharesAddress(tx, referral.ReferrerID, order.ShippingAddressID)
+		if err != nil {
+			return err
+		}
+		if shared {
+			return tx.Model(&referral).Updates(map[string]interface{}{
+				"order_id":      order.ID,
+				"status":        models.ReferralRejected,
+				"reject_reason": reasonSameAddress,
+			}).Error
+		}
+	}
+	return s.reward(tx, &referral)
+}
+
+// Approve clears a rejected referral. A referral rejected at its qualifying
+// order is rewarded now; one rejected at sign-up goes back to waiting for a
+// qualifying order.
+func (s *Service) Approve(referralID uint, adminID *uint) (*models.Referral, error) {
+	var referral models.Referral
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&referral, referralID).Error; err != nil {
+			return err
+		}
+		if referral.Status != models.ReferralRejected {
+			return ErrNotRejected
+		}
+		referral.ApprovedByID = adminID
+		referral.RejectReason = ""
+		if referral.OrderID != nil {
+			if err := tx.Model(&referral).Updates(map[string]interface{}{"approved_by_id": adminID, "reject_reason": ""}).Error; err != nil {
+				return err
+			}
+			return s.reward(tx, &referral)
+		}
+		referral.Status = models.ReferralSignedUp
+		if referral.FirstOrderID != nil {
+			referral.Status = models.ReferralOrdered
+		}
+		return tx.Model(&referral).Updates(map[string]interface{}{
+			"approved_by_id": adminID,
+			"reject_reason":  "",
+			"status":         referral.Status,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// Dashboard is a referrer's view of their referrals
+type Dashboard struct {
+	Code           string              `json:"code"`
+	ShareURL       string              `json:"share_url"`
+	RewardType     string              `json:"reward_type"`
+	ReferrerReward float64             `json:"referrer_reward"`
+	RefereeReward  float64             `json:"referee_reward"`
+	SignedUp       int64               `json:"signed_up"` // every referral, whatever its status
+	Ordered        int64               `json:"ordered"`
+	Rewarded       int64               `json:"rewarded"`
+	Rejected       int64               `json:"rejected"`
+	RewardsEarned  map[string]float64  `json:"rewards_earned"` // by reward type
+	Recent         []DashboardReferral `json:"recent"`
+}
+
+// DashboardReferral is a referral as shown to the referrer
+type DashboardReferral struct {
+	Name       string                `json:"name"`
+	Email      string                `json:"email"` // masked
+	Status     models.ReferralStatus `json:"status"`
+	SignedUpAt time.Time             `json:"signed_up_at"`
+	RewardedAt *time.Time            `json:"rewarded_at,omitempty"`
+	Reward     float64               `json:"reward,omitempty"`
+}
+
+// Dashboard summarizes a user's referral code and the conversions it brought
+func (s *Service) Dashboard(userID uint) (*Dashboard, error) {
+	code, err := s.Code(userID)
+	if err != nil {
+		return nil, err
+	}
+	dashboard := Dashboard{
+		Code:           code,
+		ShareURL:       s.ShareURL(code),
+		RewardType:     s.config.RewardType,
+		ReferrerReward: s.config.ReferrerReward,
+		RefereeReward:  s.config.RefereeReward,
+		RewardsEarned:  map[string]float64{},
+		Recent:         []DashboardReferral{},
+	}
+
+	var counts []struct {
+		Status models.ReferralStatus
+		Count  int64
+	}
+	if err := s.db.Model(&models.Referral{}).Select("status, COUNT(*) AS count").
+		Where("referrer_id = ?", userID).Group("status").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		dashboard.SignedUp += c.Count
+		switch c.Status {
+		case models.ReferralOrdered:
+			dashboard.Ordered += c.Count
+		case models.ReferralRewarded:
+			dashboard.Ordered += c.Count
+			dashboard.Rewarded += c.Count
+		case models.ReferralRejected:
+			dashboard.Rejected += c.Count
+		}
+	}
+
+	var earned []struct {
+		RewardType string
+		Total      float64
+	}
+	if err := s.db.Model(&models.Referral{}).Select("reward_type, SUM(referrer_reward) AS total").
+		Where("referrer_id = ? AND status = ?", userID, models.ReferralRewarded).Group("reward_type").Scan(&earned).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range earned {
+		dashboard.RewardsEarned[e.RewardType] = e.Total
+	}
+
+	var recent []models.Referral
+	if err := s.db.Preload("Referee").Where("referrer_id = ?", userID).
+		Order("created_at DESC").Limit(10).Find(&recent).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range recent {
+		entry := DashboardReferral{Status: r.Status, SignedUpAt: r.CreatedAt, RewardedAt: r.RewardedAt, Reward: r.ReferrerReward}
+		if r.Referee != nil {
+			entry.Name = r.Referee.FirstName
+			entry.Email = maskEmail(r.Referee.Email)
+		}
+		dashboard.Recent = append(dashboard.Recent, entry)
+	}
+	return &dashboard, nil
+}
+
+// reward issues the configured rewards to the referrer and the referee
+func (s *Service) reward(tx *gorm.DB, referral *models.Referral) error {
+	var referee models.User
+	if err := tx.Select("id", "first_name").First(&referee, referral.RefereeID).Error; err != nil {
+		return err
+	}
+	if s.config.ReferrerReward > 0 {
+		if err := s.issue(tx, referral, referral.ReferrerID, s.config.ReferrerReward,
+			fmt.Sprintf("Referral reward for inviting %s", referee.FirstName)); err != nil {
+			return err
+		}
+	}
+	if s.config.RefereeReward > 0 {
+		if err := s.issue(tx, referral, referral.RefereeID, s.config.RefereeReward, "Welcome reward for joining with a referral"); err != nil {
+			return err
+		}
+	}
+
+	now := s.now()
+	referral.Status, referral.RewardedAt = models.ReferralRewarded, &now
+	referral.RewardType, referral.ReferrerReward, referral.RefereeReward = s.config.RewardType, s.config.ReferrerReward, s.config.RefereeReward
+	return tx.Model(referral).Updates(map[string]interface{}{
+		"status":          referral.Status,
+		"order_id":        referral.OrderID,
+		"rewarded_at":     now,
+		"reward_type":     referral.RewardType,
+		"referrer_reward": referral.ReferrerReward,
+		"referee_reward":  referral.RefereeReward,
+	}).Error
+}
+
+// issue gives a user a reward as loyalty points or a coupon
+func (s *Service) issue(tx *gorm.DB, referral *models.Referral, userID uint, value float64, description string) error {
+	if s.config.RewardType == RewardPoints {
+		return s.points.Reward(tx, userID, int(value), description)
+	}
+	coupon := models.Coupon{
+		Code:        "REF-" + generateCode(10),
+		UserID:      &userID,
+		Amount:      value,
+		ReferralID:  &referral.ID,
+		Description: description,
+	}
+	if s.config.CouponExpiryDays > 0 {
+		expiresAt := s.now().AddDate(0, 0, s.config.CouponExpiryDays)
+		coupon.ExpiresAt = &expiresAt
+	}
+	return tx.Create(&coupon).Error
+}
+
+// sharesAddress reports whether an order's shipping address is one of the
+// referrer's saved addresses, comparing the first line and postcode
+func (s *Service) sharesAddress(tx *gorm.DB, referrerID, addressID uint) (bool, error) {
+	var address models.Address
+	if err := tx.Unscoped().First(&address, addressID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	var addresses []models.Address
+	if err := tx.Unscoped().Where("user_id = ?", referrerID).Find(&addresses).Error; err != nil {
+		return false, err
+	}
+	for _, a := range addresses {
+		if normalize(a.PostalCode) == normalize(address.PostalCode) &&
+			normalize(a.StreetAddress1) == normalize(address.StreetAddress1) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// normalize drops case, spaces and punctuation from part of an address
+func normalize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, value)
+}
+
+// maskEmail hides all but the first letter of an email's local part
+func maskEmail(email string) string {
+	at := strings.IndexByte(email, '@')
+	if at < 1 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// generateCode returns a random code of n characters
+func generateCode(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,216 @@
+package referral
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Address{}, &models.Order{}, &models.RefreshToken{},
+		&models.LoyaltyTransaction{}, &models.Referral{}, &models.Coupon{}))
+	return db
+}
+
+// setupService rewards the referrer with 500 points and the referee with 250
+// once the referee's order of at least £20 is delivered
+func setupService(db *gorm.DB, rewardType string) *Service {
+	points := loyalty.NewService(db, &cfg.LoyaltyConfig{PointsPerPound: 1, PointValue: 0.01, MaxRedeemPercent: 20, ExpiryDays: 365})
+	s := NewService(db, &cfg.ReferralConfig{
+		RewardType:       rewardType,
+		ReferrerReward:   500,
+		RefereeReward:    250,
+		MinOrderAmount:   20,
+		CouponExpiryDays: 90,
+		ShareURL:         "https://shop.example/register?ref=",
+	}, points)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func createUser(t *testing.T, db *gorm.DB, email, street string) *models.User {
+	user := models.User{Email: email, FirstName: "Sam", Password: "x", UserType: models.Customer, IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	address := models.Address{StreetAddress1: street, City: "London", PostalCode: "E1 6AN", Country: "GB", UserID: &user.ID}
+	require.NoError(t, db.Create(&address).Error)
+	user.Addresses = []*models.Address{&address}
+	return &user
+}
+
+// refer signs referee up with referrer's code from the given device
+func refer(t *testing.T, s *Service, referrer, referee *models.User, ip string) *models.Referral {
+	code, err := s.Code(referrer.ID)
+	require.NoError(t, err)
+	found, err := s.Referrer(s.db, " "+code+" ")
+	require.NoError(t, err)
+	referral, err := s.Attribute(s.db, found, referee, ip, "Mozilla/5.0")
+	require.NoError(t, err)
+	return referral
+}
+
+func placeOrder(t *testing.T, s *Service, user *models.User, number string, final float64) *models.Order {
+	order := models.Order{OrderNumber: number, UserID: user.ID, Status: models.OrderStatusPending,
+		FinalAmount: final, ShippingAddressID: user.Addresses[0].ID}
+	require.NoError(t, s.db.Create(&order).Error)
+	require.NoError(t, s.RecordOrder(s.db, &order))
+	return &order
+}
+
+func reload(t *testing.T, db *gorm.DB, referral *models.Referral) {
+	require.NoError(t, db.First(referral, referral.ID).Error)
+}
+
+func TestCodeIsStable(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db, RewardPoints)
+	user := createUser(t, db, "referrer@example.com", "1 High Street")
+
+	code, err := s.Code(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, code, 8)
+	again, err := s.Code(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, code, again)
+
+	_, err = s.Referrer(db, "NOPE1234")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestQualifyingOrderRewardsPoints(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db, RewardPoints)
+	referrer := createUser(t, db, "referrer@example.com", "1 High Street")
+	referee := createUser(t, db, "friend@example.com", "22 Mill Lane")
+	referral := refer(t, s, referrer, referee, "10.0.0.2")
+	assert.Equal(t, models.ReferralSignedUp, referral.Status)
+
+	small := placeOrder(t, s, referee, "ORD-1", 15)
+	reload(t, db, referral)
+	assert.Equal(t, models.ReferralOrdered, referral.Status)
+	assert.Equal(t, small.ID, *referral.FirstOrderID)
+
+	require.NoError(t, s.Qualify(db, small))
+	reload(t, db, referral)
+	assert.Equal(t, models.ReferralOrdered, referral.Status, "orders under the minimum do not qualify")
+
+	order := placeOrder(t, s, referee, "ORD-2", 40)
+	require.NoError(t, s.Qualify(db, order))
+	require.NoError(t, s.Qualify(db, order))
+	reload(t, db, referral)
+	assert.Equal(t, models.ReferralRewarded, referral.Status)
+	assert.Equal(t, order.ID, *referral.OrderID)
+
+	balance, err := s.points.Balance(db, referrer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 500, balance, "the referrer is rewarded once")
+	balance, err = s.points.Balance(db, referee.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 250, balance)
+
+	dashboard, err := s.Dashboard(referrer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), dashboard.SignedUp)
+	assert.Equal(t, int64(1), dashboard.Ordered)
+	assert.Equal(t, int64(1), dashboard.Rewarded)
+	assert.Equal(t, 500.0, dashboard.RewardsEarned[RewardPoints])
+	assert.Equal(t, "https://shop.example/register?ref="+dashboard.Code, dashboard.ShareURL)
+	require.Len(t, dashboard.Recent, 1)
+	assert.Equal(t, "f***@example.com", dashboard.Recent[0].Email)
+}
+
+func TestCouponReward(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db, RewardCoupon)
+	s.config.ReferrerReward, s.config.RefereeReward = 10, 5
+	referrer := createUser(t, db, "referrer@example.com", "1 High Street")
+	referee := createUser(t, db, "friend@example.com", "22 Mill Lane")
+	refer(t, s, referrer, referee, "10.0.0.2")
+
+	require.NoError(t, s.Qualify(db, placeOrder(t, s, referee, "ORD-1", 40)))
+	coupons, err := s.Coupons(referrer.ID)
+	require.NoError(t, err)
+	require.Len(t, coupons, 1)
+	coupon := coupons[0]
+	assert.Equal(t, 10.0, coupon.Amount)
+	assert.Equal(t, testNow.AddDate(0, 0, 90), coupon.ExpiresAt.UTC())
+
+	_, _, err = s.QuoteCoupon(db, referee.ID, coupon.Code, 50)
+	assert.ErrorIs(t, err, ErrInvalidCoupon, "coupons are personal")
+
+	quoted, discount, err := s.QuoteCoupon(db, referrer.ID, coupon.Code, 6)
+	require.NoError(t, err)
+	assert.Equal(t, 6.0, discount, "the discount is capped at the goods total")
+
+	order := models.Order{OrderNumber: "ORD-2", UserID: referrer.ID, CouponID: &quoted.ID}
+	require.NoError(t, db.Create(&order).Error)
+	require.NoError(t, s.UseCoupon(db, quoted, &order))
+	_, _, err = s.QuoteCoupon(db, referrer.ID, coupon.Code, 50)
+	assert.ErrorIs(t, err, ErrInvalidCoupon, "coupons are single use")
+
+	require.NoError(t, s.ReleaseCoupon(db, &order))
+	_, _, err = s.QuoteCoupon(db, referrer.ID, coupon.Code, 50)
+	assert.NoError(t, err, "a cancelled order gives the coupon back")
+
+	s.now = func() time.Time { return testNow.AddDate(0, 0, 91) }
+	_, _, err = s.QuoteCoupon(db, referrer.ID, coupon.Code, 50)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+}
+
+func TestSameDeviceIsRejected(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db, RewardPoints)
+	referrer := createUser(t, db, "referrer@example.com", "1 High Street")
+	referee := createUser(t, db, "friend@example.com", "22 Mill Lane")
+	require.NoError(t, db.Create(&models.RefreshToken{UserID: referrer.ID, TokenHash: "hash", FamilyID: "family",
+		ExpiresAt: testNow.Add(time.Hour), IPAddress: "10.0.0.1", UserAgent: "Mozilla/5.0"}).Error)
+
+	referral := refer(t, s, referrer, referee, "10.0.0.1")
+	assert.Equal(t, models.ReferralRejected, referral.Status)
+
+	order := placeOrder(t, s, referee, "ORD-1", 40)
+	require.NoError(t, s.Qualify(db, order))
+	reload(t, db, referral)
+	assert.Equal(t, models.ReferralRejected, referral.Status, "rejected referrals are not rewarded")
+
+	_, err := s.Approve(referral.ID, nil)
+	require.NoError(t, err)
+	reload(t, db, referral)
+	assert.Equal(t, models.ReferralOrdered, referral.Status, "an approved referral waits for a qualifying order")
+
+	_, err = s.Approve(referral.ID, nil)
+	assert.ErrorIs(t, err, ErrNotRejected)
+}
+
+func TestSameAddressIsRejectedUntilApproved(t *testing.T) {
+	db := setupTestDB(t)
+	s := setupService(db, RewardPoints)
+	referrer := createUser(t, db, "referrer@example.com", "1 High Street")
+	referee := createUser(t, db, "friend@example.com", "1 high street.")
+	referral := refer(t, s, referrer, referee, "10.0.0.2")
+
+	order := placeOrder(t, s, referee, "ORD-1", 40)
+	require.NoError(t, s.Qualify(db, order))
+	reload(t, db, referral)
+	assert.Equal(t, models.ReferralRejected, referral.Status)
+	assert.Equal(t, reasonSameAddress, referral.RejectReason)
+
+	_, err := s.Approve(referral.ID, nil)
+	require.NoError(t, err)
+	reload(t, db, referral)
+	assert.Equal(t, models.ReferralRewarded, referral.Status, "approving a referral rejected at its order rewards it")
+
+	balance, err := s.points.Balance(db, referrer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 500, balance)
+}
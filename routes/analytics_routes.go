@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/cache"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/analytics"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func AnalyticsRoutes(router *gin.RouterGroup, db *gorm.DB, reports *cache.Analytics) {
+	analyticsHandler := analytics.NewAnalyticsHandler(db, reports)
+
+	// Reports aggregate every order in their range, so they are read from a replica
+	salesRouter := router.Group("/admin/analytics/sales")
+	salesRouter.Use(middlewares.RequireScope(permissions.OrdersRead), middlewares.ReadReplica())
+	{
+		salesRouter.GET("", analyticsHandler.GetSales)
+		salesRouter.GET("/products", analyticsHandler.GetTopProducts)
+		salesRouter.GET("/categories", analyticsHandler.GetTopCategories)
+		salesRouter.GET("/cohorts", analyticsHandler.GetCohorts)
+	}
+}
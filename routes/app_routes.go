@@ -1,60 +1,179 @@
 package routes
 
 import (
+	"time"
+
 	fileHandler "github.com/YasserCherfaoui/MarketProGo/handlers/file"
 
+	"github.com/YasserCherfaoui/MarketProGo/address"
 	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/cache"
+	"github.com/YasserCherfaoui/MarketProGo/campaign"
+	cartService "github.com/YasserCherfaoui/MarketProGo/cart"
 	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	companyService "github.com/YasserCherfaoui/MarketProGo/company"
+	deliveryService "github.com/YasserCherfaoui/MarketProGo/delivery"
 	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/feeds"
+	fraudService "github.com/YasserCherfaoui/MarketProGo/fraud"
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
+	giftCardService "github.com/YasserCherfaoui/MarketProGo/giftcard"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/auth"
+	emailHandlers "github.com/YasserCherfaoui/MarketProGo/handlers/email"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/inventory"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/order"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/payment"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/promotion"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/quote"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/review"
+	"github.com/YasserCherfaoui/MarketProGo/lockout"
+	loyaltyService "github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	moderationService "github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/oauth"
 	paymentService "github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
+	referralService "github.com/YasserCherfaoui/MarketProGo/referral"
+	smsService "github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	subscriptionService "github.com/YasserCherfaoui/MarketProGo/subscription"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	verificationService "github.com/YasserCherfaoui/MarketProGo/verification"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func AppRoutes(r *gin.Engine, db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService, config *cfg.AppConfig, emailTriggerSvc *email.EmailTriggerService) {
+func AppRoutes(r *gin.Engine, db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService, images *storage.Images, config *cfg.AppConfig, emailTriggerSvc *email.EmailTriggerService, cartSvc *cartService.CartService, loginGuard *lockout.Guard, limiter *ratelimit.Limiter, catalog *cache.Catalog, availability *cache.Availability, reports *cache.Analytics, campaigns *campaign.Service, feedService *feeds.Service, emailHandler *emailHandlers.EmailHandler) {
+	// Throttle every client, per user when authenticated and per IP otherwise
+	r.Use(middlewares.RateLimit(limiter, ratelimit.ClassGlobal))
+
+	// Restrict and audit requests made with impersonation tokens
+	r.Use(middlewares.ImpersonationGuard(db))
+
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 		})
 	})
-	router := r.Group("/api/v1")
-	authHandler := auth.NewAuthHandler(db, emailTriggerSvc)
+	loyaltyPoints := loyaltyService.NewService(db, &config.Loyalty)
+	referrals := referralService.NewService(db, &config.Referral, loyaltyPoints)
+	giftCards := giftCardService.NewService(db, &config.GiftCard, emailTriggerSvc)
+	companies := companyService.NewService(db, &config.Company, emailTriggerSvc)
+	verifications := verificationService.NewService(db, &config.Verification)
+	phones := smsService.NewService(db, smsService.NewProvider(&config.SMS), &config.SMS)
+	authHandler := auth.NewAuthHandler(db, emailTriggerSvc, loginGuard, referrals).
+		WithVerification(verifications).
+		WithOAuth(oauth.NewService(db, &config.OAuth)).
+		WithSMS(phones)
 	inventoryHandler := inventory.NewInventoryHandler(db, gcsService, appwriteService)
-	orderHandler := order.NewOrderHandler(db, emailTriggerSvc)
-
-	AuthRoutes(router, authHandler)
-	CategoryRoutes(router, db, gcsService, appwriteService)
-	BrandRoutes(router, db, gcsService, appwriteService)
-	ProductRoutes(router, db, gcsService, appwriteService)
-	UserRoutes(router, db)
-	CarouselRoutes(router, db, gcsService, appwriteService)
-	CartRoutes(router, db)
-	WishlistRoutes(router, db)
-	OrderRoutes(router, orderHandler)
-	InventoryRoutes(router, inventoryHandler)
-
-	// Register Promotion routes
+	taxService := tax.NewTaxService(db, &config.Tax)
+	deliverySlots := deliveryService.NewService(&config.Delivery)
+	fraudChecks := fraudService.NewService(db, &config.Fraud)
+	revolutPaymentService := paymentService.NewRevolutPaymentService(db, &config.Revolut, giftCards, fraudChecks)
+	orderHandler := order.NewOrderHandler(db, emailTriggerSvc, taxService, fulfillment.NewAllocator(&config.Fulfillment), deliverySlots, loyaltyPoints, referrals, giftCards, companies).
+		WithPayments(revolutPaymentService).
+		WithTexts(phones)
+	subscriptions := subscriptionService.NewService(db, &config.Subscription, taxService, fulfillment.NewAllocator(&config.Fulfillment), revolutPaymentService, emailTriggerSvc)
+	quoteHandler := quote.NewQuoteHandler(db, emailTriggerSvc, taxService)
 	promotionHandler := promotion.NewPromotionHandler(db, gcsService, appwriteService)
-	RegisterPromotionRoutes(router, promotionHandler)
+	moderations := moderationService.NewService(db, &config.Moderation, emailTriggerSvc)
+	reviewHandler := review.NewReviewHandler(db, appwriteService, emailTriggerSvc).WithModeration(moderations)
+	paymentHandler := payment.NewPaymentHandler(db, revolutPaymentService)
 
-	// Register Review routes
-	reviewHandler := review.NewReviewHandler(db, appwriteService)
-	RegisterReviewRoutes(router, reviewHandler)
+	// mount registers the API on a version's group. Every version shares the
+	// same services and handlers; a handler that changes its response shape
+	// in a later version checks middlewares.Version.
+	mount := func(router *gin.RouterGroup) {
+		AuthRoutes(router, authHandler, limiter)
+		CategoryRoutes(router, db, gcsService, appwriteService)
+		BrandRoutes(router, db, gcsService, appwriteService)
+		ProductRoutes(router, db, gcsService, appwriteService, images, catalog, availability)
+		UserRoutes(router, db, address.NewGeocoder(&config.Geocoding))
+		CarouselRoutes(router, db, gcsService, appwriteService)
+		CartRoutes(router, db, cartSvc, emailTriggerSvc)
+		WishlistRoutes(router, db)
+		OrderRoutes(router, db, orderHandler, verifications, phones)
+		SubscriptionRoutes(router, db, subscriptions)
+		InventoryRoutes(router, db, inventoryHandler)
+		PurchasingRoutes(router, db)
+		DeliveryRoutes(router, db, deliverySlots)
+		CampaignRoutes(router, db, campaigns)
+		LoyaltyRoutes(router, db, loyaltyPoints)
+		CompanyRoutes(router, db, companies, orderHandler)
+		ReferralRoutes(router, db, referrals)
+		GiftCardRoutes(router, db, giftCards, limiter)
+		CMSRoutes(router, db, appwriteService, images, catalog)
+		FeedRoutes(router, feedService)
+		TranslationRoutes(router, db)
 
-	// Register Payment routes
-	revolutPaymentService := paymentService.NewRevolutPaymentService(db, &config.Revolut)
-	paymentHandler := payment.NewPaymentHandler(db, revolutPaymentService)
-	SetupPaymentRoutes(r, paymentHandler)
+		// Register Quote routes
+		QuoteRoutes(router, quoteHandler)
+
+		// Register Price List routes
+		PriceListRoutes(router, db)
+
+		// Register Tax Rate routes
+		TaxRateRoutes(router, db)
+
+		// Register Role permission routes
+		RoleRoutes(router, db)
+		SettingsRoutes(router, db, config)
+
+		// Register Impersonation routes
+		ImpersonationRoutes(router, db)
+
+		// Register Audit log routes
+		AuditRoutes(router, db)
+		TrashRoutes(router, db)
+
+		// Register Text message delivery log routes
+		SMSRoutes(router, db, phones)
+
+		// Register Sales analytics routes
+		AnalyticsRoutes(router, db, reports)
+
+		// Register Notification center routes
+		NotificationRoutes(router, db)
+
+		// Register Admin search routes
+		SearchRoutes(router, db)
+
+		// Register Webhook subscription routes
+		WebhookRoutes(router, db)
+
+		// Register Rate limit stats routes
+		RateLimitRoutes(router, limiter)
+
+		// Register Promotion routes
+		RegisterPromotionRoutes(router, promotionHandler)
+
+		// Register Review routes
+		RegisterReviewRoutes(router, db, reviewHandler, limiter, verifications)
+
+		// Register Payment routes
+		SetupPaymentRoutes(router, paymentHandler)
+		FraudRoutes(router, db, fraudChecks, revolutPaymentService)
+
+		// Register Email routes
+		SetupEmailRoutes(router, emailHandler)
+
+		// Register Support routes
+		SupportRoutes(router, db, gcsService, appwriteService, emailTriggerSvc, config.Email.InboundSecret, config.Email.PreviousInboundSecret, limiter, moderations)
+
+		// Register User moderation routes
+		ModerationRoutes(router, db, moderations)
+
+		router.GET("/file/preview/:fileId", fileHandler.ProxyFilePreview)
+	}
 
-	// Register Support routes
-	SupportRoutes(router, db, gcsService, appwriteService, emailTriggerSvc)
+	maintenance := middlewares.MaintenanceMode(config.Maintenance.Enabled, time.Duration(config.Maintenance.RetryAfterSeconds)*time.Second)
 
-	router.GET("/file/preview/:fileId", fileHandler.ProxyFilePreview)
+	// v1 is announced as deprecated once it has a sunset date
+	v1 := r.Group("/api/v1", middlewares.APIVersion("v1"), maintenance)
+	if sunset, err := time.Parse(time.RFC3339, config.API.V1Sunset); err == nil {
+		v1.Use(middlewares.Deprecated(sunset, "/api/v1", "/api/v2"))
+	}
+	mount(v1)
+	mount(r.Group("/api/v2", middlewares.APIVersion("v2"), maintenance))
 }
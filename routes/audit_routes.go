@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/audit"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func AuditRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	auditHandler := audit.NewAuditHandler(db)
+
+	router.GET("/admin/audit-logs", middlewares.RequireScope(permissions.AuditRead), auditHandler.GetAuditLogs)
+}
@@ -3,21 +3,49 @@ package routes
 import (
 	"github.com/YasserCherfaoui/MarketProGo/handlers/auth"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(router *gin.RouterGroup, h *auth.AuthHandler) {
+func AuthRoutes(router *gin.RouterGroup, h *auth.AuthHandler, limiter *ratelimit.Limiter) {
+	// Credential and recovery endpoints get a tighter limit
+	throttle := middlewares.RateLimit(limiter, ratelimit.ClassAuth)
 
 	auth := router.Group("/auth")
 	{
-		auth.POST("/login", h.Login)
-		auth.POST("/register", h.CreateUser)
-		auth.POST("/forgot-password", h.ForgotPassword)
-		auth.GET("/verify-reset-token", h.VerifyResetToken)
-		auth.POST("/reset-password", h.ResetPassword)
+		auth.POST("/login", throttle, h.Login)
+		auth.POST("/register", throttle, h.CreateUser)
+		auth.POST("/forgot-password", throttle, h.ForgotPassword)
+		auth.GET("/verify-reset-token", throttle, h.VerifyResetToken)
+		auth.POST("/reset-password", throttle, h.ResetPassword)
+		auth.GET("/activate", throttle, h.Activate)
+
+		// Social sign-in
+		auth.GET("/oauth", h.GetOAuthProviders)
+		auth.GET("/oauth/:provider", throttle, h.StartOAuth)
+		auth.GET("/oauth/:provider/callback", throttle, h.OAuthCallback)
+		auth.POST("/oauth/:provider/callback", throttle, h.OAuthCallback)
+		auth.POST("/refresh", h.RefreshToken)
+		auth.POST("/2fa/verify", throttle, h.VerifyTwoFactor)
+		auth.POST("/unlock", throttle, h.UnlockAccount)
 	}
 	protectedAuth := auth.Use(middlewares.AuthMiddleware())
 	{
 		protectedAuth.GET("/me", h.GetUser)
+		protectedAuth.POST("/logout", h.Logout)
+		protectedAuth.POST("/change-password", h.ChangePassword)
+		protectedAuth.POST("/resend-verification", throttle, h.ResendVerification)
+		protectedAuth.POST("/phone/send-code", throttle, h.SendPhoneCode)
+		protectedAuth.POST("/phone/verify", throttle, h.VerifyPhone)
+		protectedAuth.GET("/sessions", h.GetSessions)
+		protectedAuth.DELETE("/sessions", h.RevokeAllSessions)
+		protectedAuth.DELETE("/sessions/:id", h.RevokeSession)
+
+		// Two-factor authentication
+		protectedAuth.GET("/2fa", h.GetTwoFactorStatus)
+		protectedAuth.POST("/2fa/setup", h.SetupTwoFactor)
+		protectedAuth.POST("/2fa/enable", h.EnableTwoFactor)
+		protectedAuth.POST("/2fa/disable", h.DisableTwoFactor)
+		protectedAuth.POST("/2fa/backup-codes", h.RegenerateBackupCodes)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/brand"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -15,6 +16,8 @@ func BrandRoutes(r *gin.RouterGroup, db *gorm.DB, gcs *gcs.GCService, appwriteSe
 
 	brandRouter.GET("", brandHandler.GetAllBrands)
 	brandRouter.GET("/:id", brandHandler.GetBrand)
+
+	r.GET("/admin/brands/analytics", middlewares.RequireScope(permissions.OrdersRead), brandHandler.GetBrandAnalytics)
 	brandRouter.Use(middlewares.AuthMiddleware())
 	{
 		brandRouter.POST("", brandHandler.CreateBrand)
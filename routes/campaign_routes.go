@@ -0,0 +1,55 @@
+package routes
+
+import (
+	campaignService "github.com/YasserCherfaoui/MarketProGo/campaign"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/campaign"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CampaignRoutes(r *gin.RouterGroup, db *gorm.DB, campaigns *campaignService.Service) {
+	campaignHandler := campaign.NewCampaignHandler(db, campaigns)
+
+	// Links in campaign emails; the token identifies and authenticates the
+	// recipient
+	trackGroup := r.Group("/campaigns/track/:token")
+	{
+		trackGroup.GET("/open", campaignHandler.TrackOpen)
+		trackGroup.GET("/click", campaignHandler.TrackClick)
+		trackGroup.GET("/unsubscribe", campaignHandler.Unsubscribe)
+		trackGroup.POST("/unsubscribe", campaignHandler.Unsubscribe)
+	}
+
+	adminGroup := r.Group("/admin/marketing")
+	canRead := middlewares.RequireScope(permissions.MarketingRead)
+	canWrite := middlewares.RequireScope(permissions.MarketingWrite)
+	auditSegments := middlewares.AuditTrail(db, "segment", func() interface{} { return &models.Segment{} })
+	auditCampaigns := middlewares.AuditTrail(db, "campaign", func() interface{} { return &models.Campaign{} })
+
+	segmentGroup := adminGroup.Group("/segments")
+	{
+		segmentGroup.POST("", canWrite, auditSegments, campaignHandler.CreateSegment)
+		segmentGroup.GET("", canRead, campaignHandler.GetSegments)
+		segmentGroup.GET("/:id", canRead, campaignHandler.GetSegment)
+		segmentGroup.PUT("/:id", canWrite, auditSegments, campaignHandler.UpdateSegment)
+		segmentGroup.DELETE("/:id", canWrite, auditSegments, campaignHandler.DeleteSegment)
+		segmentGroup.GET("/:id/preview", canRead, campaignHandler.PreviewSegment)
+	}
+
+	campaignGroup := adminGroup.Group("/campaigns")
+	{
+		campaignGroup.POST("", canWrite, auditCampaigns, campaignHandler.CreateCampaign)
+		campaignGroup.GET("", canRead, campaignHandler.GetCampaigns)
+		campaignGroup.GET("/:id", canRead, campaignHandler.GetCampaign)
+		campaignGroup.PUT("/:id", canWrite, auditCampaigns, campaignHandler.UpdateCampaign)
+		campaignGroup.DELETE("/:id", canWrite, auditCampaigns, campaignHandler.DeleteCampaign)
+		campaignGroup.POST("/:id/schedule", canWrite, auditCampaigns, campaignHandler.ScheduleCampaign)
+		campaignGroup.POST("/:id/unschedule", canWrite, auditCampaigns, campaignHandler.UnscheduleCampaign)
+		campaignGroup.POST("/:id/cancel", canWrite, auditCampaigns, campaignHandler.CancelCampaign)
+		campaignGroup.GET("/:id/stats", canRead, campaignHandler.GetCampaignStats)
+		campaignGroup.GET("/:id/recipients", canRead, campaignHandler.GetCampaignRecipients)
+	}
+}
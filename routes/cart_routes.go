@@ -1,21 +1,29 @@
 package routes
 
 import (
+	cartService "github.com/YasserCherfaoui/MarketProGo/cart"
+	"github.com/YasserCherfaoui/MarketProGo/email"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/cart"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func CartRoutes(router *gin.RouterGroup, db *gorm.DB) {
-	cartHandler := cart.NewCartHandler(db)
+func CartRoutes(router *gin.RouterGroup, db *gorm.DB, cartSvc *cartService.CartService, emailTriggerSvc *email.EmailTriggerService) {
+	cartHandler := cart.NewCartHandler(db, cartSvc, emailTriggerSvc)
 
 	cartRouter := router.Group("/cart")
 	cartRouter.Use(middlewares.AuthMiddleware())
 	{
 		cartRouter.GET("", cartHandler.GetCart)
+		cartRouter.POST("/validate", cartHandler.ValidateCart)
 		cartRouter.POST("/items", cartHandler.AddItem)
 		cartRouter.PUT("/items/:id", cartHandler.UpdateItem)
 		cartRouter.DELETE("/items/:id", cartHandler.DeleteItem)
 	}
+
+	// Rebuild the cart from a previous order
+	router.POST("/orders/:id/reorder", middlewares.AuthMiddleware(), cartHandler.Reorder)
+	// Price a B2B buyer's SKU list and optionally add it to the cart
+	router.POST("/orders/bulk", middlewares.AuthMiddleware(), cartHandler.BulkOrder)
 }
@@ -14,10 +14,13 @@ func CategoryRoutes(r *gin.RouterGroup, db *gorm.DB, gcs *gcs.GCService, appwrit
 	categoryRouter := r.Group("/categories")
 
 	categoryRouter.GET("", categoryHandler.GetAllCategories)
+	categoryRouter.GET("/tree", categoryHandler.GetCategoryTree)
 	categoryRouter.GET("/:id", categoryHandler.GetCategory)
 	categoryRouter.Use(middlewares.AuthMiddleware())
 	{
 		categoryRouter.POST("", categoryHandler.CreateCategory)
+		categoryRouter.PUT("/order", categoryHandler.ReorderCategories)
+		categoryRouter.PUT("/:id/move", categoryHandler.MoveCategory)
 		categoryRouter.PUT("/:id", categoryHandler.UpdateCategory)
 		categoryRouter.DELETE("/:id", categoryHandler.DeleteCategory)
 	}
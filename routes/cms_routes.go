@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/cache"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/cms"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CMSRoutes(r *gin.RouterGroup, db *gorm.DB, appwriteService *aw.AppwriteService, images *storage.Images, catalog *cache.Catalog) {
+	cmsHandler := cms.NewCMSHandler(db, appwriteService, images, catalog)
+
+	r.GET("/home", cmsHandler.GetHome)
+
+	canRead := middlewares.RequireScope(permissions.MarketingRead)
+	canWrite := middlewares.RequireScope(permissions.MarketingWrite)
+
+	bannerGroup := r.Group("/admin/banners")
+	auditBanner := middlewares.AuditTrail(db, "banner", func() interface{} { return &models.Banner{} })
+	{
+		bannerGroup.GET("", canRead, cmsHandler.ListBanners)
+		bannerGroup.POST("", canWrite, auditBanner, cmsHandler.CreateBanner)
+		bannerGroup.PUT("/order", canWrite, cmsHandler.ReorderBanners)
+		bannerGroup.PUT("/:id", canWrite, auditBanner, cmsHandler.UpdateBanner)
+		bannerGroup.DELETE("/:id", canWrite, auditBanner, cmsHandler.DeleteBanner)
+	}
+
+	collectionGroup := r.Group("/admin/collections")
+	auditCollection := middlewares.AuditTrail(db, "collection", func() interface{} { return &models.Collection{} })
+	{
+		collectionGroup.GET("", canRead, cmsHandler.ListCollections)
+		collectionGroup.GET("/:id", canRead, cmsHandler.GetCollection)
+		collectionGroup.POST("", canWrite, auditCollection, cmsHandler.CreateCollection)
+		collectionGroup.PUT("/order", canWrite, cmsHandler.ReorderCollections)
+		collectionGroup.PUT("/:id", canWrite, auditCollection, cmsHandler.UpdateCollection)
+		collectionGroup.DELETE("/:id", canWrite, auditCollection, cmsHandler.DeleteCollection)
+	}
+}
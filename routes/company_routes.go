@@ -0,0 +1,41 @@
+package routes
+
+import (
+	companyService "github.com/YasserCherfaoui/MarketProGo/company"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/company"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/order"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CompanyRoutes(r *gin.RouterGroup, db *gorm.DB, companies *companyService.Service, orderHandler *order.OrderHandler) {
+	companyHandler := company.NewCompanyHandler(db, companies)
+
+	companyGroup := r.Group("/company")
+	companyGroup.Use(middlewares.AuthMiddleware())
+	{
+		companyGroup.GET("", companyHandler.GetMyCompany)
+		companyGroup.PUT("/settings", companyHandler.UpdateSettings)
+
+		companyGroup.POST("/invitations", companyHandler.InviteBuyer)
+		companyGroup.GET("/invitations", companyHandler.GetInvitations)
+		companyGroup.DELETE("/invitations/:id", companyHandler.RevokeInvitation)
+		companyGroup.POST("/invitations/accept", companyHandler.AcceptInvitation)
+
+		companyGroup.PUT("/members/:id", companyHandler.UpdateMember)
+		companyGroup.DELETE("/members/:id", companyHandler.RemoveMember)
+
+		companyGroup.GET("/approvals", orderHandler.GetPendingApprovals)
+		companyGroup.POST("/approvals/:id/approve", middlewares.ForcePrimary(), orderHandler.ApproveOrder)
+		companyGroup.POST("/approvals/:id/reject", middlewares.ForcePrimary(), orderHandler.RejectOrder)
+	}
+
+	adminGroup := r.Group("/admin/companies")
+	auditCompany := middlewares.AuditTrail(db, "company", func() interface{} { return &models.Company{} })
+	{
+		adminGroup.PUT("/:id/owner", middlewares.RequireScope(permissions.PermissionsAdmin), auditCompany, companyHandler.SetCompanyOwner)
+	}
+}
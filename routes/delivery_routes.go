@@ -0,0 +1,52 @@
+package routes
+
+import (
+	deliveryService "github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func DeliveryRoutes(r *gin.RouterGroup, db *gorm.DB, slots *deliveryService.Service) {
+	deliveryHandler := delivery.NewDeliveryHandler(db, slots)
+
+	// Customers pick a slot for their address before checkout
+	r.GET("/delivery/slots", deliveryHandler.GetAvailableSlots)
+
+	adminGroup := r.Group("/admin/delivery")
+	canRead := middlewares.RequireScope(permissions.OrdersRead)
+	canWrite := middlewares.RequireScope(permissions.OrdersWrite)
+	auditZones := middlewares.AuditTrail(db, "delivery_zone", func() interface{} { return &models.DeliveryZone{} })
+	auditWindows := middlewares.AuditTrail(db, "delivery_window", func() interface{} { return &models.DeliveryWindow{} })
+	auditSlots := middlewares.AuditTrail(db, "delivery_slot", func() interface{} { return &models.DeliverySlot{} })
+
+	zoneGroup := adminGroup.Group("/zones")
+	{
+		zoneGroup.POST("", canWrite, auditZones, deliveryHandler.CreateZone)
+		zoneGroup.GET("", canRead, deliveryHandler.GetZones)
+		zoneGroup.PUT("/:id", canWrite, auditZones, deliveryHandler.UpdateZone)
+		zoneGroup.DELETE("/:id", canWrite, auditZones, deliveryHandler.DeleteZone)
+		zoneGroup.POST("/:id/windows", canWrite, auditWindows, deliveryHandler.CreateWindow)
+	}
+
+	windowGroup := adminGroup.Group("/windows")
+	{
+		windowGroup.PUT("/:id", canWrite, auditWindows, deliveryHandler.UpdateWindow)
+		windowGroup.DELETE("/:id", canWrite, auditWindows, deliveryHandler.DeleteWindow)
+	}
+
+	// Slots are created from the zone windows as they are listed; each can
+	// then have its capacity changed or be closed for that date alone
+	slotGroup := adminGroup.Group("/slots")
+	{
+		slotGroup.GET("", canRead, deliveryHandler.GetSlots)
+		slotGroup.PUT("/:id", canWrite, auditSlots, deliveryHandler.UpdateSlot)
+		slotGroup.POST("/:id/close", canWrite, auditSlots, deliveryHandler.CloseSlot)
+		slotGroup.POST("/:id/reopen", canWrite, auditSlots, deliveryHandler.ReopenSlot)
+		slotGroup.GET("/:id/orders", canRead, deliveryHandler.GetSlotOrders)
+		slotGroup.POST("/:id/move", canWrite, auditSlots, deliveryHandler.MoveOrders)
+	}
+}
@@ -7,9 +7,9 @@ import (
 )
 
 // SetupEmailRoutes sets up email-related routes
-func SetupEmailRoutes(router *gin.Engine, emailHandler *email.EmailHandler) {
+func SetupEmailRoutes(router *gin.RouterGroup, emailHandler *email.EmailHandler) {
 	// Email routes group
-	emailGroup := router.Group("/api/v1/email")
+	emailGroup := router.Group("/email")
 	{
 		// Public email endpoints (no authentication required)
 		emailGroup.POST("/send", emailHandler.SendEmail)
@@ -20,6 +20,9 @@ func SetupEmailRoutes(router *gin.Engine, emailHandler *email.EmailHandler) {
 		emailGroup.GET("/templates", emailHandler.GetEmailTemplates)
 		emailGroup.GET("/test-db", emailHandler.TestDatabaseConnection)
 
+		// Provider bounce and complaint webhook, authenticated by signature
+		emailGroup.POST("/webhooks/events", emailHandler.HandleDeliveryWebhook)
+
 		// Admin email management endpoints (require authentication)
 		adminGroup := emailGroup.Group("/admin")
 		adminGroup.Use(middlewares.AuthMiddleware())
@@ -27,7 +30,52 @@ func SetupEmailRoutes(router *gin.Engine, emailHandler *email.EmailHandler) {
 			adminGroup.GET("/list", emailHandler.GetEmailList)
 			adminGroup.POST("/retry/:id", emailHandler.RetryFailedEmail)
 			adminGroup.POST("/retry-all", emailHandler.RetryAllFailedEmails)
+			adminGroup.DELETE("/scheduled/:id", emailHandler.CancelScheduledEmail)
 			adminGroup.POST("/metrics", emailHandler.GetEmailMetrics)
 		}
+
+		// Dead-letter queue: emails that failed after the maximum number of retries
+		deadLetterGroup := emailGroup.Group("/admin/dead-letters")
+		deadLetterGroup.Use(middlewares.AdminMiddleware())
+		{
+			deadLetterGroup.GET("", emailHandler.GetDeadLetters)
+			deadLetterGroup.POST("/requeue", emailHandler.RequeueDeadLetters)
+			deadLetterGroup.POST("/delete", emailHandler.DeleteDeadLetters)
+		}
+
+		// Sent email browser: search stored emails, view them and resend them
+		sentGroup := emailGroup.Group("/admin/emails")
+		sentGroup.Use(middlewares.AdminMiddleware())
+		{
+			sentGroup.GET("", emailHandler.SearchEmails)
+			sentGroup.POST("/resend", emailHandler.ResendEmails)
+			sentGroup.GET("/:id", emailHandler.GetEmail)
+			sentGroup.GET("/:id/html", emailHandler.GetEmailHTML)
+			sentGroup.POST("/:id/resend", emailHandler.ResendEmail)
+		}
+
+		// Suppression list: addresses that no email is sent to
+		suppressionGroup := emailGroup.Group("/admin/suppressions")
+		suppressionGroup.Use(middlewares.AdminMiddleware())
+		{
+			suppressionGroup.GET("", emailHandler.GetSuppressions)
+			suppressionGroup.POST("", emailHandler.CreateSuppression)
+			suppressionGroup.DELETE("/:id", emailHandler.DeleteSuppression)
+		}
+
+		// Template editor: versions are stored in the database and override
+		// the filesystem templates once activated
+		templateGroup := emailGroup.Group("/admin/templates")
+		templateGroup.Use(middlewares.AdminMiddleware())
+		{
+			templateGroup.GET("", emailHandler.ListTemplateVersions)
+			templateGroup.POST("", emailHandler.CreateTemplateVersion)
+			templateGroup.POST("/preview", emailHandler.PreviewTemplate)
+			templateGroup.GET("/:id", emailHandler.GetTemplateVersion)
+			templateGroup.PUT("/:id", emailHandler.UpdateTemplateVersion)
+			templateGroup.POST("/:id/preview", emailHandler.PreviewTemplateVersion)
+			templateGroup.POST("/:id/activate", emailHandler.ActivateTemplateVersion)
+			templateGroup.POST("/:id/deactivate", emailHandler.DeactivateTemplateVersion)
+		}
 	}
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/feeds"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/feed"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+)
+
+func FeedRoutes(r *gin.RouterGroup, feedService *feeds.Service) {
+	feedHandler := feed.NewFeedHandler(feedService)
+
+	feedGroup := r.Group("/feeds")
+	{
+		feedGroup.GET("/sitemap.xml", feedHandler.GetSitemap)
+		feedGroup.GET("/google-merchant.xml", feedHandler.GetMerchantFeedXML)
+		feedGroup.GET("/google-merchant.csv", feedHandler.GetMerchantFeedCSV)
+	}
+
+	adminGroup := r.Group("/admin/feeds")
+	adminGroup.Use(middlewares.RequireScope(permissions.ProductsWrite))
+	{
+		adminGroup.GET("", feedHandler.ListFeeds)
+		adminGroup.POST("/regenerate", feedHandler.RegenerateFeeds)
+	}
+}
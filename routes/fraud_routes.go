@@ -0,0 +1,27 @@
+package routes
+
+import (
+	fraudService "github.com/YasserCherfaoui/MarketProGo/fraud"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/fraud"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func FraudRoutes(r *gin.RouterGroup, db *gorm.DB, fraudChecks *fraudService.Service, payments payment.PaymentService) {
+	fraudHandler := fraud.NewFraudHandler(fraudChecks, payments)
+
+	adminGroup := r.Group("/admin/fraud/reviews")
+	canRead := middlewares.RequireScope(permissions.OrdersRead)
+	canDecide := middlewares.RequireScope(permissions.PaymentsRefund)
+	auditFraudCheck := middlewares.AuditTrail(db, "fraud_check", func() interface{} { return &models.FraudCheck{} })
+	{
+		adminGroup.GET("", canRead, fraudHandler.ListReviews)
+		adminGroup.GET("/:id", canRead, fraudHandler.GetReview)
+		adminGroup.POST("/:id/approve", canDecide, auditFraudCheck, fraudHandler.ApproveReview)
+		adminGroup.POST("/:id/reject", canDecide, auditFraudCheck, fraudHandler.RejectReview)
+	}
+}
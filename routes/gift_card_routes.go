@@ -0,0 +1,36 @@
+package routes
+
+import (
+	giftCardService "github.com/YasserCherfaoui/MarketProGo/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/giftcard"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func GiftCardRoutes(r *gin.RouterGroup, db *gorm.DB, giftCards *giftCardService.Service, limiter *ratelimit.Limiter) {
+	giftCardHandler := giftcard.NewGiftCardHandler(db, giftCards)
+
+	// Balance checks get the credential limit, so codes cannot be guessed
+	r.POST("/gift-cards/balance", middlewares.RateLimit(limiter, ratelimit.ClassAuth), giftCardHandler.CheckBalance)
+
+	customerGroup := r.Group("/gift-cards")
+	customerGroup.Use(middlewares.AuthMiddleware())
+	{
+		customerGroup.GET("/mine", giftCardHandler.GetMyGiftCards)
+	}
+
+	adminGroup := r.Group("/admin/gift-cards")
+	canRead := middlewares.RequireScope(permissions.OrdersRead)
+	canWrite := middlewares.RequireScope(permissions.OrdersWrite)
+	auditGiftCard := middlewares.AuditTrail(db, "gift_card", func() interface{} { return &models.GiftCard{} })
+	{
+		adminGroup.GET("", canRead, giftCardHandler.ListGiftCards)
+		adminGroup.GET("/:id", canRead, giftCardHandler.GetGiftCard)
+		adminGroup.POST("", canWrite, auditGiftCard, giftCardHandler.IssueGiftCard)
+		adminGroup.POST("/:id/void", canWrite, auditGiftCard, giftCardHandler.VoidGiftCard)
+	}
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/impersonation"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func ImpersonationRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	impersonationHandler := impersonation.NewImpersonationHandler(db)
+
+	router.POST("/admin/impersonate/:userId", middlewares.RequireScope(permissions.UsersImpersonate), impersonationHandler.Impersonate)
+	router.GET("/admin/impersonation-logs", middlewares.RequireScope(permissions.ImpersonationRead), impersonationHandler.GetImpersonationLogs)
+}
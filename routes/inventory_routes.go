@@ -3,57 +3,79 @@ package routes
 import (
 	"github.com/YasserCherfaoui/MarketProGo/handlers/inventory"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func InventoryRoutes(r *gin.RouterGroup, inventoryHandler *inventory.InventoryHandler) {
-	// All inventory routes require authentication and admin privileges
+func InventoryRoutes(r *gin.RouterGroup, db *gorm.DB, inventoryHandler *inventory.InventoryHandler) {
+	// All inventory routes require the inventory scopes; reads and writes are granted separately
 	inventoryGroup := r.Group("/inventory")
-	inventoryGroup.Use(middlewares.AuthMiddleware())
-	// inventoryGroup.Use(middlewares.AdminMiddleware()) // Uncomment when admin middleware is implemented
+	canRead := middlewares.RequireScope(permissions.InventoryRead)
+	canWrite := middlewares.RequireScope(permissions.InventoryWrite)
+	auditWarehouses := middlewares.AuditTrail(db, "warehouse", func() interface{} { return &models.Warehouse{} })
+	auditStock := middlewares.AuditTrail(db, "stock_adjustment", nil)
 
 	// Dashboard route - comprehensive overview for admin
-	inventoryGroup.GET("/dashboard", inventoryHandler.GetInventoryDashboard)
+	inventoryGroup.GET("/dashboard", canRead, inventoryHandler.GetInventoryDashboard)
 
 	// Warehouse management routes
 	warehouseGroup := inventoryGroup.Group("/warehouses")
 	{
-		warehouseGroup.POST("", inventoryHandler.CreateWarehouse)
-		warehouseGroup.GET("", inventoryHandler.GetAllWarehouses)
-		warehouseGroup.GET("/:id", inventoryHandler.GetWarehouse)
-		warehouseGroup.PUT("/:id", inventoryHandler.UpdateWarehouse)
-		warehouseGroup.DELETE("/:id", inventoryHandler.DeleteWarehouse)
+		warehouseGroup.POST("", canWrite, auditWarehouses, inventoryHandler.CreateWarehouse)
+		warehouseGroup.GET("", canRead, inventoryHandler.GetAllWarehouses)
+		warehouseGroup.GET("/:id", canRead, inventoryHandler.GetWarehouse)
+		warehouseGroup.PUT("/:id", canWrite, auditWarehouses, inventoryHandler.UpdateWarehouse)
+		warehouseGroup.DELETE("/:id", canWrite, auditWarehouses, inventoryHandler.DeleteWarehouse)
 	}
 
 	// Product inventory overview route
-	inventoryGroup.GET("/products", inventoryHandler.GetProductInventoryOverview)
+	inventoryGroup.GET("/products", canRead, inventoryHandler.GetProductInventoryOverview)
 
 	// Stock management routes
 	stockGroup := inventoryGroup.Group("/stock")
 	{
-		stockGroup.GET("", inventoryHandler.GetStockLevels)
-		stockGroup.POST("/adjust", inventoryHandler.AdjustStock)
-		stockGroup.GET("/by-product/:product_variant_id", inventoryHandler.GetMultiWarehouseStock)
+		stockGroup.GET("", canRead, inventoryHandler.GetStockLevels)
+		stockGroup.POST("/adjust", canWrite, auditStock, inventoryHandler.AdjustStock)
+		stockGroup.GET("/by-product/:product_variant_id", canRead, inventoryHandler.GetMultiWarehouseStock)
+		stockGroup.GET("/expiring", canRead, inventoryHandler.GetExpiringStock)
+		stockGroup.PUT("/:id/bin", canWrite, auditStock, inventoryHandler.SetBinLocation)
+		stockGroup.POST("/:id/write-off", canWrite, auditStock, inventoryHandler.WriteOffStock)
+		stockGroup.POST("/write-off-expired", canWrite, auditStock, inventoryHandler.WriteOffExpiredStock)
 		// stockGroup.POST("/bulk-adjust", inventoryHandler.BulkAdjustStock)
 		// stockGroup.POST("/transfer", inventoryHandler.TransferStock)
 		// stockGroup.POST("/reserve", inventoryHandler.ReserveStock)
 		// stockGroup.DELETE("/reserve/:id", inventoryHandler.ReleaseReservation)
 	}
 
+	// Stocktake routes: open a count, record counted quantities and post the variances
+	auditCounts := middlewares.AuditTrail(db, "stock_count", func() interface{} { return &models.StockCount{} })
+	countGroup := inventoryGroup.Group("/counts")
+	{
+		countGroup.POST("", canWrite, auditCounts, inventoryHandler.OpenStockCount)
+		countGroup.GET("", canRead, inventoryHandler.GetStockCounts)
+		countGroup.GET("/:id", canRead, inventoryHandler.GetStockCount)
+		countGroup.PUT("/:id/lines", canWrite, auditCounts, inventoryHandler.RecordStockCounts)
+		countGroup.POST("/:id/lines/upload", canWrite, auditCounts, inventoryHandler.UploadStockCounts)
+		countGroup.POST("/:id/approve", canWrite, auditCounts, inventoryHandler.ApproveStockCount)
+		countGroup.POST("/:id/cancel", canWrite, auditCounts, inventoryHandler.CancelStockCount)
+	}
+
 	// Batch tracking route
-	inventoryGroup.GET("/batches", inventoryHandler.GetInventoryBatches)
+	inventoryGroup.GET("/batches", canRead, inventoryHandler.GetInventoryBatches)
 
 	// Stock movement and audit routes
 	movementGroup := inventoryGroup.Group("/movements")
 	{
-		movementGroup.GET("", inventoryHandler.GetStockMovements)
-		movementGroup.GET("/:id", inventoryHandler.GetStockMovement)
+		movementGroup.GET("", canRead, inventoryHandler.GetStockMovements)
+		movementGroup.GET("/:id", canRead, inventoryHandler.GetStockMovement)
 	}
 
 	// Alerts and notifications routes
 	alertsGroup := inventoryGroup.Group("/alerts")
 	{
-		alertsGroup.GET("", inventoryHandler.GetStockAlerts)
+		alertsGroup.GET("", canRead, inventoryHandler.GetStockAlerts)
 		// alertsGroup.POST("", inventoryHandler.CreateStockAlert)
 		// alertsGroup.PUT("/:id", inventoryHandler.UpdateStockAlert)
 		// alertsGroup.DELETE("/:id", inventoryHandler.DeleteStockAlert)
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/loyalty"
+	loyaltyService "github.com/YasserCherfaoui/MarketProGo/loyalty"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func LoyaltyRoutes(r *gin.RouterGroup, db *gorm.DB, points *loyaltyService.Service) {
+	loyaltyHandler := loyalty.NewLoyaltyHandler(db, points)
+
+	customerGroup := r.Group("/loyalty")
+	customerGroup.Use(middlewares.AuthMiddleware())
+	{
+		customerGroup.GET("", loyaltyHandler.GetMyPoints)
+		customerGroup.GET("/history", loyaltyHandler.GetMyHistory)
+	}
+
+	adminGroup := r.Group("/admin/loyalty/users/:id")
+	canRead := middlewares.RequireScope(permissions.MarketingRead)
+	canWrite := middlewares.RequireScope(permissions.MarketingWrite)
+	auditPoints := middlewares.AuditTrail(db, "loyalty_transaction", func() interface{} { return &models.LoyaltyTransaction{} })
+	{
+		adminGroup.GET("", canRead, loyaltyHandler.GetUserPoints)
+		adminGroup.GET("/history", canRead, loyaltyHandler.GetUserHistory)
+		adminGroup.POST("/adjust", canWrite, auditPoints, loyaltyHandler.AdjustUserPoints)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"crypto/subtle"
+
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsRoutes serves Prometheus metrics at /metrics. When token is set,
+// scrapers must send it as a bearer token.
+func MetricsRoutes(r *gin.Engine, token string) {
+	handler := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+
+	r.GET("/metrics", func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			response.GenerateUnauthorizedResponse(c, "metrics", "token is invalid")
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	})
+}
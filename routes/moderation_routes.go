@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	moderationService "github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func ModerationRoutes(r *gin.RouterGroup, db *gorm.DB, moderations *moderationService.Service) {
+	moderationHandler := moderation.NewModerationHandler(db, moderations)
+
+	adminGroup := r.Group("/admin/moderation/users/:id")
+	canRead := middlewares.RequireScope(permissions.SupportRead)
+	canWrite := middlewares.RequireScope(permissions.SupportAdmin)
+	auditStrikes := middlewares.AuditTrail(db, "user_strike", func() interface{} { return &models.UserStrike{} })
+	auditRestrictions := middlewares.AuditTrail(db, "user_restriction", func() interface{} { return &models.UserRestriction{} })
+	{
+		adminGroup.GET("", canRead, moderationHandler.GetUserStanding)
+		adminGroup.POST("/strikes", canWrite, auditStrikes, moderationHandler.IssueStrike)
+		adminGroup.POST("/strikes/:strikeId/revoke", canWrite, auditStrikes, moderationHandler.RevokeStrike)
+		adminGroup.POST("/restrictions", canWrite, auditRestrictions, moderationHandler.RestrictUser)
+		adminGroup.POST("/restrictions/:restrictionId/lift", canWrite, auditRestrictions, moderationHandler.LiftRestriction)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/notification"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func NotificationRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	notificationHandler := notification.NewNotificationHandler(db)
+
+	// In-app notification center for the signed-in user
+	notificationGroup := router.Group("/notifications")
+	notificationGroup.Use(middlewares.AuthMiddleware())
+	{
+		notificationGroup.GET("", notificationHandler.GetNotifications)
+		notificationGroup.GET("/unread-count", notificationHandler.GetUnreadCount)
+		notificationGroup.PUT("/read-all", notificationHandler.MarkAllRead)
+		notificationGroup.PUT("/:id/read", notificationHandler.MarkRead)
+	}
+}
@@ -3,41 +3,93 @@ package routes
 import (
 	"github.com/YasserCherfaoui/MarketProGo/handlers/order"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/YasserCherfaoui/MarketProGo/verification"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func OrderRoutes(router *gin.RouterGroup, orderHandler *order.OrderHandler) {
+func OrderRoutes(router *gin.RouterGroup, db *gorm.DB, orderHandler *order.OrderHandler, verifications *verification.Service, phones *sms.Service) {
+	// Placing orders can be limited to verified email addresses and phone numbers
+	verified := middlewares.RequireVerifiedEmail(verifications)
+	verifiedPhone := middlewares.RequireVerifiedPhone(phones)
+
 	// Customer order routes (require authentication)
 	orderRouter := router.Group("/orders")
 	orderRouter.Use(middlewares.AuthMiddleware())
 	{
-		orderRouter.POST("/place", orderHandler.PlaceOrder)
+		orderRouter.POST("/place", verified, verifiedPhone, middlewares.ForcePrimary(), orderHandler.PlaceOrder)
 		orderRouter.GET("", orderHandler.GetOrders)
+		orderRouter.GET("/messages/unread", orderHandler.GetUnreadOrderMessages)
 		orderRouter.GET("/:id", orderHandler.GetOrder)
 		orderRouter.PUT("/:id/cancel", orderHandler.CancelOrder)
+		orderRouter.GET("/:id/messages", orderHandler.GetOrderMessages)
+		orderRouter.POST("/:id/messages", orderHandler.SendOrderMessage)
+	}
+
+	// Checkout places the cart's order and starts its payment in one request
+	router.POST("/checkout", middlewares.AuthMiddleware(), verified, verifiedPhone, middlewares.ForcePrimary(), orderHandler.Checkout)
+
+	// Customer account routes
+	accountRouter := router.Group("/account")
+	accountRouter.Use(middlewares.AuthMiddleware())
+	{
+		accountRouter.GET("/orders/export", orderHandler.ExportOrderHistory)
 	}
 
 	// Admin order routes (require admin authentication)
+	canRead := middlewares.RequireScope(permissions.OrdersRead)
+	canWrite := middlewares.RequireScope(permissions.OrdersWrite)
+	auditOrders := middlewares.AuditTrail(db, "order", func() interface{} { return &models.Order{} })
+	auditInvoices := middlewares.AuditTrail(db, "invoice", func() interface{} { return &models.Invoice{} })
+	auditPickLists := middlewares.AuditTrail(db, "pick_list", func() interface{} { return &models.PickList{} })
+
 	adminOrderRouter := router.Group("/admin/orders")
-	adminOrderRouter.Use(middlewares.AuthMiddleware()) // TODO: Add admin middleware when available
 	{
 		// Order management
-		adminOrderRouter.GET("", orderHandler.GetAllOrders)
-		adminOrderRouter.GET("/stats", orderHandler.GetOrderStats)
-		adminOrderRouter.GET("/:id", orderHandler.GetOrderByID)
+		adminOrderRouter.GET("", canRead, orderHandler.GetAllOrders)
+		adminOrderRouter.GET("/stats", canRead, middlewares.ReadReplica(), orderHandler.GetOrderStats)
+
+		// Pick lists group the shipments of a batch of paid orders by warehouse and bin
+		adminOrderRouter.POST("/pick-lists", canWrite, auditPickLists, orderHandler.CreatePickList)
+		adminOrderRouter.GET("/pick-lists", canRead, orderHandler.GetPickLists)
+		adminOrderRouter.GET("/pick-lists/:id", canRead, orderHandler.GetPickList)
+
+		adminOrderRouter.GET("/messages/unread", canRead, orderHandler.GetUnreadOrderMessagesAdmin)
+
+		adminOrderRouter.GET("/:id", canRead, orderHandler.GetOrderByID)
+		adminOrderRouter.GET("/:id/timeline", canRead, orderHandler.GetOrderTimeline)
+
+		// Internal notes, never shown to the customer
+		adminOrderRouter.GET("/:id/notes", canRead, orderHandler.GetOrderNotes)
+		adminOrderRouter.POST("/:id/notes", canWrite, orderHandler.AddOrderNote)
+
+		// Conversation with the customer about the order
+		adminOrderRouter.GET("/:id/messages", canRead, orderHandler.GetOrderMessagesAdmin)
+		adminOrderRouter.POST("/:id/messages", canWrite, orderHandler.SendOrderMessageAdmin)
 
 		// Order status management
-		adminOrderRouter.PUT("/:id/status", orderHandler.UpdateOrderStatus)
-		adminOrderRouter.PUT("/:id/payment", orderHandler.UpdatePaymentStatus)
+		adminOrderRouter.PUT("/:id/status", canWrite, auditOrders, orderHandler.UpdateOrderStatus)
+		adminOrderRouter.PUT("/:id/status/force", canWrite, auditOrders, orderHandler.ForceOrderStatus)
+		adminOrderRouter.PUT("/:id/payment", canWrite, auditOrders, orderHandler.UpdatePaymentStatus)
+		adminOrderRouter.POST("/:id/out-for-delivery", canWrite, auditOrders, orderHandler.MarkOutForDelivery)
+
+		// Shipments, one per fulfilling warehouse
+		adminOrderRouter.GET("/:id/shipments", canRead, orderHandler.GetOrderShipments)
+		adminOrderRouter.GET("/:id/shipments/:shipmentId/packing-slip", canRead, orderHandler.GetPackingSlip)
+		adminOrderRouter.PUT("/:id/shipments/:shipmentId/pick", canWrite, auditOrders, orderHandler.PickShipment)
+		adminOrderRouter.PUT("/:id/shipments/:shipmentId/pack", canWrite, auditOrders, orderHandler.PackShipment)
+		adminOrderRouter.PUT("/:id/shipments/:shipmentId/ship", canWrite, auditOrders, orderHandler.ShipShipment)
 	}
 
 	// Admin invoice routes
 	adminInvoiceRouter := router.Group("/admin/invoices")
-	adminInvoiceRouter.Use(middlewares.AuthMiddleware()) // TODO: Add admin middleware when available
 	{
-		adminInvoiceRouter.POST("", orderHandler.CreateInvoice)
-		adminInvoiceRouter.GET("", orderHandler.GetInvoices)
-		adminInvoiceRouter.GET("/:id", orderHandler.GetInvoice)
-		adminInvoiceRouter.PUT("/:id", orderHandler.UpdateInvoice)
+		adminInvoiceRouter.POST("", canWrite, auditInvoices, orderHandler.CreateInvoice)
+		adminInvoiceRouter.GET("", canRead, orderHandler.GetInvoices)
+		adminInvoiceRouter.GET("/:id", canRead, orderHandler.GetInvoice)
+		adminInvoiceRouter.PUT("/:id", canWrite, auditInvoices, orderHandler.UpdateInvoice)
 	}
 }
@@ -3,13 +3,14 @@ package routes
 import (
 	"github.com/YasserCherfaoui/MarketProGo/handlers/payment"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupPaymentRoutes sets up payment-related routes
-func SetupPaymentRoutes(router *gin.Engine, paymentHandler *payment.PaymentHandler) {
+func SetupPaymentRoutes(router *gin.RouterGroup, paymentHandler *payment.PaymentHandler) {
 	// Payment routes group
-	paymentRoutes := router.Group("/api/v1/payments")
+	paymentRoutes := router.Group("/payments")
 	{
 		// Customer routes (require authentication)
 		customerRoutes := paymentRoutes.Group("")
@@ -33,8 +34,7 @@ func SetupPaymentRoutes(router *gin.Engine, paymentHandler *payment.PaymentHandl
 
 		// Admin routes (require admin authentication)
 		adminRoutes := paymentRoutes.Group("/admin")
-		adminRoutes.Use(middlewares.AuthMiddleware())
-		adminRoutes.Use(middlewares.AdminMiddleware())
+		adminRoutes.Use(middlewares.RequireScope(permissions.PaymentsRefund))
 		{
 			// Process refund (admin only)
 			adminRoutes.POST("/:id/refund", paymentHandler.RefundPayment)
@@ -43,4 +43,19 @@ func SetupPaymentRoutes(router *gin.Engine, paymentHandler *payment.PaymentHandl
 		// Webhook route (no authentication required, but signature validation)
 		paymentRoutes.POST("/webhook", paymentHandler.HandleWebhook)
 	}
+
+	// Retry the payment of an unpaid order, linked from the payment failed email
+	orderPaymentRoutes := router.Group("/orders")
+	orderPaymentRoutes.Use(middlewares.AuthMiddleware())
+	{
+		orderPaymentRoutes.POST("/:id/payment/retry", paymentHandler.RetryPayment)
+	}
+
+	// Admin payment operations
+	adminPaymentRoutes := router.Group("/admin/payments")
+	adminPaymentRoutes.Use(middlewares.RequireScope(permissions.PaymentsRefund))
+	{
+		// Capture an authorized manual capture payment, in full or in part
+		adminPaymentRoutes.POST("/:id/capture", paymentHandler.CapturePayment)
+	}
 }
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/pricelist"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func PriceListRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	priceListHandler := pricelist.NewPriceListHandler(db)
+
+	adminPriceLists := router.Group("/admin/price-lists")
+	adminPriceLists.Use(middlewares.AdminMiddleware())
+	{
+		adminPriceLists.POST("", priceListHandler.CreatePriceList)
+		adminPriceLists.GET("", priceListHandler.GetPriceLists)
+		adminPriceLists.GET("/:id", priceListHandler.GetPriceList)
+		adminPriceLists.PUT("/:id", priceListHandler.UpdatePriceList)
+		adminPriceLists.PUT("/:id/items", priceListHandler.SetPriceListItems)
+		adminPriceLists.DELETE("/:id", priceListHandler.DeletePriceList)
+	}
+}
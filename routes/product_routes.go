@@ -2,20 +2,33 @@ package routes
 
 import (
 	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/cache"
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/product"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/storage"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func ProductRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService) {
+func ProductRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService, images *storage.Images, catalog *cache.Catalog, availability *cache.Availability) {
 	productRouter := router.Group("/products")
-	productHandler := product.NewProductHandler(db, gcsService, appwriteService)
+	productHandler := product.NewProductHandler(db, gcsService, appwriteService, images, catalog, availability)
 
-	productRouter.GET("", productHandler.GetAllProducts)
-	productRouter.GET("/:id", productHandler.GetProduct)
+	productRouter.GET("", middlewares.ReadReplica(), middlewares.OptionalAuthMiddleware(), productHandler.GetAllProducts)
+	productRouter.GET("/:id", middlewares.OptionalAuthMiddleware(), productHandler.GetProduct)
 	productRouter.GET("/:id/review-stats", productHandler.GetProductReviewStats)
+	productRouter.GET("/:id/rating", middlewares.ReadReplica(), productHandler.GetProductRating)
+	productRouter.GET("/:id/availability", middlewares.ReadReplica(), productHandler.GetProductAvailability)
+	router.GET("/brands/:id/products", middlewares.ReadReplica(), productHandler.GetBrandProducts)
+	router.GET("/variants/:id/pricing", middlewares.OptionalAuthMiddleware(), productHandler.GetVariantPricing)
+
+	// Batch reads, so a storefront page loads its products in one request each
+	productRouter.POST("/batch", middlewares.ReadReplica(), middlewares.OptionalAuthMiddleware(), productHandler.GetProductsBatch)
+	router.POST("/variants/availability/batch", middlewares.ReadReplica(), productHandler.GetVariantsAvailabilityBatch)
+	router.POST("/reviews/summary/batch", middlewares.ReadReplica(), productHandler.GetReviewSummariesBatch)
 
 	// Product variants endpoint - requires authentication for stock management
 	productVariantRouter := router.Group("/product-variants")
@@ -24,11 +37,23 @@ func ProductRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCServi
 		productVariantRouter.GET("", productHandler.GetProductVariants)
 	}
 
-	productRouter.Use(middlewares.AuthMiddleware())
+	auditProducts := middlewares.AuditTrail(db, "product", func() interface{} { return &models.Product{} })
+	productRouter.Use(middlewares.RequireScope(permissions.ProductsWrite), auditProducts)
 	{
 		productRouter.POST("", productHandler.CreateProduct)
 		productRouter.PUT("/:id", productHandler.UpdateProduct)
 		productRouter.DELETE("/:id", productHandler.DeleteProduct)
+		productRouter.PUT("/:id/schedule", productHandler.ScheduleProduct)
+	}
+	router.GET("/admin/products/scheduled", middlewares.RequireScope(permissions.ProductsWrite), productHandler.GetScheduledProducts)
+
+	auditVariants := middlewares.AuditTrail(db, "product_variant", func() interface{} { return &models.ProductVariant{} })
+	bundleRouter := router.Group("/product-variants/:id/bundle")
+	bundleRouter.Use(middlewares.RequireScope(permissions.ProductsWrite), auditVariants)
+	{
+		bundleRouter.GET("", productHandler.GetBundle)
+		bundleRouter.PUT("", productHandler.SetBundle)
+		bundleRouter.DELETE("", productHandler.DeleteBundle)
 	}
 
 }
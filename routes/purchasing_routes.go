@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/purchasing"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func PurchasingRoutes(r *gin.RouterGroup, db *gorm.DB) {
+	purchasingHandler := purchasing.NewPurchasingHandler(db)
+
+	purchasingGroup := r.Group("/purchasing")
+	canRead := middlewares.RequireScope(permissions.PurchasingRead)
+	canWrite := middlewares.RequireScope(permissions.PurchasingWrite)
+	auditSuppliers := middlewares.AuditTrail(db, "supplier", func() interface{} { return &models.Supplier{} })
+	auditOrders := middlewares.AuditTrail(db, "purchase_order", func() interface{} { return &models.PurchaseOrder{} })
+
+	supplierGroup := purchasingGroup.Group("/suppliers")
+	{
+		supplierGroup.POST("", canWrite, auditSuppliers, purchasingHandler.CreateSupplier)
+		supplierGroup.GET("", canRead, purchasingHandler.GetSuppliers)
+		supplierGroup.GET("/performance", canRead, purchasingHandler.GetSupplierPerformance)
+		supplierGroup.GET("/:id", canRead, purchasingHandler.GetSupplier)
+		supplierGroup.PUT("/:id", canWrite, auditSuppliers, purchasingHandler.UpdateSupplier)
+		supplierGroup.DELETE("/:id", canWrite, auditSuppliers, purchasingHandler.DeleteSupplier)
+	}
+
+	// Purchase orders move from draft to sent, then to partially received and
+	// received as goods receipts book their stock in
+	orderGroup := purchasingGroup.Group("/orders")
+	{
+		orderGroup.POST("", canWrite, auditOrders, purchasingHandler.CreatePurchaseOrder)
+		orderGroup.GET("", canRead, purchasingHandler.GetPurchaseOrders)
+		orderGroup.GET("/:id", canRead, purchasingHandler.GetPurchaseOrder)
+		orderGroup.PUT("/:id", canWrite, auditOrders, purchasingHandler.UpdatePurchaseOrder)
+		orderGroup.POST("/:id/send", canWrite, auditOrders, purchasingHandler.SendPurchaseOrder)
+		orderGroup.POST("/:id/cancel", canWrite, auditOrders, purchasingHandler.CancelPurchaseOrder)
+		orderGroup.POST("/:id/receipts", canWrite, auditOrders, purchasingHandler.ReceiveGoods)
+	}
+}
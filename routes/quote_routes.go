@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/quote"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+func QuoteRoutes(router *gin.RouterGroup, quoteHandler *quote.QuoteHandler) {
+	// Customer quote routes (B2B customers)
+	quoteRouter := router.Group("/quotes")
+	quoteRouter.Use(middlewares.AuthMiddleware())
+	{
+		quoteRouter.POST("", quoteHandler.RequestQuote)
+		quoteRouter.GET("", quoteHandler.GetQuotes)
+		quoteRouter.GET("/:id", quoteHandler.GetQuote)
+		quoteRouter.POST("/:id/accept", quoteHandler.AcceptQuote)
+		quoteRouter.POST("/:id/decline", quoteHandler.DeclineQuote)
+	}
+
+	// Admin quote routes
+	adminQuoteRouter := router.Group("/admin/quotes")
+	adminQuoteRouter.Use(middlewares.AdminMiddleware())
+	{
+		adminQuoteRouter.GET("", quoteHandler.GetAllQuotes)
+		adminQuoteRouter.GET("/:id", quoteHandler.GetQuoteByID)
+		adminQuoteRouter.PUT("/:id/respond", quoteHandler.RespondQuote)
+	}
+}
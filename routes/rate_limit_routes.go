@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
+	"github.com/YasserCherfaoui/MarketProGo/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+func RateLimitRoutes(router *gin.RouterGroup, limiter *ratelimit.Limiter) {
+	// Requests rejected by the rate limiter since startup, per route class
+	router.GET("/admin/rate-limits", middlewares.RequireScope(permissions.RateLimitsRead), func(c *gin.Context) {
+		response.GenerateSuccessResponse(c, "Rate limit stats retrieved successfully", gin.H{
+			"enabled":   limiter.Enabled(),
+			"throttled": limiter.Throttled(),
+		})
+	})
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/referral"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	referralService "github.com/YasserCherfaoui/MarketProGo/referral"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func ReferralRoutes(r *gin.RouterGroup, db *gorm.DB, referrals *referralService.Service) {
+	referralHandler := referral.NewReferralHandler(db, referrals)
+
+	customerGroup := r.Group("")
+	customerGroup.Use(middlewares.AuthMiddleware())
+	{
+		customerGroup.GET("/referrals/me", referralHandler.GetMyReferrals)
+		customerGroup.GET("/coupons", referralHandler.GetMyCoupons)
+	}
+
+	adminGroup := r.Group("/admin/referrals")
+	canRead := middlewares.RequireScope(permissions.MarketingRead)
+	canWrite := middlewares.RequireScope(permissions.MarketingWrite)
+	auditReferral := middlewares.AuditTrail(db, "referral", func() interface{} { return &models.Referral{} })
+	{
+		adminGroup.GET("", canRead, referralHandler.ListReferrals)
+		adminGroup.POST("/:id/approve", canWrite, auditReferral, referralHandler.ApproveReferral)
+	}
+}
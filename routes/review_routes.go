@@ -3,16 +3,23 @@ package routes
 import (
 	"github.com/YasserCherfaoui/MarketProGo/handlers/review"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
+	"github.com/YasserCherfaoui/MarketProGo/verification"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // RegisterReviewRoutes sets up all review-related routes
-func RegisterReviewRoutes(router *gin.RouterGroup, reviewHandler *review.ReviewHandler) {
+func RegisterReviewRoutes(router *gin.RouterGroup, db *gorm.DB, reviewHandler *review.ReviewHandler, limiter *ratelimit.Limiter, verifications *verification.Service) {
+	// Writing reviews can be limited to verified email addresses
+	verified := middlewares.RequireVerifiedEmail(verifications)
+
 	// Public routes (no authentication required)
 	reviews := router.Group("/reviews")
 	{
 		// Get reviews for a specific product variant
-		reviews.GET("/product/:productVariantId", reviewHandler.GetProductReviews)
+		reviews.GET("/product/:productVariantId", middlewares.ReadReplica(), reviewHandler.GetProductReviews)
 	}
 
 	// Routes with optional authentication (for GetReview to allow admin access)
@@ -28,13 +35,16 @@ func RegisterReviewRoutes(router *gin.RouterGroup, reviewHandler *review.ReviewH
 	authenticatedReviews.Use(middlewares.AuthMiddleware())
 	{
 		// Customer review management
-		authenticatedReviews.POST("", reviewHandler.CreateReview)
+		authenticatedReviews.POST("", verified, middlewares.RateLimit(limiter, ratelimit.ClassReview), reviewHandler.CreateReview)
 		authenticatedReviews.PUT("/:id", reviewHandler.UpdateReview)
 		authenticatedReviews.DELETE("/:id", reviewHandler.DeleteReview)
 
 		// Review helpfulness
 		authenticatedReviews.POST("/:id/helpful", reviewHandler.MarkReviewHelpful)
 
+		// Follow-up thread below a seller response
+		authenticatedReviews.POST("/:id/response/replies", verified, reviewHandler.CreateReviewReply)
+
 		// Image upload for reviews
 		authenticatedReviews.POST("/upload-images", reviewHandler.UploadReviewImages)
 
@@ -45,6 +55,10 @@ func RegisterReviewRoutes(router *gin.RouterGroup, reviewHandler *review.ReviewH
 		authenticatedReviews.GET("/user/me", reviewHandler.GetUserReviews)
 	}
 
+	// Whether the user can review a product variant. The segment is a variant
+	// ID, named :id to share the /products/:id route tree.
+	router.GET("/products/:id/review-eligibility", middlewares.AuthMiddleware(), reviewHandler.GetReviewEligibility)
+
 	// Seller routes (seller role required)
 	sellerReviews := router.Group("/reviews")
 	sellerReviews.Use(middlewares.SellerMiddleware())
@@ -56,15 +70,30 @@ func RegisterReviewRoutes(router *gin.RouterGroup, reviewHandler *review.ReviewH
 
 	// Admin routes (admin role required)
 	adminReviews := router.Group("/admin/reviews")
+	auditReviews := middlewares.AuditTrail(db, "review", func() interface{} { return &models.ProductReview{} })
 	adminReviews.Use(middlewares.AdminMiddleware())
 	{
 		// Admin review management
-		adminReviews.GET("", reviewHandler.GetAllReviews)
-		adminReviews.PUT("/:id/moderate", reviewHandler.ModerateReview)
-		adminReviews.DELETE("/:id", reviewHandler.AdminDeleteReview)
+		adminReviews.GET("", middlewares.ReadReplica(), reviewHandler.GetAllReviews)
+		adminReviews.PUT("/:id/moderate", auditReviews, reviewHandler.ModerateReview)
+		adminReviews.DELETE("/:id", auditReviews, reviewHandler.AdminDeleteReview)
+
+		// Moderation queue and bulk actions
+		adminReviews.GET("/moderation-queue", middlewares.ReadReplica(), reviewHandler.GetModerationQueue)
+		adminReviews.POST("/bulk/approve", auditReviews, reviewHandler.BulkApproveReviews)
+		adminReviews.POST("/bulk/reject", auditReviews, reviewHandler.BulkRejectReviews)
+		adminReviews.PUT("/assign", auditReviews, reviewHandler.AssignReviews)
+
+		// Reply moderation
+		adminReviews.GET("/replies", middlewares.ReadReplica(), reviewHandler.GetReviewReplies)
+		adminReviews.PUT("/replies/:id/moderate", middlewares.AuditTrail(db, "review_reply", func() interface{} { return &models.ReviewReply{} }), reviewHandler.ModerateReviewReply)
 
 		// Moderation statistics
-		adminReviews.GET("/stats", reviewHandler.GetModerationStats)
+		adminReviews.GET("/stats", middlewares.ReadReplica(), reviewHandler.GetModerationStats)
+
+		// Rating aggregates drifted from the approved reviews
+		adminReviews.GET("/ratings/drift", reviewHandler.GetRatingDrift)
+		adminReviews.POST("/ratings/reconcile", reviewHandler.ReconcileRatings)
 	}
 
 	// Seller dashboard routes
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/role"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RoleRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	roleHandler := role.NewRoleHandler(db)
+
+	adminRoles := router.Group("/admin/roles")
+	adminRoles.Use(middlewares.RequireScope(permissions.PermissionsAdmin))
+	{
+		adminRoles.GET("", roleHandler.GetRoles)
+		adminRoles.PUT("/:role/scopes", roleHandler.UpdateRoleScopes)
+	}
+}
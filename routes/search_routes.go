@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/search"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SearchRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	searchHandler := search.NewSearchHandler(db)
+
+	// Any admin scope can search; each result group is limited to the caller's scopes
+	router.GET("/admin/search", middlewares.RequireScope(permissions.AllScopes...), searchHandler.Search)
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/handlers/setting"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SettingsRoutes(router *gin.RouterGroup, db *gorm.DB, config *cfg.AppConfig) {
+	settingHandler := setting.NewSettingHandler(db).WithConfig(config)
+
+	router.GET("/settings", settingHandler.GetSettings)
+	router.GET("/maintenance", settingHandler.GetMaintenance)
+
+	adminSettings := router.Group("/admin/settings")
+	adminSettings.Use(middlewares.RequireScope(permissions.PermissionsAdmin))
+	{
+		adminSettings.GET("", settingHandler.GetSettings)
+		adminSettings.PUT("", middlewares.AuditTrail(db, "settings", nil), settingHandler.UpdateSettings)
+		adminSettings.GET("/maintenance", settingHandler.GetMaintenance)
+		adminSettings.PUT("/maintenance", middlewares.AuditTrail(db, "settings", nil), settingHandler.UpdateMaintenance)
+	}
+
+	// Redacted startup configuration, to diagnose deployments
+	router.GET("/admin/config", middlewares.RequireScope(permissions.PermissionsAdmin), settingHandler.GetConfig)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/sms"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	smsService "github.com/YasserCherfaoui/MarketProGo/sms"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SMSRoutes(router *gin.RouterGroup, db *gorm.DB, texts *smsService.Service) {
+	smsHandler := sms.NewSMSHandler(db, texts)
+
+	// Provider delivery reports, authenticated by signature
+	router.POST("/sms/webhooks/twilio", smsHandler.HandleTwilioStatus)
+
+	router.GET("/admin/sms/messages", middlewares.RequireScope(permissions.SMSRead), smsHandler.GetMessages)
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/subscription"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	subscriptionService "github.com/YasserCherfaoui/MarketProGo/subscription"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SubscriptionRoutes(router *gin.RouterGroup, db *gorm.DB, subscriptions *subscriptionService.Service) {
+	subscriptionHandler := subscription.NewSubscriptionHandler(db, subscriptions)
+
+	// Customer subscription routes
+	subscriptionRouter := router.Group("/subscriptions")
+	subscriptionRouter.Use(middlewares.AuthMiddleware())
+	{
+		subscriptionRouter.POST("", subscriptionHandler.CreateSubscription)
+		subscriptionRouter.GET("", subscriptionHandler.GetSubscriptions)
+		subscriptionRouter.GET("/:id", subscriptionHandler.GetSubscription)
+		subscriptionRouter.POST("/:id/skip", subscriptionHandler.SkipSubscription)
+		subscriptionRouter.POST("/:id/pause", subscriptionHandler.PauseSubscription)
+		subscriptionRouter.POST("/:id/resume", subscriptionHandler.ResumeSubscription)
+		subscriptionRouter.POST("/:id/cancel", subscriptionHandler.CancelSubscription)
+	}
+
+	// Admin subscription routes
+	router.GET("/admin/subscriptions", middlewares.RequireScope(permissions.OrdersRead), subscriptionHandler.GetAllSubscriptions)
+}
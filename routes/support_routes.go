@@ -6,13 +6,26 @@ import (
 	"github.com/YasserCherfaoui/MarketProGo/gcs"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/support"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/moderation"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/ratelimit"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SupportRoutes registers all support-related routes
-func SupportRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService, emailTriggerSvc *email.EmailTriggerService) {
-	supportHandler := support.NewSupportHandler(db, gcsService, appwriteService, emailTriggerSvc)
+func SupportRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCService, appwriteService *aw.AppwriteService, emailTriggerSvc *email.EmailTriggerService, inboundEmailSecret, previousInboundEmailSecret string, limiter *ratelimit.Limiter, moderations *moderation.Service) {
+	supportHandler := support.NewSupportHandler(db, gcsService, appwriteService, emailTriggerSvc).WithInboundEmailSecret(inboundEmailSecret, previousInboundEmailSecret).WithModeration(moderations)
+
+	// Staff changes to support records are written to the audit log
+	auditTickets := middlewares.AuditTrail(db, "support_ticket", func() interface{} { return &models.SupportTicket{} })
+	auditAbuse := middlewares.AuditTrail(db, "abuse_report", func() interface{} { return &models.AbuseReport{} })
+	auditContact := middlewares.AuditTrail(db, "contact_inquiry", func() interface{} { return &models.ContactInquiry{} })
+	auditDisputes := middlewares.AuditTrail(db, "dispute", func() interface{} { return &models.Dispute{} })
+
+	// Attachment uploads for tickets, disputes and abuse reports
+	router.POST("/support/attachments", middlewares.AuthMiddleware(), supportHandler.UploadAttachment)
 
 	// Support tickets routes
 	tickets := router.Group("/tickets", middlewares.AuthMiddleware())
@@ -20,15 +33,22 @@ func SupportRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCServi
 		tickets.POST("/", supportHandler.CreateTicket)
 		tickets.GET("/", supportHandler.GetUserTickets)
 		tickets.GET("/:id", supportHandler.GetTicket)
-		tickets.PUT("/:id", supportHandler.UpdateTicket)
-		tickets.DELETE("/:id", supportHandler.DeleteTicket)
-		tickets.POST("/:id/responses", supportHandler.AddTicketResponse)
+		tickets.PUT("/:id", auditTickets, supportHandler.UpdateTicket)
+		tickets.DELETE("/:id", auditTickets, supportHandler.DeleteTicket)
+		tickets.POST("/:id/responses", auditTickets, supportHandler.AddTicketResponse)
 	}
 
 	// Admin-only ticket routes
-	adminTickets := router.Group("/admin/tickets", middlewares.AuthMiddleware())
+	adminTickets := router.Group("/admin/tickets", middlewares.RequireScope(permissions.SupportRead))
 	{
 		adminTickets.GET("/", supportHandler.GetAllTickets)
+		adminTickets.GET("/export", supportHandler.ExportTickets)
+		adminTickets.GET("/:id/merges", supportHandler.GetTicketMerges)
+		adminTickets.POST("/:id/merge", middlewares.RequireScope(permissions.SupportAdmin), auditTickets, supportHandler.MergeTicket)
+		adminTickets.PUT("/:id/order", middlewares.RequireScope(permissions.SupportAdmin), auditTickets, supportHandler.LinkTicketOrder)
+		adminTickets.DELETE("/:id/order", middlewares.RequireScope(permissions.SupportAdmin), auditTickets, supportHandler.UnlinkTicketOrder)
+		adminTickets.PUT("/:id/dispute", middlewares.RequireScope(permissions.SupportAdmin), auditTickets, supportHandler.LinkTicketDispute)
+		adminTickets.DELETE("/:id/dispute", middlewares.RequireScope(permissions.SupportAdmin), auditTickets, supportHandler.UnlinkTicketDispute)
 	}
 
 	// Abuse reports routes
@@ -37,32 +57,37 @@ func SupportRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCServi
 		abuse.POST("/reports", supportHandler.CreateAbuseReport)
 		abuse.GET("/reports", supportHandler.GetUserAbuseReports)
 		abuse.GET("/reports/:id", supportHandler.GetAbuseReport)
-		abuse.PUT("/reports/:id", supportHandler.UpdateAbuseReport)
-		abuse.DELETE("/reports/:id", supportHandler.DeleteAbuseReport)
+		abuse.PUT("/reports/:id", auditAbuse, supportHandler.UpdateAbuseReport)
+		abuse.DELETE("/reports/:id", auditAbuse, supportHandler.DeleteAbuseReport)
 	}
 
 	// Admin-only abuse report routes
-	adminAbuse := router.Group("/admin/abuse", middlewares.AuthMiddleware())
+	adminAbuse := router.Group("/admin/abuse", middlewares.RequireScope(permissions.SupportRead))
 	{
 		adminAbuse.GET("/reports", supportHandler.GetAllAbuseReports)
+		adminAbuse.GET("/reports/export", supportHandler.ExportAbuseReports)
 	}
 	
-	router.POST("/contact/inquiries", supportHandler.CreateContactInquiry)
+	router.POST("/contact/inquiries", middlewares.RateLimit(limiter, ratelimit.ClassContact), supportHandler.CreateContactInquiry)
+
+	// Inbound support mailbox webhook, signed with EMAIL_INBOUND_SECRET
+	router.POST("/support/inbound-email", supportHandler.HandleInboundEmail)
 
 	// Contact inquiries routes
 	contact := router.Group("/contact", middlewares.AuthMiddleware())
 	{
 		contact.GET("/inquiries", supportHandler.GetUserContactInquiries)
 		contact.GET("/inquiries/:id", supportHandler.GetContactInquiry)
-		contact.PUT("/inquiries/:id", supportHandler.UpdateContactInquiry)
-		contact.DELETE("/inquiries/:id", supportHandler.DeleteContactInquiry)
+		contact.PUT("/inquiries/:id", auditContact, supportHandler.UpdateContactInquiry)
+		contact.DELETE("/inquiries/:id", auditContact, supportHandler.DeleteContactInquiry)
 	}
 
 	// Admin-only contact inquiry routes
-	adminContact := router.Group("/admin/contact", middlewares.AuthMiddleware())
+	adminContact := router.Group("/admin/contact", middlewares.RequireScope(permissions.SupportRead))
 	{
 		adminContact.GET("/inquiries", supportHandler.GetAllContactInquiries)
-		adminContact.POST("/inquiries/:id/reply", supportHandler.ReplyToContactInquiry)
+		adminContact.GET("/inquiries/export", supportHandler.ExportContactInquiries)
+		adminContact.POST("/inquiries/:id/reply", auditContact, supportHandler.ReplyToContactInquiry)
 	}
 
 	// Disputes routes
@@ -71,14 +96,37 @@ func SupportRoutes(router *gin.RouterGroup, db *gorm.DB, gcsService *gcs.GCServi
 		disputes.POST("/", supportHandler.CreateDispute)
 		disputes.GET("/", supportHandler.GetUserDisputes)
 		disputes.GET("/:id", supportHandler.GetDispute)
-		disputes.PUT("/:id", supportHandler.UpdateDispute)
-		disputes.DELETE("/:id", supportHandler.DeleteDispute)
-		disputes.POST("/:id/responses", supportHandler.AddDisputeResponse)
+		disputes.PUT("/:id", auditDisputes, supportHandler.UpdateDispute)
+		disputes.DELETE("/:id", auditDisputes, supportHandler.DeleteDispute)
+		disputes.POST("/:id/responses", auditDisputes, supportHandler.AddDisputeResponse)
 	}
 
 	// Admin-only dispute routes
-	adminDisputes := router.Group("/admin/disputes", middlewares.AuthMiddleware())
+	adminDisputes := router.Group("/admin/disputes", middlewares.RequireScope(permissions.SupportRead))
 	{
 		adminDisputes.GET("/", supportHandler.GetAllDisputes)
+		adminDisputes.GET("/export", supportHandler.ExportDisputes)
+	}
+
+	// Canned responses for support agents
+	cannedResponses := router.Group("/admin/canned-responses", middlewares.RequireScope(permissions.SupportRead))
+	{
+		cannedResponses.GET("/", supportHandler.GetCannedResponses)
+		cannedResponses.GET("/:id", supportHandler.GetCannedResponse)
+		cannedResponses.POST("/:id/preview", supportHandler.PreviewCannedResponse)
+		cannedResponses.POST("/", middlewares.RequireScope(permissions.SupportAdmin), supportHandler.CreateCannedResponse)
+		cannedResponses.PUT("/:id", middlewares.RequireScope(permissions.SupportAdmin), supportHandler.UpdateCannedResponse)
+		cannedResponses.DELETE("/:id", middlewares.RequireScope(permissions.SupportAdmin), supportHandler.DeleteCannedResponse)
+	}
+
+	// Admin-only SLA routes
+	adminSLA := router.Group("/admin/sla", middlewares.RequireScope(permissions.SupportRead))
+	{
+		adminSLA.GET("/policies", supportHandler.GetSLAPolicies)
+		adminSLA.POST("/policies", middlewares.RequireScope(permissions.SupportAdmin), supportHandler.CreateSLAPolicy)
+		adminSLA.PUT("/policies/:id", middlewares.RequireScope(permissions.SupportAdmin), supportHandler.UpdateSLAPolicy)
+		adminSLA.DELETE("/policies/:id", middlewares.RequireScope(permissions.SupportAdmin), supportHandler.DeleteSLAPolicy)
+		adminSLA.GET("/report", supportHandler.GetSLAReport)
+		adminSLA.GET("/breaches", supportHandler.GetSLABreaches)
 	}
 }
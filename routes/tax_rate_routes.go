@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/taxrate"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TaxRateRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	taxRateHandler := taxrate.NewTaxRateHandler(db)
+
+	adminTaxRates := router.Group("/admin/tax-rates")
+	adminTaxRates.Use(middlewares.AdminMiddleware())
+	{
+		adminTaxRates.GET("", taxRateHandler.GetTaxRates)
+		adminTaxRates.POST("", taxRateHandler.CreateTaxRate)
+		adminTaxRates.PUT("/:id", taxRateHandler.UpdateTaxRate)
+		adminTaxRates.DELETE("/:id", taxRateHandler.DeleteTaxRate)
+	}
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/translation"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TranslationRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	translationHandler := translation.NewTranslationHandler(db)
+
+	productTranslations := router.Group("/products/:id/translations")
+	productTranslations.Use(middlewares.RequireScope(permissions.ProductsWrite))
+	{
+		productTranslations.GET("", translationHandler.GetProductTranslations)
+		productTranslations.PUT("/:locale", translationHandler.SetProductTranslation)
+		productTranslations.DELETE("/:locale", translationHandler.DeleteProductTranslation)
+	}
+
+	categoryTranslations := router.Group("/categories/:id/translations")
+	categoryTranslations.Use(middlewares.RequireScope(permissions.ProductsWrite))
+	{
+		categoryTranslations.GET("", translationHandler.GetCategoryTranslations)
+		categoryTranslations.PUT("/:locale", translationHandler.SetCategoryTranslation)
+		categoryTranslations.DELETE("/:locale", translationHandler.DeleteCategoryTranslation)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/handlers/trash"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashRoutes lets admins restore deleted products and support records. Each
+// kind also needs its own scope, checked by the handler.
+func TrashRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	trashHandler := trash.NewTrashHandler(db)
+
+	adminTrash := router.Group("/admin/trash")
+	adminTrash.Use(middlewares.RequireScope(permissions.ProductsWrite, permissions.SupportAdmin))
+	{
+		adminTrash.GET("/:kind", trashHandler.ListDeleted)
+		adminTrash.POST("/:kind/:id/restore", middlewares.AuditTrail(db, "trash", nil), trashHandler.Restore)
+	}
+}
@@ -1,15 +1,16 @@
 package routes
 
 import (
+	"github.com/YasserCherfaoui/MarketProGo/address"
 	"github.com/YasserCherfaoui/MarketProGo/handlers/user"
 	"github.com/YasserCherfaoui/MarketProGo/middlewares"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func UserRoutes(router *gin.RouterGroup, db *gorm.DB) {
+func UserRoutes(router *gin.RouterGroup, db *gorm.DB, geocoder address.Geocoder) {
 	userRouter := router.Group("/users")
-	userHandler := user.NewUserHandler(db)
+	userHandler := user.NewUserHandler(db, geocoder)
 
 	// Public routes
 	userRouter.POST("/seller", userHandler.CreateSeller)
@@ -27,6 +28,7 @@ func UserRoutes(router *gin.RouterGroup, db *gorm.DB) {
 		{
 			addressRouter.POST("", userHandler.CreateAddress)
 			addressRouter.GET("", userHandler.GetAddresses)
+			addressRouter.GET("/defaults", userHandler.GetDefaultAddresses)
 			addressRouter.GET("/:id", userHandler.GetAddress)
 			addressRouter.PUT("/:id", userHandler.UpdateAddress)
 			addressRouter.DELETE("/:id", userHandler.DeleteAddress)
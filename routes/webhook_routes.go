@@ -0,0 +1,30 @@
+package routes
+
+import (
+	webhookHandler "github.com/YasserCherfaoui/MarketProGo/handlers/webhook"
+	"github.com/YasserCherfaoui/MarketProGo/middlewares"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func WebhookRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	handler := webhookHandler.NewWebhookHandler(db, webhook.NewService(db, nil))
+
+	canRead := middlewares.RequireScope(permissions.WebhooksRead)
+	canWrite := middlewares.RequireScope(permissions.WebhooksWrite)
+
+	adminWebhooks := router.Group("/admin/webhooks")
+	{
+		adminWebhooks.GET("/events", canRead, handler.GetEvents)
+		adminWebhooks.GET("", canRead, handler.GetSubscriptions)
+		adminWebhooks.POST("", canWrite, handler.CreateSubscription)
+		adminWebhooks.GET("/:id", canRead, handler.GetSubscription)
+		adminWebhooks.PUT("/:id", canWrite, handler.UpdateSubscription)
+		adminWebhooks.DELETE("/:id", canWrite, handler.DeleteSubscription)
+		adminWebhooks.POST("/:id/rotate-secret", canWrite, handler.RotateSecret)
+		adminWebhooks.POST("/:id/test", canWrite, handler.TestSubscription)
+		adminWebhooks.GET("/:id/deliveries", canRead, handler.GetDeliveries)
+	}
+}
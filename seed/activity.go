@@ -0,0 +1,323 @@
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+)
+
+const (
+	vatRate           = 20.0
+	freeShippingOver  = 50.0
+	standardShipping  = 4.99
+	orderHistoryDays  = 90
+	reviewProbability = 2 // one in reviewProbability delivered items is reviewed
+)
+
+// orderStates are the order and payment states seeded, with their weights
+var orderStates = []struct {
+	status  models.OrderStatus
+	payment models.PaymentStatus
+	weight  int
+}{
+	{models.OrderStatusDelivered, models.PaymentStatusPaid, 40},
+	{models.OrderStatusShipped, models.PaymentStatusPaid, 15},
+	{models.OrderStatusProcessing, models.PaymentStatusPaid, 15},
+	{models.OrderStatusPending, models.PaymentStatusPending, 12},
+	{models.OrderStatusPending, models.PaymentStatusFailed, 3},
+	{models.OrderStatusCancelled, models.PaymentStatusRefunded, 10},
+	{models.OrderStatusReturned, models.PaymentStatusRefunded, 5},
+}
+
+// reviewed is a user and variant pair, which can only be reviewed once
+type reviewed struct {
+	userID, variantID uint
+}
+
+// seedOrders creates orders in every state. Wholesalers buy in bulk at B2B
+// prices. Delivered orders are reviewed now and then.
+func (s *seeder) seedOrders() error {
+	weights := make([]int, len(orderStates))
+	for i, state := range orderStates {
+		weights[i] = state.weight
+	}
+	done := make(map[reviewed]bool)
+	ratedVariants := make(map[uint]bool)
+
+	for i := 0; i < s.opts.Orders; i++ {
+		buyer := s.buyers[s.rng.Intn(len(s.buyers))]
+		state := orderStates[s.pick(weights)]
+		orderDate := s.pastTime(orderHistoryDays)
+		wholesale := buyer.UserType == models.Wholesaler
+
+		order := models.Order{
+			OrderNumber:       fmt.Sprintf("ORD-SEED-%05d", i+1),
+			UserID:            buyer.ID,
+			CompanyID:         buyer.CompanyID,
+			Status:            state.status,
+			PaymentStatus:     state.payment,
+			TaxCountry:        "GB",
+			ShippingAddressID: buyer.Addresses[0].ID,
+			ShippingMethod:    "standard",
+			PaymentMethod:     "card",
+			PaymentProvider:   "revolut",
+			OrderDate:         orderDate,
+		}
+		order.CreatedAt = orderDate
+		if wholesale {
+			order.ShippingMethod = "freight"
+			order.PaymentMethod = "invoice"
+		}
+
+		var taxLines []tax.Line
+		lines := 1 + s.rng.Intn(4)
+		for l := 0; l < lines; l++ {
+			variant := s.variants[s.rng.Intn(len(s.variants))]
+			quantity := 1 + s.rng.Intn(4)
+			price := variant.BasePrice
+			if wholesale {
+				quantity = 10 + s.rng.Intn(60)
+				price = variant.B2BPrice
+				for _, tier := range variant.PriceTiers {
+					if quantity >= tier.MinQuantity && tier.Price < price {
+						price = tier.Price
+					}
+				}
+			}
+			total := round2(price * float64(quantity))
+			item := models.OrderItem{
+				ProductVariantID: variant.ID,
+				Quantity:         quantity,
+				UnitPrice:        price,
+				TotalAmount:      total,
+				Status:           "active",
+			}
+			if len(variant.InventoryItems) > 0 {
+				item.InventoryItemID = &variant.InventoryItems[0].ID
+			}
+			taxLines = append(taxLines, tax.Line{ProductVariantID: variant.ID, IsVAT: s.vatProducts[variant.ProductID], Amount: total})
+			switch order.Status {
+			case models.OrderStatusCancelled:
+				item.Status = "cancelled"
+			case models.OrderStatusReturned:
+				item.Status = "returned"
+			}
+			order.Items = append(order.Items, item)
+			order.TotalAmount += total
+		}
+
+		breakdown := tax.Compute(taxLines, "GB", vatRate, false)
+		for l, line := range breakdown.Lines {
+			order.Items[l].TaxRate = line.Rate
+			order.Items[l].TaxAmount = line.TaxAmount
+		}
+		order.TotalAmount = round2(order.TotalAmount)
+		order.TaxAmount = breakdown.TaxAmount
+		order.TaxBreakdown = breakdown.ToJSON()
+		if order.TotalAmount < freeShippingOver {
+			order.ShippingAmount = standardShipping
+		}
+		order.FinalAmount = round2(order.TotalAmount + order.TaxAmount + order.ShippingAmount)
+		s.setOrderDates(&order)
+
+		if err := s.db.Omit("User", "Company", "ShippingAddress").Create(&order).Error; err != nil {
+			return err
+		}
+		s.summary.Orders++
+
+		if order.Status != models.OrderStatusDelivered {
+			continue
+		}
+		for _, item := range order.Items {
+			key := reviewed{buyer.ID, item.ProductVariantID}
+			if done[key] || s.rng.Intn(reviewProbability) != 0 {
+				continue
+			}
+			done[key] = true
+			if err := s.seedReview(&order, &item); err != nil {
+				return err
+			}
+			ratedVariants[item.ProductVariantID] = true
+		}
+	}
+
+	for _, variant := range s.variants {
+		if ratedVariants[variant.ID] {
+			if err := s.updateRating(variant.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setOrderDates fills in payment, shipping and delivery dates that follow
+// the order date and match its state
+func (s *seeder) setOrderDates(order *models.Order) {
+	if order.PaymentStatus == models.PaymentStatusPaid || order.PaymentStatus == models.PaymentStatusRefunded {
+		paid := order.OrderDate.Add(time.Duration(5+s.rng.Intn(55)) * time.Minute)
+		order.PaymentDate = &paid
+		order.PaymentReference = fmt.Sprintf("PAY-%s", order.OrderNumber)
+	}
+	switch order.Status {
+	case models.OrderStatusShipped, models.OrderStatusDelivered, models.OrderStatusReturned:
+		shipped := s.notAfterNow(order.OrderDate.Add(time.Duration(12+s.rng.Intn(48)) * time.Hour))
+		order.ShippedDate = &shipped
+		order.TrackingNumber = fmt.Sprintf("TRK%09d", s.rng.Intn(1000000000))
+		if order.Status != models.OrderStatusShipped {
+			delivered := s.notAfterNow(shipped.Add(time.Duration(24+s.rng.Intn(72)) * time.Hour))
+			order.DeliveredDate = &delivered
+		}
+	}
+}
+
+// seedReview reviews item after its order was delivered. Most reviews are
+// approved and lean positive, and some get a response from a vendor.
+func (s *seeder) seedReview(order *models.Order, item *models.OrderItem) error {
+	rating := []int{1, 2, 3, 4, 5}[s.pick([]int{4, 6, 15, 35, 40})]
+	status := []models.ReviewStatus{
+		models.ReviewStatusApproved, models.ReviewStatusPending, models.ReviewStatusRejected, models.ReviewStatusFlagged,
+	}[s.pick([]int{80, 10, 5, 5})]
+	text := reviewTexts[rating-1][s.rng.Intn(len(reviewTexts[rating-1]))]
+
+	review := models.ProductReview{
+		ProductVariantID:   item.ProductVariantID,
+		UserID:             order.UserID,
+		OrderItemID:        &item.ID,
+		Rating:             rating,
+		Title:              text[0],
+		Content:            text[1],
+		IsVerifiedPurchase: true,
+		Status:             status,
+	}
+	review.CreatedAt = s.notAfterNow(order.DeliveredDate.Add(time.Duration(1+s.rng.Intn(10)) * 24 * time.Hour))
+	if status != models.ReviewStatusPending {
+		moderated := s.notAfterNow(review.CreatedAt.Add(6 * time.Hour))
+		review.ModeratedBy = &s.admin.ID
+		review.ModeratedAt = &moderated
+		if status == models.ReviewStatusRejected {
+			review.ModerationReason = "Does not describe the product"
+		}
+	}
+	if err := s.db.Create(&review).Error; err != nil {
+		return err
+	}
+	s.summary.Reviews++
+
+	if status == models.ReviewStatusApproved && s.rng.Intn(4) == 0 {
+		response := models.SellerResponse{
+			ProductReviewID: review.ID,
+			UserID:          s.vendors[s.rng.Intn(len(s.vendors))].ID,
+			Content:         "Thank you for your feedback, we have shared it with our team.",
+		}
+		return s.db.Create(&response).Error
+	}
+	return nil
+}
+
+// updateRating stores the rating aggregate of a variant from its approved reviews
+func (s *seeder) updateRating(variantID uint) error {
+	var ratings []int
+	if err := s.db.Model(&models.ProductReview{}).
+		Where("product_variant_id = ? AND status = ?", variantID, models.ReviewStatusApproved).
+		Pluck("rating", &ratings).Error; err != nil {
+		return err
+	}
+	breakdown := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+	sum := 0
+	for _, rating := range ratings {
+		breakdown[rating]++
+		sum += rating
+	}
+	average := 0.0
+	if len(ratings) > 0 {
+		average = float64(sum) / float64(len(ratings))
+	}
+	encoded, err := json.Marshal(breakdown)
+	if err != nil {
+		return err
+	}
+	return s.db.Create(&models.ProductRating{
+		ProductVariantID: variantID,
+		AverageRating:    float64(int(average*10+0.5)) / 10,
+		TotalReviews:     len(ratings),
+		RatingBreakdown:  string(encoded),
+	}).Error
+}
+
+// seedTickets opens support tickets in every status, half of them about an
+// order of the customer, with a short conversation with the support agent
+func (s *seeder) seedTickets() error {
+	var orders []models.Order
+	if err := s.db.Select("id", "user_id", "order_number").Find(&orders).Error; err != nil {
+		return err
+	}
+	ordersByUser := make(map[uint][]models.Order)
+	for _, order := range orders {
+		ordersByUser[order.UserID] = append(ordersByUser[order.UserID], order)
+	}
+
+	statuses := []models.TicketStatus{
+		models.TicketStatusOpen, models.TicketStatusInProgress, models.TicketStatusWaiting, models.TicketStatusResolved, models.TicketStatusClosed,
+	}
+	priorities := []models.TicketPriority{
+		models.TicketPriorityLow, models.TicketPriorityMedium, models.TicketPriorityHigh, models.TicketPriorityUrgent,
+	}
+
+	for i := 0; i < s.opts.Tickets; i++ {
+		buyer := s.buyers[s.rng.Intn(len(s.buyers))]
+		topic := ticketTopics[s.rng.Intn(len(ticketTopics))]
+		created := s.pastTime(30)
+
+		ticket := models.SupportTicket{
+			UserID:      buyer.ID,
+			Title:       topic.title,
+			Description: topic.description,
+			Category:    topic.category,
+			Priority:    priorities[s.pick([]int{25, 45, 20, 10})],
+			Status:      statuses[s.pick([]int{30, 25, 15, 20, 10})],
+		}
+		ticket.CreatedAt = created
+		if topic.aboutOrder && len(ordersByUser[buyer.ID]) > 0 {
+			order := ordersByUser[buyer.ID][s.rng.Intn(len(ordersByUser[buyer.ID]))]
+			ticket.OrderID = &order.ID
+			ticket.Description = fmt.Sprintf("Order %s: %s", order.OrderNumber, topic.description)
+		}
+		if ticket.Status != models.TicketStatusOpen {
+			ticket.AssignedTo = &s.agent.ID
+			responded := s.notAfterNow(created.Add(time.Duration(1+s.rng.Intn(8)) * time.Hour))
+			ticket.FirstRespondedAt = &responded
+		}
+		if ticket.Status == models.TicketStatusResolved || ticket.Status == models.TicketStatusClosed {
+			resolved := s.notAfterNow(created.Add(time.Duration(1+s.rng.Intn(4)) * 24 * time.Hour))
+			ticket.ResolvedAt = &resolved
+			ticket.ResolvedBy = &s.agent.ID
+			ticket.Resolution = "Resolved with the customer."
+		}
+		if err := s.db.Create(&ticket).Error; err != nil {
+			return err
+		}
+		s.summary.Tickets++
+
+		if ticket.AssignedTo == nil {
+			continue
+		}
+		responses := []models.TicketResponse{
+			{TicketID: ticket.ID, UserID: s.agent.ID, Message: "Thanks for getting in touch, we are looking into this for you.", IsFromAdmin: true},
+			{TicketID: ticket.ID, UserID: s.agent.ID, Message: "Checked with the warehouse team.", IsFromAdmin: true, IsInternal: true},
+		}
+		if ticket.Status != models.TicketStatusInProgress {
+			responses = append(responses, models.TicketResponse{TicketID: ticket.ID, UserID: buyer.ID, Message: "Thank you, that helps."})
+		}
+		for r := range responses {
+			responses[r].CreatedAt = ticket.FirstRespondedAt.Add(time.Duration(r) * time.Hour)
+		}
+		if err := s.db.Create(&responses).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
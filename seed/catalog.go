@@ -0,0 +1,263 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// categoryTree lists the top level categories and their children
+var categoryTree = []struct {
+	name     string
+	children []string
+}{
+	{"Pantry", []string{"Rice & Grains", "Pasta", "Oils & Vinegar", "Spices"}},
+	{"Beverages", []string{"Juices", "Tea & Coffee", "Water"}},
+	{"Snacks", []string{"Biscuits", "Nuts & Dried Fruit"}},
+	{"Dairy", []string{"Cheese", "Yoghurt"}},
+	{"Household", []string{"Cleaning", "Paper Goods"}},
+}
+
+var brandNames = []string{"Atlas Foods", "Sahara Gold", "Blue Coast", "Green Valley", "Maison Dor", "Kasbah Kitchen"}
+
+// productTemplates describe the kinds of products seeded. Variant prices are
+// price times the factor of their option value.
+var productTemplates = []struct {
+	name     string
+	category string
+	option   string
+	values   []string
+	factors  []float64
+	price    float64
+	vat      bool
+	perishes bool
+}{
+	{"Basmati Rice", "Rice & Grains", "Size", []string{"1kg", "5kg", "10kg"}, []float64{1, 4.5, 8.5}, 2.49, false, false},
+	{"Couscous", "Rice & Grains", "Size", []string{"500g", "1kg"}, []float64{1, 1.8}, 1.29, false, false},
+	{"Durum Spaghetti", "Pasta", "Size", []string{"500g", "1kg", "5kg"}, []float64{1, 1.8, 8}, 0.99, false, false},
+	{"Penne Rigate", "Pasta", "Size", []string{"500g", "3kg"}, []float64{1, 5.5}, 0.95, false, false},
+	{"Extra Virgin Olive Oil", "Oils & Vinegar", "Size", []string{"500ml", "1L", "5L"}, []float64{1, 1.8, 8}, 5.49, false, false},
+	{"Sunflower Oil", "Oils & Vinegar", "Size", []string{"1L", "5L"}, []float64{1, 4.6}, 2.19, false, false},
+	{"Ras el Hanout", "Spices", "Size", []string{"50g", "250g"}, []float64{1, 4.2}, 2.99, false, false},
+	{"Ground Cumin", "Spices", "Size", []string{"100g", "500g"}, []float64{1, 4.4}, 1.79, false, false},
+	{"Orange Juice", "Juices", "Size", []string{"1L", "6 x 1L"}, []float64{1, 5.5}, 1.89, false, true},
+	{"Mango Nectar", "Juices", "Size", []string{"1L", "12 x 330ml"}, []float64{1, 4}, 1.59, false, true},
+	{"Mint Green Tea", "Tea & Coffee", "Pack", []string{"25 bags", "100 bags"}, []float64{1, 3.6}, 2.29, false, false},
+	{"Arabica Coffee Beans", "Tea & Coffee", "Size", []string{"250g", "1kg"}, []float64{1, 3.7}, 4.99, false, false},
+	{"Sparkling Water", "Water", "Pack", []string{"6 x 500ml", "24 x 500ml"}, []float64{1, 3.8}, 2.49, true, false},
+	{"Date Biscuits", "Biscuits", "Pack", []string{"200g", "12 x 200g"}, []float64{1, 11}, 1.49, true, true},
+	{"Almond Shortbread", "Biscuits", "Pack", []string{"150g", "10 x 150g"}, []float64{1, 9.2}, 1.99, true, true},
+	{"Medjool Dates", "Nuts & Dried Fruit", "Size", []string{"500g", "1kg", "5kg"}, []float64{1, 1.9, 9}, 6.99, false, true},
+	{"Roasted Almonds", "Nuts & Dried Fruit", "Size", []string{"200g", "1kg"}, []float64{1, 4.5}, 2.99, true, true},
+	{"Halloumi", "Cheese", "Size", []string{"250g", "1kg"}, []float64{1, 3.7}, 2.79, false, true},
+	{"Greek Yoghurt", "Yoghurt", "Size", []string{"500g", "1kg"}, []float64{1, 1.8}, 1.69, false, true},
+	{"Multi-Surface Cleaner", "Cleaning", "Size", []string{"750ml", "5L"}, []float64{1, 5}, 1.99, true, false},
+	{"Kitchen Towels", "Paper Goods", "Pack", []string{"2 rolls", "12 rolls"}, []float64{1, 5.2}, 2.49, true, false},
+}
+
+// seedCategories creates the category tree
+func (s *seeder) seedCategories() error {
+	for _, parent := range categoryTree {
+		category := models.Category{Name: parent.name, Slug: slugify(parent.name), Description: parent.name + " products", IsFeatureOne: true}
+		if err := s.db.Create(&category).Error; err != nil {
+			return err
+		}
+		if err := catalog.PlaceCategory(s.db, &category); err != nil {
+			return err
+		}
+		s.categories[parent.name] = &category
+		for _, name := range parent.children {
+			child := models.Category{Name: name, Slug: slugify(name), Description: name, ParentID: &category.ID}
+			if err := s.db.Create(&child).Error; err != nil {
+				return err
+			}
+			if err := catalog.PlaceCategory(s.db, &child); err != nil {
+				return err
+			}
+			s.categories[name] = &child
+		}
+	}
+	s.summary.Categories = len(s.categories)
+	return nil
+}
+
+func (s *seeder) seedBrands() error {
+	for _, name := range brandNames {
+		brand := models.Brand{Name: name, Slug: slugify(name), Image: "https://picsum.photos/seed/" + slugify(name) + "/300/300", IsDisplayed: true}
+		if err := s.db.Create(&brand).Error; err != nil {
+			return err
+		}
+		s.brands = append(s.brands, brand)
+	}
+	s.summary.Brands = len(s.brands)
+	return nil
+}
+
+func (s *seeder) seedWarehouses() error {
+	for i, city := range []string{"London", "Manchester", "Birmingham"} {
+		address := s.address(nil)
+		address.City = city
+		if err := s.db.Create(&address).Error; err != nil {
+			return err
+		}
+		warehouse := models.Warehouse{
+			Name:      city + " Warehouse",
+			Code:      fmt.Sprintf("WH-%s-%d", strings.ToUpper(city[:3]), i+1),
+			AddressID: address.ID,
+			IsActive:  true,
+		}
+		if err := s.db.Create(&warehouse).Error; err != nil {
+			return err
+		}
+		s.warehouses = append(s.warehouses, warehouse)
+	}
+	s.summary.Warehouses = len(s.warehouses)
+	return nil
+}
+
+// seedProducts creates products from the templates, cycling through them and
+// the brands, with an option, a variant per option value, B2B price tiers and
+// stock in one or more warehouses
+func (s *seeder) seedProducts() error {
+	for i := 0; i < s.opts.Products; i++ {
+		template := productTemplates[i%len(productTemplates)]
+		brand := s.brands[(i/len(productTemplates)+i)%len(s.brands)]
+		category := s.categories[template.category]
+		parent := s.categories[parentOf(template.category)]
+
+		product := models.Product{
+			Name:        brand.Name + " " + template.name,
+			Description: fmt.Sprintf("%s from %s, sold by the unit and in wholesale packs.", template.name, brand.Name),
+			IsActive:    s.rng.Intn(20) != 0,
+			IsFeatured:  s.rng.Intn(5) == 0,
+			IsVAT:       template.vat,
+			BrandID:     &brand.ID,
+			Categories:  []*models.Category{category, parent},
+		}
+		if err := s.db.Create(&product).Error; err != nil {
+			return err
+		}
+		s.vatProducts[product.ID] = template.vat
+
+		image := models.ProductImage{
+			ProductID: &product.ID,
+			URL:       fmt.Sprintf("https://picsum.photos/seed/product-%d/600/600", i+1),
+			IsPrimary: true,
+			AltText:   product.Name,
+		}
+		if err := s.db.Create(&image).Error; err != nil {
+			return err
+		}
+
+		option := models.ProductOption{ProductID: product.ID, Name: template.option}
+		for _, value := range template.values {
+			option.Values = append(option.Values, models.ProductOptionValue{Value: value})
+		}
+		if err := s.db.Create(&option).Error; err != nil {
+			return err
+		}
+
+		// Prices drift a little between brands
+		price := template.price * (0.9 + 0.2*s.rng.Float64())
+		for j := range option.Values {
+			base := round2(price * template.factors[j])
+			variant := models.ProductVariant{
+				ProductID:    product.ID,
+				Name:         option.Values[j].Value,
+				SKU:          fmt.Sprintf("SEED-%04d-%d", i+1, j+1),
+				Barcode:      fmt.Sprintf("50%011d", s.rng.Int63n(100000000000)),
+				BasePrice:    base,
+				B2BPrice:     round2(base * 0.85),
+				CostPrice:    round2(base * 0.6),
+				IsActive:     true,
+				MinQuantity:  1,
+				OptionValues: []*models.ProductOptionValue{&option.Values[j]},
+				PriceTiers: []models.ProductVariantPriceTier{
+					{MinQuantity: 10, Price: round2(base * 0.8)},
+					{MinQuantity: 50, Price: round2(base * 0.75)},
+				},
+			}
+			if err := s.db.Omit("Product").Create(&variant).Error; err != nil {
+				return err
+			}
+			if err := s.seedStock(&variant, template.perishes); err != nil {
+				return err
+			}
+			s.variants = append(s.variants, variant)
+		}
+		s.summary.Products++
+	}
+	s.summary.Variants = len(s.variants)
+	return nil
+}
+
+// seedStock stocks variant in one to all warehouses. Some items are low on
+// stock, and perishable items get batches with expiry dates.
+func (s *seeder) seedStock(variant *models.ProductVariant, perishes bool) error {
+	warehouses := 1 + s.rng.Intn(len(s.warehouses))
+	total := 0
+	for w := 0; w < warehouses; w++ {
+		quantity := 20 + s.rng.Intn(400)
+		if s.rng.Intn(8) == 0 {
+			quantity = s.rng.Intn(10)
+		}
+		item := models.InventoryItem{
+			ProductVariantID: variant.ID,
+			WarehouseID:      s.warehouses[w].ID,
+			Quantity:         quantity,
+			Status:           "active",
+		}
+		if perishes {
+			expiry := s.opts.Now.AddDate(0, 0, 7+s.rng.Intn(180)).Truncate(24 * time.Hour)
+			item.ExpiryDate = &expiry
+			item.BatchNumber = fmt.Sprintf("B%s-%03d", expiry.Format("0601"), s.rng.Intn(1000))
+		}
+		if err := s.db.Create(&item).Error; err != nil {
+			return err
+		}
+		movement := models.StockMovement{
+			InventoryItemID: item.ID,
+			MovementType:    "adjustment_in",
+			Quantity:        quantity,
+			Reason:          "Opening stock",
+			UserID:          &s.admin.ID,
+		}
+		if err := s.db.Create(&movement).Error; err != nil {
+			return err
+		}
+		variant.InventoryItems = append(variant.InventoryItems, item)
+		total += quantity
+		s.summary.InventoryItems++
+	}
+	variant.QuantityInStock = total
+	return s.db.Model(variant).Update("quantity_in_stock", total).Error
+}
+
+func parentOf(category string) string {
+	for _, parent := range categoryTree {
+		for _, child := range parent.children {
+			if child == category {
+				return parent.name
+			}
+		}
+	}
+	return category
+}
+
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,65 @@
+package seed
+
+import "github.com/YasserCherfaoui/MarketProGo/models"
+
+var firstNames = []string{
+	"Yasmine", "Omar", "Sarah", "James", "Leila", "Karim", "Emily", "Mohamed", "Sophie", "Adam",
+	"Nadia", "Daniel", "Ines", "Thomas", "Rania", "Oliver", "Hana", "Youssef", "Chloe", "Bilal",
+}
+
+var lastNames = []string{
+	"Smith", "Khan", "Benali", "Jones", "Haddad", "Taylor", "Mansouri", "Brown", "Cherif", "Wilson",
+	"Amrani", "Davies", "Bouzid", "Evans", "Hamidi", "Walker",
+}
+
+var companyNames = []string{"Northside Grocers Ltd", "Crescent Catering Supplies", "Riverside Corner Shops"}
+
+var streets = []string{"High Street", "Station Road", "Church Lane", "Victoria Road", "Park Avenue", "Mill Lane", "King Street", "Queens Road"}
+
+var cities = []struct {
+	name, postcode string
+}{
+	{"London", "E1"}, {"Manchester", "M4"}, {"Birmingham", "B5"}, {"Leeds", "LS1"}, {"Glasgow", "G2"}, {"Bristol", "BS1"},
+}
+
+// reviewTexts holds a title and content for each rating, one star first
+var reviewTexts = [5][][2]string{
+	{
+		{"Disappointed", "Arrived damaged and the quality was not what I expected."},
+		{"Would not buy again", "Taste was off and the packaging had been opened."},
+	},
+	{
+		{"Not great", "Smaller than it looked and a bit overpriced."},
+		{"Below average", "Did the job but I have had better from other brands."},
+	},
+	{
+		{"Okay", "Decent for the price, nothing special."},
+		{"Average", "Fine for everyday use. Delivery was a day late."},
+	},
+	{
+		{"Good value", "Good quality and well packed. Will order again."},
+		{"Very good", "Tastes great, the bulk pack lasts a long time."},
+	},
+	{
+		{"Excellent", "Best I have found, my customers love it."},
+		{"Perfect", "Fresh, well priced and delivered quickly."},
+	},
+}
+
+// ticketTopics are the support requests tickets are opened with
+var ticketTopics = []struct {
+	title       string
+	description string
+	category    models.TicketCategory
+	aboutOrder  bool
+}{
+	{"Where is my order?", "The tracking has not updated for three days.", models.TicketCategoryShipping, true},
+	{"Missing item", "One of the items was not in the parcel.", models.TicketCategoryOrder, true},
+	{"Damaged on arrival", "Two bottles were broken when the box arrived.", models.TicketCategoryReturn, true},
+	{"Charged twice", "My card was charged twice for the same order.", models.TicketCategoryPayment, true},
+	{"Invoice needed", "Please send a VAT invoice for our accounts.", models.TicketCategoryBilling, true},
+	{"Cannot log in", "The password reset email never arrives.", models.TicketCategoryAccount, false},
+	{"Allergen information", "Does this product contain nuts or traces of nuts?", models.TicketCategoryProduct, false},
+	{"Wholesale account", "How do we apply for trade prices?", models.TicketCategoryGeneral, false},
+	{"Checkout error", "The payment page shows an error after entering my card.", models.TicketCategoryTechnical, false},
+}
@@ -0,0 +1,386 @@
+// Package seed fills a database with realistic fixtures for development and
+// staging: users of every role, a category tree, brands, products with
+// options, variants and price tiers, stock across warehouses, orders in every
+// state, reviews and support tickets. The same seed produces the same data.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/catalog"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/password"
+	"gorm.io/gorm"
+)
+
+// EmailDomain is the domain of every seeded user's email address
+const EmailDomain = "seed.marketpro.test"
+
+// DefaultPassword is the password of every seeded user unless Options.Password is set
+const DefaultPassword = "Password123!"
+
+// Options controls what is seeded
+type Options struct {
+	Seed      int64     // the same seed produces the same data
+	Wipe      bool      // empty the seeded tables first
+	Customers int       // default 12
+	Products  int       // default 40
+	Orders    int       // default 80
+	Tickets   int       // default 15
+	Password  string    // password of every user, DefaultPassword if empty
+	Now       time.Time // dates are spread over the 90 days before Now, default time.Now()
+}
+
+func (o *Options) setDefaults() {
+	if o.Customers <= 0 {
+		o.Customers = 12
+	}
+	if o.Products <= 0 {
+		o.Products = 40
+	}
+	if o.Orders <= 0 {
+		o.Orders = 80
+	}
+	if o.Tickets <= 0 {
+		o.Tickets = 15
+	}
+	if o.Password == "" {
+		o.Password = DefaultPassword
+	}
+	if o.Now.IsZero() {
+		o.Now = time.Now()
+	}
+}
+
+// Summary counts the seeded records
+type Summary struct {
+	Users          int
+	Companies      int
+	Categories     int
+	Brands         int
+	Products       int
+	Variants       int
+	Warehouses     int
+	InventoryItems int
+	Orders         int
+	Reviews        int
+	Tickets        int
+}
+
+// seeder holds the state shared by the seeding steps
+type seeder struct {
+	db      *gorm.DB
+	rng     *rand.Rand
+	opts    Options
+	summary Summary
+
+	admin       models.User
+	agent       models.User
+	vendors     []models.User
+	buyers      []models.User // customers and wholesalers
+	categories  map[string]*models.Category
+	brands      []models.Brand
+	variants    []models.ProductVariant
+	warehouses  []models.Warehouse
+	vatProducts map[uint]bool
+}
+
+// Run seeds db in one transaction. Unless opts.Wipe is set it fails when the
+// database has already been seeded.
+func Run(ctx context.Context, db *gorm.DB, opts Options) (*Summary, error) {
+	opts.setDefaults()
+	db = db.WithContext(ctx)
+
+	if opts.Wipe {
+		if err := Wipe(db); err != nil {
+			return nil, err
+		}
+	} else {
+		var count int64
+		if err := db.Model(&models.User{}).Where("email LIKE ?", "%@"+EmailDomain).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("database has already been seeded, run with wipe to reseed")
+		}
+	}
+
+	s := &seeder{
+		rng:         rand.New(rand.NewSource(opts.Seed)),
+		opts:        opts,
+		categories:  make(map[string]*models.Category),
+		vatProducts: make(map[uint]bool),
+	}
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"users", s.seedUsers},
+		{"categories", s.seedCategories},
+		{"brands", s.seedBrands},
+		{"warehouses", s.seedWarehouses},
+		{"products", s.seedProducts},
+		{"orders", s.seedOrders},
+		{"tickets", s.seedTickets},
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		s.db = tx
+		for _, step := range steps {
+			if err := step.fn(); err != nil {
+				return fmt.Errorf("failed to seed %s: %w", step.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := catalog.NewSummaries(db).RefreshAll(ctx); err != nil {
+		return nil, fmt.Errorf("failed to refresh product summaries: %w", err)
+	}
+	return &s.summary, nil
+}
+
+// wipedTables are emptied by Wipe, children before parents
+var wipedTables = []string{
+	"user_restrictions",
+	"user_strikes",
+	"enforcement_actions",
+	"fraud_checks",
+	"order_notes",
+	"order_status_changes",
+	"order_message_attachments",
+	"order_messages",
+	"collection_products",
+	"collections",
+	"banners",
+	"gift_card_transactions",
+	"gift_cards",
+	"coupons",
+	"referrals",
+	"loyalty_transactions",
+	"campaign_recipients",
+	"campaigns",
+	"segments",
+	"ticket_responses",
+	"support_tickets",
+	"review_replies",
+	"seller_responses",
+	"review_helpful_votes",
+	"review_images",
+	"product_reviews",
+	"product_ratings",
+	"stock_allocations",
+	"shipments",
+	"pick_lists",
+	"checkouts",
+	"subscription_items",
+	"order_items",
+	"orders",
+	"subscriptions",
+	"delivery_slots",
+	"delivery_zone_warehouses",
+	"goods_receipt_items",
+	"goods_receipts",
+	"po_items",
+	"purchase_orders",
+	"supplier_contacts",
+	"suppliers",
+	"stock_count_lines",
+	"stock_counts",
+	"stock_movements",
+	"inventory_items",
+	"warehouses",
+	"bundle_components",
+	"product_variant_price_tiers",
+	"variant_option_values",
+	"product_option_values",
+	"product_options",
+	"product_images",
+	"product_translations",
+	"category_translations",
+	"product_categories",
+	"product_variants",
+	"product_summaries",
+	"products",
+	"categories",
+	"brands",
+	"addresses",
+	"company_invitations",
+	"users",
+	"companies",
+}
+
+// Wipe empties every table the seed writes to. On PostgreSQL the tables are
+// truncated with CASCADE, which also empties the tables referencing them,
+// such as carts and sessions, and their IDs restart at 1.
+func Wipe(db *gorm.DB) error {
+	if db.Dialector.Name() == "postgres" {
+		sql := "TRUNCATE TABLE "
+		for i, table := range wipedTables {
+			if i > 0 {
+				sql += ", "
+			}
+			sql += table
+		}
+		return db.Exec(sql + " RESTART IDENTITY CASCADE").Error
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range wipedTables {
+			if !tx.Migrator().HasTable(table) {
+				continue
+			}
+			if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+				return fmt.Errorf("failed to wipe %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
+
+// seedUsers creates an admin, a support agent, vendors, wholesalers with
+// their companies and customers, each with an address
+func (s *seeder) seedUsers() error {
+	hash, err := password.Hash(s.opts.Password)
+	if err != nil {
+		return err
+	}
+
+	newUser := func(kind models.UserType, local, first, last string) models.User {
+		user := models.User{
+			Email:     local + "@" + EmailDomain,
+			Password:  hash,
+			FirstName: first,
+			LastName:  last,
+			Phone:     fmt.Sprintf("+447700%06d", s.rng.Intn(1000000)),
+			UserType:  kind,
+			IsActive:  true,
+			LastLogin: s.pastTime(30),
+		}
+		// seeded accounts have verified their email by their last login
+		verifiedAt := user.LastLogin
+		user.VerifiedAt = &verifiedAt
+		return user
+	}
+
+	s.admin = newUser(models.Admin, "admin", "Amina", "Haddad")
+	s.agent = newUser(models.SupportAgent, "support", "Samir", "Benali")
+	for _, user := range []*models.User{&s.admin, &s.agent} {
+		if err := s.db.Create(user).Error; err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		first, last := s.name()
+		vendor := newUser(models.Vendor, fmt.Sprintf("vendor%d", i+1), first, last)
+		if err := s.db.Create(&vendor).Error; err != nil {
+			return err
+		}
+		s.vendors = append(s.vendors, vendor)
+	}
+
+	for i := 0; i < 3; i++ {
+		address := s.address(nil)
+		if err := s.db.Create(&address).Error; err != nil {
+			return err
+		}
+		company := models.Company{
+			Name:               companyNames[i%len(companyNames)],
+			VATNumber:          fmt.Sprintf("GB%09d", s.rng.Intn(1000000000)),
+			RegistrationNumber: fmt.Sprintf("%08d", s.rng.Intn(100000000)),
+			Email:              fmt.Sprintf("accounts%d@%s", i+1, EmailDomain),
+			IsVerified:         i != 2,
+			CreditLimit:        float64(5000 * (i + 1)),
+			PaymentTerms:       30,
+			AddressID:          address.ID,
+		}
+		if err := s.db.Create(&company).Error; err != nil {
+			return err
+		}
+		s.summary.Companies++
+
+		first, last := s.name()
+		wholesaler := newUser(models.Wholesaler, fmt.Sprintf("wholesaler%d", i+1), first, last)
+		wholesaler.CompanyID = &company.ID
+		wholesaler.Role = models.CompanyRoleOwner
+		if err := s.db.Create(&wholesaler).Error; err != nil {
+			return err
+		}
+		s.buyers = append(s.buyers, wholesaler)
+	}
+
+	for i := 0; i < s.opts.Customers; i++ {
+		first, last := s.name()
+		customer := newUser(models.Customer, fmt.Sprintf("customer%d", i+1), first, last)
+		if err := s.db.Create(&customer).Error; err != nil {
+			return err
+		}
+		s.buyers = append(s.buyers, customer)
+	}
+
+	for i := range s.buyers {
+		address := s.address(&s.buyers[i].ID)
+		address.IsDefault = true
+		if err := s.db.Create(&address).Error; err != nil {
+			return err
+		}
+		s.buyers[i].Addresses = []*models.Address{&address}
+	}
+
+	s.summary.Users = 2 + len(s.vendors) + len(s.buyers)
+	return nil
+}
+
+func (s *seeder) name() (string, string) {
+	return firstNames[s.rng.Intn(len(firstNames))], lastNames[s.rng.Intn(len(lastNames))]
+}
+
+func (s *seeder) address(userID *uint) models.Address {
+	city := cities[s.rng.Intn(len(cities))]
+	return models.Address{
+		StreetAddress1: fmt.Sprintf("%d %s", 1+s.rng.Intn(200), streets[s.rng.Intn(len(streets))]),
+		City:           city.name,
+		PostalCode:     fmt.Sprintf("%s %d%c%c", city.postcode, s.rng.Intn(10), 'A'+rune(s.rng.Intn(26)), 'A'+rune(s.rng.Intn(26))),
+		Country:        "GB",
+		UserID:         userID,
+	}
+}
+
+// pastTime returns a time up to days before Now
+func (s *seeder) pastTime(days int) time.Time {
+	return s.opts.Now.Add(-time.Duration(s.rng.Int63n(int64(days) * int64(24*time.Hour))))
+}
+
+func (s *seeder) notAfterNow(t time.Time) time.Time {
+	if t.After(s.opts.Now) {
+		return s.opts.Now
+	}
+	return t
+}
+
+// pick returns an index chosen with the given weights
+func (s *seeder) pick(weights []int) int {
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	n := s.rng.Intn(total)
+	for i, weight := range weights {
+		if n < weight {
+			return i
+		}
+		n -= weight
+	}
+	return len(weights) - 1
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,121 @@
+package seed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Company{}, &models.User{}, &models.Address{},
+		&models.Category{}, &models.Brand{}, &models.Product{}, &models.ProductImage{},
+		&models.ProductOption{}, &models.ProductOptionValue{}, &models.ProductVariant{},
+		&models.ProductVariantPriceTier{}, &models.Warehouse{}, &models.InventoryItem{},
+		&models.StockMovement{}, &models.Order{}, &models.OrderItem{},
+		&models.ProductReview{}, &models.SellerResponse{}, &models.ProductRating{},
+		&models.SupportTicket{}, &models.TicketResponse{}, &models.ProductSummary{},
+	))
+	return db
+}
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func testOptions() Options {
+	return Options{Seed: 7, Customers: 4, Products: 6, Orders: 25, Tickets: 6, Password: "secret", Now: testNow}
+}
+
+func TestRunSeedsEveryDomain(t *testing.T) {
+	db := setupTestDB(t)
+
+	summary, err := Run(context.Background(), db, testOptions())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2+2+3+4, summary.Users)
+	assert.Equal(t, 6, summary.Products)
+	assert.Equal(t, 25, summary.Orders)
+	assert.Equal(t, 6, summary.Tickets)
+	assert.Greater(t, summary.Variants, summary.Products)
+
+	for _, kind := range []models.UserType{models.Admin, models.SupportAgent, models.Vendor, models.Wholesaler, models.Customer} {
+		var count int64
+		db.Model(&models.User{}).Where("user_type = ?", kind).Count(&count)
+		assert.NotZero(t, count, "users of type %s", kind)
+	}
+
+	var tiers, stock, summaries int64
+	db.Model(&models.ProductVariantPriceTier{}).Count(&tiers)
+	db.Model(&models.InventoryItem{}).Count(&stock)
+	db.Model(&models.ProductSummary{}).Count(&summaries)
+	assert.Equal(t, int64(2*summary.Variants), tiers)
+	assert.Equal(t, int64(summary.InventoryItems), stock)
+	assert.Equal(t, int64(summary.Products), summaries)
+
+	// Orders add up and are never dated after now
+	var orders []models.Order
+	require.NoError(t, db.Preload("Items").Find(&orders).Error)
+	for _, order := range orders {
+		sum := 0.0
+		for _, item := range order.Items {
+			sum += item.TotalAmount
+		}
+		assert.InDelta(t, order.TotalAmount, sum, 0.01, order.OrderNumber)
+		assert.InDelta(t, order.FinalAmount, order.TotalAmount+order.TaxAmount+order.ShippingAmount, 0.01, order.OrderNumber)
+		assert.False(t, order.OrderDate.After(testNow))
+		if order.DeliveredDate != nil {
+			assert.False(t, order.DeliveredDate.After(testNow))
+		}
+	}
+}
+
+func TestRunIsDeterministic(t *testing.T) {
+	snapshot := func() []string {
+		db := setupTestDB(t)
+		_, err := Run(context.Background(), db, testOptions())
+		require.NoError(t, err)
+
+		var rows []string
+		var orders []models.Order
+		require.NoError(t, db.Order("id").Find(&orders).Error)
+		for _, order := range orders {
+			rows = append(rows, order.OrderNumber+string(order.Status), order.OrderDate.UTC().String())
+		}
+		var variants []models.ProductVariant
+		require.NoError(t, db.Order("id").Find(&variants).Error)
+		for _, variant := range variants {
+			rows = append(rows, variant.SKU, variant.Barcode)
+		}
+		return rows
+	}
+
+	assert.Equal(t, snapshot(), snapshot())
+}
+
+func TestRunRefusesToSeedTwiceWithoutWipe(t *testing.T) {
+	db := setupTestDB(t)
+	_, err := Run(context.Background(), db, testOptions())
+	require.NoError(t, err)
+
+	_, err = Run(context.Background(), db, testOptions())
+	assert.Error(t, err)
+
+	opts := testOptions()
+	opts.Wipe = true
+	summary, err := Run(context.Background(), db, opts)
+	require.NoError(t, err)
+
+	var users, orders int64
+	db.Model(&models.User{}).Count(&users)
+	db.Model(&models.Order{}).Count(&orders)
+	assert.Equal(t, int64(summary.Users), users)
+	assert.Equal(t, int64(summary.Orders), orders)
+}
@@ -0,0 +1,100 @@
+package settings
+
+import (
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// Maintenance setting keys
+const (
+	KeyMaintenanceEnabled  = "maintenance_enabled"
+	KeyMaintenanceMessage  = "maintenance_message"
+	KeyMaintenanceStartsAt = "maintenance_starts_at"
+	KeyMaintenanceEndsAt   = "maintenance_ends_at"
+)
+
+// Maintenance is the maintenance mode. While it is active the API only
+// serves reads, except to admins. It is active when switched on, or during
+// a scheduled window, which the storefront announces beforehand.
+type Maintenance struct {
+	Enabled  bool       `json:"enabled"`
+	Message  string     `json:"message" binding:"max=500"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"` // also when an enabled maintenance is expected to end
+}
+
+var maintenance Maintenance
+
+// CurrentMaintenance returns the maintenance mode in effect
+func CurrentMaintenance() Maintenance {
+	mu.RLock()
+	defer mu.RUnlock()
+	return maintenance
+}
+
+// Active reports whether maintenance is on at now
+func (m Maintenance) Active(now time.Time) bool {
+	if m.Enabled {
+		return true
+	}
+	return m.StartsAt != nil && !now.Before(*m.StartsAt) && (m.EndsAt == nil || now.Before(*m.EndsAt))
+}
+
+// Scheduled reports whether a maintenance window starts after now
+func (m Maintenance) Scheduled(now time.Time) bool {
+	return !m.Active(now) && m.StartsAt != nil && now.Before(*m.StartsAt)
+}
+
+// SaveMaintenance replaces the maintenance mode and caches the result
+func SaveMaintenance(db *gorm.DB, m Maintenance, updatedBy uint) (Maintenance, error) {
+	values := map[string]string{
+		KeyMaintenanceEnabled:  "false",
+		KeyMaintenanceMessage:  m.Message,
+		KeyMaintenanceStartsAt: formatTime(m.StartsAt),
+		KeyMaintenanceEndsAt:   formatTime(m.EndsAt),
+	}
+	if m.Enabled {
+		values[KeyMaintenanceEnabled] = "true"
+	}
+	rows := make([]models.Setting, 0, len(values))
+	for key, value := range values {
+		rows = append(rows, models.Setting{Key: key, Value: value, UpdatedByID: &updatedBy})
+	}
+	if err := upsert(db, rows); err != nil {
+		return Maintenance{}, err
+	}
+	if err := Load(db); err != nil {
+		return Maintenance{}, err
+	}
+	return CurrentMaintenance(), nil
+}
+
+func (m *Maintenance) set(key, value string) {
+	switch key {
+	case KeyMaintenanceEnabled:
+		m.Enabled = value == "true"
+	case KeyMaintenanceMessage:
+		m.Message = value
+	case KeyMaintenanceStartsAt:
+		m.StartsAt = parseTime(value)
+	case KeyMaintenanceEndsAt:
+		m.EndsAt = parseTime(value)
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
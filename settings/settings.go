@@ -0,0 +1,199 @@
+// Package settings holds the business settings admins edit at runtime: the
+// company name, site URL, support email and default currency used in emails,
+// invoices and prices, and the maintenance mode. They are cached in memory
+// and reloaded periodically, so edits made on one instance reach the others.
+package settings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setting keys
+const (
+	KeyCompanyName  = "company_name"
+	KeySiteURL      = "site_url"
+	KeySupportEmail = "support_email"
+	KeyCurrency     = "currency"
+)
+
+// Business is the set of business settings
+type Business struct {
+	CompanyName  string `json:"company_name"`
+	SiteURL      string `json:"site_url"` // without a trailing slash
+	SupportEmail string `json:"support_email"`
+	Currency     string `json:"currency"` // ISO 4217 code
+}
+
+// Defaults are used for settings that were never edited
+var Defaults = Business{
+	CompanyName:  "Algeria Market",
+	SiteURL:      "https://algeriamarket.co.uk",
+	SupportEmail: "enquirees@algeriamarket.co.uk",
+	Currency:     "GBP",
+}
+
+// Update changes business settings; fields left out are kept
+type Update struct {
+	CompanyName  *string `json:"company_name" binding:"omitempty,min=1,max=100"`
+	SiteURL      *string `json:"site_url" binding:"omitempty,url"`
+	SupportEmail *string `json:"support_email" binding:"omitempty,email"`
+	Currency     *string `json:"currency" binding:"omitempty,iso4217"`
+}
+
+var (
+	mu      sync.RWMutex
+	current = Defaults
+)
+
+// Current returns the business settings in effect
+func Current() Business {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// URL joins a path, formatted with args, to the site URL
+func (b Business) URL(path string, args ...interface{}) string {
+	if len(args) > 0 {
+		path = fmt.Sprintf(path, args...)
+	}
+	return b.SiteURL + path
+}
+
+// CurrencySymbol returns the symbol of the currency, or its code followed by
+// a space when it has no common symbol
+func (b Business) CurrencySymbol() string {
+	switch b.Currency {
+	case "GBP":
+		return "£"
+	case "EUR":
+		return "€"
+	case "USD":
+		return "$"
+	default:
+		return b.Currency + " "
+	}
+}
+
+// TemplateData returns the settings under the keys email templates use
+func (b Business) TemplateData() map[string]interface{} {
+	return map[string]interface{}{
+		"CompanyName":    b.CompanyName,
+		"SiteURL":        b.SiteURL,
+		"SupportEmail":   b.SupportEmail,
+		"Currency":       b.Currency,
+		"CurrencySymbol": b.CurrencySymbol(),
+	}
+}
+
+// WithTemplateData returns data with the current settings added under the
+// keys it does not set. data itself is left unchanged.
+func WithTemplateData(data map[string]interface{}) map[string]interface{} {
+	merged := Current().TemplateData()
+	for key, value := range data {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Load replaces the cached settings with the settings table, using the
+// defaults for keys without a row
+func Load(db *gorm.DB) error {
+	var rows []models.Setting
+	if err := db.Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	loaded := Defaults
+	loadedMaintenance := Maintenance{}
+	for _, row := range rows {
+		loaded.set(row.Key, row.Value)
+		loadedMaintenance.set(row.Key, row.Value)
+	}
+	mu.Lock()
+	current = loaded
+	maintenance = loadedMaintenance
+	mu.Unlock()
+	return nil
+}
+
+// Save stores the changed settings and caches the result
+func Save(db *gorm.DB, update Update, updatedBy uint) (Business, error) {
+	changes := map[string]*string{
+		KeyCompanyName:  update.CompanyName,
+		KeySiteURL:      update.SiteURL,
+		KeySupportEmail: update.SupportEmail,
+		KeyCurrency:     update.Currency,
+	}
+	rows := []models.Setting{}
+	for key, value := range changes {
+		if value == nil {
+			continue
+		}
+		rows = append(rows, models.Setting{Key: key, Value: normalize(key, *value), UpdatedByID: &updatedBy})
+	}
+	if err := upsert(db, rows); err != nil {
+		return Business{}, err
+	}
+	if err := Load(db); err != nil {
+		return Business{}, err
+	}
+	return Current(), nil
+}
+
+func upsert(db *gorm.DB, rows []models.Setting) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by_id", "updated_at"}),
+	}).Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	return nil
+}
+
+// StartRefresher reloads the settings every interval until ctx is done
+func StartRefresher(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	for worker.Sleep(ctx, interval) {
+		if err := Load(db.WithContext(ctx)); err != nil {
+			slog.ErrorContext(ctx, "failed to reload settings", "component", "settings", "error", err)
+		}
+	}
+}
+
+func (b *Business) set(key, value string) {
+	switch key {
+	case KeyCompanyName:
+		b.CompanyName = value
+	case KeySiteURL:
+		b.SiteURL = value
+	case KeySupportEmail:
+		b.SupportEmail = value
+	case KeyCurrency:
+		b.Currency = value
+	}
+}
+
+func normalize(key, value string) string {
+	value = strings.TrimSpace(value)
+	switch key {
+	case KeySiteURL:
+		return strings.TrimRight(value, "/")
+	case KeySupportEmail:
+		return strings.ToLower(value)
+	case KeyCurrency:
+		return strings.ToUpper(value)
+	}
+	return value
+}
@@ -0,0 +1,102 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Setting{}))
+	t.Cleanup(func() {
+		mu.Lock()
+		current = Defaults
+		maintenance = Maintenance{}
+		mu.Unlock()
+	})
+	return db
+}
+
+func ptr(s string) *string { return &s }
+
+func TestLoadUsesDefaults(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, Load(db))
+	assert.Equal(t, Defaults, Current())
+}
+
+func TestSave(t *testing.T) {
+	db := setupTestDB(t)
+
+	saved, err := Save(db, Update{SiteURL: ptr(" https://shop.example.com/ "), Currency: ptr("EUR")}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "https://shop.example.com", saved.SiteURL)
+	assert.Equal(t, "EUR", saved.Currency)
+	assert.Equal(t, Defaults.CompanyName, saved.CompanyName, "fields left out are kept")
+
+	saved, err = Save(db, Update{Currency: ptr("USD")}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", saved.Currency)
+	assert.Equal(t, "https://shop.example.com", saved.SiteURL)
+
+	var rows int64
+	require.NoError(t, db.Model(&models.Setting{}).Count(&rows).Error)
+	assert.EqualValues(t, 2, rows)
+
+	// Another instance picks the settings up on load
+	mu.Lock()
+	current = Defaults
+	mu.Unlock()
+	require.NoError(t, Load(db))
+	assert.Equal(t, saved, Current())
+}
+
+func TestURL(t *testing.T) {
+	b := Business{SiteURL: "https://shop.example.com"}
+	assert.Equal(t, "https://shop.example.com/cart", b.URL("/cart"))
+	assert.Equal(t, "https://shop.example.com/orders/7", b.URL("/orders/%d", 7))
+}
+
+func TestCurrencySymbol(t *testing.T) {
+	assert.Equal(t, "£", Business{Currency: "GBP"}.CurrencySymbol())
+	assert.Equal(t, "DZD ", Business{Currency: "DZD"}.CurrencySymbol())
+}
+
+func TestWithTemplateData(t *testing.T) {
+	setupTestDB(t)
+	data := map[string]interface{}{"CompanyName": "Acme Ltd", "OrderNumber": "ORD-1"}
+
+	merged := WithTemplateData(data)
+	assert.Equal(t, "Acme Ltd", merged["CompanyName"], "data takes precedence")
+	assert.Equal(t, Defaults.SiteURL, merged["SiteURL"])
+	assert.Equal(t, "£", merged["CurrencySymbol"])
+	assert.NotContains(t, data, "SiteURL", "data is left unchanged")
+}
+
+func TestSaveMaintenance(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	startsAt, endsAt := now.Add(time.Hour), now.Add(3*time.Hour)
+
+	saved, err := SaveMaintenance(db, Maintenance{Message: "Upgrading payments", StartsAt: &startsAt, EndsAt: &endsAt}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Upgrading payments", saved.Message)
+	assert.True(t, saved.Scheduled(now))
+	assert.False(t, saved.Active(now))
+	assert.True(t, saved.Active(startsAt))
+	assert.False(t, saved.Active(endsAt))
+	assert.Equal(t, Defaults, Current(), "business settings are kept")
+
+	// Saving replaces the whole maintenance setting
+	saved, err = SaveMaintenance(db, Maintenance{Enabled: true}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Maintenance{Enabled: true}, saved)
+	assert.True(t, saved.Active(now))
+}
@@ -0,0 +1,169 @@
+package sla
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// Compliance counts how many records met, missed or are still within a deadline
+type Compliance struct {
+	Met      int64   `json:"met"`
+	Breached int64   `json:"breached"`
+	Pending  int64   `json:"pending"`
+	Rate     float64 `json:"compliance_rate"` // percentage of decided records that met the deadline
+}
+
+// SubjectReport is the SLA compliance of tickets or disputes
+type SubjectReport struct {
+	Subject                 models.SLASubject     `json:"subject"`
+	Total                   int64                 `json:"total"`
+	FirstResponse           Compliance            `json:"first_response"`
+	Resolution              Compliance            `json:"resolution"`
+	AvgFirstResponseMinutes float64               `json:"avg_first_response_minutes"`
+	AvgResolutionMinutes    float64               `json:"avg_resolution_minutes"`
+	ByPriority              map[string]*Breakdown `json:"by_priority"`
+	ByCategory              map[string]*Breakdown `json:"by_category"`
+}
+
+// Breakdown is the compliance of one priority or category
+type Breakdown struct {
+	Total         int64      `json:"total"`
+	FirstResponse Compliance `json:"first_response"`
+	Resolution    Compliance `json:"resolution"`
+}
+
+// Report is the SLA compliance of records created in a period
+type Report struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Tickets  *SubjectReport `json:"tickets"`
+	Disputes *SubjectReport `json:"disputes"`
+}
+
+// Report summarises SLA compliance for tickets and disputes created between
+// from and to. Records created before SLA tracking have no deadlines and are
+// left out.
+func (s *Service) Report(from, to, now time.Time) (*Report, error) {
+	tickets, err := s.subjectReport(models.SLASubjectTicket, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+	disputes, err := s.subjectReport(models.SLASubjectDispute, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{From: from, To: to, Tickets: tickets, Disputes: disputes}, nil
+}
+
+func (s *Service) subjectReport(subject models.SLASubject, from, to, now time.Time) (*SubjectReport, error) {
+	var records []record
+	if err := s.db.Model(modelFor(subject)).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Where("first_response_due_at IS NOT NULL").
+		Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load %s SLA report: %w", subject, err)
+	}
+
+	report := &SubjectReport{
+		Subject:    subject,
+		ByPriority: map[string]*Breakdown{},
+		ByCategory: map[string]*Breakdown{},
+	}
+	var firstResponseTotal, resolutionTotal time.Duration
+	var firstResponseCount, resolutionCount int64
+
+	for _, rec := range records {
+		firstResponse := classify(rec.FirstResponseDueAt, rec.FirstRespondedAt, rec.FirstResponseBreached, now)
+		resolution := classify(rec.ResolutionDueAt, rec.ResolvedAt, rec.ResolutionBreached, now)
+
+		report.Total++
+		report.FirstResponse.add(firstResponse)
+		report.Resolution.add(resolution)
+		for _, breakdown := range []*Breakdown{
+			breakdownFor(report.ByPriority, normalizePriority(rec.Priority)),
+			breakdownFor(report.ByCategory, strings.ToUpper(rec.Category)),
+		} {
+			breakdown.Total++
+			breakdown.FirstResponse.add(firstResponse)
+			breakdown.Resolution.add(resolution)
+		}
+
+		if rec.FirstRespondedAt != nil {
+			firstResponseTotal += rec.FirstRespondedAt.Sub(rec.CreatedAt)
+			firstResponseCount++
+		}
+		if rec.ResolvedAt != nil {
+			resolutionTotal += rec.ResolvedAt.Sub(rec.CreatedAt)
+			resolutionCount++
+		}
+	}
+
+	report.FirstResponse.finish()
+	report.Resolution.finish()
+	for _, breakdowns := range []map[string]*Breakdown{report.ByPriority, report.ByCategory} {
+		for _, breakdown := range breakdowns {
+			breakdown.FirstResponse.finish()
+			breakdown.Resolution.finish()
+		}
+	}
+	if firstResponseCount > 0 {
+		report.AvgFirstResponseMinutes = firstResponseTotal.Minutes() / float64(firstResponseCount)
+	}
+	if resolutionCount > 0 {
+		report.AvgResolutionMinutes = resolutionTotal.Minutes() / float64(resolutionCount)
+	}
+	return report, nil
+}
+
+type outcome int
+
+const (
+	outcomePending outcome = iota
+	outcomeMet
+	outcomeBreached
+)
+
+// classify decides whether a deadline was met, missed or is still open
+func classify(dueAt, doneAt *time.Time, flagged bool, now time.Time) outcome {
+	switch {
+	case dueAt == nil:
+		return outcomePending
+	case flagged:
+		return outcomeBreached
+	case doneAt != nil && doneAt.After(*dueAt):
+		return outcomeBreached
+	case doneAt != nil:
+		return outcomeMet
+	case now.After(*dueAt):
+		return outcomeBreached
+	default:
+		return outcomePending
+	}
+}
+
+func (c *Compliance) add(o outcome) {
+	switch o {
+	case outcomeMet:
+		c.Met++
+	case outcomeBreached:
+		c.Breached++
+	default:
+		c.Pending++
+	}
+}
+
+func (c *Compliance) finish() {
+	if decided := c.Met + c.Breached; decided > 0 {
+		c.Rate = float64(c.Met) / float64(decided) * 100
+	}
+}
+
+func breakdownFor(breakdowns map[string]*Breakdown, key string) *Breakdown {
+	if breakdowns[key] == nil {
+		breakdowns[key] = &Breakdown{}
+	}
+	return breakdowns[key]
+}
@@ -0,0 +1,262 @@
+package sla
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/notification"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+// Targets are the first-response and resolution times for a support record
+type Targets struct {
+	FirstResponse time.Duration
+	Resolution    time.Duration
+}
+
+// DefaultTargets apply by priority when no SLAPolicy matches a record
+var DefaultTargets = map[string]Targets{
+	"URGENT": {FirstResponse: 1 * time.Hour, Resolution: 8 * time.Hour},
+	"HIGH":   {FirstResponse: 4 * time.Hour, Resolution: 24 * time.Hour},
+	"MEDIUM": {FirstResponse: 8 * time.Hour, Resolution: 72 * time.Hour},
+	"LOW":    {FirstResponse: 24 * time.Hour, Resolution: 120 * time.Hour},
+}
+
+// closedStatuses are the statuses in which a record no longer counts towards an SLA
+var closedStatuses = []string{"RESOLVED", "CLOSED"}
+
+// Service computes SLA deadlines for support tickets and disputes and flags breaches
+type Service struct {
+	db       *gorm.DB
+	notifier *notification.Service
+}
+
+// NewService creates an SLA service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db, notifier: notification.NewService(db)}
+}
+
+// record is the subset of a ticket or dispute the SLA service works with
+type record struct {
+	ID                    uint
+	CreatedAt             time.Time
+	Title                 string
+	Category              string
+	Priority              string
+	Status                string
+	FirstResponseDueAt    *time.Time
+	ResolutionDueAt       *time.Time
+	FirstRespondedAt      *time.Time
+	ResolvedAt            *time.Time
+	FirstResponseBreached bool
+	ResolutionBreached    bool
+	IsEscalated           bool
+}
+
+// TargetsFor returns the targets of the most specific active policy matching
+// category and priority, falling back to DefaultTargets
+func (s *Service) TargetsFor(subject models.SLASubject, category, priority string) (Targets, error) {
+	category = strings.ToUpper(strings.TrimSpace(category))
+	priority = normalizePriority(priority)
+
+	var policies []models.SLAPolicy
+	if err := s.db.
+		Where("subject = ? AND is_active = ?", subject, true).
+		Where("category = ? OR category = ''", category).
+		Where("UPPER(priority) = ? OR priority = ''", priority).
+		Find(&policies).Error; err != nil {
+		return Targets{}, fmt.Errorf("failed to load SLA policies: %w", err)
+	}
+
+	var best *models.SLAPolicy
+	bestScore := -1
+	for i := range policies {
+		score := 0
+		if policies[i].Category != "" {
+			score += 2
+		}
+		if policies[i].Priority != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = &policies[i], score
+		}
+	}
+	if best != nil {
+		return Targets{
+			FirstResponse: time.Duration(best.FirstResponseMinutes) * time.Minute,
+			Resolution:    time.Duration(best.ResolutionMinutes) * time.Minute,
+		}, nil
+	}
+	if targets, ok := DefaultTargets[priority]; ok {
+		return targets, nil
+	}
+	return DefaultTargets["MEDIUM"], nil
+}
+
+// Schedule sets the SLA deadlines of a ticket or dispute from its creation
+// time. It is called on creation and again when category or priority change.
+func (s *Service) Schedule(subject models.SLASubject, id uint) error {
+	rec, err := s.load(subject, id)
+	if err != nil {
+		return err
+	}
+
+	targets, err := s.TargetsFor(subject, rec.Category, rec.Priority)
+	if err != nil {
+		return err
+	}
+	firstResponseDue := rec.CreatedAt.Add(targets.FirstResponse)
+	resolutionDue := rec.CreatedAt.Add(targets.Resolution)
+
+	if err := s.db.Model(modelFor(subject)).Where("id = ?", id).Updates(map[string]interface{}{
+		"first_response_due_at": firstResponseDue,
+		"resolution_due_at":     resolutionDue,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to schedule SLA for %s %d: %w", subject, id, err)
+	}
+	return nil
+}
+
+// RecordFirstResponse marks the first staff response to a ticket or dispute.
+// Later responses leave the original time in place.
+func (s *Service) RecordFirstResponse(subject models.SLASubject, id uint, at time.Time) error {
+	if err := s.db.Model(modelFor(subject)).
+		Where("id = ? AND first_responded_at IS NULL", id).
+		Update("first_responded_at", at).Error; err != nil {
+		return fmt.Errorf("failed to record first response for %s %d: %w", subject, id, err)
+	}
+	return nil
+}
+
+// Breach is an SLA deadline missed by a ticket or dispute
+type Breach struct {
+	Subject models.SLASubject `json:"subject"`
+	ID      uint              `json:"id"`
+	Title   string            `json:"title"`
+	Kind    string            `json:"kind"` // "first_response" or "resolution"
+	DueAt   time.Time         `json:"due_at"`
+}
+
+// CheckBreaches flags every open ticket and dispute that has missed a
+// deadline by now, escalates it and notifies the admins. It returns the
+// breaches found on this run.
+func (s *Service) CheckBreaches(now time.Time) ([]Breach, error) {
+	var breaches []Breach
+	for _, subject := range []models.SLASubject{models.SLASubjectTicket, models.SLASubjectDispute} {
+		found, err := s.checkSubject(subject, now)
+		if err != nil {
+			return breaches, err
+		}
+		breaches = append(breaches, found...)
+	}
+	return breaches, nil
+}
+
+// StartBreachChecker periodically checks for SLA breaches until ctx is canceled
+func (s *Service) StartBreachChecker(ctx context.Context, interval time.Duration) {
+	log.Printf("⏱️ SLA: Starting breach checker (interval: %s)", interval)
+	for {
+		if breaches, err := s.CheckBreaches(time.Now()); err != nil {
+			log.Printf("❌ SLA: Failed to check breaches: %v", err)
+		} else if len(breaches) > 0 {
+			log.Printf("⚠️ SLA: Escalated %d breached tickets and disputes", len(breaches))
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+func (s *Service) checkSubject(subject models.SLASubject, now time.Time) ([]Breach, error) {
+	var records []record
+	if err := s.db.Model(modelFor(subject)).
+		Where("status NOT IN ?", closedStatuses).
+		Where("(first_response_breached = ? AND first_responded_at IS NULL AND first_response_due_at < ?) OR (resolution_breached = ? AND resolved_at IS NULL AND resolution_due_at < ?)",
+			false, now, false, now).
+		Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to find SLA breaches: %w", err)
+	}
+
+	var breaches []Breach
+	for _, rec := range records {
+		updates := map[string]interface{}{}
+		var found []Breach
+		if !rec.FirstResponseBreached && rec.FirstRespondedAt == nil && rec.FirstResponseDueAt != nil && rec.FirstResponseDueAt.Before(now) {
+			updates["first_response_breached"] = true
+			found = append(found, Breach{Subject: subject, ID: rec.ID, Title: rec.Title, Kind: "first_response", DueAt: *rec.FirstResponseDueAt})
+		}
+		if !rec.ResolutionBreached && rec.ResolvedAt == nil && rec.ResolutionDueAt != nil && rec.ResolutionDueAt.Before(now) {
+			updates["resolution_breached"] = true
+			found = append(found, Breach{Subject: subject, ID: rec.ID, Title: rec.Title, Kind: "resolution", DueAt: *rec.ResolutionDueAt})
+		}
+		if len(found) == 0 {
+			continue
+		}
+		if !rec.IsEscalated {
+			updates["is_escalated"] = true
+			updates["escalated_at"] = now
+		}
+
+		if err := s.db.Model(modelFor(subject)).Where("id = ?", rec.ID).Updates(updates).Error; err != nil {
+			return breaches, fmt.Errorf("failed to flag SLA breach on %s %d: %w", subject, rec.ID, err)
+		}
+		for _, breach := range found {
+			s.notifyBreach(breach)
+		}
+		breaches = append(breaches, found...)
+	}
+	return breaches, nil
+}
+
+// notifyBreach tells the admins about a breach; failures are logged and never stop the check
+func (s *Service) notifyBreach(breach Breach) {
+	deadline := "first response"
+	if breach.Kind == "resolution" {
+		deadline = "resolution"
+	}
+	_, err := s.notifier.NotifyAdmins(notification.Message{
+		Type:  models.NotificationTypeSLABreach,
+		Title: fmt.Sprintf("SLA breached: %s #%d missed its %s deadline", breach.Subject, breach.ID, deadline),
+		Body:  breach.Title,
+		Link:  fmt.Sprintf("/admin/support/%ss/%d", breach.Subject, breach.ID),
+		Data:  models.JSON{"subject": breach.Subject, "id": breach.ID, "kind": breach.Kind, "due_at": breach.DueAt},
+	})
+	if err != nil {
+		log.Printf("Failed to notify admins of SLA breach on %s %d: %v", breach.Subject, breach.ID, err)
+	}
+}
+
+func (s *Service) load(subject models.SLASubject, id uint) (*record, error) {
+	var rec record
+	result := s.db.Model(modelFor(subject)).Where("id = ?", id).Limit(1).Find(&rec)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load %s %d: %w", subject, id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%s %d not found", subject, id)
+	}
+	return &rec, nil
+}
+
+func modelFor(subject models.SLASubject) interface{} {
+	if subject == models.SLASubjectDispute {
+		return &models.Dispute{}
+	}
+	return &models.SupportTicket{}
+}
+
+// normalizePriority upper-cases a priority, treating an empty one as MEDIUM
+// like the column default does
+func normalizePriority(priority string) string {
+	priority = strings.ToUpper(strings.TrimSpace(priority))
+	if priority == "" {
+		return "MEDIUM"
+	}
+	return priority
+}
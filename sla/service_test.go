@@ -0,0 +1,90 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Notification{}, &models.SLAPolicy{}, &models.SupportTicket{}, &models.Dispute{}))
+	return db
+}
+
+func TestTargetsForPrefersMostSpecificPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+
+	targets, err := service.TargetsFor(models.SLASubjectTicket, "PAYMENT", "high")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTargets["HIGH"], targets)
+
+	require.NoError(t, db.Create(&models.SLAPolicy{Subject: models.SLASubjectTicket, Priority: "HIGH", FirstResponseMinutes: 120, ResolutionMinutes: 600, IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.SLAPolicy{Subject: models.SLASubjectTicket, Category: "PAYMENT", FirstResponseMinutes: 30, ResolutionMinutes: 240, IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.SLAPolicy{Subject: models.SLASubjectDispute, Category: "PAYMENT", Priority: "HIGH", FirstResponseMinutes: 5, ResolutionMinutes: 10, IsActive: true}).Error)
+
+	targets, err = service.TargetsFor(models.SLASubjectTicket, "PAYMENT", "HIGH")
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, targets.FirstResponse)
+
+	targets, err = service.TargetsFor(models.SLASubjectTicket, "ORDER", "HIGH")
+	require.NoError(t, err)
+	assert.Equal(t, 120*time.Minute, targets.FirstResponse)
+	assert.Equal(t, 600*time.Minute, targets.Resolution)
+}
+
+func TestCheckBreachesEscalatesAndNotifiesAdmins(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewService(db)
+	require.NoError(t, db.Create(&models.User{Email: "admin@example.com", UserType: models.Admin, IsActive: true}).Error)
+
+	created := time.Now().Add(-2 * time.Hour)
+	late := models.SupportTicket{UserID: 2, Title: "Late", Category: models.TicketCategoryOrder, Priority: models.TicketPriorityUrgent, Status: models.TicketStatusOpen}
+	onTime := models.SupportTicket{UserID: 2, Title: "On time", Category: models.TicketCategoryOrder, Priority: models.TicketPriorityLow, Status: models.TicketStatusOpen}
+	for _, ticket := range []*models.SupportTicket{&late, &onTime} {
+		ticket.CreatedAt = created
+		require.NoError(t, db.Create(ticket).Error)
+		require.NoError(t, service.Schedule(models.SLASubjectTicket, ticket.ID))
+	}
+
+	breaches, err := service.CheckBreaches(time.Now())
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	assert.Equal(t, late.ID, breaches[0].ID)
+	assert.Equal(t, "first_response", breaches[0].Kind)
+
+	require.NoError(t, db.First(&late, late.ID).Error)
+	assert.True(t, late.FirstResponseBreached)
+	assert.False(t, late.ResolutionBreached)
+	assert.True(t, late.IsEscalated)
+	assert.NotNil(t, late.EscalatedAt)
+
+	var notifications int64
+	db.Model(&models.Notification{}).Where("type = ?", models.NotificationTypeSLABreach).Count(&notifications)
+	assert.Equal(t, int64(1), notifications)
+
+	// Breaches are only flagged once
+	breaches, err = service.CheckBreaches(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, breaches)
+
+	// A late response keeps the breach in the report
+	require.NoError(t, service.RecordFirstResponse(models.SLASubjectTicket, onTime.ID, time.Now()))
+	require.NoError(t, service.RecordFirstResponse(models.SLASubjectTicket, late.ID, time.Now()))
+	report, err := service.Report(created.Add(-time.Minute), time.Now(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Tickets.Total)
+	assert.Equal(t, int64(1), report.Tickets.FirstResponse.Met)
+	assert.Equal(t, int64(1), report.Tickets.FirstResponse.Breached)
+	assert.Equal(t, 50.0, report.Tickets.FirstResponse.Rate)
+	assert.Equal(t, int64(2), report.Tickets.Resolution.Pending)
+	assert.Equal(t, int64(1), report.Tickets.ByPriority["URGENT"].FirstResponse.Breached)
+	assert.Equal(t, int64(0), report.Disputes.Total)
+}
@@ -0,0 +1,57 @@
+package sms
+
+import (
+	"context"
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+)
+
+// Order updates customers can get by text message, named after their
+// templates
+const (
+	OrderConfirmed      = "order_confirmation"
+	OrderShipped        = "order_shipped"
+	OrderOutForDelivery = "order_out_for_delivery"
+)
+
+// NotifyOrder texts the customer of order, with its User loaded, about event
+// on the channel they chose for order updates. Customers who chose email or
+// have not verified their phone number are skipped; it reports whether a
+// message was sent.
+func (s *Service) NotifyOrder(ctx context.Context, order *models.Order, event string) (bool, error) {
+	user := order.User
+	channel := user.OrderUpdatesChannel
+	if channel != models.ChannelSMS && channel != models.ChannelWhatsApp {
+		return false, nil
+	}
+	if user.Phone == "" || user.PhoneVerifiedAt == nil {
+		return false, nil
+	}
+
+	message := Message{
+		Channel:  channel,
+		To:       user.Phone,
+		UserID:   &user.ID,
+		Template: event,
+		Locale:   user.Locale,
+		Data: map[string]interface{}{
+			"UserName":       user.FirstName,
+			"OrderNumber":    order.OrderNumber,
+			"TotalAmount":    order.FinalAmount,
+			"TrackingNumber": order.TrackingNumber,
+			"OrderStatusURL": settings.Current().URL("/orders/%d", order.ID),
+		},
+	}
+	_, err := s.Send(ctx, message)
+	if errors.Is(err, ErrChannelUnsupported) {
+		// Fall back to SMS while WhatsApp is not set up
+		message.Channel = models.ChannelSMS
+		_, err = s.Send(ctx, message)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,129 @@
+package sms
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderFor returns an order of user, with order update templates added to
+// the service's template directory
+func orderFor(t *testing.T, s *Service, user *models.User) *models.Order {
+	dir := s.config.TemplateDir
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order_shipped.txt"), []byte("SMS: {{.OrderNumber}} shipped {{.TrackingNumber}}"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "whatsapp"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "whatsapp", "order_shipped.txt"), []byte("WhatsApp: *{{.OrderNumber}}* shipped"), 0o644))
+	return &models.Order{OrderNumber: "ORD-1", TrackingNumber: "TRK9", UserID: user.ID, User: *user}
+}
+
+func TestNotifyOrder(t *testing.T) {
+	s, provider, user := setup(t)
+	ctx := context.Background()
+	user.Phone = "+447700900123"
+	user.PhoneVerifiedAt = &testNow
+	order := orderFor(t, s, user)
+
+	order.User.OrderUpdatesChannel = models.ChannelEmail
+	sent, err := s.NotifyOrder(ctx, order, OrderShipped)
+	require.NoError(t, err)
+	assert.False(t, sent, "customers who chose email are not texted")
+
+	order.User.OrderUpdatesChannel = models.ChannelSMS
+	sent, err = s.NotifyOrder(ctx, order, OrderShipped)
+	require.NoError(t, err)
+	assert.True(t, sent)
+
+	order.User.OrderUpdatesChannel = models.ChannelWhatsApp
+	sent, err = s.NotifyOrder(ctx, order, OrderShipped)
+	require.NoError(t, err)
+	assert.True(t, sent)
+
+	assert.Equal(t, []MockSentSMS{
+		{Channel: models.ChannelSMS, To: "+447700900123", Body: "SMS: ORD-1 shipped TRK9"},
+		{Channel: models.ChannelWhatsApp, To: "+447700900123", Body: "WhatsApp: *ORD-1* shipped"},
+	}, provider.Sent())
+
+	var logged []models.SMSMessage
+	require.NoError(t, s.db.Order("id").Find(&logged).Error)
+	require.Len(t, logged, 2)
+	assert.Equal(t, models.ChannelWhatsApp, logged[1].Channel)
+	assert.Equal(t, OrderShipped, logged[1].Template)
+}
+
+func TestNotifyOrderSkipsUnverifiedPhones(t *testing.T) {
+	s, provider, user := setup(t)
+	user.Phone = "+447700900123"
+	user.OrderUpdatesChannel = models.ChannelSMS
+	order := orderFor(t, s, user)
+
+	sent, err := s.NotifyOrder(context.Background(), order, OrderShipped)
+	require.NoError(t, err)
+	assert.False(t, sent)
+	assert.Empty(t, provider.Sent())
+}
+
+func TestNotifyOrderFallsBackToSMS(t *testing.T) {
+	s, _, user := setup(t)
+	provider := &smsOnlyProvider{}
+	s.provider = provider
+	user.Phone = "+447700900123"
+	user.PhoneVerifiedAt = &testNow
+	user.OrderUpdatesChannel = models.ChannelWhatsApp
+	order := orderFor(t, s, user)
+
+	sent, err := s.NotifyOrder(context.Background(), order, OrderShipped)
+	require.NoError(t, err)
+	assert.True(t, sent)
+	assert.Equal(t, []string{"SMS: ORD-1 shipped TRK9"}, provider.bodies)
+}
+
+type smsOnlyProvider struct {
+	bodies []string
+}
+
+func (*smsOnlyProvider) Name() string { return "sms-only" }
+
+func (p *smsOnlyProvider) Send(_ context.Context, channel, _, body string) (string, error) {
+	if channel != models.ChannelSMS {
+		return "", ErrChannelUnsupported
+	}
+	p.bodies = append(p.bodies, body)
+	return "SM1", nil
+}
+
+func TestUpdateStatus(t *testing.T) {
+	s, _, user := setup(t)
+	_, err := s.SendCode(context.Background(), user, "+447700900123", models.PhoneVerificationSignup)
+	require.NoError(t, err)
+
+	message, err := s.UpdateStatus("mock-1", "delivered", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.SMSStatusDelivered, message.Status)
+
+	message, err = s.UpdateStatus("mock-1", "sent", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.SMSStatusDelivered, message.Status, "late reports are ignored")
+
+	var logged models.SMSMessage
+	require.NoError(t, s.db.First(&logged).Error)
+	assert.Equal(t, models.SMSStatusDelivered, logged.Status)
+
+	_, err = s.UpdateStatus("SM-unknown", "delivered", "")
+	assert.ErrorIs(t, err, ErrUnknownMessage)
+}
+
+func TestUpdateStatusRecordsFailures(t *testing.T) {
+	s, _, user := setup(t)
+	_, err := s.SendCode(context.Background(), user, "+447700900123", models.PhoneVerificationSignup)
+	require.NoError(t, err)
+
+	message, err := s.UpdateStatus("mock-1", "undelivered", "30003: Unreachable destination handset")
+	require.NoError(t, err)
+	assert.Equal(t, models.SMSStatusUndelivered, message.Status)
+	assert.Equal(t, "30003: Unreachable destination handset", message.Error)
+}
@@ -0,0 +1,168 @@
+package sms
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrResendTooSoon    = errors.New("a code was just sent, please wait before asking for another")
+	ErrTooManyCodes     = errors.New("too many codes were sent, please try again later")
+	ErrInvalidCode      = errors.New("invalid or expired code")
+	ErrTooManyAttempts  = errors.New("too many wrong codes, please ask for a new one")
+	ErrAlreadyConfirmed = errors.New("phone number is already verified")
+)
+
+// otpTemplate is the template verification codes are sent with
+const otpTemplate = "otp"
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// newCode returns a random six-digit code
+func newCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// SendCode texts user a code verifying that they have phone. Codes are
+// throttled per number and per user, so numbers cannot be flooded.
+func (s *Service) SendCode(ctx context.Context, user *models.User, phone, purpose string) (*models.PhoneVerification, error) {
+	phone, err := NormalizePhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	if user.Phone == phone && user.PhoneVerifiedAt != nil {
+		return nil, ErrAlreadyConfirmed
+	}
+
+	now := s.now()
+	var last models.PhoneVerification
+	err = s.db.Where("phone = ?", phone).Order("created_at DESC").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && now.Sub(last.CreatedAt) < time.Duration(s.config.OTPResendSeconds)*time.Second {
+		return nil, ErrResendTooSoon
+	}
+	var recent int64
+	if err := s.db.Model(&models.PhoneVerification{}).
+		Where("(phone = ? OR user_id = ?) AND created_at > ?", phone, user.ID, now.Add(-time.Hour)).
+		Count(&recent).Error; err != nil {
+		return nil, err
+	}
+	if recent >= int64(s.config.OTPPerHour) {
+		return nil, ErrTooManyCodes
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return nil, err
+	}
+	verification := models.PhoneVerification{
+		CreatedAt: now,
+		UserID:    user.ID,
+		Phone:     phone,
+		Purpose:   purpose,
+		CodeHash:  hashCode(code),
+		ExpiresAt: now.Add(time.Duration(s.config.OTPExpiryMinutes) * time.Minute),
+	}
+	if err := s.db.WithContext(ctx).Create(&verification).Error; err != nil {
+		return nil, err
+	}
+
+	_, err = s.Send(ctx, Message{
+		To:       phone,
+		UserID:   &user.ID,
+		Template: otpTemplate,
+		Locale:   user.Locale,
+		Data:     map[string]interface{}{"Code": code, "ExpiryMinutes": s.config.OTPExpiryMinutes},
+		Secret:   code,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send verification code: %w", err)
+	}
+	return &verification, nil
+}
+
+// VerifyCode checks the last code sent to user for phone. On success phone
+// becomes the user's verified phone number.
+func (s *Service) VerifyCode(ctx context.Context, user *models.User, phone, code string) error {
+	phone, err := NormalizePhone(phone)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	db := s.db.WithContext(ctx)
+
+	var verification models.PhoneVerification
+	err = db.Where("user_id = ? AND phone = ? AND verified_at IS NULL AND expires_at > ?", user.ID, phone, now).
+		Order("created_at DESC").First(&verification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+	if verification.Attempts >= s.config.OTPMaxAttempts {
+		return ErrTooManyAttempts
+	}
+	if subtle.ConstantTimeCompare([]byte(hashCode(code)), []byte(verification.CodeHash)) != 1 {
+		if err := db.Model(&models.PhoneVerification{}).Where("id = ?", verification.ID).
+			Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+			return err
+		}
+		return ErrInvalidCode
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// A code works once even when requests race
+		used := tx.Model(&models.PhoneVerification{}).
+			Where("id = ? AND verified_at IS NULL", verification.ID).
+			Update("verified_at", now)
+		if used.Error != nil {
+			return used.Error
+		}
+		if used.RowsAffected == 0 {
+			return ErrInvalidCode
+		}
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"phone": phone, "phone_verified_at": now}).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.Phone = phone
+	user.PhoneVerifiedAt = &now
+	return nil
+}
+
+// RequiredAtCheckout reports whether only users with a verified phone number
+// can place orders
+func (s *Service) RequiredAtCheckout() bool {
+	return s.config.RequiredAtCheckout
+}
+
+// IsPhoneVerified reports whether the user has verified a phone number
+func (s *Service) IsPhoneVerified(userID uint) (bool, error) {
+	var user models.User
+	if err := s.db.Select("id", "phone_verified_at").First(&user, userID).Error; err != nil {
+		return false, err
+	}
+	return user.PhoneVerifiedAt != nil, nil
+}
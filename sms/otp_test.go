@@ -0,0 +1,187 @@
+package sms
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+
+func setup(t *testing.T) (*Service, *MockSMSProvider, *models.User) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "otp.txt"), []byte("Your code is {{.Code}}, valid {{.ExpiryMinutes}} minutes"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "fr"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fr", "otp.txt"), []byte("Votre code est {{.Code}}"), 0o644))
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.SMSMessage{}, &models.PhoneVerification{}))
+	user := models.User{Email: "nadia@example.com", Password: "x", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+
+	provider := NewMockSMSProvider()
+	s := NewService(db, provider, &cfg.SMSConfig{
+		TemplateDir:      dir,
+		OTPExpiryMinutes: 10,
+		OTPMaxAttempts:   3,
+		OTPResendSeconds: 60,
+		OTPPerHour:       3,
+	})
+	s.now = func() time.Time { return testNow }
+	return s, provider, &user
+}
+
+var sixDigits = regexp.MustCompile(`[0-9]{6}`)
+
+// lastCode returns the code in the last message sent
+func lastCode(t *testing.T, provider *MockSMSProvider) string {
+	sent := provider.Sent()
+	require.NotEmpty(t, sent)
+	code := sixDigits.FindString(sent[len(sent)-1].Body)
+	require.NotEmpty(t, code)
+	return code
+}
+
+func TestNormalizePhone(t *testing.T) {
+	for in, want := range map[string]string{
+		"+44 7700 900123":     "+447700900123",
+		"0044-7700-900123":    "+447700900123",
+		"+213 (555) 12.34.56": "+213555123456",
+	} {
+		got, err := NormalizePhone(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got)
+	}
+	for _, bad := range []string{"", "07700900123", "+0447700900123", "+44abc", "+123"} {
+		_, err := NormalizePhone(bad)
+		assert.ErrorIs(t, err, ErrInvalidPhone, bad)
+	}
+}
+
+func TestSendAndVerifyCode(t *testing.T) {
+	s, provider, user := setup(t)
+	ctx := context.Background()
+
+	verification, err := s.SendCode(ctx, user, "+44 7700 900123", models.PhoneVerificationSignup)
+	require.NoError(t, err)
+	assert.Equal(t, "+447700900123", verification.Phone)
+	assert.True(t, verification.ExpiresAt.Equal(testNow.Add(10*time.Minute)))
+
+	code := lastCode(t, provider)
+	assert.Equal(t, "+447700900123", provider.Sent()[0].To)
+	assert.Equal(t, "Your code is "+code+", valid 10 minutes", provider.Sent()[0].Body)
+
+	var logged models.SMSMessage
+	require.NoError(t, s.db.First(&logged).Error)
+	assert.Equal(t, models.SMSStatusSent, logged.Status)
+	assert.Equal(t, "mock", logged.Provider)
+	assert.Equal(t, "otp", logged.Template)
+	assert.Equal(t, "Your code is ******, valid 10 minutes", logged.Body, "codes are masked in the delivery log")
+
+	require.NoError(t, s.VerifyCode(ctx, user, "+447700900123", code))
+	assert.Equal(t, "+447700900123", user.Phone)
+	require.NotNil(t, user.PhoneVerifiedAt)
+
+	verified, err := s.IsPhoneVerified(user.ID)
+	require.NoError(t, err)
+	assert.True(t, verified)
+
+	assert.ErrorIs(t, s.VerifyCode(ctx, user, "+447700900123", code), ErrInvalidCode, "codes work once")
+
+	_, err = s.SendCode(ctx, user, "+447700900123", models.PhoneVerificationCheckout)
+	assert.ErrorIs(t, err, ErrAlreadyConfirmed)
+}
+
+func TestSendCodeInUserLocale(t *testing.T) {
+	s, provider, user := setup(t)
+	user.Locale = "fr"
+
+	_, err := s.SendCode(context.Background(), user, "+33612345678", models.PhoneVerificationSignup)
+	require.NoError(t, err)
+	assert.Equal(t, "Votre code est "+lastCode(t, provider), provider.Sent()[0].Body)
+}
+
+func TestVerifyCodeLimitsAttempts(t *testing.T) {
+	s, provider, user := setup(t)
+	ctx := context.Background()
+
+	_, err := s.SendCode(ctx, user, "+447700900123", models.PhoneVerificationSignup)
+	require.NoError(t, err)
+	code := lastCode(t, provider)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, s.VerifyCode(ctx, user, "+447700900123", wrong), ErrInvalidCode)
+	}
+	assert.ErrorIs(t, s.VerifyCode(ctx, user, "+447700900123", code), ErrTooManyAttempts)
+	assert.Nil(t, user.PhoneVerifiedAt)
+}
+
+func TestVerifyCodeExpires(t *testing.T) {
+	s, provider, user := setup(t)
+	ctx := context.Background()
+
+	_, err := s.SendCode(ctx, user, "+447700900123", models.PhoneVerificationSignup)
+	require.NoError(t, err)
+
+	s.now = func() time.Time { return testNow.Add(11 * time.Minute) }
+	assert.ErrorIs(t, s.VerifyCode(ctx, user, "+447700900123", lastCode(t, provider)), ErrInvalidCode)
+}
+
+func TestSendCodeThrottles(t *testing.T) {
+	s, _, user := setup(t)
+	ctx := context.Background()
+
+	_, err := s.SendCode(ctx, user, "+447700900123", models.PhoneVerificationSignup)
+	require.NoError(t, err)
+	_, err = s.SendCode(ctx, user, "+447700900123", models.PhoneVerificationSignup)
+	assert.ErrorIs(t, err, ErrResendTooSoon)
+
+	for i := 1; i < 3; i++ {
+		s.now = func() time.Time { return testNow.Add(time.Duration(i) * time.Minute) }
+		_, err = s.SendCode(ctx, user, "+447700900123", models.PhoneVerificationSignup)
+		require.NoError(t, err)
+	}
+	s.now = func() time.Time { return testNow.Add(5 * time.Minute) }
+	_, err = s.SendCode(ctx, user, "+447700900999", models.PhoneVerificationSignup)
+	assert.ErrorIs(t, err, ErrTooManyCodes, "the hourly cap also counts codes the user sent to other numbers")
+
+	s.now = func() time.Time { return testNow.Add(time.Hour + time.Minute) }
+	_, err = s.SendCode(ctx, user, "+447700900123", models.PhoneVerificationSignup)
+	assert.NoError(t, err)
+}
+
+func TestSendLogsFailures(t *testing.T) {
+	s, _, user := setup(t)
+	s.provider = failingProvider{}
+
+	_, err := s.SendCode(context.Background(), user, "+447700900123", models.PhoneVerificationSignup)
+	require.Error(t, err)
+
+	var logged models.SMSMessage
+	require.NoError(t, s.db.First(&logged).Error)
+	assert.Equal(t, models.SMSStatusFailed, logged.Status)
+	assert.Equal(t, assert.AnError.Error(), logged.Error)
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) Send(context.Context, string, string, string) (string, error) {
+	return "", assert.AnError
+}
@@ -0,0 +1,80 @@
+// Package sms sends text messages by SMS or WhatsApp: a provider abstraction
+// with a Twilio implementation, templates rendered in the recipient's locale,
+// a delivery log of every message sent with its delivery status, one-time
+// codes verifying phone numbers, and order updates.
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+)
+
+// ErrChannelUnsupported is returned by providers that cannot send messages
+// on a channel
+var ErrChannelUnsupported = errors.New("channel is not supported by the text message provider")
+
+// SMSProvider hands text messages to a carrier
+type SMSProvider interface {
+	// Name identifies the provider in the delivery log
+	Name() string
+	// Send sends body on channel, models.ChannelSMS or models.ChannelWhatsApp,
+	// to a phone number in E.164 format and returns the provider's ID for
+	// the message
+	Send(ctx context.Context, channel, to, body string) (string, error)
+}
+
+// NewProvider returns the provider configured in config, falling back to the
+// mock provider when it cannot be set up
+func NewProvider(config *cfg.SMSConfig) SMSProvider {
+	if config != nil && config.Provider == "twilio" {
+		provider, err := NewTwilioProvider(config)
+		if err == nil {
+			return provider
+		}
+		log.Printf("Failed to set up Twilio, text messages will only be logged: %v", err)
+	}
+	return NewMockSMSProvider()
+}
+
+// MockSentSMS is a message sent through the mock provider
+type MockSentSMS struct {
+	Channel string
+	To      string
+	Body    string
+}
+
+// MockSMSProvider implements SMSProvider for testing and development by
+// logging messages instead of sending them
+type MockSMSProvider struct {
+	mu   sync.Mutex
+	sent []MockSentSMS
+}
+
+// NewMockSMSProvider creates a new mock SMS provider
+func NewMockSMSProvider() *MockSMSProvider {
+	return &MockSMSProvider{}
+}
+
+func (p *MockSMSProvider) Name() string { return "mock" }
+
+// Send records the message (mock implementation)
+func (p *MockSMSProvider) Send(_ context.Context, channel, to, body string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, MockSentSMS{Channel: channel, To: to, Body: body})
+	fmt.Printf("MOCK: Sending %s message to %s\n", channel, to)
+	return "mock-" + strconv.Itoa(len(p.sent)), nil
+}
+
+// Sent returns the messages sent so far
+func (p *MockSMSProvider) Sent() []MockSentSMS {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MockSentSMS(nil), p.sent...)
+}
@@ -0,0 +1,124 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidPhone = errors.New("phone number must be in international format, such as +447700900123")
+
+// e164 matches phone numbers in E.164 format
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// NormalizePhone returns a phone number in E.164 format, leaving out spaces,
+// dashes, dots and brackets and reading a leading 00 as +
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	if !e164.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	return phone, nil
+}
+
+// Message is a text message to send from a template
+type Message struct {
+	Channel  string // models.ChannelSMS, the default, or models.ChannelWhatsApp
+	To       string
+	UserID   *uint
+	Template string
+	Locale   string
+	Data     map[string]interface{}
+	// Secret, such as a verification code, is masked in the delivery log
+	Secret string
+}
+
+// Service sends text messages and records them in the delivery log
+type Service struct {
+	db        *gorm.DB
+	provider  SMSProvider
+	templates *Templates
+	config    cfg.SMSConfig
+	now       func() time.Time
+}
+
+func NewService(db *gorm.DB, provider SMSProvider, config *cfg.SMSConfig) *Service {
+	s := &Service{db: db, provider: provider, now: time.Now}
+	if config != nil {
+		s.config = *config
+	}
+	if s.config.TemplateDir == "" {
+		s.config.TemplateDir = "templates/sms"
+	}
+	if s.config.OTPExpiryMinutes <= 0 {
+		s.config.OTPExpiryMinutes = 10
+	}
+	if s.config.OTPMaxAttempts <= 0 {
+		s.config.OTPMaxAttempts = 5
+	}
+	if s.config.OTPPerHour <= 0 {
+		s.config.OTPPerHour = 5
+	}
+	s.templates = NewTemplates(s.config.TemplateDir)
+	return s
+}
+
+// Send renders and sends a message. It is recorded in the delivery log
+// whether or not the provider accepts it.
+func (s *Service) Send(ctx context.Context, message Message) (*models.SMSMessage, error) {
+	to, err := NormalizePhone(message.To)
+	if err != nil {
+		return nil, err
+	}
+	channel := message.Channel
+	if channel == "" {
+		channel = models.ChannelSMS
+	}
+	body, err := s.templates.Render(channel, message.Template, message.Locale, message.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.SMSMessage{
+		CreatedAt: s.now(),
+		Channel:   channel,
+		UserID:    message.UserID,
+		To:        to,
+		Template:  message.Template,
+		Locale:    message.Locale,
+		Body:      body,
+		Provider:  s.provider.Name(),
+		Status:    models.SMSStatusSent,
+	}
+	if message.Secret != "" {
+		record.Body = strings.ReplaceAll(body, message.Secret, strings.Repeat("*", len(message.Secret)))
+	}
+	id, sendErr := s.provider.Send(ctx, channel, to, body)
+	record.ProviderID = id
+	if sendErr != nil {
+		record.Status = models.SMSStatusFailed
+		record.Error = sendErr.Error()
+	}
+	if err := s.db.WithContext(ctx).Create(&record).Error; err != nil {
+		return nil, err
+	}
+	if sendErr != nil {
+		return &record, sendErr
+	}
+	return &record, nil
+}
@@ -0,0 +1,68 @@
+package sms
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownMessage is returned for delivery reports of messages not in the
+// delivery log
+var ErrUnknownMessage = errors.New("no text message with this provider ID")
+
+// statusRanks orders delivery statuses, so a report arriving late never takes
+// a message back to an earlier status
+var statusRanks = map[models.SMSStatus]int{
+	models.SMSStatusSent:        1,
+	models.SMSStatusDelivered:   2,
+	models.SMSStatusUndelivered: 2,
+	models.SMSStatusFailed:      2,
+	models.SMSStatusRead:        3,
+}
+
+// twilioStatuses maps Twilio's message statuses to the delivery log's
+var twilioStatuses = map[string]models.SMSStatus{
+	"queued":      models.SMSStatusSent,
+	"accepted":    models.SMSStatusSent,
+	"sending":     models.SMSStatusSent,
+	"sent":        models.SMSStatusSent,
+	"delivered":   models.SMSStatusDelivered,
+	"read":        models.SMSStatusRead,
+	"undelivered": models.SMSStatusUndelivered,
+	"failed":      models.SMSStatusFailed,
+}
+
+// ValidStatusCallback reports whether a delivery report posted with params
+// and signature came from Twilio
+func (s *Service) ValidStatusCallback(params url.Values, signature string) bool {
+	return ValidTwilioSignature(s.config.TwilioAuthToken, s.config.StatusCallbackURL, params, signature)
+}
+
+// UpdateStatus records a delivery report of the message the provider knows
+// as providerID. Unknown statuses and reports older than the message's
+// status are ignored.
+func (s *Service) UpdateStatus(providerID, providerStatus, errorDetail string) (*models.SMSMessage, error) {
+	var message models.SMSMessage
+	err := s.db.Where("provider_id = ?", providerID).First(&message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownMessage
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status, ok := twilioStatuses[providerStatus]
+	if !ok || statusRanks[status] <= statusRanks[message.Status] {
+		return &message, nil
+	}
+	updates := map[string]interface{}{"status": status}
+	if errorDetail != "" {
+		updates["error"] = errorDetail
+	}
+	if err := s.db.Model(&message).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
@@ -0,0 +1,84 @@
+package sms
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/YasserCherfaoui/MarketProGo/i18n"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+)
+
+// Templates renders text messages from the .txt templates of a directory.
+// Templates in English are at the top of the directory, their translations
+// in a subdirectory per locale, such as fr/otp.txt. A channel can override
+// them in a subdirectory of its own laid out the same way, such as
+// whatsapp/fr/order_shipped.txt.
+type Templates struct {
+	dir   string
+	mu    sync.Mutex
+	cache map[string]*template.Template
+}
+
+// NewTemplates creates a template renderer for the templates in dir
+func NewTemplates(dir string) *Templates {
+	return &Templates{dir: dir, cache: map[string]*template.Template{}}
+}
+
+// Render renders template name for channel in locale, or in English when it
+// has no translation into locale. Settings such as CompanyName are added to
+// data.
+func (t *Templates) Render(channel, name, locale string, data map[string]interface{}) (string, error) {
+	tmpl, err := t.load(channel, name, locale)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, settings.WithTemplateData(data)); err != nil {
+		return "", fmt.Errorf("failed to render text message template %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// load returns the template name for channel in locale, parsing it the
+// first time
+func (t *Templates) load(channel, name, locale string) (*template.Template, error) {
+	dirs := []string{t.dir}
+	if channel != "" && channel != models.ChannelSMS {
+		dirs = append([]string{filepath.Join(t.dir, channel)}, dirs...)
+	}
+	var paths []string
+	for _, dir := range dirs {
+		if locale != "" && locale != i18n.Default {
+			paths = append(paths, filepath.Join(dir, locale, name+".txt"))
+		}
+		paths = append(paths, filepath.Join(dir, name+".txt"))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, path := range paths {
+		if tmpl, ok := t.cache[path]; ok {
+			return tmpl, nil
+		}
+		source, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read text message template %s: %w", path, err)
+		}
+		tmpl, err := template.New(name).Parse(string(source))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse text message template %s: %w", path, err)
+		}
+		t.cache[path] = tmpl
+		return tmpl, nil
+	}
+	return nil, fmt.Errorf("no text message template %s", name)
+}
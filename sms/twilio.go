@@ -0,0 +1,126 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// TwilioProvider implements SMSProvider with the Twilio Messages API
+type TwilioProvider struct {
+	accountSID     string
+	authToken      string
+	from           string
+	whatsappFrom   string
+	statusCallback string
+	baseURL        string
+	httpClient     *http.Client
+}
+
+// NewTwilioProvider creates a Twilio SMS provider
+func NewTwilioProvider(config *cfg.SMSConfig) (*TwilioProvider, error) {
+	if config.TwilioAccountSID == "" || config.TwilioAuthToken == "" || config.TwilioFrom == "" {
+		return nil, fmt.Errorf("missing required Twilio configuration: TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM are required")
+	}
+	return &TwilioProvider{
+		accountSID:     config.TwilioAccountSID,
+		authToken:      config.TwilioAuthToken,
+		from:           config.TwilioFrom,
+		whatsappFrom:   config.TwilioWhatsAppFrom,
+		statusCallback: config.StatusCallbackURL,
+		baseURL:        "https://api.twilio.com",
+		httpClient:     &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+func (p *TwilioProvider) Name() string { return "twilio" }
+
+// twilioMessage is the part of Twilio's message and error responses used here
+type twilioMessage struct {
+	SID     string `json:"sid"`
+	Status  string `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send sends a text message through Twilio. WhatsApp messages are sent from
+// the WhatsApp sender number.
+func (p *TwilioProvider) Send(ctx context.Context, channel, to, body string) (string, error) {
+	form := url.Values{"To": {to}, "Body": {body}}
+	switch {
+	case channel == models.ChannelWhatsApp:
+		if p.whatsappFrom == "" {
+			return "", ErrChannelUnsupported
+		}
+		form.Set("To", "whatsapp:"+to)
+		form.Set("From", "whatsapp:"+p.whatsappFrom)
+	case strings.HasPrefix(p.from, "MG"):
+		form.Set("MessagingServiceSid", p.from)
+	default:
+		form.Set("From", p.from)
+	}
+	if p.statusCallback != "" {
+		form.Set("StatusCallback", p.statusCallback)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.baseURL, p.accountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(p.accountSID, p.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var message twilioMessage
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return "", fmt.Errorf("Twilio returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Twilio returned status %d: error %d: %s", resp.StatusCode, message.Code, message.Message)
+	}
+	return message.SID, nil
+}
+
+// ValidTwilioSignature reports whether signature, the X-Twilio-Signature
+// header of a webhook posted to callbackURL with params, was made with
+// authToken
+func ValidTwilioSignature(authToken, callbackURL string, params url.Values, signature string) bool {
+	if authToken == "" || signature == "" {
+		return false
+	}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var payload strings.Builder
+	payload.WriteString(callbackURL)
+	for _, key := range keys {
+		for _, value := range params[key] {
+			payload.WriteString(key)
+			payload.WriteString(value)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(payload.String()))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
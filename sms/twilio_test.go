@@ -0,0 +1,113 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwilioSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "token", pass)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "+447700900123", r.PostForm.Get("To"))
+		assert.Equal(t, "+447700900000", r.PostForm.Get("From"))
+		assert.Equal(t, "hello", r.PostForm.Get("Body"))
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid": "SM1", "status": "queued"}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewTwilioProvider(&cfg.SMSConfig{TwilioAccountSID: "AC123", TwilioAuthToken: "token", TwilioFrom: "+447700900000"})
+	require.NoError(t, err)
+	provider.baseURL = server.URL
+
+	id, err := provider.Send(context.Background(), models.ChannelSMS, "+447700900123", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "SM1", id)
+}
+
+func TestTwilioSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code": 21211, "message": "Invalid 'To' Phone Number"}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewTwilioProvider(&cfg.SMSConfig{TwilioAccountSID: "AC123", TwilioAuthToken: "token", TwilioFrom: "MG123"})
+	require.NoError(t, err)
+	provider.baseURL = server.URL
+
+	_, err = provider.Send(context.Background(), models.ChannelSMS, "+447700900123", "hello")
+	assert.ErrorContains(t, err, "21211")
+}
+
+func TestNewProviderFallsBackToMock(t *testing.T) {
+	assert.Equal(t, "mock", NewProvider(&cfg.SMSConfig{Provider: "twilio"}).Name())
+	assert.Equal(t, "mock", NewProvider(nil).Name())
+}
+
+func TestTwilioSendWhatsApp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "whatsapp:+447700900123", r.PostForm.Get("To"))
+		assert.Equal(t, "whatsapp:+447700900001", r.PostForm.Get("From"))
+		assert.Equal(t, "https://api.example.com/sms/webhooks/twilio", r.PostForm.Get("StatusCallback"))
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid": "SM2", "status": "queued"}`))
+	}))
+	defer server.Close()
+
+	config := &cfg.SMSConfig{
+		TwilioAccountSID:  "AC123",
+		TwilioAuthToken:   "token",
+		TwilioFrom:        "+447700900000",
+		StatusCallbackURL: "https://api.example.com/sms/webhooks/twilio",
+	}
+	provider, err := NewTwilioProvider(config)
+	require.NoError(t, err)
+	provider.baseURL = server.URL
+
+	_, err = provider.Send(context.Background(), models.ChannelWhatsApp, "+447700900123", "hello")
+	assert.ErrorIs(t, err, ErrChannelUnsupported, "WhatsApp is off without a WhatsApp sender")
+
+	config.TwilioWhatsAppFrom = "+447700900001"
+	provider, err = NewTwilioProvider(config)
+	require.NoError(t, err)
+	provider.baseURL = server.URL
+
+	id, err := provider.Send(context.Background(), models.ChannelWhatsApp, "+447700900123", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "SM2", id)
+}
+
+func TestValidTwilioSignature(t *testing.T) {
+	// Example from Twilio's webhook security documentation
+	callbackURL := "https://mycompany.com/myapp.php?foo=1&bar=2"
+	params := url.Values{
+		"CallSid": {"CA1234567890ABCDE"},
+		"Caller":  {"+12349013030"},
+		"Digits":  {"1234"},
+		"From":    {"+12349013030"},
+		"To":      {"+18005551212"},
+	}
+	signature := "0/KCTR6DLpKmkAf8muzZqo1nDgQ="
+
+	assert.True(t, ValidTwilioSignature("12345", callbackURL, params, signature))
+	assert.False(t, ValidTwilioSignature("other", callbackURL, params, signature))
+	assert.False(t, ValidTwilioSignature("12345", callbackURL+"&baz=3", params, signature))
+	assert.False(t, ValidTwilioSignature("12345", callbackURL, params, ""))
+}
@@ -0,0 +1,124 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stock movement types recorded for adjustments
+const (
+	MovementAdjustmentIn  = "adjustment_in"
+	MovementAdjustmentOut = "adjustment_out"
+)
+
+// ErrNegativeNewBatch is returned when an adjustment would create a batch
+// with a negative quantity
+var ErrNegativeNewBatch = errors.New("cannot create new inventory item with negative quantity")
+
+// InsufficientStockError is returned when an adjustment removes more than a
+// batch holds
+type InsufficientStockError struct {
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("Insufficient stock. Available: %d", e.Available)
+}
+
+// Adjustment adds to or removes from the stock of a batch of a variant in a
+// warehouse. The batch is created when it does not exist yet.
+type Adjustment struct {
+	VariantID   uint
+	WarehouseID uint
+	Quantity    int // negative to remove stock
+	BatchNumber string
+	ExpiryDate  *time.Time // for a new batch
+	Reason      string
+	Notes       string
+	Reference   string
+	UserID      *uint
+}
+
+// Adjust applies an adjustment, records it as a stock movement and updates
+// the variant's stock total. It returns the batch and its quantity before.
+// Taking a batch down to the low stock threshold publishes an inventory.low
+// webhook event. The variant and warehouse are expected to exist.
+func (s *Service) Adjust(ctx context.Context, adjustment Adjustment) (*models.InventoryItem, int, error) {
+	var item models.InventoryItem
+	previousQuantity := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_variant_id = ? AND warehouse_id = ? AND batch_number = ?",
+				adjustment.VariantID, adjustment.WarehouseID, adjustment.BatchNumber).
+			First(&item).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if adjustment.Quantity < 0 {
+				return ErrNegativeNewBatch
+			}
+			item = models.InventoryItem{
+				ProductVariantID: adjustment.VariantID,
+				WarehouseID:      adjustment.WarehouseID,
+				Quantity:         adjustment.Quantity,
+				BatchNumber:      adjustment.BatchNumber,
+				ExpiryDate:       adjustment.ExpiryDate,
+				Status:           StatusActive,
+			}
+			if err := tx.Omit("ProductVariant", "Warehouse").Create(&item).Error; err != nil {
+				return fmt.Errorf("failed to create inventory item: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to get inventory item: %w", err)
+		default:
+			updateErr := AddQuantity(tx, item.ID, adjustment.Quantity)
+			if updateErr != nil && !errors.Is(updateErr, ErrNotEnoughStock) {
+				return fmt.Errorf("failed to update inventory item: %w", updateErr)
+			}
+			// Read the batch back for its quantity with this change
+			if err := tx.First(&item, item.ID).Error; err != nil {
+				return fmt.Errorf("failed to get inventory item: %w", err)
+			}
+			if updateErr != nil {
+				return &InsufficientStockError{Available: item.Quantity}
+			}
+			previousQuantity = item.Quantity - adjustment.Quantity
+		}
+
+		if adjustment.Quantity != 0 {
+			movement := models.StockMovement{
+				InventoryItemID: item.ID,
+				MovementType:    MovementAdjustmentIn,
+				Quantity:        adjustment.Quantity,
+				Reason:          adjustment.Reason,
+				Notes:           adjustment.Notes,
+				Reference:       adjustment.Reference,
+				UserID:          adjustment.UserID,
+			}
+			if adjustment.Quantity < 0 {
+				movement.MovementType = MovementAdjustmentOut
+				movement.Quantity = -adjustment.Quantity
+			}
+			if err := tx.Create(&movement).Error; err != nil {
+				return fmt.Errorf("failed to record stock movement: %w", err)
+			}
+		}
+
+		if previousQuantity > LowStockThreshold && item.Quantity <= LowStockThreshold {
+			if err := webhook.Publish(tx, webhook.EventInventoryLow, webhook.InventoryLowData(&item, LowStockThreshold)); err != nil {
+				return fmt.Errorf("failed to record stock event: %w", err)
+			}
+		}
+		return SyncVariantStock(tx, adjustment.VariantID)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return &item, previousQuantity, nil
+}
@@ -0,0 +1,58 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjust(t *testing.T) {
+	db := setupTestDB(t)
+	items := createBatches(t, db, 20, nil)
+	service := NewService(db)
+	ctx := context.Background()
+	adjustment := Adjustment{
+		VariantID:   items[0].ProductVariantID,
+		WarehouseID: items[0].WarehouseID,
+		BatchNumber: items[0].BatchNumber,
+		Quantity:    -5,
+		Reason:      "Damaged in transit",
+		Reference:   "WMS-881",
+	}
+
+	item, previous, err := service.Adjust(ctx, adjustment)
+	require.NoError(t, err)
+	assert.Equal(t, items[0].ID, item.ID)
+	assert.Equal(t, 20, previous)
+	assert.Equal(t, 15, item.Quantity)
+	assert.Equal(t, 15, variantStock(t, db, adjustment.VariantID))
+
+	var movement models.StockMovement
+	require.NoError(t, db.Where("inventory_item_id = ?", item.ID).First(&movement).Error)
+	assert.Equal(t, MovementAdjustmentOut, movement.MovementType)
+	assert.Equal(t, 5, movement.Quantity)
+	assert.Equal(t, "WMS-881", movement.Reference)
+
+	// Removing more than the batch holds fails
+	adjustment.Quantity = -16
+	_, _, err = service.Adjust(ctx, adjustment)
+	var insufficient *InsufficientStockError
+	require.True(t, errors.As(err, &insufficient))
+	assert.Equal(t, 15, insufficient.Available)
+
+	// A new batch is created, but not with a negative quantity
+	adjustment.BatchNumber = "NEW"
+	_, _, err = service.Adjust(ctx, adjustment)
+	assert.ErrorIs(t, err, ErrNegativeNewBatch)
+
+	adjustment.Quantity = 12
+	item, previous, err = service.Adjust(ctx, adjustment)
+	require.NoError(t, err)
+	assert.NotEqual(t, items[0].ID, item.ID)
+	assert.Equal(t, 0, previous)
+	assert.Equal(t, 27, variantStock(t, db, adjustment.VariantID))
+}
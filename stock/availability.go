@@ -0,0 +1,120 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// LowStockThreshold is the available quantity at or below which a variant is
+// shown as low on stock
+const LowStockThreshold = 10
+
+// Availability statuses
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityLowStock   = "low_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+// VariantAvailability is the stock of a variant that can be sold, summed
+// across warehouses so none of their details are exposed
+type VariantAvailability struct {
+	VariantID uint   `json:"variant_id"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Available int    `json:"available"`
+	Status    string `json:"status"` // in_stock, low_stock or out_of_stock
+}
+
+// Availability returns the availability of each active variant of a product:
+// the quantity not reserved of its active, unexpired batches in active
+// warehouses, or for a bundle what that of its components makes up.
+// warehouseIDs limits it to those warehouses; empty counts all.
+func (s *Service) Availability(ctx context.Context, productID uint, warehouseIDs []uint) ([]VariantAvailability, error) {
+	db := s.db.WithContext(ctx)
+	var variants []models.ProductVariant
+	if err := db.Select("id", "sku", "name").
+		Where("product_id = ? AND is_active = ?", productID, true).
+		Order("id").Find(&variants).Error; err != nil {
+		return nil, fmt.Errorf("failed to load variants: %w", err)
+	}
+	return s.availabilityOf(ctx, variants, warehouseIDs)
+}
+
+// VariantsAvailability is Availability for the given variants, of any
+// products, in order of ID. Variants that do not exist or are not active are
+// left out.
+func (s *Service) VariantsAvailability(ctx context.Context, variantIDs []uint, warehouseIDs []uint) ([]VariantAvailability, error) {
+	var variants []models.ProductVariant
+	if len(variantIDs) > 0 {
+		if err := s.db.WithContext(ctx).Select("id", "sku", "name").
+			Where("id IN ? AND is_active = ?", variantIDs, true).
+			Order("id").Find(&variants).Error; err != nil {
+			return nil, fmt.Errorf("failed to load variants: %w", err)
+		}
+	}
+	return s.availabilityOf(ctx, variants, warehouseIDs)
+}
+
+// availabilityOf sums the available stock of variants loaded with their ID,
+// SKU and name
+func (s *Service) availabilityOf(ctx context.Context, variants []models.ProductVariant, warehouseIDs []uint) ([]VariantAvailability, error) {
+	db := s.db.WithContext(ctx)
+	availability := make([]VariantAvailability, len(variants))
+	if len(variants) == 0 {
+		return availability, nil
+	}
+
+	variantIDs := make([]uint, len(variants))
+	for i, v := range variants {
+		variantIDs[i] = v.ID
+	}
+	bundles, err := BundleComponents(db, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, components := range bundles {
+		for _, component := range components {
+			variantIDs = append(variantIDs, component.ComponentVariantID)
+		}
+	}
+	query := db.Table("inventory_items").
+		Select("inventory_items.product_variant_id AS variant_id, SUM(inventory_items.quantity - inventory_items.reserved) AS available").
+		Joins("JOIN warehouses ON warehouses.id = inventory_items.warehouse_id AND warehouses.is_active = ? AND warehouses.deleted_at IS NULL", true).
+		Where("inventory_items.product_variant_id IN ? AND inventory_items.deleted_at IS NULL", variantIDs).
+		Where("inventory_items.status = ? AND inventory_items.quantity > inventory_items.reserved", StatusActive).
+		Where("(inventory_items.expiry_date IS NULL OR inventory_items.expiry_date > ?)", s.now()).
+		Group("inventory_items.product_variant_id")
+	if len(warehouseIDs) > 0 {
+		query = query.Where("inventory_items.warehouse_id IN ?", warehouseIDs)
+	}
+	var totals []struct {
+		VariantID uint
+		Available int
+	}
+	if err := query.Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum available stock: %w", err)
+	}
+	byVariant := make(map[uint]int, len(totals))
+	for _, t := range totals {
+		byVariant[t.VariantID] = t.Available
+	}
+
+	for i, v := range variants {
+		available := byVariant[v.ID]
+		if components, ok := bundles[v.ID]; ok {
+			available = BundleAvailable(components, byVariant)
+		}
+		status := AvailabilityInStock
+		switch {
+		case available <= 0:
+			status = AvailabilityOutOfStock
+		case available <= LowStockThreshold:
+			status = AvailabilityLowStock
+		}
+		availability[i] = VariantAvailability{VariantID: v.ID, SKU: v.SKU, Name: v.Name, Available: available, Status: status}
+	}
+	return availability, nil
+}
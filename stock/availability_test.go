@@ -0,0 +1,87 @@
+package stock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailability(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	yesterday, nextWeek := now.AddDate(0, 0, -1), now.AddDate(0, 0, 7)
+	items := createBatches(t, db, 10, &yesterday, &nextWeek, nil)
+	require.NoError(t, db.Model(&items[1]).Update("reserved", 4).Error)
+	variantID := items[0].ProductVariantID
+
+	var variant models.ProductVariant
+	require.NoError(t, db.First(&variant, variantID).Error)
+	soldOut := models.ProductVariant{ProductID: variant.ProductID, Name: "1kg", SKU: "HAL-1000", IsActive: true}
+	require.NoError(t, db.Omit("Product").Create(&soldOut).Error)
+
+	// Stock in an inactive warehouse is not available
+	closed := models.Warehouse{Name: "Leeds Warehouse", Code: "WH-LDS", Address: models.Address{StreetAddress1: "2 Mill Lane", City: "Leeds", Country: "GB"}}
+	require.NoError(t, db.Create(&closed).Error)
+	require.NoError(t, db.Model(&closed).Update("is_active", false).Error)
+	require.NoError(t, db.Omit("ProductVariant", "Warehouse").Create(&models.InventoryItem{
+		ProductVariantID: soldOut.ID, WarehouseID: closed.ID, Quantity: 50, Status: StatusActive,
+	}).Error)
+
+	service := NewService(db)
+	service.now = func() time.Time { return now }
+	availability, err := service.Availability(context.Background(), variant.ProductID, nil)
+	require.NoError(t, err)
+	require.Len(t, availability, 2)
+	// The batch past its expiry date and the reserved units are left out
+	assert.Equal(t, VariantAvailability{VariantID: variantID, SKU: "HAL-250", Name: "250g", Available: 16, Status: AvailabilityInStock}, availability[0])
+	assert.Equal(t, 0, availability[1].Available)
+	assert.Equal(t, AvailabilityOutOfStock, availability[1].Status)
+
+	require.NoError(t, db.Model(&items[2]).Update("reserved", 8).Error)
+	availability, err = service.Availability(context.Background(), variant.ProductID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 8, availability[0].Available)
+	assert.Equal(t, AvailabilityLowStock, availability[0].Status)
+
+	availability, err = service.Availability(context.Background(), variant.ProductID, []uint{closed.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 0, availability[0].Available)
+
+	// By variant, unknown variants are left out
+	availability, err = service.VariantsAvailability(context.Background(), []uint{soldOut.ID, variantID, 9999}, nil)
+	require.NoError(t, err)
+	require.Len(t, availability, 2)
+	assert.Equal(t, variantID, availability[0].VariantID)
+	assert.Equal(t, 8, availability[0].Available)
+	assert.Equal(t, soldOut.ID, availability[1].VariantID)
+	assert.Equal(t, AvailabilityOutOfStock, availability[1].Status)
+}
+
+func TestBundleAvailability(t *testing.T) {
+	db := setupTestDB(t)
+	items := createBatches(t, db, 10, nil)
+	require.NoError(t, db.Model(&items[0]).Update("reserved", 3).Error)
+	component := items[0].ProductVariantID
+
+	product := models.Product{Name: "Halloumi Kit", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	kit := models.ProductVariant{ProductID: product.ID, Name: "Kit", SKU: "HAL-KIT", IsActive: true}
+	require.NoError(t, db.Omit("Product").Create(&kit).Error)
+	require.NoError(t, db.Create(&models.BundleComponent{BundleVariantID: kit.ID, ComponentVariantID: component, Quantity: 3}).Error)
+
+	// 7 units are not reserved, enough for 2 kits
+	availability, err := NewService(db).Availability(context.Background(), product.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, availability, 1)
+	assert.Equal(t, 2, availability[0].Available)
+	assert.Equal(t, AvailabilityLowStock, availability[0].Status)
+
+	// The stock total counts reserved units too
+	require.NoError(t, SyncVariantStock(db, component))
+	assert.Equal(t, 3, variantStock(t, db, kit.ID))
+	assert.Equal(t, 0, BundleAvailable(nil, nil))
+}
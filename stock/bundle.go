@@ -0,0 +1,89 @@
+package stock
+
+import (
+	"fmt"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// BundleAvailable returns how many units of a bundle its components' stock
+// makes up: the fewest whole bundles any one component has enough stock for.
+// available is the stock of each component variant.
+func BundleAvailable(components []models.BundleComponent, available map[uint]int) int {
+	units := -1
+	for _, component := range components {
+		n := 0
+		if component.Quantity > 0 {
+			n = max(available[component.ComponentVariantID], 0) / component.Quantity
+		}
+		if units < 0 || n < units {
+			units = n
+		}
+	}
+	return max(units, 0)
+}
+
+// BundleComponents returns the components of those of variantIDs that are
+// bundles, by bundle variant
+func BundleComponents(tx *gorm.DB, variantIDs []uint) (map[uint][]models.BundleComponent, error) {
+	byBundle := make(map[uint][]models.BundleComponent)
+	if len(variantIDs) == 0 {
+		return byBundle, nil
+	}
+	var components []models.BundleComponent
+	if err := tx.Where("bundle_variant_id IN ?", variantIDs).Order("id").Find(&components).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bundle components: %w", err)
+	}
+	for _, component := range components {
+		byBundle[component.BundleVariantID] = append(byBundle[component.BundleVariantID], component)
+	}
+	return byBundle, nil
+}
+
+// SyncBundleStock sets the stock total of each of bundleIDs to what its
+// components' stock totals make up. Variants that are not bundles are left
+// as they are.
+func SyncBundleStock(tx *gorm.DB, bundleIDs ...uint) error {
+	byBundle, err := BundleComponents(tx, bundleIDs)
+	if err != nil || len(byBundle) == 0 {
+		return err
+	}
+	var componentIDs []uint
+	for _, components := range byBundle {
+		for _, component := range components {
+			componentIDs = append(componentIDs, component.ComponentVariantID)
+		}
+	}
+	var variants []models.ProductVariant
+	if err := tx.Select("id", "quantity_in_stock").Where("id IN ?", componentIDs).Find(&variants).Error; err != nil {
+		return fmt.Errorf("failed to load bundle components: %w", err)
+	}
+	totals := make(map[uint]int, len(variants))
+	for _, variant := range variants {
+		totals[variant.ID] = variant.QuantityInStock
+	}
+
+	for bundleID, components := range byBundle {
+		if err := tx.Model(&models.ProductVariant{Model: gorm.Model{ID: bundleID}}).
+			Update("quantity_in_stock", BundleAvailable(components, totals)).Error; err != nil {
+			return fmt.Errorf("failed to update bundle stock: %w", err)
+		}
+	}
+	return nil
+}
+
+// syncBundlesOf updates the stock totals of variantIDs that are bundles and of
+// the bundles any of them is a component of
+func syncBundlesOf(tx *gorm.DB, variantIDs []uint) error {
+	if len(variantIDs) == 0 {
+		return nil
+	}
+	var bundleIDs []uint
+	if err := tx.Model(&models.BundleComponent{}).
+		Where("component_variant_id IN ? OR bundle_variant_id IN ?", variantIDs, variantIDs).
+		Distinct().Pluck("bundle_variant_id", &bundleIDs).Error; err != nil {
+		return fmt.Errorf("failed to find bundles: %w", err)
+	}
+	return SyncBundleStock(tx, bundleIDs...)
+}
@@ -0,0 +1,397 @@
+package stock
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StocktakeReason is the reason of the adjustments posted by an approved count
+const StocktakeReason = "stocktake"
+
+var (
+	// ErrCountNotOpen is returned when changing a count that has been
+	// approved or cancelled
+	ErrCountNotOpen = errors.New("stock count is not open")
+	// ErrCountInProgress is returned when opening a count for a warehouse
+	// that already has an open one
+	ErrCountInProgress = errors.New("warehouse already has an open stock count")
+	// ErrInvalidCountEntry is returned for counts that cannot be matched to a
+	// batch or have an invalid quantity
+	ErrInvalidCountEntry = errors.New("invalid count entry")
+)
+
+// CountEntry is a counted quantity of a batch, identified by its inventory
+// item or by SKU and batch number
+type CountEntry struct {
+	InventoryItemID uint   `json:"inventory_item_id"`
+	SKU             string `json:"sku"`
+	BatchNumber     string `json:"batch_number"`
+	CountedQuantity int    `json:"counted_quantity"`
+}
+
+// CountSummary totals the lines of a count
+type CountSummary struct {
+	Lines        int `json:"lines"`
+	Counted      int `json:"counted"`
+	Uncounted    int `json:"uncounted"`
+	WithVariance int `json:"with_variance"`
+	NetVariance  int `json:"net_variance"`
+}
+
+// Summarize totals the lines of count
+func Summarize(count *models.StockCount) CountSummary {
+	summary := CountSummary{Lines: len(count.Lines)}
+	for _, line := range count.Lines {
+		if line.CountedQuantity == nil {
+			summary.Uncounted++
+			continue
+		}
+		summary.Counted++
+		if line.Variance != 0 {
+			summary.WithVariance++
+			summary.NetVariance += line.Variance
+		}
+	}
+	return summary
+}
+
+// OpenCount opens a count of a warehouse with a line for each of its active
+// or damaged batches
+func (s *Service) OpenCount(ctx context.Context, warehouseID uint, notes string, userID *uint) (*models.StockCount, error) {
+	count := &models.StockCount{WarehouseID: warehouseID, Status: models.StockCountStatusOpen, Notes: notes, OpenedByID: userID}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var warehouse models.Warehouse
+		if err := tx.First(&warehouse, warehouseID).Error; err != nil {
+			return err
+		}
+		var open int64
+		if err := tx.Model(&models.StockCount{}).
+			Where("warehouse_id = ? AND status = ?", warehouseID, models.StockCountStatusOpen).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrCountInProgress
+		}
+		if err := tx.Create(count).Error; err != nil {
+			return err
+		}
+
+		var items []models.InventoryItem
+		if err := tx.Where("warehouse_id = ? AND status <> ?", warehouseID, StatusExpired).Order("id").Find(&items).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			line := models.StockCountLine{
+				StockCountID:     count.ID,
+				InventoryItemID:  &item.ID,
+				ProductVariantID: item.ProductVariantID,
+				BatchNumber:      item.BatchNumber,
+				SystemQuantity:   item.Quantity,
+			}
+			if err := tx.Create(&line).Error; err != nil {
+				return err
+			}
+			count.Lines = append(count.Lines, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+// GetCount loads a count with its lines
+func (s *Service) GetCount(ctx context.Context, id uint) (*models.StockCount, error) {
+	var count models.StockCount
+	if err := s.db.WithContext(ctx).
+		Preload("Warehouse").
+		Preload("OpenedBy").
+		Preload("ApprovedBy").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.ProductVariant").
+		First(&count, id).Error; err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+// RecordCounts records counted quantities on an open count. Each line's
+// variance is taken against the quantity in stock when it is counted, so
+// stock that moves during the count is not counted twice. Counting a batch
+// again replaces its count.
+func (s *Service) RecordCounts(ctx context.Context, countID uint, entries []CountEntry, userID *uint) (*models.StockCount, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count models.StockCount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&count, countID).Error; err != nil {
+			return err
+		}
+		if count.Status != models.StockCountStatusOpen {
+			return ErrCountNotOpen
+		}
+
+		now := s.now()
+		for i, entry := range entries {
+			if entry.CountedQuantity < 0 {
+				return fmt.Errorf("%w: entry %d has a negative quantity", ErrInvalidCountEntry, i+1)
+			}
+			line, err := findCountLine(tx, &count, entry)
+			if err != nil {
+				return fmt.Errorf("entry %d: %w", i+1, err)
+			}
+
+			line.SystemQuantity = 0
+			if line.InventoryItemID != nil {
+				var item models.InventoryItem
+				if err := tx.Select("id", "quantity").First(&item, *line.InventoryItemID).Error; err != nil {
+					return err
+				}
+				line.SystemQuantity = item.Quantity
+			}
+			counted := entry.CountedQuantity
+			line.CountedQuantity = &counted
+			line.Variance = counted - line.SystemQuantity
+			line.CountedByID = userID
+			line.CountedAt = &now
+			if err := tx.Omit("ProductVariant").Save(line).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCount(ctx, countID)
+}
+
+// findCountLine returns the line of count that entry counts. A batch the
+// count does not have a line for gets one.
+func findCountLine(tx *gorm.DB, count *models.StockCount, entry CountEntry) (*models.StockCountLine, error) {
+	var line models.StockCountLine
+	if entry.InventoryItemID != 0 {
+		err := tx.Where("stock_count_id = ? AND inventory_item_id = ?", count.ID, entry.InventoryItemID).First(&line).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("%w: inventory item %d is not part of this count", ErrInvalidCountEntry, entry.InventoryItemID)
+		}
+		return &line, err
+	}
+
+	sku := strings.TrimSpace(entry.SKU)
+	if sku == "" {
+		return nil, fmt.Errorf("%w: an inventory item ID or SKU is required", ErrInvalidCountEntry)
+	}
+	var variant models.ProductVariant
+	if err := tx.Select("id").Where("sku = ?", sku).First(&variant).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("%w: unknown SKU %q", ErrInvalidCountEntry, sku)
+		}
+		return nil, err
+	}
+	batch := strings.TrimSpace(entry.BatchNumber)
+	err := tx.Where("stock_count_id = ? AND product_variant_id = ? AND batch_number = ?", count.ID, variant.ID, batch).First(&line).Error
+	if err != gorm.ErrRecordNotFound {
+		return &line, err
+	}
+
+	// Stock the system did not know about, or received since the count opened
+	line = models.StockCountLine{StockCountID: count.ID, ProductVariantID: variant.ID, BatchNumber: batch}
+	var item models.InventoryItem
+	err = tx.Where("warehouse_id = ? AND product_variant_id = ? AND batch_number = ? AND status <> ?", count.WarehouseID, variant.ID, batch, StatusExpired).First(&item).Error
+	if err == nil {
+		line.InventoryItemID = &item.ID
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err := tx.Create(&line).Error; err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// ParseCountCSV reads count entries from CSV with a header row. The columns
+// are sku, batch_number and counted_quantity, or inventory_item_id and
+// counted_quantity.
+func ParseCountCSV(r io.Reader) ([]CountEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidCountEntry)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	quantityColumn, ok := columns["counted_quantity"]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing counted_quantity column", ErrInvalidCountEntry)
+	}
+	_, hasSKU := columns["sku"]
+	_, hasItem := columns["inventory_item_id"]
+	if !hasSKU && !hasItem {
+		return nil, fmt.Errorf("%w: missing sku or inventory_item_id column", ErrInvalidCountEntry)
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []CountEntry
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidCountEntry, row, err)
+		}
+		if quantityColumn >= len(record) || strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		quantity, err := strconv.Atoi(field(record, "counted_quantity"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: invalid counted_quantity", ErrInvalidCountEntry, row)
+		}
+		entry := CountEntry{SKU: field(record, "sku"), BatchNumber: field(record, "batch_number"), CountedQuantity: quantity}
+		if id := field(record, "inventory_item_id"); id != "" {
+			parsed, err := strconv.ParseUint(id, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%w: row %d: invalid inventory_item_id", ErrInvalidCountEntry, row)
+			}
+			entry.InventoryItemID = uint(parsed)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no counts in file", ErrInvalidCountEntry)
+	}
+	return entries, nil
+}
+
+// ApproveCount closes an open count and posts each counted variance as a
+// stock adjustment with the reason "stocktake". Lines that were not counted
+// leave their stock unchanged.
+func (s *Service) ApproveCount(ctx context.Context, countID uint, userID *uint) (*models.StockCount, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count models.StockCount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+			First(&count, countID).Error; err != nil {
+			return err
+		}
+		if count.Status != models.StockCountStatusOpen {
+			return ErrCountNotOpen
+		}
+
+		reference := fmt.Sprintf("STOCKTAKE-%d", count.ID)
+		var variantIDs []uint
+		for _, line := range count.Lines {
+			if line.CountedQuantity == nil || line.Variance == 0 {
+				continue
+			}
+			if err := postVariance(tx, &count, &line, reference, userID); err != nil {
+				return err
+			}
+			variantIDs = append(variantIDs, line.ProductVariantID)
+		}
+
+		now := s.now()
+		if err := tx.Model(&count).Omit("Lines").Updates(map[string]interface{}{
+			"status":         models.StockCountStatusApproved,
+			"approved_by_id": userID,
+			"approved_at":    &now,
+		}).Error; err != nil {
+			return err
+		}
+		return SyncVariantStock(tx, variantIDs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCount(ctx, countID)
+}
+
+// postVariance adjusts the batch of line by its variance and records the
+// movement
+func postVariance(tx *gorm.DB, count *models.StockCount, line *models.StockCountLine, reference string, userID *uint) error {
+	var item models.InventoryItem
+	if line.InventoryItemID == nil {
+		item = models.InventoryItem{
+			ProductVariantID: line.ProductVariantID,
+			WarehouseID:      count.WarehouseID,
+			BatchNumber:      line.BatchNumber,
+			Status:           StatusActive,
+		}
+		if err := tx.Omit("ProductVariant", "Warehouse").Create(&item).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(line).Update("inventory_item_id", item.ID).Error; err != nil {
+			return err
+		}
+	} else if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, *line.InventoryItemID).Error; err != nil {
+		return err
+	}
+
+	quantity := max(item.Quantity+line.Variance, 0)
+	change := quantity - item.Quantity
+	if change == 0 {
+		return nil
+	}
+	if err := tx.Model(&item).Update("quantity", quantity).Error; err != nil {
+		return err
+	}
+
+	movementType := "adjustment_in"
+	if change < 0 {
+		movementType = "adjustment_out"
+		change = -change
+	}
+	return tx.Create(&models.StockMovement{
+		InventoryItemID: item.ID,
+		MovementType:    movementType,
+		Quantity:        change,
+		Reason:          StocktakeReason,
+		Notes:           fmt.Sprintf("Counted %d, system quantity %d", *line.CountedQuantity, line.SystemQuantity),
+		Reference:       reference,
+		UserID:          userID,
+	}).Error
+}
+
+// CancelCount closes an open count without changing stock
+func (s *Service) CancelCount(ctx context.Context, countID uint) (*models.StockCount, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count models.StockCount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&count, countID).Error; err != nil {
+			return err
+		}
+		if count.Status != models.StockCountStatusOpen {
+			return ErrCountNotOpen
+		}
+		now := s.now()
+		return tx.Model(&count).Updates(map[string]interface{}{
+			"status":       models.StockCountStatusCancelled,
+			"cancelled_at": &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCount(ctx, countID)
+}
@@ -0,0 +1,118 @@
+package stock
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStockCountWorkflow(t *testing.T) {
+	db := setupTestDB(t)
+	items := createBatches(t, db, 10, nil, nil)
+	service := NewService(db)
+	ctx := context.Background()
+	counter, approver := uint(7), uint(8)
+
+	count, err := service.OpenCount(ctx, items[0].WarehouseID, "Quarterly count", &counter)
+	require.NoError(t, err)
+	require.Len(t, count.Lines, 2)
+	assert.Equal(t, 10, count.Lines[0].SystemQuantity)
+
+	_, err = service.OpenCount(ctx, items[0].WarehouseID, "", nil)
+	assert.ErrorIs(t, err, ErrCountInProgress)
+
+	// Stock sold during the count is not counted as a loss
+	require.NoError(t, db.Model(&items[1]).Update("quantity", 9).Error)
+
+	count, err = service.RecordCounts(ctx, count.ID, []CountEntry{
+		{InventoryItemID: items[0].ID, CountedQuantity: 8},
+		{SKU: "HAL-250", BatchNumber: "B", CountedQuantity: 9},
+		{SKU: "HAL-250", BatchNumber: "Z", CountedQuantity: 3},
+	}, &counter)
+	require.NoError(t, err)
+	require.Len(t, count.Lines, 3)
+	assert.Equal(t, -2, count.Lines[0].Variance)
+	assert.Equal(t, 0, count.Lines[1].Variance)
+	assert.Nil(t, count.Lines[2].InventoryItemID)
+	assert.Equal(t, 3, count.Lines[2].Variance)
+	assert.Equal(t, counter, *count.Lines[0].CountedByID)
+	assert.Equal(t, CountSummary{Lines: 3, Counted: 3, WithVariance: 2, NetVariance: 1}, Summarize(count))
+
+	_, err = service.RecordCounts(ctx, count.ID, []CountEntry{{SKU: "NOPE", CountedQuantity: 1}}, &counter)
+	assert.ErrorIs(t, err, ErrInvalidCountEntry)
+
+	count, err = service.ApproveCount(ctx, count.ID, &approver)
+	require.NoError(t, err)
+	assert.Equal(t, models.StockCountStatusApproved, count.Status)
+	assert.Equal(t, approver, *count.ApprovedByID)
+	require.NotNil(t, count.Lines[2].InventoryItemID)
+
+	quantity := func(id uint) int {
+		var item models.InventoryItem
+		require.NoError(t, db.First(&item, id).Error)
+		return item.Quantity
+	}
+	assert.Equal(t, 8, quantity(items[0].ID))
+	assert.Equal(t, 9, quantity(items[1].ID))
+	assert.Equal(t, 3, quantity(*count.Lines[2].InventoryItemID))
+	assert.Equal(t, 20, variantStock(t, db, items[0].ProductVariantID))
+
+	var movements []models.StockMovement
+	require.NoError(t, db.Where("reason = ?", StocktakeReason).Order("id").Find(&movements).Error)
+	require.Len(t, movements, 2)
+	assert.Equal(t, "adjustment_out", movements[0].MovementType)
+	assert.Equal(t, 2, movements[0].Quantity)
+	assert.Equal(t, "adjustment_in", movements[1].MovementType)
+	assert.Equal(t, approver, *movements[1].UserID)
+
+	_, err = service.ApproveCount(ctx, count.ID, &approver)
+	assert.ErrorIs(t, err, ErrCountNotOpen)
+}
+
+func TestCancelStockCount(t *testing.T) {
+	db := setupTestDB(t)
+	items := createBatches(t, db, 10, nil)
+	service := NewService(db)
+	ctx := context.Background()
+
+	count, err := service.OpenCount(ctx, items[0].WarehouseID, "", nil)
+	require.NoError(t, err)
+	_, err = service.RecordCounts(ctx, count.ID, []CountEntry{{InventoryItemID: items[0].ID, CountedQuantity: 2}}, nil)
+	require.NoError(t, err)
+
+	count, err = service.CancelCount(ctx, count.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StockCountStatusCancelled, count.Status)
+
+	var item models.InventoryItem
+	require.NoError(t, db.First(&item, items[0].ID).Error)
+	assert.Equal(t, 10, item.Quantity)
+
+	// The warehouse can be counted again
+	_, err = service.OpenCount(ctx, items[0].WarehouseID, "", nil)
+	assert.NoError(t, err)
+}
+
+func TestParseCountCSV(t *testing.T) {
+	entries, err := ParseCountCSV(strings.NewReader("\ufeffSKU,Batch_Number,Counted_Quantity\nHAL-250,A,12\n\n  HAL-1KG , ,0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []CountEntry{
+		{SKU: "HAL-250", BatchNumber: "A", CountedQuantity: 12},
+		{SKU: "HAL-1KG", CountedQuantity: 0},
+	}, entries)
+
+	entries, err = ParseCountCSV(strings.NewReader("inventory_item_id,counted_quantity\n4,7\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []CountEntry{{InventoryItemID: 4, CountedQuantity: 7}}, entries)
+
+	_, err = ParseCountCSV(strings.NewReader("sku,quantity\nA,1\n"))
+	assert.ErrorIs(t, err, ErrInvalidCountEntry)
+	_, err = ParseCountCSV(strings.NewReader("sku,counted_quantity\nA,many\n"))
+	assert.ErrorContains(t, err, "row 2")
+	_, err = ParseCountCSV(strings.NewReader("sku,counted_quantity\n"))
+	assert.ErrorIs(t, err, ErrInvalidCountEntry)
+}
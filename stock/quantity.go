@@ -0,0 +1,84 @@
+package stock
+
+import (
+	"errors"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// The functions below change the stock of a batch with a single UPDATE whose
+// WHERE clause checks the batch holds enough, instead of reading the quantity
+// and saving it back. Concurrent changes add up rather than overwrite each
+// other, and none can take a batch below zero. Adjustments with AddQuantity
+// may leave less than the reserved stock, since a count can find less than
+// was reserved; taking or reserving stock with TakeUnreserved and Reserve
+// never touches the reserved stock. Shipping reserved stock with Ship fails
+// when a count left the batch holding less than the shipment.
+
+// ErrNotEnoughStock is returned when a batch does not hold enough stock, or
+// not enough unreserved stock, for a change
+var ErrNotEnoughStock = errors.New("batch does not hold enough stock")
+
+// AddQuantity adds delta, negative to take stock out, to the quantity of a
+// batch. Taking out more than the batch holds fails with ErrNotEnoughStock.
+func AddQuantity(tx *gorm.DB, itemID uint, delta int) error {
+	return guardedUpdate(tx.Where("quantity + ? >= 0", delta), itemID, map[string]interface{}{
+		"quantity": gorm.Expr("quantity + ?", delta),
+	})
+}
+
+// TakeUnreserved takes quantity out of a batch's stock that is not reserved
+// for orders, failing with ErrNotEnoughStock when there is less than that
+func TakeUnreserved(tx *gorm.DB, itemID uint, quantity int) error {
+	return guardedUpdate(tx.Where("quantity - reserved >= ?", quantity), itemID, map[string]interface{}{
+		"quantity": gorm.Expr("quantity - ?", quantity),
+	})
+}
+
+// Reserve reserves quantity of a batch's unreserved stock, failing with
+// ErrNotEnoughStock when there is less than that
+func Reserve(tx *gorm.DB, itemID uint, quantity int) error {
+	return guardedUpdate(tx.Where("quantity - reserved >= ?", quantity), itemID, map[string]interface{}{
+		"reserved": gorm.Expr("reserved + ?", quantity),
+	})
+}
+
+// Ship takes quantity of a batch's reserved stock out of the warehouse,
+// failing with ErrNotEnoughStock when the batch holds less than that: a count
+// may have found less than was reserved
+func Ship(tx *gorm.DB, itemID uint, quantity int) error {
+	return guardedUpdate(tx.Where("quantity >= ?", quantity), itemID, map[string]interface{}{
+		"quantity": gorm.Expr("quantity - ?", quantity),
+		"reserved": gorm.Expr("CASE WHEN reserved > ? THEN reserved - ? ELSE 0 END", quantity, quantity),
+	})
+}
+
+// Unreserve gives back quantity of a batch's reserved stock. Reserved stock
+// is not taken below zero.
+func Unreserve(tx *gorm.DB, itemID uint, quantity int) error {
+	return guardedUpdate(tx, itemID, map[string]interface{}{
+		"reserved": gorm.Expr("CASE WHEN reserved > ? THEN reserved - ? ELSE 0 END", quantity, quantity),
+	})
+}
+
+// guardedUpdate updates a batch when the conditions of tx hold, failing with
+// ErrNotEnoughStock when they do not and gorm.ErrRecordNotFound when there is
+// no such batch
+func guardedUpdate(tx *gorm.DB, itemID uint, updates map[string]interface{}) error {
+	result := tx.Model(&models.InventoryItem{}).Where("id = ?", itemID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(&models.InventoryItem{}).Where("id = ?", itemID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrNotEnoughStock
+}
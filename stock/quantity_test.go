@@ -0,0 +1,124 @@
+package stock
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func quantities(t *testing.T, db *gorm.DB, id uint) (int, int) {
+	var item models.InventoryItem
+	require.NoError(t, db.First(&item, id).Error)
+	return item.Quantity, item.Reserved
+}
+
+func TestGuardedUpdates(t *testing.T) {
+	db := setupTestDB(t)
+	item := createBatches(t, db, 10, nil)[0]
+
+	require.NoError(t, Reserve(db, item.ID, 4))
+	assert.ErrorIs(t, Reserve(db, item.ID, 7), ErrNotEnoughStock)
+
+	// Reserved stock cannot be taken, but an adjustment can go down to zero
+	assert.ErrorIs(t, TakeUnreserved(db, item.ID, 7), ErrNotEnoughStock)
+	require.NoError(t, TakeUnreserved(db, item.ID, 6))
+	require.NoError(t, AddQuantity(db, item.ID, 5))
+	assert.ErrorIs(t, AddQuantity(db, item.ID, -10), ErrNotEnoughStock)
+	require.NoError(t, AddQuantity(db, item.ID, -9))
+
+	quantity, reserved := quantities(t, db, item.ID)
+	assert.Equal(t, 0, quantity)
+	assert.Equal(t, 4, reserved)
+
+	// The count left less than was reserved, so the reservation cannot ship
+	// but can be given back
+	assert.ErrorIs(t, Ship(db, item.ID, 4), ErrNotEnoughStock)
+	require.NoError(t, AddQuantity(db, item.ID, 3))
+	assert.ErrorIs(t, Ship(db, item.ID, 4), ErrNotEnoughStock)
+	require.NoError(t, Ship(db, item.ID, 3))
+	require.NoError(t, Unreserve(db, item.ID, 2))
+	quantity, reserved = quantities(t, db, item.ID)
+	assert.Equal(t, 0, quantity)
+	assert.Equal(t, 0, reserved)
+
+	assert.ErrorIs(t, AddQuantity(db, item.ID+100, 1), gorm.ErrRecordNotFound, "a missing batch is not short of stock")
+	assert.ErrorIs(t, Unreserve(db, item.ID+100, 1), gorm.ErrRecordNotFound)
+}
+
+// openSharedTestDB opens a database on disk, shared by all the connections
+// of the pool, so that tests can change stock from several goroutines
+func openSharedTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, filepath.Join(t.TempDir(), "stock.db")+"?_busy_timeout=10000&_txlock=immediate")
+}
+
+func TestConcurrentStockChanges(t *testing.T) {
+	db := openSharedTestDB(t)
+	item := createBatches(t, db, 10, nil)[0]
+
+	var wg sync.WaitGroup
+	results := make(chan error, 25)
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- AddQuantity(db, item.ID, -1)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	taken := 0
+	for err := range results {
+		if err == nil {
+			taken++
+		} else {
+			assert.ErrorIs(t, err, ErrNotEnoughStock)
+		}
+	}
+	assert.Equal(t, 10, taken)
+	quantity, _ := quantities(t, db, item.ID)
+	assert.Equal(t, 0, quantity)
+}
+
+func TestConcurrentAdjustments(t *testing.T) {
+	db := openSharedTestDB(t)
+	item := createBatches(t, db, 50, nil)[0]
+	service := NewService(db)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := service.Adjust(context.Background(), Adjustment{
+				VariantID:   item.ProductVariantID,
+				WarehouseID: item.WarehouseID,
+				BatchNumber: item.BatchNumber,
+				Quantity:    -3,
+				Reason:      "Picked for market stall",
+			})
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		require.NoError(t, err)
+	}
+	// Every adjustment counts, none overwrites another
+	quantity, _ := quantities(t, db, item.ID)
+	assert.Equal(t, 20, quantity)
+	assert.Equal(t, 20, variantStock(t, db, item.ProductVariantID))
+
+	var movements int64
+	require.NoError(t, db.Model(&models.StockMovement{}).Where("inventory_item_id = ?", item.ID).Count(&movements).Error)
+	assert.EqualValues(t, 10, movements)
+}
@@ -0,0 +1,98 @@
+package stock
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+)
+
+// ExpiringFilter selects the batches of an expiry report
+type ExpiringFilter struct {
+	Days           int  // batches expiring within this many days, default 30
+	WarehouseID    uint // 0 for all warehouses
+	IncludeExpired bool // also list batches that have already expired
+}
+
+// ExpiringBatch is a line of the expiry report
+type ExpiringBatch struct {
+	InventoryItemID  uint       `json:"inventory_item_id"`
+	ProductVariantID uint       `json:"product_variant_id"`
+	ProductName      string     `json:"product_name"`
+	VariantName      string     `json:"variant_name"`
+	SKU              string     `json:"sku"`
+	WarehouseID      uint       `json:"warehouse_id"`
+	WarehouseName    string     `json:"warehouse_name"`
+	BatchNumber      string     `json:"batch_number"`
+	Quantity         int        `json:"quantity"`
+	Reserved         int        `json:"reserved"`
+	ExpiryDate       *time.Time `json:"expiry_date"`
+	DaysToExpiry     int        `json:"days_to_expiry"` // negative once expired
+	Status           string     `json:"status"`
+	Value            float64    `json:"value"` // quantity at cost price
+}
+
+// ExpiringReport lists the batches in stock that expire within the filter's
+// window, soonest first
+type ExpiringReport struct {
+	Batches          []ExpiringBatch `json:"batches"`
+	ExpiringQuantity int             `json:"expiring_quantity"`
+	ExpiringValue    float64         `json:"expiring_value"`
+	ExpiredQuantity  int             `json:"expired_quantity"`
+	ExpiredValue     float64         `json:"expired_value"`
+}
+
+// Expiring reports the batches that expire within filter.Days
+func (s *Service) Expiring(ctx context.Context, filter ExpiringFilter) (*ExpiringReport, error) {
+	if filter.Days <= 0 {
+		filter.Days = 30
+	}
+	now := s.now()
+
+	query := s.db.WithContext(ctx).
+		Preload("ProductVariant.Product").
+		Preload("Warehouse").
+		Where("expiry_date IS NOT NULL AND expiry_date <= ? AND quantity > 0", now.AddDate(0, 0, filter.Days))
+	if !filter.IncludeExpired {
+		query = query.Where("status <> ? AND expiry_date > ?", StatusExpired, now)
+	}
+	if filter.WarehouseID != 0 {
+		query = query.Where("warehouse_id = ?", filter.WarehouseID)
+	}
+	var items []models.InventoryItem
+	if err := query.Order("expiry_date ASC, id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	report := &ExpiringReport{Batches: make([]ExpiringBatch, 0, len(items))}
+	for _, item := range items {
+		batch := ExpiringBatch{
+			InventoryItemID:  item.ID,
+			ProductVariantID: item.ProductVariantID,
+			ProductName:      item.ProductVariant.Product.Name,
+			VariantName:      item.ProductVariant.Name,
+			SKU:              item.ProductVariant.SKU,
+			WarehouseID:      item.WarehouseID,
+			WarehouseName:    item.Warehouse.Name,
+			BatchNumber:      item.BatchNumber,
+			Quantity:         item.Quantity,
+			Reserved:         item.Reserved,
+			ExpiryDate:       item.ExpiryDate,
+			DaysToExpiry:     int(math.Floor(item.ExpiryDate.Sub(now).Hours() / 24)),
+			Status:           item.Status,
+			Value:            math.Round(float64(item.Quantity)*item.ProductVariant.CostPrice*100) / 100,
+		}
+		report.Batches = append(report.Batches, batch)
+		if item.Status == StatusExpired || !item.ExpiryDate.After(now) {
+			report.ExpiredQuantity += batch.Quantity
+			report.ExpiredValue += batch.Value
+		} else {
+			report.ExpiringQuantity += batch.Quantity
+			report.ExpiringValue += batch.Value
+		}
+	}
+	report.ExpiringValue = math.Round(report.ExpiringValue*100) / 100
+	report.ExpiredValue = math.Round(report.ExpiredValue*100) / 100
+	return report, nil
+}
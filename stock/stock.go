@@ -0,0 +1,225 @@
+// Package stock manages the lifecycle of inventory batches: batches past
+// their expiry date are marked expired and stop counting as available, and
+// expired or damaged stock is written off. Every change is recorded as a
+// stock movement.
+package stock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Inventory item statuses
+const (
+	StatusActive  = "active"
+	StatusExpired = "expired"
+	StatusDamaged = "damaged"
+)
+
+// Stock movement types recorded by the service
+const (
+	MovementExpired  = "expired"
+	MovementWriteOff = "write_off"
+)
+
+var (
+	// ErrInvalidQuantity is returned when writing off a negative quantity
+	ErrInvalidQuantity = errors.New("quantity must not be negative")
+	// ErrInsufficientStock is returned when writing off more than the
+	// unreserved quantity of a batch
+	ErrInsufficientStock = errors.New("cannot write off more than the unreserved quantity")
+	// ErrNothingToWriteOff is returned when a batch has no unreserved stock
+	ErrNothingToWriteOff = errors.New("batch has no unreserved stock to write off")
+)
+
+// Service expires and writes off stock
+type Service struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewService creates a stock service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db, now: time.Now}
+}
+
+// ExpireBatches marks active batches whose expiry date has passed as expired,
+// records an expired movement for each and updates the variants' stock
+// totals. It returns the number of batches expired.
+func (s *Service) ExpireBatches(ctx context.Context) (int, error) {
+	var expired int
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var items []models.InventoryItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND expiry_date IS NOT NULL AND expiry_date <= ?", StatusActive, s.now()).
+			Find(&items).Error; err != nil {
+			return err
+		}
+
+		variantIDs := make([]uint, 0, len(items))
+		for _, item := range items {
+			if err := tx.Model(&item).Update("status", StatusExpired).Error; err != nil {
+				return err
+			}
+			movement := models.StockMovement{
+				InventoryItemID: item.ID,
+				MovementType:    MovementExpired,
+				Quantity:        item.Quantity,
+				Reason:          "Batch expired",
+				Reference:       item.BatchNumber,
+			}
+			if err := tx.Create(&movement).Error; err != nil {
+				return err
+			}
+			variantIDs = append(variantIDs, item.ProductVariantID)
+		}
+		expired = len(items)
+		return SyncVariantStock(tx, variantIDs...)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire stock: %w", err)
+	}
+	return expired, nil
+}
+
+// StartExpiryWorker expires batches every interval until ctx is cancelled
+func (s *Service) StartExpiryWorker(ctx context.Context, interval time.Duration) {
+	log.Printf("🔄 STOCK: Starting batch expiry worker (interval: %s)", interval)
+	for {
+		if count, err := s.ExpireBatches(ctx); err != nil {
+			log.Printf("❌ STOCK: %v", err)
+		} else if count > 0 {
+			log.Printf("STOCK: Marked %d batches as expired", count)
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// WriteOff removes quantity of a batch from stock, 0 meaning all of its
+// unreserved stock, and records a write-off movement. Reserved stock belongs
+// to orders and cannot be written off.
+func (s *Service) WriteOff(ctx context.Context, inventoryItemID uint, quantity int, reason string, userID *uint) (*models.InventoryItem, error) {
+	if quantity < 0 {
+		return nil, ErrInvalidQuantity
+	}
+	var item models.InventoryItem
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, inventoryItemID).Error; err != nil {
+			return err
+		}
+		return writeOff(tx, &item, quantity, reason, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// WriteOffSummary reports a bulk write-off
+type WriteOffSummary struct {
+	Batches  int `json:"batches"`
+	Quantity int `json:"quantity"`
+}
+
+// WriteOffExpired writes off the unreserved stock of every expired batch, in
+// one warehouse or, when warehouseID is 0, in all of them
+func (s *Service) WriteOffExpired(ctx context.Context, warehouseID uint, reason string, userID *uint) (WriteOffSummary, error) {
+	var summary WriteOffSummary
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND quantity > reserved", StatusExpired)
+		if warehouseID != 0 {
+			query = query.Where("warehouse_id = ?", warehouseID)
+		}
+		var items []models.InventoryItem
+		if err := query.Order("id").Find(&items).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			quantity := items[i].Quantity - items[i].Reserved
+			if err := writeOff(tx, &items[i], quantity, reason, userID); err != nil {
+				return err
+			}
+			summary.Batches++
+			summary.Quantity += quantity
+		}
+		return nil
+	})
+	return summary, err
+}
+
+func writeOff(tx *gorm.DB, item *models.InventoryItem, quantity int, reason string, userID *uint) error {
+	unreserved := item.Quantity - item.Reserved
+	if quantity == 0 {
+		quantity = unreserved
+		if quantity <= 0 {
+			return ErrNothingToWriteOff
+		}
+	}
+	if quantity > unreserved {
+		return ErrInsufficientStock
+	}
+	if reason == "" {
+		reason = "Written off"
+		if item.Status == StatusExpired {
+			reason = "Expired stock written off"
+		}
+	}
+
+	if err := TakeUnreserved(tx, item.ID, quantity); errors.Is(err, ErrNotEnoughStock) {
+		return ErrInsufficientStock
+	} else if err != nil {
+		return err
+	}
+	item.Quantity -= quantity
+	movement := models.StockMovement{
+		InventoryItemID: item.ID,
+		MovementType:    MovementWriteOff,
+		Quantity:        quantity,
+		Reason:          reason,
+		Reference:       item.BatchNumber,
+		UserID:          userID,
+	}
+	if err := tx.Create(&movement).Error; err != nil {
+		return err
+	}
+	return SyncVariantStock(tx, item.ProductVariantID)
+}
+
+// SyncVariantStock sets the stock total of each variant to the quantity of
+// its batches that have not expired, then updates the bundles among them and
+// the bundles they are components of
+func SyncVariantStock(tx *gorm.DB, variantIDs ...uint) error {
+	seen := make(map[uint]bool, len(variantIDs))
+	for _, variantID := range variantIDs {
+		if seen[variantID] {
+			continue
+		}
+		seen[variantID] = true
+
+		var total int
+		if err := tx.Model(&models.InventoryItem{}).
+			Where("product_variant_id = ? AND status <> ?", variantID, StatusExpired).
+			Select("COALESCE(SUM(quantity), 0)").
+			Row().Scan(&total); err != nil {
+			return fmt.Errorf("failed to calculate total stock: %w", err)
+		}
+		// The model carries the ID so the product summary can tell which
+		// product changed
+		if err := tx.Model(&models.ProductVariant{Model: gorm.Model{ID: variantID}}).
+			Update("quantity_in_stock", total).Error; err != nil {
+			return fmt.Errorf("failed to update product variant stock: %w", err)
+		}
+	}
+	return syncBundlesOf(tx, variantIDs)
+}
@@ -0,0 +1,165 @@
+package stock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, ":memory:")
+}
+
+func openTestDB(t *testing.T, dsn string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Address{},
+		&models.Warehouse{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.BundleComponent{},
+		&models.InventoryItem{},
+		&models.StockMovement{},
+		&models.User{},
+		&models.StockCount{},
+		&models.StockCountLine{},
+	))
+	return db
+}
+
+// createBatches creates a variant stocked with a batch per expiry date
+func createBatches(t *testing.T, db *gorm.DB, quantity int, expiries ...*time.Time) []models.InventoryItem {
+	warehouse := models.Warehouse{Name: "London Warehouse", Code: "WH-LON", Address: models.Address{StreetAddress1: "1 Dock Road", City: "London", Country: "GB"}}
+	require.NoError(t, db.Create(&warehouse).Error)
+	product := models.Product{Name: "Halloumi", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{ProductID: product.ID, Name: "250g", SKU: "HAL-250", CostPrice: 1.5}
+	require.NoError(t, db.Omit("Product").Create(&variant).Error)
+
+	var items []models.InventoryItem
+	for i, expiry := range expiries {
+		item := models.InventoryItem{
+			ProductVariantID: variant.ID,
+			WarehouseID:      warehouse.ID,
+			Quantity:         quantity,
+			BatchNumber:      string(rune('A' + i)),
+			ExpiryDate:       expiry,
+			Status:           StatusActive,
+		}
+		require.NoError(t, db.Omit("ProductVariant", "Warehouse").Create(&item).Error)
+		items = append(items, item)
+	}
+	return items
+}
+
+func variantStock(t *testing.T, db *gorm.DB, id uint) int {
+	var variant models.ProductVariant
+	require.NoError(t, db.First(&variant, id).Error)
+	return variant.QuantityInStock
+}
+
+func TestExpireBatches(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	yesterday, nextWeek := now.AddDate(0, 0, -1), now.AddDate(0, 0, 7)
+	items := createBatches(t, db, 10, &yesterday, &nextWeek, nil)
+
+	service := NewService(db)
+	service.now = func() time.Time { return now }
+	count, err := service.ExpireBatches(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var expired models.InventoryItem
+	require.NoError(t, db.First(&expired, items[0].ID).Error)
+	assert.Equal(t, StatusExpired, expired.Status)
+	assert.Equal(t, 10, expired.Quantity, "expired stock is kept until written off")
+	assert.Equal(t, 20, variantStock(t, db, items[0].ProductVariantID))
+
+	var movement models.StockMovement
+	require.NoError(t, db.Where("inventory_item_id = ?", expired.ID).First(&movement).Error)
+	assert.Equal(t, MovementExpired, movement.MovementType)
+	assert.Equal(t, 10, movement.Quantity)
+
+	// Running again finds nothing new
+	count, err = service.ExpireBatches(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestWriteOff(t *testing.T) {
+	db := setupTestDB(t)
+	items := createBatches(t, db, 10, nil)
+	require.NoError(t, db.Model(&items[0]).Update("reserved", 4).Error)
+	service := NewService(db)
+	ctx := context.Background()
+
+	_, err := service.WriteOff(ctx, items[0].ID, 7, "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientStock, "reserved stock cannot be written off")
+	_, err = service.WriteOff(ctx, items[0].ID, -1, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+	_, err = service.WriteOff(ctx, 999, 1, "", nil)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	item, err := service.WriteOff(ctx, items[0].ID, 2, "Damaged in transit", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 8, item.Quantity)
+
+	// Zero writes off the rest of the unreserved stock
+	item, err = service.WriteOff(ctx, items[0].ID, 0, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, item.Quantity)
+	assert.Equal(t, 4, variantStock(t, db, item.ProductVariantID))
+
+	_, err = service.WriteOff(ctx, items[0].ID, 0, "", nil)
+	assert.ErrorIs(t, err, ErrNothingToWriteOff)
+
+	var movements []models.StockMovement
+	require.NoError(t, db.Where("movement_type = ?", MovementWriteOff).Order("id").Find(&movements).Error)
+	require.Len(t, movements, 2)
+	assert.Equal(t, "Damaged in transit", movements[0].Reason)
+	assert.Equal(t, 4, movements[1].Quantity)
+}
+
+func TestWriteOffExpiredAndReport(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now()
+	lastWeek, inTenDays, inSixtyDays := now.AddDate(0, 0, -7), now.AddDate(0, 0, 10), now.AddDate(0, 0, 60)
+	items := createBatches(t, db, 5, &lastWeek, &inTenDays, &inSixtyDays)
+	service := NewService(db)
+	ctx := context.Background()
+
+	_, err := service.ExpireBatches(ctx)
+	require.NoError(t, err)
+
+	report, err := service.Expiring(ctx, ExpiringFilter{Days: 30, IncludeExpired: true})
+	require.NoError(t, err)
+	require.Len(t, report.Batches, 2)
+	assert.Equal(t, items[0].ID, report.Batches[0].InventoryItemID)
+	assert.Less(t, report.Batches[0].DaysToExpiry, 0)
+	assert.Equal(t, 9, report.Batches[1].DaysToExpiry)
+	assert.Equal(t, 5, report.ExpiredQuantity)
+	assert.Equal(t, 5, report.ExpiringQuantity)
+	assert.Equal(t, 7.5, report.ExpiringValue)
+
+	report, err = service.Expiring(ctx, ExpiringFilter{Days: 30})
+	require.NoError(t, err)
+	require.Len(t, report.Batches, 1)
+	assert.Equal(t, items[1].ID, report.Batches[0].InventoryItemID)
+
+	summary, err := service.WriteOffExpired(ctx, 0, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, WriteOffSummary{Batches: 1, Quantity: 5}, summary)
+
+	var written models.InventoryItem
+	require.NoError(t, db.First(&written, items[0].ID).Error)
+	assert.Zero(t, written.Quantity)
+	assert.Equal(t, 10, variantStock(t, db, items[0].ProductVariantID))
+}
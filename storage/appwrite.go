@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/aw"
+)
+
+// previewPath is where the API proxies Appwrite files (see handlers/file)
+const previewPath = "/file/preview/"
+
+// Appwrite stores files in the Appwrite bucket. Keys are Appwrite file IDs,
+// and files are served through the API's file preview proxy, which checks
+// the signature of signed URLs.
+type Appwrite struct {
+	service *aw.AppwriteService
+	secret  string
+	now     func() time.Time
+}
+
+// NewAppwrite creates an Appwrite backend signing URLs with secret
+func NewAppwrite(service *aw.AppwriteService, secret string) *Appwrite {
+	return &Appwrite{service: service, secret: secret, now: time.Now}
+}
+
+// Name implements Storage
+func (s *Appwrite) Name() string { return BackendAppwrite }
+
+// Put implements Storage
+func (s *Appwrite) Put(_ context.Context, name, _ string, content io.Reader) (string, error) {
+	if s.service == nil {
+		return "", errors.New("appwrite storage not configured")
+	}
+	return s.service.Upload(name, content)
+}
+
+// Delete implements Storage
+func (s *Appwrite) Delete(_ context.Context, key string) error {
+	if s.service == nil {
+		return errors.New("appwrite storage not configured")
+	}
+	return s.service.DeleteFile(key)
+}
+
+// URL implements Storage
+func (s *Appwrite) URL(key string) string {
+	return previewPath + key
+}
+
+// SignedURL implements Storage
+func (s *Appwrite) SignedURL(key string, ttl time.Duration) (string, error) {
+	if s.secret == "" {
+		return "", fmt.Errorf("no signing key configured for %s", key)
+	}
+	expires := expiry(s.now(), ttl).Unix()
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {SignPreview(s.secret, key, expires)},
+	}
+	return s.URL(key) + "?" + query.Encode(), nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	gcstorage "cloud.google.com/go/storage"
+	"github.com/YasserCherfaoui/MarketProGo/gcs"
+)
+
+// maxGCSExpiry is the longest a V4 signed URL can work for
+const maxGCSExpiry = 7 * 24 * time.Hour
+
+// GCS stores files in the GCS bucket under images/. Keys are object names.
+type GCS struct {
+	service *gcs.GCService
+	now     func() time.Time
+}
+
+// NewGCS creates a GCS backend
+func NewGCS(service *gcs.GCService) *GCS {
+	return &GCS{service: service, now: time.Now}
+}
+
+// Name implements Storage
+func (s *GCS) Name() string { return BackendGCS }
+
+// Put implements Storage
+func (s *GCS) Put(ctx context.Context, name, contentType string, content io.Reader) (string, error) {
+	if s.service == nil {
+		return "", errors.New("gcs storage not configured")
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	key := "images/" + hex.EncodeToString(suffix) + "-" + objectName(name)
+	if _, err := s.service.UploadFile(ctx, content, key, contentType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Delete implements Storage
+func (s *GCS) Delete(ctx context.Context, key string) error {
+	if s.service == nil {
+		return errors.New("gcs storage not configured")
+	}
+	return s.service.DeleteFile(ctx, key)
+}
+
+// URL implements Storage. It is only readable when the bucket is public.
+func (s *GCS) URL(key string) string {
+	bucket := ""
+	if s.service != nil {
+		bucket = s.service.BucketName
+	}
+	return "https://storage.googleapis.com/" + bucket + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+// SignedURL implements Storage with a V4 signed URL, which works for 7 days
+// at most
+func (s *GCS) SignedURL(key string, ttl time.Duration) (string, error) {
+	if s.service == nil || s.service.Client == nil {
+		return "", errors.New("gcs storage not configured")
+	}
+	now := s.now()
+	expires := expiry(now, ttl)
+	if expires.Sub(now) > maxGCSExpiry {
+		expires = now.Add(maxGCSExpiry)
+	}
+	return s.service.Client.Bucket(s.service.BucketName).SignedURL(key, &gcstorage.SignedURLOptions{
+		Method:  "GET",
+		Expires: expires,
+		Scheme:  gcstorage.SigningSchemeV4,
+	})
+}
+
+// objectName keeps the letters, digits, dots, dashes and underscores of the
+// base of a file name
+func objectName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "-" {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// Sizes images are resized to, by the length of their longest side
+const (
+	ThumbnailSize = 150
+	MediumSize    = 600
+	LargeSize     = 1200
+)
+
+// maxPixels is the most pixels an uploaded image may have, so that a small
+// file cannot take up a lot of memory once decoded
+const maxPixels = 50_000_000
+
+var (
+	ErrImageTooLarge       = errors.New("image exceeds the maximum size")
+	ErrImageTypeNotAllowed = errors.New("file is not a JPEG, PNG, GIF or WebP image")
+	ErrImageCorrupt        = errors.New("image could not be read")
+	ErrImageTooManyPixels  = errors.New("image has too many pixels")
+)
+
+// imageTypes maps the accepted image content types to their file extensions
+var imageTypes = map[string][]string{
+	"image/jpeg": {".jpg", ".jpeg"},
+	"image/png":  {".png"},
+	"image/gif":  {".gif"},
+	"image/webp": {".webp"},
+}
+
+// StoredImage is the storage key of an uploaded image and of its resized copies
+type StoredImage struct {
+	Key string
+	models.ImageKeys
+}
+
+// Images validates product images, stores them with resized copies and
+// returns the URLs they are served at
+type Images struct {
+	store    Storage
+	maxBytes int64
+	signed   bool
+	ttl      time.Duration
+}
+
+// NewImages creates the product image pipeline on a storage backend
+func NewImages(store Storage, config *cfg.StorageConfig) *Images {
+	return &Images{
+		store:    store,
+		maxBytes: int64(config.MaxImageSizeMB) << 20,
+		signed:   config.SignedURLs,
+		ttl:      time.Duration(config.URLTTLMinutes) * time.Minute,
+	}
+}
+
+// Upload validates and stores an uploaded image (see Store)
+func (i *Images) Upload(ctx context.Context, fileHeader *multipart.FileHeader) (*StoredImage, error) {
+	if fileHeader.Size > i.maxBytes {
+		return nil, i.tooLarge()
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, i.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(content)) > i.maxBytes {
+		return nil, i.tooLarge()
+	}
+	return i.Store(ctx, fileHeader.Filename, content)
+}
+
+// Store checks that content is a JPEG, PNG, GIF or WebP image matching the
+// extension of name, then stores it along with copies resized to fit each of
+// the thumbnail, medium and large sizes it is larger than. Resized copies of
+// JPEGs are JPEGs, others are PNGs so they keep their transparency.
+func (i *Images) Store(ctx context.Context, name string, content []byte) (*StoredImage, error) {
+	contentType, err := DetectImageType(name, content)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrImageCorrupt
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrImageTooManyPixels
+	}
+	var decoded image.Image
+	if max(config.Width, config.Height) > ThumbnailSize {
+		if decoded, _, err = image.Decode(bytes.NewReader(content)); err != nil {
+			return nil, ErrImageCorrupt
+		}
+	}
+
+	key, err := i.store.Put(ctx, name, contentType, bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	stored := &StoredImage{Key: key}
+	for _, size := range []struct {
+		name   string
+		length int
+		key    *string
+	}{
+		{"thumbnail", ThumbnailSize, &stored.ThumbnailKey},
+		{"medium", MediumSize, &stored.MediumKey},
+		{"large", LargeSize, &stored.LargeKey},
+	} {
+		if max(config.Width, config.Height) <= size.length {
+			continue
+		}
+		data, ext, sizedType, err := encode(Resize(decoded, size.length), contentType)
+		if err == nil {
+			sizedName := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)) + "-" + size.name + ext
+			*size.key, err = i.store.Put(ctx, sizedName, sizedType, bytes.NewReader(data))
+		}
+		if err != nil {
+			i.Delete(ctx, stored)
+			return nil, fmt.Errorf("failed to store %s image: %w", size.name, err)
+		}
+	}
+	return stored, nil
+}
+
+// Delete removes a stored image and its resized copies. Failures are only
+// logged: a file left behind wastes space but breaks nothing.
+func (i *Images) Delete(ctx context.Context, stored *StoredImage) {
+	for _, key := range []string{stored.Key, stored.ThumbnailKey, stored.MediumKey, stored.LargeKey} {
+		if key == "" {
+			continue
+		}
+		if err := i.store.Delete(ctx, key); err != nil {
+			slog.WarnContext(ctx, "failed to delete image", "component", "storage", "key", key, "error", err)
+		}
+	}
+}
+
+// URL returns the URL of a stored file, signed when signed URLs are
+// configured. Absolute URLs, such as those of seeded images, are returned
+// as they are.
+func (i *Images) URL(key string) string {
+	if key == "" || strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://") {
+		return key
+	}
+	if i.signed {
+		signed, err := i.store.SignedURL(key, i.ttl)
+		if err == nil {
+			return signed
+		}
+		slog.Warn("failed to sign file URL", "component", "storage", "key", key, "error", err)
+	}
+	return i.store.URL(key)
+}
+
+// Resolve replaces the storage key of a product image with its URL and sets
+// the URLs of its sizes. Sizes without a resized copy are the original.
+func (i *Images) Resolve(image *models.ProductImage) {
+	original := i.URL(image.URL)
+	sized := func(key string) string {
+		if key == "" {
+			return original
+		}
+		return i.URL(key)
+	}
+	image.Sizes = &models.ImageSizes{
+		Thumbnail: sized(image.ThumbnailKey),
+		Medium:    sized(image.MediumKey),
+		Large:     sized(image.LargeKey),
+	}
+	image.URL = original
+}
+
+// ResolveAll resolves each of images
+func (i *Images) ResolveAll(images []models.ProductImage) {
+	for j := range images {
+		i.Resolve(&images[j])
+	}
+}
+
+func (i *Images) tooLarge() error {
+	return fmt.Errorf("%w of %sMB", ErrImageTooLarge, strconv.FormatInt(i.maxBytes>>20, 10))
+}
+
+// DetectImageType returns the content type of an image from its first
+// bytes, and rejects files that are not images of an accepted type or do not
+// match their extension
+func DetectImageType(name string, content []byte) (string, error) {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(content))
+	if err != nil {
+		return "", ErrImageTypeNotAllowed
+	}
+	extensions, ok := imageTypes[contentType]
+	if !ok {
+		return "", ErrImageTypeNotAllowed
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range extensions {
+		if ext == allowed {
+			return contentType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s content in a %s file", ErrImageTypeNotAllowed, contentType, ext)
+}
+
+// Resize scales img down to fit within a square of side length, keeping its
+// aspect ratio. Images that already fit are returned as they are.
+func Resize(img image.Image, length int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= length && height <= length {
+		return img
+	}
+	if width >= height {
+		width, height = length, max((height*length+width/2)/width, 1)
+	} else {
+		width, height = max((width*length+height/2)/height, 1), length
+	}
+	resized := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+	return resized
+}
+
+// encode encodes a resized image as a JPEG when the original is one, and as
+// a PNG otherwise. It returns the file extension and content type used.
+func encode(img image.Image, originalType string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	if originalType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), ".jpg", "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), ".png", "image/png", nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage keeps files in memory, signing URLs with their expiry
+type memoryStorage struct {
+	files map[string][]byte
+	types map[string]string
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{files: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (s *memoryStorage) Name() string { return "memory" }
+
+func (s *memoryStorage) Put(_ context.Context, name, contentType string, content io.Reader) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%d-%s", len(s.files)+1, name)
+	s.files[key], s.types[key] = data, contentType
+	return key, nil
+}
+
+func (s *memoryStorage) Delete(_ context.Context, key string) error {
+	delete(s.files, key)
+	return nil
+}
+
+func (s *memoryStorage) URL(key string) string { return "/files/" + key }
+
+func (s *memoryStorage) SignedURL(key string, ttl time.Duration) (string, error) {
+	return fmt.Sprintf("/files/%s?ttl=%s", key, ttl), nil
+}
+
+func encodedImage(t *testing.T, format string, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+	} else {
+		require.NoError(t, png.Encode(&buf, img))
+	}
+	return buf.Bytes()
+}
+
+func (s *memoryStorage) size(t *testing.T, key string) image.Point {
+	config, _, err := image.DecodeConfig(bytes.NewReader(s.files[key]))
+	require.NoError(t, err)
+	return image.Pt(config.Width, config.Height)
+}
+
+func TestStoreResizesImages(t *testing.T) {
+	store := newMemoryStorage()
+	images := NewImages(store, &cfg.StorageConfig{MaxImageSizeMB: 10})
+	ctx := context.Background()
+
+	stored, err := images.Store(ctx, "crate.png", encodedImage(t, "png", 2000, 1000))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(2000, 1000), store.size(t, stored.Key))
+	assert.Equal(t, image.Pt(150, 75), store.size(t, stored.ThumbnailKey))
+	assert.Equal(t, image.Pt(600, 300), store.size(t, stored.MediumKey))
+	assert.Equal(t, image.Pt(1200, 600), store.size(t, stored.LargeKey))
+	assert.Equal(t, "image/png", store.types[stored.LargeKey])
+
+	// Images are never enlarged: the sizes the original fits are not stored
+	stored, err = images.Store(ctx, "olives.jpg", encodedImage(t, "jpeg", 300, 400))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(113, 150), store.size(t, stored.ThumbnailKey))
+	assert.Equal(t, "image/jpeg", store.types[stored.ThumbnailKey])
+	assert.Empty(t, stored.MediumKey)
+	assert.Empty(t, stored.LargeKey)
+	assert.Len(t, store.files, 6)
+}
+
+func TestStoreValidatesImages(t *testing.T) {
+	store := newMemoryStorage()
+	images := NewImages(store, &cfg.StorageConfig{MaxImageSizeMB: 10})
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		content []byte
+		err     error
+	}{
+		{"notes.txt", []byte("not an image"), ErrImageTypeNotAllowed},
+		{"report.png", []byte("%PDF-1.7 not an image"), ErrImageTypeNotAllowed},
+		{"crate.jpg", encodedImage(t, "png", 10, 10), ErrImageTypeNotAllowed},
+		{"crate", encodedImage(t, "png", 10, 10), ErrImageTypeNotAllowed},
+		{"broken.png", encodedImage(t, "png", 10, 10)[:20], ErrImageCorrupt},
+	}
+	for _, c := range cases {
+		_, err := images.Store(ctx, c.name, c.content)
+		assert.ErrorIs(t, err, c.err, c.name)
+	}
+	assert.Empty(t, store.files)
+}
+
+func TestResolve(t *testing.T) {
+	store := newMemoryStorage()
+	images := NewImages(store, &cfg.StorageConfig{})
+
+	image := models.ProductImage{URL: "a.png", ImageKeys: models.ImageKeys{ThumbnailKey: "a-thumbnail.png"}}
+	images.Resolve(&image)
+	assert.Equal(t, "/files/a.png", image.URL)
+	assert.Equal(t, models.ImageSizes{Thumbnail: "/files/a-thumbnail.png", Medium: "/files/a.png", Large: "/files/a.png"}, *image.Sizes)
+
+	seeded := models.ProductImage{URL: "https://picsum.photos/seed/product-1/600/600"}
+	images.Resolve(&seeded)
+	assert.Equal(t, "https://picsum.photos/seed/product-1/600/600", seeded.Sizes.Thumbnail)
+
+	signed := NewImages(store, &cfg.StorageConfig{SignedURLs: true, URLTTLMinutes: 30})
+	assert.Equal(t, "/files/a.png?ttl=30m0s", signed.URL("a.png"))
+}
@@ -0,0 +1,92 @@
+// Package storage keeps product images in a storage backend, Appwrite or
+// Google Cloud Storage, and hands out the URLs they are served at, signed
+// to expire when configured. Images are validated when they are uploaded and
+// stored with resized copies (see Images).
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/gcs"
+)
+
+// Storage backends
+const (
+	BackendAppwrite = "appwrite"
+	BackendGCS      = "gcs"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid file signature")
+	ErrURLExpired       = errors.New("file URL has expired")
+)
+
+// Storage keeps files in a storage backend. A file is addressed by the key
+// Put returns for it.
+type Storage interface {
+	// Name returns the name of the backend, BackendAppwrite or BackendGCS
+	Name() string
+	// Put stores content under a key made unique from name
+	Put(ctx context.Context, name, contentType string, content io.Reader) (key string, err error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the URL a file is served at
+	URL(key string) string
+	// SignedURL returns a URL to a file that stops working once ttl has passed
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// New returns the storage backend the config selects
+func New(config *cfg.StorageConfig, appwrite *aw.AppwriteService, gcsService *gcs.GCService) (Storage, error) {
+	switch config.Backend {
+	case "", BackendAppwrite:
+		return NewAppwrite(appwrite, config.SigningKey), nil
+	case BackendGCS:
+		return NewGCS(gcsService), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Backend)
+	}
+}
+
+// SignPreview returns the hex HMAC-SHA256 of "fileID.expires" keyed with
+// secret, as sent in the signature parameter of signed preview URLs
+func SignPreview(secret, fileID string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fileID))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPreview checks the expires and signature parameters of a signed
+// preview URL of fileID
+func VerifyPreview(secret, fileID, expires, signature string, now time.Time) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || secret == "" || !hmac.Equal([]byte(signature), []byte(SignPreview(secret, fileID, expiresAt))) {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > expiresAt {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+// expiry returns when a URL signed now to work for ttl expires. It is rounded
+// up to the next multiple of ttl, so a file gets the same URL, which browsers
+// and CDNs can cache, for ttl at a time; the URL then works for up to twice ttl.
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	window := int64(ttl / time.Second)
+	if window < 1 {
+		window = 1
+	}
+	return time.Unix((now.Unix()/window+2)*window, 0)
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppwriteSignedURL(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 10, 0, 0, time.UTC)
+	store := NewAppwrite(nil, "secret")
+	store.now = func() time.Time { return now }
+
+	signed, err := store.SignedURL("file-1", time.Hour)
+	require.NoError(t, err)
+	link, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/file/preview/file-1", link.Path)
+	expires, signature := link.Query().Get("expires"), link.Query().Get("signature")
+	assert.Equal(t, "1772452800", expires, "expires on the hour after next")
+
+	// The URL is the same for the rest of the hour, so it can be cached
+	store.now = func() time.Time { return now.Add(45 * time.Minute) }
+	again, err := store.SignedURL("file-1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, signed, again)
+
+	assert.NoError(t, VerifyPreview("secret", "file-1", expires, signature, now.Add(time.Hour)))
+	assert.ErrorIs(t, VerifyPreview("secret", "file-1", expires, signature, now.Add(2*time.Hour)), ErrURLExpired)
+	assert.ErrorIs(t, VerifyPreview("secret", "file-2", expires, signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyPreview("other", "file-1", expires, signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyPreview("secret", "file-1", "1772456400", signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyPreview("", "file-1", expires, SignPreview("", "file-1", 1772452800), now), ErrInvalidSignature)
+
+	_, err = NewAppwrite(nil, "").SignedURL("file-1", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestObjectName(t *testing.T) {
+	assert.Equal(t, "olive-oil-1L.jpg", objectName("olive oil 1L.jpg"))
+	assert.Equal(t, "crate.png", objectName("../../crate.png"))
+	assert.Equal(t, "crate.png", objectName(`C:\uploads\crate.png`))
+	assert.Equal(t, "file", objectName(""))
+}
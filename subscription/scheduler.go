@@ -0,0 +1,481 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"github.com/YasserCherfaoui/MarketProGo/webhook"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+// runBatchSize is how many due subscriptions a run of the scheduler handles
+const runBatchSize = 100
+
+// StartScheduler resumes paused subscriptions, sends reminders and places
+// the orders of due subscriptions every interval until ctx is canceled
+func (s *Service) StartScheduler(ctx context.Context, interval time.Duration) {
+	for {
+		if count, err := s.ResumeDue(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to resume subscriptions", "component", "subscription", "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "resumed subscriptions", "component", "subscription", "count", count)
+		}
+		if count, err := s.SendReminders(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to send subscription reminders", "component", "subscription", "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "sent subscription reminders", "component", "subscription", "count", count)
+		}
+		if count, err := s.RunDue(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to run subscriptions", "component", "subscription", "error", err)
+		} else if count > 0 {
+			slog.InfoContext(ctx, "placed subscription orders", "component", "subscription", "count", count)
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// ResumeDue resumes the paused subscriptions whose resume date has come,
+// returning how many were resumed
+func (s *Service) ResumeDue(ctx context.Context) (int, error) {
+	var due []models.Subscription
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND resume_at <= ?", models.SubscriptionStatusPaused, s.now()).
+		Order("resume_at").Limit(runBatchSize).Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	resumed := 0
+	for i := range due {
+		// Only resume subscriptions the customer has not changed since
+		result := s.db.WithContext(ctx).Model(&due[i]).
+			Where("status = ? AND resume_at = ?", models.SubscriptionStatusPaused, due[i].ResumeAt).
+			Updates(s.resumeUpdates(&due[i]))
+		if result.Error != nil {
+			return resumed, result.Error
+		}
+		resumed += int(result.RowsAffected)
+	}
+	return resumed, nil
+}
+
+// SendReminders emails customers the subscription orders due within the
+// configured number of days, once per order, returning how many were sent
+func (s *Service) SendReminders(ctx context.Context) (int, error) {
+	if s.emails == nil || s.config.ReminderDays <= 0 {
+		return 0, nil
+	}
+	now := s.now()
+	var due []models.Subscription
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND reminded_at IS NULL AND next_run_at > ? AND next_run_at <= ?",
+			models.SubscriptionStatusActive, now, now.AddDate(0, 0, s.config.ReminderDays)).
+		Order("next_run_at").Limit(runBatchSize).Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, subscription := range due {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Claim the reminder so other instances do not send it too
+			result := tx.Model(&models.Subscription{}).
+				Where("id = ? AND reminded_at IS NULL AND next_run_at = ?", subscription.ID, subscription.NextRunAt).
+				Update("reminded_at", now)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			if err := s.remind(tx, subscription.ID); err != nil {
+				return err
+			}
+			sent++
+			return nil
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to send subscription reminder", "component", "subscription", "subscription_id", subscription.ID, "error", err)
+		}
+	}
+	return sent, nil
+}
+
+// remind queues the reminder of a subscription's next order in tx's outbox
+func (s *Service) remind(tx *gorm.DB, id uint) error {
+	subscription, err := load(tx, id)
+	if err != nil {
+		return err
+	}
+	lines, _, err := s.price(subscription)
+	if err != nil {
+		return err
+	}
+	breakdown, err := s.calculateTax(subscription, lines)
+	if err != nil {
+		return err
+	}
+
+	name := customerName(&subscription.User)
+	data := emailData(subscription, name)
+	data["Title"] = "Your next order is coming up"
+	data["subject"] = fmt.Sprintf("Your %s order is coming up on %s", describeInterval(subscription.Interval), subscription.NextRunAt.Format("January 2"))
+	data["EstimatedTotal"] = fmt.Sprintf("%.2f", breakdown.GrossAmount)
+	return s.emails.InTx(tx).TriggerSubscriptionReminder(subscription.User.Email, name, data)
+}
+
+// RunDue places the orders of the subscriptions that are due, returning how
+// many orders were placed. Runs that fail are recorded on their subscription.
+func (s *Service) RunDue(ctx context.Context) (int, error) {
+	var due []models.Subscription
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND next_run_at <= ?", models.SubscriptionStatusActive, s.now()).
+		Order("next_run_at").Limit(runBatchSize).Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	placed := 0
+	for _, subscription := range due {
+		order, err := s.Run(ctx, subscription.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "subscription run failed", "component", "subscription", "subscription_id", subscription.ID, "error", err)
+			continue
+		}
+		if order != nil {
+			placed++
+		}
+	}
+	return placed, nil
+}
+
+// Run places the order of a due subscription, reserves its stock and starts
+// its payment in one transaction, then moves the subscription on to its next
+// run. It returns nil when the subscription is not due, such as when another
+// instance ran it first. A run that fails is skipped: it is recorded on the
+// subscription, which is paused after the configured number of failures in
+// a row.
+func (s *Service) Run(ctx context.Context, id uint) (*models.Order, error) {
+	subscription, err := load(s.db.WithContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if subscription.Status != models.SubscriptionStatusActive || subscription.NextRunAt.After(now) {
+		return nil, nil
+	}
+	next := nextRun(subscription.Interval, subscription.NextRunAt, now)
+
+	var order *models.Order
+	var claimed bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Claim the run; the row stays locked until the order is committed
+		result := tx.Model(&models.Subscription{}).
+			Where("id = ? AND status = ? AND next_run_at = ?", subscription.ID, models.SubscriptionStatusActive, subscription.NextRunAt).
+			Updates(map[string]interface{}{
+				"next_run_at": next,
+				"last_run_at": now,
+				"reminded_at": nil,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		claimed = true
+
+		var err error
+		order, err = s.placeOrder(ctx, tx, subscription, next)
+		return err
+	})
+	if err != nil {
+		if claimed {
+			s.recordFailure(ctx, subscription, next, now, err)
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// placeOrder places and pays for a subscription's order in tx
+func (s *Service) placeOrder(ctx context.Context, tx *gorm.DB, subscription *models.Subscription, next time.Time) (*models.Order, error) {
+	lines, variants, err := s.price(subscription)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("none of the subscription's items are available")
+	}
+	breakdown, err := s.calculateTax(subscription, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalAmount float64
+	for _, line := range lines {
+		totalAmount += line.Amount
+	}
+	billingAddressID := subscription.BillingAddressID
+	if billingAddressID == nil {
+		billingAddressID = &subscription.ShippingAddressID
+	}
+	order := models.Order{
+		OrderNumber:       fmt.Sprintf("ORD-%s-S%d", s.now().Format("20060102"), subscription.ID),
+		UserID:            subscription.UserID,
+		CompanyID:         subscription.User.CompanyID,
+		Status:            models.OrderStatusPending,
+		PaymentStatus:     models.PaymentStatusPending,
+		TotalAmount:       totalAmount,
+		TaxAmount:         breakdown.TaxAmount,
+		TaxCountry:        breakdown.Country,
+		TaxBreakdown:      breakdown.ToJSON(),
+		FinalAmount:       breakdown.GrossAmount,
+		ShippingAddressID: subscription.ShippingAddressID,
+		BillingAddressID:  billingAddressID,
+		PaymentMethod:     "card",
+		CustomerNotes:     subscription.CustomerNotes,
+		AdminNotes:        fmt.Sprintf("Placed by subscription #%d", subscription.ID),
+		SubscriptionID:    &subscription.ID,
+		OrderDate:         s.now(),
+	}
+	if err := tx.Create(&order).Error; err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+	items := make([]models.OrderItem, 0, len(lines))
+	for i, line := range lines {
+		items = append(items, models.OrderItem{
+			OrderID:          order.ID,
+			ProductVariantID: line.ProductVariantID,
+			Quantity:         variants[i].quantity,
+			UnitPrice:        variants[i].unitPrice,
+			TaxRate:          breakdown.Lines[i].Rate,
+			TaxAmount:        breakdown.Lines[i].TaxAmount,
+			TotalAmount:      line.Amount,
+			Status:           "active",
+		})
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to create order items: %w", err)
+	}
+
+	// Reserve the stock so it is not sold to someone else while the order is paid
+	if s.allocator != nil {
+		if _, err := s.allocator.Allocate(tx, &order, nil); err != nil {
+			return nil, fmt.Errorf("failed to reserve stock: %w", err)
+		}
+	}
+
+	var complete models.Order
+	if err := tx.Preload("User").
+		Preload("ShippingAddress").
+		Preload("BillingAddress").
+		Preload("Items.ProductVariant.Product").
+		First(&complete, order.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+
+	var paid *payment.PaymentResponse
+	if s.payments != nil {
+		req := &payment.PaymentRequest{
+			OrderID:      complete.ID,
+			Amount:       math.Round(complete.FinalAmount*100) / 100,
+			Currency:     settings.Current().Currency,
+			Description:  "Order " + complete.OrderNumber,
+			CustomerInfo: customerInfo(&complete),
+		}
+		if subscription.PaysWithSavedMethod() {
+			req.SavedPaymentMethod = &payment.SavedPaymentMethod{
+				CustomerID: subscription.RevolutCustomerID,
+				ID:         subscription.SavedPaymentMethodID,
+			}
+		}
+		paid, err = s.payments.InTx(tx).CreatePayment(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create payment: %w", err)
+		}
+		complete.RevolutOrderID, complete.CheckoutURL, complete.PaymentProvider = paid.OrderID, paid.CheckoutURL, "revolut"
+	}
+
+	if err := tx.Model(subscription).Updates(map[string]interface{}{
+		"last_order_id": complete.ID,
+		"failed_runs":   0,
+		"last_error":    "",
+	}).Error; err != nil {
+		return nil, err
+	}
+	if err := webhook.Publish(tx, webhook.EventOrderCreated, webhook.OrderData(&complete)); err != nil {
+		return nil, fmt.Errorf("failed to record order events: %w", err)
+	}
+
+	// Record the emails in the same transaction, so they are sent exactly
+	// when the order is committed
+	if s.emails != nil {
+		emails := s.emails.InTx(tx)
+		name := customerName(&subscription.User)
+		subscription.NextRunAt = next
+		data := emailData(subscription, name)
+		data["Title"] = "Your subscription order is placed"
+		data["subject"] = fmt.Sprintf("Your %s order %s", describeInterval(subscription.Interval), complete.OrderNumber)
+		data["OrderNumber"] = complete.OrderNumber
+		data["TotalAmount"] = fmt.Sprintf("%.2f", complete.FinalAmount)
+		data["PaysWithSavedCard"] = paid != nil && paid.PaymentMethod == "saved_card"
+		data["PaymentURL"] = ""
+		if paid != nil && paid.PaymentMethod != "saved_card" {
+			data["PaymentURL"] = paid.CheckoutURL
+		}
+		if err := emails.TriggerSubscriptionOrder(subscription.User.Email, name, data); err != nil {
+			slog.ErrorContext(ctx, "failed to send subscription order email", "component", "subscription", "error", err)
+		}
+		orderData := map[string]interface{}{
+			"order_number":     complete.OrderNumber,
+			"order_date":       complete.OrderDate,
+			"total_amount":     complete.FinalAmount,
+			"tax_amount":       complete.TaxAmount,
+			"tax_breakdown":    complete.TaxBreakdown,
+			"currency":         settings.Current().Currency,
+			"items":            complete.Items,
+			"shipping_address": complete.ShippingAddress,
+		}
+		if err := emails.TriggerNewOrderAdminNotification(complete.ID, orderData); err != nil {
+			slog.ErrorContext(ctx, "failed to send admin notification", "component", "subscription", "error", err)
+		}
+	}
+	return &complete, nil
+}
+
+// recordFailure records a run that placed no order on its subscription,
+// pausing it once it failed the configured number of times in a row
+func (s *Service) recordFailure(ctx context.Context, subscription *models.Subscription, next, now time.Time, cause error) {
+	failed := subscription.FailedRuns + 1
+	updates := map[string]interface{}{
+		"next_run_at": next,
+		"last_run_at": now,
+		"reminded_at": nil,
+		"failed_runs": failed,
+		"last_error":  cause.Error(),
+	}
+	if s.config.MaxFailedRuns > 0 && failed >= s.config.MaxFailedRuns {
+		updates["status"] = models.SubscriptionStatusPaused
+		updates["paused_at"] = now
+	}
+	err := s.db.WithContext(ctx).Model(&models.Subscription{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", subscription.ID, models.SubscriptionStatusActive, subscription.NextRunAt).
+		Updates(updates).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record subscription failure", "component", "subscription", "subscription_id", subscription.ID, "error", err)
+	}
+}
+
+// pricedItem is the quantity and unit price of an order line
+type pricedItem struct {
+	quantity  int
+	unitPrice float64
+}
+
+// price prices a subscription's items at the customer's current prices,
+// leaving out variants no longer sold
+func (s *Service) price(subscription *models.Subscription) ([]tax.Line, []pricedItem, error) {
+	priceList, err := s.prices.ActivePriceList(subscription.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	priceType := "customer"
+	if subscription.User.CompanyID != nil || subscription.User.UserType == models.Wholesaler {
+		priceType = "b2b"
+	}
+
+	lines := make([]tax.Line, 0, len(subscription.Items))
+	priced := make([]pricedItem, 0, len(subscription.Items))
+	for _, item := range subscription.Items {
+		variant := item.ProductVariant
+		if variant.ID == 0 || !variant.IsActive || variant.Product.ID == 0 {
+			continue
+		}
+		unitPrice := pricing.UnitPriceWithList(priceList, &variant, item.Quantity, priceType)
+		lines = append(lines, tax.Line{
+			ProductVariantID: item.ProductVariantID,
+			IsVAT:            variant.Product.IsVAT,
+			Amount:           float64(item.Quantity) * unitPrice,
+		})
+		priced = append(priced, pricedItem{quantity: item.Quantity, unitPrice: unitPrice})
+	}
+	return lines, priced, nil
+}
+
+// calculateTax calculates the VAT of a subscription's lines for its shipping
+// country
+func (s *Service) calculateTax(subscription *models.Subscription, lines []tax.Line) (*tax.Breakdown, error) {
+	return s.taxes.Calculate(lines, subscription.ShippingAddress.Country)
+}
+
+// load returns a subscription with what its runs need
+func load(db *gorm.DB, id uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := db.Preload("User").
+		Preload("ShippingAddress").
+		Preload("Items.ProductVariant.Product").
+		Preload("Items.ProductVariant.PriceTiers").
+		First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// emailData is the template data shared by subscription emails
+func emailData(subscription *models.Subscription, name string) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(subscription.Items))
+	for _, item := range subscription.Items {
+		items = append(items, map[string]interface{}{
+			"Name":     strings.TrimSpace(item.ProductVariant.Product.Name + " " + item.ProductVariant.Name),
+			"Quantity": item.Quantity,
+		})
+	}
+	return map[string]interface{}{
+		"UserName":          name,
+		"Interval":          describeInterval(subscription.Interval),
+		"NextRunAt":         subscription.NextRunAt.Format("Monday, January 2, 2006"),
+		"Items":             items,
+		"Currency":          settings.Current().Currency,
+		"PaysWithSavedCard": subscription.PaysWithSavedMethod(),
+		"ManageURL":         settings.Current().URL("/account/subscriptions/%d", subscription.ID),
+	}
+}
+
+// customerInfo builds the payment customer details of an order's buyer
+func customerInfo(order *models.Order) *payment.CustomerInfo {
+	user := order.User
+	customer := &payment.CustomerInfo{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  customerName(&user),
+		Phone: user.Phone,
+	}
+	if billing := order.BillingAddress; billing != nil {
+		customer.Address = strings.TrimSpace(billing.StreetAddress1 + " " + billing.StreetAddress2)
+		customer.City = billing.City
+		customer.Country = billing.Country
+		customer.PostCode = billing.PostalCode
+	}
+	return customer
+}
+
+func customerName(user *models.User) string {
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+// describeInterval describes an interval in emails
+func describeInterval(interval models.SubscriptionInterval) string {
+	switch interval {
+	case models.SubscriptionWeekly:
+		return "weekly"
+	case models.SubscriptionBiweekly:
+		return "fortnightly"
+	default:
+		return "monthly"
+	}
+}
@@ -0,0 +1,363 @@
+// Package subscription places recurring orders. A subscription orders the
+// same items for a customer every week, two weeks or month; the scheduler
+// reminds the customer of each order ahead of time, then places it, reserves
+// its stock and starts its payment, charging the customer's Revolut saved
+// payment method when they gave one and emailing them a link to pay otherwise.
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	addressService "github.com/YasserCherfaoui/MarketProGo/address"
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/delivery"
+	"github.com/YasserCherfaoui/MarketProGo/email"
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/pricing"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotFound        = errors.New("subscription not found")
+	ErrInvalidInterval = errors.New("interval must be WEEKLY, BIWEEKLY or MONTHLY")
+	ErrNoItems         = errors.New("a subscription needs at least one item")
+	ErrAddressNotFound = errors.New("address not found")
+	// ErrInvalidItem is wrapped with the reason an item cannot be subscribed to
+	ErrInvalidItem = errors.New("invalid subscription item")
+	// ErrIncompletePaymentMethod is returned when only one of the Revolut
+	// customer and saved payment method was given
+	ErrIncompletePaymentMethod = errors.New("revolut_customer_id and saved_payment_method_id must be given together")
+	ErrNotActive               = errors.New("subscription is not active")
+	ErrNotPaused               = errors.New("subscription is not paused")
+	ErrCancelled               = errors.New("subscription has been cancelled")
+)
+
+// Service manages subscriptions and places their orders
+type Service struct {
+	db        *gorm.DB
+	config    cfg.SubscriptionConfig
+	taxes     *tax.TaxService
+	prices    *pricing.Resolver
+	allocator *fulfillment.Allocator
+	payments  payment.PaymentService
+	emails    *email.EmailTriggerService
+	now       func() time.Time
+}
+
+// NewService creates a subscription service. Without payments the orders are
+// placed unpaid, for the customer to pay from their order; without emails no
+// reminders are sent.
+func NewService(db *gorm.DB, config *cfg.SubscriptionConfig, taxes *tax.TaxService, allocator *fulfillment.Allocator, payments payment.PaymentService, emails *email.EmailTriggerService) *Service {
+	s := &Service{
+		db:        db,
+		taxes:     taxes,
+		prices:    pricing.NewResolver(db),
+		allocator: allocator,
+		payments:  payments,
+		emails:    emails,
+		now:       time.Now,
+	}
+	if config != nil {
+		s.config = *config
+	}
+	return s
+}
+
+// ItemInput is a variant to order on every run
+type ItemInput struct {
+	ProductVariantID uint
+	Quantity         int
+}
+
+// Input describes a new subscription
+type Input struct {
+	UserID            uint
+	Interval          models.SubscriptionInterval
+	Items             []ItemInput
+	StartAt           *time.Time // first order; now when nil
+	ShippingAddressID uint       // the user's default shipping address when 0
+	BillingAddressID  *uint      // the user's billing address, or the shipping address, when nil
+	CustomerNotes     string
+
+	// Revolut saved payment method to pay the orders with; the customer is
+	// emailed a link to pay for each order when empty
+	RevolutCustomerID    string
+	SavedPaymentMethodID string
+}
+
+// Create subscribes a user to the items of in. The first order is placed at
+// in.StartAt.
+func (s *Service) Create(ctx context.Context, in Input) (*models.Subscription, error) {
+	if !validInterval(in.Interval) {
+		return nil, ErrInvalidInterval
+	}
+	if len(in.Items) == 0 {
+		return nil, ErrNoItems
+	}
+	if (in.RevolutCustomerID == "") != (in.SavedPaymentMethodID == "") {
+		return nil, ErrIncompletePaymentMethod
+	}
+
+	subscription := &models.Subscription{
+		UserID:               in.UserID,
+		Status:               models.SubscriptionStatusActive,
+		Interval:             in.Interval,
+		NextRunAt:            s.now(),
+		CustomerNotes:        in.CustomerNotes,
+		RevolutCustomerID:    in.RevolutCustomerID,
+		SavedPaymentMethodID: in.SavedPaymentMethodID,
+	}
+	if in.StartAt != nil && in.StartAt.After(subscription.NextRunAt) {
+		subscription.NextRunAt = *in.StartAt
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		shipping, err := userAddress(tx, in.UserID, in.ShippingAddressID)
+		if err != nil {
+			return err
+		}
+		// Orders are placed without anyone to book a delivery slot
+		if err := delivery.RequireSlot(tx, shipping); err != nil {
+			return err
+		}
+		subscription.ShippingAddressID = shipping.ID
+		if in.BillingAddressID != nil {
+			billing, err := userAddress(tx, in.UserID, *in.BillingAddressID)
+			if err != nil {
+				return err
+			}
+			subscription.BillingAddressID = &billing.ID
+		}
+
+		quantities := make(map[uint]int, len(in.Items))
+		for _, item := range in.Items {
+			quantities[item.ProductVariantID] += item.Quantity
+		}
+		for _, item := range in.Items {
+			quantity, ok := quantities[item.ProductVariantID]
+			if !ok {
+				continue // merged into the first line of its variant
+			}
+			delete(quantities, item.ProductVariantID)
+			if err := checkItem(tx, item.ProductVariantID, quantity); err != nil {
+				return err
+			}
+			subscription.Items = append(subscription.Items, models.SubscriptionItem{
+				ProductVariantID: item.ProductVariantID,
+				Quantity:         quantity,
+			})
+		}
+		items := subscription.Items
+		if err := tx.Omit("Items").Create(subscription).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].SubscriptionID = subscription.ID
+		}
+		return tx.Omit("ProductVariant").Create(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, in.UserID, subscription.ID)
+}
+
+// List returns a user's subscriptions, newest first
+func (s *Service) List(ctx context.Context, userID uint) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := s.db.WithContext(ctx).Preload("Items.ProductVariant.Product").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Get returns a user's subscription with its items and addresses
+func (s *Service) Get(ctx context.Context, userID, id uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := s.db.WithContext(ctx).Preload("Items.ProductVariant.Product").
+		Preload("ShippingAddress").
+		Preload("BillingAddress").
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Skip skips the next order of an active subscription, moving it on by an
+// interval
+func (s *Service) Skip(ctx context.Context, userID, id uint) (*models.Subscription, error) {
+	return s.update(ctx, userID, id, func(subscription *models.Subscription) (map[string]interface{}, error) {
+		if subscription.Status != models.SubscriptionStatusActive {
+			return nil, statusError(subscription, ErrNotActive)
+		}
+		// Skipping an overdue order moves on to the first run after now
+		from := subscription.NextRunAt
+		if now := s.now(); now.After(from) {
+			from = now
+		}
+		return map[string]interface{}{
+			"next_run_at": nextRun(subscription.Interval, subscription.NextRunAt, from),
+			"reminded_at": nil,
+		}, nil
+	})
+}
+
+// Pause stops an active subscription placing orders until it is resumed, or
+// until resumeAt when given
+func (s *Service) Pause(ctx context.Context, userID, id uint, resumeAt *time.Time) (*models.Subscription, error) {
+	return s.update(ctx, userID, id, func(subscription *models.Subscription) (map[string]interface{}, error) {
+		if subscription.Status != models.SubscriptionStatusActive {
+			return nil, statusError(subscription, ErrNotActive)
+		}
+		return map[string]interface{}{
+			"status":    models.SubscriptionStatusPaused,
+			"paused_at": s.now(),
+			"resume_at": resumeAt,
+		}, nil
+	})
+}
+
+// Resume restarts a paused subscription. Orders due while it was paused are
+// not placed; the next order is the first one due from now.
+func (s *Service) Resume(ctx context.Context, userID, id uint) (*models.Subscription, error) {
+	return s.update(ctx, userID, id, func(subscription *models.Subscription) (map[string]interface{}, error) {
+		if subscription.Status != models.SubscriptionStatusPaused {
+			return nil, statusError(subscription, ErrNotPaused)
+		}
+		return s.resumeUpdates(subscription), nil
+	})
+}
+
+// Cancel ends a subscription; it places no more orders
+func (s *Service) Cancel(ctx context.Context, userID, id uint) (*models.Subscription, error) {
+	return s.update(ctx, userID, id, func(subscription *models.Subscription) (map[string]interface{}, error) {
+		if subscription.Status == models.SubscriptionStatusCancelled {
+			return nil, ErrCancelled
+		}
+		return map[string]interface{}{
+			"status":       models.SubscriptionStatusCancelled,
+			"cancelled_at": s.now(),
+			"resume_at":    nil,
+		}, nil
+	})
+}
+
+// update applies the changes change makes to a user's subscription, locking
+// it so a run cannot place an order in between
+func (s *Service) update(ctx context.Context, userID, id uint, change func(*models.Subscription) (map[string]interface{}, error)) (*models.Subscription, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var subscription models.Subscription
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userID).First(&subscription).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		updates, err := change(&subscription)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&subscription).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, id)
+}
+
+// resumeUpdates are the changes that make a paused subscription active again
+func (s *Service) resumeUpdates(subscription *models.Subscription) map[string]interface{} {
+	now := s.now()
+	return map[string]interface{}{
+		"status":      models.SubscriptionStatusActive,
+		"paused_at":   nil,
+		"resume_at":   nil,
+		"failed_runs": 0,
+		"next_run_at": nextRun(subscription.Interval, subscription.NextRunAt, now),
+		"reminded_at": nil,
+	}
+}
+
+// nextRun returns the first run after from, counting intervals on from the
+// run scheduled at scheduled so orders keep their day of the week or month
+func nextRun(interval models.SubscriptionInterval, scheduled, from time.Time) time.Time {
+	next := scheduled
+	for n := 1; !next.After(from); n++ {
+		switch interval {
+		case models.SubscriptionWeekly:
+			next = scheduled.AddDate(0, 0, 7*n)
+		case models.SubscriptionBiweekly:
+			next = scheduled.AddDate(0, 0, 14*n)
+		default:
+			next = scheduled.AddDate(0, n, 0)
+		}
+	}
+	return next
+}
+
+func validInterval(interval models.SubscriptionInterval) bool {
+	switch interval {
+	case models.SubscriptionWeekly, models.SubscriptionBiweekly, models.SubscriptionMonthly:
+		return true
+	}
+	return false
+}
+
+// statusError returns ErrCancelled for cancelled subscriptions and err
+// otherwise
+func statusError(subscription *models.Subscription, err error) error {
+	if subscription.Status == models.SubscriptionStatusCancelled {
+		return ErrCancelled
+	}
+	return err
+}
+
+// userAddress returns one of a user's addresses, or their default shipping
+// address when id is 0
+func userAddress(tx *gorm.DB, userID, id uint) (*models.Address, error) {
+	var address *models.Address
+	var err error
+	if id == 0 {
+		address, err = addressService.Default(tx, userID, addressService.Shipping)
+	} else {
+		address = &models.Address{}
+		err = tx.Where("id = ? AND user_id = ?", id, userID).First(address).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAddressNotFound
+	}
+	return address, err
+}
+
+// checkItem makes sure a variant can be ordered in quantity
+func checkItem(tx *gorm.DB, variantID uint, quantity int) error {
+	var variant models.ProductVariant
+	err := tx.First(&variant, variantID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: product variant %d not found", ErrInvalidItem, variantID)
+	}
+	if err != nil {
+		return err
+	}
+	if !variant.IsActive {
+		return fmt.Errorf("%w: product variant '%s' is not available", ErrInvalidItem, variant.Name)
+	}
+	if quantity < 1 || quantity < variant.MinQuantity {
+		return fmt.Errorf("%w: minimum quantity for variant '%s' is %d", ErrInvalidItem, variant.Name, max(variant.MinQuantity, 1))
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/fulfillment"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/payment"
+	"github.com/YasserCherfaoui/MarketProGo/tax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakePayments records payments in the run's transaction instead of creating
+// Revolut orders
+type fakePayments struct {
+	payment.PaymentService
+	tx       *gorm.DB
+	requests []*payment.PaymentRequest
+}
+
+func (f *fakePayments) InTx(tx *gorm.DB) payment.PaymentService {
+	f.tx = tx
+	return f
+}
+
+func (f *fakePayments) CreatePayment(ctx context.Context, req *payment.PaymentRequest) (*payment.PaymentResponse, error) {
+	f.requests = append(f.requests, req)
+	revolutID := fmt.Sprintf("rev-%d", req.OrderID)
+	record := models.Payment{OrderID: req.OrderID, RevolutOrderID: revolutID, RevolutPaymentID: revolutID, Amount: req.Amount,
+		Currency: req.Currency, Status: models.RevolutPaymentStatusPending, CheckoutURL: "https://checkout.example.com/" + revolutID}
+	if err := f.tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	method := ""
+	if req.SavedPaymentMethod != nil {
+		method = "saved_card"
+	}
+	return &payment.PaymentResponse{PaymentID: strconv.FormatUint(uint64(record.ID), 10), OrderID: revolutID, Amount: req.Amount,
+		Currency: req.Currency, Status: string(record.Status), CheckoutURL: record.CheckoutURL, PaymentMethod: method}, nil
+}
+
+type fixture struct {
+	db       *gorm.DB
+	s        *Service
+	payments *fakePayments
+	user     models.User
+	address  models.Address
+	variant  models.ProductVariant
+	stock    models.InventoryItem
+	now      time.Time
+}
+
+// setup creates a customer with an address and 5 units of a 10.00 variant,
+// with subscriptions paused after 2 failed runs
+func setup(t *testing.T) *fixture {
+	// Prices are read outside the run's transaction, so the connections need
+	// to share a database
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "subscription.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Company{}, &models.Address{},
+		&models.Product{}, &models.ProductVariant{}, &models.BundleComponent{}, &models.ProductVariantPriceTier{}, &models.PriceList{}, &models.PriceListItem{}, &models.TaxRate{},
+		&models.DeliveryZone{}, &models.Warehouse{}, &models.InventoryItem{}, &models.StockMovement{},
+		&models.Order{}, &models.OrderItem{}, &models.Shipment{}, &models.StockAllocation{}, &models.Payment{},
+		&models.WebhookSubscription{}, &models.OutboxMessage{}, &models.Subscription{}, &models.SubscriptionItem{}))
+
+	f := &fixture{db: db, payments: &fakePayments{}, now: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+	f.user = models.User{Email: "customer@example.com", Password: "x", FirstName: "Amina", UserType: models.Customer, IsActive: true}
+	require.NoError(t, db.Create(&f.user).Error)
+	f.address = models.Address{UserID: &f.user.ID, StreetAddress1: "2 Home Street", City: "Leeds", PostalCode: "LS1 1AA", Country: "GB", IsDefault: true}
+	require.NoError(t, db.Create(&f.address).Error)
+	product := models.Product{Name: "Olive Oil"}
+	require.NoError(t, db.Create(&product).Error)
+	f.variant = models.ProductVariant{ProductID: product.ID, Name: "1L", SKU: "OIL-1L", BasePrice: 10, IsActive: true, MinQuantity: 1}
+	require.NoError(t, db.Omit("Product").Create(&f.variant).Error)
+	warehouse := models.Warehouse{Name: "Leeds Warehouse", Code: "WH-LDS", IsActive: true,
+		Address: models.Address{StreetAddress1: "1 Dock Road", City: "Leeds", PostalCode: "LS2 2BB", Country: "GB"}}
+	require.NoError(t, db.Create(&warehouse).Error)
+	f.stock = models.InventoryItem{ProductVariantID: f.variant.ID, WarehouseID: warehouse.ID, Quantity: 5, Status: "active"}
+	require.NoError(t, db.Omit("ProductVariant", "Warehouse").Create(&f.stock).Error)
+
+	f.s = NewService(db, &cfg.SubscriptionConfig{ReminderDays: 3, MaxFailedRuns: 2}, tax.NewTaxService(db, &cfg.TaxConfig{}),
+		fulfillment.NewAllocator(nil), f.payments, nil)
+	f.s.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *fixture) create(t *testing.T, quantity int, in Input) *models.Subscription {
+	in.UserID = f.user.ID
+	if in.Interval == "" {
+		in.Interval = models.SubscriptionWeekly
+	}
+	in.Items = []ItemInput{{ProductVariantID: f.variant.ID, Quantity: quantity}}
+	subscription, err := f.s.Create(context.Background(), in)
+	require.NoError(t, err)
+	return subscription
+}
+
+func (f *fixture) reserved(t *testing.T) int {
+	var item models.InventoryItem
+	require.NoError(t, f.db.First(&item, f.stock.ID).Error)
+	return item.Reserved
+}
+
+func TestRunPlacesOrderOnSchedule(t *testing.T) {
+	f := setup(t)
+	ctx := context.Background()
+	subscription := f.create(t, 2, Input{RevolutCustomerID: "cus-1", SavedPaymentMethodID: "card-1"})
+	assert.Equal(t, f.address.ID, subscription.ShippingAddressID, "the default shipping address is used")
+	assert.Equal(t, f.now, subscription.NextRunAt.UTC())
+
+	placed, err := f.s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, placed)
+
+	var order models.Order
+	require.NoError(t, f.db.Preload("Items").Where("subscription_id = ?", subscription.ID).First(&order).Error)
+	assert.Equal(t, 20.0, order.FinalAmount)
+	require.Len(t, order.Items, 1)
+	assert.Equal(t, 2, order.Items[0].Quantity)
+	assert.Equal(t, 2, f.reserved(t))
+	require.Len(t, f.payments.requests, 1)
+	assert.Equal(t, &payment.SavedPaymentMethod{CustomerID: "cus-1", ID: "card-1"}, f.payments.requests[0].SavedPaymentMethod)
+
+	updated, err := f.s.Get(ctx, f.user.ID, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.now.AddDate(0, 0, 7), updated.NextRunAt.UTC())
+	require.NotNil(t, updated.LastOrderID)
+	assert.Equal(t, order.ID, *updated.LastOrderID)
+
+	// Nothing is due until the next week
+	placed, err = f.s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, placed)
+	order2, err := f.s.Run(ctx, subscription.ID)
+	require.NoError(t, err)
+	assert.Nil(t, order2)
+}
+
+func TestFailedRunsPauseSubscription(t *testing.T) {
+	f := setup(t)
+	ctx := context.Background()
+	subscription := f.create(t, 6, Input{Interval: models.SubscriptionMonthly})
+
+	// There are only 5 units in stock, so the run is skipped
+	_, err := f.s.Run(ctx, subscription.ID)
+	require.Error(t, err)
+	var orders int64
+	f.db.Model(&models.Order{}).Count(&orders)
+	assert.Zero(t, orders)
+	updated, err := f.s.Get(ctx, f.user.ID, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, updated.Status)
+	assert.Equal(t, 1, updated.FailedRuns)
+	assert.NotEmpty(t, updated.LastError)
+	assert.Equal(t, f.now.AddDate(0, 1, 0), updated.NextRunAt.UTC())
+
+	f.now = f.now.AddDate(0, 1, 0)
+	_, err = f.s.Run(ctx, subscription.ID)
+	require.Error(t, err)
+	updated, err = f.s.Get(ctx, f.user.ID, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusPaused, updated.Status, "the second failure in a row pauses the subscription")
+	assert.Equal(t, 2, updated.FailedRuns)
+}
+
+func TestSkipPauseResumeCancel(t *testing.T) {
+	f := setup(t)
+	ctx := context.Background()
+	start := f.now.AddDate(0, 0, 2)
+	subscription := f.create(t, 1, Input{Interval: models.SubscriptionBiweekly, StartAt: &start})
+
+	skipped, err := f.s.Skip(ctx, f.user.ID, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, start.AddDate(0, 0, 14), skipped.NextRunAt.UTC())
+
+	resumeAt := f.now.AddDate(0, 1, 0)
+	paused, err := f.s.Pause(ctx, f.user.ID, subscription.ID, &resumeAt)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusPaused, paused.Status)
+	_, err = f.s.Skip(ctx, f.user.ID, subscription.ID)
+	assert.ErrorIs(t, err, ErrNotActive)
+
+	// Orders due while paused are not placed once it resumes
+	f.now = resumeAt
+	resumed, err := f.s.ResumeDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+	active, err := f.s.Get(ctx, f.user.ID, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, active.Status)
+	assert.Nil(t, active.ResumeAt)
+	assert.Equal(t, start.AddDate(0, 0, 14*3), active.NextRunAt.UTC())
+	placed, err := f.s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, placed)
+
+	_, err = f.s.Resume(ctx, f.user.ID, subscription.ID)
+	assert.ErrorIs(t, err, ErrNotPaused)
+	cancelled, err := f.s.Cancel(ctx, f.user.ID, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusCancelled, cancelled.Status)
+	_, err = f.s.Pause(ctx, f.user.ID, subscription.ID, nil)
+	assert.ErrorIs(t, err, ErrCancelled)
+
+	_, err = f.s.Skip(ctx, f.user.ID+1, subscription.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "other users' subscriptions are not found")
+}
+
+func TestCreateValidatesItems(t *testing.T) {
+	f := setup(t)
+	_, err := f.s.Create(context.Background(), Input{UserID: f.user.ID, Interval: models.SubscriptionWeekly,
+		Items: []ItemInput{{ProductVariantID: f.variant.ID + 100, Quantity: 1}}})
+	assert.ErrorIs(t, err, ErrInvalidItem)
+	_, err = f.s.Create(context.Background(), Input{UserID: f.user.ID, Interval: models.SubscriptionWeekly,
+		Items: []ItemInput{{ProductVariantID: f.variant.ID, Quantity: 1}}, SavedPaymentMethodID: "card-1"})
+	assert.ErrorIs(t, err, ErrIncompletePaymentMethod)
+}
+
+func TestNextRunKeepsDayOfMonth(t *testing.T) {
+	scheduled := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC), nextRun(models.SubscriptionMonthly, scheduled, scheduled))
+	assert.Equal(t, time.Date(2026, 5, 15, 9, 0, 0, 0, time.UTC), nextRun(models.SubscriptionMonthly, scheduled, time.Date(2026, 4, 20, 0, 0, 0, 0, time.UTC)))
+}
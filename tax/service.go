@@ -0,0 +1,200 @@
+package tax
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"gorm.io/gorm"
+)
+
+// Line is a priced order line to be taxed
+type Line struct {
+	ProductVariantID uint
+	IsVAT            bool    // product is subject to VAT
+	Amount           float64 // line total at catalogue price
+}
+
+// LineTax is the VAT computed for a single line
+type LineTax struct {
+	ProductVariantID uint    `json:"product_variant_id"`
+	Rate             float64 `json:"rate"`
+	NetAmount        float64 `json:"net_amount"`
+	TaxAmount        float64 `json:"tax_amount"`
+	GrossAmount      float64 `json:"gross_amount"`
+}
+
+// RateTotal aggregates lines sharing the same VAT rate
+type RateTotal struct {
+	Rate        float64 `json:"rate"`
+	NetAmount   float64 `json:"net_amount"`
+	TaxAmount   float64 `json:"tax_amount"`
+	GrossAmount float64 `json:"gross_amount"`
+}
+
+// Breakdown is the VAT calculation result for an order
+type Breakdown struct {
+	Country     string      `json:"country"`
+	Rate        float64     `json:"rate"`
+	Lines       []LineTax   `json:"lines"`
+	Rates       []RateTotal `json:"rates"`
+	NetAmount   float64     `json:"net_amount"`
+	TaxAmount   float64     `json:"tax_amount"`
+	GrossAmount float64     `json:"gross_amount"`
+}
+
+// TaxService computes VAT per order line from the product VAT flag, the
+// customer's country and the configured rates
+type TaxService struct {
+	db     *gorm.DB
+	config *cfg.TaxConfig
+}
+
+// NewTaxService creates a new tax service
+func NewTaxService(db *gorm.DB, config *cfg.TaxConfig) *TaxService {
+	if config == nil {
+		config = &cfg.TaxConfig{DefaultCountry: "GB", DefaultRate: 20}
+	}
+	return &TaxService{db: db, config: config}
+}
+
+// RateForCountry returns the VAT percentage for a country code or name. Countries
+// without a configured rate are zero-rated, except the store's own country which
+// falls back to the default rate.
+func (s *TaxService) RateForCountry(country string) (float64, string, error) {
+	country = strings.TrimSpace(country)
+	if country == "" {
+		country = s.config.DefaultCountry
+	}
+
+	var rate models.TaxRate
+	err := s.db.Where("is_active = ?", true).
+		Where("UPPER(country_code) = ? OR UPPER(country_name) = ?", strings.ToUpper(country), strings.ToUpper(country)).
+		First(&rate).Error
+	if err == nil {
+		return rate.Rate, strings.ToUpper(rate.CountryCode), nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, "", fmt.Errorf("failed to load tax rate: %w", err)
+	}
+
+	if isStoreCountry(country, s.config.DefaultCountry) {
+		return s.config.DefaultRate, strings.ToUpper(s.config.DefaultCountry), nil
+	}
+	return 0, strings.ToUpper(country), nil
+}
+
+// PricesIncludeVAT reports whether catalogue prices are gross
+func (s *TaxService) PricesIncludeVAT() bool {
+	return s.config.PricesIncludeVAT
+}
+
+// Calculate computes the VAT breakdown for the given lines and destination country
+func (s *TaxService) Calculate(lines []Line, country string) (*Breakdown, error) {
+	rate, countryCode, err := s.RateForCountry(country)
+	if err != nil {
+		return nil, err
+	}
+	return Compute(lines, countryCode, rate, s.config.PricesIncludeVAT), nil
+}
+
+// Compute applies a single VAT rate to VAT-able lines. When pricesIncludeVAT is
+// set line amounts are treated as gross and the tax is extracted from them.
+func Compute(lines []Line, country string, rate float64, pricesIncludeVAT bool) *Breakdown {
+	breakdown := &Breakdown{
+		Country: country,
+		Rate:    rate,
+		Lines:   make([]LineTax, 0, len(lines)),
+	}
+	totals := map[float64]*RateTotal{}
+
+	for _, line := range lines {
+		lineRate := 0.0
+		if line.IsVAT {
+			lineRate = rate
+		}
+
+		var net, tax float64
+		if pricesIncludeVAT {
+			net = round(line.Amount / (1 + lineRate/100))
+			tax = round(line.Amount - net)
+		} else {
+			net = round(line.Amount)
+			tax = round(line.Amount * lineRate / 100)
+		}
+		gross := round(net + tax)
+
+		breakdown.Lines = append(breakdown.Lines, LineTax{
+			ProductVariantID: line.ProductVariantID,
+			Rate:             lineRate,
+			NetAmount:        net,
+			TaxAmount:        tax,
+			GrossAmount:      gross,
+		})
+
+		total, ok := totals[lineRate]
+		if !ok {
+			total = &RateTotal{Rate: lineRate}
+			totals[lineRate] = total
+		}
+		total.NetAmount = round(total.NetAmount + net)
+		total.TaxAmount = round(total.TaxAmount + tax)
+		total.GrossAmount = round(total.GrossAmount + gross)
+
+		breakdown.NetAmount = round(breakdown.NetAmount + net)
+		breakdown.TaxAmount = round(breakdown.TaxAmount + tax)
+		breakdown.GrossAmount = round(breakdown.GrossAmount + gross)
+	}
+
+	for _, total := range totals {
+		breakdown.Rates = append(breakdown.Rates, *total)
+	}
+	sort.Slice(breakdown.Rates, func(i, j int) bool {
+		return breakdown.Rates[i].Rate > breakdown.Rates[j].Rate
+	})
+
+	return breakdown
+}
+
+// ToJSON converts the per-rate totals for storage on orders and invoices
+func (b *Breakdown) ToJSON() models.JSON {
+	rates := make([]interface{}, 0, len(b.Rates))
+	for _, r := range b.Rates {
+		rates = append(rates, map[string]interface{}{
+			"rate":         r.Rate,
+			"net_amount":   r.NetAmount,
+			"tax_amount":   r.TaxAmount,
+			"gross_amount": r.GrossAmount,
+		})
+	}
+	return models.JSON{
+		"country":      b.Country,
+		"rates":        rates,
+		"net_amount":   b.NetAmount,
+		"tax_amount":   b.TaxAmount,
+		"gross_amount": b.GrossAmount,
+	}
+}
+
+// isStoreCountry matches common spellings of the store country against its code
+func isStoreCountry(country, storeCode string) bool {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	storeCode = strings.ToUpper(strings.TrimSpace(storeCode))
+	if country == storeCode {
+		return true
+	}
+	if storeCode == "GB" {
+		switch country {
+		case "UK", "UNITED KINGDOM", "GREAT BRITAIN", "ENGLAND", "SCOTLAND", "WALES", "NORTHERN IRELAND":
+			return true
+		}
+	}
+	return false
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,64 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.TaxRate{}))
+	return db
+}
+
+func TestRateForCountry(t *testing.T) {
+	db := setupTestDB(t)
+	service := NewTaxService(db, &cfg.TaxConfig{DefaultCountry: "GB", DefaultRate: 20})
+
+	require.NoError(t, db.Create(&models.TaxRate{CountryCode: "FR", CountryName: "France", Rate: 5.5, IsActive: true}).Error)
+
+	rate, country, err := service.RateForCountry("france")
+	require.NoError(t, err)
+	assert.Equal(t, 5.5, rate)
+	assert.Equal(t, "FR", country)
+
+	// Store country falls back to the default rate
+	rate, country, err = service.RateForCountry("United Kingdom")
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, rate)
+	assert.Equal(t, "GB", country)
+
+	// Unconfigured countries are zero-rated
+	rate, _, err = service.RateForCountry("US")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, rate)
+}
+
+func TestCompute(t *testing.T) {
+	lines := []Line{
+		{ProductVariantID: 1, IsVAT: true, Amount: 100},
+		{ProductVariantID: 2, IsVAT: false, Amount: 50},
+	}
+
+	breakdown := Compute(lines, "GB", 20, false)
+	assert.Equal(t, 20.0, breakdown.Lines[0].TaxAmount)
+	assert.Equal(t, 0.0, breakdown.Lines[1].TaxAmount)
+	assert.Equal(t, 150.0, breakdown.NetAmount)
+	assert.Equal(t, 20.0, breakdown.TaxAmount)
+	assert.Equal(t, 170.0, breakdown.GrossAmount)
+	require.Len(t, breakdown.Rates, 2)
+	assert.Equal(t, 20.0, breakdown.Rates[0].Rate)
+
+	// Gross prices have the VAT extracted rather than added
+	breakdown = Compute(lines, "GB", 20, true)
+	assert.Equal(t, 83.33, breakdown.Lines[0].NetAmount)
+	assert.Equal(t, 16.67, breakdown.Lines[0].TaxAmount)
+	assert.Equal(t, 150.0, breakdown.GrossAmount)
+}
@@ -80,7 +80,7 @@ func main() {
 		"CompanyName":    "Algeria Market",
 		"SiteURL":        "https://algeriamarket.co.uk",
 		"SupportEmail":   "enquirees@algeriamarket.co.uk",
-		"ActivationLink": "https://algeriamarket.co.uk/activate?email=test@example.com",
+		"ActivationLink": "https://algeriamarket.co.uk/activate?token=example-token",
 	}
 
 	err = emailService.SendTransactionalEmail(models.EmailTypeWelcome, data, recipient)
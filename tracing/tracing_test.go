@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPluginTracesQueries(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin()))
+	require.NoError(t, db.AutoMigrate(&widget{}))
+	require.NoError(t, db.Create(&widget{Name: "untraced"}).Error)
+	require.Empty(t, recorder.Ended())
+
+	ctx, parent := Start(context.Background(), "request")
+	require.NoError(t, db.WithContext(ctx).Create(&widget{Name: "a"}).Error)
+	var found widget
+	assert.Error(t, db.WithContext(ctx).Table("missing").Take(&found).Error)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	create, query := spans[0], spans[1]
+
+	assert.Equal(t, "db.create", create.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.Contains(t, create.Attributes(), attribute.String("db.sql.table", "widgets"))
+	assert.Equal(t, codes.Unset, create.Status().Code)
+
+	assert.Equal(t, "db.query", query.Name())
+	assert.Equal(t, codes.Error, query.Status().Code)
+}
+
+func TestSetupWithoutEndpointIsNoop(t *testing.T) {
+	shutdown, err := Setup(context.Background(), &cfg.TracingConfig{ServiceName: "test"})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Empty(t, TraceID(context.Background()))
+}
@@ -0,0 +1,324 @@
+// Package trash soft-deletes core entities together with the rows that
+// belong to them, such as a product's variants and images or a ticket's
+// responses and attachments, so that restoring the entity brings them back
+// as they were. Rows deleted longer ago than the retention period are purged
+// for good; the file cleanup then deletes their files.
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownKind   = errors.New("unknown kind")
+	ErrNotFound      = errors.New("not found")
+	ErrNotDeleted    = errors.New("not deleted")
+	ErrParentDeleted = errors.New("what it belongs to is deleted, restore that first")
+)
+
+// table is a soft-deleted table and the tables whose rows belong to its rows
+type table struct {
+	name     string
+	joins    []string // "join_table.column" rows referencing the table's rows, removed when they are purged
+	children []relation
+}
+
+type relation struct {
+	table      *table
+	foreignKey string
+}
+
+var (
+	productImages  = &table{name: "product_images"}
+	priceTiers     = &table{name: "product_variant_price_tiers"}
+	optionValues   = &table{name: "product_option_values", joins: []string{"variant_option_values.product_option_value_id"}}
+	specifications = &table{name: "product_specifications"}
+	variants       = &table{
+		name:     "product_variants",
+		joins:    []string{"variant_option_values.product_variant_id"},
+		children: []relation{{productImages, "product_variant_id"}, {priceTiers, "product_variant_id"}},
+	}
+	options  = &table{name: "product_options", children: []relation{{optionValues, "product_option_id"}}}
+	products = &table{
+		name:  "products",
+		joins: []string{"product_categories.product_id", "product_tags.product_id"},
+		children: []relation{
+			{variants, "product_id"}, {productImages, "product_id"}, {options, "product_id"}, {specifications, "product_id"},
+		},
+	}
+
+	ticketAttachments  = &table{name: "ticket_attachments"}
+	ticketResponses    = &table{name: "ticket_responses"}
+	tickets            = &table{name: "support_tickets", children: []relation{{ticketAttachments, "ticket_id"}, {ticketResponses, "ticket_id"}}}
+	disputeAttachments = &table{name: "dispute_attachments"}
+	disputeResponses   = &table{name: "dispute_responses"}
+	disputes           = &table{name: "disputes", children: []relation{{disputeAttachments, "dispute_id"}, {disputeResponses, "dispute_id"}}}
+	abuseAttachments   = &table{name: "abuse_report_attachments"}
+	abuseReports       = &table{name: "abuse_reports", children: []relation{{abuseAttachments, "abuse_report_id"}}}
+	contactInquiries   = &table{name: "contact_inquiries"}
+)
+
+// purgeOrder lists every table purged, rows that belong to others first
+var purgeOrder = []*table{
+	productImages, priceTiers, optionValues, specifications, variants, options, products,
+	ticketAttachments, ticketResponses, tickets,
+	disputeAttachments, disputeResponses, disputes,
+	abuseAttachments, abuseReports,
+	contactInquiries,
+}
+
+// Kind is an entity admins can restore
+type Kind string
+
+const (
+	Products         Kind = "products"
+	Variants         Kind = "variants"
+	Images           Kind = "images"
+	Tickets          Kind = "tickets"
+	Disputes         Kind = "disputes"
+	AbuseReports     Kind = "abuse-reports"
+	ContactInquiries Kind = "contact-inquiries"
+)
+
+type entity struct {
+	table   *table
+	scope   permissions.Scope // needed to list and restore it
+	parents []relation        // rows it belongs to, which must not be deleted when it is restored
+	list    func() interface{}
+}
+
+var entities = map[Kind]entity{
+	Products: {table: products, scope: permissions.ProductsWrite, list: func() interface{} { return &[]models.Product{} }},
+	Variants: {table: variants, scope: permissions.ProductsWrite, parents: []relation{{products, "product_id"}},
+		list: func() interface{} { return &[]models.ProductVariant{} }},
+	Images: {table: productImages, scope: permissions.ProductsWrite, parents: []relation{{products, "product_id"}, {variants, "product_variant_id"}},
+		list: func() interface{} { return &[]models.ProductImage{} }},
+	Tickets:          {table: tickets, scope: permissions.SupportAdmin, list: func() interface{} { return &[]models.SupportTicket{} }},
+	Disputes:         {table: disputes, scope: permissions.SupportAdmin, list: func() interface{} { return &[]models.Dispute{} }},
+	AbuseReports:     {table: abuseReports, scope: permissions.SupportAdmin, list: func() interface{} { return &[]models.AbuseReport{} }},
+	ContactInquiries: {table: contactInquiries, scope: permissions.SupportAdmin, list: func() interface{} { return &[]models.ContactInquiry{} }},
+}
+
+// Scope returns the permission scope needed to list and restore kind
+func Scope(kind Kind) (permissions.Scope, error) {
+	e, ok := entities[kind]
+	if !ok {
+		return "", ErrUnknownKind
+	}
+	return e.scope, nil
+}
+
+// Delete soft-deletes an entity and the rows that belong to it, all at the
+// same time, which is how Restore tells them from rows deleted on their own
+func Delete(tx *gorm.DB, kind Kind, id uint) error {
+	e, ok := entities[kind]
+	if !ok {
+		return ErrUnknownKind
+	}
+	var count int64
+	if err := tx.Table(e.table.name).Where("id = ? AND deleted_at IS NULL", id).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to find %s %d: %w", kind, id, err)
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	now := time.Now()
+	rows := func() *gorm.DB { return tx.Table(e.table.name).Where("id = ? AND deleted_at IS NULL", id) }
+	return cascade(tx, rows, e.table, "deleted_at IS NULL", nil, now)
+}
+
+// Restore undeletes an entity and the rows deleted along with it. Rows
+// deleted before it, on their own, stay deleted.
+func Restore(tx *gorm.DB, kind Kind, id uint) error {
+	e, ok := entities[kind]
+	if !ok {
+		return ErrUnknownKind
+	}
+	var row struct{ DeletedAt *time.Time }
+	result := tx.Table(e.table.name).Select("deleted_at").Where("id = ?", id).Limit(1).Scan(&row)
+	switch {
+	case result.Error != nil:
+		return fmt.Errorf("failed to find %s %d: %w", kind, id, result.Error)
+	case result.RowsAffected == 0:
+		return ErrNotFound
+	case row.DeletedAt == nil:
+		return ErrNotDeleted
+	}
+
+	for _, parent := range e.parents {
+		var deleted int64
+		err := tx.Table(parent.table.name).
+			Where("id = (?) AND deleted_at IS NOT NULL", tx.Table(e.table.name).Select(parent.foreignKey).Where("id = ?", id)).
+			Count(&deleted).Error
+		if err != nil {
+			return fmt.Errorf("failed to check %s of %s %d: %w", parent.table.name, kind, id, err)
+		}
+		if deleted > 0 {
+			return ErrParentDeleted
+		}
+	}
+
+	deletedAt := *row.DeletedAt
+	rows := func() *gorm.DB { return tx.Table(e.table.name).Where("id = ? AND deleted_at = ?", id, deletedAt) }
+	return cascade(tx, rows, e.table, "deleted_at = ?", []interface{}{deletedAt}, nil)
+}
+
+// cascade sets deleted_at to value on the rows of t selected by rows and on
+// the rows belonging to them that match state, children first so that they
+// are still found through their parents
+func cascade(tx *gorm.DB, rows func() *gorm.DB, t *table, state string, args []interface{}, value interface{}) error {
+	for _, child := range t.children {
+		childRows := func() *gorm.DB {
+			return tx.Table(child.table.name).
+				Where(child.foreignKey+" IN (?)", rows().Select("id")).
+				Where(state, args...)
+		}
+		if err := cascade(tx, childRows, child.table, state, args, value); err != nil {
+			return err
+		}
+	}
+	if err := rows().Update("deleted_at", value).Error; err != nil {
+		return fmt.Errorf("failed to update %s: %w", t.name, err)
+	}
+	return nil
+}
+
+// List returns a page of the deleted entities of kind, most recently
+// deleted first, and how many there are
+func List(db *gorm.DB, kind Kind, page, limit int) (interface{}, int64, error) {
+	e, ok := entities[kind]
+	if !ok {
+		return nil, 0, ErrUnknownKind
+	}
+	items := e.list()
+	query := db.Unscoped().Model(items).Where("deleted_at IS NOT NULL")
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count deleted %s: %w", kind, err)
+	}
+	if err := query.Order("deleted_at DESC").Limit(limit).Offset((page - 1) * limit).Find(items).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list deleted %s: %w", kind, err)
+	}
+	return items, total, nil
+}
+
+// Result counts what a purge did
+type Result struct {
+	Purged int64 `json:"purged"` // rows deleted for good, with the rows belonging to them
+	Kept   int   `json:"kept"`   // rows that could not be deleted, such as variants still on orders
+}
+
+// Service purges rows deleted longer ago than the retention period
+type Service struct {
+	db        *gorm.DB
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewService creates the purge service
+func NewService(db *gorm.DB, config *cfg.TrashConfig) *Service {
+	return &Service{db: db, retention: time.Duration(config.RetentionDays) * 24 * time.Hour, now: time.Now}
+}
+
+// Purge deletes for good the rows of every table deleted before the
+// retention period, with the rows and join table rows belonging to them.
+// Rows that cannot be deleted, because other records still reference them,
+// are kept and tried again next time.
+func (s *Service) Purge(ctx context.Context) (Result, error) {
+	db := s.db.WithContext(ctx)
+	cutoff := s.now().Add(-s.retention)
+	var result Result
+	for _, t := range purgeOrder {
+		var lastID uint
+		for {
+			var ids []uint
+			if err := db.Table(t.name).Where("deleted_at < ? AND id > ?", cutoff, lastID).
+				Order("id").Limit(200).Pluck("id", &ids).Error; err != nil {
+				return result, fmt.Errorf("failed to find %s to purge: %w", t.name, err)
+			}
+			if len(ids) == 0 {
+				break
+			}
+			lastID = ids[len(ids)-1]
+
+			purged, err := purge(db, t, ids)
+			if err == nil {
+				result.Purged += purged
+				continue
+			}
+			// One referenced row fails the batch, so the rows are tried one
+			// by one to purge the others
+			for _, id := range ids {
+				purged, err := purge(db, t, []uint{id})
+				if err != nil {
+					slog.DebugContext(ctx, "kept deleted row still referenced", "component", "trash", "table", t.name, "id", id, "error", err)
+					result.Kept++
+					continue
+				}
+				result.Purged += purged
+			}
+		}
+	}
+	return result, nil
+}
+
+// purge hard-deletes rows of t and what belongs to them in a transaction
+func purge(db *gorm.DB, t *table, ids []uint) (int64, error) {
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		purged, err = hardDelete(tx, t, func() *gorm.DB { return tx.Table(t.name).Where("id IN ?", ids) })
+		return err
+	})
+	return purged, err
+}
+
+func hardDelete(tx *gorm.DB, t *table, rows func() *gorm.DB) (int64, error) {
+	var purged int64
+	for _, child := range t.children {
+		childRows := func() *gorm.DB {
+			return tx.Table(child.table.name).Where(child.foreignKey+" IN (?)", rows().Select("id"))
+		}
+		n, err := hardDelete(tx, child.table, childRows)
+		if err != nil {
+			return 0, err
+		}
+		purged += n
+	}
+	for _, join := range t.joins {
+		joinTable, column, _ := strings.Cut(join, ".")
+		if err := tx.Exec("DELETE FROM "+joinTable+" WHERE "+column+" IN (?)", rows().Select("id")).Error; err != nil {
+			return 0, fmt.Errorf("failed to delete %s rows of %s: %w", joinTable, t.name, err)
+		}
+	}
+	deleted := tx.Exec("DELETE FROM "+t.name+" WHERE id IN (?)", rows().Select("id"))
+	if deleted.Error != nil {
+		return 0, fmt.Errorf("failed to delete %s: %w", t.name, deleted.Error)
+	}
+	return purged + deleted.RowsAffected, nil
+}
+
+// StartPurger purges deleted rows each interval until ctx is canceled
+func (s *Service) StartPurger(ctx context.Context, interval time.Duration) {
+	for worker.Sleep(ctx, interval) {
+		result, err := s.Purge(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to purge deleted rows", "component", "trash", "error", err)
+			continue
+		}
+		if result.Purged > 0 || result.Kept > 0 {
+			slog.InfoContext(ctx, "purged deleted rows", "component", "trash", "purged", result.Purged, "kept", result.Kept)
+		}
+	}
+}
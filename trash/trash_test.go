@@ -0,0 +1,156 @@
+package trash
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Category{},
+		&models.Tag{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.ProductVariantPriceTier{},
+		&models.ProductImage{},
+		&models.ProductOption{},
+		&models.ProductOptionValue{},
+		&models.ProductSpecification{},
+		&models.User{},
+		&models.SupportTicket{},
+		&models.TicketResponse{},
+		&models.TicketAttachment{},
+		&models.Dispute{},
+		&models.DisputeResponse{},
+		&models.DisputeAttachment{},
+		&models.AbuseReport{},
+		&models.AbuseReportAttachment{},
+		&models.ContactInquiry{},
+	))
+	return db
+}
+
+func ptr(id uint) *uint { return &id }
+
+// createProduct creates a product in a category with two variants, each with
+// an image and an option value, and an image of its own
+func createProduct(t *testing.T, db *gorm.DB) (models.Product, []models.ProductVariant) {
+	category := models.Category{Name: "Cheese", Slug: "cheese"}
+	require.NoError(t, db.Create(&category).Error)
+	option := models.ProductOption{Name: "Size", Values: []models.ProductOptionValue{{Value: "250g"}, {Value: "500g"}}}
+	product := models.Product{Name: "Halloumi", IsActive: true, Categories: []*models.Category{&category}, Options: []models.ProductOption{option}}
+	require.NoError(t, db.Create(&product).Error)
+	values := product.Options[0].Values
+
+	variants := []models.ProductVariant{
+		{ProductID: product.ID, Name: "250g", SKU: "HAL-250", OptionValues: []*models.ProductOptionValue{&values[0]}},
+		{ProductID: product.ID, Name: "500g", SKU: "HAL-500", OptionValues: []*models.ProductOptionValue{&values[1]}},
+	}
+	require.NoError(t, db.Omit("Product").Create(&variants).Error)
+	for _, variant := range variants {
+		require.NoError(t, db.Create(&models.ProductImage{ProductVariantID: ptr(variant.ID), URL: variant.SKU + ".jpg"}).Error)
+	}
+	require.NoError(t, db.Create(&models.ProductImage{ProductID: ptr(product.ID), URL: "halloumi.jpg"}).Error)
+	return product, variants
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	var n int64
+	require.NoError(t, db.Model(model).Count(&n).Error)
+	return n
+}
+
+func TestDeleteAndRestoreProduct(t *testing.T) {
+	db := setupTestDB(t)
+	product, variants := createProduct(t, db)
+
+	// A variant deleted on its own before the product stays deleted
+	require.NoError(t, Delete(db, Variants, variants[1].ID))
+	assert.EqualValues(t, 2, count(t, db, &models.ProductImage{}))
+
+	require.NoError(t, Delete(db, Products, product.ID))
+	assert.EqualValues(t, 0, count(t, db, &models.Product{}))
+	assert.EqualValues(t, 0, count(t, db, &models.ProductVariant{}))
+	assert.EqualValues(t, 0, count(t, db, &models.ProductImage{}))
+	assert.EqualValues(t, 0, count(t, db, &models.ProductOptionValue{}))
+	assert.ErrorIs(t, Delete(db, Products, product.ID), ErrNotFound)
+	assert.ErrorIs(t, Restore(db, Variants, variants[0].ID), ErrParentDeleted)
+
+	require.NoError(t, Restore(db, Products, product.ID))
+	var restored models.Product
+	require.NoError(t, db.Preload("Categories").Preload("Variants.OptionValues").Preload("Images").Preload("Options.Values").First(&restored, product.ID).Error)
+	assert.Len(t, restored.Categories, 1, "categories are kept")
+	require.Len(t, restored.Variants, 1)
+	assert.Equal(t, variants[0].ID, restored.Variants[0].ID)
+	assert.Len(t, restored.Variants[0].OptionValues, 1)
+	assert.Len(t, restored.Images, 1)
+	assert.Len(t, restored.Options[0].Values, 2)
+	assert.EqualValues(t, 2, count(t, db, &models.ProductImage{}))
+
+	// Then the variant deleted on its own, with its image
+	assert.ErrorIs(t, Restore(db, Products, product.ID), ErrNotDeleted)
+	require.NoError(t, Restore(db, Variants, variants[1].ID))
+	assert.EqualValues(t, 2, count(t, db, &models.ProductVariant{}))
+	assert.EqualValues(t, 3, count(t, db, &models.ProductImage{}))
+}
+
+func TestList(t *testing.T) {
+	db := setupTestDB(t)
+	product, _ := createProduct(t, db)
+	require.NoError(t, Delete(db, Products, product.ID))
+
+	items, total, err := List(db, Products, 1, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	listed := *items.(*[]models.Product)
+	require.Len(t, listed, 1)
+	assert.Equal(t, product.ID, listed[0].ID)
+
+	_, _, err = List(db, Kind("orders"), 1, 20)
+	assert.ErrorIs(t, err, ErrUnknownKind)
+}
+
+func TestPurge(t *testing.T) {
+	db := setupTestDB(t)
+	product, _ := createProduct(t, db)
+	user := models.User{Email: "amira@example.com", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+	ticket := models.SupportTicket{UserID: user.ID, Title: "Missing item", Description: "One jar was missing"}
+	require.NoError(t, db.Omit("User").Create(&ticket).Error)
+	require.NoError(t, db.Create(&models.TicketResponse{TicketID: ticket.ID, UserID: user.ID, Message: "Sorry"}).Error)
+
+	require.NoError(t, Delete(db, Products, product.ID))
+	require.NoError(t, Delete(db, Tickets, ticket.ID))
+
+	service := NewService(db, &cfg.TrashConfig{RetentionDays: 30})
+	service.now = func() time.Time { return time.Now().AddDate(0, 0, 29) }
+	result, err := service.Purge(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result.Purged, "rows within retention are kept")
+
+	service.now = func() time.Time { return time.Now().AddDate(0, 0, 31) }
+	result, err = service.Purge(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 11, result.Purged)
+	assert.Zero(t, result.Kept)
+	for _, table := range purgeOrder {
+		var n int64
+		require.NoError(t, db.Table(table.name).Count(&n).Error)
+		assert.Zero(t, n, table.name)
+	}
+	var joins int64
+	require.NoError(t, db.Table("product_categories").Count(&joins).Error)
+	assert.Zero(t, joins)
+	require.NoError(t, db.Table("variant_option_values").Count(&joins).Error)
+	assert.Zero(t, joins)
+}
@@ -0,0 +1,217 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/aw"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/worker"
+	"gorm.io/gorm"
+)
+
+// MaxFileSize is the largest support attachment accepted
+const MaxFileSize = 10 << 20
+
+var (
+	ErrFileTooLarge       = errors.New("file exceeds the maximum size of 10MB")
+	ErrFileTypeNotAllowed = errors.New("file type is not allowed")
+	ErrInfected           = errors.New("file failed the virus scan")
+)
+
+// allowedTypes maps the accepted content types to their file extensions
+var allowedTypes = map[string][]string{
+	"image/jpeg":      {".jpg", ".jpeg"},
+	"image/png":       {".png"},
+	"image/gif":       {".gif"},
+	"image/webp":      {".webp"},
+	"application/pdf": {".pdf"},
+	"text/plain":      {".txt", ".log", ".csv"},
+}
+
+// Store saves uploaded files in a storage backend
+type Store interface {
+	Upload(fileHeader *multipart.FileHeader) (fileID, url string, err error)
+	Delete(fileID string) error
+}
+
+// Scanner checks a file for malware before it is stored. It returns
+// ErrInfected, or an error wrapping it, when the file must be rejected.
+type Scanner interface {
+	Scan(fileName string, content io.Reader) error
+}
+
+// NoopScanner accepts every file; it is used until a scanner is configured
+type NoopScanner struct{}
+
+// Scan implements Scanner
+func (NoopScanner) Scan(string, io.Reader) error { return nil }
+
+// AppwriteStore stores uploads in the Appwrite bucket
+type AppwriteStore struct {
+	service *aw.AppwriteService
+}
+
+// NewAppwriteStore creates a store backed by Appwrite
+func NewAppwriteStore(service *aw.AppwriteService) *AppwriteStore {
+	return &AppwriteStore{service: service}
+}
+
+// Upload implements Store
+func (s *AppwriteStore) Upload(fileHeader *multipart.FileHeader) (string, string, error) {
+	if s.service == nil {
+		return "", "", errors.New("appwrite storage not configured")
+	}
+	fileID, err := s.service.UploadFile(fileHeader)
+	if err != nil {
+		return "", "", err
+	}
+	return fileID, s.service.GetFileURL(fileID), nil
+}
+
+// Delete implements Store
+func (s *AppwriteStore) Delete(fileID string) error {
+	if s.service == nil {
+		return errors.New("appwrite storage not configured")
+	}
+	return s.service.DeleteFile(fileID)
+}
+
+// Service validates, scans and stores support attachments and removes the
+// ones that are never attached to a ticket, dispute or abuse report
+type Service struct {
+	db      *gorm.DB
+	store   Store
+	scanner Scanner
+}
+
+// NewService creates an upload service
+func NewService(db *gorm.DB, store Store) *Service {
+	return &Service{db: db, store: store, scanner: NoopScanner{}}
+}
+
+// WithScanner sets the virus scanner uploads are checked with
+func (s *Service) WithScanner(scanner Scanner) *Service {
+	s.scanner = scanner
+	return s
+}
+
+// Upload validates and stores a file uploaded by userID
+func (s *Service) Upload(userID uint, fileHeader *multipart.FileHeader) (*models.SupportUpload, error) {
+	if fileHeader.Size > MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	contentType, err := DetectType(fileHeader.Filename, head[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if err := s.scanner.Scan(fileHeader.Filename, file); err != nil {
+		return nil, err
+	}
+
+	fileID, url, err := s.store.Upload(fileHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	upload := models.SupportUpload{
+		UserID:   userID,
+		FileID:   fileID,
+		FileName: filepath.Base(fileHeader.Filename),
+		FileURL:  url,
+		FileSize: fileHeader.Size,
+		FileType: contentType,
+	}
+	if err := s.db.Create(&upload).Error; err != nil {
+		return nil, fmt.Errorf("failed to record upload: %w", err)
+	}
+	return &upload, nil
+}
+
+// DetectType returns the content type of a file from its first bytes, and
+// rejects types that are not allowed or do not match the file extension
+func DetectType(fileName string, head []byte) (string, error) {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "", ErrFileTypeNotAllowed
+	}
+	extensions, ok := allowedTypes[contentType]
+	if !ok {
+		return "", ErrFileTypeNotAllowed
+	}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, allowed := range extensions {
+		if ext == allowed {
+			return contentType, nil
+		}
+	}
+	return "", ErrFileTypeNotAllowed
+}
+
+// CleanupOrphans deletes uploads older than olderThan that no ticket, dispute,
+// abuse report or order message attachment references, and returns how many were removed
+func (s *Service) CleanupOrphans(olderThan time.Duration) (int, error) {
+	var orphans []models.SupportUpload
+	if err := s.db.
+		Where("created_at < ?", time.Now().Add(-olderThan)).
+		Where("NOT EXISTS (SELECT 1 FROM ticket_attachments WHERE ticket_attachments.file_url = support_uploads.file_url AND ticket_attachments.deleted_at IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM dispute_attachments WHERE dispute_attachments.file_url = support_uploads.file_url AND dispute_attachments.deleted_at IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM abuse_report_attachments WHERE abuse_report_attachments.file_url = support_uploads.file_url AND abuse_report_attachments.deleted_at IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM order_message_attachments WHERE order_message_attachments.file_url = support_uploads.file_url AND order_message_attachments.deleted_at IS NULL)").
+		Find(&orphans).Error; err != nil {
+		return 0, fmt.Errorf("failed to find orphaned uploads: %w", err)
+	}
+
+	removed := 0
+	for _, orphan := range orphans {
+		if err := s.store.Delete(orphan.FileID); err != nil {
+			log.Printf("Failed to delete orphaned upload %d from storage: %v", orphan.ID, err)
+			continue
+		}
+		if err := s.db.Delete(&orphan).Error; err != nil {
+			return removed, fmt.Errorf("failed to delete orphaned upload %d: %w", orphan.ID, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// StartOrphanCleanup periodically removes orphaned uploads until ctx is canceled
+func (s *Service) StartOrphanCleanup(ctx context.Context, interval, olderThan time.Duration) {
+	log.Printf("🧹 UPLOADS: Starting orphaned upload cleanup (older than %s)", olderThan)
+	for {
+		if removed, err := s.CleanupOrphans(olderThan); err != nil {
+			log.Printf("❌ UPLOADS: %v", err)
+		} else if removed > 0 {
+			log.Printf("🧹 UPLOADS: Removed %d orphaned uploads", removed)
+		}
+		if !worker.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
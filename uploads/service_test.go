@@ -0,0 +1,116 @@
+package uploads
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakeStore struct {
+	files   map[string]bool
+	counter int
+}
+
+func (s *fakeStore) Upload(fileHeader *multipart.FileHeader) (string, string, error) {
+	s.counter++
+	id := fileHeader.Filename + "-" + strconv.Itoa(s.counter)
+	s.files[id] = true
+	return id, "/file/preview/" + id, nil
+}
+
+func (s *fakeStore) Delete(fileID string) error {
+	delete(s.files, fileID)
+	return nil
+}
+
+type rejectScanner struct{}
+
+func (rejectScanner) Scan(string, io.Reader) error { return ErrInfected }
+
+func setupTest(t *testing.T) (*Service, *fakeStore, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.SupportUpload{}, &models.TicketAttachment{}, &models.DisputeAttachment{}, &models.AbuseReportAttachment{}, &models.OrderMessageAttachment{}))
+	store := &fakeStore{files: map[string]bool{}}
+	return NewService(db, store), store, db
+}
+
+// fileHeader builds a multipart file header the way gin's FormFile returns it
+func fileHeader(t *testing.T, name string, content []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", name)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	require.NoError(t, req.ParseMultipartForm(MaxFileSize))
+	return req.MultipartForm.File["file"][0]
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestUploadValidatesType(t *testing.T) {
+	service, store, _ := setupTest(t)
+
+	upload, err := service.Upload(7, fileHeader(t, "photo.png", pngHeader))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", upload.FileType)
+	assert.Equal(t, uint(7), upload.UserID)
+	assert.Len(t, store.files, 1)
+
+	// The extension must match the content
+	_, err = service.Upload(7, fileHeader(t, "photo.pdf", pngHeader))
+	assert.True(t, errors.Is(err, ErrFileTypeNotAllowed))
+
+	_, err = service.Upload(7, fileHeader(t, "setup.exe", []byte("MZ\x90\x00\x03\x00\x00\x00")))
+	assert.True(t, errors.Is(err, ErrFileTypeNotAllowed))
+
+	_, err = service.WithScanner(rejectScanner{}).Upload(7, fileHeader(t, "notes.txt", []byte("hello")))
+	assert.True(t, errors.Is(err, ErrInfected))
+	assert.Len(t, store.files, 1)
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	service, store, db := setupTest(t)
+
+	attached, err := service.Upload(1, fileHeader(t, "receipt.txt", []byte("receipt")))
+	require.NoError(t, err)
+	orphan, err := service.Upload(1, fileHeader(t, "forgotten.txt", []byte("forgotten")))
+	require.NoError(t, err)
+	fresh, err := service.Upload(1, fileHeader(t, "fresh.txt", []byte("fresh")))
+	require.NoError(t, err)
+	sent, err := service.Upload(1, fileHeader(t, "photo.txt", []byte("photo")))
+	require.NoError(t, err)
+
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, db.Model(&models.SupportUpload{}).Where("id IN ?", []uint{attached.ID, orphan.ID, sent.ID}).Update("created_at", old).Error)
+	require.NoError(t, db.Create(&models.TicketAttachment{TicketID: 1, FileName: attached.FileName, FileURL: attached.FileURL}).Error)
+	require.NoError(t, db.Create(&models.OrderMessageAttachment{MessageID: 1, FileName: sent.FileName, FileURL: sent.FileURL}).Error)
+
+	removed, err := service.CleanupOrphans(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.False(t, store.files[orphan.FileID])
+	assert.True(t, store.files[attached.FileID])
+	assert.True(t, store.files[fresh.FileID])
+	assert.True(t, store.files[sent.FileID], "order message attachments are kept")
+
+	var remaining int64
+	db.Model(&models.SupportUpload{}).Count(&remaining)
+	assert.Equal(t, int64(3), remaining)
+}
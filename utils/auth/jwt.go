@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"errors"
 	"os"
 	"time"
 
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
 	"github.com/YasserCherfaoui/MarketProGo/models"
 	"github.com/golang-jwt/jwt"
 )
@@ -12,14 +14,24 @@ type MyClaims struct {
 	UserID    uint            `json:"user_id"`
 	UserType  models.UserType `json:"user_type"`
 	CompanyID *uint           `json:"company_id"`
+	MFA       bool            `json:"mfa,omitempty"` // authenticated with a second factor
+	// ImpersonatorID is the staff user acting as UserID in an impersonation session
+	ImpersonatorID *uint `json:"impersonator_id,omitempty"`
 	jwt.StandardClaims
 }
 
 func GenerateToken(userID uint, userType models.UserType, companyID *uint) (string, error) {
+	return GenerateSessionToken(userID, userType, companyID, false)
+}
+
+// GenerateSessionToken issues an access token recording whether the session
+// passed two-factor authentication
+func GenerateSessionToken(userID uint, userType models.UserType, companyID *uint, mfa bool) (string, error) {
 	claims := MyClaims{
 		UserID:    userID,
 		UserType:  userType,
 		CompanyID: companyID,
+		MFA:       mfa,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
 		},
@@ -29,14 +41,54 @@ func GenerateToken(userID uint, userType models.UserType, companyID *uint) (stri
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 }
 
+// GenerateImpersonationToken issues a short-lived token that acts as the target
+// user on behalf of the impersonator
+func GenerateImpersonationToken(user models.User, impersonatorID uint, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	claims := MyClaims{
+		UserID:         user.ID,
+		UserType:       user.UserType,
+		CompanyID:      user.CompanyID,
+		ImpersonatorID: &impersonatorID,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return signed, expiresAt, err
+}
+
+// ValidateToken checks a token signed with JWT_SECRET or, while the secret is
+// rotated, with JWT_SECRET_PREVIOUS, so tokens issued before the rotation
+// work until they expire
 func ValidateToken(tokenString string) (*MyClaims, error) {
+	claims, err := parseToken(tokenString, os.Getenv("JWT_SECRET"))
+	if err == nil {
+		metrics.SecretMatched("jwt", false)
+		return claims, nil
+	}
+
+	var invalid *jwt.ValidationError
+	previous := os.Getenv("JWT_SECRET_PREVIOUS")
+	if previous == "" || !errors.As(err, &invalid) || invalid.Errors&jwt.ValidationErrorSignatureInvalid == 0 {
+		return nil, err
+	}
+	claims, previousErr := parseToken(tokenString, previous)
+	if previousErr != nil {
+		return nil, err
+	}
+	metrics.SecretMatched("jwt", true)
+	return claims, nil
+}
+
+func parseToken(tokenString, secret string) (*MyClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &MyClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
+		return []byte(secret), nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
-
 	return token.Claims.(*MyClaims), nil
 }
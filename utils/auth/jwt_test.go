@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTokenDuringRotation(t *testing.T) {
+	t.Setenv("JWT_SECRET", "old-secret")
+	t.Setenv("JWT_SECRET_PREVIOUS", "")
+	oldToken, err := GenerateToken(7, models.Customer, nil)
+	require.NoError(t, err)
+
+	// Rotate: tokens signed with the old secret still work
+	t.Setenv("JWT_SECRET", "new-secret")
+	t.Setenv("JWT_SECRET_PREVIOUS", "old-secret")
+	claims, err := ValidateToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+
+	newToken, err := GenerateToken(8, models.Customer, nil)
+	require.NoError(t, err)
+	claims, err = ValidateToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(8), claims.UserID)
+
+	// Tokens signed with neither secret are refused
+	t.Setenv("JWT_SECRET", "other-secret")
+	t.Setenv("JWT_SECRET_PREVIOUS", "new-secret")
+	_, err = ValidateToken(oldToken)
+	assert.Error(t, err)
+
+	// Once the rotation is over only the current secret works
+	t.Setenv("JWT_SECRET", "new-secret")
+	t.Setenv("JWT_SECRET_PREVIOUS", "")
+	_, err = ValidateToken(oldToken)
+	assert.Error(t, err)
+}
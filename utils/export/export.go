@@ -0,0 +1,127 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is a tabular export file format
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// csvFlushEvery is how many CSV rows are buffered before they are flushed to the client
+const csvFlushEvery = 100
+
+// ParseFormat parses a format query parameter; empty means CSV
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(value))) {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatXLSX:
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q, use csv or xlsx", value)
+	}
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FileName returns name with the format's extension
+func (f Format) FileName(name string) string {
+	return name + "." + string(f)
+}
+
+// Writer writes rows of an export. The first row written is the header.
+type Writer interface {
+	WriteRow(values []string) error
+	// Close flushes any buffered rows; it must be called once all rows are written
+	Close() error
+}
+
+// NewWriter creates a writer for format that writes to w. CSV rows are
+// flushed as they are written; XLSX rows go through excelize's stream writer,
+// which spills to disk instead of holding the sheet in memory.
+func NewWriter(format Format, w io.Writer, sheet string) (Writer, error) {
+	if format == FormatXLSX {
+		return newXLSXWriter(w, sheet)
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+type csvWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func (c *csvWriter) WriteRow(values []string) error {
+	if err := c.w.Write(values); err != nil {
+		return err
+	}
+	c.rows++
+	if c.rows%csvFlushEvery == 0 {
+		c.w.Flush()
+		return c.w.Error()
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(w io.Writer, sheet string) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		file.Close()
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &xlsxWriter{out: w, file: file, stream: stream}, nil
+}
+
+func (x *xlsxWriter) WriteRow(values []string) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		row[i] = value
+	}
+	return x.stream.SetRow(cell, row)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.out)
+}
@@ -16,6 +16,8 @@ type APIResponse struct {
 type APIError struct {
 	Code        string `json:"code"`
 	Description string `json:"description"`
+	// Fields lists the invalid fields of a request that failed validation
+	Fields []FieldError `json:"fields,omitempty"`
 }
 
 func NewAPIError(code string, description string) *APIError {
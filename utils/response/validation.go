@@ -0,0 +1,188 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Field error codes, set on each FieldError of a request that failed
+// validation. They are stable; messages may change.
+const (
+	FieldRequired      = "required"       // the field is missing or empty
+	FieldInvalidType   = "invalid_type"   // the value has the wrong JSON type, e.g. a string for a number
+	FieldInvalidFormat = "invalid_format" // the value is not a valid email, URL, date, phone number and so on
+	FieldTooSmall      = "too_small"      // below the minimum value, length or number of items
+	FieldTooLarge      = "too_large"      // above the maximum value, length or number of items
+	FieldWrongLength   = "wrong_length"   // not the exact length required
+	FieldNotAllowed    = "not_allowed"    // not one of the allowed values
+	FieldInvalid       = "invalid"        // any other rule
+)
+
+// FieldError is a problem with one field of a request. Field is the path of
+// the field as sent, such as "items[0].quantity".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report fields by the names clients send them under rather than the Go
+	// struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// GenerateValidationErrorResponse responds 400 to a request that could not
+// be bound, listing each invalid field with a code and message. code is the
+// endpoint's error code.
+func GenerateValidationErrorResponse(c *gin.Context, code string, err error) {
+	fields := ValidationErrors(err)
+	description := "Invalid request body"
+	switch {
+	case len(fields) > 0:
+		messages := make([]string, len(fields))
+		for i, field := range fields {
+			messages[i] = strings.TrimSpace(field.Field + " " + field.Message)
+		}
+		description = "Invalid request: " + strings.Join(messages, "; ")
+	case errors.Is(err, io.EOF):
+		description = "Request body is empty"
+	}
+
+	apiErr := NewAPIError(code, description)
+	apiErr.Fields = fields
+	GenerateResponse(c, http.StatusBadRequest, description, nil, apiErr)
+}
+
+// ValidationErrors converts a binding error into field errors. Errors that
+// are not about a field, such as malformed JSON, give none.
+func ValidationErrors(err error) []FieldError {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields := make([]FieldError, len(invalid))
+		for i, fe := range invalid {
+			fields[i] = fieldError(fe)
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{Field: jsonPath(typeErr.Field), Code: FieldInvalidType, Message: "must be " + typeName(typeErr.Type)}}
+	}
+	var timeErr *time.ParseError
+	if errors.As(err, &timeErr) {
+		return []FieldError{{Code: FieldInvalidFormat, Message: "must be a date in RFC 3339 format, such as 2006-01-02T15:04:05Z"}}
+	}
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return []FieldError{{Code: FieldInvalidType, Message: fmt.Sprintf("%q is not a valid number", numErr.Num)}}
+	}
+	return nil
+}
+
+// fieldError describes a failed validation rule
+func fieldError(fe validator.FieldError) FieldError {
+	field := fe.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:] // without the request struct name
+	}
+	out := FieldError{Field: field, Code: FieldInvalid, Message: "is invalid"}
+
+	counted := "" // what min and max count, if not a value
+	switch fe.Kind() {
+	case reflect.String:
+		counted = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		counted = " items"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		out.Code, out.Message = FieldRequired, "is required"
+	case "email":
+		out.Code, out.Message = FieldInvalidFormat, "must be a valid email address"
+	case "url", "http_url", "uri":
+		out.Code, out.Message = FieldInvalidFormat, "must be a valid URL"
+	case "e164":
+		out.Code, out.Message = FieldInvalidFormat, "must be a phone number in international format, such as +447700900123"
+	case "numeric", "number":
+		out.Code, out.Message = FieldInvalidFormat, "must contain only digits"
+	case "uuid", "uuid4", "datetime", "alphanum", "alpha", "iso3166_1_alpha2", "iso4217", "hexcolor":
+		out.Code, out.Message = FieldInvalidFormat, "is not in the expected format"
+	case "min", "gte":
+		out.Code, out.Message = FieldTooSmall, "must be at least "+fe.Param()+counted
+	case "gt":
+		out.Code, out.Message = FieldTooSmall, "must be more than "+fe.Param()+counted
+	case "max", "lte":
+		out.Code, out.Message = FieldTooLarge, "must be at most "+fe.Param()+counted
+	case "lt":
+		out.Code, out.Message = FieldTooLarge, "must be less than "+fe.Param()+counted
+	case "len":
+		out.Code, out.Message = FieldWrongLength, "must be exactly "+fe.Param()+counted
+	case "oneof":
+		out.Code, out.Message = FieldNotAllowed, "must be one of: "+strings.Join(strings.Fields(fe.Param()), ", ")
+	}
+	return out
+}
+
+// jsonPath writes the array indexes of a path from encoding/json, such as
+// "items.0.quantity", the way validation errors do: "items[0].quantity"
+func jsonPath(path string) string {
+	var out strings.Builder
+	for i, part := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(part); err == nil && i > 0 {
+			out.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			out.WriteByte('.')
+		}
+		out.WriteString(part)
+	}
+	return out.String()
+}
+
+// typeName names a Go type the way a JSON client would see it
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "of type " + t.String()
+}
@@ -0,0 +1,68 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationItem struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
+type validationRequest struct {
+	Email    string           `json:"email" binding:"required,email"`
+	Name     string           `json:"name" binding:"max=5"`
+	Priority string           `json:"priority" binding:"omitempty,oneof=low high"`
+	Items    []validationItem `json:"items" binding:"required,min=1,dive"`
+}
+
+func bindAndRespond(t *testing.T, body string) (int, APIError) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	var req validationRequest
+	err := c.ShouldBindJSON(&req)
+	require.Error(t, err)
+	GenerateValidationErrorResponse(c, "test/create", err)
+
+	var resp APIResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Error)
+	return w.Code, *resp.Error
+}
+
+func TestGenerateValidationErrorResponse(t *testing.T) {
+	status, apiErr := bindAndRespond(t, `{"email": "nope", "name": "too long", "priority": "urgent", "items": [{"product_id": 1, "quantity": 0}]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "test/create", apiErr.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "email", Code: FieldInvalidFormat, Message: "must be a valid email address"},
+		{Field: "name", Code: FieldTooLarge, Message: "must be at most 5 characters"},
+		{Field: "priority", Code: FieldNotAllowed, Message: "must be one of: low, high"},
+		{Field: "items[0].quantity", Code: FieldRequired, Message: "is required"},
+	}, apiErr.Fields)
+	assert.Contains(t, apiErr.Description, "email must be a valid email address")
+
+	// Wrong JSON types are reported against the field
+	_, apiErr = bindAndRespond(t, `{"email": "a@example.com", "items": [{"product_id": "one", "quantity": 1}]}`)
+	assert.Equal(t, []FieldError{{Field: "items[0].product_id", Code: FieldInvalidType, Message: "must be a whole number"}}, apiErr.Fields)
+
+	// Errors that are not about a field have none
+	_, apiErr = bindAndRespond(t, `{"email": `)
+	assert.Empty(t, apiErr.Fields)
+	assert.Equal(t, "Invalid request body", apiErr.Description)
+	_, apiErr = bindAndRespond(t, ``)
+	assert.Equal(t, "Request body is empty", apiErr.Description)
+}
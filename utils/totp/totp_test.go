@@ -0,0 +1,57 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 SHA1 test secret
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeAtRFCVectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, expected := range vectors {
+		code, err := CodeAt(rfcSecret, time.Unix(ts, 0))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "timestamp %d", ts)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := CodeAt(secret, now)
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/Period, step)
+
+	// Codes from the adjacent step are accepted to tolerate clock drift
+	_, ok = Validate(secret, code, now.Add(Period*time.Second))
+	assert.True(t, ok)
+
+	_, ok = Validate(secret, code, now.Add(5*Period*time.Second))
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Algeria Market", "user@example.com", "SECRET")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Algeria%20Market:user@example.com?"))
+	assert.Contains(t, uri, "secret=SECRET")
+}
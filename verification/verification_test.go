@@ -0,0 +1,70 @@
+package verification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/cfg"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+
+func setup(t *testing.T) (*Service, *models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	user := models.User{Email: "nadia@example.com", Password: "x", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+
+	s := NewService(db, &cfg.VerificationConfig{Secret: "secret", ExpiryHours: 72, Required: true})
+	s.now = func() time.Time { return testNow }
+	return s, &user
+}
+
+func TestActivate(t *testing.T) {
+	s, user := setup(t)
+	token := s.Token(user)
+
+	verified, err := s.IsVerified(user.ID)
+	require.NoError(t, err)
+	assert.False(t, verified)
+
+	activated, err := s.Activate(token)
+	require.NoError(t, err)
+	require.NotNil(t, activated.VerifiedAt)
+	assert.True(t, activated.VerifiedAt.Equal(testNow))
+
+	s.now = func() time.Time { return testNow.Add(100 * time.Hour) }
+	again, err := s.Activate(token)
+	require.NoError(t, err, "opening the link again after it expired is harmless")
+	assert.True(t, again.VerifiedAt.Equal(testNow))
+
+	verified, err = s.IsVerified(user.ID)
+	require.NoError(t, err)
+	assert.True(t, verified)
+}
+
+func TestActivateRejectsBadTokens(t *testing.T) {
+	s, user := setup(t)
+	token := s.Token(user)
+
+	for _, bad := range []string{"", "nonsense", token + "x", "999" + token[1:]} {
+		_, err := s.Activate(bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+
+	require.NoError(t, s.db.Model(user).Update("email", "nadia@example.org").Error)
+	_, err := s.Activate(token)
+	assert.ErrorIs(t, err, ErrInvalidToken, "a link stops working when the address changes")
+
+	user.Email = "nadia@example.org"
+	token = s.Token(user)
+	s.now = func() time.Time { return testNow.Add(73 * time.Hour) }
+	_, err = s.Activate(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
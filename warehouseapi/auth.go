@@ -0,0 +1,77 @@
+package warehouseapi
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/permissions"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/warehouseapi/warehousepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes is the permission scope each method needs, the same as its
+// REST counterpart. Methods missing from it are refused.
+var methodScopes = map[string]permissions.Scope{
+	warehousepb.WarehouseService_GetStock_FullMethodName:    permissions.InventoryRead,
+	warehousepb.WarehouseService_ListStock_FullMethodName:   permissions.InventoryRead,
+	warehousepb.WarehouseService_AdjustStock_FullMethodName: permissions.InventoryWrite,
+	warehousepb.WarehouseService_PullOrders_FullMethodName:  permissions.OrdersRead,
+}
+
+type claimsKey struct{}
+
+// UnaryAuth authenticates each call with the bearer token in its
+// "authorization" metadata and checks the user's role holds the method's
+// scope, as middlewares.RequireScope does for REST
+func UnaryAuth() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		claims, err := authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+func authorize(ctx context.Context, method string) (*auth.MyClaims, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "method has no permission scope")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "token is required")
+	}
+	claims, err := auth.ValidateToken(strings.TrimPrefix(values[0], "Bearer "))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "token is invalid")
+	}
+
+	// Integrations act as themselves, never as an impersonated customer
+	if claims.ImpersonatorID != nil {
+		return nil, status.Error(codes.PermissionDenied, "impersonation tokens cannot be used")
+	}
+	if !permissions.HasAny(claims.UserType, scope) {
+		return nil, status.Error(codes.PermissionDenied, "missing required permission")
+	}
+	if claims.UserType == models.Admin && os.Getenv("ADMIN_REQUIRE_2FA") == "true" && !claims.MFA {
+		return nil, status.Error(codes.PermissionDenied, "two-factor authentication required")
+	}
+	return claims, nil
+}
+
+// userID returns the ID of the user making the call
+func userID(ctx context.Context) *uint {
+	if claims, ok := ctx.Value(claimsKey{}).(*auth.MyClaims); ok {
+		return &claims.UserID
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+// Package warehouseapi serves the internal gRPC API used by the warehouse
+// management system and ERP: stock queries, stock adjustments and pulling
+// orders to fulfil. It is defined in proto/warehouse/v1/warehouse.proto.
+package warehouseapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/metrics"
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/stock"
+	"github.com/YasserCherfaoui/MarketProGo/warehouseapi/warehousepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// Page sizes of ListStock and PullOrders
+const (
+	defaultStockPageSize = 100
+	maxStockPageSize     = 500
+	defaultOrderPageSize = 50
+	maxOrderPageSize     = 200
+)
+
+// Server implements warehousepb.WarehouseServiceServer
+type Server struct {
+	warehousepb.UnimplementedWarehouseServiceServer
+	db    *gorm.DB
+	stock *stock.Service
+}
+
+// NewServer creates the warehouse API server
+func NewServer(db *gorm.DB) *Server {
+	return &Server{db: db, stock: stock.NewService(db)}
+}
+
+// Register serves the warehouse API on a gRPC server
+func Register(registrar grpc.ServiceRegistrar, db *gorm.DB) {
+	warehousepb.RegisterWarehouseServiceServer(registrar, NewServer(db))
+}
+
+// GetStock returns the stock of a variant per batch
+func (s *Server) GetStock(ctx context.Context, req *warehousepb.GetStockRequest) (*warehousepb.GetStockResponse, error) {
+	variantID, err := s.variantID(ctx, req.GetSku(), req.GetVariantId())
+	if err != nil {
+		return nil, err
+	}
+	query := s.stockQuery(ctx).Where("inventory_items.product_variant_id = ?", variantID)
+	if req.GetWarehouseCode() != "" {
+		warehouseID, err := s.warehouseID(ctx, req.GetWarehouseCode())
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("inventory_items.warehouse_id = ?", warehouseID)
+	}
+
+	var items []models.InventoryItem
+	if err := query.Order("inventory_items.id").Find(&items).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to get stock")
+	}
+	resp := &warehousepb.GetStockResponse{Levels: make([]*warehousepb.StockLevel, len(items))}
+	for i := range items {
+		resp.Levels[i] = stockLevel(&items[i])
+	}
+	return resp, nil
+}
+
+// ListStock returns the stock of every batch in pages, in order of ID
+func (s *Server) ListStock(ctx context.Context, req *warehousepb.ListStockRequest) (*warehousepb.ListStockResponse, error) {
+	afterID, err := parsePageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	pageSize := pageSize(req.GetPageSize(), defaultStockPageSize, maxStockPageSize)
+
+	query := s.stockQuery(ctx).Where("inventory_items.id > ?", afterID)
+	if req.GetWarehouseCode() != "" {
+		warehouseID, err := s.warehouseID(ctx, req.GetWarehouseCode())
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("inventory_items.warehouse_id = ?", warehouseID)
+	}
+	if req.GetUpdatedSince() != nil {
+		query = query.Where("inventory_items.updated_at >= ?", req.GetUpdatedSince().AsTime())
+	}
+
+	// One more than the page tells whether there is a next one
+	var items []models.InventoryItem
+	if err := query.Order("inventory_items.id").Limit(pageSize + 1).Find(&items).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to list stock")
+	}
+	resp := &warehousepb.ListStockResponse{}
+	if len(items) > pageSize {
+		items = items[:pageSize]
+		resp.NextPageToken = strconv.FormatUint(uint64(items[pageSize-1].ID), 10)
+	}
+	resp.Levels = make([]*warehousepb.StockLevel, len(items))
+	for i := range items {
+		resp.Levels[i] = stockLevel(&items[i])
+	}
+	return resp, nil
+}
+
+// AdjustStock adds to or removes from the stock of a batch, like the REST
+// stock adjustment, recording the caller as the user who made it
+func (s *Server) AdjustStock(ctx context.Context, req *warehousepb.AdjustStockRequest) (*warehousepb.AdjustStockResponse, error) {
+	if req.GetReason() == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+	if req.GetQuantity() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must not be 0")
+	}
+	variantID, err := s.variantID(ctx, req.GetSku(), req.GetVariantId())
+	if err != nil {
+		return nil, err
+	}
+	var warehouse models.Warehouse
+	if err := s.db.WithContext(ctx).Where("code = ?", req.GetWarehouseCode()).First(&warehouse).Error; err != nil {
+		return nil, notFound(err, "warehouse not found")
+	}
+	if !warehouse.IsActive {
+		return nil, status.Error(codes.FailedPrecondition, "warehouse is not active")
+	}
+
+	adjustment := stock.Adjustment{
+		VariantID:   variantID,
+		WarehouseID: warehouse.ID,
+		Quantity:    int(req.GetQuantity()),
+		BatchNumber: req.GetBatchNumber(),
+		Reason:      req.GetReason(),
+		Notes:       req.GetNotes(),
+		Reference:   req.GetReference(),
+		UserID:      userID(ctx),
+	}
+	if req.GetExpiryDate() != nil {
+		expiry := req.GetExpiryDate().AsTime()
+		adjustment.ExpiryDate = &expiry
+	}
+	item, previousQuantity, err := s.stock.Adjust(ctx, adjustment)
+	var insufficient *stock.InsufficientStockError
+	switch {
+	case errors.Is(err, stock.ErrNegativeNewBatch), errors.As(err, &insufficient):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "failed to adjust stock")
+	}
+	metrics.InventoryAdjusted("grpc", adjustment.Quantity)
+
+	if err := s.stockQuery(ctx).First(item, item.ID).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to get adjusted stock")
+	}
+	return &warehousepb.AdjustStockResponse{Level: stockLevel(item), PreviousQuantity: int32(previousQuantity)}, nil
+}
+
+// PullOrders returns orders to fulfil in pages, in order of ID
+func (s *Server) PullOrders(ctx context.Context, req *warehousepb.PullOrdersRequest) (*warehousepb.PullOrdersResponse, error) {
+	afterID, err := parsePageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	pageSize := pageSize(req.GetPageSize(), defaultOrderPageSize, maxOrderPageSize)
+	statuses := req.GetStatuses()
+	if len(statuses) == 0 {
+		statuses = []string{string(models.OrderStatusProcessing)}
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Order{}).
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Select("id", "first_name", "last_name") }).
+		Preload("ShippingAddress").
+		Preload("Items.ProductVariant.Product").
+		Preload("Shipments.Warehouse").
+		Where("orders.id > ? AND orders.status IN ?", afterID, statuses)
+	if req.GetWarehouseCode() != "" {
+		warehouseID, err := s.warehouseID(ctx, req.GetWarehouseCode())
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("EXISTS (SELECT 1 FROM shipments WHERE shipments.order_id = orders.id AND shipments.warehouse_id = ? AND shipments.deleted_at IS NULL)", warehouseID)
+	}
+	if req.GetUpdatedSince() != nil {
+		query = query.Where("orders.updated_at >= ?", req.GetUpdatedSince().AsTime())
+	}
+
+	var orders []models.Order
+	if err := query.Order("orders.id").Limit(pageSize + 1).Find(&orders).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to pull orders")
+	}
+	resp := &warehousepb.PullOrdersResponse{}
+	if len(orders) > pageSize {
+		orders = orders[:pageSize]
+		resp.NextPageToken = strconv.FormatUint(uint64(orders[pageSize-1].ID), 10)
+	}
+	resp.Orders = make([]*warehousepb.Order, len(orders))
+	for i := range orders {
+		resp.Orders[i] = order(&orders[i])
+	}
+	return resp, nil
+}
+
+// stockQuery loads inventory items with what StockLevel reports of their
+// variant and warehouse
+func (s *Server) stockQuery(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.InventoryItem{}).
+		Preload("ProductVariant", func(db *gorm.DB) *gorm.DB { return db.Select("id", "sku") }).
+		Preload("Warehouse", func(db *gorm.DB) *gorm.DB { return db.Select("id", "code") })
+}
+
+// variantID finds a variant by SKU or, when there is none, by ID
+func (s *Server) variantID(ctx context.Context, sku string, id uint64) (uint, error) {
+	if sku == "" && id == 0 {
+		return 0, status.Error(codes.InvalidArgument, "sku or variant_id is required")
+	}
+	var variant models.ProductVariant
+	query := s.db.WithContext(ctx).Select("id")
+	if sku != "" {
+		query = query.Where("sku = ?", sku)
+	} else {
+		query = query.Where("id = ?", id)
+	}
+	if err := query.First(&variant).Error; err != nil {
+		return 0, notFound(err, "variant not found")
+	}
+	return variant.ID, nil
+}
+
+// warehouseID finds a warehouse by code
+func (s *Server) warehouseID(ctx context.Context, code string) (uint, error) {
+	var warehouse models.Warehouse
+	if err := s.db.WithContext(ctx).Select("id").Where("code = ?", code).First(&warehouse).Error; err != nil {
+		return 0, notFound(err, "warehouse not found")
+	}
+	return warehouse.ID, nil
+}
+
+// notFound maps a record not found error to NotFound with message, and any
+// other to Internal
+func notFound(err error, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status.Error(codes.NotFound, message)
+	}
+	return status.Error(codes.Internal, "failed to query the database")
+}
+
+// parsePageToken returns the ID a page starts after, 0 for the first page
+func parsePageToken(token string) (uint, error) {
+	if token == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(token, 10, 32)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "page_token is invalid")
+	}
+	return uint(id), nil
+}
+
+func pageSize(requested int32, fallback, max int) int {
+	switch {
+	case requested <= 0:
+		return fallback
+	case int(requested) > max:
+		return max
+	}
+	return int(requested)
+}
+
+func stockLevel(item *models.InventoryItem) *warehousepb.StockLevel {
+	available := item.Quantity - item.Reserved
+	if item.Status == stock.StatusExpired {
+		// Expired batches are kept until written off but cannot be sold
+		available = 0
+	}
+	return &warehousepb.StockLevel{
+		InventoryItemId: uint64(item.ID),
+		VariantId:       uint64(item.ProductVariantID),
+		Sku:             item.ProductVariant.SKU,
+		WarehouseCode:   item.Warehouse.Code,
+		BatchNumber:     item.BatchNumber,
+		Quantity:        int32(item.Quantity),
+		Reserved:        int32(item.Reserved),
+		Available:       int32(available),
+		Status:          item.Status,
+		ExpiryDate:      timestamp(item.ExpiryDate),
+		BinLocation:     item.BinLocation,
+		UpdatedAt:       timestamppb.New(item.UpdatedAt),
+	}
+}
+
+func order(o *models.Order) *warehousepb.Order {
+	address := &o.ShippingAddress
+	out := &warehousepb.Order{
+		OrderId:        uint64(o.ID),
+		OrderNumber:    o.OrderNumber,
+		Status:         string(o.Status),
+		PaymentStatus:  string(o.PaymentStatus),
+		OrderDate:      timestamppb.New(o.OrderDate),
+		UpdatedAt:      timestamppb.New(o.UpdatedAt),
+		ShippingMethod: o.ShippingMethod,
+		FinalAmount:    o.FinalAmount,
+		CustomerNotes:  o.CustomerNotes,
+		ShippingAddress: &warehousepb.Address{
+			Name:           strings.TrimSpace(o.User.FirstName + " " + o.User.LastName),
+			StreetAddress1: address.StreetAddress1,
+			StreetAddress2: address.StreetAddress2,
+			City:           address.City,
+			State:          address.State,
+			PostalCode:     address.PostalCode,
+			Country:        address.Country,
+			Phone:          address.Phone,
+		},
+	}
+	for _, item := range o.Items {
+		name := item.ProductVariant.Name
+		if item.ProductVariant.Product.Name != "" {
+			name = item.ProductVariant.Product.Name + " - " + name
+		}
+		out.Lines = append(out.Lines, &warehousepb.OrderLine{
+			OrderItemId: uint64(item.ID),
+			VariantId:   uint64(item.ProductVariantID),
+			Sku:         item.ProductVariant.SKU,
+			Name:        name,
+			Quantity:    int32(item.Quantity),
+			UnitPrice:   item.UnitPrice,
+			TotalAmount: item.TotalAmount,
+		})
+	}
+	for _, shipment := range o.Shipments {
+		code := ""
+		if shipment.Warehouse != nil {
+			code = shipment.Warehouse.Code
+		}
+		out.Shipments = append(out.Shipments, &warehousepb.Shipment{
+			ShipmentId:     uint64(shipment.ID),
+			WarehouseCode:  code,
+			Status:         string(shipment.Status),
+			TrackingNumber: shipment.TrackingNumber,
+		})
+	}
+	return out
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
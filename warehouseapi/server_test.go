@@ -0,0 +1,191 @@
+package warehouseapi
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/utils/auth"
+	"github.com/YasserCherfaoui/MarketProGo/warehouseapi/warehousepb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Address{},
+		&models.User{},
+		&models.Warehouse{},
+		&models.Product{},
+		&models.ProductVariant{},
+		&models.BundleComponent{},
+		&models.InventoryItem{},
+		&models.StockMovement{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.Shipment{},
+	))
+	return db
+}
+
+// createStock creates a variant with a batch of 20 in each of two warehouses
+func createStock(t *testing.T, db *gorm.DB) (models.ProductVariant, []models.Warehouse) {
+	warehouses := []models.Warehouse{
+		{Name: "London Warehouse", Code: "WH-LON", IsActive: true, Address: models.Address{StreetAddress1: "1 Dock Road", City: "London", Country: "GB"}},
+		{Name: "Leeds Warehouse", Code: "WH-LDS", IsActive: true, Address: models.Address{StreetAddress1: "2 Mill Lane", City: "Leeds", Country: "GB"}},
+	}
+	require.NoError(t, db.Create(&warehouses).Error)
+	product := models.Product{Name: "Halloumi", IsActive: true}
+	require.NoError(t, db.Create(&product).Error)
+	variant := models.ProductVariant{ProductID: product.ID, Name: "250g", SKU: "HAL-250"}
+	require.NoError(t, db.Omit("Product").Create(&variant).Error)
+	for _, warehouse := range warehouses {
+		item := models.InventoryItem{ProductVariantID: variant.ID, WarehouseID: warehouse.ID, Quantity: 20, Reserved: 5, BatchNumber: "A", Status: "active"}
+		require.NoError(t, db.Omit("ProductVariant", "Warehouse").Create(&item).Error)
+	}
+	return variant, warehouses
+}
+
+func TestStock(t *testing.T) {
+	db := setupTestDB(t)
+	createStock(t, db)
+	server := NewServer(db)
+	ctx := context.Background()
+
+	resp, err := server.GetStock(ctx, &warehousepb.GetStockRequest{Sku: "HAL-250", WarehouseCode: "WH-LDS"})
+	require.NoError(t, err)
+	require.Len(t, resp.Levels, 1)
+	assert.Equal(t, "WH-LDS", resp.Levels[0].WarehouseCode)
+	assert.Equal(t, int32(15), resp.Levels[0].Available)
+
+	_, err = server.GetStock(ctx, &warehousepb.GetStockRequest{Sku: "MISSING"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Pages of one, then the last one has no next page token
+	page, err := server.ListStock(ctx, &warehousepb.ListStockRequest{PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Levels, 1)
+	require.NotEmpty(t, page.NextPageToken)
+	page, err = server.ListStock(ctx, &warehousepb.ListStockRequest{PageSize: 1, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, page.Levels, 1)
+	assert.Equal(t, "WH-LDS", page.Levels[0].WarehouseCode)
+	assert.Empty(t, page.NextPageToken)
+}
+
+func TestAdjustStock(t *testing.T) {
+	db := setupTestDB(t)
+	variant, _ := createStock(t, db)
+	server := NewServer(db)
+	ctx := context.WithValue(context.Background(), claimsKey{}, &auth.MyClaims{UserID: 7})
+
+	resp, err := server.AdjustStock(ctx, &warehousepb.AdjustStockRequest{
+		Sku: "HAL-250", WarehouseCode: "WH-LON", BatchNumber: "A", Quantity: -4, Reason: "Cycle count", Reference: "WMS-12",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(20), resp.PreviousQuantity)
+	assert.Equal(t, int32(16), resp.Level.Quantity)
+	assert.Equal(t, "HAL-250", resp.Level.Sku)
+
+	var movement models.StockMovement
+	require.NoError(t, db.First(&movement).Error)
+	require.NotNil(t, movement.UserID)
+	assert.Equal(t, uint(7), *movement.UserID)
+	assert.Equal(t, "WMS-12", movement.Reference)
+
+	require.NoError(t, db.First(&variant, variant.ID).Error)
+	assert.Equal(t, 36, variant.QuantityInStock)
+
+	_, err = server.AdjustStock(ctx, &warehousepb.AdjustStockRequest{
+		Sku: "HAL-250", WarehouseCode: "WH-LON", BatchNumber: "A", Quantity: -17, Reason: "Cycle count",
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = server.AdjustStock(ctx, &warehousepb.AdjustStockRequest{Sku: "HAL-250", WarehouseCode: "WH-LON", Quantity: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPullOrders(t *testing.T) {
+	db := setupTestDB(t)
+	variant, warehouses := createStock(t, db)
+	user := models.User{Email: "amira@example.com", FirstName: "Amira", LastName: "Haddad", UserType: models.Customer}
+	require.NoError(t, db.Create(&user).Error)
+	address := models.Address{StreetAddress1: "9 High Street", City: "York", PostalCode: "YO1 7HH", Country: "GB"}
+	require.NoError(t, db.Create(&address).Error)
+
+	orders := []models.Order{
+		{OrderNumber: "ORD-1", Status: models.OrderStatusProcessing},
+		{OrderNumber: "ORD-2", Status: models.OrderStatusPending},
+		{OrderNumber: "ORD-3", Status: models.OrderStatusProcessing},
+	}
+	for i := range orders {
+		orders[i].UserID = user.ID
+		orders[i].ShippingAddressID = address.ID
+		orders[i].PaymentStatus = models.PaymentStatusPaid
+		require.NoError(t, db.Omit("User", "ShippingAddress", "BillingAddress").Create(&orders[i]).Error)
+		item := models.OrderItem{OrderID: orders[i].ID, ProductVariantID: variant.ID, Quantity: 2, UnitPrice: 3.5, TotalAmount: 7}
+		require.NoError(t, db.Omit("ProductVariant").Create(&item).Error)
+	}
+	shipment := models.Shipment{OrderID: orders[2].ID, WarehouseID: warehouses[1].ID, Status: "PENDING"}
+	require.NoError(t, db.Omit("Warehouse").Create(&shipment).Error)
+
+	server := NewServer(db)
+	resp, err := server.PullOrders(context.Background(), &warehousepb.PullOrdersRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Orders, 2)
+	assert.Equal(t, "ORD-1", resp.Orders[0].OrderNumber)
+	assert.Equal(t, "Amira Haddad", resp.Orders[0].ShippingAddress.Name)
+	assert.Equal(t, "YO1 7HH", resp.Orders[0].ShippingAddress.PostalCode)
+	require.Len(t, resp.Orders[0].Lines, 1)
+	assert.Equal(t, "Halloumi - 250g", resp.Orders[0].Lines[0].Name)
+
+	resp, err = server.PullOrders(context.Background(), &warehousepb.PullOrdersRequest{WarehouseCode: "WH-LDS"})
+	require.NoError(t, err)
+	require.Len(t, resp.Orders, 1)
+	assert.Equal(t, "ORD-3", resp.Orders[0].OrderNumber)
+	require.Len(t, resp.Orders[0].Shipments, 1)
+	assert.Equal(t, "WH-LDS", resp.Orders[0].Shipments[0].WarehouseCode)
+}
+
+func TestUnaryAuth(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret-key")
+	vendorToken, err := auth.GenerateToken(1, models.Vendor, nil)
+	require.NoError(t, err)
+	customerToken, err := auth.GenerateToken(2, models.Customer, nil)
+	require.NoError(t, err)
+
+	interceptor := UnaryAuth()
+	call := func(method, token string) (interface{}, error) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		return interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return userID(ctx), nil
+		})
+	}
+
+	caller, err := call(warehousepb.WarehouseService_AdjustStock_FullMethodName, vendorToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), *caller.(*uint))
+
+	_, err = call(warehousepb.WarehouseService_GetStock_FullMethodName, "")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call(warehousepb.WarehouseService_GetStock_FullMethodName, "invalid")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call(warehousepb.WarehouseService_GetStock_FullMethodName, customerToken)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	// Vendors manage inventory but cannot read orders
+	_, err = call(warehousepb.WarehouseService_PullOrders_FullMethodName, vendorToken)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,1283 @@
+// Internal API for the warehouse management system and ERP. Served over
+// gRPC with mutual TLS; every call also carries a bearer access token in
+// the "authorization" metadata, checked against the same permission scopes
+// as the REST API.
+//
+// Regenerate the Go code in warehouseapi/warehousepb with:
+//
+//   protoc --go_out=. --go_opt=module=github.com/YasserCherfaoui/MarketProGo \
+//     --go-grpc_out=. --go-grpc_opt=module=github.com/YasserCherfaoui/MarketProGo \
+//     proto/warehouse/v1/warehouse.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        v5.29.3
+// source: proto/warehouse/v1/warehouse.proto
+
+package warehousepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StockLevel is the stock of one batch of a variant in a warehouse
+type StockLevel struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	InventoryItemId uint64                 `protobuf:"varint,1,opt,name=inventory_item_id,json=inventoryItemId,proto3" json:"inventory_item_id,omitempty"`
+	VariantId       uint64                 `protobuf:"varint,2,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
+	Sku             string                 `protobuf:"bytes,3,opt,name=sku,proto3" json:"sku,omitempty"`
+	WarehouseCode   string                 `protobuf:"bytes,4,opt,name=warehouse_code,json=warehouseCode,proto3" json:"warehouse_code,omitempty"`
+	BatchNumber     string                 `protobuf:"bytes,5,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`
+	Quantity        int32                  `protobuf:"varint,6,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Reserved        int32                  `protobuf:"varint,7,opt,name=reserved,proto3" json:"reserved,omitempty"`
+	// Quantity that can be sold: not reserved, and 0 for expired batches
+	Available int32 `protobuf:"varint,8,opt,name=available,proto3" json:"available,omitempty"`
+	// active, expired or damaged
+	Status        string                 `protobuf:"bytes,9,opt,name=status,proto3" json:"status,omitempty"`
+	ExpiryDate    *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=expiry_date,json=expiryDate,proto3" json:"expiry_date,omitempty"`
+	BinLocation   string                 `protobuf:"bytes,11,opt,name=bin_location,json=binLocation,proto3" json:"bin_location,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StockLevel) Reset() {
+	*x = StockLevel{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StockLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StockLevel) ProtoMessage() {}
+
+func (x *StockLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StockLevel.ProtoReflect.Descriptor instead.
+func (*StockLevel) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StockLevel) GetInventoryItemId() uint64 {
+	if x != nil {
+		return x.InventoryItemId
+	}
+	return 0
+}
+
+func (x *StockLevel) GetVariantId() uint64 {
+	if x != nil {
+		return x.VariantId
+	}
+	return 0
+}
+
+func (x *StockLevel) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *StockLevel) GetWarehouseCode() string {
+	if x != nil {
+		return x.WarehouseCode
+	}
+	return ""
+}
+
+func (x *StockLevel) GetBatchNumber() string {
+	if x != nil {
+		return x.BatchNumber
+	}
+	return ""
+}
+
+func (x *StockLevel) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *StockLevel) GetReserved() int32 {
+	if x != nil {
+		return x.Reserved
+	}
+	return 0
+}
+
+func (x *StockLevel) GetAvailable() int32 {
+	if x != nil {
+		return x.Available
+	}
+	return 0
+}
+
+func (x *StockLevel) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *StockLevel) GetExpiryDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiryDate
+	}
+	return nil
+}
+
+func (x *StockLevel) GetBinLocation() string {
+	if x != nil {
+		return x.BinLocation
+	}
+	return ""
+}
+
+func (x *StockLevel) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type GetStockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of sku and variant_id identifies the variant
+	Sku       string `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	VariantId uint64 `protobuf:"varint,2,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
+	// Only this warehouse when set
+	WarehouseCode string `protobuf:"bytes,3,opt,name=warehouse_code,json=warehouseCode,proto3" json:"warehouse_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStockRequest) Reset() {
+	*x = GetStockRequest{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStockRequest) ProtoMessage() {}
+
+func (x *GetStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStockRequest.ProtoReflect.Descriptor instead.
+func (*GetStockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetStockRequest) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *GetStockRequest) GetVariantId() uint64 {
+	if x != nil {
+		return x.VariantId
+	}
+	return 0
+}
+
+func (x *GetStockRequest) GetWarehouseCode() string {
+	if x != nil {
+		return x.WarehouseCode
+	}
+	return ""
+}
+
+type GetStockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Levels        []*StockLevel          `protobuf:"bytes,1,rep,name=levels,proto3" json:"levels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStockResponse) Reset() {
+	*x = GetStockResponse{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStockResponse) ProtoMessage() {}
+
+func (x *GetStockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStockResponse.ProtoReflect.Descriptor instead.
+func (*GetStockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetStockResponse) GetLevels() []*StockLevel {
+	if x != nil {
+		return x.Levels
+	}
+	return nil
+}
+
+type ListStockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WarehouseCode string                 `protobuf:"bytes,1,opt,name=warehouse_code,json=warehouseCode,proto3" json:"warehouse_code,omitempty"`
+	UpdatedSince  *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=updated_since,json=updatedSince,proto3" json:"updated_since,omitempty"`
+	// At most 500; 100 when unset
+	PageSize int32 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page
+	PageToken     string `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStockRequest) Reset() {
+	*x = ListStockRequest{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStockRequest) ProtoMessage() {}
+
+func (x *ListStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStockRequest.ProtoReflect.Descriptor instead.
+func (*ListStockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListStockRequest) GetWarehouseCode() string {
+	if x != nil {
+		return x.WarehouseCode
+	}
+	return ""
+}
+
+func (x *ListStockRequest) GetUpdatedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedSince
+	}
+	return nil
+}
+
+func (x *ListStockRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListStockRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListStockResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Levels []*StockLevel          `protobuf:"bytes,1,rep,name=levels,proto3" json:"levels,omitempty"`
+	// Empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStockResponse) Reset() {
+	*x = ListStockResponse{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStockResponse) ProtoMessage() {}
+
+func (x *ListStockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStockResponse.ProtoReflect.Descriptor instead.
+func (*ListStockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListStockResponse) GetLevels() []*StockLevel {
+	if x != nil {
+		return x.Levels
+	}
+	return nil
+}
+
+func (x *ListStockResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type AdjustStockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of sku and variant_id identifies the variant
+	Sku           string `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	VariantId     uint64 `protobuf:"varint,2,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
+	WarehouseCode string `protobuf:"bytes,3,opt,name=warehouse_code,json=warehouseCode,proto3" json:"warehouse_code,omitempty"`
+	BatchNumber   string `protobuf:"bytes,4,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`
+	// Added to the batch; negative to remove stock
+	Quantity int32 `protobuf:"varint,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Kept for a new batch
+	ExpiryDate *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=expiry_date,json=expiryDate,proto3" json:"expiry_date,omitempty"`
+	Reason     string                 `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	Notes      string                 `protobuf:"bytes,8,opt,name=notes,proto3" json:"notes,omitempty"`
+	// The caller's own reference, such as a WMS movement ID
+	Reference     string `protobuf:"bytes,9,opt,name=reference,proto3" json:"reference,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AdjustStockRequest) Reset() {
+	*x = AdjustStockRequest{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdjustStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdjustStockRequest) ProtoMessage() {}
+
+func (x *AdjustStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdjustStockRequest.ProtoReflect.Descriptor instead.
+func (*AdjustStockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AdjustStockRequest) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *AdjustStockRequest) GetVariantId() uint64 {
+	if x != nil {
+		return x.VariantId
+	}
+	return 0
+}
+
+func (x *AdjustStockRequest) GetWarehouseCode() string {
+	if x != nil {
+		return x.WarehouseCode
+	}
+	return ""
+}
+
+func (x *AdjustStockRequest) GetBatchNumber() string {
+	if x != nil {
+		return x.BatchNumber
+	}
+	return ""
+}
+
+func (x *AdjustStockRequest) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *AdjustStockRequest) GetExpiryDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiryDate
+	}
+	return nil
+}
+
+func (x *AdjustStockRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *AdjustStockRequest) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+func (x *AdjustStockRequest) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+type AdjustStockResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Level            *StockLevel            `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	PreviousQuantity int32                  `protobuf:"varint,2,opt,name=previous_quantity,json=previousQuantity,proto3" json:"previous_quantity,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *AdjustStockResponse) Reset() {
+	*x = AdjustStockResponse{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdjustStockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdjustStockResponse) ProtoMessage() {}
+
+func (x *AdjustStockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdjustStockResponse.ProtoReflect.Descriptor instead.
+func (*AdjustStockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AdjustStockResponse) GetLevel() *StockLevel {
+	if x != nil {
+		return x.Level
+	}
+	return nil
+}
+
+func (x *AdjustStockResponse) GetPreviousQuantity() int32 {
+	if x != nil {
+		return x.PreviousQuantity
+	}
+	return 0
+}
+
+type Address struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The customer's name
+	Name           string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	StreetAddress1 string `protobuf:"bytes,2,opt,name=street_address1,json=streetAddress1,proto3" json:"street_address1,omitempty"`
+	StreetAddress2 string `protobuf:"bytes,3,opt,name=street_address2,json=streetAddress2,proto3" json:"street_address2,omitempty"`
+	City           string `protobuf:"bytes,4,opt,name=city,proto3" json:"city,omitempty"`
+	State          string `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	PostalCode     string `protobuf:"bytes,6,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	Country        string `protobuf:"bytes,7,opt,name=country,proto3" json:"country,omitempty"`
+	Phone          string `protobuf:"bytes,8,opt,name=phone,proto3" json:"phone,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Address) Reset() {
+	*x = Address{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Address) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Address) ProtoMessage() {}
+
+func (x *Address) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Address.ProtoReflect.Descriptor instead.
+func (*Address) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Address) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Address) GetStreetAddress1() string {
+	if x != nil {
+		return x.StreetAddress1
+	}
+	return ""
+}
+
+func (x *Address) GetStreetAddress2() string {
+	if x != nil {
+		return x.StreetAddress2
+	}
+	return ""
+}
+
+func (x *Address) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Address) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Address) GetPostalCode() string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return ""
+}
+
+func (x *Address) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *Address) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+type OrderLine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderItemId   uint64                 `protobuf:"varint,1,opt,name=order_item_id,json=orderItemId,proto3" json:"order_item_id,omitempty"`
+	VariantId     uint64                 `protobuf:"varint,2,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
+	Sku           string                 `protobuf:"bytes,3,opt,name=sku,proto3" json:"sku,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Quantity      int32                  `protobuf:"varint,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	UnitPrice     float64                `protobuf:"fixed64,6,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
+	TotalAmount   float64                `protobuf:"fixed64,7,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderLine) Reset() {
+	*x = OrderLine{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderLine) ProtoMessage() {}
+
+func (x *OrderLine) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderLine.ProtoReflect.Descriptor instead.
+func (*OrderLine) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *OrderLine) GetOrderItemId() uint64 {
+	if x != nil {
+		return x.OrderItemId
+	}
+	return 0
+}
+
+func (x *OrderLine) GetVariantId() uint64 {
+	if x != nil {
+		return x.VariantId
+	}
+	return 0
+}
+
+func (x *OrderLine) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *OrderLine) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OrderLine) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *OrderLine) GetUnitPrice() float64 {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return 0
+}
+
+func (x *OrderLine) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+type Shipment struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ShipmentId     uint64                 `protobuf:"varint,1,opt,name=shipment_id,json=shipmentId,proto3" json:"shipment_id,omitempty"`
+	WarehouseCode  string                 `protobuf:"bytes,2,opt,name=warehouse_code,json=warehouseCode,proto3" json:"warehouse_code,omitempty"`
+	Status         string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	TrackingNumber string                 `protobuf:"bytes,4,opt,name=tracking_number,json=trackingNumber,proto3" json:"tracking_number,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Shipment) Reset() {
+	*x = Shipment{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shipment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shipment) ProtoMessage() {}
+
+func (x *Shipment) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shipment.ProtoReflect.Descriptor instead.
+func (*Shipment) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Shipment) GetShipmentId() uint64 {
+	if x != nil {
+		return x.ShipmentId
+	}
+	return 0
+}
+
+func (x *Shipment) GetWarehouseCode() string {
+	if x != nil {
+		return x.WarehouseCode
+	}
+	return ""
+}
+
+func (x *Shipment) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Shipment) GetTrackingNumber() string {
+	if x != nil {
+		return x.TrackingNumber
+	}
+	return ""
+}
+
+type Order struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	OrderId         uint64                 `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	OrderNumber     string                 `protobuf:"bytes,2,opt,name=order_number,json=orderNumber,proto3" json:"order_number,omitempty"`
+	Status          string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	PaymentStatus   string                 `protobuf:"bytes,4,opt,name=payment_status,json=paymentStatus,proto3" json:"payment_status,omitempty"`
+	OrderDate       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=order_date,json=orderDate,proto3" json:"order_date,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	ShippingMethod  string                 `protobuf:"bytes,7,opt,name=shipping_method,json=shippingMethod,proto3" json:"shipping_method,omitempty"`
+	ShippingAddress *Address               `protobuf:"bytes,8,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
+	Lines           []*OrderLine           `protobuf:"bytes,9,rep,name=lines,proto3" json:"lines,omitempty"`
+	Shipments       []*Shipment            `protobuf:"bytes,10,rep,name=shipments,proto3" json:"shipments,omitempty"`
+	FinalAmount     float64                `protobuf:"fixed64,11,opt,name=final_amount,json=finalAmount,proto3" json:"final_amount,omitempty"`
+	CustomerNotes   string                 `protobuf:"bytes,12,opt,name=customer_notes,json=customerNotes,proto3" json:"customer_notes,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Order) GetOrderId() uint64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *Order) GetOrderNumber() string {
+	if x != nil {
+		return x.OrderNumber
+	}
+	return ""
+}
+
+func (x *Order) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Order) GetPaymentStatus() string {
+	if x != nil {
+		return x.PaymentStatus
+	}
+	return ""
+}
+
+func (x *Order) GetOrderDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OrderDate
+	}
+	return nil
+}
+
+func (x *Order) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetShippingMethod() string {
+	if x != nil {
+		return x.ShippingMethod
+	}
+	return ""
+}
+
+func (x *Order) GetShippingAddress() *Address {
+	if x != nil {
+		return x.ShippingAddress
+	}
+	return nil
+}
+
+func (x *Order) GetLines() []*OrderLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *Order) GetShipments() []*Shipment {
+	if x != nil {
+		return x.Shipments
+	}
+	return nil
+}
+
+func (x *Order) GetFinalAmount() float64 {
+	if x != nil {
+		return x.FinalAmount
+	}
+	return 0
+}
+
+func (x *Order) GetCustomerNotes() string {
+	if x != nil {
+		return x.CustomerNotes
+	}
+	return ""
+}
+
+type PullOrdersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Order statuses to pull; PROCESSING when empty
+	Statuses      []string               `protobuf:"bytes,1,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	WarehouseCode string                 `protobuf:"bytes,2,opt,name=warehouse_code,json=warehouseCode,proto3" json:"warehouse_code,omitempty"`
+	UpdatedSince  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=updated_since,json=updatedSince,proto3" json:"updated_since,omitempty"`
+	// At most 200; 50 when unset
+	PageSize int32 `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page
+	PageToken     string `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullOrdersRequest) Reset() {
+	*x = PullOrdersRequest{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullOrdersRequest) ProtoMessage() {}
+
+func (x *PullOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullOrdersRequest.ProtoReflect.Descriptor instead.
+func (*PullOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PullOrdersRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *PullOrdersRequest) GetWarehouseCode() string {
+	if x != nil {
+		return x.WarehouseCode
+	}
+	return ""
+}
+
+func (x *PullOrdersRequest) GetUpdatedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedSince
+	}
+	return nil
+}
+
+func (x *PullOrdersRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *PullOrdersRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type PullOrdersResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Orders []*Order               `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	// Empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullOrdersResponse) Reset() {
+	*x = PullOrdersResponse{}
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullOrdersResponse) ProtoMessage() {}
+
+func (x *PullOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_v1_warehouse_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullOrdersResponse.ProtoReflect.Descriptor instead.
+func (*PullOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_v1_warehouse_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PullOrdersResponse) GetOrders() []*Order {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+func (x *PullOrdersResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+var File_proto_warehouse_v1_warehouse_proto protoreflect.FileDescriptor
+
+const file_proto_warehouse_v1_warehouse_proto_rawDesc = "" +
+	"\n" +
+	"\"proto/warehouse/v1/warehouse.proto\x12\x16marketpro.warehouse.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xbc\x03\n" +
+	"\n" +
+	"StockLevel\x12*\n" +
+	"\x11inventory_item_id\x18\x01 \x01(\x04R\x0finventoryItemId\x12\x1d\n" +
+	"\n" +
+	"variant_id\x18\x02 \x01(\x04R\tvariantId\x12\x10\n" +
+	"\x03sku\x18\x03 \x01(\tR\x03sku\x12%\n" +
+	"\x0ewarehouse_code\x18\x04 \x01(\tR\rwarehouseCode\x12!\n" +
+	"\fbatch_number\x18\x05 \x01(\tR\vbatchNumber\x12\x1a\n" +
+	"\bquantity\x18\x06 \x01(\x05R\bquantity\x12\x1a\n" +
+	"\breserved\x18\a \x01(\x05R\breserved\x12\x1c\n" +
+	"\tavailable\x18\b \x01(\x05R\tavailable\x12\x16\n" +
+	"\x06status\x18\t \x01(\tR\x06status\x12;\n" +
+	"\vexpiry_date\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expiryDate\x12!\n" +
+	"\fbin_location\x18\v \x01(\tR\vbinLocation\x129\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"i\n" +
+	"\x0fGetStockRequest\x12\x10\n" +
+	"\x03sku\x18\x01 \x01(\tR\x03sku\x12\x1d\n" +
+	"\n" +
+	"variant_id\x18\x02 \x01(\x04R\tvariantId\x12%\n" +
+	"\x0ewarehouse_code\x18\x03 \x01(\tR\rwarehouseCode\"N\n" +
+	"\x10GetStockResponse\x12:\n" +
+	"\x06levels\x18\x01 \x03(\v2\".marketpro.warehouse.v1.StockLevelR\x06levels\"\xb6\x01\n" +
+	"\x10ListStockRequest\x12%\n" +
+	"\x0ewarehouse_code\x18\x01 \x01(\tR\rwarehouseCode\x12?\n" +
+	"\rupdated_since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\fupdatedSince\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\"w\n" +
+	"\x11ListStockResponse\x12:\n" +
+	"\x06levels\x18\x01 \x03(\v2\".marketpro.warehouse.v1.StockLevelR\x06levels\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xb4\x02\n" +
+	"\x12AdjustStockRequest\x12\x10\n" +
+	"\x03sku\x18\x01 \x01(\tR\x03sku\x12\x1d\n" +
+	"\n" +
+	"variant_id\x18\x02 \x01(\x04R\tvariantId\x12%\n" +
+	"\x0ewarehouse_code\x18\x03 \x01(\tR\rwarehouseCode\x12!\n" +
+	"\fbatch_number\x18\x04 \x01(\tR\vbatchNumber\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\x05R\bquantity\x12;\n" +
+	"\vexpiry_date\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expiryDate\x12\x16\n" +
+	"\x06reason\x18\a \x01(\tR\x06reason\x12\x14\n" +
+	"\x05notes\x18\b \x01(\tR\x05notes\x12\x1c\n" +
+	"\treference\x18\t \x01(\tR\treference\"|\n" +
+	"\x13AdjustStockResponse\x128\n" +
+	"\x05level\x18\x01 \x01(\v2\".marketpro.warehouse.v1.StockLevelR\x05level\x12+\n" +
+	"\x11previous_quantity\x18\x02 \x01(\x05R\x10previousQuantity\"\xea\x01\n" +
+	"\aAddress\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12'\n" +
+	"\x0fstreet_address1\x18\x02 \x01(\tR\x0estreetAddress1\x12'\n" +
+	"\x0fstreet_address2\x18\x03 \x01(\tR\x0estreetAddress2\x12\x12\n" +
+	"\x04city\x18\x04 \x01(\tR\x04city\x12\x14\n" +
+	"\x05state\x18\x05 \x01(\tR\x05state\x12\x1f\n" +
+	"\vpostal_code\x18\x06 \x01(\tR\n" +
+	"postalCode\x12\x18\n" +
+	"\acountry\x18\a \x01(\tR\acountry\x12\x14\n" +
+	"\x05phone\x18\b \x01(\tR\x05phone\"\xd2\x01\n" +
+	"\tOrderLine\x12\"\n" +
+	"\rorder_item_id\x18\x01 \x01(\x04R\vorderItemId\x12\x1d\n" +
+	"\n" +
+	"variant_id\x18\x02 \x01(\x04R\tvariantId\x12\x10\n" +
+	"\x03sku\x18\x03 \x01(\tR\x03sku\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\x05R\bquantity\x12\x1d\n" +
+	"\n" +
+	"unit_price\x18\x06 \x01(\x01R\tunitPrice\x12!\n" +
+	"\ftotal_amount\x18\a \x01(\x01R\vtotalAmount\"\x93\x01\n" +
+	"\bShipment\x12\x1f\n" +
+	"\vshipment_id\x18\x01 \x01(\x04R\n" +
+	"shipmentId\x12%\n" +
+	"\x0ewarehouse_code\x18\x02 \x01(\tR\rwarehouseCode\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12'\n" +
+	"\x0ftracking_number\x18\x04 \x01(\tR\x0etrackingNumber\"\xb2\x04\n" +
+	"\x05Order\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x04R\aorderId\x12!\n" +
+	"\forder_number\x18\x02 \x01(\tR\vorderNumber\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12%\n" +
+	"\x0epayment_status\x18\x04 \x01(\tR\rpaymentStatus\x129\n" +
+	"\n" +
+	"order_date\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\torderDate\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12'\n" +
+	"\x0fshipping_method\x18\a \x01(\tR\x0eshippingMethod\x12J\n" +
+	"\x10shipping_address\x18\b \x01(\v2\x1f.marketpro.warehouse.v1.AddressR\x0fshippingAddress\x127\n" +
+	"\x05lines\x18\t \x03(\v2!.marketpro.warehouse.v1.OrderLineR\x05lines\x12>\n" +
+	"\tshipments\x18\n" +
+	" \x03(\v2 .marketpro.warehouse.v1.ShipmentR\tshipments\x12!\n" +
+	"\ffinal_amount\x18\v \x01(\x01R\vfinalAmount\x12%\n" +
+	"\x0ecustomer_notes\x18\f \x01(\tR\rcustomerNotes\"\xd3\x01\n" +
+	"\x11PullOrdersRequest\x12\x1a\n" +
+	"\bstatuses\x18\x01 \x03(\tR\bstatuses\x12%\n" +
+	"\x0ewarehouse_code\x18\x02 \x01(\tR\rwarehouseCode\x12?\n" +
+	"\rupdated_since\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\fupdatedSince\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x05 \x01(\tR\tpageToken\"s\n" +
+	"\x12PullOrdersResponse\x125\n" +
+	"\x06orders\x18\x01 \x03(\v2\x1d.marketpro.warehouse.v1.OrderR\x06orders\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken2\xa0\x03\n" +
+	"\x10WarehouseService\x12]\n" +
+	"\bGetStock\x12'.marketpro.warehouse.v1.GetStockRequest\x1a(.marketpro.warehouse.v1.GetStockResponse\x12`\n" +
+	"\tListStock\x12(.marketpro.warehouse.v1.ListStockRequest\x1a).marketpro.warehouse.v1.ListStockResponse\x12f\n" +
+	"\vAdjustStock\x12*.marketpro.warehouse.v1.AdjustStockRequest\x1a+.marketpro.warehouse.v1.AdjustStockResponse\x12c\n" +
+	"\n" +
+	"PullOrders\x12).marketpro.warehouse.v1.PullOrdersRequest\x1a*.marketpro.warehouse.v1.PullOrdersResponseBAZ?github.com/YasserCherfaoui/MarketProGo/warehouseapi/warehousepbb\x06proto3"
+
+var (
+	file_proto_warehouse_v1_warehouse_proto_rawDescOnce sync.Once
+	file_proto_warehouse_v1_warehouse_proto_rawDescData []byte
+)
+
+func file_proto_warehouse_v1_warehouse_proto_rawDescGZIP() []byte {
+	file_proto_warehouse_v1_warehouse_proto_rawDescOnce.Do(func() {
+		file_proto_warehouse_v1_warehouse_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_warehouse_v1_warehouse_proto_rawDesc), len(file_proto_warehouse_v1_warehouse_proto_rawDesc)))
+	})
+	return file_proto_warehouse_v1_warehouse_proto_rawDescData
+}
+
+var file_proto_warehouse_v1_warehouse_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_proto_warehouse_v1_warehouse_proto_goTypes = []any{
+	(*StockLevel)(nil),            // 0: marketpro.warehouse.v1.StockLevel
+	(*GetStockRequest)(nil),       // 1: marketpro.warehouse.v1.GetStockRequest
+	(*GetStockResponse)(nil),      // 2: marketpro.warehouse.v1.GetStockResponse
+	(*ListStockRequest)(nil),      // 3: marketpro.warehouse.v1.ListStockRequest
+	(*ListStockResponse)(nil),     // 4: marketpro.warehouse.v1.ListStockResponse
+	(*AdjustStockRequest)(nil),    // 5: marketpro.warehouse.v1.AdjustStockRequest
+	(*AdjustStockResponse)(nil),   // 6: marketpro.warehouse.v1.AdjustStockResponse
+	(*Address)(nil),               // 7: marketpro.warehouse.v1.Address
+	(*OrderLine)(nil),             // 8: marketpro.warehouse.v1.OrderLine
+	(*Shipment)(nil),              // 9: marketpro.warehouse.v1.Shipment
+	(*Order)(nil),                 // 10: marketpro.warehouse.v1.Order
+	(*PullOrdersRequest)(nil),     // 11: marketpro.warehouse.v1.PullOrdersRequest
+	(*PullOrdersResponse)(nil),    // 12: marketpro.warehouse.v1.PullOrdersResponse
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+}
+var file_proto_warehouse_v1_warehouse_proto_depIdxs = []int32{
+	13, // 0: marketpro.warehouse.v1.StockLevel.expiry_date:type_name -> google.protobuf.Timestamp
+	13, // 1: marketpro.warehouse.v1.StockLevel.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: marketpro.warehouse.v1.GetStockResponse.levels:type_name -> marketpro.warehouse.v1.StockLevel
+	13, // 3: marketpro.warehouse.v1.ListStockRequest.updated_since:type_name -> google.protobuf.Timestamp
+	0,  // 4: marketpro.warehouse.v1.ListStockResponse.levels:type_name -> marketpro.warehouse.v1.StockLevel
+	13, // 5: marketpro.warehouse.v1.AdjustStockRequest.expiry_date:type_name -> google.protobuf.Timestamp
+	0,  // 6: marketpro.warehouse.v1.AdjustStockResponse.level:type_name -> marketpro.warehouse.v1.StockLevel
+	13, // 7: marketpro.warehouse.v1.Order.order_date:type_name -> google.protobuf.Timestamp
+	13, // 8: marketpro.warehouse.v1.Order.updated_at:type_name -> google.protobuf.Timestamp
+	7,  // 9: marketpro.warehouse.v1.Order.shipping_address:type_name -> marketpro.warehouse.v1.Address
+	8,  // 10: marketpro.warehouse.v1.Order.lines:type_name -> marketpro.warehouse.v1.OrderLine
+	9,  // 11: marketpro.warehouse.v1.Order.shipments:type_name -> marketpro.warehouse.v1.Shipment
+	13, // 12: marketpro.warehouse.v1.PullOrdersRequest.updated_since:type_name -> google.protobuf.Timestamp
+	10, // 13: marketpro.warehouse.v1.PullOrdersResponse.orders:type_name -> marketpro.warehouse.v1.Order
+	1,  // 14: marketpro.warehouse.v1.WarehouseService.GetStock:input_type -> marketpro.warehouse.v1.GetStockRequest
+	3,  // 15: marketpro.warehouse.v1.WarehouseService.ListStock:input_type -> marketpro.warehouse.v1.ListStockRequest
+	5,  // 16: marketpro.warehouse.v1.WarehouseService.AdjustStock:input_type -> marketpro.warehouse.v1.AdjustStockRequest
+	11, // 17: marketpro.warehouse.v1.WarehouseService.PullOrders:input_type -> marketpro.warehouse.v1.PullOrdersRequest
+	2,  // 18: marketpro.warehouse.v1.WarehouseService.GetStock:output_type -> marketpro.warehouse.v1.GetStockResponse
+	4,  // 19: marketpro.warehouse.v1.WarehouseService.ListStock:output_type -> marketpro.warehouse.v1.ListStockResponse
+	6,  // 20: marketpro.warehouse.v1.WarehouseService.AdjustStock:output_type -> marketpro.warehouse.v1.AdjustStockResponse
+	12, // 21: marketpro.warehouse.v1.WarehouseService.PullOrders:output_type -> marketpro.warehouse.v1.PullOrdersResponse
+	18, // [18:22] is the sub-list for method output_type
+	14, // [14:18] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_proto_warehouse_v1_warehouse_proto_init() }
+func file_proto_warehouse_v1_warehouse_proto_init() {
+	if File_proto_warehouse_v1_warehouse_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_warehouse_v1_warehouse_proto_rawDesc), len(file_proto_warehouse_v1_warehouse_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_warehouse_v1_warehouse_proto_goTypes,
+		DependencyIndexes: file_proto_warehouse_v1_warehouse_proto_depIdxs,
+		MessageInfos:      file_proto_warehouse_v1_warehouse_proto_msgTypes,
+	}.Build()
+	File_proto_warehouse_v1_warehouse_proto = out.File
+	file_proto_warehouse_v1_warehouse_proto_goTypes = nil
+	file_proto_warehouse_v1_warehouse_proto_depIdxs = nil
+}
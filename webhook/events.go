@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/settings"
+)
+
+// OrderData is the data of order events
+func OrderData(order *models.Order) map[string]interface{} {
+	return map[string]interface{}{
+		"order_id":       order.ID,
+		"order_number":   order.OrderNumber,
+		"user_id":        order.UserID,
+		"company_id":     order.CompanyID,
+		"status":         order.Status,
+		"payment_status": order.PaymentStatus,
+		"total_amount":   order.TotalAmount,
+		"tax_amount":     order.TaxAmount,
+		"final_amount":   order.FinalAmount,
+		"currency":       settings.Current().Currency,
+		"payment_date":   order.PaymentDate,
+		"created_at":     order.CreatedAt,
+	}
+}
+
+// InventoryLowData is the data of inventory.low events
+func InventoryLowData(item *models.InventoryItem, threshold int) map[string]interface{} {
+	return map[string]interface{}{
+		"inventory_item_id":  item.ID,
+		"product_variant_id": item.ProductVariantID,
+		"warehouse_id":       item.WarehouseID,
+		"quantity":           item.Quantity,
+		"threshold":          threshold,
+	}
+}
+
+// TicketData is the data of ticket events
+func TicketData(ticket *models.SupportTicket) map[string]interface{} {
+	return map[string]interface{}{
+		"ticket_id":  ticket.ID,
+		"user_id":    ticket.UserID,
+		"order_id":   ticket.OrderID,
+		"title":      ticket.Title,
+		"category":   ticket.Category,
+		"priority":   ticket.Priority,
+		"status":     ticket.Status,
+		"created_at": ticket.CreatedAt,
+	}
+}
@@ -0,0 +1,199 @@
+// Package webhook delivers business events to the URLs external systems have
+// subscribed with. Events are published in the transaction of the change they
+// describe and delivered through the outbox, so a delivery is retried with
+// backoff until it succeeds or the outbox gives up. Every attempt is logged.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/outbox"
+	"gorm.io/gorm"
+)
+
+// Events that can be subscribed to
+const (
+	EventOrderCreated  = "order.created"
+	EventOrderPaid     = "order.paid"
+	EventInventoryLow  = "inventory.low"
+	EventTicketCreated = "ticket.created"
+
+	// EventTest is sent by test deliveries and cannot be subscribed to
+	EventTest = "webhook.test"
+)
+
+// Events lists the events subscriptions can choose from
+var Events = []string{EventOrderCreated, EventOrderPaid, EventInventoryLow, EventTicketCreated}
+
+// KindSubscription is the outbox kind of deliveries to a subscription
+const KindSubscription = "webhook_subscription"
+
+// Envelope is the JSON body posted for every event
+type Envelope struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// delivery is the outbox payload of a delivery to a subscription. The body is
+// signed when it is sent, so a rotated secret applies to pending retries.
+type delivery struct {
+	SubscriptionID uint            `json:"subscription_id"`
+	Body           json.RawMessage `json:"body"`
+}
+
+// IsEvent reports whether event can be subscribed to
+func IsEvent(event string) bool {
+	for _, known := range Events {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish records a delivery of event to every active subscription to it in
+// tx, which should be the transaction making the change the event is about
+func Publish(tx *gorm.DB, event string, data interface{}) error {
+	var subscriptions []models.WebhookSubscription
+	if err := tx.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		return fmt.Errorf("failed to load webhook subscriptions: %w", err)
+	}
+
+	var body json.RawMessage
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(event) {
+			continue
+		}
+		if body == nil {
+			encoded, err := json.Marshal(Envelope{Event: event, CreatedAt: time.Now().UTC(), Data: data})
+			if err != nil {
+				return fmt.Errorf("failed to encode %s event: %w", event, err)
+			}
+			body = encoded
+		}
+		if err := outbox.Enqueue(tx, KindSubscription, event, delivery{SubscriptionID: subscription.ID, Body: body}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" keyed with secret, as
+// sent in the X-Webhook-Signature header after "sha256="
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a signing secret for a subscription
+func NewSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(raw), nil
+}
+
+// Service sends deliveries to subscriptions and logs them
+type Service struct {
+	db     *gorm.DB
+	client *http.Client
+}
+
+// NewService creates a delivery service. A nil client uses
+// outbox.DefaultWebhookClient.
+func NewService(db *gorm.DB, client *http.Client) *Service {
+	if client == nil {
+		client = outbox.DefaultWebhookClient()
+	}
+	return &Service{db: db, client: client}
+}
+
+// Handler delivers outbox messages of KindSubscription. Deliveries to
+// subscriptions that have since been deleted or deactivated are dropped.
+func (s *Service) Handler() outbox.Handler {
+	return func(ctx context.Context, msg *models.OutboxMessage) error {
+		var payload delivery
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid webhook delivery payload: %w", err)
+		}
+
+		var subscription models.WebhookSubscription
+		if err := s.db.WithContext(ctx).First(&subscription, payload.SubscriptionID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+		if !subscription.IsActive {
+			return nil
+		}
+
+		record := &models.WebhookDelivery{OutboxMessageID: &msg.ID, Attempt: msg.Attempts}
+		return s.send(ctx, &subscription, msg.Topic, strconv.FormatUint(uint64(msg.ID), 10), payload.Body, record)
+	}
+}
+
+// SendTest posts a webhook.test event to subscription straight away and
+// returns the logged delivery
+func (s *Service) SendTest(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(Envelope{
+		Event:     EventTest,
+		CreatedAt: time.Now().UTC(),
+		Data:      map[string]interface{}{"subscription_id": subscription.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	record := &models.WebhookDelivery{Attempt: 1}
+	deliveryID := fmt.Sprintf("test-%d-%d", subscription.ID, time.Now().UnixNano())
+	_ = s.send(ctx, subscription, EventTest, deliveryID, body, record)
+	if record.ID == 0 {
+		return record, fmt.Errorf("failed to log test delivery")
+	}
+	return record, nil
+}
+
+// send signs and posts body to subscription, and fills in and saves record
+func (s *Service) send(ctx context.Context, subscription *models.WebhookSubscription, event, deliveryID string, body []byte, record *models.WebhookDelivery) error {
+	timestamp := time.Now().Unix()
+	hook := outbox.Webhook{
+		URL:  subscription.URL,
+		Body: body,
+		Headers: map[string]string{
+			"X-Webhook-Timestamp": strconv.FormatInt(timestamp, 10),
+			"X-Webhook-Signature": "sha256=" + Sign(subscription.Secret, timestamp, body),
+		},
+	}
+
+	start := time.Now()
+	statusCode, err := outbox.PostWebhook(ctx, s.client, event, deliveryID, hook)
+
+	record.SubscriptionID = subscription.ID
+	record.Event = event
+	record.StatusCode = statusCode
+	record.Success = err == nil
+	record.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if logErr := s.db.WithContext(context.WithoutCancel(ctx)).Create(record).Error; logErr != nil {
+		slog.ErrorContext(ctx, "failed to log webhook delivery", "component", "webhook", "subscription_id", subscription.ID, "error", logErr)
+	}
+	return err
+}
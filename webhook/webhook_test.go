@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/YasserCherfaoui/MarketProGo/models"
+	"github.com/YasserCherfaoui/MarketProGo/outbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.OutboxMessage{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}))
+	return db
+}
+
+func TestPublishDeliversSignedEvents(t *testing.T) {
+	db := setupDB(t)
+
+	var received []*http.Request
+	var bodies [][]byte
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	paid := models.WebhookSubscription{URL: server.URL, Events: []string{EventOrderPaid}, Secret: "whsec_test", IsActive: true}
+	tickets := models.WebhookSubscription{URL: server.URL, Events: []string{EventTicketCreated}, Secret: "whsec_other", IsActive: true}
+	require.NoError(t, db.Create(&paid).Error)
+	require.NoError(t, db.Create(&tickets).Error)
+
+	// Only subscriptions to the event get a delivery
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return Publish(tx, EventOrderPaid, map[string]interface{}{"order_id": 42})
+	}))
+	var messages []models.OutboxMessage
+	require.NoError(t, db.Find(&messages).Error)
+	require.Len(t, messages, 1)
+
+	dispatcher := outbox.NewDispatcher(db)
+	dispatcher.Handle(KindSubscription, NewService(db, server.Client()).Handler())
+
+	// A failed attempt is logged and retried by the outbox
+	_, err := dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.OutboxMessage{}).Where("id = ?", messages[0].ID).Update("next_attempt_at", messages[0].CreatedAt).Error)
+	fail = false
+	_, err = dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, received, 2)
+	last := received[1]
+	assert.Equal(t, EventOrderPaid, last.Header.Get("X-Event"))
+	timestamp, err := strconv.ParseInt(last.Header.Get("X-Webhook-Timestamp"), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256="+Sign("whsec_test", timestamp, bodies[1]), last.Header.Get("X-Webhook-Signature"))
+
+	var envelope struct {
+		Event string         `json:"event"`
+		Data  map[string]int `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(bodies[1], &envelope))
+	assert.Equal(t, EventOrderPaid, envelope.Event)
+	assert.Equal(t, 42, envelope.Data["order_id"])
+
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, db.Order("id").Find(&deliveries).Error)
+	require.Len(t, deliveries, 2)
+	assert.False(t, deliveries[0].Success)
+	assert.Equal(t, http.StatusBadGateway, deliveries[0].StatusCode)
+	assert.True(t, deliveries[1].Success)
+	assert.Equal(t, 2, deliveries[1].Attempt)
+	assert.Equal(t, paid.ID, deliveries[1].SubscriptionID)
+}
+
+func TestDeliveryToDeactivatedSubscriptionIsDropped(t *testing.T) {
+	db := setupDB(t)
+	subscription := models.WebhookSubscription{URL: "http://127.0.0.1:1", Events: []string{EventOrderCreated}, Secret: "s", IsActive: true}
+	require.NoError(t, db.Create(&subscription).Error)
+	require.NoError(t, Publish(db, EventOrderCreated, nil))
+	require.NoError(t, db.Model(&subscription).Update("is_active", false).Error)
+
+	dispatcher := outbox.NewDispatcher(db)
+	dispatcher.Handle(KindSubscription, NewService(db, nil).Handler())
+	_, err := dispatcher.DispatchDue(context.Background())
+	require.NoError(t, err)
+
+	var msg models.OutboxMessage
+	require.NoError(t, db.First(&msg).Error)
+	assert.Equal(t, models.OutboxStatusDispatched, msg.Status)
+	var count int64
+	db.Model(&models.WebhookDelivery{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestSendTest(t *testing.T) {
+	db := setupDB(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	subscription := models.WebhookSubscription{URL: server.URL, Events: []string{EventOrderCreated}, Secret: "s", IsActive: true}
+	require.NoError(t, db.Create(&subscription).Error)
+
+	delivery, err := NewService(db, server.Client()).SendTest(context.Background(), &subscription)
+	require.NoError(t, err)
+	assert.Equal(t, EventTest, delivery.Event)
+	assert.Equal(t, http.StatusUnauthorized, delivery.StatusCode)
+	assert.False(t, delivery.Success)
+	assert.Nil(t, delivery.OutboxMessageID)
+}
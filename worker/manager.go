@@ -0,0 +1,103 @@
+// Package worker runs the application's background workers and stops them
+// together on shutdown.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Manager starts background workers with a shared context and waits for them
+// to return once it is canceled
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]int
+}
+
+// NewManager creates a manager whose workers stop when parent is canceled or
+// Shutdown is called
+func NewManager(parent context.Context) *Manager {
+	ctx, cancel := context.WithCancel(parent)
+	return &Manager{ctx: ctx, cancel: cancel, running: make(map[string]int)}
+}
+
+// Go runs fn in a goroutine. fn must return soon after ctx is canceled; a
+// panic in fn is logged and ends only that worker.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.mu.Lock()
+	m.running[name]++
+	m.mu.Unlock()
+	m.wg.Add(1)
+
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			m.mu.Lock()
+			if m.running[name]--; m.running[name] == 0 {
+				delete(m.running, name)
+			}
+			m.mu.Unlock()
+		}()
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("worker panicked", "component", "worker", "worker", name, "panic", fmt.Sprint(r))
+			}
+		}()
+
+		slog.Info("worker started", "component", "worker", "worker", name)
+		fn(m.ctx)
+		slog.Info("worker stopped", "component", "worker", "worker", name)
+	}()
+}
+
+// Shutdown cancels every worker and waits up to timeout for them to return.
+// It returns an error naming the workers still running after the timeout.
+func (m *Manager) Shutdown(timeout time.Duration) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("workers still running after %s: %s", timeout, strings.Join(m.Running(), ", "))
+	}
+}
+
+// Running returns the names of the workers that have not returned yet
+func (m *Manager) Running() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.running))
+	for name := range m.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sleep waits for d, returning false early if ctx is canceled
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
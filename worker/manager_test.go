@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownWaitsForWorkers(t *testing.T) {
+	manager := NewManager(context.Background())
+	var stopped atomic.Bool
+	manager.Go("loop", func(ctx context.Context) {
+		for Sleep(ctx, time.Millisecond) {
+		}
+		// Finish the current unit of work after cancellation
+		time.Sleep(20 * time.Millisecond)
+		stopped.Store(true)
+	})
+	manager.Go("panics", func(ctx context.Context) {
+		panic("boom")
+	})
+
+	require.NoError(t, manager.Shutdown(time.Second))
+	assert.True(t, stopped.Load())
+	assert.Empty(t, manager.Running())
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	manager := NewManager(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	manager.Go("stuck", func(ctx context.Context) {
+		<-release
+	})
+
+	err := manager.Shutdown(20 * time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stuck")
+}